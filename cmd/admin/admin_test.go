@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantRolePermissions(t *testing.T) {
+	assert.True(t, tenantAdminPermission("invoices:create"))
+	assert.True(t, tenantAdminPermission("read_products"))
+	assert.False(t, tenantAdminPermission("platform:manage_incidents"))
+
+	assert.True(t, tenantUserPermission("read_products"))
+	assert.False(t, tenantUserPermission("invoices:create"))
+}
+
+func TestGeneratePassword(t *testing.T) {
+	a, err := generatePassword()
+	require.NoError(t, err)
+	b, err := generatePassword()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 16)
+	assert.GreaterOrEqual(t, len(a), minPasswordLength)
+	assert.NotEqual(t, a, b)
+}
+
+func TestTargetTenantsFlags(t *testing.T) {
+	ctx := context.Background()
+	tenantID := uuid.New()
+
+	ids, err := targetTenants(ctx, nil, tenantID.String(), false, false)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{tenantID}, ids)
+
+	ids, err = targetTenants(ctx, nil, "", false, true)
+	require.NoError(t, err)
+	assert.Nil(t, ids)
+
+	_, err = targetTenants(ctx, nil, "", false, false)
+	assert.Error(t, err)
+	_, err = targetTenants(ctx, nil, tenantID.String(), true, false)
+	assert.Error(t, err)
+	_, err = targetTenants(ctx, nil, "not-a-uuid", false, false)
+	assert.Error(t, err)
+}
+
+func TestReadPassword(t *testing.T) {
+	password, err := readPassword(strings.NewReader("s3cret-pass\nignored\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret-pass", password)
+
+	password, err = readPassword(strings.NewReader("no-newline"))
+	require.NoError(t, err)
+	assert.Equal(t, "no-newline", password)
+
+	_, err = readPassword(strings.NewReader("short\n"))
+	assert.Error(t, err)
+	_, err = readPassword(strings.NewReader(""))
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/spf13/cobra"
+)
+
+type analyticsRecalculated struct {
+	Views   []*models.AnalyticsViewRefresh `json:"views"`
+	Tenants []*analytics.AnalyticsData     `json:"tenants,omitempty"`
+}
+
+func newAnalyticsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "analytics",
+		Short: "Manage analytics data",
+	}
+
+	var tenant string
+	var all bool
+	recalculate := &cobra.Command{
+		Use:   "recalculate",
+		Short: "Refresh the analytics views and recalculate tenant analytics",
+		Long: "Refresh every analytics materialized view. With --tenant or --all the\n" +
+			"tenants' cached analytics are dropped and their totals recalculated.\n" +
+			"The command exits non-zero when a view fails to refresh.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			env, err := openEnvironment(ctx)
+			if err != nil {
+				return err
+			}
+			defer env.Close()
+
+			tenantIDs, err := targetTenants(ctx, env.pool, tenant, all, true)
+			if err != nil {
+				return err
+			}
+
+			pool := env.pool
+			analyticsSvc := analytics.NewAnalyticsService(
+				repositories.NewOrderRepo(pool),
+				repositories.NewInvoiceRepo(pool),
+				repositories.NewInventoryRepo(pool),
+				repositories.NewProductRepo(pool),
+				repositories.NewConsignmentRepo(pool),
+				env.cache,
+				repositories.NewAnalyticsViewRepo(pool),
+				env.cfg.AnalyticsStaleTolerance,
+			)
+
+			// Tenants are recalculated even when a view fails; the failure is
+			// reported once everything else is done
+			refreshes, refreshErr := analyticsSvc.RefreshMaterializedViews(ctx)
+			log.Printf("Refreshed %d analytics views", len(refreshes))
+
+			result := &analyticsRecalculated{Views: refreshes}
+			if result.Views == nil {
+				result.Views = []*models.AnalyticsViewRefresh{}
+			}
+			for _, tenantID := range tenantIDs {
+				if err := analyticsSvc.InvalidateTenantAnalyticsCache(ctx, tenantID); err != nil {
+					log.Printf("Failed to drop cached analytics of tenant %s: %v", tenantID, err)
+				}
+				data, err := analyticsSvc.CalculateTenantAnalytics(ctx, tenantID)
+				if err != nil {
+					return fmt.Errorf("failed to recalculate analytics of tenant %s: %w", tenantID, err)
+				}
+				result.Tenants = append(result.Tenants, data)
+			}
+
+			if err := printJSON(result); err != nil {
+				return err
+			}
+			if refreshErr != nil {
+				return fmt.Errorf("failed to refresh analytics views: %w", refreshErr)
+			}
+			return nil
+		},
+	}
+	recalculate.Flags().StringVar(&tenant, "tenant", "", "tenant ID")
+	recalculate.Flags().BoolVar(&all, "all", false, "every active tenant")
+
+	cmd.AddCommand(recalculate)
+	return cmd
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// productCacheTTL matches what the product service caches products for
+const productCacheTTL = 15 * time.Minute
+
+// productWarmBatch is how many products are read and cached at a time
+const productWarmBatch = 500
+
+type cacheRebuilt struct {
+	TenantID       uuid.UUID `json:"tenant_id"`
+	ProductsCached int       `json:"products_cached"`
+}
+
+func newCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the Redis cache",
+	}
+
+	var tenant string
+	var all, noWarm bool
+	rebuild := &cobra.Command{
+		Use:   "rebuild",
+		Short: "Drop a tenant's cached data and warm the product cache again",
+		Long: "Drop the cached data of a tenant, or with --all of every tenant, then\n" +
+			"cache their products again so the first requests do not all miss.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			env, err := openEnvironment(ctx)
+			if err != nil {
+				return err
+			}
+			defer env.Close()
+
+			tenantIDs, err := targetTenants(ctx, env.pool, tenant, all, false)
+			if err != nil {
+				return err
+			}
+
+			if all {
+				if err := env.cache.InvalidateAllCache(ctx); err != nil {
+					return fmt.Errorf("failed to clear the cache: %w", err)
+				}
+				log.Printf("Cleared the cache")
+			}
+
+			productRepo := repositories.NewProductRepo(env.pool)
+			rebuilt := make([]cacheRebuilt, 0, len(tenantIDs))
+			for _, tenantID := range tenantIDs {
+				if !all {
+					if err := env.cache.InvalidateTenantCache(ctx, tenantID); err != nil {
+						return fmt.Errorf("failed to clear the cache of tenant %s: %w", tenantID, err)
+					}
+				}
+				result := cacheRebuilt{TenantID: tenantID}
+				for offset := 0; !noWarm; offset += productWarmBatch {
+					products, err := productRepo.List(ctx, tenantID, productWarmBatch, offset)
+					if err != nil {
+						return fmt.Errorf("failed to list products of tenant %s: %w", tenantID, err)
+					}
+					if len(products) == 0 {
+						break
+					}
+					if err := env.cache.SetProducts(ctx, tenantID, products, productCacheTTL); err != nil {
+						return fmt.Errorf("failed to cache products of tenant %s: %w", tenantID, err)
+					}
+					result.ProductsCached += len(products)
+					if len(products) < productWarmBatch {
+						break
+					}
+				}
+				log.Printf("Rebuilt the cache of tenant %s: %d products cached", tenantID, result.ProductsCached)
+				rebuilt = append(rebuilt, result)
+			}
+			return printJSON(rebuilt)
+		},
+	}
+	rebuild.Flags().StringVar(&tenant, "tenant", "", "tenant ID")
+	rebuild.Flags().BoolVar(&all, "all", false, "every tenant; the whole cache is cleared")
+	rebuild.Flags().BoolVar(&noWarm, "no-warm", false, "only clear, without caching products again")
+
+	cmd.AddCommand(rebuild)
+	return cmd
+}
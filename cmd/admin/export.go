@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"agromart2/internal/jobs"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// exportPollInterval is how often the takeout's progress is checked
+const exportPollInterval = 2 * time.Second
+
+func newExportCommand() *cobra.Command {
+	var tenant, format string
+	var documents bool
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export all of a tenant's data to an archive",
+		Long: "Export all of a tenant's data, as the tenant's own takeout request does:\n" +
+			"every table as JSON or CSV plus its documents, zipped into object storage.\n" +
+			"The command waits for the archive and prints the takeout with its\n" +
+			"download link. Nobody is emailed.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tenantID, err := uuid.Parse(tenant)
+			if err != nil {
+				return fmt.Errorf("invalid --tenant: %w", err)
+			}
+
+			ctx := cmd.Context()
+			env, err := openEnvironment(ctx)
+			if err != nil {
+				return err
+			}
+			defer env.Close()
+
+			cfg, pool := env.cfg, env.pool
+			minioSvc, err := services.NewMinioService(cfg.MinioEndpoint, cfg.MinioAccessKey, cfg.MinioSecretKey, cfg.MinioUseSSL)
+			if err != nil {
+				return fmt.Errorf("failed to connect to object storage: %w", err)
+			}
+			// Without a requester there is no one to notify
+			takeouts := jobs.NewTenantTakeoutService(repositories.NewTenantTakeoutRepo(pool), repositories.NewTenantRepo(pool),
+				repositories.NewUserRepo(pool, env.keyring), minioSvc, nil, env.keyring)
+
+			takeout, err := takeouts.Request(ctx, tenantID, nil, &models.TenantTakeoutRequest{
+				Format:           format,
+				IncludeDocuments: &documents,
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("Exporting tenant %s (takeout %s)", tenantID, takeout.ID)
+
+			deadline := time.Now().Add(timeout)
+			for takeout.Status == models.TenantTakeoutRunning {
+				if time.Now().After(deadline) {
+					return fmt.Errorf("takeout %s still running after %s; check it with GET /v1/tenants/%s/takeouts/%s",
+						takeout.ID, timeout, tenantID, takeout.ID)
+				}
+				time.Sleep(exportPollInterval)
+				if takeout, err = takeouts.Get(ctx, tenantID, takeout.ID); err != nil {
+					return fmt.Errorf("failed to check takeout progress: %w", err)
+				}
+				log.Printf("Takeout %s: %.0f%%", takeout.ID, takeout.Progress)
+			}
+
+			if err := printJSON(takeout); err != nil {
+				return err
+			}
+			if takeout.Status != models.TenantTakeoutSucceeded {
+				return fmt.Errorf("takeout %s failed", takeout.ID)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&tenant, "tenant", "", "tenant ID")
+	cmd.Flags().StringVar(&format, "format", models.TakeoutFormatJSON, "table file format, json or csv")
+	cmd.Flags().BoolVar(&documents, "documents", true, "include the tenant's stored documents")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Hour, "how long to wait for the archive")
+	return cmd
+}
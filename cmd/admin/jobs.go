@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"agromart2/internal/jobs"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// Job kinds that can be retried
+const (
+	jobKindDataExport = "data-export"
+	jobKindERPSync    = "erp-sync"
+)
+
+// retriedJob is a failed job and, unless dry-running, the outcome of its rerun
+type retriedJob struct {
+	Kind        string     `json:"kind"`
+	TenantID    uuid.UUID  `json:"tenant_id"`
+	TargetID    uuid.UUID  `json:"target_id"`
+	TargetName  string     `json:"target_name"`
+	FailedRunID uuid.UUID  `json:"failed_run_id"`
+	FailedError *string    `json:"failed_error,omitempty"`
+	RetryRunID  *uuid.UUID `json:"retry_run_id,omitempty"`
+	RetryStatus string     `json:"retry_status,omitempty"`
+	RetryError  *string    `json:"retry_error,omitempty"`
+}
+
+// failedAgain reports whether the job was rerun and failed; both job kinds
+// record runs with the same statuses
+func (j *retriedJob) failedAgain() bool {
+	return j.RetryStatus != "" && j.RetryStatus != models.DataExportSucceeded
+}
+
+func newJobsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Inspect and rerun background jobs",
+	}
+
+	var tenant string
+	var all, dryRun bool
+	var kinds []string
+	retry := &cobra.Command{
+		Use:   "retry",
+		Short: "Rerun data exports and ERP syncs whose last run failed",
+		Long: "Rerun every active data export destination and ERP connector whose last\n" +
+			"run failed. Data exports resume from their cursors; ERP syncs rerun the\n" +
+			"failed run's period. The command exits non-zero when a rerun fails too.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, kind := range kinds {
+				if kind != jobKindDataExport && kind != jobKindERPSync {
+					return fmt.Errorf("unknown --kind %q, use %s or %s", kind, jobKindDataExport, jobKindERPSync)
+				}
+			}
+
+			ctx := cmd.Context()
+			env, err := openEnvironment(ctx)
+			if err != nil {
+				return err
+			}
+			defer env.Close()
+
+			tenantIDs, err := targetTenants(ctx, env.pool, tenant, all, false)
+			if err != nil {
+				return err
+			}
+
+			retrier := &jobRetrier{env: env, dryRun: dryRun}
+			var retried []*retriedJob
+			for _, tenantID := range tenantIDs {
+				for _, kind := range kinds {
+					var found []*retriedJob
+					switch kind {
+					case jobKindDataExport:
+						found, err = retrier.dataExports(ctx, tenantID)
+					case jobKindERPSync:
+						found, err = retrier.erpSyncs(ctx, tenantID)
+					}
+					if err != nil {
+						return fmt.Errorf("failed to retry %s jobs of tenant %s: %w", kind, tenantID, err)
+					}
+					retried = append(retried, found...)
+				}
+			}
+
+			if retried == nil {
+				retried = []*retriedJob{}
+			}
+			if err := printJSON(retried); err != nil {
+				return err
+			}
+			for _, job := range retried {
+				if job.failedAgain() {
+					return fmt.Errorf("%s %s failed again", job.Kind, job.TargetID)
+				}
+			}
+			return nil
+		},
+	}
+	retry.Flags().StringVar(&tenant, "tenant", "", "tenant ID")
+	retry.Flags().BoolVar(&all, "all", false, "every active tenant")
+	retry.Flags().BoolVar(&dryRun, "dry-run", false, "list the failed jobs without rerunning them")
+	retry.Flags().StringSliceVar(&kinds, "kind", []string{jobKindDataExport, jobKindERPSync}, "job kinds to retry")
+
+	cmd.AddCommand(retry)
+	return cmd
+}
+
+type jobRetrier struct {
+	env    *environment
+	dryRun bool
+}
+
+func (r *jobRetrier) dataExports(ctx context.Context, tenantID uuid.UUID) ([]*retriedJob, error) {
+	exporter := jobs.NewDataExportService(repositories.NewDataExportRepo(r.env.pool))
+	destinations, err := exporter.ListDestinations(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var retried []*retriedJob
+	for _, destination := range destinations {
+		if !destination.IsActive {
+			continue
+		}
+		runs, err := exporter.ListRuns(ctx, tenantID, destination.ID, 1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) == 0 || runs[0].Status != models.DataExportFailed {
+			continue
+		}
+
+		job := &retriedJob{
+			Kind:        jobKindDataExport,
+			TenantID:    tenantID,
+			TargetID:    destination.ID,
+			TargetName:  destination.Name,
+			FailedRunID: runs[0].ID,
+			FailedError: runs[0].ErrorMessage,
+		}
+		retried = append(retried, job)
+		if r.dryRun {
+			continue
+		}
+
+		log.Printf("Rerunning data export to %s (%s)", destination.Name, destination.ID)
+		run, err := exporter.SyncNow(ctx, destination)
+		if err != nil {
+			message := err.Error()
+			job.RetryStatus, job.RetryError = models.DataExportFailed, &message
+			continue
+		}
+		job.RetryRunID, job.RetryStatus, job.RetryError = &run.ID, run.Status, run.ErrorMessage
+	}
+	return retried, nil
+}
+
+func (r *jobRetrier) erpSyncs(ctx context.Context, tenantID uuid.UUID) ([]*retriedJob, error) {
+	connectorRepo := repositories.NewERPConnectorRepo(r.env.pool)
+	connectors, err := connectorRepo.ListConnectors(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var syncer *jobs.ERPSyncService
+	var retried []*retriedJob
+	for _, connector := range connectors {
+		if !connector.IsActive {
+			continue
+		}
+		runs, err := connectorRepo.ListRuns(ctx, tenantID, connector.ID, 1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) == 0 || runs[0].Status != models.ERPSyncFailed {
+			continue
+		}
+
+		failed := runs[0]
+		job := &retriedJob{
+			Kind:        jobKindERPSync,
+			TenantID:    tenantID,
+			TargetID:    connector.ID,
+			TargetName:  connector.Name,
+			FailedRunID: failed.ID,
+			FailedError: failed.ErrorMessage,
+		}
+		retried = append(retried, job)
+		if r.dryRun {
+			continue
+		}
+
+		if syncer == nil {
+			if syncer, err = r.erpSyncService(); err != nil {
+				return nil, err
+			}
+		}
+		log.Printf("Rerunning ERP sync %s (%s) for %s to %s", connector.Name, connector.ID,
+			failed.PeriodStart.Format("2006-01-02"), failed.PeriodEnd.Format("2006-01-02"))
+		run, err := syncer.SyncNow(ctx, connector, &failed.PeriodStart, &failed.PeriodEnd)
+		if err != nil {
+			message := err.Error()
+			job.RetryStatus, job.RetryError = models.ERPSyncFailed, &message
+			continue
+		}
+		job.RetryRunID, job.RetryStatus, job.RetryError = &run.ID, run.Status, run.ErrorMessage
+	}
+	return retried, nil
+}
+
+func (r *jobRetrier) erpSyncService() (*jobs.ERPSyncService, error) {
+	cfg, pool := r.env.cfg, r.env.pool
+	minioSvc, err := services.NewMinioService(cfg.MinioEndpoint, cfg.MinioAccessKey, cfg.MinioSecretKey, cfg.MinioUseSSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to object storage: %w", err)
+	}
+	builder := jobs.NewERPDocumentBuilder(
+		repositories.NewInvoiceRepo(pool),
+		repositories.NewOrderRepo(pool),
+		repositories.NewProductRepo(pool),
+		repositories.NewSupplierRepository(pool, r.env.keyring),
+		repositories.NewDistributorRepository(pool, r.env.keyring),
+	)
+	return jobs.NewERPSyncService(repositories.NewERPConnectorRepo(pool), builder, minioSvc), nil
+}
@@ -0,0 +1,157 @@
+// Command admin runs common operational tasks against a deployment without
+// hand-written SQL. It reads the same environment as the server.
+//
+// Usage:
+//
+//	go run ./cmd/admin tenant create --name "Sri Lakshmi Traders" --subdomain srilakshmi --admin-email owner@example.com
+//	go run ./cmd/admin user reset-password --tenant <uuid> --email ravi@example.com [--password-stdin < file]
+//	go run ./cmd/admin jobs retry --tenant <uuid> | --all
+//	go run ./cmd/admin cache rebuild --tenant <uuid> | --all
+//	go run ./cmd/admin analytics recalculate [--tenant <uuid> | --all]
+//	go run ./cmd/admin export --tenant <uuid> [--format csv] [--documents=false]
+//
+// Generated passwords are printed once and never stored. Results are written
+// to stdout as JSON; progress goes to the log on stderr.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"agromart2/internal/app"
+	"agromart2/internal/caching"
+	"agromart2/internal/pii"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+	"agromart2/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "admin",
+		Short:        "Operational tasks for an Agromart deployment",
+		SilenceUsage: true,
+	}
+	root.AddCommand(
+		newTenantCommand(),
+		newUserCommand(),
+		newJobsCommand(),
+		newCacheCommand(),
+		newAnalyticsCommand(),
+		newExportCommand(),
+	)
+	return root
+}
+
+// environment holds the connections a command needs, opened from the
+// server's configuration
+type environment struct {
+	cfg   *app.Config
+	pool  *pgxpool.Pool
+	cache caching.CacheService
+	// keyring is nil unless a PII master key is configured
+	keyring *pii.DataKeyRing
+}
+
+func openEnvironment(ctx context.Context) (*environment, error) {
+	cfg, err := app.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DatabaseURL == "" {
+		return nil, errors.New("DATABASE_URL environment variable is required")
+	}
+
+	pool, err := database.Open(ctx, cfg.DatabaseURL, cfg.DBPool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	env := &environment{
+		cfg:  cfg,
+		pool: pool,
+		cache: caching.NewCircuitBreakerCache(
+			caching.NewRedisCacheService(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB),
+			caching.DefaultCircuitBreakerConfig(),
+		),
+	}
+	if len(cfg.PIIMasterKey) > 0 {
+		env.keyring, err = pii.NewDataKeyRing(repositories.NewDataKeyRepo(pool), cfg.PIIMasterKey)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to initialize PII encryption: %w", err)
+		}
+	}
+	return env, nil
+}
+
+// authService builds the auth service as the server does, so lockouts and
+// token revocations the CLI makes are the ones the server checks
+func (e *environment) authService(ctx context.Context) (services.AuthService, error) {
+	keyRing, err := services.NewKeyRing(ctx, repositories.NewSigningKeyRepo(e.pool), e.cfg.LegacyJWTSecret, time.Hour, services.DefaultKeyRefreshInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize JWT key ring: %w", err)
+	}
+	// Push and WhatsApp delivery are optional; the auth service only emails
+	notificationSvc := services.NewNotificationService(e.cfg.RedisAddr, e.cfg.RedisPassword, e.cfg.RedisDB, nil, nil)
+	return services.NewAuthService(e.cache, notificationSvc, keyRing, services.DefaultAccessTokenTTL, services.DefaultRefreshTokenTTL), nil
+}
+
+func (e *environment) Close() {
+	e.pool.Close()
+}
+
+// targetTenants resolves the --tenant and --all flags; with optional set,
+// giving neither means no particular tenant and returns nil
+func targetTenants(ctx context.Context, pool *pgxpool.Pool, tenant string, all, optional bool) ([]uuid.UUID, error) {
+	if tenant != "" && all {
+		return nil, errors.New("give either --tenant or --all, not both")
+	}
+	if tenant == "" && !all {
+		if optional {
+			return nil, nil
+		}
+		return nil, errors.New("give either --tenant or --all")
+	}
+
+	if tenant != "" {
+		tenantID, err := uuid.Parse(tenant)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tenant: %w", err)
+		}
+		return []uuid.UUID{tenantID}, nil
+	}
+
+	tenants, err := repositories.NewTenantRepo(pool).List(ctx, 10000, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	var tenantIDs []uuid.UUID
+	for _, t := range tenants {
+		if t.Status == "active" {
+			tenantIDs = append(tenantIDs, t.ID)
+		}
+	}
+	return tenantIDs, nil
+}
+
+// printJSON writes a command's result to stdout
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength matches what signup accepts
+const minPasswordLength = 6
+
+// generatePassword returns a random password for an account whose owner
+// will change it
+func generatePassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// tenantAdminPermission reports whether a new tenant's admin role gets the
+// permission; platform permissions stay with the platform admins
+func tenantAdminPermission(name string) bool {
+	return !strings.HasPrefix(name, "platform:")
+}
+
+// tenantUserPermission reports whether a new tenant's user role gets the
+// permission, the read permissions the default tenant's user role has
+func tenantUserPermission(name string) bool {
+	return strings.HasPrefix(name, "read_")
+}
+
+type tenantCreated struct {
+	Tenant        *models.Tenant `json:"tenant"`
+	AdminUserID   uuid.UUID      `json:"admin_user_id"`
+	AdminEmail    string         `json:"admin_email"`
+	AdminPassword string         `json:"admin_password,omitempty"`
+}
+
+func newTenantCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "Manage tenants",
+	}
+
+	var name, subdomain, license, email, firstName, lastName, password string
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a tenant with admin and user roles and its first admin",
+		Long: "Create a tenant with admin and user roles and its first admin.\n" +
+			"The admin role gets every permission except the platform ones. Without\n" +
+			"--admin-password a password is generated and printed once.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if email == "" {
+				return errors.New("--admin-email is required")
+			}
+			generated := password == ""
+			if generated {
+				var err error
+				if password, err = generatePassword(); err != nil {
+					return fmt.Errorf("failed to generate password: %w", err)
+				}
+			} else if len(password) < minPasswordLength {
+				return fmt.Errorf("--admin-password must be at least %d characters", minPasswordLength)
+			}
+
+			ctx := cmd.Context()
+			env, err := openEnvironment(ctx)
+			if err != nil {
+				return err
+			}
+			defer env.Close()
+
+			result, err := createTenant(ctx, env.pool, env.keyring, &services.CreateTenantRequest{
+				Name:      name,
+				Subdomain: subdomain,
+				License:   license,
+			}, email, firstName, lastName, password)
+			if err != nil {
+				return err
+			}
+			if generated {
+				result.AdminPassword = password
+			}
+			return printJSON(result)
+		},
+	}
+	create.Flags().StringVar(&name, "name", "", "business name")
+	create.Flags().StringVar(&subdomain, "subdomain", "", "subdomain, unique across tenants")
+	create.Flags().StringVar(&license, "license", "", "license number")
+	create.Flags().StringVar(&email, "admin-email", "", "email of the tenant's first admin")
+	create.Flags().StringVar(&firstName, "admin-first-name", "Admin", "first name of the admin")
+	create.Flags().StringVar(&lastName, "admin-last-name", "User", "last name of the admin")
+	create.Flags().StringVar(&password, "admin-password", "", "admin password; generated when empty")
+
+	cmd.AddCommand(create)
+	return cmd
+}
+
+// createTenant creates the tenant, its admin and user roles and the admin
+// user. The steps are not atomic: a failure part way is reported with what
+// was created so it can be finished or removed by hand
+func createTenant(ctx context.Context, pool *pgxpool.Pool, keyring *pii.DataKeyRing, req *services.CreateTenantRequest, email, firstName, lastName, password string) (*tenantCreated, error) {
+	userRepo := repositories.NewUserRepo(pool, keyring)
+	roleRepo := repositories.NewRoleRepo(pool)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tenant, err := services.NewTenantService(repositories.NewTenantRepo(pool)).Create(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	log.Printf("Created tenant %s (%s)", tenant.ID, tenant.Subdomain)
+
+	permissions, err := repositories.NewPermissionRepo(pool).List(ctx, 10000, 0)
+	if err != nil {
+		return nil, fmt.Errorf("tenant %s created, failed to list permissions: %w", tenant.ID, err)
+	}
+	rolePermissionRepo := repositories.NewRolePermissionRepo(pool)
+	var adminRoleID uuid.UUID
+	for _, spec := range []struct {
+		name        string
+		description string
+		grants      func(string) bool
+	}{
+		{"admin", "Administrator role", tenantAdminPermission},
+		{"user", "Regular user role", tenantUserPermission},
+	} {
+		description := spec.description
+		role := &models.Role{ID: uuid.New(), TenantID: tenant.ID, Name: spec.name, Description: &description}
+		if err := roleRepo.Create(ctx, role); err != nil {
+			return nil, fmt.Errorf("tenant %s created, failed to create %s role: %w", tenant.ID, spec.name, err)
+		}
+		for _, permission := range permissions {
+			if !spec.grants(permission.Name) {
+				continue
+			}
+			grant := &models.RolePermission{RoleID: role.ID, PermissionID: permission.ID}
+			if err := rolePermissionRepo.Create(ctx, tenant.ID, grant); err != nil {
+				return nil, fmt.Errorf("tenant %s created, failed to grant %s to %s role: %w", tenant.ID, permission.Name, spec.name, err)
+			}
+		}
+		if spec.name == "admin" {
+			adminRoleID = role.ID
+		}
+	}
+
+	user := &models.User{
+		ID:           uuid.New(),
+		TenantID:     tenant.ID,
+		Email:        email,
+		PasswordHash: string(hash),
+		FirstName:    firstName,
+		LastName:     lastName,
+		Status:       "active",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if err := userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("tenant %s created, failed to create admin user: %w", tenant.ID, err)
+	}
+	if err := repositories.NewUserRoleRepo(pool).Create(ctx, tenant.ID, &models.UserRole{UserID: user.ID, RoleID: adminRoleID}); err != nil {
+		return nil, fmt.Errorf("tenant %s created, failed to assign admin role: %w", tenant.ID, err)
+	}
+	log.Printf("Created admin %s for tenant %s", email, tenant.ID)
+
+	return &tenantCreated{Tenant: tenant, AdminUserID: user.ID, AdminEmail: email}, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type passwordReset struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	Password string    `json:"password,omitempty"`
+}
+
+func newUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage users",
+	}
+
+	var tenant, email string
+	var passwordStdin bool
+	reset := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Set a user's password, lift any login lockout and sign the user out",
+		Long: "Set a user's password, lift any login lockout on the account and revoke\n" +
+			"the user's access and refresh tokens. With --password-stdin the password\n" +
+			"is read from the first line of stdin, so it never shows up in shell\n" +
+			"history or the process list; otherwise one is generated and printed once.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tenantID, err := uuid.Parse(tenant)
+			if err != nil {
+				return fmt.Errorf("invalid --tenant: %w", err)
+			}
+			if email == "" {
+				return errors.New("--email is required")
+			}
+			var password string
+			if passwordStdin {
+				if password, err = readPassword(cmd.InOrStdin()); err != nil {
+					return err
+				}
+			} else if password, err = generatePassword(); err != nil {
+				return fmt.Errorf("failed to generate password: %w", err)
+			}
+
+			ctx := cmd.Context()
+			env, err := openEnvironment(ctx)
+			if err != nil {
+				return err
+			}
+			defer env.Close()
+
+			userRepo := repositories.NewUserRepo(env.pool, env.keyring)
+			user, err := userRepo.GetByEmail(ctx, tenantID, email)
+			if err != nil {
+				return fmt.Errorf("user %s not found in tenant %s: %w", email, tenantID, err)
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				return fmt.Errorf("failed to hash password: %w", err)
+			}
+			updated, err := userRepo.UpdatePassword(ctx, tenantID, user.ID, string(hash))
+			if err != nil {
+				return fmt.Errorf("failed to update password: %w", err)
+			}
+			if !updated {
+				return fmt.Errorf("user %s not found in tenant %s", email, tenantID)
+			}
+
+			auth, err := env.authService(ctx)
+			if err != nil {
+				return err
+			}
+			// Whoever knew the old password may still hold a session
+			if err := auth.RevokeUserTokens(ctx, user.ID); err != nil {
+				return fmt.Errorf("password reset, but failed to revoke the user's tokens: %w", err)
+			}
+			if err := auth.UnlockAccount(ctx, user.Email); err != nil {
+				log.Printf("Password reset, but failed to lift the lockout: %v", err)
+			}
+			log.Printf("Reset password of %s in tenant %s", user.Email, tenantID)
+
+			result := &passwordReset{UserID: user.ID, Email: user.Email}
+			if !passwordStdin {
+				result.Password = password
+			}
+			return printJSON(result)
+		},
+	}
+	reset.Flags().StringVar(&tenant, "tenant", "", "tenant ID")
+	reset.Flags().StringVar(&email, "email", "", "email of the user")
+	reset.Flags().BoolVar(&passwordStdin, "password-stdin", false, "read the new password from stdin instead of generating one")
+
+	cmd.AddCommand(reset)
+	return cmd
+}
+
+// readPassword reads a password from the first line of r
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return password, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	firstNames = []string{
+		"Aarav", "Vivaan", "Aditya", "Arjun", "Sai", "Reyansh", "Krishna", "Ishaan", "Rohan", "Kabir",
+		"Ananya", "Diya", "Saanvi", "Aadhya", "Meera", "Kavya", "Priya", "Lakshmi", "Pooja", "Nisha",
+	}
+	lastNames = []string{
+		"Sharma", "Patel", "Reddy", "Singh", "Kumar", "Naidu", "Gupta", "Iyer", "Joshi", "Desai",
+		"Rao", "Yadav", "Chauhan", "Pillai", "Menon", "Verma", "Bhat", "Kulkarni", "Das", "Mehta",
+	}
+	businessSuffixes = []string{"Traders", "Agro Agencies", "Krishi Kendra", "Fertilizers", "Seeds Corporation", "Agri Inputs"}
+	streetNames      = []string{"Market", "Station", "Mandi", "Gandhi", "Nehru", "Temple", "Canal", "Main"}
+	cityNames        = []string{"Guntur", "Nashik", "Indore", "Ludhiana", "Rajkot", "Hubli", "Karnal", "Coimbatore", "Nagpur", "Bathinda"}
+)
+
+// Anonymizer rewrites PII deterministically: the same input and key always give the
+// same output, so values shared across rows and tables stay consistent.
+type Anonymizer struct {
+	key []byte
+}
+
+// NewAnonymizer creates an anonymizer keyed with a secret salt
+func NewAnonymizer(key string) *Anonymizer {
+	return &Anonymizer{key: []byte(key)}
+}
+
+// digest returns the keyed hash of a value within a namespace
+func (a *Anonymizer) digest(namespace, value string) []byte {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(namespace))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+func (a *Anonymizer) pick(namespace, value string, options []string) string {
+	sum := a.digest(namespace, value)
+	return options[binary.BigEndian.Uint32(sum[:4])%uint32(len(options))]
+}
+
+// FirstName maps a first name onto a realistic replacement
+func (a *Anonymizer) FirstName(value string) string {
+	return a.pick("first_name", strings.ToLower(value), firstNames)
+}
+
+// LastName maps a last name onto a realistic replacement
+func (a *Anonymizer) LastName(value string) string {
+	return a.pick("last_name", strings.ToLower(value), lastNames)
+}
+
+// Email replaces an address with a unique, non-routable one
+func (a *Anonymizer) Email(value string) string {
+	sum := a.digest("email", strings.ToLower(strings.TrimSpace(value)))
+	return fmt.Sprintf("user-%s@anon.invalid", hex.EncodeToString(sum[:6]))
+}
+
+// BusinessName replaces a supplier, distributor or tenant name
+func (a *Anonymizer) BusinessName(value string) string {
+	sum := a.digest("business", strings.ToLower(value))
+	return fmt.Sprintf("%s %s %s",
+		a.pick("business_last", value, lastNames),
+		a.pick("business_suffix", value, businessSuffixes),
+		strings.ToUpper(hex.EncodeToString(sum[:2])))
+}
+
+// Address replaces a free-text address with a synthetic one
+func (a *Anonymizer) Address(value string) string {
+	sum := a.digest("address", value)
+	return fmt.Sprintf("%d %s Road, %s",
+		1+binary.BigEndian.Uint16(sum[:2])%999,
+		a.pick("street", value, streetNames),
+		a.pick("city", value, cityNames))
+}
+
+// Subdomain replaces a tenant subdomain while keeping it unique
+func (a *Anonymizer) Subdomain(value string) string {
+	sum := a.digest("subdomain", value)
+	return "t-" + hex.EncodeToString(sum[:5])
+}
+
+// Mask preserves the shape of identifiers such as phones and license numbers:
+// digits stay digits, letters stay letters of the same case, punctuation is kept.
+func (a *Anonymizer) Mask(namespace, value string) string {
+	return a.maskFrom(namespace, value, 0)
+}
+
+// GSTIN keeps the two-digit state code so regional distributions survive,
+// and masks the PAN and checksum characters
+func (a *Anonymizer) GSTIN(value string) string {
+	if len(value) < 2 {
+		return a.Mask("gstin", value)
+	}
+	return value[:2] + a.maskFrom("gstin", value, 2)[2:]
+}
+
+// Phone keeps a leading country code such as "+91" and masks the subscriber number
+func (a *Anonymizer) Phone(value string) string {
+	if strings.HasPrefix(value, "+91") {
+		return "+91" + a.Mask("phone", value)[3:]
+	}
+	return a.Mask("phone", value)
+}
+
+func (a *Anonymizer) maskFrom(namespace, value string, from int) string {
+	stream := a.digest(namespace, value)
+	out := []rune(value)
+	for i := from; i < len(out); i++ {
+		if i%len(stream) == 0 && i > 0 {
+			stream = a.digest(namespace, string(stream))
+		}
+		b := stream[i%len(stream)]
+		switch r := out[i]; {
+		case unicode.IsDigit(r):
+			out[i] = rune('0' + b%10)
+		case unicode.IsUpper(r):
+			out[i] = rune('A' + b%26)
+		case unicode.IsLower(r):
+			out[i] = rune('a' + b%26)
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymizerIsDeterministic(t *testing.T) {
+	a := NewAnonymizer("salt")
+	b := NewAnonymizer("salt")
+
+	assert.Equal(t, a.Email("Ravi@Example.com"), b.Email("ravi@example.com"))
+	assert.Equal(t, a.BusinessName("Sri Lakshmi Traders"), b.BusinessName("Sri Lakshmi Traders"))
+	assert.NotEqual(t, a.Email("ravi@example.com"), NewAnonymizer("other").Email("ravi@example.com"))
+}
+
+func TestAnonymizerPreservesShape(t *testing.T) {
+	a := NewAnonymizer("salt")
+
+	gstin := a.GSTIN("29ABCDE1234F1Z5")
+	assert.Equal(t, "29", gstin[:2])
+	assert.Regexp(t, `^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][0-9][A-Z][0-9]$`, gstin)
+	assert.NotEqual(t, "29ABCDE1234F1Z5", gstin)
+
+	phone := a.Phone("+91 98480-22338")
+	assert.Len(t, phone, len("+91 98480-22338"))
+	assert.Equal(t, "+91 ", phone[:4])
+	assert.Equal(t, "-", phone[9:10])
+}
@@ -0,0 +1,230 @@
+// Command anonymize rewrites a tenant's PII in place so a production copy can be
+// used for debugging and demos. Run it against the staging copy, never production.
+//
+// Usage:
+//
+//	go run ./cmd/anonymize -tenant <uuid> -key "$ANONYMIZE_KEY" -yes
+//
+// Names, emails, phones, addresses, license numbers and GSTINs are replaced
+// deterministically: IDs, quantities, amounts and dates are untouched, so
+// relationships and the statistical shape of the data are preserved.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// columnRule rewrites one nullable text column
+type columnRule struct {
+	column    string
+	transform func(string) string
+}
+
+// tableRule lists the PII columns of a tenant-scoped table
+type tableRule struct {
+	table   string
+	idField string
+	columns []columnRule
+}
+
+func rules(a *Anonymizer) []tableRule {
+	mask := func(namespace string) func(string) string {
+		return func(v string) string { return a.Mask(namespace, v) }
+	}
+
+	return []tableRule{
+		{table: "tenants", idField: "id", columns: []columnRule{
+			{"name", a.BusinessName},
+			{"subdomain", a.Subdomain},
+			{"license_number", mask("license")},
+		}},
+		{table: "users", columns: []columnRule{
+			{"email", a.Email},
+			{"first_name", a.FirstName},
+			{"last_name", a.LastName},
+		}},
+		{table: "suppliers", columns: []columnRule{
+			{"name", a.BusinessName},
+			{"contact_email", a.Email},
+			{"contact_phone", a.Phone},
+			{"address", a.Address},
+			{"license_number", mask("license")},
+			{"bank_account_number", mask("bank_account")},
+		}},
+		{table: "distributors", columns: []columnRule{
+			{"name", a.BusinessName},
+			{"contact_email", a.Email},
+			{"contact_phone", a.Phone},
+			{"address", a.Address},
+			{"license_number", mask("license")},
+			{"bank_account_number", mask("bank_account")},
+		}},
+		{table: "warehouses", columns: []columnRule{
+			{"address", a.Address},
+			{"license_number", mask("license")},
+		}},
+		{table: "invoices", columns: []columnRule{
+			{"gstin", a.GSTIN},
+		}},
+	}
+}
+
+// scrubStatements clear free text and snapshots that may embed PII verbatim
+var scrubStatements = []string{
+	`UPDATE audit_logs SET old_values = NULL, new_values = NULL WHERE tenant_id = $1`,
+	`UPDATE notifications SET message = 'Message removed during anonymization' WHERE tenant_id = $1`,
+	`UPDATE impersonation_sessions SET reason = 'Removed during anonymization' WHERE tenant_id = $1`,
+	`DELETE FROM tokens WHERE tenant_id = $1`,
+	// Blind indexes of rewritten contacts would still match the real values
+	`UPDATE suppliers SET contact_email_bidx = NULL, contact_phone_bidx = NULL, bank_account_number_bidx = NULL WHERE tenant_id = $1`,
+	`UPDATE distributors SET contact_email_bidx = NULL, contact_phone_bidx = NULL, bank_account_number_bidx = NULL WHERE tenant_id = $1`,
+	`UPDATE users SET email_bidx = NULL WHERE tenant_id = $1`,
+}
+
+func main() {
+	tenantStr := flag.String("tenant", "", "tenant ID to anonymize (required)")
+	key := flag.String("key", os.Getenv("ANONYMIZE_KEY"), "secret salt; reuse it to get identical output across runs (defaults to ANONYMIZE_KEY)")
+	resetPassword := flag.String("reset-password", "", "if set, every user's password is replaced with this value")
+	databaseURL := flag.String("database", os.Getenv("DATABASE_URL"), "Postgres connection string (defaults to DATABASE_URL)")
+	dryRun := flag.Bool("dry-run", false, "rewrite inside a transaction and roll it back")
+	confirmed := flag.Bool("yes", false, "confirm that the target database is a disposable copy")
+	flag.Parse()
+
+	tenantID, err := uuid.Parse(*tenantStr)
+	if err != nil {
+		log.Fatalf("A valid -tenant is required: %v", err)
+	}
+	if *key == "" {
+		log.Fatal("-key or ANONYMIZE_KEY is required")
+	}
+	if *databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable or -database flag is required")
+	}
+	if !*confirmed && !*dryRun {
+		log.Fatal("Refusing to rewrite data without -yes; use -dry-run to preview")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, *databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		log.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	anonymizer := NewAnonymizer(*key)
+	for _, rule := range rules(anonymizer) {
+		count, err := anonymizeTable(ctx, tx, tenantID, rule)
+		if err != nil {
+			log.Fatalf("Failed to anonymize %s: %v", rule.table, err)
+		}
+		log.Printf("Anonymized %d rows in %s", count, rule.table)
+	}
+
+	for _, stmt := range scrubStatements {
+		tag, err := tx.Exec(ctx, stmt, tenantID)
+		if err != nil {
+			log.Fatalf("Failed to scrub (%s): %v", stmt, err)
+		}
+		log.Printf("%s: %d rows", stmt, tag.RowsAffected())
+	}
+
+	if *resetPassword != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*resetPassword), bcrypt.DefaultCost)
+		if err != nil {
+			log.Fatalf("Failed to hash password: %v", err)
+		}
+		if _, err := tx.Exec(ctx, `UPDATE users SET password_hash = $1 WHERE tenant_id = $2`, string(hash), tenantID); err != nil {
+			log.Fatalf("Failed to reset passwords: %v", err)
+		}
+	}
+
+	if *dryRun {
+		log.Printf("Dry run complete for tenant %s; rolling back", tenantID)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Fatalf("Failed to commit: %v", err)
+	}
+	log.Printf("Tenant %s anonymized", tenantID)
+}
+
+// anonymizeTable rewrites the rule's columns for every row of the tenant
+func anonymizeTable(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, rule tableRule) (int, error) {
+	scope := "tenant_id"
+	if rule.idField != "" {
+		scope = rule.idField
+	}
+
+	selectCols := "id"
+	for _, col := range rule.columns {
+		selectCols += ", " + col.column
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`, selectCols, rule.table, scope)
+
+	rows, err := tx.Query(ctx, query, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	type rewrite struct {
+		id     uuid.UUID
+		values []*string
+	}
+	var rewrites []rewrite
+	for rows.Next() {
+		values := make([]*string, len(rule.columns))
+		dest := []interface{}{new(uuid.UUID)}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		rewrites = append(rewrites, rewrite{id: *dest[0].(*uuid.UUID), values: values})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	setClause := ""
+	for i, col := range rule.columns {
+		if i > 0 {
+			setClause += ", "
+		}
+		setClause += fmt.Sprintf("%s = $%d", col.column, i+2)
+	}
+	update := fmt.Sprintf(`UPDATE %s SET %s WHERE id = $1`, rule.table, setClause)
+
+	for _, rw := range rewrites {
+		args := []interface{}{rw.id}
+		for i, col := range rule.columns {
+			if rw.values[i] == nil || *rw.values[i] == "" {
+				args = append(args, rw.values[i])
+				continue
+			}
+			replaced := col.transform(*rw.values[i])
+			args = append(args, &replaced)
+		}
+		if _, err := tx.Exec(ctx, update, args...); err != nil {
+			return 0, err
+		}
+	}
+	return len(rewrites), nil
+}
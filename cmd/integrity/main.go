@@ -0,0 +1,104 @@
+// Command integrity scans tenants for data integrity problems and prints the
+// issues report as JSON. It reads the same environment as the server.
+//
+// Usage:
+//
+//	go run ./cmd/integrity -tenant <uuid> [-fix]
+//	go run ./cmd/integrity -all
+//
+// Orders referencing missing products, invoices whose GST components do not
+// add up to the total, negative stock and image objects out of step with the
+// product images are reported. With -fix the safe categories, orphaned image
+// objects and images without an object, are fixed; the rest are only
+// reported. Cached catalogue responses are not purged, so removed images may
+// show until they expire. The exit status is 1 when unfixed issues remain.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"agromart2/internal/app"
+	"agromart2/internal/jobs"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+	"agromart2/pkg/database"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	tenantStr := flag.String("tenant", "", "tenant ID to check")
+	all := flag.Bool("all", false, "check every active tenant")
+	fix := flag.Bool("fix", false, "fix the safe categories of issues")
+	flag.Parse()
+
+	if (*tenantStr == "") == !*all {
+		log.Fatal("Give either -tenant or -all")
+	}
+
+	cfg, err := app.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.DatabaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+
+	ctx := context.Background()
+	pool, err := database.Open(ctx, cfg.DatabaseURL, cfg.DBPool)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	minioSvc, err := services.NewMinioService(cfg.MinioEndpoint, cfg.MinioAccessKey, cfg.MinioSecretKey, cfg.MinioUseSSL)
+	if err != nil {
+		log.Fatalf("Failed to connect to object storage: %v", err)
+	}
+	checker := jobs.NewIntegrityCheckService(repositories.NewIntegrityRepo(pool), minioSvc, nil)
+
+	var tenantIDs []uuid.UUID
+	if *all {
+		tenants, err := repositories.NewTenantRepo(pool).List(ctx, 10000, 0)
+		if err != nil {
+			log.Fatalf("Failed to list tenants: %v", err)
+		}
+		for _, tenant := range tenants {
+			if tenant.Status == "active" {
+				tenantIDs = append(tenantIDs, tenant.ID)
+			}
+		}
+	} else {
+		tenantID, err := uuid.Parse(*tenantStr)
+		if err != nil {
+			log.Fatalf("Invalid -tenant: %v", err)
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+
+	reports := make([]*models.IntegrityReport, 0, len(tenantIDs))
+	unfixed := 0
+	for _, tenantID := range tenantIDs {
+		report, err := checker.Check(ctx, tenantID, *fix)
+		if err != nil {
+			log.Fatalf("Failed to check tenant %s: %v", tenantID, err)
+		}
+		log.Printf("Tenant %s: %d issues, %d fixed", tenantID, len(report.Issues), report.Fixed)
+		unfixed += len(report.Issues) - report.Fixed
+		reports = append(reports, report)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(reports); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	if unfixed > 0 {
+		os.Exit(1)
+	}
+}
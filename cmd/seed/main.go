@@ -0,0 +1,413 @@
+// Command seed fills a tenant with reproducible synthetic data for load and
+// performance testing of list, search and analytics endpoints.
+//
+// Usage:
+//
+//	go run ./cmd/seed -tenant <uuid> -products 2000 -orders 20000 -seed 42
+//
+// The same -seed always produces the same catalog, order history and invoice mix,
+// so timings can be compared across builds.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type seedConfig struct {
+	tenantID     uuid.UUID
+	products     int
+	warehouses   int
+	suppliers    int
+	distributors int
+	orders       int
+	seed         int64
+}
+
+var (
+	productNames = []string{
+		"Urea", "DAP", "MOP", "NPK 10-26-26", "NPK 19-19-19", "Zinc Sulphate", "Gypsum",
+		"Chlorpyrifos", "Imidacloprid", "Mancozeb", "Glyphosate", "Neem Oil",
+		"Paddy Seed", "Wheat Seed", "Cotton Seed", "Maize Seed", "Tomato Seed", "Chilli Seed",
+		"Drip Lateral", "Sprayer Pump", "Vermicompost", "Humic Acid", "Seaweed Extract",
+	}
+	productBrands  = []string{"IFFCO", "Coromandel", "UPL", "Bayer", "Syngenta", "Mahyco", "Nuziveedu", "Jain", "Kribhco"}
+	packSizes      = []string{"500g", "1kg", "5kg", "25kg", "50kg", "250ml", "1L", "5L"}
+	unitsOfMeasure = []string{"kg", "bag", "litre", "packet", "unit"}
+	cities         = []string{"Guntur", "Nashik", "Indore", "Ludhiana", "Rajkot", "Hubli", "Karnal", "Coimbatore", "Nagpur", "Bathinda"}
+	gstRates       = []float64{0, 5, 12, 18}
+)
+
+func main() {
+	var tenantStr string
+	cfg := seedConfig{}
+	flag.StringVar(&tenantStr, "tenant", "", "tenant ID to seed (required, must already exist)")
+	flag.IntVar(&cfg.products, "products", 500, "number of products")
+	flag.IntVar(&cfg.warehouses, "warehouses", 5, "number of warehouses")
+	flag.IntVar(&cfg.suppliers, "suppliers", 20, "number of suppliers")
+	flag.IntVar(&cfg.distributors, "distributors", 40, "number of distributors")
+	flag.IntVar(&cfg.orders, "orders", 5000, "number of orders spread across the last year")
+	flag.Int64Var(&cfg.seed, "seed", 42, "random seed; the same seed produces the same data")
+	databaseURL := flag.String("database", os.Getenv("DATABASE_URL"), "Postgres connection string (defaults to DATABASE_URL)")
+	flag.Parse()
+
+	tenantID, err := uuid.Parse(tenantStr)
+	if err != nil {
+		log.Fatalf("A valid -tenant is required: %v", err)
+	}
+	cfg.tenantID = tenantID
+
+	if cfg.products < 1 || cfg.warehouses < 1 || cfg.suppliers < 1 || cfg.distributors < 1 || cfg.orders < 0 {
+		log.Fatal("-products, -warehouses, -suppliers and -distributors must be at least 1")
+	}
+
+	if *databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable or -database flag is required")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, *databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	start := time.Now()
+	if err := newGenerator(pool, cfg).run(ctx); err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+	log.Printf("Seeded tenant %s in %v", tenantID, time.Since(start).Round(time.Millisecond))
+}
+
+type generator struct {
+	cfg seedConfig
+	rng *rand.Rand
+	now time.Time
+	ids func() uuid.UUID
+	tag string
+
+	categoryRepo    repositories.CategoryRepository
+	productRepo     repositories.ProductRepository
+	warehouseRepo   repositories.WarehouseRepository
+	supplierRepo    repositories.SupplierRepository
+	distributorRepo repositories.DistributorRepository
+	inventoryRepo   repositories.InventoryRepository
+	orderRepo       repositories.OrderRepository
+	invoiceRepo     repositories.InvoiceRepository
+
+	categories   []uuid.UUID
+	products     []*models.Product
+	warehouses   []uuid.UUID
+	suppliers    []uuid.UUID
+	distributors []uuid.UUID
+}
+
+func newGenerator(pool *pgxpool.Pool, cfg seedConfig) *generator {
+	rng := rand.New(rand.NewSource(cfg.seed))
+	return &generator{
+		cfg: cfg,
+		rng: rng,
+		// Anchor dates to midnight so reruns on the same day match exactly
+		now: time.Now().UTC().Truncate(24 * time.Hour),
+		ids: func() uuid.UUID {
+			var b [16]byte
+			rng.Read(b[:])
+			id, _ := uuid.FromBytes(b[:])
+			id[6] = (id[6] & 0x0f) | 0x40 // version 4
+			id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+			return id
+		},
+		tag: fmt.Sprintf("LT%d", cfg.seed),
+
+		categoryRepo:    repositories.NewCategoryRepo(pool),
+		productRepo:     repositories.NewProductRepo(pool),
+		warehouseRepo:   repositories.NewWarehouseRepository(pool),
+		supplierRepo:    repositories.NewSupplierRepository(pool, nil), // Plaintext; the PII key rotation job encrypts it
+		distributorRepo: repositories.NewDistributorRepository(pool, nil),
+		inventoryRepo:   repositories.NewInventoryRepo(pool),
+		orderRepo:       repositories.NewOrderRepo(pool),
+		invoiceRepo:     repositories.NewInvoiceRepo(pool),
+	}
+}
+
+func (g *generator) run(ctx context.Context) error {
+	steps := []struct {
+		name string
+		fn   func(context.Context) error
+	}{
+		{"categories", g.seedCategories},
+		{"warehouses", g.seedWarehouses},
+		{"suppliers", g.seedSuppliers},
+		{"distributors", g.seedDistributors},
+		{"products", g.seedProducts},
+		{"inventory", g.seedInventory},
+		{"orders and invoices", g.seedOrders},
+	}
+	for _, step := range steps {
+		stepStart := time.Now()
+		if err := step.fn(ctx); err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+		log.Printf("Seeded %s in %v", step.name, time.Since(stepStart).Round(time.Millisecond))
+	}
+	return nil
+}
+
+func (g *generator) seedCategories(ctx context.Context) error {
+	for _, name := range []string{"Fertilizers", "Pesticides", "Seeds", "Irrigation", "Organic Inputs"} {
+		category := &models.Category{
+			ID:          g.ids(),
+			TenantID:    g.cfg.tenantID,
+			Name:        fmt.Sprintf("%s (%s)", name, g.tag),
+			Description: "Load test category",
+			Path:        name,
+		}
+		if err := g.categoryRepo.Create(ctx, category); err != nil {
+			return err
+		}
+		g.categories = append(g.categories, category.ID)
+	}
+	return nil
+}
+
+func (g *generator) seedWarehouses(ctx context.Context) error {
+	for i := 0; i < g.cfg.warehouses; i++ {
+		city := g.pick(cities)
+		capacity := 5000 + g.rng.Intn(45000)
+		address := city + ", India"
+		warehouse := &models.Warehouse{
+			ID:       g.ids(),
+			TenantID: g.cfg.tenantID,
+			Name:     fmt.Sprintf("%s Depot %d (%s)", city, i+1, g.tag),
+			Address:  &address,
+			Capacity: &capacity,
+		}
+		if err := g.warehouseRepo.Create(ctx, warehouse); err != nil {
+			return err
+		}
+		g.warehouses = append(g.warehouses, warehouse.ID)
+	}
+	return nil
+}
+
+func (g *generator) seedSuppliers(ctx context.Context) error {
+	for i := 0; i < g.cfg.suppliers; i++ {
+		email := fmt.Sprintf("supplier%d@%s.loadtest", i+1, g.tag)
+		supplier := &models.Supplier{
+			ID:           g.ids(),
+			TenantID:     g.cfg.tenantID,
+			Name:         fmt.Sprintf("%s Agro Supplies %d", g.pick(productBrands), i+1),
+			ContactEmail: &email,
+		}
+		if err := g.supplierRepo.Create(ctx, supplier); err != nil {
+			return err
+		}
+		g.suppliers = append(g.suppliers, supplier.ID)
+	}
+	return nil
+}
+
+func (g *generator) seedDistributors(ctx context.Context) error {
+	for i := 0; i < g.cfg.distributors; i++ {
+		email := fmt.Sprintf("dealer%d@%s.loadtest", i+1, g.tag)
+		address := g.pick(cities)
+		distributor := &models.Distributor{
+			ID:           g.ids(),
+			TenantID:     g.cfg.tenantID,
+			Name:         fmt.Sprintf("%s Krishi Kendra %d", address, i+1),
+			ContactEmail: &email,
+			Address:      &address,
+		}
+		if err := g.distributorRepo.Create(ctx, distributor); err != nil {
+			return err
+		}
+		g.distributors = append(g.distributors, distributor.ID)
+	}
+	return nil
+}
+
+func (g *generator) seedProducts(ctx context.Context) error {
+	for i := 0; i < g.cfg.products; i++ {
+		categoryID := g.categories[g.rng.Intn(len(g.categories))]
+		batch := fmt.Sprintf("%s-B%05d", g.tag, i+1)
+		barcode := fmt.Sprintf("890%010d", g.cfg.seed*1000000+int64(i))
+		unit := g.pick(unitsOfMeasure)
+		expiry := g.now.AddDate(0, 3+g.rng.Intn(33), 0)
+		description := "Synthetic product for load testing"
+		product := &models.Product{
+			ID:            g.ids(),
+			TenantID:      g.cfg.tenantID,
+			CategoryID:    &categoryID,
+			Name:          fmt.Sprintf("%s %s %s", g.pick(productBrands), g.pick(productNames), g.pick(packSizes)),
+			BatchNumber:   &batch,
+			ExpiryDate:    &expiry,
+			Quantity:      g.rng.Intn(2000),
+			UnitPrice:     roundPrice(50 + g.rng.Float64()*4950),
+			Barcode:       &barcode,
+			UnitOfMeasure: &unit,
+			Description:   &description,
+		}
+		if err := g.productRepo.Create(ctx, product); err != nil {
+			return err
+		}
+		g.products = append(g.products, product)
+	}
+	return nil
+}
+
+func (g *generator) seedInventory(ctx context.Context) error {
+	for _, product := range g.products {
+		// Stock each product in one to three warehouses, leaving some near zero for low-stock alerts
+		for _, idx := range g.rng.Perm(len(g.warehouses))[:1+g.rng.Intn(min(3, len(g.warehouses)))] {
+			quantity := g.rng.Intn(500)
+			if g.rng.Float64() < 0.1 {
+				quantity = g.rng.Intn(10)
+			}
+			inventory := &models.Inventory{
+				ID:          g.ids(),
+				TenantID:    g.cfg.tenantID,
+				WarehouseID: g.warehouses[idx],
+				ProductID:   product.ID,
+				Quantity:    quantity,
+			}
+			if err := g.inventoryRepo.Create(ctx, inventory); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) seedOrders(ctx context.Context) error {
+	invoiceCount := 0
+	for i := 0; i < g.cfg.orders; i++ {
+		product := g.products[g.rng.Intn(len(g.products))]
+		orderDate := g.orderDate()
+
+		order := &models.Order{
+			ID:          g.ids(),
+			TenantID:    g.cfg.tenantID,
+			ProductID:   product.ID,
+			WarehouseID: g.warehouses[g.rng.Intn(len(g.warehouses))],
+			Quantity:    1 + g.rng.Intn(100),
+			OrderDate:   orderDate,
+			Status:      g.orderStatus(orderDate),
+		}
+		if g.rng.Float64() < 0.3 {
+			supplierID := g.suppliers[g.rng.Intn(len(g.suppliers))]
+			order.OrderType = "purchase"
+			order.SupplierID = &supplierID
+			order.UnitPrice = roundPrice(product.UnitPrice * (0.7 + g.rng.Float64()*0.15))
+		} else {
+			distributorID := g.distributors[g.rng.Intn(len(g.distributors))]
+			order.OrderType = "sales"
+			order.DistributorID = &distributorID
+			order.UnitPrice = product.UnitPrice
+		}
+		expected := orderDate.AddDate(0, 0, 2+g.rng.Intn(10))
+		order.ExpectedDelivery = &expected
+
+		if err := g.orderRepo.Create(ctx, order); err != nil {
+			return err
+		}
+
+		if order.OrderType == "sales" && order.Status == "delivered" {
+			invoiceCount++
+			if err := g.invoiceRepo.Create(ctx, g.invoiceFor(order, invoiceCount)); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("Created %d invoices", invoiceCount)
+	return nil
+}
+
+func (g *generator) invoiceFor(order *models.Order, seq int) *models.Invoice {
+	rate := gstRates[g.rng.Intn(len(gstRates))]
+	taxable := roundPrice(float64(order.Quantity) * order.UnitPrice)
+	halfTax := roundPrice(taxable * rate / 200)
+	issued := order.ExpectedDelivery.AddDate(0, 0, g.rng.Intn(3))
+	due := issued.AddDate(0, 0, 30)
+
+	invoice := &models.Invoice{
+		ID:            g.ids(),
+		TenantID:      g.cfg.tenantID,
+		OrderID:       order.ID,
+		InvoiceNumber: fmt.Sprintf("%s-%s-%06d", g.tag, g.cfg.tenantID.String()[:8], seq),
+		TaxableAmount: &taxable,
+		GSTRate:       &rate,
+		CGST:          &halfTax,
+		SGST:          &halfTax,
+		TotalAmount:   roundPrice(taxable + 2*halfTax),
+		IssuedDate:    issued,
+		DueDate:       due,
+	}
+
+	// Mixed statuses: most older invoices are paid, recent ones unpaid, some overdue or cancelled
+	switch roll := g.rng.Float64(); {
+	case roll < 0.05:
+		invoice.Status = "cancelled"
+	case due.Before(g.now) && roll < 0.8:
+		paid := issued.AddDate(0, 0, g.rng.Intn(45))
+		invoice.Status = "paid"
+		invoice.PaidDate = &paid
+	case due.Before(g.now):
+		invoice.Status = "overdue"
+	default:
+		invoice.Status = "unpaid"
+	}
+	return invoice
+}
+
+// orderDate spreads orders over the last year with a kharif/rabi seasonal bias
+func (g *generator) orderDate() time.Time {
+	for {
+		date := g.now.AddDate(0, 0, -g.rng.Intn(365)).Add(time.Duration(g.rng.Intn(24*60)) * time.Minute)
+		weight := 0.5
+		switch date.Month() {
+		case time.June, time.July, time.October, time.November:
+			weight = 1
+		}
+		if g.rng.Float64() < weight {
+			return date
+		}
+	}
+}
+
+func (g *generator) orderStatus(orderDate time.Time) string {
+	age := g.now.Sub(orderDate)
+	roll := g.rng.Float64()
+	switch {
+	case roll < 0.05:
+		return "cancelled"
+	case age > 14*24*time.Hour:
+		return "delivered"
+	case roll < 0.3:
+		return "pending"
+	case roll < 0.55:
+		return "approved"
+	case roll < 0.75:
+		return "processing"
+	default:
+		return "shipped"
+	}
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rng.Intn(len(values))]
+}
+
+func roundPrice(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
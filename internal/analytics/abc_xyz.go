@@ -0,0 +1,230 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultClassificationMonths is the sales window used for ABC/XYZ classification
+	DefaultClassificationMonths = 12
+
+	// Cumulative revenue share (percent) covered by A and by A+B products
+	abcClassAShare = 80.0
+	abcClassBShare = 95.0
+
+	// Coefficient of variation of monthly demand at or below which a product is X or Y
+	xyzClassXMaxCV = 0.5
+	xyzClassYMaxCV = 1.0
+)
+
+// replenishmentPolicies are the default reorder policies per ABC/XYZ class.
+// High-value steady sellers (A-X) are reviewed most often with a small buffer;
+// erratic low-value items (C-Z) are reviewed monthly and ordered against demand.
+var replenishmentPolicies = map[string]models.ReplenishmentPolicy{
+	"AX": {ReviewIntervalDays: 3, ServiceLevel: 0.98, SafetyStockDays: 5},
+	"AY": {ReviewIntervalDays: 7, ServiceLevel: 0.98, SafetyStockDays: 10},
+	"AZ": {ReviewIntervalDays: 7, ServiceLevel: 0.95, SafetyStockDays: 14},
+	"BX": {ReviewIntervalDays: 7, ServiceLevel: 0.95, SafetyStockDays: 5},
+	"BY": {ReviewIntervalDays: 14, ServiceLevel: 0.95, SafetyStockDays: 10},
+	"BZ": {ReviewIntervalDays: 14, ServiceLevel: 0.90, SafetyStockDays: 14},
+	"CX": {ReviewIntervalDays: 30, ServiceLevel: 0.90, SafetyStockDays: 3},
+	"CY": {ReviewIntervalDays: 30, ServiceLevel: 0.90, SafetyStockDays: 7},
+	"CZ": {ReviewIntervalDays: 30, ServiceLevel: 0.85, SafetyStockDays: 0},
+}
+
+// ReplenishmentPolicyFor returns the default reorder policy for an ABC/XYZ class
+func ReplenishmentPolicyFor(abcClass, xyzClass string) (models.ReplenishmentPolicy, bool) {
+	policy, ok := replenishmentPolicies[abcClass+xyzClass]
+	return policy, ok
+}
+
+// ProductClassificationService classifies products by revenue contribution
+// (ABC) and demand variability (XYZ) from sales orders
+type ProductClassificationService struct {
+	classificationRepo repositories.ProductClassificationRepository
+	productRepo        repositories.ProductRepository
+}
+
+func NewProductClassificationService(classificationRepo repositories.ProductClassificationRepository, productRepo repositories.ProductRepository) *ProductClassificationService {
+	return &ProductClassificationService{
+		classificationRepo: classificationRepo,
+		productRepo:        productRepo,
+	}
+}
+
+// Classify computes the classification over the last `months` full months
+// plus the current one; when save is true the classes are stored on the products
+func (s *ProductClassificationService) Classify(ctx context.Context, tenantID uuid.UUID, months int, save bool) (*models.ProductClassificationReport, error) {
+	if months <= 0 {
+		months = DefaultClassificationMonths
+	}
+
+	now := time.Now()
+	since := classificationWindowStart(now, months)
+	history, err := s.classificationRepo.SalesHistory(ctx, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := classifyProducts(history, since, months)
+	report.ClassifiedAt = now
+	if save {
+		if err := s.classificationRepo.SaveClassifications(ctx, tenantID, report.Products, now); err != nil {
+			return nil, err
+		}
+		report.Saved = true
+	}
+	return report, nil
+}
+
+// GetProductPolicy returns a product's stored class and its default
+// replenishment policy; Policy is nil until the product has been classified
+func (s *ProductClassificationService) GetProductPolicy(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductReplenishmentPolicy, error) {
+	product, err := s.productRepo.GetByID(ctx, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ProductReplenishmentPolicy{
+		ProductID:    product.ID,
+		ABCClass:     product.ABCClass,
+		XYZClass:     product.XYZClass,
+		ClassifiedAt: product.ClassifiedAt,
+	}
+	if product.ABCClass != nil && product.XYZClass != nil {
+		if policy, ok := ReplenishmentPolicyFor(*product.ABCClass, *product.XYZClass); ok {
+			result.Policy = &policy
+		}
+	}
+	return result, nil
+}
+
+// classificationWindowStart is the first day of the month `months-1` months
+// before now, so the window holds exactly `months` calendar months
+func classificationWindowStart(now time.Time, months int) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -(months - 1), 0)
+}
+
+func classifyProducts(history []*models.ProductSalesHistory, since time.Time, months int) *models.ProductClassificationReport {
+	report := &models.ProductClassificationReport{
+		Months:   months,
+		Matrix:   map[string]int{},
+		Products: []*models.ProductClassification{},
+	}
+	for _, abc := range []string{models.ABCClassA, models.ABCClassB, models.ABCClassC} {
+		for _, xyz := range []string{models.XYZClassX, models.XYZClassY, models.XYZClassZ} {
+			report.Matrix[abc+xyz] = 0
+		}
+	}
+
+	for _, h := range history {
+		report.TotalRevenue += h.Revenue
+	}
+
+	sorted := make([]*models.ProductSalesHistory, len(history))
+	copy(sorted, history)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Revenue > sorted[j].Revenue
+	})
+
+	cumulative := 0.0
+	for _, h := range sorted {
+		series := monthlySeries(h.Monthly, since, months)
+		mean, cv := demandVariation(series)
+
+		c := &models.ProductClassification{
+			ProductID:           h.ProductID,
+			ProductName:         h.ProductName,
+			Revenue:             roundMoney(h.Revenue),
+			RevenueShare:        share(h.Revenue, report.TotalRevenue),
+			AverageMonthlyUnits: math.Round(mean*100) / 100,
+			ABCClass:            abcClass(h.Revenue, cumulative, report.TotalRevenue),
+			XYZClass:            xyzClass(cv),
+		}
+		cumulative += h.Revenue
+		c.CumulativeShare = share(cumulative, report.TotalRevenue)
+		if cv != nil {
+			rounded := math.Round(*cv*1000) / 1000
+			c.DemandCV = &rounded
+		}
+		c.Policy, _ = ReplenishmentPolicyFor(c.ABCClass, c.XYZClass)
+
+		report.Matrix[c.ABCClass+c.XYZClass]++
+		report.Products = append(report.Products, c)
+	}
+	report.TotalRevenue = roundMoney(report.TotalRevenue)
+	return report
+}
+
+// abcClass ranks a product by the revenue share of the products ahead of it,
+// so the top seller is always A. Products without revenue are C.
+func abcClass(revenue, cumulativeBefore, total float64) string {
+	if revenue <= 0 || total <= 0 {
+		return models.ABCClassC
+	}
+	before := cumulativeBefore / total * 100
+	switch {
+	case before < abcClassAShare:
+		return models.ABCClassA
+	case before < abcClassBShare:
+		return models.ABCClassB
+	default:
+		return models.ABCClassC
+	}
+}
+
+// xyzClass ranks demand steadiness by coefficient of variation; no demand is Z
+func xyzClass(cv *float64) string {
+	switch {
+	case cv == nil:
+		return models.XYZClassZ
+	case *cv <= xyzClassXMaxCV:
+		return models.XYZClassX
+	case *cv <= xyzClassYMaxCV:
+		return models.XYZClassY
+	default:
+		return models.XYZClassZ
+	}
+}
+
+// monthlySeries lays monthly demand out over the window, months without sales as zero
+func monthlySeries(monthly []*models.MonthlyDemand, since time.Time, months int) []float64 {
+	series := make([]float64, months)
+	for _, m := range monthly {
+		idx := (m.Year-since.Year())*12 + m.Month - int(since.Month())
+		if idx >= 0 && idx < months {
+			series[idx] += float64(m.Units)
+		}
+	}
+	return series
+}
+
+// demandVariation returns the mean and the coefficient of variation
+// (population standard deviation over mean); the CV is nil when there is no demand
+func demandVariation(series []float64) (float64, *float64) {
+	if len(series) == 0 {
+		return 0, nil
+	}
+	sum := 0.0
+	for _, v := range series {
+		sum += v
+	}
+	mean := sum / float64(len(series))
+	if mean <= 0 {
+		return 0, nil
+	}
+	variance := 0.0
+	for _, v := range series {
+		variance += (v - mean) * (v - mean)
+	}
+	cv := math.Sqrt(variance/float64(len(series))) / mean
+	return mean, &cv
+}
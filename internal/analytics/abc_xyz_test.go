@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassificationWindowStart(t *testing.T) {
+	now := time.Date(2025, 3, 17, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), classificationWindowStart(now, 12))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), classificationWindowStart(now, 1))
+}
+
+func TestMonthlySeriesFillsGaps(t *testing.T) {
+	since := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	monthly := []*models.MonthlyDemand{
+		{Year: 2024, Month: 11, Units: 5},
+		{Year: 2025, Month: 1, Units: 7},
+		{Year: 2024, Month: 10, Units: 99},
+	}
+
+	assert.Equal(t, []float64{5, 0, 7, 0}, monthlySeries(monthly, since, 4))
+}
+
+func TestXYZClassByVariation(t *testing.T) {
+	_, cv := demandVariation([]float64{10, 10, 10, 10})
+	assert.Equal(t, models.XYZClassX, xyzClass(cv))
+
+	_, cv = demandVariation([]float64{20, 0, 20, 0})
+	assert.Equal(t, models.XYZClassY, xyzClass(cv))
+
+	_, cv = demandVariation([]float64{40, 0, 0, 0})
+	assert.Equal(t, models.XYZClassZ, xyzClass(cv))
+
+	_, cv = demandVariation([]float64{0, 0, 0})
+	assert.Nil(t, cv)
+	assert.Equal(t, models.XYZClassZ, xyzClass(cv))
+}
+
+func TestClassifyProductsABC(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	steady := []*models.MonthlyDemand{{Year: 2025, Month: 1, Units: 10}, {Year: 2025, Month: 2, Units: 10}}
+	history := []*models.ProductSalesHistory{
+		{ProductID: uuid.New(), ProductName: "Seed", Revenue: 150},
+		{ProductID: uuid.New(), ProductName: "Urea", Revenue: 700, Monthly: steady},
+		{ProductID: uuid.New(), ProductName: "DAP", Revenue: 150},
+		{ProductID: uuid.New(), ProductName: "Idle"},
+	}
+
+	report := classifyProducts(history, since, 2)
+
+	assert.Equal(t, 1000.0, report.TotalRevenue)
+	if assert.Len(t, report.Products, 4) {
+		assert.Equal(t, "Urea", report.Products[0].ProductName)
+		assert.Equal(t, models.ABCClassA, report.Products[0].ABCClass)
+		assert.Equal(t, models.XYZClassX, report.Products[0].XYZClass)
+		assert.Equal(t, 3, report.Products[0].Policy.ReviewIntervalDays)
+		assert.Equal(t, 70.0, report.Products[0].RevenueShare)
+
+		// 70% ahead of it: still A; 85% ahead: B
+		assert.Equal(t, models.ABCClassA, report.Products[1].ABCClass)
+		assert.Equal(t, models.ABCClassB, report.Products[2].ABCClass)
+
+		assert.Equal(t, "Idle", report.Products[3].ProductName)
+		assert.Equal(t, models.ABCClassC, report.Products[3].ABCClass)
+		assert.Equal(t, models.XYZClassZ, report.Products[3].XYZClass)
+	}
+	assert.Equal(t, 1, report.Matrix["AX"])
+	assert.Equal(t, 1, report.Matrix["CZ"])
+}
@@ -0,0 +1,266 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// CashFlowWeeks is how many weeks the cash flow projection covers
+const CashFlowWeeks = 13
+
+const (
+	defaultOverdueCollectionRate = 0.5
+	defaultSupplierPaymentDays   = 30
+	defaultExpenseMonths         = 3
+	// paymentHistoryDays is how far back paid invoices set each customer's
+	// typical delay
+	paymentHistoryDays = 365
+)
+
+// CashFlowProjectionService projects weekly inflows from open invoices and
+// outflows to suppliers and recurring expenses over the next 13 weeks
+type CashFlowProjectionService struct {
+	repo            repositories.CashFlowRepository
+	receivablesRepo repositories.ReceivablesRepository
+}
+
+func NewCashFlowProjectionService(repo repositories.CashFlowRepository, receivablesRepo repositories.ReceivablesRepository) *CashFlowProjectionService {
+	return &CashFlowProjectionService{repo: repo, receivablesRepo: receivablesRepo}
+}
+
+// DefaultCashFlowAssumptions is what a run uses when nothing is overridden
+func DefaultCashFlowAssumptions() models.CashFlowAssumptions {
+	return models.CashFlowAssumptions{
+		UsePaymentHistory:     true,
+		OverdueCollectionRate: defaultOverdueCollectionRate,
+		SupplierPaymentDays:   defaultSupplierPaymentDays,
+		IncludeExpenses:       true,
+		ExpenseMonths:         defaultExpenseMonths,
+	}
+}
+
+// GetProjection projects cash flow from today under the given assumptions
+func (s *CashFlowProjectionService) GetProjection(ctx context.Context, tenantID uuid.UUID, assumptions models.CashFlowAssumptions) (*models.CashFlowProjection, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	invoices, err := s.receivablesRepo.OpenInvoices(ctx, tenantID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var delays map[uuid.UUID]float64
+	var overallDelay float64
+	if assumptions.UsePaymentHistory {
+		delays, overallDelay, err = s.repo.CustomerPaymentDelays(ctx, tenantID, today.AddDate(0, 0, -paymentHistoryDays))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	payables, err := s.repo.OpenPurchaseOrders(ctx, tenantID, today.AddDate(0, 0, -assumptions.SupplierPaymentDays))
+	if err != nil {
+		return nil, err
+	}
+
+	var recurring []*models.CashFlowRecurringExpense
+	if assumptions.IncludeExpenses {
+		thisMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		months, err := s.repo.MonthlyExpenses(ctx, tenantID, thisMonth.AddDate(0, -assumptions.ExpenseMonths, 0), thisMonth)
+		if err != nil {
+			return nil, err
+		}
+		recurring = recurringExpenses(months, assumptions.ExpenseMonths, assumptions.ExpenseGrowthPercent)
+	}
+
+	projection := projectCashFlow(invoices, delays, overallDelay, payables, recurring, assumptions, today)
+	projection.AsOf = now
+	return projection, nil
+}
+
+// cashFlowWeekIndex places a date in the projection: -1 before the first
+// week starts, CashFlowWeeks or more past the last week
+func cashFlowWeekIndex(start, date time.Time) int {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(start) {
+		return -1
+	}
+	return int(day.Sub(start).Hours()/24) / 7
+}
+
+// cashFlowStart is the Monday of the week containing today
+func cashFlowStart(today time.Time) time.Time {
+	offset := (int(today.Weekday()) + 6) % 7
+	return today.AddDate(0, 0, -offset)
+}
+
+// expectedPaymentDate is when an invoice is expected to be paid: its due date
+// moved by the customer's usual delay (or the tenant's when the customer has
+// no history) and the run's extra collection delay
+func expectedPaymentDate(invoice *models.ReceivableInvoice, delays map[uuid.UUID]float64, overallDelay float64, extraDays int) time.Time {
+	delay, ok := delays[invoice.DistributorID]
+	if !ok {
+		delay = overallDelay
+	}
+	return invoice.DueDate.AddDate(0, 0, int(math.Round(delay))+extraDays)
+}
+
+// supplierPaymentDate is when a purchase order is expected to be paid: the
+// payment terms after it was received, or after it is expected to arrive.
+// ok is false for received orders whose payment date has passed; they are
+// assumed to be paid already
+func supplierPaymentDate(payable *models.CashFlowPayable, paymentDays int, today time.Time) (time.Time, bool) {
+	if payable.ReceivedAt != nil {
+		due := payable.ReceivedAt.AddDate(0, 0, paymentDays)
+		return due, !due.Before(today)
+	}
+	arrival := payable.ExpectedDelivery
+	if arrival.Before(today) {
+		arrival = today
+	}
+	return arrival.AddDate(0, 0, paymentDays), true
+}
+
+// recurringExpenses treats categories seen in at least two of the last months
+// (or in the only month looked at) as recurring, at their monthly average over
+// the whole window spread across weeks and scaled by the growth assumption
+func recurringExpenses(months []*models.CashFlowExpenseMonth, window int, growthPercent float64) []*models.CashFlowRecurringExpense {
+	if window <= 0 {
+		return nil
+	}
+	minMonths := 2
+	if window == 1 {
+		minMonths = 1
+	}
+
+	type category struct {
+		name   string
+		months int
+		total  float64
+	}
+	byCategory := make(map[uuid.UUID]*category)
+	for _, m := range months {
+		c, ok := byCategory[m.CategoryID]
+		if !ok {
+			c = &category{name: m.CategoryName}
+			byCategory[m.CategoryID] = c
+		}
+		c.months++
+		c.total += m.Amount
+	}
+
+	var expenses []*models.CashFlowRecurringExpense
+	for id, c := range byCategory {
+		if c.months < minMonths {
+			continue
+		}
+		monthly := c.total / float64(window) * (1 + growthPercent/100)
+		expenses = append(expenses, &models.CashFlowRecurringExpense{
+			CategoryID:     id,
+			CategoryName:   c.name,
+			MonthlyAverage: roundMoney(monthly),
+			Weekly:         roundMoney(monthly * 12 / 52),
+		})
+	}
+	sort.Slice(expenses, func(i, j int) bool {
+		if expenses[i].MonthlyAverage != expenses[j].MonthlyAverage {
+			return expenses[i].MonthlyAverage > expenses[j].MonthlyAverage
+		}
+		return expenses[i].CategoryName < expenses[j].CategoryName
+	})
+	return expenses
+}
+
+// projectCashFlow spreads expected receipts and payments over the weeks from
+// the Monday of today's week and runs the balance forward. Receipts already
+// past their expected date are collected in the first week at the overdue
+// collection rate; the rest of them is reported as doubtful
+func projectCashFlow(invoices []*models.ReceivableInvoice, delays map[uuid.UUID]float64, overallDelay float64, payables []*models.CashFlowPayable,
+	recurring []*models.CashFlowRecurringExpense, assumptions models.CashFlowAssumptions, today time.Time) *models.CashFlowProjection {
+	start := cashFlowStart(today)
+	projection := &models.CashFlowProjection{
+		Assumptions:       assumptions,
+		Weeks:             make([]*models.CashFlowWeek, CashFlowWeeks),
+		RecurringExpenses: []*models.CashFlowRecurringExpense{},
+	}
+	for i := range projection.Weeks {
+		weekStart := start.AddDate(0, 0, 7*i)
+		projection.Weeks[i] = &models.CashFlowWeek{Week: i + 1, WeekStart: weekStart, WeekEnd: weekStart.AddDate(0, 0, 6)}
+	}
+
+	for _, invoice := range invoices {
+		amount := invoice.Outstanding
+		expected := expectedPaymentDate(invoice, delays, overallDelay, assumptions.CollectionDelayDays)
+		if expected.Before(today) {
+			collected := amount * assumptions.OverdueCollectionRate
+			projection.DoubtfulReceivables += amount - collected
+			amount, expected = collected, today
+		}
+		idx := cashFlowWeekIndex(start, expected)
+		if idx >= CashFlowWeeks {
+			projection.InflowsBeyondHorizon += amount
+			continue
+		}
+		projection.Weeks[idx].Receivables += amount
+		projection.Weeks[idx].InvoiceCount++
+	}
+
+	for _, payable := range payables {
+		due, ok := supplierPaymentDate(payable, assumptions.SupplierPaymentDays, today)
+		if !ok {
+			continue
+		}
+		idx := cashFlowWeekIndex(start, due)
+		if idx >= CashFlowWeeks {
+			projection.OutflowsBeyondHorizon += payable.Amount
+			continue
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		projection.Weeks[idx].PurchaseOrders += payable.Amount
+		projection.Weeks[idx].PurchaseOrderCount++
+	}
+
+	var weeklyExpenses float64
+	for _, expense := range recurring {
+		weeklyExpenses += expense.Weekly
+	}
+	projection.RecurringExpenses = append(projection.RecurringExpenses, recurring...)
+
+	balance := assumptions.OpeningBalance
+	projection.LowestBalance = balance
+	for _, week := range projection.Weeks {
+		week.Expenses = weeklyExpenses
+		week.OpeningBalance = roundMoney(balance)
+		week.Receivables = roundMoney(week.Receivables)
+		week.PurchaseOrders = roundMoney(week.PurchaseOrders)
+		week.Expenses = roundMoney(week.Expenses)
+		week.TotalInflows = week.Receivables
+		week.TotalOutflows = roundMoney(week.PurchaseOrders + week.Expenses)
+		week.NetCashFlow = roundMoney(week.TotalInflows - week.TotalOutflows)
+		balance += week.NetCashFlow
+		week.ClosingBalance = roundMoney(balance)
+
+		projection.TotalInflows += week.TotalInflows
+		projection.TotalOutflows += week.TotalOutflows
+		if week.ClosingBalance < projection.LowestBalance || projection.LowestBalanceWeek == 0 {
+			projection.LowestBalance = week.ClosingBalance
+			projection.LowestBalanceWeek = week.Week
+		}
+	}
+	projection.TotalInflows = roundMoney(projection.TotalInflows)
+	projection.TotalOutflows = roundMoney(projection.TotalOutflows)
+	projection.ClosingBalance = roundMoney(balance)
+	projection.InflowsBeyondHorizon = roundMoney(projection.InflowsBeyondHorizon)
+	projection.OutflowsBeyondHorizon = roundMoney(projection.OutflowsBeyondHorizon)
+	projection.DoubtfulReceivables = roundMoney(projection.DoubtfulReceivables)
+	return projection
+}
@@ -0,0 +1,105 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCashFlowStartIsMonday(t *testing.T) {
+	wednesday := time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), cashFlowStart(wednesday))
+	sunday := time.Date(2025, 9, 7, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), cashFlowStart(sunday))
+	monday := time.Date(2025, 9, 8, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday, cashFlowStart(monday))
+}
+
+func TestExpectedPaymentDateUsesCustomerHistory(t *testing.T) {
+	due := time.Date(2025, 9, 10, 0, 0, 0, 0, time.UTC)
+	late, unknown := uuid.New(), uuid.New()
+	delays := map[uuid.UUID]float64{late: 12.4}
+
+	assert.Equal(t, due.AddDate(0, 0, 12), expectedPaymentDate(receivable(late, "Late", due, 100), delays, 5, 0))
+	assert.Equal(t, due.AddDate(0, 0, 12), expectedPaymentDate(receivable(unknown, "New", due, 100), delays, 4.6, 7))
+	assert.Equal(t, due, expectedPaymentDate(receivable(unknown, "New", due, 100), nil, 0, 0))
+}
+
+func TestSupplierPaymentDate(t *testing.T) {
+	today := time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC)
+
+	received := today.AddDate(0, 0, -10)
+	due, ok := supplierPaymentDate(&models.CashFlowPayable{ReceivedAt: &received}, 30, today)
+	assert.True(t, ok)
+	assert.Equal(t, today.AddDate(0, 0, 20), due)
+
+	paid := today.AddDate(0, 0, -45)
+	_, ok = supplierPaymentDate(&models.CashFlowPayable{ReceivedAt: &paid}, 30, today)
+	assert.False(t, ok)
+
+	// A late delivery is paid for once it arrives, no earlier than today
+	due, ok = supplierPaymentDate(&models.CashFlowPayable{ExpectedDelivery: today.AddDate(0, 0, -5)}, 30, today)
+	assert.True(t, ok)
+	assert.Equal(t, today.AddDate(0, 0, 30), due)
+}
+
+func TestRecurringExpensesNeedTwoMonths(t *testing.T) {
+	rent, repairs := uuid.New(), uuid.New()
+	months := []*models.CashFlowExpenseMonth{
+		{CategoryID: rent, CategoryName: "Rent", Month: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Amount: 52000},
+		{CategoryID: rent, CategoryName: "Rent", Month: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Amount: 52000},
+		{CategoryID: rent, CategoryName: "Rent", Month: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC), Amount: 52000},
+		{CategoryID: repairs, CategoryName: "Repairs", Month: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), Amount: 9000},
+	}
+
+	expenses := recurringExpenses(months, 3, 0)
+	assert.Len(t, expenses, 1)
+	assert.Equal(t, "Rent", expenses[0].CategoryName)
+	assert.Equal(t, 52000.0, expenses[0].MonthlyAverage)
+	assert.Equal(t, 12000.0, expenses[0].Weekly)
+
+	expenses = recurringExpenses(months, 3, 10)
+	assert.Equal(t, 57200.0, expenses[0].MonthlyAverage)
+}
+
+func TestProjectCashFlowSpreadsWeeks(t *testing.T) {
+	today := time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC) // Wednesday
+	customer := uuid.New()
+	invoices := []*models.ReceivableInvoice{
+		receivable(customer, "Ravi Agro", today.AddDate(0, 0, -20), 1000), // overdue
+		receivable(customer, "Ravi Agro", today.AddDate(0, 0, 8), 500),    // week 2
+		receivable(customer, "Ravi Agro", today.AddDate(0, 0, 120), 700),  // past week 13
+	}
+	payables := []*models.CashFlowPayable{
+		{OrderID: uuid.New(), Amount: 300, ExpectedDelivery: today.AddDate(0, 0, -16)},
+	}
+	recurring := []*models.CashFlowRecurringExpense{{CategoryName: "Rent", Weekly: 100}}
+	assumptions := DefaultCashFlowAssumptions()
+	assumptions.OpeningBalance = 2000
+	assumptions.SupplierPaymentDays = 14
+
+	projection := projectCashFlow(invoices, nil, 0, payables, recurring, assumptions, today)
+	assert.Len(t, projection.Weeks, CashFlowWeeks)
+
+	first := projection.Weeks[0]
+	assert.Equal(t, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), first.WeekStart)
+	assert.Equal(t, 500.0, first.Receivables)
+	assert.Equal(t, 100.0, first.Expenses)
+	assert.Equal(t, 2400.0, first.ClosingBalance)
+
+	// The late purchase order is paid 14 days from today, in week 3
+	assert.Equal(t, 500.0, projection.Weeks[1].Receivables)
+	assert.Equal(t, 300.0, projection.Weeks[2].PurchaseOrders)
+
+	assert.Equal(t, 500.0, projection.DoubtfulReceivables)
+	assert.Equal(t, 700.0, projection.InflowsBeyondHorizon)
+	assert.Equal(t, 1000.0, projection.TotalInflows)
+	assert.Equal(t, 1600.0, projection.TotalOutflows)
+	assert.Equal(t, 1400.0, projection.ClosingBalance)
+	assert.Equal(t, 1400.0, projection.LowestBalance)
+	assert.Equal(t, CashFlowWeeks, projection.LowestBalanceWeek)
+}
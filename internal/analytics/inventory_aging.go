@@ -0,0 +1,210 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultDeadStockDays is the age after which unmoved stock counts as dead
+	DefaultDeadStockDays = 180
+	// nearExpiryDays flags stock for markdown regardless of age
+	nearExpiryDays     = 60
+	maxMarkdownResults = 50
+)
+
+// InventoryAgingService buckets stock by days since its last movement in the
+// inventory transaction ledger and suggests markdowns for slow stock
+type InventoryAgingService struct {
+	transactionRepo repositories.InventoryTransactionRepository
+}
+
+func NewInventoryAgingService(transactionRepo repositories.InventoryTransactionRepository) *InventoryAgingService {
+	return &InventoryAgingService{transactionRepo: transactionRepo}
+}
+
+// InventoryAgingOptions tunes the report; zero values use the defaults
+type InventoryAgingOptions struct {
+	WarehouseID       *uuid.UUID
+	DeadStockDays     int
+	MinDeadStockValue float64
+	IncludeItems      bool
+}
+
+// GetInventoryAging builds the aging report as of now
+func (s *InventoryAgingService) GetInventoryAging(ctx context.Context, tenantID uuid.UUID, opts InventoryAgingOptions) (*models.InventoryAgingReport, error) {
+	positions, err := s.transactionRepo.AgingPositions(ctx, tenantID, opts.WarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	return buildAgingReport(positions, opts, time.Now()), nil
+}
+
+func buildAgingReport(positions []*models.InventoryAgingPosition, opts InventoryAgingOptions, now time.Time) *models.InventoryAgingReport {
+	if opts.DeadStockDays <= 0 {
+		opts.DeadStockDays = DefaultDeadStockDays
+	}
+
+	report := &models.InventoryAgingReport{
+		AsOf:               now,
+		WarehouseID:        opts.WarehouseID,
+		DeadStockDays:      opts.DeadStockDays,
+		DeadStock:          []*models.InventoryAgingItem{},
+		MarkdownCandidates: []*models.MarkdownCandidate{},
+	}
+	buckets := map[string]*models.InventoryAgingBucket{}
+	for _, name := range []string{models.AgingBucket0To30, models.AgingBucket31To90, models.AgingBucket91To180, models.AgingBucketOver180} {
+		bucket := &models.InventoryAgingBucket{Bucket: name}
+		buckets[name] = bucket
+		report.Buckets = append(report.Buckets, bucket)
+	}
+
+	for _, pos := range positions {
+		days := int(now.Sub(pos.LastMovementAt).Hours() / 24)
+		if days < 0 {
+			days = 0
+		}
+		item := &models.InventoryAgingItem{
+			InventoryAgingPosition: *pos,
+			DaysSinceMovement:      days,
+			Bucket:                 agingBucket(days),
+			StockValue:             roundMoney(float64(pos.Quantity) * unitCost(pos)),
+		}
+		item.IsDeadStock = days >= opts.DeadStockDays && item.StockValue >= opts.MinDeadStockValue
+
+		bucket := buckets[item.Bucket]
+		bucket.Lines++
+		bucket.Quantity += pos.Quantity
+		bucket.Value += item.StockValue
+		report.TotalValue += item.StockValue
+
+		if item.IsDeadStock {
+			report.DeadStock = append(report.DeadStock, item)
+			report.DeadStockValue += item.StockValue
+		}
+		if pct, reason, ok := suggestMarkdown(days, pos.UnitPrice, pos.CostPrice, pos.ExpiryDate, now); ok {
+			report.MarkdownCandidates = append(report.MarkdownCandidates, &models.MarkdownCandidate{
+				WarehouseID:       pos.WarehouseID,
+				ProductID:         pos.ProductID,
+				ProductName:       pos.ProductName,
+				Quantity:          pos.Quantity,
+				DaysSinceMovement: days,
+				StockValue:        item.StockValue,
+				CurrentPrice:      pos.UnitPrice,
+				SuggestedPrice:    roundMoney(pos.UnitPrice * (1 - pct/100)),
+				MarkdownPercent:   pct,
+				Reason:            reason,
+			})
+		}
+		if opts.IncludeItems {
+			report.Items = append(report.Items, item)
+		}
+	}
+
+	for _, bucket := range report.Buckets {
+		bucket.Value = roundMoney(bucket.Value)
+		bucket.ValueShare = share(bucket.Value, report.TotalValue)
+	}
+	report.TotalValue = roundMoney(report.TotalValue)
+	report.DeadStockValue = roundMoney(report.DeadStockValue)
+	report.DeadStockShare = share(report.DeadStockValue, report.TotalValue)
+
+	// Highest value first: that is where a write-down or markdown matters most
+	sort.Slice(report.DeadStock, func(i, j int) bool {
+		return report.DeadStock[i].StockValue > report.DeadStock[j].StockValue
+	})
+	sort.Slice(report.MarkdownCandidates, func(i, j int) bool {
+		return report.MarkdownCandidates[i].StockValue > report.MarkdownCandidates[j].StockValue
+	})
+	if len(report.MarkdownCandidates) > maxMarkdownResults {
+		report.MarkdownCandidates = report.MarkdownCandidates[:maxMarkdownResults]
+	}
+	if opts.IncludeItems {
+		sort.Slice(report.Items, func(i, j int) bool {
+			return report.Items[i].DaysSinceMovement > report.Items[j].DaysSinceMovement
+		})
+	}
+	return report
+}
+
+func agingBucket(days int) string {
+	switch {
+	case days <= 30:
+		return models.AgingBucket0To30
+	case days <= 90:
+		return models.AgingBucket31To90
+	case days <= 180:
+		return models.AgingBucket91To180
+	default:
+		return models.AgingBucketOver180
+	}
+}
+
+// unitCost values stock at landed cost, falling back to the selling price
+func unitCost(pos *models.InventoryAgingPosition) float64 {
+	if pos.CostPrice != nil && *pos.CostPrice > 0 {
+		return *pos.CostPrice
+	}
+	return pos.UnitPrice
+}
+
+// suggestMarkdown returns a markdown percentage for slow or near-expiry stock,
+// reduced so the price stays at or above cost. Expired stock is not a
+// markdown candidate; it needs writing off.
+func suggestMarkdown(days int, price float64, cost *float64, expiry *time.Time, now time.Time) (float64, string, bool) {
+	if price <= 0 {
+		return 0, "", false
+	}
+
+	pct, reason := 0.0, ""
+	switch {
+	case days > 365:
+		pct, reason = 40, "no movement in over a year"
+	case days > 180:
+		pct, reason = 25, "no movement in over 180 days"
+	case days > 90:
+		pct, reason = 10, "no movement in over 90 days"
+	}
+	if expiry != nil {
+		daysToExpiry := int(math.Floor(expiry.Sub(now).Hours() / 24))
+		if daysToExpiry < 0 {
+			return 0, "", false
+		}
+		if daysToExpiry <= nearExpiryDays && pct < 30 {
+			pct, reason = 30, "expires within 60 days"
+		}
+	}
+	if pct == 0 {
+		return 0, "", false
+	}
+
+	if cost != nil && *cost > 0 {
+		maxPct := math.Floor((price-*cost)/price*1000+1e-9) / 10
+		if maxPct <= 0 {
+			return 0, "", false
+		}
+		if pct > maxPct {
+			pct = maxPct
+			reason += "; limited to cost"
+		}
+	}
+	return pct, reason, true
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func share(part, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(part/total*10000) / 100
+}
@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgingBucketBoundaries(t *testing.T) {
+	assert.Equal(t, models.AgingBucket0To30, agingBucket(0))
+	assert.Equal(t, models.AgingBucket0To30, agingBucket(30))
+	assert.Equal(t, models.AgingBucket31To90, agingBucket(31))
+	assert.Equal(t, models.AgingBucket91To180, agingBucket(180))
+	assert.Equal(t, models.AgingBucketOver180, agingBucket(181))
+}
+
+func TestSuggestMarkdownLimitedToCost(t *testing.T) {
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	cost := 90.0
+
+	pct, reason, ok := suggestMarkdown(400, 100, &cost, nil, now)
+
+	assert.True(t, ok)
+	assert.Equal(t, 10.0, pct)
+	assert.Contains(t, reason, "limited to cost")
+
+	_, _, ok = suggestMarkdown(400, 100, &[]float64{100}[0], nil, now)
+	assert.False(t, ok, "no markdown when price is already at cost")
+}
+
+func TestSuggestMarkdownNearExpiry(t *testing.T) {
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	soon := now.AddDate(0, 0, 20)
+	expired := now.AddDate(0, 0, -1)
+
+	pct, _, ok := suggestMarkdown(5, 100, nil, &soon, now)
+	assert.True(t, ok)
+	assert.Equal(t, 30.0, pct)
+
+	_, _, ok = suggestMarkdown(400, 100, nil, &expired, now)
+	assert.False(t, ok, "expired stock is written off, not marked down")
+
+	_, _, ok = suggestMarkdown(10, 100, nil, nil, now)
+	assert.False(t, ok)
+}
+
+func TestBuildAgingReportDeadStockByValue(t *testing.T) {
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	cost := 50.0
+	positions := []*models.InventoryAgingPosition{
+		{ProductID: uuid.New(), ProductName: "Urea", Quantity: 10, UnitPrice: 80, CostPrice: &cost, LastMovementAt: now.AddDate(0, 0, -5)},
+		{ProductID: uuid.New(), ProductName: "DAP", Quantity: 4, UnitPrice: 100, LastMovementAt: now.AddDate(0, 0, -200)},
+		{ProductID: uuid.New(), ProductName: "Seed", Quantity: 20, UnitPrice: 100, LastMovementAt: now.AddDate(0, 0, -300)},
+	}
+
+	report := buildAgingReport(positions, InventoryAgingOptions{MinDeadStockValue: 500}, now)
+
+	assert.Equal(t, 2900.0, report.TotalValue)
+	assert.Equal(t, 500.0, report.Buckets[0].Value)
+	assert.Equal(t, 2400.0, report.Buckets[3].Value)
+	if assert.Len(t, report.DeadStock, 1) {
+		assert.Equal(t, "Seed", report.DeadStock[0].ProductName)
+	}
+	assert.Equal(t, 2000.0, report.DeadStockValue)
+	assert.Len(t, report.MarkdownCandidates, 2)
+	assert.Equal(t, "Seed", report.MarkdownCandidates[0].ProductName)
+	assert.Nil(t, report.Items)
+}
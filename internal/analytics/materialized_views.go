@@ -0,0 +1,131 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// DefaultStaleTolerance is how old a materialized view may be before reports
+// read the live tables instead
+const DefaultStaleTolerance = 30 * time.Minute
+
+// viewIsFresh reports whether a view with the given last refresh may still
+// be read at now; a view never refreshed is not
+func viewIsFresh(refresh *models.AnalyticsViewRefresh, tolerance time.Duration, now time.Time) bool {
+	if refresh == nil {
+		return false
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultStaleTolerance
+	}
+	return now.Sub(refresh.RefreshedAt) <= tolerance
+}
+
+// viewSource decides whether to read a view or the live tables, and as of
+// when the data is. Failing to look up the refresh falls back to live.
+func (a *AnalyticsService) viewSource(ctx context.Context, view string) (bool, string, time.Time) {
+	now := time.Now()
+	refresh, err := a.viewRepo.LastRefresh(ctx, view)
+	if err != nil {
+		log.Printf("Failed to get last refresh of %s: %v", view, err)
+		return true, models.AnalyticsSourceLive, now
+	}
+	if !viewIsFresh(refresh, a.staleTolerance, now) {
+		return true, models.AnalyticsSourceLive, now
+	}
+	return false, models.AnalyticsSourceMaterialized, refresh.RefreshedAt
+}
+
+// RefreshMaterializedViews refreshes every analytics view, carrying on past
+// failures, and returns the refreshes that succeeded
+func (a *AnalyticsService) RefreshMaterializedViews(ctx context.Context) ([]*models.AnalyticsViewRefresh, error) {
+	var refreshes []*models.AnalyticsViewRefresh
+	var firstErr error
+	for _, view := range repositories.AnalyticsViews {
+		refresh, err := a.viewRepo.Refresh(ctx, view)
+		if err != nil {
+			log.Printf("Failed to refresh %s: %v", view, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		refreshes = append(refreshes, refresh)
+	}
+	if len(refreshes) > 0 {
+		caching.PublishEvent(ctx, a.cacheService, uuid.Nil, caching.EventAnalyticsRefreshed)
+	}
+	return refreshes, firstErr
+}
+
+// DailySales totals non-cancelled sales orders per day between from and to inclusive
+func (a *AnalyticsService) DailySales(ctx context.Context, tenantID uuid.UUID, from, to time.Time) (*models.DailySalesReport, error) {
+	live, source, asOf := a.viewSource(ctx, repositories.ViewDailySales)
+	days, err := a.viewRepo.DailySales(ctx, tenantID, from, to, live)
+	if err != nil {
+		return nil, err
+	}
+	if days == nil {
+		days = []*models.DailySalesRow{}
+	}
+	return &models.DailySalesReport{From: from, To: to, Source: source, AsOf: asOf, Days: days}, nil
+}
+
+// StockByCategory values owned stock by product category
+func (a *AnalyticsService) StockByCategory(ctx context.Context, tenantID uuid.UUID) (*models.StockByCategoryReport, error) {
+	live, source, asOf := a.viewSource(ctx, repositories.ViewStockByCategory)
+	categories, err := a.viewRepo.StockByCategory(ctx, tenantID, live)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.StockByCategoryReport{Source: source, AsOf: asOf, Categories: []*models.CategoryStockRow{}}
+	for _, category := range categories {
+		report.TotalValue += category.StockValue
+		report.Categories = append(report.Categories, category)
+	}
+	report.TotalValue = roundMoney(report.TotalValue)
+	return report, nil
+}
+
+// ReceivablesAging ages what distributors owe on unpaid and overdue invoices
+func (a *AnalyticsService) ReceivablesAging(ctx context.Context, tenantID uuid.UUID) (*models.ReceivablesAgingReport, error) {
+	live, source, asOf := a.viewSource(ctx, repositories.ViewReceivablesAging)
+	rows, err := a.viewRepo.ReceivablesAging(ctx, tenantID, live)
+	if err != nil {
+		return nil, err
+	}
+	return buildReceivablesAging(rows, source, asOf), nil
+}
+
+func buildReceivablesAging(rows []*models.ReceivablesAgingRow, source string, asOf time.Time) *models.ReceivablesAgingReport {
+	report := &models.ReceivablesAgingReport{
+		Source:       source,
+		AsOf:         asOf,
+		Totals:       models.ReceivablesAgingRow{DistributorName: "Total"},
+		Distributors: []*models.ReceivablesAgingRow{},
+	}
+	for _, row := range rows {
+		report.Totals.InvoiceCount += row.InvoiceCount
+		report.Totals.Current += row.Current
+		report.Totals.Days1To30 += row.Days1To30
+		report.Totals.Days31To60 += row.Days31To60
+		report.Totals.Days61To90 += row.Days61To90
+		report.Totals.DaysOver90 += row.DaysOver90
+		report.Totals.Total += row.Total
+		report.Distributors = append(report.Distributors, row)
+	}
+
+	totals := &report.Totals
+	for _, v := range []*float64{&totals.Current, &totals.Days1To30, &totals.Days31To60, &totals.Days61To90, &totals.DaysOver90, &totals.Total} {
+		*v = roundMoney(*v)
+	}
+	return report
+}
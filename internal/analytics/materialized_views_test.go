@@ -0,0 +1,47 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewIsFresh(t *testing.T) {
+	now := time.Date(2025, 9, 2, 12, 0, 0, 0, time.UTC)
+	refreshed := func(ago time.Duration) *models.AnalyticsViewRefresh {
+		return &models.AnalyticsViewRefresh{RefreshedAt: now.Add(-ago)}
+	}
+
+	assert.False(t, viewIsFresh(nil, time.Hour, now))
+	assert.True(t, viewIsFresh(refreshed(time.Hour), time.Hour, now))
+	assert.False(t, viewIsFresh(refreshed(time.Hour+time.Second), time.Hour, now))
+	// Without a tolerance the default applies
+	assert.True(t, viewIsFresh(refreshed(DefaultStaleTolerance), 0, now))
+	assert.False(t, viewIsFresh(refreshed(DefaultStaleTolerance+time.Minute), 0, now))
+}
+
+func TestBuildReceivablesAgingTotals(t *testing.T) {
+	rows := []*models.ReceivablesAgingRow{
+		{InvoiceCount: 2, Current: 100.10, Days1To30: 50.05, Total: 150.15},
+		{InvoiceCount: 1, Days61To90: 20.20, DaysOver90: 9.80, Total: 30},
+	}
+	asOf := time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC)
+
+	report := buildReceivablesAging(rows, models.AnalyticsSourceMaterialized, asOf)
+	assert.Equal(t, models.AnalyticsSourceMaterialized, report.Source)
+	assert.Equal(t, asOf, report.AsOf)
+	assert.Len(t, report.Distributors, 2)
+	assert.Equal(t, 3, report.Totals.InvoiceCount)
+	assert.Equal(t, 100.10, report.Totals.Current)
+	assert.Equal(t, 50.05, report.Totals.Days1To30)
+	assert.Equal(t, 20.20, report.Totals.Days61To90)
+	assert.Equal(t, 9.80, report.Totals.DaysOver90)
+	assert.Equal(t, 180.15, report.Totals.Total)
+
+	empty := buildReceivablesAging(nil, models.AnalyticsSourceLive, asOf)
+	assert.NotNil(t, empty.Distributors)
+	assert.Zero(t, empty.Totals.Total)
+}
@@ -0,0 +1,310 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// profitabilityCacheTTL is how long a profitability report is served from cache
+const profitabilityCacheTTL = 15 * time.Minute
+
+// ErrInvalidProfitabilityQuery is returned for an unknown dimension or
+// comparison, or a period that ends before it starts
+var ErrInvalidProfitabilityQuery = errors.New("invalid profitability query")
+
+// ProfitabilityOptions selects the dimension, period and comparison of a
+// profitability report; From and To are inclusive dates
+type ProfitabilityOptions struct {
+	Dimension string
+	From      time.Time
+	To        time.Time
+	Compare   string
+}
+
+// ProfitabilityService reports gross margin per product, customer and order
+// from sales prices and the landed cost of stock. Reports are cached per
+// tenant under the analytics key prefix, so invalidating a tenant's cache
+// drops them too
+type ProfitabilityService struct {
+	repo         repositories.ProfitabilityRepository
+	cacheService caching.CacheService
+}
+
+func NewProfitabilityService(repo repositories.ProfitabilityRepository, cacheService caching.CacheService) *ProfitabilityService {
+	return &ProfitabilityService{repo: repo, cacheService: cacheService}
+}
+
+func profitabilityCacheKey(tenantID uuid.UUID, opts ProfitabilityOptions) string {
+	return fmt.Sprintf("agromart:analytics:%s:profitability:%s:%s:%s:%s", tenantID.String(), opts.Dimension,
+		opts.From.Format("20060102"), opts.To.Format("20060102"), opts.Compare)
+}
+
+// GetReport builds the profitability report, or serves it from cache
+func (s *ProfitabilityService) GetReport(ctx context.Context, tenantID uuid.UUID, opts ProfitabilityOptions) (*models.ProfitabilityReport, error) {
+	if opts.Compare == "" {
+		opts.Compare = models.ProfitabilityCompareNone
+	}
+	switch opts.Dimension {
+	case models.ProfitabilityByProduct, models.ProfitabilityByCustomer, models.ProfitabilityByOrder:
+	default:
+		return nil, fmt.Errorf("%w: dimension must be product, customer or order", ErrInvalidProfitabilityQuery)
+	}
+	if opts.To.Before(opts.From) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidProfitabilityQuery)
+	}
+	compareFrom, compareTo, ok := comparisonPeriod(opts.From, opts.To, opts.Compare)
+	if !ok {
+		return nil, fmt.Errorf("%w: compare must be none, previous_period or previous_year", ErrInvalidProfitabilityQuery)
+	}
+
+	key := profitabilityCacheKey(tenantID, opts)
+	if cached, err := s.cacheService.GetString(ctx, key); err == nil && cached != "" {
+		report := &models.ProfitabilityReport{}
+		if err := json.Unmarshal([]byte(cached), report); err == nil {
+			return report, nil
+		}
+	}
+
+	sales, err := s.repo.ListSales(ctx, tenantID, opts.From, opts.To)
+	if err != nil {
+		return nil, err
+	}
+	var previous []*models.ProfitabilitySale
+	if compareFrom != nil {
+		if previous, err = s.repo.ListSales(ctx, tenantID, *compareFrom, *compareTo); err != nil {
+			return nil, err
+		}
+	}
+
+	report := buildProfitability(opts.Dimension, sales, previous, compareFrom != nil)
+	report.From, report.To, report.Compare = opts.From, opts.To, opts.Compare
+	report.CompareFrom, report.CompareTo = compareFrom, compareTo
+	report.GeneratedAt = time.Now()
+
+	if payload, err := json.Marshal(report); err == nil {
+		if cacheErr := s.cacheService.SetString(ctx, key, string(payload), profitabilityCacheTTL); cacheErr != nil {
+			log.Printf("Failed to cache profitability report for tenant %s: %v", tenantID.String(), cacheErr)
+		}
+	}
+	return report, nil
+}
+
+// comparisonPeriod is the period a report is compared with: the same number
+// of days just before it, or the same dates a year earlier. ok is false for
+// an unknown comparison
+func comparisonPeriod(from, to time.Time, compare string) (*time.Time, *time.Time, bool) {
+	switch compare {
+	case models.ProfitabilityCompareNone:
+		return nil, nil, true
+	case models.ProfitabilityComparePreviousPeriod:
+		days := int(to.Sub(from).Hours()/24) + 1
+		compareTo := from.AddDate(0, 0, -1)
+		compareFrom := compareTo.AddDate(0, 0, -(days - 1))
+		return &compareFrom, &compareTo, true
+	case models.ProfitabilityComparePreviousYear:
+		compareFrom, compareTo := from.AddDate(-1, 0, 0), to.AddDate(-1, 0, 0)
+		return &compareFrom, &compareTo, true
+	}
+	return nil, nil, false
+}
+
+// addSale adds a sale to the figures; sales without a cost only add revenue
+func addSale(f *models.ProfitabilityFigures, sale *models.ProfitabilitySale) {
+	revenue := float64(sale.Quantity) * sale.UnitPrice
+	f.Orders++
+	f.Quantity += sale.Quantity
+	f.Revenue += revenue
+	if sale.UnitCost == nil {
+		f.UncostedRevenue += revenue
+		return
+	}
+	f.Cost += float64(sale.Quantity) * *sale.UnitCost
+}
+
+// finishFigures rounds the figures and works out the margin on costed revenue
+func finishFigures(f *models.ProfitabilityFigures) {
+	costed := f.Revenue - f.UncostedRevenue
+	f.GrossMargin = roundMoney(costed - f.Cost)
+	f.MarginPercent = share(costed-f.Cost, costed)
+	f.Revenue = roundMoney(f.Revenue)
+	f.Cost = roundMoney(f.Cost)
+	f.UncostedRevenue = roundMoney(f.UncostedRevenue)
+}
+
+// compareRow fills in the change against the comparison period
+func compareRow(row *models.ProfitabilityRow, previous models.ProfitabilityFigures) {
+	row.Previous = &previous
+	if previous.Revenue > 0 {
+		change := roundMoney((row.Current.Revenue - previous.Revenue) / previous.Revenue * 100)
+		row.RevenueChangePercent = &change
+	}
+	if previous.Revenue-previous.UncostedRevenue > 0 && row.Current.Revenue-row.Current.UncostedRevenue > 0 {
+		change := roundMoney(row.Current.MarginPercent - previous.MarginPercent)
+		row.MarginChangePoints = &change
+	}
+}
+
+// profitabilityKey is the row a sale belongs to in a dimension
+func profitabilityKey(dimension string, sale *models.ProfitabilitySale) (uuid.UUID, string) {
+	switch dimension {
+	case models.ProfitabilityByCustomer:
+		return sale.DistributorID, sale.DistributorName
+	case models.ProfitabilityByOrder:
+		return sale.OrderID, sale.ProductName
+	default:
+		return sale.ProductID, sale.ProductName
+	}
+}
+
+// groupSales totals sales into rows of the dimension, keeping first-seen order
+func groupSales(dimension string, sales []*models.ProfitabilitySale) ([]*models.ProfitabilityRow, map[uuid.UUID]*models.ProfitabilityRow) {
+	var rows []*models.ProfitabilityRow
+	byID := make(map[uuid.UUID]*models.ProfitabilityRow)
+	for _, sale := range sales {
+		id, name := profitabilityKey(dimension, sale)
+		row, ok := byID[id]
+		if !ok {
+			row = &models.ProfitabilityRow{ID: id, Name: name}
+			if dimension == models.ProfitabilityByOrder {
+				orderDate := sale.OrderDate
+				row.CustomerName = sale.DistributorName
+				row.OrderDate = &orderDate
+			}
+			byID[id] = row
+			rows = append(rows, row)
+		}
+		addSale(&row.Current, sale)
+	}
+	for _, row := range rows {
+		finishFigures(&row.Current)
+	}
+	return rows, byID
+}
+
+// buildProfitability groups the period's sales by dimension and, when
+// comparing, matches each row with the comparison period. Products and
+// customers that only sold in the comparison period are listed with zero
+// current figures; orders are only compared in the totals. Orders are listed
+// by date, other rows by gross margin, highest first
+func buildProfitability(dimension string, sales, previous []*models.ProfitabilitySale, comparing bool) *models.ProfitabilityReport {
+	report := &models.ProfitabilityReport{Dimension: dimension}
+	rows, byID := groupSales(dimension, sales)
+
+	for _, sale := range sales {
+		addSale(&report.Totals.Current, sale)
+	}
+	finishFigures(&report.Totals.Current)
+	report.Totals.Name = "Total"
+
+	if comparing {
+		var previousTotals models.ProfitabilityFigures
+		for _, sale := range previous {
+			addSale(&previousTotals, sale)
+		}
+		finishFigures(&previousTotals)
+		compareRow(&report.Totals, previousTotals)
+
+		if dimension != models.ProfitabilityByOrder {
+			previousRows, _ := groupSales(dimension, previous)
+			for _, prev := range previousRows {
+				row, ok := byID[prev.ID]
+				if !ok {
+					row = &models.ProfitabilityRow{ID: prev.ID, Name: prev.Name}
+					byID[prev.ID] = row
+					rows = append(rows, row)
+				}
+				compareRow(row, prev.Current)
+			}
+			for _, row := range rows {
+				if row.Previous == nil {
+					compareRow(row, models.ProfitabilityFigures{})
+				}
+			}
+		}
+	}
+
+	if dimension != models.ProfitabilityByOrder {
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].Current.GrossMargin != rows[j].Current.GrossMargin {
+				return rows[i].Current.GrossMargin > rows[j].Current.GrossMargin
+			}
+			return rows[i].Name < rows[j].Name
+		})
+	}
+	report.Rows = rows
+	if report.Rows == nil {
+		report.Rows = []*models.ProfitabilityRow{}
+	}
+	return report
+}
+
+func formatOptionalAmount(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatAmount(*v)
+}
+
+// ProfitabilityCSV renders the report one row per product, customer or order
+// with a totals row; comparison columns are added when the report compares
+func ProfitabilityCSV(report *models.ProfitabilityReport) ([]byte, error) {
+	comparing := report.CompareFrom != nil
+	orders := report.Dimension == models.ProfitabilityByOrder
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"Name"}
+	if orders {
+		header = []string{"Order ID", "Order Date", "Customer", "Product"}
+	}
+	header = append(header, "Orders", "Quantity", "Revenue", "Cost", "Gross Margin", "Margin %", "Uncosted Revenue")
+	if comparing {
+		header = append(header, "Previous Revenue", "Previous Gross Margin", "Previous Margin %", "Revenue Change %", "Margin Change (pts)")
+	}
+	w.Write(header)
+
+	write := func(row *models.ProfitabilityRow, total bool) {
+		var record []string
+		switch {
+		case orders && total:
+			record = []string{row.Name, "", "", ""}
+		case orders:
+			record = []string{row.ID.String(), row.OrderDate.Format("2006-01-02"), row.CustomerName, row.Name}
+		default:
+			record = []string{row.Name}
+		}
+		f := row.Current
+		record = append(record, strconv.Itoa(f.Orders), strconv.Itoa(f.Quantity), formatAmount(f.Revenue), formatAmount(f.Cost),
+			formatAmount(f.GrossMargin), formatAmount(f.MarginPercent), formatAmount(f.UncostedRevenue))
+		if comparing {
+			if row.Previous != nil {
+				record = append(record, formatAmount(row.Previous.Revenue), formatAmount(row.Previous.GrossMargin), formatAmount(row.Previous.MarginPercent))
+			} else {
+				record = append(record, "", "", "")
+			}
+			record = append(record, formatOptionalAmount(row.RevenueChangePercent), formatOptionalAmount(row.MarginChangePoints))
+		}
+		w.Write(record)
+	}
+	for _, row := range report.Rows {
+		write(row, false)
+	}
+	write(&report.Totals, true)
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,104 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func sale(product, customer uuid.UUID, date time.Time, qty int, price float64, cost *float64) *models.ProfitabilitySale {
+	return &models.ProfitabilitySale{
+		OrderID: uuid.New(), OrderDate: date, ProductID: product, ProductName: "Product " + product.String()[:4],
+		DistributorID: customer, DistributorName: "Customer " + customer.String()[:4], Quantity: qty, UnitPrice: price, UnitCost: cost,
+	}
+}
+
+func TestComparisonPeriod(t *testing.T) {
+	from := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 8, 31, 0, 0, 0, 0, time.UTC)
+
+	cf, ct, ok := comparisonPeriod(from, to, models.ProfitabilityComparePreviousPeriod)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), *cf)
+	assert.Equal(t, time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC), *ct)
+
+	cf, ct, ok = comparisonPeriod(from, to, models.ProfitabilityComparePreviousYear)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), *cf)
+	assert.Equal(t, time.Date(2024, 8, 31, 0, 0, 0, 0, time.UTC), *ct)
+
+	cf, _, ok = comparisonPeriod(from, to, models.ProfitabilityCompareNone)
+	assert.True(t, ok)
+	assert.Nil(t, cf)
+
+	_, _, ok = comparisonPeriod(from, to, "last_quarter")
+	assert.False(t, ok)
+}
+
+func TestBuildProfitabilityByProduct(t *testing.T) {
+	day := time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC)
+	urea, dap, customer := uuid.New(), uuid.New(), uuid.New()
+	cost := func(v float64) *float64 { return &v }
+
+	sales := []*models.ProfitabilitySale{
+		sale(urea, customer, day, 10, 300, cost(250)),
+		sale(urea, customer, day, 5, 300, cost(260)),
+		sale(dap, customer, day, 4, 1400, nil),
+	}
+	previous := []*models.ProfitabilitySale{
+		sale(urea, customer, day.AddDate(0, -1, 0), 10, 280, cost(250)),
+	}
+
+	report := buildProfitability(models.ProfitabilityByProduct, sales, previous, true)
+	assert.Len(t, report.Rows, 2)
+
+	first := report.Rows[0]
+	assert.Equal(t, urea, first.ID)
+	assert.Equal(t, 2, first.Current.Orders)
+	assert.Equal(t, 4500.0, first.Current.Revenue)
+	assert.Equal(t, 3800.0, first.Current.Cost)
+	assert.Equal(t, 700.0, first.Current.GrossMargin)
+	assert.Equal(t, 15.56, first.Current.MarginPercent)
+	assert.Equal(t, 2800.0, first.Previous.Revenue)
+	assert.Equal(t, 60.71, *first.RevenueChangePercent)
+	assert.Equal(t, 4.85, *first.MarginChangePoints)
+
+	// Uncosted sales add revenue but no margin
+	uncosted := report.Rows[1]
+	assert.Equal(t, 5600.0, uncosted.Current.UncostedRevenue)
+	assert.Equal(t, 0.0, uncosted.Current.GrossMargin)
+	assert.Nil(t, uncosted.RevenueChangePercent)
+
+	assert.Equal(t, 10100.0, report.Totals.Current.Revenue)
+	assert.Equal(t, 700.0, report.Totals.Current.GrossMargin)
+	assert.Equal(t, 15.56, report.Totals.Current.MarginPercent)
+}
+
+func TestBuildProfitabilityByOrderComparesTotalsOnly(t *testing.T) {
+	day := time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC)
+	product, customer := uuid.New(), uuid.New()
+	cost := 90.0
+	sales := []*models.ProfitabilitySale{sale(product, customer, day, 2, 100, &cost)}
+	previous := []*models.ProfitabilitySale{sale(product, customer, day.AddDate(0, 0, -30), 1, 100, &cost)}
+
+	report := buildProfitability(models.ProfitabilityByOrder, sales, previous, true)
+	assert.Len(t, report.Rows, 1)
+	assert.Nil(t, report.Rows[0].Previous)
+	assert.Equal(t, sales[0].DistributorName, report.Rows[0].CustomerName)
+	assert.Equal(t, 100.0, *report.Totals.RevenueChangePercent)
+
+	from, to := day.AddDate(0, 0, -9), day
+	report.From, report.To = from, to
+	report.CompareFrom, report.CompareTo = &from, &to
+	content, err := ProfitabilityCSV(report)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "Order ID,Order Date,Customer,Product,Orders"))
+	assert.True(t, strings.HasPrefix(lines[2], "Total,,,,1,2,200.00,180.00,20.00,10.00,0.00,100.00"))
+}
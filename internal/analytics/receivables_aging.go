@@ -0,0 +1,247 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTopCustomers is how many customers the dashboard widget lists
+const DefaultTopCustomers = 5
+
+// ErrCustomerNotFound is returned when drilling into an unknown customer
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// receivablesBuckets lists the aging buckets in order
+var receivablesBuckets = []string{
+	models.ReceivablesBucketCurrent,
+	models.ReceivablesBucket1To30,
+	models.ReceivablesBucket31To60,
+	models.ReceivablesBucket61To90,
+	models.ReceivablesBucketOver90,
+}
+
+// ReceivablesAgingService ages open customer invoices by days past due. It
+// reads the invoices live, so unlike the dashboard analytics views it is
+// exact as of the request. Days past due count on the tenant's calendar
+type ReceivablesAgingService struct {
+	repo            repositories.ReceivablesRepository
+	distributorRepo repositories.DistributorRepository
+	calendarRepo    repositories.TenantCalendarRepository
+}
+
+func NewReceivablesAgingService(repo repositories.ReceivablesRepository, distributorRepo repositories.DistributorRepository,
+	calendarRepo repositories.TenantCalendarRepository) *ReceivablesAgingService {
+	return &ReceivablesAgingService{repo: repo, distributorRepo: distributorRepo, calendarRepo: calendarRepo}
+}
+
+// now is the current time in the tenant's timezone, so calendar days roll
+// over at the tenant's midnight
+func (s *ReceivablesAgingService) now(ctx context.Context, tenantID uuid.UUID) time.Time {
+	calendar := models.DefaultTenantCalendar(tenantID)
+	if s.calendarRepo != nil {
+		if configured, err := s.calendarRepo.Get(ctx, tenantID); err == nil && configured != nil {
+			calendar = configured
+		}
+	}
+	return calendar.Now(time.Now())
+}
+
+// GetReceivablesAging groups open invoice balances by customer and bucket as of now
+func (s *ReceivablesAgingService) GetReceivablesAging(ctx context.Context, tenantID uuid.UUID) (*models.ReceivablesAgingReport, error) {
+	invoices, err := s.repo.OpenInvoices(ctx, tenantID, nil)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now(ctx, tenantID)
+	return buildReceivablesAging(ageCustomers(invoices, now), models.AnalyticsSourceLive, now), nil
+}
+
+// GetCustomerAging drills a customer's aging down to their open invoices
+func (s *ReceivablesAgingService) GetCustomerAging(ctx context.Context, tenantID, distributorID uuid.UUID) (*models.ReceivablesAgingDetail, error) {
+	distributor, err := s.distributorRepo.GetByID(ctx, tenantID, distributorID)
+	if err != nil || distributor == nil {
+		return nil, ErrCustomerNotFound
+	}
+	invoices, err := s.repo.OpenInvoices(ctx, tenantID, &distributorID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now(ctx, tenantID)
+	detail := &models.ReceivablesAgingDetail{
+		AsOf:     now,
+		Customer: models.ReceivablesAgingRow{DistributorID: distributor.ID, DistributorName: distributor.Name},
+		Invoices: []*models.ReceivableInvoice{},
+	}
+	if customers := ageCustomers(invoices, now); len(customers) == 1 {
+		detail.Customer = *customers[0]
+	}
+	detail.Invoices = append(detail.Invoices, invoices...)
+	return detail, nil
+}
+
+// GetSummary is the dashboard widget feed: bucket totals across customers and
+// the top customers by balance
+func (s *ReceivablesAgingService) GetSummary(ctx context.Context, tenantID uuid.UUID, top int) (*models.ReceivablesAgingSummary, error) {
+	invoices, err := s.repo.OpenInvoices(ctx, tenantID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if top <= 0 {
+		top = DefaultTopCustomers
+	}
+	return summarizeReceivables(invoices, top, s.now(ctx, tenantID)), nil
+}
+
+// receivablesBucket places an invoice by days past its due date
+func receivablesBucket(daysPastDue int) string {
+	switch {
+	case daysPastDue <= 0:
+		return models.ReceivablesBucketCurrent
+	case daysPastDue <= 30:
+		return models.ReceivablesBucket1To30
+	case daysPastDue <= 60:
+		return models.ReceivablesBucket31To60
+	case daysPastDue <= 90:
+		return models.ReceivablesBucket61To90
+	default:
+		return models.ReceivablesBucketOver90
+	}
+}
+
+// daysPastDue counts whole calendar days from the due date to asOf
+func daysPastDue(due, asOf time.Time) int {
+	dueDay := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+	asOfDay := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	return int(asOfDay.Sub(dueDay).Hours() / 24)
+}
+
+// addToBucket adds an amount to the row's bucket and total
+func addToBucket(row *models.ReceivablesAgingRow, bucket string, amount float64) {
+	switch bucket {
+	case models.ReceivablesBucketCurrent:
+		row.Current += amount
+	case models.ReceivablesBucket1To30:
+		row.Days1To30 += amount
+	case models.ReceivablesBucket31To60:
+		row.Days31To60 += amount
+	case models.ReceivablesBucket61To90:
+		row.Days61To90 += amount
+	default:
+		row.DaysOver90 += amount
+	}
+	row.Total += amount
+}
+
+// ageCustomers buckets each invoice, filling in its days past due and bucket,
+// and totals them per customer, largest balance first
+func ageCustomers(invoices []*models.ReceivableInvoice, asOf time.Time) []*models.ReceivablesAgingRow {
+	byCustomer := make(map[uuid.UUID]*models.ReceivablesAgingRow)
+	var customers []*models.ReceivablesAgingRow
+	for _, inv := range invoices {
+		inv.DaysPastDue = daysPastDue(inv.DueDate, asOf)
+		inv.Bucket = receivablesBucket(inv.DaysPastDue)
+
+		row, ok := byCustomer[inv.DistributorID]
+		if !ok {
+			row = &models.ReceivablesAgingRow{DistributorID: inv.DistributorID, DistributorName: inv.DistributorName}
+			byCustomer[inv.DistributorID] = row
+			customers = append(customers, row)
+		}
+		row.InvoiceCount++
+		addToBucket(row, inv.Bucket, inv.Outstanding)
+	}
+
+	for _, row := range customers {
+		for _, v := range []*float64{&row.Current, &row.Days1To30, &row.Days31To60, &row.Days61To90, &row.DaysOver90, &row.Total} {
+			*v = roundMoney(*v)
+		}
+	}
+	sort.SliceStable(customers, func(i, j int) bool {
+		if customers[i].Total != customers[j].Total {
+			return customers[i].Total > customers[j].Total
+		}
+		return customers[i].DistributorName < customers[j].DistributorName
+	})
+	return customers
+}
+
+func summarizeReceivables(invoices []*models.ReceivableInvoice, top int, asOf time.Time) *models.ReceivablesAgingSummary {
+	customers := ageCustomers(invoices, asOf)
+	summary := &models.ReceivablesAgingSummary{
+		AsOf:          asOf,
+		CustomerCount: len(customers),
+		Buckets:       make([]*models.ReceivablesBucketTotal, 0, len(receivablesBuckets)),
+		TopCustomers:  []*models.ReceivablesAgingRow{},
+	}
+
+	totals := make(map[string]*models.ReceivablesBucketTotal, len(receivablesBuckets))
+	for _, bucket := range receivablesBuckets {
+		totals[bucket] = &models.ReceivablesBucketTotal{Bucket: bucket}
+		summary.Buckets = append(summary.Buckets, totals[bucket])
+	}
+	for _, inv := range invoices {
+		totals[inv.Bucket].InvoiceCount++
+		totals[inv.Bucket].Amount += inv.Outstanding
+		summary.TotalOutstanding += inv.Outstanding
+		if inv.Bucket != models.ReceivablesBucketCurrent {
+			summary.Overdue += inv.Outstanding
+		}
+	}
+
+	summary.TotalOutstanding = roundMoney(summary.TotalOutstanding)
+	summary.Overdue = roundMoney(summary.Overdue)
+	summary.OverdueShare = share(summary.Overdue, summary.TotalOutstanding)
+	for _, bucket := range summary.Buckets {
+		bucket.Amount = roundMoney(bucket.Amount)
+		bucket.Share = share(bucket.Amount, summary.TotalOutstanding)
+	}
+	if len(customers) > top {
+		customers = customers[:top]
+	}
+	summary.TopCustomers = append(summary.TopCustomers, customers...)
+	return summary
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// ReceivablesAgingCSV renders the report one customer per row with a totals row
+func ReceivablesAgingCSV(report *models.ReceivablesAgingReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"Customer", "Invoices", "Current", "1-30", "31-60", "61-90", "90+", "Total"})
+	rows := append(append([]*models.ReceivablesAgingRow{}, report.Distributors...), &report.Totals)
+	for _, row := range rows {
+		w.Write([]string{row.DistributorName, strconv.Itoa(row.InvoiceCount), formatAmount(row.Current), formatAmount(row.Days1To30),
+			formatAmount(row.Days31To60), formatAmount(row.Days61To90), formatAmount(row.DaysOver90), formatAmount(row.Total)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// ReceivableInvoicesCSV renders a customer's open invoices one per row
+func ReceivableInvoicesCSV(detail *models.ReceivablesAgingDetail) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"Customer", "Invoice Number", "Issued Date", "Due Date", "Days Past Due", "Bucket", "Invoice Total",
+		"Interest", "Paid", "Outstanding"})
+	for _, inv := range detail.Invoices {
+		w.Write([]string{inv.DistributorName, inv.InvoiceNumber, inv.IssuedDate.Format("2006-01-02"), inv.DueDate.Format("2006-01-02"),
+			strconv.Itoa(inv.DaysPastDue), inv.Bucket, formatAmount(inv.TotalAmount), formatAmount(inv.InterestAccrued),
+			formatAmount(inv.Allocated), formatAmount(inv.Outstanding)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func receivable(customer uuid.UUID, name string, due time.Time, outstanding float64) *models.ReceivableInvoice {
+	inv := &models.ReceivableInvoice{DistributorID: customer, DistributorName: name}
+	inv.DueDate = due
+	inv.Outstanding = outstanding
+	return inv
+}
+
+func TestReceivablesBucketBoundaries(t *testing.T) {
+	assert.Equal(t, models.ReceivablesBucketCurrent, receivablesBucket(-5))
+	assert.Equal(t, models.ReceivablesBucketCurrent, receivablesBucket(0))
+	assert.Equal(t, models.ReceivablesBucket1To30, receivablesBucket(1))
+	assert.Equal(t, models.ReceivablesBucket1To30, receivablesBucket(30))
+	assert.Equal(t, models.ReceivablesBucket31To60, receivablesBucket(31))
+	assert.Equal(t, models.ReceivablesBucket61To90, receivablesBucket(90))
+	assert.Equal(t, models.ReceivablesBucketOver90, receivablesBucket(91))
+}
+
+func TestDaysPastDueIgnoresTimeOfDay(t *testing.T) {
+	due := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 0, daysPastDue(due, time.Date(2025, 8, 1, 23, 59, 0, 0, time.UTC)))
+	assert.Equal(t, 31, daysPastDue(due, time.Date(2025, 9, 1, 0, 30, 0, 0, time.UTC)))
+	assert.Equal(t, -1, daysPastDue(due, time.Date(2025, 7, 31, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestAgeCustomersTotalsByBucket(t *testing.T) {
+	asOf := time.Date(2025, 9, 2, 10, 0, 0, 0, time.UTC)
+	ravi, kisan := uuid.New(), uuid.New()
+	invoices := []*models.ReceivableInvoice{
+		receivable(ravi, "Ravi Agro", asOf.AddDate(0, 0, -100), 500),
+		receivable(kisan, "Kisan Traders", asOf.AddDate(0, 0, -45), 1200.50),
+		receivable(ravi, "Ravi Agro", asOf.AddDate(0, 0, 10), 250.25),
+	}
+
+	customers := ageCustomers(invoices, asOf)
+	assert.Len(t, customers, 2)
+	assert.Equal(t, kisan, customers[0].DistributorID)
+	assert.Equal(t, 1200.50, customers[0].Days31To60)
+
+	assert.Equal(t, ravi, customers[1].DistributorID)
+	assert.Equal(t, 2, customers[1].InvoiceCount)
+	assert.Equal(t, 500.0, customers[1].DaysOver90)
+	assert.Equal(t, 250.25, customers[1].Current)
+	assert.Equal(t, 750.25, customers[1].Total)
+
+	assert.Equal(t, 100, invoices[0].DaysPastDue)
+	assert.Equal(t, models.ReceivablesBucketOver90, invoices[0].Bucket)
+	assert.Equal(t, models.ReceivablesBucketCurrent, invoices[2].Bucket)
+}
+
+func TestSummarizeReceivables(t *testing.T) {
+	asOf := time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC)
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	invoices := []*models.ReceivableInvoice{
+		receivable(a, "A", asOf.AddDate(0, 0, 5), 600),
+		receivable(b, "B", asOf.AddDate(0, 0, -20), 300),
+		receivable(c, "C", asOf.AddDate(0, 0, -70), 100),
+	}
+
+	summary := summarizeReceivables(invoices, 2, asOf)
+	assert.Equal(t, 1000.0, summary.TotalOutstanding)
+	assert.Equal(t, 400.0, summary.Overdue)
+	assert.Equal(t, 40.0, summary.OverdueShare)
+	assert.Equal(t, 3, summary.CustomerCount)
+	assert.Len(t, summary.Buckets, 5)
+	assert.Equal(t, models.ReceivablesBucketCurrent, summary.Buckets[0].Bucket)
+	assert.Equal(t, 60.0, summary.Buckets[0].Share)
+	assert.Equal(t, 300.0, summary.Buckets[1].Amount)
+	assert.Equal(t, 0.0, summary.Buckets[2].Amount)
+	assert.Equal(t, 100.0, summary.Buckets[3].Amount)
+
+	assert.Len(t, summary.TopCustomers, 2)
+	assert.Equal(t, a, summary.TopCustomers[0].DistributorID)
+	assert.Equal(t, b, summary.TopCustomers[1].DistributorID)
+}
+
+func TestReceivablesAgingCSV(t *testing.T) {
+	report := buildReceivablesAging([]*models.ReceivablesAgingRow{
+		{DistributorName: "Ravi Agro", InvoiceCount: 1, Days1To30: 99.5, Total: 99.5},
+	}, models.AnalyticsSourceLive, time.Now())
+
+	content, err := ReceivablesAgingCSV(report)
+	assert.NoError(t, err)
+	assert.Equal(t, "Customer,Invoices,Current,1-30,31-60,61-90,90+,Total\n"+
+		"Ravi Agro,1,0.00,99.50,0.00,0.00,0.00,99.50\n"+
+		"Total,1,0.00,99.50,0.00,0.00,0.00,99.50\n", string(content))
+}
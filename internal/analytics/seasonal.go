@@ -0,0 +1,320 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// SeasonalDemandService reports seasonally adjusted demand and pre-season
+// stocking suggestions from the tenant's season calendar and sales history
+type SeasonalDemandService struct {
+	seasonRepo    repositories.SeasonRepository
+	inventoryRepo repositories.InventoryRepository
+	productRepo   repositories.ProductRepository
+	minimumRepo   repositories.StockMinimumRepository
+	quotes        *SupplierQuoteService
+}
+
+// NewSeasonalDemandService creates the service; quotes may be nil, in which
+// case stocking suggestions carry no preferred supplier, and minimumRepo may
+// be nil, in which case they only cover demand
+func NewSeasonalDemandService(seasonRepo repositories.SeasonRepository, inventoryRepo repositories.InventoryRepository, productRepo repositories.ProductRepository, minimumRepo repositories.StockMinimumRepository, quotes *SupplierQuoteService) *SeasonalDemandService {
+	return &SeasonalDemandService{
+		seasonRepo:    seasonRepo,
+		inventoryRepo: inventoryRepo,
+		productRepo:   productRepo,
+		minimumRepo:   minimumRepo,
+		quotes:        quotes,
+	}
+}
+
+// GetSeasonalDemand builds a monthly seasonal index from the last `years` of
+// sales and uses it to adjust the last twelve months of demand
+func (s *SeasonalDemandService) GetSeasonalDemand(ctx context.Context, tenantID, productID uuid.UUID, years int) (*models.SeasonalDemand, error) {
+	if years <= 0 {
+		years = 3
+	}
+	if _, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	now := time.Now()
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	since := firstOfMonth.AddDate(-years, 0, 0)
+
+	sales, err := s.seasonRepo.MonthlySales(ctx, tenantID, productID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	monthly := fillMonths(sales, since, firstOfMonth.AddDate(0, 1, 0))
+	index := seasonalIndex(monthly)
+
+	report := &models.SeasonalDemand{
+		ProductID:     productID,
+		Years:         years,
+		SeasonalIndex: index,
+	}
+
+	recent := monthly
+	if len(recent) > 12 {
+		recent = recent[len(recent)-12:]
+	}
+	for _, m := range recent {
+		adjusted := float64(m.Units)
+		if idx := index[m.Month]; idx > 0 {
+			adjusted = float64(m.Units) / idx
+		}
+		report.Monthly = append(report.Monthly, &models.SeasonalMonth{
+			Year:          m.Year,
+			Month:         m.Month,
+			Units:         m.Units,
+			AdjustedUnits: math.Round(adjusted*100) / 100,
+		})
+	}
+
+	seasons, err := s.seasonRepo.ListProductSeasons(ctx, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(seasons) == 0 {
+		if seasons, err = s.seasonRepo.List(ctx, tenantID); err != nil {
+			return nil, err
+		}
+	}
+	for _, season := range seasons {
+		avg, err := s.averageSeasonDemand(ctx, tenantID, productID, season, years, now)
+		if err != nil {
+			return nil, err
+		}
+		report.Seasons = append(report.Seasons, &models.SeasonDemandTotal{
+			SeasonID:     season.ID,
+			Name:         season.Name,
+			AverageUnits: math.Round(avg*100) / 100,
+		})
+	}
+
+	return report, nil
+}
+
+// GetStockingSuggestions lists products tagged with seasons starting within
+// horizonDays (or each season's own lead time when horizonDays is 0) and the
+// stock needed to cover their average demand in past seasons and still leave
+// their stock minimums on hand
+func (s *SeasonalDemandService) GetStockingSuggestions(ctx context.Context, tenantID uuid.UUID, horizonDays, years int) ([]*models.StockingSuggestion, error) {
+	if years <= 0 {
+		years = 3
+	}
+
+	seasons, err := s.seasonRepo.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var suggestions []*models.StockingSuggestion
+	for _, season := range seasons {
+		lead := horizonDays
+		if lead <= 0 {
+			lead = season.LeadDays
+		}
+		start := nextSeasonStart(season, now)
+		if start.After(now.AddDate(0, 0, lead)) {
+			continue
+		}
+
+		productIDs, err := s.seasonRepo.ListSeasonProducts(ctx, tenantID, season.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, productID := range productIDs {
+			product, err := s.productRepo.GetByID(ctx, tenantID, productID)
+			if err != nil {
+				continue
+			}
+
+			avg, err := s.averageSeasonDemand(ctx, tenantID, productID, season, years, now)
+			if err != nil {
+				return nil, err
+			}
+
+			inventories, err := s.inventoryRepo.ListByProduct(ctx, tenantID, productID)
+			if err != nil {
+				return nil, err
+			}
+			stock := 0
+			for _, inv := range inventories {
+				stock += inv.Quantity
+			}
+
+			minimum := 0
+			if s.minimumRepo != nil {
+				configured, err := s.minimumRepo.ListByProduct(ctx, tenantID, productID)
+				if err != nil {
+					return nil, err
+				}
+				minimum = minimumStock(configured, productID, inventories)
+			}
+
+			expected := int(math.Ceil(avg))
+			suggested := expected + minimum - stock
+			if suggested < 0 {
+				suggested = 0
+			}
+
+			suggestion := &models.StockingSuggestion{
+				SeasonID:       season.ID,
+				SeasonName:     season.Name,
+				SeasonStart:    start,
+				ProductID:      productID,
+				ProductName:    product.Name,
+				ExpectedDemand: expected,
+				CurrentStock:   stock,
+				MinimumStock:   minimum,
+				SuggestedOrder: suggested,
+			}
+			if suggested > 0 && s.quotes != nil {
+				comparison, err := s.quotes.Compare(ctx, tenantID, productID, suggested, now)
+				if err != nil {
+					log.Printf("Failed to compare supplier quotes for product %s: %v", productID, err)
+				} else {
+					suggestion.PreferredSupplier = comparison.Recommended
+				}
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if !suggestions[i].SeasonStart.Equal(suggestions[j].SeasonStart) {
+			return suggestions[i].SeasonStart.Before(suggestions[j].SeasonStart)
+		}
+		return suggestions[i].SuggestedOrder > suggestions[j].SuggestedOrder
+	})
+
+	return suggestions, nil
+}
+
+// minimumStock totals the product's stock minimums over the warehouses that
+// stock it or have a minimum of their own; a product-wide minimum counts once
+// when no warehouse has the product yet
+func minimumStock(configured []*models.StockMinimum, productID uuid.UUID, inventories []*models.Inventory) int {
+	minimums := models.NewStockMinimums(configured)
+	warehouses := map[uuid.UUID]bool{}
+	for _, inv := range inventories {
+		warehouses[inv.WarehouseID] = true
+	}
+	for _, minimum := range configured {
+		if minimum.WarehouseID != nil {
+			warehouses[*minimum.WarehouseID] = true
+		}
+	}
+	if len(warehouses) == 0 {
+		minimum, _ := minimums.For(productID, uuid.Nil)
+		return minimum
+	}
+
+	total := 0
+	for warehouseID := range warehouses {
+		if minimum, ok := minimums.For(productID, warehouseID); ok {
+			total += minimum
+		}
+	}
+	return total
+}
+
+// averageSeasonDemand averages units sold over the last `years` completed occurrences of the season
+func (s *SeasonalDemandService) averageSeasonDemand(ctx context.Context, tenantID, productID uuid.UUID, season *models.Season, years int, now time.Time) (float64, error) {
+	total := 0
+	counted := 0
+	for year := now.Year(); counted < years && year > now.Year()-years-2; year-- {
+		from, to := seasonWindow(season, year, now.Location())
+		if to.After(now) {
+			continue
+		}
+		units, err := s.seasonRepo.SalesBetween(ctx, tenantID, productID, from, to)
+		if err != nil {
+			return 0, err
+		}
+		total += units
+		counted++
+	}
+	if counted == 0 {
+		return 0, nil
+	}
+	return float64(total) / float64(counted), nil
+}
+
+// seasonWindow returns the [start, end) range of the season that starts in the given year
+func seasonWindow(season *models.Season, year int, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(year, time.Month(season.StartMonth), season.StartDay, 0, 0, 0, 0, loc)
+	endYear := year
+	if season.EndMonth < season.StartMonth || (season.EndMonth == season.StartMonth && season.EndDay < season.StartDay) {
+		endYear++
+	}
+	end := time.Date(endYear, time.Month(season.EndMonth), season.EndDay, 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	return start, end
+}
+
+// nextSeasonStart returns the first start of the season on or after from
+func nextSeasonStart(season *models.Season, from time.Time) time.Time {
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	start, _ := seasonWindow(season, from.Year(), from.Location())
+	if start.Before(day) {
+		start, _ = seasonWindow(season, from.Year()+1, from.Location())
+	}
+	return start
+}
+
+// fillMonths returns one entry per month in [from, to), with zero units for months without sales
+func fillMonths(sales []*models.MonthlyDemand, from, to time.Time) []*models.MonthlyDemand {
+	byMonth := make(map[[2]int]int, len(sales))
+	for _, d := range sales {
+		byMonth[[2]int{d.Year, d.Month}] = d.Units
+	}
+
+	var months []*models.MonthlyDemand
+	for m := from; m.Before(to); m = m.AddDate(0, 1, 0) {
+		months = append(months, &models.MonthlyDemand{
+			Year:  m.Year(),
+			Month: int(m.Month()),
+			Units: byMonth[[2]int{m.Year(), int(m.Month())}],
+		})
+	}
+	return months
+}
+
+// seasonalIndex is each calendar month's average demand relative to the
+// average month; with no sales every month has an index of 1
+func seasonalIndex(months []*models.MonthlyDemand) map[int]float64 {
+	var sums, counts [13]float64
+	total := 0.0
+	for _, m := range months {
+		sums[m.Month] += float64(m.Units)
+		counts[m.Month]++
+		total += float64(m.Units)
+	}
+
+	index := make(map[int]float64, 12)
+	overall := 0.0
+	if len(months) > 0 {
+		overall = total / float64(len(months))
+	}
+	for month := 1; month <= 12; month++ {
+		if overall == 0 || counts[month] == 0 {
+			index[month] = 1
+			continue
+		}
+		index[month] = math.Round(sums[month]/counts[month]/overall*100) / 100
+	}
+	return index
+}
@@ -0,0 +1,74 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeasonWindowWrapsYearEnd(t *testing.T) {
+	rabi := &models.Season{StartMonth: 10, StartDay: 15, EndMonth: 3, EndDay: 31}
+
+	start, end := seasonWindow(rabi, 2024, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), end)
+}
+
+func TestNextSeasonStart(t *testing.T) {
+	kharif := &models.Season{StartMonth: 6, StartDay: 1, EndMonth: 10, EndDay: 31}
+
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		nextSeasonStart(kharif, time.Date(2025, 5, 10, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		nextSeasonStart(kharif, time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		nextSeasonStart(kharif, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestSeasonalIndex(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	months := fillMonths([]*models.MonthlyDemand{
+		{Year: 2023, Month: 6, Units: 120},
+		{Year: 2024, Month: 6, Units: 120},
+	}, from, from.AddDate(2, 0, 0))
+
+	index := seasonalIndex(months)
+
+	assert.Len(t, months, 24)
+	assert.Equal(t, 12.0, index[6])
+	assert.Equal(t, 0.0, index[1])
+}
+
+func TestSeasonalIndexWithoutSales(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	index := seasonalIndex(fillMonths(nil, from, from.AddDate(1, 0, 0)))
+
+	for month := 1; month <= 12; month++ {
+		assert.Equal(t, 1.0, index[month])
+	}
+}
+
+func TestMinimumStockPrefersWarehouseMinimums(t *testing.T) {
+	productID := uuid.New()
+	stocked, overridden, empty := uuid.New(), uuid.New(), uuid.New()
+	configured := []*models.StockMinimum{
+		{ProductID: productID, MinQuantity: 10},
+		{ProductID: productID, WarehouseID: &overridden, MinQuantity: 25},
+		{ProductID: productID, WarehouseID: &empty, MinQuantity: 5},
+	}
+	inventories := []*models.Inventory{
+		{ProductID: productID, WarehouseID: stocked, Quantity: 40},
+		{ProductID: productID, WarehouseID: overridden, Quantity: 3},
+	}
+
+	// 10 product-wide in the stocked warehouse, 25 and 5 where overridden
+	assert.Equal(t, 40, minimumStock(configured, productID, inventories))
+	// Not stocked anywhere, the product-wide minimum counts once
+	assert.Equal(t, 10, minimumStock(configured[:1], productID, nil))
+	assert.Equal(t, 0, minimumStock(nil, productID, inventories))
+}
@@ -20,7 +20,12 @@ type AnalyticsService struct {
 	invoiceRepo   repositories.InvoiceRepository
 	inventoryRepo repositories.InventoryRepository
 	productRepo   repositories.ProductRepository
+	consignmentRepo repositories.ConsignmentRepository
 	cacheService  caching.CacheService
+	viewRepo      repositories.AnalyticsViewRepository
+	// staleTolerance is how old a materialized view may be before reports
+	// fall back to live queries
+	staleTolerance time.Duration
 }
 
 // AnalyticsData represents cached analytics
@@ -78,13 +83,16 @@ type TimeUsage struct {
 	Count int
 }
 
-func NewAnalyticsService(orderRepo repositories.OrderRepository, invoiceRepo repositories.InvoiceRepository, inventoryRepo repositories.InventoryRepository, productRepo repositories.ProductRepository, cacheService caching.CacheService) *AnalyticsService {
+func NewAnalyticsService(orderRepo repositories.OrderRepository, invoiceRepo repositories.InvoiceRepository, inventoryRepo repositories.InventoryRepository, productRepo repositories.ProductRepository, consignmentRepo repositories.ConsignmentRepository, cacheService caching.CacheService, viewRepo repositories.AnalyticsViewRepository, staleTolerance time.Duration) *AnalyticsService {
 	return &AnalyticsService{
 		orderRepo:     orderRepo,
 		invoiceRepo:   invoiceRepo,
 		inventoryRepo: inventoryRepo,
 		productRepo:   productRepo,
+		consignmentRepo: consignmentRepo,
 		cacheService:  cacheService,
+		viewRepo:      viewRepo,
+		staleTolerance: staleTolerance,
 	}
 }
 
@@ -127,6 +135,18 @@ func (a *AnalyticsService) CalculateTenantAnalytics(ctx context.Context, tenantI
 		return data, err
 	}
 
+	// Consignment units are supplier-owned and left out of stock value
+	consigned := make(map[[2]uuid.UUID]int)
+	if a.consignmentRepo != nil {
+		stock, err := a.consignmentRepo.ListStock(ctx, tenantID, nil)
+		if err != nil {
+			log.Printf("Failed to get consignment stock for analytics: %v", err)
+		}
+		for _, cs := range stock {
+			consigned[[2]uuid.UUID{cs.WarehouseID, cs.ProductID}] += cs.Quantity
+		}
+	}
+
 	var totalStockValue float64
 	lowStockCount := 0
 	for _, inv := range inventories {
@@ -134,13 +154,18 @@ func (a *AnalyticsService) CalculateTenantAnalytics(ctx context.Context, tenantI
 			lowStockCount++
 		}
 
+		owned := inv.Quantity - consigned[[2]uuid.UUID{inv.WarehouseID, inv.ProductID}]
+		if owned <= 0 {
+			continue
+		}
+
 		// Get product price to calculate stock value
 		product, err := a.productRepo.GetByID(ctx, tenantID, inv.ProductID)
 		if err != nil {
 			log.Printf("Failed to get product %s: %v", inv.ProductID.String(), err)
 			continue
 		}
-		totalStockValue += float64(inv.Quantity) * product.UnitPrice
+		totalStockValue += float64(owned) * product.UnitPrice
 	}
 
 	data.TotalStockValue = totalStockValue
@@ -149,8 +174,22 @@ func (a *AnalyticsService) CalculateTenantAnalytics(ctx context.Context, tenantI
 	return data, nil
 }
 
+// GetSalesTrends reads daily sales through the materialized view when the
+// service has one, and groups the orders in range itself otherwise
 func (a *AnalyticsService) GetSalesTrends(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]SalesTrend, error) {
-	orders, err := a.orderRepo.GetOrdersByTenantAndDateRange(ctx, tenantID, startDate, endDate)
+	if a.viewRepo != nil {
+		report, err := a.DailySales(ctx, tenantID, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]SalesTrend, 0, len(report.Days))
+		for _, day := range report.Days {
+			result = append(result, SalesTrend{Date: day.Date, SalesAmount: day.SalesAmount, OrderCount: day.OrderCount})
+		}
+		return result, nil
+	}
+
+	orders, err := a.orderRepo.GetOrdersByTenantAndDateRange(ctx, tenantID, nil, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
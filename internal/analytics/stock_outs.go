@@ -0,0 +1,126 @@
+package analytics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// StockOutService turns logged stock-outs and sales demand into fill rate and
+// lost sales KPIs, so purchasing can put a number on under-stocking
+type StockOutService struct {
+	repo repositories.StockOutRepository
+}
+
+func NewStockOutService(repo repositories.StockOutRepository) *StockOutService {
+	return &StockOutService{repo: repo}
+}
+
+// GetReport is fill rate and lost sales per product and warehouse for
+// [from, to), worst lost sales first
+func (s *StockOutService) GetReport(ctx context.Context, tenantID uuid.UUID, from, to time.Time, productID, warehouseID *uuid.UUID) (*models.StockOutReport, error) {
+	demand, err := s.repo.Demand(ctx, tenantID, from, to, productID, warehouseID, "month")
+	if err != nil {
+		return nil, err
+	}
+	rows, totals := stockOutsByProduct(demand)
+	return &models.StockOutReport{From: from, To: to, Rows: rows, Totals: *totals}, nil
+}
+
+// GetTrend is fill rate and lost sales per week or month for [from, to)
+func (s *StockOutService) GetTrend(ctx context.Context, tenantID uuid.UUID, from, to time.Time, interval string, productID, warehouseID *uuid.UUID) (*models.StockOutTrend, error) {
+	demand, err := s.repo.Demand(ctx, tenantID, from, to, productID, warehouseID, interval)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StockOutTrend{From: from, To: to, Interval: interval, Periods: stockOutsByPeriod(demand)}, nil
+}
+
+// ListEvents lists the stock-outs behind the KPIs, newest first
+func (s *StockOutService) ListEvents(ctx context.Context, tenantID uuid.UUID, from, to time.Time, productID, warehouseID *uuid.UUID, outcome string, limit, offset int) ([]*models.StockOutEvent, error) {
+	return s.repo.ListEvents(ctx, tenantID, from, to, productID, warehouseID, outcome, limit, offset)
+}
+
+// addDemand adds a demand row to the KPI. Demand is what was ordered plus what
+// was turned away; the shortfall is what was turned away plus what had to
+// wait for stock
+func addDemand(kpi *models.StockOutKPI, d *models.StockOutDemandRow) {
+	kpi.DemandedUnits += d.OrderedUnits + d.RejectedUnits
+	kpi.ShortUnits += d.RejectedUnits + d.BackorderedUnits
+	kpi.RejectedRequests += d.RejectedRequests
+	kpi.BackorderedOrders += d.BackorderedOrders
+	kpi.LostSalesValue += d.RejectedValue + d.CancelledBackorder
+}
+
+// finishKPI works out filled units and fill rate and rounds the lost sales
+func finishKPI(kpi *models.StockOutKPI) {
+	if kpi.ShortUnits > kpi.DemandedUnits {
+		kpi.ShortUnits = kpi.DemandedUnits
+	}
+	kpi.FilledUnits = kpi.DemandedUnits - kpi.ShortUnits
+	kpi.FillRate = share(float64(kpi.FilledUnits), float64(kpi.DemandedUnits))
+	kpi.StockOuts = kpi.RejectedRequests + kpi.BackorderedOrders
+	kpi.LostSalesValue = roundMoney(kpi.LostSalesValue)
+}
+
+// stockOutsByProduct rolls demand up per product and warehouse across
+// periods, worst lost sales and then lowest fill rate first, with totals
+func stockOutsByProduct(demand []*models.StockOutDemandRow) ([]*models.StockOutKPI, *models.StockOutKPI) {
+	totals := &models.StockOutKPI{}
+	byKey := make(map[[2]uuid.UUID]*models.StockOutKPI)
+	rows := []*models.StockOutKPI{}
+	for _, d := range demand {
+		key := [2]uuid.UUID{d.ProductID, d.WarehouseID}
+		kpi, ok := byKey[key]
+		if !ok {
+			productID, warehouseID := d.ProductID, d.WarehouseID
+			kpi = &models.StockOutKPI{ProductID: &productID, ProductName: d.ProductName, WarehouseID: &warehouseID, WarehouseName: d.WarehouseName}
+			byKey[key] = kpi
+			rows = append(rows, kpi)
+		}
+		addDemand(kpi, d)
+		addDemand(totals, d)
+	}
+	for _, kpi := range rows {
+		finishKPI(kpi)
+	}
+	finishKPI(totals)
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].LostSalesValue != rows[j].LostSalesValue {
+			return rows[i].LostSalesValue > rows[j].LostSalesValue
+		}
+		if rows[i].FillRate != rows[j].FillRate {
+			return rows[i].FillRate < rows[j].FillRate
+		}
+		return rows[i].ProductName < rows[j].ProductName
+	})
+	return rows, totals
+}
+
+// stockOutsByPeriod rolls demand up per period, oldest first. Periods with no
+// demand at all are left out
+func stockOutsByPeriod(demand []*models.StockOutDemandRow) []*models.StockOutKPI {
+	byPeriod := make(map[time.Time]*models.StockOutKPI)
+	periods := []*models.StockOutKPI{}
+	for _, d := range demand {
+		kpi, ok := byPeriod[d.Period]
+		if !ok {
+			period := d.Period
+			kpi = &models.StockOutKPI{Period: &period}
+			byPeriod[d.Period] = kpi
+			periods = append(periods, kpi)
+		}
+		addDemand(kpi, d)
+	}
+	for _, kpi := range periods {
+		finishKPI(kpi)
+	}
+	sort.SliceStable(periods, func(i, j int) bool { return periods[i].Period.Before(*periods[j].Period) })
+	return periods
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockOutsByProductFillRateAndLostSales(t *testing.T) {
+	urea, dap, warehouse := uuid.New(), uuid.New(), uuid.New()
+	aug := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	sep := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	demand := []*models.StockOutDemandRow{
+		{ProductID: urea, ProductName: "Urea", WarehouseID: warehouse, Period: aug, OrderedUnits: 80, Orders: 4,
+			RejectedUnits: 20, RejectedRequests: 1, RejectedValue: 6000},
+		{ProductID: urea, ProductName: "Urea", WarehouseID: warehouse, Period: sep, OrderedUnits: 50, Orders: 2,
+			BackorderedUnits: 10, BackorderedOrders: 1, CancelledBackorder: 3000},
+		{ProductID: dap, ProductName: "DAP", WarehouseID: warehouse, Period: sep, OrderedUnits: 40, Orders: 2},
+	}
+
+	rows, totals := stockOutsByProduct(demand)
+	assert.Len(t, rows, 2)
+
+	first := rows[0]
+	assert.Equal(t, urea, *first.ProductID)
+	assert.Equal(t, 150, first.DemandedUnits)
+	assert.Equal(t, 30, first.ShortUnits)
+	assert.Equal(t, 120, first.FilledUnits)
+	assert.Equal(t, 80.0, first.FillRate)
+	assert.Equal(t, 2, first.StockOuts)
+	assert.Equal(t, 9000.0, first.LostSalesValue)
+
+	assert.Equal(t, 100.0, rows[1].FillRate)
+	assert.Equal(t, 190, totals.DemandedUnits)
+	assert.Equal(t, 84.21, totals.FillRate)
+}
+
+func TestStockOutsByPeriodOrdersPeriods(t *testing.T) {
+	product, warehouse := uuid.New(), uuid.New()
+	week1 := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	week2 := week1.AddDate(0, 0, 7)
+	demand := []*models.StockOutDemandRow{
+		{ProductID: product, WarehouseID: warehouse, Period: week2, OrderedUnits: 10},
+		{ProductID: product, WarehouseID: warehouse, Period: week1, OrderedUnits: 10, RejectedUnits: 10, RejectedRequests: 1},
+		{ProductID: uuid.New(), WarehouseID: warehouse, Period: week1, OrderedUnits: 20},
+	}
+
+	periods := stockOutsByPeriod(demand)
+	assert.Len(t, periods, 2)
+	assert.Equal(t, week1, *periods[0].Period)
+	assert.Equal(t, 40, periods[0].DemandedUnits)
+	assert.Equal(t, 75.0, periods[0].FillRate)
+	assert.Equal(t, 100.0, periods[1].FillRate)
+}
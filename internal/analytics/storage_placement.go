@@ -0,0 +1,63 @@
+package analytics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// StoragePlacementService reports stock held in warehouses that lack the
+// storage conditions its product needs
+type StoragePlacementService struct {
+	storageRepo repositories.StorageConditionRepository
+}
+
+func NewStoragePlacementService(storageRepo repositories.StorageConditionRepository) *StoragePlacementService {
+	return &StoragePlacementService{storageRepo: storageRepo}
+}
+
+// NonCompliantPlacements builds the report as of now
+func (s *StoragePlacementService) NonCompliantPlacements(ctx context.Context, tenantID uuid.UUID) (*models.NonCompliantPlacementReport, error) {
+	placements, err := s.storageRepo.Placements(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return buildPlacementReport(placements, time.Now()), nil
+}
+
+func buildPlacementReport(placements []*models.StoragePlacement, now time.Time) *models.NonCompliantPlacementReport {
+	report := &models.NonCompliantPlacementReport{
+		AsOf:        now,
+		Placements:  []*models.NonCompliantPlacement{},
+		ByCondition: map[string]int{},
+	}
+	for _, placement := range placements {
+		unmet := placement.Requirement.Unmet(placement.Capability)
+		if len(unmet) == 0 {
+			continue
+		}
+		report.Placements = append(report.Placements, &models.NonCompliantPlacement{
+			StoragePlacement: *placement,
+			Unmet:            unmet,
+		})
+		report.TotalQuantity += placement.Quantity
+		for _, condition := range unmet {
+			report.ByCondition[condition]++
+		}
+	}
+
+	// Most unmet conditions first, then the largest quantities
+	sort.SliceStable(report.Placements, func(i, j int) bool {
+		a, b := report.Placements[i], report.Placements[j]
+		if len(a.Unmet) != len(b.Unmet) {
+			return len(a.Unmet) > len(b.Unmet)
+		}
+		return a.Quantity > b.Quantity
+	})
+	return report
+}
@@ -0,0 +1,50 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageRequirementUnmet(t *testing.T) {
+	class := "6.1"
+	req := &models.ProductStorageRequirement{ColdChain: true, Shade: true, HazmatClass: &class}
+
+	assert.Equal(t, []string{models.StorageConditionColdChain, models.StorageConditionShade, models.StorageConditionHazmat}, req.Unmet(nil))
+	assert.Empty(t, req.Unmet(&models.WarehouseStorageCapability{ColdChain: true, Shade: true, HazmatClasses: []string{"6"}}),
+		"a class covers its divisions")
+	assert.Equal(t, []string{models.StorageConditionHazmat},
+		req.Unmet(&models.WarehouseStorageCapability{ColdChain: true, Shade: true, HazmatClasses: []string{"6.2", "61"}}))
+}
+
+func TestBuildPlacementReport(t *testing.T) {
+	now := time.Date(2025, 9, 2, 0, 0, 0, 0, time.UTC)
+	class := "3"
+	placements := []*models.StoragePlacement{
+		{ProductID: uuid.New(), ProductName: "Vaccine", Quantity: 5,
+			Requirement: &models.ProductStorageRequirement{ColdChain: true}},
+		{ProductID: uuid.New(), ProductName: "Solvent", Quantity: 40,
+			Requirement: &models.ProductStorageRequirement{Shade: true, HazmatClass: &class},
+			Capability:  &models.WarehouseStorageCapability{Shade: false}},
+		{ProductID: uuid.New(), ProductName: "Seed", Quantity: 100,
+			Requirement: &models.ProductStorageRequirement{Shade: true},
+			Capability:  &models.WarehouseStorageCapability{Shade: true}},
+	}
+
+	report := buildPlacementReport(placements, now)
+
+	if assert.Len(t, report.Placements, 2) {
+		assert.Equal(t, "Solvent", report.Placements[0].ProductName)
+		assert.Equal(t, "Vaccine", report.Placements[1].ProductName)
+	}
+	assert.Equal(t, 45, report.TotalQuantity)
+	assert.Equal(t, map[string]int{
+		models.StorageConditionColdChain: 1,
+		models.StorageConditionShade:     1,
+		models.StorageConditionHazmat:    1,
+	}, report.ByCondition)
+}
@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Score weights; reliability has the least weight as it is the noisiest signal
+	quotePriceWeight       = 0.6
+	quoteLeadTimeWeight    = 0.25
+	quoteReliabilityWeight = 0.15
+	// neutralReliability scores suppliers with no delivery history
+	neutralReliability = 0.5
+	deliveryHistory    = 12 // months
+)
+
+// SupplierQuoteService compares suppliers' current price-list quotes for a
+// product on price, lead time and delivery reliability
+type SupplierQuoteService struct {
+	priceListRepo repositories.SupplierPriceListRepository
+	productRepo   repositories.ProductRepository
+}
+
+func NewSupplierQuoteService(priceListRepo repositories.SupplierPriceListRepository, productRepo repositories.ProductRepository) *SupplierQuoteService {
+	return &SupplierQuoteService{
+		priceListRepo: priceListRepo,
+		productRepo:   productRepo,
+	}
+}
+
+// Compare ranks the quotes valid on asOf for buying quantity units of the product
+func (s *SupplierQuoteService) Compare(ctx context.Context, tenantID, productID uuid.UUID, quantity int, asOf time.Time) (*models.SupplierQuoteComparison, error) {
+	if quantity <= 0 {
+		quantity = 1
+	}
+	if _, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	quotes, err := s.priceListRepo.CurrentQuotes(ctx, tenantID, productID, quantity, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &models.SupplierQuoteComparison{
+		ProductID: productID,
+		Quantity:  quantity,
+		AsOf:      asOf,
+		Quotes:    []*models.SupplierQuote{},
+	}
+	if len(quotes) == 0 {
+		return comparison, nil
+	}
+
+	supplierIDs := make([]uuid.UUID, len(quotes))
+	for i, quote := range quotes {
+		supplierIDs[i] = quote.SupplierID
+	}
+	performance, err := s.priceListRepo.DeliveryPerformance(ctx, tenantID, supplierIDs, asOf.AddDate(0, -deliveryHistory, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	comparison.Quotes = scoreQuotes(quotes, performance, quantity)
+	comparison.Recommended = comparison.Quotes[0]
+	return comparison, nil
+}
+
+// scoreQuotes scores each quote out of 100 relative to the best price and
+// lead time on offer, and sorts them best first
+func scoreQuotes(quotes []*models.SupplierQuote, performance []*models.SupplierDeliveryPerformance, quantity int) []*models.SupplierQuote {
+	bySupplier := make(map[uuid.UUID]*models.SupplierDeliveryPerformance, len(performance))
+	for _, p := range performance {
+		bySupplier[p.SupplierID] = p
+	}
+
+	bestPrice, bestLead := math.MaxFloat64, math.MaxInt
+	for _, quote := range quotes {
+		bestPrice = math.Min(bestPrice, quote.UnitPrice)
+		bestLead = min(bestLead, quote.LeadTimeDays)
+	}
+
+	for _, quote := range quotes {
+		quote.TotalPrice = roundMoney(quote.UnitPrice * float64(quantity))
+
+		reliability := neutralReliability
+		if p, ok := bySupplier[quote.SupplierID]; ok && p.Received > 0 {
+			rate := math.Round(float64(p.OnTime)/float64(p.Received)*10000) / 10000
+			quote.OnTimeRate = &rate
+			quote.ReceivedOrders = p.Received
+			reliability = rate
+		}
+
+		priceScore := 0.0
+		if quote.UnitPrice > 0 {
+			priceScore = bestPrice / quote.UnitPrice
+		}
+		// +1 keeps same-day suppliers comparable instead of dividing by zero
+		leadScore := float64(bestLead+1) / float64(quote.LeadTimeDays+1)
+		score := quotePriceWeight*priceScore + quoteLeadTimeWeight*leadScore + quoteReliabilityWeight*reliability
+		quote.Score = math.Round(score*1000) / 10
+	}
+
+	sort.SliceStable(quotes, func(i, j int) bool {
+		if quotes[i].Score != quotes[j].Score {
+			return quotes[i].Score > quotes[j].Score
+		}
+		return quotes[i].UnitPrice < quotes[j].UnitPrice
+	})
+	return quotes
+}
@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"testing"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreQuotesRanksPriceLeadTimeAndReliability(t *testing.T) {
+	cheap, fast, reliable := uuid.New(), uuid.New(), uuid.New()
+	quotes := []*models.SupplierQuote{
+		{SupplierID: fast, SupplierName: "Fast", UnitPrice: 120, LeadTimeDays: 2},
+		{SupplierID: cheap, SupplierName: "Cheap", UnitPrice: 100, LeadTimeDays: 9},
+		{SupplierID: reliable, SupplierName: "Reliable", UnitPrice: 105, LeadTimeDays: 4},
+	}
+	performance := []*models.SupplierDeliveryPerformance{
+		{SupplierID: reliable, Received: 10, OnTime: 10},
+		{SupplierID: cheap, Received: 4, OnTime: 1},
+	}
+
+	ranked := scoreQuotes(quotes, performance, 10)
+
+	assert.Equal(t, "Reliable", ranked[0].SupplierName)
+	assert.Equal(t, 1050.0, ranked[0].TotalPrice)
+	if assert.NotNil(t, ranked[0].OnTimeRate) {
+		assert.Equal(t, 1.0, *ranked[0].OnTimeRate)
+	}
+	assert.Equal(t, 10, ranked[0].ReceivedOrders)
+
+	var fastQuote *models.SupplierQuote
+	for _, q := range ranked {
+		if q.SupplierID == fast {
+			fastQuote = q
+		}
+	}
+	if assert.NotNil(t, fastQuote) {
+		assert.Nil(t, fastQuote.OnTimeRate, "no history leaves the rate unset")
+		// 0.6*100/120 + 0.25*1 + 0.15*0.5
+		assert.Equal(t, 82.5, fastQuote.Score)
+	}
+}
+
+func TestScoreQuotesTieBreaksOnPrice(t *testing.T) {
+	quotes := []*models.SupplierQuote{
+		{SupplierID: uuid.New(), SupplierName: "A", UnitPrice: 50, LeadTimeDays: 2},
+		{SupplierID: uuid.New(), SupplierName: "B", UnitPrice: 50, LeadTimeDays: 2},
+	}
+
+	ranked := scoreQuotes(quotes, nil, 1)
+
+	assert.Equal(t, ranked[0].Score, ranked[1].Score)
+	assert.Equal(t, 92.5, ranked[0].Score)
+	assert.Equal(t, "A", ranked[0].SupplierName)
+}
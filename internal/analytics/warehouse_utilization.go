@@ -0,0 +1,156 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// WarehouseUtilizationService reports how full warehouses are against their
+// unit, volume and pallet capacities, and warns before stock is received
+// into a warehouse it would overfill
+type WarehouseUtilizationService struct {
+	capacityRepo repositories.WarehouseCapacityRepository
+}
+
+func NewWarehouseUtilizationService(capacityRepo repositories.WarehouseCapacityRepository) *WarehouseUtilizationService {
+	return &WarehouseUtilizationService{capacityRepo: capacityRepo}
+}
+
+// Utilization reports every active warehouse of the tenant by name
+func (s *WarehouseUtilizationService) Utilization(ctx context.Context, tenantID uuid.UUID) ([]*models.WarehouseUtilization, error) {
+	utilization, err := s.capacityRepo.Utilization(ctx, tenantID, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range utilization {
+		computeUsage(u)
+	}
+	return utilization, nil
+}
+
+// WarehouseUtilization reports one warehouse, or nil when it is archived or
+// not the tenant's
+func (s *WarehouseUtilizationService) WarehouseUtilization(ctx context.Context, tenantID, warehouseID uuid.UUID) (*models.WarehouseUtilization, error) {
+	utilization, err := s.capacityRepo.Utilization(ctx, tenantID, []uuid.UUID{warehouseID})
+	if err != nil || len(utilization) == 0 {
+		return nil, err
+	}
+	computeUsage(utilization[0])
+	return utilization[0], nil
+}
+
+// CheckInbound warns about each capacity that receiving the stock would take
+// a warehouse past. Stock is still received; the warnings are advisory
+func (s *WarehouseUtilizationService) CheckInbound(ctx context.Context, tenantID uuid.UUID, inbound []*models.InboundStock) ([]*models.CapacityWarning, error) {
+	if len(inbound) == 0 {
+		return nil, nil
+	}
+	var warehouseIDs, productIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, stock := range inbound {
+		if !seen[stock.WarehouseID] {
+			seen[stock.WarehouseID] = true
+			warehouseIDs = append(warehouseIDs, stock.WarehouseID)
+		}
+		if !seen[stock.ProductID] {
+			seen[stock.ProductID] = true
+			productIDs = append(productIDs, stock.ProductID)
+		}
+	}
+
+	current, err := s.capacityRepo.Utilization(ctx, tenantID, warehouseIDs)
+	if err != nil {
+		return nil, err
+	}
+	dimensions, err := s.capacityRepo.ListProductDimensions(ctx, tenantID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	return projectInbound(current, inbound, dimensions), nil
+}
+
+// computeUsage fills the usage of each capacity the warehouse sets
+func computeUsage(u *models.WarehouseUtilization) {
+	u.Usage = []*models.CapacityUsage{}
+	if u.UnitCapacity != nil {
+		u.Usage = append(u.Usage, newCapacityUsage(models.CapacityUnits, float64(*u.UnitCapacity), float64(u.Units)))
+	}
+	if u.VolumeCapacityM3 != nil {
+		u.Usage = append(u.Usage, newCapacityUsage(models.CapacityVolume, *u.VolumeCapacityM3, math.Round(u.VolumeM3*1000)/1000))
+	}
+	if u.PalletPositions != nil {
+		u.Usage = append(u.Usage, newCapacityUsage(models.CapacityPallets, float64(*u.PalletPositions), float64(u.Pallets)))
+	}
+}
+
+func newCapacityUsage(measure string, capacity, used float64) *models.CapacityUsage {
+	usage := &models.CapacityUsage{Measure: measure, Capacity: capacity, Used: used}
+	if capacity > 0 {
+		usage.Percent = math.Round(used/capacity*1000) / 10
+	}
+	return usage
+}
+
+// projectInbound adds the inbound stock to the warehouses' current stock and
+// warns about each capacity exceeded. Received units start new pallets, and
+// units of products without dimensions add no volume
+func projectInbound(current []*models.WarehouseUtilization, inbound []*models.InboundStock, dimensions map[uuid.UUID]*models.ProductDimensions) []*models.CapacityWarning {
+	projected := make(map[uuid.UUID]*models.WarehouseUtilization, len(current))
+	for _, u := range current {
+		p := *u
+		projected[u.WarehouseID] = &p
+	}
+	for _, stock := range inbound {
+		p, ok := projected[stock.WarehouseID]
+		if !ok || stock.Quantity <= 0 {
+			continue
+		}
+		p.Units += stock.Quantity
+		if d, ok := dimensions[stock.ProductID]; ok {
+			p.VolumeM3 += float64(stock.Quantity) * d.UnitVolumeM3()
+			if d.UnitsPerPallet != nil {
+				p.Pallets += (stock.Quantity + *d.UnitsPerPallet - 1) / *d.UnitsPerPallet
+			}
+		}
+	}
+
+	var warnings []*models.CapacityWarning
+	for _, u := range current {
+		before, after := *u, projected[u.WarehouseID]
+		computeUsage(&before)
+		computeUsage(after)
+		for i, usage := range after.Usage {
+			// Receiving nothing counted by a measure leaves it alone, even
+			// when stock on hand already exceeds it
+			if usage.Used <= usage.Capacity || before.Usage[i].Used >= usage.Used {
+				continue
+			}
+			warnings = append(warnings, &models.CapacityWarning{
+				WarehouseID:   u.WarehouseID,
+				WarehouseName: u.WarehouseName,
+				Measure:       usage.Measure,
+				Capacity:      usage.Capacity,
+				Projected:     usage.Used,
+				Message: fmt.Sprintf("warehouse %s would hold %g %s against a capacity of %g (%.1f%%)",
+					u.WarehouseName, usage.Used, capacityUnit(usage.Measure), usage.Capacity, usage.Percent),
+			})
+		}
+	}
+	return warnings
+}
+
+func capacityUnit(measure string) string {
+	switch measure {
+	case models.CapacityVolume:
+		return "m³"
+	case models.CapacityPallets:
+		return "pallets"
+	}
+	return "units"
+}
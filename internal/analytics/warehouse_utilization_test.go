@@ -0,0 +1,77 @@
+package analytics
+
+import (
+	"testing"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestComputeUsageCoversCapacitiesSet(t *testing.T) {
+	u := &models.WarehouseUtilization{
+		Units: 750, VolumeM3: 42.1234, Pallets: 12,
+		UnitCapacity: intPtr(1000), PalletPositions: intPtr(40),
+	}
+	computeUsage(u)
+
+	require.Len(t, u.Usage, 2)
+	assert.Equal(t, &models.CapacityUsage{Measure: models.CapacityUnits, Capacity: 1000, Used: 750, Percent: 75}, u.Usage[0])
+	assert.Equal(t, &models.CapacityUsage{Measure: models.CapacityPallets, Capacity: 40, Used: 12, Percent: 30}, u.Usage[1])
+
+	u.VolumeCapacityM3 = floatPtr(60)
+	computeUsage(u)
+	require.Len(t, u.Usage, 3)
+	assert.Equal(t, 42.123, u.Usage[1].Used)
+	assert.Equal(t, 70.2, u.Usage[1].Percent)
+}
+
+func TestProjectInboundWarnsOnExceededCapacities(t *testing.T) {
+	godown, shed := uuid.New(), uuid.New()
+	urea, seed := uuid.New(), uuid.New()
+	current := []*models.WarehouseUtilization{
+		{WarehouseID: godown, WarehouseName: "Main Godown", Units: 900, VolumeM3: 50, Pallets: 19,
+			UnitCapacity: intPtr(1000), VolumeCapacityM3: floatPtr(60), PalletPositions: intPtr(20)},
+		{WarehouseID: shed, WarehouseName: "Seed Shed", Units: 10, UnitCapacity: intPtr(500)},
+	}
+	dimensions := map[uuid.UUID]*models.ProductDimensions{
+		// A 45 kg urea bag: 0.036 m³, 40 to a pallet
+		urea: {ProductID: urea, LengthCm: 60, WidthCm: 40, HeightCm: 15, UnitsPerPallet: intPtr(40)},
+	}
+	inbound := []*models.InboundStock{
+		{WarehouseID: godown, ProductID: urea, Quantity: 80},
+		{WarehouseID: godown, ProductID: seed, Quantity: 30},
+		{WarehouseID: shed, ProductID: seed, Quantity: 100},
+	}
+
+	warnings := projectInbound(current, inbound, dimensions)
+
+	require.Len(t, warnings, 2)
+	assert.Equal(t, godown, warnings[0].WarehouseID)
+	assert.Equal(t, models.CapacityUnits, warnings[0].Measure)
+	assert.Equal(t, 1010.0, warnings[0].Projected)
+	assert.Equal(t, models.CapacityPallets, warnings[1].Measure)
+	assert.Equal(t, 20.0, warnings[1].Capacity)
+	assert.Equal(t, 21.0, warnings[1].Projected)
+	assert.Contains(t, warnings[1].Message, "Main Godown would hold 21 pallets against a capacity of 20")
+
+	// 50 + 80 × 0.036 = 52.88 m³ fits in 60
+	assert.Equal(t, 900, current[0].Units, "current utilization is left as it was")
+}
+
+func TestProjectInboundSkipsMeasuresTheStockDoesNotAddTo(t *testing.T) {
+	godown, seed := uuid.New(), uuid.New()
+	current := []*models.WarehouseUtilization{
+		{WarehouseID: godown, WarehouseName: "Main Godown", Units: 100, Pallets: 25,
+			UnitCapacity: intPtr(1000), PalletPositions: intPtr(20)},
+	}
+	inbound := []*models.InboundStock{{WarehouseID: godown, ProductID: seed, Quantity: 50}}
+
+	assert.Empty(t, projectInbound(current, inbound, nil), "pallets were already over, and the seed is not palletized")
+}
@@ -0,0 +1,1436 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/caching"
+	"agromart2/internal/handlers"
+	"agromart2/internal/jobs"
+	"agromart2/internal/middleware"
+	"agromart2/internal/pii"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+	"agromart2/pkg/database"
+)
+
+// Config holds everything needed to assemble the application
+type Config struct {
+	DatabaseURL string
+	Port        int
+
+	// DBPool tunes the database connection pool and the slow query log
+	DBPool database.PoolConfig
+
+	// LegacyJWTSecret keeps HS256 tokens issued before the key ring verifiable
+	LegacyJWTSecret string
+	KeyRotationDays int
+
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+
+	MinioEndpoint  string
+	MinioAccessKey string
+	MinioSecretKey string
+	MinioUseSSL    bool
+
+	// ResidencyStores are the object stores of the data residency regions,
+	// by region name; tenants pinned to a region keep their files there
+	ResidencyStores map[string]services.StorageEndpoint
+
+	// WeatherAPIURL is the open-meteo compatible forecast endpoint
+	WeatherAPIURL string
+
+	// FCMCredentialsFile is a Google service account key for FCM; push
+	// notifications are only logged when it is unset
+	FCMCredentialsFile string
+
+	// WhatsApp selects the WhatsApp Business provider; messages are only
+	// logged when no provider is set
+	WhatsApp services.WhatsAppConfig
+
+	// OCR selects the provider that reads uploaded supplier invoices; they
+	// are stored for manual entry when no provider is set
+	OCR services.OCRConfig
+
+	// PayloadLog selects where request and response bodies of tenants with
+	// the payload_logging flag go; nothing is captured when no sink is set
+	PayloadLog services.PayloadLogConfig
+
+	// CookieAuth selects the client types, usually the web dashboard, whose
+	// tokens are kept in httpOnly cookies with CSRF protection
+	CookieAuth middleware.CookieAuthConfig
+
+	// AnalyticsStaleTolerance is how old the analytics materialized views may
+	// be before reports query the live tables
+	AnalyticsStaleTolerance time.Duration
+
+	// PIIMasterKey wraps the per-tenant keys that encrypt contact emails,
+	// phones and GSTINs; they are stored in plaintext when it is unset
+	PIIMasterKey       []byte
+	PIIKeyRotationDays int
+
+	// QueryAuditSampleRate is the fraction of slow queries recorded in the
+	// query diagnostics; failed queries are always recorded. 0 records none
+	QueryAuditSampleRate    float64
+	QueryAuditRetentionDays int
+}
+
+// App is a fully wired application instance
+type App struct {
+	Config *Config
+	Echo   *echo.Echo
+	Pool   *pgxpool.Pool
+	Auth   services.AuthService
+	// Features evaluates per-tenant feature flags
+	Features services.FeatureFlagService
+
+	queryAudit     *jobs.QueryAuditService
+	invoiceArchive *jobs.InvoiceArchiveService
+}
+
+// ConfigFromEnv reads the application configuration from environment variables
+func ConfigFromEnv() (*Config, error) {
+	cfg := &Config{
+		DatabaseURL:     os.Getenv("DATABASE_URL"),
+		Port:            8080,
+		LegacyJWTSecret: os.Getenv("JWT_SECRET"),
+		KeyRotationDays: 30,
+		RedisAddr:       "localhost:6379", // Default Redis address
+		RedisPassword:   os.Getenv("REDIS_PASSWORD"),
+		MinioEndpoint:   "localhost:9000", // Default MinIO endpoint
+		MinioAccessKey:  "minioadmin",     // Default for development
+		MinioSecretKey:  "minioadmin",     // Default for development
+		MinioUseSSL:     os.Getenv("MINIO_USE_SSL") == "true",
+		WeatherAPIURL:   os.Getenv("WEATHER_API_URL"),
+		CookieAuth:      middleware.DefaultCookieAuthConfig(),
+		DBPool:          database.PoolConfig{SlowQueryThreshold: 500 * time.Millisecond},
+
+		AnalyticsStaleTolerance: analytics.DefaultStaleTolerance,
+		PIIKeyRotationDays:      90,
+		QueryAuditSampleRate:    0.1,
+		QueryAuditRetentionDays: 14,
+	}
+
+	if cfg.DatabaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
+	}
+
+	if portStr := os.Getenv("PORT"); portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %s: %w", portStr, err)
+		}
+		cfg.Port = port
+	}
+
+	if err := poolConfigFromEnv(&cfg.DBPool); err != nil {
+		return nil, err
+	}
+
+	if daysStr := os.Getenv("JWT_KEY_ROTATION_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days > 0 {
+			cfg.KeyRotationDays = days
+		}
+	}
+
+	if masterKey := os.Getenv("PII_MASTER_KEY"); masterKey != "" {
+		key, err := pii.ParseMasterKey(masterKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg.PIIMasterKey = key
+	}
+	if daysStr := os.Getenv("PII_KEY_ROTATION_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days > 0 {
+			cfg.PIIKeyRotationDays = days
+		}
+	}
+
+	if rateStr := os.Getenv("DB_QUERY_AUDIT_SAMPLE_RATE"); rateStr != "" {
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid DB_QUERY_AUDIT_SAMPLE_RATE %s: must be between 0 and 1", rateStr)
+		}
+		cfg.QueryAuditSampleRate = rate
+	}
+	if daysStr := os.Getenv("DB_QUERY_AUDIT_RETENTION_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil && days > 0 {
+			cfg.QueryAuditRetentionDays = days
+		}
+	}
+
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		cfg.RedisAddr = addr
+	}
+	if dbStr := os.Getenv("REDIS_DB"); dbStr != "" {
+		if db, err := strconv.Atoi(dbStr); err == nil {
+			cfg.RedisDB = db
+		}
+	}
+
+	stores, err := residencyStoresFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	cfg.ResidencyStores = stores
+
+	if endpoint := os.Getenv("MINIO_ENDPOINT"); endpoint != "" {
+		cfg.MinioEndpoint = endpoint
+	}
+	if accessKey := os.Getenv("MINIO_ACCESS_KEY"); accessKey != "" {
+		cfg.MinioAccessKey = accessKey
+	}
+	if secretKey := os.Getenv("MINIO_SECRET_KEY"); secretKey != "" {
+		cfg.MinioSecretKey = secretKey
+	}
+
+	if toleranceStr := os.Getenv("ANALYTICS_STALE_TOLERANCE"); toleranceStr != "" {
+		if tolerance, err := time.ParseDuration(toleranceStr); err == nil && tolerance > 0 {
+			cfg.AnalyticsStaleTolerance = tolerance
+		}
+	}
+
+	if clients, ok := os.LookupEnv("COOKIE_AUTH_CLIENTS"); ok {
+		cfg.CookieAuth.ClientTypes = splitList(clients)
+	}
+	if os.Getenv("COOKIE_SECURE") == "false" {
+		cfg.CookieAuth.Secure = false
+	}
+	if sameSiteStr := os.Getenv("COOKIE_SAMESITE"); sameSiteStr != "" {
+		sameSite, ok := middleware.ParseSameSite(sameSiteStr)
+		if !ok {
+			return nil, fmt.Errorf("invalid COOKIE_SAMESITE %s: must be lax, strict or none", sameSiteStr)
+		}
+		cfg.CookieAuth.SameSite = sameSite
+	}
+	cfg.CookieAuth.Domain = os.Getenv("COOKIE_DOMAIN")
+	cfg.CookieAuth.AllowedOrigins = splitList(os.Getenv("COOKIE_AUTH_ORIGINS"))
+
+	cfg.FCMCredentialsFile = os.Getenv("FCM_CREDENTIALS_FILE")
+
+	cfg.WhatsApp = services.WhatsAppConfig{
+		Provider:      os.Getenv("WHATSAPP_PROVIDER"),
+		APIToken:      os.Getenv("WHATSAPP_API_TOKEN"),
+		PhoneNumberID: os.Getenv("WHATSAPP_PHONE_NUMBER_ID"),
+		SourceNumber:  os.Getenv("WHATSAPP_SOURCE_NUMBER"),
+		AppName:       os.Getenv("WHATSAPP_APP_NAME"),
+		AppSecret:     os.Getenv("WHATSAPP_APP_SECRET"),
+		VerifyToken:   os.Getenv("WHATSAPP_VERIFY_TOKEN"),
+	}
+
+	cfg.OCR = services.OCRConfig{
+		Provider:      os.Getenv("OCR_PROVIDER"),
+		TesseractPath: os.Getenv("OCR_TESSERACT_PATH"),
+		Languages:     os.Getenv("OCR_LANGUAGES"),
+		APIKey:        os.Getenv("OCR_API_KEY"),
+	}
+
+	cfg.PayloadLog = services.PayloadLogConfig{
+		Sink:       os.Getenv("PAYLOAD_LOG_SINK"),
+		Stream:     os.Getenv("PAYLOAD_LOG_STREAM"),
+		SampleRate: 1,
+	}
+	if maxLenStr := os.Getenv("PAYLOAD_LOG_STREAM_MAX_LEN"); maxLenStr != "" {
+		if maxLen, err := strconv.ParseInt(maxLenStr, 10, 64); err == nil && maxLen > 0 {
+			cfg.PayloadLog.MaxLen = maxLen
+		}
+	}
+	if rateStr := os.Getenv("PAYLOAD_LOG_SAMPLE_RATE"); rateStr != "" {
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid PAYLOAD_LOG_SAMPLE_RATE %s: must be above 0 and at most 1", rateStr)
+		}
+		cfg.PayloadLog.SampleRate = rate
+	}
+	if maxBodyStr := os.Getenv("PAYLOAD_LOG_MAX_BODY_BYTES"); maxBodyStr != "" {
+		if maxBody, err := strconv.Atoi(maxBodyStr); err == nil && maxBody > 0 {
+			cfg.PayloadLog.MaxBody = maxBody
+		}
+	}
+
+	return cfg, nil
+}
+
+// Build connects to backing services, wires repositories, services and handlers,
+// and registers all routes. The caller owns the returned App and must Close it.
+func Build(ctx context.Context, cfg *Config) (*App, error) {
+	// Slow and failing queries are recorded for the query diagnostics report
+	queryAudit := jobs.NewQueryAuditService(cfg.QueryAuditSampleRate, time.Duration(cfg.QueryAuditRetentionDays)*24*time.Hour)
+	poolConfig := cfg.DBPool
+	poolConfig.Observer = queryAudit
+
+	// Create database connection pool
+	pool, err := database.Open(ctx, cfg.DatabaseURL, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Initialize MinIO service
+	minioSvc, err := services.NewMinioService(cfg.MinioEndpoint, cfg.MinioAccessKey, cfg.MinioSecretKey, cfg.MinioUseSSL)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to initialize MinIO service: %w", err)
+	}
+
+	// Each residency region has its own object store
+	regionalStores := make(map[string]services.MinioService, len(cfg.ResidencyStores))
+	for region, store := range cfg.ResidencyStores {
+		regionalStores[region], err = services.NewMinioService(store.Endpoint, store.AccessKey, store.SecretKey, store.UseSSL)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to initialize MinIO service for region %s: %w", region, err)
+		}
+	}
+
+	// PII columns are encrypted once a master key is configured
+	var piiKeyring *pii.DataKeyRing
+	if len(cfg.PIIMasterKey) > 0 {
+		piiKeyring, err = pii.NewDataKeyRing(repositories.NewDataKeyRepo(pool), cfg.PIIMasterKey)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to initialize PII encryption: %w", err)
+		}
+	}
+
+	// Create repositories
+	userRepo := repositories.NewUserRepo(pool, piiKeyring)
+	tenantRepo := repositories.NewTenantRepo(pool)
+	residencySvc := services.NewDataResidencyService(tenantRepo, slices.Sorted(maps.Keys(cfg.ResidencyStores)))
+	minioSvc = services.NewResidencyMinioService(minioSvc, regionalStores, residencySvc)
+	roleRepo := repositories.NewRoleRepo(pool)
+	userRoleRepo := repositories.NewUserRoleRepo(pool)
+	rolePermissionRepo := repositories.NewRolePermissionRepo(pool)
+	permissionRepo := repositories.NewPermissionRepo(pool)
+	categoryRepo := repositories.NewCategoryRepo(pool)
+	productRepo := repositories.NewProductRepo(pool)
+	warehouseRepo := repositories.NewWarehouseRepository(pool)
+	supplierRepo := repositories.NewSupplierRepository(pool, piiKeyring)
+	distributorRepo := repositories.NewDistributorRepository(pool, piiKeyring)
+	inventoryRepo := repositories.NewInventoryRepo(pool)
+	inventoryTransactionRepo := repositories.NewInventoryTransactionRepo(pool)
+	availabilityRepo := repositories.NewAvailabilityRepo(pool)
+	priceHistoryRepo := repositories.NewPriceHistoryRepo(pool)
+	marginRepo := repositories.NewMarginRepo(pool)
+	seasonRepo := repositories.NewSeasonRepo(pool)
+	weatherAlertRepo := repositories.NewWeatherAlertRepo(pool)
+	orderRepo := repositories.NewOrderRepo(pool)
+	invoiceRepo := repositories.NewInvoiceRepo(pool)
+	productImageRepo := repositories.NewProductImageRepo(pool)
+	catalogRepo := repositories.NewCatalogRepo(pool)
+	marketplaceRepo := repositories.NewMarketplaceRepo(pool)
+	erpConnectorRepo := repositories.NewERPConnectorRepo(pool)
+	tallySyncRepo := repositories.NewTallySyncRepo(pool)
+	signingKeyRepo := repositories.NewSigningKeyRepo(pool)
+	auditLogsRepo := repositories.NewAuditLogsRepo(pool)
+	impersonationRepo := repositories.NewImpersonationRepo(pool)
+	consignmentRepo := repositories.NewConsignmentRepo(pool)
+	bundleRepo := repositories.NewBundleRepo(pool)
+	purchaseReceiptRepo := repositories.NewPurchaseReceiptRepo(pool)
+	complianceRepo := repositories.NewComplianceRepo(pool)
+	storageConditionRepo := repositories.NewStorageConditionRepo(pool)
+	stockMinimumRepo := repositories.NewStockMinimumRepo(pool)
+	productTemplateRepo := repositories.NewProductTemplateRepo(pool)
+	dependencyRepo := repositories.NewDependencyRepo(pool)
+	warehouseCapacityRepo := repositories.NewWarehouseCapacityRepo(pool)
+
+	// Create cache service; while Redis is down the breaker serves from the database only
+	cacheSvc := caching.NewCircuitBreakerCache(
+		caching.NewRedisCacheService(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB),
+		caching.DefaultCircuitBreakerConfig(),
+	)
+
+	// Create services
+	// Create analytics service
+	analyticsSvc := analytics.NewAnalyticsService(orderRepo, invoiceRepo, inventoryRepo, productRepo, consignmentRepo, cacheSvc,
+		repositories.NewAnalyticsViewRepo(pool), cfg.AnalyticsStaleTolerance)
+
+	rbacService := services.NewRBACService(userRoleRepo, rolePermissionRepo, permissionRepo)
+
+	// RBAC middleware
+	rbacMiddleware := middleware.NewRBACMiddleware(rbacService)
+
+	// Create audit and notification services
+	auditLogsService := services.NewAuditLogsService(auditLogsRepo)
+	auditMiddleware := middleware.NewAuditMiddleware(auditLogsService)
+	pushDriver := services.NewLogPushDriver()
+	if cfg.FCMCredentialsFile != "" {
+		fcmDriver, err := services.NewFCMDriver(cfg.FCMCredentialsFile)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to initialize FCM: %w", err)
+		}
+		pushDriver = fcmDriver
+	}
+	pushSvc := services.NewPushService(repositories.NewDeviceTokenRepo(pool), pushDriver)
+	whatsAppDriver, err := services.NewWhatsAppDriver(cfg.WhatsApp)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to initialize WhatsApp: %w", err)
+	}
+	ocrDriver, err := services.NewOCRDriver(cfg.OCR)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to initialize OCR: %w", err)
+	}
+	whatsAppSvc := services.NewWhatsAppService(repositories.NewWhatsAppRepo(pool), distributorRepo, whatsAppDriver, cfg.WhatsApp.VerifyToken)
+	// Sandbox tenants' notifications are only logged, so demo data never reaches real recipients
+	sandboxRepo := repositories.NewSandboxRepo(pool)
+	sandboxSvc := services.NewSandboxService(sandboxRepo, cacheSvc)
+	notificationSvc := services.NewSandboxNotificationService(
+		services.NewNotificationService(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, pushSvc, whatsAppSvc),
+		sandboxSvc,
+	)
+
+	// Load the JWT key ring; rotated keys stay verifiable for one access token lifetime
+	keyRing, err := services.NewKeyRing(ctx, signingKeyRepo, cfg.LegacyJWTSecret, time.Hour, services.DefaultKeyRefreshInterval)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to initialize JWT key ring: %w", err)
+	}
+	if rotated, err := keyRing.RotateIfOlderThan(ctx, time.Duration(cfg.KeyRotationDays)*24*time.Hour); err != nil {
+		log.Printf("WARNING: JWT key rotation failed: %v", err)
+	} else if rotated {
+		log.Printf("JWT signing key was older than %d days and has been rotated", cfg.KeyRotationDays)
+	}
+
+	// Create auth service
+	authService := services.NewAuthService(cacheSvc, notificationSvc, keyRing, services.DefaultAccessTokenTTL, services.DefaultRefreshTokenTTL)
+
+	// Create impersonation service
+	impersonationService := services.NewImpersonationService(impersonationRepo, userRepo, roleRepo, userRoleRepo, authService, notificationSvc)
+
+	// Create product service
+	storageConditionSvc := services.NewStorageConditionService(storageConditionRepo, productRepo, warehouseRepo)
+	dependencySvc := services.NewDependencyService(dependencyRepo)
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, inventoryTransactionRepo, cacheSvc, storageConditionSvc, stockMinimumRepo)
+	bulkOpsSvc := services.NewBulkOperationService()
+	productSvc := services.NewProductService(productRepo, inventoryRepo, categoryRepo, productImageRepo, warehouseRepo, inventoryService, priceHistoryRepo, minioSvc, cacheSvc, dependencySvc, bulkOpsSvc)
+
+	// Create product handlers
+	productHandlers := handlers.NewProductHandlers(productSvc, rbacMiddleware)
+	searchHandlers := handlers.NewSearchHandlers(services.NewSearchService(repositories.NewSearchRepo(pool, piiKeyring), cacheSvc), rbacService)
+	availabilityHandlers := handlers.NewAvailabilityHandlers(
+		services.NewAvailabilityService(availabilityRepo, productRepo, bundleRepo, cacheSvc),
+		rbacMiddleware,
+	)
+
+	// Create tenant service
+	tenantService := services.NewTenantService(tenantRepo)
+	tenantCalendarRepo := repositories.NewTenantCalendarRepo(pool)
+	tenantCalendarSvc := services.NewTenantCalendarService(tenantCalendarRepo)
+
+	// Create order service
+	// orderSvc := services.NewOrderService(orderRepo, inventoryRepo, inventoryService) // moved after inventoryService
+
+	// Create invoice service
+	// invoiceSvc := services.NewInvoiceService(invoiceRepo, orderRepo, analyticsSvc, pool) // moved after inventoryService
+
+	// Create handlers
+	authHandlers := handlers.NewAuthHandlers(
+		authService,
+		userRepo,
+		roleRepo,
+		userRoleRepo,
+		rbacMiddleware,
+		cfg.CookieAuth,
+	)
+	userHandlers := handlers.NewUserHandlers(userRepo, tenantRepo, rbacMiddleware)
+	tenantHandlers := handlers.NewTenantHandlers(tenantService, rbacMiddleware)
+	tenantCalendarHandlers := handlers.NewTenantCalendarHandlers(tenantCalendarSvc, rbacMiddleware)
+	categoryHandlers := handlers.NewCategoryHandlers(categoryRepo, dependencySvc, cacheSvc, rbacMiddleware)
+	branchSvc := services.NewBranchService(repositories.NewBranchRepo(pool), warehouseRepo)
+	branchHandlers := handlers.NewBranchHandlers(branchSvc, rbacMiddleware)
+	periodCloseSvc := services.NewPeriodCloseService(repositories.NewPeriodCloseRepo(pool), branchSvc, tenantCalendarSvc)
+	periodCloseHandlers := handlers.NewPeriodCloseHandlers(periodCloseSvc, rbacMiddleware)
+	approvalDelegationSvc := services.NewApprovalDelegationService(repositories.NewApprovalDelegationRepo(pool), userRepo, rbacService, pushSvc, tenantCalendarSvc)
+	approvalDelegationHandlers := handlers.NewApprovalDelegationHandlers(approvalDelegationSvc, rbacMiddleware)
+	warehouseSvc := services.NewWarehouseService(warehouseRepo, dependencySvc, branchSvc)
+	warehouseUtilizationSvc := analytics.NewWarehouseUtilizationService(warehouseCapacityRepo)
+	distributorSvc := services.NewDistributorService(distributorRepo, dependencySvc)
+	supplierSvc := services.NewSupplierService(supplierRepo, dependencySvc)
+	warehouseHandlers := handlers.NewWarehouseHandlers(warehouseSvc, warehouseUtilizationSvc, rbacMiddleware)
+	distributorHandlers := handlers.NewDistributorHandlers(distributorSvc, rbacMiddleware)
+	supplierHandlers := handlers.NewSupplierHandlers(supplierSvc, rbacMiddleware)
+
+	marginSvc := services.NewMarginService(marginRepo, productRepo)
+	consignmentSvc := services.NewConsignmentService(consignmentRepo, supplierRepo)
+	bundleSvc := services.NewBundleService(bundleRepo, productRepo, inventoryRepo, inventoryService)
+	complianceSvc := services.NewComplianceService(complianceRepo, supplierRepo, warehouseRepo, distributorRepo, minioSvc, notificationSvc)
+	stockOutRepo := repositories.NewStockOutRepo(pool)
+	statusHistoryRepo := repositories.NewStatusHistoryRepo(pool)
+	orderWorkflowSvc := services.NewOrderWorkflowService(repositories.NewOrderWorkflowRepo(pool))
+	orderSvc := services.NewOrderService(orderRepo, inventoryRepo, inventoryService, marginSvc, notificationSvc, consignmentSvc, bundleSvc, complianceSvc, bulkOpsSvc, stockOutRepo, statusHistoryRepo, orderWorkflowSvc)
+
+	withholdingTaxRepo := repositories.NewWithholdingTaxRepo(pool, piiKeyring)
+	invoiceSvc := services.NewInvoiceService(invoiceRepo, orderRepo, analyticsSvc, pool, notificationSvc, withholdingTaxRepo, statusHistoryRepo, tenantCalendarSvc, periodCloseSvc)
+	inventoryHandlers := handlers.NewInventoryHandlers(
+		inventoryService,
+		rbacMiddleware,
+	)
+	inventoryReconciliationHandlers := handlers.NewInventoryReconciliationHandlers(
+		services.NewInventoryReconciliationService(repositories.NewInventoryReconciliationRepo(pool), productRepo, warehouseRepo, inventoryService),
+		approvalDelegationSvc,
+		rbacMiddleware,
+	)
+	jwksHandlers := handlers.NewJWKSHandlers(keyRing, rbacMiddleware)
+	impersonationHandlers := handlers.NewImpersonationHandlers(impersonationService, rbacMiddleware)
+	featureFlagSvc := services.NewFeatureFlagService(repositories.NewFeatureFlagRepo(pool), cacheSvc)
+	payloadLogSink, err := services.NewPayloadLogSink(cfg.PayloadLog, cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to initialize payload logging: %w", err)
+	}
+	payloadLogMiddleware := middleware.NewPayloadLogMiddleware(featureFlagSvc, payloadLogSink, cfg.PayloadLog.SampleRate, cfg.PayloadLog.MaxBody)
+	featureFlagHandlers := handlers.NewFeatureFlagHandlers(featureFlagSvc, rbacMiddleware)
+	operationalModeSvc := services.NewOperationalModeService(repositories.NewOperationalModeRepo(pool), cacheSvc)
+	operationalModeHandlers := handlers.NewOperationalModeHandlers(operationalModeSvc, rbacMiddleware)
+	statusHandlers := handlers.NewStatusHandlers(
+		services.NewStatusIncidentService(repositories.NewStatusIncidentRepo(pool), operationalModeSvc, cacheSvc),
+		rbacMiddleware,
+	)
+	sandboxHandlers := handlers.NewSandboxHandlers(
+		sandboxSvc,
+		jobs.NewSandboxResetService(sandboxRepo, categoryRepo, productRepo, warehouseRepo, supplierRepo, distributorRepo, inventoryRepo, orderRepo, invoiceRepo),
+		rbacMiddleware,
+	)
+	queryDiagnosticsHandlers := handlers.NewQueryDiagnosticsHandlers(queryAudit, rbacMiddleware)
+	dataResidencyHandlers := handlers.NewDataResidencyHandlers(residencySvc, rbacMiddleware)
+	integrityHandlers := handlers.NewIntegrityHandlers(
+		jobs.NewIntegrityCheckService(repositories.NewIntegrityRepo(pool), minioSvc, cacheSvc),
+		tenantService,
+		rbacMiddleware,
+	)
+	bulkOperationHandlers := handlers.NewBulkOperationHandlers(bulkOpsSvc)
+	orderHandlers := handlers.NewOrderHandlers(orderSvc, productSvc, warehouseSvc, supplierSvc, distributorSvc, approvalDelegationSvc, rbacMiddleware)
+	orderWorkflowHandlers := handlers.NewOrderWorkflowHandlers(orderWorkflowSvc, rbacMiddleware)
+	marginHandlers := handlers.NewMarginHandlers(marginSvc, rbacMiddleware)
+	weatherHandlers := handlers.NewWeatherHandlers(
+		services.NewWeatherAlertService(weatherAlertRepo, warehouseRepo, services.NewOpenMeteoClient(cfg.WeatherAPIURL), notificationSvc, cacheSvc),
+		rbacMiddleware,
+	)
+	supplierPriceListRepo := repositories.NewSupplierPriceListRepo(pool)
+	supplierQuotes := analytics.NewSupplierQuoteService(supplierPriceListRepo, productRepo)
+	supplierPriceListHandlers := handlers.NewSupplierPriceListHandlers(
+		services.NewSupplierPriceListService(supplierPriceListRepo, supplierRepo, productRepo),
+		supplierQuotes,
+		rbacMiddleware,
+	)
+	seasonHandlers := handlers.NewSeasonHandlers(
+		services.NewSeasonService(seasonRepo, productRepo),
+		analytics.NewSeasonalDemandService(seasonRepo, inventoryRepo, productRepo, stockMinimumRepo, supplierQuotes),
+		rbacMiddleware,
+	)
+	catalogHandlers := handlers.NewCatalogHandlers(
+		services.NewCatalogService(catalogRepo, productRepo, minioSvc),
+		cacheSvc,
+		rbacMiddleware,
+	)
+	marketplaceHandlers := handlers.NewMarketplaceHandlers(
+		services.NewMarketplaceService(marketplaceRepo, productRepo, warehouseRepo, distributorRepo, orderSvc),
+		rbacMiddleware,
+	)
+	erpHandlers := handlers.NewERPHandlers(
+		jobs.NewERPSyncService(
+			erpConnectorRepo,
+			jobs.NewERPDocumentBuilder(invoiceRepo, orderRepo, productRepo, supplierRepo, distributorRepo),
+			minioSvc,
+		),
+		rbacMiddleware,
+	)
+	dataExportHandlers := handlers.NewDataExportHandlers(jobs.NewDataExportService(repositories.NewDataExportRepo(pool)), rbacMiddleware)
+	tenantTakeoutHandlers := handlers.NewTenantTakeoutHandlers(
+		jobs.NewTenantTakeoutService(repositories.NewTenantTakeoutRepo(pool), tenantRepo, userRepo, minioSvc, notificationSvc, piiKeyring),
+		rbacMiddleware,
+	)
+	catalogExportHandlers := handlers.NewCatalogExportHandlers(
+		jobs.NewCatalogPDFService(
+			repositories.NewCatalogExportRepo(pool),
+			productRepo,
+			categoryRepo,
+			productImageRepo,
+			supplierPriceListRepo,
+			tenantRepo,
+			minioSvc,
+		),
+		rbacMiddleware,
+	)
+	statementHandlers := handlers.NewStatementHandlers(
+		jobs.NewStatementService(repositories.NewStatementRepo(pool, piiKeyring), distributorRepo, tenantRepo, minioSvc, notificationSvc, tenantCalendarSvc, periodCloseSvc),
+		rbacMiddleware,
+	)
+	tallyHandlers := handlers.NewTallyHandlers(
+		jobs.NewTallySyncService(
+			jobs.NewTallyExporter(invoiceRepo, orderRepo, productRepo),
+			jobs.NewTallyImporter(orderRepo, invoiceRepo, tallySyncRepo),
+			tallySyncRepo,
+			minioSvc,
+		),
+		rbacMiddleware,
+	)
+	syncHandlers := handlers.NewSyncHandlers(
+		services.NewSyncService(repositories.NewSyncRepo(pool, piiKeyring), productRepo, inventoryRepo, orderSvc, stockOutRepo),
+		rbacMiddleware,
+	)
+	deviceHandlers := handlers.NewDeviceHandlers(pushSvc)
+	whatsAppHandlers := handlers.NewWhatsAppHandlers(whatsAppSvc, rbacMiddleware)
+	overdueInterestHandlers := handlers.NewOverdueInterestHandlers(
+		jobs.NewOverdueInterestService(repositories.NewOverdueInterestRepo(pool), invoiceRepo, distributorRepo, tenantCalendarSvc),
+		rbacMiddleware,
+	)
+	paymentAllocationRepo := repositories.NewPaymentAllocationRepo(pool)
+	paymentAllocationHandlers := handlers.NewPaymentAllocationHandlers(
+		services.NewPaymentAllocationService(paymentAllocationRepo, distributorRepo, withholdingTaxRepo, periodCloseSvc),
+		rbacMiddleware,
+	)
+	withholdingTaxHandlers := handlers.NewWithholdingTaxHandlers(
+		services.NewWithholdingTaxService(withholdingTaxRepo, paymentAllocationRepo),
+		rbacMiddleware,
+	)
+	dunningHandlers := handlers.NewDunningHandlers(
+		services.NewDunningService(repositories.NewDunningRepo(pool, piiKeyring), notificationSvc),
+		rbacMiddleware,
+	)
+	orderSLAHandlers := handlers.NewOrderSLAHandlers(
+		services.NewOrderSLAService(repositories.NewOrderSLARepo(pool), notificationSvc),
+		rbacMiddleware,
+	)
+	receivablesRepo := repositories.NewReceivablesRepo(pool)
+	notificationDigestHandlers := handlers.NewNotificationDigestHandlers(
+		jobs.NewDigestService(repositories.NewNotificationDigestRepo(pool, piiKeyring), receivablesRepo, userRepo, tenantRepo, notificationSvc),
+		rbacMiddleware,
+	)
+	reportHandlers := handlers.NewReportHandlers(
+		analytics.NewInventoryAgingService(inventoryTransactionRepo),
+		analytics.NewReceivablesAgingService(receivablesRepo, distributorRepo, tenantCalendarRepo),
+		analytics.NewCashFlowProjectionService(repositories.NewCashFlowRepo(pool), receivablesRepo),
+		rbacMiddleware,
+	)
+	purchaseReceiptHandlers := handlers.NewPurchaseReceiptHandlers(
+		services.NewPurchaseReceiptService(purchaseReceiptRepo, orderRepo, productRepo, inventoryRepo, inventoryService, consignmentRepo, storageConditionSvc, statusHistoryRepo, orderWorkflowSvc, warehouseUtilizationSvc),
+		rbacMiddleware,
+	)
+	purchaseReturnHandlers := handlers.NewPurchaseReturnHandlers(
+		services.NewPurchaseReturnService(repositories.NewPurchaseReturnRepo(pool), orderRepo, supplierRepo, inventoryRepo, inventoryService, periodCloseSvc),
+		rbacMiddleware,
+	)
+	purchaseRequisitionHandlers := handlers.NewPurchaseRequisitionHandlers(
+		services.NewPurchaseRequisitionService(repositories.NewPurchaseRequisitionRepo(pool), productRepo, warehouseRepo, supplierRepo, orderRepo, purchaseReceiptRepo, orderSvc),
+		approvalDelegationSvc,
+		rbacMiddleware,
+	)
+	advanceBookingHandlers := handlers.NewAdvanceBookingHandlers(
+		services.NewAdvanceBookingService(repositories.NewAdvanceBookingRepo(pool), distributorRepo, productRepo, warehouseRepo, orderSvc),
+		rbacMiddleware,
+	)
+	counterSaleHandlers := handlers.NewCounterSaleHandlers(
+		services.NewCounterSaleService(repositories.NewCounterSaleRepo(pool), orderSvc, invoiceSvc, productRepo, distributorRepo, warehouseRepo),
+		rbacMiddleware,
+	)
+	consignmentHandlers := handlers.NewConsignmentHandlers(consignmentSvc, rbacMiddleware)
+	bundleHandlers := handlers.NewBundleHandlers(bundleSvc, rbacMiddleware)
+	productTemplateHandlers := handlers.NewProductTemplateHandlers(
+		services.NewProductTemplateService(productTemplateRepo, categoryRepo, productRepo, productSvc, storageConditionSvc),
+		rbacMiddleware,
+	)
+	complianceHandlers := handlers.NewComplianceHandlers(complianceSvc, rbacMiddleware)
+	stockMinimumHandlers := handlers.NewStockMinimumHandlers(
+		services.NewStockMinimumService(stockMinimumRepo, productRepo, warehouseRepo),
+		rbacMiddleware,
+	)
+	storageConditionHandlers := handlers.NewStorageConditionHandlers(
+		storageConditionSvc,
+		analytics.NewStoragePlacementService(storageConditionRepo),
+		rbacMiddleware,
+	)
+	warehouseCapacityHandlers := handlers.NewWarehouseCapacityHandlers(
+		services.NewProductDimensionsService(warehouseCapacityRepo, productRepo),
+		warehouseUtilizationSvc,
+		rbacMiddleware,
+	)
+	inboundAppointmentHandlers := handlers.NewInboundAppointmentHandlers(
+		services.NewInboundAppointmentService(repositories.NewInboundAppointmentRepo(pool), warehouseRepo, orderRepo, tenantCalendarSvc),
+		rbacMiddleware,
+	)
+	analyticsViewHandlers := handlers.NewAnalyticsViewHandlers(analyticsSvc, cacheSvc, tenantCalendarSvc, rbacMiddleware)
+	stockOutHandlers := handlers.NewStockOutHandlers(analytics.NewStockOutService(stockOutRepo), rbacMiddleware)
+	profitabilityHandlers := handlers.NewProfitabilityHandlers(
+		analytics.NewProfitabilityService(repositories.NewProfitabilityRepo(pool), cacheSvc),
+		tenantCalendarSvc,
+		rbacMiddleware,
+	)
+	classificationHandlers := handlers.NewClassificationHandlers(
+		analytics.NewProductClassificationService(repositories.NewProductClassificationRepo(pool), productRepo),
+		rbacMiddleware,
+	)
+	salesVisitHandlers := handlers.NewSalesVisitHandlers(
+		services.NewSalesVisitService(repositories.NewSalesVisitRepo(pool), distributorRepo, minioSvc),
+		rbacMiddleware,
+	)
+	exportInvoiceSvc := services.NewExportInvoiceService(repositories.NewExportInvoiceRepo(pool), invoiceRepo, orderRepo)
+	consolidatedInvoiceSvc := services.NewConsolidatedInvoiceService(repositories.NewConsolidatedInvoiceRepo(pool), invoiceRepo, withholdingTaxRepo, statusHistoryRepo, tenantCalendarSvc)
+	upiPaymentSvc := services.NewUPIPaymentService(repositories.NewTenantPaymentSettingsRepo(pool), tenantRepo)
+	tenantPaymentSettingsHandlers := handlers.NewTenantPaymentSettingsHandlers(upiPaymentSvc, rbacMiddleware)
+	invoiceArchive := jobs.NewInvoiceArchiveService(repositories.NewInvoiceDocumentRepo(pool), invoiceRepo, orderRepo, productRepo,
+		exportInvoiceSvc, consolidatedInvoiceSvc, upiPaymentSvc, minioSvc)
+	invoiceHandlers := handlers.NewInvoiceHandlers(invoiceSvc, orderSvc, exportInvoiceSvc, upiPaymentSvc, invoiceArchive)
+	exportInvoiceHandlers := handlers.NewExportInvoiceHandlers(exportInvoiceSvc, rbacMiddleware)
+	consolidatedInvoiceHandlers := handlers.NewConsolidatedInvoiceHandlers(consolidatedInvoiceSvc, rbacMiddleware)
+	invoiceAmendmentHandlers := handlers.NewInvoiceAmendmentHandlers(
+		services.NewInvoiceAmendmentService(repositories.NewInvoiceAmendmentRepo(pool), invoiceRepo, withholdingTaxRepo, statusHistoryRepo, tenantCalendarSvc),
+		rbacMiddleware,
+	)
+	salesTargetHandlers := handlers.NewSalesTargetHandlers(
+		services.NewSalesTargetService(repositories.NewSalesTargetRepo(pool), userRepo, categoryRepo),
+		rbacMiddleware,
+	)
+	territorySvc := services.NewTerritoryService(repositories.NewTerritoryRepo(pool))
+	territoryHandlers := handlers.NewTerritoryHandlers(territorySvc, rbacMiddleware)
+	// Users restricted to a territory only see territory analytics
+	tenantWide := middleware.NewTerritoryScopeMiddleware(territorySvc).RequireTenantWide()
+	commissionHandlers := handlers.NewCommissionHandlers(
+		services.NewCommissionService(repositories.NewCommissionRepo(pool), userRepo),
+		rbacMiddleware,
+	)
+	expenseHandlers := handlers.NewExpenseHandlers(
+		services.NewExpenseService(repositories.NewExpenseRepo(pool), warehouseRepo, minioSvc),
+		rbacMiddleware,
+	)
+	inboundWebhookHandlers := handlers.NewInboundWebhookHandlers(
+		services.NewInboundWebhookService(repositories.NewInboundWebhookRepo(pool), productRepo, warehouseRepo, distributorRepo, orderSvc, invoiceSvc),
+		rbacMiddleware,
+	)
+	emailOrderHandlers := handlers.NewEmailOrderHandlers(
+		services.NewEmailOrderService(repositories.NewEmailOrderRepo(pool), productRepo, warehouseRepo, distributorRepo, orderSvc),
+		rbacMiddleware,
+	)
+	purchaseCaptureHandlers := handlers.NewPurchaseCaptureHandlers(
+		services.NewPurchaseCaptureService(repositories.NewPurchaseCaptureRepo(pool, piiKeyring), supplierRepo, warehouseRepo, productRepo, orderSvc, minioSvc, ocrDriver),
+		rbacMiddleware,
+	)
+
+	// Create Echo instance
+	e := echo.New()
+	// Leaves cost and margin fields out of responses for users not allowed to see them
+	e.JSONSerializer = handlers.NewPermissionSerializer(rbacService)
+
+	// Global middleware
+	e.Use(echoMiddleware.Logger())
+	e.Use(echoMiddleware.Recover())
+	if len(cfg.CookieAuth.AllowedOrigins) > 0 {
+		// Cookies are only sent cross-origin to listed origins with credentials allowed
+		e.Use(echoMiddleware.CORSWithConfig(echoMiddleware.CORSConfig{
+			AllowOrigins:     cfg.CookieAuth.AllowedOrigins,
+			AllowCredentials: true,
+			AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization,
+				middleware.CSRFHeader, middleware.ClientTypeHeader},
+		}))
+	} else {
+		e.Use(echoMiddleware.CORS())
+	}
+	e.Use(echoMiddleware.RemoveTrailingSlash())
+	e.Use(middleware.RequestContext())
+
+	// Read-only and maintenance modes; the global mode is enforced here and
+	// tenant modes once the caller is authenticated
+	operationalModeMiddleware := middleware.NewOperationalModeMiddleware(operationalModeSvc)
+	e.Use(operationalModeMiddleware.Enforce())
+
+	// Version middleware
+	versionMiddleware := middleware.NewVersionMiddleware()
+	e.Use(versionMiddleware.APIVersionResolver())
+
+	// Health endpoints (no auth required)
+	e.GET("/health", handlers.HealthCheck)
+	e.GET("/health/ready", handlers.ReadinessCheck)
+	e.GET("/health/detailed", func(c echo.Context) error {
+		return handlers.HealthCheckDetailed(c, pool)
+	})
+
+	// Public signing keys for other services validating our tokens (no auth required)
+	e.GET("/.well-known/jwks.json", jwksHandlers.JWKS)
+
+	// Metrics endpoint (no auth required)
+	e.GET("/metrics", func(c echo.Context) error {
+		return handlers.MetricsHandler(c, pool)
+	})
+
+	// Documentation static files (no auth required)
+	e.Static("/docs", "docs")
+
+	// API routes
+	v1 := e.Group("/v1")
+	v1.Use(versionMiddleware.VersionHeader("v1"))
+	v1.Use(middleware.CSRFProtection())
+
+	// Documentation routes (no auth required)
+	v1.GET("/docs/guide", handlers.DocumentationGuideHandler)
+	v1.GET("/docs/spec", handlers.DocumentationSpecHandler)
+
+	// Authentication routes (no JWT required for signup/login)
+	auth := v1.Group("/auth")
+	auth.POST("/signup", authHandlers.Signup)
+	auth.POST("/login", authHandlers.Login)
+	auth.POST("/refresh", authHandlers.Refresh)
+
+	// Storefront catalog routes (catalog token instead of JWT)
+	catalog := v1.Group("/catalog/public")
+	catalog.Use(catalogHandlers.RequireCatalogToken())
+	catalog.GET("/products", catalogHandlers.ListCatalogProducts)
+	catalog.GET("/products/:id", catalogHandlers.GetCatalogProduct)
+	catalog.GET("/images/:id", catalogHandlers.GetCatalogImage)
+
+	// Inbound marketplace orders (signed with the channel secret instead of JWT)
+	v1.POST("/integrations/marketplaces/:channel_id/orders", marketplaceHandlers.IngestOrder)
+
+	// WhatsApp provider callbacks (verified by signature or token instead of JWT)
+	v1.GET("/webhooks/whatsapp", whatsAppHandlers.VerifyWebhook)
+	v1.POST("/webhooks/whatsapp", whatsAppHandlers.ReceiveWebhook)
+
+	// Tenant-configured inbound webhooks (verified by the webhook's secret instead of JWT)
+	v1.POST("/hooks/:slug", inboundWebhookHandlers.ReceiveWebhook)
+
+	// Inbound parse webhook for dealer order emails (the token in the path identifies the tenant)
+	v1.POST("/inbound-email/:token", emailOrderHandlers.ReceiveEmail)
+
+	// Public status page (no auth required)
+	v1.GET("/status", statusHandlers.GetStatus)
+	v1.GET("/status/incidents", statusHandlers.GetStatusHistory)
+
+	// Protected routes (require JWT and RBAC)
+	protected := v1.Group("")
+	protected.Use(middleware.JWTMiddleware(userRepo, userRoleRepo, impersonationRepo, keyRing, authService))
+	protected.Use(auditMiddleware.AuditImpersonatedRequests())
+	protected.Use(operationalModeMiddleware.Enforce())
+	protected.Use(middleware.NewRateLimitMiddleware(cacheSvc, sandboxSvc).LimitUser())
+	// Bodies are only captured for tenants with the payload_logging flag on
+	protected.Use(payloadLogMiddleware.LogPayloads(middleware.PayloadLogOptions{}))
+
+	// Protected auth routes
+	protected.POST("/auth/logout", authHandlers.Logout)
+
+	// Impersonation routes (platform admin only)
+	protected.POST("/admin/impersonations", impersonationHandlers.StartImpersonation)
+	protected.GET("/admin/impersonations", impersonationHandlers.ListImpersonations)
+	protected.DELETE("/admin/impersonations/:id", impersonationHandlers.EndImpersonation)
+	protected.POST("/admin/jwt/rotate", jwksHandlers.RotateSigningKey)
+	protected.POST("/admin/jwt/keys/:kid/revoke", jwksHandlers.RevokeSigningKey)
+
+	// Feature flag routes (platform admin only, except the tenant's own features)
+	protected.GET("/admin/feature-flags", featureFlagHandlers.ListFeatureFlags)
+	protected.GET("/admin/feature-flags/:key", featureFlagHandlers.GetFeatureFlag)
+	protected.PUT("/admin/feature-flags/:key", featureFlagHandlers.SaveFeatureFlag)
+	protected.DELETE("/admin/feature-flags/:key", featureFlagHandlers.DeleteFeatureFlag)
+	protected.PUT("/admin/feature-flags/:key/tenants/:tenant_id", featureFlagHandlers.SetFeatureFlagOverride)
+	protected.DELETE("/admin/feature-flags/:key/tenants/:tenant_id", featureFlagHandlers.DeleteFeatureFlagOverride)
+	protected.GET("/features", featureFlagHandlers.GetFeatures)
+
+	// Operational mode routes (platform admin only)
+	protected.GET("/admin/operational-modes", operationalModeHandlers.ListOperationalModes)
+	protected.PUT("/admin/operational-modes/global", operationalModeHandlers.SetGlobalOperationalMode)
+	protected.PUT("/admin/operational-modes/tenants/:tenant_id", operationalModeHandlers.SetTenantOperationalMode)
+
+	// Incident routes for the status page (platform admin only)
+	protected.GET("/admin/incidents", statusHandlers.ListIncidents)
+	protected.POST("/admin/incidents", statusHandlers.CreateIncident)
+	protected.GET("/admin/incidents/:id", statusHandlers.GetIncident)
+	protected.POST("/admin/incidents/:id/updates", statusHandlers.PostIncidentUpdate)
+
+	// Sandbox tenant routes (platform admin only)
+	protected.GET("/admin/sandboxes", sandboxHandlers.ListSandboxes)
+	protected.PUT("/admin/sandboxes/:tenant_id", sandboxHandlers.EnableSandbox)
+	protected.DELETE("/admin/sandboxes/:tenant_id", sandboxHandlers.DisableSandbox)
+	protected.POST("/admin/sandboxes/:tenant_id/reset", sandboxHandlers.ResetSandbox)
+	protected.POST("/admin/tenants/:tenant_id/integrity-check", integrityHandlers.CheckTenant)
+	protected.GET("/admin/query-diagnostics", queryDiagnosticsHandlers.ListQueryOffenders)
+	protected.GET("/admin/data-residency/regions", dataResidencyHandlers.ListRegions)
+	protected.PUT("/admin/tenants/:tenant_id/data-residency", dataResidencyHandlers.SetTenantResidency)
+	protected.GET("/impersonations", impersonationHandlers.ListTenantImpersonations)
+
+	// User routes
+	protected.GET("/me", authHandlers.Me)
+	protected.GET("/users", userHandlers.ListUsers)
+	protected.GET("/users/:id", userHandlers.GetUser)
+	protected.POST("/users/:id/unlock", authHandlers.UnlockAccount)
+	protected.POST("/users", userHandlers.CreateUser)
+	protected.PUT("/users/:id", userHandlers.UpdateUser)
+	protected.DELETE("/users/:id", userHandlers.DeleteUser)
+
+	// Tenant routes
+	protected.GET("/tenants", tenantHandlers.ListTenants)
+	protected.GET("/tenants/:id", tenantHandlers.GetTenant)
+	protected.PUT("/tenants/:id", tenantHandlers.UpdateTenant)
+	protected.DELETE("/tenants/:id", tenantHandlers.DeleteTenant)
+	protected.POST("/tenants/:id/takeout", tenantTakeoutHandlers.RequestTakeout)
+	protected.GET("/tenants/:id/takeouts", tenantTakeoutHandlers.ListTakeouts)
+	protected.GET("/tenants/:id/takeouts/:takeout_id", tenantTakeoutHandlers.GetTakeout)
+	protected.GET("/tenant/calendar", tenantCalendarHandlers.GetCalendar)
+	protected.PUT("/tenant/calendar", tenantCalendarHandlers.UpdateCalendar)
+	protected.GET("/tenant/payment-settings", tenantPaymentSettingsHandlers.GetPaymentSettings)
+	protected.PUT("/tenant/payment-settings", tenantPaymentSettingsHandlers.UpdatePaymentSettings)
+
+	// Business routes
+	protected.GET("/categories", categoryHandlers.ListCategories)
+	protected.POST("/categories", categoryHandlers.CreateCategory)
+	protected.POST("/categories", categoryHandlers.CreateCategory)
+	protected.GET("/categories/:id", categoryHandlers.GetCategory)
+	protected.PUT("/categories/:id", categoryHandlers.UpdateCategory)
+	protected.DELETE("/categories/:id", categoryHandlers.DeleteCategory)
+
+	// Global search routes
+	protected.GET("/search", searchHandlers.Search)
+	protected.GET("/search/suggest", searchHandlers.Suggest)
+
+	// Product routes
+	protected.GET("/products", productHandlers.ListProducts)
+	protected.POST("/products", productHandlers.CreateProduct)
+	protected.GET("/products/:id", productHandlers.GetProduct)
+	protected.POST("/products/batch-get", productHandlers.BatchGetProducts)
+	protected.PUT("/products/:id", productHandlers.UpdateProduct)
+	protected.DELETE("/products/:id", productHandlers.DeleteProduct)
+	protected.GET("/products/search", productHandlers.SearchProducts)
+	protected.GET("/products/:id/availability", availabilityHandlers.GetProductAvailability)
+	protected.GET("/products/:id/price-history", productHandlers.GetPriceHistory)
+	protected.POST("/products/bulk/update", productHandlers.BulkUpdateProducts)
+	protected.POST("/products/bulk/create", productHandlers.BulkCreateProducts)
+	protected.POST("/products/bulk/delete", productHandlers.BulkDeleteProducts)
+	protected.GET("/bulk-operations/:id", bulkOperationHandlers.GetBulkOperation)
+
+	// Product image routes
+	protected.POST("/products/:id/images", productHandlers.UploadProductImage)
+	protected.GET("/products/:id/images", productHandlers.GetProductImages)
+	protected.GET("/products/:id/images/:imageId/url", productHandlers.GetProductImageURL)
+	protected.DELETE("/products/:id/images/:imageId", productHandlers.DeleteProductImage)
+
+	// Branches: legal entities of the tenant, each with its own GSTIN
+	protected.GET("/branches", branchHandlers.ListBranches)
+	protected.POST("/branches", branchHandlers.CreateBranch)
+	protected.GET("/branches/:id", branchHandlers.GetBranch)
+	protected.PUT("/branches/:id", branchHandlers.UpdateBranch)
+	protected.DELETE("/branches/:id", branchHandlers.DeleteBranch)
+
+	protected.GET("/warehouses", warehouseHandlers.ListWarehouses)
+	protected.POST("/warehouses", warehouseHandlers.CreateWarehouse)
+	protected.GET("/warehouses/:id", warehouseHandlers.GetWarehouse)
+	protected.PUT("/warehouses/:id", warehouseHandlers.UpdateWarehouse)
+	protected.DELETE("/warehouses/:id", warehouseHandlers.DeleteWarehouse)
+	protected.PUT("/warehouses/:id/default", warehouseHandlers.SetDefaultWarehouse)
+
+	protected.GET("/distributors", distributorHandlers.ListDistributors)
+	protected.GET("/distributors/lookup", distributorHandlers.LookupDistributors)
+	protected.POST("/distributors", distributorHandlers.CreateDistributor)
+	protected.GET("/distributors/:id", distributorHandlers.GetDistributor)
+	protected.PUT("/distributors/:id", distributorHandlers.UpdateDistributor)
+	protected.DELETE("/distributors/:id", distributorHandlers.DeleteDistributor)
+
+	// Customer statements of account; customers are distributors
+	protected.GET("/customers/:id/statement", statementHandlers.GetStatement)
+	protected.GET("/customers/:id/credit-notes", statementHandlers.ListCreditNotes)
+	protected.POST("/customers/:id/credit-notes", statementHandlers.IssueCreditNote)
+
+	// Customer payments and their allocation across invoices
+	protected.POST("/customers/:id/payments", paymentAllocationHandlers.RecordPayment)
+	protected.GET("/customers/:id/payments", paymentAllocationHandlers.ListPayments)
+	protected.GET("/customers/:id/open-invoices", paymentAllocationHandlers.ListOpenInvoices)
+	protected.GET("/payments/:id", paymentAllocationHandlers.GetPayment)
+	protected.POST("/payments/:id/allocate", paymentAllocationHandlers.AllocatePayment)
+	protected.POST("/payments/:id/reallocate", paymentAllocationHandlers.ReallocatePayment)
+	protected.GET("/payments/:id/allocations", paymentAllocationHandlers.ListPaymentAllocations)
+	protected.GET("/invoices/:id/allocations", paymentAllocationHandlers.ListInvoiceAllocations)
+
+	// TDS deducted by customers and TCS collected on sales invoices
+	protected.GET("/withholding-tax/sections", withholdingTaxHandlers.ListSections)
+	protected.POST("/withholding-tax/sections", withholdingTaxHandlers.CreateSection)
+	protected.PUT("/withholding-tax/sections/:id", withholdingTaxHandlers.UpdateSection)
+	protected.GET("/invoices/:id/tcs", withholdingTaxHandlers.GetInvoiceTCS)
+	protected.PUT("/payments/:id/tds-certificate", withholdingTaxHandlers.RecordTDSCertificate)
+	protected.GET("/reports/tds-tcs", withholdingTaxHandlers.GetQuarterlySummary)
+
+	protected.GET("/suppliers", supplierHandlers.ListSuppliers)
+	protected.POST("/suppliers", supplierHandlers.CreateSupplier)
+	protected.GET("/suppliers/:id", supplierHandlers.GetSupplier)
+	protected.PUT("/suppliers/:id", supplierHandlers.UpdateSupplier)
+	protected.DELETE("/suppliers/:id", supplierHandlers.DeleteSupplier)
+	protected.GET("/suppliers/:id/statement", purchaseReturnHandlers.GetSupplierStatement)
+	protected.POST("/suppliers/:id/price-lists", supplierPriceListHandlers.CreatePriceList)
+	protected.GET("/suppliers/:id/price-lists", supplierPriceListHandlers.ListPriceLists)
+	protected.GET("/price-lists/:id", supplierPriceListHandlers.GetPriceList)
+	protected.DELETE("/price-lists/:id", supplierPriceListHandlers.DeletePriceList)
+	protected.GET("/products/:id/supplier-quotes", supplierPriceListHandlers.CompareSupplierQuotes)
+
+	protected.GET("/inventory", inventoryHandlers.ListInventories)
+	protected.POST("/inventory", inventoryHandlers.CreateInventory)
+	protected.GET("/inventory/:id", inventoryHandlers.GetInventory)
+	protected.POST("/inventory/batch-get", inventoryHandlers.BatchGetInventories)
+	protected.PUT("/inventory/:id", inventoryHandlers.UpdateInventory)
+	protected.DELETE("/inventory/:id", inventoryHandlers.DeleteInventory)
+	protected.GET("/inventory/search", inventoryHandlers.SearchInventories)
+	protected.POST("/inventory/reconciliations", inventoryReconciliationHandlers.CreateReconciliation)
+	protected.GET("/inventory/reconciliations", inventoryReconciliationHandlers.ListReconciliations)
+	protected.GET("/inventory/reconciliations/:id", inventoryReconciliationHandlers.GetReconciliation)
+	protected.POST("/inventory/reconciliations/:id/apply", inventoryReconciliationHandlers.ApplyReconciliation)
+	protected.POST("/inventory/reconciliations/:id/discard", inventoryReconciliationHandlers.DiscardReconciliation)
+
+	protected.GET("/orders", orderHandlers.GetOrders)
+	protected.POST("/orders", orderHandlers.CreateOrder)
+	protected.GET("/orders/:id", orderHandlers.GetOrder)
+	protected.PUT("/orders/:id", orderHandlers.UpdateOrder)
+	protected.DELETE("/orders/:id", orderHandlers.DeleteOrder)
+	protected.POST("/orders/bulk/cancel", orderHandlers.BulkCancelOrders)
+	protected.GET("/orders/workflow", orderWorkflowHandlers.GetOrderWorkflow)
+	protected.PUT("/orders/workflow", orderWorkflowHandlers.SetOrderWorkflow)
+	protected.DELETE("/orders/workflow", orderWorkflowHandlers.DeleteOrderWorkflow)
+	protected.GET("/orders/:id/history", orderHandlers.GetOrderHistory)
+	protected.POST("/orders/:id/approve", orderHandlers.ApproveOrder)
+	protected.POST("/orders/:id/process", orderHandlers.ProcessOrder)
+	protected.POST("/orders/:id/quality-check", orderHandlers.QualityCheckOrder)
+	protected.POST("/orders/:id/ship", orderHandlers.ShipOrder)
+	protected.POST("/orders/:id/receive", orderHandlers.ReceiveOrder)
+	protected.POST("/orders/:id/deliver", orderHandlers.DeliverOrder)
+	protected.POST("/orders/:id/cancel", orderHandlers.CancelOrder)
+	protected.POST("/purchase-receipts", purchaseReceiptHandlers.ReceivePurchase)
+	protected.GET("/purchase-receipts", purchaseReceiptHandlers.ListReceipts)
+	protected.GET("/purchase-receipts/:id", purchaseReceiptHandlers.GetReceipt)
+	protected.POST("/purchase-returns", purchaseReturnHandlers.CreatePurchaseReturn)
+	protected.GET("/purchase-returns", purchaseReturnHandlers.ListPurchaseReturns)
+	protected.GET("/purchase-returns/:id", purchaseReturnHandlers.GetPurchaseReturn)
+	protected.GET("/debit-notes", purchaseReturnHandlers.ListDebitNotes)
+	protected.POST("/purchase-requisitions", purchaseRequisitionHandlers.RaiseRequisition)
+	protected.GET("/purchase-requisitions", purchaseRequisitionHandlers.ListRequisitions)
+	protected.POST("/purchase-requisitions/convert", purchaseRequisitionHandlers.ConvertRequisitions)
+	protected.GET("/purchase-requisitions/:id", purchaseRequisitionHandlers.GetRequisition)
+	protected.POST("/purchase-requisitions/:id/approve", purchaseRequisitionHandlers.ApproveRequisition)
+	protected.POST("/purchase-requisitions/:id/reject", purchaseRequisitionHandlers.RejectRequisition)
+	protected.POST("/purchase-requisitions/:id/cancel", purchaseRequisitionHandlers.CancelRequisition)
+
+	// Approval delegation: route an approver's pending approvals to a delegate while away
+	protected.GET("/approvals/pending", approvalDelegationHandlers.GetPendingApprovals)
+	protected.GET("/approval-delegations", approvalDelegationHandlers.ListDelegations)
+	protected.POST("/approval-delegations", approvalDelegationHandlers.CreateDelegation)
+	protected.GET("/approval-delegations/:id", approvalDelegationHandlers.GetDelegation)
+	protected.POST("/approval-delegations/:id/revoke", approvalDelegationHandlers.RevokeDelegation)
+
+	protected.POST("/bookings", advanceBookingHandlers.CreateBooking)
+	protected.GET("/bookings", advanceBookingHandlers.ListBookings)
+	protected.GET("/bookings/due", advanceBookingHandlers.ListDueBookings)
+	protected.GET("/bookings/:id", advanceBookingHandlers.GetBooking)
+	protected.POST("/bookings/:id/payments", advanceBookingHandlers.RecordPayment)
+	protected.POST("/bookings/:id/cancel", advanceBookingHandlers.CancelBooking)
+	protected.POST("/bookings/:id/convert", advanceBookingHandlers.ConvertBooking)
+	protected.GET("/analytics/booking-demand", advanceBookingHandlers.GetBookingDemand)
+
+	// Point of sale counter routes
+	protected.POST("/pos/sales", counterSaleHandlers.CreateCounterSale)
+	protected.POST("/pos/registers", counterSaleHandlers.OpenRegister)
+	protected.GET("/pos/registers", counterSaleHandlers.ListRegisters)
+	protected.GET("/pos/registers/:id", counterSaleHandlers.GetRegister)
+	protected.POST("/pos/registers/:id/close", counterSaleHandlers.CloseRegister)
+	protected.GET("/pos/day-book", counterSaleHandlers.GetDayBook)
+
+	// Compliance routes
+	protected.POST("/compliance/licenses", complianceHandlers.CreateLicense)
+	protected.GET("/compliance/licenses", complianceHandlers.ListLicenses)
+	protected.GET("/compliance/licenses/:id", complianceHandlers.GetLicense)
+	protected.PUT("/compliance/licenses/:id", complianceHandlers.UpdateLicense)
+	protected.DELETE("/compliance/licenses/:id", complianceHandlers.DeleteLicense)
+	protected.POST("/compliance/licenses/:id/document", complianceHandlers.UploadLicenseDocument)
+	protected.GET("/compliance/licenses/:id/document", complianceHandlers.GetLicenseDocumentURL)
+	protected.GET("/compliance/status", complianceHandlers.GetComplianceStatus)
+	protected.PUT("/products/:id/license-requirement", complianceHandlers.SetProductLicenseRequirement)
+	protected.PUT("/products/:id/regulatory-flag", complianceHandlers.SetProductRegulatoryFlag)
+	protected.GET("/orders/:id/buyer-license", complianceHandlers.GetOrderBuyerLicense)
+	protected.GET("/reports/restricted-sale-overrides", complianceHandlers.ListRestrictedSaleOverrides)
+
+	// Storage condition routes
+	protected.GET("/products/:id/storage-requirements", storageConditionHandlers.GetProductStorageRequirement)
+	protected.PUT("/products/:id/storage-requirements", storageConditionHandlers.SetProductStorageRequirement)
+	protected.GET("/products/:id/stock-minimums", stockMinimumHandlers.ListStockMinimums)
+	protected.PUT("/products/:id/stock-minimums", stockMinimumHandlers.SetStockMinimum)
+	protected.DELETE("/products/:id/stock-minimums/:minimum_id", stockMinimumHandlers.DeleteStockMinimum)
+	protected.GET("/warehouses/:id/storage-capabilities", storageConditionHandlers.GetWarehouseStorageCapability)
+	protected.PUT("/warehouses/:id/storage-capabilities", storageConditionHandlers.SetWarehouseStorageCapability)
+	protected.GET("/reports/storage-compliance", storageConditionHandlers.GetStorageCompliance)
+
+	// Warehouse capacity: product unit dimensions and utilization against
+	// unit, volume and pallet capacities
+	protected.GET("/products/:id/dimensions", warehouseCapacityHandlers.GetProductDimensions)
+	protected.PUT("/products/:id/dimensions", warehouseCapacityHandlers.SetProductDimensions)
+	protected.GET("/analytics/warehouse-utilization", warehouseCapacityHandlers.GetWarehouseUtilization)
+
+	// Inbound delivery appointments at warehouse docks
+	protected.GET("/warehouses/:id/appointments", inboundAppointmentHandlers.GetSchedule)
+	protected.POST("/warehouses/:id/appointments", inboundAppointmentHandlers.BookAppointment)
+	protected.GET("/appointments/:id", inboundAppointmentHandlers.GetAppointment)
+	protected.PUT("/appointments/:id", inboundAppointmentHandlers.RescheduleAppointment)
+	protected.POST("/appointments/:id/cancel", inboundAppointmentHandlers.CancelAppointment)
+	protected.GET("/consignment/stock", consignmentHandlers.ListStock)
+	protected.POST("/consignment/settlements", consignmentHandlers.Settle)
+	protected.GET("/reports/consignment-settlement", consignmentHandlers.GetSettlementReport)
+
+	protected.GET("/pricing/margin-policy", marginHandlers.GetMarginPolicy)
+	protected.PUT("/pricing/margin-policy", marginHandlers.SetMarginPolicy)
+	protected.DELETE("/pricing/margin-policy", marginHandlers.DeleteMarginPolicy)
+	protected.GET("/reports/margin-violations", marginHandlers.ListMarginViolations)
+	protected.GET("/reports/inventory-aging", reportHandlers.GetInventoryAging)
+	protected.GET("/reports/receivables-aging", reportHandlers.GetReceivablesAging, tenantWide)
+	protected.GET("/reports/receivables-aging/:customer_id", reportHandlers.GetCustomerReceivables, tenantWide)
+	protected.GET("/reports/cashflow-projection", reportHandlers.GetCashFlowProjection, tenantWide)
+	protected.GET("/dashboard/receivables-aging", reportHandlers.GetReceivablesSummary, tenantWide)
+
+	protected.GET("/seasons", seasonHandlers.ListSeasons)
+	protected.POST("/seasons", seasonHandlers.CreateSeason)
+	protected.POST("/seasons/defaults", seasonHandlers.CreateDefaultSeasons)
+	protected.PUT("/seasons/:id", seasonHandlers.UpdateSeason)
+	protected.DELETE("/seasons/:id", seasonHandlers.DeleteSeason)
+	protected.GET("/products/:id/seasons", seasonHandlers.GetProductSeasons)
+	protected.PUT("/products/:id/seasons", seasonHandlers.SetProductSeasons)
+	protected.GET("/analytics/seasonal-demand", seasonHandlers.GetSeasonalDemand)
+	protected.GET("/analytics/stocking-suggestions", seasonHandlers.GetStockingSuggestions)
+	protected.GET("/analytics/abc-xyz", classificationHandlers.GetClassification)
+	protected.POST("/analytics/abc-xyz/run", classificationHandlers.RunClassification)
+	protected.GET("/analytics/daily-sales", analyticsViewHandlers.GetDailySales, tenantWide)
+	protected.GET("/analytics/stock-by-category", analyticsViewHandlers.GetStockByCategory)
+	protected.GET("/analytics/receivables-aging", analyticsViewHandlers.GetReceivablesAging, tenantWide)
+	protected.POST("/analytics/views/refresh", analyticsViewHandlers.RefreshViews)
+	protected.GET("/analytics/profitability", profitabilityHandlers.GetProfitability, tenantWide)
+	protected.GET("/analytics/stock-outs", stockOutHandlers.GetStockOuts)
+	protected.GET("/analytics/stock-outs/trend", stockOutHandlers.GetFillRateTrend)
+	protected.GET("/analytics/stock-outs/events", stockOutHandlers.ListStockOutEvents)
+	protected.GET("/products/:id/replenishment-policy", classificationHandlers.GetReplenishmentPolicy)
+	protected.GET("/products/:id/components", bundleHandlers.GetComponents)
+	protected.PUT("/products/:id/components", bundleHandlers.SetComponents)
+	protected.POST("/products/:id/assemble", bundleHandlers.Assemble)
+	protected.POST("/products/:id/disassemble", bundleHandlers.Disassemble)
+	protected.POST("/products/from-template/:templateId", productTemplateHandlers.CreateFromTemplate)
+	protected.POST("/products/:id/clone", productTemplateHandlers.CloneProduct)
+	protected.GET("/product-templates", productTemplateHandlers.ListTemplates)
+	protected.POST("/product-templates", productTemplateHandlers.CreateTemplate)
+	protected.GET("/product-templates/:id", productTemplateHandlers.GetTemplate)
+	protected.PUT("/product-templates/:id", productTemplateHandlers.UpdateTemplate)
+	protected.DELETE("/product-templates/:id", productTemplateHandlers.DeleteTemplate)
+
+	protected.POST("/products/:id/publish", catalogHandlers.PublishProduct)
+	protected.DELETE("/products/:id/publish", catalogHandlers.UnpublishProduct)
+	protected.POST("/catalog/tokens", catalogHandlers.CreateToken)
+	protected.GET("/catalog/tokens", catalogHandlers.ListTokens)
+	protected.DELETE("/catalog/tokens/:id", catalogHandlers.RevokeToken)
+	protected.POST("/catalogs/generate", catalogExportHandlers.GenerateCatalog)
+	protected.GET("/catalogs/exports", catalogExportHandlers.ListCatalogExports)
+	protected.GET("/catalogs/exports/:id", catalogExportHandlers.GetCatalogExport)
+	protected.GET("/marketplaces/channels", marketplaceHandlers.ListChannels)
+	protected.POST("/marketplaces/channels", marketplaceHandlers.CreateChannel)
+	protected.PUT("/marketplaces/channels/:id", marketplaceHandlers.UpdateChannel)
+	protected.DELETE("/marketplaces/channels/:id", marketplaceHandlers.DeleteChannel)
+	protected.GET("/marketplaces/orders", marketplaceHandlers.ListOrders)
+	protected.POST("/marketplaces/sync", marketplaceHandlers.SyncStatuses)
+
+	// Inbound webhook endpoints and their delivery log
+	protected.GET("/inbound-webhooks", inboundWebhookHandlers.ListInboundWebhooks)
+	protected.POST("/inbound-webhooks", inboundWebhookHandlers.CreateInboundWebhook)
+	protected.GET("/inbound-webhooks/:id", inboundWebhookHandlers.GetInboundWebhook)
+	protected.PUT("/inbound-webhooks/:id", inboundWebhookHandlers.UpdateInboundWebhook)
+	protected.DELETE("/inbound-webhooks/:id", inboundWebhookHandlers.DeleteInboundWebhook)
+	protected.POST("/inbound-webhooks/:id/rotate-secret", inboundWebhookHandlers.RotateInboundWebhookSecret)
+	protected.GET("/inbound-webhooks/:id/deliveries", inboundWebhookHandlers.ListInboundDeliveries)
+
+	// Email-to-order ingestion
+	protected.GET("/email-orders/settings", emailOrderHandlers.GetEmailOrderSettings)
+	protected.PUT("/email-orders/settings", emailOrderHandlers.SaveEmailOrderSettings)
+	protected.POST("/email-orders/settings/rotate-token", emailOrderHandlers.RotateEmailOrderToken)
+	protected.GET("/email-orders/senders", emailOrderHandlers.ListEmailOrderSenders)
+	protected.POST("/email-orders/senders", emailOrderHandlers.AddEmailOrderSender)
+	protected.DELETE("/email-orders/senders/:id", emailOrderHandlers.RemoveEmailOrderSender)
+	protected.GET("/email-orders/emails", emailOrderHandlers.ListInboundEmails)
+	protected.GET("/email-orders/drafts", emailOrderHandlers.ListEmailDraftOrders)
+	protected.GET("/email-orders/drafts/:id", emailOrderHandlers.GetEmailDraftOrder)
+	protected.PUT("/email-orders/drafts/:id", emailOrderHandlers.UpdateEmailDraftOrder)
+	protected.POST("/email-orders/drafts/:id/approve", emailOrderHandlers.ApproveEmailDraftOrder)
+	protected.POST("/email-orders/drafts/:id/reject", emailOrderHandlers.RejectEmailDraftOrder)
+
+	// OCR capture of supplier invoices into purchase orders
+	protected.GET("/purchase-captures", purchaseCaptureHandlers.ListPurchaseCaptures)
+	protected.POST("/purchase-captures", purchaseCaptureHandlers.UploadPurchaseInvoice)
+	protected.GET("/purchase-captures/duplicates", purchaseCaptureHandlers.GetDuplicateBillReport)
+	protected.GET("/purchase-captures/:id", purchaseCaptureHandlers.GetPurchaseCapture)
+	protected.PUT("/purchase-captures/:id", purchaseCaptureHandlers.UpdatePurchaseCapture)
+	protected.GET("/purchase-captures/:id/duplicates", purchaseCaptureHandlers.CheckPurchaseCaptureDuplicates)
+	protected.POST("/purchase-captures/:id/confirm", purchaseCaptureHandlers.ConfirmPurchaseCapture)
+	protected.POST("/purchase-captures/:id/discard", purchaseCaptureHandlers.DiscardPurchaseCapture)
+
+	protected.GET("/erp/connectors", erpHandlers.ListConnectors)
+	protected.POST("/erp/connectors", erpHandlers.CreateConnector)
+	protected.PUT("/erp/connectors/:id", erpHandlers.UpdateConnector)
+	protected.DELETE("/erp/connectors/:id", erpHandlers.DeleteConnector)
+	protected.POST("/erp/connectors/:id/sync", erpHandlers.SyncConnector)
+	protected.GET("/erp/connectors/:id/runs", erpHandlers.ListRuns)
+	protected.GET("/erp/runs/:id/download", erpHandlers.DownloadRun)
+	protected.GET("/erp/sync-status", erpHandlers.GetSyncStatus)
+
+	// Data warehouse exports of fact tables to a tenant's BigQuery or ClickHouse
+	protected.GET("/data-exports", dataExportHandlers.ListDestinations)
+	protected.POST("/data-exports", dataExportHandlers.CreateDestination)
+	protected.PUT("/data-exports/:id", dataExportHandlers.UpdateDestination)
+	protected.DELETE("/data-exports/:id", dataExportHandlers.DeleteDestination)
+	protected.POST("/data-exports/:id/sync", dataExportHandlers.SyncDestination)
+	protected.POST("/data-exports/:id/reset", dataExportHandlers.ResetDestination)
+	protected.GET("/data-exports/:id/runs", dataExportHandlers.ListRuns)
+	protected.POST("/tally/exports/:entity", tallyHandlers.ExportIncremental)
+	protected.GET("/tally/exports/:id/download", tallyHandlers.DownloadExport)
+	protected.POST("/tally/cursors/:entity/reset", tallyHandlers.ResetCursor)
+	protected.POST("/tally/import", tallyHandlers.Import)
+	protected.GET("/tally/sync-status", tallyHandlers.GetSyncStatus)
+	protected.PUT("/tally/sync-settings", tallyHandlers.UpdateSyncSettings)
+	protected.GET("/sync/changes", syncHandlers.GetChanges)
+	protected.POST("/sync/orders", syncHandlers.UploadOrders)
+	protected.POST("/devices", deviceHandlers.RegisterDevice)
+	protected.GET("/devices", deviceHandlers.ListDevices)
+	protected.POST("/devices/unregister", deviceHandlers.UnregisterToken)
+	protected.DELETE("/devices/:id", deviceHandlers.UnregisterDevice)
+	protected.GET("/notifications/digest", notificationDigestHandlers.GetDigestPreference)
+	protected.PUT("/notifications/digest", notificationDigestHandlers.UpdateDigestPreference)
+	protected.GET("/notifications/digest/preview", notificationDigestHandlers.PreviewDigest)
+	protected.GET("/notifications/digest/deliveries", notificationDigestHandlers.ListDigestDeliveries)
+	protected.GET("/notifications/digest/template", notificationDigestHandlers.GetDigestTemplate)
+	protected.PUT("/notifications/digest/template", notificationDigestHandlers.UpdateDigestTemplate)
+	protected.DELETE("/notifications/digest/template", notificationDigestHandlers.DeleteDigestTemplate)
+
+	// WhatsApp channel routes
+	protected.GET("/whatsapp/templates", whatsAppHandlers.ListTemplates)
+	protected.POST("/whatsapp/templates", whatsAppHandlers.CreateTemplate)
+	protected.PUT("/whatsapp/templates/:id", whatsAppHandlers.UpdateTemplate)
+	protected.DELETE("/whatsapp/templates/:id", whatsAppHandlers.DeleteTemplate)
+	protected.GET("/whatsapp/contacts", whatsAppHandlers.ListContacts)
+	protected.PUT("/whatsapp/contacts", whatsAppHandlers.SetContactOptIn)
+	protected.GET("/whatsapp/messages", whatsAppHandlers.ListMessages)
+	protected.POST("/whatsapp/messages", whatsAppHandlers.SendMessage)
+	protected.POST("/visits/check-in", salesVisitHandlers.CheckIn)
+	protected.POST("/visits/:id/check-out", salesVisitHandlers.CheckOut)
+	protected.POST("/visits/:id/photos", salesVisitHandlers.UploadPhoto)
+	protected.GET("/visits", salesVisitHandlers.ListVisits)
+	protected.GET("/visits/route-report", salesVisitHandlers.RouteReport)
+	protected.GET("/visits/:id", salesVisitHandlers.GetVisit)
+	protected.GET("/warehouses/:id/weather", weatherHandlers.GetWarehouseForecast)
+	protected.GET("/weather/alert-rules", weatherHandlers.ListRules)
+	protected.POST("/weather/alert-rules", weatherHandlers.CreateRule)
+	protected.PUT("/weather/alert-rules/:id", weatherHandlers.UpdateRule)
+	protected.DELETE("/weather/alert-rules/:id", weatherHandlers.DeleteRule)
+	protected.GET("/weather/alerts", weatherHandlers.ListAlerts)
+	protected.POST("/weather/alerts/check", weatherHandlers.CheckAlerts)
+
+	protected.GET("/invoices", invoiceHandlers.ListInvoices)
+	protected.POST("/invoices", invoiceHandlers.CreateInvoice)
+	protected.GET("/invoices/:id", invoiceHandlers.GetInvoice)
+	protected.PUT("/invoices/:id", invoiceHandlers.UpdateInvoice)
+	protected.PUT("/invoices/:id/status", invoiceHandlers.UpdateInvoiceStatus)
+	protected.GET("/invoices/unpaid", invoiceHandlers.GetUnpaidInvoices)
+	protected.POST("/invoices/:id/generate-pdf", invoiceHandlers.GenerateInvoicePDF)
+	protected.GET("/invoices/:id/upi-qr", invoiceHandlers.GetInvoiceUPIQR)
+	protected.DELETE("/invoices/:id", invoiceHandlers.DeleteInvoice)
+
+	// Export invoices under LUT or on payment of IGST
+	protected.POST("/invoices/export", exportInvoiceHandlers.CreateExportInvoice)
+	protected.GET("/invoices/:id/export", exportInvoiceHandlers.GetExportInvoice)
+	protected.PUT("/invoices/:id/export/shipping", exportInvoiceHandlers.UpdateShipping)
+	protected.POST("/invoices/consolidated", consolidatedInvoiceHandlers.CreateConsolidatedInvoice)
+	protected.GET("/invoices/:id/consolidated", consolidatedInvoiceHandlers.GetConsolidatedInvoice)
+	protected.GET("/orders/:id/invoices", consolidatedInvoiceHandlers.GetOrderInvoices)
+
+	// Amendments: cancel and reissue an invoice with a credit note for the original
+	protected.POST("/invoices/:id/amend", invoiceAmendmentHandlers.AmendInvoice)
+	protected.GET("/invoices/:id/amendments", invoiceAmendmentHandlers.ListInvoiceAmendments)
+	protected.GET("/reports/gst", invoiceHandlers.GetGSTReport)
+	protected.GET("/reports/gstr1/exports", exportInvoiceHandlers.GetGSTR1Exports)
+	protected.GET("/reports/gstr3b/itc-reversals", purchaseReturnHandlers.GetITCReversals)
+
+	// Accounting period close: closed months lock their invoices and payments
+	protected.GET("/accounting-periods/closed", periodCloseHandlers.ListClosedPeriods)
+	protected.POST("/accounting-periods/close", periodCloseHandlers.ClosePeriod)
+	protected.POST("/accounting-periods/reopen", periodCloseHandlers.ReopenPeriod)
+	protected.GET("/accounting-periods/history", periodCloseHandlers.GetPeriodCloseHistory)
+
+	// Monthly sales targets per sales rep, region and product category
+	protected.GET("/sales-targets", salesTargetHandlers.ListTargets)
+	protected.POST("/sales-targets", salesTargetHandlers.CreateTarget)
+	protected.PUT("/sales-targets/:id", salesTargetHandlers.UpdateTarget)
+	protected.DELETE("/sales-targets/:id", salesTargetHandlers.DeleteTarget)
+	protected.PUT("/customers/:id/territory", salesTargetHandlers.SetCustomerTerritory)
+	protected.GET("/analytics/targets", salesTargetHandlers.GetLeaderboard)
+	protected.POST("/analytics/targets/calculate", salesTargetHandlers.CalculateAchievement)
+
+	// Commission for sales reps and agents, run monthly for payroll
+	protected.GET("/commission-agents", commissionHandlers.ListAgents)
+	protected.POST("/commission-agents", commissionHandlers.CreateAgent)
+	protected.PUT("/commission-agents/:id", commissionHandlers.UpdateAgent)
+	protected.PUT("/customers/:id/commission-agent", commissionHandlers.SetCustomerAgent)
+	protected.GET("/commission-rules", commissionHandlers.ListRules)
+	protected.POST("/commission-rules", commissionHandlers.CreateRule)
+	protected.PUT("/commission-rules/:id", commissionHandlers.UpdateRule)
+	protected.GET("/commission-runs", commissionHandlers.ListRuns)
+	protected.POST("/commission-runs", commissionHandlers.RunCommissions)
+	protected.GET("/commission-runs/:id", commissionHandlers.GetRun)
+	protected.POST("/commission-runs/:id/finalize", commissionHandlers.FinalizeRun)
+	protected.GET("/commission-runs/:id/statements/:payee_type/:payee_id", commissionHandlers.GetStatement)
+
+	// Territory hierarchy and sales by territory
+	protected.GET("/territories", territoryHandlers.ListTerritories)
+	protected.POST("/territories", territoryHandlers.CreateTerritory)
+	protected.PUT("/territories/assignments", territoryHandlers.AssignTerritory)
+	protected.PUT("/territories/:id", territoryHandlers.UpdateTerritory)
+	protected.DELETE("/territories/:id", territoryHandlers.DeleteTerritory)
+	protected.GET("/analytics/territories", territoryHandlers.GetTerritorySales)
+	protected.GET("/analytics/territories/daily-sales", territoryHandlers.GetTerritoryDailySales)
+
+	// Warehouse running costs against monthly budgets
+	protected.GET("/expense-categories", expenseHandlers.ListCategories)
+	protected.POST("/expense-categories", expenseHandlers.CreateCategory)
+	protected.PUT("/expense-categories/:id", expenseHandlers.UpdateCategory)
+	protected.GET("/expenses", expenseHandlers.ListExpenses)
+	protected.POST("/expenses", expenseHandlers.CreateExpense)
+	protected.GET("/expenses/:id", expenseHandlers.GetExpense)
+	protected.PUT("/expenses/:id", expenseHandlers.UpdateExpense)
+	protected.DELETE("/expenses/:id", expenseHandlers.DeleteExpense)
+	protected.POST("/expenses/:id/attachments", expenseHandlers.UploadAttachment)
+	protected.DELETE("/expenses/:id/attachments/:attachment_id", expenseHandlers.DeleteAttachment)
+	protected.GET("/expense-budgets", expenseHandlers.ListBudgets)
+	protected.PUT("/expense-budgets", expenseHandlers.SetBudget)
+	protected.DELETE("/expense-budgets/:id", expenseHandlers.DeleteBudget)
+	protected.GET("/reports/budget-vs-actual", expenseHandlers.GetBudgetVsActual, tenantWide)
+	protected.GET("/dashboard/expenses", expenseHandlers.GetDashboard, tenantWide)
+	protected.GET("/invoices/:id/reminders", dunningHandlers.ListInvoiceReminders)
+
+	// Payment reminder cadence routes
+	protected.GET("/dunning/schedule", dunningHandlers.GetSchedule)
+	protected.PUT("/dunning/schedule", dunningHandlers.UpdateSchedule)
+	protected.GET("/dunning/templates", dunningHandlers.ListTemplates)
+	protected.PUT("/dunning/templates", dunningHandlers.SaveTemplate)
+	protected.DELETE("/dunning/templates/:id", dunningHandlers.DeleteTemplate)
+	protected.POST("/dunning/run", dunningHandlers.RunReminders)
+
+	// Order SLA routes
+	protected.GET("/sla/definitions", orderSLAHandlers.ListDefinitions)
+	protected.POST("/sla/definitions", orderSLAHandlers.CreateDefinition)
+	protected.PUT("/sla/definitions/:id", orderSLAHandlers.UpdateDefinition)
+	protected.DELETE("/sla/definitions/:id", orderSLAHandlers.DeleteDefinition)
+	protected.POST("/sla/run", orderSLAHandlers.RunBreachCheck)
+	protected.GET("/sla/breaches", orderSLAHandlers.ListBreaches)
+	protected.GET("/reports/sla-compliance", orderSLAHandlers.GetCompliance, tenantWide)
+
+	// Interest on overdue invoices
+	protected.GET("/overdue-interest/settings", overdueInterestHandlers.GetSettings)
+	protected.PUT("/overdue-interest/settings", overdueInterestHandlers.UpdateSettings)
+	protected.POST("/overdue-interest/run", overdueInterestHandlers.RunAccrual)
+	protected.GET("/customers/:id/interest-terms", overdueInterestHandlers.GetCustomerTerms)
+	protected.PUT("/customers/:id/interest-terms", overdueInterestHandlers.UpdateCustomerTerms)
+	protected.DELETE("/customers/:id/interest-terms", overdueInterestHandlers.DeleteCustomerTerms)
+	protected.GET("/invoices/:id/interest", overdueInterestHandlers.GetInvoiceInterest)
+
+	// Started last so a failed build leaves no writer behind; queries seen
+	// until now wait in its buffer
+	queryAudit.Start(repositories.NewQueryDiagnosticsRepo(pool))
+	invoiceArchive.Start()
+
+	return &App{
+		Config: cfg,
+		Echo:   e,
+		Pool:   pool,
+		Auth:   authService,
+
+		Features:       featureFlagSvc,
+		queryAudit:     queryAudit,
+		invoiceArchive: invoiceArchive,
+	}, nil
+}
+
+// Close releases the resources held by the application
+func (a *App) Close() {
+	// Let the archive sweep and query recording finish while the pool is
+	// still open
+	a.invoiceArchive.Stop()
+	a.queryAudit.Stop()
+	a.Pool.Close()
+}
+
+// residencyStoresFromEnv reads the stores of the regions listed in
+// DATA_RESIDENCY_REGIONS from MINIO_<REGION>_ENDPOINT, _ACCESS_KEY,
+// _SECRET_KEY and _USE_SSL
+func residencyStoresFromEnv() (map[string]services.StorageEndpoint, error) {
+	stores := make(map[string]services.StorageEndpoint)
+	for _, region := range splitList(os.Getenv("DATA_RESIDENCY_REGIONS")) {
+		region = strings.ToLower(region)
+		prefix := "MINIO_" + strings.ToUpper(strings.ReplaceAll(region, "-", "_")) + "_"
+		store := services.StorageEndpoint{
+			Endpoint:  os.Getenv(prefix + "ENDPOINT"),
+			AccessKey: os.Getenv(prefix + "ACCESS_KEY"),
+			SecretKey: os.Getenv(prefix + "SECRET_KEY"),
+			UseSSL:    os.Getenv(prefix+"USE_SSL") == "true",
+		}
+		if store.Endpoint == "" {
+			return nil, fmt.Errorf("data residency region %s needs %sENDPOINT", region, prefix)
+		}
+		stores[region] = store
+	}
+	return stores, nil
+}
+
+// splitList splits a comma separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// poolConfigFromEnv reads the DB_* pool settings over the defaults in pool
+func poolConfigFromEnv(pool *database.PoolConfig) error {
+	for name, target := range map[string]*int32{
+		"DB_MAX_CONNS": &pool.MaxConns,
+		"DB_MIN_CONNS": &pool.MinConns,
+	} {
+		if value := os.Getenv(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 32)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s %s", name, value)
+			}
+			*target = int32(n)
+		}
+	}
+
+	for name, target := range map[string]*time.Duration{
+		"DB_MAX_CONN_LIFETIME":    &pool.MaxConnLifetime,
+		"DB_MAX_CONN_IDLE_TIME":   &pool.MaxConnIdleTime,
+		"DB_HEALTH_CHECK_PERIOD":  &pool.HealthCheckPeriod,
+		"DB_SLOW_QUERY_THRESHOLD": &pool.SlowQueryThreshold,
+	} {
+		if value := os.Getenv(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return fmt.Errorf("invalid %s %s", name, value)
+			}
+			*target = d
+		}
+	}
+
+	if mode := os.Getenv("DB_STATEMENT_CACHE_MODE"); mode != "" {
+		pool.StatementCacheMode = mode
+	}
+	if err := pool.Validate(); err != nil {
+		return fmt.Errorf("invalid database pool config: %w", err)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 	"agromart2/internal/models"
 )
 
@@ -18,6 +19,13 @@ type CacheService interface {
 	GetProduct(ctx context.Context, tenantID, productID uuid.UUID) (*models.Product, error)
 	SetProduct(ctx context.Context, tenantID uuid.UUID, product *models.Product, ttl time.Duration) error
 	DeleteProduct(ctx context.Context, tenantID, productID uuid.UUID) error
+	// GetProducts fetches many products in one round trip; misses are left out of the map
+	GetProducts(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID]*models.Product, error)
+	SetProducts(ctx context.Context, tenantID uuid.UUID, products []*models.Product, ttl time.Duration) error
+	// GetOrLoadProduct returns the cached product or caches what load returns.
+	// Concurrent misses on the same product share a single load
+	GetOrLoadProduct(ctx context.Context, tenantID, productID uuid.UUID, ttl time.Duration,
+		load func(ctx context.Context) (*models.Product, error)) (*models.Product, error)
 
 	// Inventory caching
 	GetInventory(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (*models.Inventory, error)
@@ -46,14 +54,24 @@ type CacheService interface {
 	IsRateLimited(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
 	IncrementRateLimit(ctx context.Context, key string, window time.Duration) error
 
+	// Counters with a sliding expiry set on first increment
+	IncrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
 	// Generic string operations for token management
 	SetString(ctx context.Context, key string, value string, ttl time.Duration) error
 	GetString(ctx context.Context, key string) (string, error)
 	Delete(ctx context.Context, key string) error
+
+	// Response caching; entries are tagged with surrogate keys and purged by them
+	GetResponse(ctx context.Context, key string) ([]byte, error)
+	SetResponse(ctx context.Context, key string, body []byte, surrogateKeys []string, ttl time.Duration) error
+	PurgeSurrogateKeys(ctx context.Context, surrogateKeys ...string) error
 }
 
 type redisCacheService struct {
 	client *redis.Client
+	// loads collapses concurrent loads of the same key after a cache miss
+	loads singleflight.Group
 }
 
 func NewRedisCacheService(addr, password string, db int) CacheService {
@@ -84,15 +102,21 @@ func NewRedisCacheService(addr, password string, db int) CacheService {
 	return &redisCacheService{client: client}
 }
 
+func productKey(tenantID, productID uuid.UUID) string {
+	return fmt.Sprintf("agromart:product:%s:%s", tenantID.String(), productID.String())
+}
+
 func (r *redisCacheService) GetProduct(ctx context.Context, tenantID, productID uuid.UUID) (*models.Product, error) {
-	key := fmt.Sprintf("agromart:product:%s:%s", tenantID.String(), productID.String())
+	key := productKey(tenantID, productID)
 	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
+			recordLookups(CacheTypeProduct, 0, 1)
 			return nil, nil // cache miss
 		}
 		return nil, err
 	}
+	recordLookups(CacheTypeProduct, 1, 0)
 
 	var product models.Product
 	if err := json.Unmarshal(data, &product); err != nil {
@@ -102,7 +126,7 @@ func (r *redisCacheService) GetProduct(ctx context.Context, tenantID, productID
 }
 
 func (r *redisCacheService) SetProduct(ctx context.Context, tenantID uuid.UUID, product *models.Product, ttl time.Duration) error {
-	key := fmt.Sprintf("agromart:product:%s:%s", tenantID.String(), product.ID.String())
+	key := productKey(tenantID, product.ID)
 	data, err := json.Marshal(product)
 	if err != nil {
 		return err
@@ -111,19 +135,98 @@ func (r *redisCacheService) SetProduct(ctx context.Context, tenantID uuid.UUID,
 }
 
 func (r *redisCacheService) DeleteProduct(ctx context.Context, tenantID, productID uuid.UUID) error {
-	key := fmt.Sprintf("agromart:product:%s:%s", tenantID.String(), productID.String())
+	key := productKey(tenantID, productID)
 	return r.client.Del(ctx, key).Err()
 }
 
+func (r *redisCacheService) GetProducts(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID]*models.Product, error) {
+	found := make(map[uuid.UUID]*models.Product, len(productIDs))
+	if len(productIDs) == 0 {
+		return found, nil
+	}
+
+	keys := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		keys[i] = productKey(tenantID, id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // cache miss
+		}
+		var product models.Product
+		if err := json.Unmarshal([]byte(data), &product); err != nil {
+			// Treat an unreadable entry as a miss so it gets rewritten
+			continue
+		}
+		found[productIDs[i]] = &product
+	}
+	recordLookups(CacheTypeProduct, len(found), len(productIDs)-len(found))
+	return found, nil
+}
+
+func (r *redisCacheService) SetProducts(ctx context.Context, tenantID uuid.UUID, products []*models.Product, ttl time.Duration) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for _, product := range products {
+		data, err := json.Marshal(product)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, productKey(tenantID, product.ID), data, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *redisCacheService) GetOrLoadProduct(ctx context.Context, tenantID, productID uuid.UUID, ttl time.Duration,
+	load func(ctx context.Context) (*models.Product, error)) (*models.Product, error) {
+	product, err := r.GetProduct(ctx, tenantID, productID)
+	if product != nil {
+		return product, nil
+	} else if err != nil {
+		// Cache errors shouldn't fail the read; fall through to the loader
+		log.Printf("Cache error for product %s: %v", productID, err)
+	}
+
+	key := productKey(tenantID, productID)
+	value, err, _ := r.loads.Do(key, func() (interface{}, error) {
+		// The load is shared, so one caller going away must not cancel it for the rest
+		loadCtx := context.WithoutCancel(ctx)
+		product, err := load(loadCtx)
+		if err != nil || product == nil {
+			return product, err
+		}
+		if err := r.SetProduct(loadCtx, tenantID, product, ttl); err != nil {
+			log.Printf("Failed to cache product %s: %v", productID, err)
+		}
+		return product, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*models.Product), nil
+}
+
 func (r *redisCacheService) GetInventory(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (*models.Inventory, error) {
 	key := fmt.Sprintf("agromart:inventory:%s:%s:%s", tenantID.String(), warehouseID.String(), productID.String())
 	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
+			recordLookups(CacheTypeInventory, 0, 1)
 			return nil, nil // cache miss
 		}
 		return nil, err
 	}
+	recordLookups(CacheTypeInventory, 1, 0)
 
 	var inventory models.Inventory
 	if err := json.Unmarshal(data, &inventory); err != nil {
@@ -151,10 +254,12 @@ func (r *redisCacheService) GetCategory(ctx context.Context, tenantID, categoryI
 	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
+			recordLookups(CacheTypeCategory, 0, 1)
 			return nil, nil // cache miss
 		}
 		return nil, err
 	}
+	recordLookups(CacheTypeCategory, 1, 0)
 
 	var category models.Category
 	if err := json.Unmarshal(data, &category); err != nil {
@@ -182,10 +287,12 @@ func (r *redisCacheService) GetTenantAnalytics(ctx context.Context, tenantID uui
 	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
+			recordLookups(CacheTypeAnalytics, 0, 1)
 			return nil, nil // cache miss
 		}
 		return nil, err
 	}
+	recordLookups(CacheTypeAnalytics, 1, 0)
 
 	var analytics map[string]interface{}
 	if err := json.Unmarshal(data, &analytics); err != nil {
@@ -277,6 +384,18 @@ func (r *redisCacheService) IncrementRateLimit(ctx context.Context, key string,
 	return nil
 }
 
+// IncrementCounter increments and sets the expiry in one transaction, so a
+// counter never outlives its window; NX leaves a running window alone
+func (r *redisCacheService) IncrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
 func (r *redisCacheService) SetString(ctx context.Context, key string, value string, ttl time.Duration) error {
 	return r.client.Set(ctx, key, value, ttl).Err()
 }
@@ -294,4 +413,51 @@ func (r *redisCacheService) GetString(ctx context.Context, key string) (string,
 
 func (r *redisCacheService) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
-}
\ No newline at end of file
+}
+
+// GetResponse returns a cached response body, or nil on a cache miss
+func (r *redisCacheService) GetResponse(ctx context.Context, key string) ([]byte, error) {
+	body, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		recordLookups(CacheTypeResponse, 0, 1)
+		return nil, nil
+	}
+	if err == nil {
+		recordLookups(CacheTypeResponse, 1, 0)
+	}
+	return body, err
+}
+
+// SetResponse caches a response body and records its key under each surrogate
+// key. The surrogate key sets outlive their newest entry so a purge always
+// finds every live entry
+func (r *redisCacheService) SetResponse(ctx context.Context, key string, body []byte, surrogateKeys []string, ttl time.Duration) error {
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, key, body, ttl)
+	for _, surrogateKey := range surrogateKeys {
+		setKey := surrogateSetKey(surrogateKey)
+		pipe.SAdd(ctx, setKey, key)
+		pipe.Expire(ctx, setKey, ttl+time.Minute)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// PurgeSurrogateKeys deletes every cached response tagged with the surrogate keys
+func (r *redisCacheService) PurgeSurrogateKeys(ctx context.Context, surrogateKeys ...string) error {
+	for _, surrogateKey := range surrogateKeys {
+		setKey := surrogateSetKey(surrogateKey)
+		keys, err := r.client.SMembers(ctx, setKey).Result()
+		if err != nil {
+			return err
+		}
+		if err := r.client.Del(ctx, append(keys, setKey)...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func surrogateSetKey(surrogateKey string) string {
+	return fmt.Sprintf("agromart:surrogate:%s", surrogateKey)
+}
@@ -0,0 +1,95 @@
+package caching
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCache keeps products in memory; the rest of CacheService is left
+// unimplemented
+type memoryCache struct {
+	CacheService
+
+	mu       sync.Mutex
+	products map[uuid.UUID]*models.Product
+	misses   int
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{products: make(map[uuid.UUID]*models.Product)}
+}
+
+func (m *memoryCache) GetProduct(ctx context.Context, tenantID, productID uuid.UUID) (*models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[productID]
+	if !ok {
+		m.misses++
+	}
+	return product, nil
+}
+
+func (m *memoryCache) SetProduct(ctx context.Context, tenantID uuid.UUID, product *models.Product, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.products[product.ID] = product
+	return nil
+}
+
+func (m *memoryCache) missCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.misses
+}
+
+func TestGetOrLoadProductSharesConcurrentLoads(t *testing.T) {
+	const callers = 8
+	inner := newMemoryCache()
+	cache := NewCircuitBreakerCache(inner, CircuitBreakerConfig{})
+	tenantID, productID := uuid.New(), uuid.New()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (*models.Product, error) {
+		loads.Add(1)
+		<-release
+		return &models.Product{ID: productID, TenantID: tenantID, Name: "Urea 50kg"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*models.Product, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cache.GetOrLoadProduct(context.Background(), tenantID, productID, time.Minute, load)
+		}(i)
+	}
+
+	// Hold the load until every caller has missed the cache and joined it
+	require.Eventually(t, func() bool { return inner.missCount() == callers }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for i := 0; i < callers; i++ {
+		require.NoError(t, errs[i])
+		assert.Same(t, results[0], results[i])
+	}
+
+	cached, err := cache.GetOrLoadProduct(context.Background(), tenantID, productID, time.Minute, load)
+	require.NoError(t, err)
+	assert.Same(t, results[0], cached)
+	assert.Equal(t, int32(1), loads.Load(), "the loaded product is cached")
+}
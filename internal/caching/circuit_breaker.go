@@ -0,0 +1,486 @@
+package caching
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCacheUnavailable is returned by operations that cannot be skipped, such
+// as sessions and stored tokens, while the cache is degraded
+var ErrCacheUnavailable = errors.New("cache unavailable")
+
+// Cache health states reported in /health/detailed
+const (
+	CacheStatusOK         = "ok"
+	CacheStatusDegraded   = "degraded"
+	CacheStatusRecovering = "recovering"
+)
+
+const (
+	// maxPendingInvalidations bounds the invalidations remembered while
+	// degraded; past it the whole cache is dropped on recovery
+	maxPendingInvalidations = 1000
+	replayTimeout           = 30 * time.Second
+)
+
+// CircuitBreakerConfig tunes when the cache is considered down
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the consecutive failures that trip the breaker
+	FailureThreshold int
+	// Cooldown is how long the breaker stays open before one call probes
+	// whether the cache is back
+	Cooldown time.Duration
+}
+
+// DefaultCircuitBreakerConfig trips after five straight failures and probes
+// every ten seconds
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{FailureThreshold: 5, Cooldown: 10 * time.Second}
+}
+
+// CacheHealth is the circuit breaker's view of the cache
+type CacheHealth struct {
+	Status string `json:"status"`
+	// Since is when the cache entered its current status
+	Since          time.Time `json:"since"`
+	LastError      string    `json:"last_error,omitempty"`
+	Trips          uint64    `json:"trips"`
+	ShortCircuited uint64    `json:"short_circuited"`
+	// PendingInvalidations are waiting to be replayed on recovery
+	PendingInvalidations int `json:"pending_invalidations"`
+}
+
+// breakerTrips and breakerShortCircuits are process wide, like the hit
+// ratio counters
+var (
+	breakerTrips         atomic.Uint64
+	breakerShortCircuits atomic.Uint64
+	activeBreaker        atomic.Pointer[circuitBreakerCache]
+)
+
+// Health returns the cache's circuit breaker state; ok is false when the
+// cache is not wrapped in a breaker
+func Health() (CacheHealth, bool) {
+	cache := activeBreaker.Load()
+	if cache == nil {
+		return CacheHealth{}, false
+	}
+	return cache.health(), true
+}
+
+// circuitBreakerCache keeps a failing cache out of the hot path. After
+// FailureThreshold consecutive failures it stops calling the cache: lookups
+// miss, writes are skipped and invalidations are remembered, so callers fall
+// back to the database without waiting on timeouts or logging every request.
+// After the cooldown a single call probes the cache; when it succeeds the
+// remembered invalidations are replayed and normal operation resumes.
+// Operations whose data only lives in the cache fail fast with
+// ErrCacheUnavailable instead
+type circuitBreakerCache struct {
+	inner CacheService
+	cfg   CircuitBreakerConfig
+	now   func() time.Time
+
+	mu        sync.Mutex
+	status    string
+	since     time.Time
+	failures  int
+	probing   bool
+	lastError string
+	// pendingTenants and pendingSurrogates are invalidations skipped while
+	// degraded; pendingAll is set once they overflow
+	pendingTenants    map[uuid.UUID]bool
+	pendingSurrogates map[string]bool
+	pendingAll        bool
+
+	loads singleflight.Group
+}
+
+// NewCircuitBreakerCache wraps inner with a circuit breaker and reports its
+// state through Health
+func NewCircuitBreakerCache(inner CacheService, cfg CircuitBreakerConfig) CacheService {
+	defaults := DefaultCircuitBreakerConfig()
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaults.Cooldown
+	}
+	cache := &circuitBreakerCache{
+		inner:             inner,
+		cfg:               cfg,
+		now:               time.Now,
+		status:            CacheStatusOK,
+		since:             time.Now(),
+		pendingTenants:    make(map[uuid.UUID]bool),
+		pendingSurrogates: make(map[string]bool),
+	}
+	activeBreaker.Store(cache)
+	return cache
+}
+
+// allow reports whether a call may reach the cache. While open, the first
+// call after the cooldown is let through as the probe
+func (c *circuitBreakerCache) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.status == CacheStatusOK:
+		return true
+	case !c.probing && c.now().Sub(c.since) >= c.cfg.Cooldown:
+		c.probing = true
+		c.status = CacheStatusRecovering
+		return true
+	}
+	breakerShortCircuits.Add(1)
+	return false
+}
+
+// record updates the breaker with a call's outcome
+func (c *circuitBreakerCache) record(err error) {
+	if err != nil && !countsAsFailure(err) {
+		// Says nothing about the cache; let the next call probe again
+		c.mu.Lock()
+		if c.probing {
+			c.probing = false
+			c.status = CacheStatusDegraded
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.failures = 0
+		if c.status != CacheStatusOK {
+			log.Printf("Cache recovered after %s, leaving degraded mode", c.now().Sub(c.since).Round(time.Second))
+			c.status, c.since, c.probing, c.lastError = CacheStatusOK, c.now(), false, ""
+			go c.replayInvalidations()
+		}
+		return
+	}
+
+	c.failures++
+	c.lastError = err.Error()
+	if c.probing {
+		c.status, c.since, c.probing = CacheStatusDegraded, c.now(), false
+		return
+	}
+	if c.status == CacheStatusOK && c.failures >= c.cfg.FailureThreshold {
+		breakerTrips.Add(1)
+		log.Printf("Cache failed %d times in a row, switching to database-only mode: %v", c.failures, err)
+		c.status, c.since = CacheStatusDegraded, c.now()
+	}
+}
+
+// countsAsFailure leaves out errors caused by the caller or the data rather
+// than the cache
+func countsAsFailure(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return !errors.Is(err, context.Canceled) && !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr)
+}
+
+func (c *circuitBreakerCache) health() CacheHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheHealth{
+		Status:               c.status,
+		Since:                c.since,
+		LastError:            c.lastError,
+		Trips:                breakerTrips.Load(),
+		ShortCircuited:       breakerShortCircuits.Load(),
+		PendingInvalidations: len(c.pendingTenants) + len(c.pendingSurrogates),
+	}
+}
+
+// rememberTenant and rememberSurrogates queue an invalidation that could not
+// be applied, so entries cached before the outage are not served stale
+func (c *circuitBreakerCache) rememberTenant(tenantID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pendingTenants[tenantID] = true
+	c.checkPendingLocked()
+}
+
+func (c *circuitBreakerCache) rememberSurrogates(surrogateKeys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range surrogateKeys {
+		c.pendingSurrogates[key] = true
+	}
+	c.checkPendingLocked()
+}
+
+func (c *circuitBreakerCache) rememberAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pendingAll = true
+	c.pendingTenants = make(map[uuid.UUID]bool)
+	c.pendingSurrogates = make(map[string]bool)
+}
+
+func (c *circuitBreakerCache) checkPendingLocked() {
+	if len(c.pendingTenants)+len(c.pendingSurrogates) > maxPendingInvalidations {
+		c.pendingAll = true
+		c.pendingTenants = make(map[uuid.UUID]bool)
+		c.pendingSurrogates = make(map[string]bool)
+	}
+}
+
+// replayInvalidations applies the invalidations skipped while degraded;
+// failures are remembered again for the next recovery
+func (c *circuitBreakerCache) replayInvalidations() {
+	c.mu.Lock()
+	all, tenants, surrogates := c.pendingAll, c.pendingTenants, c.pendingSurrogates
+	c.pendingAll = false
+	c.pendingTenants = make(map[uuid.UUID]bool)
+	c.pendingSurrogates = make(map[string]bool)
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+	defer cancel()
+	if all {
+		if err := c.InvalidateAllCache(ctx); err != nil {
+			log.Printf("Failed to clear the cache after recovery: %v", err)
+		}
+		return
+	}
+	for tenantID := range tenants {
+		if err := c.InvalidateTenantCache(ctx, tenantID); err != nil {
+			log.Printf("Failed to replay cache invalidation for tenant %s: %v", tenantID, err)
+		}
+	}
+	surrogateKeys := make([]string, 0, len(surrogates))
+	for key := range surrogates {
+		surrogateKeys = append(surrogateKeys, key)
+	}
+	if len(surrogateKeys) > 0 {
+		if err := c.PurgeSurrogateKeys(ctx, surrogateKeys...); err != nil {
+			log.Printf("Failed to replay %d response cache purges: %v", len(surrogateKeys), err)
+		}
+	}
+}
+
+// lookup runs a cache read; while degraded it is a miss
+func lookup[T any](c *circuitBreakerCache, read func() (T, error)) (T, error) {
+	var miss T
+	if !c.allow() {
+		return miss, nil
+	}
+	value, err := read()
+	c.record(err)
+	return value, err
+}
+
+// write runs a cache write; while degraded it is skipped
+func (c *circuitBreakerCache) write(fn func() error) error {
+	if !c.allow() {
+		return nil
+	}
+	err := fn()
+	c.record(err)
+	return err
+}
+
+// invalidate runs an invalidation; while degraded, or when it fails, it is
+// remembered for replay on recovery
+func (c *circuitBreakerCache) invalidate(fn func() error, remember func()) error {
+	if !c.allow() {
+		remember()
+		return nil
+	}
+	err := fn()
+	c.record(err)
+	if err != nil {
+		remember()
+	}
+	return err
+}
+
+// required runs an operation that cannot be skipped; while degraded it
+// fails fast
+func (c *circuitBreakerCache) required(fn func() error) error {
+	if !c.allow() {
+		return ErrCacheUnavailable
+	}
+	err := fn()
+	c.record(err)
+	return err
+}
+
+func (c *circuitBreakerCache) GetProduct(ctx context.Context, tenantID, productID uuid.UUID) (*models.Product, error) {
+	return lookup(c, func() (*models.Product, error) { return c.inner.GetProduct(ctx, tenantID, productID) })
+}
+
+func (c *circuitBreakerCache) SetProduct(ctx context.Context, tenantID uuid.UUID, product *models.Product, ttl time.Duration) error {
+	return c.write(func() error { return c.inner.SetProduct(ctx, tenantID, product, ttl) })
+}
+
+func (c *circuitBreakerCache) DeleteProduct(ctx context.Context, tenantID, productID uuid.UUID) error {
+	return c.invalidate(func() error { return c.inner.DeleteProduct(ctx, tenantID, productID) },
+		func() { c.rememberTenant(tenantID) })
+}
+
+func (c *circuitBreakerCache) GetProducts(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID]*models.Product, error) {
+	found, err := lookup(c, func() (map[uuid.UUID]*models.Product, error) {
+		return c.inner.GetProducts(ctx, tenantID, productIDs)
+	})
+	if found == nil && err == nil {
+		found = make(map[uuid.UUID]*models.Product)
+	}
+	return found, err
+}
+
+func (c *circuitBreakerCache) SetProducts(ctx context.Context, tenantID uuid.UUID, products []*models.Product, ttl time.Duration) error {
+	return c.write(func() error { return c.inner.SetProducts(ctx, tenantID, products, ttl) })
+}
+
+// GetOrLoadProduct goes through the breaker's own lookup and write rather
+// than the inner cache's, which swallows cache errors the breaker must see
+func (c *circuitBreakerCache) GetOrLoadProduct(ctx context.Context, tenantID, productID uuid.UUID, ttl time.Duration,
+	load func(ctx context.Context) (*models.Product, error)) (*models.Product, error) {
+	product, err := c.GetProduct(ctx, tenantID, productID)
+	if product != nil {
+		return product, nil
+	} else if err != nil {
+		log.Printf("Cache error for product %s: %v", productID, err)
+	}
+
+	value, err, _ := c.loads.Do(productKey(tenantID, productID), func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		product, err := load(loadCtx)
+		if err != nil || product == nil {
+			return product, err
+		}
+		if err := c.SetProduct(loadCtx, tenantID, product, ttl); err != nil {
+			log.Printf("Failed to cache product %s: %v", productID, err)
+		}
+		return product, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*models.Product), nil
+}
+
+func (c *circuitBreakerCache) GetInventory(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (*models.Inventory, error) {
+	return lookup(c, func() (*models.Inventory, error) { return c.inner.GetInventory(ctx, tenantID, warehouseID, productID) })
+}
+
+func (c *circuitBreakerCache) SetInventory(ctx context.Context, tenantID uuid.UUID, inventory *models.Inventory, ttl time.Duration) error {
+	return c.write(func() error { return c.inner.SetInventory(ctx, tenantID, inventory, ttl) })
+}
+
+func (c *circuitBreakerCache) DeleteInventory(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) error {
+	return c.invalidate(func() error { return c.inner.DeleteInventory(ctx, tenantID, warehouseID, productID) },
+		func() { c.rememberTenant(tenantID) })
+}
+
+func (c *circuitBreakerCache) GetCategory(ctx context.Context, tenantID, categoryID uuid.UUID) (*models.Category, error) {
+	return lookup(c, func() (*models.Category, error) { return c.inner.GetCategory(ctx, tenantID, categoryID) })
+}
+
+func (c *circuitBreakerCache) SetCategory(ctx context.Context, tenantID uuid.UUID, category *models.Category, ttl time.Duration) error {
+	return c.write(func() error { return c.inner.SetCategory(ctx, tenantID, category, ttl) })
+}
+
+func (c *circuitBreakerCache) DeleteCategory(ctx context.Context, tenantID, categoryID uuid.UUID) error {
+	return c.invalidate(func() error { return c.inner.DeleteCategory(ctx, tenantID, categoryID) },
+		func() { c.rememberTenant(tenantID) })
+}
+
+func (c *circuitBreakerCache) GetTenantAnalytics(ctx context.Context, tenantID uuid.UUID) (map[string]interface{}, error) {
+	return lookup(c, func() (map[string]interface{}, error) { return c.inner.GetTenantAnalytics(ctx, tenantID) })
+}
+
+func (c *circuitBreakerCache) SetTenantAnalytics(ctx context.Context, tenantID uuid.UUID, analytics map[string]interface{}, ttl time.Duration) error {
+	return c.write(func() error { return c.inner.SetTenantAnalytics(ctx, tenantID, analytics, ttl) })
+}
+
+func (c *circuitBreakerCache) InvalidateTenantCache(ctx context.Context, tenantID uuid.UUID) error {
+	return c.invalidate(func() error { return c.inner.InvalidateTenantCache(ctx, tenantID) },
+		func() { c.rememberTenant(tenantID) })
+}
+
+func (c *circuitBreakerCache) InvalidateAllCache(ctx context.Context) error {
+	return c.invalidate(func() error { return c.inner.InvalidateAllCache(ctx) }, c.rememberAll)
+}
+
+func (c *circuitBreakerCache) SetSession(ctx context.Context, sessionID, userID string, ttl time.Duration) error {
+	return c.required(func() error { return c.inner.SetSession(ctx, sessionID, userID, ttl) })
+}
+
+func (c *circuitBreakerCache) GetSession(ctx context.Context, sessionID string) (string, error) {
+	var userID string
+	err := c.required(func() (err error) {
+		userID, err = c.inner.GetSession(ctx, sessionID)
+		return err
+	})
+	return userID, err
+}
+
+func (c *circuitBreakerCache) DeleteSession(ctx context.Context, sessionID string) error {
+	return c.required(func() error { return c.inner.DeleteSession(ctx, sessionID) })
+}
+
+// IsRateLimited fails open while degraded, as callers already do on errors
+func (c *circuitBreakerCache) IsRateLimited(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	return lookup(c, func() (bool, error) { return c.inner.IsRateLimited(ctx, key, limit, window) })
+}
+
+func (c *circuitBreakerCache) IncrementRateLimit(ctx context.Context, key string, window time.Duration) error {
+	return c.write(func() error { return c.inner.IncrementRateLimit(ctx, key, window) })
+}
+
+func (c *circuitBreakerCache) IncrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var count int64
+	err := c.required(func() (err error) {
+		count, err = c.inner.IncrementCounter(ctx, key, ttl)
+		return err
+	})
+	return count, err
+}
+
+// SetString, GetString and Delete also hold tokens and login state, so they
+// fail fast rather than pretend to succeed
+func (c *circuitBreakerCache) SetString(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return c.required(func() error { return c.inner.SetString(ctx, key, value, ttl) })
+}
+
+func (c *circuitBreakerCache) GetString(ctx context.Context, key string) (string, error) {
+	var value string
+	err := c.required(func() (err error) {
+		value, err = c.inner.GetString(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (c *circuitBreakerCache) Delete(ctx context.Context, key string) error {
+	return c.required(func() error { return c.inner.Delete(ctx, key) })
+}
+
+func (c *circuitBreakerCache) GetResponse(ctx context.Context, key string) ([]byte, error) {
+	return lookup(c, func() ([]byte, error) { return c.inner.GetResponse(ctx, key) })
+}
+
+func (c *circuitBreakerCache) SetResponse(ctx context.Context, key string, body []byte, surrogateKeys []string, ttl time.Duration) error {
+	return c.write(func() error { return c.inner.SetResponse(ctx, key, body, surrogateKeys, ttl) })
+}
+
+func (c *circuitBreakerCache) PurgeSurrogateKeys(ctx context.Context, surrogateKeys ...string) error {
+	return c.invalidate(func() error { return c.inner.PurgeSurrogateKeys(ctx, surrogateKeys...) },
+		func() { c.rememberSurrogates(surrogateKeys) })
+}
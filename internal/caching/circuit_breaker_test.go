@@ -0,0 +1,144 @@
+package caching
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyCache fails every call while down and records the invalidations that
+// reach it
+type flakyCache struct {
+	CacheService
+
+	mu          sync.Mutex
+	down        bool
+	calls       int
+	tenants     []uuid.UUID
+	surrogates  []string
+	invalidated chan struct{}
+}
+
+func newFlakyCache() *flakyCache {
+	return &flakyCache{invalidated: make(chan struct{}, 10)}
+}
+
+func (f *flakyCache) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func (f *flakyCache) call() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.down {
+		return errors.New("dial tcp: connection refused")
+	}
+	return nil
+}
+
+func (f *flakyCache) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *flakyCache) GetProduct(ctx context.Context, tenantID, productID uuid.UUID) (*models.Product, error) {
+	return nil, f.call()
+}
+
+func (f *flakyCache) InvalidateTenantCache(ctx context.Context, tenantID uuid.UUID) error {
+	if err := f.call(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.tenants = append(f.tenants, tenantID)
+	f.mu.Unlock()
+	f.invalidated <- struct{}{}
+	return nil
+}
+
+func (f *flakyCache) PurgeSurrogateKeys(ctx context.Context, surrogateKeys ...string) error {
+	if err := f.call(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.surrogates = append(f.surrogates, surrogateKeys...)
+	f.mu.Unlock()
+	f.invalidated <- struct{}{}
+	return nil
+}
+
+func TestCircuitBreakerRecoversAndReplaysInvalidations(t *testing.T) {
+	inner := newFlakyCache()
+	cache := NewCircuitBreakerCache(inner, CircuitBreakerConfig{FailureThreshold: 2, Cooldown: 10 * time.Second}).(*circuitBreakerCache)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+	tenantID := uuid.New()
+
+	// Consecutive failures open the breaker
+	inner.setDown(true)
+	for i := 0; i < 2; i++ {
+		_, err := cache.GetProduct(ctx, tenantID, uuid.New())
+		require.Error(t, err)
+	}
+	require.Equal(t, CacheStatusDegraded, cache.health().Status)
+
+	// While open the cache is not called and invalidations are remembered
+	calls := inner.callCount()
+	product, err := cache.GetProduct(ctx, tenantID, uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, product)
+	require.NoError(t, cache.InvalidateTenantCache(ctx, tenantID))
+	require.NoError(t, cache.PurgeSurrogateKeys(ctx, CatalogSurrogateKey(tenantID)))
+	assert.Equal(t, calls, inner.callCount())
+	assert.Equal(t, 2, cache.health().PendingInvalidations)
+
+	// A probe after the cooldown that fails keeps it open for another cooldown
+	now = now.Add(10 * time.Second)
+	_, err = cache.GetProduct(ctx, tenantID, uuid.New())
+	require.Error(t, err)
+	assert.Equal(t, CacheStatusDegraded, cache.health().Status)
+	_, err = cache.GetProduct(ctx, tenantID, uuid.New())
+	assert.NoError(t, err, "the failed probe restarts the cooldown")
+	assert.Equal(t, calls+1, inner.callCount())
+
+	// Half-open: the first call after the cooldown probes while the rest
+	// still short-circuit
+	now = now.Add(10 * time.Second)
+	inner.setDown(false)
+	require.True(t, cache.allow())
+	assert.Equal(t, CacheStatusRecovering, cache.health().Status)
+	assert.False(t, cache.allow())
+
+	// A successful probe closes the breaker and replays the invalidations
+	cache.record(nil)
+	assert.Equal(t, CacheStatusOK, cache.health().Status)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-inner.invalidated:
+		case <-time.After(time.Second):
+			t.Fatal("pending invalidations were not replayed")
+		}
+	}
+	inner.mu.Lock()
+	assert.Equal(t, []uuid.UUID{tenantID}, inner.tenants)
+	assert.Equal(t, []string{CatalogSurrogateKey(tenantID)}, inner.surrogates)
+	inner.mu.Unlock()
+	assert.Zero(t, cache.health().PendingInvalidations)
+
+	_, err = cache.GetProduct(ctx, tenantID, uuid.New())
+	assert.NoError(t, err)
+	assert.Equal(t, calls+4, inner.callCount(), "calls reach the cache again")
+}
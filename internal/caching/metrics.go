@@ -0,0 +1,69 @@
+package caching
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Cache types reported in the hit ratio metrics
+const (
+	CacheTypeProduct   = "product"
+	CacheTypeInventory = "inventory"
+	CacheTypeCategory  = "category"
+	CacheTypeAnalytics = "analytics"
+	CacheTypeResponse  = "response"
+)
+
+// CacheTypeStats is the lookup count of one cache type since startup
+type CacheTypeStats struct {
+	Type   string `json:"type"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// HitRatio is hits over lookups, 0 before the first lookup
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+type cacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// cacheStats holds the counters of every cache type seen so far; they are
+// process wide, like the rest of the /metrics output
+var cacheStats sync.Map
+
+func countersFor(cacheType string) *cacheCounters {
+	if counters, ok := cacheStats.Load(cacheType); ok {
+		return counters.(*cacheCounters)
+	}
+	counters, _ := cacheStats.LoadOrStore(cacheType, &cacheCounters{})
+	return counters.(*cacheCounters)
+}
+
+// recordLookups counts the hits and misses of a cache lookup
+func recordLookups(cacheType string, hits, misses int) {
+	counters := countersFor(cacheType)
+	counters.hits.Add(uint64(hits))
+	counters.misses.Add(uint64(misses))
+}
+
+// Stats returns the hit ratio of each cache type, sorted by type
+func Stats() []CacheTypeStats {
+	var stats []CacheTypeStats
+	cacheStats.Range(func(key, value interface{}) bool {
+		counters := value.(*cacheCounters)
+		s := CacheTypeStats{
+			Type:   key.(string),
+			Hits:   counters.hits.Load(),
+			Misses: counters.misses.Load(),
+		}
+		if lookups := s.Hits + s.Misses; lookups > 0 {
+			s.HitRatio = float64(s.Hits) / float64(lookups)
+		}
+		stats = append(stats, s)
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Type < stats[j].Type })
+	return stats
+}
@@ -0,0 +1,83 @@
+package caching
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Domain events that make cached responses stale
+const (
+	EventProductUpdated     = "product.updated"
+	EventProductDeleted     = "product.deleted"
+	EventCategoryUpdated    = "category.updated"
+	EventAnalyticsRefreshed = "analytics.refreshed"
+	// EventStockChanged covers every write to on-hand stock; catalog
+	// responses carry each product's availability
+	EventStockChanged = "stock.changed"
+)
+
+// CatalogSurrogateKey tags the tenant's storefront catalog responses
+func CatalogSurrogateKey(tenantID uuid.UUID) string {
+	return "catalog:" + tenantID.String()
+}
+
+// CategoriesSurrogateKey tags the tenant's category listings
+func CategoriesSurrogateKey(tenantID uuid.UUID) string {
+	return "categories:" + tenantID.String()
+}
+
+// AnalyticsSurrogateKey tags responses read from the analytics materialized
+// views, which are refreshed for every tenant at once
+const AnalyticsSurrogateKey = "analytics"
+
+// eventSurrogateKeys lists the surrogate keys each domain event purges
+var eventSurrogateKeys = map[string]func(tenantID uuid.UUID) []string{
+	EventProductUpdated: func(tenantID uuid.UUID) []string {
+		return []string{CatalogSurrogateKey(tenantID)}
+	},
+	EventProductDeleted: func(tenantID uuid.UUID) []string {
+		return []string{CatalogSurrogateKey(tenantID)}
+	},
+	EventCategoryUpdated: func(tenantID uuid.UUID) []string {
+		return []string{CategoriesSurrogateKey(tenantID), CatalogSurrogateKey(tenantID)}
+	},
+	EventStockChanged: func(tenantID uuid.UUID) []string {
+		return []string{CatalogSurrogateKey(tenantID)}
+	},
+	EventAnalyticsRefreshed: func(uuid.UUID) []string {
+		return []string{AnalyticsSurrogateKey}
+	},
+}
+
+// EventSurrogateKeys returns the surrogate keys the event purges for the tenant
+func EventSurrogateKeys(tenantID uuid.UUID, event string) []string {
+	keysFor, ok := eventSurrogateKeys[event]
+	if !ok {
+		return nil
+	}
+	return keysFor(tenantID)
+}
+
+// PublishEvent purges the cached responses the domain event makes stale.
+// Failures are only logged; the entries expire on their own
+func PublishEvent(ctx context.Context, cache CacheService, tenantID uuid.UUID, event string) {
+	if cache == nil {
+		return
+	}
+	if err := cache.PurgeSurrogateKeys(ctx, EventSurrogateKeys(tenantID, event)...); err != nil {
+		log.Printf("Failed to purge cached responses for %s: %v", event, err)
+	}
+}
+
+// ResponseKey is the cache key of a tenant's response from a route with the
+// given parameters; parameters are order sensitive, so pass them in a fixed order
+func ResponseKey(tenantID uuid.UUID, route string, params ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(params, "\x00")))
+	return fmt.Sprintf("agromart:response:%s:%s:%s", tenantID, route, hex.EncodeToString(sum[:16]))
+}
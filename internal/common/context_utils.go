@@ -1,7 +1,6 @@
 package common
 
 import (
-	"context"
 	"fmt"
 	"html"
 	"net/http"
@@ -13,13 +12,6 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-type contextKey string
-
-const (
-	UserIDKey   contextKey = "user_id"
-	TenantIDKey contextKey = "tenant_id"
-)
-
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
 	Error struct {
@@ -153,8 +145,8 @@ func ValidateGSTIN(gstin, fieldName string) error {
 		return fmt.Errorf("%s must be exactly 15 characters", fieldName)
 	}
 
-	// Pattern: First 2 digits, then 10 alphanumeric, then 1 alpha, 1 digit, 1 alpha
-	pattern := `^[0-9]{2}[A-Z]{10}[0-9]{1}[A-Z]{1}[A-Z0-9]{1}$`
+	// Pattern: 2-digit state code, 10-character PAN, entity number, 'Z', check character
+	pattern := `^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`
 	matched, err := regexp.MatchString(pattern, gstin)
 	if err != nil {
 		return fmt.Errorf("invalid GSTIN validation pattern")
@@ -166,6 +158,33 @@ func ValidateGSTIN(gstin, fieldName string) error {
 	return nil
 }
 
+// gstinCharset orders the characters used by the GSTIN check character
+const gstinCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// ValidateGSTINChecksum verifies the GSTIN's final check character, which
+// catches most mistyped numbers. The format must already be valid
+func ValidateGSTINChecksum(gstin, fieldName string) error {
+	if len(gstin) != 15 {
+		return fmt.Errorf("%s must be exactly 15 characters", fieldName)
+	}
+
+	factor, sum := 1, 0
+	for i := 0; i < 14; i++ {
+		code := strings.IndexByte(gstinCharset, gstin[i])
+		if code < 0 {
+			return fmt.Errorf("%s has invalid GSTIN format", fieldName)
+		}
+		product := code * factor
+		sum += product/36 + product%36
+		factor = 3 - factor
+	}
+	if gstinCharset[(36-sum%36)%36] != gstin[14] {
+		return fmt.Errorf("%s has an invalid check character", fieldName)
+	}
+
+	return nil
+}
+
 // ValidateRequiredString validates required string fields
 func ValidateRequiredString(value, fieldName string) error {
 	if strings.TrimSpace(value) == "" {
@@ -189,11 +208,11 @@ func ValidateOptionalString(value *string, fieldName string, maxLength int) erro
 // ValidateOrderStatus validates order status values
 func ValidateOrderStatus(status string) error {
 	validStatuses := map[string]bool{
-		"pending": true, "approved": true, "processing": true,
+		"pending": true, "approved": true, "processing": true, "quality_check": true,
 		"shipped": true, "delivered": true, "cancelled": true,
 	}
 	if !validStatuses[status] {
-		return fmt.Errorf("order status must be one of: pending, approved, processing, shipped, delivered, cancelled")
+		return fmt.Errorf("order status must be one of: pending, approved, processing, quality_check, shipped, delivered, cancelled")
 	}
 	return nil
 }
@@ -233,17 +252,6 @@ func SafeFloat64(f *float64) float64 {
 	return *f
 }
 
-// GetUserIDFromContext extracts the user ID from the request context
-func GetUserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
-	userID, ok := ctx.Value(UserIDKey).(uuid.UUID)
-	return userID, ok
-}
-
-// GetTenantIDFromContext extracts the tenant ID from the request context
-func GetTenantIDFromContext(ctx context.Context) (uuid.UUID, bool) {
-	tenantID, ok := ctx.Value(TenantIDKey).(uuid.UUID)
-	return tenantID, ok
-}
 // SanitizeHTMLElement escapes HTML characters to prevent XSS attacks
 func SanitizeHTMLElement(input string) string {
 	return html.EscapeString(input)
@@ -0,0 +1,21 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateGSTIN(t *testing.T) {
+	assert.NoError(t, ValidateGSTIN("27AAPFU0939F1ZV", "gstin"))
+	assert.NoError(t, ValidateGSTIN("", "gstin"))
+	assert.Error(t, ValidateGSTIN("27AAPFU0939F1Z", "gstin"))
+	assert.Error(t, ValidateGSTIN("27AAPFU0939F1XV", "gstin"))
+}
+
+func TestValidateGSTINChecksum(t *testing.T) {
+	assert.NoError(t, ValidateGSTINChecksum("27AAPFU0939F1ZV", "gstin"))
+	assert.NoError(t, ValidateGSTINChecksum("29AAGCB7383J1Z4", "gstin"))
+	assert.Error(t, ValidateGSTINChecksum("27AAPFU0939F1ZW", "gstin"))
+	assert.Error(t, ValidateGSTINChecksum("27AAPFU0938F1ZV", "gstin"))
+}
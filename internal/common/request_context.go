@@ -0,0 +1,82 @@
+package common
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type requestContextKey struct{}
+
+// RequestContext carries the per-request identity and metadata set by the
+// middleware chain. Handlers and services read it through RequestContextFrom
+// rather than pulling individual values off the context
+type RequestContext struct {
+	RequestID      string
+	Locale         string
+	TenantID       uuid.UUID
+	UserID         uuid.UUID
+	ImpersonatorID uuid.UUID
+	RoleIDs        []uuid.UUID
+}
+
+// WithRequestContext returns a copy of ctx carrying rc
+func WithRequestContext(ctx context.Context, rc *RequestContext) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, rc)
+}
+
+// RequestContextFrom returns the request context stored in ctx, or nil when
+// the request has not passed through the middleware. The accessors are safe
+// to call on a nil value
+func RequestContextFrom(ctx context.Context) *RequestContext {
+	rc, _ := ctx.Value(requestContextKey{}).(*RequestContext)
+	return rc
+}
+
+// Clone returns a copy that can be modified without affecting rc; a nil rc
+// yields an empty request context
+func (rc *RequestContext) Clone() *RequestContext {
+	if rc == nil {
+		return &RequestContext{}
+	}
+	clone := *rc
+	clone.RoleIDs = append([]uuid.UUID(nil), rc.RoleIDs...)
+	return &clone
+}
+
+// Tenant returns the tenant the request is scoped to
+func (rc *RequestContext) Tenant() (uuid.UUID, bool) {
+	if rc == nil || rc.TenantID == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return rc.TenantID, true
+}
+
+// User returns the authenticated user
+func (rc *RequestContext) User() (uuid.UUID, bool) {
+	if rc == nil || rc.UserID == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return rc.UserID, true
+}
+
+// Impersonator returns the admin acting as User, if the request is impersonated
+func (rc *RequestContext) Impersonator() (uuid.UUID, bool) {
+	if rc == nil || rc.ImpersonatorID == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return rc.ImpersonatorID, true
+}
+
+// HasRole reports whether the user holds the role in the request's tenant
+func (rc *RequestContext) HasRole(roleID uuid.UUID) bool {
+	if rc == nil {
+		return false
+	}
+	for _, id := range rc.RoleIDs {
+		if id == roleID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// AdvanceBookingHandlers handles advance bookings and their conversion into sales orders
+type AdvanceBookingHandlers struct {
+	bookingService services.AdvanceBookingService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewAdvanceBookingHandlers creates a new advance booking handlers instance
+func NewAdvanceBookingHandlers(bookingService services.AdvanceBookingService, rbacMiddleware *middleware.RBACMiddleware) *AdvanceBookingHandlers {
+	return &AdvanceBookingHandlers{
+		bookingService: bookingService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *AdvanceBookingHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// bookingError maps advance booking service errors to HTTP errors
+func bookingError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrBookingNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Advance booking not found")
+	case errors.Is(err, services.ErrInvalidBooking):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// CreateBooking handles POST /bookings
+func (h *AdvanceBookingHandlers) CreateBooking(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req services.CreateBookingRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	booking, err := h.bookingService.Create(ctx, tenantID, currentUser(c), &req)
+	if err != nil {
+		return bookingError(err, "Failed to create advance booking")
+	}
+
+	return c.JSON(http.StatusCreated, booking)
+}
+
+// ListBookings handles GET /bookings?status=&distributor_id=&product_id=&limit=&offset=
+func (h *AdvanceBookingHandlers) ListBookings(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	filter := &models.AdvanceBookingFilter{}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	filter.Limit, filter.Offset = page.Limit, page.Offset
+	if status := c.QueryParam("status"); status != "" {
+		filter.Status = &status
+	}
+	if distributorIDStr := c.QueryParam("distributor_id"); distributorIDStr != "" {
+		distributorID, err := uuid.Parse(distributorIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid distributor ID format")
+		}
+		filter.DistributorID = &distributorID
+	}
+	if productIDStr := c.QueryParam("product_id"); productIDStr != "" {
+		productID, err := uuid.Parse(productIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+		}
+		filter.ProductID = &productID
+	}
+
+	bookings, err := h.bookingService.List(ctx, tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve advance bookings")
+	}
+	if bookings == nil {
+		bookings = []*models.AdvanceBooking{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"bookings":    bookings,
+		"next_cursor": page.NextCursor(len(bookings)),
+	})
+}
+
+// ListDueBookings handles GET /bookings/due?days=30
+func (h *AdvanceBookingHandlers) ListDueBookings(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	days := 0
+	if daysStr := c.QueryParam("days"); daysStr != "" {
+		var err error
+		if days, err = strconv.Atoi(daysStr); err != nil || days <= 0 || days > 365 {
+			return echo.NewHTTPError(http.StatusBadRequest, "days must be between 1 and 365")
+		}
+	}
+
+	bookings, err := h.bookingService.ListDue(ctx, tenantID, days)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve due bookings")
+	}
+	if bookings == nil {
+		bookings = []*models.AdvanceBooking{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"bookings": bookings,
+	})
+}
+
+// GetBooking handles GET /bookings/:id
+func (h *AdvanceBookingHandlers) GetBooking(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid booking ID format")
+	}
+
+	booking, err := h.bookingService.Get(ctx, tenantID, bookingID)
+	if err != nil {
+		return bookingError(err, "Failed to retrieve advance booking")
+	}
+
+	return c.JSON(http.StatusOK, booking)
+}
+
+// RecordPayment handles POST /bookings/:id/payments
+func (h *AdvanceBookingHandlers) RecordPayment(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid booking ID format")
+	}
+
+	var req services.BookingPaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	booking, err := h.bookingService.RecordPayment(ctx, tenantID, bookingID, currentUser(c), &req)
+	if err != nil {
+		return bookingError(err, "Failed to record advance payment")
+	}
+
+	return c.JSON(http.StatusCreated, booking)
+}
+
+// CancelBooking handles POST /bookings/:id/cancel
+func (h *AdvanceBookingHandlers) CancelBooking(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid booking ID format")
+	}
+
+	booking, err := h.bookingService.Cancel(ctx, tenantID, bookingID)
+	if err != nil {
+		return bookingError(err, "Failed to cancel advance booking")
+	}
+
+	return c.JSON(http.StatusOK, booking)
+}
+
+// ConvertBooking handles POST /bookings/:id/convert
+func (h *AdvanceBookingHandlers) ConvertBooking(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid booking ID format")
+	}
+
+	booking, order, err := h.bookingService.Convert(ctx, tenantID, bookingID)
+	if err != nil {
+		return bookingError(err, "Failed to convert advance booking")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"booking": booking,
+		"order":   order,
+	})
+}
+
+// GetBookingDemand handles GET /analytics/booking-demand?from=YYYY-MM-DD&to=YYYY-MM-DD
+// The window defaults to the next twelve months; to is inclusive
+func (h *AdvanceBookingHandlers) GetBookingDemand(c echo.Context) error {
+	if err := h.requirePermission(c, "bookings:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	to := from.AddDate(1, 0, 0)
+	var err error
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+	}
+	if toStr := c.QueryParam("to"); toStr != "" {
+		day, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	report, err := h.bookingService.DemandReport(ctx, tenantID, from, to)
+	if err != nil {
+		return bookingError(err, "Failed to build booking demand report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/caching"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AnalyticsViewHandlers serves the sales, stock and receivables analytics
+// backed by materialized views
+type AnalyticsViewHandlers struct {
+	analytics      *analytics.AnalyticsService
+	cacheSvc       caching.CacheService
+	calendarSvc    services.TenantCalendarService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewAnalyticsViewHandlers creates a new analytics view handlers instance
+func NewAnalyticsViewHandlers(analytics *analytics.AnalyticsService, cacheSvc caching.CacheService,
+	calendarSvc services.TenantCalendarService, rbacMiddleware *middleware.RBACMiddleware) *AnalyticsViewHandlers {
+	return &AnalyticsViewHandlers{
+		analytics:      analytics,
+		cacheSvc:       cacheSvc,
+		calendarSvc:    calendarSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *AnalyticsViewHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetDailySales handles GET /analytics/daily-sales?from=YYYY-MM-DD&to=YYYY-MM-DD
+// or ?period=fiscal_year and the like. The window defaults to the last 30
+// days on the tenant's calendar; both ends are inclusive
+func (h *AnalyticsViewHandlers) GetDailySales(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	from, to, err := reportPeriod(c, services.TenantCalendarOrDefault(ctx, h.calendarSvc, tenantID), 30)
+	if err != nil {
+		return err
+	}
+	if to.Before(from) {
+		return echo.NewHTTPError(http.StatusBadRequest, "to must not be before from")
+	}
+
+	key := caching.ResponseKey(tenantID, "daily-sales", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	body, err := cachedResponse(c, h.cacheSvc, key, []string{caching.AnalyticsSurrogateKey}, analyticsResponseTTL, func() (interface{}, error) {
+		report, err := h.analytics.DailySales(ctx, tenantID, from, to)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get daily sales")
+		}
+		return report, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// GetStockByCategory handles GET /analytics/stock-by-category
+func (h *AnalyticsViewHandlers) GetStockByCategory(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	key := caching.ResponseKey(tenantID, "stock-by-category")
+	body, err := cachedResponse(c, h.cacheSvc, key, []string{caching.AnalyticsSurrogateKey}, analyticsResponseTTL, func() (interface{}, error) {
+		report, err := h.analytics.StockByCategory(ctx, tenantID)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get stock by category")
+		}
+		return report, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// GetReceivablesAging handles GET /analytics/receivables-aging
+func (h *AnalyticsViewHandlers) GetReceivablesAging(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	key := caching.ResponseKey(tenantID, "receivables-aging")
+	body, err := cachedResponse(c, h.cacheSvc, key, []string{caching.AnalyticsSurrogateKey}, analyticsResponseTTL, func() (interface{}, error) {
+		report, err := h.analytics.ReceivablesAging(ctx, tenantID)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get receivables aging")
+		}
+		return report, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// RefreshViews handles POST /analytics/views/refresh
+// It refreshes the views now rather than waiting for the scheduled job
+func (h *AnalyticsViewHandlers) RefreshViews(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:manage"); err != nil {
+		return err
+	}
+
+	refreshes, err := h.analytics.RefreshMaterializedViews(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to refresh analytics views")
+	}
+
+	return c.JSON(http.StatusOK, refreshes)
+}
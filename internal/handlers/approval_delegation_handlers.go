@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ApprovalDelegationHandlers handles approval delegations and the pending
+// approvals inbox they route
+type ApprovalDelegationHandlers struct {
+	delegationSvc  services.ApprovalDelegationService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewApprovalDelegationHandlers creates a new approval delegation handlers instance
+func NewApprovalDelegationHandlers(delegationSvc services.ApprovalDelegationService, rbacMiddleware *middleware.RBACMiddleware) *ApprovalDelegationHandlers {
+	return &ApprovalDelegationHandlers{
+		delegationSvc:  delegationSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *ApprovalDelegationHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// requireApprover checks the user may approve the kind, either covering for a
+// delegator who holds the permission or holding it themselves; anyone may
+// approve a kind without one. The delegation is returned when the user acts
+// under one, so the approval is recorded on the delegator's behalf
+func requireApprover(c echo.Context, rbac *middleware.RBACMiddleware, delegations services.ApprovalDelegationService, kind, permission string) (*models.ApprovalDelegation, error) {
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, hasTenant := rc.Tenant()
+	userID, hasUser := rc.User()
+	if delegations != nil && hasTenant && hasUser {
+		delegation, err := delegations.ActingFor(ctx, tenantID, userID, kind, permission)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error checking approval delegations")
+		}
+		if delegation != nil {
+			return delegation, nil
+		}
+	}
+	if permission == "" {
+		return nil, nil
+	}
+	return nil, rbac.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// recordDelegatedApproval records an approval made under a delegation, if any
+func recordDelegatedApproval(c echo.Context, delegations services.ApprovalDelegationService, delegation *models.ApprovalDelegation, kind string, entityID uuid.UUID, action string) {
+	if delegation == nil || delegations == nil {
+		return
+	}
+	delegations.RecordApproval(c.Request().Context(), delegation, kind, entityID, action, currentUser(c))
+}
+
+// approvalDelegationError maps approval delegation service errors to HTTP errors
+func approvalDelegationError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrApprovalDelegationNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Approval delegation not found")
+	case errors.Is(err, services.ErrInvalidApprovalDelegation):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrApprovalDelegationConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// canManageDelegation checks the user is a party to the delegation or may
+// manage everyone's
+func (h *ApprovalDelegationHandlers) canManageDelegation(c echo.Context, userID uuid.UUID, delegation *models.ApprovalDelegation) error {
+	if delegation.DelegatorID == userID || delegation.DelegateID == userID {
+		return nil
+	}
+	return h.requirePermission(c, "approvals:manage_delegations")
+}
+
+// ListDelegations handles GET /approval-delegations?all=&include_past=, the
+// user's delegations either way round, or everyone's with all=true
+func (h *ApprovalDelegationHandlers) ListDelegations(c echo.Context) error {
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := rc.User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	filter := &userID
+	if c.QueryParam("all") == "true" {
+		if err := h.requirePermission(c, "approvals:manage_delegations"); err != nil {
+			return err
+		}
+		filter = nil
+	}
+
+	delegations, err := h.delegationSvc.List(ctx, tenantID, filter, c.QueryParam("include_past") == "true")
+	if err != nil {
+		return approvalDelegationError(err, "Failed to list approval delegations")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"delegations": delegations,
+	})
+}
+
+// CreateDelegation handles POST /approval-delegations. Users delegate their
+// own approvals; naming another delegator needs approvals:manage_delegations
+func (h *ApprovalDelegationHandlers) CreateDelegation(c echo.Context) error {
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := rc.User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req models.ApprovalDelegationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if req.DelegatorID == nil || *req.DelegatorID == userID {
+		if err := h.requirePermission(c, "approvals:delegate"); err != nil {
+			return err
+		}
+		req.DelegatorID = &userID
+	} else if err := h.requirePermission(c, "approvals:manage_delegations"); err != nil {
+		return err
+	}
+
+	delegation, err := h.delegationSvc.Create(ctx, tenantID, &userID, &req)
+	if err != nil {
+		return approvalDelegationError(err, "Failed to create approval delegation")
+	}
+
+	return c.JSON(http.StatusCreated, delegation)
+}
+
+// GetDelegation handles GET /approval-delegations/:id, with the approvals
+// made under it
+func (h *ApprovalDelegationHandlers) GetDelegation(c echo.Context) error {
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := rc.User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid delegation ID format")
+	}
+
+	delegation, err := h.delegationSvc.Get(ctx, tenantID, id)
+	if err != nil {
+		return approvalDelegationError(err, "Failed to retrieve approval delegation")
+	}
+	if err := h.canManageDelegation(c, userID, delegation); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, delegation)
+}
+
+// RevokeDelegation handles POST /approval-delegations/:id/revoke; either
+// party can end a delegation early
+func (h *ApprovalDelegationHandlers) RevokeDelegation(c echo.Context) error {
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := rc.User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid delegation ID format")
+	}
+
+	delegation, err := h.delegationSvc.Get(ctx, tenantID, id)
+	if err != nil {
+		return approvalDelegationError(err, "Failed to retrieve approval delegation")
+	}
+	if err := h.canManageDelegation(c, userID, delegation); err != nil {
+		return err
+	}
+
+	delegation, err = h.delegationSvc.Revoke(ctx, tenantID, id, &userID)
+	if err != nil {
+		return approvalDelegationError(err, "Failed to revoke approval delegation")
+	}
+
+	return c.JSON(http.StatusOK, delegation)
+}
+
+// GetPendingApprovals handles GET /approvals/pending, the user's approvals
+// inbox including the items routed to them by delegations
+func (h *ApprovalDelegationHandlers) GetPendingApprovals(c echo.Context) error {
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := rc.User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	pending, err := h.delegationSvc.Pending(ctx, tenantID, userID)
+	if err != nil {
+		return approvalDelegationError(err, "Failed to load pending approvals")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"approvals": pending,
+	})
+}
@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 	"time"
 
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
 	"agromart2/internal/services"
@@ -41,7 +41,7 @@ func (h *AuditLogsHandlers) ListAuditLogs(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -76,18 +76,13 @@ func (h *AuditLogsHandlers) ListAuditLogs(c echo.Context) error {
 		filters.IncludeDeleted = true
 	}
 
-	// Parse pagination
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 || limit > 1000 {
-		limit = 50 // Default limit
-	}
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
-	if offset < 0 {
-		offset = 0
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 1000})
+	if err != nil {
+		return err
 	}
 
-	filters.Limit = limit
-	filters.Offset = offset
+	filters.Limit = page.Limit
+	filters.Offset = page.Offset
 
 	// Validate filters (security and performance)
 	if err := h.auditLogsService.ValidateAuditFilters(filters); err != nil {
@@ -101,10 +96,11 @@ func (h *AuditLogsHandlers) ListAuditLogs(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"data":   logs,
-		"total":  len(logs),
-		"limit":  filters.Limit,
-		"offset": filters.Offset,
+		"data":        logs,
+		"total":       len(logs),
+		"limit":       filters.Limit,
+		"offset":      filters.Offset,
+		"next_cursor": page.NextCursor(len(logs)),
 	})
 }
 
@@ -113,7 +109,7 @@ func (h *AuditLogsHandlers) GetAuditLog(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -138,7 +134,7 @@ func (h *AuditLogsHandlers) GetEntityHistory(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -151,29 +147,25 @@ func (h *AuditLogsHandlers) GetEntityHistory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Table name and record ID are required")
 	}
 
-	// Parse pagination
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 || limit > 1000 {
-		limit = 100 // Default for entity history
-	}
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
-	if offset < 0 {
-		offset = 0
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 100, MaxSize: 1000})
+	if err != nil {
+		return err
 	}
 
 	// Get entity history
-	logs, err := h.auditLogsService.GetEntityHistory(ctx, tenantID, tableName, recordID, limit, offset)
+	logs, err := h.auditLogsService.GetEntityHistory(ctx, tenantID, tableName, recordID, page.Limit, page.Offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve entity history")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"data":      logs,
-		"total":     len(logs),
-		"limit":     limit,
-		"offset":    offset,
-		"table":     tableName,
-		"record_id": recordID,
+		"data":        logs,
+		"total":       len(logs),
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(logs)),
+		"table":       tableName,
+		"record_id":   recordID,
 	})
 }
 
@@ -182,7 +174,7 @@ func (h *AuditLogsHandlers) GetUserActivity(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -193,28 +185,24 @@ func (h *AuditLogsHandlers) GetUserActivity(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
 	}
 
-	// Parse pagination
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 || limit > 1000 {
-		limit = 100 // Default for user activity
-	}
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
-	if offset < 0 {
-		offset = 0
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 100, MaxSize: 1000})
+	if err != nil {
+		return err
 	}
 
 	// Get user activity logs
-	logs, err := h.auditLogsService.GetUserActivity(ctx, tenantID, userID, limit, offset)
+	logs, err := h.auditLogsService.GetUserActivity(ctx, tenantID, userID, page.Limit, page.Offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve user activity")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"data":    logs,
-		"total":   len(logs),
-		"limit":   limit,
-		"offset":  offset,
-		"user_id": userID,
+		"data":        logs,
+		"total":       len(logs),
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(logs)),
+		"user_id":     userID,
 	})
 }
 
@@ -223,7 +211,7 @@ func (h *AuditLogsHandlers) GetAuditSummary(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -268,7 +256,7 @@ func (h *AuditLogsHandlers) GetTableNames(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -290,7 +278,7 @@ func (h *AuditLogsHandlers) GetActions(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -312,13 +300,13 @@ func (h *AuditLogsHandlers) SoftDeleteAuditLog(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	// Get user ID from context for authorization
-	userID, ok := common.GetUserIDFromContext(ctx)
+	userID, ok := common.RequestContextFrom(ctx).User()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
 	}
@@ -355,13 +343,13 @@ func (h *AuditLogsHandlers) CreateManualAuditLog(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	// Get user ID from context
-	userID, ok := common.GetUserIDFromContext(ctx)
+	userID, ok := common.RequestContextFrom(ctx).User()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
 	}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"log"
+	"math"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
 	"agromart2/internal/common"
@@ -24,23 +26,46 @@ type AuthHandlers struct {
 	roleRepo       repositories.RoleRepository
 	userRoleRepo   repositories.UserRoleRepository
 	rbacMiddleware *middleware.RBACMiddleware
+	cookieAuth     middleware.CookieAuthConfig
 }
 
 // NewAuthHandlers creates a new auth handlers instance
-func NewAuthHandlers(authService services.AuthService, userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, userRoleRepo repositories.UserRoleRepository, rbacMiddleware *middleware.RBACMiddleware) *AuthHandlers {
+func NewAuthHandlers(authService services.AuthService, userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, userRoleRepo repositories.UserRoleRepository, rbacMiddleware *middleware.RBACMiddleware, cookieAuth middleware.CookieAuthConfig) *AuthHandlers {
 	return &AuthHandlers{
 		authService:    authService,
 		userRepo:       userRepo,
 		roleRepo:       roleRepo,
 		userRoleRepo:   userRoleRepo,
 		rbacMiddleware: rbacMiddleware,
+		cookieAuth:     cookieAuth,
 	}
 }
 
-// LoginResponse represents the login response
+// LoginResponse represents the login response. In cookie auth mode the
+// tokens are set as cookies and left out, and the CSRF token is returned instead
 type LoginResponse struct {
 	models.TokenResponse
-	User *models.User `json:"user"`
+	User      *models.User `json:"user,omitempty"`
+	CSRFToken string       `json:"csrf_token,omitempty"`
+}
+
+// sessionResponse returns the tokens in the body, or sets them as cookies for
+// client types using cookie auth
+func (h *AuthHandlers) sessionResponse(c echo.Context, status int, tokens *models.TokenResponse, user *models.User, useCookies bool) error {
+	response := LoginResponse{
+		TokenResponse: *tokens,
+		User:          user,
+	}
+	if useCookies {
+		csrfToken, err := h.cookieAuth.SetSessionCookies(c, tokens)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to start session")
+		}
+		response.AccessToken = ""
+		response.RefreshToken = ""
+		response.CSRFToken = csrfToken
+	}
+	return c.JSON(status, response)
 }
 
 // LoginRequest represents the login request payload
@@ -62,6 +87,17 @@ func (h *AuthHandlers) Login(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Email and password are required")
 	}
 
+	// Reject early while the account or client IP is locked out
+	ip := c.RealIP()
+	if err := h.authService.CheckLoginAllowed(ctx, req.Email, ip); err != nil {
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			return echo.NewHTTPError(http.StatusTooManyRequests, "Too many failed login attempts. Please try again later.")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check login status")
+	}
+
 	// Get user by email - search across all tenants for multi-tenant authentication
 	// In production, this could be optimized with email domain routing
 	// For now, using known tenant IDs
@@ -74,11 +110,8 @@ func (h *AuthHandlers) Login(c echo.Context) error {
 		devTenantID, _ := uuid.Parse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
 		user, err = h.userRepo.GetByEmail(ctx, devTenantID, req.Email)
 	}
-	if err != nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, "User not found")
-	}
-
-	if user == nil {
+	if err != nil || user == nil {
+		h.recordFailedLogin(c, req.Email, ip)
 		return echo.NewHTTPError(http.StatusUnauthorized, "User not found")
 	}
 
@@ -89,6 +122,7 @@ func (h *AuthHandlers) Login(c echo.Context) error {
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		h.recordFailedLogin(c, req.Email, ip)
 		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid password")
 	}
 
@@ -104,12 +138,54 @@ func (h *AuthHandlers) Login(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens")
 	}
 
-	response := LoginResponse{
-		TokenResponse: *tokenResponse,
-		User:          user,
+	// Reset failure counters and notify the user about unfamiliar devices
+	if err := h.authService.RecordSuccessfulLogin(ctx, user, ip, c.Request().UserAgent()); err != nil {
+		log.Printf("Failed to record successful login for %s: %v", user.Email, err)
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return h.sessionResponse(c, http.StatusOK, tokenResponse, user, h.cookieAuth.UsesCookies(c))
+}
+
+// recordFailedLogin records a failed attempt without failing the request on cache errors
+func (h *AuthHandlers) recordFailedLogin(c echo.Context, email, ip string) {
+	if err := h.authService.RecordFailedLogin(c.Request().Context(), email, ip); err != nil {
+		log.Printf("Failed to record failed login for %s: %v", email, err)
+	}
+}
+
+// UnlockAccount clears lockouts for a user after too many failed logins (admin only)
+func (h *AuthHandlers) UnlockAccount(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("users:update")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	user, err := h.userRepo.GetByID(ctx, tenantID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+
+	if err := h.authService.UnlockAccount(ctx, user.Email); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unlock account")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Account unlocked successfully",
+	})
 }
 
 // SignupRequest represents the signup request payload
@@ -122,10 +198,7 @@ type SignupRequest struct {
 }
 
 // SignupResponse represents the signup response
-type SignupResponse struct {
-	models.TokenResponse
-	User *models.User `json:"user"`
-}
+type SignupResponse = LoginResponse
 
 // Signup handles user registration
 func (h *AuthHandlers) Signup(c echo.Context) error {
@@ -217,12 +290,7 @@ func (h *AuthHandlers) Signup(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate tokens")
 	}
 
-	response := SignupResponse{
-		TokenResponse: *tokenResponse,
-		User:          user,
-	}
-
-	return c.JSON(http.StatusCreated, response)
+	return h.sessionResponse(c, http.StatusCreated, tokenResponse, user, h.cookieAuth.UsesCookies(c))
 }
 
 // LogoutRequest represents the logout request payload
@@ -234,19 +302,17 @@ type LogoutRequest struct {
 func (h *AuthHandlers) Logout(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	_, ok := common.GetUserIDFromContext(ctx)
+	_, ok := common.RequestContextFrom(ctx).User()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
 	}
 
-	// Get the token from Authorization header
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
+	// Get the token from the Authorization header or the session cookie
+	tokenString, fromCookie, err := middleware.RequestToken(c)
+	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Authorization header missing")
 	}
 
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
 	var req LogoutRequest
 	if err := c.Bind(&req); err != nil {
 		// Bind is optional for logout, but we'll proceed with access token revocation
@@ -258,6 +324,17 @@ func (h *AuthHandlers) Logout(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke token")
 	}
 
+	// A cookie session ends with its refresh token, which the client never sees
+	if fromCookie {
+		if cookie, err := c.Cookie(middleware.RefreshTokenCookie); err == nil && cookie.Value != "" {
+			hint := "refresh_token"
+			if err := h.authService.RevokeToken(ctx, cookie.Value, &hint); err != nil {
+				log.Printf("Failed to revoke refresh token: %v", err)
+			}
+		}
+		h.cookieAuth.ClearSessionCookies(c)
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Logged out successfully",
 	})
@@ -271,13 +348,27 @@ type RefreshRequest struct {
 	Scope        *string `json:"scope"`
 }
 
-// Refresh handles token refresh
+// Refresh handles token refresh. Without a refresh token in the body the
+// refresh cookie is used, and the new tokens are set as cookies again
 func (h *AuthHandlers) Refresh(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	var req RefreshRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+		}
+	}
+
+	fromCookie := false
+	if req.RefreshToken == "" {
+		if cookie, err := c.Cookie(middleware.RefreshTokenCookie); err == nil && cookie.Value != "" {
+			req.RefreshToken = cookie.Value
+			if req.GrantType == "" {
+				req.GrantType = "refresh_token"
+			}
+			fromCookie = true
+		}
 	}
 
 	if req.RefreshToken == "" {
@@ -291,9 +382,15 @@ func (h *AuthHandlers) Refresh(c echo.Context) error {
 	// Refresh tokens
 	tokenResponse, err := h.authService.RefreshToken(ctx, req.RefreshToken, req.ClientID)
 	if err != nil {
+		if fromCookie {
+			h.cookieAuth.ClearSessionCookies(c)
+		}
 		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid or expired refresh token")
 	}
 
+	if fromCookie {
+		return h.sessionResponse(c, http.StatusOK, tokenResponse, nil, true)
+	}
 	return c.JSON(http.StatusOK, tokenResponse)
 }
 
@@ -301,12 +398,12 @@ func (h *AuthHandlers) Refresh(c echo.Context) error {
 func (h *AuthHandlers) Me(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	userID, ok := common.GetUserIDFromContext(ctx)
+	userID, ok := common.RequestContextFrom(ctx).User()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// AvailabilityHandlers handles product availability requests
+type AvailabilityHandlers struct {
+	availabilityService services.AvailabilityService
+	rbacMiddleware      *middleware.RBACMiddleware
+}
+
+// NewAvailabilityHandlers creates a new availability handlers instance
+func NewAvailabilityHandlers(availabilityService services.AvailabilityService, rbacMiddleware *middleware.RBACMiddleware) *AvailabilityHandlers {
+	return &AvailabilityHandlers{
+		availabilityService: availabilityService,
+		rbacMiddleware:      rbacMiddleware,
+	}
+}
+
+// GetProductAvailability handles GET /products/:id/availability
+func (h *AvailabilityHandlers) GetProductAvailability(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("inventories:read")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	availability, err := h.availabilityService.GetProductAvailability(ctx, tenantID, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	return c.JSON(http.StatusOK, availability)
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// BranchHandlers handles the tenant's branches, each trading under its own GSTIN
+type BranchHandlers struct {
+	branchService  services.BranchService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewBranchHandlers creates a new branch handlers instance
+func NewBranchHandlers(branchService services.BranchService, rbacMiddleware *middleware.RBACMiddleware) *BranchHandlers {
+	return &BranchHandlers{
+		branchService:  branchService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *BranchHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// parseBranchFilter reads the optional branch_id filter shared by listings,
+// reports and analytics
+func parseBranchFilter(c echo.Context) (*uuid.UUID, error) {
+	raw := c.QueryParam("branch_id")
+	if raw == "" {
+		return nil, nil
+	}
+	branchID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid branch_id format")
+	}
+	return &branchID, nil
+}
+
+// branchError maps branch service errors to HTTP errors
+func branchError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrBranchNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Branch not found")
+	case errors.Is(err, services.ErrInvalidBranch), errors.Is(err, services.ErrInvalidGSTIN):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrDuplicateName):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// BranchRequest is the body of branch create and update requests; code and
+// gstin are only read on create
+type BranchRequest struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	LegalName *string `json:"legal_name"`
+	GSTIN     string  `json:"gstin"`
+	Address   *string `json:"address"`
+}
+
+// ListBranches handles GET /branches?include_archived=
+func (h *BranchHandlers) ListBranches(c echo.Context) error {
+	if err := h.requirePermission(c, "branches:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	branches, err := h.branchService.List(ctx, tenantID, c.QueryParam("include_archived") == "true")
+	if err != nil {
+		return branchError(err, "Failed to list branches")
+	}
+	if branches == nil {
+		branches = []*models.Branch{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"branches": branches,
+	})
+}
+
+// CreateBranch handles POST /branches
+func (h *BranchHandlers) CreateBranch(c echo.Context) error {
+	if err := h.requirePermission(c, "branches:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req BranchRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	branch := &models.Branch{
+		Code:      req.Code,
+		Name:      req.Name,
+		LegalName: req.LegalName,
+		GSTIN:     req.GSTIN,
+		Address:   req.Address,
+	}
+	if err := h.branchService.Create(ctx, tenantID, branch); err != nil {
+		return branchError(err, "Failed to create branch")
+	}
+
+	return c.JSON(http.StatusCreated, branch)
+}
+
+// GetBranch handles GET /branches/:id
+func (h *BranchHandlers) GetBranch(c echo.Context) error {
+	if err := h.requirePermission(c, "branches:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid branch ID format")
+	}
+
+	branch, err := h.branchService.Get(ctx, tenantID, id)
+	if err != nil {
+		return branchError(err, "Failed to retrieve branch")
+	}
+
+	return c.JSON(http.StatusOK, branch)
+}
+
+// UpdateBranch handles PUT /branches/:id
+func (h *BranchHandlers) UpdateBranch(c echo.Context) error {
+	if err := h.requirePermission(c, "branches:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid branch ID format")
+	}
+
+	var req BranchRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	branch, err := h.branchService.Update(ctx, tenantID, &models.Branch{
+		ID:        id,
+		Name:      req.Name,
+		LegalName: req.LegalName,
+		Address:   req.Address,
+	})
+	if err != nil {
+		return branchError(err, "Failed to update branch")
+	}
+
+	return c.JSON(http.StatusOK, branch)
+}
+
+// DeleteBranch handles DELETE /branches/:id
+func (h *BranchHandlers) DeleteBranch(c echo.Context) error {
+	if err := h.requirePermission(c, "branches:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid branch ID format")
+	}
+
+	archived, err := h.branchService.Delete(ctx, tenantID, id)
+	if err != nil {
+		return branchError(err, "Failed to delete branch")
+	}
+
+	return deletedResponse(c, "Branch", archived)
+}
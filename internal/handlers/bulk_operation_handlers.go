@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxBulkItems caps the IDs a single bulk delete or cancel may name
+const maxBulkItems = 5000
+
+// BulkOperationHandlers serves the status of background bulk operations
+type BulkOperationHandlers struct {
+	bulkOps services.BulkOperationService
+}
+
+// NewBulkOperationHandlers creates a new bulk operation handlers instance
+func NewBulkOperationHandlers(bulkOps services.BulkOperationService) *BulkOperationHandlers {
+	return &BulkOperationHandlers{bulkOps: bulkOps}
+}
+
+// GetBulkOperation handles GET /bulk-operations/:id
+func (h *BulkOperationHandlers) GetBulkOperation(c echo.Context) error {
+	tenantID, ok := common.RequestContextFrom(c.Request().Context()).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	result := h.bulkOps.Get(tenantID, c.Param("id"))
+	if result == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Bulk operation not found or expired")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// sendBulkResult answers a bulk request: 202 with a polling location while the
+// operation runs in the background, otherwise the finished result
+func sendBulkResult(c echo.Context, result *models.BulkOperationResult) error {
+	switch result.Status {
+	case "pending", "processing":
+		c.Response().Header().Set(echo.HeaderLocation, "/v1/bulk-operations/"+result.OperationID)
+		return c.JSON(http.StatusAccepted, result)
+	case "partial":
+		return c.JSON(http.StatusPartialContent, result)
+	default:
+		return c.JSON(http.StatusOK, result)
+	}
+}
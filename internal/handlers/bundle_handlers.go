@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// BundleHandlers handles bundle (kit) definitions and builds
+type BundleHandlers struct {
+	bundleService  services.BundleService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewBundleHandlers creates a new bundle handlers instance
+func NewBundleHandlers(bundleService services.BundleService, rbacMiddleware *middleware.RBACMiddleware) *BundleHandlers {
+	return &BundleHandlers{
+		bundleService:  bundleService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *BundleHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// bundleError maps bundle service errors to HTTP errors
+func bundleError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrBundleNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Bundle not found")
+	case errors.Is(err, services.ErrInvalidBundle):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInsufficientStock):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// GetComponents handles GET /products/:id/components
+func (h *BundleHandlers) GetComponents(c echo.Context) error {
+	if err := h.requirePermission(c, "bundles:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bundleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	components, err := h.bundleService.GetComponents(ctx, tenantID, bundleID)
+	if err != nil {
+		return bundleError(err, "Failed to retrieve bundle components")
+	}
+	if components == nil {
+		components = []*models.BundleComponent{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"components": components,
+	})
+}
+
+type setBundleComponentsRequest struct {
+	Components []*models.BundleComponent `json:"components"`
+}
+
+// SetComponents handles PUT /products/:id/components; an empty list turns the
+// bundle back into a plain product
+func (h *BundleHandlers) SetComponents(c echo.Context) error {
+	if err := h.requirePermission(c, "bundles:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bundleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	var req setBundleComponentsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	components, err := h.bundleService.SetComponents(ctx, tenantID, bundleID, req.Components)
+	if err != nil {
+		return bundleError(err, "Failed to update bundle components")
+	}
+	if components == nil {
+		components = []*models.BundleComponent{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"components": components,
+	})
+}
+
+type bundleBuildRequest struct {
+	WarehouseID uuid.UUID `json:"warehouse_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// bindBuild reads the product ID and build request shared by assemble and disassemble
+func bindBuild(c echo.Context) (uuid.UUID, *bundleBuildRequest, error) {
+	bundleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+	req := &bundleBuildRequest{}
+	if err := c.Bind(req); err != nil {
+		return uuid.Nil, nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if req.WarehouseID == uuid.Nil {
+		return uuid.Nil, nil, echo.NewHTTPError(http.StatusBadRequest, "warehouse_id is required")
+	}
+	if req.Quantity <= 0 {
+		return uuid.Nil, nil, echo.NewHTTPError(http.StatusBadRequest, "quantity must be positive")
+	}
+	return bundleID, req, nil
+}
+
+// Assemble handles POST /products/:id/assemble
+func (h *BundleHandlers) Assemble(c echo.Context) error {
+	if err := h.requirePermission(c, "bundles:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bundleID, req, err := bindBuild(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.bundleService.Assemble(ctx, tenantID, bundleID, req.WarehouseID, req.Quantity)
+	if err != nil {
+		return bundleError(err, "Failed to assemble bundles")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// Disassemble handles POST /products/:id/disassemble
+func (h *BundleHandlers) Disassemble(c echo.Context) error {
+	if err := h.requirePermission(c, "bundles:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	bundleID, req, err := bindBuild(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.bundleService.Disassemble(ctx, tenantID, bundleID, req.WarehouseID, req.Quantity)
+	if err != nil {
+		return bundleError(err, "Failed to disassemble bundles")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
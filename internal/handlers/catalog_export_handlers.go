@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// CatalogExportHandlers handles generating printable product catalog PDFs
+type CatalogExportHandlers struct {
+	catalogPDF     *jobs.CatalogPDFService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewCatalogExportHandlers creates a new catalog export handlers instance
+func NewCatalogExportHandlers(catalogPDF *jobs.CatalogPDFService, rbacMiddleware *middleware.RBACMiddleware) *CatalogExportHandlers {
+	return &CatalogExportHandlers{
+		catalogPDF:     catalogPDF,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *CatalogExportHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GenerateCatalog handles POST /catalogs/generate; the PDF is rendered in the
+// background, so the response is the export record to poll
+func (h *CatalogExportHandlers) GenerateCatalog(c echo.Context) error {
+	if err := h.requirePermission(c, "catalog:export"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.CatalogExportRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var requestedBy *uuid.UUID
+	if userID, ok := rc.User(); ok {
+		requestedBy = &userID
+	}
+
+	export, err := h.catalogPDF.Generate(ctx, tenantID, requestedBy, &req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/v1/catalogs/exports/"+export.ID.String())
+	return c.JSON(http.StatusAccepted, export)
+}
+
+// ListCatalogExports handles GET /catalogs/exports
+func (h *CatalogExportHandlers) ListCatalogExports(c echo.Context) error {
+	if err := h.requirePermission(c, "catalog:export"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 20})
+	if err != nil {
+		return err
+	}
+
+	exports, err := h.catalogPDF.List(ctx, tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list catalog exports")
+	}
+	if exports == nil {
+		exports = []*models.CatalogExport{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"exports":     exports,
+		"next_cursor": page.NextCursor(len(exports)),
+	})
+}
+
+// GetCatalogExport handles GET /catalogs/exports/:id, reporting progress and,
+// once finished, a shareable download link
+func (h *CatalogExportHandlers) GetCatalogExport(c echo.Context) error {
+	if err := h.requirePermission(c, "catalog:export"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid catalog export ID format")
+	}
+
+	export, err := h.catalogPDF.Get(ctx, tenantID, id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Catalog export not found")
+	}
+
+	return c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// catalogCacheControl lets storefronts and CDNs cache catalog responses briefly
+const catalogCacheControl = "public, max-age=60"
+
+// CatalogHandlers handles product publishing and the public storefront catalog
+type CatalogHandlers struct {
+	catalogService services.CatalogService
+	cacheSvc       caching.CacheService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewCatalogHandlers creates a new catalog handlers instance
+func NewCatalogHandlers(catalogService services.CatalogService, cacheSvc caching.CacheService, rbacMiddleware *middleware.RBACMiddleware) *CatalogHandlers {
+	return &CatalogHandlers{
+		catalogService: catalogService,
+		cacheSvc:       cacheSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *CatalogHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// RequireCatalogToken authenticates storefront requests by catalog token
+// instead of a user session and scopes them to the token's tenant
+func (h *CatalogHandlers) RequireCatalogToken() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			rawToken := c.Request().Header.Get("X-Catalog-Token")
+			if rawToken == "" {
+				rawToken = c.QueryParam("token")
+			}
+			if rawToken == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Catalog token is required")
+			}
+
+			token, err := h.catalogService.Authenticate(c.Request().Context(), rawToken)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid catalog token")
+			}
+
+			rc := common.RequestContextFrom(c.Request().Context()).Clone()
+			rc.TenantID = token.TenantID
+			ctx := common.WithRequestContext(c.Request().Context(), rc)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// PublishProduct handles POST /products/:id/publish
+func (h *CatalogHandlers) PublishProduct(c echo.Context) error {
+	return h.setPublished(c, true)
+}
+
+// UnpublishProduct handles DELETE /products/:id/publish
+func (h *CatalogHandlers) UnpublishProduct(c echo.Context) error {
+	return h.setPublished(c, false)
+}
+
+func (h *CatalogHandlers) setPublished(c echo.Context, published bool) error {
+	if err := h.requirePermission(c, "catalog:publish"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	if err := h.catalogService.SetPublished(ctx, tenantID, productID, published); err != nil {
+		if errors.Is(err, services.ErrCatalogProductNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update product publishing")
+	}
+	caching.PublishEvent(ctx, h.cacheSvc, tenantID, caching.EventProductUpdated)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"product_id":   productID,
+		"is_published": published,
+	})
+}
+
+// CreateToken handles POST /catalog/tokens
+func (h *CatalogHandlers) CreateToken(c echo.Context) error {
+	if err := h.requirePermission(c, "catalog:manage_tokens"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var createdBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		createdBy = &userID
+	}
+
+	token, rawToken, err := h.catalogService.CreateToken(ctx, tenantID, req.Name, createdBy)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "token name") {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create catalog token")
+	}
+
+	// The raw token is only ever returned here
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"token":         rawToken,
+		"catalog_token": token,
+	})
+}
+
+// ListTokens handles GET /catalog/tokens
+func (h *CatalogHandlers) ListTokens(c echo.Context) error {
+	if err := h.requirePermission(c, "catalog:manage_tokens"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	tokens, err := h.catalogService.ListTokens(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve catalog tokens")
+	}
+	if tokens == nil {
+		tokens = []*models.CatalogToken{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tokens": tokens,
+	})
+}
+
+// RevokeToken handles DELETE /catalog/tokens/:id
+func (h *CatalogHandlers) RevokeToken(c echo.Context) error {
+	if err := h.requirePermission(c, "catalog:manage_tokens"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid token ID format")
+	}
+
+	if err := h.catalogService.RevokeToken(ctx, tenantID, id); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke catalog token")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListCatalogProducts handles GET /catalog/public/products for storefronts
+func (h *CatalogHandlers) ListCatalogProducts(c echo.Context) error {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	var categoryID *uuid.UUID
+	if raw := c.QueryParam("category_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID format")
+		}
+		categoryID = &parsed
+	}
+
+	locale, err := catalogLocale(c)
+	if err != nil {
+		return err
+	}
+
+	category := ""
+	if categoryID != nil {
+		category = categoryID.String()
+	}
+	key := caching.ResponseKey(tenantID, "catalog-products", category, locale, strconv.Itoa(page.Limit), strconv.Itoa(page.Offset))
+	payload, err := cachedResponse(c, h.cacheSvc, key, []string{caching.CatalogSurrogateKey(tenantID)}, catalogResponseTTL, func() (interface{}, error) {
+		products, err := h.catalogService.ListPublished(ctx, tenantID, categoryID, locale, page.Limit, page.Offset)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve catalog")
+		}
+		if products == nil {
+			products = []*models.CatalogProduct{}
+		}
+		for _, product := range products {
+			setCatalogImageURLs(product)
+		}
+
+		return map[string]interface{}{
+			"products":    products,
+			"limit":       page.Limit,
+			"offset":      page.Offset,
+			"next_cursor": page.NextCursor(len(products)),
+		}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return catalogJSON(c, payload)
+}
+
+// GetCatalogProduct handles GET /catalog/public/products/:id for storefronts
+func (h *CatalogHandlers) GetCatalogProduct(c echo.Context) error {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	locale, err := catalogLocale(c)
+	if err != nil {
+		return err
+	}
+
+	key := caching.ResponseKey(tenantID, "catalog-product", productID.String(), locale)
+	payload, err := cachedResponse(c, h.cacheSvc, key, []string{caching.CatalogSurrogateKey(tenantID)}, catalogResponseTTL, func() (interface{}, error) {
+		product, err := h.catalogService.GetPublished(ctx, tenantID, productID, locale)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Product not found")
+		}
+		setCatalogImageURLs(product)
+		return product, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return catalogJSON(c, payload)
+}
+
+// GetCatalogImage handles GET /catalog/public/images/:id by redirecting to a short-lived image URL
+func (h *CatalogHandlers) GetCatalogImage(c echo.Context) error {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	imageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid image ID format")
+	}
+
+	url, err := h.catalogService.GetImageURL(ctx, tenantID, imageID, 15*time.Minute)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Image not found")
+	}
+
+	// The redirect is as token-scoped as the catalog itself
+	header := c.Response().Header()
+	header.Set("Cache-Control", catalogCacheControl)
+	header.Add("Vary", "X-Catalog-Token")
+	return c.Redirect(http.StatusFound, url)
+}
+
+func catalogLocale(c echo.Context) (string, error) {
+	locale := strings.ToLower(strings.TrimSpace(c.QueryParam("locale")))
+	if locale != "" && !localePattern.MatchString(locale) {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Invalid locale")
+	}
+	if locale == "" {
+		// Without an explicit locale, fall back to the request's Accept-Language
+		c.Response().Header().Add("Vary", "Accept-Language")
+		if rc := common.RequestContextFrom(c.Request().Context()); rc != nil {
+			locale = rc.Locale
+		}
+	}
+	return locale, nil
+}
+
+// setCatalogImageURLs points images at the catalog image endpoint; the links are
+// stable across requests, unlike presigned URLs, so responses stay cacheable
+func setCatalogImageURLs(product *models.CatalogProduct) {
+	for i := range product.Images {
+		product.Images[i].URL = "/v1/catalog/public/images/" + product.Images[i].ID.String()
+	}
+}
+
+// catalogJSON writes an encoded JSON payload as a cacheable response,
+// answering 304 when the client's If-None-Match already carries the current ETag
+func catalogJSON(c echo.Context, payload []byte) error {
+	sum := sha256.Sum256(payload)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", catalogCacheControl)
+	// Add, not Set: catalogLocale may already vary the response on Accept-Language
+	header.Add("Vary", "X-Catalog-Token")
+
+	for _, candidate := range strings.Split(c.Request().Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+
+	return c.JSONBlob(http.StatusOK, payload)
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubCatalogService keeps a tenant's products and whether each is
+// published; the rest of the service is left unimplemented
+type stubCatalogService struct {
+	services.CatalogService
+	tokens    map[string]*models.CatalogToken
+	products  map[uuid.UUID]*models.CatalogProduct
+	published map[uuid.UUID]bool
+}
+
+func newStubCatalogService(products ...*models.CatalogProduct) *stubCatalogService {
+	s := &stubCatalogService{
+		tokens:    map[string]*models.CatalogToken{},
+		products:  map[uuid.UUID]*models.CatalogProduct{},
+		published: map[uuid.UUID]bool{},
+	}
+	for _, product := range products {
+		s.products[product.ID] = product
+	}
+	return s
+}
+
+func (s *stubCatalogService) Authenticate(ctx context.Context, rawToken string) (*models.CatalogToken, error) {
+	token, ok := s.tokens[rawToken]
+	if !ok {
+		return nil, errors.New("invalid catalog token")
+	}
+	return token, nil
+}
+
+func (s *stubCatalogService) SetPublished(ctx context.Context, tenantID, productID uuid.UUID, published bool) error {
+	if _, ok := s.products[productID]; !ok {
+		return services.ErrCatalogProductNotFound
+	}
+	s.published[productID] = published
+	return nil
+}
+
+func (s *stubCatalogService) GetPublished(ctx context.Context, tenantID, productID uuid.UUID, locale string) (*models.CatalogProduct, error) {
+	if !s.published[productID] {
+		return nil, errors.New("product not found")
+	}
+	return s.products[productID], nil
+}
+
+func (s *stubCatalogService) GetImageURL(ctx context.Context, tenantID, imageID uuid.UUID, expiry time.Duration) (string, error) {
+	return "https://minio.example.com/product-images/" + imageID.String() + "?X-Amz-Signature=abc", nil
+}
+
+// purgeRecorder records the surrogate keys purged from the response cache
+// and caches nothing
+type purgeRecorder struct {
+	caching.CacheService
+	purged []string
+}
+
+func (r *purgeRecorder) GetResponse(ctx context.Context, key string) ([]byte, error) {
+	return nil, nil
+}
+
+func (r *purgeRecorder) SetResponse(ctx context.Context, key string, body []byte, surrogateKeys []string, ttl time.Duration) error {
+	return nil
+}
+
+func (r *purgeRecorder) PurgeSurrogateKeys(ctx context.Context, surrogateKeys ...string) error {
+	r.purged = append(r.purged, surrogateKeys...)
+	return nil
+}
+
+func catalogProduct() *models.CatalogProduct {
+	return &models.CatalogProduct{ID: uuid.New(), Name: "Urea 45kg", Images: []models.CatalogImage{}}
+}
+
+// catalogContext builds a storefront request already scoped to a tenant, as
+// RequireCatalogToken leaves it
+func catalogContext(target string, headers map[string]string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rc := &common.RequestContext{TenantID: uuid.New(), Locale: "hi"}
+	req = req.WithContext(common.WithRequestContext(req.Context(), rc))
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func getCatalogProduct(t *testing.T, h *CatalogHandlers, productID uuid.UUID, query string, headers map[string]string) (*httptest.ResponseRecorder, error) {
+	t.Helper()
+	c, rec := catalogContext("/v1/catalog/public/products/"+productID.String()+query, headers)
+	c.SetParamNames("id")
+	c.SetParamValues(productID.String())
+	return rec, h.GetCatalogProduct(c)
+}
+
+func assertHTTPStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, status, httpErr.Code)
+}
+
+func TestRequireCatalogToken(t *testing.T) {
+	tenantID := uuid.New()
+	catalog := newStubCatalogService()
+	catalog.tokens["cat_valid"] = &models.CatalogToken{ID: uuid.New(), TenantID: tenantID}
+	h := NewCatalogHandlers(catalog, nil, nil)
+
+	tests := []struct {
+		name    string
+		target  string
+		header  string
+		allowed bool
+	}{
+		{name: "header token", target: "/v1/catalog/public/products", header: "cat_valid", allowed: true},
+		{name: "query token", target: "/v1/catalog/public/products?token=cat_valid", allowed: true},
+		{name: "missing token", target: "/v1/catalog/public/products"},
+		{name: "unknown token", target: "/v1/catalog/public/products", header: "cat_revoked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("X-Catalog-Token", tt.header)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			var scopedTo uuid.UUID
+			err := h.RequireCatalogToken()(func(c echo.Context) error {
+				scopedTo, _ = common.RequestContextFrom(c.Request().Context()).Tenant()
+				return nil
+			})(c)
+
+			if !tt.allowed {
+				assertHTTPStatus(t, err, http.StatusUnauthorized)
+				assert.Equal(t, uuid.Nil, scopedTo)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tenantID, scopedTo)
+		})
+	}
+}
+
+func TestCatalogResponsesVaryOnTokenAndLanguage(t *testing.T) {
+	product := catalogProduct()
+	catalog := newStubCatalogService(product)
+	catalog.published[product.ID] = true
+	h := NewCatalogHandlers(catalog, nil, nil)
+
+	rec, err := getCatalogProduct(t, h, product.ID, "", map[string]string{"Accept-Language": "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.ElementsMatch(t, []string{"Accept-Language", "X-Catalog-Token"}, rec.Header().Values("Vary"))
+
+	// An explicit locale does not depend on Accept-Language
+	rec, err = getCatalogProduct(t, h, product.ID, "?locale=en", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"X-Catalog-Token"}, rec.Header().Values("Vary"))
+}
+
+func TestCatalogResponsesAnswerNotModified(t *testing.T) {
+	product := catalogProduct()
+	catalog := newStubCatalogService(product)
+	catalog.published[product.ID] = true
+	h := NewCatalogHandlers(catalog, nil, nil)
+
+	rec, err := getCatalogProduct(t, h, product.ID, "", nil)
+	require.NoError(t, err)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, catalogCacheControl, rec.Header().Get("Cache-Control"))
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"stale", ` + etag, "*"} {
+		rec, err = getCatalogProduct(t, h, product.ID, "", map[string]string{"If-None-Match": ifNoneMatch})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotModified, rec.Code, ifNoneMatch)
+		assert.Empty(t, rec.Body.String(), ifNoneMatch)
+		assert.Equal(t, etag, rec.Header().Get("ETag"), ifNoneMatch)
+	}
+
+	// A changed product has a new ETag, so the stale one gets the full body
+	product.Name = "Urea 50kg"
+	rec, err = getCatalogProduct(t, h, product.ID, "", map[string]string{"If-None-Match": etag})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	assert.Equal(t, "Urea 50kg", decodeBody(t, rec)["name"])
+}
+
+func TestCatalogImageRedirectVariesOnToken(t *testing.T) {
+	h := NewCatalogHandlers(newStubCatalogService(), nil, nil)
+	imageID := uuid.New()
+	c, rec := catalogContext("/v1/catalog/public/images/"+imageID.String(), nil)
+	c.SetParamNames("id")
+	c.SetParamValues(imageID.String())
+
+	require.NoError(t, h.GetCatalogImage(c))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Contains(t, rec.Header().Get("Location"), imageID.String())
+	assert.Equal(t, []string{"X-Catalog-Token"}, rec.Header().Values("Vary"))
+}
+
+func TestPublishingControlsTheStorefront(t *testing.T) {
+	product := catalogProduct()
+	catalog := newStubCatalogService(product)
+	cache := &purgeRecorder{}
+	h := NewCatalogHandlers(catalog, cache, middleware.NewRBACMiddleware(&stubRBACService{permissions: []string{"catalog:publish"}}))
+	tenantID := uuid.New()
+
+	setPublished := func(productID uuid.UUID, published bool) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/products/"+productID.String()+"/publish", nil)
+		rc := &common.RequestContext{TenantID: tenantID, UserID: uuid.New()}
+		req = req.WithContext(common.WithRequestContext(req.Context(), rc))
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(productID.String())
+		if published {
+			return rec, h.PublishProduct(c)
+		}
+		return rec, h.UnpublishProduct(c)
+	}
+
+	// Unpublished products are not in the storefront
+	_, err := getCatalogProduct(t, h, product.ID, "", nil)
+	assertHTTPStatus(t, err, http.StatusNotFound)
+
+	rec, err := setPublished(product.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, true, decodeBody(t, rec)["is_published"])
+	assert.Equal(t, []string{caching.CatalogSurrogateKey(tenantID)}, cache.purged)
+	rec, err = getCatalogProduct(t, h, product.ID, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec, err = setPublished(product.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, false, decodeBody(t, rec)["is_published"])
+	assert.Len(t, cache.purged, 2)
+	_, err = getCatalogProduct(t, h, product.ID, "", nil)
+	assertHTTPStatus(t, err, http.StatusNotFound)
+
+	_, err = setPublished(uuid.New(), true)
+	assertHTTPStatus(t, err, http.StatusNotFound)
+	assert.Len(t, cache.purged, 2)
+
+	// Without catalog:publish nothing changes
+	h = NewCatalogHandlers(catalog, cache, middleware.NewRBACMiddleware(&stubRBACService{}))
+	_, err = setPublished(product.ID, true)
+	assertHTTPStatus(t, err, http.StatusForbidden)
+	assert.False(t, catalog.published[product.ID])
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
+	"agromart2/internal/caching"
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
+	"agromart2/internal/services"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -16,23 +22,21 @@ import (
 // CategoryHandlers handles category-related HTTP requests
 type CategoryHandlers struct {
 	categoryRepo  repositories.CategoryRepository
+	dependencyService services.DependencyService
+	cacheSvc       caching.CacheService
 	rbacMiddleware *middleware.RBACMiddleware
 }
 
 // NewCategoryHandlers creates a new category handlers instance
-func NewCategoryHandlers(categoryRepo repositories.CategoryRepository, rbacMiddleware *middleware.RBACMiddleware) *CategoryHandlers {
+func NewCategoryHandlers(categoryRepo repositories.CategoryRepository, dependencyService services.DependencyService, cacheSvc caching.CacheService, rbacMiddleware *middleware.RBACMiddleware) *CategoryHandlers {
 	return &CategoryHandlers{
 		categoryRepo:  categoryRepo,
+		dependencyService: dependencyService,
+		cacheSvc:       cacheSvc,
 		rbacMiddleware: rbacMiddleware,
 	}
 }
 
-// ListCategoriesRequest represents query parameters for listing categories
-type ListCategoriesRequest struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
-}
-
 // ListCategories handles getting a list of categories with tenant filtering
 func (h *CategoryHandlers) ListCategories(c echo.Context) error {
 	log.Printf("DEBUG: ListCategories handler called")
@@ -48,39 +52,37 @@ func (h *CategoryHandlers) ListCategories(c echo.Context) error {
 	ctx := c.Request().Context()
 	log.Printf("DEBUG: ListCategories handler context retrieved")
 
-	var req ListCategoriesRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
-	}
-
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
-	if req.Limit > 100 {
-		req.Limit = 100 // Maximum limit
-	}
-	if req.Offset < 0 {
-		req.Offset = 0
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.CategorySortColumns})
+	if err != nil {
+		return err
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	// Get categories from the tenant
-	categories, err := h.categoryRepo.List(ctx, tenantID, req.Limit, req.Offset)
+	// Get categories from the tenant, or the cached page of them
+	key := caching.ResponseKey(tenantID, "categories", strconv.Itoa(page.Limit), strconv.Itoa(page.Offset), fmt.Sprint(page.Sort))
+	body, err := cachedResponse(c, h.cacheSvc, key, []string{caching.CategoriesSurrogateKey(tenantID)}, categoryResponseTTL, func() (interface{}, error) {
+		categories, err := h.categoryRepo.ListPage(ctx, tenantID, page)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to list categories")
+		}
+
+		return map[string]interface{}{
+			"categories":  categories,
+			"limit":       page.Limit,
+			"offset":      page.Offset,
+			"next_cursor": page.NextCursor(len(categories)),
+		}, nil
+	})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list categories")
+		return err
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"categories": categories,
-		"limit":      req.Limit,
-		"offset":     req.Offset,
-	})
+	return c.JSONBlob(http.StatusOK, body)
 }
 
 // CreateCategoryRequest represents the category creation request payload
@@ -113,7 +115,7 @@ func (h *CategoryHandlers) CreateCategory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -162,6 +164,7 @@ func (h *CategoryHandlers) CreateCategory(c echo.Context) error {
 	if err := h.categoryRepo.Create(ctx, category); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create category")
 	}
+	caching.PublishEvent(ctx, h.cacheSvc, tenantID, caching.EventCategoryUpdated)
 
 	return c.JSON(http.StatusCreated, category)
 }
@@ -189,7 +192,7 @@ func (h *CategoryHandlers) GetCategory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	log.Printf("DEBUG: ListCategories tenant ID: %s, ok: %v", tenantID.String(), ok)
 	if !ok {
 		log.Printf("DEBUG: ListCategories - tenant not found in context")
@@ -202,7 +205,7 @@ func (h *CategoryHandlers) GetCategory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Category not found")
 	}
 
-	return c.JSON(http.StatusOK, category)
+	return jsonWithETag(c, resourceETag(category.ID, category.UpdatedAt), category)
 }
 
 // UpdateCategoryRequest represents the category update request payload
@@ -239,7 +242,7 @@ func (h *CategoryHandlers) UpdateCategory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -249,6 +252,9 @@ func (h *CategoryHandlers) UpdateCategory(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Category not found")
 	}
+	if ctx, err = checkIfMatch(ctx, c, resourceETag(category.ID, category.UpdatedAt), category.ID, category.UpdatedAt); err != nil {
+		return err
+	}
 
 	// Update fields if provided
 	if req.Name != nil {
@@ -259,17 +265,19 @@ func (h *CategoryHandlers) UpdateCategory(c echo.Context) error {
 	}
 
 	if err := h.categoryRepo.Update(ctx, category); err != nil {
+		if errors.Is(err, repositories.ErrPreconditionFailed) {
+			return modifiedError()
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update category")
 	}
+	caching.PublishEvent(ctx, h.cacheSvc, tenantID, caching.EventCategoryUpdated)
 
 	return c.JSON(http.StatusOK, category)
 }
 
 // SearchCategoriesRequest represents query parameters for searching categories
 type SearchCategoriesRequest struct {
-	Query  string `query:"q"`
-	Limit  int    `query:"limit"`
-	Offset int    `query:"offset"`
+	Query string `query:"q"`
 }
 
 // SearchCategories handles searching categories by name or description
@@ -288,35 +296,29 @@ func (h *CategoryHandlers) SearchCategories(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
 	}
-
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
-	if req.Limit > 100 {
-		req.Limit = 100 // Maximum limit
-	}
-	if req.Offset < 0 {
-		req.Offset = 0
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	// Search categories
-	categories, err := h.categoryRepo.Search(ctx, tenantID, req.Query, req.Limit, req.Offset)
+	categories, err := h.categoryRepo.Search(ctx, tenantID, req.Query, page.Limit, page.Offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to search categories")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"categories": categories,
-		"limit":      req.Limit,
-		"offset":     req.Offset,
-		"query":      req.Query,
+		"categories":  categories,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(categories)),
+		"query":       req.Query,
 	})
 }
 
@@ -343,7 +345,7 @@ func (h *CategoryHandlers) DeleteCategory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -354,9 +356,18 @@ func (h *CategoryHandlers) DeleteCategory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Category not found")
 	}
 
+	if err := h.dependencyService.CheckDeletable(ctx, tenantID, models.DependentCategory, categoryID); err != nil {
+		var conflict *services.DependencyConflictError
+		if errors.As(err, &conflict) {
+			return sendDependencyConflict(c, conflict)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check category dependencies")
+	}
+
 	if err := h.categoryRepo.Delete(ctx, tenantID, categoryID); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete category")
 	}
+	caching.PublishEvent(ctx, h.cacheSvc, tenantID, caching.EventCategoryUpdated)
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Category deleted successfully",
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ClassificationHandlers handles ABC/XYZ product classification
+type ClassificationHandlers struct {
+	classification *analytics.ProductClassificationService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewClassificationHandlers creates a new classification handlers instance
+func NewClassificationHandlers(classification *analytics.ProductClassificationService, rbacMiddleware *middleware.RBACMiddleware) *ClassificationHandlers {
+	return &ClassificationHandlers{
+		classification: classification,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *ClassificationHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// parseClassificationMonths reads the optional months query parameter
+func parseClassificationMonths(c echo.Context) (int, error) {
+	monthsStr := c.QueryParam("months")
+	if monthsStr == "" {
+		return analytics.DefaultClassificationMonths, nil
+	}
+	months, err := strconv.Atoi(monthsStr)
+	if err != nil || months < 3 || months > 36 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "months must be between 3 and 36")
+	}
+	return months, nil
+}
+
+// GetClassification handles GET /analytics/abc-xyz?months=12
+// It computes the classification without storing it
+func (h *ClassificationHandlers) GetClassification(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	months, err := parseClassificationMonths(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.classification.Classify(ctx, tenantID, months, false)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to classify products")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// RunClassification handles POST /analytics/abc-xyz/run?months=12
+// It classifies products now and stores the classes, as the nightly job does
+func (h *ClassificationHandlers) RunClassification(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	months, err := parseClassificationMonths(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.classification.Classify(ctx, tenantID, months, true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to classify products")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetReplenishmentPolicy handles GET /products/:id/replenishment-policy
+func (h *ClassificationHandlers) GetReplenishmentPolicy(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	policy, err := h.classification.GetProductPolicy(ctx, tenantID, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
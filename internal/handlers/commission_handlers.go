@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// CommissionHandlers handles commission agents and rules, monthly commission
+// runs and payee statements
+type CommissionHandlers struct {
+	commissionSvc  services.CommissionService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewCommissionHandlers creates a new commission handlers instance
+func NewCommissionHandlers(commissionSvc services.CommissionService, rbacMiddleware *middleware.RBACMiddleware) *CommissionHandlers {
+	return &CommissionHandlers{
+		commissionSvc:  commissionSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *CommissionHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// commissionError maps commission service errors to HTTP errors
+func commissionError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrCommissionAgentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Commission agent not found")
+	case errors.Is(err, services.ErrCommissionRuleNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Commission rule not found")
+	case errors.Is(err, services.ErrCommissionRunNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrCommissionCustomerNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	case errors.Is(err, services.ErrInvalidCommission):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrCommissionConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// ListAgents handles GET /commission-agents
+func (h *CommissionHandlers) ListAgents(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	agents, err := h.commissionSvc.ListAgents(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list commission agents")
+	}
+	if agents == nil {
+		agents = []*models.CommissionAgent{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"agents": agents})
+}
+
+// CreateAgent handles POST /commission-agents
+func (h *CommissionHandlers) CreateAgent(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.CommissionAgentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	agent, err := h.commissionSvc.CreateAgent(ctx, tenantID, &req)
+	if err != nil {
+		return commissionError(err, "Failed to create commission agent")
+	}
+
+	return c.JSON(http.StatusCreated, agent)
+}
+
+// UpdateAgent handles PUT /commission-agents/:id
+func (h *CommissionHandlers) UpdateAgent(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	agentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid commission agent ID")
+	}
+
+	var req models.CommissionAgentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	agent, err := h.commissionSvc.UpdateAgent(ctx, tenantID, agentID, &req)
+	if err != nil {
+		return commissionError(err, "Failed to update commission agent")
+	}
+
+	return c.JSON(http.StatusOK, agent)
+}
+
+// SetCustomerAgent handles PUT /customers/:id/commission-agent, assigning the
+// customer to the agent its sales earn commission for
+func (h *CommissionHandlers) SetCustomerAgent(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	var req models.CustomerAgentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.commissionSvc.SetCustomerAgent(ctx, tenantID, customerID, &req); err != nil {
+		return commissionError(err, "Failed to assign customer")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListRules handles GET /commission-rules
+func (h *CommissionHandlers) ListRules(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	rules, err := h.commissionSvc.ListRules(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list commission rules")
+	}
+	if rules == nil {
+		rules = []*models.CommissionRule{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"rules": rules})
+}
+
+// CreateRule handles POST /commission-rules
+func (h *CommissionHandlers) CreateRule(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.CommissionRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	rule, err := h.commissionSvc.CreateRule(ctx, tenantID, &req)
+	if err != nil {
+		return commissionError(err, "Failed to create commission rule")
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule handles PUT /commission-rules/:id
+func (h *CommissionHandlers) UpdateRule(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid commission rule ID")
+	}
+
+	var req models.CommissionRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	rule, err := h.commissionSvc.UpdateRule(ctx, tenantID, ruleID, &req)
+	if err != nil {
+		return commissionError(err, "Failed to update commission rule")
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// ListRuns handles GET /commission-runs
+func (h *CommissionHandlers) ListRuns(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	runs, err := h.commissionSvc.ListRuns(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list commission runs")
+	}
+	if runs == nil {
+		runs = []*models.CommissionRun{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"runs": runs})
+}
+
+// RunCommissions handles POST /commission-runs?month=2025-09
+// It calculates the month's commission, the previous month by default,
+// replacing the month's draft run
+func (h *CommissionHandlers) RunCommissions(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	var createdBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		createdBy = &userID
+	}
+
+	month := time.Now().AddDate(0, 0, -time.Now().Day())
+	if monthStr := c.QueryParam("month"); monthStr != "" {
+		parsed, err := time.Parse("2006-01", monthStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "month must be in YYYY-MM format")
+		}
+		month = parsed
+	}
+
+	run, err := h.commissionSvc.RunCommissions(ctx, tenantID, month, createdBy)
+	if err != nil {
+		return commissionError(err, "Failed to run commissions")
+	}
+
+	return c.JSON(http.StatusCreated, run)
+}
+
+// GetRun handles GET /commission-runs/:id?format=csv
+// The CSV has one row per payee for payroll
+func (h *CommissionHandlers) GetRun(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid commission run ID")
+	}
+
+	run, err := h.commissionSvc.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return commissionError(err, "Failed to get commission run")
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return c.JSON(http.StatusOK, run)
+	}
+	content, err := services.CommissionRunCSV(run)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export commission run")
+	}
+	return sendCSV(c, fmt.Sprintf("commissions_%s_%s.csv", run.Month.Format("200601"), run.Status), content)
+}
+
+// FinalizeRun handles POST /commission-runs/:id/finalize
+func (h *CommissionHandlers) FinalizeRun(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	var finalizedBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		finalizedBy = &userID
+	}
+
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid commission run ID")
+	}
+
+	run, err := h.commissionSvc.FinalizeRun(ctx, tenantID, runID, finalizedBy)
+	if err != nil {
+		return commissionError(err, "Failed to finalize commission run")
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+// GetStatement handles GET /commission-runs/:id/statements/:payee_type/:payee_id?format=csv
+// It lists the invoices, returns and collections behind a payee's commission
+func (h *CommissionHandlers) GetStatement(c echo.Context) error {
+	if err := h.requirePermission(c, "commissions:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid commission run ID")
+	}
+	payeeType := c.Param("payee_type")
+	if payeeType != models.CommissionPayeeUser && payeeType != models.CommissionPayeeAgent {
+		return echo.NewHTTPError(http.StatusBadRequest, "payee_type must be user or agent")
+	}
+	payeeID, err := uuid.Parse(c.Param("payee_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payee ID")
+	}
+
+	statement, err := h.commissionSvc.Statement(ctx, tenantID, runID, payeeType, payeeID)
+	if err != nil {
+		return commissionError(err, "Failed to get commission statement")
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return c.JSON(http.StatusOK, statement)
+	}
+	content, err := services.CommissionStatementCSV(statement)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export commission statement")
+	}
+	return sendCSV(c, fmt.Sprintf("commission_statement_%s_%s.csv", statement.Month.Format("200601"), payeeID), content)
+}
@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxLicenseDocumentSize caps uploaded license scans at 10MB
+const maxLicenseDocumentSize = 10 * 1024 * 1024
+
+// ComplianceHandlers handles license records, their documents and compliance status
+type ComplianceHandlers struct {
+	complianceService services.ComplianceService
+	rbacMiddleware    *middleware.RBACMiddleware
+}
+
+// NewComplianceHandlers creates a new compliance handlers instance
+func NewComplianceHandlers(complianceService services.ComplianceService, rbacMiddleware *middleware.RBACMiddleware) *ComplianceHandlers {
+	return &ComplianceHandlers{
+		complianceService: complianceService,
+		rbacMiddleware:    rbacMiddleware,
+	}
+}
+
+func (h *ComplianceHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// complianceError maps compliance service errors to HTTP errors
+func complianceError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrLicenseNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidLicense):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// CreateLicense handles POST /compliance/licenses
+func (h *ComplianceHandlers) CreateLicense(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req services.LicenseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	license, err := h.complianceService.CreateLicense(ctx, tenantID, currentUser(c), &req)
+	if err != nil {
+		return complianceError(err, "Failed to create license")
+	}
+
+	return c.JSON(http.StatusCreated, license)
+}
+
+// ListLicenses handles GET /compliance/licenses?holder_type=&holder_id=&license_type=&expiring_within_days=
+func (h *ComplianceHandlers) ListLicenses(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	filter := &models.ComplianceLicenseFilter{}
+	if holderType := c.QueryParam("holder_type"); holderType != "" {
+		filter.HolderType = &holderType
+	}
+	if holderIDStr := c.QueryParam("holder_id"); holderIDStr != "" {
+		holderID, err := uuid.Parse(holderIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid holder ID format")
+		}
+		filter.HolderID = &holderID
+	}
+	if licenseType := c.QueryParam("license_type"); licenseType != "" {
+		filter.LicenseType = &licenseType
+	}
+	if daysStr := c.QueryParam("expiring_within_days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 || days > 3650 {
+			return echo.NewHTTPError(http.StatusBadRequest, "expiring_within_days must be between 0 and 3650")
+		}
+		before := time.Now().UTC().AddDate(0, 0, days)
+		filter.ExpiringBefore = &before
+	}
+
+	licenses, err := h.complianceService.ListLicenses(ctx, tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve licenses")
+	}
+	if licenses == nil {
+		licenses = []*models.ComplianceLicense{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"licenses": licenses,
+	})
+}
+
+// GetLicense handles GET /compliance/licenses/:id
+func (h *ComplianceHandlers) GetLicense(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	licenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID format")
+	}
+
+	license, err := h.complianceService.GetLicense(ctx, tenantID, licenseID)
+	if err != nil {
+		return complianceError(err, "Failed to retrieve license")
+	}
+
+	return c.JSON(http.StatusOK, license)
+}
+
+// UpdateLicense handles PUT /compliance/licenses/:id
+func (h *ComplianceHandlers) UpdateLicense(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	licenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID format")
+	}
+
+	var req services.LicenseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	license, err := h.complianceService.UpdateLicense(ctx, tenantID, licenseID, &req)
+	if err != nil {
+		return complianceError(err, "Failed to update license")
+	}
+
+	return c.JSON(http.StatusOK, license)
+}
+
+// DeleteLicense handles DELETE /compliance/licenses/:id
+func (h *ComplianceHandlers) DeleteLicense(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	licenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID format")
+	}
+
+	if err := h.complianceService.DeleteLicense(ctx, tenantID, licenseID); err != nil {
+		return complianceError(err, "Failed to delete license")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UploadLicenseDocument handles POST /compliance/licenses/:id/document (multipart field "document")
+func (h *ComplianceHandlers) UploadLicenseDocument(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	licenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID format")
+	}
+
+	file, err := c.FormFile("document")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Document file is required")
+	}
+	if file.Size > maxLicenseDocumentSize {
+		return echo.NewHTTPError(http.StatusBadRequest, "File size exceeds maximum limit of 10MB")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open document file")
+	}
+	defer src.Close()
+
+	// Detect the content type from the first 512 bytes rather than trusting the client
+	buffer := make([]byte, 512)
+	n, err := src.Read(buffer)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file content")
+	}
+	contentType := http.DetectContentType(buffer[:n])
+	allowedTypes := map[string]bool{
+		"application/pdf": true,
+		"image/jpeg":      true,
+		"image/png":       true,
+	}
+	if !allowedTypes[contentType] {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file type. Only PDF, JPEG and PNG documents are allowed")
+	}
+	if _, err := src.Seek(0, 0); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file content")
+	}
+
+	license, err := h.complianceService.UploadDocument(ctx, tenantID, licenseID, file.Filename, contentType, src, file.Size)
+	if err != nil {
+		return complianceError(err, "Failed to upload license document")
+	}
+
+	return c.JSON(http.StatusCreated, license)
+}
+
+// GetLicenseDocumentURL handles GET /compliance/licenses/:id/document
+func (h *ComplianceHandlers) GetLicenseDocumentURL(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	licenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid license ID format")
+	}
+
+	url, err := h.complianceService.DocumentURL(ctx, tenantID, licenseID)
+	if err != nil {
+		return complianceError(err, "Failed to generate document URL")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"url": url,
+	})
+}
+
+// SetProductLicenseRequirement handles PUT /products/:id/license-requirement
+func (h *ComplianceHandlers) SetProductLicenseRequirement(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	var req struct {
+		LicenseType *string `json:"license_type"` // null lifts the restriction
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.complianceService.SetProductRequirement(ctx, tenantID, productID, req.LicenseType); err != nil {
+		return complianceError(err, "Failed to set product license requirement")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"product_id":            productID,
+		"required_license_type": req.LicenseType,
+	})
+}
+
+// GetComplianceStatus handles GET /compliance/status
+func (h *ComplianceHandlers) GetComplianceStatus(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	status, err := h.complianceService.Status(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build compliance status")
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// SetProductRegulatoryFlag handles PUT /products/:id/regulatory-flag
+func (h *ComplianceHandlers) SetProductRegulatoryFlag(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	var req struct {
+		RegulatoryFlag *string `json:"regulatory_flag"` // null clears the flag
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.complianceService.SetRegulatoryFlag(ctx, tenantID, productID, req.RegulatoryFlag); err != nil {
+		return complianceError(err, "Failed to set product regulatory flag")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"product_id":      productID,
+		"regulatory_flag": req.RegulatoryFlag,
+	})
+}
+
+// GetOrderBuyerLicense handles GET /orders/:id/buyer-license
+func (h *ComplianceHandlers) GetOrderBuyerLicense(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID format")
+	}
+
+	capture, err := h.complianceService.GetOrderBuyerLicense(ctx, tenantID, orderID)
+	if err != nil {
+		return complianceError(err, "Failed to retrieve order buyer license")
+	}
+
+	return c.JSON(http.StatusOK, capture)
+}
+
+// ListRestrictedSaleOverrides handles GET /reports/restricted-sale-overrides?from=&to=&limit=&offset=
+func (h *ComplianceHandlers) ListRestrictedSaleOverrides(c echo.Context) error {
+	if err := h.requirePermission(c, "compliance:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	filter := &models.RestrictedSaleOverrideFilter{}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	filter.Limit, filter.Offset = page.Limit, page.Offset
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+		}
+		filter.From = &t
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+		}
+		// Include the whole of the end day
+		t = t.Add(24*time.Hour - time.Nanosecond)
+		filter.To = &t
+	}
+
+	overrides, err := h.complianceService.ListOverrides(ctx, tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve restricted sale overrides")
+	}
+	if overrides == nil {
+		overrides = []*models.RestrictedSaleOverride{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"overrides":   overrides,
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+		"next_cursor": page.NextCursor(len(overrides)),
+	})
+}
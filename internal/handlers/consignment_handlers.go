@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ConsignmentHandlers handles supplier consignment stock and settlements
+type ConsignmentHandlers struct {
+	consignmentService services.ConsignmentService
+	rbacMiddleware     *middleware.RBACMiddleware
+}
+
+// NewConsignmentHandlers creates a new consignment handlers instance
+func NewConsignmentHandlers(consignmentService services.ConsignmentService, rbacMiddleware *middleware.RBACMiddleware) *ConsignmentHandlers {
+	return &ConsignmentHandlers{
+		consignmentService: consignmentService,
+		rbacMiddleware:     rbacMiddleware,
+	}
+}
+
+func (h *ConsignmentHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// parseSupplierFilter reads the optional supplier_id query parameter
+func parseSupplierFilter(c echo.Context) (*uuid.UUID, error) {
+	supplierIDStr := c.QueryParam("supplier_id")
+	if supplierIDStr == "" {
+		return nil, nil
+	}
+	supplierID, err := uuid.Parse(supplierIDStr)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid supplier ID format")
+	}
+	return &supplierID, nil
+}
+
+// ListStock handles GET /consignment/stock?supplier_id=
+func (h *ConsignmentHandlers) ListStock(c echo.Context) error {
+	if err := h.requirePermission(c, "consignment:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	supplierID, err := parseSupplierFilter(c)
+	if err != nil {
+		return err
+	}
+
+	stock, err := h.consignmentService.ListStock(ctx, tenantID, supplierID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve consignment stock")
+	}
+	if stock == nil {
+		stock = []*models.ConsignmentStock{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"stock": stock,
+	})
+}
+
+// GetSettlementReport handles GET /reports/consignment-settlement?supplier_id=&from=YYYY-MM-DD&to=YYYY-MM-DD
+// The window defaults to the current month; to is inclusive
+func (h *ConsignmentHandlers) GetSettlementReport(c echo.Context) error {
+	if err := h.requirePermission(c, "consignment:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	supplierID, err := parseSupplierFilter(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now.Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+	}
+	if toStr := c.QueryParam("to"); toStr != "" {
+		day, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	report, err := h.consignmentService.SettlementReport(ctx, tenantID, supplierID, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSettlement) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build consignment settlement report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+type settleConsignmentRequest struct {
+	SupplierID uuid.UUID  `json:"supplier_id"`
+	UpTo       *time.Time `json:"up_to"`
+	Reference  string     `json:"reference"`
+}
+
+// Settle handles POST /consignment/settlements
+func (h *ConsignmentHandlers) Settle(c echo.Context) error {
+	if err := h.requirePermission(c, "consignment:settle"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req settleConsignmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if req.SupplierID == uuid.Nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "supplier_id is required")
+	}
+
+	settlement, err := h.consignmentService.Settle(ctx, tenantID, req.SupplierID, req.UpTo, req.Reference)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSupplierNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Supplier not found")
+		case errors.Is(err, services.ErrInvalidSettlement):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to settle consignment liabilities")
+	}
+
+	return c.JSON(http.StatusOK, settlement)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ConsolidatedInvoiceHandlers handles invoices covering several delivered
+// orders of one customer and the links from orders to their invoices
+type ConsolidatedInvoiceHandlers struct {
+	consolidatedSvc services.ConsolidatedInvoiceService
+	rbacMiddleware  *middleware.RBACMiddleware
+}
+
+// NewConsolidatedInvoiceHandlers creates a new consolidated invoice handlers instance
+func NewConsolidatedInvoiceHandlers(consolidatedSvc services.ConsolidatedInvoiceService, rbacMiddleware *middleware.RBACMiddleware) *ConsolidatedInvoiceHandlers {
+	return &ConsolidatedInvoiceHandlers{
+		consolidatedSvc: consolidatedSvc,
+		rbacMiddleware:  rbacMiddleware,
+	}
+}
+
+func (h *ConsolidatedInvoiceHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// consolidatedInvoiceError maps consolidated invoice service errors to HTTP errors
+func consolidatedInvoiceError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrConsolidatedInvoiceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Consolidated invoice not found")
+	case errors.Is(err, services.ErrInvalidConsolidatedInvoice):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrConsolidatedInvoiceConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// CreateConsolidatedInvoice handles POST /invoices/consolidated
+func (h *ConsolidatedInvoiceHandlers) CreateConsolidatedInvoice(c echo.Context) error {
+	if err := h.requirePermission(c, "invoices:consolidate"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req models.ConsolidatedInvoiceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	invoice, err := h.consolidatedSvc.CreateConsolidatedInvoice(ctx, tenantID, &req)
+	if err != nil {
+		return consolidatedInvoiceError(err, "Failed to create consolidated invoice")
+	}
+
+	return c.JSON(http.StatusCreated, invoice)
+}
+
+// GetConsolidatedInvoice handles GET /invoices/:id/consolidated
+func (h *ConsolidatedInvoiceHandlers) GetConsolidatedInvoice(c echo.Context) error {
+	if err := h.requirePermission(c, "invoices:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID format")
+	}
+
+	invoice, err := h.consolidatedSvc.GetConsolidatedInvoice(ctx, tenantID, invoiceID)
+	if err != nil {
+		return consolidatedInvoiceError(err, "Failed to retrieve consolidated invoice")
+	}
+
+	return c.JSON(http.StatusOK, invoice)
+}
+
+// GetOrderInvoices handles GET /orders/:id/invoices
+func (h *ConsolidatedInvoiceHandlers) GetOrderInvoices(c echo.Context) error {
+	if err := h.requirePermission(c, "invoices:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID format")
+	}
+
+	invoices, err := h.consolidatedSvc.OrderInvoices(ctx, tenantID, orderID)
+	if err != nil {
+		return consolidatedInvoiceError(err, "Failed to retrieve order invoices")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"invoices": invoices,
+	})
+}
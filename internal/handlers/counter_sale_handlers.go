@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// CounterSaleHandlers handles the point-of-sale flow: counter sales, cash
+// registers and the day book
+type CounterSaleHandlers struct {
+	counterSaleService services.CounterSaleService
+	rbacMiddleware     *middleware.RBACMiddleware
+}
+
+// NewCounterSaleHandlers creates a new counter sale handlers instance
+func NewCounterSaleHandlers(counterSaleService services.CounterSaleService, rbacMiddleware *middleware.RBACMiddleware) *CounterSaleHandlers {
+	return &CounterSaleHandlers{
+		counterSaleService: counterSaleService,
+		rbacMiddleware:     rbacMiddleware,
+	}
+}
+
+func (h *CounterSaleHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// counterSaleError maps counter sale service errors to HTTP errors
+func counterSaleError(c echo.Context, err error, fallback string) error {
+	if marginErr, ok := err.(*services.MarginViolationError); ok {
+		return sendMarginViolation(c, marginErr)
+	}
+	if blockedErr, ok := err.(*services.ComplianceBlockedError); ok {
+		return sendComplianceBlocked(c, blockedErr)
+	}
+	if blockedErr, ok := err.(*services.RestrictedSaleBlockedError); ok {
+		return sendRestrictedSaleBlocked(c, blockedErr)
+	}
+	switch {
+	case errors.Is(err, services.ErrRegisterNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Cash register not found")
+	case errors.Is(err, services.ErrRegisterNotOpen), errors.Is(err, services.ErrRegisterAlreadyOpen):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidCounterSale), errors.Is(err, services.ErrInvalidLicense):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback+": "+err.Error())
+}
+
+// CreateCounterSale handles POST /pos/sales, creating the order, its invoice
+// and the payment in one call
+func (h *CounterSaleHandlers) CreateCounterSale(c echo.Context) error {
+	if err := h.requirePermission(c, "pos:sell"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.CounterSaleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var userID *uuid.UUID
+	if id, ok := rc.User(); ok {
+		userID = &id
+	}
+
+	receipt, err := h.counterSaleService.Sell(ctx, tenantID, userID, &req)
+	if err != nil {
+		return counterSaleError(c, err, "Failed to complete counter sale")
+	}
+
+	return c.JSON(http.StatusCreated, receipt)
+}
+
+// OpenRegister handles POST /pos/registers
+func (h *CounterSaleHandlers) OpenRegister(c echo.Context) error {
+	if err := h.requirePermission(c, "pos:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.OpenRegisterRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var userID *uuid.UUID
+	if id, ok := rc.User(); ok {
+		userID = &id
+	}
+
+	register, err := h.counterSaleService.OpenRegister(ctx, tenantID, userID, &req)
+	if err != nil {
+		return counterSaleError(c, err, "Failed to open cash register")
+	}
+
+	return c.JSON(http.StatusCreated, register)
+}
+
+// CloseRegister handles POST /pos/registers/:id/close
+func (h *CounterSaleHandlers) CloseRegister(c echo.Context) error {
+	if err := h.requirePermission(c, "pos:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	registerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid register ID format")
+	}
+
+	var req models.CloseRegisterRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var userID *uuid.UUID
+	if id, ok := rc.User(); ok {
+		userID = &id
+	}
+
+	register, err := h.counterSaleService.CloseRegister(ctx, tenantID, registerID, userID, &req)
+	if err != nil {
+		return counterSaleError(c, err, "Failed to close cash register")
+	}
+
+	return c.JSON(http.StatusOK, register)
+}
+
+// GetRegister handles GET /pos/registers/:id
+func (h *CounterSaleHandlers) GetRegister(c echo.Context) error {
+	if err := h.requirePermission(c, "pos:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	registerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid register ID format")
+	}
+
+	register, err := h.counterSaleService.GetRegister(ctx, tenantID, registerID)
+	if err != nil {
+		return counterSaleError(c, err, "Failed to retrieve cash register")
+	}
+
+	return c.JSON(http.StatusOK, register)
+}
+
+// ListRegisters handles GET /pos/registers?warehouse_id=&status=
+func (h *CounterSaleHandlers) ListRegisters(c echo.Context) error {
+	if err := h.requirePermission(c, "pos:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	filter := &models.CashRegisterFilter{}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	filter.Limit, filter.Offset = page.Limit, page.Offset
+	if status := c.QueryParam("status"); status != "" {
+		filter.Status = &status
+	}
+	if warehouseIDStr := c.QueryParam("warehouse_id"); warehouseIDStr != "" {
+		warehouseID, err := uuid.Parse(warehouseIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+		}
+		filter.WarehouseID = &warehouseID
+	}
+
+	registers, err := h.counterSaleService.ListRegisters(ctx, tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve cash registers")
+	}
+	if registers == nil {
+		registers = []*models.CashRegister{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"registers":   registers,
+		"next_cursor": page.NextCursor(len(registers)),
+	})
+}
+
+// GetDayBook handles GET /pos/day-book?date=YYYY-MM-DD&warehouse_id=, listing
+// every counter transaction of the day (today by default) per warehouse
+func (h *CounterSaleHandlers) GetDayBook(c echo.Context) error {
+	if err := h.requirePermission(c, "pos:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	day := time.Now()
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+		}
+		day = parsed
+	}
+
+	var warehouseID *uuid.UUID
+	if warehouseIDStr := c.QueryParam("warehouse_id"); warehouseIDStr != "" {
+		id, err := uuid.Parse(warehouseIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+		}
+		warehouseID = &id
+	}
+
+	book, err := h.counterSaleService.DayBook(ctx, tenantID, day, warehouseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build day book")
+	}
+
+	return c.JSON(http.StatusOK, book)
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// DataExportHandlers handles data warehouse export destinations and their runs
+type DataExportHandlers struct {
+	exportService  *jobs.DataExportService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewDataExportHandlers creates a new data export handlers instance
+func NewDataExportHandlers(exportService *jobs.DataExportService, rbacMiddleware *middleware.RBACMiddleware) *DataExportHandlers {
+	return &DataExportHandlers{
+		exportService:  exportService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+// dataExportDestinationRequest creates or updates a destination. Credentials
+// are write-only and left unchanged on update when omitted; Consent must be
+// given to create a destination
+type dataExportDestinationRequest struct {
+	Name                string                    `json:"name"`
+	Provider            string                    `json:"provider"`
+	Settings            models.DataExportSettings `json:"settings"`
+	Credentials         *string                   `json:"credentials"`
+	Facts               []string                  `json:"facts"`
+	SyncIntervalMinutes *int                      `json:"sync_interval_minutes"`
+	IsActive            *bool                     `json:"is_active"`
+	Consent             bool                      `json:"consent"`
+}
+
+func (h *DataExportHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// destinationFromPath loads the tenant's destination named by the :id parameter
+func (h *DataExportHandlers) destinationFromPath(c echo.Context, tenantID uuid.UUID) (*models.DataExportDestination, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid destination ID format")
+	}
+	destination, err := h.exportService.GetDestination(c.Request().Context(), tenantID, id)
+	if errors.Is(err, jobs.ErrDataExportDestinationNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Data export destination not found")
+	}
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve data export destination")
+	}
+	return destination, nil
+}
+
+// ListDestinations handles GET /data-exports
+func (h *DataExportHandlers) ListDestinations(c echo.Context) error {
+	if err := h.requirePermission(c, "data_exports:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	destinations, err := h.exportService.ListDestinations(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve data export destinations")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"destinations": destinations,
+	})
+}
+
+// CreateDestination handles POST /data-exports
+func (h *DataExportHandlers) CreateDestination(c echo.Context) error {
+	if err := h.requirePermission(c, "data_exports:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req dataExportDestinationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	destination := &models.DataExportDestination{
+		TenantID: tenantID,
+		Name:     req.Name,
+		Provider: req.Provider,
+		Settings: req.Settings,
+		Facts:    req.Facts,
+	}
+	if req.Credentials != nil {
+		destination.Credentials = *req.Credentials
+	}
+	if req.SyncIntervalMinutes != nil {
+		destination.SyncIntervalMinutes = *req.SyncIntervalMinutes
+	}
+
+	var consentedBy *uuid.UUID
+	if userID, ok := rc.User(); ok {
+		consentedBy = &userID
+	}
+
+	if err := h.exportService.CreateDestination(ctx, destination, req.Consent, consentedBy); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, destination)
+}
+
+// UpdateDestination handles PUT /data-exports/:id
+func (h *DataExportHandlers) UpdateDestination(c echo.Context) error {
+	if err := h.requirePermission(c, "data_exports:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	destination, err := h.destinationFromPath(c, tenantID)
+	if err != nil {
+		return err
+	}
+
+	var req dataExportDestinationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	// The provider is fixed at creation, like the consent given for it
+	destination.Name = req.Name
+	destination.Settings = req.Settings
+	destination.Facts = req.Facts
+	if req.Credentials != nil {
+		destination.Credentials = *req.Credentials
+	}
+	if req.SyncIntervalMinutes != nil {
+		destination.SyncIntervalMinutes = *req.SyncIntervalMinutes
+	}
+	if req.IsActive != nil {
+		destination.IsActive = *req.IsActive
+	}
+
+	if err := h.exportService.UpdateDestination(ctx, destination); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, destination)
+}
+
+// DeleteDestination handles DELETE /data-exports/:id, withdrawing consent
+func (h *DataExportHandlers) DeleteDestination(c echo.Context) error {
+	if err := h.requirePermission(c, "data_exports:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid destination ID format")
+	}
+
+	err = h.exportService.DeleteDestination(ctx, tenantID, id)
+	if errors.Is(err, jobs.ErrDataExportDestinationNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Data export destination not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete data export destination")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SyncDestination handles POST /data-exports/:id/sync
+func (h *DataExportHandlers) SyncDestination(c echo.Context) error {
+	if err := h.requirePermission(c, "data_exports:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	destination, err := h.destinationFromPath(c, tenantID)
+	if err != nil {
+		return err
+	}
+
+	run, err := h.exportService.SyncNow(ctx, destination)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to run data export")
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+// ResetDestination handles POST /data-exports/:id/reset so the next run
+// pushes every row again
+func (h *DataExportHandlers) ResetDestination(c echo.Context) error {
+	if err := h.requirePermission(c, "data_exports:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	destination, err := h.destinationFromPath(c, tenantID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.exportService.ResetCursors(ctx, destination); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to reset data export")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListRuns handles GET /data-exports/:id/runs
+func (h *DataExportHandlers) ListRuns(c echo.Context) error {
+	if err := h.requirePermission(c, "data_exports:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	destination, err := h.destinationFromPath(c, tenantID)
+	if err != nil {
+		return err
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 20})
+	if err != nil {
+		return err
+	}
+
+	runs, err := h.exportService.ListRuns(ctx, tenantID, destination.ID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve data export runs")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"runs":        runs,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(runs)),
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/middleware"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// DataResidencyHandlers pins tenants to the region their files must be stored in
+type DataResidencyHandlers struct {
+	residencySvc   services.DataResidencyService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewDataResidencyHandlers creates a new data residency handlers instance
+func NewDataResidencyHandlers(residencySvc services.DataResidencyService, rbacMiddleware *middleware.RBACMiddleware) *DataResidencyHandlers {
+	return &DataResidencyHandlers{
+		residencySvc:   residencySvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *DataResidencyHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ListRegions handles GET /admin/data-residency/regions (platform admin only)
+func (h *DataResidencyHandlers) ListRegions(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_data_residency"); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"regions": h.residencySvc.Regions()})
+}
+
+// SetTenantResidency handles PUT /admin/tenants/:tenant_id/data-residency,
+// pinning the tenant to a region or, with an empty region, back to the
+// default store (platform admin only). Files already stored are not moved
+func (h *DataResidencyHandlers) SetTenantResidency(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_data_residency"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+
+	var req struct {
+		Region string `json:"region"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.residencySvc.SetRegion(ctx, tenantID, req.Region); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownResidencyRegion):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrResidencyTenantNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Tenant not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set data residency")
+	}
+
+	region, err := h.residencySvc.RegionFor(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load data residency")
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tenant_id":      tenantID,
+		"data_residency": region,
+	})
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// DeviceHandlers handles push notification device registration. Devices are
+// scoped to the signed-in user, so no extra permission is required.
+type DeviceHandlers struct {
+	pushService services.PushService
+}
+
+// NewDeviceHandlers creates a new device handlers instance
+func NewDeviceHandlers(pushService services.PushService) *DeviceHandlers {
+	return &DeviceHandlers{pushService: pushService}
+}
+
+func deviceOwnerFromContext(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "User ID not found")
+	}
+	return tenantID, userID, nil
+}
+
+// RegisterDevice handles POST /devices; registering an existing token refreshes it
+func (h *DeviceHandlers) RegisterDevice(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Token      string   `json:"token"`
+		Platform   string   `json:"platform"`
+		Topics     []string `json:"topics"`
+		AppVersion *string  `json:"app_version"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	device := &models.DeviceToken{
+		Token:      req.Token,
+		Platform:   req.Platform,
+		Topics:     req.Topics,
+		AppVersion: req.AppVersion,
+	}
+	if err := h.pushService.RegisterDevice(c.Request().Context(), tenantID, userID, device); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, device)
+}
+
+// ListDevices handles GET /devices
+func (h *DeviceHandlers) ListDevices(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	devices, err := h.pushService.ListDevices(c.Request().Context(), tenantID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve devices")
+	}
+	if devices == nil {
+		devices = []*models.DeviceToken{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"devices": devices,
+	})
+}
+
+// UnregisterDevice handles DELETE /devices/:id
+func (h *DeviceHandlers) UnregisterDevice(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device ID format")
+	}
+
+	if err := h.pushService.UnregisterDevice(c.Request().Context(), tenantID, userID, deviceID); err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Device not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unregister device")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UnregisterToken handles POST /devices/unregister for apps that only know their token (e.g. on logout)
+func (h *DeviceHandlers) UnregisterToken(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.Bind(&req); err != nil || req.Token == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Token is required")
+	}
+
+	if err := h.pushService.UnregisterToken(c.Request().Context(), tenantID, userID, req.Token); err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Device not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unregister device")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
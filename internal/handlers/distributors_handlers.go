@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
+	"agromart2/internal/repositories"
 	"agromart2/internal/services"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -24,12 +28,6 @@ func NewDistributorHandlers(distributorService services.DistributorService, rbac
 	}
 }
 
-// ListDistributorsRequest represents query parameters for listing distributors
-type ListDistributorsRequest struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
-}
-
 // ListDistributors handles getting a list of distributors with tenant filtering
 func (h *DistributorHandlers) ListDistributors(c echo.Context) error {
 	// Use RBAC middleware directly
@@ -42,38 +40,61 @@ func (h *DistributorHandlers) ListDistributors(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	var req ListDistributorsRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.DistributorSortColumns, DefaultSort: "-created_at"})
+	if err != nil {
+		return err
 	}
 
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
+	// Get tenant ID from context
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
-	if req.Limit > 100 {
-		req.Limit = 100 // Maximum limit
+
+	// Get distributors from the tenant
+	distributors, err := h.distributorService.List(ctx, tenantID, page)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list distributors")
 	}
-	if req.Offset < 0 {
-		req.Offset = 0
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"distributors": distributors,
+		"limit":        page.Limit,
+		"offset":       page.Offset,
+		"next_cursor":  page.NextCursor(len(distributors)),
+	})
+}
+
+// LookupDistributors handles GET /distributors/lookup?value=, an exact match
+// on contact email, phone, GSTIN or bank account number; those are
+// encrypted, so partial matches are not possible
+func (h *DistributorHandlers) LookupDistributors(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("distributors:list")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
 	}
 
-	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	ctx := c.Request().Context()
+
+	value := strings.TrimSpace(c.QueryParam("value"))
+	if value == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "value is required")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	// Get distributors from the tenant
-	distributors, err := h.distributorService.List(ctx, tenantID, req.Limit, req.Offset)
+	distributors, err := h.distributorService.FindByContact(ctx, tenantID, value)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list distributors")
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to look up distributors")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"distributors": distributors,
-		"limit":        req.Limit,
-		"offset":       req.Offset,
 	})
 }
 
@@ -84,6 +105,13 @@ type CreateDistributorRequest struct {
 	ContactPhone   *string `json:"contact_phone"`
 	Address        *string `json:"address"`
 	LicenseNumber  *string `json:"license_number"`
+	GSTIN          *string `json:"gstin"`
+	BankAccountNumber *string `json:"bank_account_number"`
+	BankIFSC          *string `json:"bank_ifsc"`
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
+	GeofenceRadiusM *int    `json:"geofence_radius_m"`
+	PreferredLanguage *string `json:"preferred_language"`
 }
 
 // CreateDistributor handles creating a new distributor
@@ -107,9 +135,15 @@ func (h *DistributorHandlers) CreateDistributor(c echo.Context) error {
 	if req.Name == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
 	}
+	if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+		return err
+	}
+	if err := validateGeofenceRadius(req.GeofenceRadiusM); err != nil {
+		return err
+	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -121,10 +155,19 @@ func (h *DistributorHandlers) CreateDistributor(c echo.Context) error {
 		ContactPhone:  req.ContactPhone,
 		Address:       req.Address,
 		LicenseNumber: req.LicenseNumber,
+		GSTIN:         req.GSTIN,
+		BankAccountNumber: req.BankAccountNumber,
+		BankIFSC:          req.BankIFSC,
+		Latitude:      req.Latitude,
+		Longitude:     req.Longitude,
+		PreferredLanguage: req.PreferredLanguage,
+	}
+	if req.GeofenceRadiusM != nil {
+		distributor.GeofenceRadiusM = *req.GeofenceRadiusM
 	}
 
 	if err := h.distributorService.Create(ctx, tenantID, distributor); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return masterDataError(err, err.Error())
 	}
 
 	return c.JSON(http.StatusCreated, distributor)
@@ -153,7 +196,7 @@ func (h *DistributorHandlers) GetDistributor(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -164,7 +207,7 @@ func (h *DistributorHandlers) GetDistributor(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Distributor not found")
 	}
 
-	return c.JSON(http.StatusOK, distributor)
+	return jsonWithETag(c, resourceETag(distributor.ID, distributor.UpdatedAt), distributor)
 }
 
 // UpdateDistributorRequest represents the distributor update request payload
@@ -174,6 +217,13 @@ type UpdateDistributorRequest struct {
 	ContactPhone  *string `json:"contact_phone"`
 	Address       *string `json:"address"`
 	LicenseNumber *string `json:"license_number"`
+	GSTIN         *string `json:"gstin"`
+	BankAccountNumber *string `json:"bank_account_number"`
+	BankIFSC          *string `json:"bank_ifsc"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	GeofenceRadiusM *int   `json:"geofence_radius_m"`
+	PreferredLanguage *string `json:"preferred_language"`
 }
 
 // UpdateDistributor handles updating distributor details
@@ -204,7 +254,7 @@ func (h *DistributorHandlers) UpdateDistributor(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -214,6 +264,9 @@ func (h *DistributorHandlers) UpdateDistributor(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Distributor not found")
 	}
+	if ctx, err = checkIfMatch(ctx, c, resourceETag(distributor.ID, distributor.UpdatedAt), distributor.ID, distributor.UpdatedAt); err != nil {
+		return err
+	}
 
 	// Update fields if provided
 	if req.Name != nil {
@@ -231,9 +284,34 @@ func (h *DistributorHandlers) UpdateDistributor(c echo.Context) error {
 	if req.LicenseNumber != nil {
 		distributor.LicenseNumber = req.LicenseNumber
 	}
+	if req.GSTIN != nil {
+		distributor.GSTIN = req.GSTIN
+	}
+	if req.BankAccountNumber != nil {
+		distributor.BankAccountNumber = req.BankAccountNumber
+	}
+	if req.BankIFSC != nil {
+		distributor.BankIFSC = req.BankIFSC
+	}
+	if req.PreferredLanguage != nil {
+		distributor.PreferredLanguage = req.PreferredLanguage
+	}
+	if req.Latitude != nil || req.Longitude != nil {
+		if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+			return err
+		}
+		distributor.Latitude = req.Latitude
+		distributor.Longitude = req.Longitude
+	}
+	if req.GeofenceRadiusM != nil {
+		if err := validateGeofenceRadius(req.GeofenceRadiusM); err != nil {
+			return err
+		}
+		distributor.GeofenceRadiusM = *req.GeofenceRadiusM
+	}
 
 	if err := h.distributorService.Update(ctx, tenantID, distributor); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return masterDataError(err, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, distributor)
@@ -262,7 +340,7 @@ func (h *DistributorHandlers) DeleteDistributor(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -273,11 +351,22 @@ func (h *DistributorHandlers) DeleteDistributor(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Distributor not found")
 	}
 
-	if err := h.distributorService.Delete(ctx, tenantID, distributorID); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete distributor")
+	archived, err := h.distributorService.Delete(ctx, tenantID, distributorID)
+	if err != nil {
+		var conflict *services.DependencyConflictError
+		if errors.As(err, &conflict) {
+			return sendDependencyConflict(c, conflict)
+		}
+		return masterDataError(err, "Failed to delete distributor")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Distributor deleted successfully",
-	})
-}
\ No newline at end of file
+	return deletedResponse(c, "Distributor", archived)
+}
+
+// validateGeofenceRadius keeps check-in radii between a building and a small village
+func validateGeofenceRadius(radius *int) error {
+	if radius != nil && (*radius < 25 || *radius > 5000) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Geofence radius must be between 25 and 5000 meters")
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// DunningHandlers handles payment reminder cadences, reminder templates and
+// invoice reminder history
+type DunningHandlers struct {
+	dunningSvc     services.DunningService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewDunningHandlers creates a new dunning handlers instance
+func NewDunningHandlers(dunningSvc services.DunningService, rbacMiddleware *middleware.RBACMiddleware) *DunningHandlers {
+	return &DunningHandlers{
+		dunningSvc:     dunningSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *DunningHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetSchedule handles GET /dunning/schedule
+func (h *DunningHandlers) GetSchedule(c echo.Context) error {
+	if err := h.requirePermission(c, "dunning:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	schedule, err := h.dunningSvc.GetSchedule(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load reminder schedule")
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateSchedule handles PUT /dunning/schedule
+func (h *DunningHandlers) UpdateSchedule(c echo.Context) error {
+	if err := h.requirePermission(c, "dunning:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	schedule := &models.DunningSchedule{Enabled: true}
+	if err := c.Bind(schedule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.dunningSvc.UpdateSchedule(ctx, tenantID, schedule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// ListTemplates handles GET /dunning/templates
+func (h *DunningHandlers) ListTemplates(c echo.Context) error {
+	if err := h.requirePermission(c, "dunning:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	templates, err := h.dunningSvc.ListTemplates(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list reminder templates")
+	}
+	if templates == nil {
+		templates = []*models.DunningTemplate{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"templates": templates})
+}
+
+// SaveTemplate handles PUT /dunning/templates, creating or replacing the
+// email or SMS wording for one language
+func (h *DunningHandlers) SaveTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "dunning:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var template models.DunningTemplate
+	if err := c.Bind(&template); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.dunningSvc.SaveTemplate(ctx, tenantID, &template); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /dunning/templates/:id
+func (h *DunningHandlers) DeleteTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "dunning:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID format")
+	}
+
+	if err := h.dunningSvc.DeleteTemplate(ctx, tenantID, id); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Template not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RunReminders handles POST /dunning/run, sending reminders that are due now
+// instead of waiting for the daily job
+func (h *DunningHandlers) RunReminders(c echo.Context) error {
+	if err := h.requirePermission(c, "dunning:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	sent, err := h.dunningSvc.RunReminders(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"sent": sent})
+}
+
+// ListInvoiceReminders handles GET /invoices/:id/reminders, newest first
+func (h *DunningHandlers) ListInvoiceReminders(c echo.Context) error {
+	if err := h.requirePermission(c, "dunning:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
+	}
+
+	reminders, err := h.dunningSvc.ListInvoiceReminders(ctx, tenantID, invoiceID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list invoice reminders")
+	}
+	if reminders == nil {
+		reminders = []*models.InvoiceReminder{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"reminders": reminders})
+}
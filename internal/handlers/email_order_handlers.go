@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxInboundEmailPayload caps inbound email requests, attachments included
+	maxInboundEmailPayload = 10 << 20
+	// maxEmailAttachment caps each kept attachment; order sheets are small
+	maxEmailAttachment = 256 << 10
+)
+
+// EmailOrderHandlers handles the order mailbox, its known dealer senders and
+// the review queue of draft orders parsed from email
+type EmailOrderHandlers struct {
+	emailOrderSvc  services.EmailOrderService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewEmailOrderHandlers creates a new email order handlers instance
+func NewEmailOrderHandlers(emailOrderSvc services.EmailOrderService, rbacMiddleware *middleware.RBACMiddleware) *EmailOrderHandlers {
+	return &EmailOrderHandlers{
+		emailOrderSvc:  emailOrderSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+type emailOrderSenderRequest struct {
+	Address       string `json:"address"`
+	DistributorID string `json:"distributor_id"`
+	WarehouseID   string `json:"warehouse_id"`
+}
+
+// inboundEmailRequest is the JSON form of an inbound email; attachment
+// content is base64 encoded
+type inboundEmailRequest struct {
+	From        string `json:"from"`
+	Subject     string `json:"subject"`
+	Text        string `json:"text"`
+	MessageID   string `json:"message_id"`
+	Attachments []struct {
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+		Content     string `json:"content"`
+	} `json:"attachments"`
+}
+
+func (h *EmailOrderHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// emailOrderError maps email order service errors to HTTP errors
+func emailOrderError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrEmailOrdersNotConfigured):
+		return echo.NewHTTPError(http.StatusNotFound, "Email ordering is not configured")
+	case errors.Is(err, services.ErrEmailOrderNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Email order not found")
+	case errors.Is(err, services.ErrInvalidEmailOrder):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrEmailOrderConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+func emailOrderSettingsResponse(settings *models.EmailOrderSettings) map[string]interface{} {
+	return map[string]interface{}{
+		"settings":    settings,
+		"inbound_url": "/v1/inbound-email/" + settings.InboundToken,
+	}
+}
+
+// GetEmailOrderSettings handles GET /email-orders/settings
+func (h *EmailOrderHandlers) GetEmailOrderSettings(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	settings, err := h.emailOrderSvc.GetSettings(ctx, tenantID)
+	if err != nil {
+		return emailOrderError(err, "Failed to retrieve email order settings")
+	}
+	return c.JSON(http.StatusOK, emailOrderSettingsResponse(settings))
+}
+
+// SaveEmailOrderSettings handles PUT /email-orders/settings
+func (h *EmailOrderHandlers) SaveEmailOrderSettings(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.EmailOrderSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	settings, err := h.emailOrderSvc.SaveSettings(ctx, tenantID, &req)
+	if err != nil {
+		return emailOrderError(err, "Failed to save email order settings")
+	}
+	return c.JSON(http.StatusOK, emailOrderSettingsResponse(settings))
+}
+
+// RotateEmailOrderToken handles POST /email-orders/settings/rotate-token.
+// The old inbound address stops working immediately
+func (h *EmailOrderHandlers) RotateEmailOrderToken(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	settings, err := h.emailOrderSvc.RotateToken(ctx, tenantID)
+	if err != nil {
+		return emailOrderError(err, "Failed to rotate inbound email token")
+	}
+	return c.JSON(http.StatusOK, emailOrderSettingsResponse(settings))
+}
+
+// ListEmailOrderSenders handles GET /email-orders/senders
+func (h *EmailOrderHandlers) ListEmailOrderSenders(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	senders, err := h.emailOrderSvc.ListSenders(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list email order senders")
+	}
+	if senders == nil {
+		senders = []*models.EmailOrderSender{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"senders": senders})
+}
+
+// AddEmailOrderSender handles POST /email-orders/senders
+func (h *EmailOrderHandlers) AddEmailOrderSender(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req emailOrderSenderRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	distributorID, err := uuid.Parse(req.DistributorID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid distributor ID format")
+	}
+
+	sender := &models.EmailOrderSender{TenantID: tenantID, Address: req.Address, DistributorID: distributorID}
+	if req.WarehouseID != "" {
+		warehouseID, err := uuid.Parse(req.WarehouseID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+		}
+		sender.WarehouseID = &warehouseID
+	}
+
+	if err := h.emailOrderSvc.AddSender(ctx, sender); err != nil {
+		return emailOrderError(err, "Failed to add email order sender")
+	}
+	return c.JSON(http.StatusCreated, sender)
+}
+
+// RemoveEmailOrderSender handles DELETE /email-orders/senders/:id
+func (h *EmailOrderHandlers) RemoveEmailOrderSender(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid sender ID format")
+	}
+
+	if err := h.emailOrderSvc.RemoveSender(ctx, tenantID, id); err != nil {
+		return emailOrderError(err, "Failed to remove email order sender")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListInboundEmails handles GET /email-orders/emails
+func (h *EmailOrderHandlers) ListInboundEmails(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	emails, err := h.emailOrderSvc.ListEmails(ctx, tenantID, c.QueryParam("status"), page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list inbound emails")
+	}
+	if emails == nil {
+		emails = []*models.InboundEmail{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"emails":      emails,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(emails)),
+	})
+}
+
+// ListEmailDraftOrders handles GET /email-orders/drafts
+func (h *EmailOrderHandlers) ListEmailDraftOrders(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	drafts, err := h.emailOrderSvc.ListDrafts(ctx, tenantID, c.QueryParam("status"), page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list draft orders")
+	}
+	if drafts == nil {
+		drafts = []*models.EmailDraftOrder{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"drafts":      drafts,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(drafts)),
+	})
+}
+
+// GetEmailDraftOrder handles GET /email-orders/drafts/:id
+func (h *EmailOrderHandlers) GetEmailDraftOrder(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid draft order ID format")
+	}
+
+	draft, err := h.emailOrderSvc.GetDraft(ctx, tenantID, id)
+	if err != nil {
+		return emailOrderError(err, "Failed to retrieve draft order")
+	}
+	return c.JSON(http.StatusOK, draft)
+}
+
+// UpdateEmailDraftOrder handles PUT /email-orders/drafts/:id
+func (h *EmailOrderHandlers) UpdateEmailDraftOrder(c echo.Context) error {
+	if err := h.requirePermission(c, "email_orders:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid draft order ID format")
+	}
+
+	var req models.EmailDraftOrderUpdate
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	draft, err := h.emailOrderSvc.UpdateDraft(ctx, tenantID, id, &req)
+	if err != nil {
+		return emailOrderError(err, "Failed to update draft order")
+	}
+	return c.JSON(http.StatusOK, draft)
+}
+
+// ApproveEmailDraftOrder handles POST /email-orders/drafts/:id/approve
+func (h *EmailOrderHandlers) ApproveEmailDraftOrder(c echo.Context) error {
+	return h.reviewDraft(c, true)
+}
+
+// RejectEmailDraftOrder handles POST /email-orders/drafts/:id/reject
+func (h *EmailOrderHandlers) RejectEmailDraftOrder(c echo.Context) error {
+	return h.reviewDraft(c, false)
+}
+
+func (h *EmailOrderHandlers) reviewDraft(c echo.Context, approve bool) error {
+	if err := h.requirePermission(c, "email_orders:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid draft order ID format")
+	}
+
+	var req models.EmailDraftOrderReview
+	if c.Request().ContentLength > 0 {
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+		}
+	}
+
+	var draft *models.EmailDraftOrder
+	if approve {
+		draft, err = h.emailOrderSvc.ApproveDraft(ctx, tenantID, id, userID, req.Note)
+	} else {
+		draft, err = h.emailOrderSvc.RejectDraft(ctx, tenantID, id, userID, req.Note)
+	}
+	if err != nil {
+		return emailOrderError(err, "Failed to review draft order")
+	}
+	return c.JSON(http.StatusOK, draft)
+}
+
+// ReceiveEmail handles POST /inbound-email/:token, called by the mail
+// provider's inbound parse webhook. The unguessable token in the address
+// identifies the tenant. Both multipart form posts (SendGrid style fields
+// from, subject, text, headers and attachment files) and JSON are accepted
+func (h *EmailOrderHandlers) ReceiveEmail(c echo.Context) error {
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxInboundEmailPayload)
+
+	var (
+		email *models.InboundEmail
+		err   error
+	)
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		email, err = inboundEmailFromForm(c)
+	} else {
+		email, err = inboundEmailFromJSON(c)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := h.emailOrderSvc.ReceiveEmail(c.Request().Context(), c.Param("token"), email); err != nil {
+		if errors.Is(err, services.ErrEmailOrdersNotConfigured) {
+			return echo.NewHTTPError(http.StatusNotFound, "Mailbox not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to store email")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"email_id": email.ID,
+		"status":   email.Status,
+	})
+}
+
+// newInboundEmail normalises the sender address and message ID of an email
+func newInboundEmail(from, subject, text, messageID string) (*models.InboundEmail, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid from address")
+	}
+	email := &models.InboundEmail{
+		FromAddress: strings.ToLower(address.Address),
+		Subject:     subject,
+		BodyText:    text,
+		Attachments: []models.EmailAttachment{},
+	}
+	if messageID = strings.Trim(strings.TrimSpace(messageID), "<>"); messageID != "" {
+		email.MessageID = &messageID
+	}
+	return email, nil
+}
+
+// keepEmailAttachment reports whether an attachment is a CSV order sheet
+// worth storing
+func keepEmailAttachment(filename, contentType string, size int) bool {
+	if size > maxEmailAttachment {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(filename), ".csv") ||
+		strings.HasPrefix(strings.ToLower(contentType), "text/csv")
+}
+
+func inboundEmailFromForm(c echo.Context) (*models.InboundEmail, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid multipart form")
+	}
+
+	// SendGrid posts the raw headers; the Message-ID is used to drop redeliveries
+	var messageID string
+	if raw := c.FormValue("headers"); raw != "" {
+		if msg, err := mail.ReadMessage(strings.NewReader(raw + "\r\n\r\n")); err == nil {
+			messageID = msg.Header.Get("Message-Id")
+		}
+	}
+
+	email, err := newInboundEmail(c.FormValue("from"), c.FormValue("subject"), c.FormValue("text"), messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, files := range form.File {
+		for _, file := range files {
+			contentType := file.Header.Get(echo.HeaderContentType)
+			if !keepEmailAttachment(file.Filename, contentType, int(file.Size)) {
+				continue
+			}
+			f, err := file.Open()
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Failed to read attachment")
+			}
+			content, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Failed to read attachment")
+			}
+			email.Attachments = append(email.Attachments, models.EmailAttachment{
+				Filename:    file.Filename,
+				ContentType: contentType,
+				Content:     string(content),
+			})
+		}
+	}
+	return email, nil
+}
+
+func inboundEmailFromJSON(c echo.Context) (*models.InboundEmail, error) {
+	var req inboundEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	email, err := newInboundEmail(req.From, req.Subject, req.Text, req.MessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, attachment := range req.Attachments {
+		content, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Attachment %d is not valid base64", i+1))
+		}
+		if !keepEmailAttachment(attachment.Filename, attachment.ContentType, len(content)) {
+			continue
+		}
+		email.Attachments = append(email.Attachments, models.EmailAttachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Content:     string(content),
+		})
+	}
+	return email, nil
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ERPHandlers handles ERP connector configuration, syncs and the sync dashboard
+type ERPHandlers struct {
+	syncService    *jobs.ERPSyncService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewERPHandlers creates a new ERP handlers instance
+func NewERPHandlers(syncService *jobs.ERPSyncService, rbacMiddleware *middleware.RBACMiddleware) *ERPHandlers {
+	return &ERPHandlers{
+		syncService:    syncService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+type erpConnectorRequest struct {
+	Name                string                      `json:"name"`
+	ConnectorType       string                      `json:"connector_type"`
+	DocumentTypes       []string                    `json:"document_types"`
+	Settings            models.ERPConnectorSettings `json:"settings"`
+	SyncIntervalMinutes *int                        `json:"sync_interval_minutes"`
+	IsActive            *bool                       `json:"is_active"`
+}
+
+func (h *ERPHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// connectorFromPath loads the tenant's connector named by the :id parameter
+func (h *ERPHandlers) connectorFromPath(c echo.Context, tenantID uuid.UUID) (*models.ERPConnector, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid connector ID format")
+	}
+	connector, err := h.syncService.GetConnector(c.Request().Context(), tenantID, id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "ERP connector not found")
+	}
+	return connector, nil
+}
+
+// ListConnectors handles GET /erp/connectors
+func (h *ERPHandlers) ListConnectors(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	connectors, err := h.syncService.ListConnectors(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve ERP connectors")
+	}
+	if connectors == nil {
+		connectors = []*models.ERPConnector{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"connectors": connectors,
+	})
+}
+
+// CreateConnector handles POST /erp/connectors
+func (h *ERPHandlers) CreateConnector(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req erpConnectorRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	connector := &models.ERPConnector{
+		TenantID:            tenantID,
+		Name:                req.Name,
+		ConnectorType:       req.ConnectorType,
+		DocumentTypes:       req.DocumentTypes,
+		Settings:            req.Settings,
+		SyncIntervalMinutes: 1440,
+	}
+	if req.SyncIntervalMinutes != nil {
+		connector.SyncIntervalMinutes = *req.SyncIntervalMinutes
+	}
+
+	if err := h.syncService.CreateConnector(ctx, connector); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, connector)
+}
+
+// UpdateConnector handles PUT /erp/connectors/:id
+func (h *ERPHandlers) UpdateConnector(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	connector, err := h.connectorFromPath(c, tenantID)
+	if err != nil {
+		return err
+	}
+
+	var req erpConnectorRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	// The connector type is fixed at creation; create a new connector to switch ERPs
+	connector.Name = req.Name
+	connector.DocumentTypes = req.DocumentTypes
+	connector.Settings = req.Settings
+	if req.SyncIntervalMinutes != nil {
+		connector.SyncIntervalMinutes = *req.SyncIntervalMinutes
+	}
+	if req.IsActive != nil {
+		connector.IsActive = *req.IsActive
+	}
+
+	if err := h.syncService.UpdateConnector(ctx, connector); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, connector)
+}
+
+// DeleteConnector handles DELETE /erp/connectors/:id
+func (h *ERPHandlers) DeleteConnector(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid connector ID format")
+	}
+
+	if err := h.syncService.DeleteConnector(ctx, tenantID, id); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete ERP connector")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SyncConnector handles POST /erp/connectors/:id/sync with optional start_date and end_date (YYYY-MM-DD)
+func (h *ERPHandlers) SyncConnector(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	connector, err := h.connectorFromPath(c, tenantID)
+	if err != nil {
+		return err
+	}
+
+	var start, end *time.Time
+	if raw := c.QueryParam("start_date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid start_date format, expected YYYY-MM-DD")
+		}
+		start = &parsed
+	}
+	if raw := c.QueryParam("end_date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid end_date format, expected YYYY-MM-DD")
+		}
+		end = &parsed
+	}
+
+	run, err := h.syncService.SyncNow(ctx, connector, start, end)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+// ListRuns handles GET /erp/connectors/:id/runs
+func (h *ERPHandlers) ListRuns(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	connector, err := h.connectorFromPath(c, tenantID)
+	if err != nil {
+		return err
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 20})
+	if err != nil {
+		return err
+	}
+
+	runs, err := h.syncService.ListRuns(ctx, tenantID, connector.ID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve sync runs")
+	}
+	if runs == nil {
+		runs = []*models.ERPSyncRun{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"runs":        runs,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(runs)),
+	})
+}
+
+// DownloadRun handles GET /erp/runs/:id/download by redirecting to the export file
+func (h *ERPHandlers) DownloadRun(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid run ID format")
+	}
+
+	url, err := h.syncService.DownloadURL(ctx, tenantID, runID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Export file not found")
+	}
+
+	return c.Redirect(http.StatusFound, url)
+}
+
+// GetSyncStatus handles GET /erp/sync-status
+func (h *ERPHandlers) GetSyncStatus(c echo.Context) error {
+	if err := h.requirePermission(c, "erp:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	statuses, err := h.syncService.SyncStatus(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve ERP sync status")
+	}
+
+	summary := map[string]int{}
+	for _, status := range statuses {
+		summary[status.Health]++
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"connectors": statuses,
+		"summary":    summary,
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// resourceETag derives an ETag from a record's identity and last modification
+// time. Renderings selected by query parameters, such as ?locale=, live at
+// different URLs and so may share it
+func resourceETag(id uuid.UUID, updatedAt time.Time) string {
+	h := sha256.New()
+	h.Write(id[:])
+	h.Write([]byte(strconv.FormatInt(updatedAt.UnixNano(), 10)))
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagListMatches reports whether an If-None-Match or If-Match header lists
+// etag; weak comparison is used, so W/ prefixes are ignored
+func etagListMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonWithETag writes body with its ETag, answering 304 when the client's
+// If-None-Match already carries it
+func jsonWithETag(c echo.Context, etag string, body interface{}) error {
+	c.Response().Header().Set("ETag", etag)
+	if inm := c.Request().Header.Get("If-None-Match"); inm != "" && etagListMatches(inm, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, body)
+}
+
+// checkIfMatch rejects a write with 412 when the client sent If-Match and the
+// record has changed since it was read. When the precondition holds, the
+// returned context makes the record's update conditional on version, the
+// modification time the ETag was derived from, so a write landing in between
+// still fails (see modifiedError). Requests without If-Match proceed, so
+// existing clients keep last-write-wins behaviour
+func checkIfMatch(ctx context.Context, c echo.Context, currentETag string, id uuid.UUID, version time.Time) (context.Context, error) {
+	ifMatch := c.Request().Header.Get("If-Match")
+	if ifMatch == "" {
+		return ctx, nil
+	}
+	if etagListMatches(ifMatch, currentETag) {
+		return repositories.WithExpectedVersion(ctx, id, version), nil
+	}
+	c.Response().Header().Set("ETag", currentETag)
+	return ctx, modifiedError()
+}
+
+// modifiedError answers 412 for a failed If-Match precondition
+func modifiedError() error {
+	return echo.NewHTTPError(http.StatusPreconditionFailed, "Resource has been modified; reload it and retry")
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ifMatchContext(ifMatch string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPut, "/v1/suppliers/1", nil)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func assertPreconditionFailed(t *testing.T, err error) {
+	t.Helper()
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusPreconditionFailed, httpErr.Code)
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	id, version := uuid.New(), time.Now()
+	etag := resourceETag(id, version)
+	ctx := context.Background()
+
+	c, _ := ifMatchContext("")
+	got, err := checkIfMatch(ctx, c, etag, id, version)
+	require.NoError(t, err)
+	assert.Equal(t, ctx, got, "writes without If-Match stay unconditional")
+
+	c, _ = ifMatchContext(`W/` + etag)
+	got, err = checkIfMatch(ctx, c, etag, id, version)
+	require.NoError(t, err)
+	assert.NotEqual(t, ctx, got, "a matching If-Match makes the update conditional")
+
+	c, rec := ifMatchContext(resourceETag(id, version.Add(-time.Second)))
+	_, err = checkIfMatch(ctx, c, etag, id, version)
+	assertPreconditionFailed(t, err)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestConditionalUpdateConflictsAnswer412(t *testing.T) {
+	err := fmt.Errorf("update supplier: %w", repositories.ErrPreconditionFailed)
+
+	assertPreconditionFailed(t, masterDataError(err, "Failed to update supplier"))
+	assertPreconditionFailed(t, productWriteError(err))
+	assertPreconditionFailed(t, invoiceWriteError(err))
+}
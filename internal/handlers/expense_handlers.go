@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ExpenseHandlers handles warehouse expenses, their attachments, expense
+// budgets and the budget vs actual report
+type ExpenseHandlers struct {
+	expenseSvc     services.ExpenseService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewExpenseHandlers creates a new expense handlers instance
+func NewExpenseHandlers(expenseSvc services.ExpenseService, rbacMiddleware *middleware.RBACMiddleware) *ExpenseHandlers {
+	return &ExpenseHandlers{
+		expenseSvc:     expenseSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *ExpenseHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// expenseError maps expense service errors to HTTP errors
+func expenseError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrExpenseNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Expense not found")
+	case errors.Is(err, services.ErrExpenseCategoryNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Expense category not found")
+	case errors.Is(err, services.ErrExpenseAttachmentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Expense attachment not found")
+	case errors.Is(err, services.ErrExpenseBudgetNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Expense budget not found")
+	case errors.Is(err, services.ErrInvalidExpense):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrExpenseCategoryConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// optionalWarehouseID reads the warehouse_id query parameter if present
+func optionalWarehouseID(c echo.Context) (*uuid.UUID, error) {
+	value := c.QueryParam("warehouse_id")
+	if value == "" {
+		return nil, nil
+	}
+	warehouseID, err := uuid.Parse(value)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+	return &warehouseID, nil
+}
+
+// ListCategories handles GET /expense-categories
+func (h *ExpenseHandlers) ListCategories(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	categories, err := h.expenseSvc.ListCategories(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list expense categories")
+	}
+	if categories == nil {
+		categories = []*models.ExpenseCategory{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"categories": categories})
+}
+
+// CreateCategory handles POST /expense-categories
+func (h *ExpenseHandlers) CreateCategory(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.ExpenseCategoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	category, err := h.expenseSvc.CreateCategory(ctx, tenantID, &req)
+	if err != nil {
+		return expenseError(err, "Failed to create expense category")
+	}
+
+	return c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory handles PUT /expense-categories/:id
+func (h *ExpenseHandlers) UpdateCategory(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense category ID")
+	}
+
+	var req models.ExpenseCategoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	category, err := h.expenseSvc.UpdateCategory(ctx, tenantID, categoryID, &req)
+	if err != nil {
+		return expenseError(err, "Failed to update expense category")
+	}
+
+	return c.JSON(http.StatusOK, category)
+}
+
+// ListExpenses handles GET /expenses?warehouse_id=&category_id=&month=&limit=&offset=
+func (h *ExpenseHandlers) ListExpenses(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	filter := &models.ExpenseFilter{}
+	warehouseID, err := optionalWarehouseID(c)
+	if err != nil {
+		return err
+	}
+	filter.WarehouseID = warehouseID
+	if categoryIDStr := c.QueryParam("category_id"); categoryIDStr != "" {
+		categoryID, err := uuid.Parse(categoryIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense category ID")
+		}
+		filter.CategoryID = &categoryID
+	}
+	if monthStr := c.QueryParam("month"); monthStr != "" {
+		month, err := time.Parse("2006-01", monthStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "month must be in YYYY-MM format")
+		}
+		filter.Month = &month
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	filter.Limit, filter.Offset = page.Limit, page.Offset
+
+	expenses, err := h.expenseSvc.ListExpenses(ctx, tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list expenses")
+	}
+	if expenses == nil {
+		expenses = []*models.Expense{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"expenses":    expenses,
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+		"next_cursor": page.NextCursor(len(expenses)),
+	})
+}
+
+// GetExpense handles GET /expenses/:id
+func (h *ExpenseHandlers) GetExpense(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense ID")
+	}
+
+	expense, err := h.expenseSvc.GetExpense(ctx, tenantID, expenseID)
+	if err != nil {
+		return expenseError(err, "Failed to get expense")
+	}
+
+	return c.JSON(http.StatusOK, expense)
+}
+
+// CreateExpense handles POST /expenses
+func (h *ExpenseHandlers) CreateExpense(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	var createdBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		createdBy = &userID
+	}
+
+	var req models.ExpenseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	expense, err := h.expenseSvc.CreateExpense(ctx, tenantID, createdBy, &req)
+	if err != nil {
+		return expenseError(err, "Failed to create expense")
+	}
+
+	return c.JSON(http.StatusCreated, expense)
+}
+
+// UpdateExpense handles PUT /expenses/:id
+func (h *ExpenseHandlers) UpdateExpense(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense ID")
+	}
+
+	var req models.ExpenseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	expense, err := h.expenseSvc.UpdateExpense(ctx, tenantID, expenseID, &req)
+	if err != nil {
+		return expenseError(err, "Failed to update expense")
+	}
+
+	return c.JSON(http.StatusOK, expense)
+}
+
+// DeleteExpense handles DELETE /expenses/:id
+func (h *ExpenseHandlers) DeleteExpense(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense ID")
+	}
+
+	if err := h.expenseSvc.DeleteExpense(ctx, tenantID, expenseID); err != nil {
+		return expenseError(err, "Failed to delete expense")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UploadAttachment handles POST /expenses/:id/attachments with a multipart
+// "file" holding a bill or receipt
+func (h *ExpenseHandlers) UploadAttachment(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense ID")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Attachment file is required")
+	}
+	const maxFileSize = 10 * 1024 * 1024 // 10MB in bytes
+	if file.Size > maxFileSize {
+		return echo.NewHTTPError(http.StatusBadRequest, "File size exceeds maximum limit of 10MB")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open attachment file")
+	}
+	defer src.Close()
+
+	buffer := make([]byte, 512)
+	n, err := src.Read(buffer)
+	if err != nil && err != io.EOF {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file content")
+	}
+	contentType := http.DetectContentType(buffer[:n])
+	allowedTypes := map[string]bool{
+		"image/jpeg":      true,
+		"image/png":       true,
+		"image/webp":      true,
+		"application/pdf": true,
+	}
+	if !allowedTypes[contentType] {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file type. Only JPEG, PNG, WebP images and PDF documents are allowed")
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file content")
+	}
+
+	attachment, err := h.expenseSvc.AddAttachment(ctx, tenantID, expenseID, file.Filename, contentType, src, file.Size)
+	if err != nil {
+		return expenseError(err, "Failed to upload attachment")
+	}
+
+	return c.JSON(http.StatusCreated, attachment)
+}
+
+// DeleteAttachment handles DELETE /expenses/:id/attachments/:attachment_id
+func (h *ExpenseHandlers) DeleteAttachment(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense ID")
+	}
+	attachmentID, err := uuid.Parse(c.Param("attachment_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid attachment ID")
+	}
+
+	if err := h.expenseSvc.DeleteAttachment(ctx, tenantID, expenseID, attachmentID); err != nil {
+		return expenseError(err, "Failed to delete attachment")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListBudgets handles GET /expense-budgets?month=&warehouse_id=
+func (h *ExpenseHandlers) ListBudgets(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	warehouseID, err := optionalWarehouseID(c)
+	if err != nil {
+		return err
+	}
+
+	budgets, err := h.expenseSvc.ListBudgets(ctx, tenantID, c.QueryParam("month"), warehouseID)
+	if err != nil {
+		return expenseError(err, "Failed to list expense budgets")
+	}
+	if budgets == nil {
+		budgets = []*models.ExpenseBudget{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"budgets": budgets})
+}
+
+// SetBudget handles PUT /expense-budgets, replacing the budget of the
+// warehouse, category and month
+func (h *ExpenseHandlers) SetBudget(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.ExpenseBudgetRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	budget, err := h.expenseSvc.SetBudget(ctx, tenantID, &req)
+	if err != nil {
+		return expenseError(err, "Failed to set expense budget")
+	}
+
+	return c.JSON(http.StatusOK, budget)
+}
+
+// DeleteBudget handles DELETE /expense-budgets/:id
+func (h *ExpenseHandlers) DeleteBudget(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	budgetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid expense budget ID")
+	}
+
+	if err := h.expenseSvc.DeleteBudget(ctx, tenantID, budgetID); err != nil {
+		return expenseError(err, "Failed to delete expense budget")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetBudgetVsActual handles GET /reports/budget-vs-actual?from=&to=&warehouse_id=&format=csv
+// from and to are YYYY-MM months and default to the current month
+func (h *ExpenseHandlers) GetBudgetVsActual(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	warehouseID, err := optionalWarehouseID(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.expenseSvc.BudgetVsActual(ctx, tenantID, c.QueryParam("from"), c.QueryParam("to"), warehouseID)
+	if err != nil {
+		return expenseError(err, "Failed to generate budget vs actual report")
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return c.JSON(http.StatusOK, report)
+	}
+	content, err := services.BudgetVsActualCSV(report)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export budget vs actual report")
+	}
+	return sendCSV(c, fmt.Sprintf("budget_vs_actual_%s_%s.csv", report.From.Format("200601"), report.To.Format("200601")), content)
+}
+
+// GetDashboard handles GET /dashboard/expenses?month=&top=
+func (h *ExpenseHandlers) GetDashboard(c echo.Context) error {
+	if err := h.requirePermission(c, "expenses:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	top := services.DefaultExpenseOverruns
+	if topStr := c.QueryParam("top"); topStr != "" {
+		parsed, err := strconv.Atoi(topStr)
+		if err != nil || parsed <= 0 || parsed > 50 {
+			return echo.NewHTTPError(http.StatusBadRequest, "top must be between 1 and 50")
+		}
+		top = parsed
+	}
+
+	dashboard, err := h.expenseSvc.Dashboard(ctx, tenantID, c.QueryParam("month"), top)
+	if err != nil {
+		return expenseError(err, "Failed to load expense dashboard")
+	}
+
+	return c.JSON(http.StatusOK, dashboard)
+}
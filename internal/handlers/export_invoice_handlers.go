@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ExportInvoiceHandlers handles export invoices and the GSTR-1 export section
+type ExportInvoiceHandlers struct {
+	exportSvc      services.ExportInvoiceService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewExportInvoiceHandlers creates a new export invoice handlers instance
+func NewExportInvoiceHandlers(exportSvc services.ExportInvoiceService, rbacMiddleware *middleware.RBACMiddleware) *ExportInvoiceHandlers {
+	return &ExportInvoiceHandlers{
+		exportSvc:      exportSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *ExportInvoiceHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// exportInvoiceError maps export invoice service errors to HTTP errors
+func exportInvoiceError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrExportInvoiceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Export invoice not found")
+	case errors.Is(err, services.ErrInvalidExportInvoice):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrExportInvoiceConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// CreateExportInvoice handles POST /invoices/export
+func (h *ExportInvoiceHandlers) CreateExportInvoice(c echo.Context) error {
+	if err := h.requirePermission(c, "exports:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.ExportInvoiceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	invoice, err := h.exportSvc.CreateExportInvoice(ctx, tenantID, &req)
+	if err != nil {
+		return exportInvoiceError(err, "Failed to create export invoice")
+	}
+
+	return c.JSON(http.StatusCreated, invoice)
+}
+
+// GetExportInvoice handles GET /invoices/:id/export
+func (h *ExportInvoiceHandlers) GetExportInvoice(c echo.Context) error {
+	if err := h.requirePermission(c, "exports:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
+	}
+
+	invoice, err := h.exportSvc.GetExportInvoice(ctx, tenantID, invoiceID)
+	if err != nil {
+		return exportInvoiceError(err, "Failed to retrieve export invoice")
+	}
+
+	return c.JSON(http.StatusOK, invoice)
+}
+
+// UpdateShipping handles PUT /invoices/:id/export/shipping
+func (h *ExportInvoiceHandlers) UpdateShipping(c echo.Context) error {
+	if err := h.requirePermission(c, "exports:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
+	}
+
+	var req models.ExportShippingRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	invoice, err := h.exportSvc.UpdateShipping(ctx, tenantID, invoiceID, &req)
+	if err != nil {
+		return exportInvoiceError(err, "Failed to update shipping details")
+	}
+
+	return c.JSON(http.StatusOK, invoice)
+}
+
+// GetGSTR1Exports handles GET /reports/gstr1/exports?month=2025-08, GSTR-1
+// table 6A for the month, the current one by default
+func (h *ExportInvoiceHandlers) GetGSTR1Exports(c echo.Context) error {
+	if err := h.requirePermission(c, "exports:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	period := time.Now()
+	if month := c.QueryParam("month"); month != "" {
+		parsed, err := time.Parse("2006-01", month)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "month must be in YYYY-MM format")
+		}
+		period = parsed
+	}
+
+	section, err := h.exportSvc.GSTR1Exports(ctx, tenantID, period)
+	if err != nil {
+		return exportInvoiceError(err, "Failed to generate GSTR-1 export section")
+	}
+
+	return c.JSON(http.StatusOK, section)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// FeatureFlagHandlers handles feature flag administration and lets tenants
+// see which features are on for them
+type FeatureFlagHandlers struct {
+	featureFlagSvc services.FeatureFlagService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewFeatureFlagHandlers creates a new feature flag handlers instance
+func NewFeatureFlagHandlers(featureFlagSvc services.FeatureFlagService, rbacMiddleware *middleware.RBACMiddleware) *FeatureFlagHandlers {
+	return &FeatureFlagHandlers{
+		featureFlagSvc: featureFlagSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *FeatureFlagHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// featureFlagError maps feature flag service errors to HTTP errors
+func featureFlagError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrFeatureFlagNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Feature flag not found")
+	case errors.Is(err, services.ErrFeatureFlagTenantNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Tenant not found")
+	case errors.Is(err, services.ErrInvalidFeatureFlag):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// GetFeatures handles GET /features, listing the flags on for the caller's tenant
+func (h *FeatureFlagHandlers) GetFeatures(c echo.Context) error {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	features, err := h.featureFlagSvc.EnabledFeatures(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get features")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"features": features})
+}
+
+// ListFeatureFlags handles GET /admin/feature-flags (platform admin only)
+func (h *FeatureFlagHandlers) ListFeatureFlags(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_feature_flags"); err != nil {
+		return err
+	}
+
+	flags, err := h.featureFlagSvc.ListFlags(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list feature flags")
+	}
+	if flags == nil {
+		flags = []*models.FeatureFlag{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"feature_flags": flags})
+}
+
+// GetFeatureFlag handles GET /admin/feature-flags/:key (platform admin only)
+func (h *FeatureFlagHandlers) GetFeatureFlag(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_feature_flags"); err != nil {
+		return err
+	}
+
+	flag, err := h.featureFlagSvc.GetFlag(c.Request().Context(), c.Param("key"))
+	if err != nil {
+		return featureFlagError(err, "Failed to get feature flag")
+	}
+
+	return c.JSON(http.StatusOK, flag)
+}
+
+// SaveFeatureFlag handles PUT /admin/feature-flags/:key, creating the flag or
+// changing whether it is enabled and its rollout percentage (platform admin only)
+func (h *FeatureFlagHandlers) SaveFeatureFlag(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_feature_flags"); err != nil {
+		return err
+	}
+
+	var req models.FeatureFlagRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	flag, err := h.featureFlagSvc.SaveFlag(c.Request().Context(), c.Param("key"), &req)
+	if err != nil {
+		return featureFlagError(err, "Failed to save feature flag")
+	}
+
+	return c.JSON(http.StatusOK, flag)
+}
+
+// DeleteFeatureFlag handles DELETE /admin/feature-flags/:key (platform admin only)
+func (h *FeatureFlagHandlers) DeleteFeatureFlag(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_feature_flags"); err != nil {
+		return err
+	}
+
+	if err := h.featureFlagSvc.DeleteFlag(c.Request().Context(), c.Param("key")); err != nil {
+		return featureFlagError(err, "Failed to delete feature flag")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SetFeatureFlagOverride handles PUT /admin/feature-flags/:key/tenants/:tenant_id,
+// forcing the flag on or off for one tenant (platform admin only)
+func (h *FeatureFlagHandlers) SetFeatureFlagOverride(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_feature_flags"); err != nil {
+		return err
+	}
+
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+
+	var req models.FeatureFlagOverrideRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	override, err := h.featureFlagSvc.SetOverride(c.Request().Context(), c.Param("key"), tenantID, req.Enabled)
+	if err != nil {
+		return featureFlagError(err, "Failed to save feature flag override")
+	}
+
+	return c.JSON(http.StatusOK, override)
+}
+
+// DeleteFeatureFlagOverride handles DELETE /admin/feature-flags/:key/tenants/:tenant_id,
+// returning the tenant to the flag's rollout (platform admin only)
+func (h *FeatureFlagHandlers) DeleteFeatureFlagOverride(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_feature_flags"); err != nil {
+		return err
+	}
+
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+
+	if err := h.featureFlagSvc.DeleteOverride(c.Request().Context(), c.Param("key"), tenantID); err != nil {
+		return featureFlagError(err, "Failed to delete feature flag override")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"agromart2/internal/common"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fieldPermissionTag marks model fields shown only to users holding the
+// named permission, as in `perm:"products:view_cost"`
+const fieldPermissionTag = "perm"
+
+// grantedPermissionsKey caches the user's permissions on the echo context
+const grantedPermissionsKey = "granted_permissions"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// jsonField is a struct field as encoding/json sees it
+type jsonField struct {
+	name       string
+	index      []int
+	permission string
+}
+
+// structFieldCache memoizes jsonFields of struct types
+var structFieldCache sync.Map
+
+// restrictableCache memoizes whether values of a type may hold fields
+// restricted by permission
+var restrictableCache sync.Map
+
+// PermissionSerializer encodes API responses, leaving out fields tagged with
+// a permission the user does not hold: counter staff see a product's selling
+// price but not its purchase cost. Requests without a user see none of them
+type PermissionSerializer struct {
+	echo.DefaultJSONSerializer
+	rbacService services.RBACService
+}
+
+// NewPermissionSerializer creates the serializer; install it as the echo
+// instance's JSONSerializer
+func NewPermissionSerializer(rbacService services.RBACService) *PermissionSerializer {
+	return &PermissionSerializer{rbacService: rbacService}
+}
+
+// Serialize encodes i, pruning the fields the user may not see. Responses
+// without restricted fields are encoded directly
+func (s *PermissionSerializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	restricted := make(map[string]bool)
+	collectPermissions(reflect.ValueOf(i), restricted)
+	if len(restricted) == 0 {
+		return s.DefaultJSONSerializer.Serialize(c, i, indent)
+	}
+	denied, err := s.deniedPermissions(c, restricted)
+	if err != nil {
+		return err
+	}
+	if len(denied) == 0 {
+		return s.DefaultJSONSerializer.Serialize(c, i, indent)
+	}
+
+	tree, err := maskedTree(i, denied)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.Response())
+	if indent != "" {
+		enc.SetIndent("", indent)
+	}
+	return enc.Encode(tree)
+}
+
+// deniedPermissions returns which of the permissions the user lacks
+func (s *PermissionSerializer) deniedPermissions(c echo.Context, permissions map[string]bool) (map[string]bool, error) {
+	granted, err := grantedPermissions(c, s.rbacService)
+	if err != nil {
+		return nil, err
+	}
+
+	denied := make(map[string]bool)
+	for permission := range permissions {
+		if !granted[permission] {
+			denied[permission] = true
+		}
+	}
+	return denied, nil
+}
+
+// grantedPermissions returns the permissions the user holds, looked up once
+// per request. Requests without a user hold none
+func grantedPermissions(c echo.Context, rbacService services.RBACService) (map[string]bool, error) {
+	if granted, ok := c.Get(grantedPermissionsKey).(map[string]bool); ok {
+		return granted, nil
+	}
+
+	granted := make(map[string]bool)
+	rc := common.RequestContextFrom(c.Request().Context())
+	userID, hasUser := rc.User()
+	tenantID, hasTenant := rc.Tenant()
+	if hasUser && hasTenant {
+		names, err := rbacService.GetUserPermissions(c.Request().Context(), userID, tenantID)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error checking permission")
+		}
+		for _, name := range names {
+			granted[name] = true
+		}
+	}
+	c.Set(grantedPermissionsKey, granted)
+	return granted, nil
+}
+
+// deniedFieldPermissions returns the permissions restricting fields of
+// values of t that the user lacks, when the API uses PermissionSerializer
+func deniedFieldPermissions(c echo.Context, t reflect.Type) (map[string]bool, error) {
+	s, ok := c.Echo().JSONSerializer.(*PermissionSerializer)
+	if !ok {
+		return nil, nil
+	}
+	restricted := make(map[string]bool)
+	collectTypePermissions(t, restricted, make(map[reflect.Type]bool))
+	if len(restricted) == 0 {
+		return nil, nil
+	}
+	return s.deniedPermissions(c, restricted)
+}
+
+// maskedTree encodes v and decodes it back to maps and slices with the
+// fields of denied permissions removed. Numbers are kept as written
+func maskedTree(v interface{}, denied map[string]bool) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	pruneFields(reflect.ValueOf(v), tree, denied)
+	return tree, nil
+}
+
+// collectPermissions adds the permissions restricting fields held by v
+func collectPermissions(v reflect.Value, found map[string]bool) {
+	if !v.IsValid() || !restrictable(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if !v.IsNil() {
+			collectPermissions(v.Elem(), found)
+		}
+	case reflect.Struct:
+		for _, f := range structFields(v.Type()) {
+			if f.permission != "" {
+				found[f.permission] = true
+			}
+			if field, err := v.FieldByIndexErr(f.index); err == nil {
+				collectPermissions(field, found)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectPermissions(v.Index(i), found)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectPermissions(iter.Value(), found)
+		}
+	}
+}
+
+// pruneFields walks v alongside its decoded JSON, deleting the keys of
+// fields whose permission is denied
+func pruneFields(v reflect.Value, node interface{}, denied map[string]bool) {
+	if !v.IsValid() || node == nil || !restrictable(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if !v.IsNil() {
+			pruneFields(v.Elem(), node, denied)
+		}
+	case reflect.Struct:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, f := range structFields(v.Type()) {
+			if denied[f.permission] {
+				delete(obj, f.name)
+				continue
+			}
+			if field, err := v.FieldByIndexErr(f.index); err == nil {
+				pruneFields(field, obj[f.name], denied)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := node.([]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(items); i++ {
+			pruneFields(v.Index(i), items[i], denied)
+		}
+	case reflect.Map:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if key, ok := mapKeyString(iter.Key()); ok {
+				pruneFields(iter.Value(), obj[key], denied)
+			}
+		}
+	}
+}
+
+// mapKeyString is the JSON object key encoding/json writes for a map key
+func mapKeyString(k reflect.Value) (string, bool) {
+	if k.Kind() == reflect.String {
+		return k.String(), true
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		return string(text), err == nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(k.Uint(), 10), true
+	}
+	return "", false
+}
+
+// restrictable reports whether values of t may hold restricted fields.
+// Interfaces may hold anything; types encoding themselves are opaque
+func restrictable(t reflect.Type) bool {
+	if cached, ok := restrictableCache.Load(t); ok {
+		return cached.(bool)
+	}
+	found := make(map[string]bool)
+	dynamic := collectTypePermissions(t, found, make(map[reflect.Type]bool))
+	result := dynamic || len(found) > 0
+	restrictableCache.Store(t, result)
+	return result
+}
+
+// collectTypePermissions adds the permissions restricting fields reachable
+// from t and reports whether t can also hold values only known at runtime
+func collectTypePermissions(t reflect.Type, found map[string]bool, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if encodesItself(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return collectTypePermissions(t.Elem(), found, seen)
+	case reflect.Struct:
+		dynamic := false
+		for _, f := range structFields(t) {
+			if f.permission != "" {
+				found[f.permission] = true
+			}
+			if collectTypePermissions(t.FieldByIndex(f.index).Type, found, seen) {
+				dynamic = true
+			}
+		}
+		return dynamic
+	}
+	return false
+}
+
+// encodesItself reports whether encoding/json defers to t's own marshaling
+func encodesItself(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// structFields lists the JSON fields of a struct type, promoting the fields
+// of untagged embedded structs like encoding/json does
+func structFields(t reflect.Type) []jsonField {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.([]jsonField)
+	}
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for _, inner := range structFields(embedded) {
+					inner.index = append([]int{i}, inner.index...)
+					fields = append(fields, inner)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, index: []int{i}, permission: f.Tag.Get(fieldPermissionTag)})
+	}
+	structFieldCache.Store(t, fields)
+	return fields
+}
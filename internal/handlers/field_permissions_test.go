@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRBACService struct {
+	permissions []string
+	calls       int
+}
+
+func (s *stubRBACService) UserHasPermission(ctx context.Context, userID, tenantID uuid.UUID, permissionName string) (bool, error) {
+	for _, p := range s.permissions {
+		if p == permissionName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *stubRBACService) GetUserPermissions(ctx context.Context, userID, tenantID uuid.UUID) ([]string, error) {
+	s.calls++
+	return s.permissions, nil
+}
+
+func permissionContext(rbac *stubRBACService, target string, authenticated bool) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.JSONSerializer = NewPermissionSerializer(rbac)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if authenticated {
+		rc := &common.RequestContext{TenantID: uuid.New(), UserID: uuid.New()}
+		req = req.WithContext(common.WithRequestContext(req.Context(), rc))
+	}
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func costedResponse() map[string]interface{} {
+	cost := 240.0
+	return map[string]interface{}{
+		"products":   []*models.Product{{ID: uuid.New(), Name: "Urea 45kg", UnitPrice: 266.5, CostPrice: &cost}},
+		"violations": []models.MarginViolation{{ID: uuid.New(), UnitPrice: 250, CostPrice: 240, MarginPercent: 4.17}},
+		"aging": []models.InventoryAgingItem{{
+			InventoryAgingPosition: models.InventoryAgingPosition{ProductName: "DAP 50kg", UnitPrice: 1350, CostPrice: &cost},
+			StockValue:             2400,
+		}},
+		"total": 3,
+	}
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body
+}
+
+func TestPermissionSerializerMasksCostFields(t *testing.T) {
+	rbac := &stubRBACService{permissions: []string{"read_products"}}
+	c, rec := permissionContext(rbac, "/products", true)
+
+	require.NoError(t, c.JSON(http.StatusOK, costedResponse()))
+	body := decodeBody(t, rec)
+
+	product := body["products"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 266.5, product["unit_price"])
+	assert.NotContains(t, product, "cost_price")
+
+	violation := body["violations"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 250.0, violation["unit_price"])
+	assert.NotContains(t, violation, "cost_price")
+	assert.NotContains(t, violation, "margin_percent")
+
+	item := body["aging"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "DAP 50kg", item["product_name"])
+	assert.NotContains(t, item, "cost_price")
+	assert.NotContains(t, item, "stock_value")
+
+	assert.Equal(t, 3.0, body["total"])
+}
+
+func TestPermissionSerializerKeepsCostFieldsWhenGranted(t *testing.T) {
+	rbac := &stubRBACService{permissions: []string{"products:view_cost"}}
+	c, rec := permissionContext(rbac, "/products", true)
+
+	require.NoError(t, c.JSON(http.StatusOK, costedResponse()))
+	body := decodeBody(t, rec)
+
+	product := body["products"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 240.0, product["cost_price"])
+	violation := body["violations"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 4.17, violation["margin_percent"])
+}
+
+func TestPermissionSerializerSkipsLookupWithoutRestrictedFields(t *testing.T) {
+	rbac := &stubRBACService{}
+	c, rec := permissionContext(rbac, "/categories", true)
+
+	require.NoError(t, c.JSON(http.StatusOK, map[string]interface{}{
+		"categories": []*models.Category{{ID: uuid.New(), Name: "Fertilizers"}},
+	}))
+	assert.Equal(t, 0, rbac.calls)
+	assert.Contains(t, rec.Body.String(), "Fertilizers")
+}
+
+func TestPermissionSerializerMasksAnonymousRequests(t *testing.T) {
+	rbac := &stubRBACService{permissions: []string{"products:view_cost"}}
+	c, rec := permissionContext(rbac, "/catalog", false)
+
+	require.NoError(t, c.JSON(http.StatusOK, costedResponse()))
+	product := decodeBody(t, rec)["products"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, product, "cost_price")
+	assert.Equal(t, 0, rbac.calls)
+}
+
+func TestFieldSelectionMasksCostFields(t *testing.T) {
+	cost := 240.0
+	products := []*models.Product{{ID: uuid.New(), Name: "Urea 45kg", UnitPrice: 266.5, CostPrice: &cost}}
+	loaders := map[string]embedLoader[*models.Product]{}
+
+	c, _ := permissionContext(&stubRBACService{}, "/products?fields=name,cost_price", true)
+	sel, err := parseFieldSelection(c, &models.Product{}, loaders)
+	require.NoError(t, err)
+	out, err := serializeList(sel, products, loaders)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"id", "name"}, keys(out[0]))
+
+	c, _ = permissionContext(&stubRBACService{permissions: []string{"products:view_cost"}}, "/products?fields=name,cost_price", true)
+	sel, err = parseFieldSelection(c, &models.Product{}, loaders)
+	require.NoError(t, err)
+	out, err = serializeList(sel, products, loaders)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"id", "name", "cost_price"}, keys(out[0]))
+}
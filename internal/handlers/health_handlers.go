@@ -5,8 +5,13 @@ import (
 	"log"
 	"net/http"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
+	"agromart2/internal/caching"
+	"agromart2/pkg/database"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 )
@@ -58,6 +63,15 @@ func HealthCheckDetailed(c echo.Context, pool *pgxpool.Pool) error {
 		checks["database"] = "unknown"
 	}
 
+	// Cache health as seen by the circuit breaker; requests are served from
+	// the database while it is degraded
+	if cache, ok := caching.Health(); ok {
+		checks["cache"] = cache
+		if cache.Status != caching.CacheStatusOK {
+			response["status"] = "degraded"
+		}
+	}
+
 	// Memory usage (basic)
 	// We'll add more checks here as needed
 
@@ -70,7 +84,7 @@ func HealthCheckDetailed(c echo.Context, pool *pgxpool.Pool) error {
 }
 
 // MetricsHandler handles GET /metrics (Prometheus metrics format)
-func MetricsHandler(c echo.Context) error {
+func MetricsHandler(c echo.Context, pool *pgxpool.Pool) error {
 	// Basic metrics - in a real application, you'd integrate with a metrics library
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -91,12 +105,74 @@ health_status 1
 	response := fmt.Sprintf(metrics,
 		runtime.NumGoroutine(),
 		m.HeapAlloc,
-	)
+	) + cacheMetrics() + poolMetrics(pool)
 
 	c.Response().Header().Set("Content-Type", "text/plain; charset=utf-8")
 	return c.String(http.StatusOK, response)
 }
 
+// cacheMetrics reports the lookups and hit ratio of each cache type, and the
+// circuit breaker's state
+func cacheMetrics() string {
+	stats := caching.Stats()
+	families := []struct {
+		name, kind, help string
+		value            func(caching.CacheTypeStats) string
+	}{
+		{"agromart_cache_hits_total", "counter", "Cache lookups that found an entry, by cache type.",
+			func(s caching.CacheTypeStats) string { return strconv.FormatUint(s.Hits, 10) }},
+		{"agromart_cache_misses_total", "counter", "Cache lookups that found no entry, by cache type.",
+			func(s caching.CacheTypeStats) string { return strconv.FormatUint(s.Misses, 10) }},
+		{"agromart_cache_hit_ratio", "gauge", "Share of cache lookups that were hits, by cache type.",
+			func(s caching.CacheTypeStats) string { return strconv.FormatFloat(s.HitRatio, 'g', -1, 64) }},
+	}
+
+	var b strings.Builder
+	for _, family := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+		for _, s := range stats {
+			fmt.Fprintf(&b, "%s{cache=%q} %s\n", family.name, s.Type, family.value(s))
+		}
+	}
+
+	if health, ok := caching.Health(); ok {
+		degraded := 0
+		if health.Status != caching.CacheStatusOK {
+			degraded = 1
+		}
+		metric := func(name, kind, help string, value interface{}) {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+		}
+		metric("agromart_cache_degraded", "gauge", "1 while the cache circuit breaker is open and requests skip the cache.", degraded)
+		metric("agromart_cache_breaker_trips_total", "counter", "Times the cache circuit breaker opened.", health.Trips)
+		metric("agromart_cache_short_circuited_total", "counter", "Cache calls skipped while the breaker was open.", health.ShortCircuited)
+	}
+	return b.String()
+}
+
+// poolMetrics reports the database pool's connections and acquires, and the
+// slow query count
+func poolMetrics(pool *pgxpool.Pool) string {
+	var b strings.Builder
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+
+	if pool != nil {
+		stat := pool.Stat()
+		metric("agromart_db_pool_max_conns", "gauge", "Maximum size of the database pool.", stat.MaxConns())
+		metric("agromart_db_pool_total_conns", "gauge", "Open connections in the database pool.", stat.TotalConns())
+		metric("agromart_db_pool_acquired_conns", "gauge", "Connections currently in use.", stat.AcquiredConns())
+		metric("agromart_db_pool_idle_conns", "gauge", "Idle connections in the pool.", stat.IdleConns())
+		metric("agromart_db_pool_acquires_total", "counter", "Connections acquired from the pool.", stat.AcquireCount())
+		metric("agromart_db_pool_empty_acquires_total", "counter", "Acquires that waited because the pool had no idle connection.", stat.EmptyAcquireCount())
+		metric("agromart_db_pool_canceled_acquires_total", "counter", "Acquires canceled before a connection was available.", stat.CanceledAcquireCount())
+		metric("agromart_db_pool_acquire_seconds_total", "counter", "Time spent acquiring connections.", stat.AcquireDuration().Seconds())
+	}
+	metric("agromart_db_slow_queries_total", "counter", "Queries slower than the slow query threshold.", database.SlowQueryCount())
+	return b.String()
+}
+
 // DocumentationGuideHandler handles GET /v1/docs/guide
 func DocumentationGuideHandler(c echo.Context) error {
 	guide := `# Agromart2 API Developer Guide
@@ -132,7 +208,7 @@ Errors return a status code and message:
 
 ## Rate Limits
 - 1000 requests per hour per IP
-- 10000 requests per hour per authenticated user
+- 10000 requests per hour per authenticated user (100000 in sandbox tenants)
 
 ## Support
 For support, contact: support@agromart2.com
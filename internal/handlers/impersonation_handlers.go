@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ImpersonationHandlers handles support impersonation HTTP requests
+type ImpersonationHandlers struct {
+	impersonationService services.ImpersonationService
+	rbacMiddleware       *middleware.RBACMiddleware
+}
+
+// NewImpersonationHandlers creates a new impersonation handlers instance
+func NewImpersonationHandlers(impersonationService services.ImpersonationService, rbacMiddleware *middleware.RBACMiddleware) *ImpersonationHandlers {
+	return &ImpersonationHandlers{
+		impersonationService: impersonationService,
+		rbacMiddleware:       rbacMiddleware,
+	}
+}
+
+// StartImpersonationRequest represents the impersonation request payload
+type StartImpersonationRequest struct {
+	TenantID        string `json:"tenant_id" validate:"required"`
+	UserID          string `json:"user_id" validate:"required"`
+	Reason          string `json:"reason" validate:"required"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// StartImpersonationResponse returns the session and the token acting as the target user
+type StartImpersonationResponse struct {
+	Session *models.ImpersonationSession `json:"session"`
+	Token   *models.TokenResponse        `json:"token"`
+}
+
+// StartImpersonation mints a short-lived token acting as a tenant user (platform admin only)
+func (h *ImpersonationHandlers) StartImpersonation(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("platform:impersonate")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	// Impersonation tokens cannot be used to start nested sessions
+	if _, impersonating := common.RequestContextFrom(ctx).Impersonator(); impersonating {
+		return echo.NewHTTPError(http.StatusForbidden, "Cannot start impersonation from an impersonated session")
+	}
+
+	impersonatorID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+	impersonatorTenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req StartImpersonationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	tenantID, err := common.ValidateUUID(req.TenantID, "tenant_id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	targetUserID, err := common.ValidateUUID(req.UserID, "user_id")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	ttl := time.Duration(req.DurationMinutes) * time.Minute
+	session, token, err := h.impersonationService.Start(ctx, impersonatorID, impersonatorTenantID, tenantID, targetUserID, req.Reason, ttl)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, StartImpersonationResponse{
+		Session: session,
+		Token:   token,
+	})
+}
+
+// EndImpersonation ends a session and notifies the tenant admins with an activity summary
+func (h *ImpersonationHandlers) EndImpersonation(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("platform:impersonate")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid session ID format")
+	}
+
+	impersonatorID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	summary, err := h.impersonationService.End(ctx, sessionID, impersonatorID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+// ListImpersonations lists sessions started by the current platform admin
+func (h *ImpersonationHandlers) ListImpersonations(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("platform:impersonate")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	impersonatorID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.impersonationService.ListByImpersonator(ctx, impersonatorID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list impersonation sessions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"sessions":    sessions,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(sessions)),
+	})
+}
+
+// ListTenantImpersonations lets tenant admins see who accessed their account
+func (h *ImpersonationHandlers) ListTenantImpersonations(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("audit:read")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.impersonationService.ListByTenant(ctx, tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list impersonation sessions")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"sessions":    sessions,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(sessions)),
+	})
+}
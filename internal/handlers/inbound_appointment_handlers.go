@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// InboundAppointmentHandlers handles booking supplier deliveries into
+// warehouse dock slots and the daily receiving schedule
+type InboundAppointmentHandlers struct {
+	appointmentService services.InboundAppointmentService
+	rbacMiddleware     *middleware.RBACMiddleware
+}
+
+// NewInboundAppointmentHandlers creates a new inbound appointment handlers instance
+func NewInboundAppointmentHandlers(appointmentService services.InboundAppointmentService, rbacMiddleware *middleware.RBACMiddleware) *InboundAppointmentHandlers {
+	return &InboundAppointmentHandlers{
+		appointmentService: appointmentService,
+		rbacMiddleware:     rbacMiddleware,
+	}
+}
+
+func (h *InboundAppointmentHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// appointmentError maps inbound appointment service errors to HTTP errors
+func appointmentError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrAppointmentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Appointment not found")
+	case errors.Is(err, services.ErrInvalidAppointment):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrAppointmentConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// BookAppointment handles POST /warehouses/:id/appointments
+func (h *InboundAppointmentHandlers) BookAppointment(c echo.Context) error {
+	if err := h.requirePermission(c, "appointments:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	warehouseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+	var bookedBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		bookedBy = &userID
+	}
+
+	var req services.BookAppointmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	appointment, err := h.appointmentService.Book(ctx, tenantID, warehouseID, bookedBy, &req)
+	if err != nil {
+		return appointmentError(err, "Failed to book appointment")
+	}
+
+	return c.JSON(http.StatusCreated, appointment)
+}
+
+// GetSchedule handles GET /warehouses/:id/appointments?date=YYYY-MM-DD&include_cancelled=,
+// the date being in the tenant's timezone
+func (h *InboundAppointmentHandlers) GetSchedule(c echo.Context) error {
+	if err := h.requirePermission(c, "appointments:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	warehouseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+
+	schedule, err := h.appointmentService.DailySchedule(ctx, tenantID, warehouseID, c.QueryParam("date"), c.QueryParam("include_cancelled") == "true")
+	if err != nil {
+		return appointmentError(err, "Failed to retrieve schedule")
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// GetAppointment handles GET /appointments/:id
+func (h *InboundAppointmentHandlers) GetAppointment(c echo.Context) error {
+	if err := h.requirePermission(c, "appointments:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid appointment ID format")
+	}
+
+	appointment, err := h.appointmentService.Get(ctx, tenantID, id)
+	if err != nil {
+		return appointmentError(err, "Failed to retrieve appointment")
+	}
+
+	return c.JSON(http.StatusOK, appointment)
+}
+
+// RescheduleAppointment handles PUT /appointments/:id
+func (h *InboundAppointmentHandlers) RescheduleAppointment(c echo.Context) error {
+	if err := h.requirePermission(c, "appointments:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid appointment ID format")
+	}
+
+	var req services.RescheduleAppointmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	appointment, err := h.appointmentService.Reschedule(ctx, tenantID, id, &req)
+	if err != nil {
+		return appointmentError(err, "Failed to reschedule appointment")
+	}
+
+	return c.JSON(http.StatusOK, appointment)
+}
+
+// CancelAppointment handles POST /appointments/:id/cancel
+func (h *InboundAppointmentHandlers) CancelAppointment(c echo.Context) error {
+	if err := h.requirePermission(c, "appointments:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid appointment ID format")
+	}
+
+	var req struct {
+		Reason *string `json:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	appointment, err := h.appointmentService.Cancel(ctx, tenantID, id, req.Reason)
+	if err != nil {
+		return appointmentError(err, "Failed to cancel appointment")
+	}
+
+	return c.JSON(http.StatusOK, appointment)
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxInboundWebhookPayload caps inbound webhook bodies
+const maxInboundWebhookPayload = 1 << 20
+
+// InboundWebhookHandlers handles inbound webhook endpoints, their delivery
+// log and the deliveries pushed to them
+type InboundWebhookHandlers struct {
+	inboundWebhookSvc services.InboundWebhookService
+	rbacMiddleware    *middleware.RBACMiddleware
+}
+
+// NewInboundWebhookHandlers creates a new inbound webhook handlers instance
+func NewInboundWebhookHandlers(inboundWebhookSvc services.InboundWebhookService, rbacMiddleware *middleware.RBACMiddleware) *InboundWebhookHandlers {
+	return &InboundWebhookHandlers{
+		inboundWebhookSvc: inboundWebhookSvc,
+		rbacMiddleware:    rbacMiddleware,
+	}
+}
+
+func (h *InboundWebhookHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// inboundWebhookError maps inbound webhook service errors to HTTP errors
+func inboundWebhookError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInboundWebhookNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Inbound webhook not found")
+	case errors.Is(err, services.ErrInvalidInboundWebhook):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInboundWebhookConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+func inboundWebhookURL(webhook *models.InboundWebhook) string {
+	return "/v1/hooks/" + webhook.Slug
+}
+
+// ListInboundWebhooks handles GET /inbound-webhooks
+func (h *InboundWebhookHandlers) ListInboundWebhooks(c echo.Context) error {
+	if err := h.requirePermission(c, "webhooks:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	webhooks, err := h.inboundWebhookSvc.ListWebhooks(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list inbound webhooks")
+	}
+	if webhooks == nil {
+		webhooks = []*models.InboundWebhook{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"webhooks": webhooks})
+}
+
+// GetInboundWebhook handles GET /inbound-webhooks/:id
+func (h *InboundWebhookHandlers) GetInboundWebhook(c echo.Context) error {
+	if err := h.requirePermission(c, "webhooks:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	webhook, err := h.inboundWebhookSvc.GetWebhook(ctx, tenantID, id)
+	if err != nil {
+		return inboundWebhookError(err, "Failed to get inbound webhook")
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// CreateInboundWebhook handles POST /inbound-webhooks
+func (h *InboundWebhookHandlers) CreateInboundWebhook(c echo.Context) error {
+	if err := h.requirePermission(c, "webhooks:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.InboundWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	webhook, secret, err := h.inboundWebhookSvc.CreateWebhook(ctx, tenantID, &req)
+	if err != nil {
+		return inboundWebhookError(err, "Failed to create inbound webhook")
+	}
+
+	// The secret is only ever returned here and on rotation
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"webhook": webhook,
+		"secret":  secret,
+		"url":     inboundWebhookURL(webhook),
+	})
+}
+
+// UpdateInboundWebhook handles PUT /inbound-webhooks/:id
+func (h *InboundWebhookHandlers) UpdateInboundWebhook(c echo.Context) error {
+	if err := h.requirePermission(c, "webhooks:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	var req models.InboundWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	webhook, err := h.inboundWebhookSvc.UpdateWebhook(ctx, tenantID, id, &req)
+	if err != nil {
+		return inboundWebhookError(err, "Failed to update inbound webhook")
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// RotateInboundWebhookSecret handles POST /inbound-webhooks/:id/rotate-secret.
+// The old secret stops working immediately
+func (h *InboundWebhookHandlers) RotateInboundWebhookSecret(c echo.Context) error {
+	if err := h.requirePermission(c, "webhooks:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	secret, err := h.inboundWebhookSvc.RotateSecret(ctx, tenantID, id)
+	if err != nil {
+		return inboundWebhookError(err, "Failed to rotate webhook secret")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"secret": secret})
+}
+
+// DeleteInboundWebhook handles DELETE /inbound-webhooks/:id
+func (h *InboundWebhookHandlers) DeleteInboundWebhook(c echo.Context) error {
+	if err := h.requirePermission(c, "webhooks:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	if err := h.inboundWebhookSvc.DeleteWebhook(ctx, tenantID, id); err != nil {
+		return inboundWebhookError(err, "Failed to delete inbound webhook")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListInboundDeliveries handles GET /inbound-webhooks/:id/deliveries?status=
+func (h *InboundWebhookHandlers) ListInboundDeliveries(c echo.Context) error {
+	if err := h.requirePermission(c, "webhooks:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	status := c.QueryParam("status")
+	switch status {
+	case "", models.InboundDeliveryProcessed, models.InboundDeliveryDuplicate, models.InboundDeliveryRejected, models.InboundDeliveryFailed:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "status must be processed, duplicate, rejected or failed")
+	}
+
+	deliveries, err := h.inboundWebhookSvc.ListDeliveries(ctx, tenantID, id, status, page.Limit, page.Offset)
+	if err != nil {
+		return inboundWebhookError(err, "Failed to list webhook deliveries")
+	}
+	if deliveries == nil {
+		deliveries = []*models.InboundWebhookDelivery{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"deliveries":  deliveries,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(deliveries)),
+	})
+}
+
+// ReceiveWebhook handles POST /hooks/:slug. Requests carry no session; they
+// are verified with the webhook's secret in its signature header
+func (h *InboundWebhookHandlers) ReceiveWebhook(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxInboundWebhookPayload+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+	}
+	if len(body) > maxInboundWebhookPayload {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Webhook payload too large")
+	}
+
+	delivery, err := h.inboundWebhookSvc.Receive(c.Request().Context(), c.Param("slug"), c.Request().Header, body, c.RealIP())
+	if err != nil {
+		if errors.Is(err, services.ErrInboundWebhookNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Webhook not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to process webhook")
+	}
+
+	response := map[string]interface{}{
+		"delivery_id": delivery.ID,
+		"status":      delivery.Status,
+	}
+	if delivery.OrderID != nil {
+		response["order_id"] = delivery.OrderID
+	}
+	if delivery.InvoiceID != nil {
+		response["invoice_id"] = delivery.InvoiceID
+	}
+
+	switch delivery.Status {
+	case models.InboundDeliveryRejected:
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid webhook signature")
+	case models.InboundDeliveryFailed:
+		response["error"] = delivery.Error
+		return c.JSON(http.StatusUnprocessableEntity, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/jobs"
+	"agromart2/internal/middleware"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// IntegrityHandlers runs data integrity checks on tenants
+type IntegrityHandlers struct {
+	integrity      *jobs.IntegrityCheckService
+	tenantSvc      services.TenantService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewIntegrityHandlers creates a new integrity handlers instance
+func NewIntegrityHandlers(integrity *jobs.IntegrityCheckService, tenantSvc services.TenantService, rbacMiddleware *middleware.RBACMiddleware) *IntegrityHandlers {
+	return &IntegrityHandlers{
+		integrity:      integrity,
+		tenantSvc:      tenantSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *IntegrityHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// CheckTenant handles POST /admin/tenants/:tenant_id/integrity-check?auto_fix=true,
+// scanning the tenant and, with auto_fix, applying the safe fixes (platform admin only)
+func (h *IntegrityHandlers) CheckTenant(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:check_integrity"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+	if tenant, err := h.tenantSvc.GetByID(ctx, tenantID); err != nil || tenant == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Tenant not found")
+	}
+
+	report, err := h.integrity.Check(ctx, tenantID, c.QueryParam("auto_fix") == "true")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check tenant data")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
+	"agromart2/internal/repositories"
 	"agromart2/internal/services"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -24,12 +27,6 @@ func NewInventoryHandlers(inventoryService services.InventoryService, rbacMiddle
 	}
 }
 
-// ListInventoriesRequest represents query parameters for listing inventories
-type ListInventoriesRequest struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
-}
-
 // ListInventories handles getting a list of inventories with tenant filtering
 func (h *InventoryHandlers) ListInventories(c echo.Context) error {
 	// Use RBAC middleware directly
@@ -42,38 +39,28 @@ func (h *InventoryHandlers) ListInventories(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	var req ListInventoriesRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
-	}
-
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
-	if req.Limit > 100 {
-		req.Limit = 100 // Maximum limit
-	}
-	if req.Offset < 0 {
-		req.Offset = 0
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.InventorySortColumns, DefaultSort: "-last_updated"})
+	if err != nil {
+		return err
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	// Get inventories from the tenant
-	inventories, err := h.inventoryService.List(ctx, tenantID, req.Limit, req.Offset)
+	inventories, err := h.inventoryService.List(ctx, tenantID, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list inventories")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"inventories": inventories,
-		"limit":       req.Limit,
-		"offset":      req.Offset,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(inventories)),
 	})
 }
 
@@ -107,7 +94,7 @@ func (h *InventoryHandlers) CreateInventory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -149,7 +136,7 @@ func (h *InventoryHandlers) GetInventory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -160,7 +147,43 @@ func (h *InventoryHandlers) GetInventory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Inventory not found")
 	}
 
-	return c.JSON(http.StatusOK, inventory)
+	return jsonWithETag(c, resourceETag(inventory.ID, inventory.LastUpdated), inventory)
+}
+
+// BatchGetInventories handles POST /inventory/batch-get
+func (h *InventoryHandlers) BatchGetInventories(c echo.Context) error {
+	if err := h.rbacMiddleware.RequirePermission("inventories:read")(func(c echo.Context) error {
+		return nil
+	})(c); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	ids, err := bindBatchGetIDs(c)
+	if err != nil {
+		return err
+	}
+
+	inventories, err := h.inventoryService.GetByIDs(ctx, tenantID, ids)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve inventory records")
+	}
+
+	found := make(map[uuid.UUID]bool, len(inventories))
+	for _, inventory := range inventories {
+		found[inventory.ID] = true
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"inventories": inventories,
+		"missing":     missingIDs(ids, found),
+	})
 }
 
 // UpdateInventoryRequest represents the inventory update request payload
@@ -203,7 +226,7 @@ func (h *InventoryHandlers) UpdateInventory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -213,6 +236,9 @@ func (h *InventoryHandlers) UpdateInventory(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Inventory not found")
 	}
+	if ctx, err = checkIfMatch(ctx, c, resourceETag(inventory.ID, inventory.LastUpdated), inventory.ID, inventory.LastUpdated); err != nil {
+		return err
+	}
 
 	// Update fields if provided
 	if req.WarehouseID != nil {
@@ -226,6 +252,9 @@ func (h *InventoryHandlers) UpdateInventory(c echo.Context) error {
 	}
 
 	if err := h.inventoryService.Update(ctx, tenantID, inventory); err != nil {
+		if errors.Is(err, repositories.ErrPreconditionFailed) {
+			return modifiedError()
+		}
 		// Handle unique constraint violation
 		if err.Error() == "UNIQUE constraint failed" || err.Error() == "pq: duplicate key value violates unique constraint" {
 			return echo.NewHTTPError(http.StatusConflict, "Inventory record already exists for this warehouse and product combination")
@@ -259,7 +288,7 @@ func (h *InventoryHandlers) DeleteInventory(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -304,7 +333,7 @@ func (h *InventoryHandlers) AdjustStock(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -347,7 +376,7 @@ func (h *InventoryHandlers) CheckAvailability(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -400,12 +429,15 @@ func (h *InventoryHandlers) TransferStock(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	if err := h.inventoryService.Transfer(ctx, tenantID, req.ProductID, req.FromWarehouseID, req.ToWarehouseID, req.Quantity); err != nil {
+		if errors.Is(err, services.ErrUnsuitableWarehouse) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -432,7 +464,7 @@ func (h *InventoryHandlers) SearchInventories(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
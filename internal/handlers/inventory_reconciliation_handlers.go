@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// InventoryReconciliationHandlers handles reconciling inventory against
+// stock snapshots from an external WMS
+type InventoryReconciliationHandlers struct {
+	reconciliationService services.InventoryReconciliationService
+	delegations           services.ApprovalDelegationService
+	rbacMiddleware        *middleware.RBACMiddleware
+}
+
+// NewInventoryReconciliationHandlers creates a new inventory reconciliation handlers instance
+func NewInventoryReconciliationHandlers(reconciliationService services.InventoryReconciliationService, delegations services.ApprovalDelegationService, rbacMiddleware *middleware.RBACMiddleware) *InventoryReconciliationHandlers {
+	return &InventoryReconciliationHandlers{
+		reconciliationService: reconciliationService,
+		delegations:           delegations,
+		rbacMiddleware:        rbacMiddleware,
+	}
+}
+
+func (h *InventoryReconciliationHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+func reconciliationError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidReconciliation):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrReconciliationNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Inventory reconciliation not found")
+	case errors.Is(err, services.ErrReconciliationResolved):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// CreateReconciliation handles POST /inventory/reconciliations, diffing an
+// external stock snapshot against inventory into a report for review
+func (h *InventoryReconciliationHandlers) CreateReconciliation(c echo.Context) error {
+	if err := h.requirePermission(c, "inventories:reconcile"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	var createdBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		createdBy = &userID
+	}
+
+	var req models.InventoryReconciliationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	rec, err := h.reconciliationService.Reconcile(ctx, tenantID, createdBy, &req)
+	if err != nil {
+		return reconciliationError(err, "Failed to reconcile inventory")
+	}
+
+	return c.JSON(http.StatusCreated, rec)
+}
+
+// ListReconciliations handles GET /inventory/reconciliations?status=&limit=&offset=
+func (h *InventoryReconciliationHandlers) ListReconciliations(c echo.Context) error {
+	if err := h.requirePermission(c, "inventories:reconcile"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50})
+	if err != nil {
+		return err
+	}
+
+	recs, err := h.reconciliationService.ListReconciliations(ctx, tenantID, c.QueryParam("status"), page.Limit, page.Offset)
+	if err != nil {
+		return reconciliationError(err, "Failed to retrieve inventory reconciliations")
+	}
+	if recs == nil {
+		recs = []*models.InventoryReconciliation{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"reconciliations": recs,
+		"next_cursor":     page.NextCursor(len(recs)),
+	})
+}
+
+// GetReconciliation handles GET /inventory/reconciliations/:id
+func (h *InventoryReconciliationHandlers) GetReconciliation(c echo.Context) error {
+	if err := h.requirePermission(c, "inventories:reconcile"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid reconciliation ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	rec, err := h.reconciliationService.GetReconciliation(ctx, tenantID, id)
+	if err != nil {
+		return reconciliationError(err, "Failed to retrieve inventory reconciliation")
+	}
+
+	return c.JSON(http.StatusOK, rec)
+}
+
+// ApplyReconciliation handles POST /inventory/reconciliations/:id/apply with
+// the approved line_ids; without any every line is applied
+func (h *InventoryReconciliationHandlers) ApplyReconciliation(c echo.Context) error {
+	delegation, err := requireApprover(c, h.rbacMiddleware, h.delegations, models.ApprovalKindStockAdjustment, "inventories:reconcile")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid reconciliation ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	var appliedBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		appliedBy = &userID
+	}
+
+	var req struct {
+		LineIDs []uuid.UUID `json:"line_ids"`
+	}
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+		}
+	}
+
+	rec, err := h.reconciliationService.Apply(ctx, tenantID, id, appliedBy, req.LineIDs)
+	if err != nil {
+		return reconciliationError(err, "Failed to apply inventory reconciliation")
+	}
+	recordDelegatedApproval(c, h.delegations, delegation, models.ApprovalKindStockAdjustment, id, "applied")
+
+	return c.JSON(http.StatusOK, rec)
+}
+
+// DiscardReconciliation handles POST /inventory/reconciliations/:id/discard
+func (h *InventoryReconciliationHandlers) DiscardReconciliation(c echo.Context) error {
+	delegation, err := requireApprover(c, h.rbacMiddleware, h.delegations, models.ApprovalKindStockAdjustment, "inventories:reconcile")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid reconciliation ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	var discardedBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		discardedBy = &userID
+	}
+
+	rec, err := h.reconciliationService.Discard(ctx, tenantID, id, discardedBy)
+	if err != nil {
+		return reconciliationError(err, "Failed to discard inventory reconciliation")
+	}
+	recordDelegatedApproval(c, h.delegations, delegation, models.ApprovalKindStockAdjustment, id, "discarded")
+
+	return c.JSON(http.StatusOK, rec)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// InvoiceAmendmentHandlers handles cancelling and reissuing invoices
+type InvoiceAmendmentHandlers struct {
+	amendmentSvc   services.InvoiceAmendmentService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewInvoiceAmendmentHandlers creates a new invoice amendment handlers instance
+func NewInvoiceAmendmentHandlers(amendmentSvc services.InvoiceAmendmentService, rbacMiddleware *middleware.RBACMiddleware) *InvoiceAmendmentHandlers {
+	return &InvoiceAmendmentHandlers{
+		amendmentSvc:   amendmentSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *InvoiceAmendmentHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// invoiceAmendmentError maps invoice amendment service errors to HTTP errors
+func invoiceAmendmentError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrAmendmentInvoiceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Invoice not found")
+	case errors.Is(err, services.ErrInvalidInvoiceAmendment):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInvoiceAmendmentConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// AmendInvoice handles POST /invoices/:id/amend
+func (h *InvoiceAmendmentHandlers) AmendInvoice(c echo.Context) error {
+	if err := h.requirePermission(c, "invoices:amend"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID format")
+	}
+
+	var req models.InvoiceAmendmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var amendedBy *uuid.UUID
+	if userID, ok := rc.User(); ok {
+		amendedBy = &userID
+	}
+
+	amendment, err := h.amendmentSvc.AmendInvoice(ctx, tenantID, invoiceID, amendedBy, &req)
+	if err != nil {
+		return invoiceAmendmentError(err, "Failed to amend invoice")
+	}
+
+	return c.JSON(http.StatusCreated, amendment)
+}
+
+// ListInvoiceAmendments handles GET /invoices/:id/amendments
+func (h *InvoiceAmendmentHandlers) ListInvoiceAmendments(c echo.Context) error {
+	if err := h.requirePermission(c, "invoices:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID format")
+	}
+
+	amendments, err := h.amendmentSvc.ListAmendments(ctx, tenantID, invoiceID)
+	if err != nil {
+		return invoiceAmendmentError(err, "Failed to retrieve invoice amendments")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"amendments": amendments,
+	})
+}
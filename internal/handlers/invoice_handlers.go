@@ -2,18 +2,18 @@ package handlers
 
 import (
 	"agromart2/internal/common"
-	"bytes"
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
+	"agromart2/internal/jobs"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
+	"agromart2/internal/repositories"
 	"agromart2/internal/services"
 
 	"github.com/google/uuid"
-	"github.com/jung-kurt/gofpdf"
 	"github.com/labstack/echo/v4"
 )
 
@@ -21,17 +21,19 @@ import (
 type InvoiceHandlers struct {
 	invoiceService services.InvoiceServiceInterface
 	orderService   services.OrderServiceInterface
-	productService services.ProductService
-	minioSvc       services.MinioService
+	exportSvc      services.ExportInvoiceService
+	upiSvc         services.UPIPaymentService
+	archive        *jobs.InvoiceArchiveService
 }
 
 // NewInvoiceHandlers creates a new invoice handlers instance
-func NewInvoiceHandlers(invoiceService services.InvoiceServiceInterface, orderService services.OrderServiceInterface, productService services.ProductService, minioSvc services.MinioService) *InvoiceHandlers {
+func NewInvoiceHandlers(invoiceService services.InvoiceServiceInterface, orderService services.OrderServiceInterface, exportSvc services.ExportInvoiceService, upiSvc services.UPIPaymentService, archive *jobs.InvoiceArchiveService) *InvoiceHandlers {
 	return &InvoiceHandlers{
 		invoiceService: invoiceService,
 		orderService:   orderService,
-		productService: productService,
-		minioSvc:       minioSvc,
+		exportSvc:      exportSvc,
+		upiSvc:         upiSvc,
+		archive:        archive,
 	}
 }
 
@@ -41,7 +43,7 @@ func (h *InvoiceHandlers) CreateInvoice(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Extract tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
@@ -129,45 +131,89 @@ func (h *InvoiceHandlers) CreateInvoice(c echo.Context) error {
 	invoice.IGST = nil // Assuming intra-state for now
 
 	if err := h.invoiceService.CreateInvoice(ctx, invoice); err != nil {
+		if errors.Is(err, services.ErrPeriodClosed) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return common.SendServerError(c, "Failed to create invoice: " + err.Error())
 	}
 
 	return c.JSON(http.StatusCreated, invoice)
 }
 
+// invoiceWriteError maps a failed invoice change to an HTTP error, telling
+// invoices locked in a closed period apart from failures
+func invoiceWriteError(err error) error {
+	if errors.Is(err, services.ErrPeriodClosed) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if errors.Is(err, repositories.ErrPreconditionFailed) {
+		return modifiedError()
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
+
 // GetInvoices handles GET /invoices
 func (h *InvoiceHandlers) GetInvoices(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
 
-	limit := 10
-	offset := 0
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.InvoiceSortColumns, DefaultSort: "-issued_date"})
+	if err != nil {
+		return err
+	}
+	branchID, err := parseBranchFilter(c)
+	if err != nil {
+		return err
+	}
 
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			limit = l
-		}
+	invoices, err := h.invoiceService.ListInvoices(ctx, tenantID, branchID, page)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"invoices":    invoices,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(invoices)),
+	})
+}
+
+// GetGSTReport handles GET /reports/gst?month=2025-08&branch_id=, the month's
+// GST documents grouped by the issuing GSTIN
+func (h *InvoiceHandlers) GetGSTReport(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return common.SendUnauthorizedError(c)
 	}
 
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
+	period := time.Now()
+	if month := c.QueryParam("month"); month != "" {
+		parsed, err := time.Parse("2006-01", month)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "month must be in YYYY-MM format")
 		}
+		period = parsed
+	}
+	branchID, err := parseBranchFilter(c)
+	if err != nil {
+		return err
 	}
 
-	invoices, err := h.invoiceService.ListInvoices(ctx, tenantID, limit, offset)
+	reports, err := h.invoiceService.GSTReport(ctx, tenantID, branchID, period)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"invoices": invoices,
-		"limit":    limit,
-		"offset":   offset,
+		"period": period.Format("2006-01"),
+		"gstins": reports,
 	})
 }
 
@@ -186,7 +232,7 @@ func (h *InvoiceHandlers) GetInvoiceByID(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
@@ -200,7 +246,7 @@ func (h *InvoiceHandlers) GetInvoiceByID(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Invoice not found")
 	}
 
-	return c.JSON(http.StatusOK, invoice)
+	return jsonWithETag(c, resourceETag(invoice.ID, invoice.UpdatedAt), invoice)
 }
 
 // GetInvoice handles GET /invoices/:id (alias for GetInvoiceByID)
@@ -218,13 +264,14 @@ func (h *InvoiceHandlers) UpdateInvoiceStatus(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
 
 	var req struct {
-		Status string `json:"status"`
+		Status  string  `json:"status"`
+		Comment *string `json:"comment"`
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -235,8 +282,9 @@ func (h *InvoiceHandlers) UpdateInvoiceStatus(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid status. Must be unpaid, paid, overdue, or cancelled")
 	}
 
+	ctx = withStatusComment(ctx, req.Comment)
 	if err := h.invoiceService.UpdateInvoiceStatus(ctx, tenantID, invoiceID, req.Status); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return invoiceWriteError(err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -254,27 +302,54 @@ func (h *InvoiceHandlers) UpdateInvoice(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
 
 	var req struct {
-		Status string  `json:"status"`
-		GSTIN  *string `json:"gstin"`
+		Status  string  `json:"status"`
+		GSTIN   *string `json:"gstin"`
+		Comment *string `json:"comment"`
 	}
 
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
 	}
 
+	// The GSTIN is printed on the archived PDF, which is never regenerated
+	if req.GSTIN != nil {
+		doc, err := h.archive.Get(ctx, tenantID, invoiceID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if doc != nil {
+			return echo.NewHTTPError(http.StatusConflict, "The invoice has been issued; amend it to change the GSTIN")
+		}
+	}
+
+	// The status and GSTIN are written separately, so check the precondition
+	// against the invoice as it stood before either write
+	if c.Request().Header.Get("If-Match") != "" {
+		current, err := h.invoiceService.GetInvoiceByID(ctx, tenantID, invoiceID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if current == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "Invoice not found")
+		}
+		if ctx, err = checkIfMatch(ctx, c, resourceETag(current.ID, current.UpdatedAt), current.ID, current.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
 	// Update status if provided
 	if req.Status != "" {
 		if req.Status != "unpaid" && req.Status != "paid" && req.Status != "overdue" && req.Status != "cancelled" {
 			return echo.NewHTTPError(http.StatusBadRequest, "Invalid status. Must be unpaid, paid, overdue, or cancelled")
 		}
-		if err := h.invoiceService.UpdateInvoiceStatus(ctx, tenantID, invoiceID, req.Status); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		if err := h.invoiceService.UpdateInvoiceStatus(withStatusComment(ctx, req.Comment), tenantID, invoiceID, req.Status); err != nil {
+			return invoiceWriteError(err)
 		}
 	}
 
@@ -291,7 +366,7 @@ func (h *InvoiceHandlers) UpdateInvoice(c echo.Context) error {
 		invoice.GSTIN = req.GSTIN
 		invoice.UpdatedAt = time.Now()
 		if err := h.invoiceService.UpdateInvoice(ctx, invoice); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			return invoiceWriteError(err)
 		}
 	}
 
@@ -310,7 +385,7 @@ func (h *InvoiceHandlers) DeleteInvoice(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
@@ -330,7 +405,7 @@ func (h *InvoiceHandlers) DeleteInvoice(c echo.Context) error {
 	}
 
 	if err := h.invoiceService.DeleteInvoice(ctx, tenantID, invoiceID); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return invoiceWriteError(err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
@@ -342,222 +417,79 @@ func (h *InvoiceHandlers) DeleteInvoice(c echo.Context) error {
 func (h *InvoiceHandlers) GetUnpaidInvoices(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
 
-	limit := 10
-	offset := 0
-
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			limit = l
-		}
-	}
-
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
 	}
 
-	invoices, err := h.invoiceService.GetUnpaidInvoices(ctx, tenantID, limit, offset)
+	invoices, err := h.invoiceService.GetUnpaidInvoices(ctx, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"invoices": invoices,
-		"limit":    limit,
-		"offset":   offset,
+		"invoices":    invoices,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(invoices)),
 	})
 }
 
-// generateInvoicePDF creates a professional PDF invoice
-func (h *InvoiceHandlers) generateInvoicePDF(ctx context.Context, invoice *models.Invoice, order *models.Order, tenantID uuid.UUID) ([]byte, error) {
-	// Get product details for the order
-	product, err := h.productService.GetByID(ctx, tenantID, order.ProductID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get product details: %w", err)
-	}
-
-	// Create new PDF
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
-
-	// Set margins
-	marginX := 20.0
-	marginY := 20.0
-	pdf.SetMargins(marginX, marginY, marginX)
-	pdf.SetAutoPageBreak(true, marginY)
-
-	// Set fonts
-	pdf.SetFont("Arial", "B", 16)
-	pdf.SetTextColor(33, 37, 41) // Dark gray
-
-	// Company header
-	pdf.SetXY(marginX, marginY)
-	pdf.Cell(0, 10, "AGROMART INVOICE")
-	pdf.Ln(15)
-
-	// Invoice details
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Cell(0, 8, fmt.Sprintf("Invoice Number: %s", invoice.ID.String()))
-	pdf.Ln(8)
-	pdf.Cell(0, 8, fmt.Sprintf("Invoice Date: %s", invoice.IssuedDate.Format("02-Jan-2006")))
-	pdf.Ln(8)
-	pdf.Cell(0, 8, fmt.Sprintf("Order ID: %s", order.ID.String()))
-	pdf.Ln(8)
-
-	// GSTIN if provided
-	if invoice.GSTIN != nil && *invoice.GSTIN != "" {
-		pdf.Cell(0, 8, fmt.Sprintf("GSTIN: %s", *invoice.GSTIN))
-		pdf.Ln(8)
-	}
-
-	pdf.Ln(5)
-
-	// Billing Information section
-	pdf.SetFont("Arial", "B", 11)
-	pdf.Cell(0, 8, "BILL TO:")
-	pdf.Ln(6)
-
-	pdf.SetFont("Arial", "", 10)
-	pdf.Cell(0, 6, "Agromart Customer")
-	pdf.Ln(6)
-	pdf.Cell(0, 6, "Address: To be configured")
-	pdf.Ln(6)
-	pdf.Cell(0, 6, "Contact: support@agromart.com")
-	pdf.Ln(10)
-
-	// Items table header
-	pdf.SetFont("Arial", "B", 10)
-	pdf.SetFillColor(240, 240, 240) // Light gray background
-
-	// Table headers
-	headers := []string{"Description", "Qty", "Rate", "Amount"}
-	colWidths := []float64{80, 20, 30, 40}
-
-	for i, header := range headers {
-		pdf.CellFormat(colWidths[i], 8, header, "1", 0, "C", true, 0, "")
-	}
-	pdf.Ln(8)
-
-	// Item row
-	pdf.SetFont("Arial", "", 10)
-	pdf.SetFillColor(255, 255, 255) // White background
-
-	description := product.Name
-	if product.Description != nil && *product.Description != "" {
-		description += "\n" + *product.Description
-	}
-
-	pdf.CellFormat(colWidths[0], 8, description, "1", 0, "L", false, 0, "")
-	pdf.CellFormat(colWidths[1], 8, fmt.Sprintf("%d", order.Quantity), "1", 0, "C", false, 0, "")
-	pdf.CellFormat(colWidths[2], 8, fmt.Sprintf("%.2f", order.UnitPrice), "1", 0, "R", false, 0, "")
-	pdf.CellFormat(colWidths[3], 8, fmt.Sprintf("%.2f", float64(order.Quantity)*order.UnitPrice), "1", 0, "R", false, 0, "")
-	pdf.Ln(8)
-
-	// Empty rows for future multiple items
-	for i := 0; i < 3; i++ {
-		for j, width := range colWidths {
-			border := "1"
-			if j == len(colWidths)-1 {
-				border = "1" // Last column
-			}
-			pdf.CellFormat(width, 8, "", border, 0, "C", false, 0, "")
-		}
-		pdf.Ln(8)
-	}
-
-	pdf.Ln(5)
-
-	// GST and totals section
-	pdf.SetFont("Arial", "B", 10)
-
-	// Subtotal
-	subtotal := float64(order.Quantity) * order.UnitPrice
-	pdf.CellFormat(130, 6, "Subtotal:", "", 0, "R", false, 0, "")
-	pdf.CellFormat(40, 6, fmt.Sprintf("%.2f", subtotal), "", 0, "R", false, 0, "")
-	pdf.Ln(6)
-
-	// GST breakdown
-	if invoice.CGST != nil && *invoice.CGST > 0 {
-		pdf.SetFont("Arial", "", 9)
-		pdf.CellFormat(130, 5, "CGST (9%):", "", 0, "R", false, 0, "")
-		pdf.CellFormat(40, 5, fmt.Sprintf("%.2f", *invoice.CGST), "", 0, "R", false, 0, "")
-		pdf.Ln(5)
-	}
-
-	if invoice.SGST != nil && *invoice.SGST > 0 {
-		pdf.CellFormat(130, 5, "SGST (9%):", "", 0, "R", false, 0, "")
-		pdf.CellFormat(40, 5, fmt.Sprintf("%.2f", *invoice.SGST), "", 0, "R", false, 0, "")
-		pdf.Ln(5)
-	}
+// GenerateInvoicePDF handles POST /invoices/:id/generate-pdf
+// Returns a download link to the invoice's archived PDF, archiving it first
+// when the background job has not got to it yet. An issued invoice's PDF is
+// never regenerated; amend the invoice to correct it
+func (h *InvoiceHandlers) GenerateInvoicePDF(c echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
 
-	if invoice.IGST != nil && *invoice.IGST > 0 {
-		pdf.CellFormat(130, 5, "IGST (18%):", "", 0, "R", false, 0, "")
-		pdf.CellFormat(40, 5, fmt.Sprintf("%.2f", *invoice.IGST), "", 0, "R", false, 0, "")
-		pdf.Ln(5)
+	invoiceID, err := uuid.Parse(id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
 	}
 
-	// Total
-	pdf.SetFont("Arial", "B", 11)
-	pdf.SetTextColor(220, 20, 60) // Red color for total
-	pdf.CellFormat(130, 8, "TOTAL:", "", 0, "R", false, 0, "")
-	pdf.CellFormat(40, 8, fmt.Sprintf("%.2f", invoice.TotalAmount), "", 0, "R", false, 0, "")
-	pdf.Ln(10)
-
-	// Terms and conditions
-	pdf.SetTextColor(33, 37, 41) // Reset to dark
-	pdf.SetFont("Arial", "B", 9)
-	pdf.Cell(0, 6, "Terms & Conditions:")
-	pdf.Ln(6)
-
-	pdf.SetFont("Arial", "", 8)
-	terms := []string{
-		"1. Payment is due within 30 days of invoice date",
-		"2. Late payments may incur additional charges",
-		"3. Goods once sold will not be taken back",
-		"4. This is a computer generated invoice",
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return common.SendUnauthorizedError(c)
 	}
 
-	for _, term := range terms {
-		pdf.Cell(0, 5, term)
-		pdf.Ln(5)
+	doc, err := h.archive.Archive(ctx, tenantID, invoiceID)
+	if err != nil {
+		if errors.Is(err, jobs.ErrInvoiceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Invoice not found")
+		}
+		return common.SendServerError(c, fmt.Sprintf("Failed to generate PDF: %v", err))
 	}
 
-	// Footer
-	pdf.Ln(10)
-	pdf.SetFont("Arial", "I", 8)
-	pdf.SetTextColor(128, 128, 128) // Gray
-	pdf.Cell(0, 5, "Thank you for your business!")
-	pdf.Ln(5)
-	pdf.Cell(0, 5, "For any queries, contact: support@agromart.com | +91-XXXXXXXXXX")
-
-	// Get PDF bytes
-	var buf bytes.Buffer
-	err = pdf.Output(&buf)
+	pdfURL, err := h.archive.DownloadURL(doc, 24*time.Hour)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+		return common.SendServerError(c, "Failed to generate download URL: "+err.Error())
 	}
 
-	return buf.Bytes(), nil
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "PDF generated and uploaded successfully",
+		"pdf_url":    pdfURL,
+		"expires_in": "24 hours",
+		"document":   doc,
+	})
 }
 
-// GenerateInvoicePDF handles POST /invoices/:id/generate-pdf
-// Generates and stores PDF invoice using MinIO
-func (h *InvoiceHandlers) GenerateInvoicePDF(c echo.Context) error {
+// GetInvoiceUPIQR handles GET /invoices/:id/upi-qr
+// Returns a PNG QR code with a UPI payment intent for the invoice total
+func (h *InvoiceHandlers) GetInvoiceUPIQR(c echo.Context) error {
 	ctx := c.Request().Context()
-	id := c.Param("id")
 
-	invoiceID, err := uuid.Parse(id)
+	invoiceID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return common.SendUnauthorizedError(c)
 	}
@@ -566,55 +498,29 @@ func (h *InvoiceHandlers) GenerateInvoicePDF(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
-
 	if invoice == nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Invoice not found")
 	}
 
-	// Get the associated order details
-	order, err := h.orderService.GetOrderByID(ctx, tenantID, invoice.OrderID)
+	export, err := h.exportSvc.GetExportDetails(ctx, tenantID, invoiceID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-	}
-
-	if order == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Order not found for this invoice")
-	}
-
-	// Generate PDF bytes with comprehensive error handling
-	pdfBytes, err := h.generateInvoicePDF(ctx, invoice, order, tenantID)
-	if err != nil {
-		return common.SendServerError(c, fmt.Sprintf("Failed to generate PDF: %v", err))
-	}
-
-	// Validate PDF was generated successfully
-	if len(pdfBytes) == 0 {
-		return common.SendServerError(c, "Generated PDF is empty")
+		return common.SendServerError(c, "Failed to retrieve export details")
 	}
-
-	// Store PDF in MinIO with retry logic consideration
-	bucketName := "invoices"
-	objectName := fmt.Sprintf("%s-%s.pdf", tenantID.String(), invoiceID.String())
-
-	err = h.minioSvc.UploadImage(ctx, bucketName, objectName, bytes.NewReader(pdfBytes), int64(len(pdfBytes)))
-	if err != nil {
-		return common.SendServerError(c, "Failed to upload PDF to storage: " + err.Error())
+	if export != nil {
+		return echo.NewHTTPError(http.StatusConflict, "Export invoices cannot be paid by UPI")
 	}
 
-	// Generate presigned URL for download with error handling
-	pdfURL, err := h.minioSvc.GetPresignedURL(bucketName, objectName, 24*time.Hour)
+	png, err := h.upiSvc.InvoiceQR(ctx, tenantID, invoice)
 	if err != nil {
-		return common.SendServerError(c, "Failed to generate download URL: " + err.Error())
-	}
-
-	// Validate the URL was generated
-	if pdfURL == "" {
-		return common.SendServerError(c, "Generated download URL is empty")
+		switch {
+		case errors.Is(err, services.ErrUPINotConfigured):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrInvoiceNotPayable):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return common.SendServerError(c, "Failed to generate UPI QR code")
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message":    "PDF generated and uploaded successfully",
-		"pdf_url":    pdfURL,
-		"expires_in": "24 hours",
-	})
-}
\ No newline at end of file
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, no-store")
+	return c.Blob(http.StatusOK, "image/png", png)
+}
@@ -51,7 +51,7 @@ func (h *JobHandlers) ExportInvoices(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -95,7 +95,7 @@ func (h *JobHandlers) ExportInvoices(c echo.Context) error {
 func (h *JobHandlers) ExportOrders(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -132,7 +132,7 @@ func (h *JobHandlers) ExportOrders(c echo.Context) error {
 func (h *JobHandlers) ImportData(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -163,7 +163,7 @@ func (h *JobHandlers) ImportData(c echo.Context) error {
 func (h *JobHandlers) GetInventoryAlerts(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -192,7 +192,7 @@ func (h *JobHandlers) GetInventoryAlerts(c echo.Context) error {
 func (h *JobHandlers) TriggerAnalyticsRefresh(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -210,12 +210,12 @@ func (h *JobHandlers) TriggerAnalyticsRefresh(c echo.Context) error {
 func (h *JobHandlers) GetAnalyticsData(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	analyticsService := analytics.NewAnalyticsService(h.orderRepo, h.invoiceRepo, h.inventoryRepo, h.productRepo, nil)
+	analyticsService := analytics.NewAnalyticsService(h.orderRepo, h.invoiceRepo, h.inventoryRepo, h.productRepo, nil, nil, nil, 0)
 	data, err := analyticsService.CalculateTenantAnalytics(ctx, tenantID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get analytics data")
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/middleware"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKSHandlers exposes the public signing keys, key rotation and revocation
+type JWKSHandlers struct {
+	keyRing        *services.KeyRing
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewJWKSHandlers creates a new JWKS handlers instance
+func NewJWKSHandlers(keyRing *services.KeyRing, rbacMiddleware *middleware.RBACMiddleware) *JWKSHandlers {
+	return &JWKSHandlers{
+		keyRing:        keyRing,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+// JWKS serves the public keys used to validate access tokens (no auth required)
+func (h *JWKSHandlers) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.keyRing.JWKS())
+}
+
+// RotateSigningKey generates a new signing key; the previous key stays valid until its tokens expire
+func (h *JWKSHandlers) RotateSigningKey(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("platform:manage_keys")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	key, err := h.keyRing.Rotate(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to rotate signing key")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Signing key rotated successfully",
+		"kid":     key.KID,
+	})
+}
+
+// RevokeSigningKey stops a compromised signing key verifying tokens; revoking
+// the active key rotates to a new one first
+func (h *JWKSHandlers) RevokeSigningKey(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("platform:manage_keys")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	kid := c.Param("kid")
+	if err := h.keyRing.Revoke(c.Request().Context(), kid); err != nil {
+		if errors.Is(err, services.ErrSigningKeyNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Signing key not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to revoke signing key")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Signing key revoked successfully",
+		"kid":     kid,
+	})
+}
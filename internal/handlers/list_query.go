@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/listquery"
+
+	"github.com/labstack/echo/v4"
+)
+
+// parseListQuery applies the shared paging and sort contract (see package
+// listquery) to a list request, answering 400 for invalid values
+func parseListQuery(c echo.Context, opts listquery.Options) (listquery.Page, error) {
+	page, err := listquery.Parse(c.QueryParams(), opts)
+	if err != nil {
+		return page, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return page, nil
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// MarginHandlers handles margin policy and margin violation report requests
+type MarginHandlers struct {
+	marginService  services.MarginService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewMarginHandlers creates a new margin handlers instance
+func NewMarginHandlers(marginService services.MarginService, rbacMiddleware *middleware.RBACMiddleware) *MarginHandlers {
+	return &MarginHandlers{
+		marginService:  marginService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+// GetMarginPolicy handles GET /pricing/margin-policy
+func (h *MarginHandlers) GetMarginPolicy(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("pricing:read")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	policy, err := h.marginService.GetPolicy(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve margin policy")
+	}
+	if policy == nil {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"enabled": false,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"enabled": true,
+		"policy":  policy,
+	})
+}
+
+// SetMarginPolicy handles PUT /pricing/margin-policy
+func (h *MarginHandlers) SetMarginPolicy(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("pricing:manage")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req struct {
+		MinMarginPercent float64 `json:"min_margin_percent"`
+		Enforcement      string  `json:"enforcement"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	policy, err := h.marginService.SetPolicy(ctx, tenantID, req.MinMarginPercent, req.Enforcement)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// DeleteMarginPolicy handles DELETE /pricing/margin-policy, turning margin checks off
+func (h *MarginHandlers) DeleteMarginPolicy(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("pricing:manage")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	if err := h.marginService.DeletePolicy(ctx, tenantID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete margin policy")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListMarginViolations handles GET /reports/margin-violations
+func (h *MarginHandlers) ListMarginViolations(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("pricing:read")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	filter := &models.MarginViolationFilter{}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	filter.Limit, filter.Offset = page.Limit, page.Offset
+
+	if from := c.QueryParam("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+		}
+		filter.From = &t
+	}
+	if to := c.QueryParam("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+		}
+		// Include the whole of the end day
+		t = t.Add(24*time.Hour - time.Nanosecond)
+		filter.To = &t
+	}
+	if productID := c.QueryParam("product_id"); productID != "" {
+		id, err := uuid.Parse(productID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+		}
+		filter.ProductID = &id
+	}
+	if outcome := c.QueryParam("outcome"); outcome != "" {
+		if outcome != models.MarginOutcomeBlocked && outcome != models.MarginOutcomeOverridden {
+			return echo.NewHTTPError(http.StatusBadRequest, "Outcome must be blocked or overridden")
+		}
+		filter.Outcome = &outcome
+	}
+
+	violations, err := h.marginService.ListViolations(ctx, tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve margin violations")
+	}
+	if violations == nil {
+		violations = []*models.MarginViolation{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"violations":  violations,
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+		"next_cursor": page.NextCursor(len(violations)),
+	})
+}
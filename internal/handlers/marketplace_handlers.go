@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxMarketplacePayload caps inbound marketplace order bodies
+const maxMarketplacePayload = 1 << 20
+
+// MarketplaceHandlers handles marketplace channels and inbound marketplace orders
+type MarketplaceHandlers struct {
+	marketplaceService services.MarketplaceService
+	rbacMiddleware     *middleware.RBACMiddleware
+}
+
+// NewMarketplaceHandlers creates a new marketplace handlers instance
+func NewMarketplaceHandlers(marketplaceService services.MarketplaceService, rbacMiddleware *middleware.RBACMiddleware) *MarketplaceHandlers {
+	return &MarketplaceHandlers{
+		marketplaceService: marketplaceService,
+		rbacMiddleware:     rbacMiddleware,
+	}
+}
+
+type marketplaceChannelRequest struct {
+	Name             string                         `json:"name"`
+	Protocol         string                         `json:"protocol"`
+	WarehouseID      string                         `json:"warehouse_id"`
+	DistributorID    string                         `json:"distributor_id"`
+	StatusWebhookURL *string                        `json:"status_webhook_url"`
+	FieldMapping     models.MarketplaceFieldMapping `json:"field_mapping"`
+	IsActive         *bool                          `json:"is_active"`
+}
+
+func (h *MarketplaceHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// applyChannelRequest copies the request onto the channel
+func applyChannelRequest(channel *models.MarketplaceChannel, req *marketplaceChannelRequest) error {
+	warehouseID, err := uuid.Parse(req.WarehouseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+	distributorID, err := uuid.Parse(req.DistributorID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid distributor ID format")
+	}
+
+	channel.Name = req.Name
+	channel.WarehouseID = warehouseID
+	channel.DistributorID = distributorID
+	channel.StatusWebhookURL = req.StatusWebhookURL
+	if channel.StatusWebhookURL != nil && strings.TrimSpace(*channel.StatusWebhookURL) == "" {
+		channel.StatusWebhookURL = nil
+	}
+	channel.FieldMapping = req.FieldMapping
+	if req.IsActive != nil {
+		channel.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// ListChannels handles GET /marketplaces/channels
+func (h *MarketplaceHandlers) ListChannels(c echo.Context) error {
+	if err := h.requirePermission(c, "marketplace:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	channels, err := h.marketplaceService.ListChannels(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve marketplace channels")
+	}
+	if channels == nil {
+		channels = []*models.MarketplaceChannel{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"channels": channels,
+	})
+}
+
+// CreateChannel handles POST /marketplaces/channels
+func (h *MarketplaceHandlers) CreateChannel(c echo.Context) error {
+	if err := h.requirePermission(c, "marketplace:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req marketplaceChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	channel := &models.MarketplaceChannel{TenantID: tenantID, Protocol: req.Protocol}
+	if err := applyChannelRequest(channel, &req); err != nil {
+		return err
+	}
+
+	secret, err := h.marketplaceService.CreateChannel(ctx, channel)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// The signing secret is only ever returned here
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"channel":        channel,
+		"signing_secret": secret,
+		"ingest_url":     "/v1/integrations/marketplaces/" + channel.ID.String() + "/orders",
+	})
+}
+
+// UpdateChannel handles PUT /marketplaces/channels/:id
+func (h *MarketplaceHandlers) UpdateChannel(c echo.Context) error {
+	if err := h.requirePermission(c, "marketplace:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid channel ID format")
+	}
+
+	channel, err := h.marketplaceService.GetChannel(ctx, tenantID, id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Marketplace channel not found")
+	}
+
+	var req marketplaceChannelRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if err := applyChannelRequest(channel, &req); err != nil {
+		return err
+	}
+
+	if err := h.marketplaceService.UpdateChannel(ctx, channel); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, channel)
+}
+
+// DeleteChannel handles DELETE /marketplaces/channels/:id
+func (h *MarketplaceHandlers) DeleteChannel(c echo.Context) error {
+	if err := h.requirePermission(c, "marketplace:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid channel ID format")
+	}
+
+	if err := h.marketplaceService.DeleteChannel(ctx, tenantID, id); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete marketplace channel")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListOrders handles GET /marketplaces/orders
+func (h *MarketplaceHandlers) ListOrders(c echo.Context) error {
+	if err := h.requirePermission(c, "marketplace:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	var channelID *uuid.UUID
+	if raw := c.QueryParam("channel_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid channel ID format")
+		}
+		channelID = &parsed
+	}
+
+	links, err := h.marketplaceService.ListOrderLinks(ctx, tenantID, channelID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve marketplace orders")
+	}
+	if links == nil {
+		links = []*models.MarketplaceOrderLink{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"orders":      links,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(links)),
+	})
+}
+
+// SyncStatuses handles POST /marketplaces/sync
+func (h *MarketplaceHandlers) SyncStatuses(c echo.Context) error {
+	if err := h.requirePermission(c, "marketplace:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	pushed, err := h.marketplaceService.SyncStatuses(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to sync marketplace order status")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"pushed": pushed,
+	})
+}
+
+// IngestOrder handles POST /integrations/marketplaces/:channel_id/orders.
+// Requests carry no session; they are signed with the channel's secret in
+// X-Marketplace-Signature (hex HMAC-SHA256 of the raw body).
+func (h *MarketplaceHandlers) IngestOrder(c echo.Context) error {
+	channelID, err := uuid.Parse(c.Param("channel_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Marketplace channel not found")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxMarketplacePayload+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+	}
+	if len(body) > maxMarketplacePayload {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Order payload too large")
+	}
+
+	signature := c.Request().Header.Get("X-Marketplace-Signature")
+	if signature == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Missing marketplace signature")
+	}
+
+	ctx := c.Request().Context()
+	channel, err := h.marketplaceService.AuthenticateChannel(ctx, channelID, body, signature)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid marketplace signature")
+	}
+
+	result, err := h.marketplaceService.IngestOrder(ctx, channel, body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	status := http.StatusCreated
+	if result.Duplicate {
+		status = http.StatusOK
+	}
+	return c.JSON(status, result)
+}
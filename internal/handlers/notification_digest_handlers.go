@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NotificationDigestHandlers handles daily and weekly notification digests.
+// Preferences, previews and delivery history are the signed-in user's own and
+// need no extra permission; the tenant's template and everyone's deliveries
+// need notifications:manage.
+type NotificationDigestHandlers struct {
+	digests        *jobs.DigestService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewNotificationDigestHandlers creates a new notification digest handlers instance
+func NewNotificationDigestHandlers(digests *jobs.DigestService, rbacMiddleware *middleware.RBACMiddleware) *NotificationDigestHandlers {
+	return &NotificationDigestHandlers{
+		digests:        digests,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *NotificationDigestHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetDigestPreference handles GET /notifications/digest
+func (h *NotificationDigestHandlers) GetDigestPreference(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	pref, err := h.digests.GetPreference(c.Request().Context(), tenantID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve digest preference")
+	}
+	return c.JSON(http.StatusOK, pref)
+}
+
+// UpdateDigestPreference handles PUT /notifications/digest. While a digest
+// is on, the push alerts its sections summarise are no longer sent to the user
+func (h *NotificationDigestHandlers) UpdateDigestPreference(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		Frequency string   `json:"frequency"`
+		Sections  []string `json:"sections"`
+		SendHour  *int     `json:"send_hour"`
+		WeeklyDay *int     `json:"weekly_day"`
+		Timezone  string   `json:"timezone"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	ctx := c.Request().Context()
+	pref, err := h.digests.GetPreference(ctx, tenantID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve digest preference")
+	}
+	pref.Frequency = req.Frequency
+	if req.Sections != nil {
+		pref.Sections = req.Sections
+	}
+	if req.SendHour != nil {
+		pref.SendHour = *req.SendHour
+	}
+	if req.WeeklyDay != nil {
+		pref.WeeklyDay = *req.WeeklyDay
+	}
+	if req.Timezone != "" {
+		pref.Timezone = req.Timezone
+	}
+
+	if err := h.digests.SavePreference(ctx, pref); err != nil {
+		if errors.Is(err, jobs.ErrInvalidDigest) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save digest preference")
+	}
+	return c.JSON(http.StatusOK, pref)
+}
+
+// PreviewDigest handles GET /notifications/digest/preview, rendering the
+// digest the user would get now without sending it
+func (h *NotificationDigestHandlers) PreviewDigest(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	email, err := h.digests.Preview(c.Request().Context(), tenantID, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to render digest: "+err.Error())
+	}
+	return c.JSON(http.StatusOK, email)
+}
+
+// ListDigestDeliveries handles GET /notifications/digest/deliveries; all=true
+// lists every user's deliveries and needs notifications:manage
+func (h *NotificationDigestHandlers) ListDigestDeliveries(c echo.Context) error {
+	tenantID, userID, err := deviceOwnerFromContext(c)
+	if err != nil {
+		return err
+	}
+	scope := &userID
+	if c.QueryParam("all") == "true" {
+		if err := h.requirePermission(c, "notifications:manage"); err != nil {
+			return err
+		}
+		scope = nil
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	deliveries, err := h.digests.ListDeliveries(c.Request().Context(), tenantID, scope, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve digest deliveries")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"deliveries":  deliveries,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(deliveries)),
+	})
+}
+
+// GetDigestTemplate handles GET /notifications/digest/template
+func (h *NotificationDigestHandlers) GetDigestTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "notifications:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	tmpl, err := h.digests.GetTemplate(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve digest template")
+	}
+	return c.JSON(http.StatusOK, tmpl)
+}
+
+// UpdateDigestTemplate handles PUT /notifications/digest/template. Subject
+// and body are Go templates over the digest content
+func (h *NotificationDigestHandlers) UpdateDigestTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "notifications:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req struct {
+		Subject string `json:"subject"`
+		Body    string `json:"body"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	tmpl := &models.DigestTemplate{TenantID: tenantID, Subject: req.Subject, Body: req.Body}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		tmpl.UpdatedBy = &userID
+	}
+	if err := h.digests.SaveTemplate(ctx, tmpl); err != nil {
+		if errors.Is(err, jobs.ErrInvalidDigest) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save digest template")
+	}
+	return c.JSON(http.StatusOK, tmpl)
+}
+
+// DeleteDigestTemplate handles DELETE /notifications/digest/template,
+// returning the tenant to the built-in template
+func (h *NotificationDigestHandlers) DeleteDigestTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "notifications:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	deleted, err := h.digests.DeleteTemplate(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete digest template")
+	}
+	if !deleted {
+		return echo.NewHTTPError(http.StatusNotFound, "Tenant is already using the default digest template")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// OperationalModeHandlers switches the service or a tenant into read-only or
+// maintenance mode
+type OperationalModeHandlers struct {
+	operationalModeSvc services.OperationalModeService
+	rbacMiddleware     *middleware.RBACMiddleware
+}
+
+// NewOperationalModeHandlers creates a new operational mode handlers instance
+func NewOperationalModeHandlers(operationalModeSvc services.OperationalModeService, rbacMiddleware *middleware.RBACMiddleware) *OperationalModeHandlers {
+	return &OperationalModeHandlers{
+		operationalModeSvc: operationalModeSvc,
+		rbacMiddleware:     rbacMiddleware,
+	}
+}
+
+func (h *OperationalModeHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ListOperationalModes handles GET /admin/operational-modes, listing the
+// global and tenant modes in force (platform admin only)
+func (h *OperationalModeHandlers) ListOperationalModes(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_operational_modes"); err != nil {
+		return err
+	}
+
+	modes, err := h.operationalModeSvc.ListModes(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list operational modes")
+	}
+	if modes == nil {
+		modes = []*models.OperationalMode{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"operational_modes": modes})
+}
+
+// SetGlobalOperationalMode handles PUT /admin/operational-modes/global (platform admin only)
+func (h *OperationalModeHandlers) SetGlobalOperationalMode(c echo.Context) error {
+	return h.setMode(c, nil)
+}
+
+// SetTenantOperationalMode handles PUT /admin/operational-modes/tenants/:tenant_id (platform admin only)
+func (h *OperationalModeHandlers) SetTenantOperationalMode(c echo.Context) error {
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+	return h.setMode(c, &tenantID)
+}
+
+func (h *OperationalModeHandlers) setMode(c echo.Context, tenantID *uuid.UUID) error {
+	if err := h.requirePermission(c, "platform:manage_operational_modes"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req models.OperationalModeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	mode, err := h.operationalModeSvc.SetMode(ctx, tenantID, userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidOperationalMode):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrOperationalModeTenantNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Tenant not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to set operational mode")
+	}
+	if mode == nil {
+		return c.JSON(http.StatusOK, map[string]interface{}{"mode": models.OperationalModeNormal})
+	}
+
+	return c.JSON(http.StatusOK, mode)
+}
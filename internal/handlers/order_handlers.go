@@ -2,12 +2,18 @@ package handlers
 
 import (
 	"agromart2/internal/common"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
 	"agromart2/internal/models"
+	"agromart2/internal/repositories"
 	"agromart2/internal/services"
 
 	"github.com/google/uuid"
@@ -16,16 +22,182 @@ import (
 
 // OrderHandlers handles HTTP requests for orders
 type OrderHandlers struct {
-	orderService services.OrderServiceInterface
+	orderService       services.OrderServiceInterface
+	productService     services.ProductService
+	warehouseService   services.WarehouseService
+	supplierService    services.SupplierService
+	distributorService services.DistributorService
+	delegations        services.ApprovalDelegationService
+	rbacMiddleware     *middleware.RBACMiddleware
 }
 
-// NewOrderHandlers creates a new order handlers instance
-func NewOrderHandlers(orderService services.OrderServiceInterface) *OrderHandlers {
+// NewOrderHandlers creates a new order handlers instance; the product,
+// warehouse, supplier and distributor services resolve ?embed= on order lists
+func NewOrderHandlers(orderService services.OrderServiceInterface, productService services.ProductService, warehouseService services.WarehouseService, supplierService services.SupplierService, distributorService services.DistributorService, delegations services.ApprovalDelegationService, rbacMiddleware *middleware.RBACMiddleware) *OrderHandlers {
 	return &OrderHandlers{
-		orderService: orderService,
+		orderService:       orderService,
+		productService:     productService,
+		warehouseService:   warehouseService,
+		supplierService:    supplierService,
+		distributorService: distributorService,
+		delegations:        delegations,
+		rbacMiddleware:     rbacMiddleware,
 	}
 }
 
+// orderEmbeds are the relations ?embed= can attach to listed orders
+func (h *OrderHandlers) orderEmbeds(ctx context.Context, tenantID uuid.UUID) map[string]embedLoader[*models.Order] {
+	return map[string]embedLoader[*models.Order]{
+		"product": func(orders []*models.Order) ([]interface{}, error) {
+			ids := make([]uuid.UUID, len(orders))
+			for i, o := range orders {
+				ids[i] = o.ProductID
+			}
+			products, err := h.productService.GetByIDs(ctx, tenantID, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]*models.Product, len(products))
+			for _, p := range products {
+				byID[p.ID] = p
+			}
+			return embedByID(orders, byID, func(o *models.Order) *uuid.UUID { return &o.ProductID }), nil
+		},
+		"warehouse": func(orders []*models.Order) ([]interface{}, error) {
+			ids := make([]uuid.UUID, len(orders))
+			for i, o := range orders {
+				ids[i] = o.WarehouseID
+			}
+			warehouses, err := h.warehouseService.GetByIDs(ctx, tenantID, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]*models.Warehouse, len(warehouses))
+			for _, w := range warehouses {
+				byID[w.ID] = w
+			}
+			return embedByID(orders, byID, func(o *models.Order) *uuid.UUID { return &o.WarehouseID }), nil
+		},
+		"supplier": func(orders []*models.Order) ([]interface{}, error) {
+			var ids []uuid.UUID
+			for _, o := range orders {
+				if o.SupplierID != nil {
+					ids = append(ids, *o.SupplierID)
+				}
+			}
+			suppliers, err := h.supplierService.GetByIDs(ctx, tenantID, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]*models.Supplier, len(suppliers))
+			for _, s := range suppliers {
+				byID[s.ID] = s
+			}
+			return embedByID(orders, byID, func(o *models.Order) *uuid.UUID { return o.SupplierID }), nil
+		},
+		"distributor": func(orders []*models.Order) ([]interface{}, error) {
+			var ids []uuid.UUID
+			for _, o := range orders {
+				if o.DistributorID != nil {
+					ids = append(ids, *o.DistributorID)
+				}
+			}
+			distributors, err := h.distributorService.GetByIDs(ctx, tenantID, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]*models.Distributor, len(distributors))
+			for _, d := range distributors {
+				byID[d.ID] = d
+			}
+			return embedByID(orders, byID, func(o *models.Order) *uuid.UUID { return o.DistributorID }), nil
+		},
+	}
+}
+
+// withMarginOverride allows pricing below the minimum margin when the request
+// asks for it and the caller holds orders:override_margin
+func (h *OrderHandlers) withMarginOverride(c echo.Context, ctx context.Context, reason *string) (context.Context, error) {
+	if reason == nil {
+		return ctx, nil
+	}
+	err := h.rbacMiddleware.RequirePermission("orders:override_margin")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return ctx, err
+	}
+	return services.WithMarginOverride(ctx, *reason), nil
+}
+
+// withStatusComment attaches the request's comment, if any, to the status
+// transition it makes so the status history records why
+func withStatusComment(ctx context.Context, comment *string) context.Context {
+	if comment == nil || strings.TrimSpace(*comment) == "" {
+		return ctx
+	}
+	return services.WithStatusComment(ctx, *comment)
+}
+
+// bindStatusComment reads the optional comment of a status transition; an
+// empty body is fine
+func bindStatusComment(c echo.Context, ctx context.Context) (context.Context, error) {
+	var req struct {
+		Comment *string `json:"comment"`
+	}
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return ctx, common.SendClientError(c, "Invalid request format")
+		}
+	}
+	return withStatusComment(ctx, req.Comment), nil
+}
+
+// isOrderTransitionError reports whether the tenant's order workflow does not
+// allow the requested move, which is answered with a conflict
+func isOrderTransitionError(err error) bool {
+	var transitionErr *services.OrderTransitionError
+	return errors.As(err, &transitionErr) || errors.Is(err, services.ErrOrderEventNotInWorkflow)
+}
+
+// sendMarginViolation reports a sales order priced below the minimum margin
+func sendMarginViolation(c echo.Context, err *services.MarginViolationError) error {
+	details := map[string]string{
+		"unit_price":         fmt.Sprintf("%.2f", err.UnitPrice),
+		"minimum_price":      fmt.Sprintf("%.2f", err.MinimumPrice),
+		"min_margin_percent": fmt.Sprintf("%.2f", err.MinMarginPercent),
+		"margin_percent":     fmt.Sprintf("%.2f", err.MarginPercent),
+		"override_allowed":   strconv.FormatBool(err.OverrideAllowed),
+	}
+	return c.JSON(http.StatusUnprocessableEntity, common.CreateErrorResponse("MARGIN_BELOW_MINIMUM", "Unit price is below the minimum margin", details))
+}
+
+// sendComplianceBlocked reports an order for a restricted product that no valid license covers
+func sendComplianceBlocked(c echo.Context, err *services.ComplianceBlockedError) error {
+	details := map[string]string{
+		"operation":    err.Operation,
+		"license_type": err.LicenseType,
+		"product_id":   err.ProductID.String(),
+		"holder_type":  err.HolderType,
+		"holder_id":    err.HolderID.String(),
+	}
+	return c.JSON(http.StatusUnprocessableEntity, common.CreateErrorResponse("LICENSE_REQUIRED", "A valid license is required for this product", details))
+}
+
+// sendRestrictedSaleBlocked reports a restricted sale confirmed without a valid buyer license
+func sendRestrictedSaleBlocked(c echo.Context, err *services.RestrictedSaleBlockedError) error {
+	details := map[string]string{
+		"order_id":        err.OrderID.String(),
+		"product_id":      err.ProductID.String(),
+		"regulatory_flag": err.RegulatoryFlag,
+		"license_problem": err.Problem,
+	}
+	if err.DistributorID != nil {
+		details["distributor_id"] = err.DistributorID.String()
+	}
+	return c.JSON(http.StatusUnprocessableEntity, common.CreateErrorResponse("BUYER_LICENSE_REQUIRED", "The buyer has no valid license for this restricted product", details))
+}
+
 // validateOrderType validates order type
 func (h *OrderHandlers) validateOrderType(orderType string) error {
 	if orderType != "purchase" && orderType != "sales" {
@@ -48,7 +220,7 @@ func (h *OrderHandlers) CreateOrder(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Extract tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -78,6 +250,12 @@ func (h *OrderHandlers) CreateOrder(c echo.Context) error {
 			order.TenantID = tenantID
 
 			if err := h.orderService.CreateOrder(ctx, tenantID, bulkReq.Orders[i]); err != nil {
+				if marginErr, ok := err.(*services.MarginViolationError); ok {
+					return sendMarginViolation(c, marginErr)
+				}
+				if blockedErr, ok := err.(*services.ComplianceBlockedError); ok {
+					return sendComplianceBlocked(c, blockedErr)
+				}
 				return common.SendServerError(c, fmt.Sprintf("Failed to create order at index %d: %s", i, err.Error()))
 			}
 			createdOrders = append(createdOrders, bulkReq.Orders[i])
@@ -100,6 +278,9 @@ func (h *OrderHandlers) CreateOrder(c echo.Context) error {
 		SupplierID       *string `json:"supplier_id"`
 		DistributorID    *string `json:"distributor_id"`
 		Notes            *string `json:"notes"`
+		BuyerLicenseID   *string `json:"buyer_license_id"`
+
+		MarginOverrideReason *string `json:"margin_override_reason"`
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -165,6 +346,13 @@ func (h *OrderHandlers) CreateOrder(c echo.Context) error {
 		}
 		order.DistributorID = &distributorID
 	}
+	if req.BuyerLicenseID != nil && common.SafeString(req.BuyerLicenseID) != "" {
+		licenseID, err := common.ValidateUUID(common.SafeString(req.BuyerLicenseID), "buyer_license_id")
+		if err != nil {
+			return common.SendClientError(c, err.Error())
+		}
+		order.BuyerLicenseID = &licenseID
+	}
 	if req.ExpectedDelivery != nil {
 		expectedDate := common.SafeString(req.ExpectedDelivery)
 		if expectedDate != "" {
@@ -176,7 +364,21 @@ func (h *OrderHandlers) CreateOrder(c echo.Context) error {
 		}
 	}
 
+	ctx, err = h.withMarginOverride(c, ctx, req.MarginOverrideReason)
+	if err != nil {
+		return err
+	}
+
 	if err := h.orderService.CreateOrder(ctx, tenantID, order); err != nil {
+		if marginErr, ok := err.(*services.MarginViolationError); ok {
+			return sendMarginViolation(c, marginErr)
+		}
+		if blockedErr, ok := err.(*services.ComplianceBlockedError); ok {
+			return sendComplianceBlocked(c, blockedErr)
+		}
+		if errors.Is(err, services.ErrInvalidLicense) {
+			return common.SendClientError(c, err.Error())
+		}
 		return common.SendServerError(c, "Failed to create order: " + err.Error())
 	}
 
@@ -187,38 +389,49 @@ func (h *OrderHandlers) CreateOrder(c echo.Context) error {
 }
 
 // GetOrders handles GET /orders
+//
+// ?fields= trims each order to the listed fields and
+// ?embed=product,warehouse,supplier,distributor attaches the related records
 func (h *OrderHandlers) GetOrders(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	limit := 10  // default
-	offset := 0  // default
-
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			limit = l
-		}
+	embeds := h.orderEmbeds(ctx, tenantID)
+	sel, err := parseFieldSelection(c, &models.Order{}, embeds)
+	if err != nil {
+		return err
 	}
 
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.OrderSortColumns, DefaultSort: "-order_date"})
+	if err != nil {
+		return err
+	}
+	branchID, err := parseBranchFilter(c)
+	if err != nil {
+		return err
 	}
 
-	orders, err := h.orderService.ListOrders(ctx, tenantID, limit, offset)
+	orders, err := h.orderService.ListOrders(ctx, tenantID, branchID, page)
 	if err != nil {
 		return common.SendServerError(c, "Failed to retrieve orders: " + err.Error())
 	}
 
+	var body interface{} = orders
+	if !sel.isZero() {
+		if body, err = serializeList(sel, orders, embeds); err != nil {
+			return common.SendServerError(c, "Failed to retrieve orders: "+err.Error())
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"orders": orders,
-		"limit":  limit,
-		"offset": offset,
+		"orders":      body,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(orders)),
 	})
 }
 
@@ -232,7 +445,7 @@ func (h *OrderHandlers) GetOrderByID(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -244,8 +457,11 @@ func (h *OrderHandlers) GetOrderByID(c echo.Context) error {
 	if order == nil {
 		return common.SendNotFoundError(c, "order")
 	}
+	if order.AvailableEvents, err = h.orderService.OrderEvents(ctx, tenantID, order); err != nil {
+		return common.SendServerError(c, "Failed to load order workflow: " + err.Error())
+	}
 
-	return c.JSON(http.StatusOK, order)
+	return jsonWithETag(c, resourceETag(order.ID, order.UpdatedAt), order)
 }
 // GetOrder handles GET /orders/:id (alias for GetOrderByID)
 func (h *OrderHandlers) GetOrder(c echo.Context) error {
@@ -262,7 +478,7 @@ func (h *OrderHandlers) UpdateOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -272,6 +488,8 @@ func (h *OrderHandlers) UpdateOrder(c echo.Context) error {
 		UnitPrice        *float64 `json:"unit_price"`
 		ExpectedDelivery *string  `json:"expected_delivery"`
 		Notes            *string  `json:"notes"`
+
+		MarginOverrideReason *string `json:"margin_override_reason"`
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -286,6 +504,9 @@ func (h *OrderHandlers) UpdateOrder(c echo.Context) error {
 	if existingOrder == nil {
 		return common.SendNotFoundError(c, "order")
 	}
+	if ctx, err = checkIfMatch(ctx, c, resourceETag(existingOrder.ID, existingOrder.UpdatedAt), existingOrder.ID, existingOrder.UpdatedAt); err != nil {
+		return err
+	}
 
 	// Update only the fields provided
 	order := *existingOrder // Copy existing order
@@ -319,7 +540,18 @@ func (h *OrderHandlers) UpdateOrder(c echo.Context) error {
 		order.Notes = req.Notes
 	}
 
+	ctx, err = h.withMarginOverride(c, ctx, req.MarginOverrideReason)
+	if err != nil {
+		return err
+	}
+
 	if err := h.orderService.UpdateOrder(ctx, tenantID, &order); err != nil {
+		if marginErr, ok := err.(*services.MarginViolationError); ok {
+			return sendMarginViolation(c, marginErr)
+		}
+		if errors.Is(err, repositories.ErrPreconditionFailed) {
+			return modifiedError()
+		}
 		return common.SendServerError(c, "Failed to update order: " + err.Error())
 	}
 
@@ -333,7 +565,7 @@ func (h *OrderHandlers) UpdateOrder(c echo.Context) error {
 func (h *OrderHandlers) GetOrderAnalytics(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -361,7 +593,12 @@ func (h *OrderHandlers) GetOrderAnalytics(c echo.Context) error {
 		endDate, _ = time.Parse("2006-01-02", endDateStr)
 	}
 
-	analytics, err := h.orderService.GetOrderAnalytics(ctx, tenantID, startDate, endDate)
+	branchID, err := parseBranchFilter(c)
+	if err != nil {
+		return err
+	}
+
+	analytics, err := h.orderService.GetOrderAnalytics(ctx, tenantID, branchID, startDate, endDate)
 	if err != nil {
 		return common.SendServerError(c, "Failed to generate order analytics: " + err.Error())
 	}
@@ -373,20 +610,18 @@ func (h *OrderHandlers) GetOrderAnalytics(c echo.Context) error {
 func (h *OrderHandlers) SearchOrders(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	var filters models.OrderSearchFilter
-	limit := 10
-	offset := 0
 
 	// Parse query parameters
 	status := c.QueryParam("status")
 	if status != "" {
 		// Validate status
-		validStatuses := []string{"pending", "approved", "processing", "shipped", "delivered", "cancelled"}
+		validStatuses := []string{"pending", "approved", "processing", "quality_check", "shipped", "delivered", "cancelled"}
 		valid := false
 		for _, s := range validStatuses {
 			if status == s {
@@ -399,19 +634,19 @@ func (h *OrderHandlers) SearchOrders(c echo.Context) error {
 		}
 	}
 
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			limit = l
-		}
+	branchID, err := parseBranchFilter(c)
+	if err != nil {
+		return err
 	}
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
+	filters.BranchID = branchID
+
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
 	}
 
-	filters.Limit = limit
-	filters.Offset = offset
+	filters.Limit = page.Limit
+	filters.Offset = page.Offset
 
 	orders, err := h.orderService.SearchOrders(ctx, tenantID, &filters)
 	if err != nil {
@@ -419,9 +654,10 @@ func (h *OrderHandlers) SearchOrders(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"orders": orders,
-		"limit":  limit,
-		"offset": offset,
+		"orders":      orders,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(orders)),
 	})
 }
 
@@ -435,14 +671,52 @@ func (h *OrderHandlers) ApproveOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
+	// An empty body is fine; the override reason is only needed for restricted sales
+	var req struct {
+		LicenseOverrideReason *string `json:"license_override_reason"`
+		Comment               *string `json:"comment"`
+	}
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return common.SendClientError(c, "Invalid request format")
+		}
+	}
+	if req.LicenseOverrideReason != nil {
+		reason := strings.TrimSpace(*req.LicenseOverrideReason)
+		if reason == "" {
+			return common.SendValidationError(c, "license_override_reason", "license_override_reason must not be empty")
+		}
+		err := h.rbacMiddleware.RequirePermission("orders:override_restricted")(func(c echo.Context) error {
+			return nil
+		})(c)
+		if err != nil {
+			return err
+		}
+		ctx = services.WithRestrictedSaleOverride(ctx, reason)
+	}
+	ctx = withStatusComment(ctx, req.Comment)
+
+	// Approving while covering for someone is recorded on their behalf
+	delegation, err := requireApprover(c, h.rbacMiddleware, h.delegations, models.ApprovalKindOrder, "")
+	if err != nil {
+		return err
+	}
+
 	if err := h.orderService.ApproveOrder(ctx, tenantID, orderID); err != nil {
+		if isOrderTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if blockedErr, ok := err.(*services.RestrictedSaleBlockedError); ok {
+			return sendRestrictedSaleBlocked(c, blockedErr)
+		}
 		return common.SendServerError(c, "Failed to approve order: " + err.Error())
 	}
+	recordDelegatedApproval(c, h.delegations, delegation, models.ApprovalKindOrder, orderID, "approved")
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Order approved successfully",
@@ -459,12 +733,20 @@ func (h *OrderHandlers) ProcessOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
+	ctx, err = bindStatusComment(c, ctx)
+	if err != nil {
+		return err
+	}
+
 	if err := h.orderService.ProcessOrder(ctx, tenantID, orderID); err != nil {
+		if isOrderTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -482,12 +764,20 @@ func (h *OrderHandlers) ReceiveOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
+	ctx, err = bindStatusComment(c, ctx)
+	if err != nil {
+		return err
+	}
+
 	if err := h.orderService.ReceiveOrder(ctx, tenantID, orderID); err != nil {
+		if isOrderTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -496,6 +786,39 @@ func (h *OrderHandlers) ReceiveOrder(c echo.Context) error {
 	})
 }
 
+// QualityCheckOrder handles POST /orders/:id/quality-check, holding a
+// processed order for its quality check
+func (h *OrderHandlers) QualityCheckOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	id := c.Param("id")
+	orderID, err := common.ValidateUUID(id, "order_id")
+	if err != nil {
+		return common.SendClientError(c, err.Error())
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	ctx, err = bindStatusComment(c, ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := h.orderService.QualityCheckOrder(ctx, tenantID, orderID); err != nil {
+		if isOrderTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Order sent for quality check",
+	})
+}
+
 // ShipOrder handles POST /orders/:id/ship
 func (h *OrderHandlers) ShipOrder(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -506,13 +829,14 @@ func (h *OrderHandlers) ShipOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	var req struct {
 		ExpectedDelivery *string `json:"expected_delivery"`
+		Comment          *string `json:"comment"`
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -528,7 +852,11 @@ func (h *OrderHandlers) ShipOrder(c echo.Context) error {
 		expectedDelivery = &deliveryDate
 	}
 
+	ctx = withStatusComment(ctx, req.Comment)
 	if err := h.orderService.ShipOrder(ctx, tenantID, orderID, expectedDelivery); err != nil {
+		if isOrderTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return common.SendServerError(c, "Failed to ship order: " + err.Error())
 	}
 
@@ -547,12 +875,20 @@ func (h *OrderHandlers) DeliverOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
+	ctx, err = bindStatusComment(c, ctx)
+	if err != nil {
+		return err
+	}
+
 	if err := h.orderService.DeliverOrder(ctx, tenantID, orderID); err != nil {
+		if isOrderTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -572,12 +908,20 @@ func (h *OrderHandlers) CancelOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
+	ctx, err = bindStatusComment(c, ctx)
+	if err != nil {
+		return err
+	}
+
 	if err := h.orderService.CancelOrder(ctx, tenantID, orderID); err != nil {
+		if isOrderTransitionError(err) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -586,6 +930,30 @@ func (h *OrderHandlers) CancelOrder(c echo.Context) error {
 	})
 }
 
+// BulkCancelOrders handles POST /orders/bulk/cancel
+func (h *OrderHandlers) BulkCancelOrders(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req models.OrderBulkCancel
+	if err := c.Bind(&req); err != nil {
+		return common.SendClientError(c, "Invalid request format")
+	}
+
+	if len(req.OrderIDs) == 0 {
+		return common.SendClientError(c, "Order IDs are required")
+	}
+	if len(req.OrderIDs) > maxBulkItems {
+		return common.SendClientError(c, fmt.Sprintf("Cannot cancel more than %d orders at once", maxBulkItems))
+	}
+
+	return sendBulkResult(c, h.orderService.BulkCancelOrders(ctx, tenantID, &req))
+}
+
 // GetOrderHistory handles GET /orders/:id/history
 func (h *OrderHandlers) GetOrderHistory(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -596,7 +964,7 @@ func (h *OrderHandlers) GetOrderHistory(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -618,7 +986,7 @@ func (h *OrderHandlers) DeleteOrder(c echo.Context) error {
 		return common.SendClientError(c, err.Error())
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// OrderSLAHandlers handles order fulfillment SLA definitions, breaches and
+// the SLA compliance report
+type OrderSLAHandlers struct {
+	slaSvc         services.OrderSLAService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewOrderSLAHandlers creates a new order SLA handlers instance
+func NewOrderSLAHandlers(slaSvc services.OrderSLAService, rbacMiddleware *middleware.RBACMiddleware) *OrderSLAHandlers {
+	return &OrderSLAHandlers{
+		slaSvc:         slaSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *OrderSLAHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// orderSLARequest is an SLA definition; the target is given in minutes or,
+// more conveniently, hours
+type orderSLARequest struct {
+	Name          string     `json:"name"`
+	OrderType     *string    `json:"order_type"`
+	WarehouseID   *uuid.UUID `json:"warehouse_id"`
+	StartEvent    string     `json:"start_event"`
+	EndEvent      string     `json:"end_event"`
+	TargetMinutes int        `json:"target_minutes"`
+	TargetHours   float64    `json:"target_hours"`
+	NotifyEmails  []string   `json:"notify_emails"`
+	IsActive      *bool      `json:"is_active"`
+}
+
+func (r *orderSLARequest) toSLA() *models.OrderSLA {
+	sla := &models.OrderSLA{
+		Name:          r.Name,
+		OrderType:     r.OrderType,
+		WarehouseID:   r.WarehouseID,
+		StartEvent:    r.StartEvent,
+		EndEvent:      r.EndEvent,
+		TargetMinutes: r.TargetMinutes,
+		NotifyEmails:  r.NotifyEmails,
+		IsActive:      true,
+	}
+	if sla.TargetMinutes == 0 && r.TargetHours > 0 {
+		sla.TargetMinutes = int(math.Round(r.TargetHours * 60))
+	}
+	if r.IsActive != nil {
+		sla.IsActive = *r.IsActive
+	}
+	return sla
+}
+
+// ListDefinitions handles GET /sla/definitions
+func (h *OrderSLAHandlers) ListDefinitions(c echo.Context) error {
+	if err := h.requirePermission(c, "sla:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	slas, err := h.slaSvc.ListDefinitions(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list SLAs")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"slas": slas})
+}
+
+// CreateDefinition handles POST /sla/definitions
+func (h *OrderSLAHandlers) CreateDefinition(c echo.Context) error {
+	if err := h.requirePermission(c, "sla:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req orderSLARequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	sla := req.toSLA()
+	if err := h.slaSvc.CreateDefinition(ctx, tenantID, sla); err != nil {
+		if errors.Is(err, services.ErrInvalidSLA) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create SLA")
+	}
+
+	return c.JSON(http.StatusCreated, sla)
+}
+
+// UpdateDefinition handles PUT /sla/definitions/:id
+func (h *OrderSLAHandlers) UpdateDefinition(c echo.Context) error {
+	if err := h.requirePermission(c, "sla:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid SLA ID format")
+	}
+
+	var req orderSLARequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	sla := req.toSLA()
+	sla.ID = id
+	if err := h.slaSvc.UpdateDefinition(ctx, tenantID, sla); err != nil {
+		if errors.Is(err, services.ErrInvalidSLA) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusNotFound, "SLA not found")
+	}
+
+	return c.JSON(http.StatusOK, sla)
+}
+
+// DeleteDefinition handles DELETE /sla/definitions/:id; its breaches go with it
+func (h *OrderSLAHandlers) DeleteDefinition(c echo.Context) error {
+	if err := h.requirePermission(c, "sla:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid SLA ID format")
+	}
+
+	if err := h.slaSvc.DeleteDefinition(ctx, tenantID, id); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "SLA not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RunBreachCheck handles POST /sla/run, checking for breaches now instead of
+// waiting for the scheduled job
+func (h *OrderSLAHandlers) RunBreachCheck(c echo.Context) error {
+	if err := h.requirePermission(c, "sla:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	found, err := h.slaSvc.RunBreachCheck(ctx, tenantID, time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"breaches": found})
+}
+
+// ListBreaches handles GET /sla/breaches?sla_id=&open=true, newest first
+func (h *OrderSLAHandlers) ListBreaches(c echo.Context) error {
+	if err := h.requirePermission(c, "sla:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var slaID *uuid.UUID
+	if raw := c.QueryParam("sla_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid SLA ID format")
+		}
+		slaID = &id
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	breaches, err := h.slaSvc.ListBreaches(ctx, tenantID, slaID, c.QueryParam("open") == "true", page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list SLA breaches")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"breaches":    breaches,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(breaches)),
+	})
+}
+
+// GetCompliance handles GET /reports/sla-compliance?from=&to=&group_by=&sla_id=.
+// Dates are inclusive and default to the last 30 days; group_by is warehouse
+// (default) or territory
+func (h *OrderSLAHandlers) GetCompliance(c echo.Context) error {
+	if err := h.requirePermission(c, "sla:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today.AddDate(0, 0, -29), today
+	if raw := c.QueryParam("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "from must be in YYYY-MM-DD format")
+		}
+		from = parsed
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "to must be in YYYY-MM-DD format")
+		}
+		to = parsed
+	}
+	var slaID *uuid.UUID
+	if raw := c.QueryParam("sla_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid SLA ID format")
+		}
+		slaID = &id
+	}
+
+	report, err := h.slaSvc.ComplianceReport(ctx, tenantID, from, to.AddDate(0, 0, 1), c.QueryParam("group_by"), slaID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSLA) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build SLA compliance report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
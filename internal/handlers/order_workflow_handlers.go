@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OrderWorkflowHandlers handles the tenant's order workflow configuration
+type OrderWorkflowHandlers struct {
+	workflowService services.OrderWorkflowService
+	rbacMiddleware  *middleware.RBACMiddleware
+}
+
+// NewOrderWorkflowHandlers creates a new order workflow handlers instance
+func NewOrderWorkflowHandlers(workflowService services.OrderWorkflowService, rbacMiddleware *middleware.RBACMiddleware) *OrderWorkflowHandlers {
+	return &OrderWorkflowHandlers{
+		workflowService: workflowService,
+		rbacMiddleware:  rbacMiddleware,
+	}
+}
+
+func (h *OrderWorkflowHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// sendWorkflow responds with the workflow and the transitions it allows
+func sendWorkflow(c echo.Context, workflow *models.OrderWorkflow) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"workflow":    workflow,
+		"transitions": services.NewOrderStateMachine(workflow).Transitions(),
+	})
+}
+
+// GetOrderWorkflow handles GET /orders/workflow
+func (h *OrderWorkflowHandlers) GetOrderWorkflow(c echo.Context) error {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	workflow, err := h.workflowService.GetWorkflow(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve order workflow")
+	}
+	return sendWorkflow(c, workflow)
+}
+
+// SetOrderWorkflow handles PUT /orders/workflow
+func (h *OrderWorkflowHandlers) SetOrderWorkflow(c echo.Context) error {
+	if err := h.requirePermission(c, "orders:workflow"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req struct {
+		SkipApproval bool `json:"skip_approval"`
+		QualityCheck bool `json:"quality_check"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	workflow := &models.OrderWorkflow{TenantID: tenantID, SkipApproval: req.SkipApproval, QualityCheck: req.QualityCheck}
+	if err := h.workflowService.SetWorkflow(ctx, workflow); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save order workflow")
+	}
+	return sendWorkflow(c, workflow)
+}
+
+// DeleteOrderWorkflow handles DELETE /orders/workflow, going back to the
+// default workflow
+func (h *OrderWorkflowHandlers) DeleteOrderWorkflow(c echo.Context) error {
+	if err := h.requirePermission(c, "orders:workflow"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	if err := h.workflowService.DeleteWorkflow(ctx, tenantID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete order workflow")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// OverdueInterestHandlers handles overdue interest terms, per tenant and per
+// customer, and the interest accrued on invoices
+type OverdueInterestHandlers struct {
+	interest       *jobs.OverdueInterestService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewOverdueInterestHandlers creates a new overdue interest handlers instance
+func NewOverdueInterestHandlers(interest *jobs.OverdueInterestService, rbacMiddleware *middleware.RBACMiddleware) *OverdueInterestHandlers {
+	return &OverdueInterestHandlers{
+		interest:       interest,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *OverdueInterestHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetSettings handles GET /overdue-interest/settings
+func (h *OverdueInterestHandlers) GetSettings(c echo.Context) error {
+	if err := h.requirePermission(c, "interest:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	settings, err := h.interest.GetSettings(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load interest settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /overdue-interest/settings
+func (h *OverdueInterestHandlers) UpdateSettings(c echo.Context) error {
+	if err := h.requirePermission(c, "interest:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var settings models.OverdueInterestSettings
+	if err := c.Bind(&settings); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.interest.UpdateSettings(ctx, tenantID, &settings); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// RunAccrual handles POST /overdue-interest/run, accruing interest up to today
+// instead of waiting for the daily job
+func (h *OverdueInterestHandlers) RunAccrual(c echo.Context) error {
+	if err := h.requirePermission(c, "interest:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	charged, err := h.interest.AccrueInterest(ctx, tenantID, time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"invoices_charged": charged})
+}
+
+// GetCustomerTerms handles GET /customers/:id/interest-terms
+func (h *OverdueInterestHandlers) GetCustomerTerms(c echo.Context) error {
+	if err := h.requirePermission(c, "interest:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	terms, err := h.interest.GetCustomerTerms(ctx, tenantID, customerID)
+	if errors.Is(err, jobs.ErrCustomerNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load customer interest terms")
+	}
+
+	return c.JSON(http.StatusOK, terms)
+}
+
+// UpdateCustomerTerms handles PUT /customers/:id/interest-terms
+func (h *OverdueInterestHandlers) UpdateCustomerTerms(c echo.Context) error {
+	if err := h.requirePermission(c, "interest:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	var terms models.CustomerInterestTerms
+	if err := c.Bind(&terms); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	err = h.interest.UpdateCustomerTerms(ctx, tenantID, customerID, &terms)
+	if errors.Is(err, jobs.ErrCustomerNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, terms)
+}
+
+// DeleteCustomerTerms handles DELETE /customers/:id/interest-terms, putting
+// the customer back on the tenant's terms
+func (h *OverdueInterestHandlers) DeleteCustomerTerms(c echo.Context) error {
+	if err := h.requirePermission(c, "interest:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	err = h.interest.DeleteCustomerTerms(ctx, tenantID, customerID)
+	if errors.Is(err, jobs.ErrCustomerNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to remove customer interest terms")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetInvoiceInterest handles GET /invoices/:id/interest, listing the interest
+// accrued on an invoice and the amount due including it
+func (h *OverdueInterestHandlers) GetInvoiceInterest(c echo.Context) error {
+	if err := h.requirePermission(c, "interest:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
+	}
+
+	interest, err := h.interest.GetInvoiceInterest(ctx, tenantID, invoiceID)
+	if errors.Is(err, jobs.ErrInvoiceNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Invoice not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load invoice interest")
+	}
+
+	return c.JSON(http.StatusOK, interest)
+}
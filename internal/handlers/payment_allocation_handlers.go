@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// PaymentAllocationHandlers handles customer payments and their allocation
+// across invoices. Customers are distributors.
+type PaymentAllocationHandlers struct {
+	allocationSvc  services.PaymentAllocationService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewPaymentAllocationHandlers creates a new payment allocation handlers instance
+func NewPaymentAllocationHandlers(allocationSvc services.PaymentAllocationService, rbacMiddleware *middleware.RBACMiddleware) *PaymentAllocationHandlers {
+	return &PaymentAllocationHandlers{
+		allocationSvc:  allocationSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *PaymentAllocationHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// allocationError maps payment allocation service errors to HTTP errors
+func allocationError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrPaymentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	case errors.Is(err, services.ErrPaymentCustomerNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	case errors.Is(err, services.ErrInvalidAllocation):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrAllocationConflict), errors.Is(err, services.ErrPeriodClosed):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// RecordPayment handles POST /customers/:id/payments, recording a payment and
+// allocating it oldest invoice first unless told otherwise
+func (h *PaymentAllocationHandlers) RecordPayment(c echo.Context) error {
+	if err := h.requirePermission(c, "payments:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	var req models.CustomerPaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var userID *uuid.UUID
+	if id, ok := rc.User(); ok {
+		userID = &id
+	}
+
+	payment, err := h.allocationSvc.RecordPayment(ctx, tenantID, customerID, userID, &req)
+	if err != nil {
+		return allocationError(err, "Failed to record payment")
+	}
+
+	return c.JSON(http.StatusCreated, payment)
+}
+
+// ListPayments handles GET /customers/:id/payments, newest first
+func (h *PaymentAllocationHandlers) ListPayments(c echo.Context) error {
+	if err := h.requirePermission(c, "payments:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	payments, err := h.allocationSvc.ListPayments(ctx, tenantID, customerID, page.Limit, page.Offset)
+	if err != nil {
+		return allocationError(err, "Failed to list payments")
+	}
+	if payments == nil {
+		payments = []*models.CustomerPayment{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"payments":    payments,
+		"next_cursor": page.NextCursor(len(payments)),
+	})
+}
+
+// ListOpenInvoices handles GET /customers/:id/open-invoices, the invoices a
+// payment can be allocated to, oldest due first
+func (h *PaymentAllocationHandlers) ListOpenInvoices(c echo.Context) error {
+	if err := h.requirePermission(c, "payments:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	invoices, err := h.allocationSvc.OpenInvoices(ctx, tenantID, customerID)
+	if err != nil {
+		return allocationError(err, "Failed to list open invoices")
+	}
+	if invoices == nil {
+		invoices = []*models.OpenInvoice{}
+	}
+
+	outstanding := 0.0
+	for _, invoice := range invoices {
+		outstanding += invoice.Outstanding
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"invoices":          invoices,
+		"total_outstanding": math.Round(outstanding*100) / 100,
+	})
+}
+
+// GetPayment handles GET /payments/:id with its active allocations
+func (h *PaymentAllocationHandlers) GetPayment(c echo.Context) error {
+	if err := h.requirePermission(c, "payments:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID format")
+	}
+
+	payment, err := h.allocationSvc.GetPayment(ctx, tenantID, paymentID)
+	if err != nil {
+		return allocationError(err, "Failed to retrieve payment")
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// AllocatePayment handles POST /payments/:id/allocate, applying what is still
+// unallocated on the payment
+func (h *PaymentAllocationHandlers) AllocatePayment(c echo.Context) error {
+	return h.allocate(c, false)
+}
+
+// ReallocatePayment handles POST /payments/:id/reallocate, reversing the
+// payment's allocations and applying it afresh
+func (h *PaymentAllocationHandlers) ReallocatePayment(c echo.Context) error {
+	return h.allocate(c, true)
+}
+
+func (h *PaymentAllocationHandlers) allocate(c echo.Context, reallocate bool) error {
+	if err := h.requirePermission(c, "payments:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID format")
+	}
+
+	var req models.AllocationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var userID *uuid.UUID
+	if id, ok := rc.User(); ok {
+		userID = &id
+	}
+
+	var payment *models.CustomerPayment
+	if reallocate {
+		payment, err = h.allocationSvc.Reallocate(ctx, tenantID, paymentID, userID, &req)
+	} else {
+		payment, err = h.allocationSvc.Allocate(ctx, tenantID, paymentID, userID, &req)
+	}
+	if err != nil {
+		return allocationError(err, "Failed to allocate payment")
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// ListPaymentAllocations handles GET /payments/:id/allocations, the full
+// allocation history of a payment including reversed allocations
+func (h *PaymentAllocationHandlers) ListPaymentAllocations(c echo.Context) error {
+	if err := h.requirePermission(c, "payments:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID format")
+	}
+
+	allocations, err := h.allocationSvc.AllocationHistory(ctx, tenantID, paymentID)
+	if err != nil {
+		return allocationError(err, "Failed to list payment allocations")
+	}
+	if allocations == nil {
+		allocations = []*models.PaymentAllocation{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"allocations": allocations})
+}
+
+// ListInvoiceAllocations handles GET /invoices/:id/allocations, every payment
+// allocated to the invoice including reversed allocations
+func (h *PaymentAllocationHandlers) ListInvoiceAllocations(c echo.Context) error {
+	if err := h.requirePermission(c, "payments:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
+	}
+
+	allocations, err := h.allocationSvc.InvoiceAllocations(ctx, tenantID, invoiceID)
+	if err != nil {
+		return allocationError(err, "Failed to list invoice allocations")
+	}
+	if allocations == nil {
+		allocations = []*models.PaymentAllocation{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"allocations": allocations})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// PeriodCloseHandlers handles closing and reopening accounting periods
+type PeriodCloseHandlers struct {
+	periodSvc      services.PeriodCloseService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewPeriodCloseHandlers creates a new period close handlers instance
+func NewPeriodCloseHandlers(periodSvc services.PeriodCloseService, rbacMiddleware *middleware.RBACMiddleware) *PeriodCloseHandlers {
+	return &PeriodCloseHandlers{
+		periodSvc:      periodSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *PeriodCloseHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// periodCloseError maps period close service errors to HTTP errors
+func periodCloseError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrBranchNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Branch not found")
+	case errors.Is(err, services.ErrInvalidPeriodClose):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPeriodCloseConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// ListClosedPeriods handles GET /accounting-periods/closed
+func (h *PeriodCloseHandlers) ListClosedPeriods(c echo.Context) error {
+	if err := h.requirePermission(c, "periods:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	closes, err := h.periodSvc.List(ctx, tenantID)
+	if err != nil {
+		return periodCloseError(err, "Failed to list closed periods")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"closed_periods": closes,
+	})
+}
+
+// ClosePeriod handles POST /accounting-periods/close, locking the month's
+// invoices and payments for the tenant or the given branch
+func (h *PeriodCloseHandlers) ClosePeriod(c echo.Context) error {
+	if err := h.requirePermission(c, "periods:close"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.PeriodCloseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	var userID *uuid.UUID
+	if id, ok := rc.User(); ok {
+		userID = &id
+	}
+
+	periodClose, err := h.periodSvc.Close(ctx, tenantID, userID, &req)
+	if err != nil {
+		return periodCloseError(err, "Failed to close period")
+	}
+
+	return c.JSON(http.StatusCreated, periodClose)
+}
+
+// ReopenPeriod handles POST /accounting-periods/reopen; a reason is required
+// and kept in the history
+func (h *PeriodCloseHandlers) ReopenPeriod(c echo.Context) error {
+	if err := h.requirePermission(c, "periods:unlock"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.PeriodCloseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	var userID *uuid.UUID
+	if id, ok := rc.User(); ok {
+		userID = &id
+	}
+
+	if err := h.periodSvc.Reopen(ctx, tenantID, userID, &req); err != nil {
+		return periodCloseError(err, "Failed to reopen period")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Period reopened successfully",
+	})
+}
+
+// GetPeriodCloseHistory handles GET /accounting-periods/history, every close
+// and reopen, newest first
+func (h *PeriodCloseHandlers) GetPeriodCloseHistory(c echo.Context) error {
+	if err := h.requirePermission(c, "periods:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	events, err := h.periodSvc.History(ctx, tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return periodCloseError(err, "Failed to load period close history")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"events":      events,
+		"next_cursor": page.NextCursor(len(events)),
+	})
+}
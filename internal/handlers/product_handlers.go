@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
+	"agromart2/internal/repositories"
 	"agromart2/internal/services"
 
 	"github.com/google/uuid"
@@ -30,18 +36,27 @@ func NewProductHandlers(productService services.ProductService, rbacMiddleware *
 	}
 }
 
-// validateProduct validates product data
-func (h *ProductHandlers) validateProduct(req *struct {
+// productRequest is the create/update product payload
+type productRequest struct {
 	Name           string   `json:"name"`
 	CategoryID     *string  `json:"category_id"`
 	BatchNumber    *string  `json:"batch_number"`
 	ExpiryDate     *string  `json:"expiry_date"`
 	Quantity       int      `json:"quantity"`
 	UnitPrice      float64  `json:"unit_price"`
+	CostPrice      *float64 `json:"cost_price"`
 	Barcode        *string  `json:"barcode"`
 	UnitOfMeasure  *string  `json:"unit_of_measure"`
 	Description    *string  `json:"description"`
-}) error {
+	Translations   models.ProductTranslations `json:"translations"`
+	GSTRate        *float64 `json:"gst_rate"`
+
+	// PriceChangeReason is stored in the price history when an update changes the price
+	PriceChangeReason *string `json:"price_change_reason"`
+}
+
+// validateProduct validates product data
+func (h *ProductHandlers) validateProduct(req *productRequest) error {
 	if strings.TrimSpace(req.Name) == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Product name is required")
 	}
@@ -51,9 +66,43 @@ func (h *ProductHandlers) validateProduct(req *struct {
 	if req.Quantity < 0 {
 		return echo.NewHTTPError(http.StatusBadRequest, "Quantity cannot be negative")
 	}
+	if req.CostPrice != nil && *req.CostPrice < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Cost price cannot be negative")
+	}
+	if req.GSTRate != nil && (*req.GSTRate < 0 || *req.GSTRate > 100) {
+		return echo.NewHTTPError(http.StatusBadRequest, "GST rate must be between 0 and 100")
+	}
+	if req.Translations != nil {
+		normalized := make(models.ProductTranslations, len(req.Translations))
+		for locale, tr := range req.Translations {
+			locale = strings.ToLower(strings.TrimSpace(locale))
+			if !localePattern.MatchString(locale) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid translation locale: "+locale)
+			}
+			if strings.TrimSpace(tr.Name) == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "Translated name is required for locale "+locale)
+			}
+			normalized[locale] = tr
+		}
+		req.Translations = normalized
+	}
 	return nil
 }
 
+// localePattern accepts language codes with an optional region, e.g. "hi" or "mr-in"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}([-_][a-z]{2})?$`)
+
+// localizeProducts renders products in the requested locale, keeping the
+// default-locale text where no translation exists
+func localizeProducts(products []*models.Product, locale string) {
+	if locale == "" {
+		return
+	}
+	for _, product := range products {
+		product.Localize(locale)
+	}
+}
+
 // validateUUID validates UUID string with enhanced checks
 func (h *ProductHandlers) validateUUID(idStr string) (uuid.UUID, error) {
 	// Enhanced logging for UUID format validation
@@ -92,27 +141,30 @@ func (h *ProductHandlers) validateUUID(idStr string) (uuid.UUID, error) {
 	return id, nil
 }
 
+// productWriteError maps product create and update errors to HTTP errors
+func productWriteError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrNoWarehouseForStock):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Stock cannot be recorded: "+err.Error())
+	case errors.Is(err, services.ErrInsufficientStock):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, repositories.ErrPreconditionFailed):
+		return modifiedError()
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
+
 // CreateProduct handles POST /products
 func (h *ProductHandlers) CreateProduct(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Extract tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	var req struct {
-		Name           string   `json:"name"`
-		CategoryID     *string  `json:"category_id"`
-		BatchNumber    *string  `json:"batch_number"`
-		ExpiryDate     *string  `json:"expiry_date"`
-		Quantity       int      `json:"quantity"`
-		UnitPrice      float64  `json:"unit_price"`
-		Barcode        *string  `json:"barcode"`
-		UnitOfMeasure  *string  `json:"unit_of_measure"`
-		Description    *string  `json:"description"`
-	}
+	var req productRequest
 
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
@@ -127,9 +179,12 @@ func (h *ProductHandlers) CreateProduct(c echo.Context) error {
 		BatchNumber:   req.BatchNumber,
 		Quantity:      req.Quantity,
 		UnitPrice:     req.UnitPrice,
+		CostPrice:     req.CostPrice,
 		Barcode:       req.Barcode,
 		UnitOfMeasure: req.UnitOfMeasure,
 		Description:   req.Description,
+		Translations:  req.Translations,
+		GSTRate:       req.GSTRate,
 	}
 
 	if req.CategoryID != nil && *req.CategoryID != "" {
@@ -149,7 +204,7 @@ func (h *ProductHandlers) CreateProduct(c echo.Context) error {
 	}
 
 	if err := h.productService.Create(ctx, tenantID, product); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return productWriteError(err)
 	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
@@ -158,39 +213,96 @@ func (h *ProductHandlers) CreateProduct(c echo.Context) error {
 	})
 }
 
+// productEmbeds are the relations ?embed= can attach to listed products
+func (h *ProductHandlers) productEmbeds(ctx context.Context, tenantID uuid.UUID) map[string]embedLoader[*models.Product] {
+	return map[string]embedLoader[*models.Product]{
+		"category": func(products []*models.Product) ([]interface{}, error) {
+			var ids []uuid.UUID
+			for _, p := range products {
+				if p.CategoryID != nil {
+					ids = append(ids, *p.CategoryID)
+				}
+			}
+			categories, err := h.productService.GetCategoriesByIDs(ctx, tenantID, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]interface{}, len(products))
+			for i, p := range products {
+				if p.CategoryID != nil {
+					if category, ok := categories[*p.CategoryID]; ok {
+						out[i] = category
+					}
+				}
+			}
+			return out, nil
+		},
+		"images": func(products []*models.Product) ([]interface{}, error) {
+			ids := make([]uuid.UUID, len(products))
+			for i, p := range products {
+				ids[i] = p.ID
+			}
+			images, err := h.productService.GetImagesByProductIDs(ctx, tenantID, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]interface{}, len(products))
+			for i, p := range products {
+				if productImages := images[p.ID]; productImages != nil {
+					out[i] = productImages
+				} else {
+					out[i] = []*models.ProductImage{}
+				}
+			}
+			return out, nil
+		},
+	}
+}
+
 // ListProducts handles GET /products
+//
+// ?fields= trims each product to the listed fields and ?embed=category,images
+// attaches the related records
 func (h *ProductHandlers) ListProducts(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	limit := 10  // default
-	offset := 0  // default
-
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			limit = l
-		}
+	embeds := h.productEmbeds(ctx, tenantID)
+	sel, err := parseFieldSelection(c, &models.Product{}, embeds)
+	if err != nil {
+		return err
 	}
 
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.ProductSortColumns, DefaultSort: "-created_at"})
+	if err != nil {
+		return err
 	}
 
-	products, err := h.productService.List(ctx, tenantID, limit, offset)
+	products, err := h.productService.List(ctx, tenantID, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
+	locale := c.QueryParam("locale")
+	localizeProducts(products, locale)
+
+	var body interface{} = products
+	if !sel.isZero() {
+		if body, err = serializeList(sel, products, embeds); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"products": products,
-		"limit":    limit,
-		"offset":   offset,
+		"products":    body,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(products)),
+		"locale":      locale,
 	})
 }
 
@@ -203,7 +315,7 @@ func (h *ProductHandlers) GetProductByID(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -213,9 +325,89 @@ func (h *ProductHandlers) GetProductByID(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	return c.JSON(http.StatusOK, product)
+	etag := resourceETag(product.ID, product.UpdatedAt)
+	if locale := c.QueryParam("locale"); locale != "" {
+		product.Localize(locale)
+	}
+
+	return jsonWithETag(c, etag, product)
+
 
+}
+
+// maxBatchGetIDs caps how many records one batch-get request may ask for
+const maxBatchGetIDs = 500
+
+// batchGetRequest is the body of the batch-get endpoints
+type batchGetRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// bindBatchGetIDs reads and de-duplicates the requested IDs, keeping their order
+func bindBatchGetIDs(c echo.Context) ([]uuid.UUID, error) {
+	var req batchGetRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if len(req.IDs) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "ids is required")
+	}
+	if len(req.IDs) > maxBatchGetIDs {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("ids cannot exceed %d entries", maxBatchGetIDs))
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.IDs))
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// missingIDs returns the requested IDs absent from found
+func missingIDs(requested []uuid.UUID, found map[uuid.UUID]bool) []uuid.UUID {
+	missing := []uuid.UUID{}
+	for _, id := range requested {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// BatchGetProducts handles POST /products/batch-get
+func (h *ProductHandlers) BatchGetProducts(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	ids, err := bindBatchGetIDs(c)
+	if err != nil {
+		return err
+	}
+
+	products, err := h.productService.GetByIDs(ctx, tenantID, ids)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve products")
+	}
+
+	localizeProducts(products, c.QueryParam("locale"))
+
+	found := make(map[uuid.UUID]bool, len(products))
+	for _, product := range products {
+		found[product.ID] = true
+	}
 
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"products": products,
+		"missing":  missingIDs(ids, found),
+	})
 }
 
 // GetProduct handles GET /products/:id (alias for GetProductByID)
@@ -232,22 +424,12 @@ func (h *ProductHandlers) UpdateProduct(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	var req struct {
-		Name           string   `json:"name"`
-		CategoryID     *string  `json:"category_id"`
-		BatchNumber    *string  `json:"batch_number"`
-		ExpiryDate     *string  `json:"expiry_date"`
-		Quantity       int      `json:"quantity"`
-		UnitPrice      float64  `json:"unit_price"`
-		Barcode        *string  `json:"barcode"`
-		UnitOfMeasure  *string  `json:"unit_of_measure"`
-		Description    *string  `json:"description"`
-	}
+	var req productRequest
 
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
@@ -261,14 +443,26 @@ func (h *ProductHandlers) UpdateProduct(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	if ctx, err = checkIfMatch(ctx, c, resourceETag(existing.ID, existing.UpdatedAt), existing.ID, existing.UpdatedAt); err != nil {
+		return err
+	}
 
 	existing.Name = req.Name
 	existing.BatchNumber = req.BatchNumber
 	existing.Quantity = req.Quantity
 	existing.UnitPrice = req.UnitPrice
+	if req.CostPrice != nil {
+		existing.CostPrice = req.CostPrice
+	}
 	existing.Barcode = req.Barcode
 	existing.UnitOfMeasure = req.UnitOfMeasure
 	existing.Description = req.Description
+	if req.Translations != nil {
+		existing.Translations = req.Translations
+	}
+	if req.GSTRate != nil {
+		existing.GSTRate = req.GSTRate
+	}
 
 	if req.CategoryID != nil && *req.CategoryID != "" {
 		categoryID, err := h.validateUUID(*req.CategoryID)
@@ -286,8 +480,12 @@ func (h *ProductHandlers) UpdateProduct(c echo.Context) error {
 		existing.ExpiryDate = &expiryDate
 	}
 
+	if req.PriceChangeReason != nil {
+		ctx = services.WithPriceChangeReason(ctx, *req.PriceChangeReason)
+	}
+
 	if err := h.productService.Update(ctx, tenantID, existing); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return productWriteError(err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -296,6 +494,54 @@ func (h *ProductHandlers) UpdateProduct(c echo.Context) error {
 	})
 }
 
+// GetPriceHistory handles GET /products/:id/price-history
+func (h *ProductHandlers) GetPriceHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	productID, err := h.validateUUID(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50})
+	if err != nil {
+		return err
+	}
+
+	// Realization window defaults to the last 90 days
+	days, _ := strconv.Atoi(c.QueryParam("days"))
+	if days <= 0 {
+		days = 90
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	history, err := h.productService.GetPriceHistory(ctx, tenantID, productID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	if history == nil {
+		history = []*models.PriceHistory{}
+	}
+
+	realization, err := h.productService.GetPriceRealization(ctx, tenantID, productID, since)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate price realization")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"history":     history,
+		"realization": realization,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(history)),
+	})
+}
+
 // DeleteProduct handles DELETE /products/:id
 func (h *ProductHandlers) DeleteProduct(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -305,12 +551,16 @@ func (h *ProductHandlers) DeleteProduct(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	if err := h.productService.Delete(ctx, tenantID, productID); err != nil {
+		var conflict *services.DependencyConflictError
+		if errors.As(err, &conflict) {
+			return sendDependencyConflict(c, conflict)
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -323,7 +573,7 @@ func (h *ProductHandlers) DeleteProduct(c echo.Context) error {
 func (h *ProductHandlers) SearchProducts(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -340,31 +590,26 @@ func (h *ProductHandlers) SearchProducts(c echo.Context) error {
 		categoryID = &catID
 	}
 
-	limit := 10
-	offset := 0
-
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			limit = l
-		}
-	}
-
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
 	}
 
-	products, err := h.productService.Search(ctx, tenantID, query, categoryID, limit, offset)
+	products, err := h.productService.Search(ctx, tenantID, query, categoryID, page.Limit, page.Offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
+	locale := c.QueryParam("locale")
+	localizeProducts(products, locale)
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"products": products,
-		"limit":    limit,
-		"offset":   offset,
-		"query":    query,
+		"products":    products,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(products)),
+		"query":       query,
+		"locale":      locale,
 	})
 }
 
@@ -372,7 +617,7 @@ func (h *ProductHandlers) SearchProducts(c echo.Context) error {
 func (h *ProductHandlers) GetProductAnalytics(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -397,7 +642,7 @@ func (h *ProductHandlers) UploadProductImage(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -489,7 +734,7 @@ func (h *ProductHandlers) GetProductImages(c echo.Context) error {
 
 	log.Printf("DEBUG: UUID validation successful: %s", productID.String())
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -515,7 +760,7 @@ func (h *ProductHandlers) GetProductImageURL(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -548,7 +793,7 @@ func (h *ProductHandlers) DeleteProductImage(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -566,7 +811,7 @@ func (h *ProductHandlers) DeleteProductImage(c echo.Context) error {
 func (h *ProductHandlers) BulkUpdateProducts(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -593,11 +838,35 @@ func (h *ProductHandlers) BulkUpdateProducts(c echo.Context) error {
 	return c.JSON(statusCode, result)
 }
 
+// BulkDeleteProducts handles POST /products/bulk/delete
+func (h *ProductHandlers) BulkDeleteProducts(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req models.ProductBulkDelete
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if len(req.ProductIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Product IDs are required")
+	}
+	if len(req.ProductIDs) > maxBulkItems {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Cannot delete more than %d products at once", maxBulkItems))
+	}
+
+	return sendBulkResult(c, h.productService.BulkDeleteProducts(ctx, tenantID, &req))
+}
+
 // BulkCreateProducts handles POST /products/bulk/create
 func (h *ProductHandlers) BulkCreateProducts(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
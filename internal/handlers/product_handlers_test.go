@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 
+	"agromart2/internal/services"
+
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -102,4 +107,22 @@ func TestValidateUUIDEnhanced(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestProductWriteError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"no warehouse for opening stock", services.ErrNoWarehouseForStock, http.StatusUnprocessableEntity},
+		{"stock removal beyond on hand", fmt.Errorf("%w: cannot remove 5, 2 on hand", services.ErrInsufficientStock), http.StatusConflict},
+		{"unexpected", errors.New("connection reset"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, productWriteError(tt.err), &httpErr)
+			assert.Equal(t, tt.code, httpErr.Code)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ProductTemplateHandlers handles product templates and creating products
+// from templates or by cloning
+type ProductTemplateHandlers struct {
+	templateService services.ProductTemplateService
+	rbacMiddleware  *middleware.RBACMiddleware
+}
+
+// NewProductTemplateHandlers creates a new product template handlers instance
+func NewProductTemplateHandlers(templateService services.ProductTemplateService, rbacMiddleware *middleware.RBACMiddleware) *ProductTemplateHandlers {
+	return &ProductTemplateHandlers{
+		templateService: templateService,
+		rbacMiddleware:  rbacMiddleware,
+	}
+}
+
+func (h *ProductTemplateHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// productTemplateError maps product template service errors to HTTP errors
+func productTemplateError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidProductTemplate), errors.Is(err, services.ErrInvalidProductCopy):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrDuplicateName), errors.Is(err, services.ErrDuplicateBarcode):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrProductTemplateNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Product template not found")
+	case errors.Is(err, services.ErrCloneSourceNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// ListTemplates handles GET /product-templates
+func (h *ProductTemplateHandlers) ListTemplates(c echo.Context) error {
+	if err := h.requirePermission(c, "product_templates:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	templates, err := h.templateService.ListTemplates(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list product templates")
+	}
+	if templates == nil {
+		templates = []*models.ProductTemplate{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"templates": templates})
+}
+
+// GetTemplate handles GET /product-templates/:id
+func (h *ProductTemplateHandlers) GetTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "product_templates:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID format")
+	}
+
+	template, err := h.templateService.GetTemplate(ctx, tenantID, templateID)
+	if err != nil {
+		return productTemplateError(err, "Failed to retrieve product template")
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// CreateTemplate handles POST /product-templates
+func (h *ProductTemplateHandlers) CreateTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "product_templates:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var template models.ProductTemplate
+	if err := c.Bind(&template); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.templateService.CreateTemplate(ctx, tenantID, &template); err != nil {
+		return productTemplateError(err, "Failed to create product template")
+	}
+
+	return c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTemplate handles PUT /product-templates/:id; products already made
+// from the template keep their attributes
+func (h *ProductTemplateHandlers) UpdateTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "product_templates:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID format")
+	}
+
+	var template models.ProductTemplate
+	if err := c.Bind(&template); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	template.ID = templateID
+
+	if err := h.templateService.UpdateTemplate(ctx, tenantID, &template); err != nil {
+		return productTemplateError(err, "Failed to update product template")
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /product-templates/:id
+func (h *ProductTemplateHandlers) DeleteTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "product_templates:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID format")
+	}
+
+	if err := h.templateService.DeleteTemplate(ctx, tenantID, templateID); err != nil {
+		return productTemplateError(err, "Failed to delete product template")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateFromTemplate handles POST /products/from-template/:templateId
+func (h *ProductTemplateHandlers) CreateFromTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	templateID, err := uuid.Parse(c.Param("templateId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID format")
+	}
+
+	var req models.ProductFromTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	product, err := h.templateService.CreateFromTemplate(ctx, tenantID, templateID, &req)
+	if err != nil {
+		return productTemplateError(err, "Failed to create product from template")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "Product created successfully",
+		"product": product,
+	})
+}
+
+// CloneProduct handles POST /products/:id/clone
+func (h *ProductTemplateHandlers) CloneProduct(c echo.Context) error {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	var req models.CloneProductRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	product, err := h.templateService.CloneProduct(ctx, tenantID, productID, &req)
+	if err != nil {
+		return productTemplateError(err, "Failed to clone product")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "Product cloned successfully",
+		"product": product,
+	})
+}
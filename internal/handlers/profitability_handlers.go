@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProfitabilityHandlers serves gross margin analytics per product, customer and order
+type ProfitabilityHandlers struct {
+	profitability  *analytics.ProfitabilityService
+	calendarSvc    services.TenantCalendarService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewProfitabilityHandlers creates a new profitability handlers instance
+func NewProfitabilityHandlers(profitability *analytics.ProfitabilityService, calendarSvc services.TenantCalendarService,
+	rbacMiddleware *middleware.RBACMiddleware) *ProfitabilityHandlers {
+	return &ProfitabilityHandlers{
+		profitability:  profitability,
+		calendarSvc:    calendarSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *ProfitabilityHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetProfitability handles GET /analytics/profitability?dimension=product|customer|order
+// &from=YYYY-MM-DD&to=YYYY-MM-DD&compare=none|previous_period|previous_year&format=csv
+// or &period=fiscal_quarter and the like in place of from and to. The window
+// defaults to the last 30 days on the tenant's calendar; both ends are inclusive
+func (h *ProfitabilityHandlers) GetProfitability(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	opts := analytics.ProfitabilityOptions{
+		Dimension: models.ProfitabilityByProduct,
+		Compare:   models.ProfitabilityCompareNone,
+	}
+	var err error
+	if opts.From, opts.To, err = reportPeriod(c, services.TenantCalendarOrDefault(ctx, h.calendarSvc, tenantID), 30); err != nil {
+		return err
+	}
+	if dimension := c.QueryParam("dimension"); dimension != "" {
+		opts.Dimension = strings.ToLower(dimension)
+	}
+	if compare := c.QueryParam("compare"); compare != "" {
+		opts.Compare = strings.ToLower(compare)
+	}
+
+	report, err := h.profitability.GetReport(ctx, tenantID, opts)
+	if errors.Is(err, analytics.ErrInvalidProfitabilityQuery) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build profitability report")
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return c.JSON(http.StatusOK, report)
+	}
+	// The CSV is written as is, so cost and margin columns need the permission
+	if err := h.requirePermission(c, "products:view_cost"); err != nil {
+		return err
+	}
+	content, err := analytics.ProfitabilityCSV(report)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export profitability report")
+	}
+	fileName := fmt.Sprintf("profitability_%s_%s_%s.csv", report.Dimension, report.From.Format("20060102"), report.To.Format("20060102"))
+	return sendCSV(c, fileName, content)
+}
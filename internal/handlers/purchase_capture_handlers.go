@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxPurchaseInvoiceSize caps uploaded supplier invoice documents
+const maxPurchaseInvoiceSize = 10 * 1024 * 1024
+
+// PurchaseCaptureHandlers handles OCR capture of supplier invoices and their
+// confirmation into purchase orders
+type PurchaseCaptureHandlers struct {
+	purchaseCaptureSvc services.PurchaseCaptureService
+	rbacMiddleware     *middleware.RBACMiddleware
+}
+
+// NewPurchaseCaptureHandlers creates a new purchase capture handlers instance
+func NewPurchaseCaptureHandlers(purchaseCaptureSvc services.PurchaseCaptureService, rbacMiddleware *middleware.RBACMiddleware) *PurchaseCaptureHandlers {
+	return &PurchaseCaptureHandlers{
+		purchaseCaptureSvc: purchaseCaptureSvc,
+		rbacMiddleware:     rbacMiddleware,
+	}
+}
+
+func (h *PurchaseCaptureHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+type confirmPurchaseCaptureRequest struct {
+	// OverrideReason posts a bill flagged as a likely duplicate
+	OverrideReason *string `json:"override_reason"`
+}
+
+// purchaseCaptureError maps purchase capture service errors to HTTP errors
+func purchaseCaptureError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrPurchaseCaptureNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Purchase invoice capture not found")
+	case errors.Is(err, services.ErrInvalidPurchaseCapture):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPurchaseCaptureConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// sendDuplicateBill reports a supplier invoice confirmed while it looks like
+// an earlier bill, with the bills it matched
+func sendDuplicateBill(c echo.Context, err *services.DuplicateBillError, overrideAllowed bool) error {
+	details := map[string]string{
+		"matches":          strconv.Itoa(len(err.Duplicates)),
+		"override_allowed": strconv.FormatBool(overrideAllowed),
+	}
+	response := common.CreateErrorResponse("POSSIBLE_DUPLICATE_BILL", "This invoice looks like a bill already recorded for the supplier", details)
+	return c.JSON(http.StatusConflict, map[string]interface{}{
+		"error":      response.Error,
+		"duplicates": err.Duplicates,
+	})
+}
+
+// UploadPurchaseInvoice handles POST /purchase-captures, a multipart upload
+// with a "file" holding a supplier invoice image or PDF. The response is the
+// draft read from it, waiting for review
+func (h *PurchaseCaptureHandlers) UploadPurchaseInvoice(c echo.Context) error {
+	if err := h.requirePermission(c, "purchase_captures:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invoice file is required")
+	}
+	if file.Size > maxPurchaseInvoiceSize {
+		return echo.NewHTTPError(http.StatusBadRequest, "File size exceeds maximum limit of 10MB")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open invoice file")
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(io.LimitReader(src, maxPurchaseInvoiceSize))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file content")
+	}
+	contentType := http.DetectContentType(content)
+	allowedTypes := map[string]bool{
+		"image/jpeg":      true,
+		"image/png":       true,
+		"image/webp":      true,
+		"application/pdf": true,
+	}
+	if !allowedTypes[contentType] {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file type. Only JPEG, PNG, WebP images and PDF documents are allowed")
+	}
+
+	capture, err := h.purchaseCaptureSvc.Upload(ctx, tenantID, userID, file.Filename, contentType, content)
+	if err != nil {
+		return purchaseCaptureError(err, "Failed to capture purchase invoice")
+	}
+	return c.JSON(http.StatusCreated, capture)
+}
+
+// ListPurchaseCaptures handles GET /purchase-captures?status=&order_id=.
+// order_id finds the source document of a purchase order
+func (h *PurchaseCaptureHandlers) ListPurchaseCaptures(c echo.Context) error {
+	if err := h.requirePermission(c, "purchase_captures:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	var orderID *uuid.UUID
+	if raw := c.QueryParam("order_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID format")
+		}
+		orderID = &parsed
+	}
+
+	captures, err := h.purchaseCaptureSvc.List(ctx, tenantID, c.QueryParam("status"), orderID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list purchase invoice captures")
+	}
+	if captures == nil {
+		captures = []*models.PurchaseInvoiceCapture{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"captures":    captures,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(captures)),
+	})
+}
+
+// GetPurchaseCapture handles GET /purchase-captures/:id
+func (h *PurchaseCaptureHandlers) GetPurchaseCapture(c echo.Context) error {
+	if err := h.requirePermission(c, "purchase_captures:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid capture ID format")
+	}
+
+	capture, err := h.purchaseCaptureSvc.Get(ctx, tenantID, id)
+	if err != nil {
+		return purchaseCaptureError(err, "Failed to retrieve purchase invoice capture")
+	}
+	return c.JSON(http.StatusOK, capture)
+}
+
+// UpdatePurchaseCapture handles PUT /purchase-captures/:id, replacing the
+// extracted draft with the reviewer's corrections
+func (h *PurchaseCaptureHandlers) UpdatePurchaseCapture(c echo.Context) error {
+	if err := h.requirePermission(c, "purchase_captures:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid capture ID format")
+	}
+
+	var draft models.PurchaseCaptureDraft
+	if err := c.Bind(&draft); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	capture, err := h.purchaseCaptureSvc.UpdateDraft(ctx, tenantID, id, &draft)
+	if err != nil {
+		return purchaseCaptureError(err, "Failed to update purchase invoice capture")
+	}
+	return c.JSON(http.StatusOK, capture)
+}
+
+// ConfirmPurchaseCapture handles POST /purchase-captures/:id/confirm. A bill
+// flagged as a likely duplicate is refused with 409 unless the body carries
+// an override_reason and the caller holds purchase_captures:override_duplicates
+func (h *PurchaseCaptureHandlers) ConfirmPurchaseCapture(c echo.Context) error {
+	return h.finishCapture(c, true)
+}
+
+// DiscardPurchaseCapture handles POST /purchase-captures/:id/discard
+func (h *PurchaseCaptureHandlers) DiscardPurchaseCapture(c echo.Context) error {
+	return h.finishCapture(c, false)
+}
+
+func (h *PurchaseCaptureHandlers) finishCapture(c echo.Context, confirm bool) error {
+	if err := h.requirePermission(c, "purchase_captures:manage"); err != nil {
+		return err
+	}
+
+	var req confirmPurchaseCaptureRequest
+	if confirm && c.Request().ContentLength > 0 {
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+		}
+	}
+	if req.OverrideReason != nil {
+		if err := h.requirePermission(c, "purchase_captures:override_duplicates"); err != nil {
+			return err
+		}
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid capture ID format")
+	}
+
+	var capture *models.PurchaseInvoiceCapture
+	if confirm {
+		capture, err = h.purchaseCaptureSvc.Confirm(ctx, tenantID, id, userID, req.OverrideReason)
+	} else {
+		capture, err = h.purchaseCaptureSvc.Discard(ctx, tenantID, id, userID)
+	}
+	if err != nil {
+		var duplicateErr *services.DuplicateBillError
+		if errors.As(err, &duplicateErr) {
+			overrideAllowed := h.requirePermission(c, "purchase_captures:override_duplicates") == nil
+			return sendDuplicateBill(c, duplicateErr, overrideAllowed)
+		}
+		return purchaseCaptureError(err, "Failed to review purchase invoice capture")
+	}
+	return c.JSON(http.StatusOK, capture)
+}
+
+// CheckPurchaseCaptureDuplicates handles GET /purchase-captures/:id/duplicates,
+// comparing a bill under review with the supplier's earlier bills
+func (h *PurchaseCaptureHandlers) CheckPurchaseCaptureDuplicates(c echo.Context) error {
+	if err := h.requirePermission(c, "purchase_captures:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid capture ID format")
+	}
+
+	duplicates, err := h.purchaseCaptureSvc.CheckDuplicates(ctx, tenantID, id)
+	if err != nil {
+		return purchaseCaptureError(err, "Failed to check for duplicate bills")
+	}
+	if duplicates == nil {
+		duplicates = []*models.SupplierBillDuplicate{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"duplicates": duplicates})
+}
+
+// GetDuplicateBillReport handles GET /purchase-captures/duplicates?status=&from=&to=,
+// the flagged duplicate supplier bills over a period (default the last 90 days)
+func (h *PurchaseCaptureHandlers) GetDuplicateBillReport(c echo.Context) error {
+	if err := h.requirePermission(c, "purchase_captures:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -89)
+	if raw := c.QueryParam("from"); raw != "" {
+		if from, err = time.Parse("2006-01-02", raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		if to, err = time.Parse("2006-01-02", raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return echo.NewHTTPError(http.StatusBadRequest, "to must not be before from")
+	}
+
+	// to is inclusive
+	report, err := h.purchaseCaptureSvc.DuplicateReport(ctx, tenantID, c.QueryParam("status"), from, to.AddDate(0, 0, 1), page.Limit, page.Offset)
+	if err != nil {
+		return purchaseCaptureError(err, "Failed to build duplicate bill report")
+	}
+	report.To = to
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"report":      report,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(report.Duplicates)),
+	})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// PurchaseReceiptHandlers handles receiving purchase orders with landed cost charges
+type PurchaseReceiptHandlers struct {
+	receiptService services.PurchaseReceiptService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewPurchaseReceiptHandlers creates a new purchase receipt handlers instance
+func NewPurchaseReceiptHandlers(receiptService services.PurchaseReceiptService, rbacMiddleware *middleware.RBACMiddleware) *PurchaseReceiptHandlers {
+	return &PurchaseReceiptHandlers{
+		receiptService: receiptService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *PurchaseReceiptHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ReceivePurchase handles POST /purchase-receipts
+func (h *PurchaseReceiptHandlers) ReceivePurchase(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:receive"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	var receivedBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		receivedBy = &userID
+	}
+
+	var req services.ReceivePurchaseRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	receipt, err := h.receiptService.Receive(ctx, tenantID, receivedBy, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReceipt) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, services.ErrUnsuitableWarehouse) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to receive purchase orders")
+	}
+
+	return c.JSON(http.StatusCreated, receipt)
+}
+
+// ListReceipts handles GET /purchase-receipts?limit=&offset=
+func (h *PurchaseReceiptHandlers) ListReceipts(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50})
+	if err != nil {
+		return err
+	}
+
+	receipts, err := h.receiptService.ListReceipts(ctx, tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve purchase receipts")
+	}
+	if receipts == nil {
+		receipts = []*models.PurchaseReceipt{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"receipts":    receipts,
+		"next_cursor": page.NextCursor(len(receipts)),
+	})
+}
+
+// GetReceipt handles GET /purchase-receipts/:id
+func (h *PurchaseReceiptHandlers) GetReceipt(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	receiptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid receipt ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	receipt, err := h.receiptService.GetReceipt(ctx, tenantID, receiptID)
+	if err != nil {
+		if errors.Is(err, services.ErrReceiptNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Purchase receipt not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve purchase receipt")
+	}
+
+	return c.JSON(http.StatusOK, receipt)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// PurchaseRequisitionHandlers handles the requisition to purchase order workflow
+type PurchaseRequisitionHandlers struct {
+	requisitionService services.PurchaseRequisitionService
+	delegations        services.ApprovalDelegationService
+	rbacMiddleware     *middleware.RBACMiddleware
+}
+
+// NewPurchaseRequisitionHandlers creates a new purchase requisition handlers instance
+func NewPurchaseRequisitionHandlers(requisitionService services.PurchaseRequisitionService, delegations services.ApprovalDelegationService, rbacMiddleware *middleware.RBACMiddleware) *PurchaseRequisitionHandlers {
+	return &PurchaseRequisitionHandlers{
+		requisitionService: requisitionService,
+		delegations:        delegations,
+		rbacMiddleware:     rbacMiddleware,
+	}
+}
+
+func (h *PurchaseRequisitionHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// requisitionError maps requisition service errors to HTTP errors
+func requisitionError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrRequisitionNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Purchase requisition not found")
+	case errors.Is(err, services.ErrInvalidRequisition):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// currentUser returns the authenticated user's ID, if any
+func currentUser(c echo.Context) *uuid.UUID {
+	if userID, ok := common.RequestContextFrom(c.Request().Context()).User(); ok {
+		return &userID
+	}
+	return nil
+}
+
+// RaiseRequisition handles POST /purchase-requisitions
+func (h *PurchaseRequisitionHandlers) RaiseRequisition(c echo.Context) error {
+	if err := h.requirePermission(c, "requisitions:create"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req services.RaiseRequisitionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	requisition, err := h.requisitionService.Raise(ctx, tenantID, currentUser(c), &req)
+	if err != nil {
+		return requisitionError(err, "Failed to raise purchase requisition")
+	}
+
+	return c.JSON(http.StatusCreated, requisition)
+}
+
+// ListRequisitions handles GET /purchase-requisitions?status=&warehouse_id=&order_id=&limit=&offset=
+// order_id finds the requisitions behind a purchase order
+func (h *PurchaseRequisitionHandlers) ListRequisitions(c echo.Context) error {
+	if err := h.requirePermission(c, "requisitions:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	filter := &models.PurchaseRequisitionFilter{}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	filter.Limit, filter.Offset = page.Limit, page.Offset
+	if status := c.QueryParam("status"); status != "" {
+		filter.Status = &status
+	}
+	if warehouseIDStr := c.QueryParam("warehouse_id"); warehouseIDStr != "" {
+		warehouseID, err := uuid.Parse(warehouseIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+		}
+		filter.WarehouseID = &warehouseID
+	}
+	if orderIDStr := c.QueryParam("order_id"); orderIDStr != "" {
+		orderID, err := uuid.Parse(orderIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID format")
+		}
+		filter.OrderID = &orderID
+	}
+
+	requisitions, err := h.requisitionService.List(ctx, tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve purchase requisitions")
+	}
+	if requisitions == nil {
+		requisitions = []*models.PurchaseRequisition{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"requisitions": requisitions,
+		"next_cursor":  page.NextCursor(len(requisitions)),
+	})
+}
+
+// GetRequisition handles GET /purchase-requisitions/:id, including the
+// purchase order and receipt it led to
+func (h *PurchaseRequisitionHandlers) GetRequisition(c echo.Context) error {
+	if err := h.requirePermission(c, "requisitions:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	requisitionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid requisition ID format")
+	}
+
+	trace, err := h.requisitionService.Get(ctx, tenantID, requisitionID)
+	if err != nil {
+		return requisitionError(err, "Failed to retrieve purchase requisition")
+	}
+
+	return c.JSON(http.StatusOK, trace)
+}
+
+type approveRequisitionRequest struct {
+	SupplierID *uuid.UUID `json:"supplier_id"`
+}
+
+// ApproveRequisition handles POST /purchase-requisitions/:id/approve
+func (h *PurchaseRequisitionHandlers) ApproveRequisition(c echo.Context) error {
+	delegation, err := requireApprover(c, h.rbacMiddleware, h.delegations, models.ApprovalKindRequisition, "requisitions:approve")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	requisitionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid requisition ID format")
+	}
+
+	var req approveRequisitionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	requisition, err := h.requisitionService.Approve(ctx, tenantID, requisitionID, currentUser(c), req.SupplierID)
+	if err != nil {
+		return requisitionError(err, "Failed to approve purchase requisition")
+	}
+	recordDelegatedApproval(c, h.delegations, delegation, models.ApprovalKindRequisition, requisition.ID, "approved")
+
+	return c.JSON(http.StatusOK, requisition)
+}
+
+type rejectRequisitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RejectRequisition handles POST /purchase-requisitions/:id/reject
+func (h *PurchaseRequisitionHandlers) RejectRequisition(c echo.Context) error {
+	delegation, err := requireApprover(c, h.rbacMiddleware, h.delegations, models.ApprovalKindRequisition, "requisitions:approve")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	requisitionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid requisition ID format")
+	}
+
+	var req rejectRequisitionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	requisition, err := h.requisitionService.Reject(ctx, tenantID, requisitionID, currentUser(c), req.Reason)
+	if err != nil {
+		return requisitionError(err, "Failed to reject purchase requisition")
+	}
+	recordDelegatedApproval(c, h.delegations, delegation, models.ApprovalKindRequisition, requisition.ID, "rejected")
+
+	return c.JSON(http.StatusOK, requisition)
+}
+
+// CancelRequisition handles POST /purchase-requisitions/:id/cancel
+func (h *PurchaseRequisitionHandlers) CancelRequisition(c echo.Context) error {
+	if err := h.requirePermission(c, "requisitions:create"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	requisitionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid requisition ID format")
+	}
+
+	requisition, err := h.requisitionService.Cancel(ctx, tenantID, requisitionID)
+	if err != nil {
+		return requisitionError(err, "Failed to cancel purchase requisition")
+	}
+
+	return c.JSON(http.StatusOK, requisition)
+}
+
+// ConvertRequisitions handles POST /purchase-requisitions/convert
+func (h *PurchaseRequisitionHandlers) ConvertRequisitions(c echo.Context) error {
+	if err := h.requirePermission(c, "requisitions:convert"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req services.ConvertRequisitionsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	purchaseOrders, err := h.requisitionService.Convert(ctx, tenantID, &req)
+	if err != nil {
+		return requisitionError(err, "Failed to convert purchase requisitions")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"purchase_orders": purchaseOrders,
+	})
+}
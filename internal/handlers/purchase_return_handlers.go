@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// PurchaseReturnHandlers handles returns to suppliers, their debit notes and
+// supplier statements
+type PurchaseReturnHandlers struct {
+	returnService  services.PurchaseReturnService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewPurchaseReturnHandlers creates a new purchase return handlers instance
+func NewPurchaseReturnHandlers(returnService services.PurchaseReturnService, rbacMiddleware *middleware.RBACMiddleware) *PurchaseReturnHandlers {
+	return &PurchaseReturnHandlers{
+		returnService:  returnService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *PurchaseReturnHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// CreatePurchaseReturn handles POST /purchase-returns
+func (h *PurchaseReturnHandlers) CreatePurchaseReturn(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:return"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	var returnedBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		returnedBy = &userID
+	}
+
+	var req models.PurchaseReturnRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	ret, err := h.returnService.CreateReturn(ctx, tenantID, returnedBy, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPurchaseReturn) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, services.ErrPeriodClosed) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to return goods to supplier")
+	}
+
+	return c.JSON(http.StatusCreated, ret)
+}
+
+// ListPurchaseReturns handles GET /purchase-returns?order_id=&limit=&offset=
+func (h *PurchaseReturnHandlers) ListPurchaseReturns(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var orderID *uuid.UUID
+	if raw := c.QueryParam("order_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID format")
+		}
+		orderID = &parsed
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50})
+	if err != nil {
+		return err
+	}
+
+	returns, err := h.returnService.ListReturns(ctx, tenantID, orderID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve purchase returns")
+	}
+	if returns == nil {
+		returns = []*models.PurchaseReturn{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"returns":     returns,
+		"next_cursor": page.NextCursor(len(returns)),
+	})
+}
+
+// GetPurchaseReturn handles GET /purchase-returns/:id
+func (h *PurchaseReturnHandlers) GetPurchaseReturn(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	returnID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid return ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	ret, err := h.returnService.GetReturn(ctx, tenantID, returnID)
+	if err != nil {
+		if errors.Is(err, services.ErrPurchaseReturnNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Purchase return not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve purchase return")
+	}
+
+	return c.JSON(http.StatusOK, ret)
+}
+
+// ListDebitNotes handles GET /debit-notes?supplier_id=&from=&to=, the period
+// defaulting to the current month to date
+func (h *PurchaseReturnHandlers) ListDebitNotes(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var supplierID *uuid.UUID
+	if raw := c.QueryParam("supplier_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid supplier ID format")
+		}
+		supplierID = &parsed
+	}
+	from, to, err := parseStatementPeriod(c)
+	if err != nil {
+		return err
+	}
+
+	notes, err := h.returnService.ListDebitNotes(ctx, tenantID, supplierID, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list debit notes")
+	}
+	if notes == nil {
+		notes = []*models.DebitNote{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"debit_notes": notes})
+}
+
+// GetSupplierStatement handles GET /suppliers/:id/statement?from=&to=, the
+// period defaulting to the current month to date
+func (h *PurchaseReturnHandlers) GetSupplierStatement(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid supplier ID format")
+	}
+	from, to, err := parseStatementPeriod(c)
+	if err != nil {
+		return err
+	}
+
+	statement, err := h.returnService.SupplierStatement(ctx, tenantID, supplierID, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrSupplierNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Supplier not found")
+		}
+		if errors.Is(err, services.ErrInvalidPurchaseReturn) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate supplier statement")
+	}
+
+	return c.JSON(http.StatusOK, statement)
+}
+
+// GetITCReversals handles GET /reports/gstr3b/itc-reversals?month=2025-08,
+// the input tax credit to reverse for debit notes issued in the month, the
+// current one by default
+func (h *PurchaseReturnHandlers) GetITCReversals(c echo.Context) error {
+	if err := h.requirePermission(c, "purchases:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	period := time.Now()
+	if month := c.QueryParam("month"); month != "" {
+		parsed, err := time.Parse("2006-01", month)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "month must be in YYYY-MM format")
+		}
+		period = parsed
+	}
+
+	report, err := h.returnService.ITCReversals(ctx, tenantID, period)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate ITC reversal report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
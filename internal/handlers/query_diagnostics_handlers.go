@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"agromart2/internal/jobs"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	queryDiagnosticsDefaultWindow = 24 * time.Hour
+	queryDiagnosticsDefaultLimit  = 50
+	queryDiagnosticsMaxLimit      = 500
+)
+
+// QueryDiagnosticsHandlers reports the slow and failing queries recorded by
+// the query audit
+type QueryDiagnosticsHandlers struct {
+	queryAudit     *jobs.QueryAuditService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewQueryDiagnosticsHandlers creates a new query diagnostics handlers instance
+func NewQueryDiagnosticsHandlers(queryAudit *jobs.QueryAuditService, rbacMiddleware *middleware.RBACMiddleware) *QueryDiagnosticsHandlers {
+	return &QueryDiagnosticsHandlers{
+		queryAudit:     queryAudit,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *QueryDiagnosticsHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ListQueryOffenders handles GET /admin/query-diagnostics?tenant_id=&since=&order_by=&limit=,
+// listing the worst queries per tenant (platform admin only). since is a
+// duration such as 6h or an RFC 3339 time and defaults to the last day;
+// order_by is total_time, max_duration or errors
+func (h *QueryDiagnosticsHandlers) ListQueryOffenders(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:read_query_diagnostics"); err != nil {
+		return err
+	}
+
+	filter := models.QueryOffenderFilter{
+		Since:   time.Now().Add(-queryDiagnosticsDefaultWindow),
+		OrderBy: c.QueryParam("order_by"),
+	}
+	if tenantStr := c.QueryParam("tenant_id"); tenantStr != "" {
+		tenantID, err := uuid.Parse(tenantStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+		}
+		filter.TenantID = &tenantID
+	}
+	if sinceStr := c.QueryParam("since"); sinceStr != "" {
+		if window, err := time.ParseDuration(sinceStr); err == nil && window > 0 {
+			filter.Since = time.Now().Add(-window)
+		} else if since, err := time.Parse(time.RFC3339, sinceStr); err == nil {
+			filter.Since = since
+		} else {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid since parameter, use a duration such as 6h or an RFC 3339 time")
+		}
+	}
+	switch filter.OrderBy {
+	case "", "total_time", "max_duration", "errors":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order_by, use total_time, max_duration or errors")
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: queryDiagnosticsDefaultLimit, MaxSize: queryDiagnosticsMaxLimit})
+	if err != nil {
+		return err
+	}
+	filter.Limit = page.Limit
+
+	offenders, err := h.queryAudit.TopOffenders(c.Request().Context(), filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load query diagnostics")
+	}
+	if offenders == nil {
+		offenders = []*models.QueryOffender{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"since":     filter.Since,
+		"offenders": offenders,
+		"dropped":   h.queryAudit.Dropped(),
+	})
+}
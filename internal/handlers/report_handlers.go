@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// ReportHandlers handles inventory, receivables and cash flow reports
+type ReportHandlers struct {
+	inventoryAging   *analytics.InventoryAgingService
+	receivablesAging *analytics.ReceivablesAgingService
+	cashFlow         *analytics.CashFlowProjectionService
+	rbacMiddleware   *middleware.RBACMiddleware
+}
+
+// NewReportHandlers creates a new report handlers instance
+func NewReportHandlers(inventoryAging *analytics.InventoryAgingService, receivablesAging *analytics.ReceivablesAgingService, cashFlow *analytics.CashFlowProjectionService, rbacMiddleware *middleware.RBACMiddleware) *ReportHandlers {
+	return &ReportHandlers{
+		inventoryAging:   inventoryAging,
+		receivablesAging: receivablesAging,
+		cashFlow:         cashFlow,
+		rbacMiddleware:   rbacMiddleware,
+	}
+}
+
+func (h *ReportHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetInventoryAging handles GET /reports/inventory-aging?warehouse_id=&dead_stock_days=180&min_dead_stock_value=&include_items=true
+func (h *ReportHandlers) GetInventoryAging(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	opts := analytics.InventoryAgingOptions{
+		IncludeItems: c.QueryParam("include_items") == "true",
+	}
+	if warehouseIDStr := c.QueryParam("warehouse_id"); warehouseIDStr != "" {
+		warehouseID, err := uuid.Parse(warehouseIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+		}
+		opts.WarehouseID = &warehouseID
+	}
+	if daysStr := c.QueryParam("dead_stock_days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 31 || days > 3650 {
+			return echo.NewHTTPError(http.StatusBadRequest, "dead_stock_days must be between 31 and 3650")
+		}
+		opts.DeadStockDays = days
+	}
+	if valueStr := c.QueryParam("min_dead_stock_value"); valueStr != "" {
+		value, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil || value < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid min_dead_stock_value parameter")
+		}
+		opts.MinDeadStockValue = value
+	}
+
+	report, err := h.inventoryAging.GetInventoryAging(ctx, tenantID, opts)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build inventory aging report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// sendCSV responds with a CSV attachment
+func sendCSV(c echo.Context, fileName string, content []byte) error {
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fileName))
+	return c.Blob(http.StatusOK, "text/csv", content)
+}
+
+// GetReceivablesAging handles GET /reports/receivables-aging?format=csv
+// It groups open invoice balances by customer into 30/60/90 day buckets
+func (h *ReportHandlers) GetReceivablesAging(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	report, err := h.receivablesAging.GetReceivablesAging(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build receivables aging report")
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return c.JSON(http.StatusOK, report)
+	}
+	content, err := analytics.ReceivablesAgingCSV(report)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export receivables aging report")
+	}
+	return sendCSV(c, fmt.Sprintf("receivables_aging_%s.csv", report.AsOf.Format("20060102")), content)
+}
+
+// GetCustomerReceivables handles GET /reports/receivables-aging/:customer_id?format=csv
+// It drills a customer's aging down to their open invoices
+func (h *ReportHandlers) GetCustomerReceivables(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("customer_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	detail, err := h.receivablesAging.GetCustomerAging(ctx, tenantID, customerID)
+	if errors.Is(err, analytics.ErrCustomerNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build customer receivables")
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return c.JSON(http.StatusOK, detail)
+	}
+	content, err := analytics.ReceivableInvoicesCSV(detail)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export customer receivables")
+	}
+	fileName := fmt.Sprintf("receivables_%s_%s.csv", customerID.String()[:8], detail.AsOf.Format("20060102"))
+	return sendCSV(c, fileName, content)
+}
+
+// GetReceivablesSummary handles GET /dashboard/receivables-aging?top=5
+// It feeds the dashboard widget with bucket totals and the top customers
+func (h *ReportHandlers) GetReceivablesSummary(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	top := analytics.DefaultTopCustomers
+	if topStr := c.QueryParam("top"); topStr != "" {
+		n, err := strconv.Atoi(topStr)
+		if err != nil || n < 1 || n > 50 {
+			return echo.NewHTTPError(http.StatusBadRequest, "top must be between 1 and 50")
+		}
+		top = n
+	}
+
+	summary, err := h.receivablesAging.GetSummary(ctx, tenantID, top)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build receivables summary")
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+// parseCashFlowAssumptions overrides the default projection assumptions with
+// any given in the query string
+func parseCashFlowAssumptions(c echo.Context) (models.CashFlowAssumptions, error) {
+	assumptions := analytics.DefaultCashFlowAssumptions()
+
+	floatParam := func(name string, min, max float64, dst *float64) error {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			return nil
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < min || value > max {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid %s parameter", name))
+		}
+		*dst = value
+		return nil
+	}
+	intParam := func(name string, min, max int, dst *int) error {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			return nil
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < min || value > max {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be between %d and %d", name, min, max))
+		}
+		*dst = value
+		return nil
+	}
+	boolParam := func(name string, dst *bool) error {
+		raw := c.QueryParam(name)
+		if raw == "" {
+			return nil
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid %s parameter", name))
+		}
+		*dst = value
+		return nil
+	}
+
+	for _, err := range []error{
+		floatParam("opening_balance", -1e12, 1e12, &assumptions.OpeningBalance),
+		floatParam("overdue_collection_rate", 0, 1, &assumptions.OverdueCollectionRate),
+		floatParam("expense_growth_percent", -100, 1000, &assumptions.ExpenseGrowthPercent),
+		intParam("collection_delay_days", -90, 365, &assumptions.CollectionDelayDays),
+		intParam("supplier_payment_days", 0, 365, &assumptions.SupplierPaymentDays),
+		intParam("expense_months", 1, 12, &assumptions.ExpenseMonths),
+		boolParam("use_payment_history", &assumptions.UsePaymentHistory),
+		boolParam("include_expenses", &assumptions.IncludeExpenses),
+	} {
+		if err != nil {
+			return assumptions, err
+		}
+	}
+	return assumptions, nil
+}
+
+// GetCashFlowProjection handles GET /reports/cashflow-projection?opening_balance=&collection_delay_days=
+// &overdue_collection_rate=&supplier_payment_days=&use_payment_history=&include_expenses=&expense_months=&expense_growth_percent=
+// It projects weekly inflows and outflows over the next 13 weeks; every
+// assumption can be overridden per run
+func (h *ReportHandlers) GetCashFlowProjection(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	assumptions, err := parseCashFlowAssumptions(c)
+	if err != nil {
+		return err
+	}
+
+	projection, err := h.cashFlow.GetProjection(ctx, tenantID, assumptions)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build cash flow projection")
+	}
+
+	return c.JSON(http.StatusOK, projection)
+}
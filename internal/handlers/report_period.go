@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// reportPeriod reads the inclusive from and to dates of a report. A named
+// ?period= is resolved on the tenant's calendar; otherwise ?from= and ?to=
+// override a window of defaultDays ending on the tenant's today
+func reportPeriod(c echo.Context, calendar *models.TenantCalendar, defaultDays int) (time.Time, time.Time, error) {
+	if period := c.QueryParam("period"); period != "" {
+		from, to, ok := calendar.Period(period, time.Now())
+		if !ok {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest,
+				"period must be month, previous_month, fiscal_quarter, previous_fiscal_quarter, fiscal_year or previous_fiscal_year")
+		}
+		return from, to, nil
+	}
+
+	to := calendar.Today(time.Now())
+	from := to.AddDate(0, 0, -(defaultDays - 1))
+	var err error
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+	}
+	if toStr := c.QueryParam("to"); toStr != "" {
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+	}
+	return from, to, nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"agromart2/internal/caching"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Response cache lifetimes; domain events purge entries sooner when the data
+// behind them changes
+const (
+	catalogResponseTTL    = 10 * time.Minute
+	categoryResponseTTL   = 30 * time.Minute
+	analyticsResponseTTL  = 10 * time.Minute
+	responseCacheHeader   = "X-Cache"
+	responseCacheHit      = "HIT"
+	responseCacheMiss     = "MISS"
+	responseCacheBypassed = "BYPASS"
+)
+
+// cachedResponse returns the JSON body cached under key, or builds, encodes
+// and caches it tagged with the surrogate keys. It runs after the permission
+// checks, as a cached body is served to anyone in the tenant who asks for it.
+// Cache failures fall through to building the response
+func cachedResponse(c echo.Context, cache caching.CacheService, key string, surrogateKeys []string, ttl time.Duration,
+	build func() (interface{}, error)) ([]byte, error) {
+	ctx := c.Request().Context()
+	header := c.Response().Header()
+
+	if cache == nil || c.Request().Header.Get("Cache-Control") == "no-cache" {
+		header.Set(responseCacheHeader, responseCacheBypassed)
+	} else if body, err := cache.GetResponse(ctx, key); err != nil {
+		log.Printf("Failed to read cached response: %v", err)
+	} else if body != nil {
+		header.Set(responseCacheHeader, responseCacheHit)
+		return body, nil
+	}
+
+	result, err := build()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to encode response")
+	}
+
+	if header.Get(responseCacheHeader) == "" {
+		header.Set(responseCacheHeader, responseCacheMiss)
+	}
+	if cache != nil {
+		if err := cache.SetResponse(ctx, key, body, surrogateKeys, ttl); err != nil {
+			log.Printf("Failed to cache response: %v", err)
+		}
+	}
+	return body, nil
+}
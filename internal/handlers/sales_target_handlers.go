@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// SalesTargetHandlers handles sales targets, customer territories and the
+// target achievement leaderboard
+type SalesTargetHandlers struct {
+	targetSvc      services.SalesTargetService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewSalesTargetHandlers creates a new sales target handlers instance
+func NewSalesTargetHandlers(targetSvc services.SalesTargetService, rbacMiddleware *middleware.RBACMiddleware) *SalesTargetHandlers {
+	return &SalesTargetHandlers{
+		targetSvc:      targetSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *SalesTargetHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// salesTargetError maps sales target service errors to HTTP errors
+func salesTargetError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrSalesTargetNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Sales target not found")
+	case errors.Is(err, services.ErrTerritoryCustomerNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	case errors.Is(err, services.ErrInvalidSalesTarget):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSalesTargetConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// ListTargets handles GET /sales-targets?month=2025-09&scope=user
+func (h *SalesTargetHandlers) ListTargets(c echo.Context) error {
+	if err := h.requirePermission(c, "targets:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	targets, err := h.targetSvc.ListTargets(ctx, tenantID, c.QueryParam("month"), c.QueryParam("scope"))
+	if err != nil {
+		return salesTargetError(err, "Failed to list sales targets")
+	}
+	if targets == nil {
+		targets = []*models.SalesTarget{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"targets": targets})
+}
+
+// CreateTarget handles POST /sales-targets
+func (h *SalesTargetHandlers) CreateTarget(c echo.Context) error {
+	if err := h.requirePermission(c, "targets:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	var createdBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		createdBy = &userID
+	}
+
+	var req models.SalesTargetRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	target, err := h.targetSvc.CreateTarget(ctx, tenantID, createdBy, &req)
+	if err != nil {
+		return salesTargetError(err, "Failed to create sales target")
+	}
+
+	return c.JSON(http.StatusCreated, target)
+}
+
+// UpdateTarget handles PUT /sales-targets/:id
+func (h *SalesTargetHandlers) UpdateTarget(c echo.Context) error {
+	if err := h.requirePermission(c, "targets:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid sales target ID")
+	}
+
+	var req models.SalesTargetRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	target, err := h.targetSvc.UpdateTarget(ctx, tenantID, targetID, &req)
+	if err != nil {
+		return salesTargetError(err, "Failed to update sales target")
+	}
+
+	return c.JSON(http.StatusOK, target)
+}
+
+// DeleteTarget handles DELETE /sales-targets/:id
+func (h *SalesTargetHandlers) DeleteTarget(c echo.Context) error {
+	if err := h.requirePermission(c, "targets:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid sales target ID")
+	}
+
+	if err := h.targetSvc.DeleteTarget(ctx, tenantID, targetID); err != nil {
+		return salesTargetError(err, "Failed to delete sales target")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SetCustomerTerritory handles PUT /customers/:id/territory, assigning the
+// customer to the sales rep and region its sales count towards
+func (h *SalesTargetHandlers) SetCustomerTerritory(c echo.Context) error {
+	if err := h.requirePermission(c, "targets:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	var req models.CustomerTerritoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.targetSvc.SetCustomerTerritory(ctx, tenantID, customerID, &req); err != nil {
+		return salesTargetError(err, "Failed to assign customer")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetLeaderboard handles GET /analytics/targets?month=2025-09&scope=user
+// It ranks the month's targets of the scope, sales reps by default, by achievement
+func (h *SalesTargetHandlers) GetLeaderboard(c echo.Context) error {
+	if err := h.requirePermission(c, "targets:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	board, err := h.targetSvc.Leaderboard(ctx, tenantID, c.QueryParam("month"), c.QueryParam("scope"))
+	if err != nil {
+		return salesTargetError(err, "Failed to build sales target leaderboard")
+	}
+
+	return c.JSON(http.StatusOK, board)
+}
+
+// CalculateAchievement handles POST /analytics/targets/calculate?month=2025-09
+// It recalculates achievement now rather than waiting for the scheduled job
+func (h *SalesTargetHandlers) CalculateAchievement(c echo.Context) error {
+	if err := h.requirePermission(c, "targets:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	month := time.Now()
+	if monthStr := c.QueryParam("month"); monthStr != "" {
+		parsed, err := time.Parse("2006-01", monthStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "month must be in YYYY-MM format")
+		}
+		month = parsed
+	}
+
+	updated, err := h.targetSvc.CalculateAchievement(ctx, tenantID, month)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate sales target achievement")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"targets_updated": updated})
+}
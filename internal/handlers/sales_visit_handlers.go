@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// SalesVisitHandlers handles field-force visit check-ins and route reports
+type SalesVisitHandlers struct {
+	visitService   services.SalesVisitService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewSalesVisitHandlers creates a new sales visit handlers instance
+func NewSalesVisitHandlers(visitService services.SalesVisitService, rbacMiddleware *middleware.RBACMiddleware) *SalesVisitHandlers {
+	return &SalesVisitHandlers{
+		visitService:   visitService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *SalesVisitHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// visitCaller returns the tenant and user for the request
+func (h *SalesVisitHandlers) visitCaller(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return uuid.Nil, uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "User ID not found")
+	}
+	return tenantID, userID, nil
+}
+
+// visitError maps service errors to HTTP errors
+func visitError(err error, fallback string) error {
+	var geofenceErr *services.GeofenceViolationError
+	switch {
+	case errors.As(err, &geofenceErr):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, map[string]interface{}{
+			"message":    geofenceErr.Error(),
+			"distance_m": geofenceErr.DistanceM,
+			"radius_m":   geofenceErr.RadiusM,
+		})
+	case errors.Is(err, services.ErrVisitNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Visit not found")
+	case errors.Is(err, services.ErrVisitAlreadyOpen):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, fallback+": "+err.Error())
+}
+
+// CheckIn handles POST /visits/check-in
+func (h *SalesVisitHandlers) CheckIn(c echo.Context) error {
+	if err := h.requirePermission(c, "visits:create"); err != nil {
+		return err
+	}
+	tenantID, userID, err := h.visitCaller(c)
+	if err != nil {
+		return err
+	}
+
+	var req services.CheckInRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	visit, err := h.visitService.CheckIn(c.Request().Context(), tenantID, userID, &req)
+	if err != nil {
+		return visitError(err, "Failed to check in")
+	}
+
+	return c.JSON(http.StatusCreated, visit)
+}
+
+// CheckOut handles POST /visits/:id/check-out
+func (h *SalesVisitHandlers) CheckOut(c echo.Context) error {
+	if err := h.requirePermission(c, "visits:create"); err != nil {
+		return err
+	}
+	tenantID, userID, err := h.visitCaller(c)
+	if err != nil {
+		return err
+	}
+
+	visitID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid visit ID format")
+	}
+
+	var req services.CheckOutRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	visit, err := h.visitService.CheckOut(c.Request().Context(), tenantID, userID, visitID, &req)
+	if err != nil {
+		return visitError(err, "Failed to check out")
+	}
+
+	return c.JSON(http.StatusOK, visit)
+}
+
+// UploadPhoto handles POST /visits/:id/photos (multipart field "photo")
+func (h *SalesVisitHandlers) UploadPhoto(c echo.Context) error {
+	if err := h.requirePermission(c, "visits:create"); err != nil {
+		return err
+	}
+	tenantID, userID, err := h.visitCaller(c)
+	if err != nil {
+		return err
+	}
+
+	visitID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid visit ID format")
+	}
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Photo file is required")
+	}
+	const maxFileSize = 5 * 1024 * 1024 // 5MB in bytes
+	if file.Size > maxFileSize {
+		return echo.NewHTTPError(http.StatusBadRequest, "File size exceeds maximum limit of 5MB")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open photo file")
+	}
+	defer src.Close()
+
+	buffer := make([]byte, 512)
+	n, err := src.Read(buffer)
+	if err != nil && err != io.EOF {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file content")
+	}
+	allowedTypes := map[string]bool{
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/webp": true,
+	}
+	if !allowedTypes[http.DetectContentType(buffer[:n])] {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file type. Only JPEG, PNG and WebP images are allowed")
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read file content")
+	}
+
+	var caption *string
+	if value := c.FormValue("caption"); value != "" {
+		caption = &value
+	}
+
+	photo, err := h.visitService.AddPhoto(c.Request().Context(), tenantID, userID, visitID, file.Filename, src, file.Size, caption)
+	if err != nil {
+		if errors.Is(err, services.ErrVisitNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Visit not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to upload photo")
+	}
+
+	return c.JSON(http.StatusCreated, photo)
+}
+
+// authorizeUserScope requires visits:read to look at another user's visits
+func (h *SalesVisitHandlers) authorizeUserScope(c echo.Context, callerID uuid.UUID) (uuid.UUID, error) {
+	userIDStr := c.QueryParam("user_id")
+	if userIDStr == "" {
+		return callerID, h.requirePermission(c, "visits:create")
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID format")
+	}
+	if userID == callerID {
+		return userID, h.requirePermission(c, "visits:create")
+	}
+	return userID, h.requirePermission(c, "visits:read")
+}
+
+// ListVisits handles GET /visits?user_id=&distributor_id=&from=&to=&limit=&offset=
+func (h *SalesVisitHandlers) ListVisits(c echo.Context) error {
+	tenantID, callerID, err := h.visitCaller(c)
+	if err != nil {
+		return err
+	}
+	userID, err := h.authorizeUserScope(c, callerID)
+	if err != nil {
+		return err
+	}
+
+	filter := &models.SalesVisitFilter{UserID: &userID}
+	if distributorIDStr := c.QueryParam("distributor_id"); distributorIDStr != "" {
+		distributorID, err := uuid.Parse(distributorIDStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid distributor ID format")
+		}
+		filter.DistributorID = &distributorID
+	}
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.QueryParam(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid "+param+" timestamp, use RFC3339")
+			}
+			*target = &parsed
+		}
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+	filter.Limit, filter.Offset = page.Limit, page.Offset
+
+	visits, err := h.visitService.ListVisits(c.Request().Context(), tenantID, filter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve visits")
+	}
+	if visits == nil {
+		visits = []*models.SalesVisit{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"visits":      visits,
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+		"next_cursor": page.NextCursor(len(visits)),
+	})
+}
+
+// GetVisit handles GET /visits/:id
+func (h *SalesVisitHandlers) GetVisit(c echo.Context) error {
+	tenantID, callerID, err := h.visitCaller(c)
+	if err != nil {
+		return err
+	}
+
+	visitID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid visit ID format")
+	}
+
+	visit, err := h.visitService.GetVisit(c.Request().Context(), tenantID, visitID)
+	if err != nil {
+		if errors.Is(err, services.ErrVisitNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Visit not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve visit")
+	}
+
+	permission := "visits:read"
+	if visit.UserID == callerID {
+		permission = "visits:create"
+	}
+	if err := h.requirePermission(c, permission); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, visit)
+}
+
+// RouteReport handles GET /visits/route-report?date=YYYY-MM-DD&user_id=&tz=Asia/Kolkata
+func (h *SalesVisitHandlers) RouteReport(c echo.Context) error {
+	tenantID, callerID, err := h.visitCaller(c)
+	if err != nil {
+		return err
+	}
+	userID, err := h.authorizeUserScope(c, callerID)
+	if err != nil {
+		return err
+	}
+
+	loc := time.UTC
+	if tz := c.QueryParam("tz"); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid tz parameter")
+		}
+	}
+
+	date := time.Now().In(loc)
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		date, err = time.ParseInLocation("2006-01-02", dateStr, loc)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid date format, use YYYY-MM-DD")
+		}
+	}
+
+	report, err := h.visitService.RouteReport(c.Request().Context(), tenantID, userID, date, loc)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build route report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
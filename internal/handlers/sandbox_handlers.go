@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// SandboxHandlers marks tenants as sandboxes and resets their demo data
+type SandboxHandlers struct {
+	sandboxSvc     services.SandboxService
+	resetSvc       *jobs.SandboxResetService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewSandboxHandlers creates a new sandbox handlers instance
+func NewSandboxHandlers(sandboxSvc services.SandboxService, resetSvc *jobs.SandboxResetService, rbacMiddleware *middleware.RBACMiddleware) *SandboxHandlers {
+	return &SandboxHandlers{
+		sandboxSvc:     sandboxSvc,
+		resetSvc:       resetSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *SandboxHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ListSandboxes handles GET /admin/sandboxes (platform admin only)
+func (h *SandboxHandlers) ListSandboxes(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_sandboxes"); err != nil {
+		return err
+	}
+
+	sandboxes, err := h.sandboxSvc.ListSandboxes(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list sandboxes")
+	}
+	if sandboxes == nil {
+		sandboxes = []*models.TenantSandbox{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"sandboxes": sandboxes})
+}
+
+// EnableSandbox handles PUT /admin/sandboxes/:tenant_id, making the tenant a
+// sandbox or changing its reset schedule (platform admin only)
+func (h *SandboxHandlers) EnableSandbox(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_sandboxes"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req models.TenantSandboxRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	sandbox, err := h.sandboxSvc.EnableSandbox(ctx, tenantID, userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSandbox):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrSandboxTenantNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "Tenant not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save sandbox")
+	}
+
+	return c.JSON(http.StatusOK, sandbox)
+}
+
+// DisableSandbox handles DELETE /admin/sandboxes/:tenant_id (platform admin only)
+func (h *SandboxHandlers) DisableSandbox(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_sandboxes"); err != nil {
+		return err
+	}
+
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+
+	if err := h.sandboxSvc.DisableSandbox(c.Request().Context(), tenantID); err != nil {
+		if errors.Is(err, services.ErrSandboxNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Tenant is not a sandbox")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to remove sandbox")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ResetSandbox handles POST /admin/sandboxes/:tenant_id/reset, resetting the
+// sandbox to its demo data now (platform admin only)
+func (h *SandboxHandlers) ResetSandbox(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_sandboxes"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, err := uuid.Parse(c.Param("tenant_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID")
+	}
+
+	if _, err := h.sandboxSvc.GetSandbox(ctx, tenantID); err != nil {
+		if errors.Is(err, services.ErrSandboxNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Tenant is not a sandbox")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load sandbox")
+	}
+
+	sandbox, err := h.resetSvc.Reset(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to reset sandbox")
+	}
+
+	return c.JSON(http.StatusOK, sandbox)
+}
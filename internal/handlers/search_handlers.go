@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Suggestion limits; the search box shows a short list per keystroke
+const (
+	defaultSuggestLimit = 8
+	maxSuggestLimit     = 20
+)
+
+// searchPermissions names the permission needed to see each search type;
+// products and orders are listed without one elsewhere too
+var searchPermissions = map[string]string{
+	models.SearchTypeProduct:  "",
+	models.SearchTypeCustomer: "distributors:list",
+	models.SearchTypeSupplier: "suppliers:list",
+	models.SearchTypeOrder:    "",
+	models.SearchTypeInvoice:  "invoices:read",
+}
+
+// Types searched by each endpoint, in the order results are returned
+var (
+	suggestionTypes = []string{models.SearchTypeProduct, models.SearchTypeCustomer, models.SearchTypeSupplier, models.SearchTypeOrder}
+	searchTypes     = []string{models.SearchTypeProduct, models.SearchTypeOrder, models.SearchTypeInvoice, models.SearchTypeCustomer, models.SearchTypeSupplier}
+)
+
+// SearchHandlers handles the global search box
+type SearchHandlers struct {
+	searchService services.SearchService
+	rbacService   services.RBACService
+}
+
+// NewSearchHandlers creates a new search handlers instance
+func NewSearchHandlers(searchService services.SearchService, rbacService services.RBACService) *SearchHandlers {
+	return &SearchHandlers{
+		searchService: searchService,
+		rbacService:   rbacService,
+	}
+}
+
+// allowedTypes returns the types of available named by the types parameter,
+// all of them by default, leaving out those the user may not list
+func (h *SearchHandlers) allowedTypes(c echo.Context, available []string) ([]string, error) {
+	requested := available
+	if typesStr := c.QueryParam("types"); typesStr != "" {
+		requested = nil
+		for _, t := range strings.Split(typesStr, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(available, t) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Unknown search type: "+t)
+			}
+			requested = append(requested, t)
+		}
+	}
+
+	granted, err := grantedPermissions(c, h.rbacService)
+	if err != nil {
+		return nil, err
+	}
+	var types []string
+	for _, t := range requested {
+		if permission := searchPermissions[t]; permission == "" || granted[permission] {
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// Suggest handles GET /search/suggest?q=, returning ranked products,
+// customers, suppliers and orders matching what has been typed so far.
+// types narrows the suggestion types; those the user may not list are left out
+func (h *SearchHandlers) Suggest(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: defaultSuggestLimit, MaxSize: maxSuggestLimit})
+	if err != nil {
+		return err
+	}
+
+	types, err := h.allowedTypes(c, suggestionTypes)
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := h.searchService.Suggest(ctx, tenantID, c.QueryParam("q"), types, page.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to search")
+	}
+
+	return c.JSON(http.StatusOK, suggestions)
+}
+
+// Search handles GET /search?q=, finding products, orders (by number or
+// notes), invoices, customers and suppliers matching an identifier or text
+// without the caller knowing which it belongs to. Results come grouped by
+// type with highlights; page[size] and page[cursor] page every group, and
+// types narrows the search to fetch more of one group
+func (h *SearchHandlers) Search(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 5, MaxSize: 50})
+	if err != nil {
+		return err
+	}
+
+	types, err := h.allowedTypes(c, searchTypes)
+	if err != nil {
+		return err
+	}
+
+	results, err := h.searchService.Search(ctx, tenantID, c.QueryParam("q"), types, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to search")
+	}
+	for _, group := range results.Groups {
+		group.NextCursor = page.NextCursor(len(group.Results))
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSearchService struct {
+	query  string
+	types  []string
+	limit  int
+	offset int
+	found  map[string]int
+}
+
+func (s *stubSearchService) Suggest(ctx context.Context, tenantID uuid.UUID, query string, types []string, limit int) (*models.SearchSuggestions, error) {
+	s.query, s.types, s.limit = query, types, limit
+	return &models.SearchSuggestions{Query: query, Suggestions: []*models.SearchSuggestion{}}, nil
+}
+
+func (s *stubSearchService) Search(ctx context.Context, tenantID uuid.UUID, query string, types []string, limit, offset int) (*models.SearchResults, error) {
+	s.query, s.types, s.limit, s.offset = query, types, limit, offset
+	results := &models.SearchResults{Query: query}
+	for _, t := range types {
+		group := &models.SearchResultGroup{Type: t}
+		for i := 0; i < s.found[t]; i++ {
+			group.Results = append(group.Results, &models.SearchResult{Type: t, ID: uuid.New()})
+		}
+		results.Groups = append(results.Groups, group)
+	}
+	return results, nil
+}
+
+func TestSuggestLeavesOutTypesTheUserCannotList(t *testing.T) {
+	search := &stubSearchService{}
+	h := NewSearchHandlers(search, &stubRBACService{permissions: []string{"suppliers:list"}})
+	c, rec := permissionContext(&stubRBACService{}, "/search/suggest?q=ure", true)
+
+	require.NoError(t, h.Suggest(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ure", search.query)
+	assert.Equal(t, []string{models.SearchTypeProduct, models.SearchTypeSupplier, models.SearchTypeOrder}, search.types)
+	assert.Equal(t, defaultSuggestLimit, search.limit)
+}
+
+func TestSuggestNarrowsToRequestedTypes(t *testing.T) {
+	search := &stubSearchService{}
+	h := NewSearchHandlers(search, &stubRBACService{permissions: []string{"distributors:list", "suppliers:list"}})
+	c, _ := permissionContext(&stubRBACService{}, "/search/suggest?q=ram&types=customer,%20order&limit=5", true)
+
+	require.NoError(t, h.Suggest(c))
+	assert.Equal(t, []string{models.SearchTypeCustomer, models.SearchTypeOrder}, search.types)
+	assert.Equal(t, 5, search.limit)
+}
+
+func TestSuggestRejectsBadParameters(t *testing.T) {
+	h := NewSearchHandlers(&stubSearchService{}, &stubRBACService{})
+	for _, target := range []string{"/search/suggest?q=ure&types=invoice", "/search/suggest?q=ure&limit=50"} {
+		c, _ := permissionContext(&stubRBACService{}, target, true)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, h.Suggest(c), &httpErr, target)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, target)
+	}
+}
+
+func TestSearchPagesEachGroup(t *testing.T) {
+	search := &stubSearchService{found: map[string]int{models.SearchTypeInvoice: 2, models.SearchTypeOrder: 1}}
+	h := NewSearchHandlers(search, &stubRBACService{permissions: []string{"invoices:read"}})
+	c, rec := permissionContext(&stubRBACService{}, "/search?q=inv-2025&page[size]=2", true)
+
+	require.NoError(t, h.Search(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{models.SearchTypeProduct, models.SearchTypeOrder, models.SearchTypeInvoice}, search.types)
+	assert.Equal(t, 2, search.limit)
+
+	body := decodeBody(t, rec)
+	cursors := map[string]interface{}{}
+	for _, group := range body["groups"].([]interface{}) {
+		g := group.(map[string]interface{})
+		cursors[g["type"].(string)] = g["next_cursor"]
+	}
+	assert.Equal(t, "", cursors[models.SearchTypeOrder])
+	assert.NotEmpty(t, cursors[models.SearchTypeInvoice])
+}
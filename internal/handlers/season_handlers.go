@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// SeasonHandlers handles season calendar, product season tag and seasonal demand requests
+type SeasonHandlers struct {
+	seasonService  services.SeasonService
+	seasonalDemand *analytics.SeasonalDemandService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewSeasonHandlers creates a new season handlers instance
+func NewSeasonHandlers(seasonService services.SeasonService, seasonalDemand *analytics.SeasonalDemandService, rbacMiddleware *middleware.RBACMiddleware) *SeasonHandlers {
+	return &SeasonHandlers{
+		seasonService:  seasonService,
+		seasonalDemand: seasonalDemand,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+type seasonRequest struct {
+	Name       string  `json:"name"`
+	SeasonType string  `json:"season_type"`
+	Region     *string `json:"region"`
+	StartMonth int     `json:"start_month"`
+	StartDay   int     `json:"start_day"`
+	EndMonth   int     `json:"end_month"`
+	EndDay     int     `json:"end_day"`
+	LeadDays   *int    `json:"lead_days"`
+}
+
+func (h *SeasonHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ListSeasons handles GET /seasons
+func (h *SeasonHandlers) ListSeasons(c echo.Context) error {
+	if err := h.requirePermission(c, "seasons:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	seasons, err := h.seasonService.List(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve seasons")
+	}
+	if seasons == nil {
+		seasons = []*models.Season{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"seasons": seasons,
+	})
+}
+
+// CreateSeason handles POST /seasons
+func (h *SeasonHandlers) CreateSeason(c echo.Context) error {
+	if err := h.requirePermission(c, "seasons:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req seasonRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	season := &models.Season{
+		TenantID:   tenantID,
+		Name:       req.Name,
+		SeasonType: req.SeasonType,
+		Region:     req.Region,
+		StartMonth: req.StartMonth,
+		StartDay:   req.StartDay,
+		EndMonth:   req.EndMonth,
+		EndDay:     req.EndDay,
+		LeadDays:   30,
+	}
+	if req.LeadDays != nil {
+		season.LeadDays = *req.LeadDays
+	}
+
+	if err := h.seasonService.Create(ctx, season); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, season)
+}
+
+// CreateDefaultSeasons handles POST /seasons/defaults
+func (h *SeasonHandlers) CreateDefaultSeasons(c echo.Context) error {
+	if err := h.requirePermission(c, "seasons:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	created, err := h.seasonService.CreateDefaults(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create default seasons")
+	}
+	if created == nil {
+		created = []*models.Season{}
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"seasons": created,
+	})
+}
+
+// UpdateSeason handles PUT /seasons/:id
+func (h *SeasonHandlers) UpdateSeason(c echo.Context) error {
+	if err := h.requirePermission(c, "seasons:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	seasonID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid season ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req seasonRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	season, err := h.seasonService.GetByID(ctx, tenantID, seasonID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Season not found")
+	}
+
+	season.Name = req.Name
+	season.SeasonType = req.SeasonType
+	season.Region = req.Region
+	season.StartMonth = req.StartMonth
+	season.StartDay = req.StartDay
+	season.EndMonth = req.EndMonth
+	season.EndDay = req.EndDay
+	if req.LeadDays != nil {
+		season.LeadDays = *req.LeadDays
+	}
+
+	if err := h.seasonService.Update(ctx, season); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, season)
+}
+
+// DeleteSeason handles DELETE /seasons/:id
+func (h *SeasonHandlers) DeleteSeason(c echo.Context) error {
+	if err := h.requirePermission(c, "seasons:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	seasonID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid season ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	if err := h.seasonService.Delete(ctx, tenantID, seasonID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete season")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetProductSeasons handles GET /products/:id/seasons
+func (h *SeasonHandlers) GetProductSeasons(c echo.Context) error {
+	if err := h.requirePermission(c, "seasons:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	tags, err := h.seasonService.GetProductTags(ctx, tenantID, productID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+// SetProductSeasons handles PUT /products/:id/seasons
+func (h *SeasonHandlers) SetProductSeasons(c echo.Context) error {
+	if err := h.requirePermission(c, "seasons:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req struct {
+		SeasonIDs []uuid.UUID `json:"season_ids"`
+		Crops     []string    `json:"crops"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	tags, err := h.seasonService.SetProductTags(ctx, tenantID, productID, req.SeasonIDs, req.Crops)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+// GetSeasonalDemand handles GET /analytics/seasonal-demand?product_id=&years=
+func (h *SeasonHandlers) GetSeasonalDemand(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	productID, err := uuid.Parse(c.QueryParam("product_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "product_id is required")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	years, _ := strconv.Atoi(c.QueryParam("years"))
+	if years > 10 {
+		years = 10
+	}
+
+	report, err := h.seasonalDemand.GetSeasonalDemand(ctx, tenantID, productID, years)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetStockingSuggestions handles GET /analytics/stocking-suggestions?horizon_days=&years=
+func (h *SeasonHandlers) GetStockingSuggestions(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	horizonDays, _ := strconv.Atoi(c.QueryParam("horizon_days"))
+	if horizonDays > 365 {
+		horizonDays = 365
+	}
+	years, _ := strconv.Atoi(c.QueryParam("years"))
+	if years > 10 {
+		years = 10
+	}
+
+	suggestions, err := h.seasonalDemand.GetStockingSuggestions(ctx, tenantID, horizonDays, years)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to calculate stocking suggestions")
+	}
+	if suggestions == nil {
+		suggestions = []*models.StockingSuggestion{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"suggestions": suggestions,
+	})
+}
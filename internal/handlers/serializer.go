@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// fieldSelection is the parsed ?fields= and ?embed= of a list request. The
+// zero value keeps every field and embeds nothing
+type fieldSelection struct {
+	fields map[string]bool
+	embeds map[string]bool
+	// denied are the permissions of fields the user may not see; they are
+	// masked here as the serializer cannot see into trimmed items
+	denied map[string]bool
+}
+
+// embedLoader resolves one embeddable relation for a page of items, returning
+// the related value for each item by index; nil marks an item without one
+type embedLoader[T any] func(items []T) ([]interface{}, error)
+
+// parseFieldSelection reads ?fields= and ?embed=, rejecting field names that
+// model's JSON form does not have and relations loaders cannot embed
+func parseFieldSelection[T any](c echo.Context, model T, loaders map[string]embedLoader[T]) (fieldSelection, error) {
+	var sel fieldSelection
+
+	if raw := c.QueryParam("fields"); raw != "" {
+		known := jsonFieldNames(reflect.TypeOf(model))
+		sel.fields = map[string]bool{"id": true}
+		for _, name := range splitList(raw) {
+			if !known[name] {
+				return sel, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown field '%s'", name))
+			}
+			sel.fields[name] = true
+		}
+	}
+
+	if raw := c.QueryParam("embed"); raw != "" {
+		sel.embeds = make(map[string]bool)
+		for _, name := range splitList(raw) {
+			if _, ok := loaders[name]; !ok {
+				return sel, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Cannot embed '%s'; supported: %s", name, strings.Join(embedNames(loaders), ", ")))
+			}
+			sel.embeds[name] = true
+		}
+	}
+
+	denied, err := deniedFieldPermissions(c, reflect.TypeOf(model))
+	if err != nil {
+		return sel, err
+	}
+	sel.denied = denied
+
+	return sel, nil
+}
+
+// isZero reports whether the selection leaves responses untouched
+func (s fieldSelection) isZero() bool {
+	return s.fields == nil && len(s.embeds) == 0
+}
+
+// serializeList renders items under sel, running only the loaders for the
+// requested embeds. Each loader sees the whole page so it can batch its query
+func serializeList[T any](sel fieldSelection, items []T, loaders map[string]embedLoader[T]) ([]map[string]interface{}, error) {
+	embedded := make(map[string][]interface{}, len(sel.embeds))
+	for name := range sel.embeds {
+		values, err := loaders[name](items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed %s: %w", name, err)
+		}
+		embedded[name] = values
+	}
+
+	out := make([]map[string]interface{}, 0, len(items))
+	for i, item := range items {
+		obj, err := sel.serialize(item)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range embedded {
+			obj[name] = values[i]
+		}
+		out = append(out, obj)
+	}
+	return out, nil
+}
+
+// serialize renders item as a JSON object holding only the selected fields
+func (s fieldSelection) serialize(item interface{}) (map[string]interface{}, error) {
+	if len(s.denied) > 0 {
+		tree, err := maskedTree(item, s.denied)
+		if err != nil {
+			return nil, err
+		}
+		masked, _ := tree.(map[string]interface{})
+		obj := make(map[string]interface{}, len(masked))
+		for name, value := range masked {
+			if s.fields == nil || s.fields[name] {
+				obj[name] = value
+			}
+		}
+		return obj, nil
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	obj := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		if s.fields == nil || s.fields[name] {
+			obj[name] = value
+		}
+	}
+	return obj, nil
+}
+
+// jsonFieldNames lists the JSON keys a struct type (or pointer to one) encodes to
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := make(map[string]bool)
+	for _, f := range structFields(t) {
+		names[f.name] = true
+	}
+	return names
+}
+
+// splitList splits a comma-separated query value, dropping blanks
+func splitList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func embedNames[T any](loaders map[string]embedLoader[T]) []string {
+	names := make([]string, 0, len(loaders))
+	for name := range loaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// embedByID maps each item to the record its foreign key points at, for
+// loaders that fetched the related records in one batch
+func embedByID[T any, R any](items []T, byID map[uuid.UUID]*R, key func(T) *uuid.UUID) []interface{} {
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		if id := key(item); id != nil {
+			if related, ok := byID[*id]; ok {
+				out[i] = related
+			}
+		}
+	}
+	return out
+}
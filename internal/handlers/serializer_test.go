@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func selectionContext(query string) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, "/products?"+query, nil)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestFieldSelectionTrimsAndEmbeds(t *testing.T) {
+	categoryID := uuid.New()
+	products := []*models.Product{
+		{ID: uuid.New(), Name: "Urea 45kg", UnitPrice: 266.5, CategoryID: &categoryID},
+		{ID: uuid.New(), Name: "DAP 50kg", UnitPrice: 1350},
+	}
+	loaders := map[string]embedLoader[*models.Product]{
+		"category": func(items []*models.Product) ([]interface{}, error) {
+			out := make([]interface{}, len(items))
+			for i, p := range items {
+				if p.CategoryID != nil {
+					out[i] = &models.Category{ID: *p.CategoryID, Name: "Fertilizers"}
+				}
+			}
+			return out, nil
+		},
+	}
+
+	sel, err := parseFieldSelection(selectionContext("fields=name,unit_price&embed=category"), &models.Product{}, loaders)
+	require.NoError(t, err)
+
+	out, err := serializeList(sel, products, loaders)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(out)
+	require.NoError(t, err)
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	require.Len(t, decoded, 2)
+	assert.ElementsMatch(t, []string{"id", "name", "unit_price", "category"}, keys(decoded[0]))
+	assert.Equal(t, "Fertilizers", decoded[0]["category"].(map[string]interface{})["name"])
+	assert.Nil(t, decoded[1]["category"])
+}
+
+func TestFieldSelectionRejectsUnknownNames(t *testing.T) {
+	loaders := map[string]embedLoader[*models.Product]{}
+
+	_, err := parseFieldSelection(selectionContext("fields=name,colour"), &models.Product{}, loaders)
+	assert.Error(t, err)
+
+	_, err = parseFieldSelection(selectionContext("embed=supplier"), &models.Product{}, loaders)
+	assert.Error(t, err)
+
+	sel, err := parseFieldSelection(selectionContext(""), &models.Product{}, loaders)
+	require.NoError(t, err)
+	assert.True(t, sel.isZero())
+}
+
+func keys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
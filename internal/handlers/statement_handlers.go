@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// StatementHandlers handles customer statements of account and credit notes.
+// Customers are distributors.
+type StatementHandlers struct {
+	statements     *jobs.StatementService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewStatementHandlers creates a new statement handlers instance
+func NewStatementHandlers(statements *jobs.StatementService, rbacMiddleware *middleware.RBACMiddleware) *StatementHandlers {
+	return &StatementHandlers{
+		statements:     statements,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *StatementHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetStatement handles GET /customers/:id/statement?from=&to=. The period
+// defaults to the current month to date; format=pdf, or an Accept header of
+// application/pdf, returns the printable statement instead of JSON
+func (h *StatementHandlers) GetStatement(c echo.Context) error {
+	if err := h.requirePermission(c, "statements:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+	from, to, err := parseStatementPeriod(c)
+	if err != nil {
+		return err
+	}
+
+	statement, err := h.statements.Generate(ctx, tenantID, customerID, from, to)
+	if errors.Is(err, jobs.ErrCustomerNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	wantsPDF := c.QueryParam("format") == "pdf" ||
+		strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "application/pdf")
+	if !wantsPDF {
+		return c.JSON(http.StatusOK, statement)
+	}
+
+	content, err := h.statements.RenderPDF(ctx, statement)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to render statement")
+	}
+	fileName := fmt.Sprintf("statement_%s_%s_%s.pdf", customerID.String()[:8],
+		statement.From.Format("20060102"), statement.To.Format("20060102"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fileName))
+	return c.Blob(http.StatusOK, "application/pdf", content)
+}
+
+// ListCreditNotes handles GET /customers/:id/credit-notes?from=&to=
+func (h *StatementHandlers) ListCreditNotes(c echo.Context) error {
+	if err := h.requirePermission(c, "statements:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+	from, to, err := parseStatementPeriod(c)
+	if err != nil {
+		return err
+	}
+
+	notes, err := h.statements.ListCreditNotes(ctx, tenantID, customerID, from, to)
+	if errors.Is(err, jobs.ErrCustomerNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list credit notes")
+	}
+	if notes == nil {
+		notes = []*models.CreditNote{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"credit_notes": notes})
+}
+
+// IssueCreditNote handles POST /customers/:id/credit-notes
+func (h *StatementHandlers) IssueCreditNote(c echo.Context) error {
+	if err := h.requirePermission(c, "statements:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	rc := common.RequestContextFrom(ctx)
+	tenantID, ok := rc.Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	customerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid customer ID format")
+	}
+
+	var req models.CreditNoteRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var createdBy *uuid.UUID
+	if userID, ok := rc.User(); ok {
+		createdBy = &userID
+	}
+
+	note, err := h.statements.IssueCreditNote(ctx, tenantID, customerID, createdBy, &req)
+	if errors.Is(err, jobs.ErrCustomerNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Customer not found")
+	}
+	if errors.Is(err, services.ErrPeriodClosed) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, note)
+}
+
+// parseStatementPeriod reads the from and to query dates (YYYY-MM-DD),
+// defaulting to the first of the current month and today
+func parseStatementPeriod(c echo.Context) (time.Time, time.Time, error) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if raw := c.QueryParam("from"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "from must be in YYYY-MM-DD format")
+		}
+		from = parsed
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "to must be in YYYY-MM-DD format")
+		}
+		to = parsed
+	}
+	return from, to, nil
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// StatusHandlers serves the public status page and lets platform admins
+// record incidents on it
+type StatusHandlers struct {
+	incidentSvc    services.StatusIncidentService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewStatusHandlers creates a new status handlers instance
+func NewStatusHandlers(incidentSvc services.StatusIncidentService, rbacMiddleware *middleware.RBACMiddleware) *StatusHandlers {
+	return &StatusHandlers{
+		incidentSvc:    incidentSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *StatusHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// statusIncidentError maps status incident service errors to HTTP errors
+func statusIncidentError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrStatusIncidentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Incident not found")
+	case errors.Is(err, services.ErrInvalidStatusIncident):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrStatusIncidentResolved):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// GetStatus handles GET /status, the current status of each platform
+// component and the open incidents (no auth required)
+func (h *StatusHandlers) GetStatus(c echo.Context) error {
+	status, err := h.incidentSvc.Status(c.Request().Context())
+	if err != nil {
+		return statusIncidentError(err, "Failed to load platform status")
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=30")
+	return c.JSON(http.StatusOK, status)
+}
+
+// GetStatusHistory handles GET /status/incidents, the incidents of the last
+// 90 days with their updates (no auth required)
+func (h *StatusHandlers) GetStatusHistory(c echo.Context) error {
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 20, MaxSize: 100})
+	if err != nil {
+		return err
+	}
+
+	incidents, err := h.incidentSvc.History(c.Request().Context(), page.Limit, page.Offset)
+	if err != nil {
+		return statusIncidentError(err, "Failed to load incident history")
+	}
+	if incidents == nil {
+		incidents = []*models.StatusIncident{}
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=60")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"incidents":   incidents,
+		"next_cursor": page.NextCursor(len(incidents)),
+	})
+}
+
+// ListIncidents handles GET /admin/incidents; ?open=true keeps the
+// unresolved ones (platform admin only)
+func (h *StatusHandlers) ListIncidents(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_incidents"); err != nil {
+		return err
+	}
+
+	openOnly := false
+	if raw := c.QueryParam("open"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "open must be true or false")
+		}
+		openOnly = parsed
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	incidents, err := h.incidentSvc.List(c.Request().Context(), openOnly, page.Limit, page.Offset)
+	if err != nil {
+		return statusIncidentError(err, "Failed to list incidents")
+	}
+	if incidents == nil {
+		incidents = []*models.StatusIncident{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"incidents":   incidents,
+		"next_cursor": page.NextCursor(len(incidents)),
+	})
+}
+
+// CreateIncident handles POST /admin/incidents, recording an incident with
+// its first update (platform admin only)
+func (h *StatusHandlers) CreateIncident(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_incidents"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req models.StatusIncidentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	incident, err := h.incidentSvc.Create(ctx, userID, &req)
+	if err != nil {
+		return statusIncidentError(err, "Failed to create incident")
+	}
+
+	return c.JSON(http.StatusCreated, incident)
+}
+
+// GetIncident handles GET /admin/incidents/:id (platform admin only)
+func (h *StatusHandlers) GetIncident(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_incidents"); err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid incident ID")
+	}
+
+	incident, err := h.incidentSvc.Get(c.Request().Context(), id)
+	if err != nil {
+		return statusIncidentError(err, "Failed to get incident")
+	}
+
+	return c.JSON(http.StatusOK, incident)
+}
+
+// PostIncidentUpdate handles POST /admin/incidents/:id/updates; the resolved
+// status closes the incident (platform admin only)
+func (h *StatusHandlers) PostIncidentUpdate(c echo.Context) error {
+	if err := h.requirePermission(c, "platform:manage_incidents"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid incident ID")
+	}
+
+	var req models.StatusIncidentUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	incident, err := h.incidentSvc.PostUpdate(ctx, id, userID, &req)
+	if err != nil {
+		return statusIncidentError(err, "Failed to update incident")
+	}
+
+	return c.JSON(http.StatusOK, incident)
+}
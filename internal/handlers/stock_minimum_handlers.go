@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// StockMinimumHandlers handles the per-product and per-warehouse stock
+// minimums low stock is measured against
+type StockMinimumHandlers struct {
+	minimumSvc     services.StockMinimumService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewStockMinimumHandlers creates a new stock minimum handlers instance
+func NewStockMinimumHandlers(minimumSvc services.StockMinimumService, rbacMiddleware *middleware.RBACMiddleware) *StockMinimumHandlers {
+	return &StockMinimumHandlers{
+		minimumSvc:     minimumSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *StockMinimumHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// stockMinimumError maps stock minimum service errors to HTTP errors
+func stockMinimumError(err error, fallback string) error {
+	if errors.Is(err, services.ErrInvalidStockMinimum) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// ListStockMinimums handles GET /products/:id/stock-minimums
+func (h *StockMinimumHandlers) ListStockMinimums(c echo.Context) error {
+	if err := h.requirePermission(c, "inventories:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	minimums, err := h.minimumSvc.List(ctx, tenantID, productID)
+	if err != nil {
+		return stockMinimumError(err, "Failed to list stock minimums")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"stock_minimums": minimums,
+	})
+}
+
+// SetStockMinimum handles PUT /products/:id/stock-minimums, setting the
+// product-wide minimum or, with warehouse_id, the one for that warehouse
+func (h *StockMinimumHandlers) SetStockMinimum(c echo.Context) error {
+	if err := h.requirePermission(c, "inventories:update"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	var req models.StockMinimumRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	minimum, err := h.minimumSvc.Set(ctx, tenantID, productID, &req)
+	if err != nil {
+		return stockMinimumError(err, "Failed to save stock minimum")
+	}
+
+	return c.JSON(http.StatusOK, minimum)
+}
+
+// DeleteStockMinimum handles DELETE /products/:id/stock-minimums/:minimum_id
+func (h *StockMinimumHandlers) DeleteStockMinimum(c echo.Context) error {
+	if err := h.requirePermission(c, "inventories:update"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+	minimumID, err := uuid.Parse(c.Param("minimum_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid stock minimum ID format")
+	}
+
+	deleted, err := h.minimumSvc.Delete(ctx, tenantID, productID, minimumID)
+	if err != nil {
+		return stockMinimumError(err, "Failed to delete stock minimum")
+	}
+	if !deleted {
+		return echo.NewHTTPError(http.StatusNotFound, "Stock minimum not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// StockOutHandlers serves stock-out, fill rate and lost sales analytics
+type StockOutHandlers struct {
+	stockOuts      *analytics.StockOutService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewStockOutHandlers creates a new stock-out handlers instance
+func NewStockOutHandlers(stockOuts *analytics.StockOutService, rbacMiddleware *middleware.RBACMiddleware) *StockOutHandlers {
+	return &StockOutHandlers{
+		stockOuts:      stockOuts,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *StockOutHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// stockOutQuery is the window and filters shared by the stock-out endpoints;
+// to is exclusive, the day after the inclusive to date of the query string
+type stockOutQuery struct {
+	from        time.Time
+	to          time.Time
+	productID   *uuid.UUID
+	warehouseID *uuid.UUID
+}
+
+// parseStockOutQuery reads from=YYYY-MM-DD&to=YYYY-MM-DD&product_id=&warehouse_id=;
+// the window defaults to the last 90 days and both ends are inclusive
+func parseStockOutQuery(c echo.Context) (*stockOutQuery, error) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -89)
+	var err error
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+	}
+	if toStr := c.QueryParam("to"); toStr != "" {
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "to must not be before from")
+	}
+
+	q := &stockOutQuery{from: from, to: to.AddDate(0, 0, 1)}
+	if raw := c.QueryParam("product_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+		}
+		q.productID = &id
+	}
+	if raw := c.QueryParam("warehouse_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+		}
+		q.warehouseID = &id
+	}
+	return q, nil
+}
+
+// GetStockOuts handles GET /analytics/stock-outs?from=&to=&product_id=&warehouse_id=
+// It reports fill rate and lost sales per product and warehouse
+func (h *StockOutHandlers) GetStockOuts(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	q, err := parseStockOutQuery(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.stockOuts.GetReport(ctx, tenantID, q.from, q.to, q.productID, q.warehouseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build stock-out report")
+	}
+	report.To = report.To.AddDate(0, 0, -1)
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetFillRateTrend handles GET /analytics/stock-outs/trend?interval=week|month&from=&to=&product_id=&warehouse_id=
+func (h *StockOutHandlers) GetFillRateTrend(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	q, err := parseStockOutQuery(c)
+	if err != nil {
+		return err
+	}
+	interval := c.QueryParam("interval")
+	switch interval {
+	case "":
+		interval = "week"
+	case "week", "month":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "interval must be week or month")
+	}
+
+	trend, err := h.stockOuts.GetTrend(ctx, tenantID, q.from, q.to, interval, q.productID, q.warehouseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build fill rate trend")
+	}
+	trend.To = trend.To.AddDate(0, 0, -1)
+
+	return c.JSON(http.StatusOK, trend)
+}
+
+// ListStockOutEvents handles GET /analytics/stock-outs/events?outcome=rejected|backordered&from=&to=&product_id=&warehouse_id=
+func (h *StockOutHandlers) ListStockOutEvents(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	q, err := parseStockOutQuery(c)
+	if err != nil {
+		return err
+	}
+	outcome := c.QueryParam("outcome")
+	if outcome != "" && outcome != models.StockOutRejected && outcome != models.StockOutBackordered {
+		return echo.NewHTTPError(http.StatusBadRequest, "outcome must be rejected or backordered")
+	}
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50, MaxSize: 200})
+	if err != nil {
+		return err
+	}
+
+	events, err := h.stockOuts.ListEvents(ctx, tenantID, q.from, q.to, q.productID, q.warehouseID, outcome, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve stock-out events")
+	}
+	if events == nil {
+		events = []*models.StockOutEvent{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"events":      events,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(events)),
+	})
+}
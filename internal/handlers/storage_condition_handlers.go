@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// StorageConditionHandlers handles product storage requirements, warehouse
+// storage capabilities and the placement compliance report
+type StorageConditionHandlers struct {
+	storageService services.StorageConditionService
+	placements     *analytics.StoragePlacementService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewStorageConditionHandlers creates a new storage condition handlers instance
+func NewStorageConditionHandlers(storageService services.StorageConditionService, placements *analytics.StoragePlacementService, rbacMiddleware *middleware.RBACMiddleware) *StorageConditionHandlers {
+	return &StorageConditionHandlers{
+		storageService: storageService,
+		placements:     placements,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *StorageConditionHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// storageError maps storage condition service errors to HTTP errors
+func storageError(err error, fallback string) error {
+	if errors.Is(err, services.ErrInvalidStorageCondition) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// GetProductStorageRequirement handles GET /products/:id/storage-requirements
+func (h *StorageConditionHandlers) GetProductStorageRequirement(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	req, err := h.storageService.GetProductRequirement(ctx, tenantID, productID)
+	if err != nil {
+		return storageError(err, "Failed to retrieve storage requirements")
+	}
+
+	return c.JSON(http.StatusOK, req)
+}
+
+// SetProductStorageRequirement handles PUT /products/:id/storage-requirements
+func (h *StorageConditionHandlers) SetProductStorageRequirement(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	var req models.ProductStorageRequirement
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	req.ProductID = productID
+
+	if err := h.storageService.SetProductRequirement(ctx, tenantID, &req); err != nil {
+		return storageError(err, "Failed to save storage requirements")
+	}
+
+	return c.JSON(http.StatusOK, req)
+}
+
+// GetWarehouseStorageCapability handles GET /warehouses/:id/storage-capabilities
+func (h *StorageConditionHandlers) GetWarehouseStorageCapability(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	warehouseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+
+	capability, err := h.storageService.GetWarehouseCapability(ctx, tenantID, warehouseID)
+	if err != nil {
+		return storageError(err, "Failed to retrieve storage capabilities")
+	}
+
+	return c.JSON(http.StatusOK, capability)
+}
+
+// SetWarehouseStorageCapability handles PUT /warehouses/:id/storage-capabilities
+func (h *StorageConditionHandlers) SetWarehouseStorageCapability(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	warehouseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+
+	var capability models.WarehouseStorageCapability
+	if err := c.Bind(&capability); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	capability.WarehouseID = warehouseID
+
+	if err := h.storageService.SetWarehouseCapability(ctx, tenantID, &capability); err != nil {
+		return storageError(err, "Failed to save storage capabilities")
+	}
+
+	return c.JSON(http.StatusOK, capability)
+}
+
+// GetStorageCompliance handles GET /reports/storage-compliance
+func (h *StorageConditionHandlers) GetStorageCompliance(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	report, err := h.placements.NonCompliantPlacements(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to build storage compliance report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
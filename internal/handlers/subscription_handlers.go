@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/services"
 
@@ -40,7 +40,7 @@ func (h *SubscriptionHandlers) CreateSubscription(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Extract tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -77,35 +77,26 @@ func (h *SubscriptionHandlers) CreateSubscription(c echo.Context) error {
 func (h *SubscriptionHandlers) ListSubscriptions(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
-	limit := 10  // default
-	offset := 0  // default
-
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			limit = l
-		}
-	}
-
-	if offsetParam := c.QueryParam("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
 	}
 
-	subscriptions, err := h.subscriptionService.List(ctx, tenantID, limit, offset)
+	subscriptions, err := h.subscriptionService.List(ctx, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"subscriptions": subscriptions,
-		"limit":         limit,
-		"offset":        offset,
+		"limit":         page.Limit,
+		"offset":        page.Offset,
+		"next_cursor":   page.NextCursor(len(subscriptions)),
 	})
 }
 
@@ -118,7 +109,7 @@ func (h *SubscriptionHandlers) GetSubscriptionByID(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -140,7 +131,7 @@ func (h *SubscriptionHandlers) CancelSubscription(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -164,7 +155,7 @@ func (h *SubscriptionHandlers) PauseSubscription(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -188,7 +179,7 @@ func (h *SubscriptionHandlers) ResumeSubscription(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -212,7 +203,7 @@ func (h *SubscriptionHandlers) UpdateSubscriptionPlan(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -248,7 +239,7 @@ func (h *SubscriptionHandlers) ValidateBilling(c echo.Context) error {
 		return err
 	}
 
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// SupplierPriceListHandlers handles supplier price lists and quote comparison
+type SupplierPriceListHandlers struct {
+	priceListService services.SupplierPriceListService
+	quotes           *analytics.SupplierQuoteService
+	rbacMiddleware   *middleware.RBACMiddleware
+}
+
+// NewSupplierPriceListHandlers creates a new supplier price list handlers instance
+func NewSupplierPriceListHandlers(priceListService services.SupplierPriceListService, quotes *analytics.SupplierQuoteService, rbacMiddleware *middleware.RBACMiddleware) *SupplierPriceListHandlers {
+	return &SupplierPriceListHandlers{
+		priceListService: priceListService,
+		quotes:           quotes,
+		rbacMiddleware:   rbacMiddleware,
+	}
+}
+
+func (h *SupplierPriceListHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// priceListError maps price list service errors to HTTP errors
+func priceListError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrSupplierNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Supplier not found")
+	case errors.Is(err, services.ErrPriceListNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Price list not found")
+	case errors.Is(err, services.ErrInvalidPriceList):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// CreatePriceList handles POST /suppliers/:id/price-lists
+func (h *SupplierPriceListHandlers) CreatePriceList(c echo.Context) error {
+	if err := h.requirePermission(c, "suppliers:update"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid supplier ID format")
+	}
+
+	var req services.CreatePriceListRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	list, err := h.priceListService.Create(ctx, tenantID, supplierID, &req)
+	if err != nil {
+		return priceListError(err, "Failed to create price list")
+	}
+
+	return c.JSON(http.StatusCreated, list)
+}
+
+// ListPriceLists handles GET /suppliers/:id/price-lists
+func (h *SupplierPriceListHandlers) ListPriceLists(c echo.Context) error {
+	if err := h.requirePermission(c, "suppliers:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	supplierID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid supplier ID format")
+	}
+
+	lists, err := h.priceListService.ListBySupplier(ctx, tenantID, supplierID)
+	if err != nil {
+		return priceListError(err, "Failed to retrieve price lists")
+	}
+	if lists == nil {
+		lists = []*models.SupplierPriceList{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"price_lists": lists,
+	})
+}
+
+// GetPriceList handles GET /price-lists/:id
+func (h *SupplierPriceListHandlers) GetPriceList(c echo.Context) error {
+	if err := h.requirePermission(c, "suppliers:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	listID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid price list ID format")
+	}
+
+	list, err := h.priceListService.Get(ctx, tenantID, listID)
+	if err != nil {
+		return priceListError(err, "Failed to retrieve price list")
+	}
+
+	return c.JSON(http.StatusOK, list)
+}
+
+// DeletePriceList handles DELETE /price-lists/:id
+func (h *SupplierPriceListHandlers) DeletePriceList(c echo.Context) error {
+	if err := h.requirePermission(c, "suppliers:update"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	listID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid price list ID format")
+	}
+
+	if err := h.priceListService.Delete(ctx, tenantID, listID); err != nil {
+		return priceListError(err, "Failed to delete price list")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CompareSupplierQuotes handles GET /products/:id/supplier-quotes?quantity=&date=YYYY-MM-DD
+func (h *SupplierPriceListHandlers) CompareSupplierQuotes(c echo.Context) error {
+	if err := h.requirePermission(c, "suppliers:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	quantity := 1
+	if quantityStr := c.QueryParam("quantity"); quantityStr != "" {
+		if quantity, err = strconv.Atoi(quantityStr); err != nil || quantity <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "quantity must be a positive integer")
+		}
+	}
+	asOf := time.Now()
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		if asOf, err = time.Parse("2006-01-02", dateStr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid date, use YYYY-MM-DD")
+		}
+	}
+
+	comparison, err := h.quotes.Compare(ctx, tenantID, productID, quantity, asOf)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compare supplier quotes")
+	}
+
+	return c.JSON(http.StatusOK, comparison)
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
+	"agromart2/internal/repositories"
 	"agromart2/internal/services"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -24,12 +27,6 @@ func NewSupplierHandlers(supplierService services.SupplierService, rbacMiddlewar
 	}
 }
 
-// ListSuppliersRequest represents query parameters for listing suppliers
-type ListSuppliersRequest struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
-}
-
 // ListSuppliers handles getting a list of suppliers with tenant filtering
 func (h *SupplierHandlers) ListSuppliers(c echo.Context) error {
 	// Use RBAC middleware directly
@@ -42,38 +39,28 @@ func (h *SupplierHandlers) ListSuppliers(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	var req ListSuppliersRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
-	}
-
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
-	if req.Limit > 100 {
-		req.Limit = 100 // Maximum limit
-	}
-	if req.Offset < 0 {
-		req.Offset = 0
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.SupplierSortColumns, DefaultSort: "-created_at"})
+	if err != nil {
+		return err
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	// Get suppliers from the tenant
-	suppliers, err := h.supplierService.List(ctx, tenantID, req.Limit, req.Offset)
+	suppliers, err := h.supplierService.List(ctx, tenantID, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list suppliers")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"suppliers": suppliers,
-		"limit":     req.Limit,
-		"offset":    req.Offset,
+		"suppliers":   suppliers,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(suppliers)),
 	})
 }
 
@@ -84,6 +71,9 @@ type CreateSupplierRequest struct {
 	ContactPhone   *string `json:"contact_phone"`
 	Address        *string `json:"address"`
 	LicenseNumber  *string `json:"license_number"`
+	GSTIN          *string `json:"gstin"`
+	BankAccountNumber *string `json:"bank_account_number"`
+	BankIFSC          *string `json:"bank_ifsc"`
 }
 
 // CreateSupplier handles creating a new supplier
@@ -109,7 +99,7 @@ func (h *SupplierHandlers) CreateSupplier(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -121,10 +111,13 @@ func (h *SupplierHandlers) CreateSupplier(c echo.Context) error {
 		ContactPhone:  req.ContactPhone,
 		Address:       req.Address,
 		LicenseNumber: req.LicenseNumber,
+		GSTIN:         req.GSTIN,
+		BankAccountNumber: req.BankAccountNumber,
+		BankIFSC:          req.BankIFSC,
 	}
 
 	if err := h.supplierService.Create(ctx, tenantID, supplier); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return masterDataError(err, err.Error())
 	}
 
 	return c.JSON(http.StatusCreated, supplier)
@@ -153,7 +146,7 @@ func (h *SupplierHandlers) GetSupplier(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -164,7 +157,7 @@ func (h *SupplierHandlers) GetSupplier(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Supplier not found")
 	}
 
-	return c.JSON(http.StatusOK, supplier)
+	return jsonWithETag(c, resourceETag(supplier.ID, supplier.UpdatedAt), supplier)
 }
 
 // UpdateSupplierRequest represents the supplier update request payload
@@ -174,6 +167,9 @@ type UpdateSupplierRequest struct {
 	ContactPhone  *string `json:"contact_phone"`
 	Address       *string `json:"address"`
 	LicenseNumber *string `json:"license_number"`
+	GSTIN         *string `json:"gstin"`
+	BankAccountNumber *string `json:"bank_account_number"`
+	BankIFSC          *string `json:"bank_ifsc"`
 }
 
 // UpdateSupplier handles updating supplier details
@@ -204,7 +200,7 @@ func (h *SupplierHandlers) UpdateSupplier(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -214,6 +210,9 @@ func (h *SupplierHandlers) UpdateSupplier(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Supplier not found")
 	}
+	if ctx, err = checkIfMatch(ctx, c, resourceETag(supplier.ID, supplier.UpdatedAt), supplier.ID, supplier.UpdatedAt); err != nil {
+		return err
+	}
 
 	// Update fields if provided
 	if req.Name != nil {
@@ -231,9 +230,18 @@ func (h *SupplierHandlers) UpdateSupplier(c echo.Context) error {
 	if req.LicenseNumber != nil {
 		supplier.LicenseNumber = req.LicenseNumber
 	}
+	if req.GSTIN != nil {
+		supplier.GSTIN = req.GSTIN
+	}
+	if req.BankAccountNumber != nil {
+		supplier.BankAccountNumber = req.BankAccountNumber
+	}
+	if req.BankIFSC != nil {
+		supplier.BankIFSC = req.BankIFSC
+	}
 
 	if err := h.supplierService.Update(ctx, tenantID, supplier); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return masterDataError(err, err.Error())
 	}
 
 	return c.JSON(http.StatusOK, supplier)
@@ -262,7 +270,7 @@ func (h *SupplierHandlers) DeleteSupplier(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -273,11 +281,14 @@ func (h *SupplierHandlers) DeleteSupplier(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Supplier not found")
 	}
 
-	if err := h.supplierService.Delete(ctx, tenantID, supplierID); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete supplier")
+	archived, err := h.supplierService.Delete(ctx, tenantID, supplierID)
+	if err != nil {
+		var conflict *services.DependencyConflictError
+		if errors.As(err, &conflict) {
+			return sendDependencyConflict(c, conflict)
+		}
+		return masterDataError(err, "Failed to delete supplier")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Supplier deleted successfully",
-	})
+	return deletedResponse(c, "Supplier", archived)
 }
\ No newline at end of file
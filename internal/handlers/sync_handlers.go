@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SyncHandlers handles the offline sync API used by field sales apps
+type SyncHandlers struct {
+	syncService    services.SyncService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewSyncHandlers creates a new sync handlers instance
+func NewSyncHandlers(syncService services.SyncService, rbacMiddleware *middleware.RBACMiddleware) *SyncHandlers {
+	return &SyncHandlers{
+		syncService:    syncService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *SyncHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetChanges handles GET /sync/changes?since=<token>&entities=products,orders&limit=500
+func (h *SyncHandlers) GetChanges(c echo.Context) error {
+	if err := h.requirePermission(c, "sync:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	// Sync pages by token, so only the page size applies
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: services.SyncDefaultPageSize, MaxSize: services.SyncMaxPageSize})
+	if err != nil {
+		return err
+	}
+
+	var entities []string
+	if entitiesStr := c.QueryParam("entities"); entitiesStr != "" {
+		for _, entity := range strings.Split(entitiesStr, ",") {
+			if entity = strings.TrimSpace(entity); entity != "" {
+				entities = append(entities, entity)
+			}
+		}
+	}
+
+	changes, err := h.syncService.GetChanges(ctx, tenantID, c.QueryParam("since"), entities, page.Limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSyncToken) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid sync token; perform a full resync")
+		}
+		if strings.HasPrefix(err.Error(), "unknown sync entity") {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve changes")
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
+
+// UploadOrders handles POST /sync/orders
+func (h *SyncHandlers) UploadOrders(c echo.Context) error {
+	if err := h.requirePermission(c, "sync:upload"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.SyncUploadRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	resp, err := h.syncService.UploadOrders(ctx, tenantID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSyncToken) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid base_token")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
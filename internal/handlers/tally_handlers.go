@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// TallyHandlers handles incremental Tally exports, imports and sync scheduling
+type TallyHandlers struct {
+	syncService    *jobs.TallySyncService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewTallyHandlers creates a new Tally handlers instance
+func NewTallyHandlers(syncService *jobs.TallySyncService, rbacMiddleware *middleware.RBACMiddleware) *TallyHandlers {
+	return &TallyHandlers{
+		syncService:    syncService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *TallyHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ExportIncremental handles POST /tally/exports/:entity
+func (h *TallyHandlers) ExportIncremental(c echo.Context) error {
+	if err := h.requirePermission(c, "tally:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	entity := c.Param("entity")
+	if entity != models.TallyEntityInvoices && entity != models.TallyEntityOrders {
+		return echo.NewHTTPError(http.StatusBadRequest, "Entity must be invoices or orders")
+	}
+
+	run, _, err := h.syncService.ExportIncremental(ctx, tenantID, entity, "manual")
+	if err != nil {
+		if run != nil {
+			return c.JSON(http.StatusInternalServerError, run)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to export to Tally")
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+// DownloadExport handles GET /tally/exports/:id/download by redirecting to the export file
+func (h *TallyHandlers) DownloadExport(c echo.Context) error {
+	if err := h.requirePermission(c, "tally:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid export ID format")
+	}
+
+	url, err := h.syncService.DownloadURL(ctx, tenantID, runID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Export file not found")
+	}
+
+	return c.Redirect(http.StatusFound, url)
+}
+
+// ResetCursor handles POST /tally/cursors/:entity/reset so the next export starts over
+func (h *TallyHandlers) ResetCursor(c echo.Context) error {
+	if err := h.requirePermission(c, "tally:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	if err := h.syncService.ResetCursor(ctx, tenantID, c.Param("entity")); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Import handles POST /tally/import
+func (h *TallyHandlers) Import(c echo.Context) error {
+	if err := h.requirePermission(c, "tally:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req jobs.ImportRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request data")
+	}
+	req.TenantID = tenantID
+
+	if req.DataType == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "data_type is required (orders or invoices)")
+	}
+
+	result, err := h.syncService.Import(ctx, req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to import data")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Import completed",
+		"result":  result,
+	})
+}
+
+// GetSyncStatus handles GET /tally/sync-status
+func (h *TallyHandlers) GetSyncStatus(c echo.Context) error {
+	if err := h.requirePermission(c, "tally:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	statuses, err := h.syncService.SyncStatus(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve Tally sync status")
+	}
+	settings, err := h.syncService.GetSettings(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve Tally sync settings")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"entities": statuses,
+		"schedule": settings,
+	})
+}
+
+// UpdateSyncSettings handles PUT /tally/sync-settings
+func (h *TallyHandlers) UpdateSyncSettings(c echo.Context) error {
+	if err := h.requirePermission(c, "tally:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	settings, err := h.syncService.GetSettings(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve Tally sync settings")
+	}
+
+	var req struct {
+		Enabled         *bool    `json:"enabled"`
+		IntervalMinutes *int     `json:"interval_minutes"`
+		Entities        []string `json:"entities"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.IntervalMinutes != nil {
+		settings.IntervalMinutes = *req.IntervalMinutes
+	}
+	if req.Entities != nil {
+		settings.Entities = req.Entities
+	}
+
+	if err := h.syncService.SaveSettings(ctx, settings); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TenantCalendarHandlers handles the tenant's timezone and fiscal year settings
+type TenantCalendarHandlers struct {
+	calendarSvc    services.TenantCalendarService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewTenantCalendarHandlers creates a new tenant calendar handlers instance
+func NewTenantCalendarHandlers(calendarSvc services.TenantCalendarService, rbacMiddleware *middleware.RBACMiddleware) *TenantCalendarHandlers {
+	return &TenantCalendarHandlers{
+		calendarSvc:    calendarSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *TenantCalendarHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetCalendar handles GET /tenant/calendar
+func (h *TenantCalendarHandlers) GetCalendar(c echo.Context) error {
+	if err := h.requirePermission(c, "tenant_settings:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	calendar, err := h.calendarSvc.GetCalendar(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load calendar settings")
+	}
+
+	return c.JSON(http.StatusOK, calendar)
+}
+
+// UpdateCalendar handles PUT /tenant/calendar. Changes apply to what is dated
+// from now on; invoices and reports already issued keep their dates
+func (h *TenantCalendarHandlers) UpdateCalendar(c echo.Context) error {
+	if err := h.requirePermission(c, "tenant_settings:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req models.TenantCalendarRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	calendar, err := h.calendarSvc.UpdateCalendar(ctx, tenantID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTenantCalendar) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save calendar settings")
+	}
+
+	return c.JSON(http.StatusOK, calendar)
+}
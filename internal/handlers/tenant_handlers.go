@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/services"
 
@@ -24,12 +25,6 @@ func NewTenantHandlers(tenantService services.TenantService, rbacMiddleware *mid
 	}
 }
 
-// ListTenantsRequest represents query parameters for listing tenants
-type ListTenantsRequest struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
-}
-
 // ListTenants handles getting a list of tenants (admin only)
 func (h *TenantHandlers) ListTenants(c echo.Context) error {
 	// Check admin permission - only admins can list all tenants
@@ -40,32 +35,22 @@ func (h *TenantHandlers) ListTenants(c echo.Context) error {
 		return err
 	}
 
-	var req ListTenantsRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
-	}
-
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
-	if req.Limit > 100 {
-		req.Limit = 100
-	}
-	if req.Offset < 0 {
-		req.Offset = 0
+	page, err := parseListQuery(c, listquery.Options{})
+	if err != nil {
+		return err
 	}
 
 	// List tenants
-	tenants, err := h.tenantService.List(c.Request().Context(), req.Limit, req.Offset)
+	tenants, err := h.tenantService.List(c.Request().Context(), page.Limit, page.Offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list tenants")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"tenants": tenants,
-		"limit":   req.Limit,
-		"offset":  req.Offset,
+		"tenants":     tenants,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(tenants)),
 	})
 }
 
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TenantPaymentSettingsHandlers handles the UPI details buyers pay the tenant at
+type TenantPaymentSettingsHandlers struct {
+	upiSvc         services.UPIPaymentService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewTenantPaymentSettingsHandlers creates a new tenant payment settings handlers instance
+func NewTenantPaymentSettingsHandlers(upiSvc services.UPIPaymentService, rbacMiddleware *middleware.RBACMiddleware) *TenantPaymentSettingsHandlers {
+	return &TenantPaymentSettingsHandlers{
+		upiSvc:         upiSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *TenantPaymentSettingsHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetPaymentSettings handles GET /tenant/payment-settings
+func (h *TenantPaymentSettingsHandlers) GetPaymentSettings(c echo.Context) error {
+	if err := h.requirePermission(c, "tenant_settings:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	settings, err := h.upiSvc.GetSettings(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load payment settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdatePaymentSettings handles PUT /tenant/payment-settings. Invoices
+// generated from now on carry the new UPI details
+func (h *TenantPaymentSettingsHandlers) UpdatePaymentSettings(c echo.Context) error {
+	if err := h.requirePermission(c, "tenant_settings:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req models.TenantPaymentSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	settings, err := h.upiSvc.UpdateSettings(ctx, tenantID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPaymentSettings) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save payment settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/jobs"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// TenantTakeoutHandlers handles tenants exporting all of their data
+type TenantTakeoutHandlers struct {
+	takeoutService *jobs.TenantTakeoutService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewTenantTakeoutHandlers creates a new tenant takeout handlers instance
+func NewTenantTakeoutHandlers(takeoutService *jobs.TenantTakeoutService, rbacMiddleware *middleware.RBACMiddleware) *TenantTakeoutHandlers {
+	return &TenantTakeoutHandlers{
+		takeoutService: takeoutService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *TenantTakeoutHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ownTenant checks the :id parameter names the caller's tenant; a takeout is
+// self-service, so no one exports another tenant's data
+func (h *TenantTakeoutHandlers) ownTenant(c echo.Context) (uuid.UUID, error) {
+	tenantID, ok := common.RequestContextFrom(c.Request().Context()).Tenant()
+	if !ok {
+		return uuid.Nil, echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	pathTenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid tenant ID format")
+	}
+	if pathTenantID != tenantID {
+		return uuid.Nil, echo.NewHTTPError(http.StatusForbidden, "Takeouts can only be requested for your own tenant")
+	}
+	return tenantID, nil
+}
+
+// RequestTakeout handles POST /tenants/:id/takeout; the archive is built in
+// the background, so the response is the takeout record to poll
+func (h *TenantTakeoutHandlers) RequestTakeout(c echo.Context) error {
+	if err := h.requirePermission(c, "tenants:takeout"); err != nil {
+		return err
+	}
+	tenantID, err := h.ownTenant(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.TenantTakeoutRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	var requestedBy *uuid.UUID
+	if userID, ok := common.RequestContextFrom(c.Request().Context()).User(); ok {
+		requestedBy = &userID
+	}
+
+	takeout, err := h.takeoutService.Request(c.Request().Context(), tenantID, requestedBy, &req)
+	if errors.Is(err, jobs.ErrInvalidTakeout) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if errors.Is(err, jobs.ErrTakeoutInProgress) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to start takeout")
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/v1/tenants/"+tenantID.String()+"/takeouts/"+takeout.ID.String())
+	return c.JSON(http.StatusAccepted, takeout)
+}
+
+// ListTakeouts handles GET /tenants/:id/takeouts
+func (h *TenantTakeoutHandlers) ListTakeouts(c echo.Context) error {
+	if err := h.requirePermission(c, "tenants:takeout"); err != nil {
+		return err
+	}
+	tenantID, err := h.ownTenant(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 20})
+	if err != nil {
+		return err
+	}
+
+	takeouts, err := h.takeoutService.List(c.Request().Context(), tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list takeouts")
+	}
+	if takeouts == nil {
+		takeouts = []*models.TenantTakeout{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"takeouts":    takeouts,
+		"next_cursor": page.NextCursor(len(takeouts)),
+	})
+}
+
+// GetTakeout handles GET /tenants/:id/takeouts/:takeout_id, reporting
+// progress and, once finished, a fresh download link
+func (h *TenantTakeoutHandlers) GetTakeout(c echo.Context) error {
+	if err := h.requirePermission(c, "tenants:takeout"); err != nil {
+		return err
+	}
+	tenantID, err := h.ownTenant(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(c.Param("takeout_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid takeout ID format")
+	}
+
+	takeout, err := h.takeoutService.Get(c.Request().Context(), tenantID, id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Takeout not found")
+	}
+
+	return c.JSON(http.StatusOK, takeout)
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// TerritoryHandlers handles the territory hierarchy, territory assignments
+// and sales analytics by territory
+type TerritoryHandlers struct {
+	territorySvc   services.TerritoryService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewTerritoryHandlers creates a new territory handlers instance
+func NewTerritoryHandlers(territorySvc services.TerritoryService, rbacMiddleware *middleware.RBACMiddleware) *TerritoryHandlers {
+	return &TerritoryHandlers{
+		territorySvc:   territorySvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *TerritoryHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// territoryError maps territory service errors to HTTP errors
+func territoryError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrTerritoryNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Territory not found")
+	case errors.Is(err, services.ErrTerritoryEntityNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrTerritoryOutOfScope):
+		return echo.NewHTTPError(http.StatusForbidden, "Territory is outside your territory")
+	case errors.Is(err, services.ErrInvalidTerritory):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTerritoryConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// territoryAnalyticsQuery reads the optional territory_id and the from/to
+// window, which defaults to the last 30 days with both ends inclusive
+func territoryAnalyticsQuery(c echo.Context) (*uuid.UUID, time.Time, time.Time, error) {
+	var territoryID *uuid.UUID
+	if idStr := c.QueryParam("territory_id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid territory ID")
+		}
+		territoryID = &id
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -29)
+	var err error
+	if fromStr := c.QueryParam("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			return nil, time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+	}
+	if toStr := c.QueryParam("to"); toStr != "" {
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			return nil, time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return nil, time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "to must not be before from")
+	}
+	return territoryID, from, to, nil
+}
+
+// ListTerritories handles GET /territories
+func (h *TerritoryHandlers) ListTerritories(c echo.Context) error {
+	if err := h.requirePermission(c, "territories:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	territories, err := h.territorySvc.ListTerritories(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list territories")
+	}
+	if territories == nil {
+		territories = []*models.Territory{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"territories": territories})
+}
+
+// CreateTerritory handles POST /territories
+func (h *TerritoryHandlers) CreateTerritory(c echo.Context) error {
+	if err := h.requirePermission(c, "territories:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.TerritoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	territory, err := h.territorySvc.CreateTerritory(ctx, tenantID, &req)
+	if err != nil {
+		return territoryError(err, "Failed to create territory")
+	}
+
+	return c.JSON(http.StatusCreated, territory)
+}
+
+// UpdateTerritory handles PUT /territories/:id
+func (h *TerritoryHandlers) UpdateTerritory(c echo.Context) error {
+	if err := h.requirePermission(c, "territories:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	territoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid territory ID")
+	}
+
+	var req models.TerritoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	territory, err := h.territorySvc.UpdateTerritory(ctx, tenantID, territoryID, &req)
+	if err != nil {
+		return territoryError(err, "Failed to update territory")
+	}
+
+	return c.JSON(http.StatusOK, territory)
+}
+
+// DeleteTerritory handles DELETE /territories/:id
+func (h *TerritoryHandlers) DeleteTerritory(c echo.Context) error {
+	if err := h.requirePermission(c, "territories:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	territoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid territory ID")
+	}
+
+	if err := h.territorySvc.DeleteTerritory(ctx, tenantID, territoryID); err != nil {
+		return territoryError(err, "Failed to delete territory")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AssignTerritory handles PUT /territories/assignments, placing a customer,
+// warehouse or user in a territory. Users placed in one only see analytics
+// within it
+func (h *TerritoryHandlers) AssignTerritory(c echo.Context) error {
+	if err := h.requirePermission(c, "territories:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.TerritoryAssignmentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.territorySvc.Assign(ctx, tenantID, &req); err != nil {
+		return territoryError(err, "Failed to assign territory")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetTerritorySales handles GET /analytics/territories?level=district&territory_id=&from=&to=
+// It breaks order and invoice totals down by the territories of a level,
+// within the caller's own territory when they have one
+func (h *TerritoryHandlers) GetTerritorySales(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	territoryID, from, to, err := territoryAnalyticsQuery(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.territorySvc.SalesByTerritory(ctx, tenantID, userID, c.QueryParam("level"), territoryID, from, to)
+	if err != nil {
+		return territoryError(err, "Failed to get territory sales")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetTerritoryDailySales handles GET /analytics/territories/daily-sales?territory_id=&from=&to=
+// It totals orders and invoices per day for customers within the territory
+func (h *TerritoryHandlers) GetTerritoryDailySales(c echo.Context) error {
+	if err := h.requirePermission(c, "analytics:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+	userID, ok := common.RequestContextFrom(ctx).User()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	territoryID, from, to, err := territoryAnalyticsQuery(c)
+	if err != nil {
+		return err
+	}
+
+	days, err := h.territorySvc.DailySales(ctx, tenantID, userID, territoryID, from, to)
+	if err != nil {
+		return territoryError(err, "Failed to get territory daily sales")
+	}
+	if days == nil {
+		days = []*models.TerritoryDailySales{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"days": days,
+	})
+}
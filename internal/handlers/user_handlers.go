@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
@@ -28,12 +29,6 @@ func NewUserHandlers(userRepo repositories.UserRepository, tenantRepo repositori
 	}
 }
 
-// ListUsersRequest represents query parameters for listing users
-type ListUsersRequest struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
-}
-
 // ListUsers handles getting a list of users with tenant filtering
 func (h *UserHandlers) ListUsers(c echo.Context) error {
 	// Use RBAC middleware directly
@@ -46,38 +41,28 @@ func (h *UserHandlers) ListUsers(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
-	var req ListUsersRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
-	}
-
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
-	if req.Limit > 100 {
-		req.Limit = 100 // Maximum limit
-	}
-	if req.Offset < 0 {
-		req.Offset = 0
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.UserSortColumns, DefaultSort: "-created_at"})
+	if err != nil {
+		return err
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
 	// Get users from the tenant
-	users, err := h.userRepo.List(ctx, tenantID, req.Limit, req.Offset)
+	users, err := h.userRepo.ListPage(ctx, tenantID, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list users")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"users":  users,
-		"limit":  req.Limit,
-		"offset": req.Offset,
+		"users":       users,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(users)),
 	})
 }
 
@@ -125,7 +110,7 @@ func (h *UserHandlers) CreateUser(c echo.Context) error {
 	c.Logger().Infof("DEBUG: Parsed tenant_id UUID: %s", tenantID.String())
 
 	// Check if this is a cross-tenant operation
-	currentUserTenantID, ok := common.GetTenantIDFromContext(ctx)
+	currentUserTenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found for current user")
 	}
@@ -230,7 +215,7 @@ func (h *UserHandlers) GetUser(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -279,7 +264,7 @@ func (h *UserHandlers) UpdateUser(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -336,7 +321,7 @@ func (h *UserHandlers) DeleteUser(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
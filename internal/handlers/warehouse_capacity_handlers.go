@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// WarehouseCapacityHandlers handles product dimensions and the warehouse
+// utilization report
+type WarehouseCapacityHandlers struct {
+	dimensionsService services.ProductDimensionsService
+	utilization       *analytics.WarehouseUtilizationService
+	rbacMiddleware    *middleware.RBACMiddleware
+}
+
+// NewWarehouseCapacityHandlers creates a new warehouse capacity handlers instance
+func NewWarehouseCapacityHandlers(dimensionsService services.ProductDimensionsService, utilization *analytics.WarehouseUtilizationService, rbacMiddleware *middleware.RBACMiddleware) *WarehouseCapacityHandlers {
+	return &WarehouseCapacityHandlers{
+		dimensionsService: dimensionsService,
+		utilization:       utilization,
+		rbacMiddleware:    rbacMiddleware,
+	}
+}
+
+func (h *WarehouseCapacityHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// GetProductDimensions handles GET /products/:id/dimensions
+func (h *WarehouseCapacityHandlers) GetProductDimensions(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	dimensions, err := h.dimensionsService.GetProductDimensions(ctx, tenantID, productID)
+	if errors.Is(err, services.ErrInvalidDimensions) {
+		return echo.NewHTTPError(http.StatusNotFound, "Product not found")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve product dimensions")
+	}
+	if dimensions == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Product has no dimensions")
+	}
+
+	return c.JSON(http.StatusOK, dimensions)
+}
+
+// SetProductDimensions handles PUT /products/:id/dimensions
+func (h *WarehouseCapacityHandlers) SetProductDimensions(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	var dimensions models.ProductDimensions
+	if err := c.Bind(&dimensions); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	dimensions.ProductID = productID
+
+	if err := h.dimensionsService.SetProductDimensions(ctx, tenantID, &dimensions); err != nil {
+		if errors.Is(err, services.ErrInvalidDimensions) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save product dimensions")
+	}
+
+	return c.JSON(http.StatusOK, dimensions)
+}
+
+// GetWarehouseUtilization handles GET /analytics/warehouse-utilization,
+// reporting how full each active warehouse is
+func (h *WarehouseCapacityHandlers) GetWarehouseUtilization(c echo.Context) error {
+	if err := h.requirePermission(c, "storage:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	utilization, err := h.utilization.Utilization(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compute warehouse utilization")
+	}
+	if utilization == nil {
+		utilization = []*models.WarehouseUtilization{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"warehouses": utilization,
+	})
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"agromart2/internal/analytics"
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/middleware"
 	"agromart2/internal/models"
+	"agromart2/internal/repositories"
 	"agromart2/internal/services"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -14,23 +18,19 @@ import (
 // WarehouseHandlers handles warehouse-related HTTP requests
 type WarehouseHandlers struct {
 	warehouseService services.WarehouseService
+	utilization      *analytics.WarehouseUtilizationService
 	rbacMiddleware   *middleware.RBACMiddleware
 }
 
 // NewWarehouseHandlers creates a new warehouse handlers instance
-func NewWarehouseHandlers(warehouseService services.WarehouseService, rbacMiddleware *middleware.RBACMiddleware) *WarehouseHandlers {
+func NewWarehouseHandlers(warehouseService services.WarehouseService, utilization *analytics.WarehouseUtilizationService, rbacMiddleware *middleware.RBACMiddleware) *WarehouseHandlers {
 	return &WarehouseHandlers{
 		warehouseService: warehouseService,
+		utilization:      utilization,
 		rbacMiddleware:   rbacMiddleware,
 	}
 }
 
-// ListWarehousesRequest represents query parameters for listing warehouses
-type ListWarehousesRequest struct {
-	Limit  int `query:"limit"`
-	Offset int `query:"offset"`
-}
-
 // ListWarehouses handles getting a list of warehouses with tenant filtering
 func (h *WarehouseHandlers) ListWarehouses(c echo.Context) error {
 	log.Printf("DEBUG: ListWarehouses handler called")
@@ -49,38 +49,33 @@ func (h *WarehouseHandlers) ListWarehouses(c echo.Context) error {
 	ctx := c.Request().Context()
 	log.Printf("DEBUG: ListWarehouses context retrieved")
 
-	var req ListWarehousesRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
-	}
-
-	// Set defaults
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
-	if req.Limit > 100 {
-		req.Limit = 100 // Maximum limit
-	}
-	if req.Offset < 0 {
-		req.Offset = 0
+	page, err := parseListQuery(c, listquery.Options{Sorts: repositories.WarehouseSortColumns, DefaultSort: "-created_at"})
+	if err != nil {
+		return err
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
 
+	branchID, err := parseBranchFilter(c)
+	if err != nil {
+		return err
+	}
+
 	// Get warehouses from the tenant
-	warehouses, err := h.warehouseService.List(ctx, tenantID, req.Limit, req.Offset)
+	warehouses, err := h.warehouseService.List(ctx, tenantID, branchID, page)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list warehouses")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"warehouses": warehouses,
-		"limit":      req.Limit,
-		"offset":     req.Offset,
+		"warehouses":  warehouses,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(warehouses)),
 	})
 }
 
@@ -89,7 +84,12 @@ type CreateWarehouseRequest struct {
 	Name          string  `json:"name" validate:"required"`
 	Address       *string `json:"address"`
 	Capacity      *int    `json:"capacity" validate:"required"`
+	VolumeCapacityM3 *float64 `json:"volume_capacity_m3"`
+	PalletPositions  *int     `json:"pallet_positions"`
 	LicenseNumber *string `json:"license_number"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	BranchID      *uuid.UUID `json:"branch_id"`
 }
 
 // CreateWarehouse handles creating a new warehouse
@@ -116,9 +116,12 @@ func (h *WarehouseHandlers) CreateWarehouse(c echo.Context) error {
 	if req.Capacity == nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Capacity is required")
 	}
+	if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+		return err
+	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -128,11 +131,16 @@ func (h *WarehouseHandlers) CreateWarehouse(c echo.Context) error {
 		Name:          req.Name,
 		Address:       req.Address,
 		Capacity:      req.Capacity,
+		VolumeCapacityM3: req.VolumeCapacityM3,
+		PalletPositions:  req.PalletPositions,
 		LicenseNumber: req.LicenseNumber,
+		Latitude:      req.Latitude,
+		Longitude:     req.Longitude,
+		BranchID:      req.BranchID,
 	}
 
 	if err := h.warehouseService.Create(ctx, tenantID, warehouse); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return masterDataError(err, err.Error())
 	}
 
 	return c.JSON(http.StatusCreated, warehouse)
@@ -161,7 +169,7 @@ func (h *WarehouseHandlers) GetWarehouse(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	log.Printf("DEBUG: ListWarehouses tenant ID: %s, ok: %v", tenantID.String(), ok)
 	if !ok {
 		log.Printf("DEBUG: ListWarehouses - tenant not found in context")
@@ -173,8 +181,21 @@ func (h *WarehouseHandlers) GetWarehouse(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Warehouse not found")
 	}
+	if warehouse.Utilization, err = h.utilization.WarehouseUtilization(ctx, tenantID, warehouseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compute warehouse utilization")
+	}
 
-	return c.JSON(http.StatusOK, warehouse)
+	return jsonWithETag(c, warehouseETag(warehouse), warehouse)
+}
+
+// warehouseETag versions the warehouse detail, which changes with the stock
+// behind its utilization as well as with the warehouse itself
+func warehouseETag(warehouse *models.Warehouse) string {
+	version := warehouse.UpdatedAt
+	if u := warehouse.Utilization; u != nil && u.AsOf != nil && u.AsOf.After(version) {
+		version = *u.AsOf
+	}
+	return resourceETag(warehouse.ID, version)
 }
 
 // UpdateWarehouseRequest represents the warehouse update request payload
@@ -182,7 +203,67 @@ type UpdateWarehouseRequest struct {
 	Name          *string `json:"name"`
 	Address       *string `json:"address"`
 	Capacity      *int    `json:"capacity"`
+	VolumeCapacityM3 *float64 `json:"volume_capacity_m3"`
+	PalletPositions  *int     `json:"pallet_positions"`
 	LicenseNumber *string `json:"license_number"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	// BranchID moves the warehouse to another branch; its past orders stay
+	// with the branch they were placed under
+	BranchID      *uuid.UUID `json:"branch_id"`
+}
+
+// validateCoordinates requires latitude and longitude together and within range
+func validateCoordinates(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Latitude and longitude must be set together")
+	}
+	if latitude != nil && (*latitude < -90 || *latitude > 90 || *longitude < -180 || *longitude > 180) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid latitude or longitude")
+	}
+	return nil
+}
+
+// masterDataError maps supplier, distributor and warehouse rule violations to
+// HTTP errors
+func masterDataError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidGSTIN), errors.Is(err, services.ErrInvalidBankAccount), errors.Is(err, services.ErrInvalidBranch):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrDuplicateName):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, repositories.ErrPreconditionFailed):
+		return modifiedError()
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// deletedResponse reports whether a delete removed the record or archived it
+func deletedResponse(c echo.Context, entity string, archived bool) error {
+	if archived {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":  entity + " archived because past orders reference it",
+			"archived": true,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":  entity + " deleted successfully",
+		"archived": false,
+	})
+}
+
+// dependencyConflictResponse lists the records blocking a delete
+type dependencyConflictResponse struct {
+	common.ErrorResponse
+	BlockingReferences []*models.BlockingReference `json:"blocking_references"`
+}
+
+// sendDependencyConflict answers a blocked delete with 409 and what blocks it
+func sendDependencyConflict(c echo.Context, err *services.DependencyConflictError) error {
+	resp := dependencyConflictResponse{BlockingReferences: err.References}
+	resp.Error.Code = "HAS_DEPENDENCIES"
+	resp.Error.Message = err.Error()
+	return c.JSON(http.StatusConflict, resp)
 }
 
 // UpdateWarehouse handles updating warehouse details
@@ -213,7 +294,7 @@ func (h *WarehouseHandlers) UpdateWarehouse(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -223,6 +304,12 @@ func (h *WarehouseHandlers) UpdateWarehouse(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Warehouse not found")
 	}
+	if warehouse.Utilization, err = h.utilization.WarehouseUtilization(ctx, tenantID, warehouseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compute warehouse utilization")
+	}
+	if ctx, err = checkIfMatch(ctx, c, warehouseETag(warehouse), warehouse.ID, warehouse.UpdatedAt); err != nil {
+		return err
+	}
 
 	// Update fields if provided
 	if req.Name != nil {
@@ -234,12 +321,32 @@ func (h *WarehouseHandlers) UpdateWarehouse(c echo.Context) error {
 	if req.Capacity != nil {
 		warehouse.Capacity = req.Capacity
 	}
+	if req.VolumeCapacityM3 != nil {
+		warehouse.VolumeCapacityM3 = req.VolumeCapacityM3
+	}
+	if req.PalletPositions != nil {
+		warehouse.PalletPositions = req.PalletPositions
+	}
 	if req.LicenseNumber != nil {
 		warehouse.LicenseNumber = req.LicenseNumber
 	}
+	if req.Latitude != nil || req.Longitude != nil {
+		if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+			return err
+		}
+		warehouse.Latitude = req.Latitude
+		warehouse.Longitude = req.Longitude
+	}
+	if req.BranchID != nil {
+		warehouse.BranchID = req.BranchID
+	}
 
 	if err := h.warehouseService.Update(ctx, tenantID, warehouse); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return masterDataError(err, err.Error())
+	}
+	// Usage is measured against the capacities just saved
+	if warehouse.Utilization, err = h.utilization.WarehouseUtilization(ctx, tenantID, warehouseID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to compute warehouse utilization")
 	}
 
 	return c.JSON(http.StatusOK, warehouse)
@@ -268,7 +375,7 @@ func (h *WarehouseHandlers) DeleteWarehouse(c echo.Context) error {
 	}
 
 	// Get tenant ID from context
-	tenantID, ok := common.GetTenantIDFromContext(ctx)
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 	}
@@ -279,11 +386,46 @@ func (h *WarehouseHandlers) DeleteWarehouse(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Warehouse not found")
 	}
 
-	if err := h.warehouseService.Delete(ctx, tenantID, warehouseID); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete warehouse")
+	archived, err := h.warehouseService.Delete(ctx, tenantID, warehouseID)
+	if err != nil {
+		var conflict *services.DependencyConflictError
+		if errors.As(err, &conflict) {
+			return sendDependencyConflict(c, conflict)
+		}
+		return masterDataError(err, "Failed to delete warehouse")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Warehouse deleted successfully",
-	})
-}
\ No newline at end of file
+	return deletedResponse(c, "Warehouse", archived)
+}
+// SetDefaultWarehouse marks a warehouse as the tenant's default for stock changes
+func (h *WarehouseHandlers) SetDefaultWarehouse(c echo.Context) error {
+	err := h.rbacMiddleware.RequirePermission("warehouses:update")(func(c echo.Context) error {
+		return nil
+	})(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	warehouseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	if err := h.warehouseService.SetDefault(ctx, tenantID, warehouseID); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Warehouse not found")
+	}
+
+	warehouse, err := h.warehouseService.GetByID(ctx, tenantID, warehouseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Warehouse not found")
+	}
+
+	return c.JSON(http.StatusOK, warehouse)
+}
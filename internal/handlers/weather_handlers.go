@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// WeatherHandlers handles warehouse forecast and weather alert rule requests
+type WeatherHandlers struct {
+	weatherService services.WeatherAlertService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewWeatherHandlers creates a new weather handlers instance
+func NewWeatherHandlers(weatherService services.WeatherAlertService, rbacMiddleware *middleware.RBACMiddleware) *WeatherHandlers {
+	return &WeatherHandlers{
+		weatherService: weatherService,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+type weatherRuleRequest struct {
+	Name          string   `json:"name"`
+	Condition     string   `json:"condition"`
+	Threshold     float64  `json:"threshold"`
+	LookaheadDays int      `json:"lookahead_days"`
+	CategoryID    *string  `json:"category_id"`
+	Message       string   `json:"message"`
+	Severity      string   `json:"severity"`
+	Recipients    []string `json:"recipients"`
+	Enabled       *bool    `json:"enabled"`
+}
+
+func (h *WeatherHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// applyRuleRequest copies the request onto the rule
+func applyRuleRequest(rule *models.WeatherAlertRule, req *weatherRuleRequest) error {
+	rule.Name = req.Name
+	rule.Condition = req.Condition
+	rule.Threshold = req.Threshold
+	rule.LookaheadDays = req.LookaheadDays
+	rule.Message = req.Message
+	rule.Severity = req.Severity
+	rule.Recipients = req.Recipients
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	rule.CategoryID = nil
+	if req.CategoryID != nil && *req.CategoryID != "" {
+		categoryID, err := uuid.Parse(*req.CategoryID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid category ID format")
+		}
+		rule.CategoryID = &categoryID
+	}
+	return nil
+}
+
+// ListRules handles GET /weather/alert-rules
+func (h *WeatherHandlers) ListRules(c echo.Context) error {
+	if err := h.requirePermission(c, "weather:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	rules, err := h.weatherService.ListRules(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve weather alert rules")
+	}
+	if rules == nil {
+		rules = []*models.WeatherAlertRule{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"rules": rules,
+	})
+}
+
+// CreateRule handles POST /weather/alert-rules
+func (h *WeatherHandlers) CreateRule(c echo.Context) error {
+	if err := h.requirePermission(c, "weather:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req weatherRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	rule := &models.WeatherAlertRule{TenantID: tenantID, Enabled: true}
+	if err := applyRuleRequest(rule, &req); err != nil {
+		return err
+	}
+
+	if err := h.weatherService.CreateRule(ctx, rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule handles PUT /weather/alert-rules/:id
+func (h *WeatherHandlers) UpdateRule(c echo.Context) error {
+	if err := h.requirePermission(c, "weather:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	var req weatherRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	rule, err := h.weatherService.GetRule(ctx, tenantID, ruleID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Weather alert rule not found")
+	}
+	if err := applyRuleRequest(rule, &req); err != nil {
+		return err
+	}
+
+	if err := h.weatherService.UpdateRule(ctx, rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles DELETE /weather/alert-rules/:id
+func (h *WeatherHandlers) DeleteRule(c echo.Context) error {
+	if err := h.requirePermission(c, "weather:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid rule ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	if err := h.weatherService.DeleteRule(ctx, tenantID, ruleID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete weather alert rule")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetWarehouseForecast handles GET /warehouses/:id/weather
+func (h *WeatherHandlers) GetWarehouseForecast(c echo.Context) error {
+	if err := h.requirePermission(c, "weather:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	warehouseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid warehouse ID format")
+	}
+
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	forecast, err := h.weatherService.GetWarehouseForecast(ctx, tenantID, warehouseID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"warehouse_id": warehouseID,
+		"forecast":     forecast,
+	})
+}
+
+// CheckAlerts handles POST /weather/alerts/check, evaluating rules immediately
+func (h *WeatherHandlers) CheckAlerts(c echo.Context) error {
+	if err := h.requirePermission(c, "weather:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	events, err := h.weatherService.CheckTenant(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check weather alerts")
+	}
+	if events == nil {
+		events = []*models.WeatherAlertEvent{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"raised": events,
+	})
+}
+
+// ListAlerts handles GET /weather/alerts
+func (h *WeatherHandlers) ListAlerts(c echo.Context) error {
+	if err := h.requirePermission(c, "weather:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50})
+	if err != nil {
+		return err
+	}
+
+	events, err := h.weatherService.ListEvents(ctx, tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve weather alerts")
+	}
+	if events == nil {
+		events = []*models.WeatherAlertEvent{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"alerts":      events,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor(len(events)),
+	})
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxWhatsAppWebhookPayload caps provider callback bodies
+const maxWhatsAppWebhookPayload = 1 << 20
+
+// WhatsAppHandlers handles WhatsApp templates, contact opt-in, the message log
+// and provider callbacks
+type WhatsAppHandlers struct {
+	whatsAppSvc    services.WhatsAppService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewWhatsAppHandlers creates a new WhatsApp handlers instance
+func NewWhatsAppHandlers(whatsAppSvc services.WhatsAppService, rbacMiddleware *middleware.RBACMiddleware) *WhatsAppHandlers {
+	return &WhatsAppHandlers{
+		whatsAppSvc:    whatsAppSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *WhatsAppHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// ListTemplates handles GET /whatsapp/templates
+func (h *WhatsAppHandlers) ListTemplates(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	templates, err := h.whatsAppSvc.ListTemplates(ctx, tenantID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list WhatsApp templates")
+	}
+	if templates == nil {
+		templates = []*models.WhatsAppTemplate{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"templates": templates})
+}
+
+// CreateTemplate handles POST /whatsapp/templates, registering a template
+// already approved with the provider for an event
+func (h *WhatsAppHandlers) CreateTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	template := &models.WhatsAppTemplate{IsActive: true}
+	if err := c.Bind(template); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.whatsAppSvc.CreateTemplate(ctx, tenantID, template); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTemplate handles PUT /whatsapp/templates/:id
+func (h *WhatsAppHandlers) UpdateTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID format")
+	}
+
+	var template models.WhatsAppTemplate
+	if err := c.Bind(&template); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	template.ID = id
+
+	if err := h.whatsAppSvc.UpdateTemplate(ctx, tenantID, &template); err != nil {
+		if err.Error() == "template not found" {
+			return echo.NewHTTPError(http.StatusNotFound, "Template not found")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /whatsapp/templates/:id
+func (h *WhatsAppHandlers) DeleteTemplate(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid template ID format")
+	}
+
+	if err := h.whatsAppSvc.DeleteTemplate(ctx, tenantID, id); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Template not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListContacts handles GET /whatsapp/contacts
+func (h *WhatsAppHandlers) ListContacts(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50})
+	if err != nil {
+		return err
+	}
+
+	contacts, err := h.whatsAppSvc.ListContacts(ctx, tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list WhatsApp contacts")
+	}
+	if contacts == nil {
+		contacts = []*models.WhatsAppContact{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"contacts":    contacts,
+		"next_cursor": page.NextCursor(len(contacts)),
+	})
+}
+
+// SetContactOptIn handles PUT /whatsapp/contacts, recording a contact's
+// consent to business-initiated messages or its withdrawal
+func (h *WhatsAppHandlers) SetContactOptIn(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var contact models.WhatsAppContact
+	if err := c.Bind(&contact); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.whatsAppSvc.SetContactOptIn(ctx, tenantID, &contact); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, contact)
+}
+
+// ListMessages handles GET /whatsapp/messages, newest first with delivery status
+func (h *WhatsAppHandlers) ListMessages(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	page, err := parseListQuery(c, listquery.Options{DefaultSize: 50})
+	if err != nil {
+		return err
+	}
+
+	messages, err := h.whatsAppSvc.ListMessages(ctx, tenantID, page.Limit, page.Offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to list WhatsApp messages")
+	}
+	if messages == nil {
+		messages = []*models.WhatsAppMessage{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"messages":    messages,
+		"next_cursor": page.NextCursor(len(messages)),
+	})
+}
+
+// SendMessage handles POST /whatsapp/messages, sending an event's template to
+// a phone number or a distributor
+func (h *WhatsAppHandlers) SendMessage(c echo.Context) error {
+	if err := h.requirePermission(c, "whatsapp:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.WhatsAppSend
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+	if req.EventType == "" || (req.Phone == "" && req.DistributorID == nil) {
+		return echo.NewHTTPError(http.StatusBadRequest, "event_type and a phone or distributor_id are required")
+	}
+
+	message, err := h.whatsAppSvc.Send(ctx, tenantID, &req)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusCreated, message)
+	case message != nil:
+		// The provider rejected the message; it is logged as failed
+		return c.JSON(http.StatusBadGateway, message)
+	case errors.Is(err, services.ErrWhatsAppNotOptedIn), errors.Is(err, services.ErrWhatsAppNoTemplate):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+}
+
+// VerifyWebhook handles GET /webhooks/whatsapp, Meta's subscription check
+func (h *WhatsAppHandlers) VerifyWebhook(c echo.Context) error {
+	if !h.whatsAppSvc.VerifySubscription(c.QueryParam("hub.mode"), c.QueryParam("hub.verify_token")) {
+		return echo.NewHTTPError(http.StatusForbidden, "Invalid verify token")
+	}
+	return c.String(http.StatusOK, c.QueryParam("hub.challenge"))
+}
+
+// ReceiveWebhook handles POST /webhooks/whatsapp: delivery receipts and
+// opt-out replies, authenticated by the provider's signature or token
+func (h *WhatsAppHandlers) ReceiveWebhook(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWhatsAppWebhookPayload+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+	}
+	if len(body) > maxWhatsAppWebhookPayload {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Webhook payload too large")
+	}
+
+	if err := h.whatsAppSvc.HandleWebhook(c.Request().Context(), c.Request().Header, c.QueryParams(), body); err != nil {
+		if errors.Is(err, services.ErrWhatsAppWebhookUnauthorized) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid webhook signature")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.NoContent(http.StatusOK)
+}
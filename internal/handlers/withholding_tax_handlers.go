@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"agromart2/internal/common"
+	"agromart2/internal/middleware"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// WithholdingTaxHandlers handles TDS/TCS sections, TDS certificates and the
+// quarterly TDS/TCS summary
+type WithholdingTaxHandlers struct {
+	withholdingSvc services.WithholdingTaxService
+	rbacMiddleware *middleware.RBACMiddleware
+}
+
+// NewWithholdingTaxHandlers creates a new TDS/TCS handlers instance
+func NewWithholdingTaxHandlers(withholdingSvc services.WithholdingTaxService, rbacMiddleware *middleware.RBACMiddleware) *WithholdingTaxHandlers {
+	return &WithholdingTaxHandlers{
+		withholdingSvc: withholdingSvc,
+		rbacMiddleware: rbacMiddleware,
+	}
+}
+
+func (h *WithholdingTaxHandlers) requirePermission(c echo.Context, permission string) error {
+	return h.rbacMiddleware.RequirePermission(permission)(func(c echo.Context) error {
+		return nil
+	})(c)
+}
+
+// withholdingError maps TDS/TCS service errors to HTTP errors
+func withholdingError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrTaxSectionNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Tax section not found")
+	case errors.Is(err, services.ErrPaymentNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Payment not found")
+	case errors.Is(err, services.ErrInvalidWithholding):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTaxSectionConflict):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, fallback)
+}
+
+// ListSections handles GET /withholding-tax/sections, optionally filtered by
+// kind (tds or tcs)
+func (h *WithholdingTaxHandlers) ListSections(c echo.Context) error {
+	if err := h.requirePermission(c, "withholding_tax:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	sections, err := h.withholdingSvc.ListSections(ctx, tenantID, c.QueryParam("kind"))
+	if err != nil {
+		return withholdingError(err, "Failed to list tax sections")
+	}
+	if sections == nil {
+		sections = []*models.TaxSection{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"sections": sections})
+}
+
+// CreateSection handles POST /withholding-tax/sections
+func (h *WithholdingTaxHandlers) CreateSection(c echo.Context) error {
+	if err := h.requirePermission(c, "withholding_tax:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var req models.TaxSectionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	section, err := h.withholdingSvc.CreateSection(ctx, tenantID, &req)
+	if err != nil {
+		return withholdingError(err, "Failed to create tax section")
+	}
+
+	return c.JSON(http.StatusCreated, section)
+}
+
+// UpdateSection handles PUT /withholding-tax/sections/:id
+func (h *WithholdingTaxHandlers) UpdateSection(c echo.Context) error {
+	if err := h.requirePermission(c, "withholding_tax:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	sectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid section ID format")
+	}
+
+	var req models.TaxSectionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	section, err := h.withholdingSvc.UpdateSection(ctx, tenantID, sectionID, &req)
+	if err != nil {
+		return withholdingError(err, "Failed to update tax section")
+	}
+
+	return c.JSON(http.StatusOK, section)
+}
+
+// GetInvoiceTCS handles GET /invoices/:id/tcs, the TCS included in an
+// invoice's total
+func (h *WithholdingTaxHandlers) GetInvoiceTCS(c echo.Context) error {
+	if err := h.requirePermission(c, "withholding_tax:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid invoice ID")
+	}
+
+	tcs, err := h.withholdingSvc.InvoiceTCS(ctx, tenantID, invoiceID)
+	if err != nil {
+		return withholdingError(err, "Failed to retrieve invoice TCS")
+	}
+	if tcs == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "No TCS on this invoice")
+	}
+
+	return c.JSON(http.StatusOK, tcs)
+}
+
+// RecordTDSCertificate handles PUT /payments/:id/tds-certificate
+func (h *WithholdingTaxHandlers) RecordTDSCertificate(c echo.Context) error {
+	if err := h.requirePermission(c, "withholding_tax:manage"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment ID format")
+	}
+
+	var req models.TDSCertificateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request format")
+	}
+
+	payment, err := h.withholdingSvc.RecordTDSCertificate(ctx, tenantID, paymentID, &req)
+	if err != nil {
+		return withholdingError(err, "Failed to record TDS certificate")
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// GetQuarterlySummary handles GET /reports/tds-tcs?financial_year=2025&quarter=1,
+// defaulting to the current quarter. Financial years start in April
+func (h *WithholdingTaxHandlers) GetQuarterlySummary(c echo.Context) error {
+	if err := h.requirePermission(c, "withholding_tax:read"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Tenant ID not found")
+	}
+
+	var financialYear, quarter int
+	if v := c.QueryParam("financial_year"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "financial_year must be a year such as 2025")
+		}
+		financialYear = parsed
+	}
+	if v := c.QueryParam("quarter"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "quarter must be between 1 and 4")
+		}
+		quarter = parsed
+	}
+
+	summary, err := h.withholdingSvc.QuarterlySummary(ctx, tenantID, financialYear, quarter)
+	if err != nil {
+		return withholdingError(err, "Failed to generate TDS/TCS summary")
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
@@ -2,13 +2,19 @@ package background
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
 	"agromart2/internal/analytics"
 	"agromart2/internal/caching"
+	"agromart2/internal/jobs"
+	"agromart2/internal/models"
 	"agromart2/internal/repositories"
+	"agromart2/internal/services"
 
 	"github.com/go-co-op/gocron/v2"
 	"github.com/google/uuid"
@@ -22,6 +28,28 @@ type JobScheduler struct {
 	inventoryRepo repositories.InventoryRepository
 	orderRepo   repositories.OrderRepository
 	tenantRepo  repositories.TenantRepository
+	weatherAlerts services.WeatherAlertService
+	marketplace services.MarketplaceService
+	erpSync     *jobs.ERPSyncService
+	tallySync   *jobs.TallySyncService
+	notificationSvc services.NotificationService
+	pushSvc     services.PushService
+	classification *analytics.ProductClassificationService
+	compliance  services.ComplianceService
+	dunning     services.DunningService
+	statements  *jobs.StatementService
+	interest    *jobs.OverdueInterestService
+	targets     services.SalesTargetService
+	commissions services.CommissionService
+	emailOrders *jobs.EmailOrderIngestionService
+	digests     *jobs.DigestService
+	slas        services.OrderSLAService
+	sandboxes   services.SandboxService
+	sandboxReset *jobs.SandboxResetService
+	calendars   services.TenantCalendarService
+	dataExports *jobs.DataExportService
+	piiKeys     *jobs.PIIKeyRotationService
+	impersonations services.ImpersonationService
 	jobJobs     map[string]gocron.Job
 	mu          sync.RWMutex
 }
@@ -29,7 +57,18 @@ type JobScheduler struct {
 // NewJobScheduler creates a new job scheduler
 func NewJobScheduler(analyticsSvc *analytics.AnalyticsService, cacheSvc caching.CacheService,
 	inventoryRepo repositories.InventoryRepository, orderRepo repositories.OrderRepository,
-	tenantRepo repositories.TenantRepository) *JobScheduler {
+	tenantRepo repositories.TenantRepository, weatherAlerts services.WeatherAlertService,
+	marketplace services.MarketplaceService, erpSync *jobs.ERPSyncService,
+	tallySync *jobs.TallySyncService, notificationSvc services.NotificationService,
+	pushSvc services.PushService, classification *analytics.ProductClassificationService,
+	compliance services.ComplianceService, dunning services.DunningService,
+	statements *jobs.StatementService, interest *jobs.OverdueInterestService,
+	targets services.SalesTargetService, commissions services.CommissionService,
+	emailOrders *jobs.EmailOrderIngestionService, digests *jobs.DigestService,
+	slas services.OrderSLAService, sandboxes services.SandboxService,
+	sandboxReset *jobs.SandboxResetService, calendars services.TenantCalendarService,
+	dataExports *jobs.DataExportService, piiKeys *jobs.PIIKeyRotationService,
+	impersonations services.ImpersonationService) *JobScheduler {
 
 	scheduler, err := gocron.NewScheduler()
 	if err != nil {
@@ -43,6 +82,28 @@ func NewJobScheduler(analyticsSvc *analytics.AnalyticsService, cacheSvc caching.
 		inventoryRepo: inventoryRepo,
 		orderRepo:     orderRepo,
 		tenantRepo:    tenantRepo,
+		weatherAlerts: weatherAlerts,
+		marketplace:   marketplace,
+		erpSync:       erpSync,
+		tallySync:     tallySync,
+		notificationSvc: notificationSvc,
+		pushSvc:       pushSvc,
+		classification: classification,
+		compliance:    compliance,
+		dunning:       dunning,
+		statements:    statements,
+		interest:      interest,
+		targets:       targets,
+		commissions:   commissions,
+		emailOrders:   emailOrders,
+		digests:       digests,
+		slas:          slas,
+		sandboxes:     sandboxes,
+		sandboxReset:  sandboxReset,
+		calendars:     calendars,
+		dataExports:   dataExports,
+		piiKeys:       piiKeys,
+		impersonations: impersonations,
 		jobJobs:       make(map[string]gocron.Job),
 	}
 
@@ -79,6 +140,19 @@ func (js *JobScheduler) registerJobs() {
 		js.jobJobs["analytics"] = analyticsJob
 	}
 
+	// Analytics materialized views refresh - every 10 minutes
+	viewsJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(10*time.Minute),
+		gocron.NewTask(js.refreshAnalyticsViews),
+		gocron.WithName("analytics-views-refresh"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create analytics views job: %v", err)
+	} else {
+		js.jobJobs["analytics-views"] = viewsJob
+	}
+
 	// Cache cleanup job - every hour
 	cacheJob, err := js.scheduler.NewJob(
 		gocron.DurationJob(1*time.Hour),
@@ -103,6 +177,254 @@ func (js *JobScheduler) registerJobs() {
 		js.jobJobs["inventory-alerts"] = alertsJob
 	}
 
+	// Weather alerts job - every 3 hours
+	weatherJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(3*time.Hour),
+		gocron.NewTask(js.processWeatherAlerts),
+		gocron.WithName("weather-alerts"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create weather alerts job: %v", err)
+	} else {
+		js.jobJobs["weather-alerts"] = weatherJob
+	}
+
+	// Marketplace order status sync - every 5 minutes
+	marketplaceJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(5*time.Minute),
+		gocron.NewTask(js.syncMarketplaceStatuses),
+		gocron.WithName("marketplace-status-sync"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create marketplace status sync job: %v", err)
+	} else {
+		js.jobJobs["marketplace-status-sync"] = marketplaceJob
+	}
+
+	// ERP connector sync - every 15 minutes; each connector runs on its own interval
+	erpJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(15*time.Minute),
+		gocron.NewTask(js.runERPSyncs),
+		gocron.WithName("erp-sync"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create ERP sync job: %v", err)
+	} else {
+		js.jobJobs["erp-sync"] = erpJob
+	}
+
+	// Tally incremental exports - every 15 minutes; each tenant runs on its own interval
+	tallyJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(15*time.Minute),
+		gocron.NewTask(js.runTallySyncs),
+		gocron.WithName("tally-sync"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create Tally sync job: %v", err)
+	} else {
+		js.jobJobs["tally-sync"] = tallyJob
+	}
+
+	// Data warehouse exports - every 15 minutes; each destination runs on its own interval
+	dataExportJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(15*time.Minute),
+		gocron.NewTask(js.runDataExports),
+		gocron.WithName("data-export"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create data export job: %v", err)
+	} else {
+		js.jobJobs["data-export"] = dataExportJob
+	}
+
+	// PII data key rotation and re-encryption - daily
+	piiKeyJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.runPIIKeyRotation),
+		gocron.WithName("pii-key-rotation"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create PII key rotation job: %v", err)
+	} else {
+		js.jobJobs["pii-key-rotation"] = piiKeyJob
+	}
+
+	// Expired impersonation sessions - every 5 minutes, so tenant admins hear
+	// about sessions nobody ended soon after they lapse
+	impersonationJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(5*time.Minute),
+		gocron.NewTask(js.closeExpiredImpersonations),
+		gocron.WithName("impersonation-expiry"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create impersonation expiry job: %v", err)
+	} else {
+		js.jobJobs["impersonation-expiry"] = impersonationJob
+	}
+
+	// Device token pruning - daily
+	pruneJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.pruneDeviceTokens),
+		gocron.WithName("device-token-prune"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create device token prune job: %v", err)
+	} else {
+		js.jobJobs["device-token-prune"] = pruneJob
+	}
+
+	// ABC/XYZ product classification - daily
+	classificationJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.classifyProducts),
+		gocron.WithName("abc-xyz-classification"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create ABC/XYZ classification job: %v", err)
+	} else {
+		js.jobJobs["abc-xyz-classification"] = classificationJob
+	}
+
+	// License expiry alerts at 90/30/7 days - daily
+	licenseJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.sendLicenseExpiryAlerts),
+		gocron.WithName("license-expiry-alerts"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create license expiry alert job: %v", err)
+	} else {
+		js.jobJobs["license-expiry-alerts"] = licenseJob
+	}
+
+	// Invoice payment reminders on each tenant's cadence - daily
+	reminderJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.sendPaymentReminders),
+		gocron.WithName("payment-reminders"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create payment reminder job: %v", err)
+	} else {
+		js.jobJobs["payment-reminders"] = reminderJob
+	}
+
+	// Monthly customer statements, sent early each month - daily
+	statementJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.sendMonthlyStatements),
+		gocron.WithName("monthly-statements"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create monthly statement job: %v", err)
+	} else {
+		js.jobJobs["monthly-statements"] = statementJob
+	}
+
+	// Sandbox tenant data reset - hourly, resetting the sandboxes that are due
+	sandboxJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(time.Hour),
+		gocron.NewTask(js.resetSandboxes),
+		gocron.WithName("sandbox-reset"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create sandbox reset job: %v", err)
+	} else {
+		js.jobJobs["sandbox-reset"] = sandboxJob
+	}
+
+	// Interest accrual on overdue invoices - daily
+	interestJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.accrueOverdueInterest),
+		gocron.WithName("overdue-interest"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create overdue interest job: %v", err)
+	} else {
+		js.jobJobs["overdue-interest"] = interestJob
+	}
+
+	// Sales target achievement - hourly
+	targetsJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(time.Hour),
+		gocron.NewTask(js.calculateTargetAchievement),
+		gocron.WithName("sales-target-achievement"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create sales target achievement job: %v", err)
+	} else {
+		js.jobJobs["sales-target-achievement"] = targetsJob
+	}
+
+	// Monthly commission draft run - daily, acting in the first days of a month
+	commissionJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(24*time.Hour),
+		gocron.NewTask(js.runMonthlyCommissions),
+		gocron.WithName("monthly-commission-run"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create monthly commission run job: %v", err)
+	} else {
+		js.jobJobs["monthly-commission-run"] = commissionJob
+	}
+
+	// Email order ingestion - every 2 minutes
+	emailOrdersJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(2*time.Minute),
+		gocron.NewTask(js.ingestOrderEmails),
+		gocron.WithName("email-order-ingestion"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create email order ingestion job: %v", err)
+	} else {
+		js.jobJobs["email-order-ingestion"] = emailOrdersJob
+	}
+
+	// Notification digests - every 15 minutes, sending those whose hour has come
+	digestsJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(15*time.Minute),
+		gocron.NewTask(js.sendNotificationDigests),
+		gocron.WithName("notification-digests"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create notification digest job: %v", err)
+	} else {
+		js.jobJobs["notification-digests"] = digestsJob
+	}
+
+	// Order SLA breach check - every 15 minutes
+	slaJob, err := js.scheduler.NewJob(
+		gocron.DurationJob(15*time.Minute),
+		gocron.NewTask(js.checkOrderSLAs),
+		gocron.WithName("order-sla-breaches"),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("Failed to create order SLA job: %v", err)
+	} else {
+		js.jobJobs["order-sla-breaches"] = slaJob
+	}
+
 	// Performance metrics collection - every 15 minutes
 	metricsJob, err := js.scheduler.NewJob(
 		gocron.DurationJob(15*time.Minute),
@@ -206,7 +528,14 @@ func (js *JobScheduler) processInventoryAlerts() error {
 
 		if lowStockCount > 0 {
 			log.Printf("ALERT: Tenant %s has %d inventory items with low stock", tenant.Name, lowStockCount)
-			// TODO: Send notifications via email/SMS
+			msg := &models.PushMessage{
+				Title: "Low stock",
+				Body:  fmt.Sprintf("%d inventory items are running low", lowStockCount),
+				Data:  map[string]string{"event_type": "low_stock", "count": strconv.Itoa(lowStockCount)},
+			}
+			if err := js.notificationSvc.SendPush(context.Background(), tenant.ID, models.PushTopicLowStock, msg); err != nil {
+				log.Printf("Failed to push low stock alert for tenant %s: %v", tenant.ID.String(), err)
+			}
 		}
 	}
 
@@ -214,6 +543,413 @@ func (js *JobScheduler) processInventoryAlerts() error {
 	return nil
 }
 
+// processWeatherAlerts evaluates weather alert rules for every active tenant
+func (js *JobScheduler) processWeatherAlerts() error {
+	log.Printf("Starting weather alerts processing")
+
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for weather alerts: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		events, err := js.weatherAlerts.CheckTenant(context.Background(), tenant.ID)
+		if err != nil {
+			log.Printf("Failed to check weather alerts for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if len(events) > 0 {
+			log.Printf("ALERT: Tenant %s raised %d weather alerts", tenant.Name, len(events))
+		}
+	}
+
+	log.Printf("Completed weather alerts processing")
+	return nil
+}
+
+// syncMarketplaceStatuses pushes order status changes to marketplace channels
+func (js *JobScheduler) syncMarketplaceStatuses() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for marketplace status sync: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		// Demo orders must not reach real marketplaces
+		sandbox, err := js.sandboxes.IsSandbox(context.Background(), tenant.ID)
+		if err != nil {
+			log.Printf("Failed to check sandbox for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if sandbox {
+			continue
+		}
+
+		pushed, err := js.marketplace.SyncStatuses(context.Background(), tenant.ID)
+		if err != nil {
+			log.Printf("Failed to sync marketplace statuses for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if pushed > 0 {
+			log.Printf("Pushed %d marketplace order status updates for tenant %s", pushed, tenant.Name)
+		}
+	}
+	return nil
+}
+
+// runERPSyncs runs every ERP connector whose scheduled sync is due
+func (js *JobScheduler) runERPSyncs() error {
+	attempted, err := js.erpSync.RunDue(context.Background())
+	if err != nil {
+		log.Printf("Failed to run scheduled ERP syncs: %v", err)
+		return err
+	}
+	if attempted > 0 {
+		log.Printf("Ran %d scheduled ERP syncs", attempted)
+	}
+	return nil
+}
+
+// runTallySyncs runs scheduled incremental Tally exports that are due
+func (js *JobScheduler) runTallySyncs() error {
+	tenants, err := js.tallySync.RunScheduled(context.Background())
+	if err != nil {
+		log.Printf("Failed to run scheduled Tally exports: %v", err)
+		return err
+	}
+	if tenants > 0 {
+		log.Printf("Ran scheduled Tally exports for %d tenants", tenants)
+	}
+	return nil
+}
+
+// runDataExports pushes changes to every data warehouse destination that is due
+func (js *JobScheduler) runDataExports() error {
+	ran, err := js.dataExports.RunDue(context.Background())
+	if err != nil {
+		log.Printf("Failed to run scheduled data exports: %v", err)
+		return err
+	}
+	if ran > 0 {
+		log.Printf("Ran %d scheduled data exports", ran)
+	}
+	return nil
+}
+
+// runPIIKeyRotation rotates tenants' PII data keys and re-encrypts stale values
+func (js *JobScheduler) runPIIKeyRotation() error {
+	resealed, err := js.piiKeys.Run(context.Background())
+	if err != nil {
+		log.Printf("Failed to run PII key rotation: %v", err)
+		return err
+	}
+	if resealed > 0 {
+		log.Printf("Re-encrypted PII of %d rows", resealed)
+	}
+	return nil
+}
+
+// closeExpiredImpersonations closes the impersonation sessions that expired
+// without being ended and sends their summaries
+func (js *JobScheduler) closeExpiredImpersonations() error {
+	closed, err := js.impersonations.CloseExpired(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("Failed to close expired impersonation sessions: %v", err)
+		return err
+	}
+	if closed > 0 {
+		log.Printf("Closed %d expired impersonation sessions", closed)
+	}
+	return nil
+}
+
+// deviceTokenMaxAge is how long a device may go without re-registering before
+// its token is treated as abandoned; the app refreshes its registration on launch
+const deviceTokenMaxAge = 60 * 24 * time.Hour
+
+// pruneDeviceTokens removes push registrations from devices that stopped checking in
+func (js *JobScheduler) pruneDeviceTokens() error {
+	pruned, err := js.pushSvc.PruneStaleTokens(context.Background(), deviceTokenMaxAge)
+	if err != nil {
+		log.Printf("Failed to prune stale device tokens: %v", err)
+		return err
+	}
+	if pruned > 0 {
+		log.Printf("Pruned %d stale device tokens", pruned)
+	}
+	return nil
+}
+
+// classifyProducts stores the ABC/XYZ class of every product for each active tenant
+func (js *JobScheduler) classifyProducts() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for ABC/XYZ classification: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		report, err := js.classification.Classify(context.Background(), tenant.ID, analytics.DefaultClassificationMonths, true)
+		if err != nil {
+			log.Printf("Failed to classify products for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		log.Printf("Classified %d products for tenant %s", len(report.Products), tenant.Name)
+	}
+	return nil
+}
+
+// sendLicenseExpiryAlerts pushes license expiry alerts for each active tenant
+func (js *JobScheduler) sendLicenseExpiryAlerts() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for license expiry alerts: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		sent, err := js.compliance.SendExpiryAlerts(context.Background(), tenant.ID)
+		if err != nil {
+			log.Printf("Failed to send license expiry alerts for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if sent > 0 {
+			log.Printf("Sent %d license expiry alerts for tenant %s", sent, tenant.Name)
+		}
+	}
+	return nil
+}
+
+// sendPaymentReminders runs the payment reminder cadence for each active tenant
+func (js *JobScheduler) sendPaymentReminders() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for payment reminders: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		sent, err := js.dunning.RunReminders(context.Background(), tenant.ID)
+		if err != nil {
+			log.Printf("Failed to send payment reminders for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if sent > 0 {
+			log.Printf("Sent %d payment reminders for tenant %s", sent, tenant.Name)
+		}
+	}
+	return nil
+}
+
+// sendMonthlyStatements emails last month's statement of account to each
+// active tenant's customers with outstanding balances
+func (js *JobScheduler) sendMonthlyStatements() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for monthly statements: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		sent, err := js.statements.SendMonthlyStatements(context.Background(), tenant.ID, time.Now())
+		if err != nil {
+			log.Printf("Failed to send monthly statements for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if sent > 0 {
+			log.Printf("Sent %d monthly statements for tenant %s", sent, tenant.Name)
+		}
+	}
+	return nil
+}
+
+// accrueOverdueInterest accrues interest on each active tenant's overdue invoices
+func (js *JobScheduler) accrueOverdueInterest() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for overdue interest: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		charged, err := js.interest.AccrueInterest(context.Background(), tenant.ID, time.Now())
+		if err != nil {
+			log.Printf("Failed to accrue overdue interest for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if charged > 0 {
+			log.Printf("Accrued interest on %d overdue invoices for tenant %s", charged, tenant.Name)
+		}
+	}
+	return nil
+}
+
+// resetSandboxes resets the sandbox tenants whose reset is due to the demo data
+func (js *JobScheduler) resetSandboxes() error {
+	reset, err := js.sandboxReset.ResetDue(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("Failed to reset sandboxes: %v", err)
+		return err
+	}
+	if reset > 0 {
+		log.Printf("Reset %d sandbox tenants", reset)
+	}
+	return nil
+}
+
+// refreshAnalyticsViews refreshes the analytics materialized views; they hold
+// every tenant's rows, so there is no per-tenant loop
+func (js *JobScheduler) refreshAnalyticsViews() error {
+	refreshes, err := js.analyticsSvc.RefreshMaterializedViews(context.Background())
+	for _, refresh := range refreshes {
+		log.Printf("Refreshed %s in %dms", refresh.ViewName, refresh.DurationMs)
+	}
+	return err
+}
+
+// calculateTargetAchievement recalculates each active tenant's sales target
+// achievement for the current month, and for the previous one during the
+// first days of a month so late invoices still count. Months follow each
+// tenant's calendar
+func (js *JobScheduler) calculateTargetAchievement() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for sales target achievement: %v", err)
+		return err
+	}
+
+	now := time.Now()
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		today := services.TenantCalendarOrDefault(context.Background(), js.calendars, tenant.ID).Today(now)
+		months := []time.Time{today}
+		if today.Day() <= 3 {
+			months = append(months, today.AddDate(0, 0, -today.Day()))
+		}
+		for _, month := range months {
+			if _, err := js.targets.CalculateAchievement(context.Background(), tenant.ID, month); err != nil {
+				log.Printf("Failed to calculate sales target achievement for tenant %s: %v", tenant.ID.String(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// runMonthlyCommissions drafts each active tenant's commission run for the
+// previous month during the first days of a month on the tenant's calendar,
+// recalculating it daily so late invoices and returns are picked up until it
+// is finalized
+func (js *JobScheduler) runMonthlyCommissions() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for commission run: %v", err)
+		return err
+	}
+
+	now := time.Now()
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		today := services.TenantCalendarOrDefault(context.Background(), js.calendars, tenant.ID).Today(now)
+		if today.Day() > 5 {
+			continue
+		}
+		month := today.AddDate(0, 0, -today.Day())
+		_, err := js.commissions.RunCommissions(context.Background(), tenant.ID, month, nil)
+		if err != nil && !errors.Is(err, services.ErrCommissionConflict) {
+			log.Printf("Failed to run commissions for tenant %s: %v", tenant.ID.String(), err)
+		}
+	}
+	return nil
+}
+
+// ingestOrderEmails parses received dealer emails into draft orders for review
+func (js *JobScheduler) ingestOrderEmails() error {
+	created, err := js.emailOrders.ProcessPending(context.Background())
+	if err != nil {
+		log.Printf("Failed to ingest order emails: %v", err)
+		return err
+	}
+	if created > 0 {
+		log.Printf("Created %d draft orders from email", created)
+	}
+	return nil
+}
+
+// sendNotificationDigests emails the daily and weekly digests that are due
+func (js *JobScheduler) sendNotificationDigests() error {
+	sent, err := js.digests.SendDue(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("Failed to send notification digests: %v", err)
+		return err
+	}
+	if sent > 0 {
+		log.Printf("Sent %d notification digests", sent)
+	}
+	return nil
+}
+
+// checkOrderSLAs records and alerts on orders that have missed an SLA for
+// each active tenant
+func (js *JobScheduler) checkOrderSLAs() error {
+	tenants, err := js.tenantRepo.List(context.Background(), 1000, 0)
+	if err != nil {
+		log.Printf("Failed to get tenants for SLA checks: %v", err)
+		return err
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Status != "active" {
+			continue
+		}
+
+		found, err := js.slas.RunBreachCheck(context.Background(), tenant.ID, time.Now())
+		if err != nil {
+			log.Printf("Failed to check order SLAs for tenant %s: %v", tenant.ID.String(), err)
+			continue
+		}
+		if found > 0 {
+			log.Printf("Found %d order SLA breaches for tenant %s", found, tenant.Name)
+		}
+	}
+	return nil
+}
+
 // collectPerformanceMetrics collects and stores performance metrics
 func (js *JobScheduler) collectPerformanceMetrics() error {
 	log.Printf("Collecting performance metrics")
@@ -0,0 +1,576 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"agromart2/internal/listquery"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	catalogExportBucket   = "catalog-exports"
+	catalogImageBucket    = "product-images"
+	catalogLinkExpiry     = 7 * 24 * time.Hour
+	catalogPageSize       = 500
+	catalogProgressEvery  = 20
+	catalogMaxProducts    = 5000
+	catalogDefaultTitle   = "Product Catalog"
+	catalogUncategorized  = "Other Products"
+	catalogMaxTitleLength = 200
+)
+
+// CatalogPDFService renders branded product catalog PDFs in the background and
+// shares them through presigned MinIO links
+type CatalogPDFService struct {
+	exportRepo       repositories.CatalogExportRepository
+	productRepo      repositories.ProductRepository
+	categoryRepo     repositories.CategoryRepository
+	productImageRepo repositories.ProductImageRepository
+	priceListRepo    repositories.SupplierPriceListRepository
+	tenantRepo       repositories.TenantRepository
+	minioService     services.MinioService
+}
+
+func NewCatalogPDFService(
+	exportRepo repositories.CatalogExportRepository,
+	productRepo repositories.ProductRepository,
+	categoryRepo repositories.CategoryRepository,
+	productImageRepo repositories.ProductImageRepository,
+	priceListRepo repositories.SupplierPriceListRepository,
+	tenantRepo repositories.TenantRepository,
+	minioService services.MinioService,
+) *CatalogPDFService {
+	return &CatalogPDFService{
+		exportRepo:       exportRepo,
+		productRepo:      productRepo,
+		categoryRepo:     categoryRepo,
+		productImageRepo: productImageRepo,
+		priceListRepo:    priceListRepo,
+		tenantRepo:       tenantRepo,
+		minioService:     minioService,
+	}
+}
+
+// catalogDocument is everything a catalog PDF prints
+type catalogDocument struct {
+	TenantName string
+	Title      string
+	PriceNote  string
+	Sections   []catalogSection
+}
+
+type catalogSection struct {
+	Name  string
+	Items []catalogItem
+}
+
+type catalogItem struct {
+	Product  *models.Product
+	Prices   []catalogPrice
+	ImageKey string
+}
+
+// catalogPrice is a unit price from MinQuantity units upwards
+type catalogPrice struct {
+	MinQuantity int
+	UnitPrice   float64
+}
+
+// Generate validates the request, records the export and renders it in the
+// background; poll Get for progress and the download link
+func (s *CatalogPDFService) Generate(ctx context.Context, tenantID uuid.UUID, requestedBy *uuid.UUID, req *models.CatalogExportRequest) (*models.CatalogExport, error) {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		req.Title = catalogDefaultTitle
+	}
+	if len(req.Title) > catalogMaxTitleLength {
+		return nil, fmt.Errorf("title must be at most %d characters", catalogMaxTitleLength)
+	}
+
+	var priceList *models.SupplierPriceList
+	if req.PriceListID != nil {
+		list, err := s.priceListRepo.GetByID(ctx, tenantID, *req.PriceListID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load price list: %w", err)
+		}
+		if list == nil {
+			return nil, fmt.Errorf("price list not found")
+		}
+		priceList = list
+	}
+
+	export := &models.CatalogExport{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Title:       req.Title,
+		PriceListID: req.PriceListID,
+		Status:      models.CatalogExportRunning,
+		RequestedBy: requestedBy,
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, fmt.Errorf("failed to record catalog export: %w", err)
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Panic in catalog export %s: %v", export.ID, r)
+			}
+		}()
+		s.run(context.WithoutCancel(ctx), export, req, priceList)
+	}()
+
+	return export, nil
+}
+
+// Get returns an export with its progress and, once it has succeeded, a
+// shareable download link
+func (s *CatalogPDFService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.CatalogExport, error) {
+	export, err := s.exportRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.decorate(export)
+	return export, nil
+}
+
+// List returns the tenant's exports, newest first
+func (s *CatalogPDFService) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.CatalogExport, error) {
+	exports, err := s.exportRepo.List(ctx, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, export := range exports {
+		s.decorate(export)
+	}
+	return exports, nil
+}
+
+func (s *CatalogPDFService) decorate(export *models.CatalogExport) {
+	if export.ProductsTotal > 0 {
+		export.Progress = float64(export.ProductsDone) / float64(export.ProductsTotal) * 100
+	}
+	if export.Status != models.CatalogExportSucceeded || export.ObjectKey == nil {
+		return
+	}
+	export.Progress = 100
+	url, err := s.minioService.GetPresignedURL(catalogExportBucket, *export.ObjectKey, catalogLinkExpiry)
+	if err != nil {
+		log.Printf("Failed to presign catalog export %s: %v", export.ID, err)
+		return
+	}
+	export.DownloadURL = &url
+}
+
+func (s *CatalogPDFService) run(ctx context.Context, export *models.CatalogExport, req *models.CatalogExportRequest, priceList *models.SupplierPriceList) {
+	if err := s.export(ctx, export, req, priceList); err != nil {
+		message := err.Error()
+		export.Status = models.CatalogExportFailed
+		export.ErrorMessage = &message
+	} else {
+		export.Status = models.CatalogExportSucceeded
+	}
+
+	if err := s.exportRepo.Finish(ctx, export); err != nil {
+		log.Printf("Failed to record catalog export %s result: %v", export.ID, err)
+	}
+}
+
+func (s *CatalogPDFService) export(ctx context.Context, export *models.CatalogExport, req *models.CatalogExportRequest, priceList *models.SupplierPriceList) error {
+	doc, err := s.buildDocument(ctx, export.TenantID, req, priceList)
+	if err != nil {
+		return err
+	}
+
+	for _, section := range doc.Sections {
+		export.ProductsTotal += len(section.Items)
+	}
+	if export.ProductsTotal == 0 {
+		return fmt.Errorf("no products match the catalog selection")
+	}
+	if err := s.exportRepo.UpdateProgress(ctx, export.ID, export.ProductsTotal, 0); err != nil {
+		log.Printf("Failed to update catalog export %s progress: %v", export.ID, err)
+	}
+
+	loadImage := func(key string) []byte {
+		if req.ExcludeImages || key == "" {
+			return nil
+		}
+		data, err := s.minioService.GetObject(ctx, catalogImageBucket, key)
+		if err != nil {
+			log.Printf("Skipping catalog image %s: %v", key, err)
+			return nil
+		}
+		return data
+	}
+	progress := func(done int) {
+		export.ProductsDone = done
+		if done%catalogProgressEvery != 0 {
+			return
+		}
+		if err := s.exportRepo.UpdateProgress(ctx, export.ID, export.ProductsTotal, done); err != nil {
+			log.Printf("Failed to update catalog export %s progress: %v", export.ID, err)
+		}
+	}
+
+	content, err := renderCatalogPDF(doc, time.Now(), loadImage, progress)
+	if err != nil {
+		return fmt.Errorf("failed to render catalog: %w", err)
+	}
+
+	fileName := catalogFileName(export.Title, export.StartedAt)
+	objectKey := fmt.Sprintf("%s/%s/%s", export.TenantID.String(), export.ID.String(), fileName)
+	if err := s.minioService.EnsureBucketExists(ctx, catalogExportBucket); err != nil {
+		return fmt.Errorf("failed to prepare catalog storage: %w", err)
+	}
+	if err := s.minioService.UploadObject(ctx, catalogExportBucket, objectKey, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		return fmt.Errorf("failed to store catalog: %w", err)
+	}
+	export.FileName = &fileName
+	export.ObjectKey = &objectKey
+	return nil
+}
+
+// buildDocument gathers the selected products, their prices and images into
+// category sections
+func (s *CatalogPDFService) buildDocument(ctx context.Context, tenantID uuid.UUID, req *models.CatalogExportRequest, priceList *models.SupplierPriceList) (*catalogDocument, error) {
+	doc := &catalogDocument{Title: req.Title, PriceNote: "List prices"}
+	if tenant, err := s.tenantRepo.GetByID(ctx, tenantID); err == nil && tenant != nil {
+		doc.TenantName = tenant.Name
+	}
+
+	var products []*models.Product
+	prices := make(map[uuid.UUID][]catalogPrice)
+	if priceList != nil {
+		doc.PriceNote = fmt.Sprintf("Prices from %s, valid from %s", priceList.Name, priceList.ValidFrom.Format("02 Jan 2006"))
+		if priceList.ValidTo != nil {
+			doc.PriceNote += " to " + priceList.ValidTo.Format("02 Jan 2006")
+		}
+		var ids []uuid.UUID
+		for _, item := range priceList.Items {
+			if _, seen := prices[item.ProductID]; !seen {
+				ids = append(ids, item.ProductID)
+			}
+			prices[item.ProductID] = append(prices[item.ProductID], catalogPrice{MinQuantity: item.MinQuantity, UnitPrice: item.UnitPrice})
+		}
+		loaded, err := s.productRepo.GetByIDs(ctx, tenantID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load products: %w", err)
+		}
+		products = loaded
+	} else {
+		page := listquery.Page{Limit: catalogPageSize, Sort: []listquery.SortField{{Field: "name", Column: "name"}}}
+		for {
+			batch, err := s.productRepo.ListPage(ctx, tenantID, page)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load products: %w", err)
+			}
+			products = append(products, batch...)
+			if len(batch) < page.Limit || len(products) >= catalogMaxProducts {
+				break
+			}
+			page.Offset += len(batch)
+		}
+	}
+
+	wanted := make(map[uuid.UUID]bool, len(req.CategoryIDs))
+	for _, id := range req.CategoryIDs {
+		wanted[id] = true
+	}
+	var selected []*models.Product
+	for _, product := range products {
+		if req.PublishedOnly && !product.IsPublished {
+			continue
+		}
+		if len(wanted) > 0 && (product.CategoryID == nil || !wanted[*product.CategoryID]) {
+			continue
+		}
+		selected = append(selected, product)
+		if len(selected) == catalogMaxProducts {
+			break
+		}
+	}
+	if len(selected) == 0 {
+		return doc, nil
+	}
+
+	categoryIDs := make([]uuid.UUID, 0)
+	productIDs := make([]uuid.UUID, 0, len(selected))
+	seenCategory := make(map[uuid.UUID]bool)
+	for _, product := range selected {
+		productIDs = append(productIDs, product.ID)
+		if product.CategoryID != nil && !seenCategory[*product.CategoryID] {
+			seenCategory[*product.CategoryID] = true
+			categoryIDs = append(categoryIDs, *product.CategoryID)
+		}
+	}
+
+	categoryNames := make(map[uuid.UUID]string)
+	if len(categoryIDs) > 0 {
+		categories, err := s.categoryRepo.GetByIDs(ctx, tenantID, categoryIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load categories: %w", err)
+		}
+		for _, category := range categories {
+			categoryNames[category.ID] = category.Name
+		}
+	}
+
+	imageKeys := make(map[uuid.UUID]string)
+	if !req.ExcludeImages {
+		images, err := s.productImageRepo.GetByProductIDs(ctx, tenantID, productIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load product images: %w", err)
+		}
+		for _, image := range images {
+			if _, ok := imageKeys[image.ProductID]; !ok {
+				imageKeys[image.ProductID] = image.ImageURL
+			}
+		}
+	}
+
+	for _, product := range selected {
+		if priceList == nil {
+			prices[product.ID] = []catalogPrice{{MinQuantity: 1, UnitPrice: product.UnitPrice}}
+		}
+	}
+	doc.Sections = groupCatalogSections(selected, categoryNames, prices, imageKeys)
+	return doc, nil
+}
+
+// groupCatalogSections sorts products into category sections by name, with
+// uncategorized products last
+func groupCatalogSections(products []*models.Product, categoryNames map[uuid.UUID]string, prices map[uuid.UUID][]catalogPrice, imageKeys map[uuid.UUID]string) []catalogSection {
+	byName := make(map[string]*catalogSection)
+	for _, product := range products {
+		name := catalogUncategorized
+		if product.CategoryID != nil {
+			if categoryName, ok := categoryNames[*product.CategoryID]; ok {
+				name = categoryName
+			}
+		}
+		section, ok := byName[name]
+		if !ok {
+			section = &catalogSection{Name: name}
+			byName[name] = section
+		}
+		section.Items = append(section.Items, catalogItem{Product: product, Prices: prices[product.ID], ImageKey: imageKeys[product.ID]})
+	}
+
+	sections := make([]catalogSection, 0, len(byName))
+	for _, section := range byName {
+		sort.Slice(section.Items, func(i, j int) bool {
+			return strings.ToLower(section.Items[i].Product.Name) < strings.ToLower(section.Items[j].Product.Name)
+		})
+		sections = append(sections, *section)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		if (sections[i].Name == catalogUncategorized) != (sections[j].Name == catalogUncategorized) {
+			return sections[j].Name == catalogUncategorized
+		}
+		return strings.ToLower(sections[i].Name) < strings.ToLower(sections[j].Name)
+	})
+	return sections
+}
+
+// renderCatalogPDF lays out doc on A4 pages: a branded header band, one
+// heading per category and a row per product with its image and prices.
+// loadImage returns an item's image bytes or nil; progress is called after
+// each product with the count rendered so far
+func renderCatalogPDF(doc *catalogDocument, generatedAt time.Time, loadImage func(key string) []byte, progress func(done int)) ([]byte, error) {
+	const (
+		margin    = 15.0
+		imageBox  = 28.0
+		rowHeight = 34.0
+		priceCol  = 45.0
+	)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, pageHeight := pdf.GetPageSize()
+	contentWidth := pageWidth - 2*margin
+	pdf.SetMargins(margin, margin+14, margin)
+	pdf.SetAutoPageBreak(false, margin)
+	pdf.AliasNbPages("")
+
+	brand := doc.TenantName
+	if brand == "" {
+		brand = "AgroMart"
+	}
+	pdf.SetHeaderFuncMode(func() {
+		pdf.SetFillColor(34, 139, 34)
+		pdf.Rect(0, 0, pageWidth, 16, "F")
+		pdf.SetTextColor(255, 255, 255)
+		pdf.SetFont("Arial", "B", 13)
+		pdf.SetXY(margin, 4)
+		pdf.CellFormat(contentWidth/2, 8, tr(brand), "", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 10)
+		pdf.CellFormat(contentWidth/2, 8, tr(doc.Title), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(33, 37, 41)
+		pdf.SetY(margin + 14)
+	}, true)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Arial", "", 8)
+		pdf.SetTextColor(108, 117, 125)
+		pdf.CellFormat(contentWidth/2, 6, tr("Generated "+generatedAt.Format("02 Jan 2006")), "", 0, "L", false, 0, "")
+		pdf.CellFormat(contentWidth/2, 6, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 20)
+	pdf.CellFormat(contentWidth, 10, tr(doc.Title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetTextColor(108, 117, 125)
+	pdf.CellFormat(contentWidth, 6, tr(doc.PriceNote), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(33, 37, 41)
+	pdf.Ln(4)
+
+	bottom := pageHeight - margin - 6
+	done := 0
+	for _, section := range doc.Sections {
+		if pdf.GetY()+12+rowHeight > bottom {
+			pdf.AddPage()
+		}
+		pdf.SetFont("Arial", "B", 14)
+		pdf.SetFillColor(233, 245, 233)
+		pdf.CellFormat(contentWidth, 9, tr(section.Name), "", 1, "L", true, 0, "")
+		pdf.Ln(2)
+
+		for _, item := range section.Items {
+			if pdf.GetY()+rowHeight > bottom {
+				pdf.AddPage()
+			}
+			top := pdf.GetY()
+			textX := margin
+
+			if data := loadImage(item.ImageKey); data != nil {
+				if placeCatalogImage(pdf, item.Product.ID.String(), data, margin, top, imageBox) {
+					textX = margin + imageBox + 4
+				}
+			}
+
+			textWidth := contentWidth - (textX - margin) - priceCol
+			product := item.Product
+			pdf.SetXY(textX, top)
+			pdf.SetFont("Arial", "B", 11)
+			pdf.CellFormat(textWidth, 6, tr(product.Name), "", 2, "L", false, 0, "")
+
+			if product.Description != nil && *product.Description != "" {
+				pdf.SetFont("Arial", "", 9)
+				lines := pdf.SplitText(tr(*product.Description), textWidth)
+				if len(lines) > 3 {
+					lines = append(lines[:2], strings.TrimSpace(lines[2])+"...")
+				}
+				for _, line := range lines {
+					pdf.CellFormat(textWidth, 4.5, line, "", 2, "L", false, 0, "")
+				}
+			}
+
+			var details []string
+			if product.UnitOfMeasure != nil && *product.UnitOfMeasure != "" {
+				details = append(details, "Unit: "+*product.UnitOfMeasure)
+			}
+			if product.Barcode != nil && *product.Barcode != "" {
+				details = append(details, "Code: "+*product.Barcode)
+			}
+			if len(details) > 0 {
+				pdf.SetFont("Arial", "", 8)
+				pdf.SetTextColor(108, 117, 125)
+				pdf.CellFormat(textWidth, 5, tr(strings.Join(details, "   ")), "", 2, "L", false, 0, "")
+				pdf.SetTextColor(33, 37, 41)
+			}
+
+			priceX := pageWidth - margin - priceCol
+			pdf.SetXY(priceX, top)
+			for i, price := range item.Prices {
+				if i == 0 {
+					pdf.SetFont("Arial", "B", 12)
+					pdf.CellFormat(priceCol, 7, fmt.Sprintf("Rs. %.2f", price.UnitPrice), "", 2, "R", false, 0, "")
+					continue
+				}
+				pdf.SetFont("Arial", "", 8)
+				pdf.CellFormat(priceCol, 4.5, fmt.Sprintf("Rs. %.2f for %d+", price.UnitPrice, price.MinQuantity), "", 2, "R", false, 0, "")
+			}
+
+			pdf.SetDrawColor(222, 226, 230)
+			pdf.Line(margin, top+rowHeight-2, pageWidth-margin, top+rowHeight-2)
+			pdf.SetY(top + rowHeight)
+
+			done++
+			progress(done)
+		}
+		pdf.Ln(2)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// placeCatalogImage draws an image scaled to fit a square box, reporting
+// false and leaving the document usable when the image cannot be decoded
+func placeCatalogImage(pdf *gofpdf.Fpdf, name string, data []byte, x, y, box float64) bool {
+	var imageType string
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		imageType = "JPG"
+	case "image/png":
+		imageType = "PNG"
+	case "image/gif":
+		imageType = "GIF"
+	default:
+		return false
+	}
+
+	options := gofpdf.ImageOptions{ImageType: imageType}
+	info := pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(data))
+	if !pdf.Ok() || info == nil || info.Width() <= 0 || info.Height() <= 0 {
+		pdf.ClearError()
+		return false
+	}
+
+	w, h := box, box
+	if ratio := info.Width() / info.Height(); ratio > 1 {
+		h = box / ratio
+	} else {
+		w = box * ratio
+	}
+	pdf.ImageOptions(name, x+(box-w)/2, y+(box-h)/2, w, h, false, options, 0, "")
+	return true
+}
+
+// catalogFileName turns a catalog title into a safe, dated PDF file name
+func catalogFileName(title string, at time.Time) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteByte('_')
+		}
+	}
+	slug := strings.Trim(b.String(), "_")
+	if slug == "" {
+		slug = "catalog"
+	}
+	if len(slug) > 60 {
+		slug = strings.Trim(slug[:60], "_")
+	}
+	return fmt.Sprintf("%s_%s.pdf", slug, at.Format("20060102"))
+}
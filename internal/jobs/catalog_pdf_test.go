@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func samplePNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			img.Set(x, y, color.RGBA{G: 160, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestGroupCatalogSectionsOrdersCategoriesAndProducts(t *testing.T) {
+	seeds, fertilizers := uuid.New(), uuid.New()
+	products := []*models.Product{
+		{ID: uuid.New(), Name: "Urea 45kg", CategoryID: &fertilizers},
+		{ID: uuid.New(), Name: "Sprayer"},
+		{ID: uuid.New(), Name: "DAP 50kg", CategoryID: &fertilizers},
+		{ID: uuid.New(), Name: "Paddy Seed", CategoryID: &seeds},
+	}
+	names := map[uuid.UUID]string{seeds: "Seeds", fertilizers: "Fertilizers"}
+
+	sections := groupCatalogSections(products, names, map[uuid.UUID][]catalogPrice{}, map[uuid.UUID]string{})
+
+	require.Len(t, sections, 3)
+	assert.Equal(t, "Fertilizers", sections[0].Name)
+	assert.Equal(t, "DAP 50kg", sections[0].Items[0].Product.Name)
+	assert.Equal(t, "Seeds", sections[1].Name)
+	assert.Equal(t, catalogUncategorized, sections[2].Name)
+}
+
+func TestRenderCatalogPDF(t *testing.T) {
+	description := "Nitrogen fertilizer for paddy and wheat"
+	unit := "Bag"
+	product := &models.Product{ID: uuid.New(), Name: "Urea 45kg", Description: &description, UnitOfMeasure: &unit}
+	doc := &catalogDocument{
+		TenantName: "Green Fields Agro",
+		Title:      "Kharif 2025 Catalog",
+		PriceNote:  "List prices",
+		Sections: []catalogSection{{
+			Name: "Fertilizers",
+			Items: []catalogItem{
+				{Product: product, Prices: []catalogPrice{{MinQuantity: 1, UnitPrice: 266.5}, {MinQuantity: 50, UnitPrice: 255}}, ImageKey: "urea.png"},
+				{Product: &models.Product{ID: uuid.New(), Name: "DAP 50kg"}, Prices: []catalogPrice{{MinQuantity: 1, UnitPrice: 1350}}, ImageKey: "broken.jpg"},
+			},
+		}},
+	}
+	images := map[string][]byte{"urea.png": samplePNG(t), "broken.jpg": []byte("\xff\xd8\xffnot really a jpeg")}
+
+	var done []int
+	content, err := renderCatalogPDF(doc, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		func(key string) []byte { return images[key] },
+		func(n int) { done = append(done, n) })
+
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+	assert.Equal(t, []int{1, 2}, done)
+}
+
+func TestCatalogFileName(t *testing.T) {
+	at := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "kharif_2025_dealer_catalog_20250601.pdf", catalogFileName("Kharif 2025 — Dealer Catalog!", at))
+	assert.Equal(t, "catalog_20250601.pdf", catalogFileName("खरीफ", at))
+}
@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPeriodCloses keeps closed periods in memory, keyed by branch and month
+type memoryPeriodCloses struct {
+	closes map[string]*models.PeriodClose
+}
+
+func periodCloseKey(branchID *uuid.UUID, period time.Time) string {
+	key := period.Format("2006-01")
+	if branchID != nil {
+		key = branchID.String() + "/" + key
+	}
+	return key
+}
+
+func (r *memoryPeriodCloses) Close(ctx context.Context, periodClose *models.PeriodClose, event *models.PeriodCloseEvent) (bool, error) {
+	key := periodCloseKey(periodClose.BranchID, periodClose.Period)
+	if _, ok := r.closes[key]; ok {
+		return false, nil
+	}
+	r.closes[key] = periodClose
+	return true, nil
+}
+
+func (r *memoryPeriodCloses) Reopen(ctx context.Context, event *models.PeriodCloseEvent) (bool, error) {
+	key := periodCloseKey(event.BranchID, event.Period)
+	if _, ok := r.closes[key]; !ok {
+		return false, nil
+	}
+	delete(r.closes, key)
+	return true, nil
+}
+
+func (r *memoryPeriodCloses) List(ctx context.Context, tenantID uuid.UUID) ([]*models.PeriodClose, error) {
+	return nil, nil
+}
+
+func (r *memoryPeriodCloses) Covering(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, date time.Time) (*models.PeriodClose, error) {
+	month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if periodClose, ok := r.closes[periodCloseKey(nil, month)]; ok {
+		return periodClose, nil
+	}
+	if branchID != nil {
+		return r.closes[periodCloseKey(branchID, month)], nil
+	}
+	return nil, nil
+}
+
+func (r *memoryPeriodCloses) ListEvents(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PeriodCloseEvent, error) {
+	return nil, nil
+}
+
+type stubStatementRepo struct {
+	repositories.StatementRepository
+	invoiceBranches map[uuid.UUID]*uuid.UUID
+	distributorID   uuid.UUID
+	notes           []*models.CreditNote
+}
+
+func (r *stubStatementRepo) InvoiceDistributor(ctx context.Context, tenantID, invoiceID uuid.UUID) (*uuid.UUID, *uuid.UUID, error) {
+	branchID, ok := r.invoiceBranches[invoiceID]
+	if !ok {
+		return nil, nil, nil
+	}
+	return &r.distributorID, branchID, nil
+}
+
+func (r *stubStatementRepo) CreateCreditNote(ctx context.Context, note *models.CreditNote) error {
+	r.notes = append(r.notes, note)
+	return nil
+}
+
+type stubDistributorRepo struct {
+	repositories.DistributorRepository
+}
+
+func (r *stubDistributorRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Distributor, error) {
+	return &models.Distributor{ID: id, TenantID: tenantID}, nil
+}
+
+type stubBranchService struct {
+	services.BranchService
+}
+
+func (s *stubBranchService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.Branch, error) {
+	return &models.Branch{ID: id, TenantID: tenantID}, nil
+}
+
+func TestCreditNotesRespectClosedPeriods(t *testing.T) {
+	ctx := context.Background()
+	tenantID, customerID := uuid.New(), uuid.New()
+	branchID := uuid.New()
+	branchInvoice, tenantInvoice := uuid.New(), uuid.New()
+
+	periods := services.NewPeriodCloseService(&memoryPeriodCloses{closes: map[string]*models.PeriodClose{}}, &stubBranchService{}, nil)
+	repo := &stubStatementRepo{
+		distributorID:   customerID,
+		invoiceBranches: map[uuid.UUID]*uuid.UUID{branchInvoice: &branchID, tenantInvoice: nil},
+	}
+	svc := NewStatementService(repo, &stubDistributorRepo{}, nil, nil, nil, nil, periods)
+
+	issue := func(date string, invoiceID *uuid.UUID) error {
+		_, err := svc.IssueCreditNote(ctx, tenantID, customerID, nil, &models.CreditNoteRequest{
+			Amount:     100,
+			IssuedDate: &date,
+			InvoiceID:  invoiceID,
+		})
+		return err
+	}
+
+	_, err := periods.Close(ctx, tenantID, nil, &models.PeriodCloseRequest{Period: "2025-03"})
+	require.NoError(t, err)
+	_, err = periods.Close(ctx, tenantID, nil, &models.PeriodCloseRequest{Period: "2025-05", BranchID: &branchID})
+	require.NoError(t, err)
+
+	// Backdating into the closed month is refused, whatever the invoice
+	assert.ErrorIs(t, issue("2025-03-31", nil), services.ErrPeriodClosed)
+	assert.ErrorIs(t, issue("2025-03-01", &tenantInvoice), services.ErrPeriodClosed)
+	assert.NoError(t, issue("2025-04-01", nil))
+
+	// A branch's close only locks credit notes against that branch's invoices
+	assert.ErrorIs(t, issue("2025-05-10", &branchInvoice), services.ErrPeriodClosed)
+	assert.NoError(t, issue("2025-05-10", &tenantInvoice))
+	assert.NoError(t, issue("2025-05-10", nil))
+
+	reason := "late supplier claim"
+	require.NoError(t, periods.Reopen(ctx, tenantID, nil, &models.PeriodCloseRequest{Period: "2025-03", Reason: &reason}))
+	assert.NoError(t, issue("2025-03-31", nil))
+
+	assert.Len(t, repo.notes, 4)
+}
@@ -0,0 +1,296 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// dataExportBatch caps rows per fact table and run; the remainder goes
+	// out on the next run
+	dataExportBatch = 5000
+	// dataExportWriteLag keeps rows written in the last few seconds out of a
+	// batch, so a transaction committing late cannot slip behind the cursor
+	dataExportWriteLag        = 5 * time.Second
+	dataExportDefaultInterval = 60
+	dataExportDueDestinations = 100
+)
+
+// ErrDataExportDestinationNotFound is returned for destinations the tenant does not have
+var ErrDataExportDestinationNotFound = errors.New("data export destination not found")
+
+// dataExportIdentifier is what dataset, database and table prefix names may contain
+var dataExportIdentifier = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+
+// DataExportService pushes denormalized fact tables to tenants' analytical
+// stores. Each fact table has its own cursor per destination, so every run
+// only sends the rows changed since the last one
+type DataExportService struct {
+	repo       repositories.DataExportRepository
+	httpClient *http.Client
+}
+
+func NewDataExportService(repo repositories.DataExportRepository) *DataExportService {
+	return &DataExportService{
+		repo:       repo,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// ValidateDestination normalises and checks a destination before it is saved
+func (s *DataExportService) ValidateDestination(destination *models.DataExportDestination) error {
+	destination.Name = strings.TrimSpace(destination.Name)
+	if destination.Name == "" {
+		return fmt.Errorf("destination name is required")
+	}
+
+	settings := &destination.Settings
+	if !dataExportIdentifier.MatchString(settings.TablePrefix) {
+		return fmt.Errorf("table_prefix may only contain letters, digits and underscores")
+	}
+	switch destination.Provider {
+	case models.DataExportBigQuery:
+		if settings.Dataset == "" || !dataExportIdentifier.MatchString(settings.Dataset) {
+			return fmt.Errorf("dataset is required and may only contain letters, digits and underscores")
+		}
+		if _, err := services.NewGoogleServiceAccount([]byte(destination.Credentials), bigQueryScope); err != nil {
+			return fmt.Errorf("credentials must be a BigQuery service account key: %v", err)
+		}
+	case models.DataExportClickHouse:
+		parsed, err := url.Parse(settings.URL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("url must be the http(s) address of the ClickHouse server")
+		}
+		if !dataExportIdentifier.MatchString(settings.Database) {
+			return fmt.Errorf("database may only contain letters, digits and underscores")
+		}
+	default:
+		return fmt.Errorf("provider must be %s or %s", models.DataExportBigQuery, models.DataExportClickHouse)
+	}
+
+	if len(destination.Facts) == 0 {
+		destination.Facts = models.DataExportFacts
+	}
+	seen := make(map[string]bool, len(destination.Facts))
+	facts := make([]string, 0, len(destination.Facts))
+	for _, fact := range destination.Facts {
+		if !validDataExportFact(fact) {
+			return fmt.Errorf("facts must be among %s", strings.Join(models.DataExportFacts, ", "))
+		}
+		if !seen[fact] {
+			seen[fact] = true
+			facts = append(facts, fact)
+		}
+	}
+	destination.Facts = facts
+
+	if destination.SyncIntervalMinutes == 0 {
+		destination.SyncIntervalMinutes = dataExportDefaultInterval
+	}
+	if destination.SyncIntervalMinutes < 15 {
+		return fmt.Errorf("sync_interval_minutes must be at least 15")
+	}
+	return nil
+}
+
+func validDataExportFact(fact string) bool {
+	for _, known := range models.DataExportFacts {
+		if fact == known {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateDestination saves a destination the tenant has consented to export
+// to, recording who consented, and schedules its first run straight away
+func (s *DataExportService) CreateDestination(ctx context.Context, destination *models.DataExportDestination, consent bool, consentedBy *uuid.UUID) error {
+	if !consent {
+		return fmt.Errorf("consent is required to export tenant data to an external store")
+	}
+	if err := s.ValidateDestination(destination); err != nil {
+		return err
+	}
+	now := time.Now()
+	destination.ID = uuid.New()
+	destination.IsActive = true
+	destination.ConsentedBy = consentedBy
+	destination.ConsentedAt = now
+	destination.NextSyncAt = &now
+	if err := s.repo.CreateDestination(ctx, destination); err != nil {
+		return err
+	}
+	destination.HasCredentials = destination.Credentials != ""
+	return nil
+}
+
+// UpdateDestination saves changes; pausing a destination unschedules it
+func (s *DataExportService) UpdateDestination(ctx context.Context, destination *models.DataExportDestination) error {
+	if err := s.ValidateDestination(destination); err != nil {
+		return err
+	}
+	if !destination.IsActive {
+		destination.NextSyncAt = nil
+	} else if destination.NextSyncAt == nil {
+		now := time.Now()
+		destination.NextSyncAt = &now
+	}
+	if err := s.repo.UpdateDestination(ctx, destination); err != nil {
+		return err
+	}
+	destination.HasCredentials = destination.Credentials != ""
+	return nil
+}
+
+func (s *DataExportService) GetDestination(ctx context.Context, tenantID, id uuid.UUID) (*models.DataExportDestination, error) {
+	destination, err := s.repo.GetDestination(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if destination == nil {
+		return nil, ErrDataExportDestinationNotFound
+	}
+	return destination, nil
+}
+
+func (s *DataExportService) ListDestinations(ctx context.Context, tenantID uuid.UUID) ([]*models.DataExportDestination, error) {
+	destinations, err := s.repo.ListDestinations(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if destinations == nil {
+		destinations = []*models.DataExportDestination{}
+	}
+	return destinations, nil
+}
+
+// DeleteDestination withdraws consent: the destination, its cursors and runs
+// are removed and nothing more is pushed to the store
+func (s *DataExportService) DeleteDestination(ctx context.Context, tenantID, id uuid.UUID) error {
+	deleted, err := s.repo.DeleteDestination(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDataExportDestinationNotFound
+	}
+	return nil
+}
+
+// ResetCursors makes the next run push every row again, e.g. after the
+// tenant recreated their tables
+func (s *DataExportService) ResetCursors(ctx context.Context, destination *models.DataExportDestination) error {
+	return s.repo.ResetCursors(ctx, destination.ID)
+}
+
+func (s *DataExportService) ListRuns(ctx context.Context, tenantID, destinationID uuid.UUID, limit, offset int) ([]*models.DataExportRun, error) {
+	return s.repo.ListRuns(ctx, tenantID, destinationID, limit, offset)
+}
+
+// SyncNow pushes the destination's pending changes now
+func (s *DataExportService) SyncNow(ctx context.Context, destination *models.DataExportDestination) (*models.DataExportRun, error) {
+	return s.run(ctx, destination, models.DataExportTriggerManual)
+}
+
+// RunDue runs every active destination whose next sync has passed and
+// returns how many ran
+func (s *DataExportService) RunDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	destinations, err := s.repo.ListDueDestinations(ctx, now, dataExportDueDestinations)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, destination := range destinations {
+		next := now.Add(time.Duration(destination.SyncIntervalMinutes) * time.Minute)
+		run, err := s.run(ctx, destination, models.DataExportTriggerScheduled)
+		if err != nil {
+			log.Printf("Data export failed for destination %s: %v", destination.ID.String(), err)
+		} else if run.HasMore {
+			// Catch up on the next tick when a batch was capped
+			next = now
+		}
+		if err := s.repo.ScheduleDestination(ctx, destination.ID, next); err != nil {
+			log.Printf("Failed to reschedule data export destination %s: %v", destination.ID.String(), err)
+		}
+	}
+	return len(destinations), nil
+}
+
+// run pushes each fact table's changes; failures are recorded on the run
+// rather than returned, and facts pushed before a failure keep their progress
+func (s *DataExportService) run(ctx context.Context, destination *models.DataExportDestination, trigger string) (*models.DataExportRun, error) {
+	run := &models.DataExportRun{
+		ID:            uuid.New(),
+		TenantID:      destination.TenantID,
+		DestinationID: destination.ID,
+		Trigger:       trigger,
+		Status:        models.DataExportRunning,
+	}
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to record export run: %w", err)
+	}
+
+	if err := s.export(ctx, destination, run); err != nil {
+		message := err.Error()
+		run.Status = models.DataExportFailed
+		run.ErrorMessage = &message
+	} else {
+		run.Status = models.DataExportSucceeded
+	}
+	if err := s.repo.FinishRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to record export result: %w", err)
+	}
+	return run, nil
+}
+
+func (s *DataExportService) export(ctx context.Context, destination *models.DataExportDestination, run *models.DataExportRun) error {
+	sink, err := dataExportSinkFor(destination, s.httpClient)
+	if err != nil {
+		return err
+	}
+
+	before := time.Now().Add(-dataExportWriteLag)
+	for _, fact := range destination.Facts {
+		cursor, err := s.repo.GetCursor(ctx, destination.ID, fact)
+		if err != nil {
+			return fmt.Errorf("failed to load %s cursor: %w", fact, err)
+		}
+		records, err := s.repo.ListChangesAfter(ctx, destination.TenantID, cursor, before, dataExportBatch+1)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", fact, err)
+		}
+		if len(records) > dataExportBatch {
+			records = records[:dataExportBatch]
+			run.HasMore = true
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		if err := sink.Insert(ctx, destination.Settings.TablePrefix+fact, records); err != nil {
+			return err
+		}
+		last := records[len(records)-1]
+		cursor.LastExportedAt = &last.ChangedAt
+		cursor.LastExportedID = &last.RowID
+		if err := s.repo.SaveCursor(ctx, cursor); err != nil {
+			return fmt.Errorf("failed to advance %s cursor: %w", fact, err)
+		}
+		run.RecordsExported += len(records)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+)
+
+const (
+	bigQueryScope     = "https://www.googleapis.com/auth/bigquery.insertdata"
+	bigQueryInsertURL = "https://bigquery.googleapis.com/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll"
+	// bigQueryInsertBatch is the row count BigQuery recommends per streaming insert
+	bigQueryInsertBatch = 500
+)
+
+// DataExportSink appends fact rows to a table of an analytical store
+type DataExportSink interface {
+	Insert(ctx context.Context, table string, records []*models.DataExportRecord) error
+}
+
+// dataExportSinkFor returns the sink writing to a destination's store
+func dataExportSinkFor(destination *models.DataExportDestination, httpClient *http.Client) (DataExportSink, error) {
+	switch destination.Provider {
+	case models.DataExportBigQuery:
+		account, err := services.NewGoogleServiceAccount([]byte(destination.Credentials), bigQueryScope)
+		if err != nil {
+			return nil, err
+		}
+		projectID := destination.Settings.ProjectID
+		if projectID == "" {
+			projectID = account.ProjectID
+		}
+		return &bigQuerySink{account: account, projectID: projectID, dataset: destination.Settings.Dataset, httpClient: httpClient}, nil
+	case models.DataExportClickHouse:
+		return &clickHouseSink{settings: destination.Settings, password: destination.Credentials, httpClient: httpClient}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", destination.Provider)
+	}
+}
+
+// bigQuerySink streams rows with tabledata.insertAll. Each row's insert ID is
+// its ID and change time, so BigQuery drops a retried batch's duplicates
+type bigQuerySink struct {
+	account    *services.GoogleServiceAccount
+	projectID  string
+	dataset    string
+	httpClient *http.Client
+}
+
+type bigQueryInsertRow struct {
+	InsertID string      `json:"insertId"`
+	JSON     interface{} `json:"json"`
+}
+
+func (s *bigQuerySink) Insert(ctx context.Context, table string, records []*models.DataExportRecord) error {
+	for start := 0; start < len(records); start += bigQueryInsertBatch {
+		end := start + bigQueryInsertBatch
+		if end > len(records) {
+			end = len(records)
+		}
+		if err := s.insertBatch(ctx, table, records[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *bigQuerySink) insertBatch(ctx context.Context, table string, records []*models.DataExportRecord) error {
+	rows := make([]bigQueryInsertRow, len(records))
+	for i, record := range records {
+		rows[i] = bigQueryInsertRow{
+			InsertID: fmt.Sprintf("%s-%d", record.RowID.String(), record.ChangedAt.UnixNano()),
+			JSON:     record.Row,
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"ignoreUnknownValues": true,
+		"rows":                rows,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal rows: %w", err)
+	}
+
+	accessToken, err := s.account.Token(ctx)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf(bigQueryInsertURL, url.PathEscape(s.projectID), url.PathEscape(s.dataset), url.PathEscape(table))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create BigQuery request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("BigQuery request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode == http.StatusUnauthorized {
+		s.account.Invalidate()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("BigQuery returned status %d for %s: %s", resp.StatusCode, table, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+	if err := json.Unmarshal(respBody, &result); err == nil && len(result.InsertErrors) > 0 {
+		first := result.InsertErrors[0]
+		message := "row rejected"
+		if len(first.Errors) > 0 {
+			message = first.Errors[0].Message
+		}
+		return fmt.Errorf("BigQuery rejected %d rows of %s, first at %d: %s", len(result.InsertErrors), table, first.Index, message)
+	}
+	return nil
+}
+
+// clickHouseSink inserts rows as JSONEachRow over ClickHouse's HTTP interface
+type clickHouseSink struct {
+	settings   models.DataExportSettings
+	password   string
+	httpClient *http.Client
+}
+
+func (s *clickHouseSink) Insert(ctx context.Context, table string, records []*models.DataExportRecord) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, record := range records {
+		if err := encoder.Encode(record.Row); err != nil {
+			return fmt.Errorf("failed to marshal rows: %w", err)
+		}
+	}
+
+	params := url.Values{
+		"query":                            {fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table)},
+		"input_format_skip_unknown_fields": {"1"},
+		"date_time_input_format":           {"best_effort"},
+	}
+	if s.settings.Database != "" {
+		params.Set("database", s.settings.Database)
+	}
+	endpoint := strings.TrimRight(s.settings.URL, "/") + "/?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create ClickHouse request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.settings.Username != "" {
+		req.Header.Set("X-ClickHouse-User", s.settings.Username)
+		req.Header.Set("X-ClickHouse-Key", s.password)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ClickHouse request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return fmt.Errorf("ClickHouse returned status %d for %s: %s", resp.StatusCode, table, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
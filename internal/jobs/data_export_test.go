@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickHouseSinkPostsJSONEachRow(t *testing.T) {
+	var query, database, user, key string
+	var rows []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		database = r.URL.Query().Get("database")
+		user = r.Header.Get("X-ClickHouse-User")
+		key = r.Header.Get("X-ClickHouse-Key")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &row))
+			rows = append(rows, row)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink, err := dataExportSinkFor(&models.DataExportDestination{
+		Provider:    models.DataExportClickHouse,
+		Settings:    models.DataExportSettings{URL: server.URL + "/", Database: "analytics", Username: "exporter"},
+		Credentials: "secret",
+	}, server.Client())
+	require.NoError(t, err)
+
+	records := []*models.DataExportRecord{
+		{RowID: uuid.New(), ChangedAt: time.Now(), Row: models.OrderFact{OrderType: "sales", Status: "pending", TotalQuantity: 2}},
+		{RowID: uuid.New(), ChangedAt: time.Now(), Row: models.OrderFact{OrderType: "purchase", Status: "received", TotalQuantity: 5}},
+	}
+	require.NoError(t, sink.Insert(context.Background(), "agro_orders", records))
+
+	assert.Equal(t, "INSERT INTO agro_orders FORMAT JSONEachRow", query)
+	assert.Equal(t, "analytics", database)
+	assert.Equal(t, "exporter", user)
+	assert.Equal(t, "secret", key)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "sales", rows[0]["order_type"])
+	assert.Equal(t, float64(5), rows[1]["total_quantity"])
+}
+
+func TestClickHouseSinkReportsServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Table analytics.orders doesn't exist", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	sink, err := dataExportSinkFor(&models.DataExportDestination{
+		Provider: models.DataExportClickHouse,
+		Settings: models.DataExportSettings{URL: server.URL},
+	}, server.Client())
+	require.NoError(t, err)
+
+	err = sink.Insert(context.Background(), "orders", []*models.DataExportRecord{{RowID: uuid.New(), Row: models.OrderFact{}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "doesn't exist")
+}
+
+func TestValidateDestinationDefaultsFactsAndInterval(t *testing.T) {
+	service := NewDataExportService(nil)
+	destination := &models.DataExportDestination{
+		Name:     "  Warehouse  ",
+		Provider: models.DataExportClickHouse,
+		Settings: models.DataExportSettings{URL: "https://clickhouse.example.com:8443"},
+	}
+
+	require.NoError(t, service.ValidateDestination(destination))
+	assert.Equal(t, "Warehouse", destination.Name)
+	assert.Equal(t, models.DataExportFacts, destination.Facts)
+	assert.Equal(t, dataExportDefaultInterval, destination.SyncIntervalMinutes)
+}
+
+func TestValidateDestinationRejectsInvalidSettings(t *testing.T) {
+	service := NewDataExportService(nil)
+	clickHouse := models.DataExportSettings{URL: "https://clickhouse.example.com"}
+
+	tests := []struct {
+		name        string
+		destination models.DataExportDestination
+	}{
+		{"unknown provider", models.DataExportDestination{Name: "x", Provider: "snowflake"}},
+		{"table prefix with a dot", models.DataExportDestination{Name: "x", Provider: models.DataExportClickHouse,
+			Settings: models.DataExportSettings{URL: clickHouse.URL, TablePrefix: "a.b"}}},
+		{"non-http url", models.DataExportDestination{Name: "x", Provider: models.DataExportClickHouse,
+			Settings: models.DataExportSettings{URL: "tcp://clickhouse:9000"}}},
+		{"unknown fact", models.DataExportDestination{Name: "x", Provider: models.DataExportClickHouse,
+			Settings: clickHouse, Facts: []string{"payments"}}},
+		{"interval too short", models.DataExportDestination{Name: "x", Provider: models.DataExportClickHouse,
+			Settings: clickHouse, SyncIntervalMinutes: 5}},
+		{"bigquery without a key", models.DataExportDestination{Name: "x", Provider: models.DataExportBigQuery,
+			Settings: models.DataExportSettings{Dataset: "agromart"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination := tt.destination
+			assert.Error(t, service.ValidateDestination(&destination))
+		})
+	}
+}
+
+func TestValidateDestinationDeduplicatesFacts(t *testing.T) {
+	service := NewDataExportService(nil)
+	destination := &models.DataExportDestination{
+		Name:     "Warehouse",
+		Provider: models.DataExportClickHouse,
+		Settings: models.DataExportSettings{URL: "http://clickhouse:8123"},
+		Facts:    []string{models.DataExportFactInvoices, models.DataExportFactOrders, models.DataExportFactInvoices},
+	}
+
+	require.NoError(t, service.ValidateDestination(destination))
+	assert.Equal(t, []string{models.DataExportFactInvoices, models.DataExportFactOrders}, destination.Facts)
+}
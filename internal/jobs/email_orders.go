@@ -0,0 +1,253 @@
+package jobs
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// emailOrderBatch bounds the emails parsed per worker run
+const emailOrderBatch = 100
+
+// EmailOrderIngestionService turns received order emails from known dealers
+// into draft orders waiting for review. Lines come from CSV attachments when
+// there are any, otherwise from the body using the tenant's line pattern
+type EmailOrderIngestionService struct {
+	repo        repositories.EmailOrderRepository
+	productRepo repositories.ProductRepository
+}
+
+func NewEmailOrderIngestionService(repo repositories.EmailOrderRepository, productRepo repositories.ProductRepository) *EmailOrderIngestionService {
+	return &EmailOrderIngestionService{
+		repo:        repo,
+		productRepo: productRepo,
+	}
+}
+
+// parsedEmailLine is an order line read from an email before it is matched
+// to a product
+type parsedEmailLine struct {
+	SKU       string
+	Quantity  int
+	UnitPrice *float64
+}
+
+// parseEmailOrderBody reads one order line from each body line the pattern
+// matches. Quoted reply lines are skipped so a forwarded order is not read twice
+func parseEmailOrderBody(body string, pattern *regexp.Regexp) []parsedEmailLine {
+	skuIndex := pattern.SubexpIndex("sku")
+	quantityIndex := pattern.SubexpIndex("quantity")
+	priceIndex := pattern.SubexpIndex("price")
+
+	var lines []parsedEmailLine
+	for _, text := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(text), ">") {
+			continue
+		}
+		match := pattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		quantity, err := strconv.Atoi(strings.TrimSpace(match[quantityIndex]))
+		if err != nil || quantity <= 0 {
+			continue
+		}
+		line := parsedEmailLine{SKU: strings.TrimSpace(match[skuIndex]), Quantity: quantity}
+		if priceIndex >= 0 && match[priceIndex] != "" {
+			if price, err := strconv.ParseFloat(strings.TrimSpace(match[priceIndex]), 64); err == nil {
+				line.UnitPrice = &price
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseEmailOrderCSV reads order lines from a CSV attachment whose header row
+// names the SKU, quantity and optional price columns
+func parseEmailOrderCSV(content string, settings *models.EmailOrderSettings) ([]parsedEmailLine, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	skuCol, quantityCol, priceCol := -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF"))) {
+		case settings.SKUColumn:
+			skuCol = i
+		case settings.QuantityColumn:
+			quantityCol = i
+		case settings.PriceColumn:
+			priceCol = i
+		}
+	}
+	if skuCol < 0 || quantityCol < 0 {
+		return nil, fmt.Errorf("CSV needs %q and %q columns", settings.SKUColumn, settings.QuantityColumn)
+	}
+
+	var lines []parsedEmailLine
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV row %d: %w", row, err)
+		}
+		if skuCol >= len(record) || quantityCol >= len(record) || strings.TrimSpace(record[skuCol]) == "" {
+			continue
+		}
+		quantity, err := strconv.Atoi(strings.TrimSpace(record[quantityCol]))
+		if err != nil || quantity <= 0 {
+			return nil, fmt.Errorf("CSV row %d: quantity must be a positive whole number", row)
+		}
+		line := parsedEmailLine{SKU: strings.TrimSpace(record[skuCol]), Quantity: quantity}
+		if priceCol >= 0 && priceCol < len(record) && strings.TrimSpace(record[priceCol]) != "" {
+			price, err := strconv.ParseFloat(strings.TrimSpace(record[priceCol]), 64)
+			if err != nil || price < 0 {
+				return nil, fmt.Errorf("CSV row %d: invalid price", row)
+			}
+			line.UnitPrice = &price
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// isCSVAttachment reports whether an attachment should be read as CSV
+func isCSVAttachment(attachment models.EmailAttachment) bool {
+	return strings.HasSuffix(strings.ToLower(attachment.Filename), ".csv") ||
+		strings.HasPrefix(strings.ToLower(attachment.ContentType), "text/csv")
+}
+
+// ProcessPending parses pending emails into draft orders and returns how many
+// drafts it created. Emails that cannot be parsed are marked failed with the
+// reason, and those from unknown senders are set aside
+func (s *EmailOrderIngestionService) ProcessPending(ctx context.Context) (int, error) {
+	emails, err := s.repo.ListPendingEmails(ctx, emailOrderBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pending emails: %w", err)
+	}
+
+	created := 0
+	settingsByTenant := make(map[uuid.UUID]*models.EmailOrderSettings)
+	for _, email := range emails {
+		settings, ok := settingsByTenant[email.TenantID]
+		if !ok {
+			if settings, err = s.repo.GetSettings(ctx, email.TenantID); err != nil {
+				log.Printf("Failed to load email order settings for tenant %s: %v", email.TenantID, err)
+				continue
+			}
+			settingsByTenant[email.TenantID] = settings
+		}
+
+		draft, status, reason := s.parseEmail(ctx, email, settings)
+		if draft != nil {
+			if err := s.repo.SaveDraft(ctx, email, draft); err != nil {
+				log.Printf("Failed to save draft order for email %s: %v", email.ID, err)
+				continue
+			}
+			created++
+			continue
+		}
+
+		email.Status = status
+		email.Error = &reason
+		if err := s.repo.MarkEmail(ctx, email); err != nil {
+			log.Printf("Failed to mark email %s %s: %v", email.ID, status, err)
+		}
+	}
+	return created, nil
+}
+
+// parseEmail builds the email's draft order, or returns the status and
+// reason for not creating one
+func (s *EmailOrderIngestionService) parseEmail(ctx context.Context, email *models.InboundEmail, settings *models.EmailOrderSettings) (*models.EmailDraftOrder, string, string) {
+	if settings == nil {
+		return nil, models.InboundEmailFailed, "email ordering is not configured"
+	}
+
+	sender, err := s.repo.MatchSender(ctx, email.TenantID, email.FromAddress)
+	if err != nil {
+		return nil, models.InboundEmailFailed, "failed to look up sender"
+	}
+	if sender == nil {
+		return nil, models.InboundEmailUnknownSender, fmt.Sprintf("%s is not a known dealer address", email.FromAddress)
+	}
+
+	var parsed []parsedEmailLine
+	for _, attachment := range email.Attachments {
+		if !isCSVAttachment(attachment) {
+			continue
+		}
+		lines, err := parseEmailOrderCSV(attachment.Content, settings)
+		if err != nil {
+			return nil, models.InboundEmailFailed, fmt.Sprintf("%s: %v", attachment.Filename, err)
+		}
+		parsed = append(parsed, lines...)
+	}
+	if len(parsed) == 0 {
+		pattern, err := services.CompileEmailOrderLinePattern(settings.LinePattern)
+		if err != nil {
+			return nil, models.InboundEmailFailed, err.Error()
+		}
+		parsed = parseEmailOrderBody(email.BodyText, pattern)
+	}
+	if len(parsed) == 0 {
+		return nil, models.InboundEmailFailed, "no order lines found"
+	}
+
+	draft := &models.EmailDraftOrder{
+		ID:            uuid.New(),
+		TenantID:      email.TenantID,
+		EmailID:       email.ID,
+		FromAddress:   email.FromAddress,
+		Subject:       email.Subject,
+		DistributorID: sender.DistributorID,
+		WarehouseID:   sender.WarehouseID,
+		Status:        models.EmailDraftPendingReview,
+		OrderIDs:      []uuid.UUID{},
+	}
+	if draft.WarehouseID == nil {
+		draft.WarehouseID = settings.DefaultWarehouseID
+	}
+	for _, line := range parsed {
+		draft.Lines = append(draft.Lines, s.matchLine(ctx, email.TenantID, line))
+	}
+	return draft, models.InboundEmailParsed, ""
+}
+
+// matchLine resolves the line's SKU to a product by ID or barcode
+func (s *EmailOrderIngestionService) matchLine(ctx context.Context, tenantID uuid.UUID, line parsedEmailLine) models.EmailDraftOrderLine {
+	draftLine := models.EmailDraftOrderLine{SKU: line.SKU, Quantity: line.Quantity, UnitPrice: line.UnitPrice}
+
+	var product *models.Product
+	if id, err := uuid.Parse(line.SKU); err == nil {
+		product, _ = s.productRepo.GetByID(ctx, tenantID, id)
+	}
+	if product == nil {
+		product, _ = s.productRepo.GetByBarcode(ctx, tenantID, line.SKU)
+	}
+	if product == nil {
+		draftLine.Error = fmt.Sprintf("no product matches SKU %q", line.SKU)
+		return draftLine
+	}
+	draftLine.ProductID = &product.ID
+	draftLine.ProductName = product.Name
+	return draftLine
+}
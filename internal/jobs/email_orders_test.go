@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"testing"
+
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEmailOrderBody(t *testing.T) {
+	pattern, err := services.CompileEmailOrderLinePattern(nil)
+	require.NoError(t, err)
+
+	body := "Hi,\r\nPlease send:\r\nUREA-50 x 10 @ 266.50\r\nDAP-50, 4, 1350\r\nZINC-1 12\r\n> OLD-1 x 99\r\nThanks\r\n"
+	lines := parseEmailOrderBody(body, pattern)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, "UREA-50", lines[0].SKU)
+	assert.Equal(t, 10, lines[0].Quantity)
+	require.NotNil(t, lines[0].UnitPrice)
+	assert.Equal(t, 266.5, *lines[0].UnitPrice)
+
+	assert.Equal(t, "DAP-50", lines[1].SKU)
+	assert.Equal(t, 4, lines[1].Quantity)
+	require.NotNil(t, lines[1].UnitPrice)
+	assert.Equal(t, 1350.0, *lines[1].UnitPrice)
+
+	assert.Equal(t, "ZINC-1", lines[2].SKU)
+	assert.Equal(t, 12, lines[2].Quantity)
+	assert.Nil(t, lines[2].UnitPrice)
+}
+
+func TestParseEmailOrderBodyCustomPattern(t *testing.T) {
+	custom := `^Item (?P<sku>\S+) qty (?P<quantity>\d+)$`
+	pattern, err := services.CompileEmailOrderLinePattern(&custom)
+	require.NoError(t, err)
+
+	lines := parseEmailOrderBody("Item UREA-50 qty 3\nUREA-50 x 10", pattern)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "UREA-50", lines[0].SKU)
+	assert.Equal(t, 3, lines[0].Quantity)
+
+	missing := `^(?P<sku>\S+)$`
+	_, err = services.CompileEmailOrderLinePattern(&missing)
+	assert.ErrorIs(t, err, services.ErrInvalidEmailOrder)
+}
+
+func TestParseEmailOrderCSV(t *testing.T) {
+	settings := &models.EmailOrderSettings{SKUColumn: "sku", QuantityColumn: "qty", PriceColumn: "rate"}
+
+	lines, err := parseEmailOrderCSV("\uFEFFSKU, Qty, Rate\nUREA-50, 10, 266.5\n,,\nDAP-50, 4,\n", settings)
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "UREA-50", lines[0].SKU)
+	assert.Equal(t, 10, lines[0].Quantity)
+	require.NotNil(t, lines[0].UnitPrice)
+	assert.Equal(t, 266.5, *lines[0].UnitPrice)
+	assert.Equal(t, "DAP-50", lines[1].SKU)
+	assert.Nil(t, lines[1].UnitPrice)
+
+	_, err = parseEmailOrderCSV("sku,qty\nUREA-50,ten\n", settings)
+	assert.Error(t, err)
+
+	_, err = parseEmailOrderCSV("product,amount\nUREA-50,1\n", settings)
+	assert.Error(t, err)
+}
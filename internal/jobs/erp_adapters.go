@@ -0,0 +1,301 @@
+package jobs
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"agromart2/internal/models"
+)
+
+// ERPAdapter renders an ERPDocument in a specific accounting package's import format
+type ERPAdapter interface {
+	Render(doc *ERPDocument, settings models.ERPConnectorSettings) ([]byte, error)
+	FileExtension() string
+	ContentType() string
+}
+
+// ERPAdapterFor returns the adapter for a connector type
+func ERPAdapterFor(connectorType string) (ERPAdapter, error) {
+	switch connectorType {
+	case models.ERPConnectorTallyXML:
+		return TallyXMLAdapter{}, nil
+	case models.ERPConnectorBusyCSV:
+		return BusyCSVAdapter{}, nil
+	case models.ERPConnectorMarg:
+		return MargAdapter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported connector type: %s", connectorType)
+	}
+}
+
+func ledgerFor(voucher ERPVoucher, settings models.ERPConnectorSettings) string {
+	if voucher.Kind == "purchase" {
+		if settings.PurchaseLedger != "" {
+			return settings.PurchaseLedger
+		}
+		return "Purchase"
+	}
+	if settings.SalesLedger != "" {
+		return settings.SalesLedger
+	}
+	return "Sales"
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// TallyXMLAdapter produces a Tally "Import Data" envelope of vouchers, which
+// can be imported from file or posted to Tally's HTTP server
+type TallyXMLAdapter struct{}
+
+type tallyEnvelope struct {
+	XMLName xml.Name `xml:"ENVELOPE"`
+	Header  struct {
+		TallyRequest string `xml:"TALLYREQUEST"`
+	} `xml:"HEADER"`
+	Body struct {
+		ImportData struct {
+			RequestDesc struct {
+				ReportName     string `xml:"REPORTNAME"`
+				CurrentCompany string `xml:"STATICVARIABLES>SVCURRENTCOMPANY,omitempty"`
+			} `xml:"REQUESTDESC"`
+			Messages []tallyMessage `xml:"REQUESTDATA>TALLYMESSAGE"`
+		} `xml:"IMPORTDATA"`
+	} `xml:"BODY"`
+}
+
+type tallyMessage struct {
+	Voucher tallyVoucher `xml:"VOUCHER"`
+}
+
+type tallyVoucher struct {
+	VchType          string                `xml:"VCHTYPE,attr"`
+	Action           string                `xml:"ACTION,attr"`
+	Date             string                `xml:"DATE"`
+	VoucherTypeName  string                `xml:"VOUCHERTYPENAME"`
+	VoucherNumber    string                `xml:"VOUCHERNUMBER"`
+	Reference        string                `xml:"REFERENCE,omitempty"`
+	PartyLedgerName  string                `xml:"PARTYLEDGERNAME"`
+	PartyGSTIN       string                `xml:"PARTYGSTIN,omitempty"`
+	Narration        string                `xml:"NARRATION,omitempty"`
+	IsInvoice        string                `xml:"ISINVOICE"`
+	InventoryEntries []tallyInventoryEntry `xml:"ALLINVENTORYENTRIES.LIST"`
+	LedgerEntries    []tallyLedgerEntry    `xml:"LEDGERENTRIES.LIST"`
+}
+
+type tallyInventoryEntry struct {
+	StockItemName string           `xml:"STOCKITEMNAME"`
+	HSN           string           `xml:"GSTHSNNAME,omitempty"`
+	IsDeemedPos   string           `xml:"ISDEEMEDPOSITIVE"`
+	Rate          string           `xml:"RATE"`
+	Amount        string           `xml:"AMOUNT"`
+	ActualQty     string           `xml:"ACTUALQTY"`
+	BilledQty     string           `xml:"BILLEDQTY"`
+	Allocation    tallyLedgerEntry `xml:"ACCOUNTINGALLOCATIONS.LIST"`
+}
+
+type tallyLedgerEntry struct {
+	LedgerName  string `xml:"LEDGERNAME"`
+	IsDeemedPos string `xml:"ISDEEMEDPOSITIVE"`
+	Amount      string `xml:"AMOUNT"`
+}
+
+func tallyYesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
+
+func (TallyXMLAdapter) Render(doc *ERPDocument, settings models.ERPConnectorSettings) ([]byte, error) {
+	envelope := tallyEnvelope{}
+	envelope.Header.TallyRequest = "Import Data"
+	envelope.Body.ImportData.RequestDesc.ReportName = "Vouchers"
+	envelope.Body.ImportData.RequestDesc.CurrentCompany = settings.CompanyName
+
+	for _, voucher := range doc.Vouchers {
+		// Tally signs debits negative: a sale debits the party and credits
+		// sales and tax ledgers, a purchase the reverse
+		sales := voucher.Kind != "purchase"
+		sign := 1.0
+		if !sales {
+			sign = -1.0
+		}
+
+		vch := tallyVoucher{
+			VchType:         voucher.VoucherType(),
+			Action:          "Create",
+			Date:            voucher.Date.Format("20060102"),
+			VoucherTypeName: voucher.VoucherType(),
+			VoucherNumber:   voucher.Number,
+			Reference:       voucher.Reference,
+			PartyLedgerName: voucher.PartyName,
+			PartyGSTIN:      voucher.PartyGSTIN,
+			Narration:       voucher.Narration,
+			IsInvoice:       tallyYesNo(voucher.Source == "invoice"),
+		}
+
+		vch.LedgerEntries = append(vch.LedgerEntries, tallyLedgerEntry{
+			LedgerName:  voucher.PartyName,
+			IsDeemedPos: tallyYesNo(sales),
+			Amount:      formatAmount(-sign * voucher.TotalAmount),
+		})
+
+		for _, line := range voucher.Lines {
+			qty := fmt.Sprintf(" %d %s", line.Quantity, line.Unit)
+			vch.InventoryEntries = append(vch.InventoryEntries, tallyInventoryEntry{
+				StockItemName: line.ItemName,
+				HSN:           line.HSN,
+				IsDeemedPos:   tallyYesNo(!sales),
+				Rate:          fmt.Sprintf("%s/%s", formatAmount(line.Rate), line.Unit),
+				Amount:        formatAmount(sign * line.Amount),
+				ActualQty:     qty,
+				BilledQty:     qty,
+				Allocation: tallyLedgerEntry{
+					LedgerName:  ledgerFor(voucher, settings),
+					IsDeemedPos: tallyYesNo(!sales),
+					Amount:      formatAmount(sign * line.Amount),
+				},
+			})
+		}
+
+		for _, tax := range []struct {
+			ledger string
+			amount float64
+		}{{"CGST", voucher.CGST}, {"SGST", voucher.SGST}, {"IGST", voucher.IGST}} {
+			if tax.amount == 0 {
+				continue
+			}
+			vch.LedgerEntries = append(vch.LedgerEntries, tallyLedgerEntry{
+				LedgerName:  tax.ledger,
+				IsDeemedPos: tallyYesNo(!sales),
+				Amount:      formatAmount(sign * tax.amount),
+			})
+		}
+
+		envelope.Body.ImportData.Messages = append(envelope.Body.ImportData.Messages, tallyMessage{Voucher: vch})
+	}
+
+	out, err := xml.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func (TallyXMLAdapter) FileExtension() string { return "xml" }
+
+func (TallyXMLAdapter) ContentType() string { return "application/xml" }
+
+// writeVoucherRows writes one CSV row per voucher line; voucher-level tax and
+// totals go on the first line only so imports do not double count them
+func writeVoucherRows(doc *ERPDocument, header []string, row func(voucher ERPVoucher, line ERPVoucherLine, first bool) []string) ([]byte, error) {
+	var sb strings.Builder
+	writer := csv.NewWriter(&sb)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, voucher := range doc.Vouchers {
+		for i, line := range voucher.Lines {
+			if err := writer.Write(row(voucher, line, i == 0)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+func firstOnly(first bool, amount float64) string {
+	if !first {
+		return ""
+	}
+	return formatAmount(amount)
+}
+
+// BusyCSVAdapter produces Busy's voucher import sheet as CSV
+type BusyCSVAdapter struct{}
+
+func (BusyCSVAdapter) Render(doc *ERPDocument, settings models.ERPConnectorSettings) ([]byte, error) {
+	header := []string{
+		"Date", "Vch/Bill No", "Vch Type", "Party Name", "Party GSTIN", "Sale/Purc Type",
+		"Item Name", "HSN Code", "Qty", "Unit", "Price", "Amount",
+		"CGST", "SGST", "IGST", "Bill Amount", "Narration",
+	}
+	return writeVoucherRows(doc, header, func(voucher ERPVoucher, line ERPVoucherLine, first bool) []string {
+		return []string{
+			voucher.Date.Format("02-01-2006"),
+			voucher.Number,
+			voucher.VoucherType(),
+			voucher.PartyName,
+			voucher.PartyGSTIN,
+			ledgerFor(voucher, settings),
+			line.ItemName,
+			line.HSN,
+			strconv.Itoa(line.Quantity),
+			line.Unit,
+			formatAmount(line.Rate),
+			formatAmount(line.Amount),
+			firstOnly(first, voucher.CGST),
+			firstOnly(first, voucher.SGST),
+			firstOnly(first, voucher.IGST),
+			firstOnly(first, voucher.TotalAmount),
+			voucher.Narration,
+		}
+	})
+}
+
+func (BusyCSVAdapter) FileExtension() string { return "csv" }
+
+func (BusyCSVAdapter) ContentType() string { return "text/csv" }
+
+// MargAdapter produces the column layout of Marg's bill import utility as CSV
+type MargAdapter struct{}
+
+func (MargAdapter) Render(doc *ERPDocument, settings models.ERPConnectorSettings) ([]byte, error) {
+	header := []string{
+		"VOUCHER TYPE", "BILL NO", "BILL DATE", "PARTY NAME", "GSTIN",
+		"ITEM NAME", "HSN", "QTY", "UNIT", "RATE", "AMOUNT",
+		"TAXABLE AMT", "CGST AMT", "SGST AMT", "IGST AMT", "NET AMOUNT",
+	}
+	return writeVoucherRows(doc, header, func(voucher ERPVoucher, line ERPVoucherLine, first bool) []string {
+		voucherType := "SALE"
+		if voucher.Kind == "purchase" {
+			voucherType = "PURCHASE"
+		}
+		if voucher.Source == "order" {
+			voucherType += " ORDER"
+		}
+		return []string{
+			voucherType,
+			voucher.Number,
+			voucher.Date.Format("02/01/2006"),
+			strings.ToUpper(voucher.PartyName),
+			voucher.PartyGSTIN,
+			line.ItemName,
+			line.HSN,
+			strconv.Itoa(line.Quantity),
+			line.Unit,
+			formatAmount(line.Rate),
+			formatAmount(line.Amount),
+			firstOnly(first, voucher.TaxableAmount),
+			firstOnly(first, voucher.CGST),
+			firstOnly(first, voucher.SGST),
+			firstOnly(first, voucher.IGST),
+			firstOnly(first, voucher.TotalAmount),
+		}
+	})
+}
+
+func (MargAdapter) FileExtension() string { return "csv" }
+
+func (MargAdapter) ContentType() string { return "text/csv" }
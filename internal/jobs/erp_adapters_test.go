@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleERPDocument() *ERPDocument {
+	date := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	return &ERPDocument{
+		PeriodStart: date,
+		PeriodEnd:   date,
+		Vouchers: []ERPVoucher{
+			{
+				Kind:          "sales",
+				Source:        "invoice",
+				Number:        "INV-2025-0001",
+				Date:          date,
+				PartyName:     "Green Fields Agro",
+				PartyGSTIN:    "27ABCDE1234F1Z5",
+				Lines:         []ERPVoucherLine{{ItemName: "Urea 50kg", HSN: "3102", Unit: "Bag", Quantity: 10, Rate: 300, Amount: 3000}},
+				TaxableAmount: 3000,
+				CGST:          75,
+				SGST:          75,
+				TotalAmount:   3150,
+			},
+			{
+				Kind:          "purchase",
+				Source:        "order",
+				Number:        "ORD-1a2b3c4d",
+				Date:          date,
+				PartyName:     "Seed Co",
+				Lines:         []ERPVoucherLine{{ItemName: "Paddy Seed", Unit: "Kg", Quantity: 50, Rate: 40, Amount: 2000}},
+				TaxableAmount: 2000,
+				TotalAmount:   2000,
+			},
+		},
+	}
+}
+
+func TestERPAdapterFor(t *testing.T) {
+	for _, connectorType := range []string{models.ERPConnectorTallyXML, models.ERPConnectorBusyCSV, models.ERPConnectorMarg} {
+		adapter, err := ERPAdapterFor(connectorType)
+		require.NoError(t, err, connectorType)
+		assert.NotEmpty(t, adapter.FileExtension())
+	}
+
+	_, err := ERPAdapterFor("sap")
+	assert.Error(t, err)
+}
+
+func TestTallyXMLAdapter_Render(t *testing.T) {
+	out, err := TallyXMLAdapter{}.Render(sampleERPDocument(), models.ERPConnectorSettings{CompanyName: "Agromart Demo"})
+	require.NoError(t, err)
+
+	xml := string(out)
+	assert.Contains(t, xml, "<TALLYREQUEST>Import Data</TALLYREQUEST>")
+	assert.Contains(t, xml, "<SVCURRENTCOMPANY>Agromart Demo</SVCURRENTCOMPANY>")
+	assert.Contains(t, xml, `<VOUCHER VCHTYPE="Sales" ACTION="Create">`)
+	assert.Contains(t, xml, `<VOUCHER VCHTYPE="Purchase Order" ACTION="Create">`)
+	assert.Contains(t, xml, "<DATE>20250314</DATE>")
+	// The sale debits the party (negative in Tally) and credits tax ledgers
+	assert.Contains(t, xml, "<AMOUNT>-3150.00</AMOUNT>")
+	assert.Contains(t, xml, "<LEDGERNAME>CGST</LEDGERNAME>")
+	assert.NotContains(t, xml, "<LEDGERNAME>IGST</LEDGERNAME>")
+}
+
+func TestBusyCSVAdapter_Render(t *testing.T) {
+	out, err := BusyCSVAdapter{}.Render(sampleERPDocument(), models.ERPConnectorSettings{SalesLedger: "Sales GST 5%"})
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(strings.NewReader(string(out))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"14-03-2025", "INV-2025-0001", "Sales", "Green Fields Agro", "27ABCDE1234F1Z5", "Sales GST 5%"}, records[1][:6])
+	assert.Equal(t, "3150.00", records[1][15])
+	assert.Equal(t, "Purchase", records[2][5])
+}
+
+func TestMargAdapter_Render(t *testing.T) {
+	out, err := MargAdapter{}.Render(sampleERPDocument(), models.ERPConnectorSettings{})
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(strings.NewReader(string(out))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "SALE", records[1][0])
+	assert.Equal(t, "PURCHASE ORDER", records[2][0])
+	assert.Equal(t, "GREEN FIELDS AGRO", records[1][3])
+}
+
+func TestPendingWindow(t *testing.T) {
+	now := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+
+	start, end := pendingWindow(&models.ERPConnector{}, now)
+	assert.Equal(t, time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC), end)
+
+	synced := time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)
+	start, end = pendingWindow(&models.ERPConnector{SyncedThrough: &synced}, now)
+	assert.Equal(t, time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, start, end)
+}
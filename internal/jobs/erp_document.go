@@ -0,0 +1,237 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ERPDocument is the connector-neutral export of a tenant's vouchers for a
+// period; every ERP adapter renders from this model
+type ERPDocument struct {
+	TenantID    uuid.UUID
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Vouchers    []ERPVoucher
+}
+
+// ERPVoucher is a single accounting voucher (an invoice or an order)
+type ERPVoucher struct {
+	Kind          string // "sales" or "purchase"
+	Source        string // "invoice" or "order"
+	Number        string
+	Date          time.Time
+	PartyName     string
+	PartyGSTIN    string
+	Reference     string
+	Narration     string
+	Lines         []ERPVoucherLine
+	TaxableAmount float64
+	CGST          float64
+	SGST          float64
+	IGST          float64
+	TotalAmount   float64
+}
+
+// ERPVoucherLine is one stock item on a voucher
+type ERPVoucherLine struct {
+	ItemName string
+	HSN      string
+	Unit     string
+	Quantity int
+	Rate     float64
+	Amount   float64
+}
+
+// VoucherType names the voucher the way accounting packages do
+func (v ERPVoucher) VoucherType() string {
+	switch {
+	case v.Source == "order" && v.Kind == "purchase":
+		return "Purchase Order"
+	case v.Source == "order":
+		return "Sales Order"
+	case v.Kind == "purchase":
+		return "Purchase"
+	default:
+		return "Sales"
+	}
+}
+
+// ERPDocumentBuilder assembles ERP documents from invoices and orders
+type ERPDocumentBuilder struct {
+	invoiceRepo     repositories.InvoiceRepository
+	orderRepo       repositories.OrderRepository
+	productRepo     repositories.ProductRepository
+	supplierRepo    repositories.SupplierRepository
+	distributorRepo repositories.DistributorRepository
+}
+
+func NewERPDocumentBuilder(invoiceRepo repositories.InvoiceRepository, orderRepo repositories.OrderRepository, productRepo repositories.ProductRepository,
+	supplierRepo repositories.SupplierRepository, distributorRepo repositories.DistributorRepository) *ERPDocumentBuilder {
+	return &ERPDocumentBuilder{
+		invoiceRepo:     invoiceRepo,
+		orderRepo:       orderRepo,
+		productRepo:     productRepo,
+		supplierRepo:    supplierRepo,
+		distributorRepo: distributorRepo,
+	}
+}
+
+// erpLookups caches names resolved while building one document
+type erpLookups struct {
+	products map[uuid.UUID]*models.Product
+	parties  map[uuid.UUID]string
+}
+
+// Build collects the requested document types for whole days from start to end.
+// Cancelled invoices and orders are left out.
+func (b *ERPDocumentBuilder) Build(ctx context.Context, tenantID uuid.UUID, documentTypes []string, start, end time.Time) (*ERPDocument, error) {
+	doc := &ERPDocument{TenantID: tenantID, PeriodStart: start, PeriodEnd: end}
+	lookups := &erpLookups{products: map[uuid.UUID]*models.Product{}, parties: map[uuid.UUID]string{}}
+	rangeEnd := end.AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+	for _, documentType := range documentTypes {
+		switch documentType {
+		case models.ERPDocumentInvoices:
+			invoices, err := b.invoiceRepo.GetInvoicesByTenantAndDateRange(ctx, tenantID, start, rangeEnd)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get invoices: %w", err)
+			}
+			for _, invoice := range invoices {
+				if invoice.Status == "cancelled" {
+					continue
+				}
+				voucher, err := b.invoiceVoucher(ctx, tenantID, invoice, lookups)
+				if err != nil {
+					return nil, err
+				}
+				doc.Vouchers = append(doc.Vouchers, voucher)
+			}
+		case models.ERPDocumentOrders:
+			orders, err := b.orderRepo.GetOrdersByTenantAndDateRange(ctx, tenantID, nil, start, rangeEnd)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get orders: %w", err)
+			}
+			for _, order := range orders {
+				if order.Status == "cancelled" {
+					continue
+				}
+				doc.Vouchers = append(doc.Vouchers, b.orderVoucher(ctx, tenantID, order, lookups))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported document type: %s", documentType)
+		}
+	}
+
+	sort.SliceStable(doc.Vouchers, func(i, j int) bool {
+		return doc.Vouchers[i].Date.Before(doc.Vouchers[j].Date)
+	})
+	return doc, nil
+}
+
+func (b *ERPDocumentBuilder) invoiceVoucher(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice, lookups *erpLookups) (ERPVoucher, error) {
+	order, err := b.orderRepo.GetByID(ctx, tenantID, invoice.OrderID)
+	if err != nil || order == nil {
+		return ERPVoucher{}, fmt.Errorf("failed to get order for invoice %s", invoice.InvoiceNumber)
+	}
+
+	voucher := b.orderVoucher(ctx, tenantID, order, lookups)
+	voucher.Source = "invoice"
+	voucher.Number = invoice.InvoiceNumber
+	voucher.Date = invoice.IssuedDate
+	voucher.PartyGSTIN = nullToEmpty(invoice.GSTIN)
+	voucher.Reference = fmt.Sprintf("ORD-%s", order.ID.String()[:8])
+	if invoice.HSNSAC != nil {
+		for i := range voucher.Lines {
+			voucher.Lines[i].HSN = *invoice.HSNSAC
+		}
+	}
+	if invoice.TaxableAmount != nil {
+		voucher.TaxableAmount = *invoice.TaxableAmount
+	}
+	voucher.CGST = nullPointerSum(invoice.CGST)
+	voucher.SGST = nullPointerSum(invoice.SGST)
+	voucher.IGST = nullPointerSum(invoice.IGST)
+	voucher.TotalAmount = invoice.TotalAmount
+	return voucher, nil
+}
+
+func (b *ERPDocumentBuilder) orderVoucher(ctx context.Context, tenantID uuid.UUID, order *models.Order, lookups *erpLookups) ERPVoucher {
+	amount := float64(order.Quantity) * order.UnitPrice
+	line := ERPVoucherLine{
+		ItemName: order.ProductID.String(),
+		Unit:     "Nos",
+		Quantity: order.Quantity,
+		Rate:     order.UnitPrice,
+		Amount:   amount,
+	}
+	if product := b.product(ctx, tenantID, order.ProductID, lookups); product != nil {
+		line.ItemName = product.Name
+		if product.UnitOfMeasure != nil && *product.UnitOfMeasure != "" {
+			line.Unit = *product.UnitOfMeasure
+		}
+	}
+
+	voucher := ERPVoucher{
+		Kind:          order.OrderType,
+		Source:        "order",
+		Number:        fmt.Sprintf("ORD-%s", order.ID.String()[:8]),
+		Date:          order.OrderDate,
+		PartyName:     b.partyName(ctx, tenantID, order, lookups),
+		Narration:     nullToEmpty(order.Notes),
+		Lines:         []ERPVoucherLine{line},
+		TaxableAmount: amount,
+		TotalAmount:   amount,
+	}
+	if voucher.Kind != "purchase" {
+		voucher.Kind = "sales"
+	}
+	return voucher
+}
+
+func (b *ERPDocumentBuilder) product(ctx context.Context, tenantID, productID uuid.UUID, lookups *erpLookups) *models.Product {
+	if product, ok := lookups.products[productID]; ok {
+		return product
+	}
+	product, err := b.productRepo.GetByID(ctx, tenantID, productID)
+	if err != nil {
+		product = nil
+	}
+	lookups.products[productID] = product
+	return product
+}
+
+// partyName resolves the supplier or distributor name, falling back to a generic ledger
+func (b *ERPDocumentBuilder) partyName(ctx context.Context, tenantID uuid.UUID, order *models.Order, lookups *erpLookups) string {
+	var partyID *uuid.UUID
+	fallback := "Cash"
+	if order.OrderType == "purchase" {
+		partyID = order.SupplierID
+		fallback = "Sundry Creditors"
+	} else {
+		partyID = order.DistributorID
+	}
+	if partyID == nil {
+		return fallback
+	}
+	if name, ok := lookups.parties[*partyID]; ok {
+		return name
+	}
+
+	name := fallback
+	if order.OrderType == "purchase" {
+		if supplier, err := b.supplierRepo.GetByID(ctx, tenantID, *partyID); err == nil && supplier != nil {
+			name = supplier.Name
+		}
+	} else if distributor, err := b.distributorRepo.GetByID(ctx, tenantID, *partyID); err == nil && distributor != nil {
+		name = distributor.Name
+	}
+	lookups.parties[*partyID] = name
+	return name
+}
@@ -0,0 +1,330 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	erpExportBucket         = "erp-exports"
+	erpDefaultLookbackDays  = 7
+	erpMaxSyncDays          = 366
+	erpDueConnectorsPerTick = 100
+)
+
+// ERPSyncService manages ERP connectors and runs their exports
+type ERPSyncService struct {
+	connectorRepo repositories.ERPConnectorRepository
+	builder       *ERPDocumentBuilder
+	minioService  services.MinioService
+	httpClient    *http.Client
+}
+
+func NewERPSyncService(connectorRepo repositories.ERPConnectorRepository, builder *ERPDocumentBuilder, minioService services.MinioService) *ERPSyncService {
+	return &ERPSyncService{
+		connectorRepo: connectorRepo,
+		builder:       builder,
+		minioService:  minioService,
+		httpClient:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func truncateToDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ValidateConnector normalises and checks a connector before it is saved
+func (s *ERPSyncService) ValidateConnector(connector *models.ERPConnector) error {
+	connector.Name = strings.TrimSpace(connector.Name)
+	if connector.Name == "" {
+		return fmt.Errorf("connector name is required")
+	}
+	if _, err := ERPAdapterFor(connector.ConnectorType); err != nil {
+		return err
+	}
+	if len(connector.DocumentTypes) == 0 {
+		connector.DocumentTypes = []string{models.ERPDocumentInvoices}
+	}
+	for _, documentType := range connector.DocumentTypes {
+		if documentType != models.ERPDocumentInvoices && documentType != models.ERPDocumentOrders {
+			return fmt.Errorf("document_types must be invoices or orders")
+		}
+	}
+	if connector.SyncIntervalMinutes < 0 {
+		return fmt.Errorf("sync_interval_minutes cannot be negative")
+	}
+	if connector.SyncIntervalMinutes > 0 && connector.SyncIntervalMinutes < 15 {
+		return fmt.Errorf("sync_interval_minutes must be at least 15")
+	}
+	if connector.Settings.InitialLookbackDays < 0 || connector.Settings.InitialLookbackDays > erpMaxSyncDays {
+		return fmt.Errorf("initial_lookback_days must be between 0 and %d", erpMaxSyncDays)
+	}
+	if connector.Settings.EndpointURL != "" {
+		parsed, err := url.Parse(connector.Settings.EndpointURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("endpoint_url must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+func (s *ERPSyncService) CreateConnector(ctx context.Context, connector *models.ERPConnector) error {
+	if err := s.ValidateConnector(connector); err != nil {
+		return err
+	}
+	connector.ID = uuid.New()
+	connector.IsActive = true
+	if connector.SyncIntervalMinutes > 0 {
+		now := time.Now()
+		connector.NextSyncAt = &now
+	}
+	return s.connectorRepo.CreateConnector(ctx, connector)
+}
+
+func (s *ERPSyncService) UpdateConnector(ctx context.Context, connector *models.ERPConnector) error {
+	if err := s.ValidateConnector(connector); err != nil {
+		return err
+	}
+	if connector.SyncIntervalMinutes == 0 {
+		connector.NextSyncAt = nil
+	} else if connector.NextSyncAt == nil {
+		now := time.Now()
+		connector.NextSyncAt = &now
+	}
+	return s.connectorRepo.UpdateConnector(ctx, connector)
+}
+
+func (s *ERPSyncService) GetConnector(ctx context.Context, tenantID, id uuid.UUID) (*models.ERPConnector, error) {
+	return s.connectorRepo.GetConnector(ctx, tenantID, id)
+}
+
+func (s *ERPSyncService) ListConnectors(ctx context.Context, tenantID uuid.UUID) ([]*models.ERPConnector, error) {
+	return s.connectorRepo.ListConnectors(ctx, tenantID)
+}
+
+func (s *ERPSyncService) DeleteConnector(ctx context.Context, tenantID, id uuid.UUID) error {
+	return s.connectorRepo.DeleteConnector(ctx, tenantID, id)
+}
+
+func (s *ERPSyncService) ListRuns(ctx context.Context, tenantID, connectorID uuid.UUID, limit, offset int) ([]*models.ERPSyncRun, error) {
+	return s.connectorRepo.ListRuns(ctx, tenantID, connectorID, limit, offset)
+}
+
+// pendingWindow returns the days not yet exported: from the day after the
+// watermark (or the initial lookback) through yesterday
+func pendingWindow(connector *models.ERPConnector, now time.Time) (time.Time, time.Time) {
+	end := truncateToDay(now).AddDate(0, 0, -1)
+	if connector.SyncedThrough != nil {
+		return truncateToDay(*connector.SyncedThrough).AddDate(0, 0, 1), end
+	}
+	lookback := connector.Settings.InitialLookbackDays
+	if lookback == 0 {
+		lookback = erpDefaultLookbackDays
+	}
+	return end.AddDate(0, 0, 1-lookback), end
+}
+
+// SyncNow runs a manual export. Without explicit dates it exports the pending
+// window through today; manual runs never move the scheduled watermark.
+func (s *ERPSyncService) SyncNow(ctx context.Context, connector *models.ERPConnector, start, end *time.Time) (*models.ERPSyncRun, error) {
+	periodStart, _ := pendingWindow(connector, time.Now())
+	periodEnd := truncateToDay(time.Now())
+	if start != nil {
+		periodStart = truncateToDay(*start)
+	}
+	if end != nil {
+		periodEnd = truncateToDay(*end)
+	}
+	if periodEnd.Before(periodStart) {
+		return nil, fmt.Errorf("end date must not be before start date")
+	}
+	if periodEnd.Sub(periodStart) > erpMaxSyncDays*24*time.Hour {
+		return nil, fmt.Errorf("sync period cannot exceed %d days", erpMaxSyncDays)
+	}
+	return s.run(ctx, connector, models.ERPSyncTriggerManual, periodStart, periodEnd)
+}
+
+// RunDue runs every scheduled connector whose next sync time has passed and
+// returns how many runs were attempted
+func (s *ERPSyncService) RunDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	connectors, err := s.connectorRepo.ListDueConnectors(ctx, now, erpDueConnectorsPerTick)
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for _, connector := range connectors {
+		nextSyncAt := now.Add(time.Duration(connector.SyncIntervalMinutes) * time.Minute)
+		start, end := pendingWindow(connector, now)
+		if end.Sub(start) > erpMaxSyncDays*24*time.Hour {
+			start = end.AddDate(0, 0, -erpMaxSyncDays)
+		}
+
+		var syncedThrough *time.Time
+		if !end.Before(start) {
+			attempted++
+			run, err := s.run(ctx, connector, models.ERPSyncTriggerScheduled, start, end)
+			if err != nil {
+				log.Printf("ERP sync failed for connector %s: %v", connector.ID.String(), err)
+			} else if run.Status == models.ERPSyncSucceeded {
+				syncedThrough = &end
+			}
+		}
+
+		if err := s.connectorRepo.ScheduleConnector(ctx, connector.ID, syncedThrough, &nextSyncAt); err != nil {
+			log.Printf("Failed to reschedule ERP connector %s: %v", connector.ID.String(), err)
+		}
+	}
+	return attempted, nil
+}
+
+// run exports one period; failures are recorded on the run rather than returned
+func (s *ERPSyncService) run(ctx context.Context, connector *models.ERPConnector, trigger string, start, end time.Time) (*models.ERPSyncRun, error) {
+	adapter, err := ERPAdapterFor(connector.ConnectorType)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &models.ERPSyncRun{
+		ID:          uuid.New(),
+		TenantID:    connector.TenantID,
+		ConnectorID: connector.ID,
+		Trigger:     trigger,
+		Status:      models.ERPSyncRunning,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+	if err := s.connectorRepo.CreateRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to record sync run: %w", err)
+	}
+
+	if err := s.export(ctx, connector, adapter, run); err != nil {
+		message := err.Error()
+		run.Status = models.ERPSyncFailed
+		run.ErrorMessage = &message
+	} else {
+		run.Status = models.ERPSyncSucceeded
+	}
+
+	if err := s.connectorRepo.FinishRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to record sync result: %w", err)
+	}
+	return run, nil
+}
+
+func (s *ERPSyncService) export(ctx context.Context, connector *models.ERPConnector, adapter ERPAdapter, run *models.ERPSyncRun) error {
+	doc, err := s.builder.Build(ctx, connector.TenantID, connector.DocumentTypes, run.PeriodStart, run.PeriodEnd)
+	if err != nil {
+		return err
+	}
+
+	content, err := adapter.Render(doc, connector.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to render export: %w", err)
+	}
+	run.RecordsExported = len(doc.Vouchers)
+
+	fileName := fmt.Sprintf("%s_%s_%s.%s", connector.ConnectorType, run.PeriodStart.Format("20060102"), run.PeriodEnd.Format("20060102"), adapter.FileExtension())
+	objectKey := fmt.Sprintf("%s/%s/%s/%s", connector.TenantID.String(), connector.ID.String(), run.ID.String(), fileName)
+	if err := s.minioService.EnsureBucketExists(ctx, erpExportBucket); err != nil {
+		return fmt.Errorf("failed to prepare export storage: %w", err)
+	}
+	if err := s.minioService.UploadImage(ctx, erpExportBucket, objectKey, bytes.NewReader(content), int64(len(content))); err != nil {
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+	run.FileName = &fileName
+	run.ObjectKey = &objectKey
+
+	if connector.Settings.EndpointURL == "" || len(doc.Vouchers) == 0 {
+		return nil
+	}
+	if err := s.deliver(ctx, connector.Settings.EndpointURL, adapter.ContentType(), content); err != nil {
+		return err
+	}
+	run.Delivered = true
+	return nil
+}
+
+// deliver posts the export to the connector endpoint (e.g. Tally's HTTP server)
+func (s *ERPSyncService) deliver(ctx context.Context, endpoint, contentType string, content []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to create delivery request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("delivery to ERP endpoint failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ERP endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// DownloadURL returns a short-lived link to a run's export file
+func (s *ERPSyncService) DownloadURL(ctx context.Context, tenantID, runID uuid.UUID) (string, error) {
+	run, err := s.connectorRepo.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return "", err
+	}
+	if run.ObjectKey == nil {
+		return "", fmt.Errorf("run has no export file")
+	}
+	return s.minioService.GetPresignedURL(erpExportBucket, *run.ObjectKey, 15*time.Minute)
+}
+
+func staleAfter(connector *models.ERPConnector) time.Duration {
+	threshold := 2 * time.Duration(connector.SyncIntervalMinutes) * time.Minute
+	if threshold < 48*time.Hour {
+		threshold = 48 * time.Hour
+	}
+	return threshold
+}
+
+// SyncStatus builds the sync dashboard for a tenant. Health is "running",
+// "failing" when the last run failed, "stale" when a scheduled connector has
+// not succeeded within two intervals (at least two days, since only whole
+// days are exported), "idle" before any run, else "healthy".
+func (s *ERPSyncService) SyncStatus(ctx context.Context, tenantID uuid.UUID) ([]*models.ERPSyncStatus, error) {
+	statuses, err := s.connectorRepo.GetSyncStatuses(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, status := range statuses {
+		connector := status.Connector
+		switch {
+		case status.LastRun == nil:
+			status.Health = "idle"
+		case status.LastRun.Status == models.ERPSyncRunning:
+			status.Health = "running"
+		case status.LastRun.Status == models.ERPSyncFailed:
+			status.Health = "failing"
+		case connector.IsActive && connector.SyncIntervalMinutes > 0 && status.LastSuccessAt != nil &&
+			now.Sub(*status.LastSuccessAt) > staleAfter(connector):
+			status.Health = "stale"
+		default:
+			status.Health = "healthy"
+		}
+	}
+	return statuses, nil
+}
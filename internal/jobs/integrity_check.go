@@ -0,0 +1,217 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// invoiceTotalTolerance absorbs rounding each amount to paise on its own
+const invoiceTotalTolerance = 0.05
+
+// IntegrityCheckService scans a tenant for data that the database constraints
+// do not rule out but should never happen: orders pointing at another
+// tenant's or a deleted product, invoice totals that do not add up, negative
+// stock and image objects out of step with the product images that use them
+type IntegrityCheckService struct {
+	repo         repositories.IntegrityRepository
+	minioService services.MinioService
+	cacheService caching.CacheService
+}
+
+func NewIntegrityCheckService(repo repositories.IntegrityRepository, minioService services.MinioService, cacheService caching.CacheService) *IntegrityCheckService {
+	return &IntegrityCheckService{repo: repo, minioService: minioService, cacheService: cacheService}
+}
+
+// Check scans the tenant and reports what it found. With autoFix the safe
+// categories are fixed as they are found: orphaned image objects are deleted
+// and product images whose object is gone are removed. Nothing else is changed
+func (s *IntegrityCheckService) Check(ctx context.Context, tenantID uuid.UUID, autoFix bool) (*models.IntegrityReport, error) {
+	report := &models.IntegrityReport{
+		TenantID:  tenantID,
+		AutoFix:   autoFix,
+		StartedAt: time.Now(),
+		Counts:    map[string]int{},
+		Issues:    []*models.IntegrityIssue{},
+	}
+
+	orders, err := s.repo.OrdersWithMissingProducts(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check order products: %w", err)
+	}
+	for _, order := range orders {
+		report.Issues = append(report.Issues, missingProductIssue(order))
+	}
+
+	invoices, err := s.repo.InvoiceAmounts(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check invoice totals: %w", err)
+	}
+	for _, invoice := range invoices {
+		if issue := invoiceTotalIssue(invoice); issue != nil {
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	stock, err := s.repo.NegativeStock(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check stock levels: %w", err)
+	}
+	for _, row := range stock {
+		id := row.InventoryID
+		report.Issues = append(report.Issues, &models.IntegrityIssue{
+			Category:   models.IntegrityNegativeInventory,
+			EntityType: "inventory",
+			EntityID:   &id,
+			Detail: fmt.Sprintf("product %s has %d units in warehouse %s; count the stock and adjust it",
+				row.ProductID, row.Quantity, row.WarehouseID),
+		})
+	}
+
+	imageIssues, err := s.checkImages(ctx, tenantID, autoFix)
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = append(report.Issues, imageIssues...)
+
+	for _, issue := range report.Issues {
+		report.Counts[issue.Category]++
+		if issue.Fixed {
+			report.Fixed++
+		}
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// checkImages compares the tenant's objects in the image bucket with its
+// product images, fixing the differences when autoFix is set
+func (s *IntegrityCheckService) checkImages(ctx context.Context, tenantID uuid.UUID, autoFix bool) ([]*models.IntegrityIssue, error) {
+	images, err := s.repo.ProductImages(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check product images: %w", err)
+	}
+	if s.minioService == nil {
+		return nil, nil
+	}
+	// Product images are uploaded under a prefix per tenant
+	keys, err := s.minioService.ListObjects(ctx, catalogImageBucket, tenantID.String()+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list image objects: %w", err)
+	}
+
+	orphaned, missing := diffImageObjects(keys, images)
+	// When no image at all is in storage the bucket is more likely pointed at
+	// the wrong place than emptied, so the images are reported but kept
+	fixMissing := autoFix && len(missing) < len(images)
+	if autoFix && !fixMissing && len(missing) > 0 {
+		log.Printf("None of tenant %s's %d product images is in storage; not removing them", tenantID, len(images))
+	}
+	var issues []*models.IntegrityIssue
+	changed := false
+	for _, key := range orphaned {
+		issue := &models.IntegrityIssue{
+			Category:   models.IntegrityOrphanedImage,
+			EntityType: "image_object",
+			Reference:  key,
+			Detail:     "no product image refers to this object",
+			Fixable:    true,
+		}
+		if autoFix {
+			if err := s.minioService.DeleteImage(ctx, catalogImageBucket, key); err != nil {
+				log.Printf("Failed to delete orphaned image object %s: %v", key, err)
+			} else {
+				issue.Fixed = true
+			}
+		}
+		issues = append(issues, issue)
+	}
+	for _, image := range missing {
+		id := image.ImageID
+		issue := &models.IntegrityIssue{
+			Category:   models.IntegrityMissingImageObject,
+			EntityType: "product_image",
+			EntityID:   &id,
+			Reference:  image.ObjectKey,
+			Detail:     fmt.Sprintf("image of product %s points at an object that is not in storage", image.ProductID),
+			Fixable:    true,
+		}
+		if fixMissing {
+			if deleted, err := s.repo.DeleteProductImage(ctx, tenantID, image.ImageID); err != nil {
+				log.Printf("Failed to remove product image %s without an object: %v", image.ImageID, err)
+			} else if deleted {
+				issue.Fixed = true
+				changed = true
+			}
+		}
+		issues = append(issues, issue)
+	}
+	if changed {
+		caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventProductUpdated)
+	}
+	return issues, nil
+}
+
+// diffImageObjects splits the difference between the stored object keys and
+// the product images into objects no image uses and images whose object is
+// missing. Orphaned keys are sorted
+func diffImageObjects(keys []string, images []*models.IntegrityImageRef) (orphaned []string, missing []*models.IntegrityImageRef) {
+	stored := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		stored[key] = true
+	}
+	referenced := make(map[string]bool, len(images))
+	for _, image := range images {
+		referenced[image.ObjectKey] = true
+		if !stored[image.ObjectKey] {
+			missing = append(missing, image)
+		}
+	}
+	for _, key := range keys {
+		if !referenced[key] {
+			orphaned = append(orphaned, key)
+		}
+	}
+	sort.Strings(orphaned)
+	return orphaned, missing
+}
+
+func missingProductIssue(order *models.IntegrityOrderRef) *models.IntegrityIssue {
+	id := order.OrderID
+	return &models.IntegrityIssue{
+		Category:   models.IntegrityOrderMissingProduct,
+		EntityType: "order",
+		EntityID:   &id,
+		Detail: fmt.Sprintf("%s order (%s) references product %s, which is not in this tenant's catalogue",
+			order.OrderType, order.Status, order.ProductID),
+	}
+}
+
+// invoiceTotalIssue reports an invoice whose taxable amount, GST components
+// and TCS do not add up to its total, or nil when they do
+func invoiceTotalIssue(invoice *models.IntegrityInvoiceAmounts) *models.IntegrityIssue {
+	expected := invoice.TaxableAmount + invoice.CGST + invoice.SGST + invoice.IGST + invoice.TCS
+	if math.Abs(expected-invoice.TotalAmount) <= invoiceTotalTolerance {
+		return nil
+	}
+	id := invoice.InvoiceID
+	return &models.IntegrityIssue{
+		Category:   models.IntegrityInvoiceGSTMismatch,
+		EntityType: "invoice",
+		EntityID:   &id,
+		Reference:  invoice.InvoiceNumber,
+		Detail: fmt.Sprintf("taxable %.2f + CGST %.2f + SGST %.2f + IGST %.2f + TCS %.2f = %.2f, but the total is %.2f",
+			invoice.TaxableAmount, invoice.CGST, invoice.SGST, invoice.IGST, invoice.TCS,
+			roundStatementAmount(expected), invoice.TotalAmount),
+	}
+}
@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"testing"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoiceTotalIssue(t *testing.T) {
+	balanced := &models.IntegrityInvoiceAmounts{InvoiceID: uuid.New(), TaxableAmount: 1000, CGST: 90, SGST: 90, TotalAmount: 1180}
+	assert.Nil(t, invoiceTotalIssue(balanced))
+
+	withTCS := &models.IntegrityInvoiceAmounts{InvoiceID: uuid.New(), TaxableAmount: 1000, IGST: 180, TCS: 1.18, TotalAmount: 1181.18}
+	assert.Nil(t, invoiceTotalIssue(withTCS))
+
+	rounded := &models.IntegrityInvoiceAmounts{InvoiceID: uuid.New(), TaxableAmount: 333.33, CGST: 30, SGST: 30, TotalAmount: 393.36}
+	assert.Nil(t, invoiceTotalIssue(rounded), "paise of rounding are tolerated")
+
+	broken := &models.IntegrityInvoiceAmounts{InvoiceID: uuid.New(), InvoiceNumber: "INV-9", TaxableAmount: 1000, CGST: 90, SGST: 90, TotalAmount: 1000}
+	issue := invoiceTotalIssue(broken)
+	require.NotNil(t, issue)
+	assert.Equal(t, models.IntegrityInvoiceGSTMismatch, issue.Category)
+	assert.Equal(t, "INV-9", issue.Reference)
+	assert.Equal(t, broken.InvoiceID, *issue.EntityID)
+	assert.False(t, issue.Fixable)
+	assert.Contains(t, issue.Detail, "= 1180.00, but the total is 1000.00")
+}
+
+func TestDiffImageObjects(t *testing.T) {
+	tenant := uuid.New().String()
+	kept := &models.IntegrityImageRef{ImageID: uuid.New(), ObjectKey: tenant + "/p1/front.jpg"}
+	gone := &models.IntegrityImageRef{ImageID: uuid.New(), ObjectKey: tenant + "/p1/back.jpg"}
+	keys := []string{tenant + "/p2/old.jpg", kept.ObjectKey, tenant + "/p1/extra.jpg"}
+
+	orphaned, missing := diffImageObjects(keys, []*models.IntegrityImageRef{kept, gone})
+	assert.Equal(t, []string{tenant + "/p1/extra.jpg", tenant + "/p2/old.jpg"}, orphaned)
+	require.Len(t, missing, 1)
+	assert.Equal(t, gone.ImageID, missing[0].ImageID)
+
+	orphaned, missing = diffImageObjects(nil, nil)
+	assert.Empty(t, orphaned)
+	assert.Empty(t, missing)
+}
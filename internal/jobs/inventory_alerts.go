@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 
+	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
 	"github.com/google/uuid"
@@ -12,6 +13,7 @@ import (
 type InventoryAlertService struct {
 	inventoryRepo repositories.InventoryRepository
 	productRepo   repositories.ProductRepository
+	minimumRepo   repositories.StockMinimumRepository
 }
 
 type InventoryAlert struct {
@@ -23,18 +25,33 @@ type InventoryAlert struct {
 	Threshold    int
 }
 
-func NewInventoryAlertService(inventoryRepo repositories.InventoryRepository, productRepo repositories.ProductRepository) *InventoryAlertService {
+// NewInventoryAlertService creates the service; minimumRepo may be nil, in
+// which case every product is checked against the threshold
+func NewInventoryAlertService(inventoryRepo repositories.InventoryRepository, productRepo repositories.ProductRepository, minimumRepo repositories.StockMinimumRepository) *InventoryAlertService {
 	return &InventoryAlertService{
 		inventoryRepo: inventoryRepo,
 		productRepo:   productRepo,
+		minimumRepo:   minimumRepo,
 	}
 }
 
+// CheckLowStock lists stock below its product's configured minimum, or at or
+// below threshold for products without one
 func (a *InventoryAlertService) CheckLowStock(ctx context.Context, tenantID uuid.UUID, threshold int) ([]InventoryAlert, error) {
 	if threshold <= 0 {
 		threshold = 10 // Default threshold
 	}
 
+	var minimums *models.StockMinimums
+	if a.minimumRepo != nil {
+		configured, err := a.minimumRepo.ListByTenant(ctx, tenantID)
+		if err != nil {
+			log.Printf("Failed to load stock minimums for tenant %s: %v", tenantID.String(), err)
+			return nil, err
+		}
+		minimums = models.NewStockMinimums(configured)
+	}
+
 	inventories, err := a.inventoryRepo.List(ctx, tenantID, 1000, 0) // Get all, in practice should paginate
 	if err != nil {
 		log.Printf("Failed to list inventories for tenant %s: %v", tenantID.String(), err)
@@ -44,7 +61,8 @@ func (a *InventoryAlertService) CheckLowStock(ctx context.Context, tenantID uuid
 	var alerts []InventoryAlert
 
 	for _, inv := range inventories {
-		if inv.Quantity <= threshold {
+		limit, low := lowStockLimit(minimums, inv, threshold)
+		if low {
 			// Get product name (this could be cached for performance)
 			product, err := a.productRepo.GetByID(ctx, tenantID, inv.ProductID)
 			if err != nil {
@@ -58,7 +76,7 @@ func (a *InventoryAlertService) CheckLowStock(ctx context.Context, tenantID uuid
 				ProductID:    inv.ProductID,
 				ProductName:  product.Name,
 				CurrentStock: inv.Quantity,
-				Threshold:    threshold,
+				Threshold:    limit,
 			}
 			alerts = append(alerts, alert)
 		}
@@ -67,6 +85,15 @@ func (a *InventoryAlertService) CheckLowStock(ctx context.Context, tenantID uuid
 	return alerts, nil
 }
 
+// lowStockLimit returns the stock level the record is measured against and
+// whether it is low: below a configured minimum, or at or below the threshold
+func lowStockLimit(minimums *models.StockMinimums, inv *models.Inventory, threshold int) (int, bool) {
+	if minimum, ok := minimums.For(inv.ProductID, inv.WarehouseID); ok {
+		return minimum, inv.Quantity < minimum
+	}
+	return threshold, inv.Quantity <= threshold
+}
+
 func (a *InventoryAlertService) LogLowStockAlerts(ctx context.Context, alerts []InventoryAlert) {
 	if len(alerts) == 0 {
 		log.Println("No low stock alerts to log")
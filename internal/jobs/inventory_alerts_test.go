@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 
 	"github.com/google/uuid"
@@ -32,6 +33,11 @@ func (m *MockInventoryRepository) GetByID(ctx context.Context, tenantID, id uuid
 	return args.Get(0).(*models.Inventory), args.Error(1)
 }
 
+func (m *MockInventoryRepository) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Inventory, error) {
+	args := m.Called(ctx, tenantID, ids)
+	return args.Get(0).([]*models.Inventory), args.Error(1)
+}
+
 func (m *MockInventoryRepository) Update(ctx context.Context, inventory *models.Inventory) error {
 	args := m.Called(ctx, inventory)
 	return args.Error(0)
@@ -42,11 +48,24 @@ func (m *MockInventoryRepository) Delete(ctx context.Context, tenantID, id uuid.
 	return args.Error(0)
 }
 
+func (m *MockInventoryRepository) ListByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.Inventory, error) {
+	args := m.Called(ctx, tenantID, productID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Inventory), args.Error(1)
+}
+
 func (m *MockInventoryRepository) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Inventory, error) {
 	args := m.Called(ctx, tenantID, limit, offset)
 	return args.Get(0).([]*models.Inventory), args.Error(1)
 }
 
+func (m *MockInventoryRepository) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Inventory, error) {
+	args := m.Called(ctx, tenantID, page)
+	return args.Get(0).([]*models.Inventory), args.Error(1)
+}
+
 func (m *MockInventoryRepository) GetByWarehouseAndProduct(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (*models.Inventory, error) {
 	args := m.Called(ctx, tenantID, warehouseID, productID)
 	if args.Get(0) == nil {
@@ -78,6 +97,11 @@ func (m *MockProductRepository) GetByID(ctx context.Context, tenantID, id uuid.U
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Product, error) {
+	args := m.Called(ctx, tenantID, ids)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
 func (m *MockProductRepository) Update(ctx context.Context, product *models.Product) error {
 	args := m.Called(ctx, product)
 	return args.Error(0)
@@ -93,6 +117,11 @@ func (m *MockProductRepository) List(ctx context.Context, tenantID uuid.UUID, li
 	return args.Get(0).([]*models.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Product, error) {
+	args := m.Called(ctx, tenantID, page)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
 func (m *MockProductRepository) GetByBarcode(ctx context.Context, tenantID uuid.UUID, barcode string) (*models.Product, error) {
 	args := m.Called(ctx, tenantID, barcode)
 	if args.Get(0) == nil {
@@ -135,7 +164,7 @@ type InventoryAlertServiceTestSuite struct {
 func (suite *InventoryAlertServiceTestSuite) SetupTest() {
 	suite.mockInventoryRepo = &MockInventoryRepository{}
 	suite.mockProductRepo = &MockProductRepository{}
-	suite.service = NewInventoryAlertService(suite.mockInventoryRepo, suite.mockProductRepo)
+	suite.service = NewInventoryAlertService(suite.mockInventoryRepo, suite.mockProductRepo, nil)
 	suite.tenantID = uuid.New()
 	suite.warehouseID = uuid.New()
 }
@@ -535,7 +564,7 @@ func TestInventoryAlertComprehensiveScenario(t *testing.T) {
 
 	mockInventoryRepo := &MockInventoryRepository{}
 	mockProductRepo := &MockProductRepository{}
-	service := NewInventoryAlertService(mockInventoryRepo, mockProductRepo)
+	service := NewInventoryAlertService(mockInventoryRepo, mockProductRepo, nil)
 
 	tenantID := uuid.New()
 	threshold := 15
@@ -579,4 +608,23 @@ func TestInventoryAlertComprehensiveScenario(t *testing.T) {
 		assert.True(t, alert.CurrentStock < threshold, "Alert stock should be below threshold")
 		assert.Equal(t, threshold, alert.Threshold)
 	}
-}
\ No newline at end of file
+}
+func TestLowStockLimitUsesConfiguredMinimums(t *testing.T) {
+	productID, warehouseID, otherWarehouse := uuid.New(), uuid.New(), uuid.New()
+	minimums := models.NewStockMinimums([]*models.StockMinimum{
+		{ProductID: productID, MinQuantity: 50},
+		{ProductID: productID, WarehouseID: &warehouseID, MinQuantity: 5},
+	})
+
+	limit, low := lowStockLimit(minimums, &models.Inventory{ProductID: productID, WarehouseID: warehouseID, Quantity: 8}, 10)
+	assert.Equal(t, 5, limit)
+	assert.False(t, low, "the warehouse minimum overrides the threshold")
+
+	limit, low = lowStockLimit(minimums, &models.Inventory{ProductID: productID, WarehouseID: otherWarehouse, Quantity: 30}, 10)
+	assert.Equal(t, 50, limit)
+	assert.True(t, low, "other warehouses use the product-wide minimum")
+
+	limit, low = lowStockLimit(nil, &models.Inventory{ProductID: uuid.New(), WarehouseID: warehouseID, Quantity: 10}, 10)
+	assert.Equal(t, 10, limit)
+	assert.True(t, low, "without a minimum stock at the threshold is low")
+}
@@ -0,0 +1,308 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	invoiceArchiveBucket   = "invoices"
+	invoiceArchiveInterval = 30 * time.Second
+	invoiceArchiveBatch    = 100
+	invoiceArchiveTimeout  = 30 * time.Second
+	// invoiceArchiveMaxBackoff caps how long an invoice that failed to render
+	// waits before the next attempt
+	invoiceArchiveMaxBackoff = 6 * time.Hour
+)
+
+// InvoiceArchiveService renders the PDF of every issued invoice once and
+// archives it. Documents are stored under a key carrying their SHA-256 and
+// never overwritten or rendered again; correcting an invoice means amending
+// it, which issues a new invoice with its own document. A background sweep
+// archives new invoices shortly after they are created, whichever flow
+// created them
+type InvoiceArchiveService struct {
+	repo            repositories.InvoiceDocumentRepository
+	invoiceRepo     repositories.InvoiceRepository
+	orderRepo       repositories.OrderRepository
+	productRepo     repositories.ProductRepository
+	exportSvc       services.ExportInvoiceService
+	consolidatedSvc services.ConsolidatedInvoiceService
+	upiSvc          services.UPIPaymentService
+	minioService    services.MinioService
+
+	mu       sync.Mutex
+	failures map[uuid.UUID]archiveFailure
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+type archiveFailure struct {
+	attempts  int
+	nextTryAt time.Time
+}
+
+func NewInvoiceArchiveService(
+	repo repositories.InvoiceDocumentRepository,
+	invoiceRepo repositories.InvoiceRepository,
+	orderRepo repositories.OrderRepository,
+	productRepo repositories.ProductRepository,
+	exportSvc services.ExportInvoiceService,
+	consolidatedSvc services.ConsolidatedInvoiceService,
+	upiSvc services.UPIPaymentService,
+	minioService services.MinioService,
+) *InvoiceArchiveService {
+	return &InvoiceArchiveService{
+		repo:            repo,
+		invoiceRepo:     invoiceRepo,
+		orderRepo:       orderRepo,
+		productRepo:     productRepo,
+		exportSvc:       exportSvc,
+		consolidatedSvc: consolidatedSvc,
+		upiSvc:          upiSvc,
+		minioService:    minioService,
+		failures:        make(map[uuid.UUID]archiveFailure),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Get returns the invoice's archived document, or nil when it has none yet
+func (s *InvoiceArchiveService) Get(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceDocument, error) {
+	return s.repo.Get(ctx, tenantID, invoiceID)
+}
+
+// Archive returns the invoice's document, rendering and storing it first
+// when the invoice has none yet. When two archive the same invoice at once,
+// the first document recorded stands and the other is removed
+func (s *InvoiceArchiveService) Archive(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceDocument, error) {
+	doc, err := s.repo.Get(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice document: %w", err)
+	}
+	if doc != nil {
+		return doc, nil
+	}
+
+	invoice, err := s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && invoice == nil) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+	template, content, err := s.render(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+	doc = &models.InvoiceDocument{
+		InvoiceID:     invoice.ID,
+		TenantID:      tenantID,
+		InvoiceNumber: invoice.InvoiceNumber,
+		Template:      template,
+		SHA256:        hex.EncodeToString(sum[:]),
+		SizeBytes:     int64(len(content)),
+	}
+	doc.ObjectKey = invoiceDocumentKey(doc)
+	if err := s.minioService.EnsureBucketExists(ctx, invoiceArchiveBucket); err != nil {
+		return nil, fmt.Errorf("failed to prepare invoice storage: %w", err)
+	}
+	if err := s.minioService.UploadObject(ctx, invoiceArchiveBucket, doc.ObjectKey, bytes.NewReader(content), doc.SizeBytes, "application/pdf"); err != nil {
+		return nil, fmt.Errorf("failed to store invoice document: %w", err)
+	}
+
+	created, err := s.repo.Create(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record invoice document: %w", err)
+	}
+	if !created {
+		existing, err := s.repo.Get(ctx, tenantID, invoiceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load invoice document: %w", err)
+		}
+		if existing != nil && existing.ObjectKey != doc.ObjectKey {
+			if err := s.minioService.DeleteImage(ctx, invoiceArchiveBucket, doc.ObjectKey); err != nil {
+				log.Printf("Failed to remove duplicate document %s of invoice %s: %v", doc.ObjectKey, invoiceID, err)
+			}
+		}
+		return existing, nil
+	}
+	return doc, nil
+}
+
+// DownloadURL returns a presigned link to the archived document
+func (s *InvoiceArchiveService) DownloadURL(doc *models.InvoiceDocument, expiry time.Duration) (string, error) {
+	return s.minioService.GetPresignedURL(invoiceArchiveBucket, doc.ObjectKey, expiry)
+}
+
+// render lays out the invoice with the template its kind calls for.
+// Domestic invoices awaiting payment carry a UPI QR code when the tenant has
+// a VPA; export invoices are settled in foreign currency
+func (s *InvoiceArchiveService) render(ctx context.Context, invoice *models.Invoice) (string, []byte, error) {
+	order, err := s.orderRepo.GetByID(ctx, invoice.TenantID, invoice.OrderID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	if order == nil {
+		return "", nil, fmt.Errorf("order %s of invoice %s not found", invoice.OrderID, invoice.ID)
+	}
+	export, err := s.exportSvc.GetExportDetails(ctx, invoice.TenantID, invoice.ID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load export details: %w", err)
+	}
+	consolidated, err := s.consolidatedSvc.GetConsolidatedInvoice(ctx, invoice.TenantID, invoice.ID)
+	if err != nil && !errors.Is(err, services.ErrConsolidatedInvoiceNotFound) {
+		return "", nil, fmt.Errorf("failed to load consolidated invoice lines: %w", err)
+	}
+
+	var upiQR []byte
+	if export == nil {
+		upiQR, err = s.upiSvc.InvoiceQR(ctx, invoice.TenantID, invoice)
+		if err != nil && !errors.Is(err, services.ErrUPINotConfigured) && !errors.Is(err, services.ErrInvoiceNotPayable) {
+			return "", nil, fmt.Errorf("failed to build UPI QR code: %w", err)
+		}
+	}
+
+	if consolidated != nil {
+		content, err := renderConsolidatedInvoicePDF(consolidated, upiQR)
+		return models.InvoiceTemplateConsolidated, content, err
+	}
+	product, err := s.productRepo.GetByID(ctx, invoice.TenantID, order.ProductID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get product details: %w", err)
+	}
+	if export != nil {
+		content, err := renderExportInvoicePDF(invoice, export, order, product)
+		return models.InvoiceTemplateExport, content, err
+	}
+	content, err := renderInvoicePDF(invoice, order, product, upiQR)
+	return models.InvoiceTemplateStandard, content, err
+}
+
+// invoiceDocumentKey starts with the tenant so the document is stored in the
+// tenant's residency region, and ends with the content hash so a document
+// is never written over another
+func invoiceDocumentKey(doc *models.InvoiceDocument) string {
+	return fmt.Sprintf("%s-%s-%s.pdf", doc.TenantID, doc.InvoiceID, doc.SHA256[:16])
+}
+
+// Start archives pending invoices every invoiceArchiveInterval until Stop is
+// called
+func (s *InvoiceArchiveService) Start() {
+	go s.run()
+}
+
+// Stop waits for the sweep in progress to finish
+func (s *InvoiceArchiveService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+func (s *InvoiceArchiveService) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(invoiceArchiveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if archived, err := s.ArchivePending(context.Background()); err != nil {
+				log.Printf("Invoice archive sweep failed after %d documents: %v", archived, err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// ArchivePending archives every invoice without a document and returns how
+// many were archived. Invoices that fail are retried with a growing backoff
+// so one broken invoice does not hold up the rest
+func (s *InvoiceArchiveService) ArchivePending(ctx context.Context) (int, error) {
+	archived := 0
+	var afterCreated time.Time
+	afterID := uuid.Nil
+	for {
+		pending, err := s.repo.ListPending(ctx, afterCreated, afterID, invoiceArchiveBatch)
+		if err != nil {
+			return archived, fmt.Errorf("failed to list invoices to archive: %w", err)
+		}
+		for _, p := range pending {
+			select {
+			case <-s.stop:
+				return archived, nil
+			default:
+			}
+			afterCreated, afterID = p.CreatedAt, p.InvoiceID
+			if !s.due(p.InvoiceID, time.Now()) {
+				continue
+			}
+
+			archiveCtx, cancel := context.WithTimeout(ctx, invoiceArchiveTimeout)
+			_, err := s.Archive(archiveCtx, p.TenantID, p.InvoiceID)
+			cancel()
+			if err != nil {
+				attempts := s.recordFailure(p.InvoiceID, time.Now())
+				log.Printf("Failed to archive invoice %s of tenant %s (attempt %d): %v", p.InvoiceID, p.TenantID, attempts, err)
+				continue
+			}
+			s.clearFailure(p.InvoiceID)
+			archived++
+		}
+		if len(pending) < invoiceArchiveBatch {
+			return archived, nil
+		}
+	}
+}
+
+func (s *InvoiceArchiveService) due(invoiceID uuid.UUID, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failure, ok := s.failures[invoiceID]
+	return !ok || !now.Before(failure.nextTryAt)
+}
+
+// recordFailure backs the invoice off for twice as long after each failure
+func (s *InvoiceArchiveService) recordFailure(invoiceID uuid.UUID, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failure := s.failures[invoiceID]
+	failure.attempts++
+	failure.nextTryAt = now.Add(archiveBackoff(failure.attempts))
+	s.failures[invoiceID] = failure
+	return failure.attempts
+}
+
+func (s *InvoiceArchiveService) clearFailure(invoiceID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, invoiceID)
+}
+
+// archiveBackoff is the wait after the given number of failed attempts
+func archiveBackoff(attempts int) time.Duration {
+	backoff := invoiceArchiveInterval
+	for i := 1; i < attempts && backoff < invoiceArchiveMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, invoiceArchiveMaxBackoff)
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/pkg/qrcode"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func archiveTestInvoice() (*models.Invoice, *models.Order, *models.Product) {
+	created := time.Date(2025, 9, 1, 10, 30, 0, 0, time.UTC)
+	cgst, sgst := 45.0, 45.0
+	invoice := &models.Invoice{
+		ID:            uuid.New(),
+		TenantID:      uuid.New(),
+		OrderID:       uuid.New(),
+		InvoiceNumber: "INV-2025-09-0001",
+		CGST:          &cgst,
+		SGST:          &sgst,
+		TotalAmount:   1090,
+		Status:        "unpaid",
+		IssuedDate:    created,
+		DueDate:       created.AddDate(0, 0, 30),
+		CreatedAt:     created,
+	}
+	order := &models.Order{ID: invoice.OrderID, ProductID: uuid.New(), Quantity: 10, UnitPrice: 100}
+	product := &models.Product{ID: order.ProductID, Name: "Urea 45kg"}
+	return invoice, order, product
+}
+
+func TestRenderInvoicePDFIsReproducible(t *testing.T) {
+	invoice, order, product := archiveTestInvoice()
+	code, err := qrcode.Encode([]byte("upi://pay?pa=shop@okaxis&am=1090.00&tr=INV-2025-09-0001"), qrcode.Medium)
+	require.NoError(t, err)
+	upiQR, err := code.PNG(8)
+	require.NoError(t, err)
+
+	first, err := renderInvoicePDF(invoice, order, product, upiQR)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(first, []byte("%PDF")))
+
+	second, err := renderInvoicePDF(invoice, order, product, upiQR)
+	require.NoError(t, err)
+	assert.Equal(t, sha256.Sum256(first), sha256.Sum256(second), "rendering an invoice again gives the same document")
+
+	withoutQR, err := renderInvoicePDF(invoice, order, product, nil)
+	require.NoError(t, err)
+	assert.Less(t, len(withoutQR), len(first))
+}
+
+func TestRenderExportAndConsolidatedInvoicePDF(t *testing.T) {
+	invoice, order, product := archiveTestInvoice()
+	export := &models.InvoiceExportDetails{
+		ExportType:    models.ExportTypeWithoutPayment,
+		Currency:      "USD",
+		ExchangeRate:  83.5,
+		ForeignAmount: 13.05,
+	}
+	content, err := renderExportInvoicePDF(invoice, export, order, product)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF")))
+
+	consolidated := &models.ConsolidatedInvoice{
+		Invoice:    invoice,
+		PeriodFrom: invoice.IssuedDate.AddDate(0, -1, 0),
+		PeriodTo:   invoice.IssuedDate,
+		Lines: []*models.ConsolidatedInvoiceLine{
+			{OrderID: uuid.New(), OrderDate: invoice.IssuedDate, ProductName: product.Name, Quantity: 10, UnitPrice: 100, TaxableAmount: 1000, TotalAmount: 1090},
+		},
+	}
+	content, err = renderConsolidatedInvoicePDF(consolidated, nil)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF")))
+}
+
+func TestInvoiceDocumentKey(t *testing.T) {
+	doc := &models.InvoiceDocument{
+		TenantID:  uuid.New(),
+		InvoiceID: uuid.New(),
+		SHA256:    strings.Repeat("ab", 32),
+	}
+	key := invoiceDocumentKey(doc)
+	assert.True(t, strings.HasPrefix(key, doc.TenantID.String()+"-"+doc.InvoiceID.String()+"-"))
+	assert.True(t, strings.HasSuffix(key, "-abababababababab.pdf"))
+}
+
+func TestArchiveBackoff(t *testing.T) {
+	assert.Equal(t, invoiceArchiveInterval, archiveBackoff(1))
+	assert.Equal(t, 2*invoiceArchiveInterval, archiveBackoff(2))
+	assert.Equal(t, 8*invoiceArchiveInterval, archiveBackoff(4))
+	assert.Equal(t, invoiceArchiveMaxBackoff, archiveBackoff(50))
+
+	s := NewInvoiceArchiveService(nil, nil, nil, nil, nil, nil, nil, nil)
+	invoiceID := uuid.New()
+	now := time.Now()
+	assert.True(t, s.due(invoiceID, now))
+	assert.Equal(t, 1, s.recordFailure(invoiceID, now))
+	assert.False(t, s.due(invoiceID, now.Add(time.Second)))
+	assert.True(t, s.due(invoiceID, now.Add(invoiceArchiveInterval)))
+	s.clearFailure(invoiceID)
+	assert.True(t, s.due(invoiceID, now))
+}
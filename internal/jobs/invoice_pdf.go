@@ -0,0 +1,402 @@
+package jobs
+
+import (
+	"bytes"
+	"fmt"
+
+	"agromart2/internal/models"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// newInvoicePDF starts an invoice document. The PDF is dated with the
+// invoice's creation, so rendering the same invoice again gives the same bytes
+func newInvoicePDF(invoice *models.Invoice) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(invoice.CreatedAt)
+	pdf.SetModificationDate(invoice.CreatedAt)
+	pdf.SetCatalogSort(true)
+	pdf.AddPage()
+	return pdf
+}
+
+// renderInvoicePDF creates a professional PDF invoice
+func renderInvoicePDF(invoice *models.Invoice, order *models.Order, product *models.Product, upiQR []byte) ([]byte, error) {
+	// Create new PDF
+	pdf := newInvoicePDF(invoice)
+
+	// Set margins
+	marginX := 20.0
+	marginY := 20.0
+	pdf.SetMargins(marginX, marginY, marginX)
+	pdf.SetAutoPageBreak(true, marginY)
+
+	// Set fonts
+	pdf.SetFont("Arial", "B", 16)
+	pdf.SetTextColor(33, 37, 41) // Dark gray
+
+	// Company header
+	pdf.SetXY(marginX, marginY)
+	pdf.Cell(0, 10, "AGROMART INVOICE")
+	pdf.Ln(15)
+
+	// Invoice details
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(0, 8, fmt.Sprintf("Invoice Number: %s", invoice.ID.String()))
+	pdf.Ln(8)
+	pdf.Cell(0, 8, fmt.Sprintf("Invoice Date: %s", invoice.IssuedDate.Format("02-Jan-2006")))
+	pdf.Ln(8)
+	pdf.Cell(0, 8, fmt.Sprintf("Order ID: %s", order.ID.String()))
+	pdf.Ln(8)
+
+	// GSTIN if provided
+	if invoice.GSTIN != nil && *invoice.GSTIN != "" {
+		pdf.Cell(0, 8, fmt.Sprintf("GSTIN: %s", *invoice.GSTIN))
+		pdf.Ln(8)
+	}
+
+	pdf.Ln(5)
+
+	// Billing Information section
+	pdf.SetFont("Arial", "B", 11)
+	pdf.Cell(0, 8, "BILL TO:")
+	pdf.Ln(6)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.Cell(0, 6, "Agromart Customer")
+	pdf.Ln(6)
+	pdf.Cell(0, 6, "Address: To be configured")
+	pdf.Ln(6)
+	pdf.Cell(0, 6, "Contact: support@agromart.com")
+	pdf.Ln(10)
+
+	// Items table header
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetFillColor(240, 240, 240) // Light gray background
+
+	// Table headers
+	headers := []string{"Description", "Qty", "Rate", "Amount"}
+	colWidths := []float64{80, 20, 30, 40}
+
+	for i, header := range headers {
+		pdf.CellFormat(colWidths[i], 8, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(8)
+
+	// Item row
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetFillColor(255, 255, 255) // White background
+
+	description := product.Name
+	if product.Description != nil && *product.Description != "" {
+		description += "\n" + *product.Description
+	}
+
+	pdf.CellFormat(colWidths[0], 8, description, "1", 0, "L", false, 0, "")
+	pdf.CellFormat(colWidths[1], 8, fmt.Sprintf("%d", order.Quantity), "1", 0, "C", false, 0, "")
+	pdf.CellFormat(colWidths[2], 8, fmt.Sprintf("%.2f", order.UnitPrice), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(colWidths[3], 8, fmt.Sprintf("%.2f", float64(order.Quantity)*order.UnitPrice), "1", 0, "R", false, 0, "")
+	pdf.Ln(8)
+
+	// Empty rows for future multiple items
+	for i := 0; i < 3; i++ {
+		for j, width := range colWidths {
+			border := "1"
+			if j == len(colWidths)-1 {
+				border = "1" // Last column
+			}
+			pdf.CellFormat(width, 8, "", border, 0, "C", false, 0, "")
+		}
+		pdf.Ln(8)
+	}
+
+	pdf.Ln(5)
+
+	// GST and totals section
+	pdf.SetFont("Arial", "B", 10)
+
+	// Subtotal
+	subtotal := float64(order.Quantity) * order.UnitPrice
+	pdf.CellFormat(130, 6, "Subtotal:", "", 0, "R", false, 0, "")
+	pdf.CellFormat(40, 6, fmt.Sprintf("%.2f", subtotal), "", 0, "R", false, 0, "")
+	pdf.Ln(6)
+
+	// GST breakdown
+	if invoice.CGST != nil && *invoice.CGST > 0 {
+		pdf.SetFont("Arial", "", 9)
+		pdf.CellFormat(130, 5, "CGST (9%):", "", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 5, fmt.Sprintf("%.2f", *invoice.CGST), "", 0, "R", false, 0, "")
+		pdf.Ln(5)
+	}
+
+	if invoice.SGST != nil && *invoice.SGST > 0 {
+		pdf.CellFormat(130, 5, "SGST (9%):", "", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 5, fmt.Sprintf("%.2f", *invoice.SGST), "", 0, "R", false, 0, "")
+		pdf.Ln(5)
+	}
+
+	if invoice.IGST != nil && *invoice.IGST > 0 {
+		pdf.CellFormat(130, 5, "IGST (18%):", "", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 5, fmt.Sprintf("%.2f", *invoice.IGST), "", 0, "R", false, 0, "")
+		pdf.Ln(5)
+	}
+
+	// Total
+	pdf.SetFont("Arial", "B", 11)
+	pdf.SetTextColor(220, 20, 60) // Red color for total
+	pdf.CellFormat(130, 8, "TOTAL:", "", 0, "R", false, 0, "")
+	pdf.CellFormat(40, 8, fmt.Sprintf("%.2f", invoice.TotalAmount), "", 0, "R", false, 0, "")
+	pdf.Ln(10)
+	drawUPIQR(pdf, upiQR)
+
+	// Terms and conditions
+	pdf.SetTextColor(33, 37, 41) // Reset to dark
+	pdf.SetFont("Arial", "B", 9)
+	pdf.Cell(0, 6, "Terms & Conditions:")
+	pdf.Ln(6)
+
+	pdf.SetFont("Arial", "", 8)
+	terms := []string{
+		"1. Payment is due within 30 days of invoice date",
+		"2. Late payments may incur additional charges",
+		"3. Goods once sold will not be taken back",
+		"4. This is a computer generated invoice",
+	}
+
+	for _, term := range terms {
+		pdf.Cell(0, 5, term)
+		pdf.Ln(5)
+	}
+
+	// Footer
+	pdf.Ln(10)
+	pdf.SetFont("Arial", "I", 8)
+	pdf.SetTextColor(128, 128, 128) // Gray
+	pdf.Cell(0, 5, "Thank you for your business!")
+	pdf.Ln(5)
+	pdf.Cell(0, 5, "For any queries, contact: support@agromart.com | +91-XXXXXXXXXX")
+
+	// Get PDF bytes
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// renderExportInvoicePDF creates an export invoice PDF: the LUT or IGST
+// declaration, amounts in the billing currency alongside INR and the shipping
+// details
+func renderExportInvoicePDF(invoice *models.Invoice, export *models.InvoiceExportDetails, order *models.Order, product *models.Product) ([]byte, error) {
+	pdf := newInvoicePDF(invoice)
+	marginX := 20.0
+	marginY := 20.0
+	pdf.SetMargins(marginX, marginY, marginX)
+	pdf.SetAutoPageBreak(true, marginY)
+	pdf.SetTextColor(33, 37, 41)
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.CellFormat(0, 10, "EXPORT INVOICE", "", 1, "C", false, 0, "")
+	pdf.SetFont("Arial", "B", 9)
+	declaration := "SUPPLY MEANT FOR EXPORT UNDER LUT WITHOUT PAYMENT OF INTEGRATED TAX"
+	if export.ExportType == models.ExportTypeWithPayment {
+		declaration = "SUPPLY MEANT FOR EXPORT ON PAYMENT OF INTEGRATED TAX"
+	}
+	pdf.CellFormat(0, 6, declaration, "", 1, "C", false, 0, "")
+	if export.LUTNumber != nil {
+		pdf.SetFont("Arial", "", 9)
+		pdf.CellFormat(0, 5, "LUT No. "+*export.LUTNumber, "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont("Arial", "", 10)
+	details := [][2]string{
+		{"Invoice Number", invoice.InvoiceNumber},
+		{"Invoice Date", invoice.IssuedDate.Format("02-Jan-2006")},
+		{"Currency", fmt.Sprintf("%s (1 %s = INR %.4f)", export.Currency, export.Currency, export.ExchangeRate)},
+	}
+	if export.DestinationCountry != nil {
+		details = append(details, [2]string{"Country of Destination", *export.DestinationCountry})
+	}
+	if export.PortCode != nil {
+		details = append(details, [2]string{"Port of Loading", *export.PortCode})
+	}
+	if export.ShippingBillNumber != nil {
+		shippingBill := *export.ShippingBillNumber
+		if export.ShippingBillDate != nil {
+			shippingBill += " dated " + export.ShippingBillDate.Format("02-Jan-2006")
+		}
+		details = append(details, [2]string{"Shipping Bill", shippingBill})
+	}
+	for _, d := range details {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(50, 6, d[0]+":", "", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 10)
+		pdf.CellFormat(0, 6, d[1], "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	taxable := invoice.TotalAmount
+	if invoice.TaxableAmount != nil {
+		taxable = *invoice.TaxableAmount
+	}
+	foreignTaxable := taxable / export.ExchangeRate
+
+	headers := []string{"Description", "Qty", "Rate (" + export.Currency + ")", "Amount (" + export.Currency + ")", "Amount (INR)"}
+	colWidths := []float64{60, 15, 30, 30, 35}
+	pdf.SetFont("Arial", "B", 9)
+	pdf.SetFillColor(240, 240, 240)
+	for i, header := range headers {
+		pdf.CellFormat(colWidths[i], 8, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(8)
+	pdf.SetFont("Arial", "", 9)
+	pdf.CellFormat(colWidths[0], 8, product.Name, "1", 0, "L", false, 0, "")
+	pdf.CellFormat(colWidths[1], 8, fmt.Sprintf("%d", order.Quantity), "1", 0, "C", false, 0, "")
+	pdf.CellFormat(colWidths[2], 8, fmt.Sprintf("%.2f", foreignTaxable/float64(order.Quantity)), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(colWidths[3], 8, fmt.Sprintf("%.2f", foreignTaxable), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(colWidths[4], 8, fmt.Sprintf("%.2f", taxable), "1", 0, "R", false, 0, "")
+	pdf.Ln(12)
+
+	pdf.SetFont("Arial", "", 10)
+	if export.ExportType == models.ExportTypeWithPayment && invoice.IGST != nil {
+		pdf.CellFormat(135, 6, "IGST (18%):", "", 0, "R", false, 0, "")
+		pdf.CellFormat(35, 6, fmt.Sprintf("%.2f", *invoice.IGST), "", 1, "R", false, 0, "")
+	} else {
+		pdf.CellFormat(135, 6, "IGST (0%, zero rated):", "", 0, "R", false, 0, "")
+		pdf.CellFormat(35, 6, "0.00", "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Arial", "B", 11)
+	pdf.CellFormat(135, 8, fmt.Sprintf("TOTAL (%s):", export.Currency), "", 0, "R", false, 0, "")
+	pdf.CellFormat(35, 8, fmt.Sprintf("%.2f", export.ForeignAmount), "", 1, "R", false, 0, "")
+	pdf.CellFormat(135, 8, "TOTAL (INR):", "", 0, "R", false, 0, "")
+	pdf.CellFormat(35, 8, fmt.Sprintf("%.2f", invoice.TotalAmount), "", 1, "R", false, 0, "")
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "I", 8)
+	pdf.SetTextColor(128, 128, 128)
+	pdf.Cell(0, 5, "This is a computer generated invoice")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderConsolidatedInvoicePDF lays out a consolidated invoice with a line
+// per order, grouped under the order it was delivered on
+func renderConsolidatedInvoicePDF(invoice *models.ConsolidatedInvoice, upiQR []byte) ([]byte, error) {
+	pdf := newInvoicePDF(invoice.Invoice)
+	marginX := 20.0
+	marginY := 20.0
+	pdf.SetMargins(marginX, marginY, marginX)
+	pdf.SetAutoPageBreak(true, marginY)
+	pdf.SetTextColor(33, 37, 41)
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.CellFormat(0, 10, "CONSOLIDATED TAX INVOICE", "", 1, "C", false, 0, "")
+	pdf.Ln(6)
+
+	details := [][2]string{
+		{"Invoice Number", invoice.InvoiceNumber},
+		{"Invoice Date", invoice.IssuedDate.Format("02-Jan-2006")},
+		{"Period", invoice.PeriodFrom.Format("02-Jan-2006") + " to " + invoice.PeriodTo.Format("02-Jan-2006")},
+		{"Orders", fmt.Sprintf("%d", len(invoice.Lines))},
+	}
+	if invoice.GSTIN != nil && *invoice.GSTIN != "" {
+		details = append(details, [2]string{"GSTIN", *invoice.GSTIN})
+	}
+	for _, d := range details {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(50, 6, d[0]+":", "", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 10)
+		pdf.CellFormat(0, 6, d[1], "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	headers := []string{"Description", "Qty", "Rate", "Taxable", "CGST", "SGST", "Amount"}
+	colWidths := []float64{50, 12, 20, 22, 20, 20, 26}
+	pdf.SetFont("Arial", "B", 9)
+	pdf.SetFillColor(240, 240, 240)
+	for i, header := range headers {
+		pdf.CellFormat(colWidths[i], 8, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(8)
+	for _, line := range invoice.Lines {
+		pdf.SetFont("Arial", "B", 8)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Order %s of %s", line.OrderID.String()[:8], line.OrderDate.Format("02-Jan-2006")), "LR", 1, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.CellFormat(colWidths[0], 7, line.ProductName, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(colWidths[1], 7, fmt.Sprintf("%d", line.Quantity), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(colWidths[2], 7, fmt.Sprintf("%.2f", line.UnitPrice), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(colWidths[3], 7, fmt.Sprintf("%.2f", line.TaxableAmount), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(colWidths[4], 7, fmt.Sprintf("%.2f", line.CGST+line.IGST), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(colWidths[5], 7, fmt.Sprintf("%.2f", line.SGST), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(colWidths[6], 7, fmt.Sprintf("%.2f", line.TotalAmount), "1", 1, "R", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	totals := [][2]string{}
+	if invoice.TaxableAmount != nil {
+		totals = append(totals, [2]string{"Taxable Amount:", fmt.Sprintf("%.2f", *invoice.TaxableAmount)})
+	}
+	if invoice.CGST != nil && *invoice.CGST > 0 {
+		totals = append(totals, [2]string{"CGST:", fmt.Sprintf("%.2f", *invoice.CGST)})
+	}
+	if invoice.SGST != nil && *invoice.SGST > 0 {
+		totals = append(totals, [2]string{"SGST:", fmt.Sprintf("%.2f", *invoice.SGST)})
+	}
+	if invoice.IGST != nil && *invoice.IGST > 0 {
+		totals = append(totals, [2]string{"IGST:", fmt.Sprintf("%.2f", *invoice.IGST)})
+	}
+	if invoice.TCS != nil {
+		totals = append(totals, [2]string{fmt.Sprintf("TCS u/s %s:", invoice.TCS.Section), fmt.Sprintf("%.2f", invoice.TCS.Amount)})
+	}
+	pdf.SetFont("Arial", "", 10)
+	for _, t := range totals {
+		pdf.CellFormat(144, 6, t[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(26, 6, t[1], "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Arial", "B", 11)
+	pdf.CellFormat(144, 8, "TOTAL:", "", 0, "R", false, 0, "")
+	pdf.CellFormat(26, 8, fmt.Sprintf("%.2f", invoice.TotalAmount), "", 1, "R", false, 0, "")
+	pdf.Ln(10)
+	drawUPIQR(pdf, upiQR)
+
+	pdf.SetFont("Arial", "I", 8)
+	pdf.SetTextColor(128, 128, 128)
+	pdf.Cell(0, 5, "This is a computer generated invoice")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawUPIQR places the UPI payment QR code below the totals; invoices without
+// one are left as they are
+func drawUPIQR(pdf *gofpdf.Fpdf, upiQR []byte) {
+	if len(upiQR) == 0 {
+		return
+	}
+	const size = 35.0
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+size+8 > pageHeight-bottom {
+		pdf.AddPage()
+	}
+	x, y := pdf.GetX(), pdf.GetY()
+	pdf.RegisterImageOptionsReader("upi-qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(upiQR))
+	pdf.ImageOptions("upi-qr", x, y, size, size, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	pdf.SetXY(x+size+4, y+size/2-5)
+	pdf.SetTextColor(33, 37, 41)
+	pdf.SetFont("Arial", "B", 10)
+	pdf.Cell(0, 5, "Scan to pay with any UPI app")
+	pdf.SetXY(x+size+4, y+size/2)
+	pdf.SetFont("Arial", "", 8)
+	pdf.Cell(0, 5, "The amount and invoice reference are filled in for you")
+	pdf.SetXY(x, y+size+4)
+}
@@ -0,0 +1,469 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDigestTimezone = "Asia/Kolkata"
+	defaultDigestSendHour = 8
+	// digestLowStockThreshold matches the low stock push alerts the digest replaces
+	digestLowStockThreshold = 10
+	// digestItemLimit caps each section's list; counts and totals cover everything
+	digestItemLimit = 10
+	// digestCatchUp is how late a digest still goes out after its send time,
+	// covering scheduler downtime without mailing stale summaries
+	digestCatchUp = 24 * time.Hour
+)
+
+// ErrInvalidDigest is returned for digest preferences or templates that
+// cannot be saved
+var ErrInvalidDigest = errors.New("invalid notification digest")
+
+const defaultDigestSubject = `Your {{.Frequency}} {{.TenantName}} digest: {{.ItemCount}} items need attention`
+
+const defaultDigestBody = `Hello {{.UserName}},
+
+Here is your {{.Frequency}} summary for {{.TenantName}} as of {{datetime .PeriodEnd}}.
+{{if .Includes "low_stock"}}
+LOW STOCK ({{.LowStockCount}})
+{{range .LowStock}}- {{.ProductName}} at {{.WarehouseName}}: {{.Quantity}} left
+{{else}}Nothing is running low.
+{{end}}{{with more .LowStockCount (len .LowStock)}}...and {{.}} more
+{{end}}{{end}}{{if .Includes "overdue_invoices"}}
+OVERDUE INVOICES ({{.OverdueInvoiceCount}}, Rs. {{money .OverdueTotal}} outstanding)
+{{range .OverdueInvoices}}- {{.InvoiceNumber}} from {{.DistributorName}}: Rs. {{money .Outstanding}}, {{.DaysOverdue}} days overdue
+{{else}}No invoices are overdue.
+{{end}}{{with more .OverdueInvoiceCount (len .OverdueInvoices)}}...and {{.}} more
+{{end}}{{end}}{{if .Includes "pending_approvals"}}
+PENDING APPROVALS ({{.PendingApprovalCount}})
+{{range .PendingApprovals}}- {{label .Kind}}: {{.Description}} x {{.Quantity}}, waiting {{.DaysWaiting}} days
+{{else}}Nothing is waiting for approval.
+{{end}}{{with more .PendingApprovalCount (len .PendingApprovals)}}...and {{.}} more
+{{end}}{{end}}
+You are receiving this because you opted into {{.Frequency}} digests. Change or switch them off under notification settings.`
+
+var digestTemplateFuncs = template.FuncMap{
+	"money":    func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"date":     func(t time.Time) string { return t.Format("02 Jan 2006") },
+	"datetime": func(t time.Time) string { return t.Format("02 Jan 2006 15:04 MST") },
+	"label":    func(s string) string { return strings.ReplaceAll(s, "_", " ") },
+	"more": func(total, shown int) int {
+		if total > shown {
+			return total - shown
+		}
+		return 0
+	},
+}
+
+// DigestService lets users swap individual low stock and approval alerts for
+// one daily or weekly email summarising low stock, overdue invoices and
+// pending approvals, rendered from the tenant's digest template
+type DigestService struct {
+	repo            repositories.NotificationDigestRepository
+	receivablesRepo repositories.ReceivablesRepository
+	userRepo        repositories.UserRepository
+	tenantRepo      repositories.TenantRepository
+	notificationSvc services.NotificationService
+}
+
+func NewDigestService(
+	repo repositories.NotificationDigestRepository,
+	receivablesRepo repositories.ReceivablesRepository,
+	userRepo repositories.UserRepository,
+	tenantRepo repositories.TenantRepository,
+	notificationSvc services.NotificationService,
+) *DigestService {
+	return &DigestService{
+		repo:            repo,
+		receivablesRepo: receivablesRepo,
+		userRepo:        userRepo,
+		tenantRepo:      tenantRepo,
+		notificationSvc: notificationSvc,
+	}
+}
+
+// defaultDigestPreference is what a user who never set up a digest has
+func defaultDigestPreference(tenantID, userID uuid.UUID) *models.DigestPreference {
+	return &models.DigestPreference{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Frequency: models.DigestFrequencyOff,
+		Sections:  append([]string(nil), models.DigestSections...),
+		SendHour:  defaultDigestSendHour,
+		WeeklyDay: int(time.Monday),
+		Timezone:  defaultDigestTimezone,
+	}
+}
+
+// GetPreference returns the user's digest preference, switched off by default
+func (s *DigestService) GetPreference(ctx context.Context, tenantID, userID uuid.UUID) (*models.DigestPreference, error) {
+	pref, err := s.repo.GetPreference(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pref == nil {
+		return defaultDigestPreference(tenantID, userID), nil
+	}
+	return pref, nil
+}
+
+// SavePreference validates and saves the user's digest preference
+func (s *DigestService) SavePreference(ctx context.Context, pref *models.DigestPreference) error {
+	if err := normalizeDigestPreference(pref); err != nil {
+		return err
+	}
+	return s.repo.UpsertPreference(ctx, pref)
+}
+
+func normalizeDigestPreference(pref *models.DigestPreference) error {
+	switch pref.Frequency {
+	case models.DigestFrequencyOff, models.DigestFrequencyDaily, models.DigestFrequencyWeekly:
+	default:
+		return fmt.Errorf("%w: frequency must be off, daily or weekly", ErrInvalidDigest)
+	}
+	if pref.SendHour < 0 || pref.SendHour > 23 {
+		return fmt.Errorf("%w: send_hour must be between 0 and 23", ErrInvalidDigest)
+	}
+	if pref.WeeklyDay < 0 || pref.WeeklyDay > 6 {
+		return fmt.Errorf("%w: weekly_day must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidDigest)
+	}
+	pref.Timezone = strings.TrimSpace(pref.Timezone)
+	if pref.Timezone == "" {
+		pref.Timezone = defaultDigestTimezone
+	}
+	if _, err := time.LoadLocation(pref.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidDigest, pref.Timezone)
+	}
+
+	if len(pref.Sections) == 0 {
+		pref.Sections = append([]string(nil), models.DigestSections...)
+		return nil
+	}
+	seen := make(map[string]bool)
+	var sections []string
+	for _, section := range pref.Sections {
+		section = strings.TrimSpace(section)
+		valid := false
+		for _, known := range models.DigestSections {
+			valid = valid || section == known
+		}
+		if !valid {
+			return fmt.Errorf("%w: unknown section %q", ErrInvalidDigest, section)
+		}
+		if !seen[section] {
+			seen[section] = true
+			sections = append(sections, section)
+		}
+	}
+	pref.Sections = sections
+	return nil
+}
+
+// GetTemplate returns the tenant's digest template, or the built-in one
+func (s *DigestService) GetTemplate(ctx context.Context, tenantID uuid.UUID) (*models.DigestTemplate, error) {
+	tmpl, err := s.repo.GetTemplate(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		return &models.DigestTemplate{TenantID: tenantID, Subject: defaultDigestSubject, Body: defaultDigestBody, IsDefault: true}, nil
+	}
+	return tmpl, nil
+}
+
+// SaveTemplate replaces the tenant's digest template
+func (s *DigestService) SaveTemplate(ctx context.Context, tmpl *models.DigestTemplate) error {
+	if strings.TrimSpace(tmpl.Subject) == "" || strings.TrimSpace(tmpl.Body) == "" {
+		return fmt.Errorf("%w: subject and body are required", ErrInvalidDigest)
+	}
+	// Render once with sample data so broken templates fail here rather than at send time
+	if _, err := renderDigest(tmpl.Subject, tmpl.Body, sampleDigestContent()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDigest, err)
+	}
+	tmpl.IsDefault = false
+	return s.repo.UpsertTemplate(ctx, tmpl)
+}
+
+// DeleteTemplate puts the tenant back on the built-in template; false means
+// it was already using it
+func (s *DigestService) DeleteTemplate(ctx context.Context, tenantID uuid.UUID) (bool, error) {
+	return s.repo.DeleteTemplate(ctx, tenantID)
+}
+
+// Preview renders the digest the user would get now under their preference,
+// or a daily digest of every section when they have not opted in
+func (s *DigestService) Preview(ctx context.Context, tenantID, userID uuid.UUID) (*models.DigestEmail, error) {
+	pref, err := s.GetPreference(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pref.Frequency == models.DigestFrequencyOff {
+		pref.Frequency = models.DigestFrequencyDaily
+	}
+	recipient := &models.DigestRecipient{DigestPreference: *pref}
+	if user, err := s.userRepo.GetByID(ctx, tenantID, userID); err == nil && user != nil {
+		recipient.Email = user.Email
+		recipient.UserName = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	if tenant, err := s.tenantRepo.GetByID(ctx, tenantID); err == nil && tenant != nil {
+		recipient.TenantName = tenant.Name
+	}
+
+	now := time.Now()
+	periodStart := now.AddDate(0, 0, -1)
+	if pref.Frequency == models.DigestFrequencyWeekly {
+		periodStart = now.AddDate(0, 0, -7)
+	}
+	return s.compose(ctx, recipient, periodStart, now, now)
+}
+
+// ListDeliveries lists digest runs of the tenant, or of one user
+func (s *DigestService) ListDeliveries(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, limit, offset int) ([]*models.DigestDelivery, error) {
+	return s.repo.ListDeliveries(ctx, tenantID, userID, limit, offset)
+}
+
+// SendDue emails every digest whose send time has passed since it last went
+// out, relative to now, and returns how many were sent. Digests with nothing
+// to report are recorded but not sent. A digest that fails to send is not
+// retried; the next one covers the same ground
+func (s *DigestService) SendDue(ctx context.Context, now time.Time) (int, error) {
+	recipients, err := s.repo.ListRecipients(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list digest recipients: %w", err)
+	}
+
+	sent := 0
+	for _, recipient := range recipients {
+		slot, periodStart, ok := digestDue(&recipient.DigestPreference, now)
+		if !ok {
+			continue
+		}
+		claimed, err := s.repo.ClaimSlot(ctx, recipient.TenantID, recipient.UserID, slot)
+		if err != nil {
+			log.Printf("Failed to claim digest for user %s: %v", recipient.UserID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if s.send(ctx, recipient, periodStart, slot, now) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (s *DigestService) send(ctx context.Context, recipient *models.DigestRecipient, periodStart, periodEnd, now time.Time) bool {
+	delivery := &models.DigestDelivery{
+		ID:          uuid.New(),
+		TenantID:    recipient.TenantID,
+		UserID:      recipient.UserID,
+		Frequency:   recipient.Frequency,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Recipient:   recipient.Email,
+		Status:      models.DigestDeliverySent,
+	}
+
+	email, err := s.compose(ctx, recipient, periodStart, periodEnd, now)
+	if err == nil {
+		delivery.ItemCount = email.Content.ItemCount()
+		if delivery.ItemCount == 0 {
+			delivery.Status = models.DigestDeliveryEmpty
+		} else {
+			err = s.notificationSvc.SendEmail(ctx, recipient.TenantID, recipient.Email, email.Subject, email.Body)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to send digest to user %s: %v", recipient.UserID, err)
+		message := err.Error()
+		delivery.Status = models.DigestDeliveryFailed
+		delivery.Error = &message
+	}
+	if logErr := s.repo.LogDelivery(ctx, delivery); logErr != nil {
+		log.Printf("Failed to record digest delivery for user %s: %v", recipient.UserID, logErr)
+	}
+	return delivery.Status == models.DigestDeliverySent
+}
+
+// compose gathers the recipient's sections as of now and renders them with
+// the tenant's template. Times are shown in the recipient's timezone
+func (s *DigestService) compose(ctx context.Context, recipient *models.DigestRecipient, periodStart, periodEnd, now time.Time) (*models.DigestEmail, error) {
+	loc := digestLocation(recipient.Timezone)
+	content := models.DigestContent{
+		TenantName:       recipient.TenantName,
+		UserName:         recipient.UserName,
+		Frequency:        recipient.Frequency,
+		PeriodStart:      periodStart.In(loc),
+		PeriodEnd:        periodEnd.In(loc),
+		Sections:         recipient.Sections,
+		LowStock:         []*models.DigestLowStockItem{},
+		OverdueInvoices:  []*models.DigestOverdueInvoice{},
+		PendingApprovals: []*models.DigestPendingApproval{},
+	}
+	if content.UserName == "" {
+		content.UserName = recipient.Email
+	}
+
+	var err error
+	if content.Includes(models.DigestSectionLowStock) {
+		if content.LowStock, content.LowStockCount, err = s.repo.LowStock(ctx, recipient.TenantID, digestLowStockThreshold, digestItemLimit); err != nil {
+			return nil, fmt.Errorf("failed to load low stock: %w", err)
+		}
+	}
+	if content.Includes(models.DigestSectionOverdueInvoices) {
+		invoices, err := s.receivablesRepo.OpenInvoices(ctx, recipient.TenantID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load overdue invoices: %w", err)
+		}
+		addOverdueInvoices(&content, invoices, now.In(loc))
+	}
+	if content.Includes(models.DigestSectionPendingApprovals) {
+		if content.PendingApprovals, content.PendingApprovalCount, err = s.repo.PendingApprovals(ctx, recipient.TenantID, digestItemLimit); err != nil {
+			return nil, fmt.Errorf("failed to load pending approvals: %w", err)
+		}
+		for _, approval := range content.PendingApprovals {
+			approval.DaysWaiting = int(now.Sub(approval.CreatedAt).Hours() / 24)
+		}
+	}
+	if content.LowStock == nil {
+		content.LowStock = []*models.DigestLowStockItem{}
+	}
+	if content.PendingApprovals == nil {
+		content.PendingApprovals = []*models.DigestPendingApproval{}
+	}
+
+	tmpl, err := s.GetTemplate(ctx, recipient.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load digest template: %w", err)
+	}
+	return renderDigest(tmpl.Subject, tmpl.Body, content)
+}
+
+// addOverdueInvoices adds open invoices due before today, most overdue first
+// as OpenInvoices lists them, keeping the first few
+func addOverdueInvoices(content *models.DigestContent, invoices []*models.ReceivableInvoice, now time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, invoice := range invoices {
+		due := time.Date(invoice.DueDate.Year(), invoice.DueDate.Month(), invoice.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+		if !due.Before(today) {
+			continue
+		}
+		content.OverdueInvoiceCount++
+		content.OverdueTotal += invoice.Outstanding
+		if len(content.OverdueInvoices) < digestItemLimit {
+			content.OverdueInvoices = append(content.OverdueInvoices, &models.DigestOverdueInvoice{
+				InvoiceID:       invoice.InvoiceID,
+				InvoiceNumber:   invoice.InvoiceNumber,
+				DistributorName: invoice.DistributorName,
+				DueDate:         invoice.DueDate,
+				DaysOverdue:     int(today.Sub(due).Hours() / 24),
+				Outstanding:     invoice.Outstanding,
+			})
+		}
+	}
+	content.OverdueTotal = roundStatementAmount(content.OverdueTotal)
+}
+
+// digestDue works out whether the preference has a digest due at now. slot
+// is the latest send time at or before now in the user's timezone; weekly
+// digests only have one on their weekday. The digest covers the day or week
+// up to slot and is due while slot is within the catch-up window and later
+// than the last digest sent
+func digestDue(pref *models.DigestPreference, now time.Time) (slot, periodStart time.Time, ok bool) {
+	if pref.Frequency != models.DigestFrequencyDaily && pref.Frequency != models.DigestFrequencyWeekly {
+		return time.Time{}, time.Time{}, false
+	}
+	local := now.In(digestLocation(pref.Timezone))
+	slot = time.Date(local.Year(), local.Month(), local.Day(), pref.SendHour, 0, 0, 0, local.Location())
+	if slot.After(local) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	periodStart = slot.AddDate(0, 0, -1)
+	if pref.Frequency == models.DigestFrequencyWeekly {
+		for int(slot.Weekday()) != pref.WeeklyDay {
+			slot = slot.AddDate(0, 0, -1)
+		}
+		periodStart = slot.AddDate(0, 0, -7)
+	}
+
+	if now.Sub(slot) >= digestCatchUp {
+		return time.Time{}, time.Time{}, false
+	}
+	if pref.LastSentAt != nil && !pref.LastSentAt.Before(slot) {
+		return time.Time{}, time.Time{}, false
+	}
+	return slot, periodStart, true
+}
+
+func digestLocation(timezone string) *time.Location {
+	if loc, err := time.LoadLocation(timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+func renderDigest(subjectText, bodyText string, content models.DigestContent) (*models.DigestEmail, error) {
+	subject, err := renderDigestText(subjectText, &content)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+	body, err := renderDigestText(bodyText, &content)
+	if err != nil {
+		return nil, fmt.Errorf("body: %w", err)
+	}
+	return &models.DigestEmail{Subject: strings.TrimSpace(subject), Body: body, Content: content}, nil
+}
+
+func renderDigestText(text string, content *models.DigestContent) (string, error) {
+	tmpl, err := template.New("digest").Funcs(digestTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, content); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// sampleDigestContent fills every section so template validation reaches
+// every branch a real digest can take
+func sampleDigestContent() models.DigestContent {
+	now := time.Now()
+	return models.DigestContent{
+		TenantName:  "Sample Agro",
+		UserName:    "Asha Rao",
+		Frequency:   models.DigestFrequencyDaily,
+		PeriodStart: now.AddDate(0, 0, -1),
+		PeriodEnd:   now,
+		Sections:    models.DigestSections,
+		LowStock: []*models.DigestLowStockItem{
+			{ProductID: uuid.New(), ProductName: "Urea 45kg", WarehouseName: "Main", Quantity: 4},
+		},
+		LowStockCount: 3,
+		OverdueInvoices: []*models.DigestOverdueInvoice{
+			{InvoiceID: uuid.New(), InvoiceNumber: "INV-0001", DistributorName: "Green Fields", DueDate: now.AddDate(0, 0, -7), DaysOverdue: 7, Outstanding: 1000},
+		},
+		OverdueInvoiceCount: 1,
+		OverdueTotal:        1000,
+		PendingApprovals: []*models.DigestPendingApproval{
+			{Kind: "sales_order", ID: uuid.New(), Description: "DAP 50kg", Quantity: 20, Amount: 27000, CreatedAt: now.AddDate(0, 0, -2), DaysWaiting: 2},
+		},
+		PendingApprovalCount: 1,
+	}
+}
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestDueDaily(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	pref := &models.DigestPreference{Frequency: models.DigestFrequencyDaily, SendHour: 8, Timezone: "Asia/Kolkata"}
+
+	// 07:30 local is before today's send time, so yesterday's slot is due
+	slot, start, ok := digestDue(pref, time.Date(2025, 9, 3, 7, 30, 0, 0, kolkata))
+	require.True(t, ok)
+	assert.True(t, slot.Equal(time.Date(2025, 9, 2, 8, 0, 0, 0, kolkata)))
+	assert.True(t, start.Equal(time.Date(2025, 9, 1, 8, 0, 0, 0, kolkata)))
+
+	now := time.Date(2025, 9, 3, 8, 5, 0, 0, kolkata)
+	slot, start, ok = digestDue(pref, now)
+	require.True(t, ok)
+	assert.True(t, slot.Equal(time.Date(2025, 9, 3, 8, 0, 0, 0, kolkata)))
+	assert.True(t, start.Equal(time.Date(2025, 9, 2, 8, 0, 0, 0, kolkata)))
+
+	pref.LastSentAt = &slot
+	_, _, ok = digestDue(pref, now.Add(time.Hour))
+	assert.False(t, ok, "already sent for this slot")
+
+	pref.Frequency = models.DigestFrequencyOff
+	_, _, ok = digestDue(pref, now)
+	assert.False(t, ok)
+}
+
+func TestDigestDueWeekly(t *testing.T) {
+	pref := &models.DigestPreference{Frequency: models.DigestFrequencyWeekly, SendHour: 9, WeeklyDay: int(time.Monday), Timezone: "UTC"}
+
+	// 2025-09-01 is a Monday
+	slot, start, ok := digestDue(pref, time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC), slot)
+	assert.Equal(t, time.Date(2025, 8, 25, 9, 0, 0, 0, time.UTC), start)
+
+	// Past the catch-up window the week's digest is skipped
+	_, _, ok = digestDue(pref, time.Date(2025, 9, 3, 10, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestNormalizeDigestPreference(t *testing.T) {
+	pref := &models.DigestPreference{Frequency: models.DigestFrequencyDaily, SendHour: 7, Sections: []string{"low_stock", " low_stock", "pending_approvals"}}
+	require.NoError(t, normalizeDigestPreference(pref))
+	assert.Equal(t, []string{"low_stock", "pending_approvals"}, pref.Sections)
+	assert.Equal(t, defaultDigestTimezone, pref.Timezone)
+
+	pref = &models.DigestPreference{Frequency: models.DigestFrequencyWeekly}
+	require.NoError(t, normalizeDigestPreference(pref))
+	assert.Equal(t, models.DigestSections, pref.Sections)
+
+	assert.ErrorIs(t, normalizeDigestPreference(&models.DigestPreference{Frequency: "hourly"}), ErrInvalidDigest)
+	assert.ErrorIs(t, normalizeDigestPreference(&models.DigestPreference{Frequency: "daily", SendHour: 24}), ErrInvalidDigest)
+	assert.ErrorIs(t, normalizeDigestPreference(&models.DigestPreference{Frequency: "daily", Timezone: "Mars/Base"}), ErrInvalidDigest)
+	assert.ErrorIs(t, normalizeDigestPreference(&models.DigestPreference{Frequency: "daily", Sections: []string{"weather"}}), ErrInvalidDigest)
+}
+
+func TestAddOverdueInvoices(t *testing.T) {
+	now := time.Date(2025, 9, 10, 15, 0, 0, 0, time.UTC)
+	content := &models.DigestContent{}
+	invoice := func(number string, due time.Time, outstanding float64) *models.ReceivableInvoice {
+		return &models.ReceivableInvoice{OpenInvoice: models.OpenInvoice{InvoiceNumber: number, DueDate: due, Outstanding: outstanding}}
+	}
+	addOverdueInvoices(content, []*models.ReceivableInvoice{
+		invoice("INV-1", time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), 100.105),
+		invoice("INV-2", time.Date(2025, 9, 9, 0, 0, 0, 0, time.UTC), 50),
+		invoice("INV-3", time.Date(2025, 9, 10, 0, 0, 0, 0, time.UTC), 75),
+	}, now)
+
+	assert.Equal(t, 2, content.OverdueInvoiceCount)
+	assert.Equal(t, 150.11, content.OverdueTotal)
+	require.Len(t, content.OverdueInvoices, 2)
+	assert.Equal(t, 9, content.OverdueInvoices[0].DaysOverdue)
+	assert.Equal(t, 1, content.OverdueInvoices[1].DaysOverdue)
+}
+
+func TestRenderDefaultDigest(t *testing.T) {
+	content := sampleDigestContent()
+	email, err := renderDigest(defaultDigestSubject, defaultDigestBody, content)
+	require.NoError(t, err)
+	assert.Equal(t, "Your daily Sample Agro digest: 5 items need attention", email.Subject)
+	assert.Contains(t, email.Body, "- Urea 45kg at Main: 4 left\n...and 2 more")
+	assert.Contains(t, email.Body, "INV-0001 from Green Fields: Rs. 1000.00, 7 days overdue")
+	assert.Contains(t, email.Body, "- sales order: DAP 50kg x 20, waiting 2 days")
+
+	content.Sections = []string{models.DigestSectionOverdueInvoices}
+	email, err = renderDigest(defaultDigestSubject, defaultDigestBody, content)
+	require.NoError(t, err)
+	assert.False(t, strings.Contains(email.Body, "LOW STOCK"))
+
+	_, err = renderDigest("{{.Missing}}", "body", content)
+	assert.Error(t, err)
+}
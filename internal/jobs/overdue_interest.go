@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInterestRate = 1.5
+	// maxInterestRate caps the monthly rate at 5%, 60% a year
+	maxInterestRate      = 5.0
+	maxInterestGraceDays = 365
+)
+
+// ErrInvoiceNotFound is returned for an invoice the tenant does not have
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// OverdueInterestService keeps each tenant's overdue interest terms and
+// accrues simple interest against invoices once they are past due
+type OverdueInterestService struct {
+	repo            repositories.OverdueInterestRepository
+	invoiceRepo     repositories.InvoiceRepository
+	distributorRepo repositories.DistributorRepository
+	calendars       services.TenantCalendarService
+}
+
+func NewOverdueInterestService(
+	repo repositories.OverdueInterestRepository,
+	invoiceRepo repositories.InvoiceRepository,
+	distributorRepo repositories.DistributorRepository,
+	calendars services.TenantCalendarService,
+) *OverdueInterestService {
+	return &OverdueInterestService{
+		repo:            repo,
+		invoiceRepo:     invoiceRepo,
+		distributorRepo: distributorRepo,
+		calendars:       calendars,
+	}
+}
+
+// GetSettings returns the tenant's terms, or disabled defaults
+func (s *OverdueInterestService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.OverdueInterestSettings, error) {
+	settings, err := s.repo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return &models.OverdueInterestSettings{TenantID: tenantID, RatePercentPerMonth: defaultInterestRate}, nil
+	}
+	return settings, nil
+}
+
+func (s *OverdueInterestService) UpdateSettings(ctx context.Context, tenantID uuid.UUID, settings *models.OverdueInterestSettings) error {
+	if err := validateInterestTerms(&settings.RatePercentPerMonth, &settings.GraceDays); err != nil {
+		return err
+	}
+	settings.TenantID = tenantID
+	return s.repo.UpsertSettings(ctx, settings)
+}
+
+// GetCustomerTerms returns the customer's override; without one both fields
+// are nil and the tenant's terms apply
+func (s *OverdueInterestService) GetCustomerTerms(ctx context.Context, tenantID, customerID uuid.UUID) (*models.CustomerInterestTerms, error) {
+	if err := s.requireCustomer(ctx, tenantID, customerID); err != nil {
+		return nil, err
+	}
+	terms, err := s.repo.GetCustomerTerms(ctx, tenantID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if terms == nil {
+		return &models.CustomerInterestTerms{TenantID: tenantID, DistributorID: customerID}, nil
+	}
+	return terms, nil
+}
+
+func (s *OverdueInterestService) UpdateCustomerTerms(ctx context.Context, tenantID, customerID uuid.UUID, terms *models.CustomerInterestTerms) error {
+	if err := s.requireCustomer(ctx, tenantID, customerID); err != nil {
+		return err
+	}
+	if err := validateInterestTerms(terms.RatePercentPerMonth, terms.GraceDays); err != nil {
+		return err
+	}
+	terms.TenantID = tenantID
+	terms.DistributorID = customerID
+	return s.repo.UpsertCustomerTerms(ctx, terms)
+}
+
+// DeleteCustomerTerms puts the customer back on the tenant's terms
+func (s *OverdueInterestService) DeleteCustomerTerms(ctx context.Context, tenantID, customerID uuid.UUID) error {
+	if err := s.requireCustomer(ctx, tenantID, customerID); err != nil {
+		return err
+	}
+	_, err := s.repo.DeleteCustomerTerms(ctx, tenantID, customerID)
+	return err
+}
+
+func (s *OverdueInterestService) requireCustomer(ctx context.Context, tenantID, customerID uuid.UUID) error {
+	distributor, err := s.distributorRepo.GetByID(ctx, tenantID, customerID)
+	if err != nil || distributor == nil {
+		return ErrCustomerNotFound
+	}
+	return nil
+}
+
+// AccrueInterest records the interest each overdue invoice of the tenant has
+// accrued up to and including asOf's day on the tenant's calendar since it
+// was last accrued, and
+// returns how many invoices were charged. Accruing the same day twice is a no-op
+func (s *OverdueInterestService) AccrueInterest(ctx context.Context, tenantID uuid.UUID, asOf time.Time) (int, error) {
+	settings, err := s.repo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load interest settings: %w", err)
+	}
+	if settings == nil || !settings.Enabled {
+		return 0, nil
+	}
+
+	asOf = services.TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(asOf)
+	candidates, err := s.repo.OverdueInvoices(ctx, tenantID, asOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find overdue invoices: %w", err)
+	}
+
+	charged := 0
+	for _, candidate := range candidates {
+		entry := accrueInterest(settings, candidate, asOf)
+		if entry == nil {
+			continue
+		}
+		entry.ID = uuid.New()
+		entry.TenantID = tenantID
+		created, err := s.repo.CreateEntry(ctx, entry)
+		if err != nil {
+			log.Printf("Failed to accrue interest on invoice %s: %v", candidate.InvoiceID, err)
+			continue
+		}
+		if created {
+			charged++
+		}
+	}
+	return charged, nil
+}
+
+// GetInvoiceInterest reports the interest accrued on an invoice and how it
+// adds to the amount due. A paid invoice's payment settled its accrued
+// interest first and the invoice amount after it
+func (s *OverdueInterestService) GetInvoiceInterest(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceInterest, error) {
+	invoice, err := s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+	if invoice == nil {
+		return nil, ErrInvoiceNotFound
+	}
+	entries, err := s.repo.ListEntries(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load accrued interest: %w", err)
+	}
+	if entries == nil {
+		entries = []*models.InterestEntry{}
+	}
+
+	result := &models.InvoiceInterest{
+		InvoiceID:     invoice.ID,
+		InvoiceNumber: invoice.InvoiceNumber,
+		Status:        invoice.Status,
+		Principal:     invoice.TotalAmount,
+		PaidDate:      invoice.PaidDate,
+		Entries:       entries,
+	}
+	for _, entry := range entries {
+		result.InterestAccrued += entry.Amount
+	}
+	result.InterestAccrued = roundStatementAmount(result.InterestAccrued)
+
+	switch invoice.Status {
+	case "paid":
+		result.InterestPaid = result.InterestAccrued
+		result.PrincipalPaid = result.Principal
+	case "cancelled":
+	default:
+		result.AmountDue = roundStatementAmount(result.Principal + result.InterestAccrued)
+	}
+	return result, nil
+}
+
+// accrueInterest works out the interest an overdue invoice has accrued up to
+// and including asOf that is not yet recorded, or nil when there is none.
+// Interest is simple, at the monthly rate times 12 over 365 per day, and
+// starts after the grace days, once the tenant enabled it
+func accrueInterest(settings *models.OverdueInterestSettings, candidate *models.InterestCandidate, asOf time.Time) *models.InterestEntry {
+	rate, graceDays := settings.RatePercentPerMonth, settings.GraceDays
+	if candidate.CustomerRate != nil {
+		rate = *candidate.CustomerRate
+	}
+	if candidate.CustomerGraceDays != nil {
+		graceDays = *candidate.CustomerGraceDays
+	}
+	if rate <= 0 || candidate.TotalAmount <= 0 {
+		return nil
+	}
+
+	start := statementDay(candidate.DueDate).AddDate(0, 0, graceDays)
+	if settings.EnabledSince != nil && statementDay(*settings.EnabledSince).After(start) {
+		start = statementDay(*settings.EnabledSince)
+	}
+	if candidate.AccruedThrough != nil && statementDay(*candidate.AccruedThrough).After(start) {
+		start = statementDay(*candidate.AccruedThrough)
+	}
+	end := statementDay(asOf)
+	days := int(end.Sub(start).Hours() / 24)
+	if days <= 0 {
+		return nil
+	}
+
+	amount := roundStatementAmount(candidate.TotalAmount * rate / 100 * 12 / 365 * float64(days))
+	if amount <= 0 {
+		return nil
+	}
+	return &models.InterestEntry{
+		InvoiceID:           candidate.InvoiceID,
+		DistributorID:       candidate.DistributorID,
+		PeriodStart:         start,
+		PeriodEnd:           end,
+		Days:                days,
+		Principal:           candidate.TotalAmount,
+		RatePercentPerMonth: rate,
+		Amount:              amount,
+	}
+}
+
+func validateInterestTerms(rate *float64, graceDays *int) error {
+	if rate != nil && (*rate < 0 || *rate > maxInterestRate) {
+		return fmt.Errorf("rate_percent_per_month must be between 0 and %.0f", maxInterestRate)
+	}
+	if graceDays != nil && (*graceDays < 0 || *graceDays > maxInterestGraceDays) {
+		return fmt.Errorf("grace_days must be between 0 and %d", maxInterestGraceDays)
+	}
+	return nil
+}
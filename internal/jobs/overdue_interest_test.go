@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrueInterest(t *testing.T) {
+	due := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	settings := &models.OverdueInterestSettings{Enabled: true, RatePercentPerMonth: 1.5, GraceDays: 5}
+	candidate := &models.InterestCandidate{InvoiceID: uuid.New(), DistributorID: uuid.New(), TotalAmount: 36500, DueDate: due}
+
+	// Still within the grace days
+	assert.Nil(t, accrueInterest(settings, candidate, due.AddDate(0, 0, 5)))
+
+	entry := accrueInterest(settings, candidate, due.AddDate(0, 0, 15))
+	require.NotNil(t, entry)
+	assert.Equal(t, due.AddDate(0, 0, 5), entry.PeriodStart)
+	assert.Equal(t, 10, entry.Days)
+	// 36500 at 18% a year is 18 a day
+	assert.Equal(t, 180.0, entry.Amount)
+	assert.Equal(t, 1.5, entry.RatePercentPerMonth)
+
+	// Picks up where the last accrual stopped
+	through := due.AddDate(0, 0, 15)
+	candidate.AccruedThrough = &through
+	assert.Nil(t, accrueInterest(settings, candidate, through))
+	entry = accrueInterest(settings, candidate, through.AddDate(0, 0, 1))
+	require.NotNil(t, entry)
+	assert.Equal(t, 1, entry.Days)
+	assert.Equal(t, 18.0, entry.Amount)
+}
+
+func TestAccrueInterestCustomerTermsAndEnabledSince(t *testing.T) {
+	due := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	enabledSince := due.AddDate(0, 0, 20)
+	settings := &models.OverdueInterestSettings{Enabled: true, EnabledSince: &enabledSince, RatePercentPerMonth: 1.5}
+	candidate := &models.InterestCandidate{TotalAmount: 36500, DueDate: due}
+
+	// No interest for days before the tenant switched it on
+	entry := accrueInterest(settings, candidate, due.AddDate(0, 0, 30))
+	require.NotNil(t, entry)
+	assert.Equal(t, 10, entry.Days)
+
+	rate, grace := 3.0, 25
+	candidate.CustomerRate, candidate.CustomerGraceDays = &rate, &grace
+	entry = accrueInterest(settings, candidate, due.AddDate(0, 0, 30))
+	require.NotNil(t, entry)
+	assert.Equal(t, 5, entry.Days)
+	assert.Equal(t, 180.0, entry.Amount)
+
+	exempt := 0.0
+	candidate.CustomerRate = &exempt
+	assert.Nil(t, accrueInterest(settings, candidate, due.AddDate(0, 0, 30)))
+}
+
+func TestValidateInterestTerms(t *testing.T) {
+	rate, negative, tooHigh := 2.0, -1.0, 7.5
+	grace, badGrace := 10, 400
+	assert.NoError(t, validateInterestTerms(&rate, &grace))
+	assert.NoError(t, validateInterestTerms(nil, nil))
+	assert.Error(t, validateInterestTerms(&negative, nil))
+	assert.Error(t, validateInterestTerms(&tooHigh, nil))
+	assert.Error(t, validateInterestTerms(nil, &badGrace))
+}
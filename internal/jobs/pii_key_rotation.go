@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	piiRotationBatch = 500
+	// piiRetiredKeyGrace keeps a retired key well past every instance's key
+	// cache, since an instance may still seal with it until it reloads
+	piiRetiredKeyGrace = 24 * time.Hour
+)
+
+// PIIKeyRotationService rotates tenants' PII data keys once they reach the
+// maximum age and re-encrypts values sealed with older keys, as well as
+// plaintext written before encryption was enabled. Retired keys are deleted
+// once no value uses them
+type PIIKeyRotationService struct {
+	repo    repositories.DataKeyRepository
+	keyring *pii.DataKeyRing
+	maxAge  time.Duration
+}
+
+func NewPIIKeyRotationService(repo repositories.DataKeyRepository, keyring *pii.DataKeyRing, maxAge time.Duration) *PIIKeyRotationService {
+	return &PIIKeyRotationService{repo: repo, keyring: keyring, maxAge: maxAge}
+}
+
+// Run rotates every tenant and returns how many rows were re-encrypted. It
+// does nothing when no master key is configured
+func (s *PIIKeyRotationService) Run(ctx context.Context) (int, error) {
+	if !s.keyring.Enabled() {
+		return 0, nil
+	}
+	tenantIDs, err := s.repo.ListTenantIDs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	resealed := 0
+	for _, tenantID := range tenantIDs {
+		count, err := s.RotateTenant(ctx, tenantID)
+		resealed += count
+		if err != nil {
+			log.Printf("PII key rotation failed for tenant %s: %v", tenantID.String(), err)
+		}
+	}
+	return resealed, nil
+}
+
+// RotateTenant rotates one tenant's key when due and re-encrypts its stale rows
+func (s *PIIKeyRotationService) RotateTenant(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	rotated, err := s.keyring.RotateIfOlderThan(ctx, tenantID, s.maxAge)
+	if err != nil {
+		return 0, err
+	}
+	if rotated {
+		log.Printf("PII data key rotated for tenant %s", tenantID.String())
+	}
+	active, err := s.keyring.ActiveVersion(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	resealed := 0
+	for _, table := range repositories.PIITables {
+		count, err := s.resealTable(ctx, tenantID, table, active)
+		resealed += count
+		if err != nil {
+			return resealed, fmt.Errorf("failed to re-encrypt %s: %w", table.Table, err)
+		}
+	}
+
+	inUse, err := s.repo.KeyVersionsInUse(ctx, tenantID)
+	if err != nil {
+		return resealed, err
+	}
+	inUse = append(inUse, active)
+	if _, err := s.repo.DeleteRetiredDataKeys(ctx, tenantID, inUse, time.Now().Add(-piiRetiredKeyGrace)); err != nil {
+		return resealed, fmt.Errorf("failed to delete retired keys: %w", err)
+	}
+	return resealed, nil
+}
+
+func (s *PIIKeyRotationService) resealTable(ctx context.Context, tenantID uuid.UUID, table repositories.PIITable, active int) (int, error) {
+	resealed := 0
+	afterID := uuid.Nil
+	for {
+		rows, err := s.repo.ListStaleRows(ctx, tenantID, table, active, afterID, piiRotationBatch)
+		if err != nil {
+			return resealed, err
+		}
+		for _, row := range rows {
+			afterID = row.ID
+			sealed, indexes, err := s.reseal(ctx, tenantID, table, row)
+			if err != nil {
+				// Leave the row for a later run rather than stall the tenant
+				log.Printf("Skipping %s %s during PII key rotation: %v", table.Table, row.ID.String(), err)
+				continue
+			}
+			updated, err := s.repo.UpdateSealedRow(ctx, tenantID, table, row, sealed, indexes)
+			if err != nil {
+				return resealed, err
+			}
+			if updated {
+				resealed++
+			}
+		}
+		if len(rows) < piiRotationBatch {
+			return resealed, nil
+		}
+	}
+}
+
+// reseal decrypts a row's values and seals them again with the active key
+func (s *PIIKeyRotationService) reseal(ctx context.Context, tenantID uuid.UUID, table repositories.PIITable, row *models.SealedRow) (*models.SealedRow, []*string, error) {
+	sealed := &models.SealedRow{Table: row.Table, ID: row.ID, Values: make([]*string, len(row.Values))}
+	indexes := make([]*string, len(row.Values))
+	for i, value := range row.Values {
+		plain, err := s.keyring.Decrypt(ctx, tenantID, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		if table.GlobalIndex {
+			indexes[i] = s.keyring.GlobalBlindIndex(plain)
+		} else {
+			indexes[i] = s.keyring.BlindIndex(tenantID, plain)
+		}
+		if sealed.Values[i], err = s.keyring.Encrypt(ctx, tenantID, plain); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sealed, indexes, nil
+}
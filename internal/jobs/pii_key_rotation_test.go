@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePIIRow struct {
+	id      uuid.UUID
+	values  []*string
+	indexes []*string
+}
+
+type fakeDataKeyRepo struct {
+	tenantID uuid.UUID
+	keys     []*models.TenantDataKey
+	rows     map[string][]*fakePIIRow
+}
+
+func (r *fakeDataKeyRepo) ListDataKeys(ctx context.Context, tenantID uuid.UUID) ([]*models.TenantDataKey, error) {
+	var keys []*models.TenantDataKey
+	for _, key := range r.keys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	return keys, nil
+}
+
+func (r *fakeDataKeyRepo) CreateDataKey(ctx context.Context, key *models.TenantDataKey) (bool, error) {
+	for _, existing := range r.keys {
+		if existing.Version == key.Version {
+			return false, nil
+		}
+	}
+	now := time.Now()
+	for _, existing := range r.keys {
+		if existing.IsActive {
+			existing.IsActive = false
+			existing.RetiredAt = &now
+		}
+	}
+	stored := *key
+	stored.CreatedAt = now
+	r.keys = append(r.keys, &stored)
+	return true, nil
+}
+
+func (r *fakeDataKeyRepo) ListTenantIDs(ctx context.Context) ([]uuid.UUID, error) {
+	return []uuid.UUID{r.tenantID}, nil
+}
+
+func (r *fakeDataKeyRepo) ListStaleRows(ctx context.Context, tenantID uuid.UUID, table repositories.PIITable, activeVersion int, afterID uuid.UUID, limit int) ([]*models.SealedRow, error) {
+	prefix := strings.TrimSuffix(pii.SealedPattern(activeVersion), "%")
+	var stale []*models.SealedRow
+	for _, row := range r.rows[table.Table] {
+		if bytes.Compare(row.id[:], afterID[:]) <= 0 {
+			continue
+		}
+		for i, value := range row.values {
+			if value != nil && *value != "" && (!strings.HasPrefix(*value, prefix) || row.indexes[i] == nil) {
+				stale = append(stale, &models.SealedRow{Table: table.Table, ID: row.id, Values: append([]*string(nil), row.values...)})
+				break
+			}
+		}
+		if len(stale) == limit {
+			break
+		}
+	}
+	return stale, nil
+}
+
+func (r *fakeDataKeyRepo) UpdateSealedRow(ctx context.Context, tenantID uuid.UUID, table repositories.PIITable, previous, sealed *models.SealedRow, indexes []*string) (bool, error) {
+	for _, row := range r.rows[table.Table] {
+		if row.id == sealed.ID {
+			row.values = sealed.Values
+			row.indexes = indexes
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeDataKeyRepo) KeyVersionsInUse(ctx context.Context, tenantID uuid.UUID) ([]int, error) {
+	seen := map[int]bool{}
+	var versions []int
+	for _, rows := range r.rows {
+		for _, row := range rows {
+			for _, value := range row.values {
+				if value == nil {
+					continue
+				}
+				if version, ok := pii.SealedVersion(*value); ok && !seen[version] {
+					seen[version] = true
+					versions = append(versions, version)
+				}
+			}
+		}
+	}
+	return versions, nil
+}
+
+func (r *fakeDataKeyRepo) DeleteRetiredDataKeys(ctx context.Context, tenantID uuid.UUID, inUse []int, retiredBefore time.Time) (int64, error) {
+	var kept []*models.TenantDataKey
+	var deleted int64
+	for _, key := range r.keys {
+		used := false
+		for _, version := range inUse {
+			used = used || version == key.Version
+		}
+		if !key.IsActive && key.RetiredAt.Before(retiredBefore) && !used {
+			deleted++
+			continue
+		}
+		kept = append(kept, key)
+	}
+	r.keys = kept
+	return deleted, nil
+}
+
+func (r *fakeDataKeyRepo) addRow(table string, values ...*string) *fakePIIRow {
+	row := &fakePIIRow{id: uuid.New(), values: values, indexes: make([]*string, len(values))}
+	r.rows[table] = append(r.rows[table], row)
+	sort.Slice(r.rows[table], func(i, j int) bool {
+		return bytes.Compare(r.rows[table][i].id[:], r.rows[table][j].id[:]) < 0
+	})
+	return row
+}
+
+func newRotationTest(t *testing.T) (*fakeDataKeyRepo, *pii.DataKeyRing) {
+	repo := &fakeDataKeyRepo{tenantID: uuid.New(), rows: map[string][]*fakePIIRow{}}
+	master := make([]byte, 32)
+	_, err := rand.Read(master)
+	require.NoError(t, err)
+	ring, err := pii.NewDataKeyRing(repo, master)
+	require.NoError(t, err)
+	return repo, ring
+}
+
+func TestPIIKeyRotationEncryptsLegacyPlaintext(t *testing.T) {
+	repo, ring := newRotationTest(t)
+	email, phone := "ramesh@example.com", "+91 98480 12345"
+	row := repo.addRow("distributors", &email, &phone, nil)
+
+	service := NewPIIKeyRotationService(repo, ring, 90*24*time.Hour)
+	resealed, err := service.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, resealed)
+
+	require.NotNil(t, row.values[0])
+	assert.True(t, pii.IsSealed(*row.values[0]))
+	assert.True(t, pii.IsSealed(*row.values[1]))
+	assert.Nil(t, row.values[2])
+	assert.Equal(t, ring.BlindIndex(repo.tenantID, &phone), row.indexes[1])
+
+	plain, err := ring.Decrypt(context.Background(), repo.tenantID, row.values[0])
+	require.NoError(t, err)
+	assert.Equal(t, email, *plain)
+}
+
+func TestPIIKeyRotationIndexesUserEmailsAcrossTenants(t *testing.T) {
+	repo, ring := newRotationTest(t)
+	email := "Ramesh@Example.com"
+	row := repo.addRow("users", &email)
+
+	service := NewPIIKeyRotationService(repo, ring, 90*24*time.Hour)
+	resealed, err := service.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, resealed)
+
+	require.NotNil(t, row.values[0])
+	assert.True(t, pii.IsSealed(*row.values[0]))
+	assert.Equal(t, ring.GlobalBlindIndex(&email), row.indexes[0])
+}
+
+func TestPIIKeyRotationReencryptsAndDeletesRetiredKeys(t *testing.T) {
+	repo, ring := newRotationTest(t)
+	ctx := context.Background()
+	gstin := "29ABCDE1234F1Z5"
+	sealed, err := ring.Encrypt(ctx, repo.tenantID, &gstin)
+	require.NoError(t, err)
+	row := repo.addRow("suppliers", nil, nil, sealed)
+	row.indexes[2] = ring.BlindIndex(repo.tenantID, &gstin)
+
+	// A zero maximum age makes every run rotate
+	service := NewPIIKeyRotationService(repo, ring, 0)
+	resealed, err := service.RotateTenant(ctx, repo.tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, resealed)
+
+	version, ok := pii.SealedVersion(*row.values[2])
+	require.True(t, ok)
+	assert.Equal(t, 2, version)
+	// The retired key is within its grace period, so it is kept
+	assert.Len(t, repo.keys, 2)
+
+	retiredAt := time.Now().Add(-2 * piiRetiredKeyGrace)
+	repo.keys[0].RetiredAt = &retiredAt
+	service.maxAge = 90 * 24 * time.Hour
+	_, err = service.RotateTenant(ctx, repo.tenantID)
+	require.NoError(t, err)
+	require.Len(t, repo.keys, 1)
+	assert.Equal(t, 2, repo.keys[0].Version)
+
+	plain, err := ring.Decrypt(ctx, repo.tenantID, row.values[2])
+	require.NoError(t, err)
+	assert.Equal(t, gstin, *plain)
+}
+
+func TestPIIKeyRotationDisabledWithoutMasterKey(t *testing.T) {
+	repo := &fakeDataKeyRepo{tenantID: uuid.New(), rows: map[string][]*fakePIIRow{}}
+	service := NewPIIKeyRotationService(repo, nil, time.Hour)
+
+	resealed, err := service.Run(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, resealed)
+	assert.Empty(t, repo.keys)
+}
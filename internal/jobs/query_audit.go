@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/pkg/database"
+)
+
+const (
+	queryAuditBuffer        = 1000
+	queryAuditBatch         = 200
+	queryAuditFlushInterval = 5 * time.Second
+	queryAuditPurgeInterval = time.Hour
+	queryAuditMaxText       = 4000
+	queryAuditMaxError      = 500
+)
+
+var (
+	// queryStringLiteral matches quoted literals, including doubled quotes
+	queryStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// queryNumberLiteral matches numbers that are not part of an identifier
+	// or a $n placeholder
+	queryNumberLiteral = regexp.MustCompile(`([^\w$.])-?\d+(?:\.\d+)?\b`)
+	// queryValueList collapses IN lists and multi-row VALUES of any length
+	queryValueList = regexp.MustCompile(`\(\s*(?:\?|\$\d+)(?:\s*,\s*(?:\?|\$\d+))+\s*\)`)
+)
+
+// QueryAuditService records slow and failing queries, with the request that
+// ran them, in the query_diagnostics table. It is the pool's query observer:
+// slow queries are sampled at sampleRate while failures are always kept.
+// Recordings are buffered and written in batches; when the buffer is full
+// they are dropped rather than slow down the query path
+type QueryAuditService struct {
+	repo       repositories.QueryDiagnosticsRepository
+	sampleRate float64
+	retention  time.Duration
+	events     chan *models.QueryDiagnostic
+	dropped    atomic.Uint64
+	random     func() float64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewQueryAuditService(sampleRate float64, retention time.Duration) *QueryAuditService {
+	return &QueryAuditService{
+		sampleRate: sampleRate,
+		retention:  retention,
+		events:     make(chan *models.QueryDiagnostic, queryAuditBuffer),
+		random:     rand.Float64,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// ObserveQuery queues a slow or failing query for recording
+func (s *QueryAuditService) ObserveQuery(ctx context.Context, event database.QueryEvent) {
+	sampleRate := 1.0
+	if event.Err == nil {
+		if s.sampleRate <= 0 || s.random() >= s.sampleRate {
+			return
+		}
+		sampleRate = min(s.sampleRate, 1)
+	}
+
+	text := NormalizeQuery(event.SQL)
+	diagnostic := &models.QueryDiagnostic{
+		Fingerprint: QueryFingerprint(text),
+		QueryText:   truncateRunes(text, queryAuditMaxText),
+		DurationMs:  float64(event.Duration.Microseconds()) / 1000,
+		RowCount:    event.Rows,
+		SampleRate:  sampleRate,
+		CreatedAt:   time.Now(),
+	}
+	rc := common.RequestContextFrom(ctx)
+	if tenantID, ok := rc.Tenant(); ok {
+		diagnostic.TenantID = &tenantID
+	}
+	if userID, ok := rc.User(); ok {
+		diagnostic.UserID = &userID
+	}
+	if rc != nil {
+		diagnostic.RequestID = rc.RequestID
+	}
+	if event.Err != nil {
+		message := truncateRunes(event.Err.Error(), queryAuditMaxError)
+		diagnostic.ErrorMessage = &message
+	}
+
+	select {
+	case s.events <- diagnostic:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many recordings were lost to a full buffer
+func (s *QueryAuditService) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Start writes queued recordings to repo until Stop is called, and purges
+// those older than the retention period. The repository is passed here
+// rather than to the constructor because the service observes the pool the
+// repository is built on
+func (s *QueryAuditService) Start(repo repositories.QueryDiagnosticsRepository) {
+	s.repo = repo
+	go s.run(repo)
+}
+
+// Stop flushes what is queued and waits for the writer to finish
+func (s *QueryAuditService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+func (s *QueryAuditService) run(repo repositories.QueryDiagnosticsRepository) {
+	defer close(s.done)
+	flush := time.NewTicker(queryAuditFlushInterval)
+	defer flush.Stop()
+	purge := time.NewTicker(queryAuditPurgeInterval)
+	defer purge.Stop()
+
+	batch := make([]*models.QueryDiagnostic, 0, queryAuditBatch)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.write(repo, batch); err != nil {
+			log.Printf("Failed to record %d query diagnostics: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case diagnostic := <-s.events:
+			batch = append(batch, diagnostic)
+			if len(batch) == queryAuditBatch {
+				write()
+			}
+		case <-flush.C:
+			write()
+		case <-purge.C:
+			s.purge(repo)
+		case <-s.stop:
+			for {
+				select {
+				case diagnostic := <-s.events:
+					batch = append(batch, diagnostic)
+					if len(batch) == queryAuditBatch {
+						write()
+					}
+				default:
+					write()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *QueryAuditService) write(repo repositories.QueryDiagnosticsRepository, batch []*models.QueryDiagnostic) error {
+	// The audit's own statements are never observed, or a slow insert would
+	// record itself
+	ctx, cancel := context.WithTimeout(database.WithoutQueryTrace(context.Background()), 10*time.Second)
+	defer cancel()
+	return repo.InsertBatch(ctx, batch)
+}
+
+func (s *QueryAuditService) purge(repo repositories.QueryDiagnosticsRepository) {
+	if s.retention <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(database.WithoutQueryTrace(context.Background()), time.Minute)
+	defer cancel()
+	if _, err := repo.DeleteOlderThan(ctx, time.Now().Add(-s.retention)); err != nil {
+		log.Printf("Failed to purge query diagnostics: %v", err)
+	}
+}
+
+// TopOffenders returns the worst queries recorded since filter.Since,
+// optionally for one tenant
+func (s *QueryAuditService) TopOffenders(ctx context.Context, filter models.QueryOffenderFilter) ([]*models.QueryOffender, error) {
+	if s.repo == nil {
+		return nil, fmt.Errorf("query audit is not running")
+	}
+	// The report scans the whole window and would otherwise record itself
+	return s.repo.TopOffenders(database.WithoutQueryTrace(ctx), filter)
+}
+
+// NormalizeQuery collapses whitespace and replaces literals with ?, so
+// queries differing only in inlined values group together and no values
+// are stored
+func NormalizeQuery(sql string) string {
+	normalized := strings.Join(strings.Fields(sql), " ")
+	normalized = queryStringLiteral.ReplaceAllString(normalized, "?")
+	normalized = queryNumberLiteral.ReplaceAllString(normalized, "${1}?")
+	return queryValueList.ReplaceAllString(normalized, "(...)")
+}
+
+// QueryFingerprint identifies a normalized query
+func QueryFingerprint(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
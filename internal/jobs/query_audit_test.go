@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryQueryDiagnosticsRepo struct {
+	mu          sync.Mutex
+	diagnostics []*models.QueryDiagnostic
+}
+
+func (r *memoryQueryDiagnosticsRepo) InsertBatch(ctx context.Context, diagnostics []*models.QueryDiagnostic) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.diagnostics = append(r.diagnostics, diagnostics...)
+	return nil
+}
+
+func (r *memoryQueryDiagnosticsRepo) TopOffenders(ctx context.Context, filter models.QueryOffenderFilter) ([]*models.QueryOffender, error) {
+	return nil, nil
+}
+
+func (r *memoryQueryDiagnosticsRepo) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestNormalizeQueryStripsLiterals(t *testing.T) {
+	normalized := NormalizeQuery(`
+		SELECT id, name FROM products
+		WHERE tenant_id = $1 AND sku = 'SEED-42' AND note <> 'it''s' AND price > 10.5
+			AND warehouse_id IN ($2, $3, $4) AND v2_code = $5
+		LIMIT 20
+	`)
+
+	assert.Equal(t, "SELECT id, name FROM products WHERE tenant_id = $1 AND sku = ? AND note <> ? AND price > ? "+
+		"AND warehouse_id IN (...) AND v2_code = $5 LIMIT ?", normalized)
+	assert.NotContains(t, normalized, "SEED")
+}
+
+func TestQueryFingerprintGroupsInlinedValues(t *testing.T) {
+	first := QueryFingerprint(NormalizeQuery("SELECT * FROM orders WHERE status = 'pending' LIMIT 5"))
+	second := QueryFingerprint(NormalizeQuery("SELECT *  FROM orders\n WHERE status = 'shipped' LIMIT 50"))
+	other := QueryFingerprint(NormalizeQuery("SELECT * FROM invoices WHERE status = 'pending' LIMIT 5"))
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+	assert.Len(t, first, 16)
+}
+
+func TestQueryAuditSamplesSlowQueriesAndKeepsFailures(t *testing.T) {
+	service := NewQueryAuditService(0.25, 0)
+	draws := []float64{0.9, 0.1}
+	service.random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+	tenantID, userID := uuid.New(), uuid.New()
+	ctx := common.WithRequestContext(context.Background(), &common.RequestContext{
+		RequestID: "req-1",
+		TenantID:  tenantID,
+		UserID:    userID,
+	})
+
+	service.ObserveQuery(ctx, database.QueryEvent{SQL: "SELECT 1", Duration: time.Second, Slow: true})
+	service.ObserveQuery(ctx, database.QueryEvent{SQL: "SELECT 2", Duration: 2 * time.Second, Rows: 3, Slow: true})
+	service.ObserveQuery(context.Background(), database.QueryEvent{SQL: "SELECT 3", Duration: time.Millisecond, Err: errors.New("relation does not exist")})
+
+	repo := &memoryQueryDiagnosticsRepo{}
+	service.Start(repo)
+	service.Stop()
+
+	require.Len(t, repo.diagnostics, 2)
+	sampled := repo.diagnostics[0]
+	assert.Equal(t, "SELECT ?", sampled.QueryText)
+	assert.Equal(t, 0.25, sampled.SampleRate)
+	assert.Equal(t, int64(3), sampled.RowCount)
+	assert.Equal(t, 2000.0, sampled.DurationMs)
+	assert.Equal(t, &tenantID, sampled.TenantID)
+	assert.Equal(t, &userID, sampled.UserID)
+	assert.Equal(t, "req-1", sampled.RequestID)
+
+	failed := repo.diagnostics[1]
+	assert.Equal(t, 1.0, failed.SampleRate)
+	assert.Nil(t, failed.TenantID)
+	require.NotNil(t, failed.ErrorMessage)
+	assert.Equal(t, "relation does not exist", *failed.ErrorMessage)
+}
+
+func TestQueryAuditDropsWhenBufferIsFull(t *testing.T) {
+	service := NewQueryAuditService(1, 0)
+	for i := 0; i < queryAuditBuffer+5; i++ {
+		service.ObserveQuery(context.Background(), database.QueryEvent{SQL: "SELECT 1", Slow: true})
+	}
+	assert.Equal(t, uint64(5), service.Dropped())
+}
@@ -0,0 +1,339 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Sandbox demo catalogue; small enough to reset in a few seconds but enough
+// to show every screen with data
+const (
+	sandboxProducts     = 24
+	sandboxWarehouses   = 2
+	sandboxSuppliers    = 3
+	sandboxDistributors = 6
+	sandboxOrders       = 60
+)
+
+var (
+	sandboxCategories   = []string{"Fertilizers", "Pesticides", "Seeds", "Irrigation"}
+	sandboxProductNames = []string{
+		"Urea", "DAP", "NPK 19-19-19", "Zinc Sulphate", "Chlorpyrifos", "Mancozeb",
+		"Neem Oil", "Paddy Seed", "Cotton Seed", "Tomato Seed", "Drip Lateral", "Sprayer Pump",
+	}
+	sandboxBrands    = []string{"Demo Agro", "Sample Crop Care", "Example Seeds"}
+	sandboxCities    = []string{"Guntur", "Nashik", "Indore", "Rajkot", "Karnal", "Nagpur"}
+	sandboxUnits     = []string{"kg", "bag", "litre", "packet", "unit"}
+	sandboxGSTRates  = []float64{0, 5, 12, 18}
+	sandboxCompanies = []string{"Krishi Kendra", "Agro Traders", "Kisan Seva"}
+)
+
+// sandboxDataset is the demo data a sandbox tenant is reset to
+type sandboxDataset struct {
+	Categories   []*models.Category
+	Warehouses   []*models.Warehouse
+	Suppliers    []*models.Supplier
+	Distributors []*models.Distributor
+	Products     []*models.Product
+	Inventory    []*models.Inventory
+	Orders       []*models.Order
+	Invoices     []*models.Invoice
+}
+
+// buildSandboxDataset generates the demo data for a tenant. The same tenant,
+// seed and day always give the same data, IDs included, so docs and
+// walkthroughs can rely on it
+func buildSandboxDataset(tenantID uuid.UUID, seed int64, now time.Time) *sandboxDataset {
+	rng := rand.New(rand.NewSource(seed))
+	// IDs are derived from the tenant as well as the seed, as two sandboxes
+	// on the same seed share primary key space
+	n := 0
+	ids := func() uuid.UUID {
+		n++
+		return uuid.NewSHA1(tenantID, []byte(fmt.Sprintf("sandbox-%d-%d", seed, n)))
+	}
+	pick := func(values []string) string {
+		return values[rng.Intn(len(values))]
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	tag := tenantID.String()[:8]
+
+	ds := &sandboxDataset{}
+	for _, name := range sandboxCategories {
+		ds.Categories = append(ds.Categories, &models.Category{
+			ID:          ids(),
+			TenantID:    tenantID,
+			Name:        name,
+			Description: "Demo category",
+			Path:        name,
+		})
+	}
+	for i := 0; i < sandboxWarehouses; i++ {
+		city := sandboxCities[i%len(sandboxCities)]
+		address := city + ", India"
+		capacity := 10000 * (i + 1)
+		ds.Warehouses = append(ds.Warehouses, &models.Warehouse{
+			ID:       ids(),
+			TenantID: tenantID,
+			Name:     fmt.Sprintf("%s Depot", city),
+			Address:  &address,
+			Capacity: &capacity,
+		})
+	}
+	for i := 0; i < sandboxSuppliers; i++ {
+		// example.com never delivers, in case an address leaks past the stubs
+		email := fmt.Sprintf("supplier%d@example.com", i+1)
+		ds.Suppliers = append(ds.Suppliers, &models.Supplier{
+			ID:           ids(),
+			TenantID:     tenantID,
+			Name:         fmt.Sprintf("%s Supplies %d", sandboxBrands[i%len(sandboxBrands)], i+1),
+			ContactEmail: &email,
+		})
+	}
+	for i := 0; i < sandboxDistributors; i++ {
+		email := fmt.Sprintf("dealer%d@example.com", i+1)
+		address := sandboxCities[i%len(sandboxCities)]
+		ds.Distributors = append(ds.Distributors, &models.Distributor{
+			ID:           ids(),
+			TenantID:     tenantID,
+			Name:         fmt.Sprintf("%s %s", address, pick(sandboxCompanies)),
+			ContactEmail: &email,
+			Address:      &address,
+		})
+	}
+
+	for i := 0; i < sandboxProducts; i++ {
+		categoryID := ds.Categories[rng.Intn(len(ds.Categories))].ID
+		batch := fmt.Sprintf("DEMO-B%03d", i+1)
+		unit := pick(sandboxUnits)
+		expiry := today.AddDate(0, 3+rng.Intn(21), 0)
+		description := "Demo product"
+		product := &models.Product{
+			ID:            ids(),
+			TenantID:      tenantID,
+			CategoryID:    &categoryID,
+			Name:          fmt.Sprintf("%s %s", pick(sandboxBrands), sandboxProductNames[i%len(sandboxProductNames)]),
+			BatchNumber:   &batch,
+			ExpiryDate:    &expiry,
+			UnitPrice:     roundStatementAmount(100 + rng.Float64()*1900),
+			UnitOfMeasure: &unit,
+			Description:   &description,
+		}
+		for _, warehouse := range ds.Warehouses {
+			quantity := 50 + rng.Intn(450)
+			// A few items start low so low-stock alerts have something to show
+			if i%8 == 0 {
+				quantity = rng.Intn(10)
+			}
+			product.Quantity += quantity
+			ds.Inventory = append(ds.Inventory, &models.Inventory{
+				ID:          ids(),
+				TenantID:    tenantID,
+				WarehouseID: warehouse.ID,
+				ProductID:   product.ID,
+				Quantity:    quantity,
+			})
+		}
+		ds.Products = append(ds.Products, product)
+	}
+
+	statuses := []string{"pending", "approved", "processing", "shipped"}
+	for i := 0; i < sandboxOrders; i++ {
+		product := ds.Products[rng.Intn(len(ds.Products))]
+		orderDate := today.AddDate(0, 0, -rng.Intn(90))
+		order := &models.Order{
+			ID:          ids(),
+			TenantID:    tenantID,
+			ProductID:   product.ID,
+			WarehouseID: ds.Warehouses[rng.Intn(len(ds.Warehouses))].ID,
+			Quantity:    1 + rng.Intn(40),
+			OrderDate:   orderDate,
+			Status:      "delivered",
+		}
+		if orderDate.After(today.AddDate(0, 0, -14)) {
+			order.Status = statuses[rng.Intn(len(statuses))]
+		}
+		if i%4 == 0 {
+			supplierID := ds.Suppliers[rng.Intn(len(ds.Suppliers))].ID
+			order.OrderType = "purchase"
+			order.SupplierID = &supplierID
+			order.UnitPrice = roundStatementAmount(product.UnitPrice * 0.8)
+		} else {
+			distributorID := ds.Distributors[rng.Intn(len(ds.Distributors))].ID
+			order.OrderType = "sales"
+			order.DistributorID = &distributorID
+			order.UnitPrice = product.UnitPrice
+		}
+		expected := orderDate.AddDate(0, 0, 3)
+		order.ExpectedDelivery = &expected
+		ds.Orders = append(ds.Orders, order)
+
+		if order.OrderType == "sales" && order.Status == "delivered" {
+			ds.Invoices = append(ds.Invoices, sandboxInvoiceFor(order, ids(), sandboxGSTRates[rng.Intn(len(sandboxGSTRates))],
+				fmt.Sprintf("DEMO-%s-%04d", tag, len(ds.Invoices)+1), today))
+		}
+	}
+	return ds
+}
+
+// sandboxInvoiceFor bills a delivered demo sales order; invoices past their
+// due date are paid so the demo shows both receivables and collections
+func sandboxInvoiceFor(order *models.Order, id uuid.UUID, rate float64, number string, today time.Time) *models.Invoice {
+	taxable := roundStatementAmount(float64(order.Quantity) * order.UnitPrice)
+	halfTax := roundStatementAmount(taxable * rate / 200)
+	issued := *order.ExpectedDelivery
+	due := issued.AddDate(0, 0, 30)
+	invoice := &models.Invoice{
+		ID:            id,
+		TenantID:      order.TenantID,
+		OrderID:       order.ID,
+		InvoiceNumber: number,
+		TaxableAmount: &taxable,
+		GSTRate:       &rate,
+		CGST:          &halfTax,
+		SGST:          &halfTax,
+		TotalAmount:   roundStatementAmount(taxable + 2*halfTax),
+		IssuedDate:    issued,
+		DueDate:       due,
+		Status:        "unpaid",
+	}
+	if due.Before(today) {
+		paid := issued.AddDate(0, 0, 20)
+		invoice.Status = "paid"
+		invoice.PaidDate = &paid
+	}
+	return invoice
+}
+
+// SandboxResetService resets sandbox tenants to the demo dataset
+type SandboxResetService struct {
+	repo            repositories.SandboxRepository
+	categoryRepo    repositories.CategoryRepository
+	productRepo     repositories.ProductRepository
+	warehouseRepo   repositories.WarehouseRepository
+	supplierRepo    repositories.SupplierRepository
+	distributorRepo repositories.DistributorRepository
+	inventoryRepo   repositories.InventoryRepository
+	orderRepo       repositories.OrderRepository
+	invoiceRepo     repositories.InvoiceRepository
+}
+
+func NewSandboxResetService(
+	repo repositories.SandboxRepository,
+	categoryRepo repositories.CategoryRepository,
+	productRepo repositories.ProductRepository,
+	warehouseRepo repositories.WarehouseRepository,
+	supplierRepo repositories.SupplierRepository,
+	distributorRepo repositories.DistributorRepository,
+	inventoryRepo repositories.InventoryRepository,
+	orderRepo repositories.OrderRepository,
+	invoiceRepo repositories.InvoiceRepository,
+) *SandboxResetService {
+	return &SandboxResetService{
+		repo:            repo,
+		categoryRepo:    categoryRepo,
+		productRepo:     productRepo,
+		warehouseRepo:   warehouseRepo,
+		supplierRepo:    supplierRepo,
+		distributorRepo: distributorRepo,
+		inventoryRepo:   inventoryRepo,
+		orderRepo:       orderRepo,
+		invoiceRepo:     invoiceRepo,
+	}
+}
+
+// Reset wipes the sandbox tenant's business data and loads the demo dataset.
+// It refuses tenants that are not sandboxes, so a mistyped ID cannot wipe a
+// real tenant
+func (s *SandboxResetService) Reset(ctx context.Context, tenantID uuid.UUID) (*models.TenantSandbox, error) {
+	sandbox, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if sandbox == nil {
+		return nil, fmt.Errorf("tenant %s is not a sandbox", tenantID)
+	}
+
+	now := time.Now()
+	if err := s.repo.Wipe(ctx, tenantID); err != nil {
+		return nil, fmt.Errorf("failed to wipe sandbox data: %w", err)
+	}
+	if err := s.seed(ctx, buildSandboxDataset(tenantID, sandbox.Seed, now)); err != nil {
+		return nil, fmt.Errorf("failed to seed sandbox data: %w", err)
+	}
+	if err := s.repo.MarkReset(ctx, tenantID, now); err != nil {
+		return nil, fmt.Errorf("failed to record sandbox reset: %w", err)
+	}
+	return s.repo.Get(ctx, tenantID)
+}
+
+func (s *SandboxResetService) seed(ctx context.Context, ds *sandboxDataset) error {
+	for _, category := range ds.Categories {
+		if err := s.categoryRepo.Create(ctx, category); err != nil {
+			return err
+		}
+	}
+	for _, warehouse := range ds.Warehouses {
+		if err := s.warehouseRepo.Create(ctx, warehouse); err != nil {
+			return err
+		}
+	}
+	for _, supplier := range ds.Suppliers {
+		if err := s.supplierRepo.Create(ctx, supplier); err != nil {
+			return err
+		}
+	}
+	for _, distributor := range ds.Distributors {
+		if err := s.distributorRepo.Create(ctx, distributor); err != nil {
+			return err
+		}
+	}
+	for _, product := range ds.Products {
+		if err := s.productRepo.Create(ctx, product); err != nil {
+			return err
+		}
+	}
+	for _, inventory := range ds.Inventory {
+		if err := s.inventoryRepo.Create(ctx, inventory); err != nil {
+			return err
+		}
+	}
+	for _, order := range ds.Orders {
+		if err := s.orderRepo.Create(ctx, order); err != nil {
+			return err
+		}
+	}
+	for _, invoice := range ds.Invoices {
+		if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetDue resets every sandbox whose reset is due and returns how many were
+// reset; a failing sandbox is logged and retried on the next run
+func (s *SandboxResetService) ResetDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.repo.ListDue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	reset := 0
+	for _, sandbox := range due {
+		if _, err := s.Reset(ctx, sandbox.TenantID); err != nil {
+			log.Printf("Failed to reset sandbox tenant %s: %v", sandbox.TenantID, err)
+			continue
+		}
+		reset++
+	}
+	return reset, nil
+}
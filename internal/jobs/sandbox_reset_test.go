@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSandboxDatasetIsReproducible(t *testing.T) {
+	tenantID := uuid.New()
+	now := time.Date(2025, 9, 3, 15, 30, 0, 0, time.UTC)
+
+	first := buildSandboxDataset(tenantID, 42, now)
+	again := buildSandboxDataset(tenantID, 42, now.Add(2*time.Hour))
+	assert.Equal(t, first, again)
+
+	other := buildSandboxDataset(tenantID, 7, now)
+	assert.NotEqual(t, first.Products[0].UnitPrice, other.Products[0].UnitPrice)
+
+	// Another tenant on the same seed gets the same data under its own IDs
+	otherTenant := buildSandboxDataset(uuid.New(), 42, now)
+	assert.Equal(t, first.Products[0].UnitPrice, otherTenant.Products[0].UnitPrice)
+	assert.NotEqual(t, first.Products[0].ID, otherTenant.Products[0].ID)
+	assert.NotEqual(t, first.Invoices[0].InvoiceNumber, otherTenant.Invoices[0].InvoiceNumber)
+}
+
+func TestBuildSandboxDatasetReferencesItsOwnRecords(t *testing.T) {
+	tenantID := uuid.New()
+	now := time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC)
+	ds := buildSandboxDataset(tenantID, 42, now)
+
+	require.Len(t, ds.Products, sandboxProducts)
+	require.Len(t, ds.Orders, sandboxOrders)
+	assert.Len(t, ds.Inventory, sandboxProducts*sandboxWarehouses)
+	assert.NotEmpty(t, ds.Invoices)
+
+	ids := make(map[uuid.UUID]bool)
+	for _, category := range ds.Categories {
+		ids[category.ID] = true
+	}
+	for _, warehouse := range ds.Warehouses {
+		ids[warehouse.ID] = true
+	}
+	for _, supplier := range ds.Suppliers {
+		ids[supplier.ID] = true
+	}
+	for _, distributor := range ds.Distributors {
+		ids[distributor.ID] = true
+	}
+	for _, product := range ds.Products {
+		assert.Equal(t, tenantID, product.TenantID)
+		assert.True(t, ids[*product.CategoryID])
+		ids[product.ID] = true
+	}
+
+	stocked := make(map[uuid.UUID]int)
+	for _, inventory := range ds.Inventory {
+		assert.True(t, ids[inventory.ProductID])
+		assert.True(t, ids[inventory.WarehouseID])
+		stocked[inventory.ProductID] += inventory.Quantity
+	}
+	for _, product := range ds.Products {
+		assert.Equal(t, stocked[product.ID], product.Quantity)
+	}
+
+	orders := make(map[uuid.UUID]bool)
+	for _, order := range ds.Orders {
+		assert.True(t, ids[order.ProductID])
+		assert.True(t, ids[order.WarehouseID])
+		if order.OrderType == "purchase" {
+			assert.True(t, ids[*order.SupplierID])
+		} else {
+			assert.True(t, ids[*order.DistributorID])
+		}
+		orders[order.ID] = true
+	}
+	for _, invoice := range ds.Invoices {
+		assert.True(t, orders[invoice.OrderID])
+		assert.Equal(t, invoice.TotalAmount, roundStatementAmount(*invoice.TaxableAmount+*invoice.CGST+*invoice.SGST))
+		if invoice.Status == "paid" {
+			assert.True(t, invoice.DueDate.Before(now))
+		}
+	}
+}
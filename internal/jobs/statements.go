@@ -0,0 +1,444 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	statementBucket     = "statements"
+	statementLinkExpiry = 7 * 24 * time.Hour
+	statementMaxDays    = 366 * 5
+	statementDateLayout = "2006-01-02"
+	// statementSendDays is how far into a month last month's statements still
+	// go out, covering scheduler downtime without mailing stale statements
+	statementSendDays = 7
+)
+
+// ErrCustomerNotFound is returned for statements and credit notes of an
+// unknown distributor
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// StatementService builds customer statements of account, issues credit notes
+// and emails monthly statements to customers who owe a balance
+type StatementService struct {
+	repo            repositories.StatementRepository
+	distributorRepo repositories.DistributorRepository
+	tenantRepo      repositories.TenantRepository
+	minioService    services.MinioService
+	notificationSvc services.NotificationService
+	calendars       services.TenantCalendarService
+	periods         services.PeriodCloseService
+}
+
+func NewStatementService(
+	repo repositories.StatementRepository,
+	distributorRepo repositories.DistributorRepository,
+	tenantRepo repositories.TenantRepository,
+	minioService services.MinioService,
+	notificationSvc services.NotificationService,
+	calendars services.TenantCalendarService,
+	periods services.PeriodCloseService,
+) *StatementService {
+	return &StatementService{
+		repo:            repo,
+		distributorRepo: distributorRepo,
+		tenantRepo:      tenantRepo,
+		minioService:    minioService,
+		notificationSvc: notificationSvc,
+		calendars:       calendars,
+		periods:         periods,
+	}
+}
+
+// Generate builds the customer's statement for from to to, both inclusive
+func (s *StatementService) Generate(ctx context.Context, tenantID, customerID uuid.UUID, from, to time.Time) (*models.Statement, error) {
+	from, to = statementDay(from), statementDay(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > statementMaxDays*24*time.Hour {
+		return nil, fmt.Errorf("statement period must be at most %d days", statementMaxDays)
+	}
+
+	distributor, err := s.distributorRepo.GetByID(ctx, tenantID, customerID)
+	if err != nil || distributor == nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	opening, err := s.repo.BalanceBefore(ctx, tenantID, customerID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute opening balance: %w", err)
+	}
+	entries, err := s.repo.ListEntries(ctx, tenantID, customerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load statement entries: %w", err)
+	}
+
+	statement := &models.Statement{
+		TenantID:       tenantID,
+		CustomerID:     distributor.ID,
+		CustomerName:   distributor.Name,
+		CustomerGSTIN:  distributor.GSTIN,
+		CustomerEmail:  distributor.ContactEmail,
+		From:           from,
+		To:             to,
+		OpeningBalance: roundStatementAmount(opening),
+		Entries:        entries,
+		GeneratedAt:    time.Now(),
+	}
+	if statement.Entries == nil {
+		statement.Entries = []*models.StatementEntry{}
+	}
+
+	balance := opening
+	for _, entry := range statement.Entries {
+		entry.Description = describeStatementEntry(entry)
+		statement.TotalDebits += entry.Debit
+		statement.TotalCredits += entry.Credit
+		balance += entry.Debit - entry.Credit
+		entry.Balance = roundStatementAmount(balance)
+	}
+	statement.TotalDebits = roundStatementAmount(statement.TotalDebits)
+	statement.TotalCredits = roundStatementAmount(statement.TotalCredits)
+	statement.ClosingBalance = roundStatementAmount(balance)
+	return statement, nil
+}
+
+// RenderPDF prints a statement under the tenant's name
+func (s *StatementService) RenderPDF(ctx context.Context, statement *models.Statement) ([]byte, error) {
+	tenantName := ""
+	if tenant, err := s.tenantRepo.GetByID(ctx, statement.TenantID); err == nil && tenant != nil {
+		tenantName = tenant.Name
+	}
+	return renderStatementPDF(statement, tenantName)
+}
+
+// IssueCreditNote records a credit note for the customer, checking that any
+// invoice it is against belongs to the same customer
+func (s *StatementService) IssueCreditNote(ctx context.Context, tenantID, customerID uuid.UUID, createdBy *uuid.UUID, req *models.CreditNoteRequest) (*models.CreditNote, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if _, err := s.distributorRepo.GetByID(ctx, tenantID, customerID); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	issued := services.TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(time.Now())
+	if req.IssuedDate != nil && *req.IssuedDate != "" {
+		parsed, err := time.Parse(statementDateLayout, *req.IssuedDate)
+		if err != nil {
+			return nil, fmt.Errorf("issued_date must be in YYYY-MM-DD format")
+		}
+		issued = parsed
+	}
+
+	// A credit note against an invoice is booked to the invoice's branch
+	var branchID *uuid.UUID
+	if req.InvoiceID != nil {
+		owner, invoiceBranchID, err := s.repo.InvoiceDistributor(ctx, tenantID, *req.InvoiceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load invoice: %w", err)
+		}
+		if owner == nil || *owner != customerID {
+			return nil, fmt.Errorf("invoice not found for this customer")
+		}
+		branchID = invoiceBranchID
+	}
+	if s.periods != nil {
+		if err := s.periods.CheckOpen(ctx, tenantID, branchID, issued); err != nil {
+			return nil, err
+		}
+	}
+
+	var reason *string
+	if req.Reason != nil && strings.TrimSpace(*req.Reason) != "" {
+		trimmed := strings.TrimSpace(*req.Reason)
+		reason = &trimmed
+	}
+
+	id := uuid.New()
+	note := &models.CreditNote{
+		ID:               id,
+		TenantID:         tenantID,
+		DistributorID:    customerID,
+		InvoiceID:        req.InvoiceID,
+		CreditNoteNumber: fmt.Sprintf("CN-%s-%s", issued.Format("200601"), strings.ToUpper(id.String()[:8])),
+		Amount:           roundStatementAmount(req.Amount),
+		Reason:           reason,
+		IssuedDate:       issued,
+		CreatedBy:        createdBy,
+	}
+	if err := s.repo.CreateCreditNote(ctx, note); err != nil {
+		return nil, fmt.Errorf("failed to record credit note: %w", err)
+	}
+	return note, nil
+}
+
+// ListCreditNotes lists the customer's credit notes issued from from to to
+func (s *StatementService) ListCreditNotes(ctx context.Context, tenantID, customerID uuid.UUID, from, to time.Time) ([]*models.CreditNote, error) {
+	if _, err := s.distributorRepo.GetByID(ctx, tenantID, customerID); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	return s.repo.ListCreditNotes(ctx, tenantID, customerID, statementDay(from), statementDay(to))
+}
+
+// SendMonthlyStatements emails last month's statement, relative to now on the
+// tenant's calendar, to every customer that owed a balance at the end of it and has not been sent
+// it yet, and returns how many were sent. Statements only go out in the first
+// days of the month. The email links to the stored PDF since notifications
+// carry no attachments.
+func (s *StatementService) SendMonthlyStatements(ctx context.Context, tenantID uuid.UUID, now time.Time) (int, error) {
+	today := services.TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(now)
+	periodStart, periodEnd, due := monthlyStatementPeriod(today)
+	if !due {
+		return 0, nil
+	}
+
+	recipients, err := s.repo.PendingRecipients(ctx, tenantID, periodEnd, periodStart)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find customers with outstanding balances: %w", err)
+	}
+	if len(recipients) == 0 {
+		return 0, nil
+	}
+	if err := s.minioService.EnsureBucketExists(ctx, statementBucket); err != nil {
+		return 0, fmt.Errorf("failed to prepare statement storage: %w", err)
+	}
+
+	sent := 0
+	for _, recipient := range recipients {
+		if err := s.sendStatement(ctx, tenantID, recipient, periodStart, periodEnd); err != nil {
+			log.Printf("Failed to send statement to customer %s: %v", recipient.DistributorID, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (s *StatementService) sendStatement(ctx context.Context, tenantID uuid.UUID, recipient *models.StatementRecipient, periodStart, periodEnd time.Time) error {
+	statement, err := s.Generate(ctx, tenantID, recipient.DistributorID, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+	content, err := s.RenderPDF(ctx, statement)
+	if err != nil {
+		return fmt.Errorf("failed to render statement: %w", err)
+	}
+
+	objectKey := fmt.Sprintf("%s/%s/%s.pdf", tenantID.String(), periodStart.Format("2006-01"), recipient.DistributorID.String())
+	if err := s.minioService.UploadObject(ctx, statementBucket, objectKey, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		return fmt.Errorf("failed to store statement: %w", err)
+	}
+	url, err := s.minioService.GetPresignedURL(statementBucket, objectKey, statementLinkExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to presign statement: %w", err)
+	}
+
+	claimed, err := s.repo.ClaimDispatch(ctx, tenantID, recipient.DistributorID, periodStart, statement.ClosingBalance, recipient.Email, objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to record statement dispatch: %w", err)
+	}
+	if !claimed {
+		return fmt.Errorf("statement already sent")
+	}
+
+	subject := fmt.Sprintf("Statement of account for %s", periodStart.Format("January 2006"))
+	body := fmt.Sprintf("Dear %s,\n\nYour statement of account for %s is ready. "+
+		"The balance outstanding as of %s is Rs. %.2f.\n\nDownload your statement (link valid for 7 days):\n%s\n\n"+
+		"Please ignore the balance if you have already paid.",
+		statement.CustomerName, periodStart.Format("January 2006"), periodEnd.Format("02 Jan 2006"), statement.ClosingBalance, url)
+	if err := s.notificationSvc.SendEmail(ctx, tenantID, recipient.Email, subject, body); err != nil {
+		if releaseErr := s.repo.ReleaseDispatch(ctx, tenantID, recipient.DistributorID, periodStart); releaseErr != nil {
+			log.Printf("Failed to release statement dispatch for customer %s: %v", recipient.DistributorID, releaseErr)
+		}
+		return fmt.Errorf("failed to email statement: %w", err)
+	}
+	return nil
+}
+
+func describeStatementEntry(entry *models.StatementEntry) string {
+	switch entry.Type {
+	case models.StatementEntryInvoice:
+		return "Invoice " + entry.Reference
+	case models.StatementEntryPayment:
+		return "Payment received for " + entry.Reference
+	case models.StatementEntryReceipt:
+		if entry.Description != "" {
+			return "Payment received " + entry.Reference + " (" + entry.Description + ")"
+		}
+		return "Payment received " + entry.Reference
+	case models.StatementEntryTDS:
+		if entry.Description != "" {
+			return "TDS u/s " + entry.Description + " deducted from " + entry.Reference
+		}
+		return "TDS deducted from " + entry.Reference
+	case models.StatementEntryInterest:
+		return "Interest on overdue invoice " + entry.Reference
+	case models.StatementEntryCreditNote:
+		if entry.Description != "" {
+			return "Credit note " + entry.Reference + ": " + entry.Description
+		}
+		return "Credit note " + entry.Reference
+	}
+	return entry.Reference
+}
+
+func renderStatementPDF(statement *models.Statement, tenantName string) ([]byte, error) {
+	const (
+		margin    = 15.0
+		rowHeight = 7.0
+	)
+	// Date, Description, Debit, Credit, Balance
+	colWidths := []float64{24, 78, 26, 26, 26}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageWidth, pageHeight := pdf.GetPageSize()
+	contentWidth := pageWidth - 2*margin
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, margin)
+	pdf.AliasNbPages("")
+
+	if tenantName == "" {
+		tenantName = "AgroMart"
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Arial", "", 8)
+		pdf.SetTextColor(108, 117, 125)
+		pdf.CellFormat(contentWidth/2, 6, tr("Generated "+statement.GeneratedAt.Format("02 Jan 2006")), "", 0, "L", false, 0, "")
+		pdf.CellFormat(contentWidth/2, 6, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	tableHeader := func() {
+		pdf.SetFont("Arial", "B", 9)
+		pdf.SetFillColor(240, 240, 240)
+		for i, header := range []string{"Date", "Description", "Debit", "Credit", "Balance"} {
+			align := "R"
+			if i < 2 {
+				align = "L"
+			}
+			pdf.CellFormat(colWidths[i], rowHeight, header, "1", 0, align, true, 0, "")
+		}
+		pdf.Ln(rowHeight)
+		pdf.SetFont("Arial", "", 9)
+	}
+	row := func(date, description, debit, credit, balance string) {
+		if pdf.GetY()+rowHeight > pageHeight-margin-8 {
+			pdf.AddPage()
+			tableHeader()
+		}
+		cells := []string{date, description, debit, credit, balance}
+		for i, cell := range cells {
+			align := "R"
+			if i < 2 {
+				align = "L"
+			}
+			if i == 1 {
+				cell = truncateToWidth(pdf, tr(cell), colWidths[i]-2)
+			}
+			pdf.CellFormat(colWidths[i], rowHeight, cell, "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(rowHeight)
+	}
+
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.SetTextColor(33, 37, 41)
+	pdf.CellFormat(contentWidth, 9, tr(tenantName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(contentWidth, 7, "STATEMENT OF ACCOUNT", "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(contentWidth, 6, tr("Customer: "+statement.CustomerName), "", 1, "L", false, 0, "")
+	if statement.CustomerGSTIN != nil && *statement.CustomerGSTIN != "" {
+		pdf.CellFormat(contentWidth, 6, tr("GSTIN: "+*statement.CustomerGSTIN), "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(contentWidth, 6, fmt.Sprintf("Period: %s to %s",
+		statement.From.Format("02 Jan 2006"), statement.To.Format("02 Jan 2006")), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	tableHeader()
+	row(statement.From.Format("02-01-2006"), "Opening balance", "", "", statementBalance(statement.OpeningBalance))
+	for _, entry := range statement.Entries {
+		row(entry.Date.Format("02-01-2006"), entry.Description, statementAmount(entry.Debit), statementAmount(entry.Credit), statementBalance(entry.Balance))
+	}
+
+	pdf.SetFont("Arial", "B", 9)
+	row("", "Totals", fmt.Sprintf("%.2f", statement.TotalDebits), fmt.Sprintf("%.2f", statement.TotalCredits), "")
+	row(statement.To.Format("02-01-2006"), "Closing balance", "", "", statementBalance(statement.ClosingBalance))
+
+	pdf.Ln(6)
+	pdf.SetFont("Arial", "I", 8)
+	pdf.SetTextColor(108, 117, 125)
+	pdf.MultiCell(contentWidth, 4.5, "Balances marked Dr are owed by the customer; Cr balances are in the customer's favour. "+
+		"Please report any discrepancy within 15 days of receiving this statement.", "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// truncateToWidth shortens text with an ellipsis until it fits width
+func truncateToWidth(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func statementAmount(amount float64) string {
+	if amount == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", amount)
+}
+
+func statementBalance(balance float64) string {
+	if balance < 0 {
+		return fmt.Sprintf("%.2f Cr", -balance)
+	}
+	return fmt.Sprintf("%.2f Dr", balance)
+}
+
+// monthlyStatementPeriod is last month's period as of the tenant's calendar
+// day today; due is false once the first days of the month have passed
+func monthlyStatementPeriod(today time.Time) (periodStart, periodEnd time.Time, due bool) {
+	if today.Day() > statementSendDays {
+		return time.Time{}, time.Time{}, false
+	}
+	periodStart = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	return periodStart, periodStart.AddDate(0, 1, -1), true
+}
+
+// statementDay truncates t to its calendar day
+func statementDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func roundStatementAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
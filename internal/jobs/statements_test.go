@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeStatementEntry(t *testing.T) {
+	assert.Equal(t, "Invoice INV-7", describeStatementEntry(&models.StatementEntry{Type: models.StatementEntryInvoice, Reference: "INV-7"}))
+	assert.Equal(t, "Payment received for INV-7", describeStatementEntry(&models.StatementEntry{Type: models.StatementEntryPayment, Reference: "INV-7"}))
+	assert.Equal(t, "Payment received RCPT-1 (UTR 4471)", describeStatementEntry(&models.StatementEntry{Type: models.StatementEntryReceipt, Reference: "RCPT-1", Description: "UTR 4471"}))
+	assert.Equal(t, "TDS u/s 194Q deducted from RCPT-1", describeStatementEntry(&models.StatementEntry{Type: models.StatementEntryTDS, Reference: "RCPT-1", Description: "194Q"}))
+	assert.Equal(t, "Interest on overdue invoice INV-7", describeStatementEntry(&models.StatementEntry{Type: models.StatementEntryInterest, Reference: "INV-7"}))
+	assert.Equal(t, "Credit note CN-1: Damaged bags", describeStatementEntry(&models.StatementEntry{Type: models.StatementEntryCreditNote, Reference: "CN-1", Description: "Damaged bags"}))
+	assert.Equal(t, "Credit note CN-2", describeStatementEntry(&models.StatementEntry{Type: models.StatementEntryCreditNote, Reference: "CN-2"}))
+}
+
+func TestStatementBalanceFormatting(t *testing.T) {
+	assert.Equal(t, "1250.50 Dr", statementBalance(1250.5))
+	assert.Equal(t, "300.00 Cr", statementBalance(-300))
+	assert.Equal(t, "", statementAmount(0))
+	assert.Equal(t, 10.13, roundStatementAmount(10.125000001))
+}
+
+func TestMonthlyStatementPeriodFollowsTenantCalendar(t *testing.T) {
+	calendar := models.DefaultTenantCalendar(uuid.New())
+	// 20:00 UTC on 31 August is already 1 September in India
+	now := time.Date(2025, 8, 31, 20, 0, 0, 0, time.UTC)
+
+	start, end, due := monthlyStatementPeriod(calendar.Today(now))
+	require.True(t, due)
+	assert.Equal(t, time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 8, 31, 0, 0, 0, 0, time.UTC), end)
+
+	calendar.Timezone = "America/New_York"
+	_, _, due = monthlyStatementPeriod(calendar.Today(now))
+	assert.False(t, due, "still 31 August in New York")
+
+	_, _, due = monthlyStatementPeriod(time.Date(2025, 9, statementSendDays+1, 0, 0, 0, 0, time.UTC))
+	assert.False(t, due)
+}
+
+func TestRenderStatementPDF(t *testing.T) {
+	from := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	statement := &models.Statement{
+		CustomerID:     uuid.New(),
+		CustomerName:   "Green Fields Agro",
+		From:           from,
+		To:             from.AddDate(0, 1, -1),
+		OpeningBalance: 1000,
+		GeneratedAt:    from.AddDate(0, 1, 0),
+	}
+	for i := 0; i < 60; i++ {
+		statement.Entries = append(statement.Entries, &models.StatementEntry{
+			Date:        from.AddDate(0, 0, i%28),
+			Type:        models.StatementEntryInvoice,
+			Description: "Invoice INV-2025-08-0001 with a description long enough to need truncating in the table",
+			Debit:       100,
+			Balance:     1000 + float64(i+1)*100,
+		})
+	}
+
+	content, err := renderStatementPDF(statement, "")
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF")))
+}
@@ -149,7 +149,7 @@ func (e *TallyExporter) ExportOrdersForTenant(ctx context.Context, req ExportReq
 	}
 
 	// Get orders
-	orders, err := e.orderRepo.GetOrdersByTenantAndDateRange(ctx, req.TenantID, startDate, endDate)
+	orders, err := e.orderRepo.GetOrdersByTenantAndDateRange(ctx, req.TenantID, nil, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get orders: %w", err)
 	}
@@ -18,24 +18,42 @@ import (
 type TallyImporter struct {
 	orderRepo   repositories.OrderRepository
 	invoiceRepo repositories.InvoiceRepository
+	syncRepo    repositories.TallySyncRepository
 }
 
+// Conflict policies for imports
+const (
+	ImportConflictSkip  = "skip"  // report and skip conflicting rows (default)
+	ImportConflictAllow = "allow" // report conflicting rows but import them anyway
+)
+
 type ImportRequest struct {
-	TenantID uuid.UUID `json:"tenant_id"`
-	Data     string    `json:"data"`      // CSV content
-	DataType string    `json:"data_type"` // "orders" or "invoices"
+	TenantID       uuid.UUID `json:"tenant_id"`
+	Data           string    `json:"data"`            // CSV content
+	DataType       string    `json:"data_type"`       // "orders" or "invoices"
+	ConflictPolicy string    `json:"conflict_policy"` // "skip" or "allow"
+}
+
+// ImportConflict describes a row that clashes with data already in the system
+type ImportConflict struct {
+	Row        int        `json:"row"`
+	Reason     string     `json:"reason"`
+	ExistingID *uuid.UUID `json:"existing_id,omitempty"`
+	Imported   bool       `json:"imported"`
 }
 
 type ImportResult struct {
 	RecordsProcessed int
 	RecordsImported  int
 	Errors           []string
+	Conflicts        []ImportConflict
 }
 
-func NewTallyImporter(orderRepo repositories.OrderRepository, invoiceRepo repositories.InvoiceRepository) *TallyImporter {
+func NewTallyImporter(orderRepo repositories.OrderRepository, invoiceRepo repositories.InvoiceRepository, syncRepo repositories.TallySyncRepository) *TallyImporter {
 	return &TallyImporter{
 		orderRepo:   orderRepo,
 		invoiceRepo: invoiceRepo,
+		syncRepo:    syncRepo,
 	}
 }
 
@@ -44,6 +62,15 @@ func (i *TallyImporter) ImportData(ctx context.Context, req ImportRequest) (*Imp
 		RecordsProcessed: 0,
 		RecordsImported:  0,
 		Errors:           []string{},
+		Conflicts:        []ImportConflict{},
+	}
+
+	if req.ConflictPolicy == "" {
+		req.ConflictPolicy = ImportConflictSkip
+	}
+	if req.ConflictPolicy != ImportConflictSkip && req.ConflictPolicy != ImportConflictAllow {
+		result.Errors = append(result.Errors, "Invalid conflict_policy: must be 'skip' or 'allow'")
+		return result, nil
 	}
 
 	reader := csv.NewReader(strings.NewReader(req.Data))
@@ -63,9 +90,9 @@ func (i *TallyImporter) ImportData(ctx context.Context, req ImportRequest) (*Imp
 
 	switch req.DataType {
 	case "orders":
-		err = i.importOrders(ctx, req.TenantID, dataRows, result)
+		err = i.importOrders(ctx, req.TenantID, dataRows, req.ConflictPolicy, result)
 	case "invoices":
-		err = i.importInvoices(ctx, req.TenantID, dataRows, result)
+		err = i.importInvoices(ctx, req.TenantID, dataRows, req.ConflictPolicy, result)
 	default:
 		result.Errors = append(result.Errors, "Invalid data_type: must be 'orders' or 'invoices'")
 		return result, nil
@@ -78,7 +105,63 @@ func (i *TallyImporter) ImportData(ctx context.Context, req ImportRequest) (*Imp
 	return result, nil
 }
 
-func (i *TallyImporter) importOrders(ctx context.Context, tenantID uuid.UUID, rows [][]string, result *ImportResult) error {
+// recordConflict notes a conflict and reports whether the row should still be imported
+func recordConflict(result *ImportResult, policy, reason string, existingID *uuid.UUID) bool {
+	imported := policy == ImportConflictAllow
+	result.Conflicts = append(result.Conflicts, ImportConflict{
+		Row:        result.RecordsProcessed,
+		Reason:     reason,
+		ExistingID: existingID,
+		Imported:   imported,
+	})
+	return imported
+}
+
+// orderConflict checks whether an imported order duplicates an existing one
+func (i *TallyImporter) orderConflict(ctx context.Context, tenantID uuid.UUID, order *models.Order) (string, *uuid.UUID, error) {
+	existingID, err := i.syncRepo.FindDuplicateOrder(ctx, tenantID, order)
+	if err != nil {
+		return "", nil, err
+	}
+	if existingID != nil {
+		return "matches an existing order", existingID, nil
+	}
+	return "", nil, nil
+}
+
+// invoiceConflict checks whether an imported invoice duplicates an existing
+// one, or references an order changed here since it was last exported to Tally
+func (i *TallyImporter) invoiceConflict(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) (string, *uuid.UUID, error) {
+	existingID, err := i.syncRepo.FindDuplicateInvoice(ctx, tenantID, invoice)
+	if err != nil {
+		return "", nil, err
+	}
+	if existingID != nil {
+		return "matches an existing invoice", existingID, nil
+	}
+
+	if invoice.OrderID == uuid.Nil {
+		return "", nil, nil
+	}
+	cursor, err := i.syncRepo.GetCursor(ctx, tenantID, models.TallyEntityOrders)
+	if err != nil {
+		return "", nil, err
+	}
+	if cursor.LastExportedAt == nil {
+		return "", nil, nil
+	}
+	updatedAt, err := i.syncRepo.GetOrderUpdatedAt(ctx, tenantID, invoice.OrderID)
+	if err != nil {
+		return "", nil, err
+	}
+	if updatedAt != nil && updatedAt.After(*cursor.LastExportedAt) {
+		orderID := invoice.OrderID
+		return "order was modified after the last Tally export", &orderID, nil
+	}
+	return "", nil, nil
+}
+
+func (i *TallyImporter) importOrders(ctx context.Context, tenantID uuid.UUID, rows [][]string, policy string, result *ImportResult) error {
 	for _, row := range rows {
 		result.RecordsProcessed++
 		if len(row) < 7 {
@@ -92,6 +175,15 @@ func (i *TallyImporter) importOrders(ctx context.Context, tenantID uuid.UUID, ro
 			continue
 		}
 
+		reason, existingID, err := i.orderConflict(ctx, tenantID, order)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: failed to check for conflicts: %v", result.RecordsProcessed, err))
+			continue
+		}
+		if reason != "" && !recordConflict(result, policy, reason, existingID) {
+			continue
+		}
+
 		if err := i.orderRepo.Create(ctx, order); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: failed to save order: %v", result.RecordsProcessed, err))
 			continue
@@ -182,7 +274,7 @@ func (i *TallyImporter) parseOrderRow(tenantID uuid.UUID, row []string) (*models
 	return order, nil
 }
 
-func (i *TallyImporter) importInvoices(ctx context.Context, tenantID uuid.UUID, rows [][]string, result *ImportResult) error {
+func (i *TallyImporter) importInvoices(ctx context.Context, tenantID uuid.UUID, rows [][]string, policy string, result *ImportResult) error {
 	for _, row := range rows {
 		result.RecordsProcessed++
 		if len(row) < 7 {
@@ -196,6 +288,15 @@ func (i *TallyImporter) importInvoices(ctx context.Context, tenantID uuid.UUID,
 			continue
 		}
 
+		reason, existingID, err := i.invoiceConflict(ctx, tenantID, invoice)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: failed to check for conflicts: %v", result.RecordsProcessed, err))
+			continue
+		}
+		if reason != "" && !recordConflict(result, policy, reason, existingID) {
+			continue
+		}
+
 		if err := i.invoiceRepo.Create(ctx, invoice); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: failed to save invoice: %v", result.RecordsProcessed, err))
 			continue
@@ -0,0 +1,275 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	tallyExportBucket = "tally-exports"
+	// tallyExportBatch caps records per run; the remainder goes out next run
+	tallyExportBatch = 5000
+	// tallyWriteLag keeps records written in the last few seconds out of a
+	// batch, so a transaction committing late cannot slip behind the cursor
+	tallyWriteLag         = 5 * time.Second
+	tallyDueTenantsPerRun = 100
+)
+
+// TallySyncService runs incremental Tally exports from per-entity cursors
+type TallySyncService struct {
+	exporter     *TallyExporter
+	importer     *TallyImporter
+	syncRepo     repositories.TallySyncRepository
+	minioService services.MinioService
+}
+
+func NewTallySyncService(exporter *TallyExporter, importer *TallyImporter, syncRepo repositories.TallySyncRepository, minioService services.MinioService) *TallySyncService {
+	return &TallySyncService{
+		exporter:     exporter,
+		importer:     importer,
+		syncRepo:     syncRepo,
+		minioService: minioService,
+	}
+}
+
+func validTallyEntity(entity string) bool {
+	return entity == models.TallyEntityInvoices || entity == models.TallyEntityOrders
+}
+
+// ExportIncremental exports records changed since the entity's cursor and
+// advances the cursor once the file is stored. The run is returned with the
+// CSV content; a run with nothing new has no file.
+func (s *TallySyncService) ExportIncremental(ctx context.Context, tenantID uuid.UUID, entity, trigger string) (*models.TallyExportRun, string, error) {
+	if !validTallyEntity(entity) {
+		return nil, "", fmt.Errorf("entity must be invoices or orders")
+	}
+
+	cursor, err := s.syncRepo.GetCursor(ctx, tenantID, entity)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load sync cursor: %w", err)
+	}
+
+	run := &models.TallyExportRun{
+		ID:         uuid.New(),
+		TenantID:   tenantID,
+		Entity:     entity,
+		Trigger:    trigger,
+		Status:     "running",
+		CursorFrom: cursor.LastExportedAt,
+	}
+	if err := s.syncRepo.CreateRun(ctx, run); err != nil {
+		return nil, "", fmt.Errorf("failed to record export run: %w", err)
+	}
+
+	content, next, exportErr := s.exportBatch(ctx, cursor, run)
+	if exportErr == nil && next != nil {
+		if err := s.syncRepo.SaveCursor(ctx, next); err != nil {
+			exportErr = fmt.Errorf("failed to advance sync cursor: %w", err)
+		}
+	}
+
+	if exportErr != nil {
+		message := exportErr.Error()
+		run.Status = "failed"
+		run.ErrorMessage = &message
+	} else {
+		run.Status = "succeeded"
+	}
+	if err := s.syncRepo.FinishRun(ctx, run); err != nil {
+		return nil, "", fmt.Errorf("failed to record export result: %w", err)
+	}
+	if exportErr != nil {
+		return run, "", exportErr
+	}
+	return run, content, nil
+}
+
+// exportBatch renders and stores the next batch, returning the cursor to save
+// (nil when there was nothing to export)
+func (s *TallySyncService) exportBatch(ctx context.Context, cursor *models.TallySyncCursor, run *models.TallyExportRun) (string, *models.TallySyncCursor, error) {
+	before := time.Now().Add(-tallyWriteLag)
+
+	var content string
+	var lastAt time.Time
+	var lastID uuid.UUID
+	switch run.Entity {
+	case models.TallyEntityInvoices:
+		invoices, err := s.syncRepo.ListInvoicesAfter(ctx, run.TenantID, cursor, before, tallyExportBatch+1)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get invoices: %w", err)
+		}
+		if len(invoices) > tallyExportBatch {
+			invoices = invoices[:tallyExportBatch]
+			run.HasMore = true
+		}
+		if len(invoices) == 0 {
+			return "", nil, nil
+		}
+		if content, err = s.exporter.generateGSTComplianceCSV(invoices); err != nil {
+			return "", nil, fmt.Errorf("failed to generate CSV: %w", err)
+		}
+		last := invoices[len(invoices)-1]
+		lastAt, lastID = last.UpdatedAt, last.ID
+		run.RecordsExported = len(invoices)
+	case models.TallyEntityOrders:
+		orders, err := s.syncRepo.ListOrdersAfter(ctx, run.TenantID, cursor, before, tallyExportBatch+1)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get orders: %w", err)
+		}
+		if len(orders) > tallyExportBatch {
+			orders = orders[:tallyExportBatch]
+			run.HasMore = true
+		}
+		if len(orders) == 0 {
+			return "", nil, nil
+		}
+		if content, err = s.exporter.generateOrderCSV(orders); err != nil {
+			return "", nil, fmt.Errorf("failed to generate CSV: %w", err)
+		}
+		last := orders[len(orders)-1]
+		lastAt, lastID = last.UpdatedAt, last.ID
+		run.RecordsExported = len(orders)
+	}
+
+	fileName := fmt.Sprintf("tally_%s_incremental_%s.csv", run.Entity, run.StartedAt.UTC().Format("20060102T150405"))
+	objectKey := fmt.Sprintf("%s/%s/%s", run.TenantID.String(), run.ID.String(), fileName)
+	if err := s.minioService.EnsureBucketExists(ctx, tallyExportBucket); err != nil {
+		return "", nil, fmt.Errorf("failed to prepare export storage: %w", err)
+	}
+	if err := s.minioService.UploadImage(ctx, tallyExportBucket, objectKey, bytes.NewReader([]byte(content)), int64(len(content))); err != nil {
+		return "", nil, fmt.Errorf("failed to store export: %w", err)
+	}
+	run.FileName = &fileName
+	run.ObjectKey = &objectKey
+	run.CursorTo = &lastAt
+
+	return content, &models.TallySyncCursor{
+		TenantID:       run.TenantID,
+		Entity:         run.Entity,
+		LastExportedAt: &lastAt,
+		LastExportedID: &lastID,
+	}, nil
+}
+
+// ResetCursor makes the next export start from the beginning again
+func (s *TallySyncService) ResetCursor(ctx context.Context, tenantID uuid.UUID, entity string) error {
+	if !validTallyEntity(entity) {
+		return fmt.Errorf("entity must be invoices or orders")
+	}
+	return s.syncRepo.SaveCursor(ctx, &models.TallySyncCursor{TenantID: tenantID, Entity: entity})
+}
+
+// Import runs a CSV import with conflict detection
+func (s *TallySyncService) Import(ctx context.Context, req ImportRequest) (*ImportResult, error) {
+	return s.importer.ImportData(ctx, req)
+}
+
+func (s *TallySyncService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.TallySyncSettings, error) {
+	return s.syncRepo.GetSettings(ctx, tenantID)
+}
+
+// SaveSettings validates and stores the schedule; enabling it schedules the first run immediately
+func (s *TallySyncService) SaveSettings(ctx context.Context, settings *models.TallySyncSettings) error {
+	if settings.IntervalMinutes < 15 {
+		return fmt.Errorf("interval_minutes must be at least 15")
+	}
+	if len(settings.Entities) == 0 {
+		return fmt.Errorf("at least one entity is required")
+	}
+	for _, entity := range settings.Entities {
+		if !validTallyEntity(entity) {
+			return fmt.Errorf("entities must be invoices or orders")
+		}
+	}
+
+	if settings.Enabled && settings.NextRunAt == nil {
+		now := time.Now()
+		settings.NextRunAt = &now
+	}
+	if !settings.Enabled {
+		settings.NextRunAt = nil
+	}
+	return s.syncRepo.SaveSettings(ctx, settings)
+}
+
+// RunScheduled exports every tenant whose scheduled sync is due and returns
+// the number of tenants processed
+func (s *TallySyncService) RunScheduled(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.syncRepo.ListDueSettings(ctx, now, tallyDueTenantsPerRun)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, settings := range due {
+		hasMore := false
+		for _, entity := range settings.Entities {
+			run, _, err := s.ExportIncremental(ctx, settings.TenantID, entity, "scheduled")
+			if err != nil {
+				log.Printf("Scheduled Tally %s export failed for tenant %s: %v", entity, settings.TenantID.String(), err)
+				continue
+			}
+			if run.RecordsExported > 0 {
+				log.Printf("Exported %d %s to Tally for tenant %s", run.RecordsExported, entity, settings.TenantID.String())
+			}
+			hasMore = hasMore || run.HasMore
+		}
+
+		// Catch up on the next tick when a batch was capped
+		next := now.Add(time.Duration(settings.IntervalMinutes) * time.Minute)
+		if hasMore {
+			next = now
+		}
+		if err := s.syncRepo.ScheduleNextRun(ctx, settings.TenantID, next); err != nil {
+			log.Printf("Failed to schedule next Tally sync for tenant %s: %v", settings.TenantID.String(), err)
+		}
+	}
+	return len(due), nil
+}
+
+// SyncStatus reports each entity's cursor, last run and backlog
+func (s *TallySyncService) SyncStatus(ctx context.Context, tenantID uuid.UUID) ([]*models.TallyEntitySyncStatus, error) {
+	var statuses []*models.TallyEntitySyncStatus
+	for _, entity := range []string{models.TallyEntityInvoices, models.TallyEntityOrders} {
+		cursor, err := s.syncRepo.GetCursor(ctx, tenantID, entity)
+		if err != nil {
+			return nil, err
+		}
+		lastRun, err := s.syncRepo.GetLastRun(ctx, tenantID, entity)
+		if err != nil {
+			return nil, err
+		}
+		pending, err := s.syncRepo.CountPending(ctx, tenantID, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		status := &models.TallyEntitySyncStatus{Entity: entity, LastRun: lastRun, PendingRecords: pending}
+		if cursor.LastExportedAt != nil {
+			status.Cursor = cursor
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// DownloadURL returns a short-lived link to a run's export file
+func (s *TallySyncService) DownloadURL(ctx context.Context, tenantID, runID uuid.UUID) (string, error) {
+	run, err := s.syncRepo.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return "", err
+	}
+	if run.ObjectKey == nil {
+		return "", fmt.Errorf("run has no export file")
+	}
+	return s.minioService.GetPresignedURL(tallyExportBucket, *run.ObjectKey, 15*time.Minute)
+}
@@ -0,0 +1,578 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const (
+	takeoutBucket     = "tenant-takeouts"
+	takeoutLinkExpiry = 7 * 24 * time.Hour
+	// takeoutStaleAfter is how long a takeout may stay running before it is
+	// taken to have been cut off by a restart
+	takeoutStaleAfter = 6 * time.Hour
+)
+
+var (
+	// ErrInvalidTakeout wraps takeout request validation failures
+	ErrInvalidTakeout = errors.New("invalid takeout request")
+	// ErrTakeoutInProgress is returned while the tenant has a takeout running
+	ErrTakeoutInProgress = errors.New("a takeout is already running for this tenant")
+)
+
+// takeoutExcludedTables hold keys and sessions rather than the tenant's
+// business data
+var takeoutExcludedTables = map[string]bool{
+	"tenant_data_keys": true,
+	"tokens":           true,
+	"device_tokens":    true,
+	"tenant_takeouts":  true,
+}
+
+// takeoutSecretColumnParts mark columns holding credentials, which are
+// never exported
+var takeoutSecretColumnParts = []string{"password", "secret", "token", "credential", "private_key", "wrapped_key"}
+
+// takeoutDocumentBuckets hold documents stored under the tenant's ID.
+// Generated exports are left out, as the takeout carries their data
+var takeoutDocumentBuckets = []string{
+	invoiceArchiveBucket,
+	statementBucket,
+	catalogImageBucket,
+	"compliance-documents",
+	"expense-attachments",
+	"purchase-invoices",
+	"sales-visit-photos",
+}
+
+// TenantTakeoutService exports all of a tenant's data so it can leave with
+// it: every table holding tenant data as a JSON or CSV file, plus the
+// documents it stored, zipped in the background. Encrypted PII is exported
+// in plaintext; credentials are left out. The requester is emailed when the
+// archive is ready
+type TenantTakeoutService struct {
+	repo            repositories.TenantTakeoutRepository
+	tenantRepo      repositories.TenantRepository
+	userRepo        repositories.UserRepository
+	minioService    services.MinioService
+	notificationSvc services.NotificationService
+	keyring         *pii.DataKeyRing
+}
+
+func NewTenantTakeoutService(
+	repo repositories.TenantTakeoutRepository,
+	tenantRepo repositories.TenantRepository,
+	userRepo repositories.UserRepository,
+	minioService services.MinioService,
+	notificationSvc services.NotificationService,
+	keyring *pii.DataKeyRing,
+) *TenantTakeoutService {
+	return &TenantTakeoutService{
+		repo:            repo,
+		tenantRepo:      tenantRepo,
+		userRepo:        userRepo,
+		minioService:    minioService,
+		notificationSvc: notificationSvc,
+		keyring:         keyring,
+	}
+}
+
+// takeoutManifest describes the archive's contents
+type takeoutManifest struct {
+	TenantID    uuid.UUID                 `json:"tenant_id"`
+	TenantName  string                    `json:"tenant_name"`
+	Format      string                    `json:"format"`
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Tables      []takeoutManifestTable    `json:"tables"`
+	Documents   []takeoutManifestDocument `json:"documents"`
+}
+
+type takeoutManifestTable struct {
+	Name    string   `json:"name"`
+	File    string   `json:"file"`
+	Columns []string `json:"columns"`
+	Rows    int      `json:"rows"`
+}
+
+type takeoutManifestDocument struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	File      string `json:"file"`
+	SizeBytes int    `json:"size_bytes"`
+}
+
+// Request validates the request, records the takeout and builds it in the
+// background; poll Get for progress and the download link
+func (s *TenantTakeoutService) Request(ctx context.Context, tenantID uuid.UUID, requestedBy *uuid.UUID, req *models.TenantTakeoutRequest) (*models.TenantTakeout, error) {
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = models.TakeoutFormatJSON
+	}
+	if format != models.TakeoutFormatJSON && format != models.TakeoutFormatCSV {
+		return nil, fmt.Errorf("%w: format must be json or csv", ErrInvalidTakeout)
+	}
+	includeDocuments := req.IncludeDocuments == nil || *req.IncludeDocuments
+
+	if err := s.repo.FailStale(ctx, tenantID, time.Now().Add(-takeoutStaleAfter)); err != nil {
+		return nil, fmt.Errorf("failed to check running takeouts: %w", err)
+	}
+	takeout := &models.TenantTakeout{
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		Format:           format,
+		IncludeDocuments: includeDocuments,
+		Status:           models.TenantTakeoutRunning,
+		RequestedBy:      requestedBy,
+	}
+	created, err := s.repo.Create(ctx, takeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record takeout: %w", err)
+	}
+	if !created {
+		return nil, ErrTakeoutInProgress
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Panic in tenant takeout %s: %v", takeout.ID, r)
+			}
+		}()
+		s.run(context.WithoutCancel(ctx), takeout)
+	}()
+
+	return takeout, nil
+}
+
+// Get returns a takeout with its progress and, once it has succeeded, a
+// download link
+func (s *TenantTakeoutService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.TenantTakeout, error) {
+	takeout, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.decorate(takeout)
+	return takeout, nil
+}
+
+// List returns the tenant's takeouts, newest first
+func (s *TenantTakeoutService) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.TenantTakeout, error) {
+	takeouts, err := s.repo.List(ctx, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, takeout := range takeouts {
+		s.decorate(takeout)
+	}
+	return takeouts, nil
+}
+
+func (s *TenantTakeoutService) decorate(takeout *models.TenantTakeout) {
+	if takeout.EntitiesTotal > 0 {
+		takeout.Progress = float64(takeout.EntitiesDone) / float64(takeout.EntitiesTotal) * 100
+	}
+	if takeout.Status != models.TenantTakeoutSucceeded || takeout.ObjectKey == nil {
+		return
+	}
+	takeout.Progress = 100
+	url, err := s.minioService.GetPresignedURL(takeoutBucket, *takeout.ObjectKey, takeoutLinkExpiry)
+	if err != nil {
+		log.Printf("Failed to presign tenant takeout %s: %v", takeout.ID, err)
+		return
+	}
+	takeout.DownloadURL = &url
+}
+
+func (s *TenantTakeoutService) run(ctx context.Context, takeout *models.TenantTakeout) {
+	if err := s.export(ctx, takeout); err != nil {
+		message := err.Error()
+		takeout.Status = models.TenantTakeoutFailed
+		takeout.ErrorMessage = &message
+	} else {
+		takeout.Status = models.TenantTakeoutSucceeded
+	}
+
+	if err := s.repo.Finish(ctx, takeout); err != nil {
+		log.Printf("Failed to record tenant takeout %s result: %v", takeout.ID, err)
+	}
+	if err := s.notify(ctx, takeout); err != nil {
+		log.Printf("Failed to notify requester of tenant takeout %s: %v", takeout.ID, err)
+	}
+}
+
+func (s *TenantTakeoutService) export(ctx context.Context, takeout *models.TenantTakeout) error {
+	tenant, err := s.tenantRepo.GetByID(ctx, takeout.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant: %w", err)
+	}
+	tables, err := s.repo.Tables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	var exported []*models.TakeoutTable
+	for _, table := range tables {
+		if !takeoutExcludedTables[table.Name] {
+			exported = append(exported, table)
+		}
+	}
+	var buckets []string
+	if takeout.IncludeDocuments {
+		buckets = takeoutDocumentBuckets
+	}
+	takeout.EntitiesTotal = len(exported) + len(buckets)
+	s.updateProgress(ctx, takeout)
+
+	file, err := os.CreateTemp("", "takeout-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	manifest := &takeoutManifest{
+		TenantID:    takeout.TenantID,
+		TenantName:  tenant.Name,
+		Format:      takeout.Format,
+		GeneratedAt: takeout.StartedAt.UTC(),
+		Tables:      []takeoutManifestTable{},
+		Documents:   []takeoutManifestDocument{},
+	}
+	for _, table := range exported {
+		entry, err := s.writeTable(ctx, archive, takeout, table)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", table.Name, err)
+		}
+		manifest.Tables = append(manifest.Tables, *entry)
+		takeout.RowsExported += int64(entry.Rows)
+		takeout.EntitiesDone++
+		s.updateProgress(ctx, takeout)
+	}
+	for _, bucket := range buckets {
+		documents, err := s.writeDocuments(ctx, archive, takeout.TenantID, bucket)
+		if err != nil {
+			return fmt.Errorf("failed to export documents from %s: %w", bucket, err)
+		}
+		manifest.Documents = append(manifest.Documents, documents...)
+		takeout.DocumentsExported += len(documents)
+		takeout.EntitiesDone++
+		s.updateProgress(ctx, takeout)
+	}
+
+	w, err := archive.Create("manifest.json")
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to size archive: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	fileName := fmt.Sprintf("%s-takeout-%s.zip", tenant.Subdomain, takeout.StartedAt.UTC().Format("20060102-150405"))
+	objectKey := fmt.Sprintf("%s/%s/%s", takeout.TenantID.String(), takeout.ID.String(), fileName)
+	if err := s.minioService.EnsureBucketExists(ctx, takeoutBucket); err != nil {
+		return fmt.Errorf("failed to prepare takeout storage: %w", err)
+	}
+	if err := s.minioService.UploadObject(ctx, takeoutBucket, objectKey, file, size, "application/zip"); err != nil {
+		return fmt.Errorf("failed to store archive: %w", err)
+	}
+	takeout.FileName = &fileName
+	takeout.ObjectKey = &objectKey
+	takeout.SizeBytes = &size
+	return nil
+}
+
+func (s *TenantTakeoutService) updateProgress(ctx context.Context, takeout *models.TenantTakeout) {
+	if err := s.repo.UpdateProgress(ctx, takeout); err != nil {
+		log.Printf("Failed to update tenant takeout %s progress: %v", takeout.ID, err)
+	}
+}
+
+// writeTable writes the tenant's rows of a table to data/<table>.<format>,
+// decrypting sealed PII on the way
+func (s *TenantTakeoutService) writeTable(ctx context.Context, archive *zip.Writer, takeout *models.TenantTakeout, table *models.TakeoutTable) (*takeoutManifestTable, error) {
+	columns := takeoutColumns(table.Columns)
+	entry := &takeoutManifestTable{
+		Name:    table.Name,
+		File:    "data/" + table.Name + "." + takeout.Format,
+		Columns: columns,
+	}
+	w, err := archive.Create(entry.File)
+	if err != nil {
+		return nil, err
+	}
+	out, err := newTakeoutWriter(takeout.Format, w, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.ExportRows(ctx, takeout.TenantID, table, columns, func(data []byte) error {
+		row, err := decodeTakeoutRow(data)
+		if err != nil {
+			return err
+		}
+		for name, value := range row {
+			sealed, ok := value.(string)
+			if !ok || !pii.IsSealed(sealed) {
+				continue
+			}
+			plain, err := s.keyring.Decrypt(ctx, takeout.TenantID, &sealed)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", name, err)
+			}
+			row[name] = *plain
+		}
+		entry.Rows++
+		return out.WriteRow(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, out.Close()
+}
+
+// writeDocuments copies the tenant's objects in a bucket to
+// documents/<bucket>/
+func (s *TenantTakeoutService) writeDocuments(ctx context.Context, archive *zip.Writer, tenantID uuid.UUID, bucket string) ([]takeoutManifestDocument, error) {
+	if err := s.minioService.EnsureBucketExists(ctx, bucket); err != nil {
+		return nil, err
+	}
+	keys, err := s.minioService.ListObjects(ctx, bucket, tenantID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]takeoutManifestDocument, 0, len(keys))
+	for _, key := range keys {
+		data, err := s.minioService.GetObject(ctx, bucket, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		document := takeoutManifestDocument{
+			Bucket:    bucket,
+			Key:       key,
+			File:      takeoutDocumentPath(bucket, key, tenantID),
+			SizeBytes: len(data),
+		}
+		w, err := archive.Create(document.File)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func (s *TenantTakeoutService) notify(ctx context.Context, takeout *models.TenantTakeout) error {
+	if takeout.RequestedBy == nil {
+		return nil
+	}
+	user, err := s.userRepo.GetByID(ctx, takeout.TenantID, *takeout.RequestedBy)
+	if err != nil {
+		return fmt.Errorf("failed to load requester: %w", err)
+	}
+	if user == nil || user.Email == "" {
+		return nil
+	}
+
+	var subject, body string
+	if takeout.Status == models.TenantTakeoutSucceeded {
+		url, err := s.minioService.GetPresignedURL(takeoutBucket, *takeout.ObjectKey, takeoutLinkExpiry)
+		if err != nil {
+			return fmt.Errorf("failed to presign archive: %w", err)
+		}
+		subject = "Your data export is ready"
+		body = fmt.Sprintf("The export of all your data you requested on %s is ready: %d records and %d documents.\n\n"+
+			"Download it (link valid for 7 days):\n%s\n\n"+
+			"A fresh link can be fetched from GET /v1/tenants/%s/takeouts/%s.",
+			takeout.StartedAt.Format("02 Jan 2006 15:04 MST"), takeout.RowsExported, takeout.DocumentsExported, url, takeout.TenantID, takeout.ID)
+	} else {
+		subject = "Your data export failed"
+		body = fmt.Sprintf("The export of all your data you requested on %s could not be completed: %s\n\n"+
+			"Please request a new export to try again.",
+			takeout.StartedAt.Format("02 Jan 2006 15:04 MST"), *takeout.ErrorMessage)
+	}
+	return s.notificationSvc.SendEmail(ctx, takeout.TenantID, user.Email, subject, body)
+}
+
+// takeoutColumns drops credential columns and the blind indexes kept next
+// to encrypted PII
+func takeoutColumns(columns []string) []string {
+	kept := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !takeoutSecretColumn(column) {
+			kept = append(kept, column)
+		}
+	}
+	return kept
+}
+
+func takeoutSecretColumn(column string) bool {
+	name := strings.ToLower(column)
+	if strings.HasSuffix(name, "_bidx") {
+		return true
+	}
+	for _, part := range takeoutSecretColumnParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// takeoutDocumentPath places an object under documents/<bucket>/ with the
+// tenant prefix of its key removed
+func takeoutDocumentPath(bucket, key string, tenantID uuid.UUID) string {
+	name := strings.TrimPrefix(key, tenantID.String())
+	name = strings.TrimLeft(name, "/-")
+	if name == "" {
+		name = key
+	}
+	return "documents/" + bucket + "/" + name
+}
+
+// decodeTakeoutRow decodes a row exported by the database, keeping numbers
+// exactly as written
+func decodeTakeoutRow(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var row map[string]interface{}
+	if err := dec.Decode(&row); err != nil {
+		return nil, fmt.Errorf("failed to decode row: %w", err)
+	}
+	return row, nil
+}
+
+// takeoutWriter writes the rows of one table in the takeout's format
+type takeoutWriter interface {
+	WriteRow(row map[string]interface{}) error
+	Close() error
+}
+
+func newTakeoutWriter(format string, w io.Writer, columns []string) (takeoutWriter, error) {
+	if format == models.TakeoutFormatCSV {
+		out := csv.NewWriter(w)
+		if err := out.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvTakeoutWriter{w: out, columns: columns}, nil
+	}
+	return &jsonTakeoutWriter{w: w, columns: columns}, nil
+}
+
+// jsonTakeoutWriter writes a JSON array of objects with their keys in column
+// order
+type jsonTakeoutWriter struct {
+	w       io.Writer
+	columns []string
+	rows    int
+}
+
+func (t *jsonTakeoutWriter) WriteRow(row map[string]interface{}) error {
+	var buf bytes.Buffer
+	if t.rows == 0 {
+		buf.WriteString("[\n  {")
+	} else {
+		buf.WriteString(",\n  {")
+	}
+	for i, column := range t.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(row[column])
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	t.rows++
+	_, err := t.w.Write(buf.Bytes())
+	return err
+}
+
+func (t *jsonTakeoutWriter) Close() error {
+	closing := "\n]\n"
+	if t.rows == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(t.w, closing)
+	return err
+}
+
+// csvTakeoutWriter writes a header of column names and one line per row.
+// Nested JSON values are written as compact JSON and NULL as an empty field
+type csvTakeoutWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (t *csvTakeoutWriter) WriteRow(row map[string]interface{}) error {
+	record := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		value, err := takeoutCSVValue(row[column])
+		if err != nil {
+			return err
+		}
+		record[i] = value
+	}
+	return t.w.Write(record)
+}
+
+func (t *csvTakeoutWriter) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+func takeoutCSVValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
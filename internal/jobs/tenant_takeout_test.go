@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTakeoutRepo struct {
+	repositories.TenantTakeoutRepository
+	rows    map[string][]string
+	columns map[string][]string
+}
+
+func (r *fakeTakeoutRepo) ExportRows(ctx context.Context, tenantID uuid.UUID, table *models.TakeoutTable, columns []string, fn func(row []byte) error) error {
+	r.columns[table.Name] = columns
+	for _, row := range r.rows[table.Name] {
+		if err := fn([]byte(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipEntry(t *testing.T, data []byte, name string) []byte {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	file, err := reader.Open(name)
+	require.NoError(t, err)
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	return content
+}
+
+func TestTakeoutColumnsDropCredentials(t *testing.T) {
+	columns := []string{"id", "tenant_id", "email", "password_hash", "contact_email_bidx", "signing_secret", "credentials", "token_prefix", "gstin"}
+	assert.Equal(t, []string{"id", "tenant_id", "email", "gstin"}, takeoutColumns(columns))
+}
+
+func TestTakeoutDocumentPath(t *testing.T) {
+	tenantID := uuid.New()
+	assert.Equal(t, "documents/product-images/p1/front.jpg", takeoutDocumentPath("product-images", tenantID.String()+"/p1/front.jpg", tenantID))
+	assert.Equal(t, "documents/invoices/inv-abc.pdf", takeoutDocumentPath("invoices", tenantID.String()+"-inv-abc.pdf", tenantID))
+}
+
+func TestTakeoutWriteTableDecryptsPII(t *testing.T) {
+	keys, ring := newRotationTest(t)
+	ctx := context.Background()
+	email := "ramesh@example.com"
+	sealed, err := ring.Encrypt(ctx, keys.tenantID, &email)
+	require.NoError(t, err)
+
+	row, err := json.Marshal(map[string]interface{}{"id": "d1", "name": "Ramesh Agro", "contact_email": *sealed, "credit_limit": 125000.50})
+	require.NoError(t, err)
+	repo := &fakeTakeoutRepo{rows: map[string][]string{"distributors": {string(row)}}, columns: map[string][]string{}}
+	service := &TenantTakeoutService{repo: repo, keyring: ring}
+	table := &models.TakeoutTable{
+		Name:         "distributors",
+		Columns:      []string{"id", "name", "contact_email", "contact_email_bidx", "credit_limit"},
+		TenantColumn: "tenant_id",
+	}
+
+	for _, format := range []string{models.TakeoutFormatJSON, models.TakeoutFormatCSV} {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		takeout := &models.TenantTakeout{TenantID: keys.tenantID, Format: format, StartedAt: time.Now()}
+		entry, err := service.writeTable(ctx, archive, takeout, table)
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+
+		assert.Equal(t, 1, entry.Rows)
+		assert.Equal(t, []string{"id", "name", "contact_email", "credit_limit"}, repo.columns["distributors"])
+		content := readZipEntry(t, buf.Bytes(), "data/distributors."+format)
+
+		if format == models.TakeoutFormatJSON {
+			assert.Contains(t, string(content), `{"id":"d1","name":"Ramesh Agro","contact_email":"ramesh@example.com","credit_limit":125000.5}`)
+			var rows []map[string]interface{}
+			require.NoError(t, json.Unmarshal(content, &rows))
+			assert.Len(t, rows, 1)
+		} else {
+			records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+			require.NoError(t, err)
+			assert.Equal(t, [][]string{
+				{"id", "name", "contact_email", "credit_limit"},
+				{"d1", "Ramesh Agro", "ramesh@example.com", "125000.5"},
+			}, records)
+		}
+	}
+}
+
+func TestTakeoutJSONWriterEmptyTable(t *testing.T) {
+	var buf bytes.Buffer
+	out, err := newTakeoutWriter(models.TakeoutFormatJSON, &buf, []string{"id"})
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestTakeoutCSVValue(t *testing.T) {
+	for _, tc := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, ""},
+		{"Urea", "Urea"},
+		{json.Number("12.50"), "12.50"},
+		{true, "true"},
+		{map[string]interface{}{"kg": json.Number("45")}, `{"kg":45}`},
+	} {
+		value, err := takeoutCSVValue(tc.value)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, value)
+	}
+}
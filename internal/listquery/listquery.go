@@ -0,0 +1,196 @@
+// Package listquery parses the query contract shared by list endpoints:
+//
+//	page[size]=25          page size, 1..MaxSize; limit= is accepted as an alias
+//	page[cursor]=<opaque>  resume from a previous response's next_cursor; offset= is accepted as an alias
+//	sort=-created_at,name  comma separated fields from the endpoint's whitelist, "-" for descending
+//
+// Invalid values are rejected rather than silently replaced with defaults.
+package listquery
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSize = 10
+	defaultMax  = 100
+)
+
+// ErrInvalidQuery wraps every parse failure so callers can answer 400
+var ErrInvalidQuery = errors.New("invalid list query")
+
+// Options describe one endpoint's paging limits and sortable fields
+type Options struct {
+	// DefaultSize applies when the request names no size; 10 when zero
+	DefaultSize int
+	// MaxSize caps page[size]; 100 when zero
+	MaxSize int
+	// Sorts maps each sortable API field to its SQL column. A nil map makes
+	// the endpoint unsortable and rejects any sort parameter
+	Sorts map[string]string
+	// DefaultSort applies when the request has no sort, in the same syntax
+	DefaultSort string
+}
+
+// SortField is one validated sort key
+type SortField struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Page is a parsed list query
+type Page struct {
+	Limit  int
+	Offset int
+	Sort   []SortField
+}
+
+// Parse reads the list query from values under opts
+func Parse(values url.Values, opts Options) (Page, error) {
+	if opts.DefaultSize <= 0 {
+		opts.DefaultSize = defaultSize
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMax
+	}
+
+	page := Page{Limit: opts.DefaultSize}
+
+	if raw, name := first(values, "page[size]", "limit"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 || size > opts.MaxSize {
+			return Page{}, fmt.Errorf("%w: %s must be between 1 and %d", ErrInvalidQuery, name, opts.MaxSize)
+		}
+		page.Limit = size
+	}
+
+	if raw := values.Get("page[cursor]"); raw != "" {
+		offset, err := decodeCursor(raw)
+		if err != nil {
+			return Page{}, fmt.Errorf("%w: page[cursor] is not a valid cursor", ErrInvalidQuery)
+		}
+		page.Offset = offset
+	} else if raw := values.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return Page{}, fmt.Errorf("%w: offset must be a non-negative integer", ErrInvalidQuery)
+		}
+		page.Offset = offset
+	}
+
+	rawSort := values.Get("sort")
+	if rawSort == "" {
+		rawSort = opts.DefaultSort
+	}
+	if rawSort != "" {
+		fields, err := parseSort(rawSort, opts.Sorts)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Sort = fields
+	}
+
+	return page, nil
+}
+
+// NextCursor returns the cursor for the page after this one, or "" when a
+// page of returned items shows there is nothing more to read
+func (p Page) NextCursor(returned int) string {
+	if returned < p.Limit {
+		return ""
+	}
+	return encodeCursor(p.Offset + returned)
+}
+
+// OrderBy renders the sort as an SQL ORDER BY list, or fallback when the
+// request and endpoint set none. Columns come from the whitelist, never
+// from the request, so the result is safe to interpolate
+func (p Page) OrderBy(fallback string) string {
+	if len(p.Sort) == 0 {
+		return fallback
+	}
+	parts := make([]string, len(p.Sort))
+	for i, s := range p.Sort {
+		parts[i] = s.Column + " ASC"
+		if s.Desc {
+			parts[i] = s.Column + " DESC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseSort(raw string, allowed map[string]string) ([]SortField, error) {
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		column, ok := allowed[field.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot sort by '%s'%s", ErrInvalidQuery, field.Field, sortHint(allowed))
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("%w: '%s' appears more than once in sort", ErrInvalidQuery, field.Field)
+		}
+		seen[field.Field] = true
+		field.Column = column
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func sortHint(allowed map[string]string) string {
+	if len(allowed) == 0 {
+		return "; this list is not sortable"
+	}
+	names := make([]string, 0, len(allowed))
+	for name := range allowed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "; sortable fields: " + strings.Join(names, ", ")
+}
+
+// first returns the first of names present in values, with the name used
+func first(values url.Values, names ...string) (string, string) {
+	for _, name := range names {
+		if v := values.Get(name); v != "" {
+			return v, name
+		}
+	}
+	return "", ""
+}
+
+// Cursors are opaque to clients; today they carry an offset, which leaves
+// room to switch an endpoint to keyset paging without changing the contract
+const cursorPrefix = "o:"
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, errors.New("unknown cursor format")
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor offset")
+	}
+	return offset, nil
+}
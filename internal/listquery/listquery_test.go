@@ -0,0 +1,68 @@
+package listquery
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{
+	Sorts:       map[string]string{"name": "name", "created_at": "created_at"},
+	DefaultSort: "-created_at",
+}
+
+func TestParseDefaults(t *testing.T) {
+	page, err := Parse(url.Values{}, testOptions)
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, page.Limit)
+	assert.Equal(t, 0, page.Offset)
+	assert.Equal(t, "created_at DESC", page.OrderBy("id"))
+}
+
+func TestParseSizeCursorAndSort(t *testing.T) {
+	first, err := Parse(url.Values{"page[size]": {"2"}, "sort": {"name,-created_at"}}, testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, 2, first.Limit)
+	assert.Equal(t, "name ASC, created_at DESC", first.OrderBy("id"))
+
+	cursor := first.NextCursor(2)
+	require.NotEmpty(t, cursor)
+	assert.Empty(t, first.NextCursor(1))
+
+	second, err := Parse(url.Values{"page[size]": {"2"}, "page[cursor]": {cursor}}, testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Offset)
+}
+
+func TestParseLegacyAliases(t *testing.T) {
+	page, err := Parse(url.Values{"limit": {"25"}, "offset": {"50"}}, Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 25, page.Limit)
+	assert.Equal(t, 50, page.Offset)
+	assert.Equal(t, "id", page.OrderBy("id"))
+}
+
+func TestParseRejectsInvalidValues(t *testing.T) {
+	cases := map[string]url.Values{
+		"size too large":    {"page[size]": {"101"}},
+		"size not a number": {"limit": {"ten"}},
+		"negative offset":   {"offset": {"-1"}},
+		"garbled cursor":    {"page[cursor]": {"not-a-cursor"}},
+		"unknown sort":      {"sort": {"price"}},
+		"repeated sort":     {"sort": {"name,-name"}},
+	}
+	for name, values := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(values, testOptions)
+			assert.True(t, errors.Is(err, ErrInvalidQuery), "got %v", err)
+		})
+	}
+
+	_, err := Parse(url.Values{"sort": {"name"}}, Options{})
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
@@ -34,13 +34,13 @@ func (m *AuditMiddleware) AuditRequest(sensitivityLevel string) echo.MiddlewareF
 			err := next(c)
 
 			ctx := c.Request().Context()
-			tenantID, ok := common.GetTenantIDFromContext(ctx)
+			tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 			if !ok {
 				// Skip auditing if no tenant context
 				return err
 			}
 
-			userID, ok := common.GetUserIDFromContext(ctx)
+			userID, ok := common.RequestContextFrom(ctx).User()
 			var userPtr *uuid.UUID
 			if ok {
 				userPtr = &userID
@@ -62,6 +62,51 @@ func (m *AuditMiddleware) AuditRequest(sensitivityLevel string) echo.MiddlewareF
 	}
 }
 
+// AuditImpersonatedRequests logs every request made with an impersonation token,
+// recording both the impersonated user and the real actor
+func (m *AuditMiddleware) AuditImpersonatedRequests() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			ctx := c.Request().Context()
+			impersonatorID, ok := common.RequestContextFrom(ctx).Impersonator()
+			if !ok {
+				return err
+			}
+			tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+			if !ok {
+				return err
+			}
+
+			var userPtr *uuid.UUID
+			if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+				userPtr = &userID
+			}
+
+			method := c.Request().Method
+			path := c.Path()
+			data := map[string]interface{}{
+				"method":          method,
+				"path":            path,
+				"impersonator_id": impersonatorID.String(),
+				"user_agent":      c.Request().UserAgent(),
+				"ip":              c.RealIP(),
+				"timestamp":       time.Now().Format(time.RFC3339),
+			}
+			if err != nil {
+				data["error"] = err.Error()
+			}
+
+			if logErr := m.auditService.LogActivity(ctx, tenantID, "impersonated_requests", path, method+" "+path, userPtr, nil, data); logErr != nil {
+				c.Logger().Errorf("Failed to log impersonated request: %v", logErr)
+			}
+
+			return err
+		}
+	}
+}
+
 // auditLowSensitivity logs only critical operations and errors
 func (m *AuditMiddleware) auditLowSensitivity(c echo.Context, tenantID uuid.UUID, userID *uuid.UUID, reqErr error) {
 	method := c.Request().Method
@@ -357,7 +402,7 @@ func (m *AuditMiddleware) AuditDecorator(operation string, getEntity func() (int
 		// For now, use background context
 		ctx := context.Background()
 
-		tenantID, ok := common.GetTenantIDFromContext(ctx)
+		tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 		if !ok {
 			// Can't audit without tenant context
 			entity, err := getEntity()
@@ -368,7 +413,7 @@ func (m *AuditMiddleware) AuditDecorator(operation string, getEntity func() (int
 			return nil
 		}
 
-		userID, _ := common.GetUserIDFromContext(ctx)
+		userID, _ := common.RequestContextFrom(ctx).User()
 		var userPtr *uuid.UUID
 		if userID != uuid.Nil {
 			userPtr = &userID
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Cookie auth mode keeps a browser session's tokens in httpOnly cookies, out
+// of reach of scripts, instead of handing them to the client to store. Since
+// browsers attach cookies to cross-site requests too, requests authenticated
+// by cookie must echo the CSRF cookie in the CSRF header
+const (
+	AccessTokenCookie  = "agromart_access_token"
+	RefreshTokenCookie = "agromart_refresh_token"
+	// CSRFCookie is readable by scripts so the dashboard can copy it into CSRFHeader
+	CSRFCookie = "agromart_csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+	// ClientTypeHeader selects the auth mode at login, e.g. "web" or "mobile"
+	ClientTypeHeader = "X-Client-Type"
+)
+
+// refreshCookiePath limits the refresh cookie to the endpoints that use it
+const refreshCookiePath = "/v1/auth"
+
+// csrfExemptPaths start a session rather than act within one
+var csrfExemptPaths = map[string]bool{
+	"/v1/auth/login":  true,
+	"/v1/auth/signup": true,
+}
+
+// CookieAuthConfig selects which client types use cookie auth and how the
+// cookies are set
+type CookieAuthConfig struct {
+	// ClientTypes get their tokens as cookies; every other client gets them in
+	// the response body and sends the access token as a bearer token
+	ClientTypes []string
+	// Secure restricts the cookies to HTTPS; only turn it off for local development
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+	// RefreshMaxAge matches the refresh token lifetime
+	RefreshMaxAge time.Duration
+	// AllowedOrigins are the dashboard origins allowed to send credentialed
+	// cross-origin requests; empty keeps the default CORS policy
+	AllowedOrigins []string
+}
+
+// DefaultCookieAuthConfig uses cookies for the web dashboard only
+func DefaultCookieAuthConfig() CookieAuthConfig {
+	return CookieAuthConfig{
+		ClientTypes:   []string{"web"},
+		Secure:        true,
+		SameSite:      http.SameSiteLaxMode,
+		RefreshMaxAge: 24 * time.Hour,
+	}
+}
+
+// ParseSameSite maps "lax", "strict" or "none" to the cookie attribute
+func ParseSameSite(value string) (http.SameSite, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "lax":
+		return http.SameSiteLaxMode, true
+	case "strict":
+		return http.SameSiteStrictMode, true
+	case "none":
+		return http.SameSiteNoneMode, true
+	}
+	return http.SameSiteDefaultMode, false
+}
+
+// UsesCookies reports whether the request's client type uses cookie auth
+func (cfg CookieAuthConfig) UsesCookies(c echo.Context) bool {
+	clientType := strings.ToLower(strings.TrimSpace(c.Request().Header.Get(ClientTypeHeader)))
+	if clientType == "" {
+		return false
+	}
+	for _, t := range cfg.ClientTypes {
+		if strings.EqualFold(t, clientType) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSessionCookies stores the tokens in httpOnly cookies with a fresh CSRF
+// token, which is returned for the response body as well
+func (cfg CookieAuthConfig) SetSessionCookies(c echo.Context, tokens *models.TokenResponse) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	csrfToken := hex.EncodeToString(b)
+
+	c.SetCookie(cfg.cookie(AccessTokenCookie, tokens.AccessToken, "/", time.Duration(tokens.ExpiresIn)*time.Second, true))
+	c.SetCookie(cfg.cookie(RefreshTokenCookie, tokens.RefreshToken, refreshCookiePath, cfg.RefreshMaxAge, true))
+	c.SetCookie(cfg.cookie(CSRFCookie, csrfToken, "/", cfg.RefreshMaxAge, false))
+	return csrfToken, nil
+}
+
+// ClearSessionCookies expires the session cookies
+func (cfg CookieAuthConfig) ClearSessionCookies(c echo.Context) {
+	for _, cookie := range []*http.Cookie{
+		cfg.cookie(AccessTokenCookie, "", "/", 0, true),
+		cfg.cookie(RefreshTokenCookie, "", refreshCookiePath, 0, true),
+		cfg.cookie(CSRFCookie, "", "/", 0, false),
+	} {
+		cookie.MaxAge = -1
+		c.SetCookie(cookie)
+	}
+}
+
+func (cfg CookieAuthConfig) cookie(name, value, path string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.Domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: cfg.SameSite,
+	}
+}
+
+// RequestToken returns the access token from the bearer Authorization header,
+// or from the access cookie when there is no header
+func RequestToken(c echo.Context) (token string, fromCookie bool, err error) {
+	authHeader := c.Request().Header.Get("Authorization")
+	if authHeader == "" {
+		if cookie, err := c.Cookie(AccessTokenCookie); err == nil && cookie.Value != "" {
+			return cookie.Value, true, nil
+		}
+		return "", false, echo.NewHTTPError(http.StatusUnauthorized, "Missing token")
+	}
+
+	token = strings.TrimPrefix(authHeader, "Bearer ")
+	if token == authHeader {
+		return "", false, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token format")
+	}
+	return token, false, nil
+}
+
+// CSRFProtection rejects requests that change data with session cookies but
+// without the matching CSRF header. Requests carrying a bearer token are not
+// sent automatically by browsers and need no CSRF token
+func CSRFProtection() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if isReadOnlyMethod(req.Method) || csrfExemptPaths[c.Path()] || req.Header.Get("Authorization") != "" {
+				return next(c)
+			}
+			if !hasCookie(c, AccessTokenCookie) && !hasCookie(c, RefreshTokenCookie) {
+				return next(c)
+			}
+
+			cookie, err := c.Cookie(CSRFCookie)
+			header := req.Header.Get(CSRFHeader)
+			if err != nil || cookie.Value == "" || header == "" ||
+				subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+				return echo.NewHTTPError(http.StatusForbidden, "Invalid or missing CSRF token")
+			}
+			return next(c)
+		}
+	}
+}
+
+func hasCookie(c echo.Context, name string) bool {
+	cookie, err := c.Cookie(name)
+	return err == nil && cookie.Value != ""
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRFProtection(t *testing.T) {
+	const csrfToken = "0123456789abcdef"
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		cookies map[string]string
+		header  string
+		bearer  bool
+		allowed bool
+	}{
+		{
+			name:    "matching token",
+			method:  http.MethodPost,
+			cookies: map[string]string{AccessTokenCookie: "access", CSRFCookie: csrfToken},
+			header:  csrfToken,
+			allowed: true,
+		},
+		{
+			name:    "mismatched token",
+			method:  http.MethodPost,
+			cookies: map[string]string{AccessTokenCookie: "access", CSRFCookie: csrfToken},
+			header:  "fedcba9876543210",
+		},
+		{
+			name:    "missing header",
+			method:  http.MethodPut,
+			cookies: map[string]string{AccessTokenCookie: "access", CSRFCookie: csrfToken},
+		},
+		{
+			name:    "missing cookie",
+			method:  http.MethodDelete,
+			cookies: map[string]string{AccessTokenCookie: "access"},
+			header:  csrfToken,
+		},
+		{
+			name:    "refresh cookie only",
+			method:  http.MethodPost,
+			path:    "/v1/auth/refresh",
+			cookies: map[string]string{RefreshTokenCookie: "refresh", CSRFCookie: csrfToken},
+		},
+		{
+			name:    "read-only method",
+			method:  http.MethodGet,
+			cookies: map[string]string{AccessTokenCookie: "access"},
+			allowed: true,
+		},
+		{
+			name:    "login starts a session",
+			method:  http.MethodPost,
+			path:    "/v1/auth/login",
+			cookies: map[string]string{AccessTokenCookie: "stale"},
+			allowed: true,
+		},
+		{
+			name:    "bearer token",
+			method:  http.MethodPost,
+			cookies: map[string]string{AccessTokenCookie: "access"},
+			bearer:  true,
+			allowed: true,
+		},
+		{
+			name:    "no session cookies",
+			method:  http.MethodPost,
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "/v1/products"
+			}
+			req := httptest.NewRequest(tt.method, path, nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer token")
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			c.SetPath(path)
+
+			called := false
+			err := CSRFProtection()(func(c echo.Context) error {
+				called = true
+				return nil
+			})(c)
+
+			if tt.allowed {
+				require.NoError(t, err)
+				assert.True(t, called)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusForbidden, httpErr.Code)
+			assert.False(t, called)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// FeatureFlagMiddleware hides routes of features that are not enabled for
+// the caller's tenant
+type FeatureFlagMiddleware struct {
+	featureFlagSvc services.FeatureFlagService
+}
+
+func NewFeatureFlagMiddleware(featureFlagSvc services.FeatureFlagService) *FeatureFlagMiddleware {
+	return &FeatureFlagMiddleware{
+		featureFlagSvc: featureFlagSvc,
+	}
+}
+
+// RequireFeature responds 404 unless the flag is on for the tenant, so
+// tenants without the feature see the routes as absent
+func (m *FeatureFlagMiddleware) RequireFeature(key string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+			}
+
+			enabled, err := m.featureFlagSvc.IsEnabled(ctx, tenantID, key)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Error checking feature flag")
+			}
+			if !enabled {
+				return echo.ErrNotFound
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -1,15 +1,17 @@
 package middleware
 
 import (
-	"context"
+	"errors"
 	"net/http"
-	"strings"
+	"time"
 
 	"agromart2/internal/common"
 	"agromart2/internal/repositories"
+	"agromart2/internal/services"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
@@ -25,24 +27,18 @@ type JWTCustomClaims struct {
 	Scope    *string `json:"scope,omitempty"`
 	TokenID  string  `json:"token_id"`
 	ClientID *string `json:"client_id,omitempty"`
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // ParseJWTPayload parses JWT token payload into custom claims
-func ParseJWTPayload(c echo.Context, dst *JWTCustomClaims, jwtSecret string) error {
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return echo.NewHTTPError(http.StatusUnauthorized, "Missing token")
-	}
-
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	if tokenString == authHeader {
-		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token format")
+func ParseJWTPayload(c echo.Context, dst *JWTCustomClaims, keyRing *services.KeyRing) error {
+	tokenString, _, err := RequestToken(c)
+	if err != nil {
+		return err
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+	token, err := jwt.Parse(tokenString, keyRing.Keyfunc, jwt.WithValidMethods(keyRing.ValidMethods()))
 	if err != nil {
 		return err
 	}
@@ -71,28 +67,25 @@ func ParseJWTPayload(c echo.Context, dst *JWTCustomClaims, jwtSecret string) err
 		clientIDPtr := &clientID
 		dst.ClientID = clientIDPtr
 	}
+	if impersonatorID, ok := claims["impersonator_id"].(string); ok {
+		dst.ImpersonatorID = impersonatorID
+	}
 
 	return nil
 }
 
 // JWTMiddleware handles JWT token validation
 
-func JWTMiddleware(userRepo repositories.UserRepository, jwtSecret string) echo.MiddlewareFunc {
+func JWTMiddleware(userRepo repositories.UserRepository, userRoleRepo repositories.UserRoleRepository, impersonationRepo repositories.ImpersonationRepository, keyRing *services.KeyRing, authService services.AuthService) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Missing token")
-			}
-
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			if tokenString == authHeader {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token format")
+			// Bearer tokens from API and mobile clients, or the access cookie in cookie auth mode
+			tokenString, _, err := RequestToken(c)
+			if err != nil {
+				return err
 			}
 
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				return []byte(jwtSecret), nil
-			})
+			token, err := jwt.Parse(tokenString, keyRing.Keyfunc, jwt.WithValidMethods(keyRing.ValidMethods()))
 			if err != nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
 			}
@@ -116,6 +109,33 @@ func JWTMiddleware(userRepo repositories.UserRepository, jwtSecret string) echo.
 				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid user_id format")
 			}
 
+			// Revoked tokens stay cryptographically valid until they expire
+			tokenID, _ := claims["jti"].(string)
+			issuedAt, err := claims.GetIssuedAt()
+			if err != nil || issuedAt == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Missing iat in token")
+			}
+			if authService.IsTokenRevoked(c.Request().Context(), tokenID, userID, issuedAt.Time) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Token revoked")
+			}
+
+			// Impersonation tokens carry the real actor so audit logs record both
+			// identities, and stop working as soon as their session is ended
+			var impersonatorID uuid.UUID
+			if impersonator, ok := claims["impersonator_id"].(string); ok && impersonator != "" {
+				impersonatorID, err = uuid.Parse(impersonator)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusUnauthorized, "Invalid impersonator_id format")
+				}
+				session, err := impersonationRepo.GetByTokenID(c.Request().Context(), tokenID)
+				if errors.Is(err, pgx.ErrNoRows) || (err == nil && !session.IsActive(time.Now())) {
+					return echo.NewHTTPError(http.StatusUnauthorized, "Impersonation session has ended")
+				}
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load impersonation session")
+				}
+			}
+
 			defaultTenantID, err := userRepo.GetTenantIDByUserID(c.Request().Context(), userID)
 			if err != nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, "User not found")
@@ -134,8 +154,21 @@ func JWTMiddleware(userRepo repositories.UserRepository, jwtSecret string) echo.
 				}
 			}
 
-			ctx := context.WithValue(c.Request().Context(), common.UserIDKey, userID)
-			ctx = context.WithValue(ctx, common.TenantIDKey, defaultTenantID)
+			roles, err := userRoleRepo.ListByUser(c.Request().Context(), defaultTenantID, userID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load user roles")
+			}
+
+			rc := common.RequestContextFrom(c.Request().Context()).Clone()
+			rc.UserID = userID
+			rc.TenantID = defaultTenantID
+			rc.RoleIDs = make([]uuid.UUID, 0, len(roles))
+			for _, role := range roles {
+				rc.RoleIDs = append(rc.RoleIDs, role.RoleID)
+			}
+
+			rc.ImpersonatorID = impersonatorID
+			ctx := common.WithRequestContext(c.Request().Context(), rc)
 			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
@@ -0,0 +1,299 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySigningKeys keeps signing keys in memory, newest first, and lists
+// them as the database does
+type memorySigningKeys struct {
+	keys []*models.SigningKey
+}
+
+func (r *memorySigningKeys) Create(ctx context.Context, key *models.SigningKey) error {
+	r.keys = append([]*models.SigningKey{key}, r.keys...)
+	return nil
+}
+
+func (r *memorySigningKeys) ListVerifiable(ctx context.Context) ([]*models.SigningKey, error) {
+	var keys []*models.SigningKey
+	for _, key := range r.keys {
+		switch {
+		case key.Status == models.SigningKeyStatusActive,
+			key.Status == models.SigningKeyStatusRetiring && (key.ExpiresAt == nil || key.ExpiresAt.After(time.Now())):
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memorySigningKeys) RetireActive(ctx context.Context, keepKID string, expiresAt time.Time) error {
+	now := time.Now()
+	for _, key := range r.keys {
+		if key.KID != keepKID && key.Status == models.SigningKeyStatusActive {
+			key.Status = models.SigningKeyStatusRetiring
+			key.RotatedAt = &now
+			key.ExpiresAt = &expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *memorySigningKeys) Revoke(ctx context.Context, kid string) (bool, error) {
+	for _, key := range r.keys {
+		if key.KID == kid && key.Status != models.SigningKeyStatusRevoked {
+			key.Status = models.SigningKeyStatusRevoked
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type stubUserRepo struct {
+	repositories.UserRepository
+	tenantID uuid.UUID
+}
+
+func (r *stubUserRepo) GetTenantIDByUserID(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	return r.tenantID, nil
+}
+
+type stubUserRoleRepo struct {
+	repositories.UserRoleRepository
+}
+
+func (r *stubUserRoleRepo) ListByUser(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.UserRole, error) {
+	return nil, nil
+}
+
+type stubImpersonationRepo struct {
+	repositories.ImpersonationRepository
+	sessions map[string]*models.ImpersonationSession
+}
+
+func (r *stubImpersonationRepo) GetByTokenID(ctx context.Context, tokenID string) (*models.ImpersonationSession, error) {
+	session, ok := r.sessions[tokenID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return session, nil
+}
+
+type stubAuthService struct {
+	services.AuthService
+	revoked map[string]bool
+}
+
+func (s *stubAuthService) IsTokenRevoked(ctx context.Context, tokenID string, userID uuid.UUID, issuedAt time.Time) bool {
+	return s.revoked[tokenID]
+}
+
+type jwtFixture struct {
+	keys          *memorySigningKeys
+	keyRing       *services.KeyRing
+	impersonation *stubImpersonationRepo
+	auth          *stubAuthService
+	handler       echo.HandlerFunc
+	tenantID      uuid.UUID
+}
+
+func newJWTFixture(t *testing.T) *jwtFixture {
+	return newJWTInstance(t, &memorySigningKeys{}, uuid.New(), time.Hour, services.DefaultKeyRefreshInterval)
+}
+
+// newJWTInstance sets up the middleware as one server instance would, sharing
+// the signing keys with any other instance built on them
+func newJWTInstance(t *testing.T, keys *memorySigningKeys, tenantID uuid.UUID, verifyWindow, refreshInterval time.Duration) *jwtFixture {
+	keyRing, err := services.NewKeyRing(context.Background(), keys, "", verifyWindow, refreshInterval)
+	require.NoError(t, err)
+
+	f := &jwtFixture{
+		keys:          keys,
+		keyRing:       keyRing,
+		impersonation: &stubImpersonationRepo{sessions: map[string]*models.ImpersonationSession{}},
+		auth:          &stubAuthService{revoked: map[string]bool{}},
+		tenantID:      tenantID,
+	}
+	mw := JWTMiddleware(&stubUserRepo{tenantID: f.tenantID}, &stubUserRoleRepo{}, f.impersonation, keyRing, f.auth)
+	f.handler = mw(func(c echo.Context) error {
+		rc := common.RequestContextFrom(c.Request().Context())
+		return c.String(http.StatusOK, rc.ImpersonatorID.String())
+	})
+	return f
+}
+
+// token signs an access token for a new user, acting for impersonatorID
+// when it is set
+func (f *jwtFixture) token(t *testing.T, tokenID string, impersonatorID uuid.UUID) string {
+	now := time.Now()
+	userID := uuid.NewString()
+	claims := services.TokenClaims{
+		UserID:   userID,
+		TenantID: f.tenantID.String(),
+		TokenID:  tokenID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        tokenID,
+		},
+	}
+	if impersonatorID != uuid.Nil {
+		claims.ImpersonatorID = impersonatorID.String()
+	}
+	token, err := f.keyRing.Sign(claims)
+	require.NoError(t, err)
+	return token
+}
+
+func (f *jwtFixture) serve(token string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/products", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	return rec, f.handler(echo.New().NewContext(req, rec))
+}
+
+func assertUnauthorized(t *testing.T, err error) {
+	t.Helper()
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusUnauthorized, httpErr.Code)
+}
+
+func TestJWTMiddlewareAcceptsValidToken(t *testing.T) {
+	f := newJWTFixture(t)
+
+	rec, err := f.serve(f.token(t, uuid.NewString(), uuid.Nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, uuid.Nil.String(), rec.Body.String())
+}
+
+func TestJWTMiddlewareRejectsRevokedToken(t *testing.T) {
+	f := newJWTFixture(t)
+	tokenID := uuid.NewString()
+	f.auth.revoked[tokenID] = true
+
+	_, err := f.serve(f.token(t, tokenID, uuid.Nil))
+	assertUnauthorized(t, err)
+}
+
+func TestJWTMiddlewareRejectsExpiredRetiringKeys(t *testing.T) {
+	f := newJWTInstance(t, &memorySigningKeys{}, uuid.New(), 200*time.Millisecond, time.Hour)
+	oldToken := f.token(t, uuid.NewString(), uuid.Nil)
+
+	// A rotated key keeps verifying its tokens during the verify window
+	_, err := f.keyRing.Rotate(context.Background())
+	require.NoError(t, err)
+	newToken := f.token(t, uuid.NewString(), uuid.Nil)
+	_, err = f.serve(oldToken)
+	require.NoError(t, err)
+
+	// and stops once the window has passed, without waiting for a reload
+	require.Eventually(t, func() bool {
+		_, err := f.serve(oldToken)
+		return err != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	_, err = f.serve(oldToken)
+	assertUnauthorized(t, err)
+	_, err = f.serve(newToken)
+	require.NoError(t, err)
+}
+
+func TestJWTMiddlewareRevokedKeysStopVerifyingOnEveryInstance(t *testing.T) {
+	ctx := context.Background()
+	keys, tenantID := &memorySigningKeys{}, uuid.New()
+	revoking := newJWTInstance(t, keys, tenantID, time.Hour, time.Hour)
+	other := newJWTInstance(t, keys, tenantID, time.Hour, 50*time.Millisecond)
+	revokedKID := keys.keys[0].KID
+	token := revoking.token(t, uuid.NewString(), uuid.Nil)
+	_, err := other.serve(token)
+	require.NoError(t, err)
+
+	// Revoking the signing key rotates to a new one and rejects its tokens at once
+	require.NoError(t, revoking.keyRing.Revoke(ctx, revokedKID))
+	_, err = revoking.serve(token)
+	assertUnauthorized(t, err)
+	newToken := revoking.token(t, uuid.NewString(), uuid.Nil)
+	_, err = revoking.serve(newToken)
+	require.NoError(t, err)
+	assert.ErrorIs(t, revoking.keyRing.Revoke(ctx, revokedKID), services.ErrSigningKeyNotFound)
+
+	// Other instances follow at their next refresh
+	require.Eventually(t, func() bool {
+		_, err := other.serve(token)
+		return err != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	_, err = other.serve(newToken)
+	require.NoError(t, err)
+
+	var kids []string
+	for _, key := range other.keyRing.JWKS().Keys {
+		kids = append(kids, key.KeyID)
+	}
+	assert.Equal(t, []string{keys.keys[0].KID}, kids, "JWKS lists the rotated key and drops the revoked one")
+}
+
+func TestJWTMiddlewareImpersonationSessions(t *testing.T) {
+	impersonatorID := uuid.New()
+	endedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		session *models.ImpersonationSession
+		allowed bool
+	}{
+		{
+			name:    "active session",
+			session: &models.ImpersonationSession{ExpiresAt: time.Now().Add(10 * time.Minute)},
+			allowed: true,
+		},
+		{
+			name:    "ended session",
+			session: &models.ImpersonationSession{ExpiresAt: time.Now().Add(10 * time.Minute), EndedAt: &endedAt},
+		},
+		{
+			name:    "expired session",
+			session: &models.ImpersonationSession{ExpiresAt: time.Now().Add(-time.Second)},
+		},
+		{
+			name: "unknown session",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newJWTFixture(t)
+			tokenID := uuid.NewString()
+			if tt.session != nil {
+				tt.session.TokenID = tokenID
+				tt.session.ImpersonatorID = impersonatorID
+				f.impersonation.sessions[tokenID] = tt.session
+			}
+
+			rec, err := f.serve(f.token(t, tokenID, impersonatorID))
+			if !tt.allowed {
+				assertUnauthorized(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, impersonatorID.String(), rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// operationalModeExemptPaths stay reachable in every mode: health checks for
+// the orchestrator, login so platform admins can lift the mode, and the
+// status page that announces it
+var operationalModeExemptPaths = map[string]bool{
+	"/health":              true,
+	"/health/ready":        true,
+	"/health/detailed":     true,
+	"/metrics":             true,
+	"/v1/auth/login":       true,
+	"/v1/auth/refresh":     true,
+	"/v1/status":           true,
+	"/v1/status/incidents": true,
+}
+
+// operationalModeAdminPrefixes are where the modes are switched and incidents
+// posted to the status page, which must keep working while they are in force
+var operationalModeAdminPrefixes = []string{
+	"/v1/admin/operational-modes",
+	"/v1/admin/incidents",
+}
+
+// isOperationalModeAdminPath reports whether the route is under one of the
+// admin prefixes
+func isOperationalModeAdminPath(path string) bool {
+	for _, prefix := range operationalModeAdminPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OperationalModeMiddleware rejects requests while the service or the
+// caller's tenant is in read-only or maintenance mode
+type OperationalModeMiddleware struct {
+	operationalModeSvc services.OperationalModeService
+}
+
+func NewOperationalModeMiddleware(operationalModeSvc services.OperationalModeService) *OperationalModeMiddleware {
+	return &OperationalModeMiddleware{
+		operationalModeSvc: operationalModeSvc,
+	}
+}
+
+// isReadOnlyMethod reports whether a request with the method leaves data unchanged
+func isReadOnlyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// Enforce responds 503 to every request in maintenance mode and to requests
+// that change data in read-only mode. Before authentication only the global
+// mode is known, so it is mounted again after JWTMiddleware to apply tenant
+// modes. Lookup failures let the request through rather than blocking traffic
+func (m *OperationalModeMiddleware) Enforce() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Path()
+			if operationalModeExemptPaths[path] || isOperationalModeAdminPath(path) {
+				return next(c)
+			}
+
+			ctx := c.Request().Context()
+			var tenantID *uuid.UUID
+			if id, ok := common.RequestContextFrom(ctx).Tenant(); ok {
+				tenantID = &id
+			}
+
+			mode, err := m.operationalModeSvc.Effective(ctx, tenantID)
+			if err != nil {
+				log.Printf("Failed to check operational mode: %v", err)
+				return next(c)
+			}
+			if mode == nil {
+				return next(c)
+			}
+
+			switch mode.Mode {
+			case models.OperationalModeMaintenance:
+				message := "The service is undergoing maintenance, please try again shortly"
+				if mode.Message != nil {
+					message = *mode.Message
+				}
+				c.Response().Header().Set("Retry-After", "300")
+				return echo.NewHTTPError(http.StatusServiceUnavailable, message)
+			case models.OperationalModeReadOnly:
+				if isReadOnlyMethod(c.Request().Method) {
+					return next(c)
+				}
+				message := "The service is temporarily read-only, changes cannot be saved right now"
+				if mode.Message != nil {
+					message = *mode.Message
+				}
+				c.Response().Header().Set("Retry-After", "300")
+				return echo.NewHTTPError(http.StatusServiceUnavailable, message)
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PayloadLoggingFeature is the feature flag that turns payload logging on for a tenant
+const PayloadLoggingFeature = "payload_logging"
+
+const (
+	defaultPayloadLogMaxBody = 16 << 10
+	payloadLogWriteTimeout   = 2 * time.Second
+)
+
+// PayloadLogOptions tunes payload logging for the routes it is attached to
+type PayloadLogOptions struct {
+	// SampleRate is the fraction of requests logged; 0 uses the middleware's rate
+	SampleRate float64
+	// RedactFields are extra body and query fields to redact on these routes,
+	// on top of the built-in secrets and PII
+	RedactFields []string
+	// Skipper leaves requests out entirely
+	Skipper func(c echo.Context) bool
+}
+
+// PayloadLogMiddleware logs request and response bodies for debugging
+// integrations. It only captures requests of tenants with the
+// payload_logging feature flag on, redacts secrets and PII before anything
+// leaves the process, and hands entries to a sink without holding up the
+// response
+type PayloadLogMiddleware struct {
+	featureFlagSvc services.FeatureFlagService
+	sink           services.PayloadLogSink
+	sampleRate     float64
+	maxBody        int
+	random         func() float64
+}
+
+// NewPayloadLogMiddleware creates the middleware; with a nil sink it passes
+// every request straight through. maxBody caps each captured body in bytes
+func NewPayloadLogMiddleware(featureFlagSvc services.FeatureFlagService, sink services.PayloadLogSink, sampleRate float64, maxBody int) *PayloadLogMiddleware {
+	if maxBody <= 0 {
+		maxBody = defaultPayloadLogMaxBody
+	}
+	return &PayloadLogMiddleware{
+		featureFlagSvc: featureFlagSvc,
+		sink:           sink,
+		sampleRate:     sampleRate,
+		maxBody:        maxBody,
+		random:         rand.Float64,
+	}
+}
+
+// LogPayloads captures the payloads of the routes it is attached to. It can
+// be used on a group and again, with stricter options, on single routes
+// outside it
+func (m *PayloadLogMiddleware) LogPayloads(opts PayloadLogOptions) echo.MiddlewareFunc {
+	sampleRate := opts.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = m.sampleRate
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m.sink == nil || (opts.Skipper != nil && opts.Skipper(c)) {
+				return next(c)
+			}
+			ctx := c.Request().Context()
+			rc := common.RequestContextFrom(ctx)
+			tenantID, ok := rc.Tenant()
+			if !ok {
+				return next(c)
+			}
+			if enabled, err := m.featureFlagSvc.IsEnabled(ctx, tenantID, PayloadLoggingFeature); err != nil || !enabled {
+				return next(c)
+			}
+			if sampleRate < 1 && m.random() >= sampleRate {
+				return next(c)
+			}
+
+			req := c.Request()
+			entry := &models.PayloadLogEntry{
+				TenantID:       tenantID,
+				RequestID:      rc.RequestID,
+				Method:         req.Method,
+				Route:          c.Path(),
+				RequestHeaders: pii.RedactHeaders(req.Header),
+				CreatedAt:      time.Now(),
+			}
+			if userID, ok := rc.User(); ok {
+				entry.UserID = &userID
+			}
+			if req.URL.RawQuery != "" {
+				entry.Query = pii.RedactQuery(req.URL.RawQuery, opts.RedactFields...)
+			}
+
+			var requestBody []byte
+			var requestTruncated bool
+			if req.Body != nil && loggableContentType(req.Header.Get(echo.HeaderContentType)) {
+				requestBody, requestTruncated = m.captureRequestBody(req)
+			}
+
+			capture := &payloadCaptureWriter{ResponseWriter: c.Response().Writer, max: m.maxBody}
+			c.Response().Writer = capture
+			start := time.Now()
+			err := next(c)
+			c.Response().Writer = capture.ResponseWriter
+
+			entry.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+			entry.Status = c.Response().Status
+			var httpErr *echo.HTTPError
+			if err != nil && !c.Response().Committed && errors.As(err, &httpErr) {
+				// The error handler writes the response after this middleware
+				entry.Status = httpErr.Code
+			}
+			entry.RequestBody = redactBody(req.Header.Get(echo.HeaderContentType), requestBody, requestTruncated, opts.RedactFields)
+			entry.ResponseHeaders = pii.RedactHeaders(c.Response().Header())
+			entry.ResponseBody = redactBody(c.Response().Header().Get(echo.HeaderContentType), capture.body.Bytes(), capture.truncated, opts.RedactFields)
+			entry.Truncated = requestTruncated || capture.truncated
+
+			go m.write(context.WithoutCancel(ctx), entry)
+			return err
+		}
+	}
+}
+
+// captureRequestBody reads up to maxBody bytes and puts them back in front
+// of the rest of the body for the handler
+func (m *PayloadLogMiddleware) captureRequestBody(req *http.Request) ([]byte, bool) {
+	captured, err := io.ReadAll(io.LimitReader(req.Body, int64(m.maxBody)+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(captured), req.Body), req.Body}
+	if err != nil {
+		return nil, false
+	}
+	if len(captured) > m.maxBody {
+		return captured[:m.maxBody], true
+	}
+	return captured, false
+}
+
+func (m *PayloadLogMiddleware) write(ctx context.Context, entry *models.PayloadLogEntry) {
+	ctx, cancel := context.WithTimeout(ctx, payloadLogWriteTimeout)
+	defer cancel()
+	if err := m.sink.Write(ctx, entry); err != nil {
+		log.Printf("Failed to write payload log for %s %s: %v", entry.Method, entry.Route, err)
+	}
+}
+
+// loggableContentType reports whether a body is text worth capturing;
+// uploads and binary downloads are left out
+func loggableContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == echo.MIMEApplicationJSON || mediaType == echo.MIMEApplicationForm ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// redactBody returns a body safe to log. Only JSON and form bodies can be
+// redacted field by field; a truncated JSON body no longer parses, so it is
+// left out rather than logged unredacted
+func redactBody(contentType string, body []byte, truncated bool, extra []string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == echo.MIMEApplicationForm:
+		return pii.RedactQuery(string(body), extra...)
+	case mediaType == echo.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+		if redacted, ok := pii.RedactJSON(body, extra...); ok {
+			return redacted
+		}
+	}
+	note := "[" + strconv.Itoa(len(body)) + " bytes not logged"
+	if truncated {
+		note += ", truncated"
+	}
+	return note + "]"
+}
+
+// payloadCaptureWriter copies up to max bytes of the response as it is written
+type payloadCaptureWriter struct {
+	http.ResponseWriter
+	body      bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *payloadCaptureWriter) Write(b []byte) (int, error) {
+	if room := w.max - w.body.Len(); room > 0 {
+		if len(b) > room {
+			w.body.Write(b[:room])
+			w.truncated = true
+		} else {
+			w.body.Write(b)
+		}
+	} else if len(b) > 0 {
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *payloadCaptureWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *payloadCaptureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/common"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// userRateLimit is the hourly request allowance of an authenticated user
+	userRateLimit   = 10000
+	rateLimitWindow = time.Hour
+	// sandboxRateLimitFactor relaxes the allowance in sandbox tenants, where
+	// integrators script against the API while building against it
+	sandboxRateLimitFactor = 10
+)
+
+// RateLimitMiddleware limits how many requests each authenticated user can
+// make per hour
+type RateLimitMiddleware struct {
+	cacheSvc   caching.CacheService
+	sandboxSvc services.SandboxService
+}
+
+func NewRateLimitMiddleware(cacheSvc caching.CacheService, sandboxSvc services.SandboxService) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		cacheSvc:   cacheSvc,
+		sandboxSvc: sandboxSvc,
+	}
+}
+
+// LimitUser responds 429 once the caller has used up the hourly allowance. It
+// is mounted after JWTMiddleware; requests without a user pass, and lookup
+// failures let the request through rather than blocking traffic
+func (m *RateLimitMiddleware) LimitUser() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			userID, ok := common.RequestContextFrom(ctx).User()
+			if !ok {
+				return next(c)
+			}
+
+			limit := userRateLimit
+			if tenantID, ok := common.RequestContextFrom(ctx).Tenant(); ok {
+				sandbox, err := m.sandboxSvc.IsSandbox(ctx, tenantID)
+				if err != nil {
+					log.Printf("Failed to check sandbox for rate limit: %v", err)
+				} else if sandbox {
+					limit *= sandboxRateLimitFactor
+				}
+			}
+
+			limited, err := m.cacheSvc.IsRateLimited(ctx, fmt.Sprintf("user:%s", userID), limit, rateLimitWindow)
+			if err != nil {
+				log.Printf("Failed to check rate limit: %v", err)
+				return next(c)
+			}
+			c.Response().Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			if limited {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(rateLimitWindow.Seconds())))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "Rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
@@ -23,11 +23,11 @@ func (m *RBACMiddleware) RequirePermission(permission string) echo.MiddlewareFun
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			ctx := c.Request().Context()
-			userID, ok := common.GetUserIDFromContext(ctx)
+			userID, ok := common.RequestContextFrom(ctx).User()
 			if !ok {
 				return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
 			}
-			tenantID, ok := common.GetTenantIDFromContext(ctx)
+			tenantID, ok := common.RequestContextFrom(ctx).Tenant()
 			if !ok {
 				return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
 			}
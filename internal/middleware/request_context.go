@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+
+	"agromart2/internal/common"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern bounds client supplied request IDs so they are safe to log
+// and echo back
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// localeTagPattern matches a primary language tag with an optional region
+var localeTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// RequestContext seeds every request with a common.RequestContext carrying
+// its request ID and locale; JWTMiddleware later adds the caller's identity
+func RequestContext() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			requestID := c.Request().Header.Get(requestIDHeader)
+			if !requestIDPattern.MatchString(requestID) {
+				requestID = uuid.New().String()
+			}
+			c.Response().Header().Set(requestIDHeader, requestID)
+
+			rc := &common.RequestContext{
+				RequestID: requestID,
+				Locale:    parseAcceptLanguage(c.Request().Header.Get("Accept-Language")),
+			}
+			c.SetRequest(c.Request().WithContext(common.WithRequestContext(c.Request().Context(), rc)))
+			return next(c)
+		}
+	}
+}
+
+// parseAcceptLanguage returns the first well-formed language tag of an
+// Accept-Language header, lowercased, or "" when there is none
+func parseAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		if localeTagPattern.MatchString(tag) {
+			return tag
+		}
+	}
+	return ""
+}
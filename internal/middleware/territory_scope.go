@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+
+	"agromart2/internal/common"
+	"agromart2/internal/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TerritoryScopeMiddleware keeps users restricted to a territory, such as
+// regional managers, off analytics that cover the whole tenant. They see
+// sales through the territory analytics, which are scoped to their territory
+type TerritoryScopeMiddleware struct {
+	territorySvc services.TerritoryService
+}
+
+func NewTerritoryScopeMiddleware(territorySvc services.TerritoryService) *TerritoryScopeMiddleware {
+	return &TerritoryScopeMiddleware{
+		territorySvc: territorySvc,
+	}
+}
+
+// RequireTenantWide rejects users assigned to a territory
+func (m *TerritoryScopeMiddleware) RequireTenantWide() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			userID, ok := common.RequestContextFrom(ctx).User()
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+			}
+			tenantID, ok := common.RequestContextFrom(ctx).Tenant()
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Tenant not found")
+			}
+
+			territoryID, err := m.territorySvc.UserTerritory(ctx, tenantID, userID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "Error checking territory")
+			}
+			if territoryID != nil {
+				return echo.NewHTTPError(http.StatusForbidden, "Restricted to your territory, use the territory analytics")
+			}
+
+			return next(c)
+		}
+	}
+}
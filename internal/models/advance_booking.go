@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Advance booking statuses
+const (
+	BookingStatusBooked    = "booked"
+	BookingStatusConverted = "converted"
+	BookingStatusCancelled = "cancelled"
+)
+
+// AdvanceBooking is a sale booked ahead of its delivery window, typically
+// against a season. It becomes a sales order, linked by OrderID, when converted.
+type AdvanceBooking struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	DistributorID uuid.UUID  `json:"distributor_id" db:"distributor_id"`
+	ProductID     uuid.UUID  `json:"product_id" db:"product_id"`
+	WarehouseID   uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	Quantity      int        `json:"quantity" db:"quantity"`
+	UnitPrice     float64    `json:"unit_price" db:"unit_price"`
+	Region        *string    `json:"region,omitempty" db:"region"`
+	WindowStart   time.Time  `json:"window_start" db:"window_start"`
+	WindowEnd     time.Time  `json:"window_end" db:"window_end"`
+	AdvancePaid   float64    `json:"advance_paid" db:"advance_paid"`
+	Status        string     `json:"status" db:"status"`
+	OrderID       *uuid.UUID `json:"order_id,omitempty" db:"order_id"`
+	ConvertedAt   *time.Time `json:"converted_at,omitempty" db:"converted_at"`
+	Notes         *string    `json:"notes,omitempty" db:"notes"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	// Derived
+	TotalValue float64                  `json:"total_value"`
+	BalanceDue float64                  `json:"balance_due"`
+	Payments   []*AdvanceBookingPayment `json:"payments,omitempty"`
+}
+
+// AdvanceBookingPayment is one advance received against a booking
+type AdvanceBookingPayment struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	BookingID  uuid.UUID  `json:"booking_id" db:"booking_id"`
+	Amount     float64    `json:"amount" db:"amount"`
+	Method     string     `json:"method" db:"method"`
+	Reference  *string    `json:"reference,omitempty" db:"reference"`
+	RecordedBy *uuid.UUID `json:"recorded_by,omitempty" db:"recorded_by"`
+	PaidAt     time.Time  `json:"paid_at" db:"paid_at"`
+}
+
+// AdvanceBookingFilter narrows a booking listing; WindowStartsBefore finds
+// bookings due for conversion
+type AdvanceBookingFilter struct {
+	Status             *string
+	DistributorID      *uuid.UUID
+	ProductID          *uuid.UUID
+	WindowStartsBefore *time.Time
+	Limit              int
+	Offset             int
+}
+
+// BookingDemandRow compares booked and fulfilled quantities for a product in
+// a region; fulfilled counts converted orders that have been delivered
+type BookingDemandRow struct {
+	ProductID         uuid.UUID `json:"product_id"`
+	ProductName       string    `json:"product_name"`
+	Region            string    `json:"region"`
+	Bookings          int       `json:"bookings"`
+	BookedQuantity    int       `json:"booked_quantity"`
+	ConvertedQuantity int       `json:"converted_quantity"`
+	FulfilledQuantity int       `json:"fulfilled_quantity"`
+	CancelledQuantity int       `json:"cancelled_quantity"`
+	AdvanceCollected  float64   `json:"advance_collected"`
+	FulfilmentRate    float64   `json:"fulfilment_rate"`
+}
+
+// BookingDemandReport is booked versus fulfilled demand for bookings whose
+// delivery window starts in [From, To)
+type BookingDemandReport struct {
+	From              time.Time           `json:"from"`
+	To                time.Time           `json:"to"`
+	Rows              []*BookingDemandRow `json:"rows"`
+	BookedQuantity    int                 `json:"booked_quantity"`
+	FulfilledQuantity int                 `json:"fulfilled_quantity"`
+	AdvanceCollected  float64             `json:"advance_collected"`
+	FulfilmentRate    float64             `json:"fulfilment_rate"`
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where an analytics report was read from: a materialized view refreshed by
+// the scheduler, or the live tables when the view is missing or too stale
+const (
+	AnalyticsSourceMaterialized = "materialized"
+	AnalyticsSourceLive         = "live"
+)
+
+// AnalyticsViewRefresh records the last refresh of a materialized view
+type AnalyticsViewRefresh struct {
+	ViewName    string    `json:"view_name"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	DurationMs  int       `json:"duration_ms"`
+}
+
+// DailySalesRow totals one day of non-cancelled sales orders
+type DailySalesRow struct {
+	Date        time.Time `json:"date"`
+	OrderCount  int       `json:"order_count"`
+	Quantity    int64     `json:"quantity"`
+	SalesAmount float64   `json:"sales_amount"`
+}
+
+// DailySalesReport is daily sales between From and To inclusive
+type DailySalesReport struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Source string           `json:"source"`
+	AsOf   time.Time        `json:"as_of"`
+	Days   []*DailySalesRow `json:"days"`
+}
+
+// CategoryStockRow is owned stock of one product category valued at list
+// price; CategoryID is nil for uncategorised products
+type CategoryStockRow struct {
+	CategoryID   *uuid.UUID `json:"category_id,omitempty"`
+	CategoryName string     `json:"category_name"`
+	ProductCount int        `json:"product_count"`
+	Quantity     int64      `json:"quantity"`
+	StockValue   float64    `json:"stock_value"`
+}
+
+// StockByCategoryReport is owned stock by product category, highest value first
+type StockByCategoryReport struct {
+	Source     string              `json:"source"`
+	AsOf       time.Time           `json:"as_of"`
+	TotalValue float64             `json:"total_value"`
+	Categories []*CategoryStockRow `json:"categories"`
+}
+
+// ReceivablesAgingRow is what one distributor owes on unpaid and overdue
+// invoices, accrued interest included, by days past due
+type ReceivablesAgingRow struct {
+	DistributorID   uuid.UUID `json:"distributor_id"`
+	DistributorName string    `json:"distributor_name"`
+	InvoiceCount    int       `json:"invoice_count"`
+	Current         float64   `json:"current"`
+	Days1To30       float64   `json:"days_1_30"`
+	Days31To60      float64   `json:"days_31_60"`
+	Days61To90      float64   `json:"days_61_90"`
+	DaysOver90      float64   `json:"days_over_90"`
+	Total           float64   `json:"total"`
+}
+
+// ReceivablesAgingReport ages receivables per distributor, largest balance first
+type ReceivablesAgingReport struct {
+	Source       string                 `json:"source"`
+	AsOf         time.Time              `json:"as_of"`
+	Totals       ReceivablesAgingRow    `json:"totals"`
+	Distributors []*ReceivablesAgingRow `json:"distributors"`
+}
+
+// Receivables aging buckets by days past the invoice due date
+const (
+	ReceivablesBucketCurrent = "current"
+	ReceivablesBucket1To30   = "1-30"
+	ReceivablesBucket31To60  = "31-60"
+	ReceivablesBucket61To90  = "61-90"
+	ReceivablesBucketOver90  = "90+"
+)
+
+// ReceivableInvoice is an open invoice placed in its aging bucket
+type ReceivableInvoice struct {
+	OpenInvoice
+	DistributorID   uuid.UUID `json:"distributor_id"`
+	DistributorName string    `json:"distributor_name"`
+	DaysPastDue     int       `json:"days_past_due"`
+	Bucket          string    `json:"bucket"`
+}
+
+// ReceivablesAgingDetail drills a customer's aging down to their open
+// invoices, oldest due first
+type ReceivablesAgingDetail struct {
+	AsOf     time.Time            `json:"as_of"`
+	Customer ReceivablesAgingRow  `json:"customer"`
+	Invoices []*ReceivableInvoice `json:"invoices"`
+}
+
+// ReceivablesBucketTotal totals one aging bucket across customers
+type ReceivablesBucketTotal struct {
+	Bucket       string  `json:"bucket"`
+	InvoiceCount int     `json:"invoice_count"`
+	Amount       float64 `json:"amount"`
+	Share        float64 `json:"share"`
+}
+
+// ReceivablesAgingSummary is the dashboard widget feed: bucket totals and the
+// customers owing the most
+type ReceivablesAgingSummary struct {
+	AsOf             time.Time                 `json:"as_of"`
+	TotalOutstanding float64                   `json:"total_outstanding"`
+	Overdue          float64                   `json:"overdue"`
+	OverdueShare     float64                   `json:"overdue_share"`
+	CustomerCount    int                       `json:"customer_count"`
+	Buckets          []*ReceivablesBucketTotal `json:"buckets"`
+	TopCustomers     []*ReceivablesAgingRow    `json:"top_customers"`
+}
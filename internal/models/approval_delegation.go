@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Approval kinds a delegation can route
+const (
+	ApprovalKindOrder           = "order"
+	ApprovalKindRequisition     = "requisition"
+	ApprovalKindStockAdjustment = "stock_adjustment"
+)
+
+// ApprovalKinds lists every approval kind, the default for a delegation
+var ApprovalKinds = []string{ApprovalKindOrder, ApprovalKindRequisition, ApprovalKindStockAdjustment}
+
+// ApprovalDelegation routes the delegator's approvals of the given kinds to
+// the delegate from StartsOn through EndsOn
+type ApprovalDelegation struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	DelegatorID uuid.UUID  `json:"delegator_id" db:"delegator_id"`
+	DelegateID  uuid.UUID  `json:"delegate_id" db:"delegate_id"`
+	Kinds       []string   `json:"kinds" db:"kinds"`
+	StartsOn    time.Time  `json:"starts_on" db:"starts_on"`
+	EndsOn      time.Time  `json:"ends_on" db:"ends_on"`
+	Reason      *string    `json:"reason,omitempty" db:"reason"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy   *uuid.UUID `json:"revoked_by,omitempty" db:"revoked_by"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+
+	// Approvals is filled in when a single delegation is read
+	Approvals []*DelegatedApproval `json:"approvals,omitempty" db:"-"`
+}
+
+// Covers reports whether the delegation routes the kind on the day
+func (d *ApprovalDelegation) Covers(kind string, day time.Time) bool {
+	if d.RevokedAt != nil || day.Before(d.StartsOn) || day.After(d.EndsOn) {
+		return false
+	}
+	for _, k := range d.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ApprovalDelegationRequest sets up a delegation. DelegatorID defaults to the
+// requesting user, Kinds to every kind and StartsOn to today; dates are
+// YYYY-MM-DD
+type ApprovalDelegationRequest struct {
+	DelegatorID *uuid.UUID `json:"delegator_id,omitempty"`
+	DelegateID  uuid.UUID  `json:"delegate_id"`
+	Kinds       []string   `json:"kinds,omitempty"`
+	StartsOn    string     `json:"starts_on,omitempty"`
+	EndsOn      string     `json:"ends_on"`
+	Reason      *string    `json:"reason,omitempty"`
+}
+
+// DelegatedApproval is an approval a delegate made on the delegator's behalf
+type DelegatedApproval struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	TenantID     uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	DelegationID uuid.UUID  `json:"delegation_id" db:"delegation_id"`
+	Kind         string     `json:"kind" db:"kind"`
+	EntityID     uuid.UUID  `json:"entity_id" db:"entity_id"`
+	Action       string     `json:"action" db:"action"`
+	ActedBy      *uuid.UUID `json:"acted_by,omitempty" db:"acted_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PendingApproval is an item waiting in an approver's inbox. OnBehalfOf is
+// set for items routed to the approver by a delegation
+type PendingApproval struct {
+	Kind        string     `json:"kind"`
+	ID          uuid.UUID  `json:"id"`
+	Description string     `json:"description"`
+	Quantity    int        `json:"quantity"`
+	CreatedAt   time.Time  `json:"created_at"`
+	OnBehalfOf  *uuid.UUID `json:"on_behalf_of,omitempty"`
+}
@@ -16,6 +16,7 @@ type AuditLog struct {
 	NewValues  JSONB      `json:"new_values" db:"new_values"`
 	OldValues  JSONB      `json:"old_values" db:"old_values"`
 	ChangedBy  *uuid.UUID `json:"changed_by" db:"changed_by"`
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty" db:"impersonator_id"` // Real actor when ChangedBy is impersonated
 	Deleted    bool       `json:"deleted" db:"deleted"`
 	DeletedAt  *time.Time `json:"deleted_at" db:"deleted_at"`
 	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
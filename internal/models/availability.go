@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WarehouseAvailability is a product's stock position in one warehouse
+type WarehouseAvailability struct {
+	WarehouseID        uuid.UUID `json:"warehouse_id"`
+	WarehouseName      string    `json:"warehouse_name"`
+	IsDefault          bool      `json:"is_default"`
+	OnHand             int       `json:"on_hand"`
+	Reserved           int       `json:"reserved"`
+	AvailableToPromise int       `json:"available_to_promise"`
+	InTransit          int       `json:"in_transit"`
+	// Buildable is how many more bundles the warehouse's component stock can assemble
+	Buildable int `json:"buildable,omitempty"`
+}
+
+// ProductAvailability answers "how many can I sell today?" across warehouses
+type ProductAvailability struct {
+	ProductID          uuid.UUID                `json:"product_id"`
+	OnHand             int                      `json:"on_hand"`
+	Reserved           int                      `json:"reserved"`
+	AvailableToPromise int                      `json:"available_to_promise"`
+	InTransit          int                      `json:"in_transit"`
+	Buildable          int                      `json:"buildable,omitempty"`
+	Warehouses         []*WarehouseAvailability `json:"warehouses"`
+	CalculatedAt       time.Time                `json:"calculated_at"`
+}
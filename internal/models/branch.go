@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Branch is a legal entity or registered place of business of the tenant
+// with its own GSTIN. Warehouses belong to a branch, and orders and invoices
+// follow them
+type Branch struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Code      string    `json:"code" db:"code"`
+	Name      string    `json:"name" db:"name"`
+	LegalName *string   `json:"legal_name,omitempty" db:"legal_name"`
+	GSTIN     string    `json:"gstin" db:"gstin"`
+	// StateCode is the GST state code, the first two digits of the GSTIN
+	StateCode  string     `json:"state_code" db:"state_code"`
+	Address    *string    `json:"address,omitempty" db:"address"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
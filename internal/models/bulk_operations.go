@@ -9,9 +9,11 @@ import (
 type BulkOperationResult struct {
 	OperationID    string                  `json:"operation_id"`               // Unique operation ID
 	Status         string                  `json:"status"`                     // Status: "pending", "processing", "completed", "failed", "partial"
+	DryRun         bool                    `json:"dry_run,omitempty"`         // Items were checked but not changed
 	TotalItems     int                     `json:"total_items"`                // Total items to process
 	ProcessedItems int                     `json:"processed_items"`            // Successfully processed items
 	FailedItems    int                     `json:"failed_items"`              // Failed items
+	BlockedItems   int                     `json:"blocked_items,omitempty"`   // Items a business rule or dependent record stopped
 	Progress       float64                 `json:"progress"`                   // Progress percentage (0-100)
 	StartTime      time.Time               `json:"start_time"`                // Operation start time
 	CompletionTime *time.Time              `json:"completion_time,omitempty"` // Operation completion time
@@ -30,8 +32,9 @@ type BulkOperationError struct {
 type BulkOperationItem struct {
 	ItemIndex int       `json:"item_index"` // Index of the item
 	ItemID    string    `json:"item_id"`    // ID of the item
-	Status    string    `json:"status"`     // Status: "success", "failed"
-	Error     *string   `json:"error,omitempty"` // Error message if failed
+	Status    string    `json:"status"`     // Status: "success", "failed", "blocked"
+	Error     *string   `json:"error,omitempty"` // Error message if failed or blocked
+	BlockedBy []*BlockingReference `json:"blocked_by,omitempty"` // Dependent records that block the item
 }
 
 // BulkOperationQueue represents a queued bulk operation
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BundleComponent is one component product and how many go into a bundle
+type BundleComponent struct {
+	BundleID      uuid.UUID `json:"bundle_id" db:"bundle_id"`
+	ComponentID   uuid.UUID `json:"component_id" db:"component_id"`
+	ComponentName string    `json:"component_name"`
+	Quantity      int       `json:"quantity" db:"quantity"`
+}
+
+// BundleComponentStock is a component's requirement and stock for a build
+type BundleComponentStock struct {
+	ComponentID uuid.UUID `json:"component_id"`
+	Required    int       `json:"required"`
+	Available   int       `json:"available"`
+}
+
+// BundleBuildResult reports an assembly or disassembly in one warehouse
+type BundleBuildResult struct {
+	BundleID    uuid.UUID               `json:"bundle_id"`
+	WarehouseID uuid.UUID               `json:"warehouse_id"`
+	Quantity    int                     `json:"quantity"`
+	BundleStock int                     `json:"bundle_stock"`
+	Components  []*BundleComponentStock `json:"components"`
+}
+
+// BundleSale records how a processed bundle sales order drew stock: assembled
+// bundle units first, the remainder exploded into components
+type BundleSale struct {
+	OrderID       uuid.UUID          `json:"order_id" db:"order_id"`
+	TenantID      uuid.UUID          `json:"tenant_id" db:"tenant_id"`
+	BundleID      uuid.UUID          `json:"bundle_id" db:"bundle_id"`
+	WarehouseID   uuid.UUID          `json:"warehouse_id" db:"warehouse_id"`
+	BundleUnits   int                `json:"bundle_units" db:"bundle_units"`
+	ExplodedUnits int                `json:"exploded_units" db:"exploded_units"`
+	Components    []*BundleComponent `json:"components"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CashFlowAssumptions are the knobs of a cash flow projection run. Zero
+// values fall back to the defaults noted on each field
+type CashFlowAssumptions struct {
+	// OpeningBalance is cash on hand at the start of the first week
+	OpeningBalance float64 `json:"opening_balance"`
+	// UsePaymentHistory expects each customer to pay as late as they have on
+	// average over the last year; without it invoices are paid when due
+	UsePaymentHistory bool `json:"use_payment_history"`
+	// CollectionDelayDays shifts every expected receipt by a further number
+	// of days, e.g. 7 for a pessimistic run
+	CollectionDelayDays int `json:"collection_delay_days"`
+	// OverdueCollectionRate is the share of receipts already past their
+	// expected date that arrive in the first week (default 0.5)
+	OverdueCollectionRate float64 `json:"overdue_collection_rate"`
+	// SupplierPaymentDays is how long after receipt suppliers are paid (default 30)
+	SupplierPaymentDays int `json:"supplier_payment_days"`
+	// IncludeExpenses projects recurring warehouse expenses
+	IncludeExpenses bool `json:"include_expenses"`
+	// ExpenseMonths is how many past full months set the recurring run rate (default 3)
+	ExpenseMonths int `json:"expense_months"`
+	// ExpenseGrowthPercent scales the recurring expense run rate
+	ExpenseGrowthPercent float64 `json:"expense_growth_percent"`
+}
+
+// CashFlowPayable is a purchase order still to be paid for
+type CashFlowPayable struct {
+	OrderID          uuid.UUID  `json:"order_id"`
+	Status           string     `json:"status"`
+	Amount           float64    `json:"amount"`
+	ExpectedDelivery time.Time  `json:"expected_delivery"`
+	ReceivedAt       *time.Time `json:"received_at,omitempty"`
+}
+
+// CashFlowExpenseMonth is what a category cost in one month
+type CashFlowExpenseMonth struct {
+	CategoryID   uuid.UUID `json:"category_id"`
+	CategoryName string    `json:"category_name"`
+	Month        time.Time `json:"month"`
+	Amount       float64   `json:"amount"`
+}
+
+// CashFlowRecurringExpense is a category treated as a recurring cost and its
+// projected weekly amount
+type CashFlowRecurringExpense struct {
+	CategoryID     uuid.UUID `json:"category_id"`
+	CategoryName   string    `json:"category_name"`
+	MonthlyAverage float64   `json:"monthly_average"`
+	Weekly         float64   `json:"weekly"`
+}
+
+// CashFlowWeek is one projected week, Monday to Sunday
+type CashFlowWeek struct {
+	Week               int       `json:"week"`
+	WeekStart          time.Time `json:"week_start"`
+	WeekEnd            time.Time `json:"week_end"`
+	OpeningBalance     float64   `json:"opening_balance"`
+	Receivables        float64   `json:"receivables"`
+	PurchaseOrders     float64   `json:"purchase_orders"`
+	Expenses           float64   `json:"expenses"`
+	TotalInflows       float64   `json:"total_inflows"`
+	TotalOutflows      float64   `json:"total_outflows"`
+	NetCashFlow        float64   `json:"net_cash_flow"`
+	ClosingBalance     float64   `json:"closing_balance"`
+	InvoiceCount       int       `json:"invoice_count"`
+	PurchaseOrderCount int       `json:"purchase_order_count"`
+}
+
+// CashFlowProjection projects weekly cash in and out over the coming weeks.
+// Receipts and payments expected after the last week are reported as totals
+// beyond the horizon; DoubtfulReceivables is the overdue balance the run
+// assumes will not be collected in the first week
+type CashFlowProjection struct {
+	AsOf                  time.Time                   `json:"as_of"`
+	Assumptions           CashFlowAssumptions         `json:"assumptions"`
+	Weeks                 []*CashFlowWeek             `json:"weeks"`
+	TotalInflows          float64                     `json:"total_inflows"`
+	TotalOutflows         float64                     `json:"total_outflows"`
+	ClosingBalance        float64                     `json:"closing_balance"`
+	LowestBalance         float64                     `json:"lowest_balance"`
+	LowestBalanceWeek     int                         `json:"lowest_balance_week"`
+	InflowsBeyondHorizon  float64                     `json:"inflows_beyond_horizon"`
+	OutflowsBeyondHorizon float64                     `json:"outflows_beyond_horizon"`
+	DoubtfulReceivables   float64                     `json:"doubtful_receivables"`
+	RecurringExpenses     []*CashFlowRecurringExpense `json:"recurring_expenses"`
+}
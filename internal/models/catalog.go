@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Catalog stock availability flags; exact quantities are not exposed publicly
+const (
+	CatalogInStock    = "in_stock"
+	CatalogLowStock   = "low_stock"
+	CatalogOutOfStock = "out_of_stock"
+)
+
+// CatalogToken grants read-only access to a tenant's published catalog
+type CatalogToken struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Name        string     `json:"name" db:"name"`
+	TokenPrefix string     `json:"token_prefix" db:"token_prefix"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// CatalogProduct is the storefront view of a published product
+type CatalogProduct struct {
+	ID            uuid.UUID           `json:"id"`
+	CategoryID    *uuid.UUID          `json:"category_id,omitempty"`
+	Name          string              `json:"name"`
+	Description   *string             `json:"description,omitempty"`
+	Translations  ProductTranslations `json:"-"`
+	UnitPrice     float64             `json:"unit_price"`
+	UnitOfMeasure *string             `json:"unit_of_measure,omitempty"`
+	Barcode       *string             `json:"barcode,omitempty"`
+	Availability  string              `json:"availability"`
+	Images        []CatalogImage      `json:"images"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// CatalogImage links to a product image through the catalog API
+type CatalogImage struct {
+	ID      uuid.UUID `json:"id"`
+	URL     string    `json:"url"`
+	AltText *string   `json:"alt_text,omitempty"`
+}
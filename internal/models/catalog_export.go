@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Catalog export statuses
+const (
+	CatalogExportRunning   = "running"
+	CatalogExportSucceeded = "succeeded"
+	CatalogExportFailed    = "failed"
+)
+
+// CatalogExportRequest selects what a generated catalog PDF contains
+type CatalogExportRequest struct {
+	Title string `json:"title"`
+	// PriceListID prints prices from a supplier price list and limits the
+	// catalog to its products; without it product unit prices are printed
+	PriceListID   *uuid.UUID  `json:"price_list_id,omitempty"`
+	CategoryIDs   []uuid.UUID `json:"category_ids,omitempty"`
+	PublishedOnly bool        `json:"published_only"`
+	ExcludeImages bool        `json:"exclude_images"`
+}
+
+// CatalogExport records one generated catalog PDF
+type CatalogExport struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Title         string     `json:"title" db:"title"`
+	PriceListID   *uuid.UUID `json:"price_list_id,omitempty" db:"price_list_id"`
+	Status        string     `json:"status" db:"status"`
+	ProductsTotal int        `json:"products_total" db:"products_total"`
+	ProductsDone  int        `json:"products_done" db:"products_done"`
+	FileName      *string    `json:"file_name,omitempty" db:"file_name"`
+	ObjectKey     *string    `json:"-" db:"object_key"`
+	ErrorMessage  *string    `json:"error_message,omitempty" db:"error_message"`
+	RequestedBy   *uuid.UUID `json:"requested_by,omitempty" db:"requested_by"`
+	StartedAt     time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	// Progress is the percentage of products rendered
+	Progress float64 `json:"progress"`
+	// DownloadURL is a presigned link to the PDF once generation succeeds
+	DownloadURL *string `json:"download_url,omitempty"`
+}
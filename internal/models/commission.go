@@ -0,0 +1,158 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Who earns commission: sales staff, through the customers assigned to them
+// as sales rep, and external agents assigned to customers
+const (
+	CommissionPayeeUser  = "user"
+	CommissionPayeeAgent = "agent"
+)
+
+// What commission is paid on: the taxable value invoiced in the month, less
+// returns, or the amount collected from customers in it
+const (
+	CommissionBasisInvoiced  = "invoiced"
+	CommissionBasisCollected = "collected"
+)
+
+// Commission run statuses
+const (
+	CommissionRunDraft     = "draft"
+	CommissionRunFinalized = "finalized"
+)
+
+// Kinds of documents behind a commission line
+const (
+	CommissionItemInvoice    = "invoice"
+	CommissionItemWithheld   = "withheld"
+	CommissionItemReleased   = "released"
+	CommissionItemReturn     = "return"
+	CommissionItemCollection = "collection"
+)
+
+// CommissionAgent is an external agent paid commission on the customers
+// assigned to them
+type CommissionAgent struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Name      string    `json:"name" db:"name"`
+	Phone     *string   `json:"phone,omitempty" db:"phone"`
+	Email     *string   `json:"email,omitempty" db:"email"`
+	PAN       *string   `json:"pan,omitempty" db:"pan"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CommissionAgentRequest creates or updates an agent; on update, omitted
+// fields are left as they are
+type CommissionAgentRequest struct {
+	Name   *string `json:"name,omitempty"`
+	Phone  *string `json:"phone,omitempty"`
+	Email  *string `json:"email,omitempty"`
+	PAN    *string `json:"pan,omitempty"`
+	Active *bool   `json:"active,omitempty"`
+}
+
+// CustomerAgentRequest assigns a customer to a commission agent; nil clears it
+type CustomerAgentRequest struct {
+	AgentID *uuid.UUID `json:"agent_id"`
+}
+
+// CommissionSlab applies RatePercent to the part of the month's amount from
+// FromAmount up to the next slab
+type CommissionSlab struct {
+	FromAmount  float64 `json:"from_amount"`
+	RatePercent float64 `json:"rate_percent"`
+}
+
+// CommissionRule sets how a payee's commission is worked out. Without a
+// PayeeID it is the default for every payee of PayeeType. Slabs are ordered
+// by FromAmount, the first from zero
+type CommissionRule struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	TenantID  uuid.UUID        `json:"tenant_id" db:"tenant_id"`
+	Name      string           `json:"name" db:"name"`
+	PayeeType string           `json:"payee_type" db:"payee_type"`
+	PayeeID   *uuid.UUID       `json:"payee_id,omitempty" db:"payee_id"`
+	Basis     string           `json:"basis" db:"basis"`
+	Slabs     []CommissionSlab `json:"slabs"`
+	Active    bool             `json:"active" db:"active"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// CommissionRuleRequest creates or updates a rule. RatePercent is shorthand
+// for a single slab from zero. On update, payee_type and payee_id stay as
+// they are and omitted fields are left as they are
+type CommissionRuleRequest struct {
+	Name        *string          `json:"name,omitempty"`
+	PayeeType   string           `json:"payee_type"`
+	PayeeID     *uuid.UUID       `json:"payee_id,omitempty"`
+	Basis       *string          `json:"basis,omitempty"`
+	RatePercent *float64         `json:"rate_percent,omitempty"`
+	Slabs       []CommissionSlab `json:"slabs,omitempty"`
+	Active      *bool            `json:"active,omitempty"`
+}
+
+// CommissionItem is a document counted towards, withheld from or deducted
+// from a payee's commission
+type CommissionItem struct {
+	PayeeType      string    `json:"payee_type"`
+	PayeeID        uuid.UUID `json:"payee_id"`
+	PayeeName      string    `json:"payee_name"`
+	Kind           string    `json:"kind"`
+	DocumentID     uuid.UUID `json:"document_id"`
+	DocumentNumber string    `json:"document_number"`
+	DocumentDate   time.Time `json:"document_date"`
+	Amount         float64   `json:"amount"`
+}
+
+// CommissionLine is one payee's commission for the month. BaseAmount is what
+// the rule's slabs are applied to: invoiced plus released less returns, or
+// collected, as Basis says
+type CommissionLine struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	RunID            uuid.UUID  `json:"run_id" db:"run_id"`
+	PayeeType        string     `json:"payee_type" db:"payee_type"`
+	PayeeID          uuid.UUID  `json:"payee_id" db:"payee_id"`
+	PayeeName        string     `json:"payee_name" db:"payee_name"`
+	RuleID           *uuid.UUID `json:"rule_id,omitempty" db:"rule_id"`
+	Basis            string     `json:"basis" db:"basis"`
+	InvoicedAmount   float64    `json:"invoiced_amount" db:"invoiced_amount"`
+	ReleasedAmount   float64    `json:"released_amount" db:"released_amount"`
+	ReturnsAmount    float64    `json:"returns_amount" db:"returns_amount"`
+	WithheldAmount   float64    `json:"withheld_amount" db:"withheld_amount"`
+	CollectedAmount  float64    `json:"collected_amount" db:"collected_amount"`
+	BaseAmount       float64    `json:"base_amount" db:"base_amount"`
+	CommissionAmount float64    `json:"commission_amount" db:"commission_amount"`
+}
+
+// CommissionRun is a month's commission for every payee with a rule
+type CommissionRun struct {
+	ID              uuid.UUID         `json:"id" db:"id"`
+	TenantID        uuid.UUID         `json:"tenant_id" db:"tenant_id"`
+	Month           time.Time         `json:"month" db:"period_month"`
+	Status          string            `json:"status" db:"status"`
+	TotalCommission float64           `json:"total_commission" db:"total_commission"`
+	CreatedBy       *uuid.UUID        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
+	FinalizedBy     *uuid.UUID        `json:"finalized_by,omitempty" db:"finalized_by"`
+	FinalizedAt     *time.Time        `json:"finalized_at,omitempty" db:"finalized_at"`
+	Lines           []*CommissionLine `json:"lines,omitempty"`
+}
+
+// CommissionStatement is one payee's commission for a run with the
+// documents behind it
+type CommissionStatement struct {
+	RunID  uuid.UUID         `json:"run_id"`
+	Month  time.Time         `json:"month"`
+	Status string            `json:"status"`
+	Line   *CommissionLine   `json:"line"`
+	Items  []*CommissionItem `json:"items"`
+}
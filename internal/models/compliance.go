@@ -0,0 +1,153 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// License holder types
+const (
+	LicenseHolderTenant      = "tenant"
+	LicenseHolderSupplier    = "supplier"
+	LicenseHolderWarehouse   = "warehouse"
+	LicenseHolderDistributor = "distributor"
+)
+
+// License types
+const (
+	LicenseTypePesticide  = "pesticide"
+	LicenseTypeFertilizer = "fertilizer"
+	LicenseTypeSeed       = "seed"
+	LicenseTypeOther      = "other"
+)
+
+// Compliance operations that a missing license blocks
+const (
+	ComplianceOperationSell     = "sell"
+	ComplianceOperationPurchase = "purchase"
+)
+
+// LicenseExpiryAlertDays are the days before expiry at which alerts are sent
+var LicenseExpiryAlertDays = []int{90, 30, 7}
+
+// ComplianceLicense is a license held by the tenant, a supplier or a
+// warehouse; it is valid through ExpiresOn inclusive
+type ComplianceLicense struct {
+	ID                  uuid.UUID  `json:"id" db:"id"`
+	TenantID            uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	HolderType          string     `json:"holder_type" db:"holder_type"`
+	HolderID            uuid.UUID  `json:"holder_id" db:"holder_id"`
+	HolderName          string     `json:"holder_name,omitempty"`
+	LicenseType         string     `json:"license_type" db:"license_type"`
+	LicenseNumber       string     `json:"license_number" db:"license_number"`
+	IssuingAuthority    *string    `json:"issuing_authority,omitempty" db:"issuing_authority"`
+	ValidFrom           *time.Time `json:"valid_from,omitempty" db:"valid_from"`
+	ExpiresOn           time.Time  `json:"expires_on" db:"expires_on"`
+	DocumentKey         *string    `json:"-" db:"document_key"`
+	DocumentName        *string    `json:"document_name,omitempty" db:"document_name"`
+	DocumentContentType *string    `json:"document_content_type,omitempty" db:"document_content_type"`
+	Notes               *string    `json:"notes,omitempty" db:"notes"`
+	LastAlertDays       *int       `json:"-" db:"last_alert_days"`
+	CreatedBy           *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+	// Derived
+	DaysToExpiry int    `json:"days_to_expiry"`
+	Status       string `json:"status"` // valid, expiring, expired
+}
+
+// License statuses derived from the expiry date
+const (
+	LicenseStatusValid    = "valid"
+	LicenseStatusExpiring = "expiring"
+	LicenseStatusExpired  = "expired"
+)
+
+// ComplianceLicenseFilter narrows a license listing
+type ComplianceLicenseFilter struct {
+	HolderType     *string
+	HolderID       *uuid.UUID
+	LicenseType    *string
+	ExpiringBefore *time.Time
+}
+
+// BlockedOperation is an operation a missing or expired license prevents
+type BlockedOperation struct {
+	Operation   string     `json:"operation"`
+	LicenseType string     `json:"license_type"`
+	HolderType  string     `json:"holder_type"`
+	HolderID    uuid.UUID  `json:"holder_id"`
+	HolderName  string     `json:"holder_name"`
+	OrderID     *uuid.UUID `json:"order_id,omitempty"`
+	Reason      string     `json:"reason"`
+}
+
+// ComplianceStatus summarises license health and what it currently blocks
+type ComplianceStatus struct {
+	AsOf              time.Time            `json:"as_of"`
+	Compliant         bool                 `json:"compliant"`
+	ValidLicenses     int                  `json:"valid_licenses"`
+	Expiring          []*ComplianceLicense `json:"expiring"`
+	Expired           []*ComplianceLicense `json:"expired"`
+	RestrictedTypes   []string             `json:"restricted_types"`
+	BlockedOperations []*BlockedOperation  `json:"blocked_operations"`
+}
+
+// OpenRestrictedPurchase is an open purchase order for a product requiring a license
+type OpenRestrictedPurchase struct {
+	OrderID      uuid.UUID
+	SupplierID   uuid.UUID
+	SupplierName string
+	LicenseType  string
+}
+
+// Product regulatory flags; any flag requires a licensed buyer
+const (
+	RegulatoryFlagRedTriangle    = "red_triangle"
+	RegulatoryFlagYellowTriangle = "yellow_triangle"
+	RegulatoryFlagBlueTriangle   = "blue_triangle"
+	RegulatoryFlagGreenTriangle  = "green_triangle"
+	RegulatoryFlagRestricted     = "restricted"
+)
+
+// Reasons a buyer license check fails
+const (
+	BuyerLicenseMissing = "missing"
+	BuyerLicenseExpired = "expired"
+)
+
+// OrderBuyerLicense is the buyer license captured on a restricted sales order
+type OrderBuyerLicense struct {
+	OrderID          uuid.UUID  `json:"order_id" db:"order_id"`
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	LicenseID        *uuid.UUID `json:"license_id" db:"license_id"`
+	LicenseNumber    string     `json:"license_number" db:"license_number"`
+	LicenseExpiresOn time.Time  `json:"license_expires_on" db:"license_expires_on"`
+	CapturedBy       *uuid.UUID `json:"captured_by,omitempty" db:"captured_by"`
+	CapturedAt       time.Time  `json:"captured_at" db:"captured_at"`
+}
+
+// RestrictedSaleOverride records a restricted sale confirmed without a valid
+// buyer license
+type RestrictedSaleOverride struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	TenantID       uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	OrderID        uuid.UUID  `json:"order_id" db:"order_id"`
+	ProductID      uuid.UUID  `json:"product_id" db:"product_id"`
+	DistributorID  *uuid.UUID `json:"distributor_id,omitempty" db:"distributor_id"`
+	RegulatoryFlag string     `json:"regulatory_flag" db:"regulatory_flag"`
+	LicenseID      *uuid.UUID `json:"license_id,omitempty" db:"license_id"`
+	LicenseProblem string     `json:"license_problem" db:"license_problem"`
+	Reason         string     `json:"reason" db:"reason"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RestrictedSaleOverrideFilter narrows the override trail
+type RestrictedSaleOverrideFilter struct {
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Consignment liability statuses
+const (
+	ConsignmentLiabilityOpen     = "open"
+	ConsignmentLiabilitySettled  = "settled"
+	ConsignmentLiabilityReversed = "reversed"
+)
+
+// ConsignmentStock is supplier-owned stock held in a warehouse. The units are
+// also part of inventory so they can be sold, but they are excluded from
+// stock valuation until sold.
+type ConsignmentStock struct {
+	TenantID        uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	SupplierID      uuid.UUID `json:"supplier_id" db:"supplier_id"`
+	SupplierName    string    `json:"supplier_name,omitempty"`
+	WarehouseID     uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	ProductID       uuid.UUID `json:"product_id" db:"product_id"`
+	ProductName     string    `json:"product_name,omitempty"`
+	Quantity        int       `json:"quantity" db:"quantity"`
+	UnitCost        float64   `json:"unit_cost" db:"unit_cost" perm:"products:view_cost"`
+	FirstReceivedAt time.Time `json:"first_received_at" db:"first_received_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ConsignmentLiability is the amount owed to a supplier for consignment units sold
+type ConsignmentLiability struct {
+	ID                  uuid.UUID  `json:"id" db:"id"`
+	TenantID            uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	SupplierID          uuid.UUID  `json:"supplier_id" db:"supplier_id"`
+	OrderID             uuid.UUID  `json:"order_id" db:"order_id"`
+	WarehouseID         uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	ProductID           uuid.UUID  `json:"product_id" db:"product_id"`
+	ProductName         string     `json:"product_name,omitempty"`
+	Quantity            int        `json:"quantity" db:"quantity"`
+	UnitCost            float64    `json:"unit_cost" db:"unit_cost" perm:"products:view_cost"`
+	Amount              float64    `json:"amount" db:"amount"`
+	SaleUnitPrice       float64    `json:"sale_unit_price" db:"sale_unit_price"`
+	Status              string     `json:"status" db:"status"`
+	SettlementReference *string    `json:"settlement_reference,omitempty" db:"settlement_reference"`
+	SettledAt           *time.Time `json:"settled_at,omitempty" db:"settled_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ConsignmentSettlementRow totals one supplier's consignment position. Period
+// figures cover liabilities raised in the report window; on-hand and open
+// figures are as of now.
+type ConsignmentSettlementRow struct {
+	SupplierID      uuid.UUID `json:"supplier_id"`
+	SupplierName    string    `json:"supplier_name"`
+	OnHandQuantity  int       `json:"on_hand_quantity"`
+	OnHandValue     float64   `json:"on_hand_value"`
+	SoldQuantity    int       `json:"sold_quantity"`
+	SalesValue      float64   `json:"sales_value"`
+	LiabilityAmount float64   `json:"liability_amount"`
+	SettledAmount   float64   `json:"settled_amount"`
+	OpenLiability   float64   `json:"open_liability"`
+}
+
+// ConsignmentSettlementReport is the per-supplier consignment settlement report
+type ConsignmentSettlementReport struct {
+	From        time.Time                   `json:"from"`
+	To          time.Time                   `json:"to"`
+	Suppliers   []*ConsignmentSettlementRow `json:"suppliers"`
+	Liabilities []*ConsignmentLiability     `json:"liabilities,omitempty"`
+}
+
+// ConsignmentSettlement is the result of settling a supplier's open liabilities
+type ConsignmentSettlement struct {
+	SupplierID  uuid.UUID `json:"supplier_id"`
+	Reference   string    `json:"reference"`
+	UpTo        time.Time `json:"up_to"`
+	Liabilities int       `json:"liabilities"`
+	Amount      float64   `json:"amount"`
+	SettledAt   time.Time `json:"settled_at"`
+}
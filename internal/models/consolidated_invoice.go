@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsolidatedInvoiceRequest invoices a customer's delivered sales orders
+// dated From to To, YYYY-MM-DD. OrderIDs picks some of them; by default every
+// order in the period not yet invoiced is included
+type ConsolidatedInvoiceRequest struct {
+	DistributorID uuid.UUID   `json:"customer_id"`
+	From          string      `json:"from"`
+	To            string      `json:"to"`
+	OrderIDs      []uuid.UUID `json:"order_ids,omitempty"`
+}
+
+// ConsolidatedInvoice is an invoice covering several orders of one customer,
+// with a line per order
+type ConsolidatedInvoice struct {
+	*Invoice
+	DistributorID uuid.UUID                  `json:"customer_id"`
+	PeriodFrom    time.Time                  `json:"period_from"`
+	PeriodTo      time.Time                  `json:"period_to"`
+	Lines         []*ConsolidatedInvoiceLine `json:"lines"`
+	TCS           *InvoiceTCS                `json:"tcs,omitempty"`
+}
+
+// ConsolidatedInvoiceLine is one order on a consolidated invoice and its tax
+type ConsolidatedInvoiceLine struct {
+	InvoiceID     uuid.UUID `json:"invoice_id" db:"invoice_id"`
+	OrderID       uuid.UUID `json:"order_id" db:"order_id"`
+	LineNumber    int       `json:"line_number" db:"line_number"`
+	ProductID     uuid.UUID `json:"product_id" db:"product_id"`
+	ProductName   string    `json:"product_name" db:"-"`
+	OrderDate     time.Time `json:"order_date" db:"-"`
+	Quantity      int       `json:"quantity" db:"quantity"`
+	UnitPrice     float64   `json:"unit_price" db:"unit_price"`
+	TaxableAmount float64   `json:"taxable_amount" db:"taxable_amount"`
+	GSTRate       float64   `json:"gst_rate" db:"gst_rate"`
+	CGST          float64   `json:"cgst" db:"cgst"`
+	SGST          float64   `json:"sgst" db:"sgst"`
+	IGST          float64   `json:"igst" db:"igst"`
+	TotalAmount   float64   `json:"total_amount" db:"total_amount"`
+
+	// BranchID is the branch the order was placed through; every line of
+	// an invoice comes from one branch
+	BranchID *uuid.UUID `json:"-" db:"-"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cash register statuses
+const (
+	CashRegisterOpen   = "open"
+	CashRegisterClosed = "closed"
+)
+
+// Counter sale payment methods; only cash goes through the register drawer
+const (
+	PaymentMethodCash = "cash"
+	PaymentMethodUPI  = "upi"
+	PaymentMethodCard = "card"
+)
+
+// Denominations counts notes and coins by face value in rupees, e.g. {"500": 4, "10": 7}
+type Denominations map[string]int
+
+// CashRegister is one register session at a warehouse or store, from opening
+// with a float to closing with a cash count
+type CashRegister struct {
+	ID                   uuid.UUID     `json:"id" db:"id"`
+	TenantID             uuid.UUID     `json:"tenant_id" db:"tenant_id"`
+	WarehouseID          uuid.UUID     `json:"warehouse_id" db:"warehouse_id"`
+	Status               string        `json:"status" db:"status"`
+	OpeningFloat         float64       `json:"opening_float" db:"opening_float"`
+	OpeningDenominations Denominations `json:"opening_denominations" db:"opening_denominations"`
+	OpenedBy             *uuid.UUID    `json:"opened_by,omitempty" db:"opened_by"`
+	OpenedAt             time.Time     `json:"opened_at" db:"opened_at"`
+	ClosingDenominations Denominations `json:"closing_denominations,omitempty" db:"closing_denominations"`
+	CountedCash          *float64      `json:"counted_cash,omitempty" db:"counted_cash"`
+	ExpectedCash         *float64      `json:"expected_cash,omitempty" db:"expected_cash"`
+	// Variance is counted minus expected cash; negative means the drawer is short
+	Variance *float64   `json:"variance,omitempty" db:"variance"`
+	ClosedBy *uuid.UUID `json:"closed_by,omitempty" db:"closed_by"`
+	ClosedAt *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+	Notes    *string    `json:"notes,omitempty" db:"notes"`
+	// Derived while the register is open
+	CashSales float64 `json:"cash_sales"`
+}
+
+// CounterSale is a walk-in sale rung up at a register: the sales order, its
+// invoice and the payment taken for it
+type CounterSale struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	WarehouseID      uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	RegisterID       uuid.UUID  `json:"register_id" db:"register_id"`
+	OrderID          uuid.UUID  `json:"order_id" db:"order_id"`
+	InvoiceID        *uuid.UUID `json:"invoice_id,omitempty" db:"invoice_id"`
+	DistributorID    uuid.UUID  `json:"customer_id" db:"distributor_id"`
+	ProductID        uuid.UUID  `json:"product_id" db:"product_id"`
+	Quantity         int        `json:"quantity" db:"quantity"`
+	Amount           float64    `json:"amount" db:"amount"`
+	PaymentMethod    string     `json:"payment_method" db:"payment_method"`
+	AmountTendered   *float64   `json:"amount_tendered,omitempty" db:"amount_tendered"`
+	ChangeGiven      float64    `json:"change_given" db:"change_given"`
+	PaymentReference *string    `json:"payment_reference,omitempty" db:"payment_reference"`
+	CustomerName     *string    `json:"customer_name,omitempty" db:"customer_name"`
+	CustomerPhone    *string    `json:"customer_phone,omitempty" db:"customer_phone"`
+	CreatedBy        *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	// Joined for receipts and the day book
+	InvoiceNumber *string `json:"invoice_number,omitempty"`
+	ProductName   string  `json:"product_name,omitempty"`
+}
+
+// CounterSaleRequest rings up one product. Without CustomerID the sale goes
+// to the tenant's walk-in customer; UnitPrice defaults to the product price
+type CounterSaleRequest struct {
+	WarehouseID      uuid.UUID  `json:"warehouse_id"`
+	ProductID        uuid.UUID  `json:"product_id"`
+	Quantity         int        `json:"quantity"`
+	UnitPrice        *float64   `json:"unit_price,omitempty"`
+	CustomerID       *uuid.UUID `json:"customer_id,omitempty"`
+	CustomerName     *string    `json:"customer_name,omitempty"`
+	CustomerPhone    *string    `json:"customer_phone,omitempty"`
+	PaymentMethod    string     `json:"payment_method"`
+	AmountTendered   *float64   `json:"amount_tendered,omitempty"`
+	PaymentReference *string    `json:"payment_reference,omitempty"`
+	BuyerLicenseID   *uuid.UUID `json:"buyer_license_id,omitempty"`
+}
+
+// CounterSaleReceipt is what a counter sale returns to the till
+type CounterSaleReceipt struct {
+	Sale    *CounterSale `json:"sale"`
+	Order   *Order       `json:"order"`
+	Invoice *Invoice     `json:"invoice"`
+}
+
+// OpenRegisterRequest opens a register with its float counted by denomination
+type OpenRegisterRequest struct {
+	WarehouseID   uuid.UUID     `json:"warehouse_id"`
+	Denominations Denominations `json:"denominations"`
+}
+
+// CloseRegisterRequest closes a register with the drawer counted by denomination
+type CloseRegisterRequest struct {
+	Denominations Denominations `json:"denominations"`
+	Notes         *string       `json:"notes,omitempty"`
+}
+
+// CashRegisterFilter narrows a register listing
+type CashRegisterFilter struct {
+	WarehouseID *uuid.UUID
+	Status      *string
+	Limit       int
+	Offset      int
+}
+
+// DayBookTotals sums counter sales, overall and by payment method
+type DayBookTotals struct {
+	Sales int     `json:"sales"`
+	Total float64 `json:"total"`
+	Cash  float64 `json:"cash"`
+	UPI   float64 `json:"upi"`
+	Card  float64 `json:"card"`
+}
+
+// DayBookStore is one warehouse or store's counter activity for the day
+type DayBookStore struct {
+	WarehouseID   uuid.UUID       `json:"warehouse_id"`
+	WarehouseName string          `json:"warehouse_name"`
+	Registers     []*CashRegister `json:"registers"`
+	Sales         []*CounterSale  `json:"sales"`
+	Totals        DayBookTotals   `json:"totals"`
+}
+
+// DayBook is every counter transaction of a day, per warehouse or store
+type DayBook struct {
+	Date   time.Time       `json:"date"`
+	Stores []*DayBookStore `json:"stores"`
+	Totals DayBookTotals   `json:"totals"`
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Analytical stores a data export can push to
+const (
+	DataExportBigQuery   = "bigquery"
+	DataExportClickHouse = "clickhouse"
+)
+
+// Fact tables a data export pushes
+const (
+	DataExportFactOrders                = "orders"
+	DataExportFactOrderItems            = "order_items"
+	DataExportFactInvoices              = "invoices"
+	DataExportFactInventoryTransactions = "inventory_transactions"
+)
+
+// DataExportFacts lists every fact table in export order
+var DataExportFacts = []string{DataExportFactOrders, DataExportFactOrderItems, DataExportFactInvoices, DataExportFactInventoryTransactions}
+
+// Data export run statuses and triggers
+const (
+	DataExportRunning   = "running"
+	DataExportSucceeded = "succeeded"
+	DataExportFailed    = "failed"
+
+	DataExportTriggerManual    = "manual"
+	DataExportTriggerScheduled = "scheduled"
+)
+
+// DataExportDestination is a tenant's analytical store that fact tables are
+// pushed to. It only runs with the consent of a tenant admin, recorded when
+// it is created
+type DataExportDestination struct {
+	ID                  uuid.UUID          `json:"id" db:"id"`
+	TenantID            uuid.UUID          `json:"tenant_id" db:"tenant_id"`
+	Name                string             `json:"name" db:"name"`
+	Provider            string             `json:"provider" db:"provider"`
+	Settings            DataExportSettings `json:"settings" db:"settings"`
+	Credentials         string             `json:"-" db:"credentials"`
+	HasCredentials      bool               `json:"has_credentials" db:"-"`
+	Facts               []string           `json:"facts" db:"facts"`
+	SyncIntervalMinutes int                `json:"sync_interval_minutes" db:"sync_interval_minutes"`
+	NextSyncAt          *time.Time         `json:"next_sync_at,omitempty" db:"next_sync_at"`
+	IsActive            bool               `json:"is_active" db:"is_active"`
+	ConsentedBy         *uuid.UUID         `json:"consented_by,omitempty" db:"consented_by"`
+	ConsentedAt         time.Time          `json:"consented_at" db:"consented_at"`
+	CreatedAt           time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// DataExportSettings locates the store: ProjectID and Dataset for BigQuery,
+// URL, Database and Username for ClickHouse's HTTP interface. Tables are
+// named after the facts with TablePrefix in front
+type DataExportSettings struct {
+	ProjectID   string `json:"project_id,omitempty"`
+	Dataset     string `json:"dataset,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Database    string `json:"database,omitempty"`
+	Username    string `json:"username,omitempty"`
+	TablePrefix string `json:"table_prefix,omitempty"`
+}
+
+// DataExportCursor is the (changed_at, id) of the last row of a fact table
+// pushed to a destination
+type DataExportCursor struct {
+	DestinationID  uuid.UUID  `json:"destination_id" db:"destination_id"`
+	Fact           string     `json:"fact" db:"fact"`
+	LastExportedAt *time.Time `json:"last_exported_at,omitempty" db:"last_exported_at"`
+	LastExportedID *uuid.UUID `json:"last_exported_id,omitempty" db:"last_exported_id"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DataExportRun records one push of a destination's changed rows
+type DataExportRun struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	DestinationID   uuid.UUID  `json:"destination_id" db:"destination_id"`
+	Trigger         string     `json:"trigger" db:"trigger"`
+	Status          string     `json:"status" db:"status"`
+	RecordsExported int        `json:"records_exported" db:"records_exported"`
+	HasMore         bool       `json:"has_more" db:"has_more"`
+	ErrorMessage    *string    `json:"error_message,omitempty" db:"error_message"`
+	StartedAt       time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// DataExportRecord is one row of a fact table as of ChangedAt. Rows are
+// appended on every change, so the store keeps the latest per RowID and
+// ChangedAt, e.g. with ReplacingMergeTree in ClickHouse
+type DataExportRecord struct {
+	RowID     uuid.UUID
+	ChangedAt time.Time
+	Row       interface{}
+}
+
+// OrderFact is an order with its parties and warehouse resolved
+type OrderFact struct {
+	OrderID          uuid.UUID  `json:"order_id"`
+	TenantID         uuid.UUID  `json:"tenant_id"`
+	OrderType        string     `json:"order_type"`
+	Status           string     `json:"status"`
+	OrderDate        time.Time  `json:"order_date"`
+	ExpectedDelivery *time.Time `json:"expected_delivery"`
+	CustomerID       *uuid.UUID `json:"customer_id"`
+	CustomerName     *string    `json:"customer_name"`
+	SupplierID       *uuid.UUID `json:"supplier_id"`
+	SupplierName     *string    `json:"supplier_name"`
+	WarehouseID      uuid.UUID  `json:"warehouse_id"`
+	WarehouseName    *string    `json:"warehouse_name"`
+	TotalQuantity    int        `json:"total_quantity"`
+	TotalAmount      float64    `json:"total_amount"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// OrderItemFact is a product line of an order; orders carry a single product,
+// so each has one line
+type OrderItemFact struct {
+	OrderID      uuid.UUID `json:"order_id"`
+	TenantID     uuid.UUID `json:"tenant_id"`
+	LineNumber   int       `json:"line_number"`
+	OrderType    string    `json:"order_type"`
+	OrderDate    time.Time `json:"order_date"`
+	ProductID    uuid.UUID `json:"product_id"`
+	ProductName  *string   `json:"product_name"`
+	CategoryName *string   `json:"category_name"`
+	Quantity     int       `json:"quantity"`
+	UnitPrice    float64   `json:"unit_price"`
+	LineAmount   float64   `json:"line_amount"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// InvoiceFact is an invoice with its customer resolved
+type InvoiceFact struct {
+	InvoiceID     uuid.UUID  `json:"invoice_id"`
+	TenantID      uuid.UUID  `json:"tenant_id"`
+	InvoiceNumber string     `json:"invoice_number"`
+	OrderID       uuid.UUID  `json:"order_id"`
+	CustomerID    *uuid.UUID `json:"customer_id"`
+	CustomerName  *string    `json:"customer_name"`
+	Status        string     `json:"status"`
+	IssuedDate    time.Time  `json:"issued_date"`
+	DueDate       time.Time  `json:"due_date"`
+	PaidDate      *time.Time `json:"paid_date"`
+	TaxableAmount *float64   `json:"taxable_amount"`
+	GSTRate       *float64   `json:"gst_rate"`
+	CGST          *float64   `json:"cgst"`
+	SGST          *float64   `json:"sgst"`
+	IGST          *float64   `json:"igst"`
+	TotalAmount   float64    `json:"total_amount"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// InventoryTransactionFact is a stock movement with its product and warehouse resolved
+type InventoryTransactionFact struct {
+	TransactionID  uuid.UUID  `json:"transaction_id"`
+	TenantID       uuid.UUID  `json:"tenant_id"`
+	WarehouseID    uuid.UUID  `json:"warehouse_id"`
+	WarehouseName  *string    `json:"warehouse_name"`
+	ProductID      uuid.UUID  `json:"product_id"`
+	ProductName    *string    `json:"product_name"`
+	QuantityChange int        `json:"quantity_change"`
+	QuantityAfter  int        `json:"quantity_after"`
+	Reason         string     `json:"reason"`
+	CreatedBy      *uuid.UUID `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TenantDataKey is a tenant's key for PII columns, stored wrapped with the
+// master key. The active key encrypts; retired keys only decrypt
+type TenantDataKey struct {
+	TenantID   uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Version    int        `json:"version" db:"version"`
+	WrappedKey []byte     `json:"-" db:"wrapped_key"` // Never serialize in JSON
+	IsActive   bool       `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty" db:"retired_at"`
+}
+
+// SealedRow holds the encrypted columns of one row, in the order the table's
+// PII columns are listed, for re-encryption under the active key
+type SealedRow struct {
+	Table  string
+	ID     uuid.UUID
+	Values []*string
+}
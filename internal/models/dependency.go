@@ -0,0 +1,27 @@
+package models
+
+// Resources whose deletes are guarded by dependency checks
+const (
+	DependentCategory    = "category"
+	DependentProduct     = "product"
+	DependentWarehouse   = "warehouse"
+	DependentSupplier    = "supplier"
+	DependentDistributor = "distributor"
+)
+
+// Kinds of records that block a delete
+const (
+	BlockingOpenOrders     = "open_orders"
+	BlockingClosedOrders   = "closed_orders"
+	BlockingStock          = "stock"
+	BlockingUnpaidInvoices = "unpaid_invoices"
+	BlockingProducts       = "products"
+	BlockingSubcategories  = "subcategories"
+)
+
+// BlockingReference counts records of one kind that still depend on a
+// resource being deleted; for stock, Count is units on hand
+type BlockingReference struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Device platforms accepted for push registration
+const (
+	DevicePlatformAndroid = "android"
+	DevicePlatformIOS     = "ios"
+	DevicePlatformWeb     = "web"
+)
+
+// Push topics a device can subscribe to
+const (
+	PushTopicOrderApprovals = "order_approvals"
+	PushTopicLowStock       = "low_stock"
+	PushTopicPayments       = "payments"
+	PushTopicCompliance     = "compliance"
+	PushTopicSLABreaches    = "sla_breaches"
+)
+
+// PushTopics lists every topic; devices registering without topics get all of them
+var PushTopics = []string{PushTopicOrderApprovals, PushTopicLowStock, PushTopicPayments, PushTopicCompliance, PushTopicSLABreaches}
+
+// DeviceToken is a push registration for one app installation
+type DeviceToken struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	TenantID   uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	Token      string    `json:"token" db:"token"`
+	Platform   string    `json:"platform" db:"platform"`
+	Topics     []string  `json:"topics" db:"topics"`
+	AppVersion *string   `json:"app_version,omitempty" db:"app_version"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// PushMessage is the payload delivered to devices; Data is passed to the app
+// untouched for deep links (e.g. {"order_id": "..."})
+type PushMessage struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
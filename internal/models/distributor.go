@@ -6,6 +6,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// DefaultGeofenceRadiusM is used for distributors created without a radius
+const DefaultGeofenceRadiusM = 200
+
 type Distributor struct {
 	ID             uuid.UUID `json:"id" db:"id"`
 	TenantID       uuid.UUID `json:"tenant_id" db:"tenant_id"`
@@ -14,6 +17,18 @@ type Distributor struct {
 	ContactPhone   *string   `json:"contact_phone" db:"contact_phone"`
 	Address        *string   `json:"address" db:"address"`
 	LicenseNumber  *string   `json:"license_number" db:"license_number"`
+	GSTIN          *string   `json:"gstin,omitempty" db:"gstin"`
+	// BankAccountNumber and BankIFSC identify the customer's bank transfers and refunds
+	BankAccountNumber *string `json:"bank_account_number,omitempty" db:"bank_account_number"`
+	BankIFSC          *string `json:"bank_ifsc,omitempty" db:"bank_ifsc"`
+	Latitude       *float64  `json:"latitude,omitempty" db:"latitude"`
+	Longitude      *float64  `json:"longitude,omitempty" db:"longitude"`
+	// GeofenceRadiusM is how far from the location a sales visit check-in is accepted
+	GeofenceRadiusM int      `json:"geofence_radius_m" db:"geofence_radius_m"`
+	// PreferredLanguage picks localized payment reminders; unset uses the tenant default
+	PreferredLanguage *string `json:"preferred_language,omitempty" db:"preferred_language"`
+	// ArchivedAt is set when a distributor referenced by past orders is deleted
+	ArchivedAt     *time.Time `json:"archived_at,omitempty" db:"archived_at"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
\ No newline at end of file
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Channels payment reminders can be sent through
+const (
+	DunningChannelWhatsApp = "whatsapp"
+	DunningChannelEmail    = "email"
+	DunningChannelSMS      = "sms"
+)
+
+// Outcomes recorded for each reminder
+const (
+	InvoiceReminderSent    = "sent"
+	InvoiceReminderFailed  = "failed"
+	InvoiceReminderSkipped = "skipped"
+)
+
+// DunningSchedule is a tenant's payment reminder cadence. OffsetDays are days
+// relative to the invoice due date, negative before it
+type DunningSchedule struct {
+	TenantID        uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Enabled         bool      `json:"enabled" db:"enabled"`
+	OffsetDays      []int     `json:"offset_days" db:"offset_days"`
+	Channels        []string  `json:"channels" db:"channels"`
+	DefaultLanguage string    `json:"default_language" db:"default_language"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DunningTemplate is the email or SMS wording of a reminder in one language.
+// Subject and Body are Go templates over InvoiceReminderData
+type DunningTemplate struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Channel   string    `json:"channel" db:"channel"`
+	Language  string    `json:"language" db:"language"`
+	Subject   *string   `json:"subject,omitempty" db:"subject"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// InvoiceReminderData is what reminder templates can refer to
+type InvoiceReminderData struct {
+	DistributorName string
+	InvoiceNumber   string
+	Amount          string
+	DueDate         string
+	// DaysOverdue is negative while the invoice is not yet due
+	DaysOverdue int
+}
+
+// InvoiceReminder is one reminder sent, or attempted, for an invoice
+type InvoiceReminder struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	TenantID     uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	InvoiceID    uuid.UUID `json:"invoice_id" db:"invoice_id"`
+	OffsetDays   int       `json:"offset_days" db:"offset_days"`
+	Channel      string    `json:"channel" db:"channel"`
+	Recipient    *string   `json:"recipient,omitempty" db:"recipient"`
+	Language     string    `json:"language" db:"language"`
+	Status       string    `json:"status" db:"status"`
+	ErrorMessage *string   `json:"error_message,omitempty" db:"error_message"`
+	SentAt       time.Time `json:"sent_at" db:"sent_at"`
+}
+
+// DunningCandidate is an outstanding sales invoice with its distributor's
+// contact details and the cadence steps already handled
+type DunningCandidate struct {
+	InvoiceID         uuid.UUID
+	InvoiceNumber     string
+	TotalAmount       float64
+	DueDate           time.Time
+	DistributorID     uuid.UUID
+	DistributorName   string
+	ContactPhone      *string
+	ContactEmail      *string
+	PreferredLanguage *string
+	DoneOffsets       []int
+}
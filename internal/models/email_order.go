@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Inbound email processing states
+const (
+	InboundEmailPending       = "pending"
+	InboundEmailParsed        = "parsed"
+	InboundEmailUnknownSender = "unknown_sender"
+	InboundEmailFailed        = "failed"
+)
+
+// Draft order review states
+const (
+	EmailDraftPendingReview = "pending_review"
+	EmailDraftApproved      = "approved"
+	EmailDraftRejected      = "rejected"
+)
+
+// EmailOrderSettings configures a tenant's order mailbox and how order lines
+// are read from email bodies and CSV attachments
+type EmailOrderSettings struct {
+	TenantID           uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	InboundToken       string     `json:"inbound_token" db:"inbound_token"`
+	Enabled            bool       `json:"enabled" db:"enabled"`
+	DefaultWarehouseID *uuid.UUID `json:"default_warehouse_id,omitempty" db:"default_warehouse_id"`
+	// LinePattern has named groups sku, quantity and optionally price; empty
+	// uses the built-in pattern for lines like "SKU-1 x 10 @ 250"
+	LinePattern    *string   `json:"line_pattern,omitempty" db:"line_pattern"`
+	SKUColumn      string    `json:"sku_column" db:"sku_column"`
+	QuantityColumn string    `json:"quantity_column" db:"quantity_column"`
+	PriceColumn    string    `json:"price_column" db:"price_column"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EmailOrderSettingsRequest creates or updates a tenant's email ordering settings
+type EmailOrderSettingsRequest struct {
+	Enabled            *bool      `json:"enabled,omitempty"`
+	DefaultWarehouseID *uuid.UUID `json:"default_warehouse_id,omitempty"`
+	LinePattern        *string    `json:"line_pattern,omitempty"`
+	SKUColumn          *string    `json:"sku_column,omitempty"`
+	QuantityColumn     *string    `json:"quantity_column,omitempty"`
+	PriceColumn        *string    `json:"price_column,omitempty"`
+}
+
+// EmailOrderSender is a known dealer address, or an @domain, whose order
+// emails become draft orders for the distributor
+type EmailOrderSender struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Address       string     `json:"address" db:"address"`
+	DistributorID uuid.UUID  `json:"distributor_id" db:"distributor_id"`
+	WarehouseID   *uuid.UUID `json:"warehouse_id,omitempty" db:"warehouse_id"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// EmailAttachment is a text attachment of an inbound email; binary
+// attachments are dropped on receipt
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     string `json:"content"`
+}
+
+// InboundEmail is an email received at a tenant's order mailbox
+type InboundEmail struct {
+	ID          uuid.UUID         `json:"id" db:"id"`
+	TenantID    uuid.UUID         `json:"tenant_id" db:"tenant_id"`
+	MessageID   *string           `json:"message_id,omitempty" db:"message_id"`
+	FromAddress string            `json:"from_address" db:"from_address"`
+	Subject     string            `json:"subject" db:"subject"`
+	BodyText    string            `json:"body_text" db:"body_text"`
+	Attachments []EmailAttachment `json:"attachments" db:"attachments"`
+	Status      string            `json:"status" db:"status"`
+	Error       *string           `json:"error,omitempty" db:"error"`
+	ReceivedAt  time.Time         `json:"received_at" db:"received_at"`
+	ProcessedAt *time.Time        `json:"processed_at,omitempty" db:"processed_at"`
+}
+
+// EmailDraftOrderLine is one parsed order line. Lines whose SKU matches no
+// product carry an Error and are skipped on approval unless corrected
+type EmailDraftOrderLine struct {
+	SKU         string     `json:"sku"`
+	ProductID   *uuid.UUID `json:"product_id,omitempty"`
+	ProductName string     `json:"product_name,omitempty"`
+	Quantity    int        `json:"quantity"`
+	UnitPrice   *float64   `json:"unit_price,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// EmailDraftOrder is an order parsed from an email, waiting for review
+type EmailDraftOrder struct {
+	ID            uuid.UUID             `json:"id" db:"id"`
+	TenantID      uuid.UUID             `json:"tenant_id" db:"tenant_id"`
+	EmailID       uuid.UUID             `json:"email_id" db:"email_id"`
+	FromAddress   string                `json:"from_address"`
+	Subject       string                `json:"subject"`
+	DistributorID uuid.UUID             `json:"distributor_id" db:"distributor_id"`
+	WarehouseID   *uuid.UUID            `json:"warehouse_id,omitempty" db:"warehouse_id"`
+	Status        string                `json:"status" db:"status"`
+	Lines         []EmailDraftOrderLine `json:"lines" db:"lines"`
+	OrderIDs      []uuid.UUID           `json:"order_ids" db:"order_ids"`
+	ReviewNote    *string               `json:"review_note,omitempty" db:"review_note"`
+	ReviewedBy    *uuid.UUID            `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt    *time.Time            `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt     time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// EmailDraftOrderUpdate corrects a draft before approval. Lines replace the
+// parsed ones; each needs a product ID
+type EmailDraftOrderUpdate struct {
+	WarehouseID *uuid.UUID            `json:"warehouse_id,omitempty"`
+	Lines       []EmailDraftOrderLine `json:"lines,omitempty"`
+}
+
+// EmailDraftOrderReview approves or rejects a draft, with an optional note
+type EmailDraftOrderReview struct {
+	Note *string `json:"note,omitempty"`
+}
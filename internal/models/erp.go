@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ERP connector types
+const (
+	ERPConnectorTallyXML = "tally_xml"
+	ERPConnectorBusyCSV  = "busy_csv"
+	ERPConnectorMarg     = "marg"
+)
+
+// ERP document types a connector can export
+const (
+	ERPDocumentInvoices = "invoices"
+	ERPDocumentOrders   = "orders"
+)
+
+// ERP sync run statuses and triggers
+const (
+	ERPSyncRunning   = "running"
+	ERPSyncSucceeded = "succeeded"
+	ERPSyncFailed    = "failed"
+
+	ERPSyncTriggerManual    = "manual"
+	ERPSyncTriggerScheduled = "scheduled"
+)
+
+// ERPConnector is a tenant's configured export to an accounting package
+type ERPConnector struct {
+	ID                  uuid.UUID            `json:"id" db:"id"`
+	TenantID            uuid.UUID            `json:"tenant_id" db:"tenant_id"`
+	Name                string               `json:"name" db:"name"`
+	ConnectorType       string               `json:"connector_type" db:"connector_type"`
+	DocumentTypes       []string             `json:"document_types" db:"document_types"`
+	Settings            ERPConnectorSettings `json:"settings" db:"settings"`
+	SyncIntervalMinutes int                  `json:"sync_interval_minutes" db:"sync_interval_minutes"`
+	SyncedThrough       *time.Time           `json:"synced_through,omitempty" db:"synced_through"`
+	NextSyncAt          *time.Time           `json:"next_sync_at,omitempty" db:"next_sync_at"`
+	IsActive            bool                 `json:"is_active" db:"is_active"`
+	CreatedAt           time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// ERPConnectorSettings holds adapter options; unset ledger names use the adapter defaults
+type ERPConnectorSettings struct {
+	CompanyName         string `json:"company_name,omitempty"`
+	SalesLedger         string `json:"sales_ledger,omitempty"`
+	PurchaseLedger      string `json:"purchase_ledger,omitempty"`
+	EndpointURL         string `json:"endpoint_url,omitempty"`
+	InitialLookbackDays int    `json:"initial_lookback_days,omitempty"`
+}
+
+// ERPSyncRun records one export of a connector
+type ERPSyncRun struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ConnectorID     uuid.UUID  `json:"connector_id" db:"connector_id"`
+	Trigger         string     `json:"trigger" db:"trigger"`
+	Status          string     `json:"status" db:"status"`
+	PeriodStart     time.Time  `json:"period_start" db:"period_start"`
+	PeriodEnd       time.Time  `json:"period_end" db:"period_end"`
+	RecordsExported int        `json:"records_exported" db:"records_exported"`
+	FileName        *string    `json:"file_name,omitempty" db:"file_name"`
+	ObjectKey       *string    `json:"-" db:"object_key"`
+	Delivered       bool       `json:"delivered" db:"delivered"`
+	ErrorMessage    *string    `json:"error_message,omitempty" db:"error_message"`
+	StartedAt       time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// ERPSyncStatus summarises a connector for the sync dashboard
+type ERPSyncStatus struct {
+	Connector       *ERPConnector `json:"connector"`
+	LastRun         *ERPSyncRun   `json:"last_run,omitempty"`
+	LastSuccessAt   *time.Time    `json:"last_success_at,omitempty"`
+	FailuresLast24h int           `json:"failures_last_24h"`
+	Health          string        `json:"health"`
+}
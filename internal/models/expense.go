@@ -0,0 +1,141 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpenseCategory groups running costs, e.g. rent, power or loading labour
+type ExpenseCategory struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	TenantID    uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Name        string    `json:"name" db:"name"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ExpenseCategoryRequest creates or updates a category; on update, omitted
+// fields are left as they are
+type ExpenseCategoryRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+// Expense is a cost incurred by a warehouse. Month is the first day of
+// ExpenseDate's month
+type Expense struct {
+	ID            uuid.UUID            `json:"id" db:"id"`
+	TenantID      uuid.UUID            `json:"tenant_id" db:"tenant_id"`
+	WarehouseID   uuid.UUID            `json:"warehouse_id" db:"warehouse_id"`
+	WarehouseName string               `json:"warehouse_name" db:"-"`
+	CategoryID    uuid.UUID            `json:"category_id" db:"category_id"`
+	CategoryName  string               `json:"category_name" db:"-"`
+	ExpenseDate   time.Time            `json:"expense_date" db:"expense_date"`
+	Month         time.Time            `json:"month" db:"period_month"`
+	Amount        float64              `json:"amount" db:"amount"`
+	Description   *string              `json:"description,omitempty" db:"description"`
+	Vendor        *string              `json:"vendor,omitempty" db:"vendor"`
+	Reference     *string              `json:"reference,omitempty" db:"reference"`
+	CreatedBy     *uuid.UUID           `json:"created_by,omitempty" db:"created_by"`
+	Attachments   []*ExpenseAttachment `json:"attachments,omitempty" db:"-"`
+	CreatedAt     time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// ExpenseRequest records or corrects an expense; ExpenseDate is YYYY-MM-DD.
+// On update, omitted fields are left as they are
+type ExpenseRequest struct {
+	WarehouseID *uuid.UUID `json:"warehouse_id,omitempty"`
+	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
+	ExpenseDate string     `json:"expense_date,omitempty"`
+	Amount      *float64   `json:"amount,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Vendor      *string    `json:"vendor,omitempty"`
+	Reference   *string    `json:"reference,omitempty"`
+}
+
+// ExpenseFilter narrows expense listings
+type ExpenseFilter struct {
+	WarehouseID *uuid.UUID
+	CategoryID  *uuid.UUID
+	Month       *time.Time
+	Limit       int
+	Offset      int
+}
+
+// ExpenseAttachment is a bill or receipt; URL is a short-lived download link
+type ExpenseAttachment struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	TenantID    uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	ExpenseID   uuid.UUID `json:"expense_id" db:"expense_id"`
+	ObjectKey   string    `json:"-" db:"object_key"`
+	FileName    string    `json:"file_name" db:"file_name"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	SizeBytes   int64     `json:"size_bytes" db:"size_bytes"`
+	URL         string    `json:"url,omitempty" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ExpenseBudget is what a warehouse plans to spend on a category in a month
+type ExpenseBudget struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	TenantID      uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	WarehouseID   uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	WarehouseName string    `json:"warehouse_name" db:"-"`
+	CategoryID    uuid.UUID `json:"category_id" db:"category_id"`
+	CategoryName  string    `json:"category_name" db:"-"`
+	Month         time.Time `json:"month" db:"period_month"`
+	Amount        float64   `json:"amount" db:"amount"`
+	Notes         *string   `json:"notes,omitempty" db:"notes"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ExpenseBudgetRequest sets the budget of a warehouse, category and month
+// (YYYY-MM), replacing any set before
+type ExpenseBudgetRequest struct {
+	WarehouseID uuid.UUID `json:"warehouse_id"`
+	CategoryID  uuid.UUID `json:"category_id"`
+	Month       string    `json:"month"`
+	Amount      *float64  `json:"amount"`
+	Notes       *string   `json:"notes,omitempty"`
+}
+
+// BudgetVsActualRow compares a warehouse's budget for a category with what it
+// spent. Variance is budget less actual, negative when over budget
+type BudgetVsActualRow struct {
+	WarehouseID        uuid.UUID `json:"warehouse_id"`
+	WarehouseName      string    `json:"warehouse_name"`
+	CategoryID         uuid.UUID `json:"category_id"`
+	CategoryName       string    `json:"category_name"`
+	Budget             float64   `json:"budget"`
+	Actual             float64   `json:"actual"`
+	Variance           float64   `json:"variance"`
+	UtilizationPercent float64   `json:"utilization_percent"`
+	OverBudget         bool      `json:"over_budget"`
+}
+
+// BudgetVsActualReport compares budgets with expenses over a range of months
+type BudgetVsActualReport struct {
+	From            time.Time            `json:"from"`
+	To              time.Time            `json:"to"`
+	Rows            []*BudgetVsActualRow `json:"rows"`
+	Warehouses      []*BudgetVsActualRow `json:"warehouses"`
+	Totals          BudgetVsActualRow    `json:"totals"`
+	OverBudgetCount int                  `json:"over_budget_count"`
+}
+
+// ExpenseDashboard is the month's budget use for the dashboard widget with
+// the lines most over budget
+type ExpenseDashboard struct {
+	Month              time.Time            `json:"month"`
+	Budget             float64              `json:"budget"`
+	Actual             float64              `json:"actual"`
+	UtilizationPercent float64              `json:"utilization_percent"`
+	Warehouses         []*BudgetVsActualRow `json:"warehouses"`
+	TopOverruns        []*BudgetVsActualRow `json:"top_overruns"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export types as GSTR-1 table 6A reports them
+const (
+	// ExportTypeWithoutPayment is zero-rated under a letter of undertaking
+	ExportTypeWithoutPayment = "WOPAY"
+	// ExportTypeWithPayment charges IGST, to be claimed back as a refund
+	ExportTypeWithPayment = "WPAY"
+)
+
+// InvoiceExportDetails makes an invoice an export invoice. The invoice holds
+// the INR amounts; ForeignAmount is its total in Currency at ExchangeRate INR
+// per unit
+type InvoiceExportDetails struct {
+	InvoiceID          uuid.UUID  `json:"invoice_id" db:"invoice_id"`
+	TenantID           uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ExportType         string     `json:"export_type" db:"export_type"`
+	LUTNumber          *string    `json:"lut_number,omitempty" db:"lut_number"`
+	Currency           string     `json:"currency" db:"currency"`
+	ExchangeRate       float64    `json:"exchange_rate" db:"exchange_rate"`
+	ForeignAmount      float64    `json:"foreign_amount" db:"foreign_amount"`
+	PortCode           *string    `json:"port_code,omitempty" db:"port_code"`
+	ShippingBillNumber *string    `json:"shipping_bill_number,omitempty" db:"shipping_bill_number"`
+	ShippingBillDate   *time.Time `json:"shipping_bill_date,omitempty" db:"shipping_bill_date"`
+	DestinationCountry *string    `json:"destination_country,omitempty" db:"destination_country"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ExportInvoice is an invoice with its export details
+type ExportInvoice struct {
+	*Invoice
+	Export *InvoiceExportDetails `json:"export"`
+}
+
+// ExportInvoiceRequest raises an export invoice for a delivered order.
+// ForeignUnitPrice bills the order in Currency; without it the order's INR
+// price is converted. ExportType defaults to WOPAY, which needs LUTNumber
+type ExportInvoiceRequest struct {
+	OrderID            uuid.UUID `json:"order_id"`
+	ExportType         string    `json:"export_type"`
+	LUTNumber          *string   `json:"lut_number,omitempty"`
+	Currency           string    `json:"currency"`
+	ExchangeRate       float64   `json:"exchange_rate"`
+	ForeignUnitPrice   *float64  `json:"foreign_unit_price,omitempty"`
+	PortCode           *string   `json:"port_code,omitempty"`
+	ShippingBillNumber *string   `json:"shipping_bill_number,omitempty"`
+	ShippingBillDate   *string   `json:"shipping_bill_date,omitempty"`
+	DestinationCountry *string   `json:"destination_country,omitempty"`
+}
+
+// ExportShippingRequest records shipping bill details, which usually arrive
+// after the invoice is raised
+type ExportShippingRequest struct {
+	PortCode           *string `json:"port_code,omitempty"`
+	ShippingBillNumber *string `json:"shipping_bill_number,omitempty"`
+	ShippingBillDate   *string `json:"shipping_bill_date,omitempty"`
+}
+
+// GSTR1ExportRow is one invoice of GSTR-1 table 6A (exports)
+type GSTR1ExportRow struct {
+	ExportType         string     `json:"export_type"`
+	InvoiceID          uuid.UUID  `json:"invoice_id"`
+	InvoiceNumber      string     `json:"invoice_number"`
+	InvoiceDate        time.Time  `json:"invoice_date"`
+	InvoiceValue       float64    `json:"invoice_value"`
+	PortCode           *string    `json:"port_code,omitempty"`
+	ShippingBillNumber *string    `json:"shipping_bill_number,omitempty"`
+	ShippingBillDate   *time.Time `json:"shipping_bill_date,omitempty"`
+	Rate               float64    `json:"rate"`
+	TaxableValue       float64    `json:"taxable_value"`
+	IGST               float64    `json:"igst"`
+	Currency           string     `json:"currency"`
+	ForeignAmount      float64    `json:"foreign_amount"`
+}
+
+// GSTR1ExportSection is GSTR-1 table 6A for a return period (MMYYYY)
+type GSTR1ExportSection struct {
+	TenantID          uuid.UUID         `json:"tenant_id"`
+	ReturnPeriod      string            `json:"return_period"`
+	Invoices          []*GSTR1ExportRow `json:"invoices"`
+	TotalInvoiceValue float64           `json:"total_invoice_value"`
+	TotalTaxableValue float64           `json:"total_taxable_value"`
+	TotalIGST         float64           `json:"total_igst"`
+	// MissingShippingBills counts invoices still without a shipping bill
+	MissingShippingBills int `json:"missing_shipping_bills"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Flags known to the application. Others can be created through the admin
+// endpoints and evaluated by key
+const (
+	FeatureEInvoicing        = "enable_einvoicing"
+	FeatureDistributorPortal = "enable_distributor_portal"
+)
+
+// FeatureFlag turns a feature on for a percentage of tenants, with per-tenant
+// overrides taking precedence. A disabled flag is only on where overridden
+type FeatureFlag struct {
+	Key               string                 `json:"key" db:"key"`
+	Description       *string                `json:"description,omitempty" db:"description"`
+	Enabled           bool                   `json:"enabled" db:"enabled"`
+	RolloutPercentage int                    `json:"rollout_percentage" db:"rollout_percentage"`
+	Overrides         []*FeatureFlagOverride `json:"overrides"`
+	CreatedAt         time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// FeatureFlagOverride forces a flag on or off for one tenant
+type FeatureFlagOverride struct {
+	FlagKey   string    `json:"flag_key" db:"flag_key"`
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FeatureFlagRequest creates or updates a flag. A nil RolloutPercentage
+// keeps the current one, or 100 for a new flag
+type FeatureFlagRequest struct {
+	Description       *string `json:"description,omitempty"`
+	Enabled           bool    `json:"enabled"`
+	RolloutPercentage *int    `json:"rollout_percentage,omitempty"`
+}
+
+// FeatureFlagOverrideRequest sets a tenant override
+type FeatureFlagOverrideRequest struct {
+	Enabled bool `json:"enabled"`
+}
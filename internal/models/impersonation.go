@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImpersonationSession records a platform admin acting as a tenant user
+type ImpersonationSession struct {
+	ID                   uuid.UUID  `json:"id" db:"id"`
+	TenantID             uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ImpersonatorID       uuid.UUID  `json:"impersonator_id" db:"impersonator_id"`
+	ImpersonatorTenantID uuid.UUID  `json:"impersonator_tenant_id" db:"impersonator_tenant_id"`
+	TargetUserID         uuid.UUID  `json:"target_user_id" db:"target_user_id"`
+	Reason               string     `json:"reason" db:"reason"`
+	TokenID              string     `json:"token_id" db:"token_id"`
+	ExpiresAt            time.Time  `json:"expires_at" db:"expires_at"`
+	EndedAt              *time.Time `json:"ended_at" db:"ended_at"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *ImpersonationSession) IsActive(now time.Time) bool {
+	return s.EndedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ImpersonationActivitySummary summarizes what was done during a session
+type ImpersonationActivitySummary struct {
+	SessionID       uuid.UUID      `json:"session_id"`
+	TotalActions    int            `json:"total_actions"`
+	ActionBreakdown map[string]int `json:"action_breakdown"` // Action -> count
+	PeriodStart     time.Time      `json:"period_start"`
+	PeriodEnd       time.Time      `json:"period_end"`
+}
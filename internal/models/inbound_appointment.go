@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Inbound appointment statuses
+const (
+	AppointmentScheduled = "scheduled"
+	AppointmentCancelled = "cancelled"
+)
+
+// DefaultDock is the dock of an appointment booked without one
+const DefaultDock = "Dock 1"
+
+// InboundAppointment is a supplier delivery booked into a time slot at a
+// warehouse dock, bringing the purchase orders in OrderIDs
+type InboundAppointment struct {
+	ID            uuid.UUID   `json:"id" db:"id"`
+	TenantID      uuid.UUID   `json:"tenant_id" db:"tenant_id"`
+	WarehouseID   uuid.UUID   `json:"warehouse_id" db:"warehouse_id"`
+	Dock          string      `json:"dock" db:"dock"`
+	SupplierID    *uuid.UUID  `json:"supplier_id,omitempty" db:"supplier_id"`
+	OrderIDs      []uuid.UUID `json:"order_ids" db:"order_ids"`
+	StartsAt      time.Time   `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time   `json:"ends_at" db:"ends_at"`
+	Status        string      `json:"status" db:"status"`
+	VehicleNumber *string     `json:"vehicle_number,omitempty" db:"vehicle_number"`
+	Notes         *string     `json:"notes,omitempty" db:"notes"`
+	CancelReason  *string     `json:"cancel_reason,omitempty" db:"cancel_reason"`
+	CancelledAt   *time.Time  `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedBy     *uuid.UUID  `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// InboundSchedule is a warehouse's appointments on one calendar date of the
+// tenant, in start order
+type InboundSchedule struct {
+	WarehouseID  uuid.UUID             `json:"warehouse_id"`
+	Date         string                `json:"date"`
+	Timezone     string                `json:"timezone"`
+	Appointments []*InboundAppointment `json:"appointments"`
+}
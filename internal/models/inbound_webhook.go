@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What an inbound webhook does with each event it receives
+const (
+	InboundWebhookActionCreateOrder     = "create_order"
+	InboundWebhookActionMarkInvoicePaid = "mark_invoice_paid"
+)
+
+// How an inbound webhook authenticates its sender. A shared secret is sent
+// as-is in the signature header; an HMAC signature is the hex HMAC-SHA256 of
+// the raw body, optionally prefixed with "sha256="
+const (
+	InboundWebhookVerificationSharedSecret = "shared_secret"
+	InboundWebhookVerificationHMACSHA256   = "hmac_sha256"
+)
+
+// Outcomes recorded for each inbound delivery
+const (
+	InboundDeliveryProcessed = "processed"
+	InboundDeliveryDuplicate = "duplicate"
+	InboundDeliveryRejected  = "rejected"
+	InboundDeliveryFailed    = "failed"
+)
+
+// InboundWebhook is a tenant endpoint at /v1/hooks/:slug that payment
+// gateways and marketplaces push events to
+type InboundWebhook struct {
+	ID              uuid.UUID                  `json:"id" db:"id"`
+	TenantID        uuid.UUID                  `json:"tenant_id" db:"tenant_id"`
+	Name            string                     `json:"name" db:"name"`
+	Slug            string                     `json:"slug" db:"slug"`
+	Action          string                     `json:"action" db:"action"`
+	Verification    string                     `json:"verification" db:"verification"`
+	Secret          string                     `json:"-" db:"secret"`
+	SignatureHeader string                     `json:"signature_header" db:"signature_header"`
+	FieldMapping    InboundWebhookFieldMapping `json:"field_mapping" db:"field_mapping"`
+	WarehouseID     *uuid.UUID                 `json:"warehouse_id,omitempty" db:"warehouse_id"`
+	DistributorID   *uuid.UUID                 `json:"distributor_id,omitempty" db:"distributor_id"`
+	IsActive        bool                       `json:"is_active" db:"is_active"`
+	CreatedAt       time.Time                  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time                  `json:"updated_at" db:"updated_at"`
+}
+
+// InboundWebhookFieldMapping holds dot-separated paths into the event
+// payload. Orders take the product by ID or barcode from SKU; invoices are
+// found by ID or, failing that, by number. The transaction reference of a
+// UPI payment made from an invoice QR code is the invoice number
+type InboundWebhookFieldMapping struct {
+	EventID       string `json:"event_id,omitempty"`
+	SKU           string `json:"sku,omitempty"`
+	Quantity      string `json:"quantity,omitempty"`
+	UnitPrice     string `json:"unit_price,omitempty"`
+	WarehouseID   string `json:"warehouse_id,omitempty"`
+	DistributorID string `json:"distributor_id,omitempty"`
+	Note          string `json:"note,omitempty"`
+	InvoiceID     string `json:"invoice_id,omitempty"`
+	InvoiceNumber string `json:"invoice_number,omitempty"`
+	// Amount, in rupees, is only checked when mapped: a payment short of the
+	// invoice total does not mark it paid
+	Amount string `json:"amount,omitempty"`
+}
+
+// WithDefaults fills unset paths with the conventional field names
+func (m InboundWebhookFieldMapping) WithDefaults() InboundWebhookFieldMapping {
+	if m.EventID == "" {
+		m.EventID = "event_id"
+	}
+	if m.SKU == "" {
+		m.SKU = "sku"
+	}
+	if m.Quantity == "" {
+		m.Quantity = "quantity"
+	}
+	if m.UnitPrice == "" {
+		m.UnitPrice = "unit_price"
+	}
+	if m.WarehouseID == "" {
+		m.WarehouseID = "warehouse_id"
+	}
+	if m.DistributorID == "" {
+		m.DistributorID = "distributor_id"
+	}
+	if m.Note == "" {
+		m.Note = "note"
+	}
+	if m.InvoiceID == "" {
+		m.InvoiceID = "invoice_id"
+	}
+	if m.InvoiceNumber == "" {
+		m.InvoiceNumber = "invoice_number"
+	}
+	return m
+}
+
+// InboundWebhookRequest creates or updates an inbound webhook. The slug,
+// action and verification cannot be changed once created
+type InboundWebhookRequest struct {
+	Name            string                     `json:"name"`
+	Slug            string                     `json:"slug,omitempty"`
+	Action          string                     `json:"action,omitempty"`
+	Verification    string                     `json:"verification,omitempty"`
+	SignatureHeader string                     `json:"signature_header,omitempty"`
+	FieldMapping    InboundWebhookFieldMapping `json:"field_mapping"`
+	WarehouseID     *uuid.UUID                 `json:"warehouse_id,omitempty"`
+	DistributorID   *uuid.UUID                 `json:"distributor_id,omitempty"`
+	IsActive        *bool                      `json:"is_active,omitempty"`
+}
+
+// InboundWebhookDelivery logs one request received by an inbound webhook
+type InboundWebhookDelivery struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	TenantID   uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	WebhookID  uuid.UUID  `json:"webhook_id" db:"webhook_id"`
+	EventID    *string    `json:"event_id,omitempty" db:"event_id"`
+	Status     string     `json:"status" db:"status"`
+	Error      *string    `json:"error,omitempty" db:"error"`
+	Payload    string     `json:"payload" db:"payload"`
+	OrderID    *uuid.UUID `json:"order_id,omitempty" db:"order_id"`
+	InvoiceID  *uuid.UUID `json:"invoice_id,omitempty" db:"invoice_id"`
+	SourceIP   *string    `json:"source_ip,omitempty" db:"source_ip"`
+	ReceivedAt time.Time  `json:"received_at" db:"received_at"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Integrity issue categories. Only the image categories are safe to fix
+// automatically; the rest need someone to decide what the data should be
+const (
+	// IntegrityOrderMissingProduct is an order whose product is gone or belongs to another tenant
+	IntegrityOrderMissingProduct = "order_missing_product"
+	// IntegrityInvoiceGSTMismatch is an invoice whose taxable amount, GST
+	// components and TCS do not add up to its total
+	IntegrityInvoiceGSTMismatch = "invoice_gst_mismatch"
+	// IntegrityNegativeInventory is stock below zero in a warehouse
+	IntegrityNegativeInventory = "negative_inventory"
+	// IntegrityOrphanedImage is an object in the image bucket no product image refers to
+	IntegrityOrphanedImage = "orphaned_image"
+	// IntegrityMissingImageObject is a product image whose object is not in the bucket
+	IntegrityMissingImageObject = "missing_image_object"
+)
+
+// IntegrityIssue is one problem found by an integrity check. EntityID is nil
+// for storage objects, which Reference names instead
+type IntegrityIssue struct {
+	Category   string     `json:"category"`
+	EntityType string     `json:"entity_type"`
+	EntityID   *uuid.UUID `json:"entity_id,omitempty"`
+	Reference  string     `json:"reference,omitempty"`
+	Detail     string     `json:"detail"`
+	Fixable    bool       `json:"fixable"`
+	Fixed      bool       `json:"fixed"`
+}
+
+// IntegrityReport is the result of scanning a tenant. Counts is the number of
+// issues per category
+type IntegrityReport struct {
+	TenantID   uuid.UUID         `json:"tenant_id"`
+	AutoFix    bool              `json:"auto_fix"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Counts     map[string]int    `json:"counts"`
+	Fixed      int               `json:"fixed"`
+	Issues     []*IntegrityIssue `json:"issues"`
+}
+
+// IntegrityOrderRef is an order whose product is missing from its tenant
+type IntegrityOrderRef struct {
+	OrderID   uuid.UUID
+	ProductID uuid.UUID
+	OrderType string
+	Status    string
+}
+
+// IntegrityInvoiceAmounts are the amounts of an invoice that must add up
+type IntegrityInvoiceAmounts struct {
+	InvoiceID     uuid.UUID
+	InvoiceNumber string
+	TaxableAmount float64
+	CGST          float64
+	SGST          float64
+	IGST          float64
+	TCS           float64
+	TotalAmount   float64
+}
+
+// IntegrityStockRow is a stock level below zero
+type IntegrityStockRow struct {
+	InventoryID uuid.UUID
+	ProductID   uuid.UUID
+	WarehouseID uuid.UUID
+	Quantity    int
+}
+
+// IntegrityImageRef is a product image and the object key it points to
+type IntegrityImageRef struct {
+	ImageID   uuid.UUID
+	ProductID uuid.UUID
+	ObjectKey string
+}
@@ -31,4 +31,35 @@ type Inventory struct {
 	ProductID  uuid.UUID `json:"product_id" db:"product_id"`
 	Quantity   int       `json:"quantity" db:"quantity"`
 	LastUpdated time.Time `json:"last_updated" db:"last_updated"`
-}
\ No newline at end of file
+	// MinQuantity is the configured stock minimum, filled in on listings
+	MinQuantity  *int `json:"min_quantity,omitempty" db:"-"`
+	BelowMinimum bool `json:"below_minimum" db:"-"`
+}
+// Inventory transaction reasons
+const (
+	InventoryReasonAdjustment         = "adjustment"
+	InventoryReasonProductEdit        = "product_stock_update"
+	InventoryReasonTransferIn         = "transfer_in"
+	InventoryReasonTransferOut        = "transfer_out"
+	InventoryReasonPurchaseReceipt    = "purchase_receipt"
+	InventoryReasonConsignmentReceipt = "consignment_receipt"
+	InventoryReasonPurchaseReturn     = "purchase_return"
+	InventoryReasonReconciliation     = "wms_reconciliation"
+	InventoryReasonBundleAssemble     = "bundle_assemble"
+	InventoryReasonBundleDisassemble  = "bundle_disassemble"
+	InventoryReasonBundleSale         = "bundle_sale"
+	InventoryReasonBundleSaleReversal = "bundle_sale_reversal"
+)
+
+// InventoryTransaction is one entry in the stock movement ledger
+type InventoryTransaction struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	TenantID       uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	WarehouseID    uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	ProductID      uuid.UUID  `json:"product_id" db:"product_id"`
+	QuantityChange int        `json:"quantity_change" db:"quantity_change"`
+	QuantityAfter  int        `json:"quantity_after" db:"quantity_after"`
+	Reason         string     `json:"reason" db:"reason"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Inventory aging buckets by days since the last stock movement
+const (
+	AgingBucket0To30   = "0-30"
+	AgingBucket31To90  = "31-90"
+	AgingBucket91To180 = "91-180"
+	AgingBucketOver180 = "180+"
+)
+
+// InventoryAgingPosition is one warehouse stock line with its last ledger movement.
+// LastMovementAt falls back to the inventory row's last update when the
+// ledger has no entries for it (stock loaded before the ledger existed).
+// Quantity is owned stock only; supplier consignment units are reported
+// separately and not valued.
+type InventoryAgingPosition struct {
+	WarehouseID    uuid.UUID  `json:"warehouse_id"`
+	WarehouseName  string     `json:"warehouse_name"`
+	ProductID      uuid.UUID  `json:"product_id"`
+	ProductName    string     `json:"product_name"`
+	Quantity       int        `json:"quantity"`
+	UnitPrice      float64    `json:"unit_price"`
+	CostPrice      *float64   `json:"cost_price,omitempty" perm:"products:view_cost"`
+	ExpiryDate     *time.Time `json:"expiry_date,omitempty"`
+	LastMovementAt time.Time  `json:"last_movement_at"`
+	FromLedger     bool       `json:"from_ledger"`
+	// ConsignmentQuantity is supplier-owned stock held alongside Quantity
+	ConsignmentQuantity int `json:"consignment_quantity,omitempty"`
+}
+
+// InventoryAgingItem is a position placed in an aging bucket and valued at cost
+type InventoryAgingItem struct {
+	InventoryAgingPosition
+	DaysSinceMovement int     `json:"days_since_movement"`
+	Bucket            string  `json:"bucket"`
+	StockValue        float64 `json:"stock_value" perm:"products:view_cost"`
+	IsDeadStock       bool    `json:"is_dead_stock"`
+}
+
+// InventoryAgingBucket totals one aging bucket
+type InventoryAgingBucket struct {
+	Bucket     string  `json:"bucket"`
+	Lines      int     `json:"lines"`
+	Quantity   int     `json:"quantity"`
+	Value      float64 `json:"value"`
+	ValueShare float64 `json:"value_share"`
+}
+
+// MarkdownCandidate is aged or near-expiry stock worth discounting; the
+// suggested price never goes below cost
+type MarkdownCandidate struct {
+	WarehouseID       uuid.UUID `json:"warehouse_id"`
+	ProductID         uuid.UUID `json:"product_id"`
+	ProductName       string    `json:"product_name"`
+	Quantity          int       `json:"quantity"`
+	DaysSinceMovement int       `json:"days_since_movement"`
+	StockValue        float64   `json:"stock_value" perm:"products:view_cost"`
+	CurrentPrice      float64   `json:"current_price"`
+	SuggestedPrice    float64   `json:"suggested_price"`
+	MarkdownPercent   float64   `json:"markdown_percent"`
+	Reason            string    `json:"reason"`
+}
+
+// InventoryAgingReport is the aging summary for a tenant or one warehouse
+type InventoryAgingReport struct {
+	AsOf               time.Time               `json:"as_of"`
+	WarehouseID        *uuid.UUID              `json:"warehouse_id,omitempty"`
+	DeadStockDays      int                     `json:"dead_stock_days"`
+	TotalValue         float64                 `json:"total_value"`
+	Buckets            []*InventoryAgingBucket `json:"buckets"`
+	DeadStock          []*InventoryAgingItem   `json:"dead_stock"`
+	DeadStockValue     float64                 `json:"dead_stock_value"`
+	DeadStockShare     float64                 `json:"dead_stock_share"`
+	MarkdownCandidates []*MarkdownCandidate    `json:"markdown_candidates"`
+	Items              []*InventoryAgingItem   `json:"items,omitempty"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Inventory reconciliation statuses
+const (
+	ReconciliationPending   = "pending"
+	ReconciliationApplied   = "applied"
+	ReconciliationDiscarded = "discarded"
+)
+
+// WMSStockCount is one product's quantity in one warehouse as counted by an
+// external WMS
+type WMSStockCount struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	WarehouseID uuid.UUID `json:"warehouse_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// InventoryReconciliationRequest submits an external stock snapshot. With
+// FullSnapshot the snapshot covers everything in its warehouses, so stock
+// held internally that it does not list is taken to be zero
+type InventoryReconciliationRequest struct {
+	Source       string          `json:"source"`
+	SnapshotAt   *time.Time      `json:"snapshot_at,omitempty"`
+	FullSnapshot bool            `json:"full_snapshot"`
+	Notes        *string         `json:"notes,omitempty"`
+	Counts       []WMSStockCount `json:"counts"`
+}
+
+// InventoryReconciliationLine is a product and warehouse where the external
+// count differs from internal stock. Adjustment is the proposed stock change,
+// external less internal quantity at the time of the diff
+type InventoryReconciliationLine struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	ReconciliationID uuid.UUID `json:"reconciliation_id" db:"reconciliation_id"`
+	ProductID        uuid.UUID `json:"product_id" db:"product_id"`
+	WarehouseID      uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	ExternalQuantity int       `json:"external_quantity" db:"external_quantity"`
+	InternalQuantity int       `json:"internal_quantity" db:"internal_quantity"`
+	Adjustment       int       `json:"adjustment" db:"adjustment"`
+	Approved         bool      `json:"approved" db:"approved"`
+	Applied          bool      `json:"applied" db:"applied"`
+}
+
+// InventoryReconciliation is the report of an external stock snapshot diffed
+// against internal inventory
+type InventoryReconciliation struct {
+	ID           uuid.UUID                      `json:"id" db:"id"`
+	TenantID     uuid.UUID                      `json:"tenant_id" db:"tenant_id"`
+	Source       string                         `json:"source" db:"source"`
+	Status       string                         `json:"status" db:"status"`
+	SnapshotAt   time.Time                      `json:"snapshot_at" db:"snapshot_at"`
+	FullSnapshot bool                           `json:"full_snapshot" db:"full_snapshot"`
+	Notes        *string                        `json:"notes,omitempty" db:"notes"`
+	CountedLines int                            `json:"counted_lines" db:"counted_lines"`
+	MatchedLines int                            `json:"matched_lines" db:"matched_lines"`
+	CreatedBy    *uuid.UUID                     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt    time.Time                      `json:"created_at" db:"created_at"`
+	ResolvedBy   *uuid.UUID                     `json:"resolved_by,omitempty" db:"resolved_by"`
+	ResolvedAt   *time.Time                     `json:"resolved_at,omitempty" db:"resolved_at"`
+	Lines        []*InventoryReconciliationLine `json:"lines,omitempty" db:"-"`
+}
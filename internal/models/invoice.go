@@ -10,6 +10,8 @@ type Invoice struct {
 	ID               uuid.UUID  `json:"id" db:"id"`
 	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
 	OrderID          uuid.UUID  `json:"order_id" db:"order_id"`
+	// BranchID is the issuing branch, whose GSTIN and number series the invoice uses
+	BranchID         *uuid.UUID `json:"branch_id,omitempty" db:"branch_id"`
 	InvoiceNumber    string     `json:"invoice_number" db:"invoice_number"`
 	GSTIN            *string    `json:"gstin" db:"gstin"`
 	HSNSAC           *string    `json:"hsn_sac" db:"hsn_sac"`
@@ -25,4 +27,6 @@ type Invoice struct {
 	DueDate          time.Time  `json:"due_date" db:"due_date"`
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+	// StatusHistory is the invoice's status transitions, filled on detail reads
+	StatusHistory    []*StatusChange `json:"status_history,omitempty" db:"-"`
 }
\ No newline at end of file
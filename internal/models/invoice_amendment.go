@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How an amendment is reported for GST
+const (
+	// AmendmentReissuedInPeriod is an amendment within the original's filing
+	// period: the original is reported as cancelled and only the new invoice
+	// as issued
+	AmendmentReissuedInPeriod = "reissued_in_period"
+	// AmendmentCreditNote is an amendment after the original's period: it
+	// stays reported there and the credit note reverses it in the current one
+	AmendmentCreditNote = "credit_note"
+)
+
+// InvoiceAmendmentRequest cancels an invoice and reissues it. Reason is
+// required; the new invoice copies the original's amounts and tax except for
+// what is given
+type InvoiceAmendmentRequest struct {
+	Reason        string   `json:"reason"`
+	TaxableAmount *float64 `json:"taxable_amount,omitempty"`
+	GSTRate       *float64 `json:"gst_rate,omitempty"`
+	// Interstate charges IGST rather than CGST and SGST
+	Interstate *bool   `json:"interstate,omitempty"`
+	HSNSAC     *string `json:"hsn_sac,omitempty"`
+	GSTIN      *string `json:"gstin,omitempty"`
+}
+
+// InvoiceAmendment links a cancelled invoice to the credit note issued for it
+// and the invoice that replaced it
+type InvoiceAmendment struct {
+	ID                    uuid.UUID  `json:"id" db:"id"`
+	TenantID              uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	OriginalInvoiceID     uuid.UUID  `json:"original_invoice_id" db:"original_invoice_id"`
+	OriginalInvoiceNumber string     `json:"original_invoice_number" db:"-"`
+	CreditNoteID          uuid.UUID  `json:"credit_note_id" db:"credit_note_id"`
+	CreditNoteNumber      string     `json:"credit_note_number" db:"-"`
+	NewInvoiceID          uuid.UUID  `json:"new_invoice_id" db:"new_invoice_id"`
+	NewInvoiceNumber      string     `json:"new_invoice_number" db:"-"`
+	Reason                string     `json:"reason" db:"reason"`
+	GSTTreatment          string     `json:"gst_treatment" db:"gst_treatment"`
+	OriginalPeriod        time.Time  `json:"original_period" db:"original_period"`
+	AmendedBy             *uuid.UUID `json:"amended_by,omitempty" db:"amended_by"`
+	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
+	// CreditNote and NewInvoice are filled when the amendment is made
+	CreditNote *CreditNote `json:"credit_note,omitempty" db:"-"`
+	NewInvoice *Invoice    `json:"new_invoice,omitempty" db:"-"`
+	TCS        *InvoiceTCS `json:"tcs,omitempty" db:"-"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice document templates
+const (
+	InvoiceTemplateStandard     = "standard"
+	InvoiceTemplateExport       = "export"
+	InvoiceTemplateConsolidated = "consolidated"
+)
+
+// InvoiceDocument is the PDF archived for an issued invoice. It is written
+// once and never regenerated; SHA256 lets a recipient check that a copy is
+// the document as issued
+type InvoiceDocument struct {
+	InvoiceID     uuid.UUID `json:"invoice_id" db:"invoice_id"`
+	TenantID      uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	InvoiceNumber string    `json:"invoice_number" db:"invoice_number"`
+	Template      string    `json:"template" db:"template"`
+	ObjectKey     string    `json:"-" db:"object_key"`
+	SHA256        string    `json:"sha256" db:"sha256"`
+	SizeBytes     int64     `json:"size_bytes" db:"size_bytes"`
+	GeneratedAt   time.Time `json:"generated_at" db:"generated_at"`
+}
+
+// PendingInvoiceDocument is an invoice still waiting for its document
+type PendingInvoiceDocument struct {
+	TenantID  uuid.UUID
+	InvoiceID uuid.UUID
+	CreatedAt time.Time
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Margin policy enforcement modes
+const (
+	MarginEnforcementBlock           = "block"
+	MarginEnforcementRequireOverride = "require_override"
+)
+
+// Margin violation outcomes
+const (
+	MarginOutcomeBlocked    = "blocked"
+	MarginOutcomeOverridden = "overridden"
+)
+
+// MarginPolicy is a tenant's minimum markup over product cost for sales orders
+type MarginPolicy struct {
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	MinMarginPercent float64    `json:"min_margin_percent" db:"min_margin_percent"`
+	Enforcement      string     `json:"enforcement" db:"enforcement"`
+	UpdatedBy        *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MarginViolation records a sales order priced below cost plus the minimum margin
+type MarginViolation struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	OrderID          *uuid.UUID `json:"order_id,omitempty" db:"order_id"`
+	ProductID        uuid.UUID  `json:"product_id" db:"product_id"`
+	ProductName      string     `json:"product_name,omitempty" db:"-"`
+	UnitPrice        float64    `json:"unit_price" db:"unit_price"`
+	CostPrice        float64    `json:"cost_price" db:"cost_price" perm:"products:view_cost"`
+	MinMarginPercent float64    `json:"min_margin_percent" db:"min_margin_percent"`
+	MarginPercent    float64    `json:"margin_percent" db:"margin_percent" perm:"products:view_cost"` // markup of unit price over cost
+	Outcome          string     `json:"outcome" db:"outcome"`
+	UserID           *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	OverrideReason   *string    `json:"override_reason,omitempty" db:"override_reason"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// MarginViolationFilter narrows the margin violations report
+type MarginViolationFilter struct {
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Outcome   *string    `json:"outcome,omitempty"`
+	Limit     int        `json:"limit,omitempty"`
+	Offset    int        `json:"offset,omitempty"`
+}
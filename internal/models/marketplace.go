@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Marketplace protocols understood by the ingestion adapters
+const (
+	MarketplaceProtocolONDC    = "ondc"
+	MarketplaceProtocolGeneric = "generic"
+)
+
+// MarketplaceChannel is a marketplace integration that pushes orders to a tenant
+type MarketplaceChannel struct {
+	ID               uuid.UUID               `json:"id" db:"id"`
+	TenantID         uuid.UUID               `json:"tenant_id" db:"tenant_id"`
+	Name             string                  `json:"name" db:"name"`
+	Protocol         string                  `json:"protocol" db:"protocol"`
+	WarehouseID      uuid.UUID               `json:"warehouse_id" db:"warehouse_id"`
+	DistributorID    uuid.UUID               `json:"distributor_id" db:"distributor_id"`
+	SigningSecret    string                  `json:"-" db:"signing_secret"`
+	StatusWebhookURL *string                 `json:"status_webhook_url,omitempty" db:"status_webhook_url"`
+	FieldMapping     MarketplaceFieldMapping `json:"field_mapping" db:"field_mapping"`
+	IsActive         bool                    `json:"is_active" db:"is_active"`
+	CreatedAt        time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at" db:"updated_at"`
+}
+
+// MarketplaceFieldMapping holds dot-separated paths into a generic order payload;
+// item paths are relative to each element of the items array
+type MarketplaceFieldMapping struct {
+	OrderID   string `json:"order_id,omitempty"`
+	Items     string `json:"items,omitempty"`
+	ItemID    string `json:"item_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Quantity  string `json:"quantity,omitempty"`
+	UnitPrice string `json:"unit_price,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// WithDefaults fills unset paths with the conventional field names
+func (m MarketplaceFieldMapping) WithDefaults() MarketplaceFieldMapping {
+	if m.OrderID == "" {
+		m.OrderID = "order_id"
+	}
+	if m.Items == "" {
+		m.Items = "items"
+	}
+	if m.ItemID == "" {
+		m.ItemID = "id"
+	}
+	if m.SKU == "" {
+		m.SKU = "sku"
+	}
+	if m.Quantity == "" {
+		m.Quantity = "quantity"
+	}
+	if m.UnitPrice == "" {
+		m.UnitPrice = "unit_price"
+	}
+	if m.Note == "" {
+		m.Note = "note"
+	}
+	return m
+}
+
+// ExternalOrder is a marketplace order normalised by an adapter
+type ExternalOrder struct {
+	ExternalOrderID string                 `json:"external_order_id"`
+	Items           []ExternalOrderItem    `json:"items"`
+	Note            *string                `json:"note,omitempty"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+}
+
+// ExternalOrderItem is one line of an external order; SKU is matched against
+// the product ID or barcode and a nil UnitPrice uses the product's price
+type ExternalOrderItem struct {
+	ExternalItemID string   `json:"external_item_id"`
+	SKU            string   `json:"sku"`
+	Quantity       int      `json:"quantity"`
+	UnitPrice      *float64 `json:"unit_price,omitempty"`
+}
+
+// MarketplaceOrderLink maps an external order line to the internal sales order
+type MarketplaceOrderLink struct {
+	ID               uuid.UUID              `json:"id" db:"id"`
+	TenantID         uuid.UUID              `json:"tenant_id" db:"tenant_id"`
+	ChannelID        uuid.UUID              `json:"channel_id" db:"channel_id"`
+	ExternalOrderID  string                 `json:"external_order_id" db:"external_order_id"`
+	ExternalItemID   string                 `json:"external_item_id" db:"external_item_id"`
+	OrderID          *uuid.UUID             `json:"order_id,omitempty" db:"order_id"`
+	ExternalContext  map[string]interface{} `json:"-" db:"external_context"`
+	LastPushedStatus *string                `json:"last_pushed_status,omitempty" db:"last_pushed_status"`
+	LastPushedAt     *time.Time             `json:"last_pushed_at,omitempty" db:"last_pushed_at"`
+	CreatedAt        time.Time              `json:"created_at" db:"created_at"`
+	OrderStatus      *string                `json:"order_status,omitempty" db:"-"`
+}
+
+// MarketplaceIngestResult reports the sales orders behind an external order
+type MarketplaceIngestResult struct {
+	ExternalOrderID string                  `json:"external_order_id"`
+	Duplicate       bool                    `json:"duplicate"`
+	Links           []*MarketplaceOrderLink `json:"orders"`
+}
@@ -11,6 +11,8 @@ const (
 	NotificationTypeEmail NotificationType = "email"
 	NotificationTypeSMS   NotificationType = "sms"
 	NotificationTypeWebhook NotificationType = "webhook"
+	NotificationTypePush    NotificationType = "push"
+	NotificationTypeWhatsApp NotificationType = "whatsapp"
 )
 
 // AlertType represents different types of alerts
@@ -21,13 +23,14 @@ const (
 	AlertTypeOrderIssue    AlertType = "order_issue"
 	AlertTypeJobFailure    AlertType = "job_failure"
 	AlertTypeInvoiceOverdue AlertType = "invoice_overdue"
+	AlertTypeWeather       AlertType = "weather"
 )
 
 // NotificationTemplate represents configurable notification templates
 type NotificationTemplate struct {
 	ID          string    `json:"id" db:"id"`
 	TenantID    string    `json:"tenant_id" db:"tenant_id"`
-	Type        string    `json:"type" db:"type"` // email, sms, webhook
+	Type        string    `json:"type" db:"type"` // email, sms, webhook, push, whatsapp
 	EventType   string    `json:"event_type" db:"event_type"`
 	Subject     *string   `json:"subject" db:"subject"`
 	BodyTemplate string    `json:"body_template" db:"body_template"`
@@ -149,4 +152,17 @@ type InvoiceOverdueAlertData struct {
 	DueDate         string  `json:"due_date"`
 	DaysOverdue     int     `json:"days_overdue"`
 	Amount          float64 `json:"amount"`
-}
\ No newline at end of file
+}
+
+// WeatherAlertData represents data for weather alert
+type WeatherAlertData struct {
+	RuleID         string  `json:"rule_id"`
+	Condition      string  `json:"condition"`
+	WarehouseID    string  `json:"warehouse_id"`
+	WarehouseName  string  `json:"warehouse_name"`
+	ForecastDate   string  `json:"forecast_date"`
+	ObservedValue  float64 `json:"observed_value"`
+	Threshold      float64 `json:"threshold"`
+	ProductsAtRisk int     `json:"products_at_risk"`
+	UnitsAtRisk    int     `json:"units_at_risk"`
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Digest frequencies
+const (
+	DigestFrequencyOff    = "off"
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+)
+
+// Digest sections
+const (
+	DigestSectionLowStock         = "low_stock"
+	DigestSectionOverdueInvoices  = "overdue_invoices"
+	DigestSectionPendingApprovals = "pending_approvals"
+)
+
+// DigestSections lists every section; new preferences include all of them
+var DigestSections = []string{DigestSectionLowStock, DigestSectionOverdueInvoices, DigestSectionPendingApprovals}
+
+// DigestTopicSections maps push topics to the digest section that replaces
+// them; users with that section in an active digest get no pushes on the topic
+var DigestTopicSections = map[string]string{
+	PushTopicLowStock:       DigestSectionLowStock,
+	PushTopicOrderApprovals: DigestSectionPendingApprovals,
+}
+
+// Digest delivery statuses; an empty digest is recorded but not sent
+const (
+	DigestDeliverySent   = "sent"
+	DigestDeliveryEmpty  = "empty"
+	DigestDeliveryFailed = "failed"
+)
+
+// DigestPreference is a user's digest opt-in. SendHour and WeeklyDay
+// (0 = Sunday) are in Timezone
+type DigestPreference struct {
+	TenantID   uuid.UUID  `json:"tenant_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Frequency  string     `json:"frequency"`
+	Sections   []string   `json:"sections"`
+	SendHour   int        `json:"send_hour"`
+	WeeklyDay  int        `json:"weekly_day"`
+	Timezone   string     `json:"timezone"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// HasSection reports whether the digest includes a section
+func (p *DigestPreference) HasSection(section string) bool {
+	for _, s := range p.Sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// DigestRecipient is an active digest preference with the user and tenant
+// it is addressed to
+type DigestRecipient struct {
+	DigestPreference
+	Email      string `json:"email"`
+	UserName   string `json:"user_name"`
+	TenantName string `json:"tenant_name"`
+}
+
+// DigestTemplate is the subject and body a tenant's digests are rendered
+// with, as Go text/template over DigestContent
+type DigestTemplate struct {
+	TenantID  uuid.UUID  `json:"tenant_id"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	IsDefault bool       `json:"is_default"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// DigestLowStockItem is a product running low in a warehouse
+type DigestLowStockItem struct {
+	ProductID     uuid.UUID `json:"product_id"`
+	ProductName   string    `json:"product_name"`
+	WarehouseName string    `json:"warehouse_name"`
+	Quantity      int       `json:"quantity"`
+}
+
+// DigestOverdueInvoice is a customer invoice past its due date
+type DigestOverdueInvoice struct {
+	InvoiceID       uuid.UUID `json:"invoice_id"`
+	InvoiceNumber   string    `json:"invoice_number"`
+	DistributorName string    `json:"distributor_name"`
+	DueDate         time.Time `json:"due_date"`
+	DaysOverdue     int       `json:"days_overdue"`
+	Outstanding     float64   `json:"outstanding"`
+}
+
+// DigestPendingApproval is a sales or purchase order or a purchase
+// requisition waiting for approval
+type DigestPendingApproval struct {
+	Kind        string    `json:"kind"`
+	ID          uuid.UUID `json:"id"`
+	Description string    `json:"description"`
+	Quantity    int       `json:"quantity"`
+	Amount      float64   `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+	DaysWaiting int       `json:"days_waiting"`
+}
+
+// DigestContent is what a digest reports, and the data its template is
+// rendered with. Item lists are capped; the counts and totals are not
+type DigestContent struct {
+	TenantName           string                   `json:"tenant_name"`
+	UserName             string                   `json:"user_name"`
+	Frequency            string                   `json:"frequency"`
+	PeriodStart          time.Time                `json:"period_start"`
+	PeriodEnd            time.Time                `json:"period_end"`
+	Sections             []string                 `json:"sections"`
+	LowStock             []*DigestLowStockItem    `json:"low_stock"`
+	LowStockCount        int                      `json:"low_stock_count"`
+	OverdueInvoices      []*DigestOverdueInvoice  `json:"overdue_invoices"`
+	OverdueInvoiceCount  int                      `json:"overdue_invoice_count"`
+	OverdueTotal         float64                  `json:"overdue_total"`
+	PendingApprovals     []*DigestPendingApproval `json:"pending_approvals"`
+	PendingApprovalCount int                      `json:"pending_approval_count"`
+}
+
+// Includes reports whether the digest has a section
+func (c *DigestContent) Includes(section string) bool {
+	for _, s := range c.Sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// ItemCount is how many things the digest reports across its sections
+func (c *DigestContent) ItemCount() int {
+	return c.LowStockCount + c.OverdueInvoiceCount + c.PendingApprovalCount
+}
+
+// DigestEmail is a rendered digest
+type DigestEmail struct {
+	Subject string        `json:"subject"`
+	Body    string        `json:"body"`
+	Content DigestContent `json:"content"`
+}
+
+// DigestDelivery records one digest run for a user
+type DigestDelivery struct {
+	ID          uuid.UUID `json:"id"`
+	TenantID    uuid.UUID `json:"tenant_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Frequency   string    `json:"frequency"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Recipient   string    `json:"recipient"`
+	Status      string    `json:"status"`
+	ItemCount   int       `json:"item_count"`
+	Error       *string   `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Operational modes. In read-only mode requests that change data are
+// rejected; in maintenance mode every request other than health checks is
+const (
+	OperationalModeNormal      = "normal"
+	OperationalModeReadOnly    = "read_only"
+	OperationalModeMaintenance = "maintenance"
+)
+
+// OperationalMode puts the whole service, when TenantID is nil, or one
+// tenant in read-only or maintenance mode. Message is shown to callers
+// whose requests are rejected
+type OperationalMode struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	TenantID  *uuid.UUID `json:"tenant_id,omitempty" db:"tenant_id"`
+	Mode      string     `json:"mode" db:"mode"`
+	Message   *string    `json:"message,omitempty" db:"message"`
+	SetBy     *uuid.UUID `json:"set_by,omitempty" db:"set_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// OperationalModeRequest switches a scope's mode; normal lifts any mode
+type OperationalModeRequest struct {
+	Mode    string  `json:"mode"`
+	Message *string `json:"message,omitempty"`
+}
@@ -15,6 +15,7 @@ type OrderSearchFilter struct {
 	DistributorID     *uuid.UUID `json:"distributor_id,omitempty"`     // Distributor filter
 	ProductID         *uuid.UUID `json:"product_id,omitempty"`         // Product filter
 	WarehouseID       *uuid.UUID `json:"warehouse_id,omitempty"`       // Warehouse filter
+	BranchID          *uuid.UUID `json:"branch_id,omitempty"`          // Branch filter
 	MinQuantity       *int       `json:"min_quantity,omitempty"`       // Minimum quantity
 	MaxQuantity       *int       `json:"max_quantity,omitempty"`       // Maximum quantity
 	MinValue          *float64   `json:"min_value,omitempty"`          // Minimum value (quantity * unit_price)
@@ -39,6 +40,12 @@ type OrderBulkStatusUpdate struct {
 	TransactionMode  string      `json:"transaction_mode"`                            // Mode: "atomic", "best_effort" - default atomic
 }
 
+// OrderBulkCancel represents a bulk order cancellation
+type OrderBulkCancel struct {
+	OrderIDs []uuid.UUID `json:"order_ids" validate:"required,min=1"` // List of order IDs to cancel
+	DryRun   bool        `json:"dry_run"`                             // Report what would be blocked without cancelling
+}
+
 // OrderBulkCreate represents bulk order creation
 type OrderBulkCreate struct {
 	Orders           []*Order    `json:"orders" validate:"required,min=1,dive"`        // List of orders to create
@@ -62,6 +69,8 @@ type Order struct {
 	DistributorID     *uuid.UUID `json:"distributor_id" db:"distributor_id"`
 	ProductID         uuid.UUID  `json:"product_id" db:"product_id"`
 	WarehouseID       uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	// BranchID is set from the warehouse's branch when the order is placed
+	BranchID          *uuid.UUID `json:"branch_id,omitempty" db:"branch_id"`
 	Quantity          int        `json:"quantity" db:"quantity"`
 	UnitPrice         float64    `json:"unit_price" db:"unit_price"`
 	Status            string     `json:"status" db:"status"`
@@ -70,4 +79,12 @@ type Order struct {
 	Notes             *string    `json:"notes" db:"notes"`
 	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	// BuyerLicenseID names the buyer license to capture on a sales order for a
+	// regulated product; it is not stored on the order itself
+	BuyerLicenseID    *uuid.UUID `json:"buyer_license_id,omitempty" db:"-"`
+	// StatusHistory is the order's status transitions, filled on detail reads
+	StatusHistory     []*StatusChange `json:"status_history,omitempty" db:"-"`
+	// AvailableEvents is what the tenant's workflow lets the order do next,
+	// filled on detail reads
+	AvailableEvents   []string `json:"available_events,omitempty" db:"-"`
 }
\ No newline at end of file
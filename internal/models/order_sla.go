@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Order milestones an SLA is measured between
+const (
+	SLAEventCreated    = "created"
+	SLAEventApproved   = "approved"
+	SLAEventProcessing = "processing"
+	SLAEventShipped    = "shipped"
+	SLAEventDelivered  = "delivered"
+)
+
+// SLAEvents lists the milestones in the order an order reaches them
+var SLAEvents = []string{SLAEventCreated, SLAEventApproved, SLAEventProcessing, SLAEventShipped, SLAEventDelivered}
+
+// SLA compliance report groupings
+const (
+	SLAGroupByWarehouse = "warehouse"
+	SLAGroupByTerritory = "territory"
+)
+
+// OrderSLA is a target time between two order milestones, e.g. ship within
+// 48 hours of approval. OrderType and WarehouseID narrow the orders it
+// applies to; nil means all
+type OrderSLA struct {
+	ID            uuid.UUID  `json:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id"`
+	Name          string     `json:"name"`
+	OrderType     *string    `json:"order_type,omitempty"`
+	WarehouseID   *uuid.UUID `json:"warehouse_id,omitempty"`
+	StartEvent    string     `json:"start_event"`
+	EndEvent      string     `json:"end_event"`
+	TargetMinutes int        `json:"target_minutes"`
+	NotifyEmails  []string   `json:"notify_emails"`
+	IsActive      bool       `json:"is_active"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Target is the SLA's allowed time between its milestones
+func (s *OrderSLA) Target() time.Duration {
+	return time.Duration(s.TargetMinutes) * time.Minute
+}
+
+// OrderSLABreach is an order that missed an SLA. ResolvedAt is set once the
+// order reaches the end milestone late
+type OrderSLABreach struct {
+	ID            uuid.UUID  `json:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id"`
+	SLAID         uuid.UUID  `json:"sla_id"`
+	SLAName       string     `json:"sla_name"`
+	OrderID       uuid.UUID  `json:"order_id"`
+	OrderType     string     `json:"order_type"`
+	OrderStatus   string     `json:"order_status"`
+	ProductName   string     `json:"product_name"`
+	WarehouseID   uuid.UUID  `json:"warehouse_id"`
+	WarehouseName string     `json:"warehouse_name"`
+	StartedAt     time.Time  `json:"started_at"`
+	DueAt         time.Time  `json:"due_at"`
+	DetectedAt    time.Time  `json:"detected_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+// SLAComplianceRow is how one warehouse or territory did against one SLA for
+// orders whose start milestone fell in the report period. Met and Breached
+// orders are measured; Open orders are still within their deadline and
+// cancelled orders that never reached the end milestone in time are left out
+type SLAComplianceRow struct {
+	SLAID             uuid.UUID  `json:"sla_id"`
+	SLAName           string     `json:"sla_name"`
+	TargetMinutes     int        `json:"target_minutes"`
+	GroupID           *uuid.UUID `json:"group_id,omitempty"`
+	GroupName         string     `json:"group_name"`
+	Met               int        `json:"met"`
+	Breached          int        `json:"breached"`
+	Open              int        `json:"open"`
+	CompliancePercent *float64   `json:"compliance_percent,omitempty"`
+	AvgMinutes        *float64   `json:"avg_minutes,omitempty"`
+}
+
+// SLAComplianceReport is SLA compliance for [From, To) by warehouse or territory
+type SLAComplianceReport struct {
+	From    time.Time           `json:"from"`
+	To      time.Time           `json:"to"`
+	GroupBy string              `json:"group_by"`
+	Rows    []*SLAComplianceRow `json:"rows"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Order statuses. OrderStatusQualityCheck is only reached when the tenant's
+// workflow has a quality check step
+const (
+	OrderStatusPending      = "pending"
+	OrderStatusApproved     = "approved"
+	OrderStatusProcessing   = "processing"
+	OrderStatusQualityCheck = "quality_check"
+	OrderStatusShipped      = "shipped"
+	OrderStatusDelivered    = "delivered"
+	OrderStatusCancelled    = "cancelled"
+)
+
+// Order workflow events, each moving an order to another status
+const (
+	OrderEventApprove      = "approve"
+	OrderEventProcess      = "process"
+	OrderEventQualityCheck = "quality_check"
+	OrderEventShip         = "ship"
+	OrderEventReceive      = "receive"
+	OrderEventDeliver      = "deliver"
+	OrderEventCancel       = "cancel"
+)
+
+// OrderWorkflow is a tenant's order lifecycle configuration. SkipApproval lets
+// pending orders be processed directly; QualityCheck holds processed orders
+// in quality_check before they can be shipped or received
+type OrderWorkflow struct {
+	TenantID     uuid.UUID  `json:"tenant_id"`
+	SkipApproval bool       `json:"skip_approval"`
+	QualityCheck bool       `json:"quality_check"`
+	UpdatedBy    *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// OrderTransition is an event allowed from any of the From statuses, moving
+// the order to To. OrderType limits it to purchase or sales orders; empty
+// means both
+type OrderTransition struct {
+	Event     string   `json:"event"`
+	From      []string `json:"from"`
+	To        string   `json:"to"`
+	OrderType string   `json:"order_type,omitempty"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OverdueInterestSettings are a tenant's terms for charging interest on
+// overdue invoices. Interest starts GraceDays after the due date, and never
+// before EnabledSince
+type OverdueInterestSettings struct {
+	TenantID            uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Enabled             bool       `json:"enabled" db:"enabled"`
+	EnabledSince        *time.Time `json:"enabled_since,omitempty" db:"enabled_since"`
+	RatePercentPerMonth float64    `json:"rate_percent_per_month" db:"rate_percent_per_month"`
+	GraceDays           int        `json:"grace_days" db:"grace_days"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CustomerInterestTerms override the tenant's terms for one customer. Nil
+// fields fall back to the tenant's; a zero rate exempts the customer
+type CustomerInterestTerms struct {
+	TenantID            uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	DistributorID       uuid.UUID `json:"customer_id" db:"distributor_id"`
+	RatePercentPerMonth *float64  `json:"rate_percent_per_month" db:"rate_percent_per_month"`
+	GraceDays           *int      `json:"grace_days" db:"grace_days"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// InterestEntry is interest accrued on an overdue invoice for the days after
+// PeriodStart up to and including PeriodEnd
+type InterestEntry struct {
+	ID                  uuid.UUID `json:"id" db:"id"`
+	TenantID            uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	InvoiceID           uuid.UUID `json:"invoice_id" db:"invoice_id"`
+	DistributorID       uuid.UUID `json:"customer_id" db:"distributor_id"`
+	PeriodStart         time.Time `json:"period_start" db:"period_start"`
+	PeriodEnd           time.Time `json:"period_end" db:"period_end"`
+	Days                int       `json:"days" db:"days"`
+	Principal           float64   `json:"principal" db:"principal"`
+	RatePercentPerMonth float64   `json:"rate_percent_per_month" db:"rate_percent_per_month"`
+	Amount              float64   `json:"amount" db:"amount"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+}
+
+// InterestCandidate is an overdue sales invoice with its customer's interest
+// terms and the day interest has been accrued up to, if any
+type InterestCandidate struct {
+	InvoiceID         uuid.UUID
+	DistributorID     uuid.UUID
+	TotalAmount       float64
+	DueDate           time.Time
+	CustomerRate      *float64
+	CustomerGraceDays *int
+	AccruedThrough    *time.Time
+}
+
+// InvoiceInterest is the interest accrued on an invoice and how it adds to
+// what is due. Payments settle accrued interest first, then the invoice itself
+type InvoiceInterest struct {
+	InvoiceID       uuid.UUID        `json:"invoice_id"`
+	InvoiceNumber   string           `json:"invoice_number"`
+	Status          string           `json:"status"`
+	Principal       float64          `json:"principal"`
+	InterestAccrued float64          `json:"interest_accrued"`
+	AmountDue       float64          `json:"amount_due"`
+	PaidDate        *time.Time       `json:"paid_date,omitempty"`
+	InterestPaid    float64          `json:"interest_paid"`
+	PrincipalPaid   float64          `json:"principal_paid"`
+	Entries         []*InterestEntry `json:"entries"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PayloadLogEntry is one request and its response as captured by payload
+// logging, with secrets and PII already redacted. Bodies are cut at the
+// configured size; Truncated says whether either was
+type PayloadLogEntry struct {
+	TenantID        uuid.UUID         `json:"tenant_id"`
+	UserID          *uuid.UUID        `json:"user_id,omitempty"`
+	RequestID       string            `json:"request_id,omitempty"`
+	Method          string            `json:"method"`
+	Route           string            `json:"route"`
+	Query           string            `json:"query,omitempty"`
+	Status          int               `json:"status"`
+	DurationMs      float64           `json:"duration_ms"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	Truncated       bool              `json:"truncated"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
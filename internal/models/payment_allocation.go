@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How a payment is applied to invoices: auto settles the oldest due invoices
+// first, manual uses the given lines and none leaves it all on account
+const (
+	AllocationModeAuto   = "auto"
+	AllocationModeManual = "manual"
+	AllocationModeNone   = "none"
+)
+
+// Payment methods for received payments besides the counter ones
+const (
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodCheque       = "cheque"
+)
+
+// CustomerPayment is money received from a customer. TDS the customer
+// deducted settles invoices like Amount does. Allocated is applied to invoices
+// by its active allocations; Unallocated is on account
+type CustomerPayment struct {
+	ID                   uuid.UUID            `json:"id" db:"id"`
+	TenantID             uuid.UUID            `json:"tenant_id" db:"tenant_id"`
+	DistributorID        uuid.UUID            `json:"customer_id" db:"distributor_id"`
+	PaymentNumber        string               `json:"payment_number" db:"payment_number"`
+	Amount               float64              `json:"amount" db:"amount"`
+	PaymentDate          time.Time            `json:"payment_date" db:"payment_date"`
+	Method               *string              `json:"method,omitempty" db:"method"`
+	Reference            *string              `json:"reference,omitempty" db:"reference"`
+	Notes                *string              `json:"notes,omitempty" db:"notes"`
+	TDSSectionID         *uuid.UUID           `json:"tds_section_id,omitempty" db:"tds_section_id"`
+	TDSSection           *string              `json:"tds_section,omitempty" db:"tds_section"`
+	TDSAmount            float64              `json:"tds_amount" db:"tds_amount"`
+	TDSCertificateNumber *string              `json:"tds_certificate_number,omitempty" db:"tds_certificate_number"`
+	TDSCertificateDate   *time.Time           `json:"tds_certificate_date,omitempty" db:"tds_certificate_date"`
+	CreatedBy            *uuid.UUID           `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt            time.Time            `json:"created_at" db:"created_at"`
+	Allocated            float64              `json:"allocated"`
+	Unallocated          float64              `json:"unallocated"`
+	Allocations          []*PaymentAllocation `json:"allocations,omitempty"`
+}
+
+// Settled is what the payment settles on invoices: the amount received plus
+// the TDS deducted from it
+func (p *CustomerPayment) Settled() float64 {
+	return p.Amount + p.TDSAmount
+}
+
+// PaymentAllocation applies part of a payment to an invoice. Reversed
+// allocations no longer count and are kept as history
+type PaymentAllocation struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	PaymentID   uuid.UUID  `json:"payment_id" db:"payment_id"`
+	InvoiceID   uuid.UUID  `json:"invoice_id" db:"invoice_id"`
+	Amount      float64    `json:"amount" db:"amount"`
+	AllocatedBy *uuid.UUID `json:"allocated_by,omitempty" db:"allocated_by"`
+	AllocatedAt time.Time  `json:"allocated_at" db:"allocated_at"`
+	ReversedBy  *uuid.UUID `json:"reversed_by,omitempty" db:"reversed_by"`
+	ReversedAt  *time.Time `json:"reversed_at,omitempty" db:"reversed_at"`
+	// Joined
+	InvoiceNumber string `json:"invoice_number,omitempty"`
+	PaymentNumber string `json:"payment_number,omitempty"`
+}
+
+// AllocationLine is an amount of a payment to apply to one invoice
+type AllocationLine struct {
+	InvoiceID uuid.UUID `json:"invoice_id"`
+	Amount    float64   `json:"amount"`
+}
+
+// OpenInvoice is a customer's unpaid invoice with what is still outstanding
+// on it, accrued interest included
+type OpenInvoice struct {
+	InvoiceID       uuid.UUID `json:"invoice_id"`
+	InvoiceNumber   string    `json:"invoice_number"`
+	Status          string    `json:"status"`
+	IssuedDate      time.Time `json:"issued_date"`
+	DueDate         time.Time `json:"due_date"`
+	TotalAmount     float64   `json:"total_amount"`
+	InterestAccrued float64   `json:"interest_accrued"`
+	Allocated       float64   `json:"allocated"`
+	Outstanding     float64   `json:"outstanding"`
+}
+
+// CustomerPaymentRequest records a payment and applies it as Mode says;
+// PaymentDate defaults to today and Mode to auto. TDSAmount needs the TDS
+// section it was deducted under
+type CustomerPaymentRequest struct {
+	Amount               float64          `json:"amount"`
+	PaymentDate          *string          `json:"payment_date,omitempty"`
+	Method               *string          `json:"method,omitempty"`
+	Reference            *string          `json:"reference,omitempty"`
+	Notes                *string          `json:"notes,omitempty"`
+	TDSSectionID         *uuid.UUID       `json:"tds_section_id,omitempty"`
+	TDSAmount            float64          `json:"tds_amount,omitempty"`
+	TDSCertificateNumber *string          `json:"tds_certificate_number,omitempty"`
+	Mode                 string           `json:"mode"`
+	Allocations          []AllocationLine `json:"allocations,omitempty"`
+}
+
+// AllocationRequest applies a payment as Mode says, auto by default
+type AllocationRequest struct {
+	Mode        string           `json:"mode"`
+	Allocations []AllocationLine `json:"allocations,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Period close actions recorded in the history
+const (
+	PeriodClosed   = "closed"
+	PeriodReopened = "reopened"
+)
+
+// PeriodClose is a closed accounting month. Without a branch it covers the
+// whole tenant; with one, only that branch's documents
+type PeriodClose struct {
+	ID       uuid.UUID  `json:"id" db:"id"`
+	TenantID uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	BranchID *uuid.UUID `json:"branch_id,omitempty" db:"branch_id"`
+	// Period is the first day of the closed month
+	Period   time.Time  `json:"period" db:"period"`
+	Notes    *string    `json:"notes,omitempty" db:"notes"`
+	ClosedBy *uuid.UUID `json:"closed_by,omitempty" db:"closed_by"`
+	ClosedAt time.Time  `json:"closed_at" db:"closed_at"`
+}
+
+// PeriodCloseEvent is an entry in the history of closing and reopening
+type PeriodCloseEvent struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	TenantID  uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	BranchID  *uuid.UUID `json:"branch_id,omitempty" db:"branch_id"`
+	Period    time.Time  `json:"period" db:"period"`
+	Action    string     `json:"action" db:"action"`
+	Reason    *string    `json:"reason,omitempty" db:"reason"`
+	UserID    *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PeriodCloseRequest closes or reopens a month given as YYYY-MM, for the
+// whole tenant unless BranchID is set. Reason is required to reopen
+type PeriodCloseRequest struct {
+	Period   string     `json:"period"`
+	BranchID *uuid.UUID `json:"branch_id,omitempty"`
+	Reason   *string    `json:"reason,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Price change sources
+const (
+	PriceChangeSourceManual = "manual"
+	PriceChangeSourceBulk   = "bulk"
+	PriceChangeSourceImport = "import"
+)
+
+// PriceHistory records one change of a product's list price
+type PriceHistory struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	TenantID  uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ProductID uuid.UUID  `json:"product_id" db:"product_id"`
+	OldPrice  float64    `json:"old_price" db:"old_price"`
+	NewPrice  float64    `json:"new_price" db:"new_price"`
+	ChangedBy *uuid.UUID `json:"changed_by,omitempty" db:"changed_by"`
+	Reason    *string    `json:"reason,omitempty" db:"reason"`
+	Source    string     `json:"source" db:"source"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PriceRealization compares what a product actually sold for against its list price
+type PriceRealization struct {
+	ListPrice          float64   `json:"list_price"`
+	AvgSellingPrice    float64   `json:"avg_selling_price"`
+	RealizationPercent float64   `json:"realization_percent"` // avg selling price as % of list price
+	UnitsSold          int       `json:"units_sold"`
+	OrdersCount        int       `json:"orders_count"`
+	Since              time.Time `json:"since"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -33,10 +34,17 @@ type ProductBulkUpdate struct {
 	ExpiryDate        *time.Time           `json:"expiry_date,omitempty"`                       // New expiry date for all products
 	UnitOfMeasure     *string              `json:"unit_of_measure,omitempty"`                    // New unit of measure
 	Description       *string              `json:"description,omitempty"`                        // New description for all products
+	Reason            *string              `json:"reason,omitempty"`                             // Reason recorded in price history for price changes
 	ValidationMode    string               `json:"validation_mode"`                             // Mode: "strict", "skip_invalid" - default strict
 	TransactionMode   string               `json:"transaction_mode"`                            // Mode: "atomic", "best_effort" - default atomic
 }
 
+// ProductBulkDelete represents a bulk product delete
+type ProductBulkDelete struct {
+	ProductIDs []uuid.UUID `json:"product_ids" validate:"required,min=1"` // List of product IDs to delete
+	DryRun     bool        `json:"dry_run"`                               // Report what would be blocked without deleting
+}
+
 // ProductBulkCreate represents bulk product creation
 type ProductBulkCreate struct {
 	Products         []*Product           `json:"products" validate:"required,min=1,dive"`      // List of products to create
@@ -51,11 +59,70 @@ type Product struct {
 	Name           string    `json:"name" db:"name"`
 	BatchNumber    *string   `json:"batch_number" db:"batch_number"`
 	ExpiryDate     *time.Time `json:"expiry_date" db:"expiry_date"`
+	// Deprecated: Quantity mirrors the total across warehouses; read
+	// inventory or product availability and write stock through inventory.
 	Quantity       int       `json:"quantity" db:"quantity"`
 	UnitPrice      float64   `json:"unit_price" db:"unit_price"`
+	// CostPrice is the landed cost used for margin guardrails on sales orders,
+	// shown only to users with products:view_cost
+	CostPrice      *float64  `json:"cost_price,omitempty" db:"cost_price" perm:"products:view_cost"`
 	Barcode        *string   `json:"barcode" db:"barcode"`
 	UnitOfMeasure  *string   `json:"unit_of_measure" db:"unit_of_measure"`
 	Description    *string   `json:"description" db:"description"`
+	// Translations holds per-locale catalog text keyed by locale code (e.g. "hi", "mr", "te")
+	Translations   ProductTranslations `json:"translations,omitempty" db:"translations"`
+	// IsPublished exposes the product on the public storefront catalog
+	IsPublished    bool       `json:"is_published" db:"is_published"`
+	PublishedAt    *time.Time `json:"published_at,omitempty" db:"published_at"`
+	// ABCClass and XYZClass are set by the nightly classification job (see analytics.ABCXYZService)
+	ABCClass       *string    `json:"abc_class,omitempty" db:"abc_class"`
+	XYZClass       *string    `json:"xyz_class,omitempty" db:"xyz_class"`
+	ClassifiedAt   *time.Time `json:"classified_at,omitempty" db:"classified_at"`
+	// IsBundle marks a kit built from component products (see BundleComponent)
+	IsBundle       bool       `json:"is_bundle" db:"is_bundle"`
+	// GSTRate is the product's GST slab in percent, when known
+	GSTRate        *float64   `json:"gst_rate,omitempty" db:"gst_rate"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ProductTranslation is a product's catalog text in one locale
+type ProductTranslation struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
+// ProductTranslations maps locale codes to translated catalog text
+type ProductTranslations map[string]ProductTranslation
+
+// Lookup finds the translation for a locale, falling back from a regional
+// locale such as "hi-IN" to its language "hi"
+func (t ProductTranslations) Lookup(locale string) (ProductTranslation, bool) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if locale == "" {
+		return ProductTranslation{}, false
+	}
+	if tr, ok := t[locale]; ok && tr.Name != "" {
+		return tr, true
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		if tr, ok := t[locale[:i]]; ok && tr.Name != "" {
+			return tr, true
+		}
+	}
+	return ProductTranslation{}, false
+}
+
+// Localize replaces the name and description with the locale's translation;
+// without one the default-locale text is kept. A missing translated
+// description falls back to the default description.
+func (p *Product) Localize(locale string) {
+	tr, ok := p.Translations.Lookup(locale)
+	if !ok {
+		return
+	}
+	p.Name = tr.Name
+	if tr.Description != nil {
+		p.Description = tr.Description
+	}
 }
\ No newline at end of file
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ABC classes rank products by their share of sales revenue
+const (
+	ABCClassA = "A"
+	ABCClassB = "B"
+	ABCClassC = "C"
+)
+
+// XYZ classes rank products by how steady their monthly demand is
+const (
+	XYZClassX = "X"
+	XYZClassY = "Y"
+	XYZClassZ = "Z"
+)
+
+// ProductSalesHistory is a product's sales revenue and monthly units over the
+// classification window; months without sales are absent from Monthly
+type ProductSalesHistory struct {
+	ProductID   uuid.UUID
+	ProductName string
+	Revenue     float64
+	Monthly     []*MonthlyDemand
+}
+
+// ReplenishmentPolicy is the default reorder policy for an ABC/XYZ class
+type ReplenishmentPolicy struct {
+	ReviewIntervalDays int     `json:"review_interval_days"`
+	ServiceLevel       float64 `json:"service_level"`
+	SafetyStockDays    int     `json:"safety_stock_days"`
+}
+
+// ProductClassification is one product's ABC/XYZ class and the figures behind it
+type ProductClassification struct {
+	ProductID           uuid.UUID           `json:"product_id"`
+	ProductName         string              `json:"product_name"`
+	Revenue             float64             `json:"revenue"`
+	RevenueShare        float64             `json:"revenue_share"`
+	CumulativeShare     float64             `json:"cumulative_share"`
+	AverageMonthlyUnits float64             `json:"average_monthly_units"`
+	DemandCV            *float64            `json:"demand_cv,omitempty"`
+	ABCClass            string              `json:"abc_class"`
+	XYZClass            string              `json:"xyz_class"`
+	Policy              ReplenishmentPolicy `json:"policy"`
+}
+
+// ProductClassificationReport is the ABC/XYZ classification of a tenant's products
+type ProductClassificationReport struct {
+	ClassifiedAt time.Time                `json:"classified_at"`
+	Months       int                      `json:"months"`
+	TotalRevenue float64                  `json:"total_revenue"`
+	Matrix       map[string]int           `json:"matrix"`
+	Products     []*ProductClassification `json:"products"`
+	Saved        bool                     `json:"saved"`
+}
+
+// ProductReplenishmentPolicy is the stored class of a product with its default policy
+type ProductReplenishmentPolicy struct {
+	ProductID    uuid.UUID            `json:"product_id"`
+	ABCClass     *string              `json:"abc_class"`
+	XYZClass     *string              `json:"xyz_class"`
+	ClassifiedAt *time.Time           `json:"classified_at,omitempty"`
+	Policy       *ReplenishmentPolicy `json:"policy"`
+}
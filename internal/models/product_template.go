@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductTemplate pre-fills the shared attributes of similar SKUs; products
+// copy them when created and do not follow later template edits
+type ProductTemplate struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Name          string     `json:"name" db:"name"`
+	CategoryID    *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	UnitOfMeasure *string    `json:"unit_of_measure,omitempty" db:"unit_of_measure"`
+	GSTRate       *float64   `json:"gst_rate,omitempty" db:"gst_rate"`
+	// UnitPrice is a default list price; the request may override it
+	UnitPrice   *float64 `json:"unit_price,omitempty" db:"unit_price"`
+	Description *string  `json:"description,omitempty" db:"description"`
+	// Storage attributes become the product's storage requirement
+	ColdChain   bool      `json:"cold_chain" db:"cold_chain"`
+	Shade       bool      `json:"shade" db:"shade"`
+	HazmatClass *string   `json:"hazmat_class,omitempty" db:"hazmat_class"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// HasStorageRequirement reports whether products made from the template need special storage
+func (t *ProductTemplate) HasStorageRequirement() bool {
+	return t.ColdChain || t.Shade || t.HazmatClass != nil
+}
+
+// ProductFromTemplateRequest names the SKU-specific fields of a product made
+// from a template; set fields override the template's
+type ProductFromTemplateRequest struct {
+	Name          string   `json:"name"`
+	Barcode       *string  `json:"barcode"`
+	BatchNumber   *string  `json:"batch_number"`
+	ExpiryDate    *string  `json:"expiry_date"`
+	Quantity      int      `json:"quantity"`
+	UnitPrice     *float64 `json:"unit_price"`
+	CostPrice     *float64 `json:"cost_price" perm:"products:view_cost"`
+	UnitOfMeasure *string  `json:"unit_of_measure"`
+	Description   *string  `json:"description"`
+}
+
+// CloneProductRequest duplicates a product under a new barcode; the name
+// defaults to the source product's
+type CloneProductRequest struct {
+	Name        *string `json:"name"`
+	Barcode     string  `json:"barcode"`
+	BatchNumber *string `json:"batch_number"`
+	ExpiryDate  *string `json:"expiry_date"`
+}
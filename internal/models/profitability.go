@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Profitability report dimensions
+const (
+	ProfitabilityByProduct  = "product"
+	ProfitabilityByCustomer = "customer"
+	ProfitabilityByOrder    = "order"
+)
+
+// Profitability comparison periods
+const (
+	ProfitabilityCompareNone           = "none"
+	ProfitabilityComparePreviousPeriod = "previous_period"
+	ProfitabilityComparePreviousYear   = "previous_year"
+)
+
+// ProfitabilitySale is one non-cancelled sales order with the unit cost in
+// effect on its order date. UnitCost is nil when the product has no cost
+type ProfitabilitySale struct {
+	OrderID         uuid.UUID `json:"order_id"`
+	OrderDate       time.Time `json:"order_date"`
+	ProductID       uuid.UUID `json:"product_id"`
+	ProductName     string    `json:"product_name"`
+	DistributorID   uuid.UUID `json:"distributor_id"`
+	DistributorName string    `json:"distributor_name"`
+	Quantity        int       `json:"quantity"`
+	UnitPrice       float64   `json:"unit_price"`
+	UnitCost        *float64  `json:"unit_cost" perm:"products:view_cost"`
+}
+
+// ProfitabilityFigures are the revenue, cost and gross margin of a group of
+// sales. Revenue of sales without a known cost is counted in UncostedRevenue
+// and left out of the margin
+type ProfitabilityFigures struct {
+	Orders          int     `json:"orders"`
+	Quantity        int     `json:"quantity"`
+	Revenue         float64 `json:"revenue"`
+	Cost            float64 `json:"cost" perm:"products:view_cost"`
+	GrossMargin     float64 `json:"gross_margin" perm:"products:view_cost"`
+	MarginPercent   float64 `json:"margin_percent" perm:"products:view_cost"`
+	UncostedRevenue float64 `json:"uncosted_revenue"`
+}
+
+// ProfitabilityRow is a product, customer or order with its figures for the
+// period and, when comparing, the comparison period and the change. Order
+// rows are named after the product and also carry the customer and date
+type ProfitabilityRow struct {
+	ID                   uuid.UUID             `json:"id"`
+	Name                 string                `json:"name"`
+	CustomerName         string                `json:"customer_name,omitempty"`
+	OrderDate            *time.Time            `json:"order_date,omitempty"`
+	Current              ProfitabilityFigures  `json:"current"`
+	Previous             *ProfitabilityFigures `json:"previous,omitempty"`
+	RevenueChangePercent *float64              `json:"revenue_change_percent,omitempty"`
+	// MarginChangePoints is the change in margin percent, in percentage points
+	MarginChangePoints *float64 `json:"margin_change_points,omitempty" perm:"products:view_cost"`
+}
+
+// ProfitabilityReport is gross margin by one dimension over a period
+type ProfitabilityReport struct {
+	Dimension   string              `json:"dimension"`
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	Compare     string              `json:"compare"`
+	CompareFrom *time.Time          `json:"compare_from,omitempty"`
+	CompareTo   *time.Time          `json:"compare_to,omitempty"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Rows        []*ProfitabilityRow `json:"rows"`
+	Totals      ProfitabilityRow    `json:"totals"`
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purchase invoice capture statuses
+const (
+	PurchaseCapturePendingReview = "pending_review"
+	PurchaseCaptureConfirmed     = "confirmed"
+	PurchaseCaptureDiscarded     = "discarded"
+)
+
+// PurchaseCaptureLine is one line read from a supplier invoice. ProductID is
+// a suggestion from the description and must be set on every line before
+// the capture can be confirmed
+type PurchaseCaptureLine struct {
+	Description string     `json:"description"`
+	HSNCode     *string    `json:"hsn_code,omitempty"`
+	ProductID   *uuid.UUID `json:"product_id,omitempty"`
+	Quantity    int        `json:"quantity"`
+	UnitPrice   float64    `json:"unit_price"`
+	Amount      float64    `json:"amount"`
+}
+
+// PurchaseCaptureDraft is the header and line data extracted from a supplier
+// invoice, corrected by the reviewer before confirmation
+type PurchaseCaptureDraft struct {
+	SupplierID    *uuid.UUID            `json:"supplier_id,omitempty"`
+	SupplierGSTIN *string               `json:"supplier_gstin,omitempty"`
+	InvoiceNumber *string               `json:"invoice_number,omitempty"`
+	InvoiceDate   *time.Time            `json:"invoice_date,omitempty"`
+	WarehouseID   *uuid.UUID            `json:"warehouse_id,omitempty"`
+	TaxAmount     *float64              `json:"tax_amount,omitempty"`
+	TotalAmount   *float64              `json:"total_amount,omitempty"`
+	Lines         []PurchaseCaptureLine `json:"lines"`
+}
+
+// BillAmount is the invoice total, or the sum of the lines when no total
+// was read
+func (d PurchaseCaptureDraft) BillAmount() float64 {
+	if d.TotalAmount != nil {
+		return *d.TotalAmount
+	}
+	var amount float64
+	for _, line := range d.Lines {
+		amount += line.Amount
+	}
+	return amount
+}
+
+// PurchaseInvoiceCapture is an uploaded supplier invoice, its OCR text and
+// the draft purchase extracted from it
+type PurchaseInvoiceCapture struct {
+	ID          uuid.UUID            `json:"id" db:"id"`
+	TenantID    uuid.UUID            `json:"tenant_id" db:"tenant_id"`
+	Status      string               `json:"status" db:"status"`
+	ObjectKey   string               `json:"-" db:"object_key"`
+	FileName    string               `json:"file_name" db:"file_name"`
+	ContentType string               `json:"content_type" db:"content_type"`
+	SizeBytes   int64                `json:"size_bytes" db:"size_bytes"`
+	DocumentURL string               `json:"document_url,omitempty" db:"-"`
+	OCRProvider string               `json:"ocr_provider" db:"ocr_provider"`
+	OCRText     string               `json:"ocr_text" db:"ocr_text"`
+	OCRError    *string              `json:"ocr_error,omitempty" db:"ocr_error"`
+	Extracted   PurchaseCaptureDraft `json:"extracted" db:"extracted"`
+	OrderIDs    []uuid.UUID          `json:"order_ids" db:"order_ids"`
+	UploadedBy  *uuid.UUID           `json:"uploaded_by,omitempty" db:"uploaded_by"`
+	ConfirmedBy *uuid.UUID           `json:"confirmed_by,omitempty" db:"confirmed_by"`
+	ConfirmedAt *time.Time           `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	CreatedAt   time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at" db:"updated_at"`
+	// PossibleDuplicates are the open duplicate flags on a capture under review
+	PossibleDuplicates []*SupplierBillDuplicate `json:"possible_duplicates,omitempty" db:"-"`
+}
+
+// Supplier bill duplicate flag statuses
+const (
+	BillDuplicateFlagged    = "flagged"
+	BillDuplicateOverridden = "overridden"
+	BillDuplicateDiscarded  = "discarded"
+)
+
+// SupplierBillDuplicate flags a captured supplier invoice that looks like an
+// earlier one. Reasons list what matched, e.g. "same invoice number"
+type SupplierBillDuplicate struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	CaptureID        uuid.UUID  `json:"capture_id" db:"capture_id"`
+	MatchedCaptureID uuid.UUID  `json:"matched_capture_id" db:"matched_capture_id"`
+	Score            float64    `json:"score" db:"score"`
+	Reasons          []string   `json:"reasons" db:"reasons"`
+	Status           string     `json:"status" db:"status"`
+	OverrideReason   *string    `json:"override_reason,omitempty" db:"override_reason"`
+	ResolvedBy       *uuid.UUID `json:"resolved_by,omitempty" db:"resolved_by"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty" db:"resolved_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	// Bill details of both sides, for the report
+	SupplierID           *uuid.UUID `json:"supplier_id,omitempty" db:"-"`
+	InvoiceNumber        *string    `json:"invoice_number,omitempty" db:"-"`
+	TotalAmount          *float64   `json:"total_amount,omitempty" db:"-"`
+	MatchedInvoiceNumber *string    `json:"matched_invoice_number,omitempty" db:"-"`
+	MatchedStatus        string     `json:"matched_status" db:"-"`
+}
+
+// SupplierBillDuplicateReport summarises duplicate flags over a period.
+// PreventedAmount totals the bills discarded as duplicates
+type SupplierBillDuplicateReport struct {
+	From            time.Time                `json:"from"`
+	To              time.Time                `json:"to"`
+	Flagged         int                      `json:"flagged"`
+	Overridden      int                      `json:"overridden"`
+	Discarded       int                      `json:"discarded"`
+	PreventedAmount float64                  `json:"prevented_amount"`
+	Duplicates      []*SupplierBillDuplicate `json:"duplicates"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Landed cost allocation methods
+const (
+	LandedCostByValue    = "value"
+	LandedCostByQuantity = "quantity"
+)
+
+// Purchase receipt charge types
+const (
+	ReceiptChargeFreight  = "freight"
+	ReceiptChargeCustoms  = "customs"
+	ReceiptChargeHandling = "handling"
+	ReceiptChargeOther    = "other"
+)
+
+// PurchaseReceiptCharge is a freight, customs or handling cost paid on a receipt
+type PurchaseReceiptCharge struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ReceiptID   uuid.UUID `json:"receipt_id" db:"receipt_id"`
+	ChargeType  string    `json:"charge_type" db:"charge_type"`
+	Amount      float64   `json:"amount" db:"amount"`
+	Description *string   `json:"description,omitempty" db:"description"`
+}
+
+// PurchaseReceiptLine is one received purchase order with its share of the
+// receipt charges and the resulting landed unit cost
+type PurchaseReceiptLine struct {
+	ID                uuid.UUID `json:"id" db:"id"`
+	ReceiptID         uuid.UUID `json:"receipt_id" db:"receipt_id"`
+	OrderID           uuid.UUID `json:"order_id" db:"order_id"`
+	ProductID         uuid.UUID `json:"product_id" db:"product_id"`
+	WarehouseID       uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	Quantity          int       `json:"quantity" db:"quantity"`
+	UnitPrice         float64   `json:"unit_price" db:"unit_price"`
+	AllocatedCharges  float64   `json:"allocated_charges" db:"allocated_charges"`
+	LandedUnitCost    float64   `json:"landed_unit_cost" db:"landed_unit_cost" perm:"products:view_cost"`
+	PreviousCostPrice *float64  `json:"previous_cost_price,omitempty" db:"previous_cost_price" perm:"products:view_cost"`
+	// NewCostPrice is nil on consignment receipts, which do not change product cost
+	NewCostPrice *float64 `json:"new_cost_price,omitempty" db:"new_cost_price" perm:"products:view_cost"`
+}
+
+// PurchaseReceipt records purchase orders received together and the charges
+// allocated across them
+type PurchaseReceipt struct {
+	ID               uuid.UUID                `json:"id" db:"id"`
+	TenantID         uuid.UUID                `json:"tenant_id" db:"tenant_id"`
+	SupplierID       *uuid.UUID               `json:"supplier_id,omitempty" db:"supplier_id"`
+	AllocationMethod string                   `json:"allocation_method" db:"allocation_method"`
+	Consignment      bool                     `json:"consignment" db:"is_consignment"`
+	GoodsValue       float64                  `json:"goods_value" db:"goods_value"`
+	TotalCharges     float64                  `json:"total_charges" db:"total_charges"`
+	Notes            *string                  `json:"notes,omitempty" db:"notes"`
+	ReceivedBy       *uuid.UUID               `json:"received_by,omitempty" db:"received_by"`
+	ReceivedAt       time.Time                `json:"received_at" db:"received_at"`
+	Charges          []*PurchaseReceiptCharge `json:"charges"`
+	Lines            []*PurchaseReceiptLine   `json:"lines"`
+	// CapacityWarnings lists warehouses the receipt took past a capacity;
+	// they are reported when receiving and not stored
+	CapacityWarnings []*CapacityWarning `json:"capacity_warnings,omitempty" db:"-"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purchase requisition statuses
+const (
+	RequisitionStatusPending   = "pending"
+	RequisitionStatusApproved  = "approved"
+	RequisitionStatusRejected  = "rejected"
+	RequisitionStatusConverted = "converted"
+	RequisitionStatusCancelled = "cancelled"
+)
+
+// PurchaseRequisition is a request from warehouse staff to buy stock. Once
+// approved it is converted into a purchase order, which OrderID links to.
+type PurchaseRequisition struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ProductID       uuid.UUID  `json:"product_id" db:"product_id"`
+	WarehouseID     uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	Quantity        int        `json:"quantity" db:"quantity"`
+	NeedBy          time.Time  `json:"need_by" db:"need_by"`
+	SupplierID      *uuid.UUID `json:"supplier_id,omitempty" db:"supplier_id"`
+	Notes           *string    `json:"notes,omitempty" db:"notes"`
+	Status          string     `json:"status" db:"status"`
+	RequestedBy     *uuid.UUID `json:"requested_by,omitempty" db:"requested_by"`
+	ApprovedBy      *uuid.UUID `json:"approved_by,omitempty" db:"approved_by"`
+	ApprovedAt      *time.Time `json:"approved_at,omitempty" db:"approved_at"`
+	RejectionReason *string    `json:"rejection_reason,omitempty" db:"rejection_reason"`
+	OrderID         *uuid.UUID `json:"order_id,omitempty" db:"order_id"`
+	ConvertedAt     *time.Time `json:"converted_at,omitempty" db:"converted_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// PurchaseRequisitionFilter narrows a requisition listing
+type PurchaseRequisitionFilter struct {
+	Status      *string
+	WarehouseID *uuid.UUID
+	OrderID     *uuid.UUID
+	Limit       int
+	Offset      int
+}
+
+// RequisitionTrace follows a requisition to its purchase order and the receipt
+// that received it; either is nil until that step has happened
+type RequisitionTrace struct {
+	Requisition   *PurchaseRequisition `json:"requisition"`
+	PurchaseOrder *Order               `json:"purchase_order"`
+	Receipt       *PurchaseReceipt     `json:"receipt"`
+}
+
+// SupplierPurchaseOrders are the purchase orders raised for one supplier by a
+// conversion, one per product and warehouse
+type SupplierPurchaseOrders struct {
+	SupplierID     uuid.UUID   `json:"supplier_id"`
+	Orders         []*Order    `json:"orders"`
+	RequisitionIDs []uuid.UUID `json:"requisition_ids"`
+	TotalValue     float64     `json:"total_value"`
+}
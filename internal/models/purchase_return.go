@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of supplier statement entries
+const (
+	StatementEntryPurchase  = "purchase"
+	StatementEntryDebitNote = "debit_note"
+)
+
+// PurchaseReturn is goods from a received purchase order sent back to the
+// supplier
+type PurchaseReturn struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	TenantID     uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ReturnNumber string     `json:"return_number" db:"return_number"`
+	OrderID      uuid.UUID  `json:"order_id" db:"order_id"`
+	SupplierID   *uuid.UUID `json:"supplier_id,omitempty" db:"supplier_id"`
+	ProductID    uuid.UUID  `json:"product_id" db:"product_id"`
+	WarehouseID  uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	Quantity     int        `json:"quantity" db:"quantity"`
+	UnitPrice    float64    `json:"unit_price" db:"unit_price"`
+	Reason       *string    `json:"reason,omitempty" db:"reason"`
+	ReturnedBy   *uuid.UUID `json:"returned_by,omitempty" db:"returned_by"`
+	ReturnedAt   time.Time  `json:"returned_at" db:"returned_at"`
+	DebitNote    *DebitNote `json:"debit_note,omitempty" db:"-"`
+}
+
+// DebitNote claims the value of returned goods and the GST on it back from
+// the supplier
+type DebitNote struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	SupplierID       *uuid.UUID `json:"supplier_id,omitempty" db:"supplier_id"`
+	PurchaseReturnID uuid.UUID  `json:"purchase_return_id" db:"purchase_return_id"`
+	DebitNoteNumber  string     `json:"debit_note_number" db:"debit_note_number"`
+	SupplierGSTIN    *string    `json:"supplier_gstin,omitempty" db:"supplier_gstin"`
+	TaxableAmount    float64    `json:"taxable_amount" db:"taxable_amount"`
+	GSTRate          float64    `json:"gst_rate" db:"gst_rate"`
+	CGST             float64    `json:"cgst" db:"cgst"`
+	SGST             float64    `json:"sgst" db:"sgst"`
+	IGST             float64    `json:"igst" db:"igst"`
+	TotalAmount      float64    `json:"total_amount" db:"total_amount"`
+	IssuedDate       time.Time  `json:"issued_date" db:"issued_date"`
+	CreatedBy        *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PurchaseReturnRequest returns part or all of a received purchase order.
+// GST is charged at GSTRate, as IGST when InterState and as CGST and SGST
+// otherwise
+type PurchaseReturnRequest struct {
+	OrderID    uuid.UUID `json:"order_id"`
+	Quantity   int       `json:"quantity"`
+	Reason     *string   `json:"reason,omitempty"`
+	GSTRate    float64   `json:"gst_rate"`
+	InterState bool      `json:"inter_state"`
+}
+
+// SupplierStatement is a supplier's statement of account for [From, To],
+// both dates inclusive. Received purchases are credits and debit notes are
+// debits; a positive balance is owed to the supplier
+type SupplierStatement struct {
+	TenantID       uuid.UUID         `json:"tenant_id"`
+	SupplierID     uuid.UUID         `json:"supplier_id"`
+	SupplierName   string            `json:"supplier_name"`
+	SupplierGSTIN  *string           `json:"supplier_gstin,omitempty"`
+	From           time.Time         `json:"from"`
+	To             time.Time         `json:"to"`
+	OpeningBalance float64           `json:"opening_balance"`
+	Entries        []*StatementEntry `json:"entries"`
+	TotalDebits    float64           `json:"total_debits"`
+	TotalCredits   float64           `json:"total_credits"`
+	ClosingBalance float64           `json:"closing_balance"`
+	GeneratedAt    time.Time         `json:"generated_at"`
+}
+
+// ITCReversalReport is the input tax credit to reverse in GSTR-3B for the
+// debit notes issued in a return period (MMYYYY)
+type ITCReversalReport struct {
+	TenantID          uuid.UUID    `json:"tenant_id"`
+	ReturnPeriod      string       `json:"return_period"`
+	DebitNotes        []*DebitNote `json:"debit_notes"`
+	TotalTaxableValue float64      `json:"total_taxable_value"`
+	TotalCGST         float64      `json:"total_cgst"`
+	TotalSGST         float64      `json:"total_sgst"`
+	TotalIGST         float64      `json:"total_igst"`
+}
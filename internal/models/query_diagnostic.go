@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueryDiagnostic is a slow or failing query recorded by the query audit.
+// SampleRate is the fraction of slow queries kept when it was recorded;
+// failures are always kept
+type QueryDiagnostic struct {
+	ID           int64      `json:"id"`
+	TenantID     *uuid.UUID `json:"tenant_id,omitempty"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	RequestID    string     `json:"request_id,omitempty"`
+	Fingerprint  string     `json:"fingerprint"`
+	QueryText    string     `json:"query_text"`
+	DurationMs   float64    `json:"duration_ms"`
+	RowCount     int64      `json:"row_count"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	SampleRate   float64    `json:"sample_rate"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// QueryOffender aggregates a tenant's recordings of one query. Estimated
+// counts scale sampled slow queries back up by their sample rate
+type QueryOffender struct {
+	TenantID       *uuid.UUID `json:"tenant_id,omitempty"`
+	Fingerprint    string     `json:"fingerprint"`
+	QueryText      string     `json:"query_text"`
+	Recorded       int64      `json:"recorded"`
+	EstimatedCount float64    `json:"estimated_count"`
+	Errors         int64      `json:"errors"`
+	AvgDurationMs  float64    `json:"avg_duration_ms"`
+	MaxDurationMs  float64    `json:"max_duration_ms"`
+	TotalTimeMs    float64    `json:"estimated_total_time_ms"`
+	MaxRows        int64      `json:"max_rows"`
+	LastError      *string    `json:"last_error,omitempty"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+}
+
+// QueryOffenderFilter narrows the worst offenders report
+type QueryOffenderFilter struct {
+	TenantID *uuid.UUID
+	Since    time.Time
+	// OrderBy is total_time, max_duration or errors
+	OrderBy string
+	Limit   int
+}
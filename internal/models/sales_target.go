@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What a sales target is set for: a sales rep, a region, or a product category
+const (
+	SalesTargetScopeUser     = "user"
+	SalesTargetScopeRegion   = "region"
+	SalesTargetScopeCategory = "category"
+)
+
+// What counts towards a sales target: the value of sales orders dated in the
+// month, or the taxable value of invoices issued in it
+const (
+	SalesTargetBasisOrders   = "orders"
+	SalesTargetBasisInvoices = "invoices"
+)
+
+// SalesTarget is a monthly sales target. Exactly one of UserID, Region and
+// CategoryID is set, as Scope says; Subject names it. AchievedAmount is as of
+// CalculatedAt, nil until the achievement job first runs
+type SalesTarget struct {
+	ID                 uuid.UUID  `json:"id" db:"id"`
+	TenantID           uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Month              time.Time  `json:"month" db:"period_month"`
+	Scope              string     `json:"scope" db:"scope"`
+	UserID             *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	Region             *string    `json:"region,omitempty" db:"region"`
+	CategoryID         *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	Subject            string     `json:"subject"`
+	Basis              string     `json:"basis" db:"basis"`
+	TargetAmount       float64    `json:"target_amount" db:"target_amount"`
+	AchievedAmount     float64    `json:"achieved_amount" db:"achieved_amount"`
+	AchievementPercent float64    `json:"achievement_percent"`
+	CalculatedAt       *time.Time `json:"calculated_at,omitempty" db:"calculated_at"`
+	Notes              *string    `json:"notes,omitempty" db:"notes"`
+	CreatedBy          *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SalesTargetRequest creates a target; Month is YYYY-MM and Basis defaults to
+// invoices. On update only TargetAmount and Notes change
+type SalesTargetRequest struct {
+	Month        string     `json:"month"`
+	Scope        string     `json:"scope"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	Region       *string    `json:"region,omitempty"`
+	CategoryID   *uuid.UUID `json:"category_id,omitempty"`
+	Basis        string     `json:"basis"`
+	TargetAmount *float64   `json:"target_amount,omitempty"`
+	Notes        *string    `json:"notes,omitempty"`
+}
+
+// SalesTargetStanding is a target's place on the leaderboard of its scope
+type SalesTargetStanding struct {
+	Rank int `json:"rank"`
+	*SalesTarget
+}
+
+// SalesTargetBoard ranks a month's targets of one scope by achievement, for
+// leaderboards and manager dashboards
+type SalesTargetBoard struct {
+	Month              time.Time              `json:"month"`
+	Scope              string                 `json:"scope"`
+	CalculatedAt       *time.Time             `json:"calculated_at,omitempty"`
+	TargetTotal        float64                `json:"target_total"`
+	AchievedTotal      float64                `json:"achieved_total"`
+	AchievementPercent float64                `json:"achievement_percent"`
+	TargetsMet         int                    `json:"targets_met"`
+	Standings          []*SalesTargetStanding `json:"standings"`
+}
+
+// CustomerTerritoryRequest assigns a customer to a sales rep and region,
+// whose targets its sales count towards; nil clears either
+type CustomerTerritoryRequest struct {
+	SalesRepID *uuid.UUID `json:"sales_rep_id"`
+	Region     *string    `json:"region"`
+}
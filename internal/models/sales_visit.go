@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SalesVisit is a field rep's check-in at a distributor. Distances are from
+// the distributor's location and are nil when it has none on file.
+type SalesVisit struct {
+	ID                uuid.UUID          `json:"id" db:"id"`
+	TenantID          uuid.UUID          `json:"tenant_id" db:"tenant_id"`
+	UserID            uuid.UUID          `json:"user_id" db:"user_id"`
+	DistributorID     uuid.UUID          `json:"distributor_id" db:"distributor_id"`
+	CheckInAt         time.Time          `json:"check_in_at" db:"check_in_at"`
+	CheckInLatitude   float64            `json:"check_in_latitude" db:"check_in_latitude"`
+	CheckInLongitude  float64            `json:"check_in_longitude" db:"check_in_longitude"`
+	CheckInAccuracyM  *float64           `json:"check_in_accuracy_m,omitempty" db:"check_in_accuracy_m"`
+	CheckInDistanceM  *float64           `json:"check_in_distance_m,omitempty" db:"check_in_distance_m"`
+	CheckOutAt        *time.Time         `json:"check_out_at,omitempty" db:"check_out_at"`
+	CheckOutLatitude  *float64           `json:"check_out_latitude,omitempty" db:"check_out_latitude"`
+	CheckOutLongitude *float64           `json:"check_out_longitude,omitempty" db:"check_out_longitude"`
+	CheckOutDistanceM *float64           `json:"check_out_distance_m,omitempty" db:"check_out_distance_m"`
+	Notes             *string            `json:"notes,omitempty" db:"notes"`
+	Photos            []*SalesVisitPhoto `json:"photos,omitempty" db:"-"`
+	CreatedAt         time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// SalesVisitPhoto is a photo taken during a visit; URL is a short-lived download link
+type SalesVisitPhoto struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	VisitID   uuid.UUID `json:"visit_id" db:"visit_id"`
+	ObjectKey string    `json:"-" db:"object_key"`
+	Caption   *string   `json:"caption,omitempty" db:"caption"`
+	URL       string    `json:"url,omitempty" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// SalesVisitFilter narrows visit listings
+type SalesVisitFilter struct {
+	UserID        *uuid.UUID
+	DistributorID *uuid.UUID
+	From          *time.Time
+	To            *time.Time
+	Limit         int
+	Offset        int
+}
+
+// RouteStop is one visit in a daily route
+type RouteStop struct {
+	Visit                     *SalesVisit `json:"visit"`
+	DistributorName           string      `json:"distributor_name"`
+	MinutesOnSite             *float64    `json:"minutes_on_site,omitempty"`
+	KmFromPreviousStop        *float64    `json:"km_from_previous_stop,omitempty"`
+	OutsideGeofenceAtCheckOut bool        `json:"outside_geofence_at_check_out"`
+}
+
+// RouteReport is a rep's visits for one day in check-in order. Travel is the
+// straight-line distance between consecutive check-ins.
+type RouteReport struct {
+	UserID             uuid.UUID    `json:"user_id"`
+	Date               string       `json:"date"`
+	Timezone           string       `json:"timezone"`
+	Stops              []*RouteStop `json:"stops"`
+	TotalVisits        int          `json:"total_visits"`
+	OpenVisits         int          `json:"open_visits"`
+	TotalMinutesOnSite float64      `json:"total_minutes_on_site"`
+	TotalKm            float64      `json:"total_km"`
+}
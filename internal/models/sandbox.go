@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TenantSandbox marks a tenant as a sandbox whose data is reset to the demo
+// seed every ResetIntervalHours. The same Seed always gives the same demo data
+type TenantSandbox struct {
+	TenantID           uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ResetIntervalHours int        `json:"reset_interval_hours" db:"reset_interval_hours"`
+	Seed               int64      `json:"seed" db:"seed"`
+	LastResetAt        *time.Time `json:"last_reset_at,omitempty" db:"last_reset_at"`
+	NextResetAt        time.Time  `json:"next_reset_at" db:"next_reset_at"`
+	EnabledBy          *uuid.UUID `json:"enabled_by,omitempty" db:"enabled_by"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TenantSandboxRequest makes a tenant a sandbox or changes its schedule.
+// ConfirmReset must be set when a tenant first becomes a sandbox, as its
+// data is wiped on the next reset
+type TenantSandboxRequest struct {
+	ResetIntervalHours int    `json:"reset_interval_hours"`
+	Seed               *int64 `json:"seed,omitempty"`
+	ConfirmReset       bool   `json:"confirm_reset"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "github.com/google/uuid"
+
+// Search entity types, naming both suggestions and result groups
+const (
+	SearchTypeProduct  = "product"
+	SearchTypeCustomer = "customer"
+	SearchTypeSupplier = "supplier"
+	SearchTypeOrder    = "order"
+	SearchTypeInvoice  = "invoice"
+)
+
+// SearchSuggestion is one match for the global search box
+type SearchSuggestion struct {
+	Type  string    `json:"type"`
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+	// Detail disambiguates suggestions with the same label, such as a
+	// product's barcode or an order's status
+	Detail *string `json:"detail,omitempty"`
+	// Score ranks suggestions: exact and prefix matches above fuzzy ones
+	Score float64 `json:"score"`
+}
+
+// SearchSuggestions answers a search-as-you-type query
+type SearchSuggestions struct {
+	Query       string              `json:"query"`
+	Suggestions []*SearchSuggestion `json:"suggestions"`
+}
+
+// SearchHighlight shows where a result matched: the field's text around the
+// match, HTML-escaped, with the match wrapped in <mark>
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchResult is one record found by a global search
+type SearchResult struct {
+	Type       string             `json:"type"`
+	ID         uuid.UUID          `json:"id"`
+	Title      string             `json:"title"`
+	Subtitle   *string            `json:"subtitle,omitempty"`
+	Highlights []*SearchHighlight `json:"highlights"`
+	Score      float64            `json:"score"`
+	// Fields holds the searched fields' values the highlights are cut from
+	Fields map[string]string `json:"-"`
+}
+
+// SearchResultGroup is one page of a global search's results of one type
+type SearchResultGroup struct {
+	Type       string          `json:"type"`
+	Results    []*SearchResult `json:"results"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// SearchResults answers a global search, grouped by entity type
+type SearchResults struct {
+	Query  string               `json:"query"`
+	Groups []*SearchResultGroup `json:"groups"`
+}
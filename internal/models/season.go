@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Season types
+const (
+	SeasonTypeKharif   = "kharif"
+	SeasonTypeRabi     = "rabi"
+	SeasonTypeZaid     = "zaid"
+	SeasonTypeFestival = "festival"
+	SeasonTypeOther    = "other"
+)
+
+// Season is a yearly recurring demand window such as a sowing season or a
+// regional festival. A window whose end is before its start wraps the year end.
+type Season struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	TenantID   uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Name       string    `json:"name" db:"name"`
+	SeasonType string    `json:"season_type" db:"season_type"`
+	Region     *string   `json:"region,omitempty" db:"region"`
+	StartMonth int       `json:"start_month" db:"start_month"`
+	StartDay   int       `json:"start_day" db:"start_day"`
+	EndMonth   int       `json:"end_month" db:"end_month"`
+	EndDay     int       `json:"end_day" db:"end_day"`
+	LeadDays   int       `json:"lead_days" db:"lead_days"` // how far ahead of the start to stock up
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ProductSeasonTags are the seasons and crops a product is sold for
+type ProductSeasonTags struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Seasons   []*Season `json:"seasons"`
+	Crops     []string  `json:"crops"`
+}
+
+// MonthlyDemand is the units sold in one calendar month
+type MonthlyDemand struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Units int `json:"units"`
+}
+
+// SeasonalDemand reports a product's demand with its seasonal pattern removed
+type SeasonalDemand struct {
+	ProductID     uuid.UUID            `json:"product_id"`
+	Years         int                  `json:"years"`
+	SeasonalIndex map[int]float64      `json:"seasonal_index"` // month -> demand relative to the average month
+	Monthly       []*SeasonalMonth     `json:"monthly"`
+	Seasons       []*SeasonDemandTotal `json:"seasons"`
+}
+
+// SeasonalMonth is one month of actual and seasonally adjusted demand
+type SeasonalMonth struct {
+	Year          int     `json:"year"`
+	Month         int     `json:"month"`
+	Units         int     `json:"units"`
+	AdjustedUnits float64 `json:"adjusted_units"`
+}
+
+// SeasonDemandTotal is the average units sold per occurrence of a season
+type SeasonDemandTotal struct {
+	SeasonID     uuid.UUID `json:"season_id"`
+	Name         string    `json:"name"`
+	AverageUnits float64   `json:"average_units"`
+}
+
+// StockingSuggestion recommends stock to build ahead of an upcoming season
+type StockingSuggestion struct {
+	SeasonID       uuid.UUID `json:"season_id"`
+	SeasonName     string    `json:"season_name"`
+	SeasonStart    time.Time `json:"season_start"`
+	ProductID      uuid.UUID `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	ExpectedDemand int       `json:"expected_demand"`
+	CurrentStock   int       `json:"current_stock"`
+	// MinimumStock is the stock minimums left on hand after the season's demand
+	MinimumStock   int `json:"minimum_stock"`
+	SuggestedOrder int `json:"suggested_order"`
+	// PreferredSupplier is the best-scored supplier quote for the suggested order
+	PreferredSupplier *SupplierQuote `json:"preferred_supplier,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Signing key statuses
+const (
+	SigningKeyStatusActive   = "active"
+	SigningKeyStatusRetiring = "retiring"
+	SigningKeyStatusRevoked  = "revoked"
+)
+
+// SigningKey is a persisted asymmetric key pair used to sign access tokens
+type SigningKey struct {
+	KID        string     `json:"kid" db:"kid"`
+	Algorithm  string     `json:"algorithm" db:"algorithm"`
+	PrivateKey string     `json:"-" db:"private_key"` // Never serialize in JSON
+	PublicKey  string     `json:"public_key" db:"public_key"`
+	Status     string     `json:"status" db:"status"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at" db:"rotated_at"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// JSONWebKey is a public key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of statement of account entries
+const (
+	StatementEntryInvoice    = "invoice"
+	StatementEntryPayment    = "payment"
+	StatementEntryCreditNote = "credit_note"
+	StatementEntryInterest   = "interest"
+	StatementEntryReceipt    = "receipt"
+	StatementEntryTDS        = "tds"
+)
+
+// CreditNote reduces what a distributor owes, optionally against one invoice
+type CreditNote struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	TenantID         uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	DistributorID    uuid.UUID  `json:"customer_id" db:"distributor_id"`
+	InvoiceID        *uuid.UUID `json:"invoice_id,omitempty" db:"invoice_id"`
+	CreditNoteNumber string     `json:"credit_note_number" db:"credit_note_number"`
+	Amount           float64    `json:"amount" db:"amount"`
+	Reason           *string    `json:"reason,omitempty" db:"reason"`
+	IssuedDate       time.Time  `json:"issued_date" db:"issued_date"`
+	CreatedBy        *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+// StatementEntry is one line of a statement of account. Invoices and interest
+// on overdue invoices are debits; receipts of customer payments and the TDS
+// deducted from them, payments recorded when an invoice is marked paid outside
+// of them and credit notes are credits
+type StatementEntry struct {
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	SourceID    uuid.UUID `json:"source_id"`
+	Reference   string    `json:"reference"`
+	Description string    `json:"description"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
+	// Balance is the running balance after this entry
+	Balance float64 `json:"balance"`
+}
+
+// Statement is a distributor's statement of account for [From, To], both
+// dates inclusive. A positive balance is owed by the distributor
+type Statement struct {
+	TenantID       uuid.UUID         `json:"tenant_id"`
+	CustomerID     uuid.UUID         `json:"customer_id"`
+	CustomerName   string            `json:"customer_name"`
+	CustomerGSTIN  *string           `json:"customer_gstin,omitempty"`
+	CustomerEmail  *string           `json:"customer_email,omitempty"`
+	From           time.Time         `json:"from"`
+	To             time.Time         `json:"to"`
+	OpeningBalance float64           `json:"opening_balance"`
+	Entries        []*StatementEntry `json:"entries"`
+	TotalDebits    float64           `json:"total_debits"`
+	TotalCredits   float64           `json:"total_credits"`
+	ClosingBalance float64           `json:"closing_balance"`
+	GeneratedAt    time.Time         `json:"generated_at"`
+}
+
+// StatementRecipient is a distributor with a balance owed at a date and an
+// email address to send its statement to
+type StatementRecipient struct {
+	DistributorID uuid.UUID
+	Email         string
+	Balance       float64
+}
+
+// CreditNoteRequest issues a credit note; IssuedDate defaults to today
+type CreditNoteRequest struct {
+	InvoiceID  *uuid.UUID `json:"invoice_id,omitempty"`
+	Amount     float64    `json:"amount"`
+	Reason     *string    `json:"reason,omitempty"`
+	IssuedDate *string    `json:"issued_date,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entities whose status transitions are recorded in the status history
+const (
+	StatusEntityOrder   = "order"
+	StatusEntityInvoice = "invoice"
+)
+
+// StatusChange is one status transition of an order or invoice. FromStatus is
+// nil when the entity was created in ToStatus, ActorID is nil for changes
+// made by the system
+type StatusChange struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   uuid.UUID  `json:"tenant_id"`
+	EntityType string     `json:"entity_type"`
+	EntityID   uuid.UUID  `json:"entity_id"`
+	FromStatus *string    `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	ActorID    *uuid.UUID `json:"actor_id"`
+	ActorName  *string    `json:"actor_name,omitempty"`
+	Comment    *string    `json:"comment,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status page components, the parts of the platform incidents are recorded
+// against
+const (
+	StatusComponentAPI           = "api"
+	StatusComponentWeb           = "web"
+	StatusComponentInvoicing     = "invoicing"
+	StatusComponentNotifications = "notifications"
+	StatusComponentIntegrations  = "integrations"
+	StatusComponentReports       = "reports"
+)
+
+// StatusComponents lists the components in the order the status page shows
+// them, with their display names
+var StatusComponents = []struct {
+	Key  string
+	Name string
+}{
+	{StatusComponentAPI, "API"},
+	{StatusComponentWeb, "Web application"},
+	{StatusComponentInvoicing, "Invoicing and payments"},
+	{StatusComponentNotifications, "Notifications"},
+	{StatusComponentIntegrations, "Integrations and webhooks"},
+	{StatusComponentReports, "Reports and exports"},
+}
+
+// Incident severities, from least to most disruptive
+const (
+	IncidentSeverityMinor    = "minor"
+	IncidentSeverityMajor    = "major"
+	IncidentSeverityCritical = "critical"
+)
+
+// Incident statuses; every status but resolved leaves the incident open
+const (
+	IncidentStatusInvestigating = "investigating"
+	IncidentStatusIdentified    = "identified"
+	IncidentStatusMonitoring    = "monitoring"
+	IncidentStatusResolved      = "resolved"
+)
+
+// Component statuses shown on the status page
+const (
+	ComponentStatusOperational   = "operational"
+	ComponentStatusDegraded      = "degraded_performance"
+	ComponentStatusPartialOutage = "partial_outage"
+	ComponentStatusMajorOutage   = "major_outage"
+	ComponentStatusMaintenance   = "under_maintenance"
+)
+
+// StatusIncident is a platform incident affecting one component
+type StatusIncident struct {
+	ID         uuid.UUID               `json:"id" db:"id"`
+	Title      string                  `json:"title" db:"title"`
+	Component  string                  `json:"component" db:"component"`
+	Severity   string                  `json:"severity" db:"severity"`
+	Status     string                  `json:"status" db:"status"`
+	StartedAt  time.Time               `json:"started_at" db:"started_at"`
+	ResolvedAt *time.Time              `json:"resolved_at,omitempty" db:"resolved_at"`
+	CreatedBy  *uuid.UUID              `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time               `json:"updated_at" db:"updated_at"`
+	Updates    []*StatusIncidentUpdate `json:"updates,omitempty" db:"-"`
+}
+
+// StatusIncidentUpdate is one entry in an incident's timeline
+type StatusIncidentUpdate struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	IncidentID uuid.UUID  `json:"incident_id" db:"incident_id"`
+	Status     string     `json:"status" db:"status"`
+	Message    string     `json:"message" db:"message"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// StatusIncidentRequest records a new incident with its first update.
+// StartedAt defaults to now
+type StatusIncidentRequest struct {
+	Title     string     `json:"title"`
+	Component string     `json:"component"`
+	Severity  string     `json:"severity"`
+	Status    string     `json:"status,omitempty"`
+	Message   string     `json:"message"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// StatusIncidentUpdateRequest posts an update, moving the incident to the
+// status and, when given, the severity; resolved closes it
+type StatusIncidentUpdateRequest struct {
+	Status   string  `json:"status"`
+	Message  string  `json:"message"`
+	Severity *string `json:"severity,omitempty"`
+}
+
+// ComponentStatus is a component's current status on the status page
+type ComponentStatus struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// PlatformStatus is the public status page: the overall status, each
+// component's status and the incidents still open
+type PlatformStatus struct {
+	Status      string            `json:"status"`
+	Message     *string           `json:"message,omitempty"`
+	Components  []ComponentStatus `json:"components"`
+	Incidents   []*StatusIncident `json:"active_incidents"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockMinimum is the least stock of a product to hold in a warehouse. Without
+// a warehouse it applies to every warehouse that has no minimum of its own
+type StockMinimum struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ProductID   uuid.UUID  `json:"product_id" db:"product_id"`
+	WarehouseID *uuid.UUID `json:"warehouse_id,omitempty" db:"warehouse_id"`
+	MinQuantity int        `json:"min_quantity" db:"min_quantity"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// StockMinimumRequest sets a product's minimum, for one warehouse when
+// WarehouseID is given
+type StockMinimumRequest struct {
+	WarehouseID *uuid.UUID `json:"warehouse_id,omitempty"`
+	MinQuantity int        `json:"min_quantity"`
+}
+
+type stockMinimumKey struct {
+	productID   uuid.UUID
+	warehouseID uuid.UUID
+}
+
+// StockMinimums resolves the minimum for a product in a warehouse from a
+// tenant's configured minimums; a warehouse's own minimum wins over the
+// product-wide one
+type StockMinimums struct {
+	byKey map[stockMinimumKey]int
+}
+
+// NewStockMinimums indexes the minimums for lookups
+func NewStockMinimums(minimums []*StockMinimum) *StockMinimums {
+	resolved := &StockMinimums{byKey: make(map[stockMinimumKey]int, len(minimums))}
+	for _, minimum := range minimums {
+		key := stockMinimumKey{productID: minimum.ProductID}
+		if minimum.WarehouseID != nil {
+			key.warehouseID = *minimum.WarehouseID
+		}
+		resolved.byKey[key] = minimum.MinQuantity
+	}
+	return resolved
+}
+
+// For returns the minimum for the product in the warehouse; ok is false when
+// neither the warehouse nor the product has one
+func (m *StockMinimums) For(productID, warehouseID uuid.UUID) (minimum int, ok bool) {
+	if m == nil {
+		return 0, false
+	}
+	if minimum, ok = m.byKey[stockMinimumKey{productID: productID, warehouseID: warehouseID}]; ok {
+		return minimum, true
+	}
+	minimum, ok = m.byKey[stockMinimumKey{productID: productID}]
+	return minimum, ok
+}
+
+// Annotate sets the minimum and the below-minimum flag on inventory records
+// whose product has a minimum
+func (m *StockMinimums) Annotate(inventories []*Inventory) {
+	for _, inv := range inventories {
+		if minimum, ok := m.For(inv.ProductID, inv.WarehouseID); ok {
+			inv.MinQuantity = &minimum
+			inv.BelowMinimum = inv.Quantity < minimum
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stock-out outcomes
+const (
+	StockOutRejected    = "rejected"
+	StockOutBackordered = "backordered"
+)
+
+// Where a stock-out was detected
+const (
+	StockOutSourceOrderCreate  = "order_create"
+	StockOutSourceOrderUpdate  = "order_update"
+	StockOutSourceOrderProcess = "order_process"
+	StockOutSourceOfflineSync  = "offline_sync"
+)
+
+// StockOutEvent is sales demand the warehouse could not cover from stock
+type StockOutEvent struct {
+	ID                uuid.UUID  `json:"id"`
+	TenantID          uuid.UUID  `json:"tenant_id"`
+	ProductID         uuid.UUID  `json:"product_id"`
+	ProductName       string     `json:"product_name,omitempty"`
+	WarehouseID       uuid.UUID  `json:"warehouse_id"`
+	WarehouseName     string     `json:"warehouse_name,omitempty"`
+	OrderID           *uuid.UUID `json:"order_id,omitempty"`
+	DistributorID     *uuid.UUID `json:"distributor_id,omitempty"`
+	Outcome           string     `json:"outcome"`
+	Source            string     `json:"source"`
+	RequestedQuantity int        `json:"requested_quantity"`
+	AvailableQuantity *int       `json:"available_quantity"`
+	ShortQuantity     int        `json:"short_quantity"`
+	UnitPrice         float64    `json:"unit_price"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// StockOutDemandRow is one product and warehouse's sales demand and
+// shortfall in a period. Back-ordered orders count once, at their largest
+// shortfall; their value is lost only once the order is cancelled
+type StockOutDemandRow struct {
+	ProductID          uuid.UUID `json:"product_id"`
+	ProductName        string    `json:"product_name"`
+	WarehouseID        uuid.UUID `json:"warehouse_id"`
+	WarehouseName      string    `json:"warehouse_name"`
+	Period             time.Time `json:"period"`
+	OrderedUnits       int       `json:"ordered_units"`
+	Orders             int       `json:"orders"`
+	RejectedUnits      int       `json:"rejected_units"`
+	RejectedRequests   int       `json:"rejected_requests"`
+	RejectedValue      float64   `json:"rejected_value"`
+	BackorderedUnits   int       `json:"backordered_units"`
+	BackorderedOrders  int       `json:"backordered_orders"`
+	CancelledBackorder float64   `json:"cancelled_backorder_value"`
+}
+
+// StockOutKPI is fill rate and lost sales for a product and warehouse, or
+// for a period in a trend. FillRate is the percentage of demanded units
+// that could be served from stock when asked for
+type StockOutKPI struct {
+	ProductID         *uuid.UUID `json:"product_id,omitempty"`
+	ProductName       string     `json:"product_name,omitempty"`
+	WarehouseID       *uuid.UUID `json:"warehouse_id,omitempty"`
+	WarehouseName     string     `json:"warehouse_name,omitempty"`
+	Period            *time.Time `json:"period,omitempty"`
+	DemandedUnits     int        `json:"demanded_units"`
+	FilledUnits       int        `json:"filled_units"`
+	ShortUnits        int        `json:"short_units"`
+	FillRate          float64    `json:"fill_rate"`
+	StockOuts         int        `json:"stock_outs"`
+	RejectedRequests  int        `json:"rejected_requests"`
+	BackorderedOrders int        `json:"backordered_orders"`
+	LostSalesValue    float64    `json:"lost_sales_value"`
+}
+
+// StockOutReport is fill rate and lost sales per product and warehouse over
+// a period, worst lost sales first, with totals
+type StockOutReport struct {
+	From   time.Time      `json:"from"`
+	To     time.Time      `json:"to"`
+	Rows   []*StockOutKPI `json:"rows"`
+	Totals StockOutKPI    `json:"totals"`
+}
+
+// StockOutTrend is fill rate and lost sales per week or month
+type StockOutTrend struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Interval string         `json:"interval"`
+	Periods  []*StockOutKPI `json:"periods"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Storage conditions a warehouse can lack
+const (
+	StorageConditionColdChain = "cold_chain"
+	StorageConditionShade     = "shade"
+	StorageConditionHazmat    = "hazmat"
+)
+
+// ProductStorageRequirement is how a product must be stored
+type ProductStorageRequirement struct {
+	ProductID   uuid.UUID `json:"product_id" db:"product_id"`
+	TenantID    uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	ColdChain   bool      `json:"cold_chain" db:"cold_chain"`
+	Shade       bool      `json:"shade" db:"shade"`
+	HazmatClass *string   `json:"hazmat_class,omitempty" db:"hazmat_class"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WarehouseStorageCapability is what storage a warehouse offers
+type WarehouseStorageCapability struct {
+	WarehouseID   uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	TenantID      uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	ColdChain     bool      `json:"cold_chain" db:"cold_chain"`
+	Shade         bool      `json:"shade" db:"shade"`
+	HazmatClasses []string  `json:"hazmat_classes" db:"hazmat_classes"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Unmet lists the storage conditions the warehouse cannot provide; a nil
+// capability provides none. An approved hazard class such as "6" covers its
+// divisions ("6.1").
+func (r *ProductStorageRequirement) Unmet(c *WarehouseStorageCapability) []string {
+	if c == nil {
+		c = &WarehouseStorageCapability{}
+	}
+	var unmet []string
+	if r.ColdChain && !c.ColdChain {
+		unmet = append(unmet, StorageConditionColdChain)
+	}
+	if r.Shade && !c.Shade {
+		unmet = append(unmet, StorageConditionShade)
+	}
+	if r.HazmatClass != nil && *r.HazmatClass != "" {
+		approved := false
+		for _, class := range c.HazmatClasses {
+			if class == *r.HazmatClass || strings.HasPrefix(*r.HazmatClass, class+".") {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			unmet = append(unmet, StorageConditionHazmat)
+		}
+	}
+	return unmet
+}
+
+// StoragePlacement is stock on hand of a product with storage requirements
+type StoragePlacement struct {
+	WarehouseID   uuid.UUID                   `json:"warehouse_id"`
+	WarehouseName string                      `json:"warehouse_name"`
+	ProductID     uuid.UUID                   `json:"product_id"`
+	ProductName   string                      `json:"product_name"`
+	Quantity      int                         `json:"quantity"`
+	Requirement   *ProductStorageRequirement  `json:"requirement"`
+	Capability    *WarehouseStorageCapability `json:"capability,omitempty"`
+}
+
+// NonCompliantPlacement is stock held in a warehouse lacking a storage condition it needs
+type NonCompliantPlacement struct {
+	StoragePlacement
+	Unmet []string `json:"unmet"`
+}
+
+// NonCompliantPlacementReport lists every non-compliant placement
+type NonCompliantPlacementReport struct {
+	AsOf          time.Time                `json:"as_of"`
+	Placements    []*NonCompliantPlacement `json:"placements"`
+	TotalQuantity int                      `json:"total_quantity"`
+	// ByCondition counts placements lacking each condition
+	ByCondition map[string]int `json:"by_condition"`
+}
@@ -14,6 +14,12 @@ type Supplier struct {
 	ContactPhone   *string   `json:"contact_phone" db:"contact_phone"`
 	Address        *string   `json:"address" db:"address"`
 	LicenseNumber  *string   `json:"license_number" db:"license_number"`
+	GSTIN          *string   `json:"gstin,omitempty" db:"gstin"`
+	// BankAccountNumber and BankIFSC are where payments to the supplier go
+	BankAccountNumber *string `json:"bank_account_number,omitempty" db:"bank_account_number"`
+	BankIFSC          *string `json:"bank_ifsc,omitempty" db:"bank_ifsc"`
+	// ArchivedAt is set when a supplier referenced by past orders is deleted
+	ArchivedAt     *time.Time `json:"archived_at,omitempty" db:"archived_at"`
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
\ No newline at end of file
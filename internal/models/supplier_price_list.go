@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SupplierPriceList is a supplier's prices for a validity window; valid_to is
+// inclusive and open-ended when nil
+type SupplierPriceList struct {
+	ID           uuid.UUID                `json:"id" db:"id"`
+	TenantID     uuid.UUID                `json:"tenant_id" db:"tenant_id"`
+	SupplierID   uuid.UUID                `json:"supplier_id" db:"supplier_id"`
+	SupplierName string                   `json:"supplier_name,omitempty"`
+	Name         string                   `json:"name" db:"name"`
+	ValidFrom    time.Time                `json:"valid_from" db:"valid_from"`
+	ValidTo      *time.Time               `json:"valid_to,omitempty" db:"valid_to"`
+	Notes        *string                  `json:"notes,omitempty" db:"notes"`
+	Items        []*SupplierPriceListItem `json:"items,omitempty"`
+	CreatedAt    time.Time                `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time                `json:"updated_at" db:"updated_at"`
+}
+
+// SupplierPriceListItem is a product price from MinQuantity units upwards
+type SupplierPriceListItem struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	PriceListID  uuid.UUID `json:"price_list_id" db:"price_list_id"`
+	ProductID    uuid.UUID `json:"product_id" db:"product_id"`
+	ProductName  string    `json:"product_name,omitempty"`
+	MinQuantity  int       `json:"min_quantity" db:"min_quantity"`
+	UnitPrice    float64   `json:"unit_price" db:"unit_price"`
+	LeadTimeDays int       `json:"lead_time_days" db:"lead_time_days"`
+}
+
+// SupplierQuote is a supplier's current price for a product and quantity,
+// scored against the other suppliers quoting for it
+type SupplierQuote struct {
+	SupplierID   uuid.UUID  `json:"supplier_id"`
+	SupplierName string     `json:"supplier_name"`
+	PriceListID  uuid.UUID  `json:"price_list_id"`
+	MinQuantity  int        `json:"min_quantity"`
+	UnitPrice    float64    `json:"unit_price"`
+	TotalPrice   float64    `json:"total_price"`
+	LeadTimeDays int        `json:"lead_time_days"`
+	ValidTo      *time.Time `json:"valid_to,omitempty"`
+	// OnTimeRate is the share of received purchase orders that arrived by
+	// their expected delivery date; nil without delivery history
+	OnTimeRate     *float64 `json:"on_time_rate,omitempty"`
+	ReceivedOrders int      `json:"received_orders"`
+	Score          float64  `json:"score"`
+}
+
+// SupplierDeliveryPerformance counts a supplier's received purchase orders
+type SupplierDeliveryPerformance struct {
+	SupplierID uuid.UUID
+	Received   int
+	OnTime     int
+}
+
+// SupplierQuoteComparison ranks supplier quotes for a product, best score first
+type SupplierQuoteComparison struct {
+	ProductID   uuid.UUID        `json:"product_id"`
+	Quantity    int              `json:"quantity"`
+	AsOf        time.Time        `json:"as_of"`
+	Quotes      []*SupplierQuote `json:"quotes"`
+	Recommended *SupplierQuote   `json:"recommended,omitempty"`
+}
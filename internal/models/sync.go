@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Offline sync entities; customers are distributors and price lists are the
+// tenant's current product selling prices
+const (
+	SyncEntityProducts   = "products"
+	SyncEntityCustomers  = "customers"
+	SyncEntityPriceLists = "price_lists"
+	SyncEntityOrders     = "orders"
+)
+
+// SyncEntities lists every entity served by the changes feed
+var SyncEntities = []string{SyncEntityProducts, SyncEntityCustomers, SyncEntityPriceLists, SyncEntityOrders}
+
+// Change log operations
+const (
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+)
+
+// SyncChange is the latest change to one record within a changes window
+type SyncChange struct {
+	Seq       int64     `json:"-"`
+	Entity    string    `json:"entity"`
+	RecordID  uuid.UUID `json:"record_id"`
+	Operation string    `json:"operation"`
+}
+
+// SyncProduct is the offline view of a product; cost prices are not sent to devices
+type SyncProduct struct {
+	ID            uuid.UUID           `json:"id"`
+	CategoryID    *uuid.UUID          `json:"category_id"`
+	Name          string              `json:"name"`
+	Description   *string             `json:"description"`
+	Barcode       *string             `json:"barcode"`
+	UnitOfMeasure *string             `json:"unit_of_measure"`
+	Translations  ProductTranslations `json:"translations,omitempty"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// SyncPrice is one price list entry
+type SyncPrice struct {
+	ProductID uuid.UUID `json:"product_id"`
+	UnitPrice float64   `json:"unit_price"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SyncEntityChanges holds the records created or updated and the IDs deleted for one entity
+type SyncEntityChanges struct {
+	Upserted interface{} `json:"upserted"`
+	Deleted  []uuid.UUID `json:"deleted"`
+}
+
+// SyncChangesResponse is one page of the changes feed
+type SyncChangesResponse struct {
+	Changes   map[string]*SyncEntityChanges `json:"changes"`
+	NextToken string                        `json:"next_token"`
+	HasMore   bool                          `json:"has_more"`
+}
+
+// Price conflict rules for offline orders whose price changed on the server
+// after the device last synced
+const (
+	SyncPriceServerWins = "server_wins"
+	SyncPriceClientWins = "client_wins"
+	SyncPriceReject     = "reject"
+)
+
+// SyncOrderUpload is an order created offline; ID is generated on the device
+// and makes retries idempotent
+type SyncOrderUpload struct {
+	ID               uuid.UUID  `json:"id"`
+	DistributorID    uuid.UUID  `json:"customer_id"`
+	ProductID        uuid.UUID  `json:"product_id"`
+	WarehouseID      uuid.UUID  `json:"warehouse_id"`
+	Quantity         int        `json:"quantity"`
+	UnitPrice        float64    `json:"unit_price"`
+	ExpectedDelivery *time.Time `json:"expected_delivery,omitempty"`
+	Notes            *string    `json:"notes,omitempty"`
+	CreatedOfflineAt time.Time  `json:"created_offline_at"`
+}
+
+// SyncUploadRequest is a batch of offline orders. BaseToken is the change
+// token the device held when the orders were taken, used to detect price
+// changes it had not seen.
+type SyncUploadRequest struct {
+	DeviceID      string            `json:"device_id"`
+	BaseToken     string            `json:"base_token"`
+	PriceConflict string            `json:"price_conflict"`
+	Orders        []SyncOrderUpload `json:"orders"`
+}
+
+// Per-order upload outcomes
+const (
+	SyncUploadCreated   = "created"
+	SyncUploadRepriced  = "repriced"
+	SyncUploadDuplicate = "duplicate"
+	SyncUploadRejected  = "rejected"
+	// SyncUploadRetry means the server could not decide; the device keeps the
+	// order and uploads it again with the same ID
+	SyncUploadRetry = "retry"
+)
+
+// SyncUploadResult is the outcome for one uploaded order
+type SyncUploadResult struct {
+	ID          uuid.UUID `json:"id"`
+	Status      string    `json:"status"`
+	Reason      string    `json:"reason,omitempty"`
+	ClientPrice float64   `json:"client_price,omitempty"`
+	Order       *Order    `json:"order,omitempty"`
+}
+
+// SyncUploadResponse reports each uploaded order in request order
+type SyncUploadResponse struct {
+	Results  []SyncUploadResult `json:"results"`
+	Created  int                `json:"created"`
+	Rejected int                `json:"rejected"`
+}
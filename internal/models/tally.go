@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entities synced with Tally
+const (
+	TallyEntityInvoices = "invoices"
+	TallyEntityOrders   = "orders"
+)
+
+// TallySyncCursor marks the last record exported to Tally for an entity
+type TallySyncCursor struct {
+	TenantID       uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Entity         string     `json:"entity" db:"entity"`
+	LastExportedAt *time.Time `json:"last_exported_at,omitempty" db:"last_exported_at"`
+	LastExportedID *uuid.UUID `json:"last_exported_id,omitempty" db:"last_exported_id"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TallySyncSettings configures a tenant's scheduled incremental exports
+type TallySyncSettings struct {
+	TenantID        uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	IntervalMinutes int        `json:"interval_minutes" db:"interval_minutes"`
+	Entities        []string   `json:"entities" db:"entities"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty" db:"next_run_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TallyExportRun records one incremental export
+type TallyExportRun struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	TenantID        uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Entity          string     `json:"entity" db:"entity"`
+	Trigger         string     `json:"trigger" db:"trigger"`
+	Status          string     `json:"status" db:"status"`
+	RecordsExported int        `json:"records_exported" db:"records_exported"`
+	HasMore         bool       `json:"has_more" db:"has_more"`
+	CursorFrom      *time.Time `json:"cursor_from,omitempty" db:"cursor_from"`
+	CursorTo        *time.Time `json:"cursor_to,omitempty" db:"cursor_to"`
+	FileName        *string    `json:"file_name,omitempty" db:"file_name"`
+	ObjectKey       *string    `json:"-" db:"object_key"`
+	ErrorMessage    *string    `json:"error_message,omitempty" db:"error_message"`
+	StartedAt       time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// TallyEntitySyncStatus summarises sync progress for one entity
+type TallyEntitySyncStatus struct {
+	Entity         string           `json:"entity"`
+	Cursor         *TallySyncCursor `json:"cursor,omitempty"`
+	LastRun        *TallyExportRun  `json:"last_run,omitempty"`
+	PendingRecords int              `json:"pending_records"`
+}
@@ -12,6 +12,9 @@ type Tenant struct {
 	Subdomain    string    `json:"subdomain" db:"subdomain"`
 	License      string    `json:"license" db:"license_number"`
 	Status       string    `json:"status" db:"status"`
+	// Residency is the region the tenant's files must be stored in; nil
+	// uses the default store
+	Residency    *string   `json:"data_residency,omitempty" db:"data_residency"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
\ No newline at end of file
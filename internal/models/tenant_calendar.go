@@ -0,0 +1,138 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tenants without calendar settings reckon dates in India's timezone with an
+// April to March financial year
+const (
+	DefaultTenantTimezone       = "Asia/Kolkata"
+	DefaultFiscalYearStartMonth = 4
+)
+
+// Named report periods resolved against the tenant's calendar
+const (
+	ReportPeriodMonth                 = "month"
+	ReportPeriodPreviousMonth         = "previous_month"
+	ReportPeriodFiscalQuarter         = "fiscal_quarter"
+	ReportPeriodPreviousFiscalQuarter = "previous_fiscal_quarter"
+	ReportPeriodFiscalYear            = "fiscal_year"
+	ReportPeriodPreviousFiscalYear    = "previous_fiscal_year"
+)
+
+// TenantCalendar is the timezone a tenant's dates are reckoned in and the
+// month its fiscal year starts. Calendar dates are handled as midnight UTC,
+// the way DATE columns and YYYY-MM-DD query parameters are read
+type TenantCalendar struct {
+	TenantID             uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Timezone             string    `json:"timezone" db:"timezone"`
+	FiscalYearStartMonth int       `json:"fiscal_year_start_month" db:"fiscal_year_start_month"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TenantCalendarRequest changes the tenant's calendar; omitted fields are kept
+type TenantCalendarRequest struct {
+	Timezone             *string `json:"timezone,omitempty"`
+	FiscalYearStartMonth *int    `json:"fiscal_year_start_month,omitempty"`
+}
+
+// DefaultTenantCalendar is the calendar of a tenant without settings
+func DefaultTenantCalendar(tenantID uuid.UUID) *TenantCalendar {
+	return &TenantCalendar{
+		TenantID:             tenantID,
+		Timezone:             DefaultTenantTimezone,
+		FiscalYearStartMonth: DefaultFiscalYearStartMonth,
+	}
+}
+
+// Location is the tenant's timezone, falling back to the default one and then
+// UTC when the zone is unknown to this host
+func (c *TenantCalendar) Location() *time.Location {
+	if loc, err := time.LoadLocation(c.Timezone); err == nil {
+		return loc
+	}
+	if loc, err := time.LoadLocation(DefaultTenantTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Now is now in the tenant's timezone
+func (c *TenantCalendar) Now(now time.Time) time.Time {
+	return now.In(c.Location())
+}
+
+// Today is the tenant's calendar date at now
+func (c *TenantCalendar) Today(now time.Time) time.Time {
+	y, m, d := c.Now(now).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DayStart is the instant the calendar date begins in the tenant's timezone
+func (c *TenantCalendar) DayStart(date time.Time) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, c.Location())
+}
+
+func (c *TenantCalendar) startMonth() time.Month {
+	if c.FiscalYearStartMonth < 1 || c.FiscalYearStartMonth > 12 {
+		return time.Month(DefaultFiscalYearStartMonth)
+	}
+	return time.Month(c.FiscalYearStartMonth)
+}
+
+// FiscalYear is the calendar year the fiscal year containing date starts in
+func (c *TenantCalendar) FiscalYear(date time.Time) int {
+	if date.Month() < c.startMonth() {
+		return date.Year() - 1
+	}
+	return date.Year()
+}
+
+// FiscalYearStart is the first day of the fiscal year containing date
+func (c *TenantCalendar) FiscalYearStart(date time.Time) time.Time {
+	return time.Date(c.FiscalYear(date), c.startMonth(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// FiscalQuarter is the first and last day of a quarter, 1 to 4, of the fiscal
+// year starting in fiscalYear
+func (c *TenantCalendar) FiscalQuarter(fiscalYear, quarter int) (time.Time, time.Time) {
+	from := time.Date(fiscalYear, c.startMonth()+time.Month(3*(quarter-1)), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 3, -1)
+}
+
+// Period is the first and last day of a named report period around the
+// tenant's date at now; ok is false for an unknown period
+func (c *TenantCalendar) Period(period string, now time.Time) (from, to time.Time, ok bool) {
+	today := c.Today(now)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	yearStart := c.FiscalYearStart(today)
+	quarterStart := yearStart.AddDate(0, (int(today.Month()-yearStart.Month()+12)%12)/3*3, 0)
+
+	switch period {
+	case ReportPeriodMonth:
+		from = monthStart
+		to = from.AddDate(0, 1, -1)
+	case ReportPeriodPreviousMonth:
+		from = monthStart.AddDate(0, -1, 0)
+		to = monthStart.AddDate(0, 0, -1)
+	case ReportPeriodFiscalQuarter:
+		from = quarterStart
+		to = from.AddDate(0, 3, -1)
+	case ReportPeriodPreviousFiscalQuarter:
+		from = quarterStart.AddDate(0, -3, 0)
+		to = quarterStart.AddDate(0, 0, -1)
+	case ReportPeriodFiscalYear:
+		from = yearStart
+		to = from.AddDate(1, 0, -1)
+	case ReportPeriodPreviousFiscalYear:
+		from = yearStart.AddDate(-1, 0, 0)
+		to = yearStart.AddDate(0, 0, -1)
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TenantPaymentSettings is how buyers pay the tenant. Invoices of tenants
+// with a UPI VPA carry a QR code buyers can scan to pay the exact amount
+type TenantPaymentSettings struct {
+	TenantID uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	UPIVPA   *string   `json:"upi_vpa" db:"upi_vpa"`
+	// PayeeName is shown in the buyer's UPI app; the tenant name when unset
+	PayeeName *string   `json:"payee_name" db:"payee_name"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TenantPaymentSettingsRequest changes the tenant's payment settings;
+// omitted fields are kept and empty ones are cleared
+type TenantPaymentSettingsRequest struct {
+	UPIVPA    *string `json:"upi_vpa,omitempty"`
+	PayeeName *string `json:"payee_name,omitempty"`
+}
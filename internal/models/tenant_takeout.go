@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tenant takeout statuses
+const (
+	TenantTakeoutRunning   = "running"
+	TenantTakeoutSucceeded = "succeeded"
+	TenantTakeoutFailed    = "failed"
+)
+
+// Tenant takeout file formats
+const (
+	TakeoutFormatJSON = "json"
+	TakeoutFormatCSV  = "csv"
+)
+
+// TenantTakeoutRequest chooses how a takeout is written. Format defaults to
+// JSON and documents are included unless turned off
+type TenantTakeoutRequest struct {
+	Format           string `json:"format"`
+	IncludeDocuments *bool  `json:"include_documents,omitempty"`
+}
+
+// TenantTakeout records one export of all of a tenant's data
+type TenantTakeout struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	TenantID         uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Format           string    `json:"format" db:"format"`
+	IncludeDocuments bool      `json:"include_documents" db:"include_documents"`
+	Status           string    `json:"status" db:"status"`
+	// EntitiesTotal counts the tables and document buckets to export
+	EntitiesTotal     int        `json:"entities_total" db:"entities_total"`
+	EntitiesDone      int        `json:"entities_done" db:"entities_done"`
+	RowsExported      int64      `json:"rows_exported" db:"rows_exported"`
+	DocumentsExported int        `json:"documents_exported" db:"documents_exported"`
+	FileName          *string    `json:"file_name,omitempty" db:"file_name"`
+	ObjectKey         *string    `json:"-" db:"object_key"`
+	SizeBytes         *int64     `json:"size_bytes,omitempty" db:"size_bytes"`
+	ErrorMessage      *string    `json:"error_message,omitempty" db:"error_message"`
+	RequestedBy       *uuid.UUID `json:"requested_by,omitempty" db:"requested_by"`
+	StartedAt         time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt        *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	// Progress is the percentage of entities exported
+	Progress float64 `json:"progress"`
+	// DownloadURL is a presigned link to the archive once the takeout succeeds
+	DownloadURL *string `json:"download_url,omitempty"`
+}
+
+// TakeoutTable is a table holding tenant data. Rows belong to the tenant
+// through TenantColumn, or for tables without one through ForeignKey
+// pointing at a row of ParentTable whose tenant_id is the tenant's
+type TakeoutTable struct {
+	Name         string
+	Columns      []string
+	TenantColumn string
+	ForeignKey   string
+	ParentTable  string
+	ParentColumn string
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Territory levels, from the top of the hierarchy down
+const (
+	TerritoryLevelState    = "state"
+	TerritoryLevelDistrict = "district"
+	TerritoryLevelArea     = "area"
+)
+
+// What can be assigned to a territory. Customers are distributors
+const (
+	TerritoryEntityCustomer  = "customer"
+	TerritoryEntityWarehouse = "warehouse"
+	TerritoryEntityUser      = "user"
+)
+
+// Territory is a state, a district within a state or an area within a
+// district. Path names it with its ancestors, e.g. "Maharashtra / Pune / Hadapsar"
+type Territory struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	TenantID  uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	Level     string     `json:"level" db:"level"`
+	Name      string     `json:"name" db:"name"`
+	Code      *string    `json:"code,omitempty" db:"code"`
+	Path      string     `json:"path"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TerritoryRequest creates or renames a territory. The level follows from the
+// parent, so only ParentID is given; it is ignored on update
+type TerritoryRequest struct {
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Name     string     `json:"name"`
+	Code     *string    `json:"code,omitempty"`
+}
+
+// TerritoryAssignmentRequest places a customer, warehouse or user in a
+// territory; a nil TerritoryID clears it
+type TerritoryAssignmentRequest struct {
+	EntityType  string     `json:"entity_type"`
+	EntityID    uuid.UUID  `json:"entity_id"`
+	TerritoryID *uuid.UUID `json:"territory_id"`
+}
+
+// TerritorySales is a territory's sales over a period, including those of the
+// territories below it
+type TerritorySales struct {
+	TerritoryID   uuid.UUID `json:"territory_id"`
+	Name          string    `json:"name"`
+	Level         string    `json:"level"`
+	CustomerCount int       `json:"customer_count"`
+	OrderCount    int       `json:"order_count"`
+	OrderAmount   float64   `json:"order_amount"`
+	InvoiceCount  int       `json:"invoice_count"`
+	InvoiceAmount float64   `json:"invoice_amount"`
+}
+
+// TerritorySalesReport breaks sales down by the territories of one level,
+// within TerritoryID when set
+type TerritorySalesReport struct {
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	Level       string            `json:"level"`
+	TerritoryID *uuid.UUID        `json:"territory_id,omitempty"`
+	Territories []*TerritorySales `json:"territories"`
+	Totals      TerritorySales    `json:"totals"`
+}
+
+// TerritoryDailySales is a day's orders and invoices of customers in a territory
+type TerritoryDailySales struct {
+	Date          time.Time `json:"date"`
+	OrderCount    int       `json:"order_count"`
+	OrderAmount   float64   `json:"order_amount"`
+	InvoiceCount  int       `json:"invoice_count"`
+	InvoiceAmount float64   `json:"invoice_amount"`
+}
@@ -12,7 +12,20 @@ type Warehouse struct {
 	Name          string    `json:"name" db:"name"`
 	Address       *string   `json:"address" db:"address"`
 	Capacity      *int      `json:"capacity" db:"capacity"`
+	// VolumeCapacityM3 and PalletPositions are optional physical capacities
+	// alongside Capacity, the number of units the warehouse holds
+	VolumeCapacityM3 *float64 `json:"volume_capacity_m3,omitempty" db:"volume_capacity_m3"`
+	PalletPositions  *int     `json:"pallet_positions,omitempty" db:"pallet_positions"`
 	LicenseNumber *string   `json:"license_number" db:"license_number"`
+	Latitude      *float64  `json:"latitude,omitempty" db:"latitude"`
+	Longitude     *float64  `json:"longitude,omitempty" db:"longitude"`
+	IsDefault     bool      `json:"is_default" db:"is_default"`
+	// BranchID is the branch, and so the GSTIN, the warehouse trades under
+	BranchID      *uuid.UUID `json:"branch_id,omitempty" db:"branch_id"`
+	// ArchivedAt is set when a warehouse referenced by past orders is deleted
+	ArchivedAt    *time.Time `json:"archived_at,omitempty" db:"archived_at"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	// Utilization is how full the warehouse is, filled on detail reads
+	Utilization   *WarehouseUtilization `json:"utilization,omitempty" db:"-"`
 }
\ No newline at end of file
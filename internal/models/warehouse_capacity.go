@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Capacity measures a warehouse can be limited by
+const (
+	CapacityUnits   = "units"
+	CapacityVolume  = "volume"
+	CapacityPallets = "pallets"
+)
+
+// ProductDimensions is the size of one unit of a product as stocked
+type ProductDimensions struct {
+	ProductID uuid.UUID `json:"product_id" db:"product_id"`
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	LengthCm  float64   `json:"length_cm" db:"length_cm"`
+	WidthCm   float64   `json:"width_cm" db:"width_cm"`
+	HeightCm  float64   `json:"height_cm" db:"height_cm"`
+	// UnitsPerPallet is how many units one pallet position holds, when palletized
+	UnitsPerPallet *int      `json:"units_per_pallet,omitempty" db:"units_per_pallet"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UnitVolumeM3 is the volume of one unit in cubic metres
+func (d *ProductDimensions) UnitVolumeM3() float64 {
+	return d.LengthCm * d.WidthCm * d.HeightCm / 1e6
+}
+
+// CapacityUsage is how much of one capacity measure is taken
+type CapacityUsage struct {
+	Measure  string  `json:"measure"`
+	Capacity float64 `json:"capacity"`
+	Used     float64 `json:"used"`
+	Percent  float64 `json:"percent"`
+}
+
+// WarehouseUtilization is how full a warehouse is with its stock on hand
+type WarehouseUtilization struct {
+	WarehouseID   uuid.UUID `json:"warehouse_id"`
+	WarehouseName string    `json:"warehouse_name"`
+	Units         int       `json:"units"`
+	VolumeM3      float64   `json:"volume_m3"`
+	Pallets       int       `json:"pallets"`
+	// UnmeasuredUnits are units of products without dimensions, left out of
+	// VolumeM3; UnpalletizedUnits lack units per pallet and are left out of
+	// Pallets
+	UnmeasuredUnits   int `json:"unmeasured_units"`
+	UnpalletizedUnits int `json:"unpalletized_units"`
+	// Usage has an entry for each capacity the warehouse sets
+	Usage []*CapacityUsage `json:"usage"`
+	// AsOf is when the stock or product dimensions last changed
+	AsOf *time.Time `json:"as_of,omitempty"`
+
+	UnitCapacity     *int     `json:"-"`
+	VolumeCapacityM3 *float64 `json:"-"`
+	PalletPositions  *int     `json:"-"`
+}
+
+// InboundStock is stock about to be received into a warehouse
+type InboundStock struct {
+	WarehouseID uuid.UUID
+	ProductID   uuid.UUID
+	Quantity    int
+}
+
+// CapacityWarning reports a warehouse that receiving stock would take past
+// one of its capacities
+type CapacityWarning struct {
+	WarehouseID   uuid.UUID `json:"warehouse_id"`
+	WarehouseName string    `json:"warehouse_name"`
+	Measure       string    `json:"measure"`
+	Capacity      float64   `json:"capacity"`
+	Projected     float64   `json:"projected"`
+	Message       string    `json:"message"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Weather alert rule conditions
+const (
+	WeatherConditionHeavyRain = "heavy_rain" // daily precipitation in mm at or above threshold
+	WeatherConditionHeatWave  = "heat_wave"  // daily maximum temperature in °C at or above threshold
+	WeatherConditionColdWave  = "cold_wave"  // daily minimum temperature in °C at or below threshold
+	WeatherConditionHighWind  = "high_wind"  // daily maximum wind speed in km/h at or above threshold
+)
+
+// DailyForecast is one day of a warehouse location's weather forecast
+type DailyForecast struct {
+	Date            time.Time `json:"date"`
+	PrecipitationMM float64   `json:"precipitation_mm"`
+	TempMaxC        float64   `json:"temp_max_c"`
+	TempMinC        float64   `json:"temp_min_c"`
+	WindMaxKmh      float64   `json:"wind_max_kmh"`
+}
+
+// WeatherAlertRule is a tenant-configured rule that turns a forecast into an alert
+type WeatherAlertRule struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	TenantID      uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Name          string     `json:"name" db:"name"`
+	Condition     string     `json:"condition" db:"condition"`
+	Threshold     float64    `json:"threshold" db:"threshold"`
+	LookaheadDays int        `json:"lookahead_days" db:"lookahead_days"`
+	CategoryID    *uuid.UUID `json:"category_id,omitempty" db:"category_id"` // limit stock at risk to one category
+	Message       string     `json:"message" db:"message"`
+	Severity      string     `json:"severity" db:"severity"`
+	Recipients    []string   `json:"recipients" db:"recipients"` // email addresses
+	Enabled       bool       `json:"enabled" db:"enabled"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// WeatherAlertEvent records an alert raised for a rule, warehouse and forecast day
+type WeatherAlertEvent struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	TenantID      uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	RuleID        uuid.UUID `json:"rule_id" db:"rule_id"`
+	WarehouseID   uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	ForecastDate  time.Time `json:"forecast_date" db:"forecast_date"`
+	ObservedValue float64   `json:"observed_value" db:"observed_value"`
+	Message       string    `json:"message" db:"message"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// StockAtRisk is a product held in a warehouse affected by a weather alert
+type StockAtRisk struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Quantity    int       `json:"quantity"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WhatsApp template categories, as the provider approves them
+const (
+	WhatsAppCategoryUtility        = "utility"
+	WhatsAppCategoryMarketing      = "marketing"
+	WhatsAppCategoryAuthentication = "authentication"
+)
+
+// Business events that send WhatsApp templates
+const (
+	WhatsAppEventOrderConfirmation = "order_confirmation"
+	WhatsAppEventPaymentReminder   = "payment_reminder"
+)
+
+// WhatsApp message statuses, in delivery order; failed can follow any of them
+const (
+	WhatsAppStatusSent      = "sent"
+	WhatsAppStatusDelivered = "delivered"
+	WhatsAppStatusRead      = "read"
+	WhatsAppStatusFailed    = "failed"
+)
+
+// WhatsAppTemplate maps a business event to a provider-approved template.
+// Parameters names the event data keys filling {{1}}, {{2}}, ... in order
+type WhatsAppTemplate struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	TenantID   uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	EventType  string    `json:"event_type" db:"event_type"`
+	Name       string    `json:"name" db:"name"`
+	Language   string    `json:"language" db:"language"`
+	Category   string    `json:"category" db:"category"`
+	Parameters []string  `json:"parameters" db:"parameters"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WhatsAppContact records a phone number's messaging consent
+type WhatsAppContact struct {
+	TenantID   uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Phone      string     `json:"phone" db:"phone"`
+	Name       *string    `json:"name,omitempty" db:"name"`
+	OptedIn    bool       `json:"opted_in" db:"opted_in"`
+	Source     *string    `json:"source,omitempty" db:"source"`
+	OptedInAt  *time.Time `json:"opted_in_at,omitempty" db:"opted_in_at"`
+	OptedOutAt *time.Time `json:"opted_out_at,omitempty" db:"opted_out_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// WhatsAppMessage is one template message sent to a contact and its latest
+// delivery status
+type WhatsAppMessage struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	TenantID          uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Phone             string     `json:"phone" db:"phone"`
+	TemplateID        *uuid.UUID `json:"template_id,omitempty" db:"template_id"`
+	EventType         string     `json:"event_type" db:"event_type"`
+	EventID           *string    `json:"event_id,omitempty" db:"event_id"`
+	ProviderMessageID *string    `json:"provider_message_id,omitempty" db:"provider_message_id"`
+	Status            string     `json:"status" db:"status"`
+	ErrorMessage      *string    `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	DeliveredAt       *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	ReadAt            *time.Time `json:"read_at,omitempty" db:"read_at"`
+}
+
+// WhatsAppSend asks for an event's template to be sent to a phone number or
+// to a distributor's contact phone. Params holds the template's event data;
+// Language picks among the event's templates when set
+type WhatsAppSend struct {
+	Phone         string            `json:"phone"`
+	DistributorID *uuid.UUID        `json:"distributor_id"`
+	EventType     string            `json:"event_type"`
+	EventID       string            `json:"event_id"`
+	Language      string            `json:"language,omitempty"`
+	Params        map[string]string `json:"params"`
+}
+
+// WhatsAppReceipt is a delivery status update reported by the provider
+type WhatsAppReceipt struct {
+	ProviderMessageID string
+	Status            string
+	Error             string
+	Timestamp         time.Time
+}
+
+// WhatsAppInbound is a text message a contact sent to the business number
+type WhatsAppInbound struct {
+	Phone string
+	Text  string
+}
+
+// WhatsAppWebhook is a parsed provider callback
+type WhatsAppWebhook struct {
+	Receipts []WhatsAppReceipt
+	Inbound  []WhatsAppInbound
+}
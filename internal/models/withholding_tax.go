@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of withholding tax: TDS is deducted by customers from what they pay,
+// TCS is collected from customers on top of sales invoices
+const (
+	WithholdingKindTDS = "tds"
+	WithholdingKindTCS = "tcs"
+)
+
+// TaxSection is an income tax section withheld under, e.g. TDS 194Q or TCS
+// 206C(1H). The rate applies to what a customer's financial year aggregate
+// exceeds ThresholdAmount by
+type TaxSection struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	TenantID        uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Kind            string    `json:"kind" db:"kind"`
+	Section         string    `json:"section" db:"section"`
+	Description     *string   `json:"description,omitempty" db:"description"`
+	RatePercent     float64   `json:"rate_percent" db:"rate_percent"`
+	ThresholdAmount float64   `json:"threshold_amount" db:"threshold_amount"`
+	Active          bool      `json:"active" db:"active"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TaxSectionRequest creates or updates a section; on update, omitted fields
+// are left as they are
+type TaxSectionRequest struct {
+	Kind            string   `json:"kind"`
+	Section         string   `json:"section"`
+	Description     *string  `json:"description,omitempty"`
+	RatePercent     *float64 `json:"rate_percent,omitempty"`
+	ThresholdAmount *float64 `json:"threshold_amount,omitempty"`
+	Active          *bool    `json:"active,omitempty"`
+}
+
+// InvoiceTCS is TCS added to a sales invoice on BaseAmount, the part of the
+// sale beyond the section threshold
+type InvoiceTCS struct {
+	InvoiceID     uuid.UUID  `json:"invoice_id" db:"invoice_id"`
+	TenantID      uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	DistributorID uuid.UUID  `json:"customer_id" db:"distributor_id"`
+	SectionID     *uuid.UUID `json:"section_id,omitempty" db:"section_id"`
+	Section       string     `json:"section" db:"section"`
+	RatePercent   float64    `json:"rate_percent" db:"rate_percent"`
+	BaseAmount    float64    `json:"base_amount" db:"base_amount"`
+	Amount        float64    `json:"amount" db:"amount"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TDSCertificateRequest records the TDS certificate a customer issued for a
+// payment; CertificateDate defaults to today
+type TDSCertificateRequest struct {
+	CertificateNumber string  `json:"certificate_number"`
+	CertificateDate   *string `json:"certificate_date,omitempty"`
+}
+
+// WithholdingSummaryRow totals one section for one customer over a quarter.
+// Documents counts invoices for TCS and payments for TDS
+type WithholdingSummaryRow struct {
+	Section       string    `json:"section"`
+	CustomerID    uuid.UUID `json:"customer_id"`
+	CustomerName  string    `json:"customer_name"`
+	CustomerGSTIN *string   `json:"customer_gstin,omitempty"`
+	Documents     int       `json:"documents"`
+	BaseAmount    float64   `json:"base_amount"`
+	Amount        float64   `json:"amount"`
+	// CertificatesPending counts TDS payments without a certificate yet
+	CertificatesPending int `json:"certificates_pending,omitempty"`
+}
+
+// WithholdingSummary is the TDS/TCS summary of a financial year quarter, Q1
+// being April to June
+type WithholdingSummary struct {
+	TenantID            uuid.UUID                `json:"tenant_id"`
+	FinancialYear       string                   `json:"financial_year"`
+	Quarter             int                      `json:"quarter"`
+	From                time.Time                `json:"from"`
+	To                  time.Time                `json:"to"`
+	TCS                 []*WithholdingSummaryRow `json:"tcs"`
+	TDS                 []*WithholdingSummaryRow `json:"tds"`
+	TotalTCS            float64                  `json:"total_tcs"`
+	TotalTDS            float64                  `json:"total_tds"`
+	CertificatesPending int                      `json:"certificates_pending"`
+}
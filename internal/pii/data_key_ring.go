@@ -0,0 +1,375 @@
+// Package pii encrypts personally identifiable columns at the application
+// layer. Each tenant has its own data keys, wrapped with a master key that
+// never reaches the database, and exact-match lookups go through keyed
+// blind indexes instead of the ciphertext.
+//
+// User emails are the login identifier and are kept unique across tenants, so
+// they are indexed with GlobalBlindIndex rather than a per-tenant index.
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// sealedPrefix marks an encrypted value as "pii:v1:<key version>:<base64>";
+// values without it are legacy plaintext and read as they are
+const sealedPrefix = "pii:v1:"
+
+// keyCacheTTL bounds how long a tenant's keys are cached, so keys rotated by
+// another instance are picked up
+const keyCacheTTL = 5 * time.Minute
+
+// ErrDataKeyUnavailable is returned when a value cannot be decrypted because
+// its key is missing or no master key is configured
+var ErrDataKeyUnavailable = errors.New("pii data key unavailable")
+
+// DataKeyStore persists wrapped tenant data keys
+type DataKeyStore interface {
+	ListDataKeys(ctx context.Context, tenantID uuid.UUID) ([]*models.TenantDataKey, error)
+	// CreateDataKey stores key as the tenant's active key and retires the
+	// others; it returns false when the version is already taken
+	CreateDataKey(ctx context.Context, key *models.TenantDataKey) (bool, error)
+}
+
+type tenantKeys struct {
+	active    int
+	createdAt time.Time
+	aeads     map[int]cipher.AEAD
+	loadedAt  time.Time
+}
+
+// DataKeyRing encrypts and decrypts PII values with per-tenant data keys. A
+// nil ring leaves values in plaintext, for deployments without a master key
+type DataKeyRing struct {
+	store     DataKeyStore
+	master    cipher.AEAD
+	indexRoot []byte
+
+	mu      sync.Mutex
+	tenants map[uuid.UUID]*tenantKeys
+}
+
+// NewDataKeyRing creates a ring from a 32-byte master key
+func NewDataKeyRing(store DataKeyStore, masterKey []byte) (*DataKeyRing, error) {
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("PII master key must be 32 bytes, got %d", len(masterKey))
+	}
+	master, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	// Blind indexes use their own key, so they survive data key rotation
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte("pii-blind-index"))
+	return &DataKeyRing{
+		store:     store,
+		master:    master,
+		indexRoot: mac.Sum(nil),
+		tenants:   make(map[uuid.UUID]*tenantKeys),
+	}, nil
+}
+
+// ParseMasterKey decodes a base64 master key as set in PII_MASTER_KEY
+func ParseMasterKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("PII master key must be base64: %w", err)
+	}
+	return key, nil
+}
+
+// Enabled reports whether values are encrypted
+func (r *DataKeyRing) Enabled() bool {
+	return r != nil
+}
+
+// IsSealed reports whether a stored value is encrypted
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// SealedVersion returns the key version a stored value was encrypted with
+func SealedVersion(value string) (int, bool) {
+	if !IsSealed(value) {
+		return 0, false
+	}
+	rest := value[len(sealedPrefix):]
+	end := strings.IndexByte(rest, ':')
+	if end < 0 {
+		return 0, false
+	}
+	version, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// SealedPattern is the LIKE pattern matching values encrypted with version
+func SealedPattern(version int) string {
+	return fmt.Sprintf("%s%d:%%", sealedPrefix, version)
+}
+
+// Encrypt seals a value with the tenant's active key, creating the tenant's
+// first key when it has none. Nil and empty values are stored as they are
+func (r *DataKeyRing) Encrypt(ctx context.Context, tenantID uuid.UUID, value *string) (*string, error) {
+	if r == nil || value == nil || *value == "" || IsSealed(*value) {
+		return value, nil
+	}
+	keys, err := r.keysFor(ctx, tenantID, 0)
+	if err != nil {
+		return nil, err
+	}
+	aead := keys.aeads[keys.active]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(*value), tenantID[:])
+	encoded := fmt.Sprintf("%s%d:%s", sealedPrefix, keys.active, base64.RawStdEncoding.EncodeToString(sealed))
+	return &encoded, nil
+}
+
+// Decrypt opens a sealed value; plaintext values are returned unchanged
+func (r *DataKeyRing) Decrypt(ctx context.Context, tenantID uuid.UUID, value *string) (*string, error) {
+	if value == nil || !IsSealed(*value) {
+		return value, nil
+	}
+	if r == nil {
+		return nil, ErrDataKeyUnavailable
+	}
+	version, ok := SealedVersion(*value)
+	if !ok {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	keys, err := r.keysFor(ctx, tenantID, version)
+	if err != nil {
+		return nil, err
+	}
+	aead, ok := keys.aeads[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: version %d", ErrDataKeyUnavailable, version)
+	}
+
+	encoded := *value
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded[strings.LastIndexByte(encoded, ':')+1:])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], tenantID[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	decoded := string(plain)
+	return &decoded, nil
+}
+
+// DecryptFields decrypts each field in place
+func (r *DataKeyRing) DecryptFields(ctx context.Context, tenantID uuid.UUID, fields ...**string) error {
+	for _, field := range fields {
+		plain, err := r.Decrypt(ctx, tenantID, *field)
+		if err != nil {
+			return err
+		}
+		*field = plain
+	}
+	return nil
+}
+
+// BlindIndex returns the keyed hash used to find a value by exact match.
+// Case is ignored, as are spaces and punctuation outside emails, so
+// "+91 98480-12345" and "+919848012345" match. It is nil when the ring is
+// disabled
+func (r *DataKeyRing) BlindIndex(tenantID uuid.UUID, value *string) *string {
+	if r == nil || value == nil {
+		return nil
+	}
+	normalized := normalizeForIndex(*value)
+	if normalized == "" {
+		return nil
+	}
+	tenantKey := hmac.New(sha256.New, r.indexRoot)
+	tenantKey.Write(tenantID[:])
+	mac := hmac.New(sha256.New, tenantKey.Sum(nil))
+	mac.Write([]byte(normalized))
+	index := hex.EncodeToString(mac.Sum(nil))
+	return &index
+}
+
+// GlobalBlindIndex is BlindIndex for values that must be unique across
+// tenants, such as login emails; it is the same for every tenant
+func (r *DataKeyRing) GlobalBlindIndex(value *string) *string {
+	return r.BlindIndex(uuid.Nil, value)
+}
+
+func normalizeForIndex(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "@") {
+		return strings.ToLower(value)
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', '(', ')', '.':
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, value)
+}
+
+// ActiveVersion returns the version of the tenant's active key, creating
+// the first key when the tenant has none
+func (r *DataKeyRing) ActiveVersion(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	keys, err := r.keysFor(ctx, tenantID, 0)
+	if err != nil {
+		return 0, err
+	}
+	return keys.active, nil
+}
+
+// RotateIfOlderThan makes a new active key for the tenant when the current
+// one is older than maxAge. Values sealed with older keys stay readable
+func (r *DataKeyRing) RotateIfOlderThan(ctx context.Context, tenantID uuid.UUID, maxAge time.Duration) (bool, error) {
+	keys, err := r.keysFor(ctx, tenantID, 0)
+	if err != nil {
+		return false, err
+	}
+	if time.Since(keys.createdAt) < maxAge {
+		return false, nil
+	}
+	created, err := r.createKey(ctx, tenantID, keys.active+1)
+	if err != nil {
+		return false, err
+	}
+	r.forget(tenantID)
+	return created, nil
+}
+
+// keysFor returns the tenant's cached keys, reloading them when the cache is
+// stale or lacks version; version 0 only needs an active key
+func (r *DataKeyRing) keysFor(ctx context.Context, tenantID uuid.UUID, version int) (*tenantKeys, error) {
+	r.mu.Lock()
+	keys := r.tenants[tenantID]
+	r.mu.Unlock()
+	if keys != nil && keys.active != 0 && time.Since(keys.loadedAt) < keyCacheTTL {
+		if _, ok := keys.aeads[version]; ok || version == 0 {
+			return keys, nil
+		}
+	}
+
+	keys, err := r.load(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if keys.active == 0 {
+		// First use by the tenant; a concurrent first use may win the race
+		if _, err := r.createKey(ctx, tenantID, 1); err != nil {
+			return nil, err
+		}
+		if keys, err = r.load(ctx, tenantID); err != nil {
+			return nil, err
+		}
+		if keys.active == 0 {
+			return nil, fmt.Errorf("%w: tenant has no active key", ErrDataKeyUnavailable)
+		}
+	}
+	return keys, nil
+}
+
+func (r *DataKeyRing) load(ctx context.Context, tenantID uuid.UUID) (*tenantKeys, error) {
+	stored, err := r.store.ListDataKeys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data keys: %w", err)
+	}
+	keys := &tenantKeys{aeads: make(map[int]cipher.AEAD, len(stored)), loadedAt: time.Now()}
+	for _, key := range stored {
+		aead, err := r.unwrap(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key %d: %w", key.Version, err)
+		}
+		keys.aeads[key.Version] = aead
+		if key.IsActive {
+			keys.active = key.Version
+			keys.createdAt = key.CreatedAt
+		}
+	}
+
+	r.mu.Lock()
+	r.tenants[tenantID] = keys
+	r.mu.Unlock()
+	return keys, nil
+}
+
+func (r *DataKeyRing) forget(tenantID uuid.UUID) {
+	r.mu.Lock()
+	delete(r.tenants, tenantID)
+	r.mu.Unlock()
+}
+
+func (r *DataKeyRing) createKey(ctx context.Context, tenantID uuid.UUID, version int) (bool, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return false, err
+	}
+	nonce := make([]byte, r.master.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return false, err
+	}
+	key := &models.TenantDataKey{
+		TenantID:   tenantID,
+		Version:    version,
+		WrappedKey: r.master.Seal(nonce, nonce, dataKey, wrapAAD(tenantID, version)),
+		IsActive:   true,
+	}
+	created, err := r.store.CreateDataKey(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to store data key: %w", err)
+	}
+	return created, nil
+}
+
+func (r *DataKeyRing) unwrap(key *models.TenantDataKey) (cipher.AEAD, error) {
+	size := r.master.NonceSize()
+	if len(key.WrappedKey) < size {
+		return nil, errors.New("wrapped key is truncated")
+	}
+	dataKey, err := r.master.Open(nil, key.WrappedKey[:size], key.WrappedKey[size:], wrapAAD(key.TenantID, key.Version))
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(dataKey)
+}
+
+// wrapAAD binds a wrapped key to its tenant and version
+func wrapAAD(tenantID uuid.UUID, version int) []byte {
+	aad := make([]byte, 0, len(tenantID)+8)
+	aad = append(aad, tenantID[:]...)
+	return binary.BigEndian.AppendUint64(aad, uint64(version))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
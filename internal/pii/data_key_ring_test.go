@@ -0,0 +1,165 @@
+package pii
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys []*models.TenantDataKey
+}
+
+func (s *memoryKeyStore) ListDataKeys(ctx context.Context, tenantID uuid.UUID) ([]*models.TenantDataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []*models.TenantDataKey
+	for _, key := range s.keys {
+		if key.TenantID == tenantID {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) CreateDataKey(ctx context.Context, key *models.TenantDataKey) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.keys {
+		if existing.TenantID == key.TenantID && existing.Version == key.Version {
+			return false, nil
+		}
+	}
+	now := time.Now()
+	for _, existing := range s.keys {
+		if existing.TenantID == key.TenantID && existing.IsActive {
+			existing.IsActive = false
+			existing.RetiredAt = &now
+		}
+	}
+	stored := *key
+	stored.CreatedAt = now
+	s.keys = append(s.keys, &stored)
+	return true, nil
+}
+
+func newTestRing(t *testing.T, store DataKeyStore) *DataKeyRing {
+	master := make([]byte, 32)
+	_, err := rand.Read(master)
+	require.NoError(t, err)
+	ring, err := NewDataKeyRing(store, master)
+	require.NoError(t, err)
+	return ring
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestDataKeyRingRoundTrip(t *testing.T) {
+	store := &memoryKeyStore{}
+	ring := newTestRing(t, store)
+	tenantID := uuid.New()
+	ctx := context.Background()
+
+	sealed, err := ring.Encrypt(ctx, tenantID, strPtr("ramesh@example.com"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(*sealed, "pii:v1:1:"))
+	assert.NotContains(t, *sealed, "ramesh")
+
+	plain, err := ring.Decrypt(ctx, tenantID, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "ramesh@example.com", *plain)
+	assert.Len(t, store.keys, 1)
+}
+
+func TestDataKeyRingBindsCiphertextToTenant(t *testing.T) {
+	ring := newTestRing(t, &memoryKeyStore{})
+	ctx := context.Background()
+
+	sealed, err := ring.Encrypt(ctx, uuid.New(), strPtr("9848012345"))
+	require.NoError(t, err)
+
+	_, err = ring.Decrypt(ctx, uuid.New(), sealed)
+	assert.Error(t, err)
+}
+
+func TestDataKeyRingPassesPlaintextThrough(t *testing.T) {
+	ring := newTestRing(t, &memoryKeyStore{})
+	ctx := context.Background()
+	tenantID := uuid.New()
+
+	plain, err := ring.Decrypt(ctx, tenantID, strPtr("legacy@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "legacy@example.com", *plain)
+
+	empty, err := ring.Encrypt(ctx, tenantID, strPtr(""))
+	require.NoError(t, err)
+	assert.Equal(t, "", *empty)
+
+	var disabled *DataKeyRing
+	value, err := disabled.Encrypt(ctx, tenantID, strPtr("29ABCDE1234F1Z5"))
+	require.NoError(t, err)
+	assert.Equal(t, "29ABCDE1234F1Z5", *value)
+	assert.Nil(t, disabled.BlindIndex(tenantID, value))
+}
+
+func TestDataKeyRingDecryptsRetiredKeys(t *testing.T) {
+	ring := newTestRing(t, &memoryKeyStore{})
+	ctx := context.Background()
+	tenantID := uuid.New()
+
+	old, err := ring.Encrypt(ctx, tenantID, strPtr("29ABCDE1234F1Z5"))
+	require.NoError(t, err)
+
+	rotated, err := ring.RotateIfOlderThan(ctx, tenantID, 0)
+	require.NoError(t, err)
+	assert.True(t, rotated)
+
+	fresh, err := ring.Encrypt(ctx, tenantID, strPtr("29ABCDE1234F1Z5"))
+	require.NoError(t, err)
+	version, ok := SealedVersion(*fresh)
+	require.True(t, ok)
+	assert.Equal(t, 2, version)
+
+	plain, err := ring.Decrypt(ctx, tenantID, old)
+	require.NoError(t, err)
+	assert.Equal(t, "29ABCDE1234F1Z5", *plain)
+
+	rotated, err = ring.RotateIfOlderThan(ctx, tenantID, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, rotated)
+}
+
+func TestBlindIndexIgnoresFormatting(t *testing.T) {
+	ring := newTestRing(t, &memoryKeyStore{})
+	tenantID := uuid.New()
+
+	assert.Equal(t, *ring.BlindIndex(tenantID, strPtr("+91 98480-12345")), *ring.BlindIndex(tenantID, strPtr("+919848012345")))
+	assert.Equal(t, *ring.BlindIndex(tenantID, strPtr("Ramesh@Example.com ")), *ring.BlindIndex(tenantID, strPtr("ramesh@example.com")))
+	assert.Equal(t, *ring.BlindIndex(tenantID, strPtr("29abcde1234f1z5")), *ring.BlindIndex(tenantID, strPtr("29ABCDE1234F1Z5")))
+	assert.NotEqual(t, *ring.BlindIndex(tenantID, strPtr("r.amesh@example.com")), *ring.BlindIndex(tenantID, strPtr("ramesh@example.com")))
+
+	// Indexes are per tenant, so equal values cannot be linked across tenants
+	assert.NotEqual(t, *ring.BlindIndex(uuid.New(), strPtr("9848012345")), *ring.BlindIndex(tenantID, strPtr("9848012345")))
+
+	// The global index is shared by every tenant, for login emails
+	assert.Equal(t, *ring.GlobalBlindIndex(strPtr("Ramesh@Example.com")), *ring.GlobalBlindIndex(strPtr("ramesh@example.com ")))
+	assert.NotEqual(t, *ring.GlobalBlindIndex(strPtr("ramesh@example.com")), *ring.BlindIndex(tenantID, strPtr("ramesh@example.com")))
+}
+
+func TestNewDataKeyRingRejectsShortMasterKey(t *testing.T) {
+	_, err := NewDataKeyRing(&memoryKeyStore{}, []byte("too short"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package pii
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Redacted replaces values that must not be logged
+const Redacted = "[REDACTED]"
+
+// sensitiveFieldParts are matched against lowercased field names with
+// separators removed, so apiKey, api_key and API-Key are all caught
+var sensitiveFieldParts = []string{
+	"password", "passwd", "secret", "token", "apikey", "authorization",
+	"cardnumber", "accountnumber", "ifsc", "aadhaar", "gstin", "email",
+	"phone", "mobile", "signature", "privatekey",
+}
+
+// sensitiveFieldWords are too short to match inside other words, so they
+// must be a whole word of the name: user_pin but not spinach
+var sensitiveFieldWords = map[string]bool{"otp": true, "pin": true, "pan": true, "cvv": true, "mpin": true}
+
+// sensitiveHeaders are always redacted
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+	"x-auth-token":        true,
+	"x-csrf-token":        true,
+	"x-catalog-token":     true,
+	"x-hub-signature-256": true,
+}
+
+// IsSensitiveField reports whether a field's value should be redacted,
+// given its name and any extra names configured by the caller
+func IsSensitiveField(name string, extra ...string) bool {
+	normalized := normalizeField(name)
+	for _, field := range extra {
+		if normalized == normalizeField(field) {
+			return true
+		}
+	}
+	for _, part := range sensitiveFieldParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	for _, word := range fieldWords(name) {
+		if sensitiveFieldWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeField(name string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(strings.ToLower(name))
+}
+
+// fieldWords splits snake, kebab and camel case names into lowercased words
+func fieldWords(name string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for _, r := range name {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 && !unicode.IsUpper(word[len(word)-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	return words
+}
+
+// RedactHeaders copies headers with credentials replaced
+func RedactHeaders(headers map[string][]string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if sensitiveHeaders[strings.ToLower(name)] {
+			redacted[name] = Redacted
+			continue
+		}
+		redacted[name] = strings.Join(values, ", ")
+	}
+	return redacted
+}
+
+// RedactJSON replaces the values of sensitive fields at any depth. Bodies
+// that are not valid JSON are returned as ok false
+func RedactJSON(body []byte, extra ...string) (string, bool) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", false
+	}
+	redacted, err := json.Marshal(redactValue(value, extra))
+	if err != nil {
+		return "", false
+	}
+	return string(redacted), true
+}
+
+func redactValue(value interface{}, extra []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if IsSensitiveField(key, extra...) && field != nil {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redactValue(field, extra)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, extra)
+		}
+	}
+	return value
+}
+
+// RedactQuery replaces sensitive parameters of a URL encoded query or form body
+func RedactQuery(raw string, extra ...string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return Redacted
+	}
+	for key := range values {
+		if IsSensitiveField(key, extra...) {
+			values[key] = []string{Redacted}
+		}
+	}
+	return values.Encode()
+}
@@ -0,0 +1,55 @@
+package pii
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSensitiveField(t *testing.T) {
+	for _, name := range []string{"password", "new_password", "apiKey", "X-API-Key", "refresh_token", "contact_email", "userPin", "otp", "gstin", "bank_account_number"} {
+		assert.True(t, IsSensitiveField(name), name)
+	}
+	for _, name := range []string{"name", "company", "spinach", "quantity", "panel_id", "notes"} {
+		assert.False(t, IsSensitiveField(name), name)
+	}
+	assert.True(t, IsSensitiveField("vehicleNumber", "vehicle_number"))
+}
+
+func TestRedactJSONRedactsNestedFields(t *testing.T) {
+	body := []byte(`{"name":"Ramesh","contact":{"phone":"+919848012345","email":null},"items":[{"sku":"SEED-1","otp":"1234"}],"password":"hunter2"}`)
+
+	redacted, ok := RedactJSON(body)
+	require.True(t, ok)
+
+	var value map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(redacted), &value))
+	assert.Equal(t, "Ramesh", value["name"])
+	assert.Equal(t, Redacted, value["password"])
+	contact := value["contact"].(map[string]interface{})
+	assert.Equal(t, Redacted, contact["phone"])
+	assert.Nil(t, contact["email"])
+	item := value["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "SEED-1", item["sku"])
+	assert.Equal(t, Redacted, item["otp"])
+	assert.NotContains(t, redacted, "hunter2")
+
+	_, ok = RedactJSON([]byte("not json"))
+	assert.False(t, ok)
+}
+
+func TestRedactHeadersAndQuery(t *testing.T) {
+	headers := RedactHeaders(map[string][]string{
+		"Authorization": {"Bearer abc"},
+		"Content-Type":  {"application/json"},
+	})
+	assert.Equal(t, Redacted, headers["Authorization"])
+	assert.Equal(t, "application/json", headers["Content-Type"])
+
+	query := RedactQuery("page=2&token=abc&email=a%40b.com")
+	assert.Contains(t, query, "page=2")
+	assert.NotContains(t, query, "abc")
+	assert.NotContains(t, query, "a%40b.com")
+}
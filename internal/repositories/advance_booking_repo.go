@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AdvanceBookingRepository interface {
+	Create(ctx context.Context, booking *models.AdvanceBooking) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, error)
+	List(ctx context.Context, tenantID uuid.UUID, filter *models.AdvanceBookingFilter) ([]*models.AdvanceBooking, error)
+	AddPayment(ctx context.Context, tenantID uuid.UUID, payment *models.AdvanceBookingPayment, maxTotal float64) (bool, error)
+	ListPayments(ctx context.Context, bookingID uuid.UUID) ([]*models.AdvanceBookingPayment, error)
+	SetStatus(ctx context.Context, tenantID, id uuid.UUID, fromStatus, toStatus string, orderID *uuid.UUID) (bool, error)
+	DemandByProductRegion(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.BookingDemandRow, error)
+}
+
+type advanceBookingRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAdvanceBookingRepo(db *pgxpool.Pool) AdvanceBookingRepository {
+	return &advanceBookingRepo{db: db}
+}
+
+const advanceBookingColumns = `id, tenant_id, distributor_id, product_id, warehouse_id, quantity, unit_price::float8, region, window_start, window_end, advance_paid::float8, status, order_id, converted_at, notes, created_by, created_at, updated_at`
+
+func scanAdvanceBooking(row rowScanner) (*models.AdvanceBooking, error) {
+	b := &models.AdvanceBooking{}
+	err := row.Scan(&b.ID, &b.TenantID, &b.DistributorID, &b.ProductID, &b.WarehouseID, &b.Quantity, &b.UnitPrice, &b.Region, &b.WindowStart, &b.WindowEnd,
+		&b.AdvancePaid, &b.Status, &b.OrderID, &b.ConvertedAt, &b.Notes, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *advanceBookingRepo) Create(ctx context.Context, booking *models.AdvanceBooking) error {
+	query := `
+		INSERT INTO advance_bookings (id, tenant_id, distributor_id, product_id, warehouse_id, quantity, unit_price, region, window_start, window_end, status, notes, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, booking.ID, booking.TenantID, booking.DistributorID, booking.ProductID, booking.WarehouseID, booking.Quantity, booking.UnitPrice,
+		booking.Region, booking.WindowStart, booking.WindowEnd, booking.Status, booking.Notes, booking.CreatedBy).
+		Scan(&booking.CreatedAt, &booking.UpdatedAt)
+}
+
+func (r *advanceBookingRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, error) {
+	query := `SELECT ` + advanceBookingColumns + ` FROM advance_bookings WHERE tenant_id = $1 AND id = $2`
+	booking, err := scanAdvanceBooking(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return booking, err
+}
+
+// List returns bookings earliest delivery window first
+func (r *advanceBookingRepo) List(ctx context.Context, tenantID uuid.UUID, filter *models.AdvanceBookingFilter) ([]*models.AdvanceBooking, error) {
+	query := `
+		SELECT ` + advanceBookingColumns + `
+		FROM advance_bookings
+		WHERE tenant_id = $1
+		  AND ($2::text IS NULL OR status = $2)
+		  AND ($3::uuid IS NULL OR distributor_id = $3)
+		  AND ($4::uuid IS NULL OR product_id = $4)
+		  AND ($5::date IS NULL OR window_start <= $5)
+		ORDER BY window_start, created_at
+		LIMIT $6 OFFSET $7
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, filter.Status, filter.DistributorID, filter.ProductID, filter.WindowStartsBefore, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []*models.AdvanceBooking
+	for rows.Next() {
+		booking, err := scanAdvanceBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+// AddPayment records an advance and raises the booking's advance_paid in one
+// transaction; it reports false, recording nothing, if the booking is no
+// longer open or the payment would take advances above maxTotal
+func (r *advanceBookingRepo) AddPayment(ctx context.Context, tenantID uuid.UUID, payment *models.AdvanceBookingPayment, maxTotal float64) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE advance_bookings
+		SET advance_paid = advance_paid + $3, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'booked' AND advance_paid + $3 <= $4
+	`
+	tag, err := tx.Exec(ctx, query, tenantID, payment.BookingID, payment.Amount, maxTotal)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `
+		INSERT INTO advance_booking_payments (id, booking_id, amount, method, reference, recorded_by, paid_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING paid_at
+	`
+	if err := tx.QueryRow(ctx, query, payment.ID, payment.BookingID, payment.Amount, payment.Method, payment.Reference, payment.RecordedBy).Scan(&payment.PaidAt); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *advanceBookingRepo) ListPayments(ctx context.Context, bookingID uuid.UUID) ([]*models.AdvanceBookingPayment, error) {
+	query := `
+		SELECT id, booking_id, amount::float8, method, reference, recorded_by, paid_at
+		FROM advance_booking_payments
+		WHERE booking_id = $1
+		ORDER BY paid_at
+	`
+	rows, err := r.db.Query(ctx, query, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*models.AdvanceBookingPayment
+	for rows.Next() {
+		p := &models.AdvanceBookingPayment{}
+		if err := rows.Scan(&p.ID, &p.BookingID, &p.Amount, &p.Method, &p.Reference, &p.RecordedBy, &p.PaidAt); err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// SetStatus moves a booking from fromStatus to toStatus; converting also links the sales order
+func (r *advanceBookingRepo) SetStatus(ctx context.Context, tenantID, id uuid.UUID, fromStatus, toStatus string, orderID *uuid.UUID) (bool, error) {
+	query := `
+		UPDATE advance_bookings
+		SET status = $4,
+			order_id = COALESCE($5, order_id),
+			converted_at = CASE WHEN $4 = 'converted' THEN NOW() ELSE converted_at END,
+			updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = $3
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, id, fromStatus, toStatus, orderID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DemandByProductRegion totals bookings whose window starts in [from, to) per
+// product and region; fulfilled means the converted order was delivered
+func (r *advanceBookingRepo) DemandByProductRegion(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.BookingDemandRow, error) {
+	query := `
+		SELECT b.product_id, p.name, COALESCE(NULLIF(b.region, ''), 'unassigned') AS region,
+			COUNT(*) FILTER (WHERE b.status <> 'cancelled')::int,
+			COALESCE(SUM(b.quantity) FILTER (WHERE b.status <> 'cancelled'), 0)::int,
+			COALESCE(SUM(b.quantity) FILTER (WHERE b.status = 'converted'), 0)::int,
+			COALESCE(SUM(o.quantity) FILTER (WHERE b.status = 'converted' AND o.status = 'delivered'), 0)::int,
+			COALESCE(SUM(b.quantity) FILTER (WHERE b.status = 'cancelled'), 0)::int,
+			COALESCE(SUM(b.advance_paid), 0)::float8
+		FROM advance_bookings b
+		JOIN products p ON p.id = b.product_id
+		LEFT JOIN orders o ON o.id = b.order_id
+		WHERE b.tenant_id = $1 AND b.window_start >= $2::date AND b.window_start < $3::date
+		GROUP BY b.product_id, p.name, 3
+		ORDER BY p.name, 3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var demand []*models.BookingDemandRow
+	for rows.Next() {
+		row := &models.BookingDemandRow{}
+		if err := rows.Scan(&row.ProductID, &row.ProductName, &row.Region, &row.Bookings, &row.BookedQuantity, &row.ConvertedQuantity,
+			&row.FulfilledQuantity, &row.CancelledQuantity, &row.AdvanceCollected); err != nil {
+			return nil, err
+		}
+		demand = append(demand, row)
+	}
+	return demand, rows.Err()
+}
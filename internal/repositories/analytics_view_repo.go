@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Materialized views behind the sales, stock and receivables analytics
+const (
+	ViewDailySales       = "mv_daily_sales"
+	ViewStockByCategory  = "mv_stock_by_category"
+	ViewReceivablesAging = "mv_receivables_aging"
+)
+
+// AnalyticsViews lists the materialized views in refresh order
+var AnalyticsViews = []string{ViewDailySales, ViewStockByCategory, ViewReceivablesAging}
+
+// Live equivalents of the materialized views for one tenant ($1), used when a
+// view is stale. They must produce the same columns as the view definitions
+// in migrations/20250902190000_add_analytics_materialized_views.sql
+const (
+	dailySalesLive = `(
+		SELECT o.tenant_id, o.order_date::date AS sale_date, COUNT(*)::int AS order_count, SUM(o.quantity)::bigint AS quantity,
+			SUM(o.quantity * o.unit_price)::numeric(16,2) AS sales_amount
+		FROM orders o
+		WHERE o.tenant_id = $1 AND o.order_type = 'sales' AND o.status <> 'cancelled'
+		GROUP BY o.tenant_id, o.order_date::date
+	) ds`
+
+	stockByCategoryLive = `(
+		SELECT p.tenant_id, p.category_id, COALESCE(c.name, 'Uncategorised') AS category_name, COUNT(DISTINCT p.id)::int AS product_count,
+			COALESCE(SUM(GREATEST(i.quantity - COALESCE(cs.quantity, 0), 0)), 0)::bigint AS quantity,
+			COALESCE(SUM(GREATEST(i.quantity - COALESCE(cs.quantity, 0), 0) * COALESCE(p.unit_price, 0)), 0)::numeric(16,2) AS stock_value
+		FROM products p
+		LEFT JOIN categories c ON c.id = p.category_id
+		LEFT JOIN inventory i ON i.product_id = p.id AND i.tenant_id = p.tenant_id
+		LEFT JOIN (
+			SELECT tenant_id, warehouse_id, product_id, SUM(quantity) AS quantity
+			FROM consignment_stock
+			WHERE tenant_id = $1
+			GROUP BY tenant_id, warehouse_id, product_id
+		) cs ON cs.tenant_id = i.tenant_id AND cs.warehouse_id = i.warehouse_id AND cs.product_id = i.product_id
+		WHERE p.tenant_id = $1
+		GROUP BY p.tenant_id, p.category_id, c.name
+	) sc`
+
+	receivablesAgingLive = `(
+		SELECT r.tenant_id, r.distributor_id, d.name AS distributor_name, COUNT(*)::int AS invoice_count,
+			SUM(r.outstanding) FILTER (WHERE r.days_past_due <= 0)::numeric(16,2) AS current_amount,
+			SUM(r.outstanding) FILTER (WHERE r.days_past_due BETWEEN 1 AND 30)::numeric(16,2) AS days_1_30,
+			SUM(r.outstanding) FILTER (WHERE r.days_past_due BETWEEN 31 AND 60)::numeric(16,2) AS days_31_60,
+			SUM(r.outstanding) FILTER (WHERE r.days_past_due BETWEEN 61 AND 90)::numeric(16,2) AS days_61_90,
+			SUM(r.outstanding) FILTER (WHERE r.days_past_due > 90)::numeric(16,2) AS days_over_90,
+			SUM(r.outstanding)::numeric(16,2) AS total_outstanding,
+			CURRENT_DATE AS as_of
+		FROM (
+			SELECT i.tenant_id, o.distributor_id, CURRENT_DATE - i.due_date::date AS days_past_due, ` + invoiceOutstanding + ` AS outstanding
+			FROM invoices i
+			JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+			WHERE i.tenant_id = $1 AND i.status IN ('unpaid', 'overdue') AND o.distributor_id IS NOT NULL
+		) r
+		JOIN distributors d ON d.id = r.distributor_id
+		WHERE r.outstanding > 0.005
+		GROUP BY r.tenant_id, r.distributor_id, d.name
+	) ra`
+)
+
+type AnalyticsViewRepository interface {
+	Refresh(ctx context.Context, view string) (*models.AnalyticsViewRefresh, error)
+	LastRefresh(ctx context.Context, view string) (*models.AnalyticsViewRefresh, error)
+
+	// Readers take live to query the underlying tables instead of the view
+	DailySales(ctx context.Context, tenantID uuid.UUID, from, to time.Time, live bool) ([]*models.DailySalesRow, error)
+	StockByCategory(ctx context.Context, tenantID uuid.UUID, live bool) ([]*models.CategoryStockRow, error)
+	ReceivablesAging(ctx context.Context, tenantID uuid.UUID, live bool) ([]*models.ReceivablesAgingRow, error)
+}
+
+type analyticsViewRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAnalyticsViewRepo(db *pgxpool.Pool) AnalyticsViewRepository {
+	return &analyticsViewRepo{db: db}
+}
+
+// Refresh rebuilds a materialized view without blocking readers and records
+// when it was done
+func (r *analyticsViewRepo) Refresh(ctx context.Context, view string) (*models.AnalyticsViewRefresh, error) {
+	started := time.Now()
+	if _, err := r.db.Exec(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY `+pgx.Identifier{view}.Sanitize()); err != nil {
+		return nil, err
+	}
+
+	refresh := &models.AnalyticsViewRefresh{ViewName: view, DurationMs: int(time.Since(started).Milliseconds())}
+	query := `
+		INSERT INTO analytics_view_refreshes (view_name, refreshed_at, duration_ms)
+		VALUES ($1, NOW(), $2)
+		ON CONFLICT (view_name) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at, duration_ms = EXCLUDED.duration_ms
+		RETURNING refreshed_at
+	`
+	if err := r.db.QueryRow(ctx, query, view, refresh.DurationMs).Scan(&refresh.RefreshedAt); err != nil {
+		return nil, err
+	}
+	return refresh, nil
+}
+
+// LastRefresh returns nil for a view that has not been refreshed since it was created
+func (r *analyticsViewRepo) LastRefresh(ctx context.Context, view string) (*models.AnalyticsViewRefresh, error) {
+	query := `SELECT view_name, refreshed_at, duration_ms FROM analytics_view_refreshes WHERE view_name = $1`
+	refresh := &models.AnalyticsViewRefresh{}
+	err := r.db.QueryRow(ctx, query, view).Scan(&refresh.ViewName, &refresh.RefreshedAt, &refresh.DurationMs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return refresh, nil
+}
+
+// DailySales lists days with sales between from and to inclusive, oldest first
+func (r *analyticsViewRepo) DailySales(ctx context.Context, tenantID uuid.UUID, from, to time.Time, live bool) ([]*models.DailySalesRow, error) {
+	source := ViewDailySales
+	if live {
+		source = dailySalesLive
+	}
+	query := `
+		SELECT sale_date, order_count, quantity, sales_amount::float8
+		FROM ` + source + `
+		WHERE tenant_id = $1 AND sale_date BETWEEN $2::date AND $3::date
+		ORDER BY sale_date
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []*models.DailySalesRow
+	for rows.Next() {
+		day := &models.DailySalesRow{}
+		if err := rows.Scan(&day.Date, &day.OrderCount, &day.Quantity, &day.SalesAmount); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// StockByCategory lists owned stock by product category, highest value first
+func (r *analyticsViewRepo) StockByCategory(ctx context.Context, tenantID uuid.UUID, live bool) ([]*models.CategoryStockRow, error) {
+	source := ViewStockByCategory
+	if live {
+		source = stockByCategoryLive
+	}
+	query := `
+		SELECT category_id, category_name, product_count, quantity, stock_value::float8
+		FROM ` + source + `
+		WHERE tenant_id = $1
+		ORDER BY stock_value DESC, category_name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.CategoryStockRow
+	for rows.Next() {
+		category := &models.CategoryStockRow{}
+		if err := rows.Scan(&category.CategoryID, &category.CategoryName, &category.ProductCount, &category.Quantity,
+			&category.StockValue); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// ReceivablesAging lists what each distributor owes, largest balance first
+func (r *analyticsViewRepo) ReceivablesAging(ctx context.Context, tenantID uuid.UUID, live bool) ([]*models.ReceivablesAgingRow, error) {
+	source := ViewReceivablesAging
+	if live {
+		source = receivablesAgingLive
+	}
+	query := `
+		SELECT distributor_id, distributor_name, invoice_count, COALESCE(current_amount, 0)::float8, COALESCE(days_1_30, 0)::float8,
+			COALESCE(days_31_60, 0)::float8, COALESCE(days_61_90, 0)::float8, COALESCE(days_over_90, 0)::float8, total_outstanding::float8
+		FROM ` + source + `
+		WHERE tenant_id = $1
+		ORDER BY total_outstanding DESC, distributor_name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aging []*models.ReceivablesAgingRow
+	for rows.Next() {
+		row := &models.ReceivablesAgingRow{}
+		if err := rows.Scan(&row.DistributorID, &row.DistributorName, &row.InvoiceCount, &row.Current, &row.Days1To30,
+			&row.Days31To60, &row.Days61To90, &row.DaysOver90, &row.Total); err != nil {
+			return nil, err
+		}
+		aging = append(aging, row)
+	}
+	return aging, rows.Err()
+}
@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ApprovalDelegationRepository interface {
+	// Create inserts the delegation unless an active one overlapping it in
+	// days and kinds would chain with it: the delegator already delegating,
+	// the delegate away themselves, or the delegator covering someone else.
+	// The first such delegation is returned instead
+	Create(ctx context.Context, delegation *models.ApprovalDelegation) (*models.ApprovalDelegation, error)
+	// GetByID returns the delegation, or nil when it does not exist
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ApprovalDelegation, error)
+	// List lists delegations, newest first, of one user as delegator or
+	// delegate or of everyone; without includePast only those not revoked
+	// and not yet over on the day
+	List(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, includePast bool, day time.Time) ([]*models.ApprovalDelegation, error)
+	Revoke(ctx context.Context, tenantID, id uuid.UUID, revokedBy *uuid.UUID) (bool, error)
+	// Active lists the delegations in force on the day with the user as
+	// delegator or as delegate
+	Active(ctx context.Context, tenantID, userID uuid.UUID, day time.Time) ([]*models.ApprovalDelegation, error)
+	RecordApproval(ctx context.Context, approval *models.DelegatedApproval) error
+	ListApprovals(ctx context.Context, tenantID, delegationID uuid.UUID) ([]*models.DelegatedApproval, error)
+	// PendingApprovals lists up to limit pending orders, purchase
+	// requisitions and inventory reconciliations of the kinds, longest
+	// waiting first
+	PendingApprovals(ctx context.Context, tenantID uuid.UUID, kinds []string, limit int) ([]*models.PendingApproval, error)
+}
+
+type approvalDelegationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewApprovalDelegationRepo(db *pgxpool.Pool) ApprovalDelegationRepository {
+	return &approvalDelegationRepo{db: db}
+}
+
+const approvalDelegationColumns = `id, tenant_id, delegator_id, delegate_id, kinds, starts_on, ends_on, reason, revoked_at, revoked_by, created_by, created_at`
+
+func scanApprovalDelegation(row rowScanner) (*models.ApprovalDelegation, error) {
+	d := &models.ApprovalDelegation{}
+	err := row.Scan(&d.ID, &d.TenantID, &d.DelegatorID, &d.DelegateID, &d.Kinds, &d.StartsOn, &d.EndsOn, &d.Reason, &d.RevokedAt,
+		&d.RevokedBy, &d.CreatedBy, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *approvalDelegationRepo) queryDelegations(ctx context.Context, query string, args ...interface{}) ([]*models.ApprovalDelegation, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegations []*models.ApprovalDelegation
+	for rows.Next() {
+		d, err := scanApprovalDelegation(rows)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, d)
+	}
+	return delegations, rows.Err()
+}
+
+func (r *approvalDelegationRepo) Create(ctx context.Context, delegation *models.ApprovalDelegation) (*models.ApprovalDelegation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize delegations touching either user so overlapping ones can't
+	// slip in side by side
+	if _, err := tx.Exec(ctx, `
+		SELECT id FROM users WHERE tenant_id = $1 AND id IN ($2, $3) ORDER BY id FOR UPDATE
+	`, delegation.TenantID, delegation.DelegatorID, delegation.DelegateID); err != nil {
+		return nil, err
+	}
+
+	conflict, err := scanApprovalDelegation(tx.QueryRow(ctx, `
+		SELECT `+approvalDelegationColumns+`
+		FROM approval_delegations
+		WHERE tenant_id = $1 AND revoked_at IS NULL AND starts_on <= $5 AND ends_on >= $4 AND kinds && $6
+			AND (delegator_id = $2 OR delegator_id = $3 OR delegate_id = $2)
+		ORDER BY starts_on
+		LIMIT 1
+	`, delegation.TenantID, delegation.DelegatorID, delegation.DelegateID, delegation.StartsOn, delegation.EndsOn, delegation.Kinds))
+	if err == nil {
+		return conflict, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO approval_delegations (id, tenant_id, delegator_id, delegate_id, kinds, starts_on, ends_on, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING created_at
+	`, delegation.ID, delegation.TenantID, delegation.DelegatorID, delegation.DelegateID, delegation.Kinds, delegation.StartsOn,
+		delegation.EndsOn, delegation.Reason, delegation.CreatedBy).Scan(&delegation.CreatedAt); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit(ctx)
+}
+
+func (r *approvalDelegationRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ApprovalDelegation, error) {
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations WHERE tenant_id = $1 AND id = $2`
+	d, err := scanApprovalDelegation(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+func (r *approvalDelegationRepo) List(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, includePast bool, day time.Time) ([]*models.ApprovalDelegation, error) {
+	query := `
+		SELECT ` + approvalDelegationColumns + `
+		FROM approval_delegations
+		WHERE tenant_id = $1 AND ($2::uuid IS NULL OR delegator_id = $2 OR delegate_id = $2)
+			AND ($3 OR (revoked_at IS NULL AND ends_on >= $4))
+		ORDER BY starts_on DESC, created_at DESC
+	`
+	return r.queryDelegations(ctx, query, tenantID, userID, includePast, day)
+}
+
+func (r *approvalDelegationRepo) Revoke(ctx context.Context, tenantID, id uuid.UUID, revokedBy *uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE approval_delegations SET revoked_at = NOW(), revoked_by = $3
+		WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL
+	`, tenantID, id, revokedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *approvalDelegationRepo) Active(ctx context.Context, tenantID, userID uuid.UUID, day time.Time) ([]*models.ApprovalDelegation, error) {
+	query := `
+		SELECT ` + approvalDelegationColumns + `
+		FROM approval_delegations
+		WHERE tenant_id = $1 AND (delegator_id = $2 OR delegate_id = $2)
+			AND revoked_at IS NULL AND starts_on <= $3 AND ends_on >= $3
+		ORDER BY starts_on
+	`
+	return r.queryDelegations(ctx, query, tenantID, userID, day)
+}
+
+func (r *approvalDelegationRepo) RecordApproval(ctx context.Context, approval *models.DelegatedApproval) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO delegated_approvals (id, tenant_id, delegation_id, kind, entity_id, action, acted_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING created_at
+	`, approval.ID, approval.TenantID, approval.DelegationID, approval.Kind, approval.EntityID, approval.Action,
+		approval.ActedBy).Scan(&approval.CreatedAt)
+}
+
+func (r *approvalDelegationRepo) ListApprovals(ctx context.Context, tenantID, delegationID uuid.UUID) ([]*models.DelegatedApproval, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, tenant_id, delegation_id, kind, entity_id, action, acted_by, created_at
+		FROM delegated_approvals
+		WHERE tenant_id = $1 AND delegation_id = $2
+		ORDER BY created_at
+	`, tenantID, delegationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var approvals []*models.DelegatedApproval
+	for rows.Next() {
+		a := &models.DelegatedApproval{}
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.DelegationID, &a.Kind, &a.EntityID, &a.Action, &a.ActedBy, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+func (r *approvalDelegationRepo) PendingApprovals(ctx context.Context, tenantID uuid.UUID, kinds []string, limit int) ([]*models.PendingApproval, error) {
+	query := `
+		SELECT kind, id, description, quantity, created_at
+		FROM (
+			SELECT 'order' AS kind, o.id, o.order_type || ' order: ' || p.name AS description, o.quantity, o.created_at
+			FROM orders o
+			JOIN products p ON p.id = o.product_id
+			WHERE o.tenant_id = $1 AND o.status = 'pending' AND 'order' = ANY($2)
+			UNION ALL
+			SELECT 'requisition', r.id, 'requisition: ' || p.name, r.quantity, r.created_at
+			FROM purchase_requisitions r
+			JOIN products p ON p.id = r.product_id
+			WHERE r.tenant_id = $1 AND r.status = 'pending' AND 'requisition' = ANY($2)
+			UNION ALL
+			SELECT 'stock_adjustment', ir.id, 'reconciliation from ' || ir.source, ir.counted_lines - ir.matched_lines, ir.created_at
+			FROM inventory_reconciliations ir
+			WHERE ir.tenant_id = $1 AND ir.status = 'pending' AND 'stock_adjustment' = ANY($2)
+		) pending
+		ORDER BY created_at, id
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, kinds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var approvals []*models.PendingApproval
+	for rows.Next() {
+		a := &models.PendingApproval{}
+		if err := rows.Scan(&a.Kind, &a.ID, &a.Description, &a.Quantity, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
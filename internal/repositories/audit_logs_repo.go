@@ -56,8 +56,8 @@ func (r *auditLogsRepo) Create(ctx context.Context, auditLog *models.AuditLog) e
 	}
 
 	query := `
-		INSERT INTO audit_logs (id, tenant_id, table_name, record_id, action, new_values, old_values, changed_by, deleted, deleted_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO audit_logs (id, tenant_id, table_name, record_id, action, new_values, old_values, changed_by, impersonator_id, deleted, deleted_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 
 	// Marshal JSONB fields
@@ -87,6 +87,7 @@ func (r *auditLogsRepo) Create(ctx context.Context, auditLog *models.AuditLog) e
 		newValuesBytes,
 		oldValuesBytes,
 		auditLog.ChangedBy,
+		auditLog.ImpersonatorID,
 		auditLog.Deleted,
 		auditLog.DeletedAt,
 		auditLog.CreatedAt,
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AvailabilityRepository interface {
+	GetByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.WarehouseAvailability, error)
+}
+
+type availabilityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAvailabilityRepo(db *pgxpool.Pool) AvailabilityRepository {
+	return &availabilityRepo{db: db}
+}
+
+// GetByProduct computes per-warehouse availability in one query.
+// Sales orders reserve stock until processing deducts it from inventory;
+// purchase orders are in transit from approval until they are received.
+func (r *availabilityRepo) GetByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.WarehouseAvailability, error) {
+	query := `
+		WITH on_hand AS (
+			SELECT warehouse_id, SUM(quantity) AS qty
+			FROM inventory
+			WHERE tenant_id = $1 AND product_id = $2
+			GROUP BY warehouse_id
+		),
+		reserved AS (
+			SELECT warehouse_id, SUM(quantity) AS qty
+			FROM orders
+			WHERE tenant_id = $1 AND product_id = $2
+			  AND order_type = 'sales' AND status IN ('pending', 'approved')
+			GROUP BY warehouse_id
+		),
+		in_transit AS (
+			SELECT warehouse_id, SUM(quantity) AS qty
+			FROM orders
+			WHERE tenant_id = $1 AND product_id = $2
+			  AND order_type = 'purchase' AND status IN ('approved', 'processing', 'shipped')
+			GROUP BY warehouse_id
+		)
+		SELECT w.id, w.name, w.is_default,
+			COALESCE(oh.qty, 0), COALESCE(rs.qty, 0), COALESCE(it.qty, 0)
+		FROM warehouses w
+		LEFT JOIN on_hand oh ON oh.warehouse_id = w.id
+		LEFT JOIN reserved rs ON rs.warehouse_id = w.id
+		LEFT JOIN in_transit it ON it.warehouse_id = w.id
+		WHERE w.tenant_id = $1
+		  AND (oh.qty IS NOT NULL OR rs.qty IS NOT NULL OR it.qty IS NOT NULL)
+		ORDER BY w.is_default DESC, w.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.WarehouseAvailability
+	for rows.Next() {
+		wa := &models.WarehouseAvailability{}
+		if err := rows.Scan(&wa.WarehouseID, &wa.WarehouseName, &wa.IsDefault, &wa.OnHand, &wa.Reserved, &wa.InTransit); err != nil {
+			return nil, err
+		}
+		result = append(result, wa)
+	}
+	return result, rows.Err()
+}
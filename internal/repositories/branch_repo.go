@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BranchRepository interface {
+	Create(ctx context.Context, branch *models.Branch) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Branch, error)
+	// GetByCodeOrGSTIN finds a branch, archived ones included, using the code
+	// or the GSTIN; both are unique within the tenant
+	GetByCodeOrGSTIN(ctx context.Context, tenantID uuid.UUID, code, gstin string) (*models.Branch, error)
+	Update(ctx context.Context, branch *models.Branch) error
+	List(ctx context.Context, tenantID uuid.UUID, includeArchived bool) ([]*models.Branch, error)
+	// UsageCount counts the warehouses, orders and invoices referencing the branch
+	UsageCount(ctx context.Context, tenantID, id uuid.UUID) (int, error)
+	Archive(ctx context.Context, tenantID, id uuid.UUID) error
+	Delete(ctx context.Context, tenantID, id uuid.UUID) error
+}
+
+type branchRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewBranchRepo(db *pgxpool.Pool) BranchRepository {
+	return &branchRepo{db: db}
+}
+
+const branchColumns = `id, tenant_id, code, name, legal_name, gstin, state_code, address, archived_at, created_at, updated_at`
+
+func scanBranch(row rowScanner) (*models.Branch, error) {
+	branch := &models.Branch{}
+	err := row.Scan(&branch.ID, &branch.TenantID, &branch.Code, &branch.Name, &branch.LegalName, &branch.GSTIN, &branch.StateCode,
+		&branch.Address, &branch.ArchivedAt, &branch.CreatedAt, &branch.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+func (r *branchRepo) Create(ctx context.Context, branch *models.Branch) error {
+	query := `
+		INSERT INTO branches (id, tenant_id, code, name, legal_name, gstin, state_code, address, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, branch.ID, branch.TenantID, branch.Code, branch.Name, branch.LegalName, branch.GSTIN, branch.StateCode,
+		branch.Address).Scan(&branch.CreatedAt, &branch.UpdatedAt)
+}
+
+// GetByID returns the branch, or nil when it does not exist
+func (r *branchRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Branch, error) {
+	query := `SELECT ` + branchColumns + ` FROM branches WHERE tenant_id = $1 AND id = $2`
+	branch, err := scanBranch(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return branch, err
+}
+
+func (r *branchRepo) GetByCodeOrGSTIN(ctx context.Context, tenantID uuid.UUID, code, gstin string) (*models.Branch, error) {
+	query := `SELECT ` + branchColumns + ` FROM branches WHERE tenant_id = $1 AND (UPPER(code) = UPPER($2) OR gstin = $3) LIMIT 1`
+	branch, err := scanBranch(r.db.QueryRow(ctx, query, tenantID, code, gstin))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return branch, err
+}
+
+func (r *branchRepo) Update(ctx context.Context, branch *models.Branch) error {
+	query := `
+		UPDATE branches
+		SET name = $1, legal_name = $2, address = $3, updated_at = NOW()
+		WHERE tenant_id = $4 AND id = $5
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, branch.Name, branch.LegalName, branch.Address, branch.TenantID, branch.ID).Scan(&branch.UpdatedAt)
+}
+
+func (r *branchRepo) List(ctx context.Context, tenantID uuid.UUID, includeArchived bool) ([]*models.Branch, error) {
+	query := `
+		SELECT ` + branchColumns + `
+		FROM branches
+		WHERE tenant_id = $1 AND ($2 OR archived_at IS NULL)
+		ORDER BY UPPER(code)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var branches []*models.Branch
+	for rows.Next() {
+		branch, err := scanBranch(rows)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+	return branches, rows.Err()
+}
+
+func (r *branchRepo) UsageCount(ctx context.Context, tenantID, id uuid.UUID) (int, error) {
+	query := `
+		SELECT (SELECT COUNT(*) FROM warehouses WHERE tenant_id = $1 AND branch_id = $2)
+			+ (SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND branch_id = $2)
+			+ (SELECT COUNT(*) FROM invoices WHERE tenant_id = $1 AND branch_id = $2)
+	`
+	var count int
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&count)
+	return count, err
+}
+
+// Archive hides the branch from lists while keeping it for the records that
+// reference it; its code and GSTIN stay taken
+func (r *branchRepo) Archive(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `UPDATE branches SET archived_at = NOW(), updated_at = NOW() WHERE tenant_id = $1 AND id = $2 AND archived_at IS NULL`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+func (r *branchRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM branches WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	return err
+}
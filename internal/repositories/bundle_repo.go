@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BundleRepository interface {
+	GetComponents(ctx context.Context, tenantID, bundleID uuid.UUID) ([]*models.BundleComponent, error)
+	SetComponents(ctx context.Context, tenantID, bundleID uuid.UUID, components []*models.BundleComponent) error
+	Buildable(ctx context.Context, tenantID, bundleID uuid.UUID) ([]*models.WarehouseAvailability, error)
+	CreateSale(ctx context.Context, sale *models.BundleSale) error
+	GetSale(ctx context.Context, tenantID, orderID uuid.UUID) (*models.BundleSale, error)
+	DeleteSale(ctx context.Context, tenantID, orderID uuid.UUID) error
+}
+
+type bundleRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewBundleRepo(db *pgxpool.Pool) BundleRepository {
+	return &bundleRepo{db: db}
+}
+
+// GetComponents returns the bundle's components; empty when the product is not a bundle
+func (r *bundleRepo) GetComponents(ctx context.Context, tenantID, bundleID uuid.UUID) ([]*models.BundleComponent, error) {
+	query := `
+		SELECT bc.bundle_id, bc.component_id, p.name, bc.quantity
+		FROM product_bundle_components bc
+		JOIN products p ON p.id = bc.component_id
+		WHERE bc.tenant_id = $1 AND bc.bundle_id = $2
+		ORDER BY p.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var components []*models.BundleComponent
+	for rows.Next() {
+		component := &models.BundleComponent{}
+		if err := rows.Scan(&component.BundleID, &component.ComponentID, &component.ComponentName, &component.Quantity); err != nil {
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return components, rows.Err()
+}
+
+// SetComponents replaces the bundle's components and flags the product as a
+// bundle; an empty list turns it back into a plain product
+func (r *bundleRepo) SetComponents(ctx context.Context, tenantID, bundleID uuid.UUID, components []*models.BundleComponent) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_bundle_components WHERE tenant_id = $1 AND bundle_id = $2`, tenantID, bundleID); err != nil {
+		return err
+	}
+	for _, component := range components {
+		query := `
+			INSERT INTO product_bundle_components (tenant_id, bundle_id, component_id, quantity)
+			VALUES ($1, $2, $3, $4)
+		`
+		if _, err := tx.Exec(ctx, query, tenantID, bundleID, component.ComponentID, component.Quantity); err != nil {
+			return err
+		}
+	}
+	query := `UPDATE products SET is_bundle = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`
+	if _, err := tx.Exec(ctx, query, tenantID, bundleID, len(components) > 0); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Buildable returns, per warehouse, how many bundles its component stock can
+// assemble; warehouses that cannot build one are omitted
+func (r *bundleRepo) Buildable(ctx context.Context, tenantID, bundleID uuid.UUID) ([]*models.WarehouseAvailability, error) {
+	query := `
+		SELECT w.id, w.name, w.is_default, MIN(COALESCE(i.qty, 0) / bc.quantity)::int AS buildable
+		FROM product_bundle_components bc
+		JOIN warehouses w ON w.tenant_id = bc.tenant_id
+		LEFT JOIN (
+			SELECT warehouse_id, product_id, SUM(quantity) AS qty
+			FROM inventory
+			WHERE tenant_id = $1
+			GROUP BY warehouse_id, product_id
+		) i ON i.warehouse_id = w.id AND i.product_id = bc.component_id
+		WHERE bc.tenant_id = $1 AND bc.bundle_id = $2
+		GROUP BY w.id, w.name, w.is_default
+		HAVING MIN(COALESCE(i.qty, 0) / bc.quantity) > 0
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.WarehouseAvailability
+	for rows.Next() {
+		wa := &models.WarehouseAvailability{}
+		if err := rows.Scan(&wa.WarehouseID, &wa.WarehouseName, &wa.IsDefault, &wa.Buildable); err != nil {
+			return nil, err
+		}
+		result = append(result, wa)
+	}
+	return result, rows.Err()
+}
+
+func (r *bundleRepo) CreateSale(ctx context.Context, sale *models.BundleSale) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO bundle_sales (order_id, tenant_id, bundle_id, warehouse_id, bundle_units, exploded_units, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING created_at
+	`
+	if err := tx.QueryRow(ctx, query, sale.OrderID, sale.TenantID, sale.BundleID, sale.WarehouseID, sale.BundleUnits, sale.ExplodedUnits).Scan(&sale.CreatedAt); err != nil {
+		return err
+	}
+	for _, component := range sale.Components {
+		query := `INSERT INTO bundle_sale_components (order_id, component_id, quantity) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(ctx, query, sale.OrderID, component.ComponentID, component.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetSale returns how a bundle order drew stock, or nil when the order was not a bundle sale
+func (r *bundleRepo) GetSale(ctx context.Context, tenantID, orderID uuid.UUID) (*models.BundleSale, error) {
+	sale := &models.BundleSale{}
+	query := `
+		SELECT order_id, tenant_id, bundle_id, warehouse_id, bundle_units, exploded_units, created_at
+		FROM bundle_sales
+		WHERE tenant_id = $1 AND order_id = $2
+	`
+	err := r.db.QueryRow(ctx, query, tenantID, orderID).Scan(&sale.OrderID, &sale.TenantID, &sale.BundleID, &sale.WarehouseID, &sale.BundleUnits, &sale.ExplodedUnits, &sale.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT component_id, quantity FROM bundle_sale_components WHERE order_id = $1`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		component := &models.BundleComponent{BundleID: sale.BundleID}
+		if err := rows.Scan(&component.ComponentID, &component.Quantity); err != nil {
+			return nil, err
+		}
+		sale.Components = append(sale.Components, component)
+	}
+	return sale, rows.Err()
+}
+
+func (r *bundleRepo) DeleteSale(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM bundle_sales WHERE tenant_id = $1 AND order_id = $2`, tenantID, orderID)
+	return err
+}
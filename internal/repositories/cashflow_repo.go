@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CashFlowRepository reads what the cash flow projection needs beyond open
+// invoices: how late customers pay, purchase orders still to be paid for and
+// recent running costs
+type CashFlowRepository interface {
+	CustomerPaymentDelays(ctx context.Context, tenantID uuid.UUID, since time.Time) (map[uuid.UUID]float64, float64, error)
+	OpenPurchaseOrders(ctx context.Context, tenantID uuid.UUID, receivedSince time.Time) ([]*models.CashFlowPayable, error)
+	MonthlyExpenses(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.CashFlowExpenseMonth, error)
+}
+
+type cashFlowRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCashFlowRepo(db *pgxpool.Pool) CashFlowRepository {
+	return &cashFlowRepo{db: db}
+}
+
+// CustomerPaymentDelays averages how many days after the due date invoices
+// paid since the cutoff were settled, per customer and across the tenant.
+// Early payments count as negative delays
+func (r *cashFlowRepo) CustomerPaymentDelays(ctx context.Context, tenantID uuid.UUID, since time.Time) (map[uuid.UUID]float64, float64, error) {
+	query := `
+		SELECT o.distributor_id, AVG(i.paid_date::date - i.due_date::date)::float8
+		FROM invoices i
+		JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		WHERE i.tenant_id = $1 AND i.status = 'paid' AND i.paid_date IS NOT NULL AND i.due_date IS NOT NULL
+			AND i.paid_date >= $2 AND o.distributor_id IS NOT NULL
+		GROUP BY ROLLUP (o.distributor_id)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, since)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	delays := make(map[uuid.UUID]float64)
+	var overall float64
+	for rows.Next() {
+		var distributorID *uuid.UUID
+		var delay float64
+		if err := rows.Scan(&distributorID, &delay); err != nil {
+			return nil, 0, err
+		}
+		if distributorID == nil {
+			overall = delay
+			continue
+		}
+		delays[*distributorID] = delay
+	}
+	return delays, overall, rows.Err()
+}
+
+// OpenPurchaseOrders lists purchase orders still to be paid for: those not yet
+// delivered and those received since the cutoff, when the supplier may still
+// be waiting on payment. Undelivered orders are expected on their expected
+// delivery date, falling back to the order date
+func (r *cashFlowRepo) OpenPurchaseOrders(ctx context.Context, tenantID uuid.UUID, receivedSince time.Time) ([]*models.CashFlowPayable, error) {
+	query := `
+		SELECT id, status, amount, expected, received_at
+		FROM (
+			SELECT o.id, o.status, (o.quantity * o.unit_price)::float8 AS amount,
+				COALESCE(o.expected_delivery, o.order_date)::timestamptz AS expected,
+				CASE WHEN o.status = 'delivered' THEN COALESCE(
+					(SELECT MAX(pr.received_at) FROM purchase_receipt_lines prl
+						JOIN purchase_receipts pr ON pr.id = prl.receipt_id
+						WHERE prl.order_id = o.id),
+					o.updated_at) END AS received_at
+			FROM orders o
+			WHERE o.tenant_id = $1 AND o.order_type = 'purchase' AND o.status <> 'cancelled'
+		) orders
+		WHERE received_at IS NULL OR received_at >= $2
+		ORDER BY expected
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, receivedSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payables []*models.CashFlowPayable
+	for rows.Next() {
+		p := &models.CashFlowPayable{}
+		if err := rows.Scan(&p.OrderID, &p.Status, &p.Amount, &p.ExpectedDelivery, &p.ReceivedAt); err != nil {
+			return nil, err
+		}
+		payables = append(payables, p)
+	}
+	return payables, rows.Err()
+}
+
+// MonthlyExpenses totals expenses by category and month for months starting
+// in [from, to)
+func (r *cashFlowRepo) MonthlyExpenses(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.CashFlowExpenseMonth, error) {
+	query := `
+		SELECT e.category_id, c.name, e.period_month, SUM(e.amount)::float8
+		FROM expenses e
+		JOIN expense_categories c ON c.id = e.category_id
+		WHERE e.tenant_id = $1 AND e.period_month >= $2 AND e.period_month < $3
+		GROUP BY e.category_id, c.name, e.period_month
+		ORDER BY c.name, e.period_month
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []*models.CashFlowExpenseMonth
+	for rows.Next() {
+		m := &models.CashFlowExpenseMonth{}
+		if err := rows.Scan(&m.CategoryID, &m.CategoryName, &m.Month, &m.Amount); err != nil {
+			return nil, err
+		}
+		months = append(months, m)
+	}
+	return months, rows.Err()
+}
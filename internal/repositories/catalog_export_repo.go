@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CatalogExportRepository interface {
+	Create(ctx context.Context, export *models.CatalogExport) error
+	UpdateProgress(ctx context.Context, id uuid.UUID, total, done int) error
+	Finish(ctx context.Context, export *models.CatalogExport) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.CatalogExport, error)
+	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.CatalogExport, error)
+}
+
+type catalogExportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCatalogExportRepo(db *pgxpool.Pool) CatalogExportRepository {
+	return &catalogExportRepo{db: db}
+}
+
+func (r *catalogExportRepo) Create(ctx context.Context, export *models.CatalogExport) error {
+	query := `
+		INSERT INTO catalog_exports (id, tenant_id, title, price_list_id, status, requested_by, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING started_at
+	`
+	return r.db.QueryRow(ctx, query, export.ID, export.TenantID, export.Title, export.PriceListID, export.Status, export.RequestedBy).Scan(&export.StartedAt)
+}
+
+func (r *catalogExportRepo) UpdateProgress(ctx context.Context, id uuid.UUID, total, done int) error {
+	query := `UPDATE catalog_exports SET products_total = $1, products_done = $2 WHERE id = $3`
+	_, err := r.db.Exec(ctx, query, total, done, id)
+	return err
+}
+
+func (r *catalogExportRepo) Finish(ctx context.Context, export *models.CatalogExport) error {
+	query := `
+		UPDATE catalog_exports
+		SET status = $1, products_total = $2, products_done = $3, file_name = $4, object_key = $5, error_message = $6, finished_at = NOW()
+		WHERE id = $7
+		RETURNING finished_at
+	`
+	return r.db.QueryRow(ctx, query, export.Status, export.ProductsTotal, export.ProductsDone, export.FileName, export.ObjectKey, export.ErrorMessage, export.ID).Scan(&export.FinishedAt)
+}
+
+const catalogExportColumns = `id, tenant_id, title, price_list_id, status, products_total, products_done, file_name, object_key, error_message, requested_by, started_at, finished_at`
+
+func scanCatalogExport(row rowScanner) (*models.CatalogExport, error) {
+	export := &models.CatalogExport{}
+	err := row.Scan(&export.ID, &export.TenantID, &export.Title, &export.PriceListID, &export.Status, &export.ProductsTotal, &export.ProductsDone,
+		&export.FileName, &export.ObjectKey, &export.ErrorMessage, &export.RequestedBy, &export.StartedAt, &export.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (r *catalogExportRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.CatalogExport, error) {
+	query := `SELECT ` + catalogExportColumns + ` FROM catalog_exports WHERE tenant_id = $1 AND id = $2`
+	return scanCatalogExport(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+func (r *catalogExportRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.CatalogExport, error) {
+	query := `
+		SELECT ` + catalogExportColumns + `
+		FROM catalog_exports
+		WHERE tenant_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*models.CatalogExport
+	for rows.Next() {
+		export, err := scanCatalogExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
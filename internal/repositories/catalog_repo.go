@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CatalogRepository interface {
+	CreateToken(ctx context.Context, token *models.CatalogToken, tokenHash string) error
+	ListTokens(ctx context.Context, tenantID uuid.UUID) ([]*models.CatalogToken, error)
+	RevokeToken(ctx context.Context, tenantID, id uuid.UUID) error
+	GetTokenByHash(ctx context.Context, tokenHash string) (*models.CatalogToken, error)
+	TouchToken(ctx context.Context, id uuid.UUID) error
+	SetPublished(ctx context.Context, tenantID, productID uuid.UUID, published bool) error
+	ListPublished(ctx context.Context, tenantID uuid.UUID, categoryID *uuid.UUID, lowStockThreshold, limit, offset int) ([]*models.CatalogProduct, error)
+	GetPublished(ctx context.Context, tenantID, productID uuid.UUID, lowStockThreshold int) (*models.CatalogProduct, error)
+	GetPublishedImage(ctx context.Context, tenantID, imageID uuid.UUID) (*models.ProductImage, error)
+}
+
+type catalogRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCatalogRepo(db *pgxpool.Pool) CatalogRepository {
+	return &catalogRepo{db: db}
+}
+
+func (r *catalogRepo) CreateToken(ctx context.Context, token *models.CatalogToken, tokenHash string) error {
+	query := `
+		INSERT INTO catalog_tokens (id, tenant_id, name, token_hash, token_prefix, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, token.ID, token.TenantID, token.Name, tokenHash, token.TokenPrefix, token.CreatedBy).Scan(&token.CreatedAt)
+}
+
+func (r *catalogRepo) ListTokens(ctx context.Context, tenantID uuid.UUID) ([]*models.CatalogToken, error) {
+	query := `
+		SELECT id, tenant_id, name, token_prefix, created_by, created_at, last_used_at, revoked_at
+		FROM catalog_tokens
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.CatalogToken
+	for rows.Next() {
+		token := &models.CatalogToken{}
+		if err := rows.Scan(&token.ID, &token.TenantID, &token.Name, &token.TokenPrefix, &token.CreatedBy, &token.CreatedAt, &token.LastUsedAt, &token.RevokedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (r *catalogRepo) RevokeToken(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `UPDATE catalog_tokens SET revoked_at = NOW() WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+// GetTokenByHash returns an unrevoked token
+func (r *catalogRepo) GetTokenByHash(ctx context.Context, tokenHash string) (*models.CatalogToken, error) {
+	token := &models.CatalogToken{}
+	query := `
+		SELECT id, tenant_id, name, token_prefix, created_by, created_at, last_used_at, revoked_at
+		FROM catalog_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL
+	`
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&token.ID, &token.TenantID, &token.Name, &token.TokenPrefix, &token.CreatedBy, &token.CreatedAt, &token.LastUsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *catalogRepo) TouchToken(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE catalog_tokens SET last_used_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *catalogRepo) SetPublished(ctx context.Context, tenantID, productID uuid.UUID, published bool) error {
+	query := `
+		UPDATE products
+		SET is_published = $1,
+			published_at = CASE WHEN $1 THEN COALESCE(published_at, NOW()) ELSE NULL END,
+			updated_at = NOW()
+		WHERE tenant_id = $2 AND id = $3
+	`
+	_, err := r.db.Exec(ctx, query, published, tenantID, productID)
+	return err
+}
+
+// catalogSelect aggregates stock across warehouses and images per product
+const catalogSelect = `
+	SELECT p.id, p.category_id, p.name, p.description, p.translations, p.unit_price, p.unit_of_measure, p.barcode,
+		COALESCE((SELECT SUM(i.quantity) FROM inventory i WHERE i.tenant_id = p.tenant_id AND i.product_id = p.id), 0)::int,
+		p.updated_at
+	FROM products p
+`
+
+func (r *catalogRepo) ListPublished(ctx context.Context, tenantID uuid.UUID, categoryID *uuid.UUID, lowStockThreshold, limit, offset int) ([]*models.CatalogProduct, error) {
+	query := catalogSelect + `
+		WHERE p.tenant_id = $1 AND p.is_published AND ($2::uuid IS NULL OR p.category_id = $2)
+		ORDER BY p.name, p.id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, categoryID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.CatalogProduct
+	for rows.Next() {
+		product, err := scanCatalogProduct(rows, lowStockThreshold)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachImages(ctx, tenantID, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *catalogRepo) GetPublished(ctx context.Context, tenantID, productID uuid.UUID, lowStockThreshold int) (*models.CatalogProduct, error) {
+	query := catalogSelect + `WHERE p.tenant_id = $1 AND p.id = $2 AND p.is_published`
+	product, err := scanCatalogProduct(r.db.QueryRow(ctx, query, tenantID, productID), lowStockThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.attachImages(ctx, tenantID, []*models.CatalogProduct{product}); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// GetPublishedImage returns an image only if its product is published
+func (r *catalogRepo) GetPublishedImage(ctx context.Context, tenantID, imageID uuid.UUID) (*models.ProductImage, error) {
+	image := &models.ProductImage{}
+	query := `
+		SELECT pi.id, pi.tenant_id, pi.product_id, pi.image_url, pi.alt_text, pi.created_at
+		FROM product_images pi
+		JOIN products p ON p.tenant_id = pi.tenant_id AND p.id = pi.product_id
+		WHERE pi.tenant_id = $1 AND pi.id = $2 AND p.is_published
+	`
+	err := r.db.QueryRow(ctx, query, tenantID, imageID).Scan(&image.ID, &image.TenantID, &image.ProductID, &image.ImageURL, &image.AltText, &image.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+func (r *catalogRepo) attachImages(ctx context.Context, tenantID uuid.UUID, products []*models.CatalogProduct) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*models.CatalogProduct, len(products))
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		product.Images = []models.CatalogImage{}
+		byID[product.ID] = product
+		ids = append(ids, product.ID)
+	}
+
+	query := `
+		SELECT id, product_id, alt_text
+		FROM product_images
+		WHERE tenant_id = $1 AND product_id = ANY($2)
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var image models.CatalogImage
+		var productID uuid.UUID
+		if err := rows.Scan(&image.ID, &productID, &image.AltText); err != nil {
+			return err
+		}
+		if product, ok := byID[productID]; ok {
+			product.Images = append(product.Images, image)
+		}
+	}
+	return rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCatalogProduct(row rowScanner, lowStockThreshold int) (*models.CatalogProduct, error) {
+	product := &models.CatalogProduct{}
+	var stock int
+	var updatedAt time.Time
+	if err := row.Scan(&product.ID, &product.CategoryID, &product.Name, &product.Description, &product.Translations, &product.UnitPrice,
+		&product.UnitOfMeasure, &product.Barcode, &stock, &updatedAt); err != nil {
+		return nil, err
+	}
+	product.UpdatedAt = updatedAt
+
+	switch {
+	case stock <= 0:
+		product.Availability = models.CatalogOutOfStock
+	case stock <= lowStockThreshold:
+		product.Availability = models.CatalogLowStock
+	default:
+		product.Availability = models.CatalogInStock
+	}
+	return product, nil
+}
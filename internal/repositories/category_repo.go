@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 
 	"github.com/google/uuid"
@@ -12,9 +14,11 @@ import (
 type CategoryRepository interface {
 	Create(ctx context.Context, category *models.Category) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Category, error)
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Category, error)
 	Update(ctx context.Context, category *models.Category) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Category, error)
+	ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Category, error)
 	Search(ctx context.Context, tenantID uuid.UUID, query string, limit, offset int) ([]*models.Category, error)
 	ListSubcategories(ctx context.Context, tenantID, parentID uuid.UUID, limit, offset int) ([]*models.Category, error)
 	ListRootCategories(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Category, error)
@@ -73,6 +77,31 @@ func (r *categoryRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*mo
 	return category, nil
 }
 
+// GetByIDs loads the tenant's categories among ids in one query; unknown IDs are skipped
+func (r *categoryRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Category, error) {
+	query := `
+		SELECT id, tenant_id, name, description, parent_id, level, path, created_at, updated_at
+		FROM categories
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		category := &models.Category{}
+		if err := rows.Scan(&category.ID, &category.TenantID, &category.Name, &category.Description,
+			&category.ParentID, &category.Level, &category.Path, &category.CreatedAt, &category.UpdatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
 func (r *categoryRepo) Update(ctx context.Context, category *models.Category) error {
 	// Recalculate level and path if parent_id changed
 	if category.ParentID != nil {
@@ -92,9 +121,10 @@ func (r *categoryRepo) Update(ctx context.Context, category *models.Category) er
 		SET name = $1, description = $2, parent_id = $3, level = $4, path = $5, updated_at = NOW()
 		WHERE tenant_id = $6 AND id = $7
 	`
-	_, err := r.db.Exec(ctx, query, category.Name, category.Description, category.ParentID,
-		category.Level, category.Path, category.TenantID, category.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, category.ID, "updated_at", query, []interface{}{category.Name, category.Description, category.ParentID,
+		category.Level, category.Path, category.TenantID, category.ID})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *categoryRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -103,15 +133,28 @@ func (r *categoryRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	return err
 }
 
+// CategorySortColumns whitelists the fields the category list can be sorted by
+var CategorySortColumns = map[string]string{
+	"name":       "name",
+	"level":      "level",
+	"path":       "path",
+	"created_at": "created_at",
+}
+
 func (r *categoryRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Category, error) {
-	query := `
+	return r.ListPage(ctx, tenantID, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists categories in the page's sort order, in tree order by default
+func (r *categoryRepo) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Category, error) {
+	query := fmt.Sprintf(`
 		SELECT id, tenant_id, name, description, parent_id, level, path, created_at, updated_at
 		FROM categories
 		WHERE tenant_id = $1
-		ORDER BY level ASC, path ASC
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("level ASC, path ASC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,527 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CommissionRepository interface {
+	ListAgents(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionAgent, error)
+	GetAgent(ctx context.Context, tenantID, id uuid.UUID) (*models.CommissionAgent, error)
+	CreateAgent(ctx context.Context, agent *models.CommissionAgent) error
+	UpdateAgent(ctx context.Context, agent *models.CommissionAgent) (bool, error)
+	SetCustomerAgent(ctx context.Context, tenantID, distributorID uuid.UUID, agentID *uuid.UUID) (bool, error)
+
+	ListRules(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRule, error)
+	GetRule(ctx context.Context, tenantID, id uuid.UUID) (*models.CommissionRule, error)
+	// CreateRule and UpdateRule report false when the payee already has
+	// another active rule
+	CreateRule(ctx context.Context, rule *models.CommissionRule) (bool, error)
+	UpdateRule(ctx context.Context, rule *models.CommissionRule) (bool, error)
+
+	// Documents returns every document that counts towards commission for
+	// the month, once for each payee of the customer it belongs to
+	Documents(ctx context.Context, tenantID uuid.UUID, month time.Time) ([]*models.CommissionItem, error)
+
+	ListRuns(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRun, error)
+	GetRun(ctx context.Context, tenantID, id uuid.UUID) (*models.CommissionRun, error)
+	// SaveRun replaces the month's draft run and reports false when the month
+	// has already been finalized
+	SaveRun(ctx context.Context, run *models.CommissionRun, items []*models.CommissionItem) (bool, error)
+	// FinalizeRun reports false when the run is not a draft of the tenant
+	FinalizeRun(ctx context.Context, tenantID, id uuid.UUID, finalizedBy *uuid.UUID) (bool, error)
+	RunItems(ctx context.Context, runID uuid.UUID, payeeType string, payeeID uuid.UUID) ([]*models.CommissionItem, error)
+}
+
+type commissionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCommissionRepo(db *pgxpool.Pool) CommissionRepository {
+	return &commissionRepo{db: db}
+}
+
+const (
+	commissionAgentColumns = `id, tenant_id, name, phone, email, pan, active, created_at, updated_at`
+	commissionRuleColumns  = `id, tenant_id, name, payee_type, payee_id, basis, active, created_at, updated_at`
+	commissionRunColumns   = `id, tenant_id, period_month, status, total_commission::float8, created_by, created_at,
+		finalized_by, finalized_at`
+	commissionLineColumns = `id, run_id, payee_type, payee_id, payee_name, rule_id, basis, invoiced_amount::float8,
+		released_amount::float8, returns_amount::float8, withheld_amount::float8, collected_amount::float8,
+		base_amount::float8, commission_amount::float8`
+	commissionItemColumns = `payee_type, payee_id, payee_name, kind, document_id, document_number, document_date,
+		amount::float8`
+)
+
+func scanCommissionAgent(row rowScanner) (*models.CommissionAgent, error) {
+	a := &models.CommissionAgent{}
+	err := row.Scan(&a.ID, &a.TenantID, &a.Name, &a.Phone, &a.Email, &a.PAN, &a.Active, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func scanCommissionRule(row rowScanner) (*models.CommissionRule, error) {
+	r := &models.CommissionRule{}
+	err := row.Scan(&r.ID, &r.TenantID, &r.Name, &r.PayeeType, &r.PayeeID, &r.Basis, &r.Active, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func scanCommissionRun(row rowScanner) (*models.CommissionRun, error) {
+	r := &models.CommissionRun{}
+	err := row.Scan(&r.ID, &r.TenantID, &r.Month, &r.Status, &r.TotalCommission, &r.CreatedBy, &r.CreatedAt,
+		&r.FinalizedBy, &r.FinalizedAt)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func scanCommissionLine(row rowScanner) (*models.CommissionLine, error) {
+	l := &models.CommissionLine{}
+	err := row.Scan(&l.ID, &l.RunID, &l.PayeeType, &l.PayeeID, &l.PayeeName, &l.RuleID, &l.Basis, &l.InvoicedAmount,
+		&l.ReleasedAmount, &l.ReturnsAmount, &l.WithheldAmount, &l.CollectedAmount, &l.BaseAmount, &l.CommissionAmount)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func scanCommissionItem(row rowScanner) (*models.CommissionItem, error) {
+	i := &models.CommissionItem{}
+	err := row.Scan(&i.PayeeType, &i.PayeeID, &i.PayeeName, &i.Kind, &i.DocumentID, &i.DocumentNumber, &i.DocumentDate,
+		&i.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *commissionRepo) ListAgents(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionAgent, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+commissionAgentColumns+` FROM commission_agents WHERE tenant_id = $1 ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []*models.CommissionAgent
+	for rows.Next() {
+		agent, err := scanCommissionAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+	return agents, rows.Err()
+}
+
+func (r *commissionRepo) GetAgent(ctx context.Context, tenantID, id uuid.UUID) (*models.CommissionAgent, error) {
+	query := `SELECT ` + commissionAgentColumns + ` FROM commission_agents WHERE tenant_id = $1 AND id = $2`
+	agent, err := scanCommissionAgent(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return agent, err
+}
+
+func (r *commissionRepo) CreateAgent(ctx context.Context, agent *models.CommissionAgent) error {
+	query := `
+		INSERT INTO commission_agents (id, tenant_id, name, phone, email, pan, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, agent.ID, agent.TenantID, agent.Name, agent.Phone, agent.Email, agent.PAN,
+		agent.Active).Scan(&agent.CreatedAt, &agent.UpdatedAt)
+}
+
+func (r *commissionRepo) UpdateAgent(ctx context.Context, agent *models.CommissionAgent) (bool, error) {
+	query := `
+		UPDATE commission_agents SET name = $3, phone = $4, email = $5, pan = $6, active = $7, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, agent.TenantID, agent.ID, agent.Name, agent.Phone, agent.Email, agent.PAN,
+		agent.Active).Scan(&agent.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SetCustomerAgent reports false for customers outside the tenant
+func (r *commissionRepo) SetCustomerAgent(ctx context.Context, tenantID, distributorID uuid.UUID, agentID *uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE distributors SET commission_agent_id = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`,
+		tenantID, distributorID, agentID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *commissionRepo) ListRules(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRule, error) {
+	query := `SELECT ` + commissionRuleColumns + ` FROM commission_rules WHERE tenant_id = $1 ORDER BY payee_type, payee_id NULLS FIRST, name`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.CommissionRule
+	byID := make(map[uuid.UUID]*models.CommissionRule)
+	for rows.Next() {
+		rule, err := scanCommissionRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+		byID[rule.ID] = rule
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	slabRows, err := r.db.Query(ctx, `
+		SELECT s.rule_id, s.from_amount::float8, s.rate_percent::float8
+		FROM commission_rule_slabs s
+		JOIN commission_rules cr ON cr.id = s.rule_id
+		WHERE cr.tenant_id = $1
+		ORDER BY s.rule_id, s.from_amount
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer slabRows.Close()
+
+	for slabRows.Next() {
+		var ruleID uuid.UUID
+		var slab models.CommissionSlab
+		if err := slabRows.Scan(&ruleID, &slab.FromAmount, &slab.RatePercent); err != nil {
+			return nil, err
+		}
+		if rule, ok := byID[ruleID]; ok {
+			rule.Slabs = append(rule.Slabs, slab)
+		}
+	}
+	return rules, slabRows.Err()
+}
+
+func (r *commissionRepo) GetRule(ctx context.Context, tenantID, id uuid.UUID) (*models.CommissionRule, error) {
+	query := `SELECT ` + commissionRuleColumns + ` FROM commission_rules WHERE tenant_id = $1 AND id = $2`
+	rule, err := scanCommissionRule(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT from_amount::float8, rate_percent::float8 FROM commission_rule_slabs WHERE rule_id = $1 ORDER BY from_amount`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slab models.CommissionSlab
+		if err := rows.Scan(&slab.FromAmount, &slab.RatePercent); err != nil {
+			return nil, err
+		}
+		rule.Slabs = append(rule.Slabs, slab)
+	}
+	return rule, rows.Err()
+}
+
+func insertCommissionSlabs(ctx context.Context, tx pgx.Tx, rule *models.CommissionRule) error {
+	for _, slab := range rule.Slabs {
+		query := `INSERT INTO commission_rule_slabs (rule_id, from_amount, rate_percent) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(ctx, query, rule.ID, slab.FromAmount, slab.RatePercent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *commissionRepo) CreateRule(ctx context.Context, rule *models.CommissionRule) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO commission_rules (id, tenant_id, name, payee_type, payee_id, basis, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	err = tx.QueryRow(ctx, query, rule.ID, rule.TenantID, rule.Name, rule.PayeeType, rule.PayeeID, rule.Basis,
+		rule.Active).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := insertCommissionSlabs(ctx, tx, rule); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *commissionRepo) UpdateRule(ctx context.Context, rule *models.CommissionRule) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE commission_rules cr SET name = $3, basis = $4, active = $5, updated_at = NOW()
+		WHERE cr.tenant_id = $1 AND cr.id = $2
+			AND NOT ($5 AND EXISTS (
+				SELECT 1 FROM commission_rules o
+				WHERE o.tenant_id = cr.tenant_id AND o.id <> cr.id AND o.active
+					AND o.payee_type = cr.payee_type AND o.payee_id IS NOT DISTINCT FROM cr.payee_id
+			))
+		RETURNING updated_at
+	`
+	err = tx.QueryRow(ctx, query, rule.TenantID, rule.ID, rule.Name, rule.Basis, rule.Active).Scan(&rule.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM commission_rule_slabs WHERE rule_id = $1`, rule.ID); err != nil {
+		return false, err
+	}
+	if err := insertCommissionSlabs(ctx, tx, rule); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Documents lists, for each payee of the customer:
+//   - invoices issued in the month, withheld when unpaid past their due date
+//   - invoices withheld by an earlier finalized run that have since been paid
+//     and not yet released by another finalized run
+//   - credit notes issued in the month, as returns
+//   - active payment allocations made in the month, as collections
+func (r *commissionRepo) Documents(ctx context.Context, tenantID uuid.UUID, month time.Time) ([]*models.CommissionItem, error) {
+	query := `
+		WITH payees AS (
+			SELECT d.id AS distributor_id, p.payee_type, p.payee_id, COALESCE(p.payee_name, '') AS payee_name
+			FROM distributors d
+			CROSS JOIN LATERAL (VALUES
+				('user', d.sales_rep_id, (SELECT TRIM(u.first_name || ' ' || u.last_name) FROM users u WHERE u.id = d.sales_rep_id)),
+				('agent', d.commission_agent_id, (SELECT a.name FROM commission_agents a WHERE a.id = d.commission_agent_id))
+			) AS p(payee_type, payee_id, payee_name)
+			WHERE d.tenant_id = $1 AND p.payee_id IS NOT NULL
+		)
+		SELECT py.payee_type, py.payee_id, py.payee_name,
+			CASE WHEN i.status = 'overdue' OR (i.status = 'unpaid' AND i.due_date < CURRENT_DATE) THEN 'withheld' ELSE 'invoice' END,
+			i.id, i.invoice_number, i.issued_date, COALESCE(i.taxable_amount, i.total_amount)::float8
+		FROM invoices i
+		JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		JOIN payees py ON py.distributor_id = o.distributor_id
+		WHERE i.tenant_id = $1 AND i.status <> 'cancelled'
+			AND i.issued_date >= $2::date AND i.issued_date < ($2::date + INTERVAL '1 month')
+		UNION ALL
+		SELECT w.payee_type, w.payee_id, w.payee_name, 'released', i.id, i.invoice_number, i.issued_date, w.amount::float8
+		FROM commission_run_items w
+		JOIN commission_runs cr ON cr.id = w.run_id
+		JOIN invoices i ON i.id = w.document_id
+		WHERE cr.tenant_id = $1 AND cr.status = 'finalized' AND cr.period_month < $2::date
+			AND w.kind = 'withheld' AND i.status = 'paid'
+			AND NOT EXISTS (
+				SELECT 1 FROM commission_run_items rel
+				JOIN commission_runs rr ON rr.id = rel.run_id
+				WHERE rr.tenant_id = $1 AND rr.status = 'finalized' AND rel.kind = 'released'
+					AND rel.document_id = w.document_id AND rel.payee_type = w.payee_type AND rel.payee_id = w.payee_id
+			)
+		UNION ALL
+		SELECT py.payee_type, py.payee_id, py.payee_name, 'return', cn.id, cn.credit_note_number, cn.issued_date, cn.amount::float8
+		FROM credit_notes cn
+		JOIN payees py ON py.distributor_id = cn.distributor_id
+		WHERE cn.tenant_id = $1
+			AND cn.issued_date >= $2::date AND cn.issued_date < ($2::date + INTERVAL '1 month')
+		UNION ALL
+		SELECT py.payee_type, py.payee_id, py.payee_name, 'collection', pa.id, cp.payment_number || ' / ' || i.invoice_number,
+			pa.allocated_at::date, pa.amount::float8
+		FROM payment_allocations pa
+		JOIN customer_payments cp ON cp.id = pa.payment_id
+		JOIN invoices i ON i.id = pa.invoice_id
+		JOIN payees py ON py.distributor_id = cp.distributor_id
+		WHERE pa.tenant_id = $1 AND pa.reversed_at IS NULL
+			AND pa.allocated_at >= $2::date AND pa.allocated_at < ($2::date + INTERVAL '1 month')
+		ORDER BY 1, 2, 7, 6
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.CommissionItem
+	for rows.Next() {
+		item, err := scanCommissionItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *commissionRepo) ListRuns(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRun, error) {
+	query := `SELECT ` + commissionRunColumns + ` FROM commission_runs WHERE tenant_id = $1 ORDER BY period_month DESC`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*models.CommissionRun
+	for rows.Next() {
+		run, err := scanCommissionRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetRun returns the run with its lines, largest commission first
+func (r *commissionRepo) GetRun(ctx context.Context, tenantID, id uuid.UUID) (*models.CommissionRun, error) {
+	query := `SELECT ` + commissionRunColumns + ` FROM commission_runs WHERE tenant_id = $1 AND id = $2`
+	run, err := scanCommissionRun(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT `+commissionLineColumns+` FROM commission_run_lines WHERE run_id = $1
+		ORDER BY commission_amount DESC, payee_name`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line, err := scanCommissionLine(rows)
+		if err != nil {
+			return nil, err
+		}
+		run.Lines = append(run.Lines, line)
+	}
+	return run, rows.Err()
+}
+
+func (r *commissionRepo) SaveRun(ctx context.Context, run *models.CommissionRun, items []*models.CommissionItem) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM commission_runs WHERE tenant_id = $1 AND period_month = $2::date AND status = 'draft'`,
+		run.TenantID, run.Month); err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO commission_runs (id, tenant_id, period_month, status, total_commission, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT DO NOTHING
+		RETURNING created_at
+	`
+	err = tx.QueryRow(ctx, query, run.ID, run.TenantID, run.Month, run.Status, run.TotalCommission,
+		run.CreatedBy).Scan(&run.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, line := range run.Lines {
+		query := `
+			INSERT INTO commission_run_lines (id, run_id, payee_type, payee_id, payee_name, rule_id, basis, invoiced_amount,
+				released_amount, returns_amount, withheld_amount, collected_amount, base_amount, commission_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		`
+		if _, err := tx.Exec(ctx, query, line.ID, run.ID, line.PayeeType, line.PayeeID, line.PayeeName, line.RuleID,
+			line.Basis, line.InvoicedAmount, line.ReleasedAmount, line.ReturnsAmount, line.WithheldAmount,
+			line.CollectedAmount, line.BaseAmount, line.CommissionAmount); err != nil {
+			return false, err
+		}
+	}
+	for _, item := range items {
+		query := `
+			INSERT INTO commission_run_items (run_id, payee_type, payee_id, payee_name, kind, document_id, document_number,
+				document_date, amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, query, run.ID, item.PayeeType, item.PayeeID, item.PayeeName, item.Kind, item.DocumentID,
+			item.DocumentNumber, item.DocumentDate, item.Amount); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *commissionRepo) FinalizeRun(ctx context.Context, tenantID, id uuid.UUID, finalizedBy *uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE commission_runs SET status = 'finalized', finalized_by = $3, finalized_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'draft'
+	`, tenantID, id, finalizedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *commissionRepo) RunItems(ctx context.Context, runID uuid.UUID, payeeType string, payeeID uuid.UUID) ([]*models.CommissionItem, error) {
+	query := `
+		SELECT ` + commissionItemColumns + `
+		FROM commission_run_items
+		WHERE run_id = $1 AND payee_type = $2 AND payee_id = $3
+		ORDER BY kind, document_date, document_number
+	`
+	rows, err := r.db.Query(ctx, query, runID, payeeType, payeeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.CommissionItem
+	for rows.Next() {
+		item, err := scanCommissionItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,341 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ComplianceRepository interface {
+	Create(ctx context.Context, license *models.ComplianceLicense) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ComplianceLicense, error)
+	List(ctx context.Context, tenantID uuid.UUID, filter *models.ComplianceLicenseFilter) ([]*models.ComplianceLicense, error)
+	Update(ctx context.Context, license *models.ComplianceLicense) error
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+	SetDocument(ctx context.Context, tenantID, id uuid.UUID, key, name, contentType string) error
+	MarkAlerted(ctx context.Context, id uuid.UUID, days int) error
+	HasValidLicense(ctx context.Context, tenantID uuid.UUID, holderType string, holderID uuid.UUID, licenseType string) (bool, error)
+	SetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID, licenseType *string) (bool, error)
+	ProductRegulation(ctx context.Context, tenantID, productID uuid.UUID) (licenseType, regulatoryFlag *string, err error)
+	SetRegulatoryFlag(ctx context.Context, tenantID, productID uuid.UUID, flag *string) (bool, error)
+	LatestHolderLicense(ctx context.Context, tenantID uuid.UUID, holderType string, holderID uuid.UUID, licenseType *string) (*models.ComplianceLicense, error)
+	SaveOrderBuyerLicense(ctx context.Context, capture *models.OrderBuyerLicense) error
+	GetOrderBuyerLicense(ctx context.Context, tenantID, orderID uuid.UUID) (*models.OrderBuyerLicense, error)
+	CreateOverride(ctx context.Context, override *models.RestrictedSaleOverride) error
+	ListOverrides(ctx context.Context, tenantID uuid.UUID, filter *models.RestrictedSaleOverrideFilter) ([]*models.RestrictedSaleOverride, error)
+	RestrictedTypes(ctx context.Context, tenantID uuid.UUID) ([]string, error)
+	OpenRestrictedPurchases(ctx context.Context, tenantID uuid.UUID) ([]*models.OpenRestrictedPurchase, error)
+}
+
+type complianceRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewComplianceRepo(db *pgxpool.Pool) ComplianceRepository {
+	return &complianceRepo{db: db}
+}
+
+// complianceLicenseColumns resolves the holder's display name from the table
+// matching holder_type
+const complianceLicenseColumns = `l.id, l.tenant_id, l.holder_type, l.holder_id,
+	COALESCE(CASE l.holder_type
+		WHEN 'tenant' THEN (SELECT t.name FROM tenants t WHERE t.id = l.holder_id)
+		WHEN 'supplier' THEN (SELECT s.name FROM suppliers s WHERE s.id = l.holder_id)
+		WHEN 'warehouse' THEN (SELECT w.name FROM warehouses w WHERE w.id = l.holder_id)
+		WHEN 'distributor' THEN (SELECT d.name FROM distributors d WHERE d.id = l.holder_id)
+	END, ''),
+	l.license_type, l.license_number, l.issuing_authority, l.valid_from, l.expires_on, l.document_key, l.document_name,
+	l.document_content_type, l.notes, l.last_alert_days, l.created_by, l.created_at, l.updated_at`
+
+func scanComplianceLicense(row rowScanner) (*models.ComplianceLicense, error) {
+	l := &models.ComplianceLicense{}
+	err := row.Scan(&l.ID, &l.TenantID, &l.HolderType, &l.HolderID, &l.HolderName, &l.LicenseType, &l.LicenseNumber, &l.IssuingAuthority,
+		&l.ValidFrom, &l.ExpiresOn, &l.DocumentKey, &l.DocumentName, &l.DocumentContentType, &l.Notes, &l.LastAlertDays,
+		&l.CreatedBy, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (r *complianceRepo) Create(ctx context.Context, license *models.ComplianceLicense) error {
+	query := `
+		INSERT INTO compliance_licenses (id, tenant_id, holder_type, holder_id, license_type, license_number, issuing_authority, valid_from, expires_on, notes, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, license.ID, license.TenantID, license.HolderType, license.HolderID, license.LicenseType, license.LicenseNumber,
+		license.IssuingAuthority, license.ValidFrom, license.ExpiresOn, license.Notes, license.CreatedBy).
+		Scan(&license.CreatedAt, &license.UpdatedAt)
+}
+
+func (r *complianceRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ComplianceLicense, error) {
+	query := `SELECT ` + complianceLicenseColumns + ` FROM compliance_licenses l WHERE l.tenant_id = $1 AND l.id = $2`
+	license, err := scanComplianceLicense(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return license, err
+}
+
+// List returns licenses soonest expiry first
+func (r *complianceRepo) List(ctx context.Context, tenantID uuid.UUID, filter *models.ComplianceLicenseFilter) ([]*models.ComplianceLicense, error) {
+	query := `
+		SELECT ` + complianceLicenseColumns + `
+		FROM compliance_licenses l
+		WHERE l.tenant_id = $1
+		  AND ($2::text IS NULL OR l.holder_type = $2)
+		  AND ($3::uuid IS NULL OR l.holder_id = $3)
+		  AND ($4::text IS NULL OR l.license_type = $4)
+		  AND ($5::date IS NULL OR l.expires_on <= $5)
+		ORDER BY l.expires_on, l.created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, filter.HolderType, filter.HolderID, filter.LicenseType, filter.ExpiringBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var licenses []*models.ComplianceLicense
+	for rows.Next() {
+		license, err := scanComplianceLicense(rows)
+		if err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, license)
+	}
+	return licenses, rows.Err()
+}
+
+// Update saves renewal details; a new expiry date clears the alert history so
+// the renewed license is alerted on again
+func (r *complianceRepo) Update(ctx context.Context, license *models.ComplianceLicense) error {
+	query := `
+		UPDATE compliance_licenses
+		SET license_number = $3, issuing_authority = $4, valid_from = $5, notes = $6,
+		    last_alert_days = CASE WHEN expires_on = $7 THEN last_alert_days ELSE NULL END,
+		    expires_on = $7, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, license.TenantID, license.ID, license.LicenseNumber, license.IssuingAuthority, license.ValidFrom,
+		license.Notes, license.ExpiresOn).Scan(&license.UpdatedAt)
+}
+
+func (r *complianceRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM compliance_licenses WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *complianceRepo) SetDocument(ctx context.Context, tenantID, id uuid.UUID, key, name, contentType string) error {
+	query := `
+		UPDATE compliance_licenses
+		SET document_key = $3, document_name = $4, document_content_type = $5, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+	`
+	_, err := r.db.Exec(ctx, query, tenantID, id, key, name, contentType)
+	return err
+}
+
+func (r *complianceRepo) MarkAlerted(ctx context.Context, id uuid.UUID, days int) error {
+	_, err := r.db.Exec(ctx, `UPDATE compliance_licenses SET last_alert_days = $2 WHERE id = $1`, id, days)
+	return err
+}
+
+// HasValidLicense reports whether the holder has a license of the type in
+// force today
+func (r *complianceRepo) HasValidLicense(ctx context.Context, tenantID uuid.UUID, holderType string, holderID uuid.UUID, licenseType string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM compliance_licenses
+			WHERE tenant_id = $1 AND holder_type = $2 AND holder_id = $3 AND license_type = $4
+			  AND expires_on >= CURRENT_DATE AND (valid_from IS NULL OR valid_from <= CURRENT_DATE)
+		)
+	`
+	var ok bool
+	err := r.db.QueryRow(ctx, query, tenantID, holderType, holderID, licenseType).Scan(&ok)
+	return ok, err
+}
+
+func (r *complianceRepo) SetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID, licenseType *string) (bool, error) {
+	query := `UPDATE products SET required_license_type = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`
+	tag, err := r.db.Exec(ctx, query, tenantID, productID, licenseType)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ProductRegulation returns the license type a product requires of the
+// tenant and its suppliers, and its regulatory flag for buyers
+func (r *complianceRepo) ProductRegulation(ctx context.Context, tenantID, productID uuid.UUID) (*string, *string, error) {
+	var licenseType, flag *string
+	query := `SELECT required_license_type, regulatory_flag FROM products WHERE tenant_id = $1 AND id = $2`
+	err := r.db.QueryRow(ctx, query, tenantID, productID).Scan(&licenseType, &flag)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	return licenseType, flag, err
+}
+
+func (r *complianceRepo) SetRegulatoryFlag(ctx context.Context, tenantID, productID uuid.UUID, flag *string) (bool, error) {
+	query := `UPDATE products SET regulatory_flag = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`
+	tag, err := r.db.Exec(ctx, query, tenantID, productID, flag)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// LatestHolderLicense returns the holder's license expiring last, optionally
+// of one type
+func (r *complianceRepo) LatestHolderLicense(ctx context.Context, tenantID uuid.UUID, holderType string, holderID uuid.UUID, licenseType *string) (*models.ComplianceLicense, error) {
+	query := `
+		SELECT ` + complianceLicenseColumns + `
+		FROM compliance_licenses l
+		WHERE l.tenant_id = $1 AND l.holder_type = $2 AND l.holder_id = $3
+		  AND ($4::text IS NULL OR l.license_type = $4)
+		ORDER BY l.expires_on DESC
+		LIMIT 1
+	`
+	license, err := scanComplianceLicense(r.db.QueryRow(ctx, query, tenantID, holderType, holderID, licenseType))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return license, err
+}
+
+// SaveOrderBuyerLicense captures the buyer license on an order, replacing any
+// earlier capture
+func (r *complianceRepo) SaveOrderBuyerLicense(ctx context.Context, capture *models.OrderBuyerLicense) error {
+	query := `
+		INSERT INTO order_buyer_licenses (order_id, tenant_id, license_id, license_number, license_expires_on, captured_by, captured_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (order_id) DO UPDATE
+		SET license_id = EXCLUDED.license_id, license_number = EXCLUDED.license_number,
+		    license_expires_on = EXCLUDED.license_expires_on, captured_by = EXCLUDED.captured_by, captured_at = NOW()
+		RETURNING captured_at
+	`
+	return r.db.QueryRow(ctx, query, capture.OrderID, capture.TenantID, capture.LicenseID, capture.LicenseNumber, capture.LicenseExpiresOn, capture.CapturedBy).
+		Scan(&capture.CapturedAt)
+}
+
+func (r *complianceRepo) GetOrderBuyerLicense(ctx context.Context, tenantID, orderID uuid.UUID) (*models.OrderBuyerLicense, error) {
+	query := `
+		SELECT order_id, tenant_id, license_id, license_number, license_expires_on, captured_by, captured_at
+		FROM order_buyer_licenses
+		WHERE tenant_id = $1 AND order_id = $2
+	`
+	capture := &models.OrderBuyerLicense{}
+	err := r.db.QueryRow(ctx, query, tenantID, orderID).Scan(&capture.OrderID, &capture.TenantID, &capture.LicenseID, &capture.LicenseNumber,
+		&capture.LicenseExpiresOn, &capture.CapturedBy, &capture.CapturedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return capture, nil
+}
+
+func (r *complianceRepo) CreateOverride(ctx context.Context, override *models.RestrictedSaleOverride) error {
+	query := `
+		INSERT INTO restricted_sale_overrides (id, tenant_id, order_id, product_id, distributor_id, regulatory_flag, license_id, license_problem, reason, user_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, override.ID, override.TenantID, override.OrderID, override.ProductID, override.DistributorID, override.RegulatoryFlag,
+		override.LicenseID, override.LicenseProblem, override.Reason, override.UserID).Scan(&override.CreatedAt)
+}
+
+// ListOverrides returns the override trail, newest first
+func (r *complianceRepo) ListOverrides(ctx context.Context, tenantID uuid.UUID, filter *models.RestrictedSaleOverrideFilter) ([]*models.RestrictedSaleOverride, error) {
+	query := `
+		SELECT id, tenant_id, order_id, product_id, distributor_id, regulatory_flag, license_id, license_problem, reason, user_id, created_at
+		FROM restricted_sale_overrides
+		WHERE tenant_id = $1
+		  AND ($2::timestamptz IS NULL OR created_at >= $2)
+		  AND ($3::timestamptz IS NULL OR created_at <= $3)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, filter.From, filter.To, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*models.RestrictedSaleOverride
+	for rows.Next() {
+		o := &models.RestrictedSaleOverride{}
+		if err := rows.Scan(&o.ID, &o.TenantID, &o.OrderID, &o.ProductID, &o.DistributorID, &o.RegulatoryFlag, &o.LicenseID, &o.LicenseProblem,
+			&o.Reason, &o.UserID, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// RestrictedTypes lists the license types at least one product requires
+func (r *complianceRepo) RestrictedTypes(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT DISTINCT required_license_type
+		FROM products
+		WHERE tenant_id = $1 AND required_license_type IS NOT NULL
+		ORDER BY required_license_type
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+// OpenRestrictedPurchases lists purchase orders not yet received for products
+// that require a license
+func (r *complianceRepo) OpenRestrictedPurchases(ctx context.Context, tenantID uuid.UUID) ([]*models.OpenRestrictedPurchase, error) {
+	query := `
+		SELECT o.id, s.id, s.name, p.required_license_type
+		FROM orders o
+		JOIN products p ON p.id = o.product_id
+		JOIN suppliers s ON s.id = o.supplier_id
+		WHERE o.tenant_id = $1 AND o.order_type = 'purchase'
+		  AND o.status IN ('pending', 'approved', 'processing', 'shipped')
+		  AND p.required_license_type IS NOT NULL
+		ORDER BY o.order_date
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var purchases []*models.OpenRestrictedPurchase
+	for rows.Next() {
+		p := &models.OpenRestrictedPurchase{}
+		if err := rows.Scan(&p.OrderID, &p.SupplierID, &p.SupplierName, &p.LicenseType); err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, p)
+	}
+	return purchases, rows.Err()
+}
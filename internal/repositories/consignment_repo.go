@@ -0,0 +1,317 @@
+package repositories
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ConsignmentRepository interface {
+	AddStock(ctx context.Context, tenantID, supplierID, warehouseID, productID uuid.UUID, quantity int, unitCost float64) error
+	RecordSale(ctx context.Context, tenantID uuid.UUID, order *models.Order) ([]*models.ConsignmentLiability, error)
+	ReverseSale(ctx context.Context, tenantID, orderID uuid.UUID) (int, error)
+	ListStock(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID) ([]*models.ConsignmentStock, error)
+	SettlementSummary(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) ([]*models.ConsignmentSettlementRow, error)
+	ListLiabilities(ctx context.Context, tenantID, supplierID uuid.UUID, from, to time.Time) ([]*models.ConsignmentLiability, error)
+	Settle(ctx context.Context, tenantID, supplierID uuid.UUID, upTo time.Time, reference string) (int, float64, time.Time, error)
+}
+
+type consignmentRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewConsignmentRepo(db *pgxpool.Pool) ConsignmentRepository {
+	return &consignmentRepo{db: db}
+}
+
+// AddStock adds received consignment units, averaging the supplier's unit cost
+// with any units already on hand
+func (r *consignmentRepo) AddStock(ctx context.Context, tenantID, supplierID, warehouseID, productID uuid.UUID, quantity int, unitCost float64) error {
+	query := `
+		INSERT INTO consignment_stock (tenant_id, supplier_id, warehouse_id, product_id, quantity, unit_cost, first_received_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (tenant_id, supplier_id, warehouse_id, product_id) DO UPDATE SET
+			unit_cost = ROUND((consignment_stock.quantity * consignment_stock.unit_cost + EXCLUDED.quantity * EXCLUDED.unit_cost)
+				/ (consignment_stock.quantity + EXCLUDED.quantity), 2),
+			first_received_at = CASE WHEN consignment_stock.quantity = 0 THEN NOW() ELSE consignment_stock.first_received_at END,
+			quantity = consignment_stock.quantity + EXCLUDED.quantity,
+			updated_at = NOW()
+	`
+	_, err := r.db.Exec(ctx, query, tenantID, supplierID, warehouseID, productID, quantity, unitCost)
+	return err
+}
+
+// RecordSale draws a sales order's units from consignment stock in the order
+// it was received and raises a liability to each supplier whose units were
+// sold. Units beyond the consignment on hand come from owned stock.
+func (r *consignmentRepo) RecordSale(ctx context.Context, tenantID uuid.UUID, order *models.Order) ([]*models.ConsignmentLiability, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT supplier_id, quantity, unit_cost::float8
+		FROM consignment_stock
+		WHERE tenant_id = $1 AND warehouse_id = $2 AND product_id = $3 AND quantity > 0
+		ORDER BY first_received_at, supplier_id
+		FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, query, tenantID, order.WarehouseID, order.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	type lot struct {
+		supplierID uuid.UUID
+		quantity   int
+		unitCost   float64
+	}
+	var lots []lot
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.supplierID, &l.quantity, &l.unitCost); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var liabilities []*models.ConsignmentLiability
+	remaining := order.Quantity
+	for _, l := range lots {
+		if remaining <= 0 {
+			break
+		}
+		take := l.quantity
+		if take > remaining {
+			take = remaining
+		}
+		remaining -= take
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE consignment_stock SET quantity = quantity - $5, updated_at = NOW()
+			WHERE tenant_id = $1 AND supplier_id = $2 AND warehouse_id = $3 AND product_id = $4
+		`, tenantID, l.supplierID, order.WarehouseID, order.ProductID, take); err != nil {
+			return nil, err
+		}
+
+		liability := &models.ConsignmentLiability{
+			ID:            uuid.New(),
+			TenantID:      tenantID,
+			SupplierID:    l.supplierID,
+			OrderID:       order.ID,
+			WarehouseID:   order.WarehouseID,
+			ProductID:     order.ProductID,
+			Quantity:      take,
+			UnitCost:      l.unitCost,
+			Amount:        math.Round(float64(take)*l.unitCost*100) / 100,
+			SaleUnitPrice: order.UnitPrice,
+			Status:        models.ConsignmentLiabilityOpen,
+		}
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO consignment_liabilities (id, tenant_id, supplier_id, order_id, warehouse_id, product_id, quantity, unit_cost, amount, sale_unit_price, status, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+			RETURNING created_at
+		`, liability.ID, tenantID, liability.SupplierID, liability.OrderID, liability.WarehouseID, liability.ProductID, liability.Quantity,
+			liability.UnitCost, liability.Amount, liability.SaleUnitPrice, liability.Status).Scan(&liability.CreatedAt); err != nil {
+			return nil, err
+		}
+		liabilities = append(liabilities, liability)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return liabilities, nil
+}
+
+// ReverseSale returns the consignment units of a cancelled sale to stock and
+// marks its open liabilities reversed; settled liabilities are left alone.
+// It returns the number of units returned to consignment stock.
+func (r *consignmentRepo) ReverseSale(ctx context.Context, tenantID, orderID uuid.UUID) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE consignment_liabilities SET status = 'reversed'
+		WHERE tenant_id = $1 AND order_id = $2 AND status = 'open'
+		RETURNING supplier_id, warehouse_id, product_id, quantity
+	`
+	rows, err := tx.Query(ctx, query, tenantID, orderID)
+	if err != nil {
+		return 0, err
+	}
+	type reversal struct {
+		supplierID, warehouseID, productID uuid.UUID
+		quantity                           int
+	}
+	var reversals []reversal
+	for rows.Next() {
+		var rv reversal
+		if err := rows.Scan(&rv.supplierID, &rv.warehouseID, &rv.productID, &rv.quantity); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		reversals = append(reversals, rv)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	returned := 0
+	for _, rv := range reversals {
+		if _, err := tx.Exec(ctx, `
+			UPDATE consignment_stock SET quantity = quantity + $5, updated_at = NOW()
+			WHERE tenant_id = $1 AND supplier_id = $2 AND warehouse_id = $3 AND product_id = $4
+		`, tenantID, rv.supplierID, rv.warehouseID, rv.productID, rv.quantity); err != nil {
+			return 0, err
+		}
+		returned += rv.quantity
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return returned, nil
+}
+
+// ListStock returns consignment units on hand, optionally for one supplier
+func (r *consignmentRepo) ListStock(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID) ([]*models.ConsignmentStock, error) {
+	query := `
+		SELECT cs.tenant_id, cs.supplier_id, s.name, cs.warehouse_id, cs.product_id, p.name, cs.quantity, cs.unit_cost::float8, cs.first_received_at, cs.updated_at
+		FROM consignment_stock cs
+		JOIN suppliers s ON s.id = cs.supplier_id
+		JOIN products p ON p.id = cs.product_id
+		WHERE cs.tenant_id = $1 AND cs.quantity > 0 AND ($2::uuid IS NULL OR cs.supplier_id = $2)
+		ORDER BY s.name, p.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stock []*models.ConsignmentStock
+	for rows.Next() {
+		cs := &models.ConsignmentStock{}
+		if err := rows.Scan(&cs.TenantID, &cs.SupplierID, &cs.SupplierName, &cs.WarehouseID, &cs.ProductID, &cs.ProductName, &cs.Quantity, &cs.UnitCost, &cs.FirstReceivedAt, &cs.UpdatedAt); err != nil {
+			return nil, err
+		}
+		stock = append(stock, cs)
+	}
+	return stock, rows.Err()
+}
+
+// SettlementSummary totals each supplier's consignment stock and liabilities;
+// reversed liabilities are excluded
+func (r *consignmentRepo) SettlementSummary(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) ([]*models.ConsignmentSettlementRow, error) {
+	query := `
+		SELECT s.id, s.name,
+			COALESCE(st.quantity, 0), COALESCE(st.value, 0),
+			COALESCE(l.sold, 0), COALESCE(l.sales, 0), COALESCE(l.liability, 0), COALESCE(l.settled, 0),
+			COALESCE(o.open, 0)
+		FROM suppliers s
+		LEFT JOIN (
+			SELECT supplier_id, SUM(quantity)::int AS quantity, SUM(quantity * unit_cost)::float8 AS value
+			FROM consignment_stock
+			WHERE tenant_id = $1 AND quantity > 0
+			GROUP BY supplier_id
+		) st ON st.supplier_id = s.id
+		LEFT JOIN (
+			SELECT supplier_id,
+				SUM(quantity)::int AS sold,
+				SUM(quantity * sale_unit_price)::float8 AS sales,
+				SUM(amount)::float8 AS liability,
+				COALESCE(SUM(amount) FILTER (WHERE status = 'settled'), 0)::float8 AS settled
+			FROM consignment_liabilities
+			WHERE tenant_id = $1 AND status <> 'reversed' AND created_at >= $2 AND created_at < $3
+			GROUP BY supplier_id
+		) l ON l.supplier_id = s.id
+		LEFT JOIN (
+			SELECT supplier_id, SUM(amount)::float8 AS open
+			FROM consignment_liabilities
+			WHERE tenant_id = $1 AND status = 'open'
+			GROUP BY supplier_id
+		) o ON o.supplier_id = s.id
+		WHERE s.tenant_id = $1 AND ($4::uuid IS NULL OR s.id = $4)
+		  AND (st.supplier_id IS NOT NULL OR l.supplier_id IS NOT NULL OR o.supplier_id IS NOT NULL)
+		ORDER BY COALESCE(o.open, 0) DESC, s.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summary []*models.ConsignmentSettlementRow
+	for rows.Next() {
+		row := &models.ConsignmentSettlementRow{}
+		if err := rows.Scan(&row.SupplierID, &row.SupplierName, &row.OnHandQuantity, &row.OnHandValue, &row.SoldQuantity, &row.SalesValue,
+			&row.LiabilityAmount, &row.SettledAmount, &row.OpenLiability); err != nil {
+			return nil, err
+		}
+		summary = append(summary, row)
+	}
+	return summary, rows.Err()
+}
+
+// ListLiabilities returns one supplier's liabilities raised in the window, oldest first
+func (r *consignmentRepo) ListLiabilities(ctx context.Context, tenantID, supplierID uuid.UUID, from, to time.Time) ([]*models.ConsignmentLiability, error) {
+	query := `
+		SELECT l.id, l.tenant_id, l.supplier_id, l.order_id, l.warehouse_id, l.product_id, COALESCE(p.name, ''), l.quantity, l.unit_cost::float8,
+			l.amount::float8, l.sale_unit_price::float8, l.status, l.settlement_reference, l.settled_at, l.created_at
+		FROM consignment_liabilities l
+		LEFT JOIN products p ON p.id = l.product_id
+		WHERE l.tenant_id = $1 AND l.supplier_id = $2 AND l.created_at >= $3 AND l.created_at < $4
+		ORDER BY l.created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, supplierID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var liabilities []*models.ConsignmentLiability
+	for rows.Next() {
+		l := &models.ConsignmentLiability{}
+		if err := rows.Scan(&l.ID, &l.TenantID, &l.SupplierID, &l.OrderID, &l.WarehouseID, &l.ProductID, &l.ProductName, &l.Quantity, &l.UnitCost,
+			&l.Amount, &l.SaleUnitPrice, &l.Status, &l.SettlementReference, &l.SettledAt, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		liabilities = append(liabilities, l)
+	}
+	return liabilities, rows.Err()
+}
+
+// Settle marks a supplier's open liabilities raised up to upTo as settled and
+// returns how many were settled, their total and the settlement time
+func (r *consignmentRepo) Settle(ctx context.Context, tenantID, supplierID uuid.UUID, upTo time.Time, reference string) (int, float64, time.Time, error) {
+	query := `
+		WITH settled AS (
+			UPDATE consignment_liabilities
+			SET status = 'settled', settlement_reference = $4, settled_at = NOW()
+			WHERE tenant_id = $1 AND supplier_id = $2 AND status = 'open' AND created_at <= $3
+			RETURNING amount
+		)
+		SELECT COUNT(*)::int, COALESCE(SUM(amount), 0)::float8, NOW() FROM settled
+	`
+	var count int
+	var amount float64
+	var settledAt time.Time
+	err := r.db.QueryRow(ctx, query, tenantID, supplierID, upTo, reference).Scan(&count, &amount, &settledAt)
+	return count, amount, settledAt, err
+}
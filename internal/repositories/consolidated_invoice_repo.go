@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ConsolidatedInvoiceRepository interface {
+	// UninvoicedOrders lists the customer's delivered sales orders dated from
+	// to to that no invoice in force covers, oldest first, as invoice lines
+	// without amounts
+	UninvoicedOrders(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) ([]*models.ConsolidatedInvoiceLine, error)
+	// Create creates the invoice with its lines and TCS, if any. It reports
+	// false, creating nothing, when another invoice has meanwhile covered one
+	// of the orders
+	Create(ctx context.Context, invoice *models.ConsolidatedInvoice) (bool, error)
+	// Get returns nil for invoices that are not consolidated
+	Get(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.ConsolidatedInvoice, error)
+}
+
+type consolidatedInvoiceRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewConsolidatedInvoiceRepo(db *pgxpool.Pool) ConsolidatedInvoiceRepository {
+	return &consolidatedInvoiceRepo{db: db}
+}
+
+// orderInvoicedCondition holds for an order o that an invoice in force
+// covers, on its own or on a consolidated invoice
+const orderInvoicedCondition = `(
+	EXISTS (SELECT 1 FROM invoices i WHERE i.tenant_id = o.tenant_id AND i.order_id = o.id AND i.status <> 'cancelled')
+	OR EXISTS (SELECT 1 FROM consolidated_invoice_orders co JOIN invoices i ON i.id = co.invoice_id
+		WHERE co.tenant_id = o.tenant_id AND co.order_id = o.id AND i.status <> 'cancelled')
+)`
+
+func (r *consolidatedInvoiceRepo) UninvoicedOrders(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) ([]*models.ConsolidatedInvoiceLine, error) {
+	query := `
+		SELECT o.id, o.product_id, COALESCE(p.name, ''), o.order_date, o.quantity, o.unit_price::float8, o.branch_id
+		FROM orders o
+		LEFT JOIN products p ON p.id = o.product_id AND p.tenant_id = o.tenant_id
+		WHERE o.tenant_id = $1 AND o.distributor_id = $2 AND o.order_type = 'sales' AND o.status = 'delivered'
+			AND o.order_date BETWEEN $3 AND $4
+			AND NOT ` + orderInvoicedCondition + `
+		ORDER BY o.order_date, o.created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, distributorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*models.ConsolidatedInvoiceLine
+	for rows.Next() {
+		line := &models.ConsolidatedInvoiceLine{}
+		if err := rows.Scan(&line.OrderID, &line.ProductID, &line.ProductName, &line.OrderDate, &line.Quantity, &line.UnitPrice, &line.BranchID); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func (r *consolidatedInvoiceRepo) Create(ctx context.Context, invoice *models.ConsolidatedInvoice) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the orders so a concurrent invoice cannot cover them between the
+	// check and the insert
+	orderIDs := make([]uuid.UUID, len(invoice.Lines))
+	for i, line := range invoice.Lines {
+		orderIDs[i] = line.OrderID
+	}
+	var uninvoiced int
+	query := `
+		WITH locked AS (
+			SELECT o.id, o.tenant_id FROM orders o WHERE o.tenant_id = $1 AND o.id = ANY($2) FOR UPDATE
+		)
+		SELECT COUNT(*) FROM locked o WHERE NOT ` + orderInvoicedCondition
+	if err := tx.QueryRow(ctx, query, invoice.TenantID, orderIDs).Scan(&uninvoiced); err != nil {
+		return false, err
+	}
+	if uninvoiced != len(orderIDs) {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, invoiceInsert, invoiceInsertArgs(invoice.Invoice)...); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO consolidated_invoices (invoice_id, tenant_id, distributor_id, period_from, period_to, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`, invoice.ID, invoice.TenantID, invoice.DistributorID, invoice.PeriodFrom, invoice.PeriodTo); err != nil {
+		return false, err
+	}
+	lineInsert := `
+		INSERT INTO consolidated_invoice_orders (invoice_id, order_id, tenant_id, line_number, product_id, quantity, unit_price,
+			taxable_amount, gst_rate, cgst, sgst, igst, total_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	for _, line := range invoice.Lines {
+		if _, err := tx.Exec(ctx, lineInsert, invoice.ID, line.OrderID, invoice.TenantID, line.LineNumber, line.ProductID, line.Quantity,
+			line.UnitPrice, line.TaxableAmount, line.GSTRate, line.CGST, line.SGST, line.IGST, line.TotalAmount); err != nil {
+			return false, err
+		}
+	}
+	if invoice.TCS != nil {
+		if err := insertInvoiceTCS(ctx, tx, invoice.Invoice, invoice.TCS); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *consolidatedInvoiceRepo) Get(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.ConsolidatedInvoice, error) {
+	invoice := &models.ConsolidatedInvoice{}
+	err := r.db.QueryRow(ctx, `
+		SELECT distributor_id, period_from, period_to FROM consolidated_invoices WHERE tenant_id = $1 AND invoice_id = $2
+	`, tenantID, invoiceID).Scan(&invoice.DistributorID, &invoice.PeriodFrom, &invoice.PeriodTo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT co.invoice_id, co.order_id, co.line_number, co.product_id, COALESCE(p.name, ''), o.order_date, co.quantity,
+			co.unit_price::float8, co.taxable_amount::float8, co.gst_rate::float8, co.cgst::float8, co.sgst::float8,
+			co.igst::float8, co.total_amount::float8
+		FROM consolidated_invoice_orders co
+		JOIN orders o ON o.id = co.order_id
+		LEFT JOIN products p ON p.id = co.product_id AND p.tenant_id = co.tenant_id
+		WHERE co.tenant_id = $1 AND co.invoice_id = $2
+		ORDER BY co.line_number
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoice.Lines = []*models.ConsolidatedInvoiceLine{}
+	for rows.Next() {
+		line := &models.ConsolidatedInvoiceLine{}
+		if err := rows.Scan(&line.InvoiceID, &line.OrderID, &line.LineNumber, &line.ProductID, &line.ProductName, &line.OrderDate,
+			&line.Quantity, &line.UnitPrice, &line.TaxableAmount, &line.GSTRate, &line.CGST, &line.SGST, &line.IGST, &line.TotalAmount); err != nil {
+			return nil, err
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	return invoice, rows.Err()
+}
@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type CounterSaleRepository interface {
+	OpenRegister(ctx context.Context, register *models.CashRegister) (bool, error)
+	GetRegister(ctx context.Context, tenantID, id uuid.UUID) (*models.CashRegister, error)
+	GetOpenRegister(ctx context.Context, tenantID, warehouseID uuid.UUID) (*models.CashRegister, error)
+	ListRegisters(ctx context.Context, tenantID uuid.UUID, filter *models.CashRegisterFilter) ([]*models.CashRegister, error)
+	RegistersActiveBetween(ctx context.Context, tenantID uuid.UUID, from, to time.Time, warehouseID *uuid.UUID) ([]*models.CashRegister, error)
+	CashSalesTotal(ctx context.Context, registerID uuid.UUID) (float64, error)
+	CloseRegister(ctx context.Context, register *models.CashRegister) (bool, error)
+
+	CreateSale(ctx context.Context, sale *models.CounterSale) error
+	ListSales(ctx context.Context, tenantID uuid.UUID, from, to time.Time, warehouseID *uuid.UUID) ([]*models.CounterSale, error)
+}
+
+type counterSaleRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCounterSaleRepo(db *pgxpool.Pool) CounterSaleRepository {
+	return &counterSaleRepo{db: db}
+}
+
+const cashRegisterColumns = `id, tenant_id, warehouse_id, status, opening_float::float8, opening_denominations, opened_by, opened_at,
+	closing_denominations, counted_cash::float8, expected_cash::float8, variance::float8, closed_by, closed_at, notes`
+
+func scanCashRegister(row rowScanner) (*models.CashRegister, error) {
+	r := &models.CashRegister{}
+	err := row.Scan(&r.ID, &r.TenantID, &r.WarehouseID, &r.Status, &r.OpeningFloat, &r.OpeningDenominations, &r.OpenedBy, &r.OpenedAt,
+		&r.ClosingDenominations, &r.CountedCash, &r.ExpectedCash, &r.Variance, &r.ClosedBy, &r.ClosedAt, &r.Notes)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// OpenRegister opens a register, reporting false when the warehouse already
+// has one open
+func (r *counterSaleRepo) OpenRegister(ctx context.Context, register *models.CashRegister) (bool, error) {
+	query := `
+		INSERT INTO cash_registers (id, tenant_id, warehouse_id, status, opening_float, opening_denominations, opened_by, opened_at)
+		VALUES ($1, $2, $3, 'open', $4, $5, $6, NOW())
+		ON CONFLICT (tenant_id, warehouse_id) WHERE status = 'open' DO NOTHING
+		RETURNING opened_at
+	`
+	err := r.db.QueryRow(ctx, query, register.ID, register.TenantID, register.WarehouseID, register.OpeningFloat,
+		register.OpeningDenominations, register.OpenedBy).Scan(&register.OpenedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	register.Status = models.CashRegisterOpen
+	return true, nil
+}
+
+func (r *counterSaleRepo) GetRegister(ctx context.Context, tenantID, id uuid.UUID) (*models.CashRegister, error) {
+	query := `SELECT ` + cashRegisterColumns + ` FROM cash_registers WHERE tenant_id = $1 AND id = $2`
+	register, err := scanCashRegister(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return register, err
+}
+
+func (r *counterSaleRepo) GetOpenRegister(ctx context.Context, tenantID, warehouseID uuid.UUID) (*models.CashRegister, error) {
+	query := `SELECT ` + cashRegisterColumns + ` FROM cash_registers WHERE tenant_id = $1 AND warehouse_id = $2 AND status = 'open'`
+	register, err := scanCashRegister(r.db.QueryRow(ctx, query, tenantID, warehouseID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return register, err
+}
+
+func (r *counterSaleRepo) ListRegisters(ctx context.Context, tenantID uuid.UUID, filter *models.CashRegisterFilter) ([]*models.CashRegister, error) {
+	query := `SELECT ` + cashRegisterColumns + ` FROM cash_registers WHERE tenant_id = $1`
+	args := []interface{}{tenantID}
+	if filter.WarehouseID != nil {
+		args = append(args, *filter.WarehouseID)
+		query += fmt.Sprintf(" AND warehouse_id = $%d", len(args))
+	}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY opened_at DESC, id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	return r.queryRegisters(ctx, query, args...)
+}
+
+// RegistersActiveBetween lists registers opened or closed in [from, to), or
+// open throughout it
+func (r *counterSaleRepo) RegistersActiveBetween(ctx context.Context, tenantID uuid.UUID, from, to time.Time, warehouseID *uuid.UUID) ([]*models.CashRegister, error) {
+	query := `
+		SELECT ` + cashRegisterColumns + `
+		FROM cash_registers
+		WHERE tenant_id = $1 AND opened_at < $3 AND (closed_at IS NULL OR closed_at >= $2)
+			AND ($4::uuid IS NULL OR warehouse_id = $4)
+		ORDER BY opened_at
+	`
+	return r.queryRegisters(ctx, query, tenantID, from, to, warehouseID)
+}
+
+func (r *counterSaleRepo) queryRegisters(ctx context.Context, query string, args ...interface{}) ([]*models.CashRegister, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var registers []*models.CashRegister
+	for rows.Next() {
+		register, err := scanCashRegister(rows)
+		if err != nil {
+			return nil, err
+		}
+		registers = append(registers, register)
+	}
+	return registers, rows.Err()
+}
+
+// CashSalesTotal is the cash taken at a register, net of change given
+func (r *counterSaleRepo) CashSalesTotal(ctx context.Context, registerID uuid.UUID) (float64, error) {
+	var total float64
+	query := `SELECT COALESCE(SUM(amount), 0)::float8 FROM counter_sales WHERE register_id = $1 AND payment_method = 'cash'`
+	err := r.db.QueryRow(ctx, query, registerID).Scan(&total)
+	return total, err
+}
+
+// CloseRegister records the closing count, reporting false when the register
+// was no longer open
+func (r *counterSaleRepo) CloseRegister(ctx context.Context, register *models.CashRegister) (bool, error) {
+	query := `
+		UPDATE cash_registers
+		SET status = 'closed', closing_denominations = $3, counted_cash = $4, expected_cash = $5, variance = $6,
+			closed_by = $7, closed_at = NOW(), notes = $8
+		WHERE tenant_id = $1 AND id = $2 AND status = 'open'
+		RETURNING closed_at
+	`
+	err := r.db.QueryRow(ctx, query, register.TenantID, register.ID, register.ClosingDenominations, register.CountedCash,
+		register.ExpectedCash, register.Variance, register.ClosedBy, register.Notes).Scan(&register.ClosedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	register.Status = models.CashRegisterClosed
+	return true, nil
+}
+
+func (r *counterSaleRepo) CreateSale(ctx context.Context, sale *models.CounterSale) error {
+	query := `
+		INSERT INTO counter_sales (id, tenant_id, warehouse_id, register_id, order_id, invoice_id, distributor_id, product_id, quantity, amount,
+			payment_method, amount_tendered, change_given, payment_reference, customer_name, customer_phone, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, sale.ID, sale.TenantID, sale.WarehouseID, sale.RegisterID, sale.OrderID, sale.InvoiceID,
+		sale.DistributorID, sale.ProductID, sale.Quantity, sale.Amount, sale.PaymentMethod, sale.AmountTendered, sale.ChangeGiven,
+		sale.PaymentReference, sale.CustomerName, sale.CustomerPhone, sale.CreatedBy).Scan(&sale.CreatedAt)
+}
+
+// ListSales lists counter sales rung up in [from, to), oldest first, with
+// their invoice numbers and product names
+func (r *counterSaleRepo) ListSales(ctx context.Context, tenantID uuid.UUID, from, to time.Time, warehouseID *uuid.UUID) ([]*models.CounterSale, error) {
+	query := `
+		SELECT s.id, s.tenant_id, s.warehouse_id, s.register_id, s.order_id, s.invoice_id, s.distributor_id, s.product_id, s.quantity,
+			s.amount::float8, s.payment_method, s.amount_tendered::float8, s.change_given::float8, s.payment_reference,
+			s.customer_name, s.customer_phone, s.created_by, s.created_at, i.invoice_number, COALESCE(p.name, '')
+		FROM counter_sales s
+		LEFT JOIN invoices i ON i.id = s.invoice_id
+		LEFT JOIN products p ON p.id = s.product_id AND p.tenant_id = s.tenant_id
+		WHERE s.tenant_id = $1 AND s.created_at >= $2 AND s.created_at < $3
+			AND ($4::uuid IS NULL OR s.warehouse_id = $4)
+		ORDER BY s.created_at, s.id
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []*models.CounterSale
+	for rows.Next() {
+		s := &models.CounterSale{}
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.WarehouseID, &s.RegisterID, &s.OrderID, &s.InvoiceID, &s.DistributorID, &s.ProductID,
+			&s.Quantity, &s.Amount, &s.PaymentMethod, &s.AmountTendered, &s.ChangeGiven, &s.PaymentReference,
+			&s.CustomerName, &s.CustomerPhone, &s.CreatedBy, &s.CreatedAt, &s.InvoiceNumber, &s.ProductName); err != nil {
+			return nil, err
+		}
+		sales = append(sales, s)
+	}
+	return sales, rows.Err()
+}
@@ -0,0 +1,315 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DataExportRepository interface {
+	CreateDestination(ctx context.Context, destination *models.DataExportDestination) error
+	// GetDestination returns nil for destinations the tenant does not have
+	GetDestination(ctx context.Context, tenantID, id uuid.UUID) (*models.DataExportDestination, error)
+	ListDestinations(ctx context.Context, tenantID uuid.UUID) ([]*models.DataExportDestination, error)
+	ListDueDestinations(ctx context.Context, now time.Time, limit int) ([]*models.DataExportDestination, error)
+	UpdateDestination(ctx context.Context, destination *models.DataExportDestination) error
+	DeleteDestination(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+	ScheduleDestination(ctx context.Context, id uuid.UUID, nextSyncAt time.Time) error
+
+	// GetCursor returns an empty cursor before the fact's first export
+	GetCursor(ctx context.Context, destinationID uuid.UUID, fact string) (*models.DataExportCursor, error)
+	SaveCursor(ctx context.Context, cursor *models.DataExportCursor) error
+	ResetCursors(ctx context.Context, destinationID uuid.UUID) error
+	// ListChangesAfter returns the tenant's rows of a fact table changed after
+	// the cursor and before the cutoff, oldest first; the cutoff keeps
+	// in-flight writes out of the batch
+	ListChangesAfter(ctx context.Context, tenantID uuid.UUID, cursor *models.DataExportCursor, before time.Time, limit int) ([]*models.DataExportRecord, error)
+
+	CreateRun(ctx context.Context, run *models.DataExportRun) error
+	FinishRun(ctx context.Context, run *models.DataExportRun) error
+	ListRuns(ctx context.Context, tenantID, destinationID uuid.UUID, limit, offset int) ([]*models.DataExportRun, error)
+}
+
+type dataExportRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDataExportRepo(db *pgxpool.Pool) DataExportRepository {
+	return &dataExportRepo{db: db}
+}
+
+const dataExportDestinationColumns = `id, tenant_id, name, provider, settings, credentials, facts, sync_interval_minutes, next_sync_at, is_active,
+	consented_by, consented_at, created_at, updated_at`
+
+func scanDataExportDestination(row rowScanner) (*models.DataExportDestination, error) {
+	d := &models.DataExportDestination{}
+	err := row.Scan(&d.ID, &d.TenantID, &d.Name, &d.Provider, &d.Settings, &d.Credentials, &d.Facts, &d.SyncIntervalMinutes, &d.NextSyncAt,
+		&d.IsActive, &d.ConsentedBy, &d.ConsentedAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.HasCredentials = d.Credentials != ""
+	return d, nil
+}
+
+func (r *dataExportRepo) CreateDestination(ctx context.Context, d *models.DataExportDestination) error {
+	query := `
+		INSERT INTO data_export_destinations (id, tenant_id, name, provider, settings, credentials, facts, sync_interval_minutes, next_sync_at,
+			is_active, consented_by, consented_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, d.ID, d.TenantID, d.Name, d.Provider, d.Settings, d.Credentials, d.Facts, d.SyncIntervalMinutes,
+		d.NextSyncAt, d.IsActive, d.ConsentedBy, d.ConsentedAt).Scan(&d.CreatedAt, &d.UpdatedAt)
+}
+
+func (r *dataExportRepo) GetDestination(ctx context.Context, tenantID, id uuid.UUID) (*models.DataExportDestination, error) {
+	query := `SELECT ` + dataExportDestinationColumns + ` FROM data_export_destinations WHERE tenant_id = $1 AND id = $2`
+	destination, err := scanDataExportDestination(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return destination, err
+}
+
+func (r *dataExportRepo) queryDestinations(ctx context.Context, query string, args ...interface{}) ([]*models.DataExportDestination, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var destinations []*models.DataExportDestination
+	for rows.Next() {
+		destination, err := scanDataExportDestination(rows)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, destination)
+	}
+	return destinations, rows.Err()
+}
+
+func (r *dataExportRepo) ListDestinations(ctx context.Context, tenantID uuid.UUID) ([]*models.DataExportDestination, error) {
+	query := `SELECT ` + dataExportDestinationColumns + ` FROM data_export_destinations WHERE tenant_id = $1 ORDER BY name`
+	return r.queryDestinations(ctx, query, tenantID)
+}
+
+// ListDueDestinations returns active destinations across all tenants whose next sync has passed
+func (r *dataExportRepo) ListDueDestinations(ctx context.Context, now time.Time, limit int) ([]*models.DataExportDestination, error) {
+	query := `
+		SELECT ` + dataExportDestinationColumns + `
+		FROM data_export_destinations
+		WHERE is_active AND (next_sync_at IS NULL OR next_sync_at <= $1)
+		ORDER BY next_sync_at NULLS FIRST
+		LIMIT $2
+	`
+	return r.queryDestinations(ctx, query, now, limit)
+}
+
+func (r *dataExportRepo) UpdateDestination(ctx context.Context, d *models.DataExportDestination) error {
+	query := `
+		UPDATE data_export_destinations
+		SET name = $1, settings = $2, credentials = $3, facts = $4, sync_interval_minutes = $5, next_sync_at = $6, is_active = $7, updated_at = NOW()
+		WHERE tenant_id = $8 AND id = $9
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, d.Name, d.Settings, d.Credentials, d.Facts, d.SyncIntervalMinutes, d.NextSyncAt, d.IsActive,
+		d.TenantID, d.ID).Scan(&d.UpdatedAt)
+}
+
+func (r *dataExportRepo) DeleteDestination(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM data_export_destinations WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *dataExportRepo) ScheduleDestination(ctx context.Context, id uuid.UUID, nextSyncAt time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE data_export_destinations SET next_sync_at = $1 WHERE id = $2`, nextSyncAt, id)
+	return err
+}
+
+func (r *dataExportRepo) GetCursor(ctx context.Context, destinationID uuid.UUID, fact string) (*models.DataExportCursor, error) {
+	cursor := &models.DataExportCursor{DestinationID: destinationID, Fact: fact}
+	query := `
+		SELECT last_exported_at, last_exported_id, updated_at
+		FROM data_export_cursors
+		WHERE destination_id = $1 AND fact = $2
+	`
+	err := r.db.QueryRow(ctx, query, destinationID, fact).Scan(&cursor.LastExportedAt, &cursor.LastExportedID, &cursor.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return cursor, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+func (r *dataExportRepo) SaveCursor(ctx context.Context, cursor *models.DataExportCursor) error {
+	query := `
+		INSERT INTO data_export_cursors (destination_id, fact, last_exported_at, last_exported_id, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (destination_id, fact) DO UPDATE
+		SET last_exported_at = EXCLUDED.last_exported_at, last_exported_id = EXCLUDED.last_exported_id, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, cursor.DestinationID, cursor.Fact, cursor.LastExportedAt, cursor.LastExportedID).Scan(&cursor.UpdatedAt)
+}
+
+func (r *dataExportRepo) ResetCursors(ctx context.Context, destinationID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM data_export_cursors WHERE destination_id = $1`, destinationID)
+	return err
+}
+
+// dataExportFactQueries select each fact table's rows changed after the
+// cursor ($2, $3) and before the cutoff ($4), as changed_at and id followed
+// by the row's columns
+var dataExportFactQueries = map[string]string{
+	models.DataExportFactOrders: `
+		SELECT o.updated_at, o.id, o.id, o.tenant_id, o.order_type, o.status, o.order_date, o.expected_delivery, o.distributor_id, d.name,
+			o.supplier_id, s.name, o.warehouse_id, w.name, o.quantity, (o.quantity * o.unit_price)::float8, o.created_at, o.updated_at
+		FROM orders o
+		LEFT JOIN distributors d ON d.id = o.distributor_id AND d.tenant_id = o.tenant_id
+		LEFT JOIN suppliers s ON s.id = o.supplier_id AND s.tenant_id = o.tenant_id
+		LEFT JOIN warehouses w ON w.id = o.warehouse_id AND w.tenant_id = o.tenant_id
+		WHERE o.tenant_id = $1 AND (o.updated_at, o.id) > ($2, $3) AND o.updated_at < $4
+		ORDER BY o.updated_at, o.id
+		LIMIT $5`,
+	models.DataExportFactOrderItems: `
+		SELECT o.updated_at, o.id, o.id, o.tenant_id, 1, o.order_type, o.order_date, o.product_id, p.name, c.name, o.quantity,
+			o.unit_price::float8, (o.quantity * o.unit_price)::float8, o.updated_at
+		FROM orders o
+		LEFT JOIN products p ON p.id = o.product_id AND p.tenant_id = o.tenant_id
+		LEFT JOIN categories c ON c.id = p.category_id AND c.tenant_id = p.tenant_id
+		WHERE o.tenant_id = $1 AND (o.updated_at, o.id) > ($2, $3) AND o.updated_at < $4
+		ORDER BY o.updated_at, o.id
+		LIMIT $5`,
+	models.DataExportFactInvoices: `
+		SELECT i.updated_at, i.id, i.id, i.tenant_id, i.invoice_number, i.order_id, o.distributor_id, d.name, i.status, i.issued_date,
+			i.due_date, i.paid_date, i.taxable_amount::float8, i.gst_rate::float8, i.cgst::float8, i.sgst::float8, i.igst::float8,
+			i.total_amount::float8, i.updated_at
+		FROM invoices i
+		LEFT JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		LEFT JOIN distributors d ON d.id = o.distributor_id AND d.tenant_id = i.tenant_id
+		WHERE i.tenant_id = $1 AND (i.updated_at, i.id) > ($2, $3) AND i.updated_at < $4
+		ORDER BY i.updated_at, i.id
+		LIMIT $5`,
+	models.DataExportFactInventoryTransactions: `
+		SELECT t.created_at, t.id, t.id, t.tenant_id, t.warehouse_id, w.name, t.product_id, p.name, t.quantity_change, t.quantity_after,
+			t.reason, t.created_by, t.created_at
+		FROM inventory_transactions t
+		LEFT JOIN warehouses w ON w.id = t.warehouse_id AND w.tenant_id = t.tenant_id
+		LEFT JOIN products p ON p.id = t.product_id AND p.tenant_id = t.tenant_id
+		WHERE t.tenant_id = $1 AND (t.created_at, t.id) > ($2, $3) AND t.created_at < $4
+		ORDER BY t.created_at, t.id
+		LIMIT $5`,
+}
+
+func (r *dataExportRepo) ListChangesAfter(ctx context.Context, tenantID uuid.UUID, cursor *models.DataExportCursor, before time.Time, limit int) ([]*models.DataExportRecord, error) {
+	query, ok := dataExportFactQueries[cursor.Fact]
+	if !ok {
+		return nil, fmt.Errorf("unknown fact table: %s", cursor.Fact)
+	}
+	afterAt, afterID := time.Time{}, uuid.Nil
+	if cursor.LastExportedAt != nil {
+		afterAt = *cursor.LastExportedAt
+		if cursor.LastExportedID != nil {
+			afterID = *cursor.LastExportedID
+		}
+	}
+
+	rows, err := r.db.Query(ctx, query, tenantID, afterAt, afterID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*models.DataExportRecord
+	for rows.Next() {
+		record := &models.DataExportRecord{}
+		switch cursor.Fact {
+		case models.DataExportFactOrders:
+			f := &models.OrderFact{}
+			err = rows.Scan(&record.ChangedAt, &record.RowID, &f.OrderID, &f.TenantID, &f.OrderType, &f.Status, &f.OrderDate, &f.ExpectedDelivery,
+				&f.CustomerID, &f.CustomerName, &f.SupplierID, &f.SupplierName, &f.WarehouseID, &f.WarehouseName, &f.TotalQuantity,
+				&f.TotalAmount, &f.CreatedAt, &f.UpdatedAt)
+			record.Row = f
+		case models.DataExportFactOrderItems:
+			f := &models.OrderItemFact{}
+			err = rows.Scan(&record.ChangedAt, &record.RowID, &f.OrderID, &f.TenantID, &f.LineNumber, &f.OrderType, &f.OrderDate, &f.ProductID,
+				&f.ProductName, &f.CategoryName, &f.Quantity, &f.UnitPrice, &f.LineAmount, &f.UpdatedAt)
+			record.Row = f
+		case models.DataExportFactInvoices:
+			f := &models.InvoiceFact{}
+			err = rows.Scan(&record.ChangedAt, &record.RowID, &f.InvoiceID, &f.TenantID, &f.InvoiceNumber, &f.OrderID, &f.CustomerID,
+				&f.CustomerName, &f.Status, &f.IssuedDate, &f.DueDate, &f.PaidDate, &f.TaxableAmount, &f.GSTRate, &f.CGST, &f.SGST, &f.IGST,
+				&f.TotalAmount, &f.UpdatedAt)
+			record.Row = f
+		case models.DataExportFactInventoryTransactions:
+			f := &models.InventoryTransactionFact{}
+			err = rows.Scan(&record.ChangedAt, &record.RowID, &f.TransactionID, &f.TenantID, &f.WarehouseID, &f.WarehouseName, &f.ProductID,
+				&f.ProductName, &f.QuantityChange, &f.QuantityAfter, &f.Reason, &f.CreatedBy, &f.CreatedAt)
+			record.Row = f
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (r *dataExportRepo) CreateRun(ctx context.Context, run *models.DataExportRun) error {
+	query := `
+		INSERT INTO data_export_runs (id, tenant_id, destination_id, trigger, status, started_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING started_at
+	`
+	return r.db.QueryRow(ctx, query, run.ID, run.TenantID, run.DestinationID, run.Trigger, run.Status).Scan(&run.StartedAt)
+}
+
+func (r *dataExportRepo) FinishRun(ctx context.Context, run *models.DataExportRun) error {
+	query := `
+		UPDATE data_export_runs
+		SET status = $1, records_exported = $2, has_more = $3, error_message = $4, finished_at = NOW()
+		WHERE id = $5
+		RETURNING finished_at
+	`
+	return r.db.QueryRow(ctx, query, run.Status, run.RecordsExported, run.HasMore, run.ErrorMessage, run.ID).Scan(&run.FinishedAt)
+}
+
+func (r *dataExportRepo) ListRuns(ctx context.Context, tenantID, destinationID uuid.UUID, limit, offset int) ([]*models.DataExportRun, error) {
+	query := `
+		SELECT id, tenant_id, destination_id, trigger, status, records_exported, has_more, error_message, started_at, finished_at
+		FROM data_export_runs
+		WHERE tenant_id = $1 AND destination_id = $2
+		ORDER BY started_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, destinationID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*models.DataExportRun{}
+	for rows.Next() {
+		run := &models.DataExportRun{}
+		if err := rows.Scan(&run.ID, &run.TenantID, &run.DestinationID, &run.Trigger, &run.Status, &run.RecordsExported, &run.HasMore,
+			&run.ErrorMessage, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
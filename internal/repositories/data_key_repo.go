@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PIITable lists a table's encrypted columns; each has a blind index column
+// named after it with a _bidx suffix. GlobalIndex tables are indexed with the
+// index shared by all tenants, for values unique across them
+type PIITable struct {
+	Table       string
+	Columns     []string
+	GlobalIndex bool
+}
+
+// PIITables are the tables whose PII columns are encrypted
+var PIITables = []PIITable{
+	{Table: "distributors", Columns: []string{"contact_email", "contact_phone", "gstin", "bank_account_number"}},
+	{Table: "suppliers", Columns: []string{"contact_email", "contact_phone", "gstin", "bank_account_number"}},
+	{Table: "users", Columns: []string{"email"}, GlobalIndex: true},
+}
+
+// DataKeyRepository stores tenant data keys and re-encrypts PII rows for the
+// key rotation job
+type DataKeyRepository interface {
+	pii.DataKeyStore
+	ListTenantIDs(ctx context.Context) ([]uuid.UUID, error)
+	// ListStaleRows returns rows after afterID with a value not sealed with
+	// activeVersion or missing its blind index, ordered by ID
+	ListStaleRows(ctx context.Context, tenantID uuid.UUID, table PIITable, activeVersion int, afterID uuid.UUID, limit int) ([]*models.SealedRow, error)
+	// UpdateSealedRow writes re-encrypted values and indexes unless the row
+	// changed since it was read; it returns false when it did
+	UpdateSealedRow(ctx context.Context, tenantID uuid.UUID, table PIITable, previous, row *models.SealedRow, indexes []*string) (bool, error)
+	// KeyVersionsInUse lists the key versions the tenant's values are sealed with
+	KeyVersionsInUse(ctx context.Context, tenantID uuid.UUID) ([]int, error)
+	// DeleteRetiredDataKeys deletes retired keys not in use that were retired before the cutoff
+	DeleteRetiredDataKeys(ctx context.Context, tenantID uuid.UUID, inUse []int, retiredBefore time.Time) (int64, error)
+}
+
+type dataKeyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDataKeyRepo(db *pgxpool.Pool) DataKeyRepository {
+	return &dataKeyRepo{db: db}
+}
+
+func (r *dataKeyRepo) ListDataKeys(ctx context.Context, tenantID uuid.UUID) ([]*models.TenantDataKey, error) {
+	query := `
+		SELECT tenant_id, version, wrapped_key, is_active, created_at, retired_at
+		FROM tenant_data_keys
+		WHERE tenant_id = $1
+		ORDER BY version
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.TenantDataKey
+	for rows.Next() {
+		key := &models.TenantDataKey{}
+		if err := rows.Scan(&key.TenantID, &key.Version, &key.WrappedKey, &key.IsActive, &key.CreatedAt, &key.RetiredAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *dataKeyRepo) CreateDataKey(ctx context.Context, key *models.TenantDataKey) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Retiring first keeps a single active key; a concurrent rotation to the
+	// same version then conflicts on insert and rolls back
+	_, err = tx.Exec(ctx, `
+		UPDATE tenant_data_keys SET is_active = false, retired_at = NOW()
+		WHERE tenant_id = $1 AND is_active
+	`, key.TenantID)
+	if err != nil {
+		return false, err
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO tenant_data_keys (tenant_id, version, wrapped_key, is_active, created_at)
+		VALUES ($1, $2, $3, true, NOW())
+		ON CONFLICT DO NOTHING
+	`, key.TenantID, key.Version, key.WrappedKey)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *dataKeyRepo) ListTenantIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT id FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *dataKeyRepo) ListStaleRows(ctx context.Context, tenantID uuid.UUID, table PIITable, activeVersion int, afterID uuid.UUID, limit int) ([]*models.SealedRow, error) {
+	stale := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		stale[i] = fmt.Sprintf("(%[1]s <> '' AND (%[1]s NOT LIKE $2 OR %[1]s_bidx IS NULL))", column)
+	}
+	query := fmt.Sprintf(`
+		SELECT id, %s
+		FROM %s
+		WHERE tenant_id = $1 AND id > $3 AND (%s)
+		ORDER BY id
+		LIMIT $4
+	`, strings.Join(table.Columns, ", "), table.Table, strings.Join(stale, " OR "))
+	rows, err := r.db.Query(ctx, query, tenantID, pii.SealedPattern(activeVersion), afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sealed []*models.SealedRow
+	for rows.Next() {
+		row := &models.SealedRow{Table: table.Table, Values: make([]*string, len(table.Columns))}
+		dest := []interface{}{&row.ID}
+		for i := range row.Values {
+			dest = append(dest, &row.Values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		sealed = append(sealed, row)
+	}
+	return sealed, rows.Err()
+}
+
+func (r *dataKeyRepo) UpdateSealedRow(ctx context.Context, tenantID uuid.UUID, table PIITable, previous, row *models.SealedRow, indexes []*string) (bool, error) {
+	sets := make([]string, 0, 2*len(table.Columns))
+	guards := make([]string, 0, len(table.Columns))
+	args := []interface{}{tenantID, row.ID}
+	for i, column := range table.Columns {
+		args = append(args, row.Values[i], indexes[i], previous.Values[i])
+		n := len(args)
+		sets = append(sets, fmt.Sprintf("%s = $%d, %s_bidx = $%d", column, n-2, column, n-1))
+		guards = append(guards, fmt.Sprintf("%s IS NOT DISTINCT FROM $%d", column, n))
+	}
+	// updated_at is left alone: the values themselves have not changed
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE tenant_id = $1 AND id = $2 AND %s`,
+		table.Table, strings.Join(sets, ", "), strings.Join(guards, " AND "))
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *dataKeyRepo) KeyVersionsInUse(ctx context.Context, tenantID uuid.UUID) ([]int, error) {
+	var selects []string
+	for _, table := range PIITables {
+		for _, column := range table.Columns {
+			selects = append(selects, fmt.Sprintf(
+				`SELECT DISTINCT split_part(%[1]s, ':', 3)::int FROM %[2]s WHERE tenant_id = $1 AND %[1]s LIKE 'pii:v1:%%'`,
+				column, table.Table))
+		}
+	}
+	rows, err := r.db.Query(ctx, strings.Join(selects, " UNION "), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func (r *dataKeyRepo) DeleteRetiredDataKeys(ctx context.Context, tenantID uuid.UUID, inUse []int, retiredBefore time.Time) (int64, error) {
+	if inUse == nil {
+		inUse = []int{}
+	}
+	query := `
+		DELETE FROM tenant_data_keys
+		WHERE tenant_id = $1 AND NOT is_active AND retired_at < $2 AND NOT (version = ANY($3))
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, retiredBefore, inUse)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// sealedContact is a party's contact and bank account columns as stored,
+// with their blind indexes
+type sealedContact struct {
+	email, phone, gstin, bankAccount                     *string
+	emailIndex, phoneIndex, gstinIndex, bankAccountIndex *string
+}
+
+// sealContact encrypts a supplier's or distributor's contact columns and bank
+// account number
+func sealContact(ctx context.Context, keyring *pii.DataKeyRing, tenantID uuid.UUID, email, phone, gstin, bankAccount *string) (*sealedContact, error) {
+	sealed := &sealedContact{
+		emailIndex:       keyring.BlindIndex(tenantID, email),
+		phoneIndex:       keyring.BlindIndex(tenantID, phone),
+		gstinIndex:       keyring.BlindIndex(tenantID, gstin),
+		bankAccountIndex: keyring.BlindIndex(tenantID, bankAccount),
+	}
+	var err error
+	if sealed.email, err = keyring.Encrypt(ctx, tenantID, email); err != nil {
+		return nil, fmt.Errorf("failed to encrypt contact email: %w", err)
+	}
+	if sealed.phone, err = keyring.Encrypt(ctx, tenantID, phone); err != nil {
+		return nil, fmt.Errorf("failed to encrypt contact phone: %w", err)
+	}
+	if sealed.gstin, err = keyring.Encrypt(ctx, tenantID, gstin); err != nil {
+		return nil, fmt.Errorf("failed to encrypt GSTIN: %w", err)
+	}
+	if sealed.bankAccount, err = keyring.Encrypt(ctx, tenantID, bankAccount); err != nil {
+		return nil, fmt.Errorf("failed to encrypt bank account number: %w", err)
+	}
+	return sealed, nil
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DependencyRepository counts the records that would be orphaned, or would
+// make the database reject the delete, if a resource were removed
+type DependencyRepository interface {
+	BlockingReferences(ctx context.Context, tenantID uuid.UUID, resource string, id uuid.UUID) ([]*models.BlockingReference, error)
+}
+
+// dependencyCheck is a count query taking the tenant ($1) and resource ID ($2)
+type dependencyCheck struct {
+	reference string
+	query     string
+}
+
+const (
+	openOrderStatuses   = `('pending', 'approved', 'shipped')`
+	unpaidInvoiceFilter = `i.status IN ('unpaid', 'overdue')`
+)
+
+var dependencyChecks = map[string][]dependencyCheck{
+	models.DependentCategory: {
+		{models.BlockingProducts, `SELECT COUNT(*) FROM products WHERE tenant_id = $1 AND category_id = $2`},
+		{models.BlockingSubcategories, `SELECT COUNT(*) FROM categories WHERE tenant_id = $1 AND parent_id = $2`},
+	},
+	models.DependentProduct: {
+		{models.BlockingOpenOrders, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND product_id = $2 AND status IN ` + openOrderStatuses},
+		{models.BlockingClosedOrders, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND product_id = $2 AND status NOT IN ` + openOrderStatuses},
+		{models.BlockingStock, `SELECT COALESCE(SUM(quantity), 0) FROM inventory WHERE tenant_id = $1 AND product_id = $2`},
+		{models.BlockingUnpaidInvoices, `SELECT COUNT(*) FROM invoices i JOIN orders o ON o.id = i.order_id WHERE i.tenant_id = $1 AND o.product_id = $2 AND ` + unpaidInvoiceFilter},
+	},
+	models.DependentWarehouse: {
+		{models.BlockingOpenOrders, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND warehouse_id = $2 AND status IN ` + openOrderStatuses},
+		{models.BlockingStock, `SELECT COALESCE(SUM(quantity), 0) FROM inventory WHERE tenant_id = $1 AND warehouse_id = $2`},
+	},
+	models.DependentSupplier: {
+		{models.BlockingOpenOrders, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND supplier_id = $2 AND status IN ` + openOrderStatuses},
+		{models.BlockingUnpaidInvoices, `SELECT COUNT(*) FROM invoices i JOIN orders o ON o.id = i.order_id WHERE i.tenant_id = $1 AND o.supplier_id = $2 AND ` + unpaidInvoiceFilter},
+	},
+	models.DependentDistributor: {
+		{models.BlockingOpenOrders, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND distributor_id = $2 AND status IN ` + openOrderStatuses},
+		{models.BlockingUnpaidInvoices, `SELECT COUNT(*) FROM invoices i JOIN orders o ON o.id = i.order_id WHERE i.tenant_id = $1 AND o.distributor_id = $2 AND ` + unpaidInvoiceFilter},
+	},
+}
+
+type dependencyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDependencyRepo(db *pgxpool.Pool) DependencyRepository {
+	return &dependencyRepo{db: db}
+}
+
+// BlockingReferences runs the resource's checks and returns those with a
+// non-zero count
+func (r *dependencyRepo) BlockingReferences(ctx context.Context, tenantID uuid.UUID, resource string, id uuid.UUID) ([]*models.BlockingReference, error) {
+	checks, ok := dependencyChecks[resource]
+	if !ok {
+		return nil, fmt.Errorf("no dependency checks for %s", resource)
+	}
+
+	var refs []*models.BlockingReference
+	for _, check := range checks {
+		var count int
+		if err := r.db.QueryRow(ctx, check.query, tenantID, id).Scan(&count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			refs = append(refs, &models.BlockingReference{Type: check.reference, Count: count})
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DeviceTokenRepository interface {
+	Upsert(ctx context.Context, device *models.DeviceToken) error
+	ListByUser(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.DeviceToken, error)
+	ListByTopic(ctx context.Context, tenantID uuid.UUID, topic string) ([]*models.DeviceToken, error)
+	Delete(ctx context.Context, tenantID, userID, id uuid.UUID) (bool, error)
+	DeleteUserToken(ctx context.Context, tenantID, userID uuid.UUID, token string) (bool, error)
+	DeleteByToken(ctx context.Context, token string) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+type deviceTokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewDeviceTokenRepo(db *pgxpool.Pool) DeviceTokenRepository {
+	return &deviceTokenRepo{db: db}
+}
+
+const deviceTokenColumns = `id, tenant_id, user_id, token, platform, topics, app_version, last_seen_at, created_at`
+
+func scanDeviceToken(row rowScanner) (*models.DeviceToken, error) {
+	device := &models.DeviceToken{}
+	err := row.Scan(&device.ID, &device.TenantID, &device.UserID, &device.Token, &device.Platform, &device.Topics, &device.AppVersion, &device.LastSeenAt, &device.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// Upsert registers a token, moving it to the given user when the app was
+// previously signed in as someone else
+func (r *deviceTokenRepo) Upsert(ctx context.Context, device *models.DeviceToken) error {
+	query := `
+		INSERT INTO device_tokens (id, tenant_id, user_id, token, platform, topics, app_version, last_seen_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (token) DO UPDATE
+		SET tenant_id = EXCLUDED.tenant_id, user_id = EXCLUDED.user_id, platform = EXCLUDED.platform,
+			topics = EXCLUDED.topics, app_version = EXCLUDED.app_version, last_seen_at = NOW()
+		RETURNING ` + deviceTokenColumns
+	saved, err := scanDeviceToken(r.db.QueryRow(ctx, query, device.ID, device.TenantID, device.UserID, device.Token, device.Platform, device.Topics, device.AppVersion))
+	if err != nil {
+		return err
+	}
+	*device = *saved
+	return nil
+}
+
+func (r *deviceTokenRepo) ListByUser(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.DeviceToken, error) {
+	query := `SELECT ` + deviceTokenColumns + ` FROM device_tokens WHERE tenant_id = $1 AND user_id = $2 ORDER BY last_seen_at DESC`
+	return r.list(ctx, query, tenantID, userID)
+}
+
+// ListByTopic lists devices subscribed to a topic, leaving out users who get
+// the topic in a notification digest instead
+func (r *deviceTokenRepo) ListByTopic(ctx context.Context, tenantID uuid.UUID, topic string) ([]*models.DeviceToken, error) {
+	query := `
+		SELECT ` + deviceTokenColumns + ` FROM device_tokens d
+		WHERE d.tenant_id = $1 AND $2 = ANY(d.topics)
+			AND NOT EXISTS (
+				SELECT 1 FROM notification_digest_preferences p
+				WHERE p.tenant_id = d.tenant_id AND p.user_id = d.user_id AND p.frequency <> 'off' AND $3 = ANY(p.sections)
+			)
+	`
+	return r.list(ctx, query, tenantID, topic, models.DigestTopicSections[topic])
+}
+
+func (r *deviceTokenRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.DeviceToken, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []*models.DeviceToken
+	for rows.Next() {
+		device, err := scanDeviceToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+func (r *deviceTokenRepo) Delete(ctx context.Context, tenantID, userID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM device_tokens WHERE tenant_id = $1 AND user_id = $2 AND id = $3`, tenantID, userID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *deviceTokenRepo) DeleteUserToken(ctx context.Context, tenantID, userID uuid.UUID, token string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM device_tokens WHERE tenant_id = $1 AND user_id = $2 AND token = $3`, tenantID, userID, token)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteByToken drops a token the push provider reported as no longer valid
+func (r *deviceTokenRepo) DeleteByToken(ctx context.Context, token string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM device_tokens WHERE token = $1`, token)
+	return err
+}
+
+func (r *deviceTokenRepo) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM device_tokens WHERE last_seen_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
+	"agromart2/internal/pii"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -10,65 +13,164 @@ import (
 type DistributorRepository interface {
 	Create(ctx context.Context, distributor *models.Distributor) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Distributor, error)
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Distributor, error)
 	Update(ctx context.Context, distributor *models.Distributor) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Distributor, error)
+	ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Distributor, error)
 	GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Distributor, error)
+	// FindByContact finds distributors whose email, phone, GSTIN or bank
+	// account number is exactly value
+	FindByContact(ctx context.Context, tenantID uuid.UUID, value string) ([]*models.Distributor, error)
+	Archive(ctx context.Context, tenantID, id uuid.UUID) error
+	OrderCount(ctx context.Context, tenantID, id uuid.UUID) (int, error)
 }
 
 type distributorRepo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
 }
 
-func NewDistributorRepository(db *pgxpool.Pool) DistributorRepository {
-	return &distributorRepo{db: db}
+// NewDistributorRepository creates the repository; contact emails, phones,
+// GSTINs and bank account numbers are encrypted with keyring, or kept in
+// plaintext when it is nil
+func NewDistributorRepository(db *pgxpool.Pool, keyring *pii.DataKeyRing) DistributorRepository {
+	return &distributorRepo{db: db, keyring: keyring}
+}
+
+// open decrypts a distributor's contact columns after a scan
+func (r *distributorRepo) open(ctx context.Context, distributor *models.Distributor) error {
+	return r.keyring.DecryptFields(ctx, distributor.TenantID, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.GSTIN, &distributor.BankAccountNumber)
 }
 
 func (r *distributorRepo) Create(ctx context.Context, distributor *models.Distributor) error {
+	contact, err := sealContact(ctx, r.keyring, distributor.TenantID, distributor.ContactEmail, distributor.ContactPhone, distributor.GSTIN, distributor.BankAccountNumber)
+	if err != nil {
+		return err
+	}
 	query := `
-		INSERT INTO distributors (id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		INSERT INTO distributors (id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, latitude, longitude, geofence_radius_m, preferred_language,
+			contact_email_bidx, contact_phone_bidx, gstin_bidx, bank_account_number, bank_ifsc, bank_account_number_bidx, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, NOW(), NOW())
 	`
-	_, err := r.db.Exec(ctx, query, distributor.ID, distributor.TenantID, distributor.Name, distributor.ContactEmail, distributor.ContactPhone, distributor.Address, distributor.LicenseNumber)
+	_, err = r.db.Exec(ctx, query, distributor.ID, distributor.TenantID, distributor.Name, contact.email, contact.phone, distributor.Address, distributor.LicenseNumber, contact.gstin, distributor.Latitude, distributor.Longitude, distributor.GeofenceRadiusM, distributor.PreferredLanguage,
+		contact.emailIndex, contact.phoneIndex, contact.gstinIndex, contact.bankAccount, distributor.BankIFSC, contact.bankAccountIndex)
 	return err
 }
 
 func (r *distributorRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Distributor, error) {
 	distributor := &models.Distributor{}
 	query := `
-		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, latitude, longitude, geofence_radius_m, preferred_language, created_at, updated_at
 		FROM distributors
 		WHERE tenant_id = $1 AND id = $2
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.CreatedAt, &distributor.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.GSTIN, &distributor.BankAccountNumber, &distributor.BankIFSC, &distributor.ArchivedAt, &distributor.Latitude, &distributor.Longitude, &distributor.GeofenceRadiusM, &distributor.PreferredLanguage, &distributor.CreatedAt, &distributor.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.open(ctx, distributor); err != nil {
+		return nil, err
+	}
 	return distributor, nil
 }
 
+// GetByIDs loads the tenant's distributors among ids in one query, archived
+// ones included; unknown IDs are skipped
+func (r *distributorRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Distributor, error) {
+	query := `
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, latitude, longitude, geofence_radius_m, preferred_language, created_at, updated_at
+		FROM distributors
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var distributors []*models.Distributor
+	for rows.Next() {
+		distributor := &models.Distributor{}
+		if err := rows.Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.GSTIN, &distributor.BankAccountNumber, &distributor.BankIFSC, &distributor.ArchivedAt, &distributor.Latitude, &distributor.Longitude, &distributor.GeofenceRadiusM, &distributor.PreferredLanguage, &distributor.CreatedAt, &distributor.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := r.open(ctx, distributor); err != nil {
+			return nil, err
+		}
+		distributors = append(distributors, distributor)
+	}
+	return distributors, rows.Err()
+}
+
 func (r *distributorRepo) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Distributor, error) {
 	distributor := &models.Distributor{}
 	query := `
-		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, latitude, longitude, geofence_radius_m, preferred_language, created_at, updated_at
 		FROM distributors
-		WHERE tenant_id = $1 AND name = $2
+		WHERE tenant_id = $1 AND LOWER(name) = LOWER($2) AND archived_at IS NULL
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, name).Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.CreatedAt, &distributor.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, name).Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.GSTIN, &distributor.BankAccountNumber, &distributor.BankIFSC, &distributor.ArchivedAt, &distributor.Latitude, &distributor.Longitude, &distributor.GeofenceRadiusM, &distributor.PreferredLanguage, &distributor.CreatedAt, &distributor.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.open(ctx, distributor); err != nil {
+		return nil, err
+	}
 	return distributor, nil
 }
 
+// FindByContact matches through the blind indexes; rows written before
+// encryption was enabled have none and are compared in plaintext
+func (r *distributorRepo) FindByContact(ctx context.Context, tenantID uuid.UUID, value string) ([]*models.Distributor, error) {
+	query := `
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, latitude, longitude, geofence_radius_m, preferred_language, created_at, updated_at
+		FROM distributors
+		WHERE tenant_id = $1 AND archived_at IS NULL AND (
+			contact_email_bidx = $2 OR contact_phone_bidx = $2 OR gstin_bidx = $2 OR bank_account_number_bidx = $2
+			OR (contact_email_bidx IS NULL AND LOWER(contact_email) = LOWER($3))
+			OR (contact_phone_bidx IS NULL AND contact_phone = $3)
+			OR (gstin_bidx IS NULL AND UPPER(gstin) = UPPER($3))
+			OR (bank_account_number_bidx IS NULL AND bank_account_number = $3)
+		)
+		ORDER BY name, id
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, r.keyring.BlindIndex(tenantID, &value), value)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var distributors []*models.Distributor
+	for rows.Next() {
+		distributor := &models.Distributor{}
+		if err := rows.Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.GSTIN, &distributor.BankAccountNumber, &distributor.BankIFSC, &distributor.ArchivedAt, &distributor.Latitude, &distributor.Longitude, &distributor.GeofenceRadiusM, &distributor.PreferredLanguage, &distributor.CreatedAt, &distributor.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := r.open(ctx, distributor); err != nil {
+			return nil, err
+		}
+		distributors = append(distributors, distributor)
+	}
+	return distributors, rows.Err()
+}
+
 func (r *distributorRepo) Update(ctx context.Context, distributor *models.Distributor) error {
+	contact, err := sealContact(ctx, r.keyring, distributor.TenantID, distributor.ContactEmail, distributor.ContactPhone, distributor.GSTIN, distributor.BankAccountNumber)
+	if err != nil {
+		return err
+	}
 	query := `
 		UPDATE distributors
-		SET name = $1, contact_email = $2, contact_phone = $3, address = $4, license_number = $5, updated_at = NOW()
-		WHERE tenant_id = $6 AND id = $7
+		SET name = $1, contact_email = $2, contact_phone = $3, address = $4, license_number = $5, gstin = $6, latitude = $7, longitude = $8, geofence_radius_m = $9, preferred_language = $10,
+			contact_email_bidx = $13, contact_phone_bidx = $14, gstin_bidx = $15,
+			bank_account_number = $16, bank_ifsc = $17, bank_account_number_bidx = $18, updated_at = NOW()
+		WHERE tenant_id = $11 AND id = $12
 	`
-	_, err := r.db.Exec(ctx, query, distributor.Name, distributor.ContactEmail, distributor.ContactPhone, distributor.Address, distributor.LicenseNumber, distributor.TenantID, distributor.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, distributor.ID, "updated_at", query, []interface{}{distributor.Name, contact.email, contact.phone, distributor.Address, distributor.LicenseNumber, contact.gstin, distributor.Latitude, distributor.Longitude, distributor.GeofenceRadiusM, distributor.PreferredLanguage, distributor.TenantID, distributor.ID,
+		contact.emailIndex, contact.phoneIndex, contact.gstinIndex, contact.bankAccount, distributor.BankIFSC, contact.bankAccountIndex})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *distributorRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -77,15 +179,27 @@ func (r *distributorRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) er
 	return err
 }
 
+// DistributorSortColumns whitelists the fields the distributor list can be sorted by
+var DistributorSortColumns = map[string]string{
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 func (r *distributorRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Distributor, error) {
-	query := `
-		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at
+	return r.ListPage(ctx, tenantID, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists distributors in the page's sort order, newest first by default
+func (r *distributorRepo) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Distributor, error) {
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, latitude, longitude, geofence_radius_m, preferred_language, created_at, updated_at
 		FROM distributors
-		WHERE tenant_id = $1
-		ORDER BY created_at DESC
+		WHERE tenant_id = $1 AND archived_at IS NULL
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("created_at DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -94,10 +208,28 @@ func (r *distributorRepo) List(ctx context.Context, tenantID uuid.UUID, limit, o
 	var distributors []*models.Distributor
 	for rows.Next() {
 		distributor := &models.Distributor{}
-		if err := rows.Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.CreatedAt, &distributor.UpdatedAt); err != nil {
+		if err := rows.Scan(&distributor.ID, &distributor.TenantID, &distributor.Name, &distributor.ContactEmail, &distributor.ContactPhone, &distributor.Address, &distributor.LicenseNumber, &distributor.GSTIN, &distributor.BankAccountNumber, &distributor.BankIFSC, &distributor.ArchivedAt, &distributor.Latitude, &distributor.Longitude, &distributor.GeofenceRadiusM, &distributor.PreferredLanguage, &distributor.CreatedAt, &distributor.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := r.open(ctx, distributor); err != nil {
 			return nil, err
 		}
 		distributors = append(distributors, distributor)
 	}
 	return distributors, nil
-}
\ No newline at end of file
+}
+
+// Archive hides a distributor from lists and name lookups while keeping it
+// for the orders that reference it
+func (r *distributorRepo) Archive(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `UPDATE distributors SET archived_at = NOW(), updated_at = NOW() WHERE tenant_id = $1 AND id = $2 AND archived_at IS NULL`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+// OrderCount counts the orders that reference the distributor
+func (r *distributorRepo) OrderCount(ctx context.Context, tenantID, id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND distributor_id = $2`, tenantID, id).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DunningRepository interface {
+	GetSchedule(ctx context.Context, tenantID uuid.UUID) (*models.DunningSchedule, error)
+	UpsertSchedule(ctx context.Context, schedule *models.DunningSchedule) error
+
+	ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.DunningTemplate, error)
+	UpsertTemplate(ctx context.Context, template *models.DunningTemplate) error
+	DeleteTemplate(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+	FindTemplate(ctx context.Context, tenantID uuid.UUID, channel string, languages []string) (*models.DunningTemplate, error)
+
+	OutstandingInvoices(ctx context.Context, tenantID uuid.UUID, dueFrom, dueTo time.Time) ([]*models.DunningCandidate, error)
+	IsOutstanding(ctx context.Context, tenantID, invoiceID uuid.UUID) (bool, error)
+	RecordReminder(ctx context.Context, reminder *models.InvoiceReminder) (bool, error)
+	ListReminders(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceReminder, error)
+}
+
+type dunningRepo struct {
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
+}
+
+func NewDunningRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) DunningRepository {
+	return &dunningRepo{db: db, keyring: keyring}
+}
+
+// GetSchedule returns the tenant's cadence, or nil when it has not configured one
+func (r *dunningRepo) GetSchedule(ctx context.Context, tenantID uuid.UUID) (*models.DunningSchedule, error) {
+	query := `
+		SELECT tenant_id, enabled, offset_days, channels, default_language, updated_at
+		FROM dunning_schedules
+		WHERE tenant_id = $1
+	`
+	schedule := &models.DunningSchedule{}
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&schedule.TenantID, &schedule.Enabled, &schedule.OffsetDays,
+		&schedule.Channels, &schedule.DefaultLanguage, &schedule.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (r *dunningRepo) UpsertSchedule(ctx context.Context, schedule *models.DunningSchedule) error {
+	query := `
+		INSERT INTO dunning_schedules (tenant_id, enabled, offset_days, channels, default_language, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, offset_days = EXCLUDED.offset_days, channels = EXCLUDED.channels,
+			default_language = EXCLUDED.default_language, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, schedule.TenantID, schedule.Enabled, schedule.OffsetDays, schedule.Channels,
+		schedule.DefaultLanguage).Scan(&schedule.UpdatedAt)
+}
+
+const dunningTemplateColumns = `id, tenant_id, channel, language, subject, body, created_at, updated_at`
+
+func scanDunningTemplate(row rowScanner) (*models.DunningTemplate, error) {
+	template := &models.DunningTemplate{}
+	err := row.Scan(&template.ID, &template.TenantID, &template.Channel, &template.Language, &template.Subject,
+		&template.Body, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (r *dunningRepo) ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.DunningTemplate, error) {
+	query := `SELECT ` + dunningTemplateColumns + ` FROM dunning_templates WHERE tenant_id = $1 ORDER BY channel, language`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*models.DunningTemplate
+	for rows.Next() {
+		template, err := scanDunningTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// UpsertTemplate saves the wording for a channel and language, replacing any
+// existing template for the pair
+func (r *dunningRepo) UpsertTemplate(ctx context.Context, template *models.DunningTemplate) error {
+	query := `
+		INSERT INTO dunning_templates (id, tenant_id, channel, language, subject, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (tenant_id, channel, language) DO UPDATE
+		SET subject = EXCLUDED.subject, body = EXCLUDED.body, updated_at = NOW()
+		RETURNING ` + dunningTemplateColumns
+	saved, err := scanDunningTemplate(r.db.QueryRow(ctx, query, template.ID, template.TenantID, template.Channel, template.Language,
+		template.Subject, template.Body))
+	if err != nil {
+		return err
+	}
+	*template = *saved
+	return nil
+}
+
+func (r *dunningRepo) DeleteTemplate(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM dunning_templates WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// FindTemplate returns the channel's template in the first of languages the
+// tenant has one for, or nil
+func (r *dunningRepo) FindTemplate(ctx context.Context, tenantID uuid.UUID, channel string, languages []string) (*models.DunningTemplate, error) {
+	query := `
+		SELECT ` + dunningTemplateColumns + `
+		FROM dunning_templates
+		WHERE tenant_id = $1 AND channel = $2 AND language = ANY($3::text[])
+		ORDER BY array_position($3::text[], language::text)
+		LIMIT 1
+	`
+	template, err := scanDunningTemplate(r.db.QueryRow(ctx, query, tenantID, channel, languages))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return template, err
+}
+
+// OutstandingInvoices lists unpaid and overdue sales invoices due between
+// dueFrom and dueTo, with the cadence offsets already handled for each
+func (r *dunningRepo) OutstandingInvoices(ctx context.Context, tenantID uuid.UUID, dueFrom, dueTo time.Time) ([]*models.DunningCandidate, error) {
+	query := `
+		SELECT i.id, i.invoice_number, i.total_amount, i.due_date, d.id, d.name, d.contact_phone, d.contact_email, d.preferred_language,
+			ARRAY(SELECT DISTINCT r.offset_days FROM invoice_reminders r WHERE r.invoice_id = i.id)
+		FROM invoices i
+		JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		JOIN distributors d ON d.id = o.distributor_id AND d.tenant_id = i.tenant_id
+		WHERE i.tenant_id = $1
+			AND i.status IN ('unpaid', 'overdue')
+			AND i.due_date::date BETWEEN $2::date AND $3::date
+		ORDER BY i.due_date
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, dueFrom, dueTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []*models.DunningCandidate
+	for rows.Next() {
+		candidate := &models.DunningCandidate{}
+		if err := rows.Scan(&candidate.InvoiceID, &candidate.InvoiceNumber, &candidate.TotalAmount, &candidate.DueDate,
+			&candidate.DistributorID, &candidate.DistributorName, &candidate.ContactPhone, &candidate.ContactEmail,
+			&candidate.PreferredLanguage, &candidate.DoneOffsets); err != nil {
+			return nil, err
+		}
+		if err := r.keyring.DecryptFields(ctx, tenantID, &candidate.ContactPhone, &candidate.ContactEmail); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+// IsOutstanding reports whether an invoice is still awaiting payment
+func (r *dunningRepo) IsOutstanding(ctx context.Context, tenantID, invoiceID uuid.UUID) (bool, error) {
+	var outstanding bool
+	query := `SELECT EXISTS (SELECT 1 FROM invoices WHERE tenant_id = $1 AND id = $2 AND status IN ('unpaid', 'overdue'))`
+	err := r.db.QueryRow(ctx, query, tenantID, invoiceID).Scan(&outstanding)
+	return outstanding, err
+}
+
+// RecordReminder logs a reminder, reporting false when the step was already
+// recorded for the channel
+func (r *dunningRepo) RecordReminder(ctx context.Context, reminder *models.InvoiceReminder) (bool, error) {
+	query := `
+		INSERT INTO invoice_reminders (id, tenant_id, invoice_id, offset_days, channel, recipient, language, status, error_message, sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		ON CONFLICT (invoice_id, offset_days, channel) DO NOTHING
+		RETURNING sent_at
+	`
+	err := r.db.QueryRow(ctx, query, reminder.ID, reminder.TenantID, reminder.InvoiceID, reminder.OffsetDays, reminder.Channel,
+		reminder.Recipient, reminder.Language, reminder.Status, reminder.ErrorMessage).Scan(&reminder.SentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *dunningRepo) ListReminders(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceReminder, error) {
+	query := `
+		SELECT id, tenant_id, invoice_id, offset_days, channel, recipient, language, status, error_message, sent_at
+		FROM invoice_reminders
+		WHERE tenant_id = $1 AND invoice_id = $2
+		ORDER BY sent_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*models.InvoiceReminder
+	for rows.Next() {
+		reminder := &models.InvoiceReminder{}
+		if err := rows.Scan(&reminder.ID, &reminder.TenantID, &reminder.InvoiceID, &reminder.OffsetDays, &reminder.Channel,
+			&reminder.Recipient, &reminder.Language, &reminder.Status, &reminder.ErrorMessage, &reminder.SentAt); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
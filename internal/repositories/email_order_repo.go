@@ -0,0 +1,348 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EmailOrderRepository interface {
+	GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.EmailOrderSettings, error)
+	GetSettingsByToken(ctx context.Context, token string) (*models.EmailOrderSettings, error)
+	UpsertSettings(ctx context.Context, settings *models.EmailOrderSettings) error
+
+	ListSenders(ctx context.Context, tenantID uuid.UUID) ([]*models.EmailOrderSender, error)
+	// CreateSender reports false when the address is already known
+	CreateSender(ctx context.Context, sender *models.EmailOrderSender) (bool, error)
+	DeleteSender(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+	// MatchSender finds the sender entry for an address, preferring the exact
+	// address over its domain
+	MatchSender(ctx context.Context, tenantID uuid.UUID, address string) (*models.EmailOrderSender, error)
+
+	// CreateEmail reports false when a message with the same Message-ID was
+	// already received
+	CreateEmail(ctx context.Context, email *models.InboundEmail) (bool, error)
+	ListEmails(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InboundEmail, error)
+	ListPendingEmails(ctx context.Context, limit int) ([]*models.InboundEmail, error)
+	MarkEmail(ctx context.Context, email *models.InboundEmail) error
+	// SaveDraft stores the draft and marks its email parsed in one transaction
+	SaveDraft(ctx context.Context, email *models.InboundEmail, draft *models.EmailDraftOrder) error
+
+	ListDrafts(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.EmailDraftOrder, error)
+	GetDraft(ctx context.Context, tenantID, id uuid.UUID) (*models.EmailDraftOrder, error)
+	// UpdateDraft and ReviewDraft report false unless the draft is still
+	// pending review
+	UpdateDraft(ctx context.Context, draft *models.EmailDraftOrder) (bool, error)
+	ReviewDraft(ctx context.Context, draft *models.EmailDraftOrder) (bool, error)
+}
+
+type emailOrderRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailOrderRepo(db *pgxpool.Pool) EmailOrderRepository {
+	return &emailOrderRepo{db: db}
+}
+
+const (
+	emailOrderSettingsColumns = `tenant_id, inbound_token, enabled, default_warehouse_id, line_pattern, sku_column, quantity_column,
+		price_column, created_at, updated_at`
+	inboundEmailColumns = `id, tenant_id, message_id, from_address, subject, body_text, attachments, status, error, received_at, processed_at`
+	emailDraftColumns   = `d.id, d.tenant_id, d.email_id, e.from_address, e.subject, d.distributor_id, d.warehouse_id, d.status,
+		d.lines, d.order_ids, d.review_note, d.reviewed_by, d.reviewed_at, d.created_at, d.updated_at`
+)
+
+func scanEmailOrderSettings(row rowScanner) (*models.EmailOrderSettings, error) {
+	s := &models.EmailOrderSettings{}
+	err := row.Scan(&s.TenantID, &s.InboundToken, &s.Enabled, &s.DefaultWarehouseID, &s.LinePattern, &s.SKUColumn,
+		&s.QuantityColumn, &s.PriceColumn, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func scanInboundEmail(row rowScanner) (*models.InboundEmail, error) {
+	e := &models.InboundEmail{}
+	err := row.Scan(&e.ID, &e.TenantID, &e.MessageID, &e.FromAddress, &e.Subject, &e.BodyText, &e.Attachments, &e.Status,
+		&e.Error, &e.ReceivedAt, &e.ProcessedAt)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func scanEmailDraft(row rowScanner) (*models.EmailDraftOrder, error) {
+	d := &models.EmailDraftOrder{}
+	err := row.Scan(&d.ID, &d.TenantID, &d.EmailID, &d.FromAddress, &d.Subject, &d.DistributorID, &d.WarehouseID, &d.Status,
+		&d.Lines, &d.OrderIDs, &d.ReviewNote, &d.ReviewedBy, &d.ReviewedAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *emailOrderRepo) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.EmailOrderSettings, error) {
+	query := `SELECT ` + emailOrderSettingsColumns + ` FROM email_order_settings WHERE tenant_id = $1`
+	return scanEmailOrderSettings(r.db.QueryRow(ctx, query, tenantID))
+}
+
+func (r *emailOrderRepo) GetSettingsByToken(ctx context.Context, token string) (*models.EmailOrderSettings, error) {
+	query := `SELECT ` + emailOrderSettingsColumns + ` FROM email_order_settings WHERE inbound_token = $1`
+	return scanEmailOrderSettings(r.db.QueryRow(ctx, query, token))
+}
+
+func (r *emailOrderRepo) UpsertSettings(ctx context.Context, settings *models.EmailOrderSettings) error {
+	query := `
+		INSERT INTO email_order_settings (tenant_id, inbound_token, enabled, default_warehouse_id, line_pattern, sku_column,
+			quantity_column, price_column, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			inbound_token = EXCLUDED.inbound_token,
+			enabled = EXCLUDED.enabled,
+			default_warehouse_id = EXCLUDED.default_warehouse_id,
+			line_pattern = EXCLUDED.line_pattern,
+			sku_column = EXCLUDED.sku_column,
+			quantity_column = EXCLUDED.quantity_column,
+			price_column = EXCLUDED.price_column,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, settings.TenantID, settings.InboundToken, settings.Enabled, settings.DefaultWarehouseID,
+		settings.LinePattern, settings.SKUColumn, settings.QuantityColumn, settings.PriceColumn).
+		Scan(&settings.CreatedAt, &settings.UpdatedAt)
+}
+
+func (r *emailOrderRepo) ListSenders(ctx context.Context, tenantID uuid.UUID) ([]*models.EmailOrderSender, error) {
+	query := `
+		SELECT id, tenant_id, address, distributor_id, warehouse_id, created_at
+		FROM email_order_senders
+		WHERE tenant_id = $1
+		ORDER BY address
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var senders []*models.EmailOrderSender
+	for rows.Next() {
+		s := &models.EmailOrderSender{}
+		if err := rows.Scan(&s.ID, &s.TenantID, &s.Address, &s.DistributorID, &s.WarehouseID, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		senders = append(senders, s)
+	}
+	return senders, rows.Err()
+}
+
+func (r *emailOrderRepo) CreateSender(ctx context.Context, sender *models.EmailOrderSender) (bool, error) {
+	query := `
+		INSERT INTO email_order_senders (id, tenant_id, address, distributor_id, warehouse_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (tenant_id, address) DO NOTHING
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, sender.ID, sender.TenantID, sender.Address, sender.DistributorID, sender.WarehouseID).
+		Scan(&sender.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *emailOrderRepo) DeleteSender(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM email_order_senders WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *emailOrderRepo) MatchSender(ctx context.Context, tenantID uuid.UUID, address string) (*models.EmailOrderSender, error) {
+	address = strings.ToLower(address)
+	domain := ""
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		domain = address[at:]
+	}
+	query := `
+		SELECT id, tenant_id, address, distributor_id, warehouse_id, created_at
+		FROM email_order_senders
+		WHERE tenant_id = $1 AND address IN ($2, $3)
+		ORDER BY address = $2 DESC
+		LIMIT 1
+	`
+	s := &models.EmailOrderSender{}
+	err := r.db.QueryRow(ctx, query, tenantID, address, domain).
+		Scan(&s.ID, &s.TenantID, &s.Address, &s.DistributorID, &s.WarehouseID, &s.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *emailOrderRepo) CreateEmail(ctx context.Context, email *models.InboundEmail) (bool, error) {
+	query := `
+		INSERT INTO inbound_emails (id, tenant_id, message_id, from_address, subject, body_text, attachments, status, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (tenant_id, message_id) WHERE message_id IS NOT NULL DO NOTHING
+		RETURNING received_at
+	`
+	err := r.db.QueryRow(ctx, query, email.ID, email.TenantID, email.MessageID, email.FromAddress, email.Subject, email.BodyText,
+		email.Attachments, email.Status).Scan(&email.ReceivedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *emailOrderRepo) ListEmails(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InboundEmail, error) {
+	query := `
+		SELECT ` + inboundEmailColumns + `
+		FROM inbound_emails
+		WHERE tenant_id = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY received_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	return r.queryEmails(ctx, query, tenantID, status, limit, offset)
+}
+
+func (r *emailOrderRepo) ListPendingEmails(ctx context.Context, limit int) ([]*models.InboundEmail, error) {
+	query := `
+		SELECT ` + inboundEmailColumns + `
+		FROM inbound_emails
+		WHERE status = 'pending'
+		ORDER BY received_at
+		LIMIT $1
+	`
+	return r.queryEmails(ctx, query, limit)
+}
+
+func (r *emailOrderRepo) queryEmails(ctx context.Context, query string, args ...interface{}) ([]*models.InboundEmail, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*models.InboundEmail
+	for rows.Next() {
+		email, err := scanInboundEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+func (r *emailOrderRepo) MarkEmail(ctx context.Context, email *models.InboundEmail) error {
+	_, err := r.db.Exec(ctx, `UPDATE inbound_emails SET status = $2, error = $3, processed_at = NOW() WHERE id = $1`,
+		email.ID, email.Status, email.Error)
+	return err
+}
+
+func (r *emailOrderRepo) SaveDraft(ctx context.Context, email *models.InboundEmail, draft *models.EmailDraftOrder) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO email_draft_orders (id, tenant_id, email_id, distributor_id, warehouse_id, status, lines, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err = tx.QueryRow(ctx, query, draft.ID, draft.TenantID, draft.EmailID, draft.DistributorID, draft.WarehouseID, draft.Status,
+		draft.Lines).Scan(&draft.CreatedAt, &draft.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE inbound_emails SET status = $2, error = NULL, processed_at = NOW() WHERE id = $1`,
+		email.ID, models.InboundEmailParsed); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *emailOrderRepo) ListDrafts(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.EmailDraftOrder, error) {
+	query := `
+		SELECT ` + emailDraftColumns + `
+		FROM email_draft_orders d
+		JOIN inbound_emails e ON e.id = d.email_id
+		WHERE d.tenant_id = $1 AND ($2::text = '' OR d.status = $2)
+		ORDER BY d.created_at
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drafts []*models.EmailDraftOrder
+	for rows.Next() {
+		draft, err := scanEmailDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, rows.Err()
+}
+
+func (r *emailOrderRepo) GetDraft(ctx context.Context, tenantID, id uuid.UUID) (*models.EmailDraftOrder, error) {
+	query := `
+		SELECT ` + emailDraftColumns + `
+		FROM email_draft_orders d
+		JOIN inbound_emails e ON e.id = d.email_id
+		WHERE d.tenant_id = $1 AND d.id = $2
+	`
+	draft, err := scanEmailDraft(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return draft, err
+}
+
+func (r *emailOrderRepo) UpdateDraft(ctx context.Context, draft *models.EmailDraftOrder) (bool, error) {
+	query := `
+		UPDATE email_draft_orders SET warehouse_id = $3, lines = $4, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'pending_review'
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, draft.TenantID, draft.ID, draft.WarehouseID, draft.Lines).Scan(&draft.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *emailOrderRepo) ReviewDraft(ctx context.Context, draft *models.EmailDraftOrder) (bool, error) {
+	query := `
+		UPDATE email_draft_orders SET status = $3, order_ids = $4, review_note = $5, reviewed_by = $6, reviewed_at = NOW(),
+			updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'pending_review'
+		RETURNING reviewed_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, draft.TenantID, draft.ID, draft.Status, draft.OrderIDs, draft.ReviewNote, draft.ReviewedBy).
+		Scan(&draft.ReviewedAt, &draft.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ERPConnectorRepository interface {
+	CreateConnector(ctx context.Context, connector *models.ERPConnector) error
+	GetConnector(ctx context.Context, tenantID, id uuid.UUID) (*models.ERPConnector, error)
+	ListConnectors(ctx context.Context, tenantID uuid.UUID) ([]*models.ERPConnector, error)
+	ListDueConnectors(ctx context.Context, now time.Time, limit int) ([]*models.ERPConnector, error)
+	UpdateConnector(ctx context.Context, connector *models.ERPConnector) error
+	DeleteConnector(ctx context.Context, tenantID, id uuid.UUID) error
+	ScheduleConnector(ctx context.Context, id uuid.UUID, syncedThrough *time.Time, nextSyncAt *time.Time) error
+	CreateRun(ctx context.Context, run *models.ERPSyncRun) error
+	FinishRun(ctx context.Context, run *models.ERPSyncRun) error
+	GetRun(ctx context.Context, tenantID, id uuid.UUID) (*models.ERPSyncRun, error)
+	ListRuns(ctx context.Context, tenantID, connectorID uuid.UUID, limit, offset int) ([]*models.ERPSyncRun, error)
+	GetSyncStatuses(ctx context.Context, tenantID uuid.UUID) ([]*models.ERPSyncStatus, error)
+}
+
+type erpConnectorRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewERPConnectorRepo(db *pgxpool.Pool) ERPConnectorRepository {
+	return &erpConnectorRepo{db: db}
+}
+
+const erpConnectorColumns = `id, tenant_id, name, connector_type, document_types, settings, sync_interval_minutes, synced_through, next_sync_at, is_active, created_at, updated_at`
+
+func scanERPConnector(row rowScanner) (*models.ERPConnector, error) {
+	connector := &models.ERPConnector{}
+	err := row.Scan(&connector.ID, &connector.TenantID, &connector.Name, &connector.ConnectorType, &connector.DocumentTypes, &connector.Settings,
+		&connector.SyncIntervalMinutes, &connector.SyncedThrough, &connector.NextSyncAt, &connector.IsActive, &connector.CreatedAt, &connector.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return connector, nil
+}
+
+func (r *erpConnectorRepo) CreateConnector(ctx context.Context, connector *models.ERPConnector) error {
+	query := `
+		INSERT INTO erp_connectors (id, tenant_id, name, connector_type, document_types, settings, sync_interval_minutes, next_sync_at, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, connector.ID, connector.TenantID, connector.Name, connector.ConnectorType, connector.DocumentTypes,
+		connector.Settings, connector.SyncIntervalMinutes, connector.NextSyncAt, connector.IsActive).Scan(&connector.CreatedAt, &connector.UpdatedAt)
+}
+
+func (r *erpConnectorRepo) GetConnector(ctx context.Context, tenantID, id uuid.UUID) (*models.ERPConnector, error) {
+	query := `SELECT ` + erpConnectorColumns + ` FROM erp_connectors WHERE tenant_id = $1 AND id = $2`
+	return scanERPConnector(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+func (r *erpConnectorRepo) queryConnectors(ctx context.Context, query string, args ...interface{}) ([]*models.ERPConnector, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var connectors []*models.ERPConnector
+	for rows.Next() {
+		connector, err := scanERPConnector(rows)
+		if err != nil {
+			return nil, err
+		}
+		connectors = append(connectors, connector)
+	}
+	return connectors, rows.Err()
+}
+
+func (r *erpConnectorRepo) ListConnectors(ctx context.Context, tenantID uuid.UUID) ([]*models.ERPConnector, error) {
+	query := `SELECT ` + erpConnectorColumns + ` FROM erp_connectors WHERE tenant_id = $1 ORDER BY name`
+	return r.queryConnectors(ctx, query, tenantID)
+}
+
+// ListDueConnectors returns scheduled connectors across all tenants whose next sync has passed
+func (r *erpConnectorRepo) ListDueConnectors(ctx context.Context, now time.Time, limit int) ([]*models.ERPConnector, error) {
+	query := `
+		SELECT ` + erpConnectorColumns + `
+		FROM erp_connectors
+		WHERE is_active AND sync_interval_minutes > 0 AND (next_sync_at IS NULL OR next_sync_at <= $1)
+		ORDER BY next_sync_at NULLS FIRST
+		LIMIT $2
+	`
+	return r.queryConnectors(ctx, query, now, limit)
+}
+
+func (r *erpConnectorRepo) UpdateConnector(ctx context.Context, connector *models.ERPConnector) error {
+	query := `
+		UPDATE erp_connectors
+		SET name = $1, document_types = $2, settings = $3, sync_interval_minutes = $4, next_sync_at = $5, is_active = $6, updated_at = NOW()
+		WHERE tenant_id = $7 AND id = $8
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, connector.Name, connector.DocumentTypes, connector.Settings, connector.SyncIntervalMinutes,
+		connector.NextSyncAt, connector.IsActive, connector.TenantID, connector.ID).Scan(&connector.UpdatedAt)
+}
+
+func (r *erpConnectorRepo) DeleteConnector(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `DELETE FROM erp_connectors WHERE tenant_id = $1 AND id = $2`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+// ScheduleConnector advances the watermark (when given) and the next sync time
+func (r *erpConnectorRepo) ScheduleConnector(ctx context.Context, id uuid.UUID, syncedThrough *time.Time, nextSyncAt *time.Time) error {
+	query := `
+		UPDATE erp_connectors
+		SET synced_through = COALESCE($1, synced_through), next_sync_at = $2, updated_at = NOW()
+		WHERE id = $3
+	`
+	_, err := r.db.Exec(ctx, query, syncedThrough, nextSyncAt, id)
+	return err
+}
+
+func (r *erpConnectorRepo) CreateRun(ctx context.Context, run *models.ERPSyncRun) error {
+	query := `
+		INSERT INTO erp_sync_runs (id, tenant_id, connector_id, trigger, status, period_start, period_end, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING started_at
+	`
+	return r.db.QueryRow(ctx, query, run.ID, run.TenantID, run.ConnectorID, run.Trigger, run.Status, run.PeriodStart, run.PeriodEnd).Scan(&run.StartedAt)
+}
+
+func (r *erpConnectorRepo) FinishRun(ctx context.Context, run *models.ERPSyncRun) error {
+	query := `
+		UPDATE erp_sync_runs
+		SET status = $1, records_exported = $2, file_name = $3, object_key = $4, delivered = $5, error_message = $6, finished_at = NOW()
+		WHERE id = $7
+		RETURNING finished_at
+	`
+	return r.db.QueryRow(ctx, query, run.Status, run.RecordsExported, run.FileName, run.ObjectKey, run.Delivered, run.ErrorMessage, run.ID).Scan(&run.FinishedAt)
+}
+
+const erpRunColumns = `id, tenant_id, connector_id, trigger, status, period_start, period_end, records_exported, file_name, object_key, delivered, error_message, started_at, finished_at`
+
+func scanERPRun(row rowScanner) (*models.ERPSyncRun, error) {
+	run := &models.ERPSyncRun{}
+	err := row.Scan(&run.ID, &run.TenantID, &run.ConnectorID, &run.Trigger, &run.Status, &run.PeriodStart, &run.PeriodEnd, &run.RecordsExported,
+		&run.FileName, &run.ObjectKey, &run.Delivered, &run.ErrorMessage, &run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *erpConnectorRepo) GetRun(ctx context.Context, tenantID, id uuid.UUID) (*models.ERPSyncRun, error) {
+	query := `SELECT ` + erpRunColumns + ` FROM erp_sync_runs WHERE tenant_id = $1 AND id = $2`
+	return scanERPRun(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+func (r *erpConnectorRepo) ListRuns(ctx context.Context, tenantID, connectorID uuid.UUID, limit, offset int) ([]*models.ERPSyncRun, error) {
+	query := `
+		SELECT ` + erpRunColumns + `
+		FROM erp_sync_runs
+		WHERE tenant_id = $1 AND connector_id = $2
+		ORDER BY started_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, connectorID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*models.ERPSyncRun
+	for rows.Next() {
+		run, err := scanERPRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetSyncStatuses returns every connector with its latest run, last success
+// and recent failure count; Health is left for the caller to derive
+func (r *erpConnectorRepo) GetSyncStatuses(ctx context.Context, tenantID uuid.UUID) ([]*models.ERPSyncStatus, error) {
+	connectors, err := r.ListConnectors(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*models.ERPSyncStatus, 0, len(connectors))
+	for _, connector := range connectors {
+		status := &models.ERPSyncStatus{Connector: connector}
+
+		lastRunQuery := `SELECT ` + erpRunColumns + ` FROM erp_sync_runs WHERE connector_id = $1 ORDER BY started_at DESC LIMIT 1`
+		if run, err := scanERPRun(r.db.QueryRow(ctx, lastRunQuery, connector.ID)); err == nil {
+			status.LastRun = run
+		}
+
+		summaryQuery := `
+			SELECT MAX(finished_at) FILTER (WHERE status = 'succeeded'),
+				COUNT(*) FILTER (WHERE status = 'failed' AND started_at >= NOW() - INTERVAL '24 hours')
+			FROM erp_sync_runs
+			WHERE connector_id = $1
+		`
+		if err := r.db.QueryRow(ctx, summaryQuery, connector.ID).Scan(&status.LastSuccessAt, &status.FailuresLast24h); err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
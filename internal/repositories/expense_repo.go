@@ -0,0 +1,365 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ExpenseRepository interface {
+	ListCategories(ctx context.Context, tenantID uuid.UUID) ([]*models.ExpenseCategory, error)
+	GetCategory(ctx context.Context, tenantID, id uuid.UUID) (*models.ExpenseCategory, error)
+	// CreateCategory and UpdateCategory report false when the name is taken
+	CreateCategory(ctx context.Context, category *models.ExpenseCategory) (bool, error)
+	UpdateCategory(ctx context.Context, category *models.ExpenseCategory) (bool, error)
+
+	List(ctx context.Context, tenantID uuid.UUID, filter *models.ExpenseFilter) ([]*models.Expense, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.Expense, error)
+	Create(ctx context.Context, expense *models.Expense) error
+	Update(ctx context.Context, expense *models.Expense) (bool, error)
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+
+	AddAttachment(ctx context.Context, attachment *models.ExpenseAttachment) error
+	ListAttachments(ctx context.Context, tenantID, expenseID uuid.UUID) ([]*models.ExpenseAttachment, error)
+	GetAttachment(ctx context.Context, tenantID, id uuid.UUID) (*models.ExpenseAttachment, error)
+	DeleteAttachment(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+
+	ListBudgets(ctx context.Context, tenantID uuid.UUID, month time.Time, warehouseID *uuid.UUID) ([]*models.ExpenseBudget, error)
+	UpsertBudget(ctx context.Context, budget *models.ExpenseBudget) error
+	DeleteBudget(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+
+	// BudgetVsActual sums budgets and expenses per warehouse and category over
+	// the months from through to; rows carry Budget and Actual only
+	BudgetVsActual(ctx context.Context, tenantID uuid.UUID, from, to time.Time, warehouseID *uuid.UUID) ([]*models.BudgetVsActualRow, error)
+}
+
+type expenseRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewExpenseRepo(db *pgxpool.Pool) ExpenseRepository {
+	return &expenseRepo{db: db}
+}
+
+const (
+	expenseCategoryColumns = `id, tenant_id, name, description, active, created_at, updated_at`
+	expenseColumns         = `e.id, e.tenant_id, e.warehouse_id, w.name, e.category_id, c.name, e.expense_date, e.period_month,
+		e.amount::float8, e.description, e.vendor, e.reference, e.created_by, e.created_at, e.updated_at`
+	expenseFrom = `expenses e
+		JOIN warehouses w ON w.id = e.warehouse_id
+		JOIN expense_categories c ON c.id = e.category_id`
+	expenseAttachmentColumns = `id, tenant_id, expense_id, object_key, file_name, content_type, size_bytes, created_at`
+)
+
+func scanExpenseCategory(row rowScanner) (*models.ExpenseCategory, error) {
+	c := &models.ExpenseCategory{}
+	err := row.Scan(&c.ID, &c.TenantID, &c.Name, &c.Description, &c.Active, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func scanExpense(row rowScanner) (*models.Expense, error) {
+	e := &models.Expense{}
+	err := row.Scan(&e.ID, &e.TenantID, &e.WarehouseID, &e.WarehouseName, &e.CategoryID, &e.CategoryName, &e.ExpenseDate,
+		&e.Month, &e.Amount, &e.Description, &e.Vendor, &e.Reference, &e.CreatedBy, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func scanExpenseAttachment(row rowScanner) (*models.ExpenseAttachment, error) {
+	a := &models.ExpenseAttachment{}
+	err := row.Scan(&a.ID, &a.TenantID, &a.ExpenseID, &a.ObjectKey, &a.FileName, &a.ContentType, &a.SizeBytes, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r *expenseRepo) ListCategories(ctx context.Context, tenantID uuid.UUID) ([]*models.ExpenseCategory, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+expenseCategoryColumns+` FROM expense_categories WHERE tenant_id = $1 ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.ExpenseCategory
+	for rows.Next() {
+		category, err := scanExpenseCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *expenseRepo) GetCategory(ctx context.Context, tenantID, id uuid.UUID) (*models.ExpenseCategory, error) {
+	query := `SELECT ` + expenseCategoryColumns + ` FROM expense_categories WHERE tenant_id = $1 AND id = $2`
+	category, err := scanExpenseCategory(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return category, err
+}
+
+func (r *expenseRepo) CreateCategory(ctx context.Context, category *models.ExpenseCategory) (bool, error) {
+	query := `
+		INSERT INTO expense_categories (id, tenant_id, name, description, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, category.ID, category.TenantID, category.Name, category.Description,
+		category.Active).Scan(&category.CreatedAt, &category.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *expenseRepo) UpdateCategory(ctx context.Context, category *models.ExpenseCategory) (bool, error) {
+	query := `
+		UPDATE expense_categories c SET name = $3, description = $4, active = $5, updated_at = NOW()
+		WHERE c.tenant_id = $1 AND c.id = $2
+			AND NOT EXISTS (
+				SELECT 1 FROM expense_categories o
+				WHERE o.tenant_id = c.tenant_id AND o.id <> c.id AND LOWER(o.name) = LOWER($3)
+			)
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, category.TenantID, category.ID, category.Name, category.Description,
+		category.Active).Scan(&category.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List returns expenses newest first
+func (r *expenseRepo) List(ctx context.Context, tenantID uuid.UUID, filter *models.ExpenseFilter) ([]*models.Expense, error) {
+	query := `SELECT ` + expenseColumns + ` FROM ` + expenseFrom + ` WHERE e.tenant_id = $1`
+	args := []interface{}{tenantID}
+	if filter.WarehouseID != nil {
+		args = append(args, *filter.WarehouseID)
+		query += fmt.Sprintf(" AND e.warehouse_id = $%d", len(args))
+	}
+	if filter.CategoryID != nil {
+		args = append(args, *filter.CategoryID)
+		query += fmt.Sprintf(" AND e.category_id = $%d", len(args))
+	}
+	if filter.Month != nil {
+		args = append(args, *filter.Month)
+		query += fmt.Sprintf(" AND e.period_month = $%d::date", len(args))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY e.expense_date DESC, e.created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []*models.Expense
+	for rows.Next() {
+		expense, err := scanExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, expense)
+	}
+	return expenses, rows.Err()
+}
+
+func (r *expenseRepo) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.Expense, error) {
+	query := `SELECT ` + expenseColumns + ` FROM ` + expenseFrom + ` WHERE e.tenant_id = $1 AND e.id = $2`
+	expense, err := scanExpense(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return expense, err
+}
+
+func (r *expenseRepo) Create(ctx context.Context, expense *models.Expense) error {
+	query := `
+		INSERT INTO expenses (id, tenant_id, warehouse_id, category_id, expense_date, period_month, amount, description,
+			vendor, reference, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, expense.ID, expense.TenantID, expense.WarehouseID, expense.CategoryID,
+		expense.ExpenseDate, expense.Month, expense.Amount, expense.Description, expense.Vendor, expense.Reference,
+		expense.CreatedBy).Scan(&expense.CreatedAt, &expense.UpdatedAt)
+}
+
+func (r *expenseRepo) Update(ctx context.Context, expense *models.Expense) (bool, error) {
+	query := `
+		UPDATE expenses SET warehouse_id = $3, category_id = $4, expense_date = $5, period_month = $6, amount = $7,
+			description = $8, vendor = $9, reference = $10, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, expense.TenantID, expense.ID, expense.WarehouseID, expense.CategoryID,
+		expense.ExpenseDate, expense.Month, expense.Amount, expense.Description, expense.Vendor,
+		expense.Reference).Scan(&expense.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *expenseRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM expenses WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *expenseRepo) AddAttachment(ctx context.Context, attachment *models.ExpenseAttachment) error {
+	query := `
+		INSERT INTO expense_attachments (id, tenant_id, expense_id, object_key, file_name, content_type, size_bytes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, attachment.ID, attachment.TenantID, attachment.ExpenseID, attachment.ObjectKey,
+		attachment.FileName, attachment.ContentType, attachment.SizeBytes).Scan(&attachment.CreatedAt)
+}
+
+func (r *expenseRepo) ListAttachments(ctx context.Context, tenantID, expenseID uuid.UUID) ([]*models.ExpenseAttachment, error) {
+	query := `SELECT ` + expenseAttachmentColumns + ` FROM expense_attachments WHERE tenant_id = $1 AND expense_id = $2 ORDER BY created_at`
+	rows, err := r.db.Query(ctx, query, tenantID, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.ExpenseAttachment
+	for rows.Next() {
+		attachment, err := scanExpenseAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func (r *expenseRepo) GetAttachment(ctx context.Context, tenantID, id uuid.UUID) (*models.ExpenseAttachment, error) {
+	query := `SELECT ` + expenseAttachmentColumns + ` FROM expense_attachments WHERE tenant_id = $1 AND id = $2`
+	attachment, err := scanExpenseAttachment(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return attachment, err
+}
+
+func (r *expenseRepo) DeleteAttachment(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM expense_attachments WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *expenseRepo) ListBudgets(ctx context.Context, tenantID uuid.UUID, month time.Time, warehouseID *uuid.UUID) ([]*models.ExpenseBudget, error) {
+	query := `
+		SELECT b.id, b.tenant_id, b.warehouse_id, w.name, b.category_id, c.name, b.period_month, b.amount::float8, b.notes,
+			b.created_at, b.updated_at
+		FROM expense_budgets b
+		JOIN warehouses w ON w.id = b.warehouse_id
+		JOIN expense_categories c ON c.id = b.category_id
+		WHERE b.tenant_id = $1 AND b.period_month = $2::date AND ($3::uuid IS NULL OR b.warehouse_id = $3)
+		ORDER BY w.name, c.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, month, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []*models.ExpenseBudget
+	for rows.Next() {
+		b := &models.ExpenseBudget{}
+		if err := rows.Scan(&b.ID, &b.TenantID, &b.WarehouseID, &b.WarehouseName, &b.CategoryID, &b.CategoryName, &b.Month,
+			&b.Amount, &b.Notes, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// UpsertBudget sets the budget of its warehouse, category and month, keeping
+// the ID of one set before
+func (r *expenseRepo) UpsertBudget(ctx context.Context, budget *models.ExpenseBudget) error {
+	query := `
+		INSERT INTO expense_budgets (id, tenant_id, warehouse_id, category_id, period_month, amount, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (tenant_id, warehouse_id, category_id, period_month)
+		DO UPDATE SET amount = EXCLUDED.amount, notes = EXCLUDED.notes, updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, budget.ID, budget.TenantID, budget.WarehouseID, budget.CategoryID, budget.Month,
+		budget.Amount, budget.Notes).Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+}
+
+func (r *expenseRepo) DeleteBudget(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM expense_budgets WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *expenseRepo) BudgetVsActual(ctx context.Context, tenantID uuid.UUID, from, to time.Time, warehouseID *uuid.UUID) ([]*models.BudgetVsActualRow, error) {
+	query := `
+		WITH budgets AS (
+			SELECT warehouse_id, category_id, SUM(amount) AS amount
+			FROM expense_budgets
+			WHERE tenant_id = $1 AND period_month >= $2::date AND period_month <= $3::date
+				AND ($4::uuid IS NULL OR warehouse_id = $4)
+			GROUP BY warehouse_id, category_id
+		),
+		actuals AS (
+			SELECT warehouse_id, category_id, SUM(amount) AS amount
+			FROM expenses
+			WHERE tenant_id = $1 AND period_month >= $2::date AND period_month <= $3::date
+				AND ($4::uuid IS NULL OR warehouse_id = $4)
+			GROUP BY warehouse_id, category_id
+		)
+		SELECT w.id, w.name, c.id, c.name, COALESCE(b.amount, 0)::float8, COALESCE(a.amount, 0)::float8
+		FROM budgets b
+		FULL OUTER JOIN actuals a ON a.warehouse_id = b.warehouse_id AND a.category_id = b.category_id
+		JOIN warehouses w ON w.id = COALESCE(b.warehouse_id, a.warehouse_id)
+		JOIN expense_categories c ON c.id = COALESCE(b.category_id, a.category_id)
+		ORDER BY w.name, c.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.BudgetVsActualRow
+	for rows.Next() {
+		row := &models.BudgetVsActualRow{}
+		if err := rows.Scan(&row.WarehouseID, &row.WarehouseName, &row.CategoryID, &row.CategoryName, &row.Budget,
+			&row.Actual); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
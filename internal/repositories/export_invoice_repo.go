@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ExportInvoiceRepository interface {
+	CreateExportInvoice(ctx context.Context, invoice *models.Invoice, details *models.InvoiceExportDetails) error
+	GetExportDetails(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceExportDetails, error)
+	UpdateShipping(ctx context.Context, details *models.InvoiceExportDetails) (bool, error)
+	GSTR1Exports(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.GSTR1ExportRow, error)
+}
+
+type exportInvoiceRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewExportInvoiceRepo(db *pgxpool.Pool) ExportInvoiceRepository {
+	return &exportInvoiceRepo{db: db}
+}
+
+const exportDetailsColumns = `invoice_id, tenant_id, export_type, lut_number, currency, exchange_rate::float8, foreign_amount::float8,
+	port_code, shipping_bill_number, shipping_bill_date, destination_country, created_at, updated_at`
+
+// CreateExportInvoice creates an invoice together with its export details
+func (r *exportInvoiceRepo) CreateExportInvoice(ctx context.Context, invoice *models.Invoice, details *models.InvoiceExportDetails) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, invoiceInsert, invoiceInsertArgs(invoice)...); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO invoice_export_details (invoice_id, tenant_id, export_type, lut_number, currency, exchange_rate, foreign_amount,
+			port_code, shipping_bill_number, shipping_bill_date, destination_country, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	if err := tx.QueryRow(ctx, query, invoice.ID, invoice.TenantID, details.ExportType, details.LUTNumber, details.Currency,
+		details.ExchangeRate, details.ForeignAmount, details.PortCode, details.ShippingBillNumber, details.ShippingBillDate,
+		details.DestinationCountry).Scan(&details.CreatedAt, &details.UpdatedAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetExportDetails returns nil for invoices that are not export invoices
+func (r *exportInvoiceRepo) GetExportDetails(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceExportDetails, error) {
+	query := `SELECT ` + exportDetailsColumns + ` FROM invoice_export_details WHERE tenant_id = $1 AND invoice_id = $2`
+	d := &models.InvoiceExportDetails{}
+	err := r.db.QueryRow(ctx, query, tenantID, invoiceID).Scan(&d.InvoiceID, &d.TenantID, &d.ExportType, &d.LUTNumber, &d.Currency,
+		&d.ExchangeRate, &d.ForeignAmount, &d.PortCode, &d.ShippingBillNumber, &d.ShippingBillDate, &d.DestinationCountry,
+		&d.CreatedAt, &d.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// UpdateShipping records the port and shipping bill of an export invoice; it
+// reports false if the invoice is not one
+func (r *exportInvoiceRepo) UpdateShipping(ctx context.Context, details *models.InvoiceExportDetails) (bool, error) {
+	query := `
+		UPDATE invoice_export_details
+		SET port_code = $3, shipping_bill_number = $4, shipping_bill_date = $5, updated_at = NOW()
+		WHERE tenant_id = $1 AND invoice_id = $2
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, details.TenantID, details.InvoiceID, details.PortCode, details.ShippingBillNumber,
+		details.ShippingBillDate).Scan(&details.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GSTR1Exports lists non-cancelled export invoices issued between from and to
+// inclusive as GSTR-1 table 6A reports them, in invoice number order
+func (r *exportInvoiceRepo) GSTR1Exports(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.GSTR1ExportRow, error) {
+	query := `
+		SELECT e.export_type, i.id, i.invoice_number, i.issued_date, i.total_amount::float8, e.port_code, e.shipping_bill_number,
+			e.shipping_bill_date, COALESCE(i.gst_rate, 0)::float8, COALESCE(i.taxable_amount, i.total_amount)::float8,
+			COALESCE(i.igst, 0)::float8, e.currency, e.foreign_amount::float8
+		FROM invoice_export_details e
+		JOIN invoices i ON i.id = e.invoice_id AND i.tenant_id = e.tenant_id
+		WHERE e.tenant_id = $1 AND i.status <> 'cancelled' AND i.issued_date::date BETWEEN $2::date AND $3::date
+		ORDER BY i.invoice_number
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*models.GSTR1ExportRow
+	for rows.Next() {
+		row := &models.GSTR1ExportRow{}
+		if err := rows.Scan(&row.ExportType, &row.InvoiceID, &row.InvoiceNumber, &row.InvoiceDate, &row.InvoiceValue, &row.PortCode,
+			&row.ShippingBillNumber, &row.ShippingBillDate, &row.Rate, &row.TaxableValue, &row.IGST, &row.Currency,
+			&row.ForeignAmount); err != nil {
+			return nil, err
+		}
+		exports = append(exports, row)
+	}
+	return exports, rows.Err()
+}
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FeatureFlagRepository interface {
+	// List and Get include each flag's tenant overrides
+	List(ctx context.Context) ([]*models.FeatureFlag, error)
+	Get(ctx context.Context, key string) (*models.FeatureFlag, error)
+	Upsert(ctx context.Context, flag *models.FeatureFlag) error
+	Delete(ctx context.Context, key string) (bool, error)
+
+	// SetOverride reports false when the flag or tenant does not exist
+	SetOverride(ctx context.Context, override *models.FeatureFlagOverride) (bool, error)
+	DeleteOverride(ctx context.Context, key string, tenantID uuid.UUID) (bool, error)
+}
+
+type featureFlagRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewFeatureFlagRepo(db *pgxpool.Pool) FeatureFlagRepository {
+	return &featureFlagRepo{db: db}
+}
+
+const featureFlagColumns = `key, description, enabled, rollout_percentage, created_at, updated_at`
+
+func scanFeatureFlag(row rowScanner) (*models.FeatureFlag, error) {
+	f := &models.FeatureFlag{Overrides: []*models.FeatureFlagOverride{}}
+	err := row.Scan(&f.Key, &f.Description, &f.Enabled, &f.RolloutPercentage, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *featureFlagRepo) List(ctx context.Context) ([]*models.FeatureFlag, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+featureFlagColumns+` FROM feature_flags ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []*models.FeatureFlag
+	byKey := make(map[string]*models.FeatureFlag)
+	for rows.Next() {
+		flag, err := scanFeatureFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+		byKey[flag.Key] = flag
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := r.overrides(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if flag, ok := byKey[o.FlagKey]; ok {
+			flag.Overrides = append(flag.Overrides, o)
+		}
+	}
+	return flags, nil
+}
+
+func (r *featureFlagRepo) Get(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	flag, err := scanFeatureFlag(r.db.QueryRow(ctx, `SELECT `+featureFlagColumns+` FROM feature_flags WHERE key = $1`, key))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := r.overrides(ctx, &key)
+	if err != nil {
+		return nil, err
+	}
+	flag.Overrides = append(flag.Overrides, overrides...)
+	return flag, nil
+}
+
+// overrides returns the overrides of one flag, or of every flag when key is nil
+func (r *featureFlagRepo) overrides(ctx context.Context, key *string) ([]*models.FeatureFlagOverride, error) {
+	query := `
+		SELECT flag_key, tenant_id, enabled, updated_at
+		FROM feature_flag_overrides
+		WHERE $1::text IS NULL OR flag_key = $1
+		ORDER BY flag_key, tenant_id
+	`
+	rows, err := r.db.Query(ctx, query, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*models.FeatureFlagOverride
+	for rows.Next() {
+		o := &models.FeatureFlagOverride{}
+		if err := rows.Scan(&o.FlagKey, &o.TenantID, &o.Enabled, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+func (r *featureFlagRepo) Upsert(ctx context.Context, flag *models.FeatureFlag) error {
+	query := `
+		INSERT INTO feature_flags (key, description, enabled, rollout_percentage, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			description = EXCLUDED.description,
+			enabled = EXCLUDED.enabled,
+			rollout_percentage = EXCLUDED.rollout_percentage,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, flag.Key, flag.Description, flag.Enabled, flag.RolloutPercentage).
+		Scan(&flag.CreatedAt, &flag.UpdatedAt)
+}
+
+func (r *featureFlagRepo) Delete(ctx context.Context, key string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *featureFlagRepo) SetOverride(ctx context.Context, override *models.FeatureFlagOverride) (bool, error) {
+	query := `
+		INSERT INTO feature_flag_overrides (flag_key, tenant_id, enabled, created_at, updated_at)
+		SELECT f.key, t.id, $3, NOW(), NOW()
+		FROM feature_flags f, tenants t
+		WHERE f.key = $1 AND t.id = $2
+		ON CONFLICT (flag_key, tenant_id) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW()
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, override.FlagKey, override.TenantID, override.Enabled).Scan(&override.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *featureFlagRepo) DeleteOverride(ctx context.Context, key string, tenantID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM feature_flag_overrides WHERE flag_key = $1 AND tenant_id = $2`, key, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
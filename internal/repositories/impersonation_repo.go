@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ImpersonationRepository interface {
+	Create(ctx context.Context, session *models.ImpersonationSession) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.ImpersonationSession, error)
+	GetByTokenID(ctx context.Context, tokenID string) (*models.ImpersonationSession, error)
+	// End closes an open session; false means it had already been closed
+	End(ctx context.Context, id uuid.UUID, endedAt time.Time) (bool, error)
+	// ListExpired returns open sessions that expired at or before now, oldest first
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]*models.ImpersonationSession, error)
+	ListByTenant(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error)
+	ListByImpersonator(ctx context.Context, impersonatorID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error)
+	// Count audit log actions recorded under an impersonator within a time window
+	SummarizeActivity(ctx context.Context, tenantID, impersonatorID uuid.UUID, from, to time.Time) (map[string]int, error)
+}
+
+type impersonationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewImpersonationRepo(db *pgxpool.Pool) ImpersonationRepository {
+	return &impersonationRepo{db: db}
+}
+
+const impersonationSessionColumns = `id, tenant_id, impersonator_id, impersonator_tenant_id, target_user_id, reason, token_id, expires_at, ended_at, created_at`
+
+func scanImpersonationSession(row interface{ Scan(dest ...any) error }) (*models.ImpersonationSession, error) {
+	session := &models.ImpersonationSession{}
+	err := row.Scan(&session.ID, &session.TenantID, &session.ImpersonatorID, &session.ImpersonatorTenantID, &session.TargetUserID,
+		&session.Reason, &session.TokenID, &session.ExpiresAt, &session.EndedAt, &session.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *impersonationRepo) Create(ctx context.Context, session *models.ImpersonationSession) error {
+	query := `
+		INSERT INTO impersonation_sessions (id, tenant_id, impersonator_id, impersonator_tenant_id, target_user_id, reason, token_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+	`
+	_, err := r.db.Exec(ctx, query, session.ID, session.TenantID, session.ImpersonatorID, session.ImpersonatorTenantID,
+		session.TargetUserID, session.Reason, session.TokenID, session.ExpiresAt)
+	return err
+}
+
+func (r *impersonationRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.ImpersonationSession, error) {
+	query := `SELECT ` + impersonationSessionColumns + ` FROM impersonation_sessions WHERE id = $1`
+	return scanImpersonationSession(r.db.QueryRow(ctx, query, id))
+}
+
+func (r *impersonationRepo) GetByTokenID(ctx context.Context, tokenID string) (*models.ImpersonationSession, error) {
+	query := `SELECT ` + impersonationSessionColumns + ` FROM impersonation_sessions WHERE token_id = $1`
+	return scanImpersonationSession(r.db.QueryRow(ctx, query, tokenID))
+}
+
+func (r *impersonationRepo) End(ctx context.Context, id uuid.UUID, endedAt time.Time) (bool, error) {
+	query := `UPDATE impersonation_sessions SET ended_at = $1 WHERE id = $2 AND ended_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, endedAt, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *impersonationRepo) ListExpired(ctx context.Context, now time.Time, limit int) ([]*models.ImpersonationSession, error) {
+	query := `
+		SELECT ` + impersonationSessionColumns + `
+		FROM impersonation_sessions
+		WHERE ended_at IS NULL AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+	`
+	return r.list(ctx, query, now, limit)
+}
+
+func (r *impersonationRepo) ListByTenant(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error) {
+	query := `
+		SELECT ` + impersonationSessionColumns + `
+		FROM impersonation_sessions
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	return r.list(ctx, query, tenantID, limit, offset)
+}
+
+func (r *impersonationRepo) ListByImpersonator(ctx context.Context, impersonatorID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error) {
+	query := `
+		SELECT ` + impersonationSessionColumns + `
+		FROM impersonation_sessions
+		WHERE impersonator_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	return r.list(ctx, query, impersonatorID, limit, offset)
+}
+
+func (r *impersonationRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.ImpersonationSession, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.ImpersonationSession
+	for rows.Next() {
+		session, err := scanImpersonationSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *impersonationRepo) SummarizeActivity(ctx context.Context, tenantID, impersonatorID uuid.UUID, from, to time.Time) (map[string]int, error) {
+	query := `
+		SELECT action, COUNT(*)
+		FROM audit_logs
+		WHERE tenant_id = $1 AND impersonator_id = $2 AND created_at BETWEEN $3 AND $4
+		GROUP BY action
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, impersonatorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breakdown := make(map[string]int)
+	for rows.Next() {
+		var action string
+		var count int
+		if err := rows.Scan(&action, &count); err != nil {
+			return nil, err
+		}
+		breakdown[action] = count
+	}
+	return breakdown, rows.Err()
+}
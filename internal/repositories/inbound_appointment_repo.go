@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InboundAppointmentRepository interface {
+	// Create stores the appointment unless a scheduled one conflicts with it,
+	// in which case the conflicting appointment is returned instead
+	Create(ctx context.Context, appointment *models.InboundAppointment) (*models.InboundAppointment, error)
+	// Reschedule moves a scheduled appointment to its new dock and slot unless
+	// another scheduled one conflicts, returning that one instead
+	Reschedule(ctx context.Context, appointment *models.InboundAppointment) (*models.InboundAppointment, error)
+	// Cancel cancels a scheduled appointment, returning false when it is not scheduled
+	Cancel(ctx context.Context, appointment *models.InboundAppointment) (bool, error)
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundAppointment, error)
+	// ListForWarehouse lists the warehouse's appointments overlapping [from, to)
+	ListForWarehouse(ctx context.Context, tenantID, warehouseID uuid.UUID, from, to time.Time, includeCancelled bool) ([]*models.InboundAppointment, error)
+}
+
+type inboundAppointmentRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInboundAppointmentRepo(db *pgxpool.Pool) InboundAppointmentRepository {
+	return &inboundAppointmentRepo{db: db}
+}
+
+const inboundAppointmentColumns = `id, tenant_id, warehouse_id, dock, supplier_id, order_ids, starts_at, ends_at, status, vehicle_number, notes,
+	cancel_reason, cancelled_at, created_by, created_at, updated_at`
+
+func scanInboundAppointment(row rowScanner) (*models.InboundAppointment, error) {
+	appointment := &models.InboundAppointment{}
+	err := row.Scan(&appointment.ID, &appointment.TenantID, &appointment.WarehouseID, &appointment.Dock, &appointment.SupplierID,
+		&appointment.OrderIDs, &appointment.StartsAt, &appointment.EndsAt, &appointment.Status, &appointment.VehicleNumber, &appointment.Notes,
+		&appointment.CancelReason, &appointment.CancelledAt, &appointment.CreatedBy, &appointment.CreatedAt, &appointment.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+// lockAndFindConflict locks the warehouse so bookings at it are checked one
+// at a time, then finds a scheduled appointment other than this one that
+// overlaps it at the same dock or already brings one of its orders
+func lockAndFindConflict(ctx context.Context, tx pgx.Tx, appointment *models.InboundAppointment) (*models.InboundAppointment, error) {
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM warehouses WHERE tenant_id = $1 AND id = $2 FOR UPDATE`, appointment.TenantID, appointment.WarehouseID); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + inboundAppointmentColumns + `
+		FROM inbound_appointments
+		WHERE tenant_id = $1 AND warehouse_id = $2 AND status = 'scheduled' AND id <> $3
+			AND ((LOWER(dock) = LOWER($4) AND starts_at < $6 AND ends_at > $5) OR order_ids && $7)
+		ORDER BY starts_at
+		LIMIT 1
+	`
+	conflict, err := scanInboundAppointment(tx.QueryRow(ctx, query, appointment.TenantID, appointment.WarehouseID, appointment.ID,
+		appointment.Dock, appointment.StartsAt, appointment.EndsAt, appointment.OrderIDs))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return conflict, err
+}
+
+func (r *inboundAppointmentRepo) Create(ctx context.Context, appointment *models.InboundAppointment) (*models.InboundAppointment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	conflict, err := lockAndFindConflict(ctx, tx, appointment)
+	if err != nil || conflict != nil {
+		return conflict, err
+	}
+
+	query := `
+		INSERT INTO inbound_appointments (id, tenant_id, warehouse_id, dock, supplier_id, order_ids, starts_at, ends_at, status,
+			vehicle_number, notes, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	if err := tx.QueryRow(ctx, query, appointment.ID, appointment.TenantID, appointment.WarehouseID, appointment.Dock, appointment.SupplierID,
+		appointment.OrderIDs, appointment.StartsAt, appointment.EndsAt, appointment.Status, appointment.VehicleNumber, appointment.Notes,
+		appointment.CreatedBy).Scan(&appointment.CreatedAt, &appointment.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit(ctx)
+}
+
+func (r *inboundAppointmentRepo) Reschedule(ctx context.Context, appointment *models.InboundAppointment) (*models.InboundAppointment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	conflict, err := lockAndFindConflict(ctx, tx, appointment)
+	if err != nil || conflict != nil {
+		return conflict, err
+	}
+
+	query := `
+		UPDATE inbound_appointments
+		SET dock = $1, starts_at = $2, ends_at = $3, vehicle_number = $4, notes = $5, updated_at = NOW()
+		WHERE tenant_id = $6 AND id = $7 AND status = 'scheduled'
+		RETURNING updated_at
+	`
+	if err := tx.QueryRow(ctx, query, appointment.Dock, appointment.StartsAt, appointment.EndsAt, appointment.VehicleNumber, appointment.Notes,
+		appointment.TenantID, appointment.ID).Scan(&appointment.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit(ctx)
+}
+
+func (r *inboundAppointmentRepo) Cancel(ctx context.Context, appointment *models.InboundAppointment) (bool, error) {
+	query := `
+		UPDATE inbound_appointments
+		SET status = 'cancelled', cancel_reason = $1, cancelled_at = NOW(), updated_at = NOW()
+		WHERE tenant_id = $2 AND id = $3 AND status = 'scheduled'
+		RETURNING cancelled_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, appointment.CancelReason, appointment.TenantID, appointment.ID).Scan(&appointment.CancelledAt, &appointment.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	appointment.Status = models.AppointmentCancelled
+	return true, nil
+}
+
+// GetByID returns the appointment, or nil when it does not exist
+func (r *inboundAppointmentRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundAppointment, error) {
+	query := `SELECT ` + inboundAppointmentColumns + ` FROM inbound_appointments WHERE tenant_id = $1 AND id = $2`
+	appointment, err := scanInboundAppointment(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return appointment, err
+}
+
+func (r *inboundAppointmentRepo) ListForWarehouse(ctx context.Context, tenantID, warehouseID uuid.UUID, from, to time.Time, includeCancelled bool) ([]*models.InboundAppointment, error) {
+	query := `
+		SELECT ` + inboundAppointmentColumns + `
+		FROM inbound_appointments
+		WHERE tenant_id = $1 AND warehouse_id = $2 AND starts_at < $4 AND ends_at > $3 AND ($5 OR status = 'scheduled')
+		ORDER BY starts_at, LOWER(dock)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, warehouseID, from, to, includeCancelled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var appointments []*models.InboundAppointment
+	for rows.Next() {
+		appointment, err := scanInboundAppointment(rows)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, rows.Err()
+}
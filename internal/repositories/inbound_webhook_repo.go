@@ -0,0 +1,244 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InboundWebhookRepository interface {
+	List(ctx context.Context, tenantID uuid.UUID) ([]*models.InboundWebhook, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundWebhook, error)
+	GetBySlug(ctx context.Context, slug string) (*models.InboundWebhook, error)
+	// Create and Update report false when the slug or name is already taken
+	Create(ctx context.Context, webhook *models.InboundWebhook) (bool, error)
+	Update(ctx context.Context, webhook *models.InboundWebhook) (bool, error)
+	SetSecret(ctx context.Context, tenantID, id uuid.UUID, secret string) (bool, error)
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+
+	// ReserveDelivery records a delivery as processed before its action runs;
+	// it reports false when the event was already processed
+	ReserveDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) (bool, error)
+	RecordDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) error
+	GetProcessedDelivery(ctx context.Context, webhookID uuid.UUID, eventID string) (*models.InboundWebhookDelivery, error)
+	ListDeliveries(ctx context.Context, tenantID, webhookID uuid.UUID, status string, limit, offset int) ([]*models.InboundWebhookDelivery, error)
+
+	// FindInvoiceByNumber returns the ID of the tenant's invoice with the number
+	FindInvoiceByNumber(ctx context.Context, tenantID uuid.UUID, number string) (*uuid.UUID, error)
+}
+
+type inboundWebhookRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInboundWebhookRepo(db *pgxpool.Pool) InboundWebhookRepository {
+	return &inboundWebhookRepo{db: db}
+}
+
+const (
+	inboundWebhookColumns = `id, tenant_id, name, slug, action, verification, secret, signature_header, field_mapping,
+		warehouse_id, distributor_id, is_active, created_at, updated_at`
+	inboundDeliveryColumns = `id, tenant_id, webhook_id, event_id, status, error, payload, order_id, invoice_id, source_ip, received_at`
+)
+
+func scanInboundWebhook(row rowScanner) (*models.InboundWebhook, error) {
+	w := &models.InboundWebhook{}
+	err := row.Scan(&w.ID, &w.TenantID, &w.Name, &w.Slug, &w.Action, &w.Verification, &w.Secret, &w.SignatureHeader,
+		&w.FieldMapping, &w.WarehouseID, &w.DistributorID, &w.IsActive, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func scanInboundDelivery(row rowScanner) (*models.InboundWebhookDelivery, error) {
+	d := &models.InboundWebhookDelivery{}
+	err := row.Scan(&d.ID, &d.TenantID, &d.WebhookID, &d.EventID, &d.Status, &d.Error, &d.Payload, &d.OrderID,
+		&d.InvoiceID, &d.SourceIP, &d.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *inboundWebhookRepo) List(ctx context.Context, tenantID uuid.UUID) ([]*models.InboundWebhook, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+inboundWebhookColumns+` FROM inbound_webhooks WHERE tenant_id = $1 ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*models.InboundWebhook
+	for rows.Next() {
+		webhook, err := scanInboundWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *inboundWebhookRepo) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE tenant_id = $1 AND id = $2`
+	webhook, err := scanInboundWebhook(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return webhook, err
+}
+
+func (r *inboundWebhookRepo) GetBySlug(ctx context.Context, slug string) (*models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE slug = $1`
+	webhook, err := scanInboundWebhook(r.db.QueryRow(ctx, query, slug))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return webhook, err
+}
+
+func (r *inboundWebhookRepo) Create(ctx context.Context, webhook *models.InboundWebhook) (bool, error) {
+	query := `
+		INSERT INTO inbound_webhooks (id, tenant_id, name, slug, action, verification, secret, signature_header, field_mapping,
+			warehouse_id, distributor_id, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		ON CONFLICT DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, webhook.ID, webhook.TenantID, webhook.Name, webhook.Slug, webhook.Action,
+		webhook.Verification, webhook.Secret, webhook.SignatureHeader, webhook.FieldMapping, webhook.WarehouseID,
+		webhook.DistributorID, webhook.IsActive).Scan(&webhook.CreatedAt, &webhook.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *inboundWebhookRepo) Update(ctx context.Context, webhook *models.InboundWebhook) (bool, error) {
+	query := `
+		UPDATE inbound_webhooks w SET name = $3, signature_header = $4, field_mapping = $5, warehouse_id = $6,
+			distributor_id = $7, is_active = $8, updated_at = NOW()
+		WHERE w.tenant_id = $1 AND w.id = $2
+			AND NOT EXISTS (
+				SELECT 1 FROM inbound_webhooks o WHERE o.tenant_id = w.tenant_id AND o.id <> w.id AND o.name = $3
+			)
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, webhook.TenantID, webhook.ID, webhook.Name, webhook.SignatureHeader,
+		webhook.FieldMapping, webhook.WarehouseID, webhook.DistributorID, webhook.IsActive).Scan(&webhook.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *inboundWebhookRepo) SetSecret(ctx context.Context, tenantID, id uuid.UUID, secret string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE inbound_webhooks SET secret = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`,
+		tenantID, id, secret)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *inboundWebhookRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM inbound_webhooks WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *inboundWebhookRepo) ReserveDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) (bool, error) {
+	query := `
+		INSERT INTO inbound_webhook_deliveries (id, tenant_id, webhook_id, event_id, status, payload, source_ip, received_at)
+		VALUES ($1, $2, $3, $4, 'processed', $5, $6, NOW())
+		ON CONFLICT (webhook_id, event_id) WHERE status = 'processed' AND event_id IS NOT NULL DO NOTHING
+		RETURNING received_at
+	`
+	err := r.db.QueryRow(ctx, query, delivery.ID, delivery.TenantID, delivery.WebhookID, delivery.EventID,
+		delivery.Payload, delivery.SourceIP).Scan(&delivery.ReceivedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	delivery.Status = models.InboundDeliveryProcessed
+	return true, nil
+}
+
+func (r *inboundWebhookRepo) RecordDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) error {
+	query := `
+		INSERT INTO inbound_webhook_deliveries (id, tenant_id, webhook_id, event_id, status, error, payload, order_id,
+			invoice_id, source_ip, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		RETURNING received_at
+	`
+	return r.db.QueryRow(ctx, query, delivery.ID, delivery.TenantID, delivery.WebhookID, delivery.EventID, delivery.Status,
+		delivery.Error, delivery.Payload, delivery.OrderID, delivery.InvoiceID, delivery.SourceIP).Scan(&delivery.ReceivedAt)
+}
+
+func (r *inboundWebhookRepo) UpdateDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE inbound_webhook_deliveries SET status = $2, error = $3, order_id = $4, invoice_id = $5
+		WHERE id = $1
+	`, delivery.ID, delivery.Status, delivery.Error, delivery.OrderID, delivery.InvoiceID)
+	return err
+}
+
+func (r *inboundWebhookRepo) GetProcessedDelivery(ctx context.Context, webhookID uuid.UUID, eventID string) (*models.InboundWebhookDelivery, error) {
+	query := `
+		SELECT ` + inboundDeliveryColumns + `
+		FROM inbound_webhook_deliveries
+		WHERE webhook_id = $1 AND event_id = $2 AND status = 'processed'
+	`
+	delivery, err := scanInboundDelivery(r.db.QueryRow(ctx, query, webhookID, eventID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+func (r *inboundWebhookRepo) ListDeliveries(ctx context.Context, tenantID, webhookID uuid.UUID, status string, limit, offset int) ([]*models.InboundWebhookDelivery, error) {
+	query := `
+		SELECT ` + inboundDeliveryColumns + `
+		FROM inbound_webhook_deliveries
+		WHERE tenant_id = $1 AND webhook_id = $2 AND ($3::text = '' OR status = $3)
+		ORDER BY received_at DESC
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, webhookID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.InboundWebhookDelivery
+	for rows.Next() {
+		delivery, err := scanInboundDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *inboundWebhookRepo) FindInvoiceByNumber(ctx context.Context, tenantID uuid.UUID, number string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT id FROM invoices WHERE tenant_id = $1 AND invoice_number = $2`, tenantID, number).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
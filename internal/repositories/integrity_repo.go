@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IntegrityRepository reads the rows a data integrity check inspects
+type IntegrityRepository interface {
+	OrdersWithMissingProducts(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityOrderRef, error)
+	InvoiceAmounts(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityInvoiceAmounts, error)
+	NegativeStock(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityStockRow, error)
+	ProductImages(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityImageRef, error)
+	DeleteProductImage(ctx context.Context, tenantID, imageID uuid.UUID) (bool, error)
+}
+
+type integrityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIntegrityRepo(db *pgxpool.Pool) IntegrityRepository {
+	return &integrityRepo{db: db}
+}
+
+// OrdersWithMissingProducts lists the tenant's orders whose product does not
+// exist or belongs to another tenant; the foreign key alone does not catch the latter
+func (r *integrityRepo) OrdersWithMissingProducts(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityOrderRef, error) {
+	query := `
+		SELECT o.id, o.product_id, o.order_type, o.status
+		FROM orders o
+		LEFT JOIN products p ON p.id = o.product_id AND p.tenant_id = o.tenant_id
+		WHERE o.tenant_id = $1 AND p.id IS NULL
+		ORDER BY o.created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []*models.IntegrityOrderRef
+	for rows.Next() {
+		ref := &models.IntegrityOrderRef{}
+		if err := rows.Scan(&ref.OrderID, &ref.ProductID, &ref.OrderType, &ref.Status); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// InvoiceAmounts lists the amounts of the tenant's invoices that carry a
+// taxable amount, with any TCS collected on them
+func (r *integrityRepo) InvoiceAmounts(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityInvoiceAmounts, error) {
+	query := `
+		SELECT i.id, i.invoice_number, i.taxable_amount::float8, COALESCE(i.cgst, 0)::float8, COALESCE(i.sgst, 0)::float8,
+			COALESCE(i.igst, 0)::float8, COALESCE(t.amount, 0)::float8, i.total_amount::float8
+		FROM invoices i
+		LEFT JOIN invoice_tcs t ON t.invoice_id = i.id
+		WHERE i.tenant_id = $1 AND i.taxable_amount IS NOT NULL
+		ORDER BY i.issued_date, i.invoice_number
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*models.IntegrityInvoiceAmounts
+	for rows.Next() {
+		inv := &models.IntegrityInvoiceAmounts{}
+		if err := rows.Scan(&inv.InvoiceID, &inv.InvoiceNumber, &inv.TaxableAmount, &inv.CGST, &inv.SGST,
+			&inv.IGST, &inv.TCS, &inv.TotalAmount); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+// NegativeStock lists the tenant's stock levels below zero
+func (r *integrityRepo) NegativeStock(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityStockRow, error) {
+	query := `
+		SELECT id, product_id, warehouse_id, quantity
+		FROM inventory
+		WHERE tenant_id = $1 AND quantity < 0
+		ORDER BY quantity
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stock []*models.IntegrityStockRow
+	for rows.Next() {
+		row := &models.IntegrityStockRow{}
+		if err := rows.Scan(&row.InventoryID, &row.ProductID, &row.WarehouseID, &row.Quantity); err != nil {
+			return nil, err
+		}
+		stock = append(stock, row)
+	}
+	return stock, rows.Err()
+}
+
+// ProductImages lists the tenant's product images and their object keys
+func (r *integrityRepo) ProductImages(ctx context.Context, tenantID uuid.UUID) ([]*models.IntegrityImageRef, error) {
+	query := `SELECT id, product_id, image_url FROM product_images WHERE tenant_id = $1`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []*models.IntegrityImageRef
+	for rows.Next() {
+		image := &models.IntegrityImageRef{}
+		if err := rows.Scan(&image.ImageID, &image.ProductID, &image.ObjectKey); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, rows.Err()
+}
+
+func (r *integrityRepo) DeleteProductImage(ctx context.Context, tenantID, imageID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_images WHERE tenant_id = $1 AND id = $2`, tenantID, imageID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
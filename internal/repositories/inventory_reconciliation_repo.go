@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InventoryReconciliationRepository interface {
+	StockIn(ctx context.Context, tenantID uuid.UUID, warehouseIDs []uuid.UUID) ([]*models.Inventory, error)
+	Create(ctx context.Context, rec *models.InventoryReconciliation) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.InventoryReconciliation, error)
+	List(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InventoryReconciliation, error)
+	Resolve(ctx context.Context, tenantID, id uuid.UUID, status string, resolvedBy *uuid.UUID, approvedLineIDs []uuid.UUID) (bool, error)
+	MarkLineApplied(ctx context.Context, lineID uuid.UUID) error
+}
+
+type inventoryReconciliationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInventoryReconciliationRepo(db *pgxpool.Pool) InventoryReconciliationRepository {
+	return &inventoryReconciliationRepo{db: db}
+}
+
+const inventoryReconciliationColumns = `id, tenant_id, source, status, snapshot_at, full_snapshot, notes, counted_lines, matched_lines, created_by, created_at, resolved_by, resolved_at`
+
+func scanInventoryReconciliation(row rowScanner) (*models.InventoryReconciliation, error) {
+	rec := &models.InventoryReconciliation{}
+	err := row.Scan(&rec.ID, &rec.TenantID, &rec.Source, &rec.Status, &rec.SnapshotAt, &rec.FullSnapshot, &rec.Notes, &rec.CountedLines,
+		&rec.MatchedLines, &rec.CreatedBy, &rec.CreatedAt, &rec.ResolvedBy, &rec.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// StockIn lists the tenant's stock records in the given warehouses
+func (r *inventoryReconciliationRepo) StockIn(ctx context.Context, tenantID uuid.UUID, warehouseIDs []uuid.UUID) ([]*models.Inventory, error) {
+	query := `
+		SELECT id, tenant_id, warehouse_id, product_id, quantity, last_updated
+		FROM inventory
+		WHERE tenant_id = $1 AND warehouse_id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, warehouseIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stock []*models.Inventory
+	for rows.Next() {
+		inv := &models.Inventory{}
+		if err := rows.Scan(&inv.ID, &inv.TenantID, &inv.WarehouseID, &inv.ProductID, &inv.Quantity, &inv.LastUpdated); err != nil {
+			return nil, err
+		}
+		stock = append(stock, inv)
+	}
+	return stock, rows.Err()
+}
+
+// Create stores the reconciliation with its lines in one transaction
+func (r *inventoryReconciliationRepo) Create(ctx context.Context, rec *models.InventoryReconciliation) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO inventory_reconciliations (id, tenant_id, source, status, snapshot_at, full_snapshot, notes, counted_lines, matched_lines, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		RETURNING created_at
+	`
+	if err := tx.QueryRow(ctx, query, rec.ID, rec.TenantID, rec.Source, rec.Status, rec.SnapshotAt, rec.FullSnapshot, rec.Notes,
+		rec.CountedLines, rec.MatchedLines, rec.CreatedBy).Scan(&rec.CreatedAt); err != nil {
+		return err
+	}
+
+	for _, line := range rec.Lines {
+		query := `
+			INSERT INTO inventory_reconciliation_lines (id, reconciliation_id, product_id, warehouse_id, external_quantity, internal_quantity, adjustment)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+		if _, err := tx.Exec(ctx, query, line.ID, rec.ID, line.ProductID, line.WarehouseID, line.ExternalQuantity, line.InternalQuantity, line.Adjustment); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetByID returns the reconciliation with its lines, or nil when it does not exist
+func (r *inventoryReconciliationRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.InventoryReconciliation, error) {
+	query := `SELECT ` + inventoryReconciliationColumns + ` FROM inventory_reconciliations WHERE tenant_id = $1 AND id = $2`
+	rec, err := scanInventoryReconciliation(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT id, reconciliation_id, product_id, warehouse_id, external_quantity, internal_quantity, adjustment, approved, applied
+		FROM inventory_reconciliation_lines
+		WHERE reconciliation_id = $1
+		ORDER BY ABS(adjustment) DESC, id
+	`
+	rows, err := r.db.Query(ctx, query, rec.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rec.Lines = []*models.InventoryReconciliationLine{}
+	for rows.Next() {
+		line := &models.InventoryReconciliationLine{}
+		if err := rows.Scan(&line.ID, &line.ReconciliationID, &line.ProductID, &line.WarehouseID, &line.ExternalQuantity, &line.InternalQuantity,
+			&line.Adjustment, &line.Approved, &line.Applied); err != nil {
+			return nil, err
+		}
+		rec.Lines = append(rec.Lines, line)
+	}
+	return rec, rows.Err()
+}
+
+// List returns reconciliations newest first without their lines, optionally
+// only those with a status
+func (r *inventoryReconciliationRepo) List(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InventoryReconciliation, error) {
+	query := `
+		SELECT ` + inventoryReconciliationColumns + `
+		FROM inventory_reconciliations
+		WHERE tenant_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recs []*models.InventoryReconciliation
+	for rows.Next() {
+		rec, err := scanInventoryReconciliation(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// Resolve moves a pending reconciliation to applied or discarded and marks
+// the approved lines, reporting false when it was no longer pending so it is
+// only ever applied once
+func (r *inventoryReconciliationRepo) Resolve(ctx context.Context, tenantID, id uuid.UUID, status string, resolvedBy *uuid.UUID, approvedLineIDs []uuid.UUID) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE inventory_reconciliations
+		SET status = $3, resolved_by = $4, resolved_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'pending'
+	`
+	tag, err := tx.Exec(ctx, query, tenantID, id, status, resolvedBy)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if len(approvedLineIDs) > 0 {
+		query := `UPDATE inventory_reconciliation_lines SET approved = TRUE WHERE reconciliation_id = $1 AND id = ANY($2)`
+		if _, err := tx.Exec(ctx, query, id, approvedLineIDs); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// MarkLineApplied records that an approved line's adjustment reached stock
+func (r *inventoryReconciliationRepo) MarkLineApplied(ctx context.Context, lineID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `UPDATE inventory_reconciliation_lines SET applied = TRUE WHERE id = $1`, lineID)
+	return err
+}
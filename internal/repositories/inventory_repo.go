@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 
 	"github.com/google/uuid"
@@ -14,10 +15,13 @@ import (
 type InventoryRepository interface {
 	Create(ctx context.Context, inventory *models.Inventory) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Inventory, error)
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Inventory, error)
 	Update(ctx context.Context, inventory *models.Inventory) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Inventory, error)
+	ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Inventory, error)
 	GetByWarehouseAndProduct(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (*models.Inventory, error)
+	ListByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.Inventory, error)
 	AdvancedSearch(ctx context.Context, tenantID uuid.UUID, filter *models.InventorySearchFilter) ([]*models.Inventory, error)
 }
 
@@ -53,6 +57,30 @@ func (r *inventoryRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*m
 	return inventory, nil
 }
 
+// GetByIDs loads the tenant's inventory records among ids in one query; unknown IDs are skipped
+func (r *inventoryRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Inventory, error) {
+	query := `
+		SELECT id, tenant_id, warehouse_id, product_id, quantity, last_updated
+		FROM inventory
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var inventories []*models.Inventory
+	for rows.Next() {
+		inventory := &models.Inventory{}
+		if err := rows.Scan(&inventory.ID, &inventory.TenantID, &inventory.WarehouseID, &inventory.ProductID, &inventory.Quantity, &inventory.LastUpdated); err != nil {
+			return nil, err
+		}
+		inventories = append(inventories, inventory)
+	}
+	return inventories, rows.Err()
+}
+
 func (r *inventoryRepo) GetByWarehouseAndProduct(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (*models.Inventory, error) {
 	inventory := &models.Inventory{}
 	query := `
@@ -67,14 +95,40 @@ func (r *inventoryRepo) GetByWarehouseAndProduct(ctx context.Context, tenantID,
 	return inventory, nil
 }
 
+// ListByProduct returns the product's stock in every warehouse, largest first
+func (r *inventoryRepo) ListByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.Inventory, error) {
+	query := `
+		SELECT id, tenant_id, warehouse_id, product_id, quantity, last_updated
+		FROM inventory
+		WHERE tenant_id = $1 AND product_id = $2
+		ORDER BY quantity DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var inventories []*models.Inventory
+	for rows.Next() {
+		inventory := &models.Inventory{}
+		if err := rows.Scan(&inventory.ID, &inventory.TenantID, &inventory.WarehouseID, &inventory.ProductID, &inventory.Quantity, &inventory.LastUpdated); err != nil {
+			return nil, err
+		}
+		inventories = append(inventories, inventory)
+	}
+	return inventories, nil
+}
+
 func (r *inventoryRepo) Update(ctx context.Context, inventory *models.Inventory) error {
 	query := `
 		UPDATE inventory
 		SET quantity = $1, last_updated = NOW()
 		WHERE tenant_id = $2 AND id = $3
 	`
-	_, err := r.db.Exec(ctx, query, inventory.Quantity, inventory.TenantID, inventory.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, inventory.ID, "last_updated", query, []interface{}{inventory.Quantity, inventory.TenantID, inventory.ID})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *inventoryRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -83,15 +137,26 @@ func (r *inventoryRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) erro
 	return err
 }
 
+// InventorySortColumns whitelists the fields the inventory list can be sorted by
+var InventorySortColumns = map[string]string{
+	"quantity":     "quantity",
+	"last_updated": "last_updated",
+}
+
 func (r *inventoryRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Inventory, error) {
-	query := `
+	return r.ListPage(ctx, tenantID, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists inventory in the page's sort order, most recently updated first by default
+func (r *inventoryRepo) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Inventory, error) {
+	query := fmt.Sprintf(`
 		SELECT id, tenant_id, warehouse_id, product_id, quantity, last_updated
 		FROM inventory
 		WHERE tenant_id = $1
-		ORDER BY last_updated DESC
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("last_updated DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InventoryTransactionRepository interface {
+	Create(ctx context.Context, txn *models.InventoryTransaction) error
+	ListByProduct(ctx context.Context, tenantID, productID uuid.UUID, limit, offset int) ([]*models.InventoryTransaction, error)
+	AgingPositions(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID) ([]*models.InventoryAgingPosition, error)
+}
+
+type inventoryTransactionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInventoryTransactionRepo(db *pgxpool.Pool) InventoryTransactionRepository {
+	return &inventoryTransactionRepo{db: db}
+}
+
+func (r *inventoryTransactionRepo) Create(ctx context.Context, txn *models.InventoryTransaction) error {
+	query := `
+		INSERT INTO inventory_transactions (id, tenant_id, warehouse_id, product_id, quantity_change, quantity_after, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+	`
+	_, err := r.db.Exec(ctx, query, txn.ID, txn.TenantID, txn.WarehouseID, txn.ProductID, txn.QuantityChange, txn.QuantityAfter, txn.Reason, txn.CreatedBy)
+	return err
+}
+
+func (r *inventoryTransactionRepo) ListByProduct(ctx context.Context, tenantID, productID uuid.UUID, limit, offset int) ([]*models.InventoryTransaction, error) {
+	query := `
+		SELECT id, tenant_id, warehouse_id, product_id, quantity_change, quantity_after, reason, created_by, created_at
+		FROM inventory_transactions
+		WHERE tenant_id = $1 AND product_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txns []*models.InventoryTransaction
+	for rows.Next() {
+		txn := &models.InventoryTransaction{}
+		if err := rows.Scan(&txn.ID, &txn.TenantID, &txn.WarehouseID, &txn.ProductID, &txn.QuantityChange, &txn.QuantityAfter, &txn.Reason, &txn.CreatedBy, &txn.CreatedAt); err != nil {
+			return nil, err
+		}
+		txns = append(txns, txn)
+	}
+	return txns, nil
+}
+
+// AgingPositions returns every warehouse line holding owned stock with its most
+// recent ledger movement. Consignment units belong to the supplier and are
+// left out of the quantity.
+func (r *inventoryTransactionRepo) AgingPositions(ctx context.Context, tenantID uuid.UUID, warehouseID *uuid.UUID) ([]*models.InventoryAgingPosition, error) {
+	query := `
+		SELECT warehouse_id, warehouse_name, product_id, product_name, quantity - consignment_quantity, unit_price, cost_price, expiry_date,
+			last_movement_at, from_ledger, consignment_quantity
+		FROM (
+			SELECT i.warehouse_id, w.name AS warehouse_name, i.product_id, p.name AS product_name, i.quantity, p.unit_price, p.cost_price, p.expiry_date,
+				COALESCE(m.last_movement_at, i.last_updated) AS last_movement_at, m.last_movement_at IS NOT NULL AS from_ledger,
+				LEAST(i.quantity, COALESCE(cs.quantity, 0)) AS consignment_quantity
+			FROM inventory i
+			JOIN products p ON p.id = i.product_id AND p.tenant_id = i.tenant_id
+			JOIN warehouses w ON w.id = i.warehouse_id AND w.tenant_id = i.tenant_id
+			LEFT JOIN LATERAL (
+				SELECT MAX(t.created_at) AS last_movement_at
+				FROM inventory_transactions t
+				WHERE t.tenant_id = i.tenant_id AND t.warehouse_id = i.warehouse_id AND t.product_id = i.product_id
+			) m ON TRUE
+			LEFT JOIN LATERAL (
+				SELECT SUM(c.quantity)::int AS quantity
+				FROM consignment_stock c
+				WHERE c.tenant_id = i.tenant_id AND c.warehouse_id = i.warehouse_id AND c.product_id = i.product_id
+			) cs ON TRUE
+			WHERE i.tenant_id = $1 AND i.quantity > 0 AND ($2::uuid IS NULL OR i.warehouse_id = $2)
+		) positions
+		WHERE quantity > consignment_quantity
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []*models.InventoryAgingPosition
+	for rows.Next() {
+		pos := &models.InventoryAgingPosition{}
+		if err := rows.Scan(&pos.WarehouseID, &pos.WarehouseName, &pos.ProductID, &pos.ProductName, &pos.Quantity, &pos.UnitPrice, &pos.CostPrice, &pos.ExpiryDate, &pos.LastMovementAt, &pos.FromLedger, &pos.ConsignmentQuantity); err != nil {
+			return nil, err
+		}
+		positions = append(positions, pos)
+	}
+	return positions, rows.Err()
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AmendmentSource is what decides whether an invoice can be amended
+type AmendmentSource struct {
+	// DistributorID is the customer of the invoice's order, nil when the
+	// invoice is not a sales invoice to a customer
+	DistributorID *uuid.UUID
+	Consolidated  bool
+	Export        bool
+}
+
+type InvoiceAmendmentRepository interface {
+	// Source returns nil for invoices the tenant does not have
+	Source(ctx context.Context, tenantID, invoiceID uuid.UUID) (*AmendmentSource, error)
+	// Create cancels the original invoice, which must still have the given
+	// status, and records the credit note, the new invoice with its TCS, if
+	// any, and the amendment. It reports false, changing nothing, when the
+	// original's status has meanwhile changed
+	Create(ctx context.Context, amendment *models.InvoiceAmendment, originalStatus string) (bool, error)
+	// Chain lists the amendments of every invoice the given one was amended
+	// from or into, oldest first
+	Chain(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceAmendment, error)
+}
+
+type invoiceAmendmentRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInvoiceAmendmentRepo(db *pgxpool.Pool) InvoiceAmendmentRepository {
+	return &invoiceAmendmentRepo{db: db}
+}
+
+// invoiceAmended holds for an invoice i that was cancelled by an amendment,
+// which leaves it owed by the customer until the amendment's credit note
+const invoiceAmended = `EXISTS (SELECT 1 FROM invoice_amendments am WHERE am.tenant_id = i.tenant_id AND am.original_invoice_id = i.id)`
+
+func (r *invoiceAmendmentRepo) Source(ctx context.Context, tenantID, invoiceID uuid.UUID) (*AmendmentSource, error) {
+	source := &AmendmentSource{}
+	query := `
+		SELECT CASE WHEN o.order_type = 'sales' THEN o.distributor_id END,
+			EXISTS (SELECT 1 FROM consolidated_invoices c WHERE c.invoice_id = i.id),
+			EXISTS (SELECT 1 FROM invoice_export_details e WHERE e.invoice_id = i.id)
+		FROM invoices i
+		LEFT JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		WHERE i.tenant_id = $1 AND i.id = $2
+	`
+	err := r.db.QueryRow(ctx, query, tenantID, invoiceID).Scan(&source.DistributorID, &source.Consolidated, &source.Export)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+func (r *invoiceAmendmentRepo) Create(ctx context.Context, amendment *models.InvoiceAmendment, originalStatus string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE invoices SET status = 'cancelled', updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = $3
+	`, amendment.TenantID, amendment.OriginalInvoiceID, originalStatus)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	note := amendment.CreditNote
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO credit_notes (id, tenant_id, distributor_id, invoice_id, credit_note_number, amount, reason, issued_date, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING created_at
+	`, note.ID, note.TenantID, note.DistributorID, note.InvoiceID, note.CreditNoteNumber, note.Amount, note.Reason, note.IssuedDate,
+		note.CreatedBy).Scan(&note.CreatedAt); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, invoiceInsert, invoiceInsertArgs(amendment.NewInvoice)...); err != nil {
+		return false, err
+	}
+	if amendment.TCS != nil {
+		if err := insertInvoiceTCS(ctx, tx, amendment.NewInvoice, amendment.TCS); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO invoice_amendments (id, tenant_id, original_invoice_id, credit_note_id, new_invoice_id, reason, gst_treatment,
+			original_period, amended_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING created_at
+	`, amendment.ID, amendment.TenantID, amendment.OriginalInvoiceID, amendment.CreditNoteID, amendment.NewInvoiceID, amendment.Reason,
+		amendment.GSTTreatment, amendment.OriginalPeriod, amendment.AmendedBy).Scan(&amendment.CreatedAt); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *invoiceAmendmentRepo) Chain(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceAmendment, error) {
+	query := `
+		WITH RECURSIVE earlier AS (
+			SELECT $2::uuid AS id
+			UNION
+			SELECT a.original_invoice_id FROM invoice_amendments a JOIN earlier ON a.new_invoice_id = earlier.id WHERE a.tenant_id = $1
+		), later AS (
+			SELECT $2::uuid AS id
+			UNION
+			SELECT a.new_invoice_id FROM invoice_amendments a JOIN later ON a.original_invoice_id = later.id WHERE a.tenant_id = $1
+		)
+		SELECT a.id, a.tenant_id, a.original_invoice_id, oi.invoice_number, a.credit_note_id, cn.credit_note_number, a.new_invoice_id,
+			ni.invoice_number, a.reason, a.gst_treatment, a.original_period, a.amended_by, a.created_at
+		FROM invoice_amendments a
+		JOIN invoices oi ON oi.id = a.original_invoice_id
+		JOIN invoices ni ON ni.id = a.new_invoice_id
+		JOIN credit_notes cn ON cn.id = a.credit_note_id
+		WHERE a.tenant_id = $1
+			AND (a.original_invoice_id IN (SELECT id FROM earlier) OR a.original_invoice_id IN (SELECT id FROM later))
+		ORDER BY a.created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	amendments := []*models.InvoiceAmendment{}
+	for rows.Next() {
+		a := &models.InvoiceAmendment{}
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.OriginalInvoiceID, &a.OriginalInvoiceNumber, &a.CreditNoteID, &a.CreditNoteNumber,
+			&a.NewInvoiceID, &a.NewInvoiceNumber, &a.Reason, &a.GSTTreatment, &a.OriginalPeriod, &a.AmendedBy, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		amendments = append(amendments, a)
+	}
+	return amendments, rows.Err()
+}
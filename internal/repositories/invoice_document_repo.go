@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InvoiceDocumentRepository interface {
+	Get(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceDocument, error)
+	// Create records the document unless the invoice already has one, and
+	// returns whether it was recorded
+	Create(ctx context.Context, doc *models.InvoiceDocument) (bool, error)
+	// ListPending lists invoices of every tenant without a document, oldest
+	// first, from after the given invoice onwards
+	ListPending(ctx context.Context, afterCreated time.Time, afterID uuid.UUID, limit int) ([]*models.PendingInvoiceDocument, error)
+}
+
+type invoiceDocumentRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInvoiceDocumentRepo(db *pgxpool.Pool) InvoiceDocumentRepository {
+	return &invoiceDocumentRepo{db: db}
+}
+
+func (r *invoiceDocumentRepo) Get(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceDocument, error) {
+	query := `
+		SELECT invoice_id, tenant_id, invoice_number, template, object_key, sha256, size_bytes, generated_at
+		FROM invoice_documents
+		WHERE tenant_id = $1 AND invoice_id = $2
+	`
+	doc := &models.InvoiceDocument{}
+	err := r.db.QueryRow(ctx, query, tenantID, invoiceID).Scan(&doc.InvoiceID, &doc.TenantID, &doc.InvoiceNumber,
+		&doc.Template, &doc.ObjectKey, &doc.SHA256, &doc.SizeBytes, &doc.GeneratedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (r *invoiceDocumentRepo) Create(ctx context.Context, doc *models.InvoiceDocument) (bool, error) {
+	query := `
+		INSERT INTO invoice_documents (invoice_id, tenant_id, invoice_number, template, object_key, sha256, size_bytes, generated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (invoice_id) DO NOTHING
+		RETURNING generated_at
+	`
+	err := r.db.QueryRow(ctx, query, doc.InvoiceID, doc.TenantID, doc.InvoiceNumber, doc.Template,
+		doc.ObjectKey, doc.SHA256, doc.SizeBytes).Scan(&doc.GeneratedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *invoiceDocumentRepo) ListPending(ctx context.Context, afterCreated time.Time, afterID uuid.UUID, limit int) ([]*models.PendingInvoiceDocument, error) {
+	query := `
+		SELECT i.tenant_id, i.id, i.created_at
+		FROM invoices i
+		LEFT JOIN invoice_documents d ON d.invoice_id = i.id
+		WHERE d.invoice_id IS NULL AND (i.created_at, i.id) > ($1, $2)
+		ORDER BY i.created_at, i.id
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, query, afterCreated, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []*models.PendingInvoiceDocument
+	for rows.Next() {
+		p := &models.PendingInvoiceDocument{}
+		if err := rows.Scan(&p.TenantID, &p.InvoiceID, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 
 	"github.com/google/uuid"
@@ -25,6 +26,16 @@ type GSTReportRow struct {
 	Status          string    `json:"status"`
 	IssuedDate      time.Time `json:"issued_date"`
 	GSTIN           *string   `json:"gstin"`
+
+	// BranchID and SellerGSTIN are the issuing branch and its GSTIN, nil
+	// for invoices issued before the tenant had branches
+	BranchID    *uuid.UUID `json:"branch_id,omitempty"`
+	SellerGSTIN *string    `json:"seller_gstin,omitempty"`
+
+	// DocumentType is invoice or, for an amended invoice's reversal,
+	// credit_note, whose amounts are negative
+	DocumentType     string  `json:"document_type"`
+	CreditNoteNumber *string `json:"credit_note_number,omitempty"`
 }
 
 type InvoiceRepository interface {
@@ -33,13 +44,19 @@ type InvoiceRepository interface {
 	Update(ctx context.Context, invoice *models.Invoice) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Invoice, error)
+	// ListPage lists invoices, only those of the branch when branchID is set
+	ListPage(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Invoice, error)
 	GetInvoicesByTenantAndDateRange(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]*models.Invoice, error)
 	GetInvoicesByStatus(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.Invoice, error)
 	GetInvoicesByOrderID(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.Invoice, error)
 	GetUnpaidInvoices(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Invoice, error)
-	GetGSTReportData(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]GSTReportRow, error)
+	// GetGSTReportData lists the documents to report for the period, only
+	// those of the branch when branchID is set
+	GetGSTReportData(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, startDate, endDate time.Time) ([]GSTReportRow, error)
 	UpdateInvoiceStatus(ctx context.Context, tenantID, invoiceID uuid.UUID, status string) error
-	GenerateInvoiceNumber(ctx context.Context, tenantID uuid.UUID, issuedDate time.Time) (string, error)
+	// GenerateInvoiceNumber takes the next number of the branch's series, or
+	// of the tenant's when branchID is nil
+	GenerateInvoiceNumber(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, issuedDate time.Time) (string, error)
 }
 
 type invoiceRepo struct {
@@ -50,23 +67,30 @@ func NewInvoiceRepo(db *pgxpool.Pool) InvoiceRepository {
 	return &invoiceRepo{db: db}
 }
 
+// invoiceInsert inserts the invoice given by invoiceInsertArgs; repositories
+// creating an invoice together with its details share it
+const invoiceInsert = `
+	INSERT INTO invoices (id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, branch_id, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW(), NOW())
+`
+
+func invoiceInsertArgs(invoice *models.Invoice) []interface{} {
+	return []interface{}{invoice.ID, invoice.TenantID, invoice.OrderID, invoice.InvoiceNumber, invoice.GSTIN, invoice.HSNSAC, invoice.TaxableAmount, invoice.GSTRate, invoice.CGST, invoice.SGST, invoice.IGST, invoice.TotalAmount, invoice.Status, invoice.IssuedDate, invoice.PaidDate, invoice.DueDate, invoice.BranchID}
+}
+
 func (r *invoiceRepo) Create(ctx context.Context, invoice *models.Invoice) error {
-	query := `
-		INSERT INTO invoices (id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW(), NOW())
-	`
-	_, err := r.db.Exec(ctx, query, invoice.ID, invoice.TenantID, invoice.OrderID, invoice.InvoiceNumber, invoice.GSTIN, invoice.HSNSAC, invoice.TaxableAmount, invoice.GSTRate, invoice.CGST, invoice.SGST, invoice.IGST, invoice.TotalAmount, invoice.Status, invoice.IssuedDate, invoice.PaidDate, invoice.DueDate)
+	_, err := r.db.Exec(ctx, invoiceInsert, invoiceInsertArgs(invoice)...)
 	return err
 }
 
 func (r *invoiceRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Invoice, error) {
 	invoice := &models.Invoice{}
 	query := `
-		SELECT id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
+		SELECT id, tenant_id, order_id, branch_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
 		FROM invoices
 		WHERE tenant_id = $1 AND id = $2
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.BranchID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -79,8 +103,9 @@ func (r *invoiceRepo) Update(ctx context.Context, invoice *models.Invoice) error
 		SET gstin = $1, hsn_sac = $2, taxable_amount = $3, gst_rate = $4, cgst = $5, sgst = $6, igst = $7, total_amount = $8, status = $9, issued_date = $10, paid_date = $11, due_date = $12, updated_at = NOW()
 		WHERE tenant_id = $13 AND id = $14
 	`
-	_, err := r.db.Exec(ctx, query, invoice.GSTIN, invoice.HSNSAC, invoice.TaxableAmount, invoice.GSTRate, invoice.CGST, invoice.SGST, invoice.IGST, invoice.TotalAmount, invoice.Status, invoice.IssuedDate, invoice.PaidDate, invoice.DueDate, invoice.TenantID, invoice.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, invoice.ID, "updated_at", query, []interface{}{invoice.GSTIN, invoice.HSNSAC, invoice.TaxableAmount, invoice.GSTRate, invoice.CGST, invoice.SGST, invoice.IGST, invoice.TotalAmount, invoice.Status, invoice.IssuedDate, invoice.PaidDate, invoice.DueDate, invoice.TenantID, invoice.ID})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *invoiceRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -89,15 +114,30 @@ func (r *invoiceRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	return err
 }
 
+// InvoiceSortColumns whitelists the fields the invoice list can be sorted by
+var InvoiceSortColumns = map[string]string{
+	"invoice_number": "invoice_number",
+	"total_amount":   "total_amount",
+	"status":         "status",
+	"issued_date":    "issued_date",
+	"due_date":       "due_date",
+	"created_at":     "created_at",
+}
+
 func (r *invoiceRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Invoice, error) {
-	query := `
-		SELECT id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
+	return r.ListPage(ctx, tenantID, nil, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists invoices in the page's sort order, most recently issued first by default
+func (r *invoiceRepo) ListPage(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Invoice, error) {
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, order_id, branch_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
 		FROM invoices
-		WHERE tenant_id = $1
-		ORDER BY issued_date DESC
+		WHERE tenant_id = $1 AND ($4::uuid IS NULL OR branch_id = $4)
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("issued_date DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset, branchID)
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +146,7 @@ func (r *invoiceRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offse
 	var invoices []*models.Invoice
 	for rows.Next() {
 		invoice := &models.Invoice{}
-		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.BranchID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
 			return nil, err
 		}
 		invoices = append(invoices, invoice)
@@ -116,7 +156,7 @@ func (r *invoiceRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offse
 
 func (r *invoiceRepo) GetInvoicesByTenantAndDateRange(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]*models.Invoice, error) {
 	query := `
-		SELECT id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
+		SELECT id, tenant_id, order_id, branch_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
 		FROM invoices
 		WHERE tenant_id = $1 AND issued_date BETWEEN $2 AND $3
 		ORDER BY issued_date DESC
@@ -130,7 +170,7 @@ func (r *invoiceRepo) GetInvoicesByTenantAndDateRange(ctx context.Context, tenan
 	var invoices []*models.Invoice
 	for rows.Next() {
 		invoice := &models.Invoice{}
-		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.BranchID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
 			return nil, err
 		}
 		invoices = append(invoices, invoice)
@@ -141,7 +181,7 @@ func (r *invoiceRepo) GetInvoicesByTenantAndDateRange(ctx context.Context, tenan
 // GetInvoicesByStatus retrieves invoices by status
 func (r *invoiceRepo) GetInvoicesByStatus(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.Invoice, error) {
 	query := `
-		SELECT id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
+		SELECT id, tenant_id, order_id, branch_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
 		FROM invoices
 		WHERE tenant_id = $1 AND status = $2
 		ORDER BY issued_date DESC
@@ -156,7 +196,7 @@ func (r *invoiceRepo) GetInvoicesByStatus(ctx context.Context, tenantID uuid.UUI
 	var invoices []*models.Invoice
 	for rows.Next() {
 		invoice := &models.Invoice{}
-		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.BranchID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
 			return nil, err
 		}
 		invoices = append(invoices, invoice)
@@ -164,12 +204,15 @@ func (r *invoiceRepo) GetInvoicesByStatus(ctx context.Context, tenantID uuid.UUI
 	return invoices, nil
 }
 
-// GetInvoicesByOrderID retrieves invoices for a specific order
+// GetInvoicesByOrderID retrieves invoices for a specific order, including
+// consolidated invoices it is a line of
 func (r *invoiceRepo) GetInvoicesByOrderID(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.Invoice, error) {
 	query := `
-		SELECT id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
+		SELECT id, tenant_id, order_id, branch_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
 		FROM invoices
-		WHERE tenant_id = $1 AND order_id = $2
+		WHERE tenant_id = $1 AND (order_id = $2 OR id IN (
+			SELECT invoice_id FROM consolidated_invoice_orders WHERE tenant_id = $1 AND order_id = $2
+		))
 		ORDER BY issued_date DESC
 	`
 	rows, err := r.db.Query(ctx, query, tenantID, orderID)
@@ -181,7 +224,7 @@ func (r *invoiceRepo) GetInvoicesByOrderID(ctx context.Context, tenantID, orderI
 	var invoices []*models.Invoice
 	for rows.Next() {
 		invoice := &models.Invoice{}
-		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.BranchID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
 			return nil, err
 		}
 		invoices = append(invoices, invoice)
@@ -192,7 +235,7 @@ func (r *invoiceRepo) GetInvoicesByOrderID(ctx context.Context, tenantID, orderI
 // GetUnpaidInvoices retrieves unpaid invoices
 func (r *invoiceRepo) GetUnpaidInvoices(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Invoice, error) {
 	query := `
-		SELECT id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
+		SELECT id, tenant_id, order_id, branch_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
 		FROM invoices
 		WHERE tenant_id = $1 AND status NOT IN ('paid', 'cancelled')
 		ORDER BY issued_date DESC
@@ -207,7 +250,7 @@ func (r *invoiceRepo) GetUnpaidInvoices(ctx context.Context, tenantID uuid.UUID,
 	var invoices []*models.Invoice
 	for rows.Next() {
 		invoice := &models.Invoice{}
-		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.BranchID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount, &invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate, &invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
 			return nil, err
 		}
 		invoices = append(invoices, invoice)
@@ -215,15 +258,38 @@ func (r *invoiceRepo) GetUnpaidInvoices(ctx context.Context, tenantID uuid.UUID,
 	return invoices, nil
 }
 
-// GetGSTReportData retrieves GST report data
-func (r *invoiceRepo) GetGSTReportData(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]GSTReportRow, error) {
+// GetGSTReportData retrieves GST report data for domestic supplies; export
+// invoices are reported separately in GSTR-1 table 6A. An invoice amended
+// after its filing period stays reported there as amended, and the credit
+// note reversing it is reported in the period it was issued. Each row carries
+// the issuing branch's GSTIN, as every GSTIN files its own return
+func (r *invoiceRepo) GetGSTReportData(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, startDate, endDate time.Time) ([]GSTReportRow, error) {
 	query := `
-		SELECT id, order_id, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, gstin
-		FROM invoices
-		WHERE tenant_id = $1 AND issued_date BETWEEN $2 AND $3
-		ORDER BY issued_date ASC
+		SELECT report.id, report.order_id, report.hsn_sac, report.taxable_amount, report.gst_rate, report.cgst, report.sgst, report.igst,
+			report.total_amount, report.status, report.issued_date, report.gstin, report.document_type, report.credit_note_number,
+			report.branch_id, b.gstin
+		FROM (
+			SELECT i.id, i.order_id, i.branch_id, i.hsn_sac, i.taxable_amount, i.gst_rate, i.cgst, i.sgst, i.igst, i.total_amount,
+				CASE WHEN a.gst_treatment = 'credit_note' THEN 'amended' ELSE i.status END AS status, i.issued_date, i.gstin,
+				'invoice' AS document_type, NULL::text AS credit_note_number
+			FROM invoices i
+			LEFT JOIN invoice_amendments a ON a.tenant_id = i.tenant_id AND a.original_invoice_id = i.id
+			WHERE i.tenant_id = $1 AND i.issued_date BETWEEN $2 AND $3
+				AND NOT EXISTS (SELECT 1 FROM invoice_export_details e WHERE e.invoice_id = i.id)
+				AND ($4::uuid IS NULL OR i.branch_id = $4)
+			UNION ALL
+			SELECT i.id, i.order_id, i.branch_id, i.hsn_sac, -i.taxable_amount, i.gst_rate, -i.cgst, -i.sgst, -i.igst, -cn.amount,
+				'credit_note', cn.issued_date, i.gstin, 'credit_note', cn.credit_note_number::text
+			FROM invoice_amendments a
+			JOIN credit_notes cn ON cn.id = a.credit_note_id
+			JOIN invoices i ON i.id = a.original_invoice_id
+			WHERE a.tenant_id = $1 AND a.gst_treatment = 'credit_note' AND cn.issued_date BETWEEN $2 AND $3
+				AND ($4::uuid IS NULL OR i.branch_id = $4)
+		) report
+		LEFT JOIN branches b ON b.id = report.branch_id
+		ORDER BY report.issued_date ASC
 	`
-	rows, err := r.db.Query(ctx, query, tenantID, startDate, endDate)
+	rows, err := r.db.Query(ctx, query, tenantID, startDate, endDate, branchID)
 	if err != nil {
 		return nil, err
 	}
@@ -232,7 +298,7 @@ func (r *invoiceRepo) GetGSTReportData(ctx context.Context, tenantID uuid.UUID,
 	var reportRows []GSTReportRow
 	for rows.Next() {
 		row := GSTReportRow{}
-		if err := rows.Scan(&row.InvoiceID, &row.OrderID, &row.HSNSAC, &row.TaxableAmount, &row.GSTRate, &row.CGST, &row.SGST, &row.IGST, &row.TotalAmount, &row.Status, &row.IssuedDate, &row.GSTIN); err != nil {
+		if err := rows.Scan(&row.InvoiceID, &row.OrderID, &row.HSNSAC, &row.TaxableAmount, &row.GSTRate, &row.CGST, &row.SGST, &row.IGST, &row.TotalAmount, &row.Status, &row.IssuedDate, &row.GSTIN, &row.DocumentType, &row.CreditNoteNumber, &row.BranchID, &row.SellerGSTIN); err != nil {
 			return nil, err
 		}
 		reportRows = append(reportRows, row)
@@ -247,13 +313,17 @@ func (r *invoiceRepo) UpdateInvoiceStatus(ctx context.Context, tenantID, invoice
 		SET status = $1, updated_at = NOW()
 		WHERE tenant_id = $2 AND id = $3
 	`
-	_, err := r.db.Exec(ctx, query, status, tenantID, invoiceID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, invoiceID, "updated_at", query, []interface{}{status, tenantID, invoiceID})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 // GenerateInvoiceNumber generates a unique invoice number for a tenant
-func (r *invoiceRepo) GenerateInvoiceNumber(ctx context.Context, tenantID uuid.UUID, issuedDate time.Time) (string, error) {
+func (r *invoiceRepo) GenerateInvoiceNumber(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, issuedDate time.Time) (string, error) {
 	yearMonth := issuedDate.Format("2006-01")
+	if branchID != nil {
+		return r.generateBranchInvoiceNumber(ctx, tenantID, *branchID, yearMonth)
+	}
 
 	// Get the next sequence number for this tenant and month
 	query := `
@@ -281,4 +351,30 @@ func (r *invoiceRepo) GenerateInvoiceNumber(ctx context.Context, tenantID uuid.U
 	invoiceNumber := fmt.Sprintf("INV-%s-%s-%06d", tenantSuffix, yearMonth, sequenceNum)
 
 	return invoiceNumber, nil
-}
\ No newline at end of file
+}
+
+// generateBranchInvoiceNumber takes the next number of the branch's monthly
+// series, formatted INV-BRANCHCODE-YYYY-MM-XXXXXX
+func (r *invoiceRepo) generateBranchInvoiceNumber(ctx context.Context, tenantID, branchID uuid.UUID, yearMonth string) (string, error) {
+	query := `
+		WITH branch AS (
+			SELECT id, code FROM branches WHERE tenant_id = $1 AND id = $2
+		), upsert AS (
+			INSERT INTO branch_invoice_sequences (branch_id, year_month, last_number)
+			SELECT id, $3, 1 FROM branch
+			ON CONFLICT (branch_id, year_month)
+			DO UPDATE SET
+				last_number = branch_invoice_sequences.last_number + 1,
+				updated_at = NOW()
+			RETURNING last_number
+		)
+		SELECT UPPER(branch.code), upsert.last_number FROM branch, upsert
+	`
+
+	var code string
+	var sequenceNum int
+	if err := r.db.QueryRow(ctx, query, tenantID, branchID, yearMonth).Scan(&code, &sequenceNum); err != nil {
+		return "", fmt.Errorf("failed to generate branch invoice sequence: %w", err)
+	}
+	return fmt.Sprintf("INV-%s-%s-%06d", code, yearMonth, sequenceNum), nil
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MarginRepository interface {
+	GetPolicy(ctx context.Context, tenantID uuid.UUID) (*models.MarginPolicy, error)
+	UpsertPolicy(ctx context.Context, policy *models.MarginPolicy) error
+	DeletePolicy(ctx context.Context, tenantID uuid.UUID) error
+	CreateViolation(ctx context.Context, violation *models.MarginViolation) error
+	ListViolations(ctx context.Context, tenantID uuid.UUID, filter *models.MarginViolationFilter) ([]*models.MarginViolation, error)
+}
+
+type marginRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMarginRepo(db *pgxpool.Pool) MarginRepository {
+	return &marginRepo{db: db}
+}
+
+// GetPolicy returns the tenant's margin policy, or nil when none is configured
+func (r *marginRepo) GetPolicy(ctx context.Context, tenantID uuid.UUID) (*models.MarginPolicy, error) {
+	policy := &models.MarginPolicy{}
+	query := `
+		SELECT tenant_id, min_margin_percent, enforcement, updated_by, updated_at
+		FROM margin_policies
+		WHERE tenant_id = $1
+	`
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&policy.TenantID, &policy.MinMarginPercent, &policy.Enforcement, &policy.UpdatedBy, &policy.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (r *marginRepo) UpsertPolicy(ctx context.Context, policy *models.MarginPolicy) error {
+	query := `
+		INSERT INTO margin_policies (tenant_id, min_margin_percent, enforcement, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			min_margin_percent = EXCLUDED.min_margin_percent,
+			enforcement = EXCLUDED.enforcement,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, policy.TenantID, policy.MinMarginPercent, policy.Enforcement, policy.UpdatedBy).Scan(&policy.UpdatedAt)
+}
+
+func (r *marginRepo) DeletePolicy(ctx context.Context, tenantID uuid.UUID) error {
+	query := `DELETE FROM margin_policies WHERE tenant_id = $1`
+	_, err := r.db.Exec(ctx, query, tenantID)
+	return err
+}
+
+func (r *marginRepo) CreateViolation(ctx context.Context, violation *models.MarginViolation) error {
+	query := `
+		INSERT INTO margin_violations (id, tenant_id, order_id, product_id, unit_price, cost_price, min_margin_percent, margin_percent, outcome, user_id, override_reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+	`
+	_, err := r.db.Exec(ctx, query, violation.ID, violation.TenantID, violation.OrderID, violation.ProductID, violation.UnitPrice, violation.CostPrice,
+		violation.MinMarginPercent, violation.MarginPercent, violation.Outcome, violation.UserID, violation.OverrideReason)
+	return err
+}
+
+// ListViolations returns margin violations newest first, joined with the product name
+func (r *marginRepo) ListViolations(ctx context.Context, tenantID uuid.UUID, filter *models.MarginViolationFilter) ([]*models.MarginViolation, error) {
+	if filter.Limit == 0 {
+		filter.Limit = 50
+	}
+
+	query := `
+		SELECT v.id, v.tenant_id, v.order_id, v.product_id, COALESCE(p.name, ''), v.unit_price, v.cost_price,
+			v.min_margin_percent, v.margin_percent, v.outcome, v.user_id, v.override_reason, v.created_at
+		FROM margin_violations v
+		LEFT JOIN products p ON p.tenant_id = v.tenant_id AND p.id = v.product_id
+		WHERE v.tenant_id = $1
+	`
+	args := []interface{}{tenantID}
+	conditionCount := 1
+
+	if filter.From != nil {
+		conditionCount++
+		query += fmt.Sprintf(` AND v.created_at >= $%d`, conditionCount)
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditionCount++
+		query += fmt.Sprintf(` AND v.created_at <= $%d`, conditionCount)
+		args = append(args, *filter.To)
+	}
+	if filter.ProductID != nil {
+		conditionCount++
+		query += fmt.Sprintf(` AND v.product_id = $%d`, conditionCount)
+		args = append(args, *filter.ProductID)
+	}
+	if filter.Outcome != nil {
+		conditionCount++
+		query += fmt.Sprintf(` AND v.outcome = $%d`, conditionCount)
+		args = append(args, *filter.Outcome)
+	}
+
+	query += fmt.Sprintf(` ORDER BY v.created_at DESC LIMIT $%d OFFSET $%d`, conditionCount+1, conditionCount+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var violations []*models.MarginViolation
+	for rows.Next() {
+		v := &models.MarginViolation{}
+		if err := rows.Scan(&v.ID, &v.TenantID, &v.OrderID, &v.ProductID, &v.ProductName, &v.UnitPrice, &v.CostPrice,
+			&v.MinMarginPercent, &v.MarginPercent, &v.Outcome, &v.UserID, &v.OverrideReason, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		violations = append(violations, v)
+	}
+	return violations, nil
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MarketplaceRepository interface {
+	CreateChannel(ctx context.Context, channel *models.MarketplaceChannel) error
+	GetChannel(ctx context.Context, tenantID, id uuid.UUID) (*models.MarketplaceChannel, error)
+	GetChannelByID(ctx context.Context, id uuid.UUID) (*models.MarketplaceChannel, error)
+	ListChannels(ctx context.Context, tenantID uuid.UUID) ([]*models.MarketplaceChannel, error)
+	UpdateChannel(ctx context.Context, channel *models.MarketplaceChannel) error
+	DeleteChannel(ctx context.Context, tenantID, id uuid.UUID) error
+	ReserveLink(ctx context.Context, link *models.MarketplaceOrderLink) (bool, error)
+	SetLinkOrder(ctx context.Context, linkID, orderID uuid.UUID) error
+	ReleaseLink(ctx context.Context, linkID uuid.UUID) error
+	ListLinksByExternalOrder(ctx context.Context, channelID uuid.UUID, externalOrderID string) ([]*models.MarketplaceOrderLink, error)
+	ListLinks(ctx context.Context, tenantID uuid.UUID, channelID *uuid.UUID, limit, offset int) ([]*models.MarketplaceOrderLink, error)
+	ListPendingStatusPushes(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.MarketplaceOrderLink, error)
+	MarkStatusPushed(ctx context.Context, linkID uuid.UUID, status string) error
+}
+
+type marketplaceRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMarketplaceRepo(db *pgxpool.Pool) MarketplaceRepository {
+	return &marketplaceRepo{db: db}
+}
+
+const marketplaceChannelColumns = `id, tenant_id, name, protocol, warehouse_id, distributor_id, signing_secret, status_webhook_url, field_mapping, is_active, created_at, updated_at`
+
+func scanMarketplaceChannel(row rowScanner) (*models.MarketplaceChannel, error) {
+	channel := &models.MarketplaceChannel{}
+	err := row.Scan(&channel.ID, &channel.TenantID, &channel.Name, &channel.Protocol, &channel.WarehouseID, &channel.DistributorID,
+		&channel.SigningSecret, &channel.StatusWebhookURL, &channel.FieldMapping, &channel.IsActive, &channel.CreatedAt, &channel.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (r *marketplaceRepo) CreateChannel(ctx context.Context, channel *models.MarketplaceChannel) error {
+	query := `
+		INSERT INTO marketplace_channels (id, tenant_id, name, protocol, warehouse_id, distributor_id, signing_secret, status_webhook_url, field_mapping, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, channel.ID, channel.TenantID, channel.Name, channel.Protocol, channel.WarehouseID, channel.DistributorID,
+		channel.SigningSecret, channel.StatusWebhookURL, channel.FieldMapping, channel.IsActive).Scan(&channel.CreatedAt, &channel.UpdatedAt)
+}
+
+func (r *marketplaceRepo) GetChannel(ctx context.Context, tenantID, id uuid.UUID) (*models.MarketplaceChannel, error) {
+	query := `SELECT ` + marketplaceChannelColumns + ` FROM marketplace_channels WHERE tenant_id = $1 AND id = $2`
+	return scanMarketplaceChannel(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+// GetChannelByID looks a channel up without a tenant; used for inbound
+// requests, which are authenticated by the channel's signing secret
+func (r *marketplaceRepo) GetChannelByID(ctx context.Context, id uuid.UUID) (*models.MarketplaceChannel, error) {
+	query := `SELECT ` + marketplaceChannelColumns + ` FROM marketplace_channels WHERE id = $1`
+	return scanMarketplaceChannel(r.db.QueryRow(ctx, query, id))
+}
+
+func (r *marketplaceRepo) ListChannels(ctx context.Context, tenantID uuid.UUID) ([]*models.MarketplaceChannel, error) {
+	query := `SELECT ` + marketplaceChannelColumns + ` FROM marketplace_channels WHERE tenant_id = $1 ORDER BY name`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []*models.MarketplaceChannel
+	for rows.Next() {
+		channel, err := scanMarketplaceChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+func (r *marketplaceRepo) UpdateChannel(ctx context.Context, channel *models.MarketplaceChannel) error {
+	query := `
+		UPDATE marketplace_channels
+		SET name = $1, warehouse_id = $2, distributor_id = $3, status_webhook_url = $4, field_mapping = $5, is_active = $6, updated_at = NOW()
+		WHERE tenant_id = $7 AND id = $8
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, channel.Name, channel.WarehouseID, channel.DistributorID, channel.StatusWebhookURL, channel.FieldMapping,
+		channel.IsActive, channel.TenantID, channel.ID).Scan(&channel.UpdatedAt)
+}
+
+func (r *marketplaceRepo) DeleteChannel(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `DELETE FROM marketplace_channels WHERE tenant_id = $1 AND id = $2`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+// ReserveLink claims an external order line; it returns false when the line
+// was already ingested so callers can treat the request as a replay
+func (r *marketplaceRepo) ReserveLink(ctx context.Context, link *models.MarketplaceOrderLink) (bool, error) {
+	query := `
+		INSERT INTO marketplace_order_links (id, tenant_id, channel_id, external_order_id, external_item_id, external_context, created_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6::jsonb, '{}'::jsonb), NOW())
+		ON CONFLICT (channel_id, external_order_id, external_item_id) DO NOTHING
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, link.ID, link.TenantID, link.ChannelID, link.ExternalOrderID, link.ExternalItemID, link.ExternalContext).Scan(&link.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *marketplaceRepo) SetLinkOrder(ctx context.Context, linkID, orderID uuid.UUID) error {
+	query := `UPDATE marketplace_order_links SET order_id = $1 WHERE id = $2`
+	_, err := r.db.Exec(ctx, query, orderID, linkID)
+	return err
+}
+
+func (r *marketplaceRepo) ReleaseLink(ctx context.Context, linkID uuid.UUID) error {
+	query := `DELETE FROM marketplace_order_links WHERE id = $1 AND order_id IS NULL`
+	_, err := r.db.Exec(ctx, query, linkID)
+	return err
+}
+
+const marketplaceLinkSelect = `
+	SELECT l.id, l.tenant_id, l.channel_id, l.external_order_id, l.external_item_id, l.order_id, l.external_context,
+		l.last_pushed_status, l.last_pushed_at, l.created_at, o.status
+	FROM marketplace_order_links l
+	LEFT JOIN orders o ON o.id = l.order_id
+`
+
+func (r *marketplaceRepo) queryLinks(ctx context.Context, query string, args ...interface{}) ([]*models.MarketplaceOrderLink, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*models.MarketplaceOrderLink
+	for rows.Next() {
+		link := &models.MarketplaceOrderLink{}
+		if err := rows.Scan(&link.ID, &link.TenantID, &link.ChannelID, &link.ExternalOrderID, &link.ExternalItemID, &link.OrderID,
+			&link.ExternalContext, &link.LastPushedStatus, &link.LastPushedAt, &link.CreatedAt, &link.OrderStatus); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r *marketplaceRepo) ListLinksByExternalOrder(ctx context.Context, channelID uuid.UUID, externalOrderID string) ([]*models.MarketplaceOrderLink, error) {
+	query := marketplaceLinkSelect + `WHERE l.channel_id = $1 AND l.external_order_id = $2 ORDER BY l.external_item_id`
+	return r.queryLinks(ctx, query, channelID, externalOrderID)
+}
+
+func (r *marketplaceRepo) ListLinks(ctx context.Context, tenantID uuid.UUID, channelID *uuid.UUID, limit, offset int) ([]*models.MarketplaceOrderLink, error) {
+	query := marketplaceLinkSelect + `
+		WHERE l.tenant_id = $1 AND ($2::uuid IS NULL OR l.channel_id = $2)
+		ORDER BY l.created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	return r.queryLinks(ctx, query, tenantID, channelID, limit, offset)
+}
+
+// ListPendingStatusPushes returns links whose order status differs from the
+// last status sent to an active channel with a webhook configured
+func (r *marketplaceRepo) ListPendingStatusPushes(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.MarketplaceOrderLink, error) {
+	query := marketplaceLinkSelect + `
+		JOIN marketplace_channels c ON c.id = l.channel_id
+		WHERE l.tenant_id = $1 AND o.id IS NOT NULL
+			AND c.is_active AND c.status_webhook_url IS NOT NULL
+			AND o.status IS DISTINCT FROM l.last_pushed_status
+		ORDER BY o.updated_at
+		LIMIT $2
+	`
+	return r.queryLinks(ctx, query, tenantID, limit)
+}
+
+func (r *marketplaceRepo) MarkStatusPushed(ctx context.Context, linkID uuid.UUID, status string) error {
+	query := `UPDATE marketplace_order_links SET last_pushed_status = $1, last_pushed_at = NOW() WHERE id = $2`
+	_, err := r.db.Exec(ctx, query, status, linkID)
+	return err
+}
@@ -0,0 +1,271 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationDigestRepository interface {
+	GetPreference(ctx context.Context, tenantID, userID uuid.UUID) (*models.DigestPreference, error)
+	UpsertPreference(ctx context.Context, pref *models.DigestPreference) error
+	ListRecipients(ctx context.Context) ([]*models.DigestRecipient, error)
+	ClaimSlot(ctx context.Context, tenantID, userID uuid.UUID, slot time.Time) (bool, error)
+
+	GetTemplate(ctx context.Context, tenantID uuid.UUID) (*models.DigestTemplate, error)
+	UpsertTemplate(ctx context.Context, tmpl *models.DigestTemplate) error
+	DeleteTemplate(ctx context.Context, tenantID uuid.UUID) (bool, error)
+
+	LowStock(ctx context.Context, tenantID uuid.UUID, threshold, limit int) ([]*models.DigestLowStockItem, int, error)
+	PendingApprovals(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.DigestPendingApproval, int, error)
+
+	LogDelivery(ctx context.Context, delivery *models.DigestDelivery) error
+	ListDeliveries(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, limit, offset int) ([]*models.DigestDelivery, error)
+}
+
+type notificationDigestRepo struct {
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
+}
+
+// NewNotificationDigestRepo creates the repository; keyring decrypts the
+// recipients' emails
+func NewNotificationDigestRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) NotificationDigestRepository {
+	return &notificationDigestRepo{db: db, keyring: keyring}
+}
+
+const digestPreferenceColumns = `p.tenant_id, p.user_id, p.frequency, p.sections, p.send_hour, p.weekly_day, p.timezone, p.last_sent_at, p.updated_at`
+
+func scanDigestPreference(row rowScanner, extra ...interface{}) (*models.DigestPreference, error) {
+	pref := &models.DigestPreference{}
+	dest := []interface{}{&pref.TenantID, &pref.UserID, &pref.Frequency, &pref.Sections, &pref.SendHour, &pref.WeeklyDay,
+		&pref.Timezone, &pref.LastSentAt, &pref.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return pref, nil
+}
+
+// GetPreference returns nil when the user has never set up a digest
+func (r *notificationDigestRepo) GetPreference(ctx context.Context, tenantID, userID uuid.UUID) (*models.DigestPreference, error) {
+	query := `SELECT ` + digestPreferenceColumns + ` FROM notification_digest_preferences p WHERE p.tenant_id = $1 AND p.user_id = $2`
+	pref, err := scanDigestPreference(r.db.QueryRow(ctx, query, tenantID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return pref, err
+}
+
+// UpsertPreference saves the preference, keeping when the last digest went out
+func (r *notificationDigestRepo) UpsertPreference(ctx context.Context, pref *models.DigestPreference) error {
+	query := `
+		INSERT INTO notification_digest_preferences AS p (tenant_id, user_id, frequency, sections, send_hour, weekly_day, timezone, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (tenant_id, user_id) DO UPDATE
+		SET frequency = EXCLUDED.frequency, sections = EXCLUDED.sections, send_hour = EXCLUDED.send_hour,
+			weekly_day = EXCLUDED.weekly_day, timezone = EXCLUDED.timezone, updated_at = NOW()
+		RETURNING ` + digestPreferenceColumns
+	saved, err := scanDigestPreference(r.db.QueryRow(ctx, query, pref.TenantID, pref.UserID, pref.Frequency, pref.Sections,
+		pref.SendHour, pref.WeeklyDay, pref.Timezone))
+	if err != nil {
+		return err
+	}
+	*pref = *saved
+	return nil
+}
+
+// ListRecipients lists digests that are switched on for active users of
+// active tenants
+func (r *notificationDigestRepo) ListRecipients(ctx context.Context) ([]*models.DigestRecipient, error) {
+	query := `
+		SELECT ` + digestPreferenceColumns + `, u.email, TRIM(u.first_name || ' ' || u.last_name), t.name
+		FROM notification_digest_preferences p
+		JOIN users u ON u.id = p.user_id AND u.tenant_id = p.tenant_id
+		JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.frequency <> 'off' AND u.status = 'active' AND t.status = 'active'
+		ORDER BY p.tenant_id, p.user_id
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*models.DigestRecipient
+	for rows.Next() {
+		recipient := &models.DigestRecipient{}
+		pref, err := scanDigestPreference(rows, &recipient.Email, &recipient.UserName, &recipient.TenantName)
+		if err != nil {
+			return nil, err
+		}
+		recipient.DigestPreference = *pref
+		email := &recipient.Email
+		if err := r.keyring.DecryptFields(ctx, pref.TenantID, &email); err != nil {
+			return nil, err
+		}
+		recipient.Email = *email
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// ClaimSlot marks the digest due at slot as sent; false means it already was,
+// so concurrent runs send each digest once
+func (r *notificationDigestRepo) ClaimSlot(ctx context.Context, tenantID, userID uuid.UUID, slot time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE notification_digest_preferences SET last_sent_at = $3
+		WHERE tenant_id = $1 AND user_id = $2 AND (last_sent_at IS NULL OR last_sent_at < $3)
+	`, tenantID, userID, slot)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetTemplate returns nil when the tenant uses the built-in template
+func (r *notificationDigestRepo) GetTemplate(ctx context.Context, tenantID uuid.UUID) (*models.DigestTemplate, error) {
+	query := `SELECT tenant_id, subject, body, updated_by, updated_at FROM notification_digest_templates WHERE tenant_id = $1`
+	tmpl := &models.DigestTemplate{}
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&tmpl.TenantID, &tmpl.Subject, &tmpl.Body, &tmpl.UpdatedBy, &tmpl.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func (r *notificationDigestRepo) UpsertTemplate(ctx context.Context, tmpl *models.DigestTemplate) error {
+	query := `
+		INSERT INTO notification_digest_templates (tenant_id, subject, body, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET subject = EXCLUDED.subject, body = EXCLUDED.body, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, tmpl.TenantID, tmpl.Subject, tmpl.Body, tmpl.UpdatedBy).Scan(&tmpl.UpdatedAt)
+}
+
+func (r *notificationDigestRepo) DeleteTemplate(ctx context.Context, tenantID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM notification_digest_templates WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// LowStock lists up to limit stock records below their stock minimum, or the
+// threshold when the product has none, emptiest first, and how many there are
+// in all
+func (r *notificationDigestRepo) LowStock(ctx context.Context, tenantID uuid.UUID, threshold, limit int) ([]*models.DigestLowStockItem, int, error) {
+	query := `
+		SELECT p.id, p.name, w.name, i.quantity, COUNT(*) OVER ()
+		FROM inventory i
+		JOIN products p ON p.id = i.product_id
+		JOIN warehouses w ON w.id = i.warehouse_id
+		LEFT JOIN stock_minimums wm ON wm.tenant_id = i.tenant_id AND wm.product_id = i.product_id AND wm.warehouse_id = i.warehouse_id
+		LEFT JOIN stock_minimums pm ON pm.tenant_id = i.tenant_id AND pm.product_id = i.product_id AND pm.warehouse_id IS NULL
+		WHERE i.tenant_id = $1 AND i.quantity < COALESCE(wm.min_quantity, pm.min_quantity, $2)
+		ORDER BY i.quantity, p.name, w.name
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, threshold, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var items []*models.DigestLowStockItem
+	total := 0
+	for rows.Next() {
+		item := &models.DigestLowStockItem{}
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.WarehouseName, &item.Quantity, &total); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+	return items, total, rows.Err()
+}
+
+// PendingApprovals lists up to limit orders and purchase requisitions waiting
+// for approval, longest waiting first, and how many there are in all
+func (r *notificationDigestRepo) PendingApprovals(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.DigestPendingApproval, int, error) {
+	query := `
+		SELECT kind, id, description, quantity, amount::float8, created_at, COUNT(*) OVER ()
+		FROM (
+			SELECT o.order_type || '_order' AS kind, o.id, p.name AS description, o.quantity,
+				o.quantity * o.unit_price AS amount, o.created_at
+			FROM orders o
+			JOIN products p ON p.id = o.product_id
+			WHERE o.tenant_id = $1 AND o.status = 'pending'
+			UNION ALL
+			SELECT 'purchase_requisition', r.id, p.name, r.quantity, 0::numeric, r.created_at
+			FROM purchase_requisitions r
+			JOIN products p ON p.id = r.product_id
+			WHERE r.tenant_id = $1 AND r.status = 'pending'
+		) pending
+		ORDER BY created_at, id
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var approvals []*models.DigestPendingApproval
+	total := 0
+	for rows.Next() {
+		a := &models.DigestPendingApproval{}
+		if err := rows.Scan(&a.Kind, &a.ID, &a.Description, &a.Quantity, &a.Amount, &a.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, total, rows.Err()
+}
+
+func (r *notificationDigestRepo) LogDelivery(ctx context.Context, delivery *models.DigestDelivery) error {
+	query := `
+		INSERT INTO notification_digest_deliveries (id, tenant_id, user_id, frequency, period_start, period_end, recipient, status, item_count, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, delivery.ID, delivery.TenantID, delivery.UserID, delivery.Frequency, delivery.PeriodStart,
+		delivery.PeriodEnd, delivery.Recipient, delivery.Status, delivery.ItemCount, delivery.Error).Scan(&delivery.CreatedAt)
+}
+
+// ListDeliveries lists the tenant's digest runs, or one user's, newest first
+func (r *notificationDigestRepo) ListDeliveries(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, limit, offset int) ([]*models.DigestDelivery, error) {
+	query := `
+		SELECT id, tenant_id, user_id, frequency, period_start, period_end, recipient, status, item_count, error, created_at
+		FROM notification_digest_deliveries
+		WHERE tenant_id = $1 AND ($2::uuid IS NULL OR user_id = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.DigestDelivery{}
+	for rows.Next() {
+		d := &models.DigestDelivery{}
+		if err := rows.Scan(&d.ID, &d.TenantID, &d.UserID, &d.Frequency, &d.PeriodStart, &d.PeriodEnd, &d.Recipient,
+			&d.Status, &d.ItemCount, &d.Error, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OperationalModeRepository interface {
+	// List returns the global mode, if any, followed by the tenants' modes
+	List(ctx context.Context) ([]*models.OperationalMode, error)
+	// Set replaces the mode of the scope; it reports false when the tenant
+	// does not exist
+	Set(ctx context.Context, mode *models.OperationalMode) (bool, error)
+	// Clear returns the scope, global when tenantID is nil, to normal operation
+	Clear(ctx context.Context, tenantID *uuid.UUID) (bool, error)
+}
+
+type operationalModeRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOperationalModeRepo(db *pgxpool.Pool) OperationalModeRepository {
+	return &operationalModeRepo{db: db}
+}
+
+func (r *operationalModeRepo) List(ctx context.Context) ([]*models.OperationalMode, error) {
+	query := `
+		SELECT id, tenant_id, mode, message, set_by, created_at, updated_at
+		FROM operational_modes
+		ORDER BY tenant_id NULLS FIRST
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var modes []*models.OperationalMode
+	for rows.Next() {
+		m := &models.OperationalMode{}
+		if err := rows.Scan(&m.ID, &m.TenantID, &m.Mode, &m.Message, &m.SetBy, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		modes = append(modes, m)
+	}
+	return modes, rows.Err()
+}
+
+func (r *operationalModeRepo) Set(ctx context.Context, mode *models.OperationalMode) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if mode.TenantID != nil {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)`, *mode.TenantID).Scan(&exists); err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, nil
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM operational_modes WHERE tenant_id IS NOT DISTINCT FROM $1`, mode.TenantID); err != nil {
+		return false, err
+	}
+	query := `
+		INSERT INTO operational_modes (id, tenant_id, mode, message, set_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err = tx.QueryRow(ctx, query, mode.ID, mode.TenantID, mode.Mode, mode.Message, mode.SetBy).
+		Scan(&mode.CreatedAt, &mode.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *operationalModeRepo) Clear(ctx context.Context, tenantID *uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM operational_modes WHERE tenant_id IS NOT DISTINCT FROM $1`, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
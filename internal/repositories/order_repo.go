@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 
 	"github.com/google/uuid"
@@ -18,7 +19,11 @@ type OrderRepository interface {
 	Update(ctx context.Context, order *models.Order) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Order, error)
-	GetOrdersByTenantAndDateRange(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]*models.Order, error)
+	// ListPage lists orders, only those of the branch when branchID is set
+	ListPage(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Order, error)
+	// GetOrdersByTenantAndDateRange lists orders dated in the range, only
+	// those of the branch when branchID is set
+	GetOrdersByTenantAndDateRange(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, startDate, endDate time.Time) ([]*models.Order, error)
 	GetOrdersByStatus(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.Order, error)
 	GetOrdersByTypeAndStatus(ctx context.Context, tenantID uuid.UUID, orderType, status string, limit, offset int) ([]*models.Order, error)
 	GetOrdersBySupplier(ctx context.Context, tenantID uuid.UUID, supplierID uuid.UUID, limit, offset int) ([]*models.Order, error)
@@ -38,6 +43,7 @@ func (r *orderRepo) Create(ctx context.Context, order *models.Order) error {
 	query := `
 		INSERT INTO orders (id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		RETURNING branch_id
 	`
 	var supplierID, distributorID, expectedDelivery interface{}
 	if order.SupplierID != nil {
@@ -51,18 +57,18 @@ func (r *orderRepo) Create(ctx context.Context, order *models.Order) error {
 	} else {
 		expectedDelivery = nil
 	}
-	_, err := r.db.Exec(ctx, query, order.ID, order.TenantID, order.OrderType, supplierID, distributorID, order.ProductID, order.WarehouseID, order.Quantity, order.UnitPrice, order.Status, order.OrderDate, expectedDelivery, order.Notes)
-	return err
+	// The branch is filled in from the warehouse
+	return r.db.QueryRow(ctx, query, order.ID, order.TenantID, order.OrderType, supplierID, distributorID, order.ProductID, order.WarehouseID, order.Quantity, order.UnitPrice, order.Status, order.OrderDate, expectedDelivery, order.Notes).Scan(&order.BranchID)
 }
 
 func (r *orderRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Order, error) {
 	order := &models.Order{}
 	query := `
-		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
 		FROM orders
 		WHERE tenant_id = $1 AND id = $2
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -75,8 +81,9 @@ func (r *orderRepo) Update(ctx context.Context, order *models.Order) error {
 		SET order_type = $1, supplier_id = $2, distributor_id = $3, product_id = $4, warehouse_id = $5, quantity = $6, unit_price = $7, status = $8, order_date = $9, expected_delivery = $10, notes = $11, updated_at = NOW()
 		WHERE tenant_id = $12 AND id = $13
 	`
-	_, err := r.db.Exec(ctx, query, order.OrderType, order.SupplierID, order.DistributorID, order.ProductID, order.WarehouseID, order.Quantity, order.UnitPrice, order.Status, order.OrderDate, order.ExpectedDelivery, order.Notes, order.TenantID, order.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, order.ID, "updated_at", query, []interface{}{order.OrderType, order.SupplierID, order.DistributorID, order.ProductID, order.WarehouseID, order.Quantity, order.UnitPrice, order.Status, order.OrderDate, order.ExpectedDelivery, order.Notes, order.TenantID, order.ID})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *orderRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -85,15 +92,31 @@ func (r *orderRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
 	return err
 }
 
+// OrderSortColumns whitelists the fields the order list can be sorted by
+var OrderSortColumns = map[string]string{
+	"order_date":        "order_date",
+	"expected_delivery": "expected_delivery",
+	"quantity":          "quantity",
+	"unit_price":        "unit_price",
+	"status":            "status",
+	"created_at":        "created_at",
+	"updated_at":        "updated_at",
+}
+
 func (r *orderRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Order, error) {
-	query := `
-		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+	return r.ListPage(ctx, tenantID, nil, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists orders in the page's sort order, most recent order date first by default
+func (r *orderRepo) ListPage(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Order, error) {
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
 		FROM orders
-		WHERE tenant_id = $1
-		ORDER BY order_date DESC
+		WHERE tenant_id = $1 AND ($4::uuid IS NULL OR branch_id = $4)
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("order_date DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset, branchID)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +125,7 @@ func (r *orderRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset
 	var orders []*models.Order
 	for rows.Next() {
 		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -125,7 +148,7 @@ func (r *orderRepo) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, filt
 
 	// Build query dynamically
 	queryBase := `
-		SELECT o.id, o.tenant_id, o.order_type, o.supplier_id, o.distributor_id, o.product_id, o.warehouse_id, o.quantity, o.unit_price, o.status, o.order_date, o.expected_delivery, o.notes, o.created_at, o.updated_at
+		SELECT o.id, o.tenant_id, o.order_type, o.supplier_id, o.distributor_id, o.product_id, o.warehouse_id, o.branch_id, o.quantity, o.unit_price, o.status, o.order_date, o.expected_delivery, o.notes, o.created_at, o.updated_at
 		FROM orders o
 		WHERE o.tenant_id = $1
 	`
@@ -199,6 +222,13 @@ func (r *orderRepo) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, filt
 		args = append(args, *filter.WarehouseID)
 	}
 
+	// Branch filter
+	if filter.BranchID != nil {
+		conditionCount++
+		queryBase += fmt.Sprintf(` AND o.branch_id = $%d`, conditionCount)
+		args = append(args, *filter.BranchID)
+	}
+
 	// Quantity range
 	if filter.MinQuantity != nil {
 		conditionCount++
@@ -282,7 +312,7 @@ func (r *orderRepo) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, filt
 	var orders []*models.Order
 	for rows.Next() {
 		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -291,14 +321,14 @@ func (r *orderRepo) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, filt
 	return orders, nil
 }
 
-func (r *orderRepo) GetOrdersByTenantAndDateRange(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) ([]*models.Order, error) {
+func (r *orderRepo) GetOrdersByTenantAndDateRange(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, startDate, endDate time.Time) ([]*models.Order, error) {
 	query := `
-		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
 		FROM orders
-		WHERE tenant_id = $1 AND order_date BETWEEN $2 AND $3
+		WHERE tenant_id = $1 AND order_date BETWEEN $2 AND $3 AND ($4::uuid IS NULL OR branch_id = $4)
 		ORDER BY order_date DESC
 	`
-	rows, err := r.db.Query(ctx, query, tenantID, startDate, endDate)
+	rows, err := r.db.Query(ctx, query, tenantID, startDate, endDate, branchID)
 	if err != nil {
 		return nil, err
 	}
@@ -307,7 +337,7 @@ func (r *orderRepo) GetOrdersByTenantAndDateRange(ctx context.Context, tenantID
 	var orders []*models.Order
 	for rows.Next() {
 		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -318,7 +348,7 @@ func (r *orderRepo) GetOrdersByTenantAndDateRange(ctx context.Context, tenantID
 // GetOrdersByStatus retrieves orders by status with pagination
 func (r *orderRepo) GetOrdersByStatus(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.Order, error) {
 	query := `
-		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
 		FROM orders
 		WHERE tenant_id = $1 AND status = $2
 		ORDER BY order_date DESC
@@ -333,7 +363,7 @@ func (r *orderRepo) GetOrdersByStatus(ctx context.Context, tenantID uuid.UUID, s
 	var orders []*models.Order
 	for rows.Next() {
 		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -344,7 +374,7 @@ func (r *orderRepo) GetOrdersByStatus(ctx context.Context, tenantID uuid.UUID, s
 // GetOrdersByTypeAndStatus retrieves orders by type and status with pagination
 func (r *orderRepo) GetOrdersByTypeAndStatus(ctx context.Context, tenantID uuid.UUID, orderType, status string, limit, offset int) ([]*models.Order, error) {
 	query := `
-		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
 		FROM orders
 		WHERE tenant_id = $1 AND order_type = $2 AND status = $3
 		ORDER BY order_date DESC
@@ -359,7 +389,7 @@ func (r *orderRepo) GetOrdersByTypeAndStatus(ctx context.Context, tenantID uuid.
 	var orders []*models.Order
 	for rows.Next() {
 		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -370,7 +400,7 @@ func (r *orderRepo) GetOrdersByTypeAndStatus(ctx context.Context, tenantID uuid.
 // GetOrdersBySupplier retrieves orders by supplier
 func (r *orderRepo) GetOrdersBySupplier(ctx context.Context, tenantID uuid.UUID, supplierID uuid.UUID, limit, offset int) ([]*models.Order, error) {
 	query := `
-		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
 		FROM orders
 		WHERE tenant_id = $1 AND supplier_id = $2
 		ORDER BY order_date DESC
@@ -385,7 +415,7 @@ func (r *orderRepo) GetOrdersBySupplier(ctx context.Context, tenantID uuid.UUID,
 	var orders []*models.Order
 	for rows.Next() {
 		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -396,7 +426,7 @@ func (r *orderRepo) GetOrdersBySupplier(ctx context.Context, tenantID uuid.UUID,
 // GetOrdersByDistributor retrieves orders by distributor
 func (r *orderRepo) GetOrdersByDistributor(ctx context.Context, tenantID uuid.UUID, distributorID uuid.UUID, limit, offset int) ([]*models.Order, error) {
 	query := `
-		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
 		FROM orders
 		WHERE tenant_id = $1 AND distributor_id = $2
 		ORDER BY order_date DESC
@@ -411,7 +441,7 @@ func (r *orderRepo) GetOrdersByDistributor(ctx context.Context, tenantID uuid.UU
 	var orders []*models.Order
 	for rows.Next() {
 		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
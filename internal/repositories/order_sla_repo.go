@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OrderSLARepository interface {
+	ListDefinitions(ctx context.Context, tenantID uuid.UUID, activeOnly bool) ([]*models.OrderSLA, error)
+	GetDefinition(ctx context.Context, tenantID, id uuid.UUID) (*models.OrderSLA, error)
+	CreateDefinition(ctx context.Context, sla *models.OrderSLA) error
+	UpdateDefinition(ctx context.Context, sla *models.OrderSLA) error
+	DeleteDefinition(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+
+	FindBreaches(ctx context.Context, sla *models.OrderSLA, now, dueAfter time.Time, limit int) ([]*models.OrderSLABreach, error)
+	RecordBreach(ctx context.Context, breach *models.OrderSLABreach) (bool, error)
+	ResolveBreaches(ctx context.Context, sla *models.OrderSLA) (int64, error)
+	ListBreaches(ctx context.Context, tenantID uuid.UUID, slaID *uuid.UUID, openOnly bool, limit, offset int) ([]*models.OrderSLABreach, error)
+
+	Compliance(ctx context.Context, sla *models.OrderSLA, from, to, now time.Time, groupBy string) ([]*models.SLAComplianceRow, error)
+}
+
+type orderSLARepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOrderSLARepo(db *pgxpool.Pool) OrderSLARepository {
+	return &orderSLARepo{db: db}
+}
+
+// slaStartColumns is when an order reached each start milestone
+var slaStartColumns = map[string]string{
+	models.SLAEventCreated:    "o.created_at",
+	models.SLAEventApproved:   "o.approved_at",
+	models.SLAEventProcessing: "o.processing_at",
+	models.SLAEventShipped:    "o.shipped_at",
+}
+
+// slaEndColumns is when an order reached each end milestone or any later
+// one, so purchase orders received straight from processing count as shipped
+var slaEndColumns = map[string]string{
+	models.SLAEventApproved:   "LEAST(o.approved_at, o.processing_at, o.shipped_at, o.delivered_at)",
+	models.SLAEventProcessing: "LEAST(o.processing_at, o.shipped_at, o.delivered_at)",
+	models.SLAEventShipped:    "LEAST(o.shipped_at, o.delivered_at)",
+	models.SLAEventDelivered:  "o.delivered_at",
+}
+
+// slaWaitingStatuses are the order statuses still short of each end
+// milestone. Matching on status as well as the milestone time keeps orders
+// that completed before milestones were recorded from counting as late
+var slaWaitingStatuses = map[string][]string{
+	models.SLAEventApproved:   {"pending"},
+	models.SLAEventProcessing: {"pending", "approved"},
+	models.SLAEventShipped:    {"pending", "approved", "processing", "quality_check"},
+	models.SLAEventDelivered:  {"pending", "approved", "processing", "quality_check", "shipped"},
+}
+
+func slaColumns(sla *models.OrderSLA) (string, string, []string, error) {
+	start, ok := slaStartColumns[sla.StartEvent]
+	if !ok {
+		return "", "", nil, fmt.Errorf("unknown SLA start event %q", sla.StartEvent)
+	}
+	end, ok := slaEndColumns[sla.EndEvent]
+	if !ok {
+		return "", "", nil, fmt.Errorf("unknown SLA end event %q", sla.EndEvent)
+	}
+	return start, end, slaWaitingStatuses[sla.EndEvent], nil
+}
+
+const orderSLAColumns = `id, tenant_id, name, order_type, warehouse_id, start_event, end_event, target_minutes, notify_emails, is_active, created_at, updated_at`
+
+func scanOrderSLA(row rowScanner) (*models.OrderSLA, error) {
+	sla := &models.OrderSLA{}
+	err := row.Scan(&sla.ID, &sla.TenantID, &sla.Name, &sla.OrderType, &sla.WarehouseID, &sla.StartEvent, &sla.EndEvent,
+		&sla.TargetMinutes, &sla.NotifyEmails, &sla.IsActive, &sla.CreatedAt, &sla.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return sla, nil
+}
+
+func (r *orderSLARepo) ListDefinitions(ctx context.Context, tenantID uuid.UUID, activeOnly bool) ([]*models.OrderSLA, error) {
+	query := `SELECT ` + orderSLAColumns + ` FROM order_sla_definitions WHERE tenant_id = $1 AND (NOT $2 OR is_active) ORDER BY name`
+	rows, err := r.db.Query(ctx, query, tenantID, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slas := []*models.OrderSLA{}
+	for rows.Next() {
+		sla, err := scanOrderSLA(rows)
+		if err != nil {
+			return nil, err
+		}
+		slas = append(slas, sla)
+	}
+	return slas, rows.Err()
+}
+
+func (r *orderSLARepo) GetDefinition(ctx context.Context, tenantID, id uuid.UUID) (*models.OrderSLA, error) {
+	query := `SELECT ` + orderSLAColumns + ` FROM order_sla_definitions WHERE tenant_id = $1 AND id = $2`
+	sla, err := scanOrderSLA(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return sla, err
+}
+
+func (r *orderSLARepo) CreateDefinition(ctx context.Context, sla *models.OrderSLA) error {
+	query := `
+		INSERT INTO order_sla_definitions (id, tenant_id, name, order_type, warehouse_id, start_event, end_event, target_minutes, notify_emails, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, sla.ID, sla.TenantID, sla.Name, sla.OrderType, sla.WarehouseID, sla.StartEvent, sla.EndEvent,
+		sla.TargetMinutes, sla.NotifyEmails, sla.IsActive).Scan(&sla.CreatedAt, &sla.UpdatedAt)
+}
+
+func (r *orderSLARepo) UpdateDefinition(ctx context.Context, sla *models.OrderSLA) error {
+	query := `
+		UPDATE order_sla_definitions
+		SET name = $3, order_type = $4, warehouse_id = $5, start_event = $6, end_event = $7, target_minutes = $8,
+			notify_emails = $9, is_active = $10, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, sla.TenantID, sla.ID, sla.Name, sla.OrderType, sla.WarehouseID, sla.StartEvent, sla.EndEvent,
+		sla.TargetMinutes, sla.NotifyEmails, sla.IsActive).Scan(&sla.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("SLA not found")
+	}
+	return err
+}
+
+func (r *orderSLARepo) DeleteDefinition(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM order_sla_definitions WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// FindBreaches lists up to limit orders whose SLA deadline passed before now
+// (and after dueAfter) without reaching the end milestone and that have not
+// been recorded yet, oldest deadline first
+func (r *orderSLARepo) FindBreaches(ctx context.Context, sla *models.OrderSLA, now, dueAfter time.Time, limit int) ([]*models.OrderSLABreach, error) {
+	start, end, waiting, err := slaColumns(sla)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT o.id, o.order_type, o.status, p.name, o.warehouse_id, w.name, ` + start + `, ` + start + ` + make_interval(mins => $4)
+		FROM orders o
+		JOIN products p ON p.id = o.product_id
+		JOIN warehouses w ON w.id = o.warehouse_id
+		WHERE o.tenant_id = $1 AND ($2::text IS NULL OR o.order_type = $2) AND ($3::uuid IS NULL OR o.warehouse_id = $3)
+			AND o.status = ANY($5) AND ` + end + ` IS NULL
+			AND ` + start + ` + make_interval(mins => $4) < $6 AND ` + start + ` + make_interval(mins => $4) >= $7
+			AND NOT EXISTS (SELECT 1 FROM order_sla_breaches b WHERE b.sla_id = $8 AND b.order_id = o.id)
+		ORDER BY 8
+		LIMIT $9
+	`
+	rows, err := r.db.Query(ctx, query, sla.TenantID, sla.OrderType, sla.WarehouseID, sla.TargetMinutes, waiting, now, dueAfter, sla.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var breaches []*models.OrderSLABreach
+	for rows.Next() {
+		b := &models.OrderSLABreach{TenantID: sla.TenantID, SLAID: sla.ID, SLAName: sla.Name}
+		if err := rows.Scan(&b.OrderID, &b.OrderType, &b.OrderStatus, &b.ProductName, &b.WarehouseID, &b.WarehouseName, &b.StartedAt, &b.DueAt); err != nil {
+			return nil, err
+		}
+		breaches = append(breaches, b)
+	}
+	return breaches, rows.Err()
+}
+
+// RecordBreach logs a breach; false means another run already did
+func (r *orderSLARepo) RecordBreach(ctx context.Context, breach *models.OrderSLABreach) (bool, error) {
+	query := `
+		INSERT INTO order_sla_breaches (id, tenant_id, sla_id, order_id, warehouse_id, started_at, due_at, detected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (sla_id, order_id) DO NOTHING
+		RETURNING detected_at
+	`
+	err := r.db.QueryRow(ctx, query, breach.ID, breach.TenantID, breach.SLAID, breach.OrderID, breach.WarehouseID,
+		breach.StartedAt, breach.DueAt).Scan(&breach.DetectedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ResolveBreaches closes the SLA's open breaches whose orders have since
+// reached the end milestone, or were cancelled
+func (r *orderSLARepo) ResolveBreaches(ctx context.Context, sla *models.OrderSLA) (int64, error) {
+	_, end, _, err := slaColumns(sla)
+	if err != nil {
+		return 0, err
+	}
+	query := `
+		UPDATE order_sla_breaches b
+		SET resolved_at = COALESCE(` + end + `, o.cancelled_at, NOW())
+		FROM orders o
+		WHERE b.tenant_id = $1 AND b.sla_id = $2 AND b.resolved_at IS NULL
+			AND o.tenant_id = b.tenant_id AND o.id = b.order_id
+			AND (` + end + ` IS NOT NULL OR o.status = 'cancelled')
+	`
+	tag, err := r.db.Exec(ctx, query, sla.TenantID, sla.ID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListBreaches lists recorded breaches, newest first
+func (r *orderSLARepo) ListBreaches(ctx context.Context, tenantID uuid.UUID, slaID *uuid.UUID, openOnly bool, limit, offset int) ([]*models.OrderSLABreach, error) {
+	query := `
+		SELECT b.id, b.tenant_id, b.sla_id, s.name, b.order_id, o.order_type, o.status, p.name, b.warehouse_id, w.name,
+			b.started_at, b.due_at, b.detected_at, b.resolved_at
+		FROM order_sla_breaches b
+		JOIN order_sla_definitions s ON s.id = b.sla_id
+		JOIN orders o ON o.id = b.order_id AND o.tenant_id = b.tenant_id
+		JOIN products p ON p.id = o.product_id
+		JOIN warehouses w ON w.id = b.warehouse_id
+		WHERE b.tenant_id = $1 AND ($2::uuid IS NULL OR b.sla_id = $2) AND (NOT $3 OR b.resolved_at IS NULL)
+		ORDER BY b.detected_at DESC, b.id
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, slaID, openOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breaches := []*models.OrderSLABreach{}
+	for rows.Next() {
+		b := &models.OrderSLABreach{}
+		if err := rows.Scan(&b.ID, &b.TenantID, &b.SLAID, &b.SLAName, &b.OrderID, &b.OrderType, &b.OrderStatus, &b.ProductName,
+			&b.WarehouseID, &b.WarehouseName, &b.StartedAt, &b.DueAt, &b.DetectedAt, &b.ResolvedAt); err != nil {
+			return nil, err
+		}
+		breaches = append(breaches, b)
+	}
+	return breaches, rows.Err()
+}
+
+// Compliance counts met, breached and open orders per warehouse or territory
+// for orders that reached the SLA's start milestone in [from, to)
+func (r *orderSLARepo) Compliance(ctx context.Context, sla *models.OrderSLA, from, to, now time.Time, groupBy string) ([]*models.SLAComplianceRow, error) {
+	start, end, waiting, err := slaColumns(sla)
+	if err != nil {
+		return nil, err
+	}
+	groupID, groupName := "w.id", "w.name"
+	if groupBy == models.SLAGroupByTerritory {
+		groupID, groupName = "t.id", "COALESCE(t.name, 'Unassigned')"
+	}
+	due := start + ` + make_interval(mins => $4)`
+	waitingClause := `(` + end + ` IS NULL AND o.status = ANY($5))`
+	query := `
+		SELECT ` + groupID + `, ` + groupName + `,
+			COUNT(*) FILTER (WHERE ` + end + ` <= ` + due + `),
+			COUNT(*) FILTER (WHERE ` + end + ` > ` + due + ` OR (` + waitingClause + ` AND ` + due + ` < $8)),
+			COUNT(*) FILTER (WHERE ` + waitingClause + ` AND ` + due + ` >= $8),
+			(AVG(EXTRACT(EPOCH FROM ` + end + ` - ` + start + `) / 60))::float8
+		FROM orders o
+		JOIN warehouses w ON w.id = o.warehouse_id
+		LEFT JOIN territories t ON t.id = w.territory_id
+		WHERE o.tenant_id = $1 AND ($2::text IS NULL OR o.order_type = $2) AND ($3::uuid IS NULL OR o.warehouse_id = $3)
+			AND ` + start + ` >= $6 AND ` + start + ` < $7
+			AND (` + end + ` IS NOT NULL OR o.status = ANY($5))
+		GROUP BY 1, 2
+		ORDER BY 2
+	`
+	rows, err := r.db.Query(ctx, query, sla.TenantID, sla.OrderType, sla.WarehouseID, sla.TargetMinutes, waiting, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*models.SLAComplianceRow
+	for rows.Next() {
+		row := &models.SLAComplianceRow{SLAID: sla.ID, SLAName: sla.Name, TargetMinutes: sla.TargetMinutes}
+		if err := rows.Scan(&row.GroupID, &row.GroupName, &row.Met, &row.Breached, &row.Open, &row.AvgMinutes); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
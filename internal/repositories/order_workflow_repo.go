@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OrderWorkflowRepository stores each tenant's order workflow configuration
+type OrderWorkflowRepository interface {
+	Get(ctx context.Context, tenantID uuid.UUID) (*models.OrderWorkflow, error)
+	Upsert(ctx context.Context, workflow *models.OrderWorkflow) error
+	Delete(ctx context.Context, tenantID uuid.UUID) error
+}
+
+type orderWorkflowRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOrderWorkflowRepo(db *pgxpool.Pool) OrderWorkflowRepository {
+	return &orderWorkflowRepo{db: db}
+}
+
+// Get returns the tenant's workflow, or nil when it uses the default one
+func (r *orderWorkflowRepo) Get(ctx context.Context, tenantID uuid.UUID) (*models.OrderWorkflow, error) {
+	workflow := &models.OrderWorkflow{}
+	query := `
+		SELECT tenant_id, skip_approval, quality_check, updated_by, updated_at
+		FROM order_workflows
+		WHERE tenant_id = $1
+	`
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&workflow.TenantID, &workflow.SkipApproval, &workflow.QualityCheck,
+		&workflow.UpdatedBy, &workflow.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+func (r *orderWorkflowRepo) Upsert(ctx context.Context, workflow *models.OrderWorkflow) error {
+	query := `
+		INSERT INTO order_workflows (tenant_id, skip_approval, quality_check, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			skip_approval = EXCLUDED.skip_approval,
+			quality_check = EXCLUDED.quality_check,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, workflow.TenantID, workflow.SkipApproval, workflow.QualityCheck, workflow.UpdatedBy).Scan(&workflow.UpdatedAt)
+}
+
+func (r *orderWorkflowRepo) Delete(ctx context.Context, tenantID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM order_workflows WHERE tenant_id = $1`, tenantID)
+	return err
+}
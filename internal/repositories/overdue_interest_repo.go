@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OverdueInterestRepository interface {
+	GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.OverdueInterestSettings, error)
+	UpsertSettings(ctx context.Context, settings *models.OverdueInterestSettings) error
+
+	GetCustomerTerms(ctx context.Context, tenantID, distributorID uuid.UUID) (*models.CustomerInterestTerms, error)
+	UpsertCustomerTerms(ctx context.Context, terms *models.CustomerInterestTerms) error
+	DeleteCustomerTerms(ctx context.Context, tenantID, distributorID uuid.UUID) (bool, error)
+
+	OverdueInvoices(ctx context.Context, tenantID uuid.UUID, dueBefore time.Time) ([]*models.InterestCandidate, error)
+	CreateEntry(ctx context.Context, entry *models.InterestEntry) (bool, error)
+	ListEntries(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InterestEntry, error)
+}
+
+type overdueInterestRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOverdueInterestRepo(db *pgxpool.Pool) OverdueInterestRepository {
+	return &overdueInterestRepo{db: db}
+}
+
+// GetSettings returns the tenant's interest terms, or nil when it has not configured any
+func (r *overdueInterestRepo) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.OverdueInterestSettings, error) {
+	query := `
+		SELECT tenant_id, enabled, enabled_since, rate_percent_per_month::float8, grace_days, updated_at
+		FROM overdue_interest_settings
+		WHERE tenant_id = $1
+	`
+	settings := &models.OverdueInterestSettings{}
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&settings.TenantID, &settings.Enabled, &settings.EnabledSince,
+		&settings.RatePercentPerMonth, &settings.GraceDays, &settings.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpsertSettings saves the tenant's terms, starting enabled_since afresh
+// whenever interest is switched on
+func (r *overdueInterestRepo) UpsertSettings(ctx context.Context, settings *models.OverdueInterestSettings) error {
+	query := `
+		INSERT INTO overdue_interest_settings (tenant_id, enabled, enabled_since, rate_percent_per_month, grace_days, updated_at)
+		VALUES ($1, $2, CASE WHEN $2 THEN CURRENT_DATE END, $3, $4, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+			enabled_since = CASE
+				WHEN NOT EXCLUDED.enabled THEN NULL
+				WHEN overdue_interest_settings.enabled THEN overdue_interest_settings.enabled_since
+				ELSE CURRENT_DATE
+			END,
+			rate_percent_per_month = EXCLUDED.rate_percent_per_month, grace_days = EXCLUDED.grace_days, updated_at = NOW()
+		RETURNING enabled_since, updated_at
+	`
+	return r.db.QueryRow(ctx, query, settings.TenantID, settings.Enabled, settings.RatePercentPerMonth,
+		settings.GraceDays).Scan(&settings.EnabledSince, &settings.UpdatedAt)
+}
+
+// GetCustomerTerms returns the customer's override, or nil when it has none
+func (r *overdueInterestRepo) GetCustomerTerms(ctx context.Context, tenantID, distributorID uuid.UUID) (*models.CustomerInterestTerms, error) {
+	query := `
+		SELECT tenant_id, distributor_id, rate_percent_per_month::float8, grace_days, updated_at
+		FROM customer_interest_terms
+		WHERE tenant_id = $1 AND distributor_id = $2
+	`
+	terms := &models.CustomerInterestTerms{}
+	err := r.db.QueryRow(ctx, query, tenantID, distributorID).Scan(&terms.TenantID, &terms.DistributorID,
+		&terms.RatePercentPerMonth, &terms.GraceDays, &terms.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+func (r *overdueInterestRepo) UpsertCustomerTerms(ctx context.Context, terms *models.CustomerInterestTerms) error {
+	query := `
+		INSERT INTO customer_interest_terms (tenant_id, distributor_id, rate_percent_per_month, grace_days, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id, distributor_id) DO UPDATE
+		SET rate_percent_per_month = EXCLUDED.rate_percent_per_month, grace_days = EXCLUDED.grace_days, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, terms.TenantID, terms.DistributorID, terms.RatePercentPerMonth,
+		terms.GraceDays).Scan(&terms.UpdatedAt)
+}
+
+func (r *overdueInterestRepo) DeleteCustomerTerms(ctx context.Context, tenantID, distributorID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM customer_interest_terms WHERE tenant_id = $1 AND distributor_id = $2`, tenantID, distributorID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// OverdueInvoices lists unpaid sales invoices due before the given day with
+// their customer's terms and the last day interest was accrued to. The amount
+// is what is left of the invoice total once allocated payments have settled
+// the accrued interest
+func (r *overdueInterestRepo) OverdueInvoices(ctx context.Context, tenantID uuid.UUID, dueBefore time.Time) ([]*models.InterestCandidate, error) {
+	query := `
+		SELECT i.id, o.distributor_id, LEAST(i.total_amount, ` + invoiceOutstanding + `)::float8, i.due_date,
+			t.rate_percent_per_month::float8, t.grace_days,
+			(SELECT MAX(e.period_end) FROM invoice_interest_entries e WHERE e.invoice_id = i.id)
+		FROM invoices i
+		JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		LEFT JOIN customer_interest_terms t ON t.tenant_id = i.tenant_id AND t.distributor_id = o.distributor_id
+		WHERE i.tenant_id = $1
+			AND o.distributor_id IS NOT NULL
+			AND i.status IN ('unpaid', 'overdue')
+			AND i.due_date::date < $2::date
+		ORDER BY i.due_date
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, dueBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []*models.InterestCandidate
+	for rows.Next() {
+		candidate := &models.InterestCandidate{}
+		if err := rows.Scan(&candidate.InvoiceID, &candidate.DistributorID, &candidate.TotalAmount, &candidate.DueDate,
+			&candidate.CustomerRate, &candidate.CustomerGraceDays, &candidate.AccruedThrough); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+// CreateEntry records accrued interest, reporting false when the period was
+// already accrued or the invoice has been paid or cancelled in the meantime
+func (r *overdueInterestRepo) CreateEntry(ctx context.Context, entry *models.InterestEntry) (bool, error) {
+	query := `
+		INSERT INTO invoice_interest_entries (id, tenant_id, invoice_id, distributor_id, period_start, period_end, days,
+			principal, rate_percent_per_month, amount, created_at)
+		SELECT $1, $2, $3, $4, $5::date, $6::date, $7, $8, $9, $10, NOW()
+		FROM invoices
+		WHERE tenant_id = $2 AND id = $3 AND status IN ('unpaid', 'overdue')
+		ON CONFLICT (invoice_id, period_end) DO NOTHING
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, entry.ID, entry.TenantID, entry.InvoiceID, entry.DistributorID, entry.PeriodStart,
+		entry.PeriodEnd, entry.Days, entry.Principal, entry.RatePercentPerMonth, entry.Amount).Scan(&entry.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListEntries lists the interest accrued on an invoice, oldest first
+func (r *overdueInterestRepo) ListEntries(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InterestEntry, error) {
+	query := `
+		SELECT id, tenant_id, invoice_id, distributor_id, period_start, period_end, days, principal::float8,
+			rate_percent_per_month::float8, amount::float8, created_at
+		FROM invoice_interest_entries
+		WHERE tenant_id = $1 AND invoice_id = $2
+		ORDER BY period_end
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.InterestEntry
+	for rows.Next() {
+		e := &models.InterestEntry{}
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.InvoiceID, &e.DistributorID, &e.PeriodStart, &e.PeriodEnd, &e.Days,
+			&e.Principal, &e.RatePercentPerMonth, &e.Amount, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,376 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// allocationTolerance absorbs paise rounding when comparing amounts
+const allocationTolerance = 0.005
+
+// What is still owed on invoice i: its total plus accrued interest less the
+// payments actively allocated to it
+const (
+	invoiceInterestAccrued = `COALESCE((SELECT SUM(e.amount) FROM invoice_interest_entries e WHERE e.invoice_id = i.id), 0)`
+	invoiceAllocated       = `COALESCE((SELECT SUM(a.amount) FROM payment_allocations a WHERE a.invoice_id = i.id AND a.reversed_at IS NULL), 0)`
+	invoiceOutstanding     = `(i.total_amount + ` + invoiceInterestAccrued + ` - ` + invoiceAllocated + `)`
+)
+
+type PaymentAllocationRepository interface {
+	CreatePayment(ctx context.Context, payment *models.CustomerPayment, lines []models.AllocationLine) (bool, error)
+	GetPayment(ctx context.Context, tenantID, id uuid.UUID) (*models.CustomerPayment, error)
+	ListPayments(ctx context.Context, tenantID, distributorID uuid.UUID, limit, offset int) ([]*models.CustomerPayment, error)
+	OpenInvoices(ctx context.Context, tenantID, distributorID uuid.UUID, paymentID *uuid.UUID) ([]*models.OpenInvoice, error)
+
+	Allocate(ctx context.Context, tenantID, paymentID uuid.UUID, lines []models.AllocationLine, userID *uuid.UUID, reallocate bool) (bool, error)
+	ListAllocations(ctx context.Context, tenantID, paymentID uuid.UUID) ([]*models.PaymentAllocation, error)
+	InvoiceAllocations(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.PaymentAllocation, error)
+}
+
+type paymentAllocationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPaymentAllocationRepo(db *pgxpool.Pool) PaymentAllocationRepository {
+	return &paymentAllocationRepo{db: db}
+}
+
+const customerPaymentColumns = `p.id, p.tenant_id, p.distributor_id, p.payment_number, p.amount::float8, p.payment_date, p.method,
+	p.reference, p.notes, p.tds_section_id, p.tds_section, p.tds_amount::float8, p.tds_certificate_number, p.tds_certificate_date,
+	p.created_by, p.created_at, a.allocated::float8, (p.amount + p.tds_amount - a.allocated)::float8`
+
+// customerPaymentFrom joins each payment p to the total a.allocated of its
+// active allocations
+const customerPaymentFrom = `
+	FROM customer_payments p
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(SUM(amount), 0) AS allocated
+		FROM payment_allocations
+		WHERE payment_id = p.id AND reversed_at IS NULL
+	) a`
+
+func scanCustomerPayment(row rowScanner) (*models.CustomerPayment, error) {
+	p := &models.CustomerPayment{}
+	err := row.Scan(&p.ID, &p.TenantID, &p.DistributorID, &p.PaymentNumber, &p.Amount, &p.PaymentDate, &p.Method,
+		&p.Reference, &p.Notes, &p.TDSSectionID, &p.TDSSection, &p.TDSAmount, &p.TDSCertificateNumber, &p.TDSCertificateDate,
+		&p.CreatedBy, &p.CreatedAt, &p.Allocated, &p.Unallocated)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CreatePayment records a payment and applies it to invoices in one
+// transaction; it reports false, recording nothing, if an invoice can no
+// longer take its line
+func (r *paymentAllocationRepo) CreatePayment(ctx context.Context, payment *models.CustomerPayment, lines []models.AllocationLine) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO customer_payments (id, tenant_id, distributor_id, payment_number, amount, payment_date, method, reference, notes,
+			tds_section_id, tds_section, tds_amount, tds_certificate_number, tds_certificate_date, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW())
+		RETURNING created_at
+	`
+	if err := tx.QueryRow(ctx, query, payment.ID, payment.TenantID, payment.DistributorID, payment.PaymentNumber, payment.Amount,
+		payment.PaymentDate, payment.Method, payment.Reference, payment.Notes, payment.TDSSectionID, payment.TDSSection, payment.TDSAmount,
+		payment.TDSCertificateNumber, payment.TDSCertificateDate, payment.CreatedBy).Scan(&payment.CreatedAt); err != nil {
+		return false, err
+	}
+
+	ok, err := allocateInTx(ctx, tx, payment.TenantID, payment.ID, lines, payment.CreatedBy, false)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *paymentAllocationRepo) GetPayment(ctx context.Context, tenantID, id uuid.UUID) (*models.CustomerPayment, error) {
+	query := `SELECT ` + customerPaymentColumns + customerPaymentFrom + ` WHERE p.tenant_id = $1 AND p.id = $2`
+	payment, err := scanCustomerPayment(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return payment, err
+}
+
+// ListPayments lists a customer's payments, newest first
+func (r *paymentAllocationRepo) ListPayments(ctx context.Context, tenantID, distributorID uuid.UUID, limit, offset int) ([]*models.CustomerPayment, error) {
+	query := `SELECT ` + customerPaymentColumns + customerPaymentFrom + `
+		WHERE p.tenant_id = $1 AND p.distributor_id = $2
+		ORDER BY p.payment_date DESC, p.created_at DESC
+		LIMIT $3 OFFSET $4`
+	rows, err := r.db.Query(ctx, query, tenantID, distributorID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*models.CustomerPayment
+	for rows.Next() {
+		payment, err := scanCustomerPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// OpenInvoices lists a customer's unpaid invoices with something still
+// outstanding, oldest due first. With a payment, invoices it currently has
+// allocations on are listed too, even when they are paid, so it can be
+// reallocated
+func (r *paymentAllocationRepo) OpenInvoices(ctx context.Context, tenantID, distributorID uuid.UUID, paymentID *uuid.UUID) ([]*models.OpenInvoice, error) {
+	query := `
+		SELECT id, invoice_number, status, issued_date, due_date, total_amount::float8, interest::float8, allocated::float8, outstanding::float8
+		FROM (
+			SELECT i.id, i.invoice_number, i.status, i.issued_date, i.due_date, i.total_amount,
+				` + invoiceInterestAccrued + ` AS interest, ` + invoiceAllocated + ` AS allocated, ` + invoiceOutstanding + ` AS outstanding,
+				EXISTS (
+					SELECT 1 FROM payment_allocations pa
+					WHERE pa.invoice_id = i.id AND pa.payment_id = $4 AND pa.reversed_at IS NULL
+				) AS allocated_by_payment
+			FROM invoices i
+			JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+			WHERE i.tenant_id = $1 AND o.distributor_id = $2 AND i.status IN ('unpaid', 'overdue', 'paid')
+		) invoices
+		WHERE allocated_by_payment OR (status <> 'paid' AND outstanding > $3)
+		ORDER BY due_date, issued_date, invoice_number
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, distributorID, allocationTolerance, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*models.OpenInvoice
+	for rows.Next() {
+		inv := &models.OpenInvoice{}
+		if err := rows.Scan(&inv.InvoiceID, &inv.InvoiceNumber, &inv.Status, &inv.IssuedDate, &inv.DueDate, &inv.TotalAmount,
+			&inv.InterestAccrued, &inv.Allocated, &inv.Outstanding); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+// Allocate applies a payment to invoices in one transaction, first reversing
+// its active allocations when reallocating. It reports false, changing
+// nothing, if the payment or an invoice can no longer take the amounts
+func (r *paymentAllocationRepo) Allocate(ctx context.Context, tenantID, paymentID uuid.UUID, lines []models.AllocationLine, userID *uuid.UUID, reallocate bool) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	ok, err := allocateInTx(ctx, tx, tenantID, paymentID, lines, userID, reallocate)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// allocateInTx locks the payment and the invoices, checks that the lines fit
+// what is unallocated on the payment, TDS included, and outstanding on each invoice of the
+// same customer, records them and then settles or reopens every invoice whose
+// allocations changed
+func allocateInTx(ctx context.Context, tx pgx.Tx, tenantID, paymentID uuid.UUID, lines []models.AllocationLine, userID *uuid.UUID, reallocate bool) (bool, error) {
+	var distributorID uuid.UUID
+	var settled float64
+	query := `SELECT distributor_id, (amount + tds_amount)::float8 FROM customer_payments WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+	err := tx.QueryRow(ctx, query, tenantID, paymentID).Scan(&distributorID, &settled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var affected []uuid.UUID
+	if reallocate {
+		// Reopen what the reversed allocations had settled before checking the lines
+		query = `
+			UPDATE payment_allocations SET reversed_at = NOW(), reversed_by = $3
+			WHERE tenant_id = $1 AND payment_id = $2 AND reversed_at IS NULL
+			RETURNING invoice_id
+		`
+		rows, err := tx.Query(ctx, query, tenantID, paymentID, userID)
+		if err != nil {
+			return false, err
+		}
+		for rows.Next() {
+			var invoiceID uuid.UUID
+			if err := rows.Scan(&invoiceID); err != nil {
+				rows.Close()
+				return false, err
+			}
+			affected = append(affected, invoiceID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, err
+		}
+		if err := settleInvoices(ctx, tx, tenantID, affected, userID); err != nil {
+			return false, err
+		}
+	}
+
+	var allocated, requested float64
+	query = `SELECT COALESCE(SUM(amount), 0)::float8 FROM payment_allocations WHERE payment_id = $1 AND reversed_at IS NULL`
+	if err := tx.QueryRow(ctx, query, paymentID).Scan(&allocated); err != nil {
+		return false, err
+	}
+	invoiceIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		requested += line.Amount
+		invoiceIDs = append(invoiceIDs, line.InvoiceID)
+	}
+	if allocated+requested > settled+allocationTolerance {
+		return false, nil
+	}
+
+	if len(lines) > 0 {
+		if _, err := tx.Exec(ctx, `SELECT id FROM invoices WHERE tenant_id = $1 AND id = ANY($2) ORDER BY id FOR UPDATE`, tenantID, invoiceIDs); err != nil {
+			return false, err
+		}
+
+		query = `
+			SELECT i.id, o.distributor_id, i.status, ` + invoiceOutstanding + `::float8
+			FROM invoices i
+			JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+			WHERE i.tenant_id = $1 AND i.id = ANY($2)
+		`
+		rows, err := tx.Query(ctx, query, tenantID, invoiceIDs)
+		if err != nil {
+			return false, err
+		}
+		outstanding := make(map[uuid.UUID]float64, len(lines))
+		for rows.Next() {
+			var invoiceID uuid.UUID
+			var owner *uuid.UUID
+			var status string
+			var owed float64
+			if err := rows.Scan(&invoiceID, &owner, &status, &owed); err != nil {
+				rows.Close()
+				return false, err
+			}
+			if owner != nil && *owner == distributorID && (status == "unpaid" || status == "overdue") {
+				outstanding[invoiceID] = owed
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, err
+		}
+
+		for _, line := range lines {
+			owed, ok := outstanding[line.InvoiceID]
+			if !ok || line.Amount > owed+allocationTolerance {
+				return false, nil
+			}
+			query = `
+				INSERT INTO payment_allocations (id, tenant_id, payment_id, invoice_id, amount, allocated_by, allocated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, NOW())
+			`
+			if _, err := tx.Exec(ctx, query, uuid.New(), tenantID, paymentID, line.InvoiceID, line.Amount, userID); err != nil {
+				return false, err
+			}
+		}
+		affected = append(affected, invoiceIDs...)
+	}
+
+	return true, settleInvoices(ctx, tx, tenantID, affected, userID)
+}
+
+// settleInvoices marks invoices paid once nothing is outstanding on them, as
+// of their latest allocated payment, and reopens paid ones that owe again.
+// Each status change is written to the status history as made by userID
+func settleInvoices(ctx context.Context, tx pgx.Tx, tenantID uuid.UUID, invoiceIDs []uuid.UUID, userID *uuid.UUID) error {
+	if len(invoiceIDs) == 0 {
+		return nil
+	}
+	query := `
+		WITH settled AS (
+			UPDATE invoices i
+			SET status = CASE
+					WHEN ` + invoiceOutstanding + ` <= $3 THEN 'paid'
+					WHEN i.status = 'paid' THEN CASE WHEN i.due_date < NOW() THEN 'overdue' ELSE 'unpaid' END
+					ELSE i.status
+				END,
+				paid_date = CASE
+					WHEN ` + invoiceOutstanding + ` <= $3 THEN COALESCE((
+						SELECT MAX(p.payment_date)
+						FROM payment_allocations a
+						JOIN customer_payments p ON p.id = a.payment_id
+						WHERE a.invoice_id = i.id AND a.reversed_at IS NULL
+					), i.paid_date, CURRENT_DATE)
+					ELSE NULL
+				END,
+				updated_at = NOW()
+			FROM invoices prev
+			WHERE prev.id = i.id AND i.tenant_id = $1 AND i.id = ANY($2) AND i.status <> 'cancelled'
+			RETURNING i.id, prev.status AS from_status, i.status AS to_status
+		)
+		INSERT INTO status_history (tenant_id, entity_type, entity_id, from_status, to_status, actor_id, comment)
+		SELECT $1, 'invoice', id, from_status, to_status, $4, 'Payment allocation'
+		FROM settled
+		WHERE from_status <> to_status
+	`
+	_, err := tx.Exec(ctx, query, tenantID, invoiceIDs, allocationTolerance, userID)
+	return err
+}
+
+const paymentAllocationColumns = `a.id, a.tenant_id, a.payment_id, a.invoice_id, a.amount::float8, a.allocated_by, a.allocated_at,
+	a.reversed_by, a.reversed_at, i.invoice_number, p.payment_number`
+
+func (r *paymentAllocationRepo) queryAllocations(ctx context.Context, where string, args ...interface{}) ([]*models.PaymentAllocation, error) {
+	query := `
+		SELECT ` + paymentAllocationColumns + `
+		FROM payment_allocations a
+		JOIN invoices i ON i.id = a.invoice_id
+		JOIN customer_payments p ON p.id = a.payment_id
+		WHERE ` + where + `
+		ORDER BY a.allocated_at, a.id
+	`
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allocations []*models.PaymentAllocation
+	for rows.Next() {
+		a := &models.PaymentAllocation{}
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.PaymentID, &a.InvoiceID, &a.Amount, &a.AllocatedBy, &a.AllocatedAt,
+			&a.ReversedBy, &a.ReversedAt, &a.InvoiceNumber, &a.PaymentNumber); err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, a)
+	}
+	return allocations, rows.Err()
+}
+
+// ListAllocations lists every allocation of a payment, reversed ones included,
+// oldest first
+func (r *paymentAllocationRepo) ListAllocations(ctx context.Context, tenantID, paymentID uuid.UUID) ([]*models.PaymentAllocation, error) {
+	return r.queryAllocations(ctx, `a.tenant_id = $1 AND a.payment_id = $2`, tenantID, paymentID)
+}
+
+// InvoiceAllocations lists every allocation to an invoice, reversed ones
+// included, oldest first
+func (r *paymentAllocationRepo) InvoiceAllocations(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.PaymentAllocation, error) {
+	return r.queryAllocations(ctx, `a.tenant_id = $1 AND a.invoice_id = $2`, tenantID, invoiceID)
+}
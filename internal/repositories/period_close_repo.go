@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PeriodCloseRepository interface {
+	// Close records the close and its history entry; false when the period
+	// is already closed for that scope
+	Close(ctx context.Context, periodClose *models.PeriodClose, event *models.PeriodCloseEvent) (bool, error)
+	// Reopen removes the close and records the history entry; false when the
+	// period was not closed for that scope
+	Reopen(ctx context.Context, event *models.PeriodCloseEvent) (bool, error)
+	List(ctx context.Context, tenantID uuid.UUID) ([]*models.PeriodClose, error)
+	// Covering finds a close locking the date for the branch, a tenant-wide
+	// close included, or nil when the period is open
+	Covering(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, date time.Time) (*models.PeriodClose, error)
+	ListEvents(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PeriodCloseEvent, error)
+}
+
+type periodCloseRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPeriodCloseRepo(db *pgxpool.Pool) PeriodCloseRepository {
+	return &periodCloseRepo{db: db}
+}
+
+const periodCloseColumns = `id, tenant_id, branch_id, period, notes, closed_by, closed_at`
+
+func scanPeriodClose(row rowScanner) (*models.PeriodClose, error) {
+	periodClose := &models.PeriodClose{}
+	if err := row.Scan(&periodClose.ID, &periodClose.TenantID, &periodClose.BranchID, &periodClose.Period, &periodClose.Notes, &periodClose.ClosedBy, &periodClose.ClosedAt); err != nil {
+		return nil, err
+	}
+	return periodClose, nil
+}
+
+const insertPeriodCloseEvent = `
+	INSERT INTO period_close_events (id, tenant_id, branch_id, period, action, reason, user_id, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+`
+
+func periodCloseEventArgs(event *models.PeriodCloseEvent) []interface{} {
+	return []interface{}{event.ID, event.TenantID, event.BranchID, event.Period, event.Action, event.Reason, event.UserID}
+}
+
+func (r *periodCloseRepo) Close(ctx context.Context, periodClose *models.PeriodClose, event *models.PeriodCloseEvent) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO period_closes (id, tenant_id, branch_id, period, notes, closed_by, closed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (tenant_id, (COALESCE(branch_id, '00000000-0000-0000-0000-000000000000'::uuid)), period) DO NOTHING
+		RETURNING closed_at
+	`, periodClose.ID, periodClose.TenantID, periodClose.BranchID, periodClose.Period, periodClose.Notes, periodClose.ClosedBy).Scan(&periodClose.ClosedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, insertPeriodCloseEvent, periodCloseEventArgs(event)...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *periodCloseRepo) Reopen(ctx context.Context, event *models.PeriodCloseEvent) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM period_closes
+		WHERE tenant_id = $1 AND branch_id IS NOT DISTINCT FROM $2 AND period = $3
+	`, event.TenantID, event.BranchID, event.Period)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, insertPeriodCloseEvent, periodCloseEventArgs(event)...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func (r *periodCloseRepo) List(ctx context.Context, tenantID uuid.UUID) ([]*models.PeriodClose, error) {
+	query := `SELECT ` + periodCloseColumns + ` FROM period_closes WHERE tenant_id = $1 ORDER BY period DESC, branch_id NULLS FIRST`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var closes []*models.PeriodClose
+	for rows.Next() {
+		periodClose, err := scanPeriodClose(rows)
+		if err != nil {
+			return nil, err
+		}
+		closes = append(closes, periodClose)
+	}
+	return closes, rows.Err()
+}
+
+func (r *periodCloseRepo) Covering(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, date time.Time) (*models.PeriodClose, error) {
+	query := `
+		SELECT ` + periodCloseColumns + `
+		FROM period_closes
+		WHERE tenant_id = $1 AND period = date_trunc('month', $2::date)::date
+			AND (branch_id IS NULL OR branch_id = $3)
+		ORDER BY branch_id NULLS FIRST
+		LIMIT 1
+	`
+	periodClose, err := scanPeriodClose(r.db.QueryRow(ctx, query, tenantID, date, branchID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return periodClose, err
+}
+
+func (r *periodCloseRepo) ListEvents(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PeriodCloseEvent, error) {
+	query := `
+		SELECT id, tenant_id, branch_id, period, action, reason, user_id, created_at
+		FROM period_close_events
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.PeriodCloseEvent
+	for rows.Next() {
+		event := &models.PeriodCloseEvent{}
+		if err := rows.Scan(&event.ID, &event.TenantID, &event.BranchID, &event.Period, &event.Action, &event.Reason, &event.UserID, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -16,6 +16,9 @@ type PermissionRepository interface {
 	Update(ctx context.Context, permission *models.Permission) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context, limit, offset int) ([]*models.Permission, error)
+	// ListNamesByUser resolves the names of every permission granted to the
+	// user through their roles in the tenant
+	ListNamesByUser(ctx context.Context, tenantID, userID uuid.UUID) ([]string, error)
 }
 
 type permissionRepo struct {
@@ -102,4 +105,32 @@ func (r *permissionRepo) List(ctx context.Context, limit, offset int) ([]*models
 		permissions = append(permissions, permission)
 	}
 	return permissions, nil
-}
\ No newline at end of file
+}
+
+func (r *permissionRepo) ListNamesByUser(ctx context.Context, tenantID, userID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT DISTINCT p.name
+		FROM user_roles ur
+		JOIN users u ON ur.user_id = u.id
+		JOIN roles ro ON ur.role_id = ro.id
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		JOIN permissions p ON rp.permission_id = p.id
+		WHERE u.tenant_id = $1 AND ro.tenant_id = $1 AND ur.user_id = $2
+		ORDER BY p.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrPreconditionFailed is returned by a conditional update whose record was
+// changed, or removed, after the caller read it
+var ErrPreconditionFailed = errors.New("record has been modified")
+
+type expectedVersionKey struct{}
+
+type expectedVersion struct {
+	id      uuid.UUID
+	version time.Time
+	taken   atomic.Bool
+}
+
+// WithExpectedVersion makes the next update of record id apply only while the
+// row's modification time is still version, as an If-Match precondition asks.
+// Later writes of the record in the same request are unconditional
+func WithExpectedVersion(ctx context.Context, id uuid.UUID, version time.Time) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, &expectedVersion{id: id, version: version})
+}
+
+// conditionalUpdate appends the request's version check for record id, if
+// any, to an UPDATE whose WHERE clause ends the query. It reports whether the
+// check was added, in which case no affected row means the record changed
+func conditionalUpdate(ctx context.Context, id uuid.UUID, column, query string, args []interface{}) (string, []interface{}, bool) {
+	expected, ok := ctx.Value(expectedVersionKey{}).(*expectedVersion)
+	if !ok || expected.id != id || !expected.taken.CompareAndSwap(false, true) {
+		return query, args, false
+	}
+	args = append(args, expected.version)
+	return fmt.Sprintf("%s AND %s = $%d", query, column, len(args)), args, true
+}
+
+// checkConditionalUpdate turns a conditional update that matched nothing into
+// ErrPreconditionFailed
+func checkConditionalUpdate(tag pgconn.CommandTag, err error, conditional bool) error {
+	if err != nil {
+		return err
+	}
+	if conditional && tag.RowsAffected() == 0 {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PriceHistoryRepository interface {
+	Create(ctx context.Context, entry *models.PriceHistory) error
+	ListByProduct(ctx context.Context, tenantID, productID uuid.UUID, limit, offset int) ([]*models.PriceHistory, error)
+	SellingPriceSummary(ctx context.Context, tenantID, productID uuid.UUID, since time.Time) (avgPrice float64, units int, orders int, err error)
+}
+
+type priceHistoryRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPriceHistoryRepo(db *pgxpool.Pool) PriceHistoryRepository {
+	return &priceHistoryRepo{db: db}
+}
+
+func (r *priceHistoryRepo) Create(ctx context.Context, entry *models.PriceHistory) error {
+	query := `
+		INSERT INTO price_history (id, tenant_id, product_id, old_price, new_price, changed_by, reason, source, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+	`
+	_, err := r.db.Exec(ctx, query, entry.ID, entry.TenantID, entry.ProductID, entry.OldPrice, entry.NewPrice, entry.ChangedBy, entry.Reason, entry.Source)
+	return err
+}
+
+func (r *priceHistoryRepo) ListByProduct(ctx context.Context, tenantID, productID uuid.UUID, limit, offset int) ([]*models.PriceHistory, error) {
+	query := `
+		SELECT id, tenant_id, product_id, old_price, new_price, changed_by, reason, source, created_at
+		FROM price_history
+		WHERE tenant_id = $1 AND product_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.PriceHistory
+	for rows.Next() {
+		entry := &models.PriceHistory{}
+		if err := rows.Scan(&entry.ID, &entry.TenantID, &entry.ProductID, &entry.OldPrice, &entry.NewPrice, &entry.ChangedBy, &entry.Reason, &entry.Source, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// SellingPriceSummary returns the quantity-weighted average selling price of non-cancelled sales orders
+func (r *priceHistoryRepo) SellingPriceSummary(ctx context.Context, tenantID, productID uuid.UUID, since time.Time) (float64, int, int, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity * unit_price) / NULLIF(SUM(quantity), 0), 0),
+			COALESCE(SUM(quantity), 0),
+			COUNT(*)
+		FROM orders
+		WHERE tenant_id = $1 AND product_id = $2
+		  AND order_type = 'sales' AND status <> 'cancelled'
+		  AND order_date >= $3
+	`
+	var avgPrice float64
+	var units, orders int
+	err := r.db.QueryRow(ctx, query, tenantID, productID, since).Scan(&avgPrice, &units, &orders)
+	return avgPrice, units, orders, err
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ProductClassificationRepository interface {
+	SalesHistory(ctx context.Context, tenantID uuid.UUID, since time.Time) ([]*models.ProductSalesHistory, error)
+	SaveClassifications(ctx context.Context, tenantID uuid.UUID, classifications []*models.ProductClassification, classifiedAt time.Time) error
+}
+
+type productClassificationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewProductClassificationRepo(db *pgxpool.Pool) ProductClassificationRepository {
+	return &productClassificationRepo{db: db}
+}
+
+// SalesHistory returns every product with its revenue and units per month on
+// non-cancelled sales orders since the given time; products without sales are
+// included with no months
+func (r *productClassificationRepo) SalesHistory(ctx context.Context, tenantID uuid.UUID, since time.Time) ([]*models.ProductSalesHistory, error) {
+	query := `
+		SELECT p.id, p.name, m.year, m.month, COALESCE(m.units, 0), COALESCE(m.revenue, 0)
+		FROM products p
+		LEFT JOIN (
+			SELECT product_id,
+			       EXTRACT(YEAR FROM order_date)::int AS year,
+			       EXTRACT(MONTH FROM order_date)::int AS month,
+			       SUM(quantity)::int AS units,
+			       SUM(quantity * unit_price)::float8 AS revenue
+			FROM orders
+			WHERE tenant_id = $1
+			  AND order_type = 'sales' AND status <> 'cancelled'
+			  AND order_date >= $2
+			GROUP BY 1, 2, 3
+		) m ON m.product_id = p.id
+		WHERE p.tenant_id = $1
+		ORDER BY p.id, m.year, m.month
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*models.ProductSalesHistory
+	var current *models.ProductSalesHistory
+	for rows.Next() {
+		var productID uuid.UUID
+		var name string
+		var year, month *int
+		var units int
+		var revenue float64
+		if err := rows.Scan(&productID, &name, &year, &month, &units, &revenue); err != nil {
+			return nil, err
+		}
+		if current == nil || current.ProductID != productID {
+			current = &models.ProductSalesHistory{ProductID: productID, ProductName: name}
+			history = append(history, current)
+		}
+		if year == nil || month == nil {
+			continue
+		}
+		current.Revenue += revenue
+		current.Monthly = append(current.Monthly, &models.MonthlyDemand{Year: *year, Month: *month, Units: units})
+	}
+	return history, rows.Err()
+}
+
+// SaveClassifications stores the ABC/XYZ class on each product. Rows whose
+// class is unchanged are left alone so a nightly run does not mark the whole
+// catalog as changed for offline sync; classified_at records when the current
+// class was assigned.
+func (r *productClassificationRepo) SaveClassifications(ctx context.Context, tenantID uuid.UUID, classifications []*models.ProductClassification, classifiedAt time.Time) error {
+	if len(classifications) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(classifications))
+	abc := make([]string, len(classifications))
+	xyz := make([]string, len(classifications))
+	for i, c := range classifications {
+		ids[i] = c.ProductID
+		abc[i] = c.ABCClass
+		xyz[i] = c.XYZClass
+	}
+
+	query := `
+		UPDATE products p
+		SET abc_class = c.abc_class, xyz_class = c.xyz_class, classified_at = $5
+		FROM unnest($2::uuid[], $3::text[], $4::text[]) AS c(id, abc_class, xyz_class)
+		WHERE p.tenant_id = $1 AND p.id = c.id
+		  AND (p.abc_class IS DISTINCT FROM c.abc_class OR p.xyz_class IS DISTINCT FROM c.xyz_class)
+	`
+	_, err := r.db.Exec(ctx, query, tenantID, ids, abc, xyz, classifiedAt)
+	return err
+}
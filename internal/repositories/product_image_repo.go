@@ -12,6 +12,7 @@ import (
 type ProductImageRepository interface {
 	Create(ctx context.Context, image *models.ProductImage) error
 	GetByProductID(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.ProductImage, error)
+	GetByProductIDs(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]*models.ProductImage, error)
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ProductImage, error)
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	DeleteAllByProductID(ctx context.Context, tenantID, productID uuid.UUID) error
@@ -59,6 +60,32 @@ func (r *productImageRepo) GetByProductID(ctx context.Context, tenantID, product
 	return images, nil
 }
 
+// GetByProductIDs loads the images of several products in one query, ordered
+// by product and upload time
+func (r *productImageRepo) GetByProductIDs(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]*models.ProductImage, error) {
+	query := `
+		SELECT id, tenant_id, product_id, image_url, alt_text, created_at
+		FROM product_images
+		WHERE tenant_id = $1 AND product_id = ANY($2)
+		ORDER BY product_id, created_at ASC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []*models.ProductImage
+	for rows.Next() {
+		image := &models.ProductImage{}
+		if err := rows.Scan(&image.ID, &image.TenantID, &image.ProductID, &image.ImageURL, &image.AltText, &image.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, rows.Err()
+}
+
 func (r *productImageRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ProductImage, error) {
 	query := `
 		SELECT id, tenant_id, product_id, image_url, alt_text, created_at
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 
 	"github.com/google/uuid"
@@ -14,9 +15,11 @@ import (
 type ProductRepository interface {
 	Create(ctx context.Context, product *models.Product) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Product, error)
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Product, error)
 	Update(ctx context.Context, product *models.Product) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Product, error)
+	ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Product, error)
 	GetByBarcode(ctx context.Context, tenantID uuid.UUID, barcode string) (*models.Product, error)
 	Search(ctx context.Context, tenantID uuid.UUID, query string, categoryID *uuid.UUID, limit, offset int) ([]*models.Product, error)
 	ListWithCategory(ctx context.Context, tenantID uuid.UUID, categoryID *uuid.UUID, limit, offset int) ([]*models.Product, error)
@@ -34,35 +37,59 @@ func NewProductRepo(db *pgxpool.Pool) ProductRepository {
 
 func (r *productRepo) Create(ctx context.Context, product *models.Product) error {
 	query := `
-		INSERT INTO products (id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, barcode, unit_of_measure, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		INSERT INTO products (id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, gst_rate, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, COALESCE($13::jsonb, '{}'::jsonb), $14, NOW(), NOW())
 	`
-	_, err := r.db.Exec(ctx, query, product.ID, product.TenantID, product.CategoryID, product.Name, product.BatchNumber, product.ExpiryDate, product.Quantity, product.UnitPrice, product.Barcode, product.UnitOfMeasure, product.Description)
+	_, err := r.db.Exec(ctx, query, product.ID, product.TenantID, product.CategoryID, product.Name, product.BatchNumber, product.ExpiryDate, product.Quantity, product.UnitPrice, product.CostPrice, product.Barcode, product.UnitOfMeasure, product.Description, product.Translations, product.GSTRate)
 	return err
 }
 
 func (r *productRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Product, error) {
 	product := &models.Product{}
 	query := `
-		SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, barcode, unit_of_measure, description, created_at, updated_at
+		SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, is_published, published_at, abc_class, xyz_class, classified_at, is_bundle, gst_rate, created_at, updated_at
 		FROM products
 		WHERE tenant_id = $1 AND id = $2
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.CreatedAt, &product.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.CostPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.Translations, &product.IsPublished, &product.PublishedAt, &product.ABCClass, &product.XYZClass, &product.ClassifiedAt, &product.IsBundle, &product.GSTRate, &product.CreatedAt, &product.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return product, nil
 }
 
+// GetByIDs loads the tenant's products among ids in one query; unknown IDs are skipped
+func (r *productRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Product, error) {
+	query := `
+		SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, is_published, published_at, abc_class, xyz_class, classified_at, is_bundle, gst_rate, created_at, updated_at
+		FROM products
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		product := &models.Product{}
+		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.CostPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.Translations, &product.IsPublished, &product.PublishedAt, &product.ABCClass, &product.XYZClass, &product.ClassifiedAt, &product.IsBundle, &product.GSTRate, &product.CreatedAt, &product.UpdatedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
 func (r *productRepo) GetByBarcode(ctx context.Context, tenantID uuid.UUID, barcode string) (*models.Product, error) {
 	product := &models.Product{}
 	query := `
-		SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, barcode, unit_of_measure, description, created_at, updated_at
+		SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, is_published, published_at, abc_class, xyz_class, classified_at, is_bundle, gst_rate, created_at, updated_at
 		FROM products
 		WHERE tenant_id = $1 AND barcode = $2
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, barcode).Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.CreatedAt, &product.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, barcode).Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.CostPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.Translations, &product.IsPublished, &product.PublishedAt, &product.ABCClass, &product.XYZClass, &product.ClassifiedAt, &product.IsBundle, &product.GSTRate, &product.CreatedAt, &product.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -72,11 +99,12 @@ func (r *productRepo) GetByBarcode(ctx context.Context, tenantID uuid.UUID, barc
 func (r *productRepo) Update(ctx context.Context, product *models.Product) error {
 	query := `
 		UPDATE products
-		SET category_id = $1, name = $2, batch_number = $3, expiry_date = $4, quantity = $5, unit_price = $6, barcode = $7, unit_of_measure = $8, description = $9, updated_at = NOW()
-		WHERE tenant_id = $10 AND id = $11
+		SET category_id = $1, name = $2, batch_number = $3, expiry_date = $4, quantity = $5, unit_price = $6, cost_price = $7, barcode = $8, unit_of_measure = $9, description = $10, translations = COALESCE($11::jsonb, '{}'::jsonb), gst_rate = $12, updated_at = NOW()
+		WHERE tenant_id = $13 AND id = $14
 	`
-	_, err := r.db.Exec(ctx, query, product.CategoryID, product.Name, product.BatchNumber, product.ExpiryDate, product.Quantity, product.UnitPrice, product.Barcode, product.UnitOfMeasure, product.Description, product.TenantID, product.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, product.ID, "updated_at", query, []interface{}{product.CategoryID, product.Name, product.BatchNumber, product.ExpiryDate, product.Quantity, product.UnitPrice, product.CostPrice, product.Barcode, product.UnitOfMeasure, product.Description, product.Translations, product.GSTRate, product.TenantID, product.ID})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *productRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -85,15 +113,30 @@ func (r *productRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	return err
 }
 
+// ProductSortColumns whitelists the fields the product list can be sorted by
+var ProductSortColumns = map[string]string{
+	"name":        "name",
+	"unit_price":  "unit_price",
+	"quantity":    "quantity",
+	"expiry_date": "expiry_date",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
 func (r *productRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Product, error) {
-	query := `
-		SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, barcode, unit_of_measure, description, created_at, updated_at
+	return r.ListPage(ctx, tenantID, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists products in the page's sort order, newest first by default
+func (r *productRepo) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Product, error) {
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, is_published, published_at, abc_class, xyz_class, classified_at, is_bundle, gst_rate, created_at, updated_at
 		FROM products
 		WHERE tenant_id = $1
-		ORDER BY created_at DESC
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("created_at DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +145,7 @@ func (r *productRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offse
 	var products []*models.Product
 	for rows.Next() {
 		product := &models.Product{}
-		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.CreatedAt, &product.UpdatedAt); err != nil {
+		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.CostPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.Translations, &product.IsPublished, &product.PublishedAt, &product.ABCClass, &product.XYZClass, &product.ClassifiedAt, &product.IsBundle, &product.GSTRate, &product.CreatedAt, &product.UpdatedAt); err != nil {
 			return nil, err
 		}
 		products = append(products, product)
@@ -124,7 +167,7 @@ func (r *productRepo) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, fi
 
 	// Build query dynamically
 	queryBase := `
-		SELECT p.id, p.tenant_id, p.category_id, p.name, p.batch_number, p.expiry_date, p.quantity, p.unit_price, p.barcode, p.unit_of_measure, p.description, p.created_at, p.updated_at
+		SELECT p.id, p.tenant_id, p.category_id, p.name, p.batch_number, p.expiry_date, p.quantity, p.unit_price, p.cost_price, p.barcode, p.unit_of_measure, p.description, p.translations, p.is_published, p.published_at, p.abc_class, p.xyz_class, p.classified_at, p.is_bundle, p.gst_rate, p.created_at, p.updated_at
 		FROM products p
 		WHERE p.tenant_id = $1
 	`
@@ -138,11 +181,12 @@ func (r *productRepo) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, fi
 			p.name ILIKE $%d OR
 			p.barcode ILIKE $%d OR
 			COALESCE(p.description, '') ILIKE $%d OR
+			p.translations::text ILIKE $%d OR
 			EXISTS (
 				SELECT 1 FROM categories c
 				WHERE c.tenant_id = p.tenant_id AND c.id = p.category_id AND c.name ILIKE $%d
 			)
-		)`, conditionCount, conditionCount, conditionCount, conditionCount)
+		)`, conditionCount, conditionCount, conditionCount, conditionCount, conditionCount)
 		args = append(args, "%"+filter.Query+"%")
 	}
 
@@ -232,7 +276,7 @@ func (r *productRepo) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, fi
 	var products []*models.Product
 	for rows.Next() {
 		product := &models.Product{}
-		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.CreatedAt, &product.UpdatedAt); err != nil {
+		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.CostPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.Translations, &product.IsPublished, &product.PublishedAt, &product.ABCClass, &product.XYZClass, &product.ClassifiedAt, &product.IsBundle, &product.GSTRate, &product.CreatedAt, &product.UpdatedAt); err != nil {
 			return nil, err
 		}
 		products = append(products, product)
@@ -247,7 +291,7 @@ func (r *productRepo) ListWithCategory(ctx context.Context, tenantID uuid.UUID,
 
 	if categoryID != nil {
 		query = `
-			SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, barcode, unit_of_measure, description, created_at, updated_at
+			SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, is_published, published_at, abc_class, xyz_class, classified_at, is_bundle, gst_rate, created_at, updated_at
 			FROM products
 			WHERE tenant_id = $1 AND category_id = $2
 			ORDER BY created_at DESC
@@ -256,7 +300,7 @@ func (r *productRepo) ListWithCategory(ctx context.Context, tenantID uuid.UUID,
 		args = []interface{}{tenantID, *categoryID, limit, offset}
 	} else {
 		query = `
-			SELECT p.id, p.tenant_id, p.category_id, p.name, p.batch_number, p.expiry_date, p.quantity, p.unit_price, p.barcode, p.unit_of_measure, p.description, p.created_at, p.updated_at
+			SELECT p.id, p.tenant_id, p.category_id, p.name, p.batch_number, p.expiry_date, p.quantity, p.unit_price, p.cost_price, p.barcode, p.unit_of_measure, p.description, p.translations, p.is_published, p.published_at, p.abc_class, p.xyz_class, p.classified_at, p.is_bundle, p.gst_rate, p.created_at, p.updated_at
 			FROM products p
 			LEFT JOIN categories c ON p.category_id = c.id AND p.tenant_id = c.tenant_id
 			WHERE p.tenant_id = $1
@@ -275,7 +319,7 @@ func (r *productRepo) ListWithCategory(ctx context.Context, tenantID uuid.UUID,
 	var products []*models.Product
 	for rows.Next() {
 		product := &models.Product{}
-		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.CreatedAt, &product.UpdatedAt); err != nil {
+		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.CostPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.Translations, &product.IsPublished, &product.PublishedAt, &product.ABCClass, &product.XYZClass, &product.ClassifiedAt, &product.IsBundle, &product.GSTRate, &product.CreatedAt, &product.UpdatedAt); err != nil {
 			return nil, err
 		}
 		products = append(products, product)
@@ -322,18 +366,18 @@ func (r *productRepo) Search(ctx context.Context, tenantID uuid.UUID, query stri
 
 	if categoryID != nil {
 		querySQL = `
-			SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, barcode, unit_of_measure, description, created_at, updated_at
+			SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, is_published, published_at, abc_class, xyz_class, classified_at, is_bundle, gst_rate, created_at, updated_at
 			FROM products
-			WHERE tenant_id = $1 AND category_id = $2 AND (name ILIKE $3 OR barcode ILIKE $3)
+			WHERE tenant_id = $1 AND category_id = $2 AND (name ILIKE $3 OR barcode ILIKE $3 OR translations::text ILIKE $3)
 			ORDER BY created_at DESC
 			LIMIT $4 OFFSET $5
 		`
 		args = []interface{}{tenantID, *categoryID, "%" + query + "%", limit, offset}
 	} else {
 		querySQL = `
-			SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, barcode, unit_of_measure, description, created_at, updated_at
+			SELECT id, tenant_id, category_id, name, batch_number, expiry_date, quantity, unit_price, cost_price, barcode, unit_of_measure, description, translations, is_published, published_at, abc_class, xyz_class, classified_at, is_bundle, gst_rate, created_at, updated_at
 			FROM products
-			WHERE tenant_id = $1 AND (name ILIKE $2 OR barcode ILIKE $2)
+			WHERE tenant_id = $1 AND (name ILIKE $2 OR barcode ILIKE $2 OR translations::text ILIKE $2)
 			ORDER BY created_at DESC
 			LIMIT $3 OFFSET $4
 		`
@@ -349,7 +393,7 @@ func (r *productRepo) Search(ctx context.Context, tenantID uuid.UUID, query stri
 	var products []*models.Product
 	for rows.Next() {
 		product := &models.Product{}
-		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.CreatedAt, &product.UpdatedAt); err != nil {
+		if err := rows.Scan(&product.ID, &product.TenantID, &product.CategoryID, &product.Name, &product.BatchNumber, &product.ExpiryDate, &product.Quantity, &product.UnitPrice, &product.CostPrice, &product.Barcode, &product.UnitOfMeasure, &product.Description, &product.Translations, &product.IsPublished, &product.PublishedAt, &product.ABCClass, &product.XYZClass, &product.ClassifiedAt, &product.IsBundle, &product.GSTRate, &product.CreatedAt, &product.UpdatedAt); err != nil {
 			return nil, err
 		}
 		products = append(products, product)
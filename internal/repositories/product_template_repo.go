@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ProductTemplateRepository interface {
+	Create(ctx context.Context, template *models.ProductTemplate) (bool, error)
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ProductTemplate, error)
+	GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.ProductTemplate, error)
+	List(ctx context.Context, tenantID uuid.UUID) ([]*models.ProductTemplate, error)
+	Update(ctx context.Context, template *models.ProductTemplate) (bool, error)
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+}
+
+type productTemplateRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewProductTemplateRepo(db *pgxpool.Pool) ProductTemplateRepository {
+	return &productTemplateRepo{db: db}
+}
+
+const productTemplateColumns = `id, tenant_id, name, category_id, unit_of_measure, gst_rate, unit_price, description, cold_chain, shade, hazmat_class, created_at, updated_at`
+
+func scanProductTemplate(row rowScanner) (*models.ProductTemplate, error) {
+	t := &models.ProductTemplate{}
+	err := row.Scan(&t.ID, &t.TenantID, &t.Name, &t.CategoryID, &t.UnitOfMeasure, &t.GSTRate, &t.UnitPrice, &t.Description,
+		&t.ColdChain, &t.Shade, &t.HazmatClass, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Create inserts the template, reporting false when the tenant already has
+// a template of that name
+func (r *productTemplateRepo) Create(ctx context.Context, template *models.ProductTemplate) (bool, error) {
+	query := `
+		INSERT INTO product_templates (id, tenant_id, name, category_id, unit_of_measure, gst_rate, unit_price, description, cold_chain, shade, hazmat_class, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		ON CONFLICT (tenant_id, name) DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, template.ID, template.TenantID, template.Name, template.CategoryID, template.UnitOfMeasure,
+		template.GSTRate, template.UnitPrice, template.Description, template.ColdChain, template.Shade, template.HazmatClass).
+		Scan(&template.CreatedAt, &template.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *productTemplateRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.ProductTemplate, error) {
+	query := `SELECT ` + productTemplateColumns + ` FROM product_templates WHERE tenant_id = $1 AND id = $2`
+	t, err := scanProductTemplate(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (r *productTemplateRepo) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.ProductTemplate, error) {
+	query := `SELECT ` + productTemplateColumns + ` FROM product_templates WHERE tenant_id = $1 AND name = $2`
+	t, err := scanProductTemplate(r.db.QueryRow(ctx, query, tenantID, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (r *productTemplateRepo) List(ctx context.Context, tenantID uuid.UUID) ([]*models.ProductTemplate, error) {
+	query := `SELECT ` + productTemplateColumns + ` FROM product_templates WHERE tenant_id = $1 ORDER BY name`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*models.ProductTemplate
+	for rows.Next() {
+		t, err := scanProductTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// Update saves the template, reporting false when it does not exist
+func (r *productTemplateRepo) Update(ctx context.Context, template *models.ProductTemplate) (bool, error) {
+	query := `
+		UPDATE product_templates
+		SET name = $3, category_id = $4, unit_of_measure = $5, gst_rate = $6, unit_price = $7, description = $8,
+			cold_chain = $9, shade = $10, hazmat_class = $11, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, template.TenantID, template.ID, template.Name, template.CategoryID, template.UnitOfMeasure,
+		template.GSTRate, template.UnitPrice, template.Description, template.ColdChain, template.Shade, template.HazmatClass).
+		Scan(&template.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *productTemplateRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM product_templates WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ProfitabilityRepository reads sales orders with their cost for margin analytics
+type ProfitabilityRepository interface {
+	ListSales(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.ProfitabilitySale, error)
+}
+
+type profitabilityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewProfitabilityRepo(db *pgxpool.Pool) ProfitabilityRepository {
+	return &profitabilityRepo{db: db}
+}
+
+// ListSales lists non-cancelled sales orders dated from..to inclusive. Each is
+// costed at the landed cost of the latest purchase receipt of the product on
+// or before the order date, falling back to the product's cost price
+func (r *profitabilityRepo) ListSales(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.ProfitabilitySale, error) {
+	query := `
+		SELECT o.id, o.order_date, p.id, p.name, d.id, d.name, o.quantity, o.unit_price::float8,
+			COALESCE(receipt.landed_unit_cost, p.cost_price)::float8
+		FROM orders o
+		JOIN products p ON p.id = o.product_id
+		JOIN distributors d ON d.id = o.distributor_id
+		LEFT JOIN LATERAL (
+			SELECT prl.landed_unit_cost
+			FROM purchase_receipt_lines prl
+			JOIN purchase_receipts pr ON pr.id = prl.receipt_id
+			WHERE pr.tenant_id = o.tenant_id AND prl.product_id = o.product_id AND pr.received_at::date <= o.order_date::date
+			ORDER BY pr.received_at DESC
+			LIMIT 1
+		) receipt ON TRUE
+		WHERE o.tenant_id = $1 AND o.order_type = 'sales' AND o.status <> 'cancelled'
+			AND o.order_date::date BETWEEN $2::date AND $3::date
+		ORDER BY o.order_date, o.id
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []*models.ProfitabilitySale
+	for rows.Next() {
+		sale := &models.ProfitabilitySale{}
+		if err := rows.Scan(&sale.OrderID, &sale.OrderDate, &sale.ProductID, &sale.ProductName, &sale.DistributorID, &sale.DistributorName,
+			&sale.Quantity, &sale.UnitPrice, &sale.UnitCost); err != nil {
+			return nil, err
+		}
+		sales = append(sales, sale)
+	}
+	return sales, rows.Err()
+}
@@ -0,0 +1,305 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PurchaseCaptureRepository interface {
+	Create(ctx context.Context, capture *models.PurchaseInvoiceCapture) error
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseInvoiceCapture, error)
+	// List filters by status and, when orderID is set, to the capture that
+	// produced that purchase order
+	List(ctx context.Context, tenantID uuid.UUID, status string, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseInvoiceCapture, error)
+	// UpdateDraft and Finish report false unless the capture is still
+	// pending review
+	UpdateDraft(ctx context.Context, capture *models.PurchaseInvoiceCapture) (bool, error)
+	Finish(ctx context.Context, capture *models.PurchaseInvoiceCapture) (bool, error)
+	// FindSupplierByGSTIN matches the GSTIN printed on an invoice to a supplier
+	FindSupplierByGSTIN(ctx context.Context, tenantID uuid.UUID, gstin string) (*uuid.UUID, error)
+
+	// ListDuplicateCandidates returns other live captures of the same
+	// supplier, matched by supplier ID or GSTIN, uploaded since the cutoff
+	ListDuplicateCandidates(ctx context.Context, tenantID, excludeID uuid.UUID, supplierID *uuid.UUID, gstin *string, since time.Time) ([]*models.PurchaseInvoiceCapture, error)
+	// ReplaceDuplicateFlags swaps the open flags of a capture for a fresh set;
+	// flags already resolved are kept
+	ReplaceDuplicateFlags(ctx context.Context, tenantID, captureID uuid.UUID, flags []*models.SupplierBillDuplicate) error
+	ResolveDuplicateFlags(ctx context.Context, tenantID, captureID uuid.UUID, status string, reason *string, userID uuid.UUID) error
+	// ListDuplicates lists flags, optionally for one capture or status, raised
+	// in the period
+	ListDuplicates(ctx context.Context, tenantID uuid.UUID, captureID *uuid.UUID, status string, from, to time.Time, limit, offset int) ([]*models.SupplierBillDuplicate, error)
+	SummarizeDuplicates(ctx context.Context, tenantID uuid.UUID, from, to time.Time) (*models.SupplierBillDuplicateReport, error)
+}
+
+type purchaseCaptureRepo struct {
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
+}
+
+func NewPurchaseCaptureRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) PurchaseCaptureRepository {
+	return &purchaseCaptureRepo{db: db, keyring: keyring}
+}
+
+const purchaseCaptureColumns = `id, tenant_id, status, object_key, file_name, content_type, size_bytes, ocr_provider, ocr_text, ocr_error,
+	extracted, order_ids, uploaded_by, confirmed_by, confirmed_at, created_at, updated_at`
+
+func scanPurchaseCapture(row rowScanner) (*models.PurchaseInvoiceCapture, error) {
+	c := &models.PurchaseInvoiceCapture{}
+	err := row.Scan(&c.ID, &c.TenantID, &c.Status, &c.ObjectKey, &c.FileName, &c.ContentType, &c.SizeBytes, &c.OCRProvider,
+		&c.OCRText, &c.OCRError, &c.Extracted, &c.OrderIDs, &c.UploadedBy, &c.ConfirmedBy, &c.ConfirmedAt, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *purchaseCaptureRepo) Create(ctx context.Context, capture *models.PurchaseInvoiceCapture) error {
+	query := `
+		INSERT INTO purchase_invoice_captures (id, tenant_id, status, object_key, file_name, content_type, size_bytes, ocr_provider,
+			ocr_text, ocr_error, extracted, order_ids, uploaded_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, capture.ID, capture.TenantID, capture.Status, capture.ObjectKey, capture.FileName,
+		capture.ContentType, capture.SizeBytes, capture.OCRProvider, capture.OCRText, capture.OCRError, capture.Extracted,
+		capture.OrderIDs, capture.UploadedBy).Scan(&capture.CreatedAt, &capture.UpdatedAt)
+}
+
+func (r *purchaseCaptureRepo) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseInvoiceCapture, error) {
+	query := `SELECT ` + purchaseCaptureColumns + ` FROM purchase_invoice_captures WHERE tenant_id = $1 AND id = $2`
+	capture, err := scanPurchaseCapture(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return capture, err
+}
+
+func (r *purchaseCaptureRepo) List(ctx context.Context, tenantID uuid.UUID, status string, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseInvoiceCapture, error) {
+	query := `
+		SELECT ` + purchaseCaptureColumns + `
+		FROM purchase_invoice_captures
+		WHERE tenant_id = $1 AND ($2::text = '' OR status = $2) AND ($3::uuid IS NULL OR $3 = ANY(order_ids))
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, status, orderID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var captures []*models.PurchaseInvoiceCapture
+	for rows.Next() {
+		capture, err := scanPurchaseCapture(rows)
+		if err != nil {
+			return nil, err
+		}
+		captures = append(captures, capture)
+	}
+	return captures, rows.Err()
+}
+
+func (r *purchaseCaptureRepo) UpdateDraft(ctx context.Context, capture *models.PurchaseInvoiceCapture) (bool, error) {
+	query := `
+		UPDATE purchase_invoice_captures SET extracted = $3, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'pending_review'
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, capture.TenantID, capture.ID, capture.Extracted).Scan(&capture.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *purchaseCaptureRepo) Finish(ctx context.Context, capture *models.PurchaseInvoiceCapture) (bool, error) {
+	query := `
+		UPDATE purchase_invoice_captures SET status = $3, order_ids = $4, confirmed_by = $5, confirmed_at = NOW(), updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = 'pending_review'
+		RETURNING confirmed_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, capture.TenantID, capture.ID, capture.Status, capture.OrderIDs, capture.ConfirmedBy).
+		Scan(&capture.ConfirmedAt, &capture.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *purchaseCaptureRepo) FindSupplierByGSTIN(ctx context.Context, tenantID uuid.UUID, gstin string) (*uuid.UUID, error) {
+	var id uuid.UUID
+	// Suppliers saved before encryption was enabled have no blind index yet
+	query := `
+		SELECT id FROM suppliers
+		WHERE tenant_id = $1 AND archived_at IS NULL
+			AND (gstin_bidx = $2 OR (gstin_bidx IS NULL AND UPPER(gstin) = UPPER($3)))
+		LIMIT 1
+	`
+	err := r.db.QueryRow(ctx, query, tenantID, r.keyring.BlindIndex(tenantID, &gstin), gstin).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (r *purchaseCaptureRepo) ListDuplicateCandidates(ctx context.Context, tenantID, excludeID uuid.UUID, supplierID *uuid.UUID, gstin *string, since time.Time) ([]*models.PurchaseInvoiceCapture, error) {
+	query := `
+		SELECT ` + purchaseCaptureColumns + `
+		FROM purchase_invoice_captures
+		WHERE tenant_id = $1 AND id <> $2 AND status IN ('pending_review', 'confirmed') AND created_at >= $5
+			AND (($3::uuid IS NOT NULL AND extracted->>'supplier_id' = $3::text)
+				OR ($4::text IS NOT NULL AND UPPER(extracted->>'supplier_gstin') = UPPER($4)))
+		ORDER BY created_at DESC
+		LIMIT 500
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, excludeID, supplierID, gstin, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var captures []*models.PurchaseInvoiceCapture
+	for rows.Next() {
+		capture, err := scanPurchaseCapture(rows)
+		if err != nil {
+			return nil, err
+		}
+		captures = append(captures, capture)
+	}
+	return captures, rows.Err()
+}
+
+func (r *purchaseCaptureRepo) ReplaceDuplicateFlags(ctx context.Context, tenantID, captureID uuid.UUID, flags []*models.SupplierBillDuplicate) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM supplier_bill_duplicates WHERE tenant_id = $1 AND capture_id = $2 AND status = 'flagged'`,
+		tenantID, captureID); err != nil {
+		return err
+	}
+	for _, flag := range flags {
+		query := `
+			INSERT INTO supplier_bill_duplicates (id, tenant_id, capture_id, matched_capture_id, score, reasons, status, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, 'flagged', NOW())
+			ON CONFLICT (capture_id, matched_capture_id) DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, query, flag.ID, tenantID, captureID, flag.MatchedCaptureID, flag.Score, flag.Reasons); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *purchaseCaptureRepo) ResolveDuplicateFlags(ctx context.Context, tenantID, captureID uuid.UUID, status string, reason *string, userID uuid.UUID) error {
+	query := `
+		UPDATE supplier_bill_duplicates SET status = $3, override_reason = $4, resolved_by = $5, resolved_at = NOW()
+		WHERE tenant_id = $1 AND capture_id = $2 AND status = 'flagged'
+	`
+	_, err := r.db.Exec(ctx, query, tenantID, captureID, status, reason, userID)
+	return err
+}
+
+func (r *purchaseCaptureRepo) ListDuplicates(ctx context.Context, tenantID uuid.UUID, captureID *uuid.UUID, status string, from, to time.Time, limit, offset int) ([]*models.SupplierBillDuplicate, error) {
+	query := `
+		SELECT d.id, d.tenant_id, d.capture_id, d.matched_capture_id, d.score, d.reasons, d.status, d.override_reason, d.resolved_by,
+			d.resolved_at, d.created_at, (c.extracted->>'supplier_id')::uuid, c.extracted->>'invoice_number', c.extracted,
+			m.extracted->>'invoice_number', m.status
+		FROM supplier_bill_duplicates d
+		JOIN purchase_invoice_captures c ON c.id = d.capture_id
+		JOIN purchase_invoice_captures m ON m.id = d.matched_capture_id
+		WHERE d.tenant_id = $1 AND ($2::uuid IS NULL OR d.capture_id = $2) AND ($3::text = '' OR d.status = $3)
+			AND d.created_at >= $4 AND d.created_at < $5
+		ORDER BY d.created_at DESC, d.score DESC
+		LIMIT $6 OFFSET $7
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, captureID, status, from, to, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var duplicates []*models.SupplierBillDuplicate
+	for rows.Next() {
+		d := &models.SupplierBillDuplicate{}
+		var extracted models.PurchaseCaptureDraft
+		if err := rows.Scan(&d.ID, &d.TenantID, &d.CaptureID, &d.MatchedCaptureID, &d.Score, &d.Reasons, &d.Status, &d.OverrideReason,
+			&d.ResolvedBy, &d.ResolvedAt, &d.CreatedAt, &d.SupplierID, &d.InvoiceNumber, &extracted, &d.MatchedInvoiceNumber,
+			&d.MatchedStatus); err != nil {
+			return nil, err
+		}
+		amount := extracted.BillAmount()
+		d.TotalAmount = &amount
+		duplicates = append(duplicates, d)
+	}
+	return duplicates, rows.Err()
+}
+
+func (r *purchaseCaptureRepo) SummarizeDuplicates(ctx context.Context, tenantID uuid.UUID, from, to time.Time) (*models.SupplierBillDuplicateReport, error) {
+	report := &models.SupplierBillDuplicateReport{From: from, To: to}
+	query := `
+		SELECT d.status, COUNT(DISTINCT d.capture_id)
+		FROM supplier_bill_duplicates d
+		WHERE d.tenant_id = $1 AND d.created_at >= $2 AND d.created_at < $3
+		GROUP BY d.status
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		switch status {
+		case models.BillDuplicateFlagged:
+			report.Flagged = count
+		case models.BillDuplicateOverridden:
+			report.Overridden = count
+		case models.BillDuplicateDiscarded:
+			report.Discarded = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A bill discarded as a duplicate is a payment that was not made twice
+	preventedQuery := `
+		SELECT c.extracted
+		FROM purchase_invoice_captures c
+		WHERE c.tenant_id = $1 AND EXISTS (
+			SELECT 1 FROM supplier_bill_duplicates d
+			WHERE d.capture_id = c.id AND d.status = 'discarded' AND d.created_at >= $2 AND d.created_at < $3
+		)
+	`
+	rows, err = r.db.Query(ctx, preventedQuery, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var extracted models.PurchaseCaptureDraft
+		if err := rows.Scan(&extracted); err != nil {
+			return nil, err
+		}
+		report.PreventedAmount += extracted.BillAmount()
+	}
+	return report, rows.Err()
+}
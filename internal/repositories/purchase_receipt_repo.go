@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PurchaseReceiptRepository interface {
+	Create(ctx context.Context, receipt *models.PurchaseReceipt) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseReceipt, error)
+	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PurchaseReceipt, error)
+	SetProductCost(ctx context.Context, tenantID, productID uuid.UUID, costPrice float64) error
+}
+
+type purchaseReceiptRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPurchaseReceiptRepo(db *pgxpool.Pool) PurchaseReceiptRepository {
+	return &purchaseReceiptRepo{db: db}
+}
+
+const purchaseReceiptColumns = `id, tenant_id, supplier_id, allocation_method, is_consignment, goods_value::float8, total_charges::float8, notes, received_by, received_at`
+
+func scanPurchaseReceipt(row rowScanner) (*models.PurchaseReceipt, error) {
+	receipt := &models.PurchaseReceipt{}
+	err := row.Scan(&receipt.ID, &receipt.TenantID, &receipt.SupplierID, &receipt.AllocationMethod, &receipt.Consignment, &receipt.GoodsValue, &receipt.TotalCharges, &receipt.Notes, &receipt.ReceivedBy, &receipt.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// Create stores the receipt with its charges and lines in one transaction
+func (r *purchaseReceiptRepo) Create(ctx context.Context, receipt *models.PurchaseReceipt) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO purchase_receipts (id, tenant_id, supplier_id, allocation_method, is_consignment, goods_value, total_charges, notes, received_by, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING received_at
+	`
+	if err := tx.QueryRow(ctx, query, receipt.ID, receipt.TenantID, receipt.SupplierID, receipt.AllocationMethod, receipt.Consignment, receipt.GoodsValue, receipt.TotalCharges, receipt.Notes, receipt.ReceivedBy).
+		Scan(&receipt.ReceivedAt); err != nil {
+		return err
+	}
+
+	for _, charge := range receipt.Charges {
+		query := `
+			INSERT INTO purchase_receipt_charges (id, receipt_id, charge_type, amount, description)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		if _, err := tx.Exec(ctx, query, charge.ID, receipt.ID, charge.ChargeType, charge.Amount, charge.Description); err != nil {
+			return err
+		}
+	}
+	for _, line := range receipt.Lines {
+		query := `
+			INSERT INTO purchase_receipt_lines (id, receipt_id, order_id, product_id, warehouse_id, quantity, unit_price, allocated_charges, landed_unit_cost, previous_cost_price, new_cost_price)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`
+		if _, err := tx.Exec(ctx, query, line.ID, receipt.ID, line.OrderID, line.ProductID, line.WarehouseID, line.Quantity, line.UnitPrice,
+			line.AllocatedCharges, line.LandedUnitCost, line.PreviousCostPrice, line.NewCostPrice); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetByID returns the receipt with its charges and lines, or nil when it does not exist
+func (r *purchaseReceiptRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseReceipt, error) {
+	query := `SELECT ` + purchaseReceiptColumns + ` FROM purchase_receipts WHERE tenant_id = $1 AND id = $2`
+	receipt, err := scanPurchaseReceipt(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt.Charges, err = r.listCharges(ctx, receipt.ID); err != nil {
+		return nil, err
+	}
+	if receipt.Lines, err = r.listLines(ctx, receipt.ID); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// List returns receipts newest first without their charges and lines
+func (r *purchaseReceiptRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PurchaseReceipt, error) {
+	query := `SELECT ` + purchaseReceiptColumns + ` FROM purchase_receipts WHERE tenant_id = $1 ORDER BY received_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []*models.PurchaseReceipt
+	for rows.Next() {
+		receipt, err := scanPurchaseReceipt(rows)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, rows.Err()
+}
+
+// SetProductCost updates the landed cost used for valuation and margin checks
+func (r *purchaseReceiptRepo) SetProductCost(ctx context.Context, tenantID, productID uuid.UUID, costPrice float64) error {
+	query := `UPDATE products SET cost_price = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`
+	_, err := r.db.Exec(ctx, query, tenantID, productID, costPrice)
+	return err
+}
+
+func (r *purchaseReceiptRepo) listCharges(ctx context.Context, receiptID uuid.UUID) ([]*models.PurchaseReceiptCharge, error) {
+	query := `
+		SELECT id, receipt_id, charge_type, amount::float8, description
+		FROM purchase_receipt_charges
+		WHERE receipt_id = $1
+		ORDER BY charge_type, id
+	`
+	rows, err := r.db.Query(ctx, query, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	charges := []*models.PurchaseReceiptCharge{}
+	for rows.Next() {
+		charge := &models.PurchaseReceiptCharge{}
+		if err := rows.Scan(&charge.ID, &charge.ReceiptID, &charge.ChargeType, &charge.Amount, &charge.Description); err != nil {
+			return nil, err
+		}
+		charges = append(charges, charge)
+	}
+	return charges, rows.Err()
+}
+
+func (r *purchaseReceiptRepo) listLines(ctx context.Context, receiptID uuid.UUID) ([]*models.PurchaseReceiptLine, error) {
+	query := `
+		SELECT id, receipt_id, order_id, product_id, warehouse_id, quantity, unit_price::float8, allocated_charges::float8,
+		       landed_unit_cost::float8, previous_cost_price::float8, new_cost_price::float8
+		FROM purchase_receipt_lines
+		WHERE receipt_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(ctx, query, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []*models.PurchaseReceiptLine{}
+	for rows.Next() {
+		line := &models.PurchaseReceiptLine{}
+		if err := rows.Scan(&line.ID, &line.ReceiptID, &line.OrderID, &line.ProductID, &line.WarehouseID, &line.Quantity, &line.UnitPrice,
+			&line.AllocatedCharges, &line.LandedUnitCost, &line.PreviousCostPrice, &line.NewCostPrice); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
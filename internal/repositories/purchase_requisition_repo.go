@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PurchaseRequisitionRepository interface {
+	Create(ctx context.Context, requisition *models.PurchaseRequisition) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseRequisition, error)
+	List(ctx context.Context, tenantID uuid.UUID, filter *models.PurchaseRequisitionFilter) ([]*models.PurchaseRequisition, error)
+	UpdateStatus(ctx context.Context, requisition *models.PurchaseRequisition, fromStatus string) (bool, error)
+	MarkConverted(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID, orderID uuid.UUID) (int64, error)
+	ReceiptIDForOrder(ctx context.Context, tenantID, orderID uuid.UUID) (*uuid.UUID, error)
+}
+
+type purchaseRequisitionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPurchaseRequisitionRepo(db *pgxpool.Pool) PurchaseRequisitionRepository {
+	return &purchaseRequisitionRepo{db: db}
+}
+
+const purchaseRequisitionColumns = `id, tenant_id, product_id, warehouse_id, quantity, need_by, supplier_id, notes, status, requested_by, approved_by, approved_at, rejection_reason, order_id, converted_at, created_at, updated_at`
+
+func scanPurchaseRequisition(row rowScanner) (*models.PurchaseRequisition, error) {
+	r := &models.PurchaseRequisition{}
+	err := row.Scan(&r.ID, &r.TenantID, &r.ProductID, &r.WarehouseID, &r.Quantity, &r.NeedBy, &r.SupplierID, &r.Notes, &r.Status,
+		&r.RequestedBy, &r.ApprovedBy, &r.ApprovedAt, &r.RejectionReason, &r.OrderID, &r.ConvertedAt, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *purchaseRequisitionRepo) Create(ctx context.Context, requisition *models.PurchaseRequisition) error {
+	query := `
+		INSERT INTO purchase_requisitions (id, tenant_id, product_id, warehouse_id, quantity, need_by, supplier_id, notes, status, requested_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, requisition.ID, requisition.TenantID, requisition.ProductID, requisition.WarehouseID, requisition.Quantity,
+		requisition.NeedBy, requisition.SupplierID, requisition.Notes, requisition.Status, requisition.RequestedBy).
+		Scan(&requisition.CreatedAt, &requisition.UpdatedAt)
+}
+
+func (r *purchaseRequisitionRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseRequisition, error) {
+	query := `SELECT ` + purchaseRequisitionColumns + ` FROM purchase_requisitions WHERE tenant_id = $1 AND id = $2`
+	requisition, err := scanPurchaseRequisition(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return requisition, err
+}
+
+// List returns requisitions soonest need-by date first
+func (r *purchaseRequisitionRepo) List(ctx context.Context, tenantID uuid.UUID, filter *models.PurchaseRequisitionFilter) ([]*models.PurchaseRequisition, error) {
+	query := `
+		SELECT ` + purchaseRequisitionColumns + `
+		FROM purchase_requisitions
+		WHERE tenant_id = $1
+		  AND ($2::text IS NULL OR status = $2)
+		  AND ($3::uuid IS NULL OR warehouse_id = $3)
+		  AND ($4::uuid IS NULL OR order_id = $4)
+		ORDER BY need_by, created_at
+		LIMIT $5 OFFSET $6
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, filter.Status, filter.WarehouseID, filter.OrderID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requisitions []*models.PurchaseRequisition
+	for rows.Next() {
+		requisition, err := scanPurchaseRequisition(rows)
+		if err != nil {
+			return nil, err
+		}
+		requisitions = append(requisitions, requisition)
+	}
+	return requisitions, rows.Err()
+}
+
+// UpdateStatus saves an approval, rejection or cancellation only while the
+// requisition is still in fromStatus, so concurrent reviewers cannot both act
+func (r *purchaseRequisitionRepo) UpdateStatus(ctx context.Context, requisition *models.PurchaseRequisition, fromStatus string) (bool, error) {
+	query := `
+		UPDATE purchase_requisitions
+		SET status = $3, supplier_id = $4, approved_by = $5, approved_at = $6, rejection_reason = $7, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND status = $8
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, requisition.TenantID, requisition.ID, requisition.Status, requisition.SupplierID,
+		requisition.ApprovedBy, requisition.ApprovedAt, requisition.RejectionReason, fromStatus).Scan(&requisition.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MarkConverted links approved requisitions to the purchase order raised for them
+func (r *purchaseRequisitionRepo) MarkConverted(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID, orderID uuid.UUID) (int64, error) {
+	query := `
+		UPDATE purchase_requisitions
+		SET status = 'converted', order_id = $3, converted_at = NOW(), updated_at = NOW()
+		WHERE tenant_id = $1 AND id = ANY($2) AND status = 'approved'
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, ids, orderID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ReceiptIDForOrder returns the purchase receipt that received an order, or nil
+func (r *purchaseRequisitionRepo) ReceiptIDForOrder(ctx context.Context, tenantID, orderID uuid.UUID) (*uuid.UUID, error) {
+	query := `
+		SELECT pr.id
+		FROM purchase_receipt_lines l
+		JOIN purchase_receipts pr ON pr.id = l.receipt_id
+		WHERE pr.tenant_id = $1 AND l.order_id = $2
+	`
+	var receiptID uuid.UUID
+	err := r.db.QueryRow(ctx, query, tenantID, orderID).Scan(&receiptID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &receiptID, nil
+}
@@ -0,0 +1,267 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PurchaseReturnRepository interface {
+	Create(ctx context.Context, ret *models.PurchaseReturn, received int) (bool, error)
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseReturn, error)
+	List(ctx context.Context, tenantID uuid.UUID, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseReturn, error)
+	ReturnedQuantity(ctx context.Context, tenantID, orderID uuid.UUID) (int, error)
+	ReceivedOnConsignment(ctx context.Context, tenantID, orderID uuid.UUID) (bool, error)
+	ListDebitNotes(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) ([]*models.DebitNote, error)
+
+	SupplierBalanceBefore(ctx context.Context, tenantID, supplierID uuid.UUID, before time.Time) (float64, error)
+	ListSupplierEntries(ctx context.Context, tenantID, supplierID uuid.UUID, from, to time.Time) ([]*models.StatementEntry, error)
+}
+
+type purchaseReturnRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewPurchaseReturnRepo(db *pgxpool.Pool) PurchaseReturnRepository {
+	return &purchaseReturnRepo{db: db}
+}
+
+// supplierLedger is every debit and credit of the tenant ($1) per supplier:
+// purchase orders received into owned stock at the supplier price, and debit
+// notes for goods returned. Consignment stock is owed only once it sells and
+// is settled on the consignment statement instead
+const supplierLedger = `
+	SELECT o.supplier_id, r.received_at::date AS entry_date, r.received_at AS recorded_at, 'purchase' AS entry_type,
+		r.id AS source_id, o.id::text AS reference, r.notes AS note, 0::numeric AS debit, l.quantity * l.unit_price AS credit
+	FROM purchase_receipt_lines l
+	JOIN purchase_receipts r ON r.id = l.receipt_id
+	JOIN orders o ON o.id = l.order_id AND o.tenant_id = r.tenant_id
+	WHERE r.tenant_id = $1 AND NOT r.is_consignment AND o.supplier_id IS NOT NULL
+	UNION ALL
+	SELECT dn.supplier_id, dn.issued_date, dn.created_at, 'debit_note',
+		dn.id, dn.debit_note_number, pr.reason, dn.total_amount, 0::numeric
+	FROM debit_notes dn
+	JOIN purchase_returns pr ON pr.id = dn.purchase_return_id
+	WHERE dn.tenant_id = $1 AND dn.supplier_id IS NOT NULL
+`
+
+const purchaseReturnColumns = `id, tenant_id, return_number, order_id, supplier_id, product_id, warehouse_id, quantity, unit_price::float8, reason, returned_by, returned_at`
+
+func scanPurchaseReturn(row rowScanner) (*models.PurchaseReturn, error) {
+	ret := &models.PurchaseReturn{}
+	err := row.Scan(&ret.ID, &ret.TenantID, &ret.ReturnNumber, &ret.OrderID, &ret.SupplierID, &ret.ProductID, &ret.WarehouseID,
+		&ret.Quantity, &ret.UnitPrice, &ret.Reason, &ret.ReturnedBy, &ret.ReturnedAt)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+const debitNoteColumns = `id, tenant_id, supplier_id, purchase_return_id, debit_note_number, supplier_gstin, taxable_amount::float8, gst_rate::float8,
+	cgst::float8, sgst::float8, igst::float8, total_amount::float8, issued_date, created_by, created_at`
+
+func scanDebitNote(row rowScanner) (*models.DebitNote, error) {
+	note := &models.DebitNote{}
+	err := row.Scan(&note.ID, &note.TenantID, &note.SupplierID, &note.PurchaseReturnID, &note.DebitNoteNumber, &note.SupplierGSTIN,
+		&note.TaxableAmount, &note.GSTRate, &note.CGST, &note.SGST, &note.IGST, &note.TotalAmount, &note.IssuedDate, &note.CreatedBy, &note.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Create stores the return with its debit note in one transaction. The order
+// is locked while its earlier returns are added up, and false is returned
+// without storing anything when the returns would exceed the received quantity
+func (r *purchaseReturnRepo) Create(ctx context.Context, ret *models.PurchaseReturn, received int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM orders WHERE tenant_id = $1 AND id = $2 FOR UPDATE`, ret.TenantID, ret.OrderID); err != nil {
+		return false, err
+	}
+	var returned int
+	query := `SELECT COALESCE(SUM(quantity), 0) FROM purchase_returns WHERE tenant_id = $1 AND order_id = $2`
+	if err := tx.QueryRow(ctx, query, ret.TenantID, ret.OrderID).Scan(&returned); err != nil {
+		return false, err
+	}
+	if returned+ret.Quantity > received {
+		return false, nil
+	}
+
+	query = `
+		INSERT INTO purchase_returns (id, tenant_id, return_number, order_id, supplier_id, product_id, warehouse_id, quantity, unit_price, reason, returned_by, returned_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		RETURNING returned_at
+	`
+	if err := tx.QueryRow(ctx, query, ret.ID, ret.TenantID, ret.ReturnNumber, ret.OrderID, ret.SupplierID, ret.ProductID, ret.WarehouseID,
+		ret.Quantity, ret.UnitPrice, ret.Reason, ret.ReturnedBy).Scan(&ret.ReturnedAt); err != nil {
+		return false, err
+	}
+
+	if note := ret.DebitNote; note != nil {
+		query := `
+			INSERT INTO debit_notes (id, tenant_id, supplier_id, purchase_return_id, debit_note_number, supplier_gstin, taxable_amount, gst_rate,
+				cgst, sgst, igst, total_amount, issued_date, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW())
+			RETURNING created_at
+		`
+		if err := tx.QueryRow(ctx, query, note.ID, note.TenantID, note.SupplierID, note.PurchaseReturnID, note.DebitNoteNumber, note.SupplierGSTIN,
+			note.TaxableAmount, note.GSTRate, note.CGST, note.SGST, note.IGST, note.TotalAmount, note.IssuedDate, note.CreatedBy).Scan(&note.CreatedAt); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// GetByID returns the return with its debit note, or nil when it does not exist
+func (r *purchaseReturnRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseReturn, error) {
+	query := `SELECT ` + purchaseReturnColumns + ` FROM purchase_returns WHERE tenant_id = $1 AND id = $2`
+	ret, err := scanPurchaseReturn(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `SELECT ` + debitNoteColumns + ` FROM debit_notes WHERE tenant_id = $1 AND purchase_return_id = $2`
+	ret.DebitNote, err = scanDebitNote(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// List returns returns newest first, optionally only those against one order,
+// without their debit notes
+func (r *purchaseReturnRepo) List(ctx context.Context, tenantID uuid.UUID, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseReturn, error) {
+	query := `
+		SELECT ` + purchaseReturnColumns + `
+		FROM purchase_returns
+		WHERE tenant_id = $1 AND ($2::uuid IS NULL OR order_id = $2)
+		ORDER BY returned_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, orderID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []*models.PurchaseReturn
+	for rows.Next() {
+		ret, err := scanPurchaseReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+	return returns, rows.Err()
+}
+
+// ReturnedQuantity is how much of the order has already gone back to the supplier
+func (r *purchaseReturnRepo) ReturnedQuantity(ctx context.Context, tenantID, orderID uuid.UUID) (int, error) {
+	query := `SELECT COALESCE(SUM(quantity), 0) FROM purchase_returns WHERE tenant_id = $1 AND order_id = $2`
+	var returned int
+	err := r.db.QueryRow(ctx, query, tenantID, orderID).Scan(&returned)
+	return returned, err
+}
+
+// ReceivedOnConsignment reports whether the order was received as
+// supplier-owned consignment stock
+func (r *purchaseReturnRepo) ReceivedOnConsignment(ctx context.Context, tenantID, orderID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM purchase_receipt_lines l
+			JOIN purchase_receipts r ON r.id = l.receipt_id
+			WHERE r.tenant_id = $1 AND l.order_id = $2 AND r.is_consignment
+		)
+	`
+	var consignment bool
+	err := r.db.QueryRow(ctx, query, tenantID, orderID).Scan(&consignment)
+	return consignment, err
+}
+
+// ListDebitNotes lists debit notes issued between from and to inclusive,
+// optionally for one supplier, in the order they were issued
+func (r *purchaseReturnRepo) ListDebitNotes(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) ([]*models.DebitNote, error) {
+	query := `
+		SELECT ` + debitNoteColumns + `
+		FROM debit_notes
+		WHERE tenant_id = $1 AND ($2::uuid IS NULL OR supplier_id = $2) AND issued_date BETWEEN $3::date AND $4::date
+		ORDER BY issued_date, created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, supplierID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*models.DebitNote
+	for rows.Next() {
+		note, err := scanDebitNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// SupplierBalanceBefore is what was owed to the supplier at the start of the given day
+func (r *purchaseReturnRepo) SupplierBalanceBefore(ctx context.Context, tenantID, supplierID uuid.UUID, before time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(credit - debit), 0)::float8
+		FROM (` + supplierLedger + `) ledger
+		WHERE supplier_id = $2 AND entry_date < $3::date
+	`
+	var balance float64
+	err := r.db.QueryRow(ctx, query, tenantID, supplierID, before).Scan(&balance)
+	return balance, err
+}
+
+// ListSupplierEntries lists the supplier's ledger entries dated between from
+// and to inclusive in the order they happened; running balances are left to
+// the caller
+func (r *purchaseReturnRepo) ListSupplierEntries(ctx context.Context, tenantID, supplierID uuid.UUID, from, to time.Time) ([]*models.StatementEntry, error) {
+	query := `
+		SELECT entry_date, entry_type, source_id, reference, note, debit::float8, credit::float8
+		FROM (` + supplierLedger + `) ledger
+		WHERE supplier_id = $2 AND entry_date BETWEEN $3::date AND $4::date
+		ORDER BY entry_date, recorded_at, entry_type
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, supplierID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.StatementEntry
+	for rows.Next() {
+		entry := &models.StatementEntry{}
+		var note *string
+		if err := rows.Scan(&entry.Date, &entry.Type, &entry.SourceID, &entry.Reference, &note, &entry.Debit, &entry.Credit); err != nil {
+			return nil, err
+		}
+		if note != nil {
+			entry.Description = *note
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
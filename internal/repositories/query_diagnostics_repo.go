@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// queryOffenderOrders maps the report's sort options to their columns
+var queryOffenderOrders = map[string]string{
+	"total_time":   "estimated_total_time_ms",
+	"max_duration": "max_duration_ms",
+	"errors":       "errors",
+}
+
+// QueryDiagnosticsRepository stores the query audit's recordings
+type QueryDiagnosticsRepository interface {
+	InsertBatch(ctx context.Context, diagnostics []*models.QueryDiagnostic) error
+	// TopOffenders groups recordings by tenant and query, worst first
+	TopOffenders(ctx context.Context, filter models.QueryOffenderFilter) ([]*models.QueryOffender, error)
+	DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type queryDiagnosticsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewQueryDiagnosticsRepo(db *pgxpool.Pool) QueryDiagnosticsRepository {
+	return &queryDiagnosticsRepo{db: db}
+}
+
+func (r *queryDiagnosticsRepo) InsertBatch(ctx context.Context, diagnostics []*models.QueryDiagnostic) error {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(diagnostics))
+	args := make([]interface{}, 0, 10*len(diagnostics))
+	for _, d := range diagnostics {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, NULLIF($%d, ''), $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10))
+		args = append(args, d.TenantID, d.UserID, d.RequestID, d.Fingerprint, d.QueryText,
+			d.DurationMs, d.RowCount, d.ErrorMessage, d.SampleRate, d.CreatedAt)
+	}
+	query := `
+		INSERT INTO query_diagnostics (tenant_id, user_id, request_id, fingerprint, query_text,
+			duration_ms, row_count, error_message, sample_rate, created_at)
+		VALUES ` + strings.Join(values, ", ")
+	_, err := r.db.Exec(ctx, query, args...)
+	return err
+}
+
+func (r *queryDiagnosticsRepo) TopOffenders(ctx context.Context, filter models.QueryOffenderFilter) ([]*models.QueryOffender, error) {
+	orderBy, ok := queryOffenderOrders[filter.OrderBy]
+	if !ok {
+		orderBy = queryOffenderOrders["total_time"]
+	}
+	query := fmt.Sprintf(`
+		SELECT tenant_id, fingerprint, MAX(query_text),
+			COUNT(*),
+			SUM(1 / sample_rate),
+			COUNT(*) FILTER (WHERE error_message IS NOT NULL) AS errors,
+			AVG(duration_ms),
+			MAX(duration_ms) AS max_duration_ms,
+			SUM(duration_ms / sample_rate) AS estimated_total_time_ms,
+			MAX(row_count),
+			(ARRAY_AGG(error_message ORDER BY created_at DESC) FILTER (WHERE error_message IS NOT NULL))[1],
+			MAX(created_at)
+		FROM query_diagnostics
+		WHERE created_at >= $1 AND ($2::uuid IS NULL OR tenant_id = $2)
+		GROUP BY tenant_id, fingerprint
+		ORDER BY %s DESC
+		LIMIT $3
+	`, orderBy)
+	rows, err := r.db.Query(ctx, query, filter.Since, filter.TenantID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offenders []*models.QueryOffender
+	for rows.Next() {
+		o := &models.QueryOffender{}
+		if err := rows.Scan(&o.TenantID, &o.Fingerprint, &o.QueryText, &o.Recorded, &o.EstimatedCount, &o.Errors,
+			&o.AvgDurationMs, &o.MaxDurationMs, &o.TotalTimeMs, &o.MaxRows, &o.LastError, &o.LastSeenAt); err != nil {
+			return nil, err
+		}
+		offenders = append(offenders, o)
+	}
+	return offenders, rows.Err()
+}
+
+func (r *queryDiagnosticsRepo) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM query_diagnostics WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
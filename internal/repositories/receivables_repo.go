@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReceivablesRepository interface {
+	OpenInvoices(ctx context.Context, tenantID uuid.UUID, distributorID *uuid.UUID) ([]*models.ReceivableInvoice, error)
+}
+
+type receivablesRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewReceivablesRepo(db *pgxpool.Pool) ReceivablesRepository {
+	return &receivablesRepo{db: db}
+}
+
+// OpenInvoices lists unpaid and overdue customer invoices with something still
+// outstanding, of one customer or all of them, oldest due first. The caller
+// places them in aging buckets
+func (r *receivablesRepo) OpenInvoices(ctx context.Context, tenantID uuid.UUID, distributorID *uuid.UUID) ([]*models.ReceivableInvoice, error) {
+	query := `
+		SELECT id, invoice_number, status, issued_date, due_date, total_amount::float8, interest::float8, allocated::float8,
+			outstanding::float8, distributor_id, distributor_name
+		FROM (
+			SELECT i.id, i.invoice_number, i.status, i.issued_date, i.due_date, i.total_amount,
+				` + invoiceInterestAccrued + ` AS interest, ` + invoiceAllocated + ` AS allocated, ` + invoiceOutstanding + ` AS outstanding,
+				d.id AS distributor_id, d.name AS distributor_name
+			FROM invoices i
+			JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+			JOIN distributors d ON d.id = o.distributor_id
+			WHERE i.tenant_id = $1 AND i.status IN ('unpaid', 'overdue') AND ($2::uuid IS NULL OR o.distributor_id = $2)
+		) invoices
+		WHERE outstanding > $3
+		ORDER BY due_date, issued_date, invoice_number
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, distributorID, allocationTolerance)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*models.ReceivableInvoice
+	for rows.Next() {
+		inv := &models.ReceivableInvoice{}
+		if err := rows.Scan(&inv.InvoiceID, &inv.InvoiceNumber, &inv.Status, &inv.IssuedDate, &inv.DueDate, &inv.TotalAmount,
+			&inv.InterestAccrued, &inv.Allocated, &inv.Outstanding, &inv.DistributorID, &inv.DistributorName); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
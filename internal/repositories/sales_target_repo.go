@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SalesTargetRepository interface {
+	List(ctx context.Context, tenantID uuid.UUID, month time.Time, scope string) ([]*models.SalesTarget, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.SalesTarget, error)
+	Create(ctx context.Context, target *models.SalesTarget) (bool, error)
+	Update(ctx context.Context, target *models.SalesTarget) (bool, error)
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+
+	// CalculateAchievement recomputes achieved amounts of the tenant's
+	// targets for the month and returns how many it updated
+	CalculateAchievement(ctx context.Context, tenantID uuid.UUID, month time.Time) (int64, error)
+
+	SetCustomerTerritory(ctx context.Context, tenantID, distributorID uuid.UUID, salesRepID *uuid.UUID, region *string) (bool, error)
+}
+
+type salesTargetRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSalesTargetRepo(db *pgxpool.Pool) SalesTargetRepository {
+	return &salesTargetRepo{db: db}
+}
+
+// The subject of a target is the rep's name, the region or the category name
+const (
+	salesTargetColumns = `t.id, t.tenant_id, t.period_month, t.scope, t.user_id, t.region, t.category_id,
+		COALESCE(TRIM(u.first_name || ' ' || u.last_name), t.region, c.name, ''), t.basis, t.target_amount::float8,
+		t.achieved_amount::float8, t.calculated_at, t.notes, t.created_by, t.created_at, t.updated_at`
+	salesTargetFrom = `sales_targets t
+		LEFT JOIN users u ON u.id = t.user_id
+		LEFT JOIN categories c ON c.id = t.category_id`
+)
+
+func scanSalesTarget(row rowScanner) (*models.SalesTarget, error) {
+	t := &models.SalesTarget{}
+	err := row.Scan(&t.ID, &t.TenantID, &t.Month, &t.Scope, &t.UserID, &t.Region, &t.CategoryID, &t.Subject, &t.Basis,
+		&t.TargetAmount, &t.AchievedAmount, &t.CalculatedAt, &t.Notes, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// List returns the month's targets, of one scope when scope is set
+func (r *salesTargetRepo) List(ctx context.Context, tenantID uuid.UUID, month time.Time, scope string) ([]*models.SalesTarget, error) {
+	query := `
+		SELECT ` + salesTargetColumns + `
+		FROM ` + salesTargetFrom + `
+		WHERE t.tenant_id = $1 AND t.period_month = $2::date AND ($3 = '' OR t.scope = $3)
+		ORDER BY t.scope, t.basis, t.target_amount DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, month, scope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []*models.SalesTarget
+	for rows.Next() {
+		target, err := scanSalesTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+func (r *salesTargetRepo) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.SalesTarget, error) {
+	query := `SELECT ` + salesTargetColumns + ` FROM ` + salesTargetFrom + ` WHERE t.tenant_id = $1 AND t.id = $2`
+	target, err := scanSalesTarget(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return target, err
+}
+
+// Create reports false if the subject already has a target for the month on
+// the same basis
+func (r *salesTargetRepo) Create(ctx context.Context, target *models.SalesTarget) (bool, error) {
+	query := `
+		INSERT INTO sales_targets (id, tenant_id, period_month, scope, user_id, region, category_id, basis, target_amount, notes,
+			created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		ON CONFLICT DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, target.ID, target.TenantID, target.Month, target.Scope, target.UserID, target.Region,
+		target.CategoryID, target.Basis, target.TargetAmount, target.Notes, target.CreatedBy).Scan(&target.CreatedAt, &target.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *salesTargetRepo) Update(ctx context.Context, target *models.SalesTarget) (bool, error) {
+	query := `
+		UPDATE sales_targets SET target_amount = $3, notes = $4, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, target.TenantID, target.ID, target.TargetAmount, target.Notes).Scan(&target.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *salesTargetRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM sales_targets WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CalculateAchievement totals the month's sales by the customer's sales rep
+// and region and the product's category, on both bases, and sets each
+// target's achieved amount from the total matching it
+func (r *salesTargetRepo) CalculateAchievement(ctx context.Context, tenantID uuid.UUID, month time.Time) (int64, error) {
+	query := `
+		WITH sales AS (
+			SELECT 'orders' AS basis, o.quantity * o.unit_price AS amount, d.sales_rep_id, d.region, p.category_id
+			FROM orders o
+			JOIN products p ON p.id = o.product_id
+			LEFT JOIN distributors d ON d.id = o.distributor_id
+			WHERE o.tenant_id = $1 AND o.order_type = 'sales' AND o.status <> 'cancelled'
+				AND o.order_date >= $2::date AND o.order_date < ($2::date + INTERVAL '1 month')
+			UNION ALL
+			SELECT 'invoices', COALESCE(i.taxable_amount, i.total_amount), d.sales_rep_id, d.region, p.category_id
+			FROM invoices i
+			JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+			JOIN products p ON p.id = o.product_id
+			LEFT JOIN distributors d ON d.id = o.distributor_id
+			WHERE i.tenant_id = $1 AND i.status <> 'cancelled'
+				AND i.issued_date >= $2::date AND i.issued_date < ($2::date + INTERVAL '1 month')
+		)
+		UPDATE sales_targets t
+		SET achieved_amount = COALESCE((
+				SELECT SUM(s.amount) FROM sales s
+				WHERE s.basis = t.basis AND (
+					(t.scope = 'user' AND s.sales_rep_id = t.user_id) OR
+					(t.scope = 'region' AND LOWER(s.region) = LOWER(t.region)) OR
+					(t.scope = 'category' AND s.category_id = t.category_id)
+				)
+			), 0),
+			calculated_at = NOW()
+		WHERE t.tenant_id = $1 AND t.period_month = $2::date
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, month)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SetCustomerTerritory reports false for customers outside the tenant
+func (r *salesTargetRepo) SetCustomerTerritory(ctx context.Context, tenantID, distributorID uuid.UUID, salesRepID *uuid.UUID, region *string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE distributors SET sales_rep_id = $3, region = $4, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`,
+		tenantID, distributorID, salesRepID, region)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
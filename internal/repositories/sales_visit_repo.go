@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SalesVisitRepository interface {
+	Create(ctx context.Context, visit *models.SalesVisit) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.SalesVisit, error)
+	GetOpenForUser(ctx context.Context, tenantID, userID uuid.UUID) (*models.SalesVisit, error)
+	CheckOut(ctx context.Context, visit *models.SalesVisit) error
+	List(ctx context.Context, tenantID uuid.UUID, filter *models.SalesVisitFilter) ([]*models.SalesVisit, error)
+	AddPhoto(ctx context.Context, photo *models.SalesVisitPhoto) error
+	ListPhotos(ctx context.Context, tenantID, visitID uuid.UUID) ([]*models.SalesVisitPhoto, error)
+}
+
+type salesVisitRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSalesVisitRepo(db *pgxpool.Pool) SalesVisitRepository {
+	return &salesVisitRepo{db: db}
+}
+
+const salesVisitColumns = `id, tenant_id, user_id, distributor_id, check_in_at, check_in_latitude, check_in_longitude, check_in_accuracy_m, check_in_distance_m,
+	check_out_at, check_out_latitude, check_out_longitude, check_out_distance_m, notes, created_at, updated_at`
+
+func scanSalesVisit(row rowScanner) (*models.SalesVisit, error) {
+	visit := &models.SalesVisit{}
+	err := row.Scan(&visit.ID, &visit.TenantID, &visit.UserID, &visit.DistributorID, &visit.CheckInAt, &visit.CheckInLatitude, &visit.CheckInLongitude, &visit.CheckInAccuracyM, &visit.CheckInDistanceM,
+		&visit.CheckOutAt, &visit.CheckOutLatitude, &visit.CheckOutLongitude, &visit.CheckOutDistanceM, &visit.Notes, &visit.CreatedAt, &visit.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return visit, nil
+}
+
+func (r *salesVisitRepo) Create(ctx context.Context, visit *models.SalesVisit) error {
+	query := `
+		INSERT INTO sales_visits (id, tenant_id, user_id, distributor_id, check_in_at, check_in_latitude, check_in_longitude, check_in_accuracy_m, check_in_distance_m, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, visit.ID, visit.TenantID, visit.UserID, visit.DistributorID, visit.CheckInAt, visit.CheckInLatitude, visit.CheckInLongitude, visit.CheckInAccuracyM, visit.CheckInDistanceM, visit.Notes).
+		Scan(&visit.CreatedAt, &visit.UpdatedAt)
+}
+
+func (r *salesVisitRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.SalesVisit, error) {
+	query := `SELECT ` + salesVisitColumns + ` FROM sales_visits WHERE tenant_id = $1 AND id = $2`
+	visit, err := scanSalesVisit(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return visit, err
+}
+
+func (r *salesVisitRepo) GetOpenForUser(ctx context.Context, tenantID, userID uuid.UUID) (*models.SalesVisit, error) {
+	query := `SELECT ` + salesVisitColumns + ` FROM sales_visits WHERE tenant_id = $1 AND user_id = $2 AND check_out_at IS NULL`
+	visit, err := scanSalesVisit(r.db.QueryRow(ctx, query, tenantID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return visit, err
+}
+
+func (r *salesVisitRepo) CheckOut(ctx context.Context, visit *models.SalesVisit) error {
+	query := `
+		UPDATE sales_visits
+		SET check_out_at = $1, check_out_latitude = $2, check_out_longitude = $3, check_out_distance_m = $4, notes = $5, updated_at = NOW()
+		WHERE tenant_id = $6 AND id = $7 AND check_out_at IS NULL
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, visit.CheckOutAt, visit.CheckOutLatitude, visit.CheckOutLongitude, visit.CheckOutDistanceM, visit.Notes, visit.TenantID, visit.ID).Scan(&visit.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("visit is already checked out")
+	}
+	return err
+}
+
+func (r *salesVisitRepo) List(ctx context.Context, tenantID uuid.UUID, filter *models.SalesVisitFilter) ([]*models.SalesVisit, error) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.DistributorID != nil {
+		args = append(args, *filter.DistributorID)
+		conditions = append(conditions, fmt.Sprintf("distributor_id = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("check_in_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("check_in_at < $%d", len(args)))
+	}
+
+	order := "check_in_at DESC"
+	limit := ""
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		limit = fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	} else {
+		// Unbounded listings are used for route reports and read in visit order
+		order = "check_in_at"
+	}
+
+	query := `SELECT ` + salesVisitColumns + ` FROM sales_visits WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY ` + order + limit
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var visits []*models.SalesVisit
+	for rows.Next() {
+		visit, err := scanSalesVisit(rows)
+		if err != nil {
+			return nil, err
+		}
+		visits = append(visits, visit)
+	}
+	return visits, rows.Err()
+}
+
+func (r *salesVisitRepo) AddPhoto(ctx context.Context, photo *models.SalesVisitPhoto) error {
+	query := `
+		INSERT INTO sales_visit_photos (id, tenant_id, visit_id, object_key, caption, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, photo.ID, photo.TenantID, photo.VisitID, photo.ObjectKey, photo.Caption).Scan(&photo.CreatedAt)
+}
+
+func (r *salesVisitRepo) ListPhotos(ctx context.Context, tenantID, visitID uuid.UUID) ([]*models.SalesVisitPhoto, error) {
+	query := `
+		SELECT id, tenant_id, visit_id, object_key, caption, created_at
+		FROM sales_visit_photos
+		WHERE tenant_id = $1 AND visit_id = $2
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, visitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var photos []*models.SalesVisitPhoto
+	for rows.Next() {
+		photo := &models.SalesVisitPhoto{}
+		if err := rows.Scan(&photo.ID, &photo.TenantID, &photo.VisitID, &photo.ObjectKey, &photo.Caption, &photo.CreatedAt); err != nil {
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+	return photos, rows.Err()
+}
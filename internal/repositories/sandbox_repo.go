@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SandboxRepository interface {
+	Get(ctx context.Context, tenantID uuid.UUID) (*models.TenantSandbox, error)
+	List(ctx context.Context) ([]*models.TenantSandbox, error)
+	Upsert(ctx context.Context, sandbox *models.TenantSandbox) (bool, error)
+	Delete(ctx context.Context, tenantID uuid.UUID) (bool, error)
+	ListDue(ctx context.Context, now time.Time) ([]*models.TenantSandbox, error)
+	MarkReset(ctx context.Context, tenantID uuid.UUID, resetAt time.Time) error
+	Wipe(ctx context.Context, tenantID uuid.UUID) error
+}
+
+type sandboxRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSandboxRepo(db *pgxpool.Pool) SandboxRepository {
+	return &sandboxRepo{db: db}
+}
+
+const sandboxColumns = `tenant_id, reset_interval_hours, seed, last_reset_at, next_reset_at, enabled_by, created_at, updated_at`
+
+func scanSandbox(row rowScanner) (*models.TenantSandbox, error) {
+	s := &models.TenantSandbox{}
+	err := row.Scan(&s.TenantID, &s.ResetIntervalHours, &s.Seed, &s.LastResetAt, &s.NextResetAt, &s.EnabledBy, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the tenant's sandbox settings, or nil when it is not a sandbox
+func (r *sandboxRepo) Get(ctx context.Context, tenantID uuid.UUID) (*models.TenantSandbox, error) {
+	query := `SELECT ` + sandboxColumns + ` FROM tenant_sandboxes WHERE tenant_id = $1`
+	s, err := scanSandbox(r.db.QueryRow(ctx, query, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (r *sandboxRepo) List(ctx context.Context) ([]*models.TenantSandbox, error) {
+	return r.list(ctx, `SELECT `+sandboxColumns+` FROM tenant_sandboxes ORDER BY created_at`)
+}
+
+// ListDue lists the sandboxes whose next reset is at or before now
+func (r *sandboxRepo) ListDue(ctx context.Context, now time.Time) ([]*models.TenantSandbox, error) {
+	return r.list(ctx, `SELECT `+sandboxColumns+` FROM tenant_sandboxes WHERE next_reset_at <= $1 ORDER BY next_reset_at`, now)
+}
+
+func (r *sandboxRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.TenantSandbox, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sandboxes []*models.TenantSandbox
+	for rows.Next() {
+		s, err := scanSandbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		sandboxes = append(sandboxes, s)
+	}
+	return sandboxes, rows.Err()
+}
+
+// Upsert saves the sandbox settings, reporting false when the tenant does not
+// exist. A new sandbox is due for reset straight away; changing the interval
+// of an existing one reschedules its next reset from the last
+func (r *sandboxRepo) Upsert(ctx context.Context, sandbox *models.TenantSandbox) (bool, error) {
+	query := `
+		INSERT INTO tenant_sandboxes (tenant_id, reset_interval_hours, seed, next_reset_at, enabled_by, created_at, updated_at)
+		SELECT t.id, $2, $3, NOW(), $4, NOW(), NOW()
+		FROM tenants t
+		WHERE t.id = $1
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			reset_interval_hours = EXCLUDED.reset_interval_hours,
+			seed = EXCLUDED.seed,
+			next_reset_at = COALESCE(tenant_sandboxes.last_reset_at + make_interval(hours => EXCLUDED.reset_interval_hours), tenant_sandboxes.next_reset_at),
+			updated_at = NOW()
+		RETURNING ` + sandboxColumns
+	saved, err := scanSandbox(r.db.QueryRow(ctx, query, sandbox.TenantID, sandbox.ResetIntervalHours, sandbox.Seed, sandbox.EnabledBy))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	*sandbox = *saved
+	return true, nil
+}
+
+// Delete turns the tenant back into a regular tenant, keeping its data as it is
+func (r *sandboxRepo) Delete(ctx context.Context, tenantID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM tenant_sandboxes WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkReset records a reset and schedules the next one
+func (r *sandboxRepo) MarkReset(ctx context.Context, tenantID uuid.UUID, resetAt time.Time) error {
+	query := `
+		UPDATE tenant_sandboxes
+		SET last_reset_at = $2, next_reset_at = $2 + make_interval(hours => reset_interval_hours), updated_at = NOW()
+		WHERE tenant_id = $1
+	`
+	_, err := r.db.Exec(ctx, query, tenantID, resetAt)
+	return err
+}
+
+// sandboxWipeStatements delete a tenant's business data, children before the
+// tables they reference without cascading. Documents hanging off orders,
+// invoices, products, warehouses, suppliers and distributors go with them
+// through their cascading foreign keys; users, roles and settings are kept
+var sandboxWipeStatements = []string{
+	`DELETE FROM invoices WHERE tenant_id = $1`,
+	`DELETE FROM orders WHERE tenant_id = $1`,
+	`DELETE FROM marketplace_channels WHERE tenant_id = $1`,
+	`DELETE FROM product_bundle_components WHERE tenant_id = $1`,
+	`DELETE FROM inventory WHERE tenant_id = $1`,
+	`DELETE FROM products WHERE tenant_id = $1`,
+	`UPDATE categories SET parent_id = NULL WHERE tenant_id = $1`,
+	`DELETE FROM categories WHERE tenant_id = $1`,
+	`DELETE FROM warehouses WHERE tenant_id = $1`,
+	`DELETE FROM suppliers WHERE tenant_id = $1`,
+	`DELETE FROM distributors WHERE tenant_id = $1`,
+}
+
+// Wipe deletes the tenant's business data in one transaction
+func (r *sandboxRepo) Wipe(ctx context.Context, tenantID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, stmt := range sandboxWipeStatements {
+		if _, err := tx.Exec(ctx, stmt, tenantID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
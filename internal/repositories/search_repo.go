@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SearchRepository interface {
+	// Suggest returns up to limit matches of each type, best first. The term
+	// is lowercased and free of LIKE wildcards; orderPrefix, when set, is
+	// matched against the start of order IDs
+	Suggest(ctx context.Context, tenantID uuid.UUID, term, orderPrefix string, limit int) ([]*models.SearchSuggestion, error)
+	// Search returns one page of records of the type matching the term, best
+	// first, with the values of the fields searched
+	Search(ctx context.Context, tenantID uuid.UUID, searchType, term, orderPrefix string, limit, offset int) ([]*models.SearchResult, error)
+}
+
+type searchRepo struct {
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
+}
+
+func NewSearchRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) SearchRepository {
+	return &searchRepo{db: db, keyring: keyring}
+}
+
+func (r *searchRepo) Suggest(ctx context.Context, tenantID uuid.UUID, term, orderPrefix string, limit int) ([]*models.SearchSuggestion, error) {
+	// Exact names rank first, then prefixes, then words starting with the
+	// term, with trigram similarity breaking ties and admitting typos
+	query := `
+		(SELECT 'product', p.id, p.name, NULLIF(p.barcode, ''),
+			(CASE WHEN LOWER(p.name) = $2 OR LOWER(p.barcode) = $2 THEN 3
+				WHEN LOWER(p.name) LIKE $2 || '%' OR LOWER(p.barcode) LIKE $2 || '%' THEN 2
+				WHEN LOWER(p.name) LIKE '% ' || $2 || '%' THEN 1.5
+				ELSE 0 END + similarity(LOWER(p.name), $2))::float8 AS score
+		FROM products p
+		WHERE p.tenant_id = $1
+		  AND (LOWER(p.name) LIKE '%' || $2 || '%' OR LOWER(p.name) % $2 OR LOWER(p.barcode) LIKE $2 || '%')
+		ORDER BY score DESC, p.name
+		LIMIT $3)
+		UNION ALL
+		(SELECT 'customer', d.id, d.name, d.address,
+			(CASE WHEN LOWER(d.name) = $2 THEN 3
+				WHEN LOWER(d.name) LIKE $2 || '%' THEN 2
+				WHEN LOWER(d.name) LIKE '% ' || $2 || '%' THEN 1.5
+				ELSE 0 END + similarity(LOWER(d.name), $2))::float8 AS score
+		FROM distributors d
+		WHERE d.tenant_id = $1 AND d.archived_at IS NULL
+		  AND (LOWER(d.name) LIKE '%' || $2 || '%' OR LOWER(d.name) % $2)
+		ORDER BY score DESC, d.name
+		LIMIT $3)
+		UNION ALL
+		(SELECT 'supplier', s.id, s.name, s.address,
+			(CASE WHEN LOWER(s.name) = $2 THEN 3
+				WHEN LOWER(s.name) LIKE $2 || '%' THEN 2
+				WHEN LOWER(s.name) LIKE '% ' || $2 || '%' THEN 1.5
+				ELSE 0 END + similarity(LOWER(s.name), $2))::float8 AS score
+		FROM suppliers s
+		WHERE s.tenant_id = $1 AND s.archived_at IS NULL
+		  AND (LOWER(s.name) LIKE '%' || $2 || '%' OR LOWER(s.name) % $2)
+		ORDER BY score DESC, s.name
+		LIMIT $3)
+		UNION ALL
+		(SELECT 'order', o.id, 'ORD-' || UPPER(LEFT(o.id::text, 8)), o.order_type || ' order, ' || o.status,
+			2::float8 AS score
+		FROM orders o
+		WHERE o.tenant_id = $1 AND $4 <> '' AND o.id::text LIKE $4 || '%'
+		ORDER BY o.order_date DESC
+		LIMIT $3)
+		ORDER BY score DESC, name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, term, limit, orderPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []*models.SearchSuggestion
+	for rows.Next() {
+		suggestion := &models.SearchSuggestion{}
+		if err := rows.Scan(&suggestion.Type, &suggestion.ID, &suggestion.Label, &suggestion.Detail, &suggestion.Score); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, rows.Err()
+}
+
+// searchPartyQuery searches active customers (distributors) or suppliers by
+// name, and by exact email, phone or GSTIN through their blind indexes. Rows
+// written before encryption have no index and are compared in plain text
+const searchPartyQuery = `
+	SELECT t.id, t.name, t.address,
+		(CASE WHEN LOWER(t.name) = $2 OR t.contact_email_bidx = $3 OR t.contact_phone_bidx = $3 OR t.gstin_bidx = $3 THEN 3
+			WHEN LOWER(t.name) LIKE $2 || '%%' THEN 2
+			WHEN LOWER(t.name) LIKE '%% ' || $2 || '%%' THEN 1.5
+			ELSE 0 END + similarity(LOWER(t.name), $2))::float8 AS score,
+		jsonb_build_object('name', t.name)
+			|| CASE WHEN t.contact_email_bidx = $3 OR (t.contact_email_bidx IS NULL AND LOWER(t.contact_email) = $2)
+				THEN jsonb_build_object('contact_email', $2::text) ELSE '{}'::jsonb END
+			|| CASE WHEN t.contact_phone_bidx = $3 OR (t.contact_phone_bidx IS NULL AND t.contact_phone = $2)
+				THEN jsonb_build_object('contact_phone', $2::text) ELSE '{}'::jsonb END
+			|| CASE WHEN t.gstin_bidx = $3 OR (t.gstin_bidx IS NULL AND LOWER(t.gstin) = $2)
+				THEN jsonb_build_object('gstin', $2::text) ELSE '{}'::jsonb END
+	FROM %s t
+	WHERE t.tenant_id = $1 AND t.archived_at IS NULL
+	  AND (LOWER(t.name) LIKE '%%' || $2 || '%%' OR LOWER(t.name) %% $2
+		OR t.contact_email_bidx = $3 OR t.contact_phone_bidx = $3 OR t.gstin_bidx = $3
+		OR (t.contact_email_bidx IS NULL AND LOWER(t.contact_email) = $2)
+		OR (t.contact_phone_bidx IS NULL AND t.contact_phone = $2)
+		OR (t.gstin_bidx IS NULL AND LOWER(t.gstin) = $2))
+	ORDER BY score DESC, t.name, t.id
+	LIMIT $4 OFFSET $5
+`
+
+func (r *searchRepo) Search(ctx context.Context, tenantID uuid.UUID, searchType, term, orderPrefix string, limit, offset int) ([]*models.SearchResult, error) {
+	var query string
+	var args []interface{}
+	switch searchType {
+	case models.SearchTypeProduct:
+		query = `
+			SELECT p.id, p.name, NULLIF(p.barcode, ''),
+				(CASE WHEN LOWER(p.name) = $2 OR LOWER(p.barcode) = $2 THEN 3
+					WHEN LOWER(p.name) LIKE $2 || '%' OR LOWER(p.barcode) LIKE $2 || '%' THEN 2
+					WHEN LOWER(p.name) LIKE '% ' || $2 || '%' THEN 1.5
+					ELSE 0 END + similarity(LOWER(p.name), $2))::float8 AS score,
+				jsonb_build_object('name', p.name, 'barcode', COALESCE(p.barcode, ''), 'description', COALESCE(p.description, ''))
+			FROM products p
+			WHERE p.tenant_id = $1
+			  AND (LOWER(p.name) LIKE '%' || $2 || '%' OR LOWER(p.name) % $2 OR LOWER(p.barcode) LIKE $2 || '%'
+				OR LOWER(COALESCE(p.description, '')) LIKE '%' || $2 || '%')
+			ORDER BY score DESC, p.name, p.id
+			LIMIT $3 OFFSET $4
+		`
+		args = []interface{}{tenantID, term, limit, offset}
+	case models.SearchTypeOrder:
+		query = `
+			SELECT o.id, 'ORD-' || UPPER(LEFT(o.id::text, 8)), o.order_type || ' order, ' || o.status,
+				(CASE WHEN $3 <> '' AND o.id::text LIKE $3 || '%' THEN 2 ELSE 0 END
+					+ similarity(LOWER(COALESCE(o.notes, '')), $2))::float8 AS score,
+				jsonb_build_object('order_number', 'ORD-' || UPPER(LEFT(o.id::text, 8)), 'notes', COALESCE(o.notes, ''))
+			FROM orders o
+			WHERE o.tenant_id = $1
+			  AND (($3 <> '' AND o.id::text LIKE $3 || '%') OR LOWER(COALESCE(o.notes, '')) LIKE '%' || $2 || '%')
+			ORDER BY score DESC, o.order_date DESC, o.id
+			LIMIT $4 OFFSET $5
+		`
+		args = []interface{}{tenantID, term, orderPrefix, limit, offset}
+	case models.SearchTypeInvoice:
+		query = `
+			SELECT i.id, i.invoice_number, i.status,
+				(CASE WHEN LOWER(i.invoice_number) = $2 THEN 3
+					WHEN LOWER(i.invoice_number) LIKE $2 || '%' THEN 2
+					ELSE 0 END + similarity(LOWER(i.invoice_number), $2))::float8 AS score,
+				jsonb_build_object('invoice_number', i.invoice_number)
+			FROM invoices i
+			WHERE i.tenant_id = $1 AND LOWER(i.invoice_number) LIKE '%' || $2 || '%'
+			ORDER BY score DESC, i.issued_date DESC, i.id
+			LIMIT $3 OFFSET $4
+		`
+		args = []interface{}{tenantID, term, limit, offset}
+	case models.SearchTypeCustomer:
+		query = fmt.Sprintf(searchPartyQuery, "distributors")
+		args = []interface{}{tenantID, term, r.keyring.BlindIndex(tenantID, &term), limit, offset}
+	case models.SearchTypeSupplier:
+		query = fmt.Sprintf(searchPartyQuery, "suppliers")
+		args = []interface{}{tenantID, term, r.keyring.BlindIndex(tenantID, &term), limit, offset}
+	default:
+		return nil, fmt.Errorf("unknown search type %q", searchType)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.SearchResult
+	for rows.Next() {
+		result := &models.SearchResult{Type: searchType}
+		if err := rows.Scan(&result.ID, &result.Title, &result.Subtitle, &result.Score, &result.Fields); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SeasonRepository interface {
+	Create(ctx context.Context, season *models.Season) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Season, error)
+	Update(ctx context.Context, season *models.Season) error
+	Delete(ctx context.Context, tenantID, id uuid.UUID) error
+	List(ctx context.Context, tenantID uuid.UUID) ([]*models.Season, error)
+	SetProductTags(ctx context.Context, tenantID, productID uuid.UUID, seasonIDs []uuid.UUID, crops []string) error
+	ListProductSeasons(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.Season, error)
+	ListProductCrops(ctx context.Context, tenantID, productID uuid.UUID) ([]string, error)
+	ListSeasonProducts(ctx context.Context, tenantID, seasonID uuid.UUID) ([]uuid.UUID, error)
+	MonthlySales(ctx context.Context, tenantID, productID uuid.UUID, since time.Time) ([]*models.MonthlyDemand, error)
+	SalesBetween(ctx context.Context, tenantID, productID uuid.UUID, from, to time.Time) (int, error)
+}
+
+type seasonRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSeasonRepo(db *pgxpool.Pool) SeasonRepository {
+	return &seasonRepo{db: db}
+}
+
+const seasonColumns = `id, tenant_id, name, season_type, region, start_month, start_day, end_month, end_day, lead_days, created_at, updated_at`
+
+func (r *seasonRepo) Create(ctx context.Context, season *models.Season) error {
+	query := `
+		INSERT INTO seasons (id, tenant_id, name, season_type, region, start_month, start_day, end_month, end_day, lead_days, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+	`
+	_, err := r.db.Exec(ctx, query, season.ID, season.TenantID, season.Name, season.SeasonType, season.Region,
+		season.StartMonth, season.StartDay, season.EndMonth, season.EndDay, season.LeadDays)
+	return err
+}
+
+func (r *seasonRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Season, error) {
+	season := &models.Season{}
+	query := `SELECT ` + seasonColumns + ` FROM seasons WHERE tenant_id = $1 AND id = $2`
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&season.ID, &season.TenantID, &season.Name, &season.SeasonType, &season.Region,
+		&season.StartMonth, &season.StartDay, &season.EndMonth, &season.EndDay, &season.LeadDays, &season.CreatedAt, &season.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+func (r *seasonRepo) Update(ctx context.Context, season *models.Season) error {
+	query := `
+		UPDATE seasons
+		SET name = $1, season_type = $2, region = $3, start_month = $4, start_day = $5, end_month = $6, end_day = $7, lead_days = $8, updated_at = NOW()
+		WHERE tenant_id = $9 AND id = $10
+	`
+	_, err := r.db.Exec(ctx, query, season.Name, season.SeasonType, season.Region, season.StartMonth, season.StartDay,
+		season.EndMonth, season.EndDay, season.LeadDays, season.TenantID, season.ID)
+	return err
+}
+
+func (r *seasonRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `DELETE FROM seasons WHERE tenant_id = $1 AND id = $2`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+func (r *seasonRepo) List(ctx context.Context, tenantID uuid.UUID) ([]*models.Season, error) {
+	query := `SELECT ` + seasonColumns + ` FROM seasons WHERE tenant_id = $1 ORDER BY start_month, start_day, name`
+	return r.querySeasons(ctx, query, tenantID)
+}
+
+// SetProductTags replaces the product's season and crop tags
+func (r *seasonRepo) SetProductTags(ctx context.Context, tenantID, productID uuid.UUID, seasonIDs []uuid.UUID, crops []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM product_seasons WHERE tenant_id = $1 AND product_id = $2`, tenantID, productID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM product_crops WHERE tenant_id = $1 AND product_id = $2`, tenantID, productID); err != nil {
+		return err
+	}
+
+	for _, seasonID := range seasonIDs {
+		query := `
+			INSERT INTO product_seasons (tenant_id, product_id, season_id)
+			SELECT $1, $2, id FROM seasons WHERE tenant_id = $1 AND id = $3
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, query, tenantID, productID, seasonID); err != nil {
+			return err
+		}
+	}
+	for _, crop := range crops {
+		query := `INSERT INTO product_crops (tenant_id, product_id, crop) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(ctx, query, tenantID, productID, crop); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *seasonRepo) ListProductSeasons(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.Season, error) {
+	query := `
+		SELECT s.id, s.tenant_id, s.name, s.season_type, s.region, s.start_month, s.start_day, s.end_month, s.end_day, s.lead_days, s.created_at, s.updated_at
+		FROM seasons s
+		JOIN product_seasons ps ON ps.season_id = s.id
+		WHERE ps.tenant_id = $1 AND ps.product_id = $2
+		ORDER BY s.start_month, s.start_day, s.name
+	`
+	return r.querySeasons(ctx, query, tenantID, productID)
+}
+
+func (r *seasonRepo) ListProductCrops(ctx context.Context, tenantID, productID uuid.UUID) ([]string, error) {
+	query := `SELECT crop FROM product_crops WHERE tenant_id = $1 AND product_id = $2 ORDER BY crop`
+	rows, err := r.db.Query(ctx, query, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var crops []string
+	for rows.Next() {
+		var crop string
+		if err := rows.Scan(&crop); err != nil {
+			return nil, err
+		}
+		crops = append(crops, crop)
+	}
+	return crops, nil
+}
+
+func (r *seasonRepo) ListSeasonProducts(ctx context.Context, tenantID, seasonID uuid.UUID) ([]uuid.UUID, error) {
+	query := `SELECT product_id FROM product_seasons WHERE tenant_id = $1 AND season_id = $2`
+	rows, err := r.db.Query(ctx, query, tenantID, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var productIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		productIDs = append(productIDs, id)
+	}
+	return productIDs, nil
+}
+
+// MonthlySales returns units sold per month on non-cancelled sales orders
+func (r *seasonRepo) MonthlySales(ctx context.Context, tenantID, productID uuid.UUID, since time.Time) ([]*models.MonthlyDemand, error) {
+	query := `
+		SELECT EXTRACT(YEAR FROM order_date)::int, EXTRACT(MONTH FROM order_date)::int, COALESCE(SUM(quantity), 0)::int
+		FROM orders
+		WHERE tenant_id = $1 AND product_id = $2
+		  AND order_type = 'sales' AND status <> 'cancelled'
+		  AND order_date >= $3
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var demand []*models.MonthlyDemand
+	for rows.Next() {
+		d := &models.MonthlyDemand{}
+		if err := rows.Scan(&d.Year, &d.Month, &d.Units); err != nil {
+			return nil, err
+		}
+		demand = append(demand, d)
+	}
+	return demand, nil
+}
+
+// SalesBetween returns units sold on non-cancelled sales orders in [from, to)
+func (r *seasonRepo) SalesBetween(ctx context.Context, tenantID, productID uuid.UUID, from, to time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0)::int
+		FROM orders
+		WHERE tenant_id = $1 AND product_id = $2
+		  AND order_type = 'sales' AND status <> 'cancelled'
+		  AND order_date >= $3 AND order_date < $4
+	`
+	var units int
+	err := r.db.QueryRow(ctx, query, tenantID, productID, from, to).Scan(&units)
+	return units, err
+}
+
+func (r *seasonRepo) querySeasons(ctx context.Context, query string, args ...interface{}) ([]*models.Season, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []*models.Season
+	for rows.Next() {
+		season := &models.Season{}
+		if err := rows.Scan(&season.ID, &season.TenantID, &season.Name, &season.SeasonType, &season.Region,
+			&season.StartMonth, &season.StartDay, &season.EndMonth, &season.EndDay, &season.LeadDays, &season.CreatedAt, &season.UpdatedAt); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, season)
+	}
+	return seasons, nil
+}
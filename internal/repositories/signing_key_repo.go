@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SigningKeyRepository interface {
+	Create(ctx context.Context, key *models.SigningKey) error
+	// ListVerifiable returns active keys and retiring keys that have not expired, newest first
+	ListVerifiable(ctx context.Context) ([]*models.SigningKey, error)
+	// RetireActive moves every active key except keepKID to retiring, valid until expiresAt
+	RetireActive(ctx context.Context, keepKID string, expiresAt time.Time) error
+	// Revoke stops the key verifying; it reports false when the key does not
+	// exist or is already revoked
+	Revoke(ctx context.Context, kid string) (bool, error)
+}
+
+type signingKeyRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSigningKeyRepo(db *pgxpool.Pool) SigningKeyRepository {
+	return &signingKeyRepo{db: db}
+}
+
+func (r *signingKeyRepo) Create(ctx context.Context, key *models.SigningKey) error {
+	query := `
+		INSERT INTO jwt_signing_keys (kid, algorithm, private_key, public_key, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+	_, err := r.db.Exec(ctx, query, key.KID, key.Algorithm, key.PrivateKey, key.PublicKey, key.Status)
+	return err
+}
+
+func (r *signingKeyRepo) ListVerifiable(ctx context.Context) ([]*models.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, public_key, status, created_at, rotated_at, expires_at
+		FROM jwt_signing_keys
+		WHERE status = 'active' OR (status = 'retiring' AND (expires_at IS NULL OR expires_at > NOW()))
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.SigningKey
+	for rows.Next() {
+		key := &models.SigningKey{}
+		if err := rows.Scan(&key.KID, &key.Algorithm, &key.PrivateKey, &key.PublicKey, &key.Status, &key.CreatedAt, &key.RotatedAt, &key.ExpiresAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *signingKeyRepo) RetireActive(ctx context.Context, keepKID string, expiresAt time.Time) error {
+	query := `
+		UPDATE jwt_signing_keys
+		SET status = 'retiring', rotated_at = NOW(), expires_at = $1
+		WHERE status = 'active' AND kid <> $2
+	`
+	_, err := r.db.Exec(ctx, query, expiresAt, keepKID)
+	return err
+}
+
+func (r *signingKeyRepo) Revoke(ctx context.Context, kid string) (bool, error) {
+	query := `UPDATE jwt_signing_keys SET status = 'revoked', rotated_at = NOW() WHERE kid = $1 AND status <> 'revoked'`
+	tag, err := r.db.Exec(ctx, query, kid)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
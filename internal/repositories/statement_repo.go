@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StatementRepository interface {
+	CreateCreditNote(ctx context.Context, note *models.CreditNote) error
+	ListCreditNotes(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) ([]*models.CreditNote, error)
+	InvoiceDistributor(ctx context.Context, tenantID, invoiceID uuid.UUID) (distributorID, branchID *uuid.UUID, err error)
+
+	BalanceBefore(ctx context.Context, tenantID, distributorID uuid.UUID, before time.Time) (float64, error)
+	ListEntries(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) ([]*models.StatementEntry, error)
+
+	PendingRecipients(ctx context.Context, tenantID uuid.UUID, asOf, periodStart time.Time) ([]*models.StatementRecipient, error)
+	ClaimDispatch(ctx context.Context, tenantID, distributorID uuid.UUID, periodStart time.Time, balance float64, recipient, objectKey string) (bool, error)
+	ReleaseDispatch(ctx context.Context, tenantID, distributorID uuid.UUID, periodStart time.Time) error
+}
+
+type statementRepo struct {
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
+}
+
+func NewStatementRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) StatementRepository {
+	return &statementRepo{db: db, keyring: keyring}
+}
+
+// statementLedger is every debit and credit of the tenant ($1) per
+// distributor: non-cancelled sales invoices on their issue date, interest
+// accrued on overdue invoices, payments received and the TDS deducted from
+// them, invoices marked paid as payments of whatever received payments did
+// not cover on their paid date, and credit notes. Invoices cancelled by an
+// amendment stay in, as the amendment's credit note is what settles them
+const statementLedger = `
+	SELECT o.distributor_id, i.issued_date::date AS entry_date, i.issued_date AS recorded_at, 'invoice' AS entry_type,
+		i.id AS source_id, i.invoice_number AS reference, NULL::text AS note, i.total_amount AS debit, 0::numeric AS credit
+	FROM invoices i
+	JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+	WHERE i.tenant_id = $1 AND o.distributor_id IS NOT NULL AND (i.status <> 'cancelled' OR ` + invoiceAmended + `)
+	UNION ALL
+	SELECT e.distributor_id, e.period_end, e.created_at, 'interest',
+		e.id, i.invoice_number, NULL::text, e.amount, 0::numeric
+	FROM invoice_interest_entries e
+	JOIN invoices i ON i.id = e.invoice_id AND i.tenant_id = e.tenant_id
+	WHERE e.tenant_id = $1
+	UNION ALL
+	SELECT o.distributor_id, i.paid_date::date, i.paid_date, 'payment',
+		i.id, i.invoice_number, NULL::text, 0::numeric, ` + invoiceOutstanding + `
+	FROM invoices i
+	JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+	WHERE i.tenant_id = $1 AND o.distributor_id IS NOT NULL AND i.paid_date IS NOT NULL
+		AND (i.status = 'paid' OR (i.status = 'cancelled' AND ` + invoiceAmended + `))
+		AND ` + invoiceOutstanding + ` > 0.005
+	UNION ALL
+	SELECT p.distributor_id, p.payment_date, p.created_at, 'receipt',
+		p.id, p.payment_number, p.reference, 0::numeric, p.amount
+	FROM customer_payments p
+	WHERE p.tenant_id = $1
+	UNION ALL
+	SELECT p.distributor_id, p.payment_date, p.created_at, 'tds',
+		p.id, p.payment_number, p.tds_section::text, 0::numeric, p.tds_amount
+	FROM customer_payments p
+	WHERE p.tenant_id = $1 AND p.tds_amount > 0
+	UNION ALL
+	SELECT cn.distributor_id, cn.issued_date, cn.created_at, 'credit_note',
+		cn.id, cn.credit_note_number, cn.reason, 0::numeric, cn.amount
+	FROM credit_notes cn
+	WHERE cn.tenant_id = $1
+`
+
+const creditNoteColumns = `id, tenant_id, distributor_id, invoice_id, credit_note_number, amount, reason, issued_date, created_by, created_at`
+
+func scanCreditNote(row rowScanner) (*models.CreditNote, error) {
+	note := &models.CreditNote{}
+	err := row.Scan(&note.ID, &note.TenantID, &note.DistributorID, &note.InvoiceID, &note.CreditNoteNumber, &note.Amount,
+		&note.Reason, &note.IssuedDate, &note.CreatedBy, &note.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+func (r *statementRepo) CreateCreditNote(ctx context.Context, note *models.CreditNote) error {
+	query := `
+		INSERT INTO credit_notes (id, tenant_id, distributor_id, invoice_id, credit_note_number, amount, reason, issued_date, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, note.ID, note.TenantID, note.DistributorID, note.InvoiceID, note.CreditNoteNumber,
+		note.Amount, note.Reason, note.IssuedDate, note.CreatedBy).Scan(&note.CreatedAt)
+}
+
+// ListCreditNotes lists a distributor's credit notes issued between from and
+// to inclusive, oldest first
+func (r *statementRepo) ListCreditNotes(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) ([]*models.CreditNote, error) {
+	query := `
+		SELECT ` + creditNoteColumns + `
+		FROM credit_notes
+		WHERE tenant_id = $1 AND distributor_id = $2 AND issued_date BETWEEN $3::date AND $4::date
+		ORDER BY issued_date, created_at
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, distributorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*models.CreditNote
+	for rows.Next() {
+		note, err := scanCreditNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// InvoiceDistributor returns the distributor a sales invoice was raised to
+// and the branch that issued it, or nil when the invoice does not exist or is
+// not a sales invoice
+func (r *statementRepo) InvoiceDistributor(ctx context.Context, tenantID, invoiceID uuid.UUID) (*uuid.UUID, *uuid.UUID, error) {
+	query := `
+		SELECT o.distributor_id, i.branch_id
+		FROM invoices i
+		JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		WHERE i.tenant_id = $1 AND i.id = $2
+	`
+	var distributorID, branchID *uuid.UUID
+	err := r.db.QueryRow(ctx, query, tenantID, invoiceID).Scan(&distributorID, &branchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	return distributorID, branchID, err
+}
+
+// BalanceBefore is what the distributor owed at the start of the given day
+func (r *statementRepo) BalanceBefore(ctx context.Context, tenantID, distributorID uuid.UUID, before time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(debit - credit), 0)
+		FROM (` + statementLedger + `) ledger
+		WHERE distributor_id = $2 AND entry_date < $3::date
+	`
+	var balance float64
+	err := r.db.QueryRow(ctx, query, tenantID, distributorID, before).Scan(&balance)
+	return balance, err
+}
+
+// ListEntries lists the distributor's ledger entries dated between from and to
+// inclusive in the order they happened; running balances are left to the caller
+func (r *statementRepo) ListEntries(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) ([]*models.StatementEntry, error) {
+	query := `
+		SELECT entry_date, entry_type, source_id, reference, note, debit, credit
+		FROM (` + statementLedger + `) ledger
+		WHERE distributor_id = $2 AND entry_date BETWEEN $3::date AND $4::date
+		ORDER BY entry_date, recorded_at, entry_type
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, distributorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.StatementEntry
+	for rows.Next() {
+		entry := &models.StatementEntry{}
+		var note *string
+		if err := rows.Scan(&entry.Date, &entry.Type, &entry.SourceID, &entry.Reference, &note, &entry.Debit, &entry.Credit); err != nil {
+			return nil, err
+		}
+		if note != nil {
+			entry.Description = *note
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// PendingRecipients lists distributors with an email address that owed a
+// balance at the end of asOf and have not yet been sent the statement for the
+// period starting periodStart
+func (r *statementRepo) PendingRecipients(ctx context.Context, tenantID uuid.UUID, asOf, periodStart time.Time) ([]*models.StatementRecipient, error) {
+	query := `
+		SELECT d.id, d.contact_email, b.balance
+		FROM (
+			SELECT distributor_id, SUM(debit - credit) AS balance
+			FROM (` + statementLedger + `) ledger
+			WHERE entry_date <= $2::date
+			GROUP BY distributor_id
+		) b
+		JOIN distributors d ON d.id = b.distributor_id AND d.tenant_id = $1
+		WHERE b.balance > 0
+			AND d.contact_email IS NOT NULL AND d.contact_email <> ''
+			AND NOT EXISTS (
+				SELECT 1 FROM statement_dispatches sd
+				WHERE sd.tenant_id = $1 AND sd.distributor_id = d.id AND sd.period_start = $3::date
+			)
+		ORDER BY d.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, asOf, periodStart)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*models.StatementRecipient
+	for rows.Next() {
+		recipient := &models.StatementRecipient{}
+		if err := rows.Scan(&recipient.DistributorID, &recipient.Email, &recipient.Balance); err != nil {
+			return nil, err
+		}
+		email, err := r.keyring.Decrypt(ctx, tenantID, &recipient.Email)
+		if err != nil {
+			return nil, err
+		}
+		recipient.Email = *email
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// ClaimDispatch records that a period's statement is being sent to a
+// distributor, reporting false when it already was
+func (r *statementRepo) ClaimDispatch(ctx context.Context, tenantID, distributorID uuid.UUID, periodStart time.Time, balance float64, recipient, objectKey string) (bool, error) {
+	query := `
+		INSERT INTO statement_dispatches (tenant_id, distributor_id, period_start, closing_balance, recipient, object_key, sent_at)
+		VALUES ($1, $2, $3::date, $4, $5, $6, NOW())
+		ON CONFLICT (tenant_id, distributor_id, period_start) DO NOTHING
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, distributorID, periodStart, balance, recipient, objectKey)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReleaseDispatch forgets a claimed dispatch whose email could not be sent, so
+// the next run retries it
+func (r *statementRepo) ReleaseDispatch(ctx context.Context, tenantID, distributorID uuid.UUID, periodStart time.Time) error {
+	query := `DELETE FROM statement_dispatches WHERE tenant_id = $1 AND distributor_id = $2 AND period_start = $3::date`
+	_, err := r.db.Exec(ctx, query, tenantID, distributorID, periodStart)
+	return err
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StatusHistoryRepository records order and invoice status transitions
+type StatusHistoryRepository interface {
+	Record(ctx context.Context, change *models.StatusChange) error
+	ListByEntity(ctx context.Context, tenantID uuid.UUID, entityType string, entityID uuid.UUID) ([]*models.StatusChange, error)
+}
+
+type statusHistoryRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewStatusHistoryRepo(db *pgxpool.Pool) StatusHistoryRepository {
+	return &statusHistoryRepo{db: db}
+}
+
+func (r *statusHistoryRepo) Record(ctx context.Context, change *models.StatusChange) error {
+	if change.ID == uuid.Nil {
+		change.ID = uuid.New()
+	}
+	query := `
+		INSERT INTO status_history (id, tenant_id, entity_type, entity_id, from_status, to_status, actor_id, comment, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, change.ID, change.TenantID, change.EntityType, change.EntityID, change.FromStatus,
+		change.ToStatus, change.ActorID, change.Comment).Scan(&change.CreatedAt)
+}
+
+// ListByEntity lists an entity's transitions, oldest first
+func (r *statusHistoryRepo) ListByEntity(ctx context.Context, tenantID uuid.UUID, entityType string, entityID uuid.UUID) ([]*models.StatusChange, error) {
+	query := `
+		SELECT h.id, h.tenant_id, h.entity_type, h.entity_id, h.from_status, h.to_status, h.actor_id,
+			NULLIF(TRIM(COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '')), ''), h.comment, h.created_at
+		FROM status_history h
+		LEFT JOIN users u ON u.id = h.actor_id
+		WHERE h.tenant_id = $1 AND h.entity_type = $2 AND h.entity_id = $3
+		ORDER BY h.created_at, h.id
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*models.StatusChange{}
+	for rows.Next() {
+		change := &models.StatusChange{}
+		if err := rows.Scan(&change.ID, &change.TenantID, &change.EntityType, &change.EntityID, &change.FromStatus, &change.ToStatus,
+			&change.ActorID, &change.ActorName, &change.Comment, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
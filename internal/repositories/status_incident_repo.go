@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StatusIncidentRepository interface {
+	// Create inserts the incident together with its first update
+	Create(ctx context.Context, incident *models.StatusIncident, update *models.StatusIncidentUpdate) error
+	// GetByID returns the incident, or nil when it does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*models.StatusIncident, error)
+	// List lists incidents, most recently started first; openOnly keeps the
+	// unresolved ones and since, when set, drops those resolved before it
+	List(ctx context.Context, openOnly bool, since *time.Time, limit, offset int) ([]*models.StatusIncident, error)
+	// AddUpdate appends the update and moves the incident to its status and,
+	// when given, the severity. It returns nil when the incident does not
+	// exist, and false with the incident unchanged when it is already resolved
+	AddUpdate(ctx context.Context, update *models.StatusIncidentUpdate, severity *string) (*models.StatusIncident, bool, error)
+	// ListUpdates returns the updates of the incidents, oldest first, keyed by incident
+	ListUpdates(ctx context.Context, incidentIDs []uuid.UUID) (map[uuid.UUID][]*models.StatusIncidentUpdate, error)
+}
+
+type statusIncidentRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewStatusIncidentRepo(db *pgxpool.Pool) StatusIncidentRepository {
+	return &statusIncidentRepo{db: db}
+}
+
+const statusIncidentColumns = `id, title, component, severity, status, started_at, resolved_at, created_by, created_at, updated_at`
+
+func scanStatusIncident(row rowScanner) (*models.StatusIncident, error) {
+	i := &models.StatusIncident{}
+	err := row.Scan(&i.ID, &i.Title, &i.Component, &i.Severity, &i.Status, &i.StartedAt, &i.ResolvedAt, &i.CreatedBy,
+		&i.CreatedAt, &i.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *statusIncidentRepo) Create(ctx context.Context, incident *models.StatusIncident, update *models.StatusIncidentUpdate) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO status_incidents (id, title, component, severity, status, started_at, resolved_at, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	err = tx.QueryRow(ctx, query, incident.ID, incident.Title, incident.Component, incident.Severity, incident.Status,
+		incident.StartedAt, incident.ResolvedAt, incident.CreatedBy).Scan(&incident.CreatedAt, &incident.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO status_incident_updates (id, incident_id, status, message, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at
+	`, update.ID, update.IncidentID, update.Status, update.Message, update.CreatedBy).Scan(&update.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *statusIncidentRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.StatusIncident, error) {
+	query := `SELECT ` + statusIncidentColumns + ` FROM status_incidents WHERE id = $1`
+	incident, err := scanStatusIncident(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+func (r *statusIncidentRepo) List(ctx context.Context, openOnly bool, since *time.Time, limit, offset int) ([]*models.StatusIncident, error) {
+	query := `
+		SELECT ` + statusIncidentColumns + `
+		FROM status_incidents
+		WHERE (NOT $1 OR resolved_at IS NULL)
+		  AND ($2::timestamptz IS NULL OR resolved_at IS NULL OR resolved_at >= $2)
+		ORDER BY started_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, openOnly, since, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []*models.StatusIncident
+	for rows.Next() {
+		incident, err := scanStatusIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
+
+func (r *statusIncidentRepo) AddUpdate(ctx context.Context, update *models.StatusIncidentUpdate, severity *string) (*models.StatusIncident, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT ` + statusIncidentColumns + ` FROM status_incidents WHERE id = $1 FOR UPDATE`
+	incident, err := scanStatusIncident(tx.QueryRow(ctx, query, update.IncidentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if incident.ResolvedAt != nil {
+		return incident, false, nil
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE status_incidents
+		SET status = $2,
+		    severity = COALESCE($3, severity),
+		    resolved_at = CASE WHEN $2 = 'resolved' THEN NOW() END,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+statusIncidentColumns,
+		update.IncidentID, update.Status, severity,
+	).Scan(&incident.ID, &incident.Title, &incident.Component, &incident.Severity, &incident.Status, &incident.StartedAt,
+		&incident.ResolvedAt, &incident.CreatedBy, &incident.CreatedAt, &incident.UpdatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO status_incident_updates (id, incident_id, status, message, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at
+	`, update.ID, update.IncidentID, update.Status, update.Message, update.CreatedBy).Scan(&update.CreatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+	return incident, true, tx.Commit(ctx)
+}
+
+func (r *statusIncidentRepo) ListUpdates(ctx context.Context, incidentIDs []uuid.UUID) (map[uuid.UUID][]*models.StatusIncidentUpdate, error) {
+	updates := make(map[uuid.UUID][]*models.StatusIncidentUpdate)
+	if len(incidentIDs) == 0 {
+		return updates, nil
+	}
+
+	query := `
+		SELECT id, incident_id, status, message, created_by, created_at
+		FROM status_incident_updates
+		WHERE incident_id = ANY($1)
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(ctx, query, incidentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := &models.StatusIncidentUpdate{}
+		if err := rows.Scan(&u.ID, &u.IncidentID, &u.Status, &u.Message, &u.CreatedBy, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		updates[u.IncidentID] = append(updates[u.IncidentID], u)
+	}
+	return updates, rows.Err()
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StockMinimumRepository interface {
+	// Upsert replaces the minimum for the product, or for the product in the
+	// warehouse when one is set
+	Upsert(ctx context.Context, minimum *models.StockMinimum) error
+	ListByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.StockMinimum, error)
+	ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]*models.StockMinimum, error)
+	Delete(ctx context.Context, tenantID, productID, id uuid.UUID) (bool, error)
+}
+
+type stockMinimumRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewStockMinimumRepo(db *pgxpool.Pool) StockMinimumRepository {
+	return &stockMinimumRepo{db: db}
+}
+
+const stockMinimumColumns = `id, tenant_id, product_id, warehouse_id, min_quantity, created_at, updated_at`
+
+func (r *stockMinimumRepo) Upsert(ctx context.Context, minimum *models.StockMinimum) error {
+	// Each kind of minimum has its own partial unique index to conflict on
+	conflict := `(tenant_id, product_id) WHERE warehouse_id IS NULL`
+	if minimum.WarehouseID != nil {
+		conflict = `(tenant_id, product_id, warehouse_id) WHERE warehouse_id IS NOT NULL`
+	}
+	query := `
+		INSERT INTO stock_minimums (id, tenant_id, product_id, warehouse_id, min_quantity, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT ` + conflict + ` DO UPDATE
+		SET min_quantity = EXCLUDED.min_quantity, updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, minimum.ID, minimum.TenantID, minimum.ProductID, minimum.WarehouseID, minimum.MinQuantity).
+		Scan(&minimum.ID, &minimum.CreatedAt, &minimum.UpdatedAt)
+}
+
+// ListByProduct lists the product's minimums, the product-wide one first
+func (r *stockMinimumRepo) ListByProduct(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.StockMinimum, error) {
+	query := `SELECT ` + stockMinimumColumns + ` FROM stock_minimums
+		WHERE tenant_id = $1 AND product_id = $2
+		ORDER BY warehouse_id NULLS FIRST`
+	return r.list(ctx, query, tenantID, productID)
+}
+
+func (r *stockMinimumRepo) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]*models.StockMinimum, error) {
+	query := `SELECT ` + stockMinimumColumns + ` FROM stock_minimums WHERE tenant_id = $1`
+	return r.list(ctx, query, tenantID)
+}
+
+func (r *stockMinimumRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.StockMinimum, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var minimums []*models.StockMinimum
+	for rows.Next() {
+		minimum := &models.StockMinimum{}
+		if err := rows.Scan(&minimum.ID, &minimum.TenantID, &minimum.ProductID, &minimum.WarehouseID,
+			&minimum.MinQuantity, &minimum.CreatedAt, &minimum.UpdatedAt); err != nil {
+			return nil, err
+		}
+		minimums = append(minimums, minimum)
+	}
+	return minimums, rows.Err()
+}
+
+func (r *stockMinimumRepo) Delete(ctx context.Context, tenantID, productID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM stock_minimums WHERE tenant_id = $1 AND product_id = $2 AND id = $3`, tenantID, productID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
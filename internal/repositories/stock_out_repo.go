@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StockOutRepository logs stock-out events and reads demand for fill rate analytics
+type StockOutRepository interface {
+	Record(ctx context.Context, event *models.StockOutEvent) error
+	ListEvents(ctx context.Context, tenantID uuid.UUID, from, to time.Time, productID, warehouseID *uuid.UUID, outcome string, limit, offset int) ([]*models.StockOutEvent, error)
+	Demand(ctx context.Context, tenantID uuid.UUID, from, to time.Time, productID, warehouseID *uuid.UUID, interval string) ([]*models.StockOutDemandRow, error)
+}
+
+type stockOutRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewStockOutRepo(db *pgxpool.Pool) StockOutRepository {
+	return &stockOutRepo{db: db}
+}
+
+func (r *stockOutRepo) Record(ctx context.Context, event *models.StockOutEvent) error {
+	query := `
+		INSERT INTO stock_out_events (id, tenant_id, product_id, warehouse_id, order_id, distributor_id, outcome, source,
+			requested_quantity, available_quantity, short_quantity, unit_price, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, event.ID, event.TenantID, event.ProductID, event.WarehouseID, event.OrderID, event.DistributorID,
+		event.Outcome, event.Source, event.RequestedQuantity, event.AvailableQuantity, event.ShortQuantity, event.UnitPrice).Scan(&event.CreatedAt)
+}
+
+// ListEvents lists events logged in [from, to), newest first
+func (r *stockOutRepo) ListEvents(ctx context.Context, tenantID uuid.UUID, from, to time.Time, productID, warehouseID *uuid.UUID, outcome string, limit, offset int) ([]*models.StockOutEvent, error) {
+	query := `
+		SELECT e.id, e.tenant_id, e.product_id, p.name, e.warehouse_id, w.name, e.order_id, e.distributor_id, e.outcome, e.source,
+			e.requested_quantity, e.available_quantity, e.short_quantity, e.unit_price::float8, e.created_at
+		FROM stock_out_events e
+		JOIN products p ON p.id = e.product_id
+		JOIN warehouses w ON w.id = e.warehouse_id
+		WHERE e.tenant_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+			AND ($4::uuid IS NULL OR e.product_id = $4) AND ($5::uuid IS NULL OR e.warehouse_id = $5)
+			AND ($6::text = '' OR e.outcome = $6)
+		ORDER BY e.created_at DESC
+		LIMIT $7 OFFSET $8
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to, productID, warehouseID, outcome, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.StockOutEvent
+	for rows.Next() {
+		e := &models.StockOutEvent{}
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.ProductID, &e.ProductName, &e.WarehouseID, &e.WarehouseName, &e.OrderID, &e.DistributorID,
+			&e.Outcome, &e.Source, &e.RequestedQuantity, &e.AvailableQuantity, &e.ShortQuantity, &e.UnitPrice, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Demand totals sales demand and shortfall in [from, to) per product,
+// warehouse and interval ("day", "week" or "month"). Orders count when
+// created unless cancelled without ever having been back-ordered; rejected
+// requests when logged; back-ordered orders once, when first logged
+func (r *stockOutRepo) Demand(ctx context.Context, tenantID uuid.UUID, from, to time.Time, productID, warehouseID *uuid.UUID, interval string) ([]*models.StockOutDemandRow, error) {
+	query := `
+		WITH backorders AS (
+			SELECT order_id, product_id, warehouse_id, MIN(created_at) AS first_at,
+				MAX(short_quantity) AS short_quantity, MAX(unit_price) AS unit_price
+			FROM stock_out_events
+			WHERE tenant_id = $1 AND outcome = 'backordered' AND order_id IS NOT NULL
+			GROUP BY order_id, product_id, warehouse_id
+		), demand AS (
+			SELECT o.product_id, o.warehouse_id, date_trunc($6::text, o.created_at) AS period,
+				SUM(o.quantity) AS ordered_units, COUNT(*) AS orders,
+				0 AS rejected_units, 0 AS rejected_requests, 0::numeric AS rejected_value,
+				0 AS backordered_units, 0 AS backordered_orders, 0::numeric AS cancelled_value
+			FROM orders o
+			WHERE o.tenant_id = $1 AND o.order_type = 'sales' AND o.created_at >= $2 AND o.created_at < $3
+				AND (o.status <> 'cancelled' OR EXISTS (SELECT 1 FROM backorders b WHERE b.order_id = o.id))
+			GROUP BY 1, 2, 3
+			UNION ALL
+			SELECT e.product_id, e.warehouse_id, date_trunc($6::text, e.created_at),
+				0, 0, SUM(e.requested_quantity), COUNT(*), SUM(e.short_quantity * e.unit_price), 0, 0, 0
+			FROM stock_out_events e
+			WHERE e.tenant_id = $1 AND e.outcome = 'rejected' AND e.created_at >= $2 AND e.created_at < $3
+			GROUP BY 1, 2, 3
+			UNION ALL
+			SELECT b.product_id, b.warehouse_id, date_trunc($6::text, b.first_at),
+				0, 0, 0, 0, 0, SUM(b.short_quantity), COUNT(*),
+				SUM(CASE WHEN o.status = 'cancelled' THEN b.short_quantity * b.unit_price ELSE 0 END)
+			FROM backorders b
+			JOIN orders o ON o.id = b.order_id
+			WHERE b.first_at >= $2 AND b.first_at < $3
+			GROUP BY 1, 2, 3
+		)
+		SELECT d.product_id, p.name, d.warehouse_id, w.name, d.period,
+			SUM(d.ordered_units)::int, SUM(d.orders)::int, SUM(d.rejected_units)::int, SUM(d.rejected_requests)::int,
+			SUM(d.rejected_value)::float8, SUM(d.backordered_units)::int, SUM(d.backordered_orders)::int, SUM(d.cancelled_value)::float8
+		FROM demand d
+		JOIN products p ON p.id = d.product_id
+		JOIN warehouses w ON w.id = d.warehouse_id
+		WHERE ($4::uuid IS NULL OR d.product_id = $4) AND ($5::uuid IS NULL OR d.warehouse_id = $5)
+		GROUP BY d.product_id, p.name, d.warehouse_id, w.name, d.period
+		ORDER BY d.period, p.name, w.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, from, to, productID, warehouseID, interval)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var demand []*models.StockOutDemandRow
+	for rows.Next() {
+		d := &models.StockOutDemandRow{}
+		if err := rows.Scan(&d.ProductID, &d.ProductName, &d.WarehouseID, &d.WarehouseName, &d.Period, &d.OrderedUnits, &d.Orders,
+			&d.RejectedUnits, &d.RejectedRequests, &d.RejectedValue, &d.BackorderedUnits, &d.BackorderedOrders, &d.CancelledBackorder); err != nil {
+			return nil, err
+		}
+		demand = append(demand, d)
+	}
+	return demand, rows.Err()
+}
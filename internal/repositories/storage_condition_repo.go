@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StorageConditionRepository interface {
+	GetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductStorageRequirement, error)
+	UpsertProductRequirement(ctx context.Context, req *models.ProductStorageRequirement) error
+	GetWarehouseCapability(ctx context.Context, tenantID, warehouseID uuid.UUID) (*models.WarehouseStorageCapability, error)
+	UpsertWarehouseCapability(ctx context.Context, capability *models.WarehouseStorageCapability) error
+	Placements(ctx context.Context, tenantID uuid.UUID) ([]*models.StoragePlacement, error)
+}
+
+type storageConditionRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewStorageConditionRepo(db *pgxpool.Pool) StorageConditionRepository {
+	return &storageConditionRepo{db: db}
+}
+
+func (r *storageConditionRepo) GetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductStorageRequirement, error) {
+	query := `
+		SELECT product_id, tenant_id, cold_chain, shade, hazmat_class, updated_at
+		FROM product_storage_requirements
+		WHERE tenant_id = $1 AND product_id = $2
+	`
+	req := &models.ProductStorageRequirement{}
+	err := r.db.QueryRow(ctx, query, tenantID, productID).Scan(&req.ProductID, &req.TenantID, &req.ColdChain, &req.Shade, &req.HazmatClass, &req.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (r *storageConditionRepo) UpsertProductRequirement(ctx context.Context, req *models.ProductStorageRequirement) error {
+	query := `
+		INSERT INTO product_storage_requirements (product_id, tenant_id, cold_chain, shade, hazmat_class, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (product_id) DO UPDATE
+		SET cold_chain = EXCLUDED.cold_chain, shade = EXCLUDED.shade, hazmat_class = EXCLUDED.hazmat_class, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, req.ProductID, req.TenantID, req.ColdChain, req.Shade, req.HazmatClass).Scan(&req.UpdatedAt)
+}
+
+func (r *storageConditionRepo) GetWarehouseCapability(ctx context.Context, tenantID, warehouseID uuid.UUID) (*models.WarehouseStorageCapability, error) {
+	query := `
+		SELECT warehouse_id, tenant_id, cold_chain, shade, hazmat_classes, updated_at
+		FROM warehouse_storage_capabilities
+		WHERE tenant_id = $1 AND warehouse_id = $2
+	`
+	c := &models.WarehouseStorageCapability{}
+	err := r.db.QueryRow(ctx, query, tenantID, warehouseID).Scan(&c.WarehouseID, &c.TenantID, &c.ColdChain, &c.Shade, &c.HazmatClasses, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *storageConditionRepo) UpsertWarehouseCapability(ctx context.Context, capability *models.WarehouseStorageCapability) error {
+	query := `
+		INSERT INTO warehouse_storage_capabilities (warehouse_id, tenant_id, cold_chain, shade, hazmat_classes, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (warehouse_id) DO UPDATE
+		SET cold_chain = EXCLUDED.cold_chain, shade = EXCLUDED.shade, hazmat_classes = EXCLUDED.hazmat_classes, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, capability.WarehouseID, capability.TenantID, capability.ColdChain, capability.Shade, capability.HazmatClasses).
+		Scan(&capability.UpdatedAt)
+}
+
+// Placements returns stock on hand of products that have storage
+// requirements, with the capability of the warehouse holding it
+func (r *storageConditionRepo) Placements(ctx context.Context, tenantID uuid.UUID) ([]*models.StoragePlacement, error) {
+	query := `
+		SELECT i.warehouse_id, w.name, i.product_id, p.name, i.quantity,
+		       psr.cold_chain, psr.shade, psr.hazmat_class, psr.updated_at,
+		       wsc.warehouse_id IS NOT NULL, COALESCE(wsc.cold_chain, FALSE), COALESCE(wsc.shade, FALSE),
+		       COALESCE(wsc.hazmat_classes, '{}'), COALESCE(wsc.updated_at, NOW())
+		FROM inventory i
+		JOIN product_storage_requirements psr ON psr.product_id = i.product_id
+		JOIN products p ON p.id = i.product_id
+		JOIN warehouses w ON w.id = i.warehouse_id
+		LEFT JOIN warehouse_storage_capabilities wsc ON wsc.warehouse_id = i.warehouse_id
+		WHERE i.tenant_id = $1 AND i.quantity > 0
+		ORDER BY w.name, p.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var placements []*models.StoragePlacement
+	for rows.Next() {
+		p := &models.StoragePlacement{
+			Requirement: &models.ProductStorageRequirement{TenantID: tenantID},
+		}
+		c := &models.WarehouseStorageCapability{TenantID: tenantID}
+		var hasCapability bool
+		if err := rows.Scan(&p.WarehouseID, &p.WarehouseName, &p.ProductID, &p.ProductName, &p.Quantity,
+			&p.Requirement.ColdChain, &p.Requirement.Shade, &p.Requirement.HazmatClass, &p.Requirement.UpdatedAt,
+			&hasCapability, &c.ColdChain, &c.Shade, &c.HazmatClasses, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		p.Requirement.ProductID = p.ProductID
+		if hasCapability {
+			c.WarehouseID = p.WarehouseID
+			p.Capability = c
+		}
+		placements = append(placements, p)
+	}
+	return placements, rows.Err()
+}
@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SupplierPriceListRepository interface {
+	Create(ctx context.Context, list *models.SupplierPriceList) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.SupplierPriceList, error)
+	ListBySupplier(ctx context.Context, tenantID, supplierID uuid.UUID) ([]*models.SupplierPriceList, error)
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+	CurrentQuotes(ctx context.Context, tenantID, productID uuid.UUID, quantity int, asOf time.Time) ([]*models.SupplierQuote, error)
+	DeliveryPerformance(ctx context.Context, tenantID uuid.UUID, supplierIDs []uuid.UUID, since time.Time) ([]*models.SupplierDeliveryPerformance, error)
+}
+
+type supplierPriceListRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSupplierPriceListRepo(db *pgxpool.Pool) SupplierPriceListRepository {
+	return &supplierPriceListRepo{db: db}
+}
+
+const supplierPriceListColumns = `pl.id, pl.tenant_id, pl.supplier_id, s.name, pl.name, pl.valid_from, pl.valid_to, pl.notes, pl.created_at, pl.updated_at`
+
+func scanSupplierPriceList(row rowScanner) (*models.SupplierPriceList, error) {
+	list := &models.SupplierPriceList{}
+	err := row.Scan(&list.ID, &list.TenantID, &list.SupplierID, &list.SupplierName, &list.Name, &list.ValidFrom, &list.ValidTo, &list.Notes, &list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Create stores the price list and its items in one transaction
+func (r *supplierPriceListRepo) Create(ctx context.Context, list *models.SupplierPriceList) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO supplier_price_lists (id, tenant_id, supplier_id, name, valid_from, valid_to, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	if err := tx.QueryRow(ctx, query, list.ID, list.TenantID, list.SupplierID, list.Name, list.ValidFrom, list.ValidTo, list.Notes).
+		Scan(&list.CreatedAt, &list.UpdatedAt); err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		query := `
+			INSERT INTO supplier_price_list_items (id, price_list_id, product_id, min_quantity, unit_price, lead_time_days)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		if _, err := tx.Exec(ctx, query, item.ID, list.ID, item.ProductID, item.MinQuantity, item.UnitPrice, item.LeadTimeDays); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *supplierPriceListRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.SupplierPriceList, error) {
+	query := `
+		SELECT ` + supplierPriceListColumns + `
+		FROM supplier_price_lists pl
+		JOIN suppliers s ON s.id = pl.supplier_id
+		WHERE pl.tenant_id = $1 AND pl.id = $2
+	`
+	list, err := scanSupplierPriceList(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	itemsQuery := `
+		SELECT i.id, i.price_list_id, i.product_id, p.name, i.min_quantity, i.unit_price::float8, i.lead_time_days
+		FROM supplier_price_list_items i
+		JOIN products p ON p.id = i.product_id
+		WHERE i.price_list_id = $1
+		ORDER BY p.name, i.min_quantity
+	`
+	rows, err := r.db.Query(ctx, itemsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := &models.SupplierPriceListItem{}
+		if err := rows.Scan(&item.ID, &item.PriceListID, &item.ProductID, &item.ProductName, &item.MinQuantity, &item.UnitPrice, &item.LeadTimeDays); err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+	}
+	return list, rows.Err()
+}
+
+// ListBySupplier returns the supplier's price lists, newest first, without items
+func (r *supplierPriceListRepo) ListBySupplier(ctx context.Context, tenantID, supplierID uuid.UUID) ([]*models.SupplierPriceList, error) {
+	query := `
+		SELECT ` + supplierPriceListColumns + `
+		FROM supplier_price_lists pl
+		JOIN suppliers s ON s.id = pl.supplier_id
+		WHERE pl.tenant_id = $1 AND pl.supplier_id = $2
+		ORDER BY pl.valid_from DESC, pl.created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []*models.SupplierPriceList
+	for rows.Next() {
+		list, err := scanSupplierPriceList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+func (r *supplierPriceListRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM supplier_price_lists WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CurrentQuotes returns each supplier's lowest price for the product among
+// price lists valid on asOf and quantity breaks the quantity reaches
+func (r *supplierPriceListRepo) CurrentQuotes(ctx context.Context, tenantID, productID uuid.UUID, quantity int, asOf time.Time) ([]*models.SupplierQuote, error) {
+	query := `
+		SELECT DISTINCT ON (pl.supplier_id)
+			pl.supplier_id, s.name, pl.id, i.min_quantity, i.unit_price::float8, i.lead_time_days, pl.valid_to
+		FROM supplier_price_list_items i
+		JOIN supplier_price_lists pl ON pl.id = i.price_list_id
+		JOIN suppliers s ON s.id = pl.supplier_id
+		WHERE pl.tenant_id = $1 AND i.product_id = $2 AND i.min_quantity <= $3
+		  AND pl.valid_from <= $4::date AND (pl.valid_to IS NULL OR pl.valid_to >= $4::date)
+		ORDER BY pl.supplier_id, i.unit_price, i.lead_time_days
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productID, quantity, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []*models.SupplierQuote
+	for rows.Next() {
+		quote := &models.SupplierQuote{}
+		if err := rows.Scan(&quote.SupplierID, &quote.SupplierName, &quote.PriceListID, &quote.MinQuantity, &quote.UnitPrice, &quote.LeadTimeDays, &quote.ValidTo); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes, rows.Err()
+}
+
+// DeliveryPerformance counts purchase orders received from each supplier since
+// the given time and how many arrived by their expected delivery date
+func (r *supplierPriceListRepo) DeliveryPerformance(ctx context.Context, tenantID uuid.UUID, supplierIDs []uuid.UUID, since time.Time) ([]*models.SupplierDeliveryPerformance, error) {
+	query := `
+		SELECT o.supplier_id,
+			COUNT(*)::int,
+			COUNT(*) FILTER (WHERE o.expected_delivery IS NULL OR pr.received_at::date <= o.expected_delivery::date)::int
+		FROM purchase_receipt_lines l
+		JOIN purchase_receipts pr ON pr.id = l.receipt_id
+		JOIN orders o ON o.id = l.order_id
+		WHERE pr.tenant_id = $1 AND o.supplier_id = ANY($2) AND pr.received_at >= $3
+		GROUP BY o.supplier_id
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, supplierIDs, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var performance []*models.SupplierDeliveryPerformance
+	for rows.Next() {
+		p := &models.SupplierDeliveryPerformance{}
+		if err := rows.Scan(&p.SupplierID, &p.Received, &p.OnTime); err != nil {
+			return nil, err
+		}
+		performance = append(performance, p)
+	}
+	return performance, rows.Err()
+}
@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
+	"agromart2/internal/pii"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -10,65 +13,126 @@ import (
 type SupplierRepository interface {
 	Create(ctx context.Context, supplier *models.Supplier) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Supplier, error)
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Supplier, error)
 	Update(ctx context.Context, supplier *models.Supplier) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Supplier, error)
+	ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Supplier, error)
 	GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Supplier, error)
+	Archive(ctx context.Context, tenantID, id uuid.UUID) error
+	OrderCount(ctx context.Context, tenantID, id uuid.UUID) (int, error)
 }
 
 type supplierRepo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
 }
 
-func NewSupplierRepository(db *pgxpool.Pool) SupplierRepository {
-	return &supplierRepo{db: db}
+// NewSupplierRepository creates the repository; contact emails, phones,
+// GSTINs and bank account numbers are encrypted with keyring, or kept in
+// plaintext when it is nil
+func NewSupplierRepository(db *pgxpool.Pool, keyring *pii.DataKeyRing) SupplierRepository {
+	return &supplierRepo{db: db, keyring: keyring}
+}
+
+// open decrypts a supplier's contact columns after a scan
+func (r *supplierRepo) open(ctx context.Context, supplier *models.Supplier) error {
+	return r.keyring.DecryptFields(ctx, supplier.TenantID, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.GSTIN, &supplier.BankAccountNumber)
 }
 
 func (r *supplierRepo) Create(ctx context.Context, supplier *models.Supplier) error {
+	contact, err := sealContact(ctx, r.keyring, supplier.TenantID, supplier.ContactEmail, supplier.ContactPhone, supplier.GSTIN, supplier.BankAccountNumber)
+	if err != nil {
+		return err
+	}
 	query := `
-		INSERT INTO suppliers (id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		INSERT INTO suppliers (id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin,
+			contact_email_bidx, contact_phone_bidx, gstin_bidx, bank_account_number, bank_ifsc, bank_account_number_bidx, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
 	`
-	_, err := r.db.Exec(ctx, query, supplier.ID, supplier.TenantID, supplier.Name, supplier.ContactEmail, supplier.ContactPhone, supplier.Address, supplier.LicenseNumber)
+	_, err = r.db.Exec(ctx, query, supplier.ID, supplier.TenantID, supplier.Name, contact.email, contact.phone, supplier.Address, supplier.LicenseNumber, contact.gstin,
+		contact.emailIndex, contact.phoneIndex, contact.gstinIndex, contact.bankAccount, supplier.BankIFSC, contact.bankAccountIndex)
 	return err
 }
 
 func (r *supplierRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Supplier, error) {
 	supplier := &models.Supplier{}
 	query := `
-		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, created_at, updated_at
 		FROM suppliers
 		WHERE tenant_id = $1 AND id = $2
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&supplier.ID, &supplier.TenantID, &supplier.Name, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.Address, &supplier.LicenseNumber, &supplier.CreatedAt, &supplier.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&supplier.ID, &supplier.TenantID, &supplier.Name, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.Address, &supplier.LicenseNumber, &supplier.GSTIN, &supplier.BankAccountNumber, &supplier.BankIFSC, &supplier.ArchivedAt, &supplier.CreatedAt, &supplier.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.open(ctx, supplier); err != nil {
+		return nil, err
+	}
 	return supplier, nil
 }
 
+// GetByIDs loads the tenant's suppliers among ids in one query, archived ones
+// included; unknown IDs are skipped
+func (r *supplierRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Supplier, error) {
+	query := `
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, created_at, updated_at
+		FROM suppliers
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppliers []*models.Supplier
+	for rows.Next() {
+		supplier := &models.Supplier{}
+		if err := rows.Scan(&supplier.ID, &supplier.TenantID, &supplier.Name, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.Address, &supplier.LicenseNumber, &supplier.GSTIN, &supplier.BankAccountNumber, &supplier.BankIFSC, &supplier.ArchivedAt, &supplier.CreatedAt, &supplier.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := r.open(ctx, supplier); err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, supplier)
+	}
+	return suppliers, rows.Err()
+}
+
 func (r *supplierRepo) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Supplier, error) {
 	supplier := &models.Supplier{}
 	query := `
-		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, created_at, updated_at
 		FROM suppliers
-		WHERE tenant_id = $1 AND name = $2
+		WHERE tenant_id = $1 AND LOWER(name) = LOWER($2) AND archived_at IS NULL
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, name).Scan(&supplier.ID, &supplier.TenantID, &supplier.Name, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.Address, &supplier.LicenseNumber, &supplier.CreatedAt, &supplier.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, name).Scan(&supplier.ID, &supplier.TenantID, &supplier.Name, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.Address, &supplier.LicenseNumber, &supplier.GSTIN, &supplier.BankAccountNumber, &supplier.BankIFSC, &supplier.ArchivedAt, &supplier.CreatedAt, &supplier.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.open(ctx, supplier); err != nil {
+		return nil, err
+	}
 	return supplier, nil
 }
 
 func (r *supplierRepo) Update(ctx context.Context, supplier *models.Supplier) error {
+	contact, err := sealContact(ctx, r.keyring, supplier.TenantID, supplier.ContactEmail, supplier.ContactPhone, supplier.GSTIN, supplier.BankAccountNumber)
+	if err != nil {
+		return err
+	}
 	query := `
 		UPDATE suppliers
-		SET name = $1, contact_email = $2, contact_phone = $3, address = $4, license_number = $5, updated_at = NOW()
-		WHERE tenant_id = $6 AND id = $7
+		SET name = $1, contact_email = $2, contact_phone = $3, address = $4, license_number = $5, gstin = $6,
+			contact_email_bidx = $9, contact_phone_bidx = $10, gstin_bidx = $11,
+			bank_account_number = $12, bank_ifsc = $13, bank_account_number_bidx = $14, updated_at = NOW()
+		WHERE tenant_id = $7 AND id = $8
 	`
-	_, err := r.db.Exec(ctx, query, supplier.Name, supplier.ContactEmail, supplier.ContactPhone, supplier.Address, supplier.LicenseNumber, supplier.TenantID, supplier.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, supplier.ID, "updated_at", query, []interface{}{supplier.Name, contact.email, contact.phone, supplier.Address, supplier.LicenseNumber, contact.gstin, supplier.TenantID, supplier.ID,
+		contact.emailIndex, contact.phoneIndex, contact.gstinIndex, contact.bankAccount, supplier.BankIFSC, contact.bankAccountIndex})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *supplierRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -77,15 +141,27 @@ func (r *supplierRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	return err
 }
 
+// SupplierSortColumns whitelists the fields the supplier list can be sorted by
+var SupplierSortColumns = map[string]string{
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 func (r *supplierRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Supplier, error) {
-	query := `
-		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, created_at, updated_at
+	return r.ListPage(ctx, tenantID, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists suppliers in the page's sort order, newest first by default
+func (r *supplierRepo) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Supplier, error) {
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, gstin, bank_account_number, bank_ifsc, archived_at, created_at, updated_at
 		FROM suppliers
-		WHERE tenant_id = $1
-		ORDER BY created_at DESC
+		WHERE tenant_id = $1 AND archived_at IS NULL
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("created_at DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -94,10 +170,28 @@ func (r *supplierRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offs
 	var suppliers []*models.Supplier
 	for rows.Next() {
 		supplier := &models.Supplier{}
-		if err := rows.Scan(&supplier.ID, &supplier.TenantID, &supplier.Name, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.Address, &supplier.LicenseNumber, &supplier.CreatedAt, &supplier.UpdatedAt); err != nil {
+		if err := rows.Scan(&supplier.ID, &supplier.TenantID, &supplier.Name, &supplier.ContactEmail, &supplier.ContactPhone, &supplier.Address, &supplier.LicenseNumber, &supplier.GSTIN, &supplier.BankAccountNumber, &supplier.BankIFSC, &supplier.ArchivedAt, &supplier.CreatedAt, &supplier.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := r.open(ctx, supplier); err != nil {
 			return nil, err
 		}
 		suppliers = append(suppliers, supplier)
 	}
 	return suppliers, nil
-}
\ No newline at end of file
+}
+
+// Archive hides a supplier from lists and name lookups while keeping it for
+// the orders that reference it
+func (r *supplierRepo) Archive(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `UPDATE suppliers SET archived_at = NOW(), updated_at = NOW() WHERE tenant_id = $1 AND id = $2 AND archived_at IS NULL`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+// OrderCount counts the orders that reference the supplier
+func (r *supplierRepo) OrderCount(ctx context.Context, tenantID, id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND supplier_id = $2`, tenantID, id).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SyncRepository interface {
+	PageBound(ctx context.Context, tenantID uuid.UUID, since int64, entities []string, limit int, lag time.Duration) (upper int64, hasMore bool, err error)
+	ListChanges(ctx context.Context, tenantID uuid.UUID, since, upper int64, entities []string) ([]models.SyncChange, error)
+	GetProducts(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.SyncProduct, error)
+	GetPrices(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]*models.SyncPrice, error)
+	GetCustomers(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Distributor, error)
+	GetOrders(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Order, error)
+	FindOrderTenant(ctx context.Context, orderID uuid.UUID) (*uuid.UUID, error)
+	HasTombstone(ctx context.Context, tenantID uuid.UUID, entity string, recordID uuid.UUID) (bool, error)
+	PriceChangedSince(ctx context.Context, tenantID, productID uuid.UUID, since int64) (bool, error)
+}
+
+type syncRepo struct {
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
+}
+
+func NewSyncRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) SyncRepository {
+	return &syncRepo{db: db, keyring: keyring}
+}
+
+// PageBound picks the highest seq to serve after since: at most limit log
+// rows, skipping rows newer than lag so transactions still committing are
+// not jumped over. Returns since when nothing is ready.
+func (r *syncRepo) PageBound(ctx context.Context, tenantID uuid.UUID, since int64, entities []string, limit int, lag time.Duration) (int64, bool, error) {
+	query := `
+		SELECT seq
+		FROM sync_changes
+		WHERE tenant_id = $1 AND seq > $2 AND entity = ANY($3) AND changed_at <= NOW() - make_interval(secs => $4)
+		ORDER BY seq
+		LIMIT $5
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, since, entities, lag.Seconds(), limit+1)
+	if err != nil {
+		return since, false, err
+	}
+	defer rows.Close()
+
+	upper := since
+	count := 0
+	for rows.Next() {
+		var seq int64
+		if err := rows.Scan(&seq); err != nil {
+			return since, false, err
+		}
+		count++
+		if count > limit {
+			return upper, true, nil
+		}
+		upper = seq
+	}
+	return upper, false, rows.Err()
+}
+
+// ListChanges collapses the log in (since, upper] to the latest operation per record
+func (r *syncRepo) ListChanges(ctx context.Context, tenantID uuid.UUID, since, upper int64, entities []string) ([]models.SyncChange, error) {
+	query := `
+		SELECT seq, entity, record_id, operation
+		FROM (
+			SELECT DISTINCT ON (entity, record_id) seq, entity, record_id, operation
+			FROM sync_changes
+			WHERE tenant_id = $1 AND seq > $2 AND seq <= $3 AND entity = ANY($4)
+			ORDER BY entity, record_id, seq DESC
+		) latest
+		ORDER BY seq
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, since, upper, entities)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.SyncChange
+	for rows.Next() {
+		var change models.SyncChange
+		if err := rows.Scan(&change.Seq, &change.Entity, &change.RecordID, &change.Operation); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+func (r *syncRepo) GetProducts(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.SyncProduct, error) {
+	query := `
+		SELECT id, category_id, name, description, barcode, unit_of_measure, translations, updated_at
+		FROM products
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.SyncProduct
+	for rows.Next() {
+		product := &models.SyncProduct{}
+		if err := rows.Scan(&product.ID, &product.CategoryID, &product.Name, &product.Description, &product.Barcode, &product.UnitOfMeasure, &product.Translations, &product.UpdatedAt); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+func (r *syncRepo) GetPrices(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) ([]*models.SyncPrice, error) {
+	query := `
+		SELECT id, unit_price, updated_at
+		FROM products
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []*models.SyncPrice
+	for rows.Next() {
+		price := &models.SyncPrice{}
+		if err := rows.Scan(&price.ProductID, &price.UnitPrice, &price.UpdatedAt); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
+
+func (r *syncRepo) GetCustomers(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Distributor, error) {
+	query := `
+		SELECT id, tenant_id, name, contact_email, contact_phone, address, license_number, latitude, longitude, geofence_radius_m, created_at, updated_at
+		FROM distributors
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []*models.Distributor
+	for rows.Next() {
+		customer := &models.Distributor{}
+		if err := rows.Scan(&customer.ID, &customer.TenantID, &customer.Name, &customer.ContactEmail, &customer.ContactPhone, &customer.Address, &customer.LicenseNumber, &customer.Latitude, &customer.Longitude, &customer.GeofenceRadiusM, &customer.CreatedAt, &customer.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := r.keyring.DecryptFields(ctx, tenantID, &customer.ContactEmail, &customer.ContactPhone); err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	return customers, rows.Err()
+}
+
+func (r *syncRepo) GetOrders(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Order, error) {
+	query := `
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, branch_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		FROM orders
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*models.Order
+	for rows.Next() {
+		order := &models.Order{}
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID, &order.BranchID, &order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// FindOrderTenant returns the tenant owning an order ID, or nil when the ID is unused
+func (r *syncRepo) FindOrderTenant(ctx context.Context, orderID uuid.UUID) (*uuid.UUID, error) {
+	var tenantID uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT tenant_id FROM orders WHERE id = $1`, orderID).Scan(&tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tenantID, nil
+}
+
+func (r *syncRepo) HasTombstone(ctx context.Context, tenantID uuid.UUID, entity string, recordID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sync_changes
+			WHERE tenant_id = $1 AND entity = $2 AND record_id = $3 AND operation = 'delete'
+		)
+	`
+	var exists bool
+	err := r.db.QueryRow(ctx, query, tenantID, entity, recordID).Scan(&exists)
+	return exists, err
+}
+
+// PriceChangedSince reports whether a product's price was changed after the given seq
+func (r *syncRepo) PriceChangedSince(ctx context.Context, tenantID, productID uuid.UUID, since int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sync_changes
+			WHERE tenant_id = $1 AND entity = 'price_lists' AND record_id = $2 AND seq > $3
+		)
+	`
+	var changed bool
+	err := r.db.QueryRow(ctx, query, tenantID, productID, since).Scan(&changed)
+	return changed, err
+}
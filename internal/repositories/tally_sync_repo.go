@@ -0,0 +1,322 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TallySyncRepository interface {
+	GetCursor(ctx context.Context, tenantID uuid.UUID, entity string) (*models.TallySyncCursor, error)
+	SaveCursor(ctx context.Context, cursor *models.TallySyncCursor) error
+	GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.TallySyncSettings, error)
+	SaveSettings(ctx context.Context, settings *models.TallySyncSettings) error
+	ListDueSettings(ctx context.Context, now time.Time, limit int) ([]*models.TallySyncSettings, error)
+	ScheduleNextRun(ctx context.Context, tenantID uuid.UUID, nextRunAt time.Time) error
+	CreateRun(ctx context.Context, run *models.TallyExportRun) error
+	FinishRun(ctx context.Context, run *models.TallyExportRun) error
+	GetRun(ctx context.Context, tenantID, id uuid.UUID) (*models.TallyExportRun, error)
+	GetLastRun(ctx context.Context, tenantID uuid.UUID, entity string) (*models.TallyExportRun, error)
+	ListInvoicesAfter(ctx context.Context, tenantID uuid.UUID, cursor *models.TallySyncCursor, before time.Time, limit int) ([]*models.Invoice, error)
+	ListOrdersAfter(ctx context.Context, tenantID uuid.UUID, cursor *models.TallySyncCursor, before time.Time, limit int) ([]*models.Order, error)
+	CountPending(ctx context.Context, tenantID uuid.UUID, cursor *models.TallySyncCursor) (int, error)
+	FindDuplicateOrder(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*uuid.UUID, error)
+	FindDuplicateInvoice(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) (*uuid.UUID, error)
+	GetOrderUpdatedAt(ctx context.Context, tenantID, orderID uuid.UUID) (*time.Time, error)
+}
+
+type tallySyncRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTallySyncRepo(db *pgxpool.Pool) TallySyncRepository {
+	return &tallySyncRepo{db: db}
+}
+
+// GetCursor returns the entity's cursor, or an empty cursor before the first export
+func (r *tallySyncRepo) GetCursor(ctx context.Context, tenantID uuid.UUID, entity string) (*models.TallySyncCursor, error) {
+	cursor := &models.TallySyncCursor{TenantID: tenantID, Entity: entity}
+	query := `
+		SELECT last_exported_at, last_exported_id, updated_at
+		FROM tally_sync_cursors
+		WHERE tenant_id = $1 AND entity = $2
+	`
+	err := r.db.QueryRow(ctx, query, tenantID, entity).Scan(&cursor.LastExportedAt, &cursor.LastExportedID, &cursor.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return cursor, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+func (r *tallySyncRepo) SaveCursor(ctx context.Context, cursor *models.TallySyncCursor) error {
+	query := `
+		INSERT INTO tally_sync_cursors (tenant_id, entity, last_exported_at, last_exported_id, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id, entity) DO UPDATE
+		SET last_exported_at = EXCLUDED.last_exported_at, last_exported_id = EXCLUDED.last_exported_id, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, cursor.TenantID, cursor.Entity, cursor.LastExportedAt, cursor.LastExportedID).Scan(&cursor.UpdatedAt)
+}
+
+// GetSettings returns the tenant's schedule, or disabled defaults when none is saved
+func (r *tallySyncRepo) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.TallySyncSettings, error) {
+	settings := &models.TallySyncSettings{
+		TenantID:        tenantID,
+		IntervalMinutes: 1440,
+		Entities:        []string{models.TallyEntityInvoices, models.TallyEntityOrders},
+	}
+	query := `
+		SELECT enabled, interval_minutes, entities, next_run_at, updated_at
+		FROM tally_sync_settings
+		WHERE tenant_id = $1
+	`
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&settings.Enabled, &settings.IntervalMinutes, &settings.Entities, &settings.NextRunAt, &settings.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *tallySyncRepo) SaveSettings(ctx context.Context, settings *models.TallySyncSettings) error {
+	query := `
+		INSERT INTO tally_sync_settings (tenant_id, enabled, interval_minutes, entities, next_run_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, interval_minutes = EXCLUDED.interval_minutes, entities = EXCLUDED.entities,
+			next_run_at = EXCLUDED.next_run_at, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, settings.TenantID, settings.Enabled, settings.IntervalMinutes, settings.Entities, settings.NextRunAt).Scan(&settings.UpdatedAt)
+}
+
+// ListDueSettings returns enabled schedules across all tenants whose next run has passed
+func (r *tallySyncRepo) ListDueSettings(ctx context.Context, now time.Time, limit int) ([]*models.TallySyncSettings, error) {
+	query := `
+		SELECT tenant_id, enabled, interval_minutes, entities, next_run_at, updated_at
+		FROM tally_sync_settings
+		WHERE enabled AND (next_run_at IS NULL OR next_run_at <= $1)
+		ORDER BY next_run_at NULLS FIRST
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []*models.TallySyncSettings
+	for rows.Next() {
+		settings := &models.TallySyncSettings{}
+		if err := rows.Scan(&settings.TenantID, &settings.Enabled, &settings.IntervalMinutes, &settings.Entities, &settings.NextRunAt, &settings.UpdatedAt); err != nil {
+			return nil, err
+		}
+		due = append(due, settings)
+	}
+	return due, rows.Err()
+}
+
+func (r *tallySyncRepo) ScheduleNextRun(ctx context.Context, tenantID uuid.UUID, nextRunAt time.Time) error {
+	query := `UPDATE tally_sync_settings SET next_run_at = $1 WHERE tenant_id = $2`
+	_, err := r.db.Exec(ctx, query, nextRunAt, tenantID)
+	return err
+}
+
+func (r *tallySyncRepo) CreateRun(ctx context.Context, run *models.TallyExportRun) error {
+	query := `
+		INSERT INTO tally_export_runs (id, tenant_id, entity, trigger, status, cursor_from, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING started_at
+	`
+	return r.db.QueryRow(ctx, query, run.ID, run.TenantID, run.Entity, run.Trigger, run.Status, run.CursorFrom).Scan(&run.StartedAt)
+}
+
+func (r *tallySyncRepo) FinishRun(ctx context.Context, run *models.TallyExportRun) error {
+	query := `
+		UPDATE tally_export_runs
+		SET status = $1, records_exported = $2, has_more = $3, cursor_to = $4, file_name = $5, object_key = $6, error_message = $7, finished_at = NOW()
+		WHERE id = $8
+		RETURNING finished_at
+	`
+	return r.db.QueryRow(ctx, query, run.Status, run.RecordsExported, run.HasMore, run.CursorTo, run.FileName, run.ObjectKey, run.ErrorMessage, run.ID).Scan(&run.FinishedAt)
+}
+
+const tallyRunColumns = `id, tenant_id, entity, trigger, status, records_exported, has_more, cursor_from, cursor_to, file_name, object_key, error_message, started_at, finished_at`
+
+func scanTallyRun(row rowScanner) (*models.TallyExportRun, error) {
+	run := &models.TallyExportRun{}
+	err := row.Scan(&run.ID, &run.TenantID, &run.Entity, &run.Trigger, &run.Status, &run.RecordsExported, &run.HasMore, &run.CursorFrom, &run.CursorTo,
+		&run.FileName, &run.ObjectKey, &run.ErrorMessage, &run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *tallySyncRepo) GetRun(ctx context.Context, tenantID, id uuid.UUID) (*models.TallyExportRun, error) {
+	query := `SELECT ` + tallyRunColumns + ` FROM tally_export_runs WHERE tenant_id = $1 AND id = $2`
+	return scanTallyRun(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+// GetLastRun returns the entity's most recent run, or nil when it has never run
+func (r *tallySyncRepo) GetLastRun(ctx context.Context, tenantID uuid.UUID, entity string) (*models.TallyExportRun, error) {
+	query := `SELECT ` + tallyRunColumns + ` FROM tally_export_runs WHERE tenant_id = $1 AND entity = $2 ORDER BY started_at DESC LIMIT 1`
+	run, err := scanTallyRun(r.db.QueryRow(ctx, query, tenantID, entity))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return run, err
+}
+
+// cursorArgs expands a cursor into (timestamp, id) bounds; an empty cursor
+// starts before every record
+func cursorArgs(cursor *models.TallySyncCursor) (time.Time, uuid.UUID) {
+	if cursor == nil || cursor.LastExportedAt == nil {
+		return time.Time{}, uuid.Nil
+	}
+	id := uuid.Nil
+	if cursor.LastExportedID != nil {
+		id = *cursor.LastExportedID
+	}
+	return *cursor.LastExportedAt, id
+}
+
+// ListInvoicesAfter returns invoices changed after the cursor and before the
+// cutoff, oldest first; the cutoff keeps in-flight writes out of the batch
+func (r *tallySyncRepo) ListInvoicesAfter(ctx context.Context, tenantID uuid.UUID, cursor *models.TallySyncCursor, before time.Time, limit int) ([]*models.Invoice, error) {
+	afterAt, afterID := cursorArgs(cursor)
+	query := `
+		SELECT id, tenant_id, order_id, invoice_number, gstin, hsn_sac, taxable_amount, gst_rate, cgst, sgst, igst, total_amount, status, issued_date, paid_date, due_date, created_at, updated_at
+		FROM invoices
+		WHERE tenant_id = $1 AND (updated_at, id) > ($2, $3) AND updated_at < $4
+		ORDER BY updated_at, id
+		LIMIT $5
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, afterAt, afterID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*models.Invoice
+	for rows.Next() {
+		invoice := &models.Invoice{}
+		if err := rows.Scan(&invoice.ID, &invoice.TenantID, &invoice.OrderID, &invoice.InvoiceNumber, &invoice.GSTIN, &invoice.HSNSAC, &invoice.TaxableAmount,
+			&invoice.GSTRate, &invoice.CGST, &invoice.SGST, &invoice.IGST, &invoice.TotalAmount, &invoice.Status, &invoice.IssuedDate, &invoice.PaidDate,
+			&invoice.DueDate, &invoice.CreatedAt, &invoice.UpdatedAt); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// ListOrdersAfter returns orders changed after the cursor and before the cutoff, oldest first
+func (r *tallySyncRepo) ListOrdersAfter(ctx context.Context, tenantID uuid.UUID, cursor *models.TallySyncCursor, before time.Time, limit int) ([]*models.Order, error) {
+	afterAt, afterID := cursorArgs(cursor)
+	query := `
+		SELECT id, tenant_id, order_type, supplier_id, distributor_id, product_id, warehouse_id, quantity, unit_price, status, order_date, expected_delivery, notes, created_at, updated_at
+		FROM orders
+		WHERE tenant_id = $1 AND (updated_at, id) > ($2, $3) AND updated_at < $4
+		ORDER BY updated_at, id
+		LIMIT $5
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, afterAt, afterID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*models.Order
+	for rows.Next() {
+		order := &models.Order{}
+		if err := rows.Scan(&order.ID, &order.TenantID, &order.OrderType, &order.SupplierID, &order.DistributorID, &order.ProductID, &order.WarehouseID,
+			&order.Quantity, &order.UnitPrice, &order.Status, &order.OrderDate, &order.ExpectedDelivery, &order.Notes, &order.CreatedAt, &order.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// CountPending counts records of the cursor's entity changed since the cursor
+func (r *tallySyncRepo) CountPending(ctx context.Context, tenantID uuid.UUID, cursor *models.TallySyncCursor) (int, error) {
+	table := "invoices"
+	if cursor.Entity == models.TallyEntityOrders {
+		table = "orders"
+	}
+	afterAt, afterID := cursorArgs(cursor)
+	query := `SELECT COUNT(*) FROM ` + table + ` WHERE tenant_id = $1 AND (updated_at, id) > ($2, $3)`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, tenantID, afterAt, afterID).Scan(&count)
+	return count, err
+}
+
+// FindDuplicateOrder looks for an existing order with the same business
+// fields, which usually means the row was exported from here and re-imported
+func (r *tallySyncRepo) FindDuplicateOrder(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*uuid.UUID, error) {
+	query := `
+		SELECT id FROM orders
+		WHERE tenant_id = $1 AND order_type = $2 AND product_id = $3 AND warehouse_id = $4
+			AND quantity = $5 AND unit_price = $6 AND order_date::date = $7::date
+			AND supplier_id IS NOT DISTINCT FROM $8 AND distributor_id IS NOT DISTINCT FROM $9
+		LIMIT 1
+	`
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, tenantID, order.OrderType, order.ProductID, order.WarehouseID, order.Quantity, order.UnitPrice,
+		order.OrderDate, order.SupplierID, order.DistributorID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// FindDuplicateInvoice looks for an existing invoice for the same order, or
+// with the same date, GSTIN and total when no order is given
+func (r *tallySyncRepo) FindDuplicateInvoice(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) (*uuid.UUID, error) {
+	query := `
+		SELECT id FROM invoices
+		WHERE tenant_id = $1 AND (
+			($2 <> '00000000-0000-0000-0000-000000000000'::uuid AND order_id = $2)
+			OR (issued_date::date = $3::date AND gstin IS NOT DISTINCT FROM $4 AND total_amount = $5)
+		)
+		LIMIT 1
+	`
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, tenantID, invoice.OrderID, invoice.IssuedDate, invoice.GSTIN, invoice.TotalAmount).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (r *tallySyncRepo) GetOrderUpdatedAt(ctx context.Context, tenantID, orderID uuid.UUID) (*time.Time, error) {
+	var updatedAt time.Time
+	err := r.db.QueryRow(ctx, `SELECT updated_at FROM orders WHERE tenant_id = $1 AND id = $2`, tenantID, orderID).Scan(&updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updatedAt, nil
+}
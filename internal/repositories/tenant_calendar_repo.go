@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TenantCalendarRepository interface {
+	Get(ctx context.Context, tenantID uuid.UUID) (*models.TenantCalendar, error)
+	Upsert(ctx context.Context, calendar *models.TenantCalendar) error
+}
+
+type tenantCalendarRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantCalendarRepo(db *pgxpool.Pool) TenantCalendarRepository {
+	return &tenantCalendarRepo{db: db}
+}
+
+// Get returns the tenant's calendar settings, or nil when it has not configured any
+func (r *tenantCalendarRepo) Get(ctx context.Context, tenantID uuid.UUID) (*models.TenantCalendar, error) {
+	query := `
+		SELECT tenant_id, timezone, fiscal_year_start_month, updated_at
+		FROM tenant_calendar_settings
+		WHERE tenant_id = $1
+	`
+	calendar := &models.TenantCalendar{}
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&calendar.TenantID, &calendar.Timezone,
+		&calendar.FiscalYearStartMonth, &calendar.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+func (r *tenantCalendarRepo) Upsert(ctx context.Context, calendar *models.TenantCalendar) error {
+	query := `
+		INSERT INTO tenant_calendar_settings (tenant_id, timezone, fiscal_year_start_month, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone, fiscal_year_start_month = EXCLUDED.fiscal_year_start_month, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, calendar.TenantID, calendar.Timezone, calendar.FiscalYearStartMonth).Scan(&calendar.UpdatedAt)
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TenantPaymentSettingsRepository interface {
+	Get(ctx context.Context, tenantID uuid.UUID) (*models.TenantPaymentSettings, error)
+	Upsert(ctx context.Context, settings *models.TenantPaymentSettings) error
+}
+
+type tenantPaymentSettingsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantPaymentSettingsRepo(db *pgxpool.Pool) TenantPaymentSettingsRepository {
+	return &tenantPaymentSettingsRepo{db: db}
+}
+
+// Get returns the tenant's payment settings, or nil when it has not configured any
+func (r *tenantPaymentSettingsRepo) Get(ctx context.Context, tenantID uuid.UUID) (*models.TenantPaymentSettings, error) {
+	query := `
+		SELECT tenant_id, upi_vpa, payee_name, updated_at
+		FROM tenant_payment_settings
+		WHERE tenant_id = $1
+	`
+	settings := &models.TenantPaymentSettings{}
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&settings.TenantID, &settings.UPIVPA,
+		&settings.PayeeName, &settings.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *tenantPaymentSettingsRepo) Upsert(ctx context.Context, settings *models.TenantPaymentSettings) error {
+	query := `
+		INSERT INTO tenant_payment_settings (tenant_id, upi_vpa, payee_name, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET upi_vpa = EXCLUDED.upi_vpa, payee_name = EXCLUDED.payee_name, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, settings.TenantID, settings.UPIVPA, settings.PayeeName).Scan(&settings.UpdatedAt)
+}
@@ -6,6 +6,7 @@ import (
 	"agromart2/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,6 +17,8 @@ type TenantRepository interface {
 	Update(ctx context.Context, tenant *models.Tenant) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context, limit, offset int) ([]*models.Tenant, error)
+	// SetResidency pins the tenant to a region, or back to the default store when nil
+	SetResidency(ctx context.Context, id uuid.UUID, region *string) error
 }
 
 type tenantRepo struct {
@@ -28,21 +31,21 @@ func NewTenantRepo(db *pgxpool.Pool) TenantRepository {
 
 func (r *tenantRepo) Create(ctx context.Context, tenant *models.Tenant) error {
 	query := `
-		INSERT INTO tenants (id, name, subdomain, license_number, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		INSERT INTO tenants (id, name, subdomain, license_number, status, data_residency, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
 	`
-	_, err := r.db.Exec(ctx, query, tenant.ID, tenant.Name, tenant.Subdomain, tenant.License, tenant.Status)
+	_, err := r.db.Exec(ctx, query, tenant.ID, tenant.Name, tenant.Subdomain, tenant.License, tenant.Status, tenant.Residency)
 	return err
 }
 
 func (r *tenantRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Tenant, error) {
 	tenant := &models.Tenant{}
 	query := `
-		SELECT id, name, subdomain, license_number, status, created_at, updated_at
+		SELECT id, name, subdomain, license_number, status, data_residency, created_at, updated_at
 		FROM tenants
 		WHERE id = $1
 	`
-	err := r.db.QueryRow(ctx, query, id).Scan(&tenant.ID, &tenant.Name, &tenant.Subdomain, &tenant.License, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, id).Scan(&tenant.ID, &tenant.Name, &tenant.Subdomain, &tenant.License, &tenant.Status, &tenant.Residency, &tenant.CreatedAt, &tenant.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -52,11 +55,11 @@ func (r *tenantRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Tenant,
 func (r *tenantRepo) GetBySubdomain(ctx context.Context, subdomain string) (*models.Tenant, error) {
 	tenant := &models.Tenant{}
 	query := `
-		SELECT id, name, subdomain, license_number, status, created_at, updated_at
+		SELECT id, name, subdomain, license_number, status, data_residency, created_at, updated_at
 		FROM tenants
 		WHERE subdomain = $1
 	`
-	err := r.db.QueryRow(ctx, query, subdomain).Scan(&tenant.ID, &tenant.Name, &tenant.Subdomain, &tenant.License, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, subdomain).Scan(&tenant.ID, &tenant.Name, &tenant.Subdomain, &tenant.License, &tenant.Status, &tenant.Residency, &tenant.CreatedAt, &tenant.UpdatedAt)
 	return tenant, err
 }
 
@@ -70,6 +73,18 @@ func (r *tenantRepo) Update(ctx context.Context, tenant *models.Tenant) error {
 	return err
 }
 
+func (r *tenantRepo) SetResidency(ctx context.Context, id uuid.UUID, region *string) error {
+	query := `UPDATE tenants SET data_residency = $1, updated_at = NOW() WHERE id = $2`
+	tag, err := r.db.Exec(ctx, query, region, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 func (r *tenantRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM tenants WHERE id = $1`
 	_, err := r.db.Exec(ctx, query, id)
@@ -78,7 +93,7 @@ func (r *tenantRepo) Delete(ctx context.Context, id uuid.UUID) error {
 
 func (r *tenantRepo) List(ctx context.Context, limit, offset int) ([]*models.Tenant, error) {
 	query := `
-		SELECT id, name, subdomain, license_number, status, created_at, updated_at
+		SELECT id, name, subdomain, license_number, status, data_residency, created_at, updated_at
 		FROM tenants
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -92,7 +107,7 @@ func (r *tenantRepo) List(ctx context.Context, limit, offset int) ([]*models.Ten
 	var tenants []*models.Tenant
 	for rows.Next() {
 		tenant := &models.Tenant{}
-		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.Subdomain, &tenant.License, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt); err != nil {
+		if err := rows.Scan(&tenant.ID, &tenant.Name, &tenant.Subdomain, &tenant.License, &tenant.Status, &tenant.Residency, &tenant.CreatedAt, &tenant.UpdatedAt); err != nil {
 			return nil, err
 		}
 		tenants = append(tenants, tenant)
@@ -0,0 +1,230 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TenantTakeoutRepository interface {
+	// Create records a running takeout, returning false when the tenant
+	// already has one running
+	Create(ctx context.Context, takeout *models.TenantTakeout) (bool, error)
+	// FailStale fails the tenant's running takeouts started before the cutoff,
+	// left behind by a restart
+	FailStale(ctx context.Context, tenantID uuid.UUID, startedBefore time.Time) error
+	UpdateProgress(ctx context.Context, takeout *models.TenantTakeout) error
+	Finish(ctx context.Context, takeout *models.TenantTakeout) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.TenantTakeout, error)
+	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.TenantTakeout, error)
+	// Tables lists the tables holding tenant data: those with a tenant_id,
+	// tenants itself, and tables without one referencing a table with one
+	Tables(ctx context.Context) ([]*models.TakeoutTable, error)
+	// ExportRows streams the tenant's rows of the table as JSON objects
+	// holding the given columns
+	ExportRows(ctx context.Context, tenantID uuid.UUID, table *models.TakeoutTable, columns []string, fn func(row []byte) error) error
+}
+
+type tenantTakeoutRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTenantTakeoutRepo(db *pgxpool.Pool) TenantTakeoutRepository {
+	return &tenantTakeoutRepo{db: db}
+}
+
+func (r *tenantTakeoutRepo) Create(ctx context.Context, takeout *models.TenantTakeout) (bool, error) {
+	query := `
+		INSERT INTO tenant_takeouts (id, tenant_id, format, include_documents, status, requested_by, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (tenant_id) WHERE status = 'running' DO NOTHING
+		RETURNING started_at
+	`
+	err := r.db.QueryRow(ctx, query, takeout.ID, takeout.TenantID, takeout.Format, takeout.IncludeDocuments, takeout.Status, takeout.RequestedBy).Scan(&takeout.StartedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *tenantTakeoutRepo) FailStale(ctx context.Context, tenantID uuid.UUID, startedBefore time.Time) error {
+	query := `
+		UPDATE tenant_takeouts
+		SET status = 'failed', error_message = 'interrupted before finishing', finished_at = NOW()
+		WHERE tenant_id = $1 AND status = 'running' AND started_at < $2
+	`
+	_, err := r.db.Exec(ctx, query, tenantID, startedBefore)
+	return err
+}
+
+func (r *tenantTakeoutRepo) UpdateProgress(ctx context.Context, takeout *models.TenantTakeout) error {
+	query := `
+		UPDATE tenant_takeouts
+		SET entities_total = $1, entities_done = $2, rows_exported = $3, documents_exported = $4
+		WHERE id = $5
+	`
+	_, err := r.db.Exec(ctx, query, takeout.EntitiesTotal, takeout.EntitiesDone, takeout.RowsExported, takeout.DocumentsExported, takeout.ID)
+	return err
+}
+
+func (r *tenantTakeoutRepo) Finish(ctx context.Context, takeout *models.TenantTakeout) error {
+	query := `
+		UPDATE tenant_takeouts
+		SET status = $1, entities_total = $2, entities_done = $3, rows_exported = $4, documents_exported = $5,
+			file_name = $6, object_key = $7, size_bytes = $8, error_message = $9, finished_at = NOW()
+		WHERE id = $10
+		RETURNING finished_at
+	`
+	return r.db.QueryRow(ctx, query, takeout.Status, takeout.EntitiesTotal, takeout.EntitiesDone, takeout.RowsExported, takeout.DocumentsExported,
+		takeout.FileName, takeout.ObjectKey, takeout.SizeBytes, takeout.ErrorMessage, takeout.ID).Scan(&takeout.FinishedAt)
+}
+
+const tenantTakeoutColumns = `id, tenant_id, format, include_documents, status, entities_total, entities_done, rows_exported, documents_exported,
+	file_name, object_key, size_bytes, error_message, requested_by, started_at, finished_at`
+
+func scanTenantTakeout(row rowScanner) (*models.TenantTakeout, error) {
+	takeout := &models.TenantTakeout{}
+	err := row.Scan(&takeout.ID, &takeout.TenantID, &takeout.Format, &takeout.IncludeDocuments, &takeout.Status, &takeout.EntitiesTotal,
+		&takeout.EntitiesDone, &takeout.RowsExported, &takeout.DocumentsExported, &takeout.FileName, &takeout.ObjectKey, &takeout.SizeBytes,
+		&takeout.ErrorMessage, &takeout.RequestedBy, &takeout.StartedAt, &takeout.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return takeout, nil
+}
+
+func (r *tenantTakeoutRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.TenantTakeout, error) {
+	query := `SELECT ` + tenantTakeoutColumns + ` FROM tenant_takeouts WHERE tenant_id = $1 AND id = $2`
+	return scanTenantTakeout(r.db.QueryRow(ctx, query, tenantID, id))
+}
+
+func (r *tenantTakeoutRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.TenantTakeout, error) {
+	query := `
+		SELECT ` + tenantTakeoutColumns + `
+		FROM tenant_takeouts
+		WHERE tenant_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var takeouts []*models.TenantTakeout
+	for rows.Next() {
+		takeout, err := scanTenantTakeout(rows)
+		if err != nil {
+			return nil, err
+		}
+		takeouts = append(takeouts, takeout)
+	}
+	return takeouts, rows.Err()
+}
+
+func (r *tenantTakeoutRepo) Tables(ctx context.Context) ([]*models.TakeoutTable, error) {
+	// Partitions are read through their parent and materialized views are
+	// derived data, so only plain and partitioned tables are listed. A table
+	// without tenant_id follows its first single-column foreign key to one
+	// with it
+	query := `
+		WITH tables AS (
+			SELECT c.oid, c.relname
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p') AND NOT c.relispartition
+		),
+		tenant_tables AS (
+			SELECT t.oid
+			FROM tables t
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attname = 'tenant_id' AND NOT a.attisdropped
+		)
+		SELECT t.relname,
+			ARRAY(
+				SELECT a.attname::text FROM pg_attribute a
+				WHERE a.attrelid = t.oid AND a.attnum > 0 AND NOT a.attisdropped
+				ORDER BY a.attnum
+			),
+			CASE WHEN t.oid IN (SELECT oid FROM tenant_tables) THEN 'tenant_id'
+				WHEN t.relname = 'tenants' THEN 'id'
+				ELSE '' END,
+			COALESCE(fk_column.attname::text, ''), COALESCE(parent.relname::text, ''), COALESCE(parent_column.attname::text, '')
+		FROM tables t
+		LEFT JOIN LATERAL (
+			SELECT con.confrelid, con.conkey[1] AS column_num, con.confkey[1] AS parent_column_num
+			FROM pg_constraint con
+			WHERE con.conrelid = t.oid AND con.contype = 'f' AND array_length(con.conkey, 1) = 1
+				AND con.confrelid IN (SELECT oid FROM tenant_tables)
+			ORDER BY con.conname
+			LIMIT 1
+		) fk ON t.oid NOT IN (SELECT oid FROM tenant_tables)
+		LEFT JOIN pg_class parent ON parent.oid = fk.confrelid
+		LEFT JOIN pg_attribute fk_column ON fk_column.attrelid = t.oid AND fk_column.attnum = fk.column_num
+		LEFT JOIN pg_attribute parent_column ON parent_column.attrelid = fk.confrelid AND parent_column.attnum = fk.parent_column_num
+		WHERE t.oid IN (SELECT oid FROM tenant_tables) OR t.relname = 'tenants' OR fk.confrelid IS NOT NULL
+		ORDER BY t.relname
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []*models.TakeoutTable
+	for rows.Next() {
+		table := &models.TakeoutTable{}
+		if err := rows.Scan(&table.Name, &table.Columns, &table.TenantColumn, &table.ForeignKey, &table.ParentTable, &table.ParentColumn); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+func (r *tenantTakeoutRepo) ExportRows(ctx context.Context, tenantID uuid.UUID, table *models.TakeoutTable, columns []string, fn func(row []byte) error) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns to export from %s", table.Name)
+	}
+	selected := make([]string, len(columns))
+	for i, column := range columns {
+		selected[i] = "t." + pgx.Identifier{column}.Sanitize()
+	}
+
+	var query string
+	if table.TenantColumn != "" {
+		query = fmt.Sprintf(`SELECT row_to_json(x)::text FROM (SELECT %s FROM %s t WHERE t.%s = $1) x`,
+			strings.Join(selected, ", "), pgx.Identifier{table.Name}.Sanitize(), pgx.Identifier{table.TenantColumn}.Sanitize())
+	} else if table.ParentTable != "" {
+		query = fmt.Sprintf(`SELECT row_to_json(x)::text FROM (SELECT %s FROM %s t JOIN %s p ON p.%s = t.%s WHERE p.tenant_id = $1) x`,
+			strings.Join(selected, ", "), pgx.Identifier{table.Name}.Sanitize(), pgx.Identifier{table.ParentTable}.Sanitize(),
+			pgx.Identifier{table.ParentColumn}.Sanitize(), pgx.Identifier{table.ForeignKey}.Sanitize())
+	} else {
+		return fmt.Errorf("table %s is not scoped to a tenant", table.Name)
+	}
+
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
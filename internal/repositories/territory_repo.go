@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TerritoryRepository interface {
+	List(ctx context.Context, tenantID uuid.UUID) ([]*models.Territory, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.Territory, error)
+	// Create and Update report false when the parent already has a territory
+	// of the same name
+	Create(ctx context.Context, territory *models.Territory) (bool, error)
+	Update(ctx context.Context, territory *models.Territory) (bool, error)
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+	ChildCount(ctx context.Context, tenantID, id uuid.UUID) (int, error)
+
+	// Assign reports false when the entity is not the tenant's
+	Assign(ctx context.Context, tenantID uuid.UUID, entityType string, entityID uuid.UUID, territoryID *uuid.UUID) (bool, error)
+	UserTerritory(ctx context.Context, tenantID, userID uuid.UUID) (*uuid.UUID, error)
+	// Contains reports whether territoryID is ancestorID or lies below it
+	Contains(ctx context.Context, tenantID, ancestorID, territoryID uuid.UUID) (bool, error)
+
+	SalesByTerritory(ctx context.Context, tenantID uuid.UUID, level string, within *uuid.UUID, from, to time.Time) ([]*models.TerritorySales, error)
+	DailySales(ctx context.Context, tenantID uuid.UUID, within *uuid.UUID, from, to time.Time) ([]*models.TerritoryDailySales, error)
+}
+
+type territoryRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTerritoryRepo(db *pgxpool.Pool) TerritoryRepository {
+	return &territoryRepo{db: db}
+}
+
+const (
+	// territoryTree names each of the tenant's territories with its ancestors
+	territoryTree = `
+		WITH RECURSIVE tree AS (
+			SELECT id, name::text AS path FROM territories WHERE tenant_id = $1 AND parent_id IS NULL
+			UNION ALL
+			SELECT t.id, tree.path || ' / ' || t.name FROM territories t JOIN tree ON t.parent_id = tree.id
+		)`
+	// territoryClosure pairs each of the tenant's territories with itself and
+	// every territory below it
+	territoryClosure = `
+		WITH RECURSIVE closure AS (
+			SELECT id AS ancestor_id, id AS territory_id FROM territories WHERE tenant_id = $1
+			UNION ALL
+			SELECT c.ancestor_id, t.id FROM closure c JOIN territories t ON t.parent_id = c.territory_id
+		)`
+	territoryColumns = `t.id, t.tenant_id, t.parent_id, t.level, t.name, t.code, COALESCE(tree.path, t.name), t.created_at, t.updated_at`
+)
+
+func scanTerritory(row rowScanner) (*models.Territory, error) {
+	t := &models.Territory{}
+	err := row.Scan(&t.ID, &t.TenantID, &t.ParentID, &t.Level, &t.Name, &t.Code, &t.Path, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// List returns the tenant's territories ordered so each follows its parent
+func (r *territoryRepo) List(ctx context.Context, tenantID uuid.UUID) ([]*models.Territory, error) {
+	query := territoryTree + `
+		SELECT ` + territoryColumns + `
+		FROM territories t
+		LEFT JOIN tree ON tree.id = t.id
+		WHERE t.tenant_id = $1
+		ORDER BY COALESCE(tree.path, t.name)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var territories []*models.Territory
+	for rows.Next() {
+		territory, err := scanTerritory(rows)
+		if err != nil {
+			return nil, err
+		}
+		territories = append(territories, territory)
+	}
+	return territories, rows.Err()
+}
+
+func (r *territoryRepo) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.Territory, error) {
+	query := territoryTree + `
+		SELECT ` + territoryColumns + `
+		FROM territories t
+		LEFT JOIN tree ON tree.id = t.id
+		WHERE t.tenant_id = $1 AND t.id = $2
+	`
+	territory, err := scanTerritory(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return territory, err
+}
+
+func (r *territoryRepo) Create(ctx context.Context, territory *models.Territory) (bool, error) {
+	query := `
+		INSERT INTO territories (id, tenant_id, parent_id, level, name, code, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, territory.ID, territory.TenantID, territory.ParentID, territory.Level, territory.Name,
+		territory.Code).Scan(&territory.CreatedAt, &territory.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *territoryRepo) Update(ctx context.Context, territory *models.Territory) (bool, error) {
+	query := `
+		UPDATE territories t SET name = $3, code = $4, updated_at = NOW()
+		WHERE t.tenant_id = $1 AND t.id = $2
+			AND NOT EXISTS (
+				SELECT 1 FROM territories o
+				WHERE o.tenant_id = t.tenant_id AND o.id <> t.id
+					AND o.parent_id IS NOT DISTINCT FROM t.parent_id AND LOWER(o.name) = LOWER($3)
+			)
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, territory.TenantID, territory.ID, territory.Name, territory.Code).Scan(&territory.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *territoryRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM territories WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *territoryRepo) ChildCount(ctx context.Context, tenantID, id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM territories WHERE tenant_id = $1 AND parent_id = $2`, tenantID, id).Scan(&count)
+	return count, err
+}
+
+func (r *territoryRepo) Assign(ctx context.Context, tenantID uuid.UUID, entityType string, entityID uuid.UUID, territoryID *uuid.UUID) (bool, error) {
+	var query string
+	switch entityType {
+	case models.TerritoryEntityCustomer:
+		query = `UPDATE distributors SET territory_id = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`
+	case models.TerritoryEntityWarehouse:
+		query = `UPDATE warehouses SET territory_id = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`
+	case models.TerritoryEntityUser:
+		query = `UPDATE users SET territory_id = $3, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`
+	default:
+		return false, nil
+	}
+	tag, err := r.db.Exec(ctx, query, tenantID, entityID, territoryID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *territoryRepo) UserTerritory(ctx context.Context, tenantID, userID uuid.UUID) (*uuid.UUID, error) {
+	var territoryID *uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT territory_id FROM users WHERE tenant_id = $1 AND id = $2`, tenantID, userID).Scan(&territoryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return territoryID, err
+}
+
+func (r *territoryRepo) Contains(ctx context.Context, tenantID, ancestorID, territoryID uuid.UUID) (bool, error) {
+	query := territoryClosure + `
+		SELECT EXISTS (SELECT 1 FROM closure WHERE ancestor_id = $2 AND territory_id = $3)
+	`
+	var contains bool
+	err := r.db.QueryRow(ctx, query, tenantID, ancestorID, territoryID).Scan(&contains)
+	return contains, err
+}
+
+// SalesByTerritory totals sales orders and invoices, other than cancelled
+// ones, of the customers in each territory of the level and the territories
+// below it. Orders count at quantity times unit price and invoices at their total
+func (r *territoryRepo) SalesByTerritory(ctx context.Context, tenantID uuid.UUID, level string, within *uuid.UUID, from, to time.Time) ([]*models.TerritorySales, error) {
+	query := territoryClosure + `,
+		customers AS (
+			SELECT c.ancestor_id, d.id AS distributor_id
+			FROM closure c
+			JOIN distributors d ON d.territory_id = c.territory_id AND d.tenant_id = $1
+		),
+		order_totals AS (
+			SELECT cu.ancestor_id, COUNT(*) AS order_count, SUM(o.quantity * o.unit_price) AS order_amount
+			FROM customers cu
+			JOIN orders o ON o.distributor_id = cu.distributor_id AND o.tenant_id = $1
+			WHERE o.order_type = 'sales' AND o.status <> 'cancelled'
+				AND o.order_date >= $4::date AND o.order_date < ($5::date + INTERVAL '1 day')
+			GROUP BY cu.ancestor_id
+		),
+		invoice_totals AS (
+			SELECT cu.ancestor_id, COUNT(*) AS invoice_count, SUM(i.total_amount) AS invoice_amount
+			FROM customers cu
+			JOIN orders o ON o.distributor_id = cu.distributor_id AND o.tenant_id = $1
+			JOIN invoices i ON i.order_id = o.id AND i.tenant_id = $1
+			WHERE i.status <> 'cancelled'
+				AND i.issued_date >= $4::date AND i.issued_date < ($5::date + INTERVAL '1 day')
+			GROUP BY cu.ancestor_id
+		)
+		SELECT t.id, t.name, t.level,
+			(SELECT COUNT(*) FROM customers cu WHERE cu.ancestor_id = t.id),
+			COALESCE(ot.order_count, 0), COALESCE(ot.order_amount, 0)::float8,
+			COALESCE(it.invoice_count, 0), COALESCE(it.invoice_amount, 0)::float8
+		FROM territories t
+		LEFT JOIN order_totals ot ON ot.ancestor_id = t.id
+		LEFT JOIN invoice_totals it ON it.ancestor_id = t.id
+		WHERE t.tenant_id = $1 AND t.level = $2
+			AND ($3::uuid IS NULL OR t.id IN (SELECT territory_id FROM closure WHERE ancestor_id = $3))
+		ORDER BY COALESCE(it.invoice_amount, 0) DESC, COALESCE(ot.order_amount, 0) DESC, t.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, level, within, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []*models.TerritorySales
+	for rows.Next() {
+		s := &models.TerritorySales{}
+		if err := rows.Scan(&s.TerritoryID, &s.Name, &s.Level, &s.CustomerCount, &s.OrderCount, &s.OrderAmount,
+			&s.InvoiceCount, &s.InvoiceAmount); err != nil {
+			return nil, err
+		}
+		sales = append(sales, s)
+	}
+	return sales, rows.Err()
+}
+
+// DailySales totals sales orders and invoices per day for customers within
+// the territory, or all customers when within is nil
+func (r *territoryRepo) DailySales(ctx context.Context, tenantID uuid.UUID, within *uuid.UUID, from, to time.Time) ([]*models.TerritoryDailySales, error) {
+	query := territoryClosure + `,
+		customers AS (
+			SELECT d.id
+			FROM distributors d
+			WHERE d.tenant_id = $1
+				AND ($2::uuid IS NULL OR d.territory_id IN (SELECT territory_id FROM closure WHERE ancestor_id = $2))
+		),
+		sales AS (
+			SELECT o.order_date::date AS day, 1 AS orders, o.quantity * o.unit_price AS order_amount, 0 AS invoices, 0 AS invoice_amount
+			FROM orders o
+			WHERE o.tenant_id = $1 AND o.order_type = 'sales' AND o.status <> 'cancelled'
+				AND o.distributor_id IN (SELECT id FROM customers)
+				AND o.order_date >= $3::date AND o.order_date < ($4::date + INTERVAL '1 day')
+			UNION ALL
+			SELECT i.issued_date::date, 0, 0, 1, i.total_amount
+			FROM invoices i
+			JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+			WHERE i.tenant_id = $1 AND i.status <> 'cancelled'
+				AND o.distributor_id IN (SELECT id FROM customers)
+				AND i.issued_date >= $3::date AND i.issued_date < ($4::date + INTERVAL '1 day')
+		)
+		SELECT day, SUM(orders)::int, SUM(order_amount)::float8, SUM(invoices)::int, SUM(invoice_amount)::float8
+		FROM sales
+		GROUP BY day
+		ORDER BY day
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, within, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []*models.TerritoryDailySales
+	for rows.Next() {
+		d := &models.TerritoryDailySales{}
+		if err := rows.Scan(&d.Date, &d.OrderCount, &d.OrderAmount, &d.InvoiceCount, &d.InvoiceAmount); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
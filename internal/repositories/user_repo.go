@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
+	"agromart2/internal/pii"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -17,23 +19,43 @@ type UserRepository interface {
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.User, error)
+	ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.User, error)
 	GetByEmail(ctx context.Context, tenantID uuid.UUID, email string) (*models.User, error)
 	GetTenantIDByUserID(ctx context.Context, userID uuid.UUID) (uuid.UUID, error)
+	// UpdatePassword replaces the user's password hash; it reports false when
+	// the user does not exist
+	UpdatePassword(ctx context.Context, tenantID, id uuid.UUID, passwordHash string) (bool, error)
 }
 
 type userRepo struct {
-	db *pgxpool.Pool
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
 }
 
-func NewUserRepo(db *pgxpool.Pool) UserRepository {
-	return &userRepo{db: db}
+// NewUserRepo creates the repository; emails are encrypted with keyring, or
+// kept in plaintext when it is nil
+func NewUserRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) UserRepository {
+	return &userRepo{db: db, keyring: keyring}
+}
+
+// open decrypts a user's email after a scan
+func (r *userRepo) open(ctx context.Context, user *models.User) error {
+	email := &user.Email
+	if err := r.keyring.DecryptFields(ctx, user.TenantID, &email); err != nil {
+		return err
+	}
+	user.Email = *email
+	return nil
 }
 
 func (r *userRepo) Create(ctx context.Context, user *models.User) error {
-	// Check for global email uniqueness before insertion
+	// Check for global email uniqueness before insertion. Emails are matched
+	// through the index shared by all tenants; rows written before
+	// encryption was enabled have none and are compared in plaintext
+	emailIndex := r.keyring.GlobalBlindIndex(&user.Email)
 	var count int
-	emailCheckQuery := `SELECT COUNT(*) FROM users WHERE email = $1`
-	err := r.db.QueryRow(ctx, emailCheckQuery, user.Email).Scan(&count)
+	emailCheckQuery := `SELECT COUNT(*) FROM users WHERE email_bidx = $1 OR (email_bidx IS NULL AND email = $2)`
+	err := r.db.QueryRow(ctx, emailCheckQuery, emailIndex, user.Email).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to check email uniqueness: %w", err)
 	}
@@ -46,11 +68,15 @@ func (r *userRepo) Create(ctx context.Context, user *models.User) error {
 		// This is our debug output (would appear in application logs if logging was enabled)
 	}
 
+	email, err := r.keyring.Encrypt(ctx, user.TenantID, &user.Email)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt email: %w", err)
+	}
 	query := `
-		INSERT INTO users (id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		INSERT INTO users (id, tenant_id, email, email_bidx, password_hash, first_name, last_name, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
 	`
-	_, err = r.db.Exec(ctx, query, user.ID, user.TenantID, user.Email, user.PasswordHash, user.FirstName, user.LastName, user.Status)
+	_, err = r.db.Exec(ctx, query, user.ID, user.TenantID, email, emailIndex, user.PasswordHash, user.FirstName, user.LastName, user.Status)
 	return err
 }
 
@@ -65,6 +91,9 @@ func (r *userRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models
 	if err != nil {
 		return nil, err
 	}
+	if err := r.open(ctx, user); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
@@ -73,12 +102,15 @@ func (r *userRepo) GetByEmail(ctx context.Context, tenantID uuid.UUID, email str
 	query := `
 		SELECT id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at
 		FROM users
-		WHERE tenant_id = $1 AND email = $2
+		WHERE tenant_id = $1 AND (email_bidx = $2 OR (email_bidx IS NULL AND email = $3))
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, email).Scan(&user.ID, &user.TenantID, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, r.keyring.GlobalBlindIndex(&email), email).Scan(&user.ID, &user.TenantID, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName, &user.Status, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.open(ctx, user); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
@@ -92,21 +124,48 @@ func (r *userRepo) Update(ctx context.Context, user *models.User) error {
 	return err
 }
 
+func (r *userRepo) UpdatePassword(ctx context.Context, tenantID, id uuid.UUID, passwordHash string) (bool, error) {
+	query := `
+		UPDATE users
+		SET password_hash = $1, updated_at = NOW()
+		WHERE tenant_id = $2 AND id = $3
+	`
+	tag, err := r.db.Exec(ctx, query, passwordHash, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 func (r *userRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
 	query := `DELETE FROM users WHERE tenant_id = $1 AND id = $2`
 	_, err := r.db.Exec(ctx, query, tenantID, id)
 	return err
 }
 
+// UserSortColumns whitelists the fields the user list can be sorted by;
+// emails are encrypted and cannot be sorted in the database
+var UserSortColumns = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"status":     "status",
+	"created_at": "created_at",
+}
+
 func (r *userRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.User, error) {
-	query := `
+	return r.ListPage(ctx, tenantID, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists users in the page's sort order, newest first by default
+func (r *userRepo) ListPage(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.User, error) {
+	query := fmt.Sprintf(`
 		SELECT id, tenant_id, email, first_name, last_name, status, created_at, updated_at
 		FROM users
 		WHERE tenant_id = $1
-		ORDER BY created_at DESC
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("created_at DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -118,6 +177,9 @@ func (r *userRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset i
 		if err := rows.Scan(&user.ID, &user.TenantID, &user.Email, &user.FirstName, &user.LastName, &user.Status, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			return nil, err
 		}
+		if err := r.open(ctx, user); err != nil {
+			return nil, err
+		}
 		users = append(users, user)
 	}
 	return users, nil
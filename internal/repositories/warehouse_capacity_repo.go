@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WarehouseCapacityRepository interface {
+	// GetProductDimensions returns nil when the product has no dimensions
+	GetProductDimensions(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductDimensions, error)
+	UpsertProductDimensions(ctx context.Context, dimensions *models.ProductDimensions) error
+	// ListProductDimensions returns the dimensions recorded for the products
+	// by product ID
+	ListProductDimensions(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID]*models.ProductDimensions, error)
+	// Utilization totals the stock on hand of the tenant's active warehouses,
+	// or of those among warehouseIDs when given, with their capacities
+	Utilization(ctx context.Context, tenantID uuid.UUID, warehouseIDs []uuid.UUID) ([]*models.WarehouseUtilization, error)
+}
+
+type warehouseCapacityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewWarehouseCapacityRepo(db *pgxpool.Pool) WarehouseCapacityRepository {
+	return &warehouseCapacityRepo{db: db}
+}
+
+func (r *warehouseCapacityRepo) GetProductDimensions(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductDimensions, error) {
+	query := `
+		SELECT product_id, tenant_id, length_cm, width_cm, height_cm, units_per_pallet, updated_at
+		FROM product_dimensions
+		WHERE tenant_id = $1 AND product_id = $2
+	`
+	d := &models.ProductDimensions{}
+	err := r.db.QueryRow(ctx, query, tenantID, productID).Scan(&d.ProductID, &d.TenantID, &d.LengthCm, &d.WidthCm, &d.HeightCm, &d.UnitsPerPallet, &d.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *warehouseCapacityRepo) UpsertProductDimensions(ctx context.Context, d *models.ProductDimensions) error {
+	query := `
+		INSERT INTO product_dimensions (product_id, tenant_id, length_cm, width_cm, height_cm, units_per_pallet, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (product_id) DO UPDATE
+		SET length_cm = EXCLUDED.length_cm, width_cm = EXCLUDED.width_cm, height_cm = EXCLUDED.height_cm,
+			units_per_pallet = EXCLUDED.units_per_pallet, updated_at = NOW()
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query, d.ProductID, d.TenantID, d.LengthCm, d.WidthCm, d.HeightCm, d.UnitsPerPallet).Scan(&d.UpdatedAt)
+}
+
+func (r *warehouseCapacityRepo) ListProductDimensions(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID]*models.ProductDimensions, error) {
+	query := `
+		SELECT product_id, tenant_id, length_cm, width_cm, height_cm, units_per_pallet, updated_at
+		FROM product_dimensions
+		WHERE tenant_id = $1 AND product_id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dimensions := make(map[uuid.UUID]*models.ProductDimensions)
+	for rows.Next() {
+		d := &models.ProductDimensions{}
+		if err := rows.Scan(&d.ProductID, &d.TenantID, &d.LengthCm, &d.WidthCm, &d.HeightCm, &d.UnitsPerPallet, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		dimensions[d.ProductID] = d
+	}
+	return dimensions, rows.Err()
+}
+
+// Utilization counts a pallet position for every started pallet of each
+// product, since products are not mixed on a pallet
+func (r *warehouseCapacityRepo) Utilization(ctx context.Context, tenantID uuid.UUID, warehouseIDs []uuid.UUID) ([]*models.WarehouseUtilization, error) {
+	query := `
+		SELECT w.id, w.name, w.capacity, w.volume_capacity_m3, w.pallet_positions,
+			COALESCE(SUM(i.quantity), 0)::int,
+			COALESCE(SUM(i.quantity * d.length_cm * d.width_cm * d.height_cm / 1000000), 0)::float8,
+			COALESCE(SUM(CEIL(i.quantity::numeric / d.units_per_pallet)), 0)::int,
+			COALESCE(SUM(i.quantity) FILTER (WHERE d.product_id IS NULL), 0)::int,
+			COALESCE(SUM(i.quantity) FILTER (WHERE d.units_per_pallet IS NULL), 0)::int,
+			GREATEST(MAX(i.last_updated)::timestamptz, MAX(d.updated_at))
+		FROM warehouses w
+		LEFT JOIN inventory i ON i.tenant_id = w.tenant_id AND i.warehouse_id = w.id AND i.quantity > 0
+		LEFT JOIN product_dimensions d ON d.tenant_id = i.tenant_id AND d.product_id = i.product_id
+		WHERE w.tenant_id = $1 AND w.archived_at IS NULL AND ($2::uuid[] IS NULL OR w.id = ANY($2))
+		GROUP BY w.id, w.name, w.capacity, w.volume_capacity_m3, w.pallet_positions
+		ORDER BY w.name
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, warehouseIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var utilization []*models.WarehouseUtilization
+	for rows.Next() {
+		u := &models.WarehouseUtilization{}
+		if err := rows.Scan(&u.WarehouseID, &u.WarehouseName, &u.UnitCapacity, &u.VolumeCapacityM3, &u.PalletPositions,
+			&u.Units, &u.VolumeM3, &u.Pallets, &u.UnmeasuredUnits, &u.UnpalletizedUnits, &u.AsOf); err != nil {
+			return nil, err
+		}
+		utilization = append(utilization, u)
+	}
+	return utilization, rows.Err()
+}
@@ -2,18 +2,28 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type WarehouseRepository interface {
 	Create(ctx context.Context, warehouse *models.Warehouse) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Warehouse, error)
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Warehouse, error)
 	Update(ctx context.Context, warehouse *models.Warehouse) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
 	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Warehouse, error)
+	// ListPage lists active warehouses, only those of the branch when branchID is set
+	ListPage(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Warehouse, error)
 	GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Warehouse, error)
+	GetDefault(ctx context.Context, tenantID uuid.UUID) (*models.Warehouse, error)
+	SetDefault(ctx context.Context, tenantID, id uuid.UUID) error
+	Archive(ctx context.Context, tenantID, id uuid.UUID) error
+	OrderCount(ctx context.Context, tenantID, id uuid.UUID) (int, error)
 }
 
 type warehouseRepo struct {
@@ -26,35 +36,60 @@ func NewWarehouseRepository(db *pgxpool.Pool) WarehouseRepository {
 
 func (r *warehouseRepo) Create(ctx context.Context, warehouse *models.Warehouse) error {
 	query := `
-		INSERT INTO warehouses (id, tenant_id, name, address, capacity, license_number, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		INSERT INTO warehouses (id, tenant_id, name, address, capacity, volume_capacity_m3, pallet_positions, license_number, latitude, longitude, is_default, branch_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
 	`
-	_, err := r.db.Exec(ctx, query, warehouse.ID, warehouse.TenantID, warehouse.Name, warehouse.Address, warehouse.Capacity, warehouse.LicenseNumber)
+	_, err := r.db.Exec(ctx, query, warehouse.ID, warehouse.TenantID, warehouse.Name, warehouse.Address, warehouse.Capacity, warehouse.VolumeCapacityM3, warehouse.PalletPositions, warehouse.LicenseNumber, warehouse.Latitude, warehouse.Longitude, warehouse.IsDefault, warehouse.BranchID)
 	return err
 }
 
 func (r *warehouseRepo) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Warehouse, error) {
 	warehouse := &models.Warehouse{}
 	query := `
-		SELECT id, tenant_id, name, address, capacity, license_number, created_at, updated_at
+		SELECT id, tenant_id, name, address, capacity, volume_capacity_m3, pallet_positions, license_number, latitude, longitude, is_default, branch_id, archived_at, created_at, updated_at
 		FROM warehouses
 		WHERE tenant_id = $1 AND id = $2
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.LicenseNumber, &warehouse.CreatedAt, &warehouse.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.VolumeCapacityM3, &warehouse.PalletPositions, &warehouse.LicenseNumber, &warehouse.Latitude, &warehouse.Longitude, &warehouse.IsDefault, &warehouse.BranchID, &warehouse.ArchivedAt, &warehouse.CreatedAt, &warehouse.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return warehouse, nil
 }
 
+// GetByIDs loads the tenant's warehouses among ids in one query, archived ones
+// included; unknown IDs are skipped
+func (r *warehouseRepo) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Warehouse, error) {
+	query := `
+		SELECT id, tenant_id, name, address, capacity, volume_capacity_m3, pallet_positions, license_number, latitude, longitude, is_default, branch_id, archived_at, created_at, updated_at
+		FROM warehouses
+		WHERE tenant_id = $1 AND id = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warehouses []*models.Warehouse
+	for rows.Next() {
+		warehouse := &models.Warehouse{}
+		if err := rows.Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.VolumeCapacityM3, &warehouse.PalletPositions, &warehouse.LicenseNumber, &warehouse.Latitude, &warehouse.Longitude, &warehouse.IsDefault, &warehouse.BranchID, &warehouse.ArchivedAt, &warehouse.CreatedAt, &warehouse.UpdatedAt); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+	return warehouses, rows.Err()
+}
+
 func (r *warehouseRepo) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Warehouse, error) {
 	warehouse := &models.Warehouse{}
 	query := `
-		SELECT id, tenant_id, name, address, capacity, license_number, created_at, updated_at
+		SELECT id, tenant_id, name, address, capacity, volume_capacity_m3, pallet_positions, license_number, latitude, longitude, is_default, branch_id, archived_at, created_at, updated_at
 		FROM warehouses
-		WHERE tenant_id = $1 AND name = $2
+		WHERE tenant_id = $1 AND LOWER(name) = LOWER($2) AND archived_at IS NULL
 	`
-	err := r.db.QueryRow(ctx, query, tenantID, name).Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.LicenseNumber, &warehouse.CreatedAt, &warehouse.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, tenantID, name).Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.VolumeCapacityM3, &warehouse.PalletPositions, &warehouse.LicenseNumber, &warehouse.Latitude, &warehouse.Longitude, &warehouse.IsDefault, &warehouse.BranchID, &warehouse.ArchivedAt, &warehouse.CreatedAt, &warehouse.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -64,11 +99,12 @@ func (r *warehouseRepo) GetByName(ctx context.Context, tenantID uuid.UUID, name
 func (r *warehouseRepo) Update(ctx context.Context, warehouse *models.Warehouse) error {
 	query := `
 		UPDATE warehouses
-		SET name = $1, address = $2, capacity = $3, license_number = $4, updated_at = NOW()
-		WHERE tenant_id = $5 AND id = $6
+		SET name = $1, address = $2, capacity = $3, volume_capacity_m3 = $4, pallet_positions = $5, license_number = $6, latitude = $7, longitude = $8, branch_id = $9, updated_at = NOW()
+		WHERE tenant_id = $10 AND id = $11
 	`
-	_, err := r.db.Exec(ctx, query, warehouse.Name, warehouse.Address, warehouse.Capacity, warehouse.LicenseNumber, warehouse.TenantID, warehouse.ID)
-	return err
+	query, args, conditional := conditionalUpdate(ctx, warehouse.ID, "updated_at", query, []interface{}{warehouse.Name, warehouse.Address, warehouse.Capacity, warehouse.VolumeCapacityM3, warehouse.PalletPositions, warehouse.LicenseNumber, warehouse.Latitude, warehouse.Longitude, warehouse.BranchID, warehouse.TenantID, warehouse.ID})
+	tag, err := r.db.Exec(ctx, query, args...)
+	return checkConditionalUpdate(tag, err, conditional)
 }
 
 func (r *warehouseRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
@@ -77,15 +113,28 @@ func (r *warehouseRepo) Delete(ctx context.Context, tenantID, id uuid.UUID) erro
 	return err
 }
 
+// WarehouseSortColumns whitelists the fields the warehouse list can be sorted by
+var WarehouseSortColumns = map[string]string{
+	"name":       "name",
+	"capacity":   "capacity",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 func (r *warehouseRepo) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Warehouse, error) {
-	query := `
-		SELECT id, tenant_id, name, address, capacity, license_number, created_at, updated_at
+	return r.ListPage(ctx, tenantID, nil, listquery.Page{Limit: limit, Offset: offset})
+}
+
+// ListPage lists warehouses in the page's sort order, newest first by default
+func (r *warehouseRepo) ListPage(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Warehouse, error) {
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, name, address, capacity, volume_capacity_m3, pallet_positions, license_number, latitude, longitude, is_default, branch_id, archived_at, created_at, updated_at
 		FROM warehouses
-		WHERE tenant_id = $1
-		ORDER BY created_at DESC
+		WHERE tenant_id = $1 AND archived_at IS NULL AND ($4::uuid IS NULL OR branch_id = $4)
+		ORDER BY %s, id
 		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	`, page.OrderBy("created_at DESC"))
+	rows, err := r.db.Query(ctx, query, tenantID, page.Limit, page.Offset, branchID)
 	if err != nil {
 		return nil, err
 	}
@@ -94,10 +143,60 @@ func (r *warehouseRepo) List(ctx context.Context, tenantID uuid.UUID, limit, off
 	var warehouses []*models.Warehouse
 	for rows.Next() {
 		warehouse := &models.Warehouse{}
-		if err := rows.Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.LicenseNumber, &warehouse.CreatedAt, &warehouse.UpdatedAt); err != nil {
+		if err := rows.Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.VolumeCapacityM3, &warehouse.PalletPositions, &warehouse.LicenseNumber, &warehouse.Latitude, &warehouse.Longitude, &warehouse.IsDefault, &warehouse.BranchID, &warehouse.ArchivedAt, &warehouse.CreatedAt, &warehouse.UpdatedAt); err != nil {
 			return nil, err
 		}
 		warehouses = append(warehouses, warehouse)
 	}
 	return warehouses, nil
-}
\ No newline at end of file
+}
+// GetDefault returns the tenant's default warehouse
+func (r *warehouseRepo) GetDefault(ctx context.Context, tenantID uuid.UUID) (*models.Warehouse, error) {
+	warehouse := &models.Warehouse{}
+	query := `
+		SELECT id, tenant_id, name, address, capacity, volume_capacity_m3, pallet_positions, license_number, latitude, longitude, is_default, branch_id, archived_at, created_at, updated_at
+		FROM warehouses
+		WHERE tenant_id = $1 AND is_default
+	`
+	err := r.db.QueryRow(ctx, query, tenantID).Scan(&warehouse.ID, &warehouse.TenantID, &warehouse.Name, &warehouse.Address, &warehouse.Capacity, &warehouse.VolumeCapacityM3, &warehouse.PalletPositions, &warehouse.LicenseNumber, &warehouse.Latitude, &warehouse.Longitude, &warehouse.IsDefault, &warehouse.BranchID, &warehouse.ArchivedAt, &warehouse.CreatedAt, &warehouse.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return warehouse, nil
+}
+
+// SetDefault makes the warehouse the tenant's only default
+func (r *warehouseRepo) SetDefault(ctx context.Context, tenantID, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE warehouses SET is_default = FALSE, updated_at = NOW() WHERE tenant_id = $1 AND is_default`, tenantID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `UPDATE warehouses SET is_default = TRUE, updated_at = NOW() WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+// Archive hides a warehouse from lists and name lookups while keeping it for
+// the orders that reference it; an archived warehouse is never the default
+func (r *warehouseRepo) Archive(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `UPDATE warehouses SET archived_at = NOW(), is_default = FALSE, updated_at = NOW() WHERE tenant_id = $1 AND id = $2 AND archived_at IS NULL`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+// OrderCount counts the orders that reference the warehouse
+func (r *warehouseRepo) OrderCount(ctx context.Context, tenantID, id uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM orders WHERE tenant_id = $1 AND warehouse_id = $2`, tenantID, id).Scan(&count)
+	return count, err
+}
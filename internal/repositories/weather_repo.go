@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WeatherAlertRepository interface {
+	CreateRule(ctx context.Context, rule *models.WeatherAlertRule) error
+	GetRule(ctx context.Context, tenantID, id uuid.UUID) (*models.WeatherAlertRule, error)
+	UpdateRule(ctx context.Context, rule *models.WeatherAlertRule) error
+	DeleteRule(ctx context.Context, tenantID, id uuid.UUID) error
+	ListRules(ctx context.Context, tenantID uuid.UUID, enabledOnly bool) ([]*models.WeatherAlertRule, error)
+	RecordEvent(ctx context.Context, event *models.WeatherAlertEvent) (bool, error)
+	ListEvents(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WeatherAlertEvent, error)
+	StockAtRisk(ctx context.Context, tenantID, warehouseID uuid.UUID, categoryID *uuid.UUID) ([]*models.StockAtRisk, error)
+}
+
+type weatherAlertRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewWeatherAlertRepo(db *pgxpool.Pool) WeatherAlertRepository {
+	return &weatherAlertRepo{db: db}
+}
+
+const weatherRuleColumns = `id, tenant_id, name, condition, threshold, lookahead_days, category_id, message, severity, recipients, enabled, created_at, updated_at`
+
+func (r *weatherAlertRepo) CreateRule(ctx context.Context, rule *models.WeatherAlertRule) error {
+	query := `
+		INSERT INTO weather_alert_rules (id, tenant_id, name, condition, threshold, lookahead_days, category_id, message, severity, recipients, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+	`
+	_, err := r.db.Exec(ctx, query, rule.ID, rule.TenantID, rule.Name, rule.Condition, rule.Threshold, rule.LookaheadDays,
+		rule.CategoryID, rule.Message, rule.Severity, rule.Recipients, rule.Enabled)
+	return err
+}
+
+func (r *weatherAlertRepo) GetRule(ctx context.Context, tenantID, id uuid.UUID) (*models.WeatherAlertRule, error) {
+	rule := &models.WeatherAlertRule{}
+	query := `SELECT ` + weatherRuleColumns + ` FROM weather_alert_rules WHERE tenant_id = $1 AND id = $2`
+	err := r.db.QueryRow(ctx, query, tenantID, id).Scan(&rule.ID, &rule.TenantID, &rule.Name, &rule.Condition, &rule.Threshold, &rule.LookaheadDays,
+		&rule.CategoryID, &rule.Message, &rule.Severity, &rule.Recipients, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *weatherAlertRepo) UpdateRule(ctx context.Context, rule *models.WeatherAlertRule) error {
+	query := `
+		UPDATE weather_alert_rules
+		SET name = $1, condition = $2, threshold = $3, lookahead_days = $4, category_id = $5, message = $6, severity = $7, recipients = $8, enabled = $9, updated_at = NOW()
+		WHERE tenant_id = $10 AND id = $11
+	`
+	_, err := r.db.Exec(ctx, query, rule.Name, rule.Condition, rule.Threshold, rule.LookaheadDays, rule.CategoryID, rule.Message,
+		rule.Severity, rule.Recipients, rule.Enabled, rule.TenantID, rule.ID)
+	return err
+}
+
+func (r *weatherAlertRepo) DeleteRule(ctx context.Context, tenantID, id uuid.UUID) error {
+	query := `DELETE FROM weather_alert_rules WHERE tenant_id = $1 AND id = $2`
+	_, err := r.db.Exec(ctx, query, tenantID, id)
+	return err
+}
+
+func (r *weatherAlertRepo) ListRules(ctx context.Context, tenantID uuid.UUID, enabledOnly bool) ([]*models.WeatherAlertRule, error) {
+	query := `SELECT ` + weatherRuleColumns + ` FROM weather_alert_rules WHERE tenant_id = $1 AND (enabled OR NOT $2) ORDER BY name`
+	rows, err := r.db.Query(ctx, query, tenantID, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.WeatherAlertRule
+	for rows.Next() {
+		rule := &models.WeatherAlertRule{}
+		if err := rows.Scan(&rule.ID, &rule.TenantID, &rule.Name, &rule.Condition, &rule.Threshold, &rule.LookaheadDays,
+			&rule.CategoryID, &rule.Message, &rule.Severity, &rule.Recipients, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// RecordEvent stores the event and reports whether it is new; an alert
+// already raised for the same rule, warehouse and day is not stored again
+func (r *weatherAlertRepo) RecordEvent(ctx context.Context, event *models.WeatherAlertEvent) (bool, error) {
+	query := `
+		INSERT INTO weather_alert_events (id, tenant_id, rule_id, warehouse_id, forecast_date, observed_value, message, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (rule_id, warehouse_id, forecast_date) DO NOTHING
+	`
+	tag, err := r.db.Exec(ctx, query, event.ID, event.TenantID, event.RuleID, event.WarehouseID, event.ForecastDate, event.ObservedValue, event.Message)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *weatherAlertRepo) ListEvents(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WeatherAlertEvent, error) {
+	query := `
+		SELECT id, tenant_id, rule_id, warehouse_id, forecast_date, observed_value, message, created_at
+		FROM weather_alert_events
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*models.WeatherAlertEvent
+	for rows.Next() {
+		event := &models.WeatherAlertEvent{}
+		if err := rows.Scan(&event.ID, &event.TenantID, &event.RuleID, &event.WarehouseID, &event.ForecastDate, &event.ObservedValue, &event.Message, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// StockAtRisk lists in-stock products in the warehouse, optionally limited to a category
+func (r *weatherAlertRepo) StockAtRisk(ctx context.Context, tenantID, warehouseID uuid.UUID, categoryID *uuid.UUID) ([]*models.StockAtRisk, error) {
+	query := `
+		SELECT p.id, p.name, i.quantity
+		FROM inventory i
+		JOIN products p ON p.tenant_id = i.tenant_id AND p.id = i.product_id
+		WHERE i.tenant_id = $1 AND i.warehouse_id = $2 AND i.quantity > 0
+		  AND ($3::uuid IS NULL OR p.category_id = $3)
+		ORDER BY i.quantity DESC
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, warehouseID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.StockAtRisk
+	for rows.Next() {
+		item := &models.StockAtRisk{}
+		if err := rows.Scan(&item.ProductID, &item.ProductName, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
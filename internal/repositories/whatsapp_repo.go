@@ -0,0 +1,278 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WhatsAppRepository interface {
+	CreateTemplate(ctx context.Context, template *models.WhatsAppTemplate) error
+	UpdateTemplate(ctx context.Context, template *models.WhatsAppTemplate) (bool, error)
+	DeleteTemplate(ctx context.Context, tenantID, id uuid.UUID) (bool, error)
+	ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.WhatsAppTemplate, error)
+	GetActiveTemplate(ctx context.Context, tenantID uuid.UUID, eventType, language string) (*models.WhatsAppTemplate, error)
+
+	UpsertContact(ctx context.Context, contact *models.WhatsAppContact) error
+	GetContact(ctx context.Context, tenantID uuid.UUID, phone string) (*models.WhatsAppContact, error)
+	ListContacts(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppContact, error)
+	SetOptInByPhone(ctx context.Context, phone string, optedIn bool, source string) (int64, error)
+
+	CreateMessage(ctx context.Context, message *models.WhatsAppMessage) error
+	ApplyReceipt(ctx context.Context, receipt *models.WhatsAppReceipt) (bool, error)
+	ListMessages(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppMessage, error)
+}
+
+type whatsAppRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewWhatsAppRepo(db *pgxpool.Pool) WhatsAppRepository {
+	return &whatsAppRepo{db: db}
+}
+
+const whatsAppTemplateColumns = `id, tenant_id, event_type, name, language, category, parameters, is_active, created_at, updated_at`
+
+func scanWhatsAppTemplate(row rowScanner) (*models.WhatsAppTemplate, error) {
+	template := &models.WhatsAppTemplate{}
+	err := row.Scan(&template.ID, &template.TenantID, &template.EventType, &template.Name, &template.Language, &template.Category,
+		&template.Parameters, &template.IsActive, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (r *whatsAppRepo) CreateTemplate(ctx context.Context, template *models.WhatsAppTemplate) error {
+	query := `
+		INSERT INTO whatsapp_templates (id, tenant_id, event_type, name, language, category, parameters, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, template.ID, template.TenantID, template.EventType, template.Name, template.Language,
+		template.Category, template.Parameters, template.IsActive).Scan(&template.CreatedAt, &template.UpdatedAt)
+}
+
+func (r *whatsAppRepo) UpdateTemplate(ctx context.Context, template *models.WhatsAppTemplate) (bool, error) {
+	query := `
+		UPDATE whatsapp_templates
+		SET event_type = $1, name = $2, language = $3, category = $4, parameters = $5, is_active = $6, updated_at = NOW()
+		WHERE tenant_id = $7 AND id = $8
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, template.EventType, template.Name, template.Language, template.Category, template.Parameters,
+		template.IsActive, template.TenantID, template.ID).Scan(&template.CreatedAt, &template.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *whatsAppRepo) DeleteTemplate(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM whatsapp_templates WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *whatsAppRepo) ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.WhatsAppTemplate, error) {
+	query := `SELECT ` + whatsAppTemplateColumns + ` FROM whatsapp_templates WHERE tenant_id = $1 ORDER BY event_type, language`
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*models.WhatsAppTemplate
+	for rows.Next() {
+		template, err := scanWhatsAppTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// GetActiveTemplate returns an active template for an event, preferring the
+// given language and then the most recently updated, or nil when the tenant
+// has none
+func (r *whatsAppRepo) GetActiveTemplate(ctx context.Context, tenantID uuid.UUID, eventType, language string) (*models.WhatsAppTemplate, error) {
+	query := `
+		SELECT ` + whatsAppTemplateColumns + `
+		FROM whatsapp_templates
+		WHERE tenant_id = $1 AND event_type = $2 AND is_active = TRUE
+		ORDER BY (language = $3) DESC, updated_at DESC
+		LIMIT 1
+	`
+	template, err := scanWhatsAppTemplate(r.db.QueryRow(ctx, query, tenantID, eventType, language))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return template, err
+}
+
+const whatsAppContactColumns = `tenant_id, phone, name, opted_in, source, opted_in_at, opted_out_at, updated_at`
+
+func scanWhatsAppContact(row rowScanner) (*models.WhatsAppContact, error) {
+	contact := &models.WhatsAppContact{}
+	err := row.Scan(&contact.TenantID, &contact.Phone, &contact.Name, &contact.OptedIn, &contact.Source,
+		&contact.OptedInAt, &contact.OptedOutAt, &contact.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// UpsertContact records a contact's consent, stamping when it was given or
+// withdrawn; an unchanged consent keeps its original timestamp
+func (r *whatsAppRepo) UpsertContact(ctx context.Context, contact *models.WhatsAppContact) error {
+	query := `
+		INSERT INTO whatsapp_contacts (tenant_id, phone, name, opted_in, source, opted_in_at, opted_out_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $4 THEN NOW() END, CASE WHEN NOT $4 THEN NOW() END, NOW())
+		ON CONFLICT (tenant_id, phone) DO UPDATE
+		SET name = COALESCE(EXCLUDED.name, whatsapp_contacts.name),
+			source = COALESCE(EXCLUDED.source, whatsapp_contacts.source),
+			opted_in = EXCLUDED.opted_in,
+			opted_in_at = CASE WHEN EXCLUDED.opted_in AND NOT whatsapp_contacts.opted_in THEN NOW() ELSE whatsapp_contacts.opted_in_at END,
+			opted_out_at = CASE WHEN NOT EXCLUDED.opted_in AND whatsapp_contacts.opted_in THEN NOW() ELSE whatsapp_contacts.opted_out_at END,
+			updated_at = NOW()
+		RETURNING ` + whatsAppContactColumns
+	saved, err := scanWhatsAppContact(r.db.QueryRow(ctx, query, contact.TenantID, contact.Phone, contact.Name, contact.OptedIn, contact.Source))
+	if err != nil {
+		return err
+	}
+	*contact = *saved
+	return nil
+}
+
+func (r *whatsAppRepo) GetContact(ctx context.Context, tenantID uuid.UUID, phone string) (*models.WhatsAppContact, error) {
+	query := `SELECT ` + whatsAppContactColumns + ` FROM whatsapp_contacts WHERE tenant_id = $1 AND phone = $2`
+	contact, err := scanWhatsAppContact(r.db.QueryRow(ctx, query, tenantID, phone))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return contact, err
+}
+
+func (r *whatsAppRepo) ListContacts(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppContact, error) {
+	query := `
+		SELECT ` + whatsAppContactColumns + `
+		FROM whatsapp_contacts
+		WHERE tenant_id = $1
+		ORDER BY updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []*models.WhatsAppContact
+	for rows.Next() {
+		contact, err := scanWhatsAppContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, rows.Err()
+}
+
+// SetOptInByPhone applies a keyword reply to every tenant's contact for the
+// phone, since inbound messages reach the shared business number
+func (r *whatsAppRepo) SetOptInByPhone(ctx context.Context, phone string, optedIn bool, source string) (int64, error) {
+	query := `
+		UPDATE whatsapp_contacts
+		SET opted_in = $2, source = $3,
+			opted_in_at = CASE WHEN $2 THEN NOW() ELSE opted_in_at END,
+			opted_out_at = CASE WHEN NOT $2 THEN NOW() ELSE opted_out_at END,
+			updated_at = NOW()
+		WHERE phone = $1 AND opted_in <> $2
+	`
+	tag, err := r.db.Exec(ctx, query, phone, optedIn, source)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const whatsAppMessageColumns = `id, tenant_id, phone, template_id, event_type, event_id, provider_message_id, status, error_message, created_at, updated_at, delivered_at, read_at`
+
+func scanWhatsAppMessage(row rowScanner) (*models.WhatsAppMessage, error) {
+	message := &models.WhatsAppMessage{}
+	err := row.Scan(&message.ID, &message.TenantID, &message.Phone, &message.TemplateID, &message.EventType, &message.EventID,
+		&message.ProviderMessageID, &message.Status, &message.ErrorMessage, &message.CreatedAt, &message.UpdatedAt,
+		&message.DeliveredAt, &message.ReadAt)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (r *whatsAppRepo) CreateMessage(ctx context.Context, message *models.WhatsAppMessage) error {
+	query := `
+		INSERT INTO whatsapp_messages (id, tenant_id, phone, template_id, event_type, event_id, provider_message_id, status, error_message, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query, message.ID, message.TenantID, message.Phone, message.TemplateID, message.EventType, message.EventID,
+		message.ProviderMessageID, message.Status, message.ErrorMessage).Scan(&message.CreatedAt, &message.UpdatedAt)
+}
+
+// ApplyReceipt moves a message forward to the receipt's status. Receipts can
+// arrive out of order, so a late "sent" never overwrites "read"
+func (r *whatsAppRepo) ApplyReceipt(ctx context.Context, receipt *models.WhatsAppReceipt) (bool, error) {
+	var errorMessage *string
+	if receipt.Error != "" {
+		errorMessage = &receipt.Error
+	}
+	query := `
+		UPDATE whatsapp_messages
+		SET status = $2::varchar,
+			error_message = COALESCE($3, error_message),
+			delivered_at = CASE WHEN $2::varchar IN ('delivered', 'read') THEN COALESCE(delivered_at, $4) ELSE delivered_at END,
+			read_at = CASE WHEN $2::varchar = 'read' THEN COALESCE(read_at, $4) ELSE read_at END,
+			updated_at = NOW()
+		WHERE provider_message_id = $1
+			AND CASE $2::varchar WHEN 'sent' THEN 1 WHEN 'delivered' THEN 2 WHEN 'read' THEN 3 ELSE 4 END
+				> CASE status WHEN 'sent' THEN 1 WHEN 'delivered' THEN 2 WHEN 'read' THEN 3 ELSE 4 END
+	`
+	tag, err := r.db.Exec(ctx, query, receipt.ProviderMessageID, receipt.Status, errorMessage, receipt.Timestamp)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *whatsAppRepo) ListMessages(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppMessage, error) {
+	query := `
+		SELECT ` + whatsAppMessageColumns + `
+		FROM whatsapp_messages
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*models.WhatsAppMessage
+	for rows.Next() {
+		message, err := scanWhatsAppMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,269 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/pii"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WithholdingTaxRepository interface {
+	ListSections(ctx context.Context, tenantID uuid.UUID, kind string) ([]*models.TaxSection, error)
+	GetSection(ctx context.Context, tenantID, id uuid.UUID) (*models.TaxSection, error)
+	CreateSection(ctx context.Context, section *models.TaxSection) (bool, error)
+	UpdateSection(ctx context.Context, section *models.TaxSection) (bool, error)
+	ActiveTCSSection(ctx context.Context, tenantID uuid.UUID) (*models.TaxSection, error)
+
+	CustomerSales(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) (float64, error)
+	CreateInvoiceWithTCS(ctx context.Context, invoice *models.Invoice, tcs *models.InvoiceTCS) error
+	GetInvoiceTCS(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceTCS, error)
+	SetTDSCertificate(ctx context.Context, tenantID, paymentID uuid.UUID, number string, date time.Time) (bool, error)
+
+	TCSSummary(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.WithholdingSummaryRow, error)
+	TDSSummary(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.WithholdingSummaryRow, error)
+}
+
+type withholdingTaxRepo struct {
+	db      *pgxpool.Pool
+	keyring *pii.DataKeyRing
+}
+
+func NewWithholdingTaxRepo(db *pgxpool.Pool, keyring *pii.DataKeyRing) WithholdingTaxRepository {
+	return &withholdingTaxRepo{db: db, keyring: keyring}
+}
+
+const taxSectionColumns = `id, tenant_id, kind, section, description, rate_percent::float8, threshold_amount::float8, active, created_at, updated_at`
+
+func scanTaxSection(row rowScanner) (*models.TaxSection, error) {
+	s := &models.TaxSection{}
+	err := row.Scan(&s.ID, &s.TenantID, &s.Kind, &s.Section, &s.Description, &s.RatePercent, &s.ThresholdAmount, &s.Active,
+		&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSections lists the tenant's sections of a kind, or of both kinds when
+// kind is empty
+func (r *withholdingTaxRepo) ListSections(ctx context.Context, tenantID uuid.UUID, kind string) ([]*models.TaxSection, error) {
+	query := `
+		SELECT ` + taxSectionColumns + `
+		FROM withholding_tax_sections
+		WHERE tenant_id = $1 AND ($2 = '' OR kind = $2)
+		ORDER BY kind, section
+	`
+	rows, err := r.db.Query(ctx, query, tenantID, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sections []*models.TaxSection
+	for rows.Next() {
+		section, err := scanTaxSection(rows)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, rows.Err()
+}
+
+func (r *withholdingTaxRepo) GetSection(ctx context.Context, tenantID, id uuid.UUID) (*models.TaxSection, error) {
+	query := `SELECT ` + taxSectionColumns + ` FROM withholding_tax_sections WHERE tenant_id = $1 AND id = $2`
+	section, err := scanTaxSection(r.db.QueryRow(ctx, query, tenantID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return section, err
+}
+
+// otherActiveTCS is true when a TCS section other than $2 is active for the
+// tenant ($1)
+const otherActiveTCS = `EXISTS (
+	SELECT 1 FROM withholding_tax_sections o
+	WHERE o.tenant_id = $1 AND o.kind = 'tcs' AND o.active AND o.id <> $2
+)`
+
+// CreateSection reports false, creating nothing, if the section already
+// exists or it would be a second active TCS section
+func (r *withholdingTaxRepo) CreateSection(ctx context.Context, section *models.TaxSection) (bool, error) {
+	query := `
+		INSERT INTO withholding_tax_sections (id, tenant_id, kind, section, description, rate_percent, threshold_amount, active, created_at, updated_at)
+		SELECT $2, $1, $3, $4, $5, $6, $7, $8, NOW(), NOW()
+		WHERE NOT ($3 = 'tcs' AND $8 AND ` + otherActiveTCS + `)
+		ON CONFLICT DO NOTHING
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, section.TenantID, section.ID, section.Kind, section.Section, section.Description,
+		section.RatePercent, section.ThresholdAmount, section.Active).Scan(&section.CreatedAt, &section.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UpdateSection reports false, changing nothing, if activating the section
+// would leave two TCS sections active
+func (r *withholdingTaxRepo) UpdateSection(ctx context.Context, section *models.TaxSection) (bool, error) {
+	query := `
+		UPDATE withholding_tax_sections
+		SET description = $3, rate_percent = $4, threshold_amount = $5, active = $6, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2 AND NOT (kind = 'tcs' AND $6 AND ` + otherActiveTCS + `)
+		RETURNING updated_at
+	`
+	err := r.db.QueryRow(ctx, query, section.TenantID, section.ID, section.Description, section.RatePercent,
+		section.ThresholdAmount, section.Active).Scan(&section.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ActiveTCSSection returns the TCS section added to sales invoices, or nil
+// when TCS is not collected
+func (r *withholdingTaxRepo) ActiveTCSSection(ctx context.Context, tenantID uuid.UUID) (*models.TaxSection, error) {
+	query := `SELECT ` + taxSectionColumns + ` FROM withholding_tax_sections WHERE tenant_id = $1 AND kind = 'tcs' AND active`
+	section, err := scanTaxSection(r.db.QueryRow(ctx, query, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return section, err
+}
+
+// CustomerSales totals a distributor's non-cancelled sales invoices issued
+// between from and to inclusive, before TCS
+func (r *withholdingTaxRepo) CustomerSales(ctx context.Context, tenantID, distributorID uuid.UUID, from, to time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(i.total_amount - COALESCE(t.amount, 0)), 0)::float8
+		FROM invoices i
+		JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+		LEFT JOIN invoice_tcs t ON t.invoice_id = i.id
+		WHERE i.tenant_id = $1 AND o.distributor_id = $2 AND i.status <> 'cancelled'
+			AND i.issued_date::date BETWEEN $3::date AND $4::date
+	`
+	var total float64
+	err := r.db.QueryRow(ctx, query, tenantID, distributorID, from, to).Scan(&total)
+	return total, err
+}
+
+// CreateInvoiceWithTCS creates an invoice whose total includes TCS together
+// with the TCS it collects
+func (r *withholdingTaxRepo) CreateInvoiceWithTCS(ctx context.Context, invoice *models.Invoice, tcs *models.InvoiceTCS) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, invoiceInsert, invoiceInsertArgs(invoice)...); err != nil {
+		return err
+	}
+
+	if err := insertInvoiceTCS(ctx, tx, invoice, tcs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// insertInvoiceTCS records the TCS of an invoice being created in tx
+func insertInvoiceTCS(ctx context.Context, tx pgx.Tx, invoice *models.Invoice, tcs *models.InvoiceTCS) error {
+	query := `
+		INSERT INTO invoice_tcs (invoice_id, tenant_id, distributor_id, section_id, section, rate_percent, base_amount, amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING created_at
+	`
+	return tx.QueryRow(ctx, query, invoice.ID, invoice.TenantID, tcs.DistributorID, tcs.SectionID, tcs.Section, tcs.RatePercent,
+		tcs.BaseAmount, tcs.Amount).Scan(&tcs.CreatedAt)
+}
+
+func (r *withholdingTaxRepo) GetInvoiceTCS(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceTCS, error) {
+	query := `
+		SELECT invoice_id, tenant_id, distributor_id, section_id, section, rate_percent::float8, base_amount::float8, amount::float8, created_at
+		FROM invoice_tcs
+		WHERE tenant_id = $1 AND invoice_id = $2
+	`
+	t := &models.InvoiceTCS{}
+	err := r.db.QueryRow(ctx, query, tenantID, invoiceID).Scan(&t.InvoiceID, &t.TenantID, &t.DistributorID, &t.SectionID, &t.Section,
+		&t.RatePercent, &t.BaseAmount, &t.Amount, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SetTDSCertificate records the TDS certificate of a payment; it reports
+// false if the payment does not exist or had no TDS deducted
+func (r *withholdingTaxRepo) SetTDSCertificate(ctx context.Context, tenantID, paymentID uuid.UUID, number string, date time.Time) (bool, error) {
+	query := `
+		UPDATE customer_payments SET tds_certificate_number = $3, tds_certificate_date = $4
+		WHERE tenant_id = $1 AND id = $2 AND tds_amount > 0
+	`
+	tag, err := r.db.Exec(ctx, query, tenantID, paymentID, number, date)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *withholdingTaxRepo) querySummary(ctx context.Context, query string, tenantID uuid.UUID, from, to time.Time) ([]*models.WithholdingSummaryRow, error) {
+	rows, err := r.db.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summary []*models.WithholdingSummaryRow
+	for rows.Next() {
+		row := &models.WithholdingSummaryRow{}
+		if err := rows.Scan(&row.Section, &row.CustomerID, &row.CustomerName, &row.CustomerGSTIN, &row.Documents,
+			&row.BaseAmount, &row.Amount, &row.CertificatesPending); err != nil {
+			return nil, err
+		}
+		if err := r.keyring.DecryptFields(ctx, tenantID, &row.CustomerGSTIN); err != nil {
+			return nil, err
+		}
+		summary = append(summary, row)
+	}
+	return summary, rows.Err()
+}
+
+// TCSSummary totals TCS per section and customer on non-cancelled invoices
+// issued between from and to inclusive
+func (r *withholdingTaxRepo) TCSSummary(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.WithholdingSummaryRow, error) {
+	query := `
+		SELECT t.section, d.id, d.name, d.gstin, COUNT(*)::int, SUM(t.base_amount)::float8, SUM(t.amount)::float8, 0
+		FROM invoice_tcs t
+		JOIN invoices i ON i.id = t.invoice_id
+		JOIN distributors d ON d.id = t.distributor_id
+		WHERE t.tenant_id = $1 AND i.status <> 'cancelled' AND i.issued_date::date BETWEEN $2::date AND $3::date
+		GROUP BY t.section, d.id, d.name, d.gstin
+		ORDER BY t.section, d.name
+	`
+	return r.querySummary(ctx, query, tenantID, from, to)
+}
+
+// TDSSummary totals TDS customers deducted per section on payments dated
+// between from and to inclusive; the base is what each payment settled
+func (r *withholdingTaxRepo) TDSSummary(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.WithholdingSummaryRow, error) {
+	query := `
+		SELECT COALESCE(p.tds_section, ''), d.id, d.name, d.gstin, COUNT(*)::int, SUM(p.amount + p.tds_amount)::float8,
+			SUM(p.tds_amount)::float8, COUNT(*) FILTER (WHERE p.tds_certificate_number IS NULL)::int
+		FROM customer_payments p
+		JOIN distributors d ON d.id = p.distributor_id
+		WHERE p.tenant_id = $1 AND p.tds_amount > 0 AND p.payment_date BETWEEN $2::date AND $3::date
+		GROUP BY p.tds_section, d.id, d.name, d.gstin
+		ORDER BY p.tds_section, d.name
+	`
+	return r.querySummary(ctx, query, tenantID, from, to)
+}
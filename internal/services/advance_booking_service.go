@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// BookingConversionLeadDays is how long before its delivery window a booking
+// may be converted into a sales order
+const BookingConversionLeadDays = 30
+
+var (
+	// ErrBookingNotFound is returned for bookings outside the tenant
+	ErrBookingNotFound = errors.New("advance booking not found")
+	// ErrInvalidBooking wraps booking validation and workflow failures
+	ErrInvalidBooking = errors.New("invalid advance booking")
+)
+
+// CreateBookingRequest books a sale ahead of its delivery window; dates are YYYY-MM-DD
+type CreateBookingRequest struct {
+	DistributorID uuid.UUID `json:"distributor_id"`
+	ProductID     uuid.UUID `json:"product_id"`
+	WarehouseID   uuid.UUID `json:"warehouse_id"`
+	Quantity      int       `json:"quantity"`
+	UnitPrice     float64   `json:"unit_price"`
+	Region        *string   `json:"region"`
+	WindowStart   string    `json:"window_start"`
+	WindowEnd     string    `json:"window_end"`
+	Notes         *string   `json:"notes"`
+}
+
+// BookingPaymentRequest records an advance against a booking
+type BookingPaymentRequest struct {
+	Amount    float64 `json:"amount"`
+	Method    string  `json:"method"` // cash, upi, bank_transfer, cheque, ...
+	Reference *string `json:"reference"`
+}
+
+// AdvanceBookingService books sales ahead of the season, tracks advances
+// against them and converts them into sales orders near the delivery window
+type AdvanceBookingService interface {
+	Create(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *CreateBookingRequest) (*models.AdvanceBooking, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, error)
+	List(ctx context.Context, tenantID uuid.UUID, filter *models.AdvanceBookingFilter) ([]*models.AdvanceBooking, error)
+	ListDue(ctx context.Context, tenantID uuid.UUID, days int) ([]*models.AdvanceBooking, error)
+	RecordPayment(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, req *BookingPaymentRequest) (*models.AdvanceBooking, error)
+	Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, error)
+	Convert(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, *models.Order, error)
+	DemandReport(ctx context.Context, tenantID uuid.UUID, from, to time.Time) (*models.BookingDemandReport, error)
+}
+
+type advanceBookingService struct {
+	bookingRepo     repositories.AdvanceBookingRepository
+	distributorRepo repositories.DistributorRepository
+	productRepo     repositories.ProductRepository
+	warehouseRepo   repositories.WarehouseRepository
+	orderService    OrderServiceInterface
+}
+
+// NewAdvanceBookingService creates a new advance booking service instance
+func NewAdvanceBookingService(bookingRepo repositories.AdvanceBookingRepository, distributorRepo repositories.DistributorRepository, productRepo repositories.ProductRepository,
+	warehouseRepo repositories.WarehouseRepository, orderService OrderServiceInterface) AdvanceBookingService {
+	return &advanceBookingService{
+		bookingRepo:     bookingRepo,
+		distributorRepo: distributorRepo,
+		productRepo:     productRepo,
+		warehouseRepo:   warehouseRepo,
+		orderService:    orderService,
+	}
+}
+
+// withTotals fills the derived value and balance fields
+func withTotals(booking *models.AdvanceBooking) *models.AdvanceBooking {
+	booking.TotalValue = math.Round(float64(booking.Quantity)*booking.UnitPrice*100) / 100
+	booking.BalanceDue = math.Round((booking.TotalValue-booking.AdvancePaid)*100) / 100
+	return booking
+}
+
+func (s *advanceBookingService) Create(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *CreateBookingRequest) (*models.AdvanceBooking, error) {
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidBooking)
+	}
+	if req.UnitPrice <= 0 {
+		return nil, fmt.Errorf("%w: unit_price must be positive", ErrInvalidBooking)
+	}
+	windowStart, err := time.Parse("2006-01-02", req.WindowStart)
+	if err != nil {
+		return nil, fmt.Errorf("%w: window_start must be a date in YYYY-MM-DD format", ErrInvalidBooking)
+	}
+	windowEnd, err := time.Parse("2006-01-02", req.WindowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("%w: window_end must be a date in YYYY-MM-DD format", ErrInvalidBooking)
+	}
+	if windowEnd.Before(windowStart) {
+		return nil, fmt.Errorf("%w: window_end must not be before window_start", ErrInvalidBooking)
+	}
+	if windowEnd.Before(time.Now().UTC().Truncate(24 * time.Hour)) {
+		return nil, fmt.Errorf("%w: the delivery window has already passed", ErrInvalidBooking)
+	}
+	if req.Region != nil {
+		region := strings.TrimSpace(*req.Region)
+		req.Region = &region
+		if err := common.SanitizeHTMLField(req.Region, "booking region"); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBooking, err)
+		}
+	}
+	if err := common.SanitizeHTMLField(req.Notes, "booking notes"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBooking, err)
+	}
+	if distributor, err := s.distributorRepo.GetByID(ctx, tenantID, req.DistributorID); err != nil || distributor == nil {
+		return nil, fmt.Errorf("%w: distributor not found", ErrInvalidBooking)
+	}
+	if product, err := s.productRepo.GetByID(ctx, tenantID, req.ProductID); err != nil || product == nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidBooking)
+	}
+	if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, req.WarehouseID); err != nil || warehouse == nil {
+		return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidBooking)
+	}
+
+	booking := &models.AdvanceBooking{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		DistributorID: req.DistributorID,
+		ProductID:     req.ProductID,
+		WarehouseID:   req.WarehouseID,
+		Quantity:      req.Quantity,
+		UnitPrice:     req.UnitPrice,
+		Region:        req.Region,
+		WindowStart:   windowStart,
+		WindowEnd:     windowEnd,
+		Status:        models.BookingStatusBooked,
+		Notes:         req.Notes,
+		CreatedBy:     userID,
+	}
+	if err := s.bookingRepo.Create(ctx, booking); err != nil {
+		return nil, fmt.Errorf("failed to save advance booking: %w", err)
+	}
+	return withTotals(booking), nil
+}
+
+func (s *advanceBookingService) getBooking(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, error) {
+	booking, err := s.bookingRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil {
+		return nil, ErrBookingNotFound
+	}
+	return withTotals(booking), nil
+}
+
+// Get returns a booking with its advance payments
+func (s *advanceBookingService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, error) {
+	booking, err := s.getBooking(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Payments, err = s.bookingRepo.ListPayments(ctx, booking.ID); err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+func (s *advanceBookingService) List(ctx context.Context, tenantID uuid.UUID, filter *models.AdvanceBookingFilter) ([]*models.AdvanceBooking, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	bookings, err := s.bookingRepo.List(ctx, tenantID, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, booking := range bookings {
+		withTotals(booking)
+	}
+	return bookings, nil
+}
+
+// ListDue returns open bookings whose delivery window starts within days,
+// including overdue ones: the bookings ready to be converted
+func (s *advanceBookingService) ListDue(ctx context.Context, tenantID uuid.UUID, days int) ([]*models.AdvanceBooking, error) {
+	if days <= 0 {
+		days = BookingConversionLeadDays
+	}
+	status := models.BookingStatusBooked
+	before := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, days)
+	return s.List(ctx, tenantID, &models.AdvanceBookingFilter{Status: &status, WindowStartsBefore: &before, Limit: 200})
+}
+
+// RecordPayment adds an advance; advances cannot exceed the booking's value
+func (s *advanceBookingService) RecordPayment(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, req *BookingPaymentRequest) (*models.AdvanceBooking, error) {
+	req.Amount = math.Round(req.Amount*100) / 100
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidBooking)
+	}
+	method := strings.ToLower(strings.TrimSpace(req.Method))
+	if method == "" {
+		return nil, fmt.Errorf("%w: payment method is required", ErrInvalidBooking)
+	}
+	if err := common.SanitizeHTMLField(req.Reference, "payment reference"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBooking, err)
+	}
+	booking, err := s.getBooking(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusBooked {
+		return nil, fmt.Errorf("%w: advances can only be recorded on open bookings, this one is %s", ErrInvalidBooking, booking.Status)
+	}
+	if req.Amount > booking.BalanceDue {
+		return nil, fmt.Errorf("%w: amount exceeds the balance due of %.2f", ErrInvalidBooking, booking.BalanceDue)
+	}
+
+	payment := &models.AdvanceBookingPayment{
+		ID:         uuid.New(),
+		BookingID:  booking.ID,
+		Amount:     req.Amount,
+		Method:     method,
+		Reference:  req.Reference,
+		RecordedBy: userID,
+	}
+	recorded, err := s.bookingRepo.AddPayment(ctx, tenantID, payment, booking.TotalValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record advance payment: %w", err)
+	}
+	if !recorded {
+		return nil, fmt.Errorf("%w: booking changed while recording the payment, reload and retry", ErrInvalidBooking)
+	}
+	return s.Get(ctx, tenantID, id)
+}
+
+// Cancel closes an open booking. Advances already received stay recorded for
+// refund or adjustment outside the booking.
+func (s *advanceBookingService) Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, error) {
+	booking, err := s.getBooking(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status != models.BookingStatusBooked {
+		return nil, fmt.Errorf("%w: only open bookings can be cancelled, this one is %s", ErrInvalidBooking, booking.Status)
+	}
+	updated, err := s.bookingRepo.SetStatus(ctx, tenantID, id, models.BookingStatusBooked, models.BookingStatusCancelled, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel advance booking: %w", err)
+	}
+	if !updated {
+		return nil, fmt.Errorf("%w: booking was changed by someone else, reload and retry", ErrInvalidBooking)
+	}
+	return s.Get(ctx, tenantID, id)
+}
+
+// Convert raises the sales order for a booking once its window is within
+// BookingConversionLeadDays; the order goes through the usual stock and
+// margin checks
+func (s *advanceBookingService) Convert(ctx context.Context, tenantID, id uuid.UUID) (*models.AdvanceBooking, *models.Order, error) {
+	booking, err := s.getBooking(ctx, tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if booking.Status != models.BookingStatusBooked {
+		return nil, nil, fmt.Errorf("%w: only open bookings can be converted, this one is %s", ErrInvalidBooking, booking.Status)
+	}
+	opens := booking.WindowStart.AddDate(0, 0, -BookingConversionLeadDays)
+	if time.Now().UTC().Before(opens) {
+		return nil, nil, fmt.Errorf("%w: booking can be converted from %s", ErrInvalidBooking, opens.Format("2006-01-02"))
+	}
+
+	notes := fmt.Sprintf("Converted from advance booking %s; advance received %.2f", booking.ID, booking.AdvancePaid)
+	distributorID := booking.DistributorID
+	windowEnd := booking.WindowEnd
+	order := &models.Order{
+		TenantID:         tenantID,
+		OrderType:        "sales",
+		DistributorID:    &distributorID,
+		ProductID:        booking.ProductID,
+		WarehouseID:      booking.WarehouseID,
+		Quantity:         booking.Quantity,
+		UnitPrice:        booking.UnitPrice,
+		ExpectedDelivery: &windowEnd,
+		Notes:            &notes,
+	}
+	if err := s.orderService.CreateOrder(ctx, tenantID, order); err != nil {
+		return nil, nil, fmt.Errorf("failed to create sales order: %w", err)
+	}
+
+	updated, err := s.bookingRepo.SetStatus(ctx, tenantID, id, models.BookingStatusBooked, models.BookingStatusConverted, &order.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to link booking to order %s: %w", order.ID, err)
+	}
+	if !updated {
+		fmt.Printf("Advance booking %s changed before it could be linked to order %s\n", id, order.ID)
+	}
+
+	booking, err = s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return booking, order, nil
+}
+
+// DemandReport compares booked and fulfilled quantities per product and region
+// for bookings whose window starts in [from, to)
+func (s *advanceBookingService) DemandReport(ctx context.Context, tenantID uuid.UUID, from, to time.Time) (*models.BookingDemandReport, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: report end must be after its start", ErrInvalidBooking)
+	}
+
+	rows, err := s.bookingRepo.DemandByProductRegion(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BookingDemandReport{From: from, To: to, Rows: rows}
+	if report.Rows == nil {
+		report.Rows = []*models.BookingDemandRow{}
+	}
+	for _, row := range report.Rows {
+		row.FulfilmentRate = fulfilmentRate(row.FulfilledQuantity, row.BookedQuantity)
+		report.BookedQuantity += row.BookedQuantity
+		report.FulfilledQuantity += row.FulfilledQuantity
+		report.AdvanceCollected += row.AdvanceCollected
+	}
+	report.AdvanceCollected = math.Round(report.AdvanceCollected*100) / 100
+	report.FulfilmentRate = fulfilmentRate(report.FulfilledQuantity, report.BookedQuantity)
+	return report, nil
+}
+
+func fulfilmentRate(fulfilled, booked int) float64 {
+	if booked == 0 {
+		return 0
+	}
+	return math.Round(float64(fulfilled)/float64(booked)*10000) / 100
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrApprovalDelegationNotFound is returned for delegations outside the tenant
+	ErrApprovalDelegationNotFound = errors.New("approval delegation not found")
+	// ErrInvalidApprovalDelegation wraps delegation validation failures
+	ErrInvalidApprovalDelegation = errors.New("invalid approval delegation")
+	// ErrApprovalDelegationConflict is returned when a delegation would overlap
+	// or chain with an active one
+	ErrApprovalDelegationConflict = errors.New("approval delegation conflict")
+)
+
+const (
+	// maxDelegationDays bounds how long a delegation may run
+	maxDelegationDays = 90
+	// pendingApprovalLimit caps the pending approvals inbox
+	pendingApprovalLimit = 200
+)
+
+// approvalPermissions is the permission needed to approve each kind; anyone
+// may approve orders
+var approvalPermissions = map[string]string{
+	models.ApprovalKindOrder:           "",
+	models.ApprovalKindRequisition:     "requisitions:approve",
+	models.ApprovalKindStockAdjustment: "inventories:reconcile",
+}
+
+// ApprovalDelegationService routes an approver's pending approvals to a
+// delegate while they are away, and lets the delegate approve in their stead
+type ApprovalDelegationService interface {
+	Create(ctx context.Context, tenantID uuid.UUID, createdBy *uuid.UUID, req *models.ApprovalDelegationRequest) (*models.ApprovalDelegation, error)
+	// Get returns the delegation with the approvals made under it
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.ApprovalDelegation, error)
+	List(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, includePast bool) ([]*models.ApprovalDelegation, error)
+	Revoke(ctx context.Context, tenantID, id uuid.UUID, revokedBy *uuid.UUID) (*models.ApprovalDelegation, error)
+	// ActingFor finds the delegation, active today, under which the user may
+	// approve the kind on behalf of a delegator holding the permission
+	ActingFor(ctx context.Context, tenantID, userID uuid.UUID, kind, permission string) (*models.ApprovalDelegation, error)
+	// RecordApproval records an approval made under the delegation and tells
+	// the delegator; failures are logged rather than returned
+	RecordApproval(ctx context.Context, delegation *models.ApprovalDelegation, kind string, entityID uuid.UUID, action string, actedBy *uuid.UUID)
+	// Pending is the user's approvals inbox: the kinds they approve, unless
+	// delegated away today, and the kinds routed to them by delegations,
+	// which are marked with the delegator
+	Pending(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.PendingApproval, error)
+}
+
+type approvalDelegationService struct {
+	repo      repositories.ApprovalDelegationRepository
+	userRepo  repositories.UserRepository
+	rbacSvc   RBACService
+	pushSvc   PushService
+	calendars TenantCalendarService
+}
+
+// NewApprovalDelegationService creates a new approval delegation service; push
+// may be nil, leaving delegators and delegates unnotified
+func NewApprovalDelegationService(repo repositories.ApprovalDelegationRepository, userRepo repositories.UserRepository, rbacSvc RBACService, pushSvc PushService, calendars TenantCalendarService) ApprovalDelegationService {
+	return &approvalDelegationService{
+		repo:      repo,
+		userRepo:  userRepo,
+		rbacSvc:   rbacSvc,
+		pushSvc:   pushSvc,
+		calendars: calendars,
+	}
+}
+
+func (s *approvalDelegationService) today(ctx context.Context, tenantID uuid.UUID) time.Time {
+	return TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(time.Now())
+}
+
+// approvalKinds validates the requested kinds, every kind when none are given
+func approvalKinds(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return append([]string(nil), models.ApprovalKinds...), nil
+	}
+	seen := make(map[string]bool, len(requested))
+	var kinds []string
+	for _, kind := range requested {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if _, ok := approvalPermissions[kind]; !ok {
+			return nil, fmt.Errorf("%w: kind %q must be order, requisition or stock_adjustment", ErrInvalidApprovalDelegation, kind)
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
+
+func (s *approvalDelegationService) Create(ctx context.Context, tenantID uuid.UUID, createdBy *uuid.UUID, req *models.ApprovalDelegationRequest) (*models.ApprovalDelegation, error) {
+	if req.DelegatorID == nil {
+		return nil, fmt.Errorf("%w: delegator_id is required", ErrInvalidApprovalDelegation)
+	}
+	if req.DelegateID == uuid.Nil {
+		return nil, fmt.Errorf("%w: delegate_id is required", ErrInvalidApprovalDelegation)
+	}
+	if req.DelegateID == *req.DelegatorID {
+		return nil, fmt.Errorf("%w: approvals cannot be delegated to yourself", ErrInvalidApprovalDelegation)
+	}
+	kinds, err := approvalKinds(req.Kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	today := s.today(ctx, tenantID)
+	startsOn := today
+	if req.StartsOn != "" {
+		if startsOn, err = time.Parse("2006-01-02", req.StartsOn); err != nil {
+			return nil, fmt.Errorf("%w: starts_on must be in YYYY-MM-DD format", ErrInvalidApprovalDelegation)
+		}
+	}
+	endsOn, err := time.Parse("2006-01-02", req.EndsOn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ends_on must be in YYYY-MM-DD format", ErrInvalidApprovalDelegation)
+	}
+	switch {
+	case startsOn.Before(today):
+		return nil, fmt.Errorf("%w: starts_on cannot be in the past", ErrInvalidApprovalDelegation)
+	case endsOn.Before(startsOn):
+		return nil, fmt.Errorf("%w: ends_on cannot be before starts_on", ErrInvalidApprovalDelegation)
+	case endsOn.After(startsOn.AddDate(0, 0, maxDelegationDays-1)):
+		return nil, fmt.Errorf("%w: a delegation can run at most %d days", ErrInvalidApprovalDelegation, maxDelegationDays)
+	}
+
+	delegator, err := s.userRepo.GetByID(ctx, tenantID, *req.DelegatorID)
+	if err != nil || delegator == nil {
+		return nil, fmt.Errorf("%w: delegator not found", ErrInvalidApprovalDelegation)
+	}
+	delegate, err := s.userRepo.GetByID(ctx, tenantID, req.DelegateID)
+	if err != nil || delegate == nil {
+		return nil, fmt.Errorf("%w: delegate not found", ErrInvalidApprovalDelegation)
+	}
+
+	delegation := &models.ApprovalDelegation{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		DelegatorID: delegator.ID,
+		DelegateID:  delegate.ID,
+		Kinds:       kinds,
+		StartsOn:    startsOn,
+		EndsOn:      endsOn,
+		Reason:      trimmedOrNil(req.Reason),
+		CreatedBy:   createdBy,
+	}
+	conflict, err := s.repo.Create(ctx, delegation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create approval delegation: %w", err)
+	}
+	if conflict != nil {
+		return nil, delegationConflict(delegation, conflict)
+	}
+
+	period := fmt.Sprintf("%s to %s", startsOn.Format("2 Jan"), endsOn.Format("2 Jan 2006"))
+	s.notify(ctx, delegation, delegate.ID, "Approvals delegated to you",
+		fmt.Sprintf("You are covering %s's %s approvals from %s", userDisplayName(delegator), strings.Join(kinds, ", "), period))
+	s.notify(ctx, delegation, delegator.ID, "Approvals delegated",
+		fmt.Sprintf("Your %s approvals go to %s from %s", strings.Join(kinds, ", "), userDisplayName(delegate), period))
+	return delegation, nil
+}
+
+// delegationConflict explains why the delegation clashes with an active one
+func delegationConflict(delegation, conflict *models.ApprovalDelegation) error {
+	period := fmt.Sprintf("%s to %s", conflict.StartsOn.Format("2006-01-02"), conflict.EndsOn.Format("2006-01-02"))
+	switch {
+	case conflict.DelegatorID == delegation.DelegatorID:
+		return fmt.Errorf("%w: the approvals are already delegated from %s", ErrApprovalDelegationConflict, period)
+	case conflict.DelegatorID == delegation.DelegateID:
+		return fmt.Errorf("%w: the delegate is delegating their own approvals from %s", ErrApprovalDelegationConflict, period)
+	default:
+		return fmt.Errorf("%w: the delegator is covering another approver from %s", ErrApprovalDelegationConflict, period)
+	}
+}
+
+func (s *approvalDelegationService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.ApprovalDelegation, error) {
+	delegation, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval delegation: %w", err)
+	}
+	if delegation == nil {
+		return nil, ErrApprovalDelegationNotFound
+	}
+	if delegation.Approvals, err = s.repo.ListApprovals(ctx, tenantID, id); err != nil {
+		return nil, fmt.Errorf("failed to load delegated approvals: %w", err)
+	}
+	return delegation, nil
+}
+
+func (s *approvalDelegationService) List(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, includePast bool) ([]*models.ApprovalDelegation, error) {
+	delegations, err := s.repo.List(ctx, tenantID, userID, includePast, s.today(ctx, tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approval delegations: %w", err)
+	}
+	if delegations == nil {
+		delegations = []*models.ApprovalDelegation{}
+	}
+	return delegations, nil
+}
+
+func (s *approvalDelegationService) Revoke(ctx context.Context, tenantID, id uuid.UUID, revokedBy *uuid.UUID) (*models.ApprovalDelegation, error) {
+	revoked, err := s.repo.Revoke(ctx, tenantID, id, revokedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke approval delegation: %w", err)
+	}
+	delegation, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, fmt.Errorf("%w: the delegation is already revoked", ErrApprovalDelegationConflict)
+	}
+
+	body := fmt.Sprintf("The delegation of %s approvals from %s to %s was revoked", strings.Join(delegation.Kinds, ", "),
+		delegation.StartsOn.Format("2 Jan"), delegation.EndsOn.Format("2 Jan 2006"))
+	s.notify(ctx, delegation, delegation.DelegateID, "Approval delegation revoked", body)
+	s.notify(ctx, delegation, delegation.DelegatorID, "Approval delegation revoked", body)
+	return delegation, nil
+}
+
+func (s *approvalDelegationService) ActingFor(ctx context.Context, tenantID, userID uuid.UUID, kind, permission string) (*models.ApprovalDelegation, error) {
+	today := s.today(ctx, tenantID)
+	active, err := s.repo.Active(ctx, tenantID, userID, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval delegations: %w", err)
+	}
+	for _, delegation := range active {
+		if delegation.DelegateID != userID || !delegation.Covers(kind, today) {
+			continue
+		}
+		if permission == "" {
+			return delegation, nil
+		}
+		allowed, err := s.rbacSvc.UserHasPermission(ctx, delegation.DelegatorID, tenantID, permission)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check delegator permission: %w", err)
+		}
+		if allowed {
+			return delegation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *approvalDelegationService) RecordApproval(ctx context.Context, delegation *models.ApprovalDelegation, kind string, entityID uuid.UUID, action string, actedBy *uuid.UUID) {
+	approval := &models.DelegatedApproval{
+		ID:           uuid.New(),
+		TenantID:     delegation.TenantID,
+		DelegationID: delegation.ID,
+		Kind:         kind,
+		EntityID:     entityID,
+		Action:       action,
+		ActedBy:      actedBy,
+	}
+	if err := s.repo.RecordApproval(ctx, approval); err != nil {
+		log.Printf("Failed to record delegated %s %s of %s: %v", kind, action, entityID, err)
+		return
+	}
+	s.notify(ctx, delegation, delegation.DelegatorID, "Approval made on your behalf",
+		fmt.Sprintf("Your delegate %s a %s on your behalf", action, strings.ReplaceAll(kind, "_", " ")))
+}
+
+func (s *approvalDelegationService) Pending(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.PendingApproval, error) {
+	today := s.today(ctx, tenantID)
+	active, err := s.repo.Active(ctx, tenantID, userID, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval delegations: %w", err)
+	}
+
+	// Kinds the user approves themselves, unless they handed them over today
+	own := make(map[string]bool)
+	for _, kind := range models.ApprovalKinds {
+		away := false
+		for _, delegation := range active {
+			if delegation.DelegatorID == userID && delegation.Covers(kind, today) {
+				away = true
+			}
+		}
+		if away {
+			continue
+		}
+		allowed, err := s.mayApprove(ctx, tenantID, userID, kind)
+		if err != nil {
+			return nil, err
+		}
+		own[kind] = allowed
+	}
+
+	// Kinds routed to the user by the delegators they are covering
+	routed := make(map[string]uuid.UUID)
+	for _, delegation := range active {
+		if delegation.DelegateID != userID {
+			continue
+		}
+		for _, kind := range delegation.Kinds {
+			if _, taken := routed[kind]; taken || !delegation.Covers(kind, today) {
+				continue
+			}
+			allowed, err := s.mayApprove(ctx, tenantID, delegation.DelegatorID, kind)
+			if err != nil {
+				return nil, err
+			}
+			if allowed {
+				routed[kind] = delegation.DelegatorID
+			}
+		}
+	}
+
+	var kinds []string
+	for _, kind := range models.ApprovalKinds {
+		if _, ok := routed[kind]; own[kind] || ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return []*models.PendingApproval{}, nil
+	}
+	pending, err := s.repo.PendingApprovals(ctx, tenantID, kinds, pendingApprovalLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending approvals: %w", err)
+	}
+	for _, item := range pending {
+		if delegatorID, ok := routed[item.Kind]; ok {
+			item.OnBehalfOf = &delegatorID
+		}
+	}
+	if pending == nil {
+		pending = []*models.PendingApproval{}
+	}
+	return pending, nil
+}
+
+// mayApprove checks the user holds the permission to approve the kind
+func (s *approvalDelegationService) mayApprove(ctx context.Context, tenantID, userID uuid.UUID, kind string) (bool, error) {
+	permission := approvalPermissions[kind]
+	if permission == "" {
+		return true, nil
+	}
+	allowed, err := s.rbacSvc.UserHasPermission(ctx, userID, tenantID, permission)
+	if err != nil {
+		return false, fmt.Errorf("failed to check approval permission: %w", err)
+	}
+	return allowed, nil
+}
+
+// notify pushes a delegation notice to one of its parties; push failures
+// don't fail the change being reported
+func (s *approvalDelegationService) notify(ctx context.Context, delegation *models.ApprovalDelegation, userID uuid.UUID, title, body string) {
+	if s.pushSvc == nil {
+		return
+	}
+	msg := &models.PushMessage{
+		Title: title,
+		Body:  body,
+		Data:  map[string]string{"event_type": "approval_delegation", "delegation_id": delegation.ID.String()},
+	}
+	if _, err := s.pushSvc.SendToUser(ctx, delegation.TenantID, userID, msg); err != nil {
+		log.Printf("Failed to notify user %s of approval delegation %s: %v", userID, delegation.ID, err)
+	}
+}
+
+// userDisplayName is the user's full name, or their email without one
+func userDisplayName(user *models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Email
+}
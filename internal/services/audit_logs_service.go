@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"agromart2/internal/common"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
@@ -75,6 +76,11 @@ func (s *auditLogsService) LogActivity(ctx context.Context, tenantID uuid.UUID,
 		CreatedAt:  time.Now(),
 	}
 
+	// Requests made under impersonation carry both identities
+	if impersonatorID, ok := common.RequestContextFrom(ctx).Impersonator(); ok {
+		auditLog.ImpersonatorID = &impersonatorID
+	}
+
 	return s.auditLogsRepo.Create(ctx, auditLog)
 }
 
@@ -27,6 +27,15 @@ type AuthService interface {
 	ValidateToken(ctx context.Context, token string) (*TokenClaims, error)
 	RevokeToken(ctx context.Context, token string, tokenType *string) error
 
+	// Brute-force protection and login anomaly detection
+	CheckLoginAllowed(ctx context.Context, email, ip string) error
+	RecordFailedLogin(ctx context.Context, email, ip string) error
+	RecordSuccessfulLogin(ctx context.Context, user *models.User, ip, userAgent string) error
+	UnlockAccount(ctx context.Context, email string) error
+
+	// Impersonation (short-lived access token only, no refresh token)
+	GenerateImpersonationToken(ctx context.Context, targetUserID, tenantID, impersonatorID uuid.UUID, ttl time.Duration) (*models.TokenResponse, error)
+
 	// OAuth2 flows
 	GenerateAuthorizationCode(ctx context.Context, userID, tenantID uuid.UUID, clientID string, redirectURI, scope *string) (string, error)
 	ValidateAuthorizationCode(ctx context.Context, code, clientID, redirectURI string) (*AuthorizationCodeClaims, error)
@@ -35,12 +44,20 @@ type AuthService interface {
 	// Refresh token management
 	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
 	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
+	IsTokenRevoked(ctx context.Context, tokenID string, userID uuid.UUID, issuedAt time.Time) bool
 	CleanupExpiredTokens(ctx context.Context) error
 }
 
+// Default token lifetimes in seconds
+const (
+	DefaultAccessTokenTTL  = 3600  // 1 hour
+	DefaultRefreshTokenTTL = 86400 // 24 hours
+)
+
 type authService struct {
 	cacheSvc    caching.CacheService
-	jwtSecret   []byte
+	notificationSvc NotificationService
+	keyRing     *KeyRing
 	tokenTTL    int // Access token TTL in seconds
 	refreshTTL  int // Refresh token TTL in seconds
 }
@@ -52,6 +69,7 @@ type TokenClaims struct {
 	Scope    *string `json:"scope,omitempty"`
 	TokenID  string `json:"token_id"`
 	ClientID *string `json:"client_id,omitempty"`
+	ImpersonatorID string `json:"impersonator_id,omitempty"` // Set when a platform admin acts as this user
 	jwt.RegisteredClaims
 }
 
@@ -66,10 +84,11 @@ type AuthorizationCodeClaims struct {
 }
 
 // NewAuthService creates a new authentication service
-func NewAuthService(cacheSvc caching.CacheService, jwtSecret string, tokenTTLSeconds, refreshTTLSeconds int) AuthService {
+func NewAuthService(cacheSvc caching.CacheService, notificationSvc NotificationService, keyRing *KeyRing, tokenTTLSeconds, refreshTTLSeconds int) AuthService {
 	return &authService{
-		cacheSvc:   cacheSvc,
-		jwtSecret:  []byte(jwtSecret),
+		cacheSvc:        cacheSvc,
+		notificationSvc: notificationSvc,
+		keyRing:         keyRing,
 		tokenTTL:   tokenTTLSeconds,
 		refreshTTL: refreshTTLSeconds,
 	}
@@ -97,8 +116,7 @@ func (s *authService) GenerateTokens(ctx context.Context, userID, tenantID uuid.
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessTokenString, err := accessToken.SignedString(s.jwtSecret)
+	accessTokenString, err := s.keyRing.Sign(claims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign JWT: %v", err)
 	}
@@ -133,6 +151,50 @@ func (s *authService) GenerateTokens(ctx context.Context, userID, tenantID uuid.
 	return response, nil
 }
 
+// GenerateImpersonationToken mints a short-lived access token acting as targetUserID on behalf of impersonatorID.
+// No refresh token is issued so the session cannot outlive ttl.
+func (s *authService) GenerateImpersonationToken(ctx context.Context, targetUserID, tenantID, impersonatorID uuid.UUID, ttl time.Duration) (*models.TokenResponse, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("impersonation ttl must be positive")
+	}
+
+	now := time.Now()
+	tokenID := uuid.NewString()
+	scope := "impersonation"
+
+	claims := TokenClaims{
+		UserID:         targetUserID.String(),
+		TenantID:       tenantID.String(),
+		Scope:          &scope,
+		TokenID:        tokenID,
+		ImpersonatorID: impersonatorID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "agromart-auth",
+			Subject:   targetUserID.String(),
+			Audience:  jwt.ClaimStrings{"agromart-api"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        tokenID,
+		},
+	}
+
+	accessTokenString, err := s.keyRing.Sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign JWT: %v", err)
+	}
+
+	return &models.TokenResponse{
+		AccessToken: accessTokenString,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+		Scope:       &scope,
+		UserID:      targetUserID.String(),
+		TenantID:    tenantID.String(),
+		TokenID:     tokenID,
+		IssuedAt:    now,
+	}, nil
+}
+
 // RefreshToken validates and uses refresh token to generate new tokens
 func (s *authService) RefreshToken(ctx context.Context, refreshToken string, clientID *string) (*models.TokenResponse, error) {
 	refreshTokenHash, err := s.hashToken(refreshToken)
@@ -179,15 +241,20 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string, cli
 		return nil, fmt.Errorf("invalid tenant ID in token")
 	}
 
+	// Refresh tokens issued before the user's tokens were revoked are dead
+	issuedAt := time.Unix(expiry-int64(s.refreshTTL), 0)
+	if s.revokedBefore(ctx, userID, issuedAt) {
+		s.cacheSvc.Delete(ctx, cacheKey)
+		return nil, fmt.Errorf("refresh token revoked")
+	}
+
 	// Generate new tokens
 	return s.GenerateTokens(ctx, userID, tenantID, nil)
 }
 
 // ValidateToken validates JWT access token
 func (s *authService) ValidateToken(ctx context.Context, token string) (*TokenClaims, error) {
-	jwtToken, err := jwt.ParseWithClaims(token, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return s.jwtSecret, nil
-	})
+	jwtToken, err := jwt.ParseWithClaims(token, &TokenClaims{}, s.keyRing.Keyfunc, jwt.WithValidMethods(s.keyRing.ValidMethods()))
 
 	if err != nil {
 		return nil, fmt.Errorf("token validation failed: %v", err)
@@ -346,14 +413,52 @@ func (s *authService) GetRefreshToken(ctx context.Context, tokenHash string) (*m
 	}, nil
 }
 
-// RevokeUserTokens revokes all tokens for a user
+// RevokeUserTokens revokes all tokens issued to a user so far. Tokens are not
+// indexed by user, so the revocation time is recorded instead and every
+// token issued up to then is refused until the longest of them has expired.
 func (s *authService) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
-	// In production, this would query database for all user tokens
-	log.Printf("Revoking all tokens for user %s", userID.String())
-	// Implementation would batch delete all user tokens from cache/database
+	ttl := s.refreshTTL
+	if s.tokenTTL > ttl {
+		ttl = s.tokenTTL
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := s.cacheSvc.SetString(ctx, userTokensRevokedKey(userID), now, time.Duration(ttl)*time.Second); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %v", err)
+	}
+	log.Printf("Revoked all tokens for user %s", userID.String())
 	return nil
 }
 
+// IsTokenRevoked reports whether an access token was revoked on its own or
+// along with all of its user's tokens. Cache errors fail open, as login
+// lockouts do.
+func (s *authService) IsTokenRevoked(ctx context.Context, tokenID string, userID uuid.UUID, issuedAt time.Time) bool {
+	if tokenID != "" {
+		if value, err := s.cacheSvc.GetString(ctx, fmt.Sprintf("token_blacklist:%s", tokenID)); err == nil && value != "" {
+			return true
+		}
+	}
+	return s.revokedBefore(ctx, userID, issuedAt)
+}
+
+// revokedBefore reports whether a token of the user issued at issuedAt was
+// revoked by RevokeUserTokens
+func (s *authService) revokedBefore(ctx context.Context, userID uuid.UUID, issuedAt time.Time) bool {
+	value, err := s.cacheSvc.GetString(ctx, userTokensRevokedKey(userID))
+	if err != nil || value == "" {
+		return false
+	}
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	return issuedAt.Unix() <= revokedAt
+}
+
+func userTokensRevokedKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user_tokens_revoked:%s", userID)
+}
+
 // CleanupExpiredTokens removes expired tokens from storage
 func (s *authService) CleanupExpiredTokens(ctx context.Context) error {
 	log.Println("Cleaning up expired tokens")
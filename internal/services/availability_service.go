@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// availabilityCacheTTL is short: availability changes with every order and stock movement
+const availabilityCacheTTL = 30 * time.Second
+
+type AvailabilityService interface {
+	GetProductAvailability(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductAvailability, error)
+}
+
+type availabilityService struct {
+	availabilityRepo repositories.AvailabilityRepository
+	productRepo      repositories.ProductRepository
+	bundleRepo       repositories.BundleRepository
+	cacheService     caching.CacheService
+}
+
+func NewAvailabilityService(availabilityRepo repositories.AvailabilityRepository, productRepo repositories.ProductRepository, bundleRepo repositories.BundleRepository, cacheService caching.CacheService) AvailabilityService {
+	return &availabilityService{
+		availabilityRepo: availabilityRepo,
+		productRepo:      productRepo,
+		bundleRepo:       bundleRepo,
+		cacheService:     cacheService,
+	}
+}
+
+func availabilityCacheKey(tenantID, productID uuid.UUID) string {
+	return fmt.Sprintf("availability:%s:%s", tenantID.String(), productID.String())
+}
+
+// GetProductAvailability returns on-hand, reserved, available-to-promise and
+// in-transit quantities per warehouse and in total
+func (s *availabilityService) GetProductAvailability(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductAvailability, error) {
+	key := availabilityCacheKey(tenantID, productID)
+	if cached, err := s.cacheService.GetString(ctx, key); err == nil && cached != "" {
+		availability := &models.ProductAvailability{}
+		if err := json.Unmarshal([]byte(cached), availability); err == nil {
+			return availability, nil
+		}
+	}
+
+	product, err := s.productRepo.GetByID(ctx, tenantID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	warehouses, err := s.availabilityRepo.GetByProduct(ctx, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product != nil && product.IsBundle {
+		if warehouses, err = s.mergeBuildable(ctx, tenantID, productID, warehouses); err != nil {
+			return nil, err
+		}
+	}
+
+	availability := &models.ProductAvailability{
+		ProductID:    productID,
+		Warehouses:   warehouses,
+		CalculatedAt: time.Now(),
+	}
+	if availability.Warehouses == nil {
+		availability.Warehouses = []*models.WarehouseAvailability{}
+	}
+	for _, wa := range warehouses {
+		wa.AvailableToPromise = max(wa.OnHand+wa.Buildable-wa.Reserved, 0)
+		availability.OnHand += wa.OnHand
+		availability.Reserved += wa.Reserved
+		availability.AvailableToPromise += wa.AvailableToPromise
+		availability.InTransit += wa.InTransit
+		availability.Buildable += wa.Buildable
+	}
+
+	if payload, err := json.Marshal(availability); err == nil {
+		if cacheErr := s.cacheService.SetString(ctx, key, string(payload), availabilityCacheTTL); cacheErr != nil {
+			fmt.Printf("Failed to cache availability for product %s: %v\n", productID.String(), cacheErr)
+		}
+	}
+
+	return availability, nil
+}
+
+// mergeBuildable adds how many bundles each warehouse can assemble from
+// component stock, including warehouses holding no assembled bundles
+func (s *availabilityService) mergeBuildable(ctx context.Context, tenantID, bundleID uuid.UUID, warehouses []*models.WarehouseAvailability) ([]*models.WarehouseAvailability, error) {
+	buildable, err := s.bundleRepo.Buildable(ctx, tenantID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	byWarehouse := make(map[uuid.UUID]*models.WarehouseAvailability, len(warehouses))
+	for _, wa := range warehouses {
+		byWarehouse[wa.WarehouseID] = wa
+	}
+	for _, b := range buildable {
+		if wa, ok := byWarehouse[b.WarehouseID]; ok {
+			wa.Buildable = b.Buildable
+			continue
+		}
+		warehouses = append(warehouses, b)
+	}
+	return warehouses, nil
+}
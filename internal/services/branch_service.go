@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBranchNotFound is returned for branches outside the tenant
+	ErrBranchNotFound = errors.New("branch not found")
+	// ErrInvalidBranch wraps branch validation failures
+	ErrInvalidBranch = errors.New("invalid branch")
+)
+
+// branchCodePattern matches a branch code, which appears in invoice numbers
+var branchCodePattern = regexp.MustCompile(`^[A-Za-z0-9]{1,10}$`)
+
+// BranchService keeps the tenant's branches, the legal entities or places of
+// business each registered under their own GSTIN
+type BranchService interface {
+	Create(ctx context.Context, tenantID uuid.UUID, branch *models.Branch) error
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.Branch, error)
+	List(ctx context.Context, tenantID uuid.UUID, includeArchived bool) ([]*models.Branch, error)
+	// Update changes the branch's names and address; the code and GSTIN
+	// identify its invoice series and returns, so they are fixed
+	Update(ctx context.Context, tenantID uuid.UUID, branch *models.Branch) (*models.Branch, error)
+	// Delete removes the branch, or archives it when records reference it.
+	// A branch with active warehouses is kept until they are moved
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (archived bool, err error)
+	// CheckActive checks the branch exists and is not archived, for records
+	// being attached to it
+	CheckActive(ctx context.Context, tenantID, id uuid.UUID) error
+}
+
+type branchService struct {
+	branchRepo    repositories.BranchRepository
+	warehouseRepo repositories.WarehouseRepository
+}
+
+// NewBranchService creates a new branch service instance
+func NewBranchService(branchRepo repositories.BranchRepository, warehouseRepo repositories.WarehouseRepository) BranchService {
+	return &branchService{
+		branchRepo:    branchRepo,
+		warehouseRepo: warehouseRepo,
+	}
+}
+
+// normalizeBranch trims the branch's names, dropping blank optional ones
+func normalizeBranch(branch *models.Branch) error {
+	branch.Name = strings.TrimSpace(branch.Name)
+	if branch.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidBranch)
+	}
+	if branch.LegalName != nil && strings.TrimSpace(*branch.LegalName) == "" {
+		branch.LegalName = nil
+	}
+	if branch.Address != nil && strings.TrimSpace(*branch.Address) == "" {
+		branch.Address = nil
+	}
+	return nil
+}
+
+func (s *branchService) Create(ctx context.Context, tenantID uuid.UUID, branch *models.Branch) error {
+	if err := normalizeBranch(branch); err != nil {
+		return err
+	}
+	branch.Code = strings.ToUpper(strings.TrimSpace(branch.Code))
+	if !branchCodePattern.MatchString(branch.Code) {
+		return fmt.Errorf("%w: code must be 1 to 10 letters or digits", ErrInvalidBranch)
+	}
+	gstin := &branch.GSTIN
+	if err := normalizeGSTIN(&gstin); err != nil {
+		return err
+	}
+	if gstin == nil {
+		return fmt.Errorf("%w: gstin is required", ErrInvalidBranch)
+	}
+	branch.GSTIN = *gstin
+	branch.StateCode = branch.GSTIN[:2]
+
+	existing, err := s.branchRepo.GetByCodeOrGSTIN(ctx, tenantID, branch.Code, branch.GSTIN)
+	if err != nil {
+		return common.SecureErrorMessage("check branch uniqueness", err)
+	}
+	if existing != nil {
+		if existing.GSTIN == branch.GSTIN {
+			return fmt.Errorf("%w: branch %s already uses this GSTIN", ErrDuplicateName, existing.Code)
+		}
+		return fmt.Errorf("%w: branch code %s is already in use", ErrDuplicateName, branch.Code)
+	}
+
+	branch.ID = uuid.New()
+	branch.TenantID = tenantID
+	if err := s.branchRepo.Create(ctx, branch); err != nil {
+		return common.SecureErrorMessage("create branch", err)
+	}
+	return nil
+}
+
+func (s *branchService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.Branch, error) {
+	branch, err := s.branchRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get branch", err)
+	}
+	if branch == nil {
+		return nil, ErrBranchNotFound
+	}
+	return branch, nil
+}
+
+func (s *branchService) List(ctx context.Context, tenantID uuid.UUID, includeArchived bool) ([]*models.Branch, error) {
+	branches, err := s.branchRepo.List(ctx, tenantID, includeArchived)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list branches", err)
+	}
+	return branches, nil
+}
+
+func (s *branchService) Update(ctx context.Context, tenantID uuid.UUID, update *models.Branch) (*models.Branch, error) {
+	branch, err := s.Get(ctx, tenantID, update.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeBranch(update); err != nil {
+		return nil, err
+	}
+	branch.Name, branch.LegalName, branch.Address = update.Name, update.LegalName, update.Address
+	if err := s.branchRepo.Update(ctx, branch); err != nil {
+		return nil, common.SecureErrorMessage("update branch", err)
+	}
+	return branch, nil
+}
+
+func (s *branchService) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	if _, err := s.Get(ctx, tenantID, id); err != nil {
+		return false, err
+	}
+	warehouses, err := s.warehouseRepo.ListPage(ctx, tenantID, &id, listquery.Page{Limit: 1})
+	if err != nil {
+		return false, common.SecureErrorMessage("check branch warehouses", err)
+	}
+	if len(warehouses) > 0 {
+		return false, fmt.Errorf("%w: move the branch's warehouses to another branch first", ErrInvalidBranch)
+	}
+
+	used, err := s.branchRepo.UsageCount(ctx, tenantID, id)
+	if err != nil {
+		return false, common.SecureErrorMessage("check branch usage", err)
+	}
+	if used > 0 {
+		if err := s.branchRepo.Archive(ctx, tenantID, id); err != nil {
+			return false, common.SecureErrorMessage("archive branch", err)
+		}
+		return true, nil
+	}
+	if err := s.branchRepo.Delete(ctx, tenantID, id); err != nil {
+		return false, common.SecureErrorMessage("delete branch", err)
+	}
+	return false, nil
+}
+
+func (s *branchService) CheckActive(ctx context.Context, tenantID, id uuid.UUID) error {
+	branch, err := s.branchRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return common.SecureErrorMessage("get branch", err)
+	}
+	if branch == nil {
+		return fmt.Errorf("%w: branch not found", ErrInvalidBranch)
+	}
+	if branch.ArchivedAt != nil {
+		return fmt.Errorf("%w: branch %s is archived", ErrInvalidBranch, branch.Code)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// BulkAsyncThreshold is the largest bulk operation run within the request;
+	// larger ones run in the background and are polled by operation ID
+	BulkAsyncThreshold = 100
+
+	// bulkResultRetention is how long finished operations stay available
+	bulkResultRetention = time.Hour
+)
+
+// ErrBulkItemBlocked is wrapped by item errors where a business rule, rather
+// than a failure, stops the item; such items are reported as blocked
+var ErrBulkItemBlocked = errors.New("blocked by business rule")
+
+// BulkItemFunc processes one item of a bulk operation. In a dry run it only
+// checks whether the item could be processed
+type BulkItemFunc func(ctx context.Context, id uuid.UUID, dryRun bool) error
+
+// BulkOperationService runs bulk operations and keeps their results for polling
+type BulkOperationService interface {
+	// Run applies fn to each id. Sets up to BulkAsyncThreshold items are
+	// processed before returning; larger sets return a pending result at once
+	Run(ctx context.Context, tenantID uuid.UUID, operation string, ids []uuid.UUID, dryRun bool, fn BulkItemFunc) *models.BulkOperationResult
+	// Get returns a snapshot of one of the tenant's operations, or nil if it
+	// is unknown or has expired
+	Get(tenantID uuid.UUID, operationID string) *models.BulkOperationResult
+}
+
+type bulkOperation struct {
+	tenantID uuid.UUID
+	result   *models.BulkOperationResult
+}
+
+type bulkOperationService struct {
+	mu         sync.Mutex
+	operations map[string]*bulkOperation
+}
+
+// NewBulkOperationService creates a new bulk operation service instance
+func NewBulkOperationService() BulkOperationService {
+	return &bulkOperationService{operations: make(map[string]*bulkOperation)}
+}
+
+func (s *bulkOperationService) Run(ctx context.Context, tenantID uuid.UUID, operation string, ids []uuid.UUID, dryRun bool, fn BulkItemFunc) *models.BulkOperationResult {
+	op := &bulkOperation{
+		tenantID: tenantID,
+		result: &models.BulkOperationResult{
+			OperationID: fmt.Sprintf("%s_%s", operation, uuid.New()),
+			Status:      "pending",
+			DryRun:      dryRun,
+			TotalItems:  len(ids),
+			StartTime:   time.Now(),
+			Errors:      []models.BulkOperationError{},
+			Items:       []models.BulkOperationItem{},
+		},
+	}
+
+	s.mu.Lock()
+	s.pruneLocked()
+	s.operations[op.result.OperationID] = op
+	s.mu.Unlock()
+
+	if len(ids) <= BulkAsyncThreshold {
+		s.process(ctx, op, ids, dryRun, fn)
+		return s.snapshot(op)
+	}
+
+	snapshot := s.snapshot(op)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Panic in bulk operation %s: %v", snapshot.OperationID, r)
+				s.finish(op, "failed")
+			}
+		}()
+		// The request that started the operation is gone by the time it runs
+		s.process(context.WithoutCancel(ctx), op, ids, dryRun, fn)
+	}()
+	return snapshot
+}
+
+func (s *bulkOperationService) Get(tenantID uuid.UUID, operationID string) *models.BulkOperationResult {
+	s.mu.Lock()
+	op, ok := s.operations[operationID]
+	s.mu.Unlock()
+	if !ok || op.tenantID != tenantID {
+		return nil
+	}
+	return s.snapshot(op)
+}
+
+func (s *bulkOperationService) process(ctx context.Context, op *bulkOperation, ids []uuid.UUID, dryRun bool, fn BulkItemFunc) {
+	s.mu.Lock()
+	op.result.Status = "processing"
+	s.mu.Unlock()
+
+	for i, id := range ids {
+		item := models.BulkOperationItem{ItemIndex: i, ItemID: id.String(), Status: "success"}
+		err := fn(ctx, id, dryRun)
+
+		s.mu.Lock()
+		result := op.result
+		if err != nil {
+			msg := err.Error()
+			item.Error = &msg
+			item.Status = "failed"
+			var conflict *DependencyConflictError
+			switch {
+			case errors.As(err, &conflict):
+				item.Status = "blocked"
+				item.BlockedBy = conflict.References
+			case errors.Is(err, ErrBulkItemBlocked):
+				item.Status = "blocked"
+			}
+			if item.Status == "blocked" {
+				result.BlockedItems++
+			} else {
+				result.FailedItems++
+			}
+			result.Errors = append(result.Errors, models.BulkOperationError{ItemIndex: i, ItemID: item.ItemID, Error: msg})
+		} else {
+			result.ProcessedItems++
+		}
+		result.Items = append(result.Items, item)
+		result.Progress = float64(i+1) / float64(len(ids)) * 100
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	processed := op.result.ProcessedItems
+	s.mu.Unlock()
+
+	status := "completed"
+	if processed == 0 && len(ids) > 0 {
+		status = "failed"
+	} else if processed < len(ids) {
+		status = "partial"
+	}
+	s.finish(op, status)
+}
+
+func (s *bulkOperationService) finish(op *bulkOperation, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	op.result.Status = status
+	op.result.CompletionTime = &now
+}
+
+// snapshot copies an operation's result so callers can read it while the
+// operation is still running
+func (s *bulkOperationService) snapshot(op *bulkOperation) *models.BulkOperationResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := *op.result
+	result.Errors = append([]models.BulkOperationError(nil), op.result.Errors...)
+	result.Items = append([]models.BulkOperationItem(nil), op.result.Items...)
+	return &result
+}
+
+// pruneLocked drops operations that finished more than bulkResultRetention ago
+func (s *bulkOperationService) pruneLocked() {
+	cutoff := time.Now().Add(-bulkResultRetention)
+	for id, op := range s.operations {
+		if op.result.CompletionTime != nil && op.result.CompletionTime.Before(cutoff) {
+			delete(s.operations, id)
+		}
+	}
+}
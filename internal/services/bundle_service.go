@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBundleNotFound is returned for products outside the tenant or without components
+	ErrBundleNotFound = errors.New("bundle not found")
+	// ErrInvalidBundle wraps bundle definition and build validation failures
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrInsufficientStock is returned when a warehouse cannot cover a build or sale
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// BundleService manages kits built from component products: their bill of
+// materials, assembly and disassembly, and sales drawn from components
+type BundleService interface {
+	GetComponents(ctx context.Context, tenantID, bundleID uuid.UUID) ([]*models.BundleComponent, error)
+	SetComponents(ctx context.Context, tenantID, bundleID uuid.UUID, components []*models.BundleComponent) ([]*models.BundleComponent, error)
+	Assemble(ctx context.Context, tenantID, bundleID, warehouseID uuid.UUID, quantity int) (*models.BundleBuildResult, error)
+	Disassemble(ctx context.Context, tenantID, bundleID, warehouseID uuid.UUID, quantity int) (*models.BundleBuildResult, error)
+	ConsumeForSale(ctx context.Context, tenantID uuid.UUID, order *models.Order) (bool, error)
+	RestoreSale(ctx context.Context, tenantID, orderID uuid.UUID) (bool, error)
+}
+
+type bundleService struct {
+	bundleRepo       repositories.BundleRepository
+	productRepo      repositories.ProductRepository
+	inventoryRepo    repositories.InventoryRepository
+	inventoryService InventoryService
+}
+
+// NewBundleService creates a new bundle service instance
+func NewBundleService(bundleRepo repositories.BundleRepository, productRepo repositories.ProductRepository, inventoryRepo repositories.InventoryRepository, inventoryService InventoryService) BundleService {
+	return &bundleService{
+		bundleRepo:       bundleRepo,
+		productRepo:      productRepo,
+		inventoryRepo:    inventoryRepo,
+		inventoryService: inventoryService,
+	}
+}
+
+func (s *bundleService) GetComponents(ctx context.Context, tenantID, bundleID uuid.UUID) ([]*models.BundleComponent, error) {
+	if _, err := s.productRepo.GetByID(ctx, tenantID, bundleID); err != nil {
+		return nil, ErrBundleNotFound
+	}
+	return s.bundleRepo.GetComponents(ctx, tenantID, bundleID)
+}
+
+// SetComponents replaces a product's bill of materials. Components must be
+// plain products: nesting bundles would make availability recursive.
+func (s *bundleService) SetComponents(ctx context.Context, tenantID, bundleID uuid.UUID, components []*models.BundleComponent) ([]*models.BundleComponent, error) {
+	if _, err := s.productRepo.GetByID(ctx, tenantID, bundleID); err != nil {
+		return nil, ErrBundleNotFound
+	}
+
+	seen := make(map[uuid.UUID]bool, len(components))
+	for _, component := range components {
+		if component.ComponentID == bundleID {
+			return nil, fmt.Errorf("%w: a bundle cannot contain itself", ErrInvalidBundle)
+		}
+		if seen[component.ComponentID] {
+			return nil, fmt.Errorf("%w: component %s is listed twice", ErrInvalidBundle, component.ComponentID)
+		}
+		seen[component.ComponentID] = true
+		if component.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: component quantity must be positive", ErrInvalidBundle)
+		}
+		product, err := s.productRepo.GetByID(ctx, tenantID, component.ComponentID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: component %s not found", ErrInvalidBundle, component.ComponentID)
+		}
+		if product.IsBundle {
+			return nil, fmt.Errorf("%w: %s is itself a bundle", ErrInvalidBundle, product.Name)
+		}
+		component.BundleID = bundleID
+	}
+
+	if err := s.bundleRepo.SetComponents(ctx, tenantID, bundleID, components); err != nil {
+		return nil, err
+	}
+	return s.bundleRepo.GetComponents(ctx, tenantID, bundleID)
+}
+
+// Assemble consumes components in a warehouse and adds the built bundles to its stock
+func (s *bundleService) Assemble(ctx context.Context, tenantID, bundleID, warehouseID uuid.UUID, quantity int) (*models.BundleBuildResult, error) {
+	components, err := s.bundleComponents(ctx, tenantID, bundleID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkComponentStock(ctx, tenantID, warehouseID, components, quantity); err != nil {
+		return nil, err
+	}
+
+	for _, component := range components {
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, warehouseID, component.ComponentID, -component.Quantity*quantity, models.InventoryReasonBundleAssemble); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, warehouseID, bundleID, quantity, models.InventoryReasonBundleAssemble); err != nil {
+		return nil, err
+	}
+
+	return s.buildResult(ctx, tenantID, bundleID, warehouseID, quantity, components)
+}
+
+// Disassemble breaks assembled bundles back into their components
+func (s *bundleService) Disassemble(ctx context.Context, tenantID, bundleID, warehouseID uuid.UUID, quantity int) (*models.BundleBuildResult, error) {
+	components, err := s.bundleComponents(ctx, tenantID, bundleID, quantity)
+	if err != nil {
+		return nil, err
+	}
+	stock, err := s.stockIn(ctx, tenantID, warehouseID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if stock < quantity {
+		return nil, fmt.Errorf("%w: %d assembled bundles on hand, %d requested", ErrInsufficientStock, stock, quantity)
+	}
+
+	if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, warehouseID, bundleID, -quantity, models.InventoryReasonBundleDisassemble); err != nil {
+		return nil, err
+	}
+	for _, component := range components {
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, warehouseID, component.ComponentID, component.Quantity*quantity, models.InventoryReasonBundleDisassemble); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.buildResult(ctx, tenantID, bundleID, warehouseID, quantity, components)
+}
+
+// ConsumeForSale takes stock for a sales order of a bundle: assembled bundle
+// units first, the remainder exploded into component movements. It reports
+// false for orders that are not bundle sales, leaving the caller to deduct stock.
+func (s *bundleService) ConsumeForSale(ctx context.Context, tenantID uuid.UUID, order *models.Order) (bool, error) {
+	if order.OrderType != "sales" {
+		return false, nil
+	}
+	components, err := s.bundleRepo.GetComponents(ctx, tenantID, order.ProductID)
+	if err != nil {
+		return false, err
+	}
+	if len(components) == 0 {
+		return false, nil
+	}
+
+	stock, err := s.stockIn(ctx, tenantID, order.WarehouseID, order.ProductID)
+	if err != nil {
+		return true, err
+	}
+	bundleUnits := min(stock, order.Quantity)
+	exploded := order.Quantity - bundleUnits
+	if exploded > 0 {
+		if err := s.checkComponentStock(ctx, tenantID, order.WarehouseID, components, exploded); err != nil {
+			return true, err
+		}
+	}
+
+	sale := &models.BundleSale{
+		OrderID:       order.ID,
+		TenantID:      tenantID,
+		BundleID:      order.ProductID,
+		WarehouseID:   order.WarehouseID,
+		BundleUnits:   bundleUnits,
+		ExplodedUnits: exploded,
+	}
+	if bundleUnits > 0 {
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, order.WarehouseID, order.ProductID, -bundleUnits, models.InventoryReasonBundleSale); err != nil {
+			return true, err
+		}
+	}
+	if exploded > 0 {
+		for _, component := range components {
+			if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, order.WarehouseID, component.ComponentID, -component.Quantity*exploded, models.InventoryReasonBundleSale); err != nil {
+				return true, err
+			}
+			sale.Components = append(sale.Components, &models.BundleComponent{
+				BundleID:    order.ProductID,
+				ComponentID: component.ComponentID,
+				Quantity:    component.Quantity * exploded,
+			})
+		}
+	}
+
+	if err := s.bundleRepo.CreateSale(ctx, sale); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// RestoreSale returns a cancelled bundle sale's stock exactly as it was drawn;
+// it reports false when the order was not a bundle sale
+func (s *bundleService) RestoreSale(ctx context.Context, tenantID, orderID uuid.UUID) (bool, error) {
+	sale, err := s.bundleRepo.GetSale(ctx, tenantID, orderID)
+	if err != nil {
+		return false, err
+	}
+	if sale == nil {
+		return false, nil
+	}
+
+	if sale.BundleUnits > 0 {
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, sale.WarehouseID, sale.BundleID, sale.BundleUnits, models.InventoryReasonBundleSaleReversal); err != nil {
+			return true, err
+		}
+	}
+	for _, component := range sale.Components {
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, sale.WarehouseID, component.ComponentID, component.Quantity, models.InventoryReasonBundleSaleReversal); err != nil {
+			return true, err
+		}
+	}
+
+	return true, s.bundleRepo.DeleteSale(ctx, tenantID, orderID)
+}
+
+func (s *bundleService) bundleComponents(ctx context.Context, tenantID, bundleID uuid.UUID, quantity int) ([]*models.BundleComponent, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidBundle)
+	}
+	components, err := s.bundleRepo.GetComponents(ctx, tenantID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) == 0 {
+		return nil, ErrBundleNotFound
+	}
+	return components, nil
+}
+
+// checkComponentStock verifies every component before any stock moves, so a
+// short component never leaves a build half applied
+func (s *bundleService) checkComponentStock(ctx context.Context, tenantID, warehouseID uuid.UUID, components []*models.BundleComponent, quantity int) error {
+	for _, component := range components {
+		available, err := s.stockIn(ctx, tenantID, warehouseID, component.ComponentID)
+		if err != nil {
+			return err
+		}
+		if required := component.Quantity * quantity; available < required {
+			return fmt.Errorf("%w: %s needs %d, %d on hand", ErrInsufficientStock, component.ComponentName, required, available)
+		}
+	}
+	return nil
+}
+
+// stockIn returns a product's quantity in one warehouse, zero when it has no inventory row
+func (s *bundleService) stockIn(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (int, error) {
+	stock, err := s.inventoryRepo.ListByProduct(ctx, tenantID, productID)
+	if err != nil {
+		return 0, err
+	}
+	for _, inv := range stock {
+		if inv.WarehouseID == warehouseID {
+			return inv.Quantity, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *bundleService) buildResult(ctx context.Context, tenantID, bundleID, warehouseID uuid.UUID, quantity int, components []*models.BundleComponent) (*models.BundleBuildResult, error) {
+	result := &models.BundleBuildResult{
+		BundleID:    bundleID,
+		WarehouseID: warehouseID,
+		Quantity:    quantity,
+	}
+	stock, err := s.stockIn(ctx, tenantID, warehouseID, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	result.BundleStock = stock
+	for _, component := range components {
+		available, err := s.stockIn(ctx, tenantID, warehouseID, component.ComponentID)
+		if err != nil {
+			return nil, err
+		}
+		result.Components = append(result.Components, &models.BundleComponentStock{
+			ComponentID: component.ComponentID,
+			Required:    component.Quantity * quantity,
+			Available:   available,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// catalogLowStockThreshold is the total quantity at or below which a product is flagged low_stock
+const catalogLowStockThreshold = 10
+
+// catalogTokenPrefix marks storefront tokens so they are recognisable in logs and configs
+const catalogTokenPrefix = "cat_"
+
+// ErrCatalogProductNotFound is returned when publishing a product outside the tenant
+var ErrCatalogProductNotFound = errors.New("product not found")
+
+// CatalogService manages product publishing and the token-scoped storefront catalog
+type CatalogService interface {
+	CreateToken(ctx context.Context, tenantID uuid.UUID, name string, createdBy *uuid.UUID) (*models.CatalogToken, string, error)
+	ListTokens(ctx context.Context, tenantID uuid.UUID) ([]*models.CatalogToken, error)
+	RevokeToken(ctx context.Context, tenantID, id uuid.UUID) error
+	Authenticate(ctx context.Context, rawToken string) (*models.CatalogToken, error)
+	SetPublished(ctx context.Context, tenantID, productID uuid.UUID, published bool) error
+	ListPublished(ctx context.Context, tenantID uuid.UUID, categoryID *uuid.UUID, locale string, limit, offset int) ([]*models.CatalogProduct, error)
+	GetPublished(ctx context.Context, tenantID, productID uuid.UUID, locale string) (*models.CatalogProduct, error)
+	GetImageURL(ctx context.Context, tenantID, imageID uuid.UUID, expiry time.Duration) (string, error)
+}
+
+type catalogService struct {
+	catalogRepo  repositories.CatalogRepository
+	productRepo  repositories.ProductRepository
+	minioService MinioService
+}
+
+// NewCatalogService creates a new catalog service
+func NewCatalogService(catalogRepo repositories.CatalogRepository, productRepo repositories.ProductRepository, minioService MinioService) CatalogService {
+	return &catalogService{
+		catalogRepo:  catalogRepo,
+		productRepo:  productRepo,
+		minioService: minioService,
+	}
+}
+
+func hashCatalogToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken issues a new token; the raw value is returned only once
+func (s *catalogService) CreateToken(ctx context.Context, tenantID uuid.UUID, name string, createdBy *uuid.UUID) (*models.CatalogToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("token name is required")
+	}
+	if len(name) > 100 {
+		return nil, "", fmt.Errorf("token name must be at most 100 characters")
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	rawToken := catalogTokenPrefix + hex.EncodeToString(secret)
+
+	token := &models.CatalogToken{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Name:        name,
+		TokenPrefix: rawToken[:len(catalogTokenPrefix)+8],
+		CreatedBy:   createdBy,
+	}
+	if err := s.catalogRepo.CreateToken(ctx, token, hashCatalogToken(rawToken)); err != nil {
+		return nil, "", err
+	}
+	return token, rawToken, nil
+}
+
+func (s *catalogService) ListTokens(ctx context.Context, tenantID uuid.UUID) ([]*models.CatalogToken, error) {
+	return s.catalogRepo.ListTokens(ctx, tenantID)
+}
+
+func (s *catalogService) RevokeToken(ctx context.Context, tenantID, id uuid.UUID) error {
+	return s.catalogRepo.RevokeToken(ctx, tenantID, id)
+}
+
+// Authenticate resolves a raw token to its active catalog token
+func (s *catalogService) Authenticate(ctx context.Context, rawToken string) (*models.CatalogToken, error) {
+	if !strings.HasPrefix(rawToken, catalogTokenPrefix) {
+		return nil, fmt.Errorf("invalid catalog token")
+	}
+	token, err := s.catalogRepo.GetTokenByHash(ctx, hashCatalogToken(rawToken))
+	if err != nil {
+		return nil, fmt.Errorf("invalid catalog token")
+	}
+	if err := s.catalogRepo.TouchToken(ctx, token.ID); err != nil {
+		fmt.Printf("Failed to record catalog token use %s: %v\n", token.ID, err)
+	}
+	return token, nil
+}
+
+func (s *catalogService) SetPublished(ctx context.Context, tenantID, productID uuid.UUID, published bool) error {
+	if _, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil {
+		return ErrCatalogProductNotFound
+	}
+	return s.catalogRepo.SetPublished(ctx, tenantID, productID, published)
+}
+
+func (s *catalogService) ListPublished(ctx context.Context, tenantID uuid.UUID, categoryID *uuid.UUID, locale string, limit, offset int) ([]*models.CatalogProduct, error) {
+	products, err := s.catalogRepo.ListPublished(ctx, tenantID, categoryID, catalogLowStockThreshold, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		localizeCatalogProduct(product, locale)
+	}
+	return products, nil
+}
+
+func (s *catalogService) GetPublished(ctx context.Context, tenantID, productID uuid.UUID, locale string) (*models.CatalogProduct, error) {
+	product, err := s.catalogRepo.GetPublished(ctx, tenantID, productID, catalogLowStockThreshold)
+	if err != nil {
+		return nil, err
+	}
+	localizeCatalogProduct(product, locale)
+	return product, nil
+}
+
+// GetImageURL presigns an image belonging to a published product
+func (s *catalogService) GetImageURL(ctx context.Context, tenantID, imageID uuid.UUID, expiry time.Duration) (string, error) {
+	image, err := s.catalogRepo.GetPublishedImage(ctx, tenantID, imageID)
+	if err != nil {
+		return "", err
+	}
+	return s.minioService.GetPresignedURL("product-images", image.ImageURL, expiry)
+}
+
+func localizeCatalogProduct(product *models.CatalogProduct, locale string) {
+	tr, ok := product.Translations.Lookup(locale)
+	if !ok {
+		return
+	}
+	product.Name = tr.Name
+	if tr.Description != nil {
+		product.Description = tr.Description
+	}
+}
@@ -0,0 +1,570 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCommissionAgentNotFound is returned for agents outside the tenant
+	ErrCommissionAgentNotFound = errors.New("commission agent not found")
+	// ErrCommissionRuleNotFound is returned for rules outside the tenant
+	ErrCommissionRuleNotFound = errors.New("commission rule not found")
+	// ErrCommissionRunNotFound is returned for runs outside the tenant and
+	// for statements of payees without a line in the run
+	ErrCommissionRunNotFound = errors.New("commission run not found")
+	// ErrCommissionCustomerNotFound is returned when assigning an unknown
+	// customer to an agent
+	ErrCommissionCustomerNotFound = errors.New("customer not found")
+	// ErrInvalidCommission wraps commission validation failures
+	ErrInvalidCommission = errors.New("invalid commission request")
+	// ErrCommissionConflict is returned for a second active rule for a payee
+	// and for changes to a finalized run
+	ErrCommissionConflict = errors.New("commission conflict")
+)
+
+// CommissionService works out monthly commission for sales reps and agents on
+// the sales of the customers assigned to them. On the invoiced basis unpaid
+// invoices past their due date are withheld until paid and returns are
+// deducted; on the collected basis commission follows payments received
+type CommissionService interface {
+	ListAgents(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionAgent, error)
+	CreateAgent(ctx context.Context, tenantID uuid.UUID, req *models.CommissionAgentRequest) (*models.CommissionAgent, error)
+	UpdateAgent(ctx context.Context, tenantID, agentID uuid.UUID, req *models.CommissionAgentRequest) (*models.CommissionAgent, error)
+	SetCustomerAgent(ctx context.Context, tenantID, customerID uuid.UUID, req *models.CustomerAgentRequest) error
+
+	ListRules(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRule, error)
+	CreateRule(ctx context.Context, tenantID uuid.UUID, req *models.CommissionRuleRequest) (*models.CommissionRule, error)
+	UpdateRule(ctx context.Context, tenantID, ruleID uuid.UUID, req *models.CommissionRuleRequest) (*models.CommissionRule, error)
+
+	// RunCommissions calculates the month's commission, replacing an earlier
+	// draft run of the month. A finalized month cannot be run again
+	RunCommissions(ctx context.Context, tenantID uuid.UUID, month time.Time, createdBy *uuid.UUID) (*models.CommissionRun, error)
+	ListRuns(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRun, error)
+	GetRun(ctx context.Context, tenantID, runID uuid.UUID) (*models.CommissionRun, error)
+	// FinalizeRun locks a draft run for payroll. Invoices it withheld are
+	// released into a later run once paid
+	FinalizeRun(ctx context.Context, tenantID, runID uuid.UUID, finalizedBy *uuid.UUID) (*models.CommissionRun, error)
+	Statement(ctx context.Context, tenantID, runID uuid.UUID, payeeType string, payeeID uuid.UUID) (*models.CommissionStatement, error)
+}
+
+type commissionService struct {
+	repo     repositories.CommissionRepository
+	userRepo repositories.UserRepository
+}
+
+// NewCommissionService creates a new commission service
+func NewCommissionService(repo repositories.CommissionRepository, userRepo repositories.UserRepository) CommissionService {
+	return &commissionService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func roundCommission(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func (s *commissionService) ListAgents(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionAgent, error) {
+	return s.repo.ListAgents(ctx, tenantID)
+}
+
+// applyAgentRequest sets the fields the request carries
+func applyAgentRequest(agent *models.CommissionAgent, req *models.CommissionAgentRequest) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 255 {
+			return fmt.Errorf("%w: name is required and must be at most 255 characters", ErrInvalidCommission)
+		}
+		agent.Name = name
+	}
+	if req.Phone != nil {
+		agent.Phone = trimmedOrNil(req.Phone)
+	}
+	if req.Email != nil {
+		agent.Email = trimmedOrNil(req.Email)
+	}
+	if req.PAN != nil {
+		agent.PAN = trimmedOrNil(req.PAN)
+		if agent.PAN != nil {
+			pan := strings.ToUpper(*agent.PAN)
+			if len(pan) != 10 {
+				return fmt.Errorf("%w: pan must be 10 characters", ErrInvalidCommission)
+			}
+			agent.PAN = &pan
+		}
+	}
+	if req.Active != nil {
+		agent.Active = *req.Active
+	}
+	return nil
+}
+
+func (s *commissionService) CreateAgent(ctx context.Context, tenantID uuid.UUID, req *models.CommissionAgentRequest) (*models.CommissionAgent, error) {
+	if req.Name == nil {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidCommission)
+	}
+	agent := &models.CommissionAgent{ID: uuid.New(), TenantID: tenantID, Active: true}
+	if err := applyAgentRequest(agent, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateAgent(ctx, agent); err != nil {
+		return nil, fmt.Errorf("failed to create commission agent: %w", err)
+	}
+	return agent, nil
+}
+
+func (s *commissionService) UpdateAgent(ctx context.Context, tenantID, agentID uuid.UUID, req *models.CommissionAgentRequest) (*models.CommissionAgent, error) {
+	agent, err := s.repo.GetAgent(ctx, tenantID, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commission agent: %w", err)
+	}
+	if agent == nil {
+		return nil, ErrCommissionAgentNotFound
+	}
+	if err := applyAgentRequest(agent, req); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.UpdateAgent(ctx, agent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update commission agent: %w", err)
+	}
+	if !ok {
+		return nil, ErrCommissionAgentNotFound
+	}
+	return agent, nil
+}
+
+func (s *commissionService) SetCustomerAgent(ctx context.Context, tenantID, customerID uuid.UUID, req *models.CustomerAgentRequest) error {
+	if req.AgentID != nil {
+		agent, err := s.repo.GetAgent(ctx, tenantID, *req.AgentID)
+		if err != nil {
+			return fmt.Errorf("failed to load commission agent: %w", err)
+		}
+		if agent == nil {
+			return fmt.Errorf("%w: agent not found", ErrInvalidCommission)
+		}
+	}
+
+	ok, err := s.repo.SetCustomerAgent(ctx, tenantID, customerID, req.AgentID)
+	if err != nil {
+		return fmt.Errorf("failed to assign customer: %w", err)
+	}
+	if !ok {
+		return ErrCommissionCustomerNotFound
+	}
+	return nil
+}
+
+func (s *commissionService) ListRules(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRule, error) {
+	return s.repo.ListRules(ctx, tenantID)
+}
+
+// commissionSlabs validates the request's slabs, or its flat rate as a single
+// slab, sorted by the amount they start from
+func commissionSlabs(req *models.CommissionRuleRequest) ([]models.CommissionSlab, error) {
+	slabs := req.Slabs
+	if req.RatePercent != nil {
+		if len(slabs) > 0 {
+			return nil, fmt.Errorf("%w: give either rate_percent or slabs", ErrInvalidCommission)
+		}
+		slabs = []models.CommissionSlab{{FromAmount: 0, RatePercent: *req.RatePercent}}
+	}
+	if len(slabs) == 0 {
+		return nil, fmt.Errorf("%w: rate_percent or slabs is required", ErrInvalidCommission)
+	}
+
+	sorted := append([]models.CommissionSlab{}, slabs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].FromAmount < sorted[j].FromAmount })
+	for i := range sorted {
+		sorted[i].FromAmount = roundCommission(sorted[i].FromAmount)
+		if sorted[i].RatePercent < 0 || sorted[i].RatePercent > 100 {
+			return nil, fmt.Errorf("%w: rate_percent must be between 0 and 100", ErrInvalidCommission)
+		}
+		if i > 0 && sorted[i].FromAmount == sorted[i-1].FromAmount {
+			return nil, fmt.Errorf("%w: two slabs start from %.2f", ErrInvalidCommission, sorted[i].FromAmount)
+		}
+	}
+	if sorted[0].FromAmount != 0 {
+		return nil, fmt.Errorf("%w: the first slab must start from 0", ErrInvalidCommission)
+	}
+	return sorted, nil
+}
+
+// slabCommission applies each slab's rate to the part of amount from where
+// the slab starts up to where the next one does
+func slabCommission(amount float64, slabs []models.CommissionSlab) float64 {
+	if amount <= 0 {
+		return 0
+	}
+	var commission float64
+	for i, slab := range slabs {
+		if amount <= slab.FromAmount {
+			break
+		}
+		upper := amount
+		if i+1 < len(slabs) && slabs[i+1].FromAmount < upper {
+			upper = slabs[i+1].FromAmount
+		}
+		commission += (upper - slab.FromAmount) * slab.RatePercent / 100
+	}
+	return roundCommission(commission)
+}
+
+func validCommissionBasis(basis string) bool {
+	return basis == models.CommissionBasisInvoiced || basis == models.CommissionBasisCollected
+}
+
+func (s *commissionService) CreateRule(ctx context.Context, tenantID uuid.UUID, req *models.CommissionRuleRequest) (*models.CommissionRule, error) {
+	rule := &models.CommissionRule{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		PayeeType: strings.ToLower(strings.TrimSpace(req.PayeeType)),
+		PayeeID:   req.PayeeID,
+		Basis:     models.CommissionBasisInvoiced,
+		Active:    true,
+	}
+	if req.Name != nil {
+		rule.Name = strings.TrimSpace(*req.Name)
+	}
+	if rule.Name == "" || len(rule.Name) > 100 {
+		return nil, fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidCommission)
+	}
+	if req.Basis != nil {
+		rule.Basis = strings.ToLower(strings.TrimSpace(*req.Basis))
+	}
+	if !validCommissionBasis(rule.Basis) {
+		return nil, fmt.Errorf("%w: basis must be invoiced or collected", ErrInvalidCommission)
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+	slabs, err := commissionSlabs(req)
+	if err != nil {
+		return nil, err
+	}
+	rule.Slabs = slabs
+
+	switch rule.PayeeType {
+	case models.CommissionPayeeUser:
+		if rule.PayeeID != nil {
+			user, err := s.userRepo.GetByID(ctx, tenantID, *rule.PayeeID)
+			if err != nil || user == nil {
+				return nil, fmt.Errorf("%w: user not found", ErrInvalidCommission)
+			}
+		}
+	case models.CommissionPayeeAgent:
+		if rule.PayeeID != nil {
+			agent, err := s.repo.GetAgent(ctx, tenantID, *rule.PayeeID)
+			if err != nil || agent == nil {
+				return nil, fmt.Errorf("%w: agent not found", ErrInvalidCommission)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: payee_type must be user or agent", ErrInvalidCommission)
+	}
+
+	ok, err := s.repo.CreateRule(ctx, rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create commission rule: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: the payee already has an active rule", ErrCommissionConflict)
+	}
+	return rule, nil
+}
+
+// UpdateRule changes a rule's name, basis, slabs or whether it is active.
+// Slabs are replaced only when the request gives rate_percent or slabs
+func (s *commissionService) UpdateRule(ctx context.Context, tenantID, ruleID uuid.UUID, req *models.CommissionRuleRequest) (*models.CommissionRule, error) {
+	rule, err := s.repo.GetRule(ctx, tenantID, ruleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commission rule: %w", err)
+	}
+	if rule == nil {
+		return nil, ErrCommissionRuleNotFound
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 100 {
+			return nil, fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidCommission)
+		}
+		rule.Name = name
+	}
+	if req.Basis != nil {
+		basis := strings.ToLower(strings.TrimSpace(*req.Basis))
+		if !validCommissionBasis(basis) {
+			return nil, fmt.Errorf("%w: basis must be invoiced or collected", ErrInvalidCommission)
+		}
+		rule.Basis = basis
+	}
+	if req.RatePercent != nil || len(req.Slabs) > 0 {
+		slabs, err := commissionSlabs(req)
+		if err != nil {
+			return nil, err
+		}
+		rule.Slabs = slabs
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+
+	ok, err := s.repo.UpdateRule(ctx, rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update commission rule: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: the payee already has an active rule", ErrCommissionConflict)
+	}
+	return rule, nil
+}
+
+type commissionPayee struct {
+	payeeType string
+	payeeID   uuid.UUID
+}
+
+// commissionRule picks the payee's own active rule, else the active default
+// for its type
+func commissionRule(rules []*models.CommissionRule, payee commissionPayee) *models.CommissionRule {
+	var fallback *models.CommissionRule
+	for _, rule := range rules {
+		if !rule.Active || rule.PayeeType != payee.payeeType {
+			continue
+		}
+		if rule.PayeeID != nil && *rule.PayeeID == payee.payeeID {
+			return rule
+		}
+		if rule.PayeeID == nil {
+			fallback = rule
+		}
+	}
+	return fallback
+}
+
+// buildCommissionLines totals each payee's documents and applies their rule.
+// Payees without a rule earn nothing and are left out, along with their
+// documents
+func buildCommissionLines(rules []*models.CommissionRule, items []*models.CommissionItem) ([]*models.CommissionLine, []*models.CommissionItem) {
+	lines := make(map[commissionPayee]*models.CommissionLine)
+	var order []commissionPayee
+	var kept []*models.CommissionItem
+
+	for _, item := range items {
+		payee := commissionPayee{payeeType: item.PayeeType, payeeID: item.PayeeID}
+		line, ok := lines[payee]
+		if !ok {
+			rule := commissionRule(rules, payee)
+			if rule == nil {
+				lines[payee] = nil
+				continue
+			}
+			ruleID := rule.ID
+			line = &models.CommissionLine{
+				ID:        uuid.New(),
+				PayeeType: item.PayeeType,
+				PayeeID:   item.PayeeID,
+				PayeeName: item.PayeeName,
+				RuleID:    &ruleID,
+				Basis:     rule.Basis,
+			}
+			lines[payee] = line
+			order = append(order, payee)
+		}
+		if line == nil {
+			continue
+		}
+
+		switch item.Kind {
+		case models.CommissionItemInvoice:
+			line.InvoicedAmount += item.Amount
+		case models.CommissionItemWithheld:
+			line.WithheldAmount += item.Amount
+		case models.CommissionItemReleased:
+			line.ReleasedAmount += item.Amount
+		case models.CommissionItemReturn:
+			line.ReturnsAmount += item.Amount
+		case models.CommissionItemCollection:
+			line.CollectedAmount += item.Amount
+		}
+		kept = append(kept, item)
+	}
+
+	result := make([]*models.CommissionLine, 0, len(order))
+	for _, payee := range order {
+		line := lines[payee]
+		rule := commissionRule(rules, payee)
+
+		line.InvoicedAmount = roundCommission(line.InvoicedAmount)
+		line.WithheldAmount = roundCommission(line.WithheldAmount)
+		line.ReleasedAmount = roundCommission(line.ReleasedAmount)
+		line.ReturnsAmount = roundCommission(line.ReturnsAmount)
+		line.CollectedAmount = roundCommission(line.CollectedAmount)
+		if line.Basis == models.CommissionBasisCollected {
+			line.BaseAmount = line.CollectedAmount
+		} else {
+			line.BaseAmount = roundCommission(math.Max(0, line.InvoicedAmount+line.ReleasedAmount-line.ReturnsAmount))
+		}
+		line.CommissionAmount = slabCommission(line.BaseAmount, rule.Slabs)
+		result = append(result, line)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].CommissionAmount != result[j].CommissionAmount {
+			return result[i].CommissionAmount > result[j].CommissionAmount
+		}
+		return result[i].PayeeName < result[j].PayeeName
+	})
+	return result, kept
+}
+
+func (s *commissionService) RunCommissions(ctx context.Context, tenantID uuid.UUID, month time.Time, createdBy *uuid.UUID) (*models.CommissionRun, error) {
+	month = targetMonth(month)
+
+	rules, err := s.repo.ListRules(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commission rules: %w", err)
+	}
+	items, err := s.repo.Documents(ctx, tenantID, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commission documents: %w", err)
+	}
+
+	lines, items := buildCommissionLines(rules, items)
+	run := &models.CommissionRun{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		Month:     month,
+		Status:    models.CommissionRunDraft,
+		CreatedBy: createdBy,
+		Lines:     lines,
+	}
+	for _, line := range lines {
+		line.RunID = run.ID
+		run.TotalCommission += line.CommissionAmount
+	}
+	run.TotalCommission = roundCommission(run.TotalCommission)
+
+	ok, err := s.repo.SaveRun(ctx, run, items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save commission run: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: commission for %s has been finalized", ErrCommissionConflict, month.Format("2006-01"))
+	}
+	return run, nil
+}
+
+func (s *commissionService) ListRuns(ctx context.Context, tenantID uuid.UUID) ([]*models.CommissionRun, error) {
+	return s.repo.ListRuns(ctx, tenantID)
+}
+
+func (s *commissionService) GetRun(ctx context.Context, tenantID, runID uuid.UUID) (*models.CommissionRun, error) {
+	run, err := s.repo.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commission run: %w", err)
+	}
+	if run == nil {
+		return nil, ErrCommissionRunNotFound
+	}
+	return run, nil
+}
+
+func (s *commissionService) FinalizeRun(ctx context.Context, tenantID, runID uuid.UUID, finalizedBy *uuid.UUID) (*models.CommissionRun, error) {
+	run, err := s.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == models.CommissionRunFinalized {
+		return nil, fmt.Errorf("%w: the run is already finalized", ErrCommissionConflict)
+	}
+
+	ok, err := s.repo.FinalizeRun(ctx, tenantID, runID, finalizedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize commission run: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: the run was replaced or finalized meanwhile", ErrCommissionConflict)
+	}
+	return s.GetRun(ctx, tenantID, runID)
+}
+
+func (s *commissionService) Statement(ctx context.Context, tenantID, runID uuid.UUID, payeeType string, payeeID uuid.UUID) (*models.CommissionStatement, error) {
+	run, err := s.GetRun(ctx, tenantID, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.CommissionStatement{RunID: run.ID, Month: run.Month, Status: run.Status}
+	for _, line := range run.Lines {
+		if line.PayeeType == payeeType && line.PayeeID == payeeID {
+			statement.Line = line
+			break
+		}
+	}
+	if statement.Line == nil {
+		return nil, fmt.Errorf("%w: the payee has no commission in this run", ErrCommissionRunNotFound)
+	}
+
+	items, err := s.repo.RunItems(ctx, runID, payeeType, payeeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commission documents: %w", err)
+	}
+	if items == nil {
+		items = []*models.CommissionItem{}
+	}
+	statement.Items = items
+	return statement, nil
+}
+
+func formatCommission(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// CommissionRunCSV renders a run one payee per row for payroll
+func CommissionRunCSV(run *models.CommissionRun) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"Month", "Status", "Payee Type", "Payee ID", "Payee", "Basis", "Invoiced", "Released", "Returns",
+		"Withheld", "Collected", "Commissionable", "Commission"})
+	for _, line := range run.Lines {
+		w.Write([]string{run.Month.Format("2006-01"), run.Status, line.PayeeType, line.PayeeID.String(), line.PayeeName,
+			line.Basis, formatCommission(line.InvoicedAmount), formatCommission(line.ReleasedAmount),
+			formatCommission(line.ReturnsAmount), formatCommission(line.WithheldAmount), formatCommission(line.CollectedAmount),
+			formatCommission(line.BaseAmount), formatCommission(line.CommissionAmount)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// CommissionStatementCSV renders a payee's statement one document per row
+// with the commission on the last
+func CommissionStatementCSV(statement *models.CommissionStatement) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"Payee", "Kind", "Document", "Date", "Amount"})
+	for _, item := range statement.Items {
+		w.Write([]string{item.PayeeName, item.Kind, item.DocumentNumber, item.DocumentDate.Format("2006-01-02"),
+			formatCommission(item.Amount)})
+	}
+	line := statement.Line
+	w.Write([]string{line.PayeeName, "commissionable", "", "", formatCommission(line.BaseAmount)})
+	w.Write([]string{line.PayeeName, "commission", "", "", formatCommission(line.CommissionAmount)})
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,752 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	complianceDocumentBucket = "compliance-documents"
+	// complianceDocumentURLExpiry is how long a presigned document link stays valid
+	complianceDocumentURLExpiry = 15 * time.Minute
+	// licenseExpiringDays marks a license as expiring; it matches the first alert
+	licenseExpiringDays = 90
+)
+
+var (
+	// ErrLicenseNotFound is returned for licenses outside the tenant
+	ErrLicenseNotFound = errors.New("license not found")
+	// ErrInvalidLicense wraps license validation failures
+	ErrInvalidLicense = errors.New("invalid license")
+)
+
+// ComplianceBlockedError is returned when an order needs a license its
+// holder does not have in force
+type ComplianceBlockedError struct {
+	Operation   string
+	LicenseType string
+	ProductID   uuid.UUID
+	HolderType  string
+	HolderID    uuid.UUID
+}
+
+func (e *ComplianceBlockedError) Error() string {
+	return fmt.Sprintf("%s of product %s requires a valid %s license held by the %s", e.Operation, e.ProductID, e.LicenseType, e.HolderType)
+}
+
+// RestrictedSaleBlockedError is returned when a sale of a flagged product is
+// confirmed while the buyer's license is missing or expired
+type RestrictedSaleBlockedError struct {
+	OrderID        uuid.UUID
+	ProductID      uuid.UUID
+	DistributorID  *uuid.UUID
+	RegulatoryFlag string
+	Problem        string // missing or expired
+}
+
+func (e *RestrictedSaleBlockedError) Error() string {
+	return fmt.Sprintf("product %s is %s and the buyer license is %s", e.ProductID, e.RegulatoryFlag, e.Problem)
+}
+
+type restrictedSaleOverrideKey struct{}
+
+// WithRestrictedSaleOverride allows confirming a restricted sale without a
+// valid buyer license; callers must have checked orders:override_restricted
+func WithRestrictedSaleOverride(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, restrictedSaleOverrideKey{}, reason)
+}
+
+// LicenseRequest records or renews a license; dates are YYYY-MM-DD
+type LicenseRequest struct {
+	HolderType       string     `json:"holder_type"`
+	HolderID         *uuid.UUID `json:"holder_id"` // defaults to the tenant for tenant licenses
+	LicenseType      string     `json:"license_type"`
+	LicenseNumber    string     `json:"license_number"`
+	IssuingAuthority *string    `json:"issuing_authority"`
+	ValidFrom        *string    `json:"valid_from"`
+	ExpiresOn        string     `json:"expires_on"`
+	Notes            *string    `json:"notes"`
+}
+
+// ComplianceService tracks licenses held by the tenant, its suppliers and
+// warehouses, alerts ahead of their expiry and blocks orders for restricted
+// products that no valid license covers
+type ComplianceService interface {
+	CreateLicense(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *LicenseRequest) (*models.ComplianceLicense, error)
+	GetLicense(ctx context.Context, tenantID, id uuid.UUID) (*models.ComplianceLicense, error)
+	ListLicenses(ctx context.Context, tenantID uuid.UUID, filter *models.ComplianceLicenseFilter) ([]*models.ComplianceLicense, error)
+	UpdateLicense(ctx context.Context, tenantID, id uuid.UUID, req *LicenseRequest) (*models.ComplianceLicense, error)
+	DeleteLicense(ctx context.Context, tenantID, id uuid.UUID) error
+	UploadDocument(ctx context.Context, tenantID, id uuid.UUID, filename, contentType string, reader io.Reader, size int64) (*models.ComplianceLicense, error)
+	DocumentURL(ctx context.Context, tenantID, id uuid.UUID) (string, error)
+	SetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID, licenseType *string) error
+	Status(ctx context.Context, tenantID uuid.UUID) (*models.ComplianceStatus, error)
+	CheckOrder(ctx context.Context, tenantID uuid.UUID, order *models.Order) error
+	SendExpiryAlerts(ctx context.Context, tenantID uuid.UUID) (int, error)
+	SetRegulatoryFlag(ctx context.Context, tenantID, productID uuid.UUID, flag *string) error
+	ResolveBuyerLicense(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*models.OrderBuyerLicense, error)
+	RecordBuyerLicense(ctx context.Context, capture *models.OrderBuyerLicense) error
+	GetOrderBuyerLicense(ctx context.Context, tenantID, orderID uuid.UUID) (*models.OrderBuyerLicense, error)
+	CheckSaleConfirmation(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*models.RestrictedSaleOverride, error)
+	RecordOverride(ctx context.Context, override *models.RestrictedSaleOverride) error
+	ListOverrides(ctx context.Context, tenantID uuid.UUID, filter *models.RestrictedSaleOverrideFilter) ([]*models.RestrictedSaleOverride, error)
+}
+
+type complianceService struct {
+	complianceRepo  repositories.ComplianceRepository
+	supplierRepo    repositories.SupplierRepository
+	warehouseRepo   repositories.WarehouseRepository
+	distributorRepo repositories.DistributorRepository
+	minioService    MinioService
+	notificationSvc NotificationService
+}
+
+// NewComplianceService creates a new compliance service instance
+func NewComplianceService(complianceRepo repositories.ComplianceRepository, supplierRepo repositories.SupplierRepository, warehouseRepo repositories.WarehouseRepository, distributorRepo repositories.DistributorRepository, minioService MinioService, notificationSvc NotificationService) ComplianceService {
+	return &complianceService{
+		complianceRepo:  complianceRepo,
+		supplierRepo:    supplierRepo,
+		warehouseRepo:   warehouseRepo,
+		distributorRepo: distributorRepo,
+		minioService:    minioService,
+		notificationSvc: notificationSvc,
+	}
+}
+
+func validLicenseType(licenseType string) bool {
+	switch licenseType {
+	case models.LicenseTypePesticide, models.LicenseTypeFertilizer, models.LicenseTypeSeed, models.LicenseTypeOther:
+		return true
+	}
+	return false
+}
+
+func (s *complianceService) CreateLicense(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *LicenseRequest) (*models.ComplianceLicense, error) {
+	holderID, err := s.resolveHolder(ctx, tenantID, req.HolderType, req.HolderID)
+	if err != nil {
+		return nil, err
+	}
+	if !validLicenseType(req.LicenseType) {
+		return nil, fmt.Errorf("%w: license_type must be pesticide, fertilizer, seed or other", ErrInvalidLicense)
+	}
+
+	license := &models.ComplianceLicense{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		HolderType:  req.HolderType,
+		HolderID:    holderID,
+		LicenseType: req.LicenseType,
+		CreatedBy:   userID,
+	}
+	if err := applyLicenseRequest(license, req); err != nil {
+		return nil, err
+	}
+	if err := s.complianceRepo.Create(ctx, license); err != nil {
+		return nil, common.SecureErrorMessage("create license", err)
+	}
+	return s.GetLicense(ctx, tenantID, license.ID)
+}
+
+// resolveHolder checks the holder belongs to the tenant; tenant licenses are
+// always held by the tenant itself
+func (s *complianceService) resolveHolder(ctx context.Context, tenantID uuid.UUID, holderType string, holderID *uuid.UUID) (uuid.UUID, error) {
+	switch holderType {
+	case models.LicenseHolderTenant:
+		if holderID != nil && *holderID != tenantID {
+			return uuid.Nil, fmt.Errorf("%w: tenant licenses must be held by the tenant", ErrInvalidLicense)
+		}
+		return tenantID, nil
+	case models.LicenseHolderSupplier:
+		if holderID == nil {
+			return uuid.Nil, fmt.Errorf("%w: holder_id is required for supplier licenses", ErrInvalidLicense)
+		}
+		if supplier, err := s.supplierRepo.GetByID(ctx, tenantID, *holderID); err != nil || supplier == nil {
+			return uuid.Nil, fmt.Errorf("%w: supplier not found", ErrInvalidLicense)
+		}
+		return *holderID, nil
+	case models.LicenseHolderWarehouse:
+		if holderID == nil {
+			return uuid.Nil, fmt.Errorf("%w: holder_id is required for warehouse licenses", ErrInvalidLicense)
+		}
+		if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, *holderID); err != nil || warehouse == nil {
+			return uuid.Nil, fmt.Errorf("%w: warehouse not found", ErrInvalidLicense)
+		}
+		return *holderID, nil
+	case models.LicenseHolderDistributor:
+		if holderID == nil {
+			return uuid.Nil, fmt.Errorf("%w: holder_id is required for distributor licenses", ErrInvalidLicense)
+		}
+		if distributor, err := s.distributorRepo.GetByID(ctx, tenantID, *holderID); err != nil || distributor == nil {
+			return uuid.Nil, fmt.Errorf("%w: distributor not found", ErrInvalidLicense)
+		}
+		return *holderID, nil
+	}
+	return uuid.Nil, fmt.Errorf("%w: holder_type must be tenant, supplier, warehouse or distributor", ErrInvalidLicense)
+}
+
+// applyLicenseRequest validates and copies the editable fields onto license
+func applyLicenseRequest(license *models.ComplianceLicense, req *LicenseRequest) error {
+	number := strings.TrimSpace(req.LicenseNumber)
+	if number == "" {
+		return fmt.Errorf("%w: license_number is required", ErrInvalidLicense)
+	}
+	expiresOn, err := time.Parse("2006-01-02", req.ExpiresOn)
+	if err != nil {
+		return fmt.Errorf("%w: expires_on must be a date in YYYY-MM-DD format", ErrInvalidLicense)
+	}
+	var validFrom *time.Time
+	if req.ValidFrom != nil && *req.ValidFrom != "" {
+		from, err := time.Parse("2006-01-02", *req.ValidFrom)
+		if err != nil {
+			return fmt.Errorf("%w: valid_from must be a date in YYYY-MM-DD format", ErrInvalidLicense)
+		}
+		if expiresOn.Before(from) {
+			return fmt.Errorf("%w: expires_on must not be before valid_from", ErrInvalidLicense)
+		}
+		validFrom = &from
+	}
+	license.LicenseNumber = number
+	if err := common.SanitizeHTMLField(&license.LicenseNumber, "license number"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLicense, err)
+	}
+	if err := common.SanitizeHTMLField(req.IssuingAuthority, "issuing authority"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLicense, err)
+	}
+	if err := common.SanitizeHTMLField(req.Notes, "license notes"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLicense, err)
+	}
+	license.IssuingAuthority = req.IssuingAuthority
+	license.ValidFrom = validFrom
+	license.ExpiresOn = expiresOn
+	license.Notes = req.Notes
+	return nil
+}
+
+func (s *complianceService) GetLicense(ctx context.Context, tenantID, id uuid.UUID) (*models.ComplianceLicense, error) {
+	license, err := s.complianceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get license", err)
+	}
+	if license == nil {
+		return nil, ErrLicenseNotFound
+	}
+	setLicenseStatus(license, complianceToday())
+	return license, nil
+}
+
+func (s *complianceService) ListLicenses(ctx context.Context, tenantID uuid.UUID, filter *models.ComplianceLicenseFilter) ([]*models.ComplianceLicense, error) {
+	licenses, err := s.complianceRepo.List(ctx, tenantID, filter)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list licenses", err)
+	}
+	now := complianceToday()
+	for _, license := range licenses {
+		setLicenseStatus(license, now)
+	}
+	return licenses, nil
+}
+
+// UpdateLicense renews or corrects a license; the holder and type are fixed
+func (s *complianceService) UpdateLicense(ctx context.Context, tenantID, id uuid.UUID, req *LicenseRequest) (*models.ComplianceLicense, error) {
+	license, err := s.GetLicense(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyLicenseRequest(license, req); err != nil {
+		return nil, err
+	}
+	if err := s.complianceRepo.Update(ctx, license); err != nil {
+		return nil, common.SecureErrorMessage("update license", err)
+	}
+	return s.GetLicense(ctx, tenantID, id)
+}
+
+func (s *complianceService) DeleteLicense(ctx context.Context, tenantID, id uuid.UUID) error {
+	license, err := s.GetLicense(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.complianceRepo.Delete(ctx, tenantID, id)
+	if err != nil {
+		return common.SecureErrorMessage("delete license", err)
+	}
+	if !deleted {
+		return ErrLicenseNotFound
+	}
+	if license.DocumentKey != nil {
+		if err := s.minioService.DeleteImage(ctx, complianceDocumentBucket, *license.DocumentKey); err != nil {
+			fmt.Printf("Failed to delete document for license %s: %v\n", id, err)
+		}
+	}
+	return nil
+}
+
+// UploadDocument stores the license scan, replacing any earlier document
+func (s *complianceService) UploadDocument(ctx context.Context, tenantID, id uuid.UUID, filename, contentType string, reader io.Reader, size int64) (*models.ComplianceLicense, error) {
+	license, err := s.GetLicense(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	objectKey := fmt.Sprintf("%s/%s/%s%s", tenantID.String(), id.String(), uuid.New().String(), strings.ToLower(filepath.Ext(filename)))
+	if err := s.minioService.EnsureBucketExists(ctx, complianceDocumentBucket); err != nil {
+		return nil, fmt.Errorf("failed to ensure bucket exists: %w", err)
+	}
+	if err := s.minioService.UploadObject(ctx, complianceDocumentBucket, objectKey, reader, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to upload document to storage: %w", err)
+	}
+	if err := s.complianceRepo.SetDocument(ctx, tenantID, id, objectKey, filepath.Base(filename), contentType); err != nil {
+		return nil, common.SecureErrorMessage("save license document", err)
+	}
+	if license.DocumentKey != nil {
+		if err := s.minioService.DeleteImage(ctx, complianceDocumentBucket, *license.DocumentKey); err != nil {
+			fmt.Printf("Failed to delete replaced document for license %s: %v\n", id, err)
+		}
+	}
+	return s.GetLicense(ctx, tenantID, id)
+}
+
+func (s *complianceService) DocumentURL(ctx context.Context, tenantID, id uuid.UUID) (string, error) {
+	license, err := s.GetLicense(ctx, tenantID, id)
+	if err != nil {
+		return "", err
+	}
+	if license.DocumentKey == nil {
+		return "", fmt.Errorf("%w: no document has been uploaded", ErrLicenseNotFound)
+	}
+	return s.minioService.GetPresignedURL(complianceDocumentBucket, *license.DocumentKey, complianceDocumentURLExpiry)
+}
+
+// SetProductRequirement marks a product as restricted to holders of a license
+// type; nil lifts the restriction
+func (s *complianceService) SetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID, licenseType *string) error {
+	if licenseType != nil && !validLicenseType(*licenseType) {
+		return fmt.Errorf("%w: license_type must be pesticide, fertilizer, seed or other", ErrInvalidLicense)
+	}
+	updated, err := s.complianceRepo.SetProductRequirement(ctx, tenantID, productID, licenseType)
+	if err != nil {
+		return common.SecureErrorMessage("set product license requirement", err)
+	}
+	if !updated {
+		return fmt.Errorf("%w: product not found", ErrInvalidLicense)
+	}
+	return nil
+}
+
+// Status reports expiring and expired licenses and the operations currently
+// blocked: selling a restricted product from a warehouse needs a valid
+// license of its type held by the tenant or that warehouse, and receiving
+// one from a supplier needs the supplier to hold one
+func (s *complianceService) Status(ctx context.Context, tenantID uuid.UUID) (*models.ComplianceStatus, error) {
+	licenses, err := s.complianceRepo.List(ctx, tenantID, &models.ComplianceLicenseFilter{})
+	if err != nil {
+		return nil, common.SecureErrorMessage("list licenses", err)
+	}
+	restricted, err := s.complianceRepo.RestrictedTypes(ctx, tenantID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list restricted license types", err)
+	}
+	warehouses, err := s.warehouseRepo.List(ctx, tenantID, 1000, 0)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list warehouses", err)
+	}
+	purchases, err := s.complianceRepo.OpenRestrictedPurchases(ctx, tenantID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list open restricted purchases", err)
+	}
+	return buildComplianceStatus(tenantID, licenses, restricted, warehouses, purchases, complianceToday()), nil
+}
+
+func buildComplianceStatus(tenantID uuid.UUID, licenses []*models.ComplianceLicense, restricted []string, warehouses []*models.Warehouse,
+	purchases []*models.OpenRestrictedPurchase, now time.Time) *models.ComplianceStatus {
+	status := &models.ComplianceStatus{
+		AsOf:              now,
+		Expiring:          []*models.ComplianceLicense{},
+		Expired:           []*models.ComplianceLicense{},
+		RestrictedTypes:   restricted,
+		BlockedOperations: []*models.BlockedOperation{},
+	}
+	if status.RestrictedTypes == nil {
+		status.RestrictedTypes = []string{}
+	}
+
+	// valid[holderID][licenseType]
+	valid := map[uuid.UUID]map[string]bool{}
+	for _, license := range licenses {
+		setLicenseStatus(license, now)
+		switch license.Status {
+		case models.LicenseStatusExpired:
+			status.Expired = append(status.Expired, license)
+			continue
+		case models.LicenseStatusExpiring:
+			status.Expiring = append(status.Expiring, license)
+		}
+		if license.ValidFrom != nil && license.ValidFrom.After(now) {
+			continue
+		}
+		status.ValidLicenses++
+		if valid[license.HolderID] == nil {
+			valid[license.HolderID] = map[string]bool{}
+		}
+		valid[license.HolderID][license.LicenseType] = true
+	}
+
+	for _, licenseType := range restricted {
+		if valid[tenantID][licenseType] {
+			continue
+		}
+		for _, warehouse := range warehouses {
+			if valid[warehouse.ID][licenseType] {
+				continue
+			}
+			status.BlockedOperations = append(status.BlockedOperations, &models.BlockedOperation{
+				Operation:   models.ComplianceOperationSell,
+				LicenseType: licenseType,
+				HolderType:  models.LicenseHolderWarehouse,
+				HolderID:    warehouse.ID,
+				HolderName:  warehouse.Name,
+				Reason:      fmt.Sprintf("no valid %s license held by the tenant or this warehouse", licenseType),
+			})
+		}
+	}
+	for _, purchase := range purchases {
+		if valid[purchase.SupplierID][purchase.LicenseType] {
+			continue
+		}
+		orderID := purchase.OrderID
+		status.BlockedOperations = append(status.BlockedOperations, &models.BlockedOperation{
+			Operation:   models.ComplianceOperationPurchase,
+			LicenseType: purchase.LicenseType,
+			HolderType:  models.LicenseHolderSupplier,
+			HolderID:    purchase.SupplierID,
+			HolderName:  purchase.SupplierName,
+			OrderID:     &orderID,
+			Reason:      fmt.Sprintf("supplier holds no valid %s license", purchase.LicenseType),
+		})
+	}
+
+	status.Compliant = len(status.Expired) == 0 && len(status.BlockedOperations) == 0
+	return status
+}
+
+// CheckOrder returns a *ComplianceBlockedError when the order's product
+// requires a license that is not in force
+func (s *complianceService) CheckOrder(ctx context.Context, tenantID uuid.UUID, order *models.Order) error {
+	licenseType, _, err := s.complianceRepo.ProductRegulation(ctx, tenantID, order.ProductID)
+	if err != nil {
+		return common.SecureErrorMessage("check product license requirement", err)
+	}
+	if licenseType == nil {
+		return nil
+	}
+
+	switch order.OrderType {
+	case "sales":
+		ok, err := s.complianceRepo.HasValidLicense(ctx, tenantID, models.LicenseHolderTenant, tenantID, *licenseType)
+		if err == nil && !ok {
+			ok, err = s.complianceRepo.HasValidLicense(ctx, tenantID, models.LicenseHolderWarehouse, order.WarehouseID, *licenseType)
+		}
+		if err != nil {
+			return common.SecureErrorMessage("check sale license", err)
+		}
+		if !ok {
+			return &ComplianceBlockedError{Operation: models.ComplianceOperationSell, LicenseType: *licenseType, ProductID: order.ProductID,
+				HolderType: models.LicenseHolderWarehouse, HolderID: order.WarehouseID}
+		}
+	case "purchase":
+		if order.SupplierID == nil {
+			return nil
+		}
+		ok, err := s.complianceRepo.HasValidLicense(ctx, tenantID, models.LicenseHolderSupplier, *order.SupplierID, *licenseType)
+		if err != nil {
+			return common.SecureErrorMessage("check supplier license", err)
+		}
+		if !ok {
+			return &ComplianceBlockedError{Operation: models.ComplianceOperationPurchase, LicenseType: *licenseType, ProductID: order.ProductID,
+				HolderType: models.LicenseHolderSupplier, HolderID: *order.SupplierID}
+		}
+	}
+	return nil
+}
+
+// SendExpiryAlerts pushes one alert per license as it crosses each of the
+// 90/30/7 day thresholds and returns how many were sent
+func (s *complianceService) SendExpiryAlerts(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	now := complianceToday()
+	horizon := now.AddDate(0, 0, models.LicenseExpiryAlertDays[0])
+	licenses, err := s.complianceRepo.List(ctx, tenantID, &models.ComplianceLicenseFilter{ExpiringBefore: &horizon})
+	if err != nil {
+		return 0, common.SecureErrorMessage("list expiring licenses", err)
+	}
+
+	sent := 0
+	for _, license := range licenses {
+		setLicenseStatus(license, now)
+		threshold, ok := dueAlertThreshold(license.DaysToExpiry, license.LastAlertDays)
+		if !ok {
+			continue
+		}
+		holder := license.HolderName
+		if holder == "" {
+			holder = license.HolderType
+		}
+		msg := &models.PushMessage{
+			Title: "License expiring soon",
+			Body: fmt.Sprintf("%s %s license %s expires in %d days (%s)", holder, license.LicenseType, license.LicenseNumber,
+				license.DaysToExpiry, license.ExpiresOn.Format("2006-01-02")),
+			Data: map[string]string{"event_type": "license_expiring", "license_id": license.ID.String()},
+		}
+		if err := s.notificationSvc.SendPush(ctx, tenantID, models.PushTopicCompliance, msg); err != nil {
+			fmt.Printf("Failed to push expiry alert for license %s: %v\n", license.ID, err)
+			continue
+		}
+		if err := s.complianceRepo.MarkAlerted(ctx, license.ID, threshold); err != nil {
+			fmt.Printf("Failed to record expiry alert for license %s: %v\n", license.ID, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// dueAlertThreshold returns the tightest threshold the license has reached
+// that has not yet been alerted on; a license first seen inside 30 days gets
+// the 30 day alert only
+func dueAlertThreshold(daysToExpiry int, lastAlertDays *int) (int, bool) {
+	if daysToExpiry < 0 {
+		return 0, false
+	}
+	due := 0
+	for _, threshold := range models.LicenseExpiryAlertDays {
+		if daysToExpiry <= threshold {
+			due = threshold
+		}
+	}
+	if due == 0 || (lastAlertDays != nil && *lastAlertDays <= due) {
+		return 0, false
+	}
+	return due, true
+}
+
+// setLicenseStatus derives days to expiry and the status as of now
+func setLicenseStatus(license *models.ComplianceLicense, now time.Time) {
+	license.DaysToExpiry = int(math.Floor(license.ExpiresOn.Sub(now).Hours() / 24))
+	switch {
+	case license.DaysToExpiry < 0:
+		license.Status = models.LicenseStatusExpired
+	case license.DaysToExpiry <= licenseExpiringDays:
+		license.Status = models.LicenseStatusExpiring
+	default:
+		license.Status = models.LicenseStatusValid
+	}
+}
+
+func complianceToday() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+func validRegulatoryFlag(flag string) bool {
+	switch flag {
+	case models.RegulatoryFlagRedTriangle, models.RegulatoryFlagYellowTriangle, models.RegulatoryFlagBlueTriangle,
+		models.RegulatoryFlagGreenTriangle, models.RegulatoryFlagRestricted:
+		return true
+	}
+	return false
+}
+
+// SetRegulatoryFlag flags a product whose buyers must hold a valid license;
+// nil clears the flag
+func (s *complianceService) SetRegulatoryFlag(ctx context.Context, tenantID, productID uuid.UUID, flag *string) error {
+	if flag != nil && !validRegulatoryFlag(*flag) {
+		return fmt.Errorf("%w: regulatory_flag must be red_triangle, yellow_triangle, blue_triangle, green_triangle or restricted", ErrInvalidLicense)
+	}
+	updated, err := s.complianceRepo.SetRegulatoryFlag(ctx, tenantID, productID, flag)
+	if err != nil {
+		return common.SecureErrorMessage("set product regulatory flag", err)
+	}
+	if !updated {
+		return fmt.Errorf("%w: product not found", ErrInvalidLicense)
+	}
+	return nil
+}
+
+// ResolveBuyerLicense picks the buyer license to capture on a sales order for
+// a flagged product: the one named on the order, which must belong to the
+// buyer, or else the buyer's stored license expiring last. It returns nil
+// when the product is not flagged or the buyer has no license on file.
+func (s *complianceService) ResolveBuyerLicense(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*models.OrderBuyerLicense, error) {
+	if order.OrderType != "sales" || order.DistributorID == nil {
+		return nil, nil
+	}
+	licenseType, flag, err := s.complianceRepo.ProductRegulation(ctx, tenantID, order.ProductID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("check product regulatory flag", err)
+	}
+	if flag == nil {
+		return nil, nil
+	}
+
+	var license *models.ComplianceLicense
+	if order.BuyerLicenseID != nil {
+		license, err = s.complianceRepo.GetByID(ctx, tenantID, *order.BuyerLicenseID)
+		if err != nil {
+			return nil, common.SecureErrorMessage("get buyer license", err)
+		}
+		if license == nil || license.HolderType != models.LicenseHolderDistributor || license.HolderID != *order.DistributorID {
+			return nil, fmt.Errorf("%w: buyer_license_id is not a license held by the order's distributor", ErrInvalidLicense)
+		}
+	} else {
+		license, err = s.complianceRepo.LatestHolderLicense(ctx, tenantID, models.LicenseHolderDistributor, *order.DistributorID, licenseType)
+		if err != nil {
+			return nil, common.SecureErrorMessage("find buyer license", err)
+		}
+		if license == nil {
+			return nil, nil
+		}
+	}
+
+	capture := &models.OrderBuyerLicense{
+		OrderID:          order.ID,
+		TenantID:         tenantID,
+		LicenseID:        &license.ID,
+		LicenseNumber:    license.LicenseNumber,
+		LicenseExpiresOn: license.ExpiresOn,
+	}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		capture.CapturedBy = &userID
+	}
+	return capture, nil
+}
+
+func (s *complianceService) RecordBuyerLicense(ctx context.Context, capture *models.OrderBuyerLicense) error {
+	if capture == nil {
+		return nil
+	}
+	return s.complianceRepo.SaveOrderBuyerLicense(ctx, capture)
+}
+
+func (s *complianceService) GetOrderBuyerLicense(ctx context.Context, tenantID, orderID uuid.UUID) (*models.OrderBuyerLicense, error) {
+	capture, err := s.complianceRepo.GetOrderBuyerLicense(ctx, tenantID, orderID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get order buyer license", err)
+	}
+	if capture == nil {
+		return nil, fmt.Errorf("%w: no buyer license captured on this order", ErrLicenseNotFound)
+	}
+	return capture, nil
+}
+
+// CheckSaleConfirmation checks the buyer license before a sales order for a
+// flagged product is confirmed. A captured license that has since expired or
+// been removed is replaced by the buyer's current one when that is valid.
+// Without a valid license the confirmation is blocked with a
+// *RestrictedSaleBlockedError unless the context carries an override, in
+// which case the override to record is returned.
+func (s *complianceService) CheckSaleConfirmation(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*models.RestrictedSaleOverride, error) {
+	if order.OrderType != "sales" {
+		return nil, nil
+	}
+	_, flag, err := s.complianceRepo.ProductRegulation(ctx, tenantID, order.ProductID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("check product regulatory flag", err)
+	}
+	if flag == nil {
+		return nil, nil
+	}
+
+	capture, err := s.complianceRepo.GetOrderBuyerLicense(ctx, tenantID, order.ID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get order buyer license", err)
+	}
+	problem, licenseID, err := s.buyerLicenseProblem(ctx, tenantID, capture)
+	if err != nil {
+		return nil, err
+	}
+	if problem != "" {
+		// The buyer may have renewed or filed a license since the order was taken
+		order.BuyerLicenseID = nil
+		current, err := s.ResolveBuyerLicense(ctx, tenantID, order)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && !current.LicenseExpiresOn.Before(complianceToday()) {
+			if err := s.complianceRepo.SaveOrderBuyerLicense(ctx, current); err != nil {
+				return nil, common.SecureErrorMessage("capture buyer license", err)
+			}
+			problem = ""
+		}
+	}
+	if problem == "" {
+		return nil, nil
+	}
+
+	reason, overridden := ctx.Value(restrictedSaleOverrideKey{}).(string)
+	if !overridden {
+		return nil, &RestrictedSaleBlockedError{OrderID: order.ID, ProductID: order.ProductID, DistributorID: order.DistributorID,
+			RegulatoryFlag: *flag, Problem: problem}
+	}
+	override := &models.RestrictedSaleOverride{
+		ID:             uuid.New(),
+		TenantID:       tenantID,
+		OrderID:        order.ID,
+		ProductID:      order.ProductID,
+		DistributorID:  order.DistributorID,
+		RegulatoryFlag: *flag,
+		LicenseID:      licenseID,
+		LicenseProblem: problem,
+		Reason:         reason,
+	}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		override.UserID = &userID
+	}
+	return override, nil
+}
+
+// buyerLicenseProblem reports whether the captured license is missing or
+// expired, judged on the stored license so renewals in place count
+func (s *complianceService) buyerLicenseProblem(ctx context.Context, tenantID uuid.UUID, capture *models.OrderBuyerLicense) (string, *uuid.UUID, error) {
+	if capture == nil || capture.LicenseID == nil {
+		return models.BuyerLicenseMissing, nil, nil
+	}
+	license, err := s.complianceRepo.GetByID(ctx, tenantID, *capture.LicenseID)
+	if err != nil {
+		return "", nil, common.SecureErrorMessage("get buyer license", err)
+	}
+	if license == nil {
+		return models.BuyerLicenseMissing, nil, nil
+	}
+	if license.ExpiresOn.Before(complianceToday()) {
+		return models.BuyerLicenseExpired, &license.ID, nil
+	}
+	return "", &license.ID, nil
+}
+
+func (s *complianceService) RecordOverride(ctx context.Context, override *models.RestrictedSaleOverride) error {
+	return s.complianceRepo.CreateOverride(ctx, override)
+}
+
+func (s *complianceService) ListOverrides(ctx context.Context, tenantID uuid.UUID, filter *models.RestrictedSaleOverrideFilter) ([]*models.RestrictedSaleOverride, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	overrides, err := s.complianceRepo.ListOverrides(ctx, tenantID, filter)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list restricted sale overrides", err)
+	}
+	return overrides, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSupplierNotFound is returned for suppliers outside the tenant
+	ErrSupplierNotFound = errors.New("supplier not found")
+	// ErrInvalidSettlement wraps settlement validation failures
+	ErrInvalidSettlement = errors.New("invalid consignment settlement")
+)
+
+// ConsignmentService tracks supplier-owned stock and the liabilities raised
+// when it is sold
+type ConsignmentService interface {
+	RecordSale(ctx context.Context, tenantID uuid.UUID, order *models.Order) ([]*models.ConsignmentLiability, error)
+	ReverseSale(ctx context.Context, tenantID, orderID uuid.UUID) (int, error)
+	ListStock(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID) ([]*models.ConsignmentStock, error)
+	SettlementReport(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) (*models.ConsignmentSettlementReport, error)
+	Settle(ctx context.Context, tenantID, supplierID uuid.UUID, upTo *time.Time, reference string) (*models.ConsignmentSettlement, error)
+}
+
+type consignmentService struct {
+	consignmentRepo repositories.ConsignmentRepository
+	supplierRepo    repositories.SupplierRepository
+}
+
+// NewConsignmentService creates a new consignment service instance
+func NewConsignmentService(consignmentRepo repositories.ConsignmentRepository, supplierRepo repositories.SupplierRepository) ConsignmentService {
+	return &consignmentService{
+		consignmentRepo: consignmentRepo,
+		supplierRepo:    supplierRepo,
+	}
+}
+
+// RecordSale raises supplier liabilities for the consignment units a sales
+// order draws; other order types are ignored
+func (s *consignmentService) RecordSale(ctx context.Context, tenantID uuid.UUID, order *models.Order) ([]*models.ConsignmentLiability, error) {
+	if order.OrderType != "sales" {
+		return nil, nil
+	}
+	return s.consignmentRepo.RecordSale(ctx, tenantID, order)
+}
+
+func (s *consignmentService) ReverseSale(ctx context.Context, tenantID, orderID uuid.UUID) (int, error) {
+	return s.consignmentRepo.ReverseSale(ctx, tenantID, orderID)
+}
+
+func (s *consignmentService) ListStock(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID) ([]*models.ConsignmentStock, error) {
+	return s.consignmentRepo.ListStock(ctx, tenantID, supplierID)
+}
+
+// SettlementReport totals consignment stock and liabilities per supplier for
+// [from, to); with a supplier it also lists that supplier's liabilities
+func (s *consignmentService) SettlementReport(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) (*models.ConsignmentSettlementReport, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: report end must be after its start", ErrInvalidSettlement)
+	}
+
+	suppliers, err := s.consignmentRepo.SettlementSummary(ctx, tenantID, supplierID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if suppliers == nil {
+		suppliers = []*models.ConsignmentSettlementRow{}
+	}
+
+	report := &models.ConsignmentSettlementReport{
+		From:      from,
+		To:        to,
+		Suppliers: suppliers,
+	}
+	if supplierID != nil {
+		report.Liabilities, err = s.consignmentRepo.ListLiabilities(ctx, tenantID, *supplierID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if report.Liabilities == nil {
+			report.Liabilities = []*models.ConsignmentLiability{}
+		}
+	}
+	return report, nil
+}
+
+// Settle marks the supplier's open liabilities raised up to upTo (default now) as paid
+func (s *consignmentService) Settle(ctx context.Context, tenantID, supplierID uuid.UUID, upTo *time.Time, reference string) (*models.ConsignmentSettlement, error) {
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return nil, fmt.Errorf("%w: settlement reference is required", ErrInvalidSettlement)
+	}
+	if len(reference) > 100 {
+		return nil, fmt.Errorf("%w: settlement reference must be at most 100 characters", ErrInvalidSettlement)
+	}
+
+	supplier, err := s.supplierRepo.GetByID(ctx, tenantID, supplierID)
+	if err != nil || supplier == nil {
+		return nil, ErrSupplierNotFound
+	}
+
+	cutoff := time.Now()
+	if upTo != nil {
+		cutoff = *upTo
+	}
+	count, amount, settledAt, err := s.consignmentRepo.Settle(ctx, tenantID, supplierID, cutoff, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConsignmentSettlement{
+		SupplierID:  supplierID,
+		Reference:   reference,
+		UpTo:        cutoff,
+		Liabilities: count,
+		Amount:      amount,
+		SettledAt:   settledAt,
+	}, nil
+}
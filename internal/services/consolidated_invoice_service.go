@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrConsolidatedInvoiceNotFound is returned for invoices that are not
+	// consolidated invoices of the tenant
+	ErrConsolidatedInvoiceNotFound = errors.New("consolidated invoice not found")
+	// ErrInvalidConsolidatedInvoice wraps consolidated invoice validation failures
+	ErrInvalidConsolidatedInvoice = errors.New("invalid consolidated invoice")
+	// ErrConsolidatedInvoiceConflict is returned when an order to include is
+	// already invoiced
+	ErrConsolidatedInvoiceConflict = errors.New("an order is already invoiced")
+)
+
+const (
+	// consolidatedGSTRate matches the rate invoices are generated with on delivery
+	consolidatedGSTRate = 18.0
+	// maxConsolidatedOrders keeps an invoice to what fits a printed bill
+	maxConsolidatedOrders = 200
+)
+
+// ConsolidatedInvoiceService raises a single invoice for a customer's
+// delivered orders over a period, as weekly dealers are billed. Each order
+// stays a line of its own and links back to the invoice
+type ConsolidatedInvoiceService interface {
+	CreateConsolidatedInvoice(ctx context.Context, tenantID uuid.UUID, req *models.ConsolidatedInvoiceRequest) (*models.ConsolidatedInvoice, error)
+	GetConsolidatedInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.ConsolidatedInvoice, error)
+	// OrderInvoices lists the invoices covering an order, whether its own or
+	// consolidated
+	OrderInvoices(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.Invoice, error)
+}
+
+type consolidatedInvoiceService struct {
+	repo            repositories.ConsolidatedInvoiceRepository
+	invoiceRepo     repositories.InvoiceRepository
+	withholdingRepo repositories.WithholdingTaxRepository
+	historyRepo     repositories.StatusHistoryRepository
+	calendars       TenantCalendarService
+}
+
+// NewConsolidatedInvoiceService creates a new consolidated invoice service
+func NewConsolidatedInvoiceService(repo repositories.ConsolidatedInvoiceRepository, invoiceRepo repositories.InvoiceRepository, withholdingRepo repositories.WithholdingTaxRepository,
+	historyRepo repositories.StatusHistoryRepository, calendars TenantCalendarService) ConsolidatedInvoiceService {
+	return &consolidatedInvoiceService{
+		repo:            repo,
+		invoiceRepo:     invoiceRepo,
+		withholdingRepo: withholdingRepo,
+		historyRepo:     historyRepo,
+		calendars:       calendars,
+	}
+}
+
+// CreateConsolidatedInvoice invoices the customer's uninvoiced delivered
+// orders in the period. GST is worked out per order line and the invoice
+// carries the sums, so it matches the lines to the paisa; TCS applies to the
+// invoice total as it would to a single order's
+func (s *consolidatedInvoiceService) CreateConsolidatedInvoice(ctx context.Context, tenantID uuid.UUID, req *models.ConsolidatedInvoiceRequest) (*models.ConsolidatedInvoice, error) {
+	if req.DistributorID == uuid.Nil {
+		return nil, fmt.Errorf("%w: customer_id is required", ErrInvalidConsolidatedInvoice)
+	}
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from must be in YYYY-MM-DD format", ErrInvalidConsolidatedInvoice)
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to must be in YYYY-MM-DD format", ErrInvalidConsolidatedInvoice)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to cannot be before from", ErrInvalidConsolidatedInvoice)
+	}
+
+	candidates, err := s.repo.UninvoicedOrders(ctx, tenantID, req.DistributorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load orders: %w", err)
+	}
+	lines, err := selectConsolidatedOrders(candidates, req.OrderIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: the customer has no uninvoiced delivered orders in the period", ErrInvalidConsolidatedInvoice)
+	}
+	if len(lines) > maxConsolidatedOrders {
+		return nil, fmt.Errorf("%w: at most %d orders fit one invoice, shorten the period", ErrInvalidConsolidatedInvoice, maxConsolidatedOrders)
+	}
+	branchID, err := consolidatedBranch(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	issuedDate := TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(now)
+	number, err := s.invoiceRepo.GenerateInvoiceNumber(ctx, tenantID, branchID, issuedDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invoice number: %w", err)
+	}
+	invoice := &models.Invoice{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		OrderID:       lines[0].OrderID,
+		BranchID:      branchID,
+		InvoiceNumber: number,
+		Status:        "unpaid",
+		IssuedDate:    issuedDate,
+		DueDate:       issuedDate.AddDate(0, 0, 30),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	consolidated := &models.ConsolidatedInvoice{
+		Invoice:       invoice,
+		DistributorID: req.DistributorID,
+		PeriodFrom:    from,
+		PeriodTo:      to,
+		Lines:         lines,
+	}
+	totalConsolidatedInvoice(consolidated, consolidatedGSTRate)
+
+	if s.withholdingRepo != nil {
+		section, err := s.withholdingRepo.ActiveTCSSection(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate TCS: %w", err)
+		}
+		if section != nil {
+			if consolidated.TCS, err = customerTCS(ctx, s.withholdingRepo, section, invoice, req.DistributorID); err != nil {
+				return nil, fmt.Errorf("failed to calculate TCS: %w", err)
+			}
+		}
+	}
+
+	created, err := s.repo.Create(ctx, consolidated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consolidated invoice: %w", err)
+	}
+	if !created {
+		return nil, ErrConsolidatedInvoiceConflict
+	}
+	recordStatusChange(ctx, s.historyRepo, tenantID, models.StatusEntityInvoice, invoice.ID, "", invoice.Status)
+	return consolidated, nil
+}
+
+func (s *consolidatedInvoiceService) GetConsolidatedInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.ConsolidatedInvoice, error) {
+	consolidated, err := s.repo.Get(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load consolidated invoice: %w", err)
+	}
+	if consolidated == nil {
+		return nil, ErrConsolidatedInvoiceNotFound
+	}
+	if consolidated.Invoice, err = s.invoiceRepo.GetByID(ctx, tenantID, invoiceID); err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+	if s.withholdingRepo != nil {
+		if consolidated.TCS, err = s.withholdingRepo.GetInvoiceTCS(ctx, tenantID, invoiceID); err != nil {
+			return nil, fmt.Errorf("failed to load invoice TCS: %w", err)
+		}
+	}
+	return consolidated, nil
+}
+
+func (s *consolidatedInvoiceService) OrderInvoices(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.Invoice, error) {
+	invoices, err := s.invoiceRepo.GetInvoicesByOrderID(ctx, tenantID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load order invoices: %w", err)
+	}
+	if invoices == nil {
+		invoices = []*models.Invoice{}
+	}
+	return invoices, nil
+}
+
+// selectConsolidatedOrders picks the requested orders out of the candidates,
+// keeping the candidates' order; with no request every candidate is taken
+func selectConsolidatedOrders(candidates []*models.ConsolidatedInvoiceLine, orderIDs []uuid.UUID) ([]*models.ConsolidatedInvoiceLine, error) {
+	if len(orderIDs) == 0 {
+		return candidates, nil
+	}
+	wanted := make(map[uuid.UUID]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		wanted[id] = true
+	}
+	var lines []*models.ConsolidatedInvoiceLine
+	for _, line := range candidates {
+		if wanted[line.OrderID] {
+			lines = append(lines, line)
+			delete(wanted, line.OrderID)
+		}
+	}
+	for id := range wanted {
+		return nil, fmt.Errorf("%w: order %s is not an uninvoiced delivered order of the customer in the period", ErrInvalidConsolidatedInvoice, id)
+	}
+	return lines, nil
+}
+
+// totalConsolidatedInvoice numbers the lines, works out each one's GST at
+// gstRate, split as CGST and SGST like delivery invoices, and sets the
+// invoice's amounts to their sums
+func totalConsolidatedInvoice(consolidated *models.ConsolidatedInvoice, gstRate float64) {
+	var taxable, cgst, sgst, igst float64
+	for i, line := range consolidated.Lines {
+		line.InvoiceID = consolidated.ID
+		line.LineNumber = i + 1
+		line.GSTRate = gstRate
+		line.TaxableAmount = roundAllocation(float64(line.Quantity) * line.UnitPrice)
+		line.CGST = roundAllocation(line.TaxableAmount * gstRate / 200)
+		line.SGST = line.CGST
+		line.IGST = 0
+		line.TotalAmount = roundAllocation(line.TaxableAmount + line.CGST + line.SGST + line.IGST)
+
+		taxable += line.TaxableAmount
+		cgst += line.CGST
+		sgst += line.SGST
+		igst += line.IGST
+	}
+	taxable, cgst, sgst, igst = roundAllocation(taxable), roundAllocation(cgst), roundAllocation(sgst), roundAllocation(igst)
+	consolidated.TaxableAmount = &taxable
+	consolidated.GSTRate = &gstRate
+	consolidated.CGST = &cgst
+	consolidated.SGST = &sgst
+	consolidated.IGST = &igst
+	consolidated.TotalAmount = roundAllocation(taxable + cgst + sgst + igst)
+}
+
+// consolidatedBranch is the branch all the lines were ordered through; an
+// invoice is issued under one GSTIN, so lines from different branches are
+// refused
+func consolidatedBranch(lines []*models.ConsolidatedInvoiceLine) (*uuid.UUID, error) {
+	branchID := lines[0].BranchID
+	for _, line := range lines[1:] {
+		same := branchID == nil && line.BranchID == nil ||
+			branchID != nil && line.BranchID != nil && *branchID == *line.BranchID
+		if !same {
+			return nil, fmt.Errorf("%w: the orders belong to different branches, pick the orders of one branch with order_ids", ErrInvalidConsolidatedInvoice)
+		}
+	}
+	return branchID, nil
+}
@@ -0,0 +1,475 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// WalkInCustomerName is the distributor counter sales without a named
+// customer are booked to; it is created on first use
+const WalkInCustomerName = "Walk-in Customer"
+
+// counterSaleGSTRate matches the rate invoices are generated with on delivery
+const counterSaleGSTRate = 18.0
+
+var (
+	// ErrInvalidCounterSale wraps counter sale and register validation failures
+	ErrInvalidCounterSale = errors.New("invalid counter sale")
+	// ErrRegisterNotFound is returned for registers outside the tenant
+	ErrRegisterNotFound = errors.New("cash register not found")
+	// ErrRegisterNotOpen is returned when selling at, or closing, a warehouse
+	// without an open register
+	ErrRegisterNotOpen = errors.New("no open cash register")
+	// ErrRegisterAlreadyOpen is returned when opening a second register at a warehouse
+	ErrRegisterAlreadyOpen = errors.New("a cash register is already open at this warehouse")
+)
+
+// Notes and coins accepted in register counts, in rupees
+var registerDenominations = map[string]float64{
+	"2000": 2000, "500": 500, "200": 200, "100": 100, "50": 50,
+	"20": 20, "10": 10, "5": 5, "2": 2, "1": 1,
+}
+
+// CounterSaleService runs the point-of-sale flow: cash registers per
+// warehouse or store, one-call counter sales and the daily day book
+type CounterSaleService interface {
+	// Sell creates, fulfils and invoices a sales order and records its payment
+	// at the warehouse's open register. Margin and compliance errors from the
+	// order are returned as-is
+	Sell(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.CounterSaleRequest) (*models.CounterSaleReceipt, error)
+
+	OpenRegister(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.OpenRegisterRequest) (*models.CashRegister, error)
+	CloseRegister(ctx context.Context, tenantID, registerID uuid.UUID, userID *uuid.UUID, req *models.CloseRegisterRequest) (*models.CashRegister, error)
+	GetRegister(ctx context.Context, tenantID, registerID uuid.UUID) (*models.CashRegister, error)
+	ListRegisters(ctx context.Context, tenantID uuid.UUID, filter *models.CashRegisterFilter) ([]*models.CashRegister, error)
+
+	// DayBook lists the day's counter sales and register sessions per
+	// warehouse, optionally for one warehouse only
+	DayBook(ctx context.Context, tenantID uuid.UUID, day time.Time, warehouseID *uuid.UUID) (*models.DayBook, error)
+}
+
+type counterSaleService struct {
+	repo            repositories.CounterSaleRepository
+	orderService    OrderServiceInterface
+	invoiceService  InvoiceServiceInterface
+	productRepo     repositories.ProductRepository
+	distributorRepo repositories.DistributorRepository
+	warehouseRepo   repositories.WarehouseRepository
+}
+
+// NewCounterSaleService creates a new counter sale service
+func NewCounterSaleService(repo repositories.CounterSaleRepository, orderService OrderServiceInterface, invoiceService InvoiceServiceInterface,
+	productRepo repositories.ProductRepository, distributorRepo repositories.DistributorRepository, warehouseRepo repositories.WarehouseRepository) CounterSaleService {
+	return &counterSaleService{
+		repo:            repo,
+		orderService:    orderService,
+		invoiceService:  invoiceService,
+		productRepo:     productRepo,
+		distributorRepo: distributorRepo,
+		warehouseRepo:   warehouseRepo,
+	}
+}
+
+func (s *counterSaleService) Sell(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.CounterSaleRequest) (*models.CounterSaleReceipt, error) {
+	if req.WarehouseID == uuid.Nil || req.ProductID == uuid.Nil {
+		return nil, fmt.Errorf("%w: warehouse_id and product_id are required", ErrInvalidCounterSale)
+	}
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidCounterSale)
+	}
+	req.PaymentMethod = strings.ToLower(strings.TrimSpace(req.PaymentMethod))
+	switch req.PaymentMethod {
+	case models.PaymentMethodCash, models.PaymentMethodUPI, models.PaymentMethodCard:
+	default:
+		return nil, fmt.Errorf("%w: payment_method must be cash, upi or card", ErrInvalidCounterSale)
+	}
+
+	register, err := s.repo.GetOpenRegister(ctx, tenantID, req.WarehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cash register: %w", err)
+	}
+	if register == nil {
+		return nil, ErrRegisterNotOpen
+	}
+
+	product, err := s.productRepo.GetByID(ctx, tenantID, req.ProductID)
+	if err != nil || product == nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidCounterSale)
+	}
+	unitPrice := product.UnitPrice
+	if req.UnitPrice != nil {
+		unitPrice = *req.UnitPrice
+	}
+	if unitPrice <= 0 {
+		return nil, fmt.Errorf("%w: unit price must be positive", ErrInvalidCounterSale)
+	}
+
+	// Check the payment covers the bill before any stock moves
+	taxable := float64(req.Quantity) * unitPrice
+	cgst, sgst, igst := s.invoiceService.CalculateGST(taxable, counterSaleGSTRate)
+	expectedTotal := roundRupees(taxable + cgst + sgst + igst)
+	if req.PaymentMethod == models.PaymentMethodCash && req.AmountTendered != nil && roundRupees(*req.AmountTendered) < expectedTotal {
+		return nil, fmt.Errorf("%w: amount tendered %.2f is less than the total %.2f", ErrInvalidCounterSale, *req.AmountTendered, expectedTotal)
+	}
+
+	customerID, err := s.resolveCustomer(ctx, tenantID, req.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := fmt.Sprintf("Counter sale at register %s", register.ID)
+	order := &models.Order{
+		TenantID:       tenantID,
+		OrderType:      "sales",
+		DistributorID:  &customerID,
+		ProductID:      req.ProductID,
+		WarehouseID:    req.WarehouseID,
+		Quantity:       req.Quantity,
+		UnitPrice:      unitPrice,
+		Notes:          &notes,
+		BuyerLicenseID: req.BuyerLicenseID,
+	}
+	if err := s.orderService.CreateOrder(ctx, tenantID, order); err != nil {
+		return nil, err
+	}
+	if err := s.fulfil(ctx, tenantID, order.ID); err != nil {
+		if cancelErr := s.orderService.CancelOrder(ctx, tenantID, order.ID); cancelErr != nil {
+			log.Printf("Failed to cancel counter sale order %s after error: %v", order.ID, cancelErr)
+		}
+		return nil, err
+	}
+
+	sale := &models.CounterSale{
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		WarehouseID:      req.WarehouseID,
+		RegisterID:       register.ID,
+		OrderID:          order.ID,
+		DistributorID:    customerID,
+		ProductID:        req.ProductID,
+		Quantity:         req.Quantity,
+		Amount:           expectedTotal,
+		PaymentMethod:    req.PaymentMethod,
+		PaymentReference: req.PaymentReference,
+		CustomerName:     req.CustomerName,
+		CustomerPhone:    req.CustomerPhone,
+		CreatedBy:        userID,
+		ProductName:      product.Name,
+	}
+
+	// The goods have left the counter, so the payment is recorded even if the
+	// invoice could not be raised; it can be generated from the order later
+	invoice, err := s.invoiceAndSettle(ctx, tenantID, order.ID)
+	if err != nil {
+		log.Printf("Failed to invoice counter sale order %s: %v", order.ID, err)
+	} else {
+		sale.InvoiceID = &invoice.ID
+		sale.InvoiceNumber = &invoice.InvoiceNumber
+		sale.Amount = roundRupees(invoice.TotalAmount)
+	}
+
+	if req.PaymentMethod == models.PaymentMethodCash && req.AmountTendered != nil {
+		tendered := roundRupees(*req.AmountTendered)
+		sale.AmountTendered = &tendered
+		sale.ChangeGiven = math.Max(0, roundRupees(tendered-sale.Amount))
+	}
+	if err := s.repo.CreateSale(ctx, sale); err != nil {
+		return nil, fmt.Errorf("failed to record counter sale for order %s: %w", order.ID, err)
+	}
+
+	if updated, err := s.orderService.GetOrderByID(ctx, tenantID, order.ID); err == nil && updated != nil {
+		order = updated
+	}
+	return &models.CounterSaleReceipt{Sale: sale, Order: order, Invoice: invoice}, nil
+}
+
+// fulfil walks a counter order through approval, stock issue and hand-over
+func (s *counterSaleService) fulfil(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	if err := s.orderService.ApproveOrder(ctx, tenantID, orderID); err != nil {
+		return err
+	}
+	if err := s.orderService.ProcessOrder(ctx, tenantID, orderID); err != nil {
+		return err
+	}
+	// Goods handed over the counter are checked there and then
+	if err := s.orderService.QualityCheckOrder(ctx, tenantID, orderID); err != nil && !errors.Is(err, ErrOrderEventNotInWorkflow) {
+		return err
+	}
+	now := time.Now()
+	if err := s.orderService.ShipOrder(ctx, tenantID, orderID, &now); err != nil {
+		return err
+	}
+	return s.orderService.DeliverOrder(ctx, tenantID, orderID)
+}
+
+// invoiceAndSettle raises the delivered order's invoice and marks it paid
+func (s *counterSaleService) invoiceAndSettle(ctx context.Context, tenantID, orderID uuid.UUID) (*models.Invoice, error) {
+	if err := s.invoiceService.AutoGenerateInvoiceOnDelivery(ctx, tenantID, orderID); err != nil {
+		return nil, err
+	}
+	invoices, err := s.invoiceService.GetInvoicesByOrderID(ctx, tenantID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, fmt.Errorf("invoice was not created")
+	}
+	invoice := invoices[0]
+	if err := s.invoiceService.UpdateInvoiceStatus(ctx, tenantID, invoice.ID, "paid"); err != nil {
+		return nil, err
+	}
+	if paid, err := s.invoiceService.GetInvoiceByID(ctx, tenantID, invoice.ID); err == nil && paid != nil {
+		invoice = paid
+	}
+	return invoice, nil
+}
+
+// resolveCustomer checks a named customer or finds, creating if needed, the
+// tenant's walk-in customer
+func (s *counterSaleService) resolveCustomer(ctx context.Context, tenantID uuid.UUID, customerID *uuid.UUID) (uuid.UUID, error) {
+	if customerID != nil {
+		distributor, err := s.distributorRepo.GetByID(ctx, tenantID, *customerID)
+		if err != nil || distributor == nil {
+			return uuid.Nil, fmt.Errorf("%w: customer not found", ErrInvalidCounterSale)
+		}
+		return distributor.ID, nil
+	}
+
+	if walkIn, err := s.distributorRepo.GetByName(ctx, tenantID, WalkInCustomerName); err == nil && walkIn != nil {
+		return walkIn.ID, nil
+	}
+	walkIn := &models.Distributor{
+		ID:              uuid.New(),
+		TenantID:        tenantID,
+		Name:            WalkInCustomerName,
+		GeofenceRadiusM: models.DefaultGeofenceRadiusM,
+	}
+	if err := s.distributorRepo.Create(ctx, walkIn); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create walk-in customer: %w", err)
+	}
+	return walkIn.ID, nil
+}
+
+func (s *counterSaleService) OpenRegister(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.OpenRegisterRequest) (*models.CashRegister, error) {
+	if req.WarehouseID == uuid.Nil {
+		return nil, fmt.Errorf("%w: warehouse_id is required", ErrInvalidCounterSale)
+	}
+	if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, req.WarehouseID); err != nil || warehouse == nil {
+		return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidCounterSale)
+	}
+	openingFloat, err := DenominationTotal(req.Denominations)
+	if err != nil {
+		return nil, err
+	}
+	if req.Denominations == nil {
+		req.Denominations = models.Denominations{}
+	}
+
+	register := &models.CashRegister{
+		ID:                   uuid.New(),
+		TenantID:             tenantID,
+		WarehouseID:          req.WarehouseID,
+		OpeningFloat:         openingFloat,
+		OpeningDenominations: req.Denominations,
+		OpenedBy:             userID,
+	}
+	opened, err := s.repo.OpenRegister(ctx, register)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cash register: %w", err)
+	}
+	if !opened {
+		return nil, ErrRegisterAlreadyOpen
+	}
+	return register, nil
+}
+
+func (s *counterSaleService) CloseRegister(ctx context.Context, tenantID, registerID uuid.UUID, userID *uuid.UUID, req *models.CloseRegisterRequest) (*models.CashRegister, error) {
+	register, err := s.repo.GetRegister(ctx, tenantID, registerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cash register: %w", err)
+	}
+	if register == nil {
+		return nil, ErrRegisterNotFound
+	}
+	if register.Status != models.CashRegisterOpen {
+		return nil, ErrRegisterNotOpen
+	}
+
+	counted, err := DenominationTotal(req.Denominations)
+	if err != nil {
+		return nil, err
+	}
+	cashSales, err := s.repo.CashSalesTotal(ctx, register.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total cash sales: %w", err)
+	}
+	expected := roundRupees(register.OpeningFloat + cashSales)
+	variance := roundRupees(counted - expected)
+	if req.Denominations == nil {
+		req.Denominations = models.Denominations{}
+	}
+
+	register.ClosingDenominations = req.Denominations
+	register.CountedCash = &counted
+	register.ExpectedCash = &expected
+	register.Variance = &variance
+	register.ClosedBy = userID
+	register.Notes = req.Notes
+	register.CashSales = cashSales
+	closed, err := s.repo.CloseRegister(ctx, register)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close cash register: %w", err)
+	}
+	if !closed {
+		return nil, ErrRegisterNotOpen
+	}
+	return register, nil
+}
+
+func (s *counterSaleService) GetRegister(ctx context.Context, tenantID, registerID uuid.UUID) (*models.CashRegister, error) {
+	register, err := s.repo.GetRegister(ctx, tenantID, registerID)
+	if err != nil {
+		return nil, err
+	}
+	if register == nil {
+		return nil, ErrRegisterNotFound
+	}
+	if err := s.withCashSales(ctx, register); err != nil {
+		return nil, err
+	}
+	return register, nil
+}
+
+func (s *counterSaleService) ListRegisters(ctx context.Context, tenantID uuid.UUID, filter *models.CashRegisterFilter) ([]*models.CashRegister, error) {
+	registers, err := s.repo.ListRegisters(ctx, tenantID, filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, register := range registers {
+		if err := s.withCashSales(ctx, register); err != nil {
+			return nil, err
+		}
+	}
+	return registers, nil
+}
+
+// withCashSales fills in the cash taken so far at a register
+func (s *counterSaleService) withCashSales(ctx context.Context, register *models.CashRegister) error {
+	cashSales, err := s.repo.CashSalesTotal(ctx, register.ID)
+	if err != nil {
+		return err
+	}
+	register.CashSales = cashSales
+	return nil
+}
+
+func (s *counterSaleService) DayBook(ctx context.Context, tenantID uuid.UUID, day time.Time, warehouseID *uuid.UUID) (*models.DayBook, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+
+	sales, err := s.repo.ListSales(ctx, tenantID, from, to, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list counter sales: %w", err)
+	}
+	registers, err := s.repo.RegistersActiveBetween(ctx, tenantID, from, to, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cash registers: %w", err)
+	}
+
+	stores := make(map[uuid.UUID]*models.DayBookStore)
+	store := func(id uuid.UUID) *models.DayBookStore {
+		if existing, ok := stores[id]; ok {
+			return existing
+		}
+		created := &models.DayBookStore{WarehouseID: id, Registers: []*models.CashRegister{}, Sales: []*models.CounterSale{}}
+		stores[id] = created
+		return created
+	}
+	for _, register := range registers {
+		if err := s.withCashSales(ctx, register); err != nil {
+			return nil, err
+		}
+		entry := store(register.WarehouseID)
+		entry.Registers = append(entry.Registers, register)
+	}
+
+	book := &models.DayBook{Date: from, Stores: []*models.DayBookStore{}}
+	for _, sale := range sales {
+		entry := store(sale.WarehouseID)
+		entry.Sales = append(entry.Sales, sale)
+		addToDayBookTotals(&entry.Totals, sale)
+		addToDayBookTotals(&book.Totals, sale)
+	}
+
+	ids := make([]uuid.UUID, 0, len(stores))
+	for id := range stores {
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		warehouses, err := s.warehouseRepo.GetByIDs(ctx, tenantID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load warehouses: %w", err)
+		}
+		for _, warehouse := range warehouses {
+			if entry, ok := stores[warehouse.ID]; ok {
+				entry.WarehouseName = warehouse.Name
+			}
+		}
+	}
+
+	for _, entry := range stores {
+		book.Stores = append(book.Stores, entry)
+	}
+	sort.Slice(book.Stores, func(i, j int) bool {
+		if book.Stores[i].WarehouseName != book.Stores[j].WarehouseName {
+			return book.Stores[i].WarehouseName < book.Stores[j].WarehouseName
+		}
+		return book.Stores[i].WarehouseID.String() < book.Stores[j].WarehouseID.String()
+	})
+	return book, nil
+}
+
+func addToDayBookTotals(totals *models.DayBookTotals, sale *models.CounterSale) {
+	totals.Sales++
+	totals.Total = roundRupees(totals.Total + sale.Amount)
+	switch sale.PaymentMethod {
+	case models.PaymentMethodCash:
+		totals.Cash = roundRupees(totals.Cash + sale.Amount)
+	case models.PaymentMethodUPI:
+		totals.UPI = roundRupees(totals.UPI + sale.Amount)
+	case models.PaymentMethodCard:
+		totals.Card = roundRupees(totals.Card + sale.Amount)
+	}
+}
+
+// DenominationTotal values a register count, rejecting unknown notes or coins
+// and negative counts
+func DenominationTotal(denominations models.Denominations) (float64, error) {
+	total := 0.0
+	for key, count := range denominations {
+		value, ok := registerDenominations[key]
+		if !ok {
+			return 0, fmt.Errorf("%w: unknown denomination %q", ErrInvalidCounterSale, key)
+		}
+		if count < 0 {
+			return 0, fmt.Errorf("%w: count for %s must not be negative", ErrInvalidCounterSale, key)
+		}
+		total += value * float64(count)
+	}
+	return roundRupees(total), nil
+}
+
+func roundRupees(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrUnknownResidencyRegion is returned for regions with no configured store
+	ErrUnknownResidencyRegion = errors.New("unknown data residency region")
+	// ErrResidencyViolation is returned instead of reading or writing a
+	// tenant's object outside its residency region
+	ErrResidencyViolation = errors.New("data residency violation")
+	// ErrResidencyTenantNotFound is returned when pinning an unknown tenant
+	ErrResidencyTenantNotFound = errors.New("tenant not found")
+)
+
+// residencyCacheTTL bounds how long an instance keeps routing a tenant to its
+// previous region after another instance changed it
+const residencyCacheTTL = time.Minute
+
+// StorageEndpoint is the object store of one residency region
+type StorageEndpoint struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// DataResidencyService tracks which region each tenant's files must be
+// stored in. Tenants without a region use the default store
+type DataResidencyService interface {
+	// RegionFor returns the tenant's region, or "" for the default store
+	RegionFor(ctx context.Context, tenantID uuid.UUID) (string, error)
+	// SetRegion pins the tenant to a configured region; "" returns it to the
+	// default store. Files already stored stay where they are
+	SetRegion(ctx context.Context, tenantID uuid.UUID, region string) error
+	// Regions lists the configured regions
+	Regions() []string
+}
+
+type residencyEntry struct {
+	region  string
+	expires time.Time
+}
+
+type dataResidencyService struct {
+	tenantRepo repositories.TenantRepository
+	regions    map[string]bool
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]residencyEntry
+}
+
+// NewDataResidencyService creates a residency service for the given regions
+func NewDataResidencyService(tenantRepo repositories.TenantRepository, regions []string) DataResidencyService {
+	configured := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		configured[region] = true
+	}
+	return &dataResidencyService{
+		tenantRepo: tenantRepo,
+		regions:    configured,
+		cache:      make(map[uuid.UUID]residencyEntry),
+	}
+}
+
+func (s *dataResidencyService) RegionFor(ctx context.Context, tenantID uuid.UUID) (string, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[tenantID]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.region, nil
+	}
+
+	tenant, err := s.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrResidencyTenantNotFound
+		}
+		return "", fmt.Errorf("failed to load tenant residency: %w", err)
+	}
+	region := ""
+	if tenant.Residency != nil {
+		region = *tenant.Residency
+	}
+
+	s.mu.Lock()
+	s.cache[tenantID] = residencyEntry{region: region, expires: time.Now().Add(residencyCacheTTL)}
+	s.mu.Unlock()
+	return region, nil
+}
+
+func (s *dataResidencyService) SetRegion(ctx context.Context, tenantID uuid.UUID, region string) error {
+	region = strings.ToLower(strings.TrimSpace(region))
+	var value *string
+	if region != "" {
+		if !s.regions[region] {
+			return fmt.Errorf("%w: %s", ErrUnknownResidencyRegion, region)
+		}
+		value = &region
+	}
+
+	if err := s.tenantRepo.SetResidency(ctx, tenantID, value); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrResidencyTenantNotFound
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *dataResidencyService) Regions() []string {
+	regions := make([]string, 0, len(s.regions))
+	for region := range s.regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// residencyMinioService routes object storage calls to the store of the
+// tenant that owns the object. Object keys start with the owning tenant's ID,
+// which is how the tenant is found; keys without one use the default store.
+// Calls for a tenant whose region has no store fail rather than fall back to
+// the default, and a request may not write another tenant's object when the
+// two live in different regions
+type residencyMinioService struct {
+	defaultStore MinioService
+	regional     map[string]MinioService
+	residency    DataResidencyService
+}
+
+// NewResidencyMinioService wraps the default store with per-region stores.
+// With no regional stores it returns defaultStore unchanged
+func NewResidencyMinioService(defaultStore MinioService, regional map[string]MinioService, residency DataResidencyService) MinioService {
+	if len(regional) == 0 {
+		return defaultStore
+	}
+	return &residencyMinioService{defaultStore: defaultStore, regional: regional, residency: residency}
+}
+
+// objectTenant returns the tenant an object key or prefix belongs to
+func objectTenant(key string) (uuid.UUID, bool) {
+	if len(key) < 36 {
+		return uuid.Nil, false
+	}
+	if len(key) > 36 && key[36] != '/' && key[36] != '-' {
+		return uuid.Nil, false
+	}
+	tenantID, err := uuid.Parse(key[:36])
+	return tenantID, err == nil
+}
+
+// storeFor returns the store of the region the object's tenant lives in
+func (m *residencyMinioService) storeFor(ctx context.Context, objectName string, write bool) (MinioService, error) {
+	region := ""
+	tenantID, owned := objectTenant(objectName)
+	if owned {
+		var err error
+		if region, err = m.residency.RegionFor(ctx, tenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A request may only write into its own region, so a pinned tenant's
+	// files can never be written to another region's store on its behalf
+	if requestTenant, ok := common.RequestContextFrom(ctx).Tenant(); ok && write && requestTenant != tenantID {
+		requestRegion, err := m.residency.RegionFor(ctx, requestTenant)
+		if err != nil {
+			return nil, err
+		}
+		if requestRegion != region {
+			return nil, fmt.Errorf("%w: tenant in region %q writing %s to region %q",
+				ErrResidencyViolation, requestRegion, objectName, region)
+		}
+	}
+
+	if region == "" {
+		return m.defaultStore, nil
+	}
+	store, ok := m.regional[region]
+	if !ok {
+		return nil, fmt.Errorf("%w: no store configured for region %q", ErrResidencyViolation, region)
+	}
+	return store, nil
+}
+
+func (m *residencyMinioService) UploadImage(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64) error {
+	store, err := m.storeFor(ctx, objectName, true)
+	if err != nil {
+		return err
+	}
+	return store.UploadImage(ctx, bucketName, objectName, reader, objectSize)
+}
+
+func (m *residencyMinioService) UploadObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, contentType string) error {
+	store, err := m.storeFor(ctx, objectName, true)
+	if err != nil {
+		return err
+	}
+	return store.UploadObject(ctx, bucketName, objectName, reader, objectSize, contentType)
+}
+
+func (m *residencyMinioService) GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error) {
+	store, err := m.storeFor(ctx, objectName, false)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetObject(ctx, bucketName, objectName)
+}
+
+func (m *residencyMinioService) GetPresignedURL(bucketName, objectName string, expiry time.Duration) (string, error) {
+	store, err := m.storeFor(context.Background(), objectName, false)
+	if err != nil {
+		return "", err
+	}
+	return store.GetPresignedURL(bucketName, objectName, expiry)
+}
+
+// DeleteImage deletes from the object's own region; a delete places no data,
+// so it is not held to the request's region
+func (m *residencyMinioService) DeleteImage(ctx context.Context, bucketName, objectName string) error {
+	store, err := m.storeFor(ctx, objectName, false)
+	if err != nil {
+		return err
+	}
+	return store.DeleteImage(ctx, bucketName, objectName)
+}
+
+// EnsureBucketExists creates the bucket in every region's store
+func (m *residencyMinioService) EnsureBucketExists(ctx context.Context, bucketName string) error {
+	if err := m.defaultStore.EnsureBucketExists(ctx, bucketName); err != nil {
+		return err
+	}
+	for region, store := range m.regional {
+		if err := store.EnsureBucketExists(ctx, bucketName); err != nil {
+			return fmt.Errorf("failed to create bucket %s in region %s: %w", bucketName, region, err)
+		}
+	}
+	return nil
+}
+
+// ListObjects lists the tenant's region when the prefix names a tenant, and
+// every store otherwise
+func (m *residencyMinioService) ListObjects(ctx context.Context, bucketName, prefix string) ([]string, error) {
+	if _, owned := objectTenant(prefix); owned {
+		store, err := m.storeFor(ctx, prefix, false)
+		if err != nil {
+			return nil, err
+		}
+		return store.ListObjects(ctx, bucketName, prefix)
+	}
+
+	keys, err := m.defaultStore.ListObjects(ctx, bucketName, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for region, store := range m.regional {
+		regionKeys, err := store.ListObjects(ctx, bucketName, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list bucket %s in region %s: %w", bucketName, region, err)
+		}
+		keys = append(keys, regionKeys...)
+	}
+	return keys, nil
+}
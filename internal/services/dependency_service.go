@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrHasDependencies is wrapped by *DependencyConflictError
+var ErrHasDependencies = errors.New("resource has dependent records")
+
+// DependencyConflictError is returned when a delete is blocked by records
+// that still depend on the resource
+type DependencyConflictError struct {
+	Resource   string
+	ID         uuid.UUID
+	References []*models.BlockingReference
+}
+
+func (e *DependencyConflictError) Error() string {
+	parts := make([]string, 0, len(e.References))
+	for _, ref := range e.References {
+		parts = append(parts, fmt.Sprintf("%d %s", ref.Count, strings.ReplaceAll(ref.Type, "_", " ")))
+	}
+	return fmt.Sprintf("%s cannot be deleted: %s", e.Resource, strings.Join(parts, ", "))
+}
+
+func (e *DependencyConflictError) Unwrap() error {
+	return ErrHasDependencies
+}
+
+// DependencyService guards deletes against orphaning dependent records
+type DependencyService interface {
+	// CheckDeletable returns a *DependencyConflictError listing what blocks the delete
+	CheckDeletable(ctx context.Context, tenantID uuid.UUID, resource string, id uuid.UUID) error
+}
+
+type dependencyService struct {
+	dependencyRepo repositories.DependencyRepository
+}
+
+// NewDependencyService creates a new dependency service instance
+func NewDependencyService(dependencyRepo repositories.DependencyRepository) DependencyService {
+	return &dependencyService{dependencyRepo: dependencyRepo}
+}
+
+func (s *dependencyService) CheckDeletable(ctx context.Context, tenantID uuid.UUID, resource string, id uuid.UUID) error {
+	refs, err := s.dependencyRepo.BlockingReferences(ctx, tenantID, resource, id)
+	if err != nil {
+		return common.SecureErrorMessage("check "+resource+" dependencies", err)
+	}
+	if len(refs) > 0 {
+		return &DependencyConflictError{Resource: resource, ID: id, References: refs}
+	}
+	return nil
+}
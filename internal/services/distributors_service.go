@@ -3,7 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
@@ -13,35 +17,52 @@ import (
 type DistributorService interface {
 	Create(ctx context.Context, tenantID uuid.UUID, distributor *models.Distributor) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Distributor, error)
+	// GetByIDs returns the distributors found among ids, archived ones included
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Distributor, error)
 	Update(ctx context.Context, tenantID uuid.UUID, distributor *models.Distributor) error
-	Delete(ctx context.Context, tenantID, id uuid.UUID) error
-	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Distributor, error)
+	// Delete removes the distributor, or archives it when past orders reference it
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (archived bool, err error)
+	List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Distributor, error)
 	GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Distributor, error)
+	// FindByContact finds distributors whose email, phone or GSTIN is exactly value
+	FindByContact(ctx context.Context, tenantID uuid.UUID, value string) ([]*models.Distributor, error)
 }
 
 type distributorService struct {
 	distributorRepo repositories.DistributorRepository
+	dependencySvc   DependencyService
 }
 
-func NewDistributorService(distributorRepo repositories.DistributorRepository) DistributorService {
+func NewDistributorService(distributorRepo repositories.DistributorRepository, dependencySvc DependencyService) DistributorService {
 	return &distributorService{
 		distributorRepo: distributorRepo,
+		dependencySvc:   dependencySvc,
 	}
 }
 
 func (s *distributorService) Create(ctx context.Context, tenantID uuid.UUID, distributor *models.Distributor) error {
+	distributor.Name = strings.TrimSpace(distributor.Name)
 	if distributor.Name == "" {
 		return errors.New("distributor name is required")
 	}
+	if err := normalizeGSTIN(&distributor.GSTIN); err != nil {
+		return err
+	}
+	if err := normalizeBankAccount(&distributor.BankAccountNumber, &distributor.BankIFSC); err != nil {
+		return err
+	}
 
 	// Check for duplicate name
 	existing, err := s.distributorRepo.GetByName(ctx, tenantID, distributor.Name)
 	if err == nil && existing != nil {
-		return errors.New("distributor with this name already exists")
+		return fmt.Errorf("%w: distributor with this name already exists", ErrDuplicateName)
 	}
 
 	distributor.TenantID = tenantID
 	distributor.ID = uuid.New()
+	if distributor.GeofenceRadiusM <= 0 {
+		distributor.GeofenceRadiusM = models.DefaultGeofenceRadiusM
+	}
 
 	return s.distributorRepo.Create(ctx, distributor)
 }
@@ -50,23 +71,69 @@ func (s *distributorService) GetByID(ctx context.Context, tenantID, id uuid.UUID
 	return s.distributorRepo.GetByID(ctx, tenantID, id)
 }
 
+func (s *distributorService) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Distributor, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.distributorRepo.GetByIDs(ctx, tenantID, ids)
+}
+
 func (s *distributorService) Update(ctx context.Context, tenantID uuid.UUID, distributor *models.Distributor) error {
+	distributor.Name = strings.TrimSpace(distributor.Name)
 	if distributor.Name == "" {
 		return errors.New("distributor name is required")
 	}
+	if err := normalizeGSTIN(&distributor.GSTIN); err != nil {
+		return err
+	}
+	if err := normalizeBankAccount(&distributor.BankAccountNumber, &distributor.BankIFSC); err != nil {
+		return err
+	}
+
+	existing, err := s.distributorRepo.GetByName(ctx, tenantID, distributor.Name)
+	if err == nil && existing != nil && existing.ID != distributor.ID {
+		return fmt.Errorf("%w: distributor with this name already exists", ErrDuplicateName)
+	}
 
 	distributor.TenantID = tenantID
 	return s.distributorRepo.Update(ctx, distributor)
 }
 
-func (s *distributorService) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
-	return s.distributorRepo.Delete(ctx, tenantID, id)
+func (s *distributorService) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	if err := s.dependencySvc.CheckDeletable(ctx, tenantID, models.DependentDistributor, id); err != nil {
+		return false, err
+	}
+	orders, err := s.distributorRepo.OrderCount(ctx, tenantID, id)
+	if err != nil {
+		return false, common.SecureErrorMessage("check distributor usage", err)
+	}
+	if orders > 0 {
+		if err := s.distributorRepo.Archive(ctx, tenantID, id); err != nil {
+			return false, common.SecureErrorMessage("archive distributor", err)
+		}
+		return true, nil
+	}
+	if err := s.distributorRepo.Delete(ctx, tenantID, id); err != nil {
+		return false, common.SecureErrorMessage("delete distributor", err)
+	}
+	return false, nil
 }
 
-func (s *distributorService) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Distributor, error) {
-	return s.distributorRepo.List(ctx, tenantID, limit, offset)
+func (s *distributorService) List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Distributor, error) {
+	return s.distributorRepo.ListPage(ctx, tenantID, page)
 }
 
 func (s *distributorService) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Distributor, error) {
 	return s.distributorRepo.GetByName(ctx, tenantID, name)
-}
\ No newline at end of file
+}
+
+func (s *distributorService) FindByContact(ctx context.Context, tenantID uuid.UUID, value string) ([]*models.Distributor, error) {
+	distributors, err := s.distributorRepo.FindByContact(ctx, tenantID, strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+	if distributors == nil {
+		distributors = []*models.Distributor{}
+	}
+	return distributors, nil
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// dunningCatchUpDays is how late a missed cadence step may still go out,
+	// e.g. after the scheduler was down; older steps are skipped
+	dunningCatchUpDays = 3
+
+	dunningMaxOffsetDays = 365
+	dunningMaxSteps      = 10
+)
+
+// defaultDunningSchedule applies until a tenant configures its own cadence
+func defaultDunningSchedule(tenantID uuid.UUID) *models.DunningSchedule {
+	return &models.DunningSchedule{
+		TenantID:        tenantID,
+		Enabled:         true,
+		OffsetDays:      []int{-3, 0, 7, 15},
+		Channels:        []string{models.DunningChannelWhatsApp},
+		DefaultLanguage: "en",
+	}
+}
+
+// Wording used when a tenant has no template for the channel in any fitting language
+var defaultDunningTemplates = map[string]struct{ subject, body string }{
+	models.DunningChannelEmail: {
+		subject: "Payment reminder: invoice {{.InvoiceNumber}}",
+		body: "Dear {{.DistributorName}},\n\n" +
+			"{{if lt .DaysOverdue 0}}Invoice {{.InvoiceNumber}} for Rs. {{.Amount}} is due on {{.DueDate}}." +
+			"{{else if eq .DaysOverdue 0}}Invoice {{.InvoiceNumber}} for Rs. {{.Amount}} is due today." +
+			"{{else}}Invoice {{.InvoiceNumber}} for Rs. {{.Amount}} was due on {{.DueDate}} and is {{.DaysOverdue}} days overdue.{{end}}" +
+			" Please arrange payment at the earliest.\n\nPlease ignore this reminder if you have already paid.",
+	},
+	models.DunningChannelSMS: {
+		body: "Reminder: invoice {{.InvoiceNumber}} for Rs. {{.Amount}} " +
+			"{{if lt .DaysOverdue 0}}is due on {{.DueDate}}{{else if eq .DaysOverdue 0}}is due today{{else}}is {{.DaysOverdue}} days overdue{{end}}" +
+			". Please ignore if already paid.",
+	},
+}
+
+// DunningService sends invoice payment reminders on each tenant's cadence and
+// keeps their history
+type DunningService interface {
+	// GetSchedule returns the tenant's cadence, or the default one
+	GetSchedule(ctx context.Context, tenantID uuid.UUID) (*models.DunningSchedule, error)
+	UpdateSchedule(ctx context.Context, tenantID uuid.UUID, schedule *models.DunningSchedule) error
+
+	ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.DunningTemplate, error)
+	SaveTemplate(ctx context.Context, tenantID uuid.UUID, template *models.DunningTemplate) error
+	DeleteTemplate(ctx context.Context, tenantID, templateID uuid.UUID) error
+
+	// RunReminders sends the cadence steps that have come due for the tenant's
+	// outstanding invoices and returns how many reminders went out
+	RunReminders(ctx context.Context, tenantID uuid.UUID) (int, error)
+	ListInvoiceReminders(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceReminder, error)
+}
+
+type dunningService struct {
+	repo            repositories.DunningRepository
+	notificationSvc NotificationService
+}
+
+// NewDunningService creates a new payment reminder service
+func NewDunningService(repo repositories.DunningRepository, notificationSvc NotificationService) DunningService {
+	return &dunningService{
+		repo:            repo,
+		notificationSvc: notificationSvc,
+	}
+}
+
+func (s *dunningService) GetSchedule(ctx context.Context, tenantID uuid.UUID) (*models.DunningSchedule, error) {
+	schedule, err := s.repo.GetSchedule(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return defaultDunningSchedule(tenantID), nil
+	}
+	return schedule, nil
+}
+
+func (s *dunningService) UpdateSchedule(ctx context.Context, tenantID uuid.UUID, schedule *models.DunningSchedule) error {
+	if len(schedule.OffsetDays) == 0 || len(schedule.OffsetDays) > dunningMaxSteps {
+		return fmt.Errorf("offset_days must have between 1 and %d entries", dunningMaxSteps)
+	}
+	seen := make(map[int]bool, len(schedule.OffsetDays))
+	offsets := make([]int, 0, len(schedule.OffsetDays))
+	for _, offset := range schedule.OffsetDays {
+		if offset < -dunningMaxOffsetDays || offset > dunningMaxOffsetDays {
+			return fmt.Errorf("offset_days must be within %d days of the due date", dunningMaxOffsetDays)
+		}
+		if !seen[offset] {
+			seen[offset] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	sort.Ints(offsets)
+
+	if len(schedule.Channels) == 0 {
+		return fmt.Errorf("at least one channel is required")
+	}
+	seenChannel := make(map[string]bool, len(schedule.Channels))
+	channels := make([]string, 0, len(schedule.Channels))
+	for _, channel := range schedule.Channels {
+		if !isDunningChannel(channel) {
+			return fmt.Errorf("unknown channel %q; use whatsapp, email or sms", channel)
+		}
+		if !seenChannel[channel] {
+			seenChannel[channel] = true
+			channels = append(channels, channel)
+		}
+	}
+
+	schedule.DefaultLanguage = strings.ToLower(strings.TrimSpace(schedule.DefaultLanguage))
+	if schedule.DefaultLanguage == "" {
+		schedule.DefaultLanguage = "en"
+	}
+
+	schedule.TenantID = tenantID
+	schedule.OffsetDays = offsets
+	schedule.Channels = channels
+	return s.repo.UpsertSchedule(ctx, schedule)
+}
+
+func (s *dunningService) ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.DunningTemplate, error) {
+	return s.repo.ListTemplates(ctx, tenantID)
+}
+
+func (s *dunningService) SaveTemplate(ctx context.Context, tenantID uuid.UUID, tmpl *models.DunningTemplate) error {
+	if tmpl.Channel != models.DunningChannelEmail && tmpl.Channel != models.DunningChannelSMS {
+		return fmt.Errorf("channel must be email or sms; WhatsApp reminders use approved WhatsApp templates")
+	}
+	tmpl.Language = strings.ToLower(strings.TrimSpace(tmpl.Language))
+	if tmpl.Language == "" {
+		return fmt.Errorf("language is required")
+	}
+	if strings.TrimSpace(tmpl.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+	if tmpl.Channel == models.DunningChannelEmail && (tmpl.Subject == nil || strings.TrimSpace(*tmpl.Subject) == "") {
+		return fmt.Errorf("subject is required for email templates")
+	}
+
+	// Render once with sample data so broken templates fail here rather than at send time
+	sample := models.InvoiceReminderData{DistributorName: "Sample Agro", InvoiceNumber: "INV-0001", Amount: "1000.00", DueDate: "01 Jan 2025", DaysOverdue: 7}
+	if _, err := renderDunningText(tmpl.Body, sample); err != nil {
+		return fmt.Errorf("invalid body: %v", err)
+	}
+	if tmpl.Subject != nil {
+		if _, err := renderDunningText(*tmpl.Subject, sample); err != nil {
+			return fmt.Errorf("invalid subject: %v", err)
+		}
+	}
+
+	tmpl.ID = uuid.New()
+	tmpl.TenantID = tenantID
+	return s.repo.UpsertTemplate(ctx, tmpl)
+}
+
+func (s *dunningService) DeleteTemplate(ctx context.Context, tenantID, templateID uuid.UUID) error {
+	deleted, err := s.repo.DeleteTemplate(ctx, tenantID, templateID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("template not found")
+	}
+	return nil
+}
+
+func (s *dunningService) ListInvoiceReminders(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceReminder, error) {
+	return s.repo.ListReminders(ctx, tenantID, invoiceID)
+}
+
+func (s *dunningService) RunReminders(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	schedule, err := s.GetSchedule(ctx, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load reminder schedule: %w", err)
+	}
+	if !schedule.Enabled || len(schedule.OffsetDays) == 0 {
+		return 0, nil
+	}
+
+	y, m, d := time.Now().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	first, last := schedule.OffsetDays[0], schedule.OffsetDays[len(schedule.OffsetDays)-1]
+	candidates, err := s.repo.OutstandingInvoices(ctx, tenantID,
+		today.AddDate(0, 0, -last-dunningCatchUpDays), today.AddDate(0, 0, -first))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find outstanding invoices: %w", err)
+	}
+
+	sent := 0
+	for _, candidate := range candidates {
+		offset, ok := dueReminderStep(schedule.OffsetDays, candidate.DueDate, candidate.DoneOffsets, today)
+		if !ok {
+			continue
+		}
+		// Stop as soon as the invoice is paid or cancelled, even mid-run
+		outstanding, err := s.repo.IsOutstanding(ctx, tenantID, candidate.InvoiceID)
+		if err != nil {
+			log.Printf("Failed to check invoice %s before reminding: %v", candidate.InvoiceNumber, err)
+			continue
+		}
+		if !outstanding {
+			continue
+		}
+
+		language := schedule.DefaultLanguage
+		if candidate.PreferredLanguage != nil && *candidate.PreferredLanguage != "" {
+			language = *candidate.PreferredLanguage
+		}
+		data := models.InvoiceReminderData{
+			DistributorName: candidate.DistributorName,
+			InvoiceNumber:   candidate.InvoiceNumber,
+			Amount:          fmt.Sprintf("%.2f", candidate.TotalAmount),
+			DueDate:         candidate.DueDate.Format("02 Jan 2006"),
+			DaysOverdue:     daysBetween(candidate.DueDate, today),
+		}
+
+		for _, channel := range schedule.Channels {
+			reminder := s.deliver(ctx, tenantID, channel, candidate, language, schedule.DefaultLanguage, data)
+			reminder.ID = uuid.New()
+			reminder.TenantID = tenantID
+			reminder.InvoiceID = candidate.InvoiceID
+			reminder.OffsetDays = offset
+			reminder.Channel = channel
+			recorded, err := s.repo.RecordReminder(ctx, reminder)
+			if err != nil {
+				log.Printf("Failed to record %s reminder for invoice %s: %v", channel, candidate.InvoiceNumber, err)
+				continue
+			}
+			if recorded && reminder.Status == models.InvoiceReminderSent {
+				sent++
+			}
+		}
+	}
+	return sent, nil
+}
+
+// deliver sends one reminder through a channel and reports the outcome
+func (s *dunningService) deliver(ctx context.Context, tenantID uuid.UUID, channel string, candidate *models.DunningCandidate, language, defaultLanguage string, data models.InvoiceReminderData) *models.InvoiceReminder {
+	reminder := &models.InvoiceReminder{Language: language, Status: models.InvoiceReminderSent}
+	skip := func(reason string) *models.InvoiceReminder {
+		reminder.Status = models.InvoiceReminderSkipped
+		reminder.ErrorMessage = &reason
+		return reminder
+	}
+	fail := func(err error) *models.InvoiceReminder {
+		message := err.Error()
+		reminder.Status = models.InvoiceReminderFailed
+		reminder.ErrorMessage = &message
+		return reminder
+	}
+
+	var recipient *string
+	if channel == models.DunningChannelEmail {
+		recipient = candidate.ContactEmail
+	} else {
+		recipient = candidate.ContactPhone
+	}
+	if recipient == nil || strings.TrimSpace(*recipient) == "" {
+		return skip(fmt.Sprintf("distributor has no %s contact", channel))
+	}
+	reminder.Recipient = recipient
+
+	if channel == models.DunningChannelWhatsApp {
+		err := s.notificationSvc.SendWhatsApp(ctx, tenantID, &models.WhatsAppSend{
+			Phone:     *recipient,
+			EventType: models.WhatsAppEventPaymentReminder,
+			EventID:   candidate.InvoiceID.String(),
+			Language:  language,
+			Params: map[string]string{
+				"distributor_name": data.DistributorName,
+				"invoice_number":   data.InvoiceNumber,
+				"amount":           data.Amount,
+				"due_date":         data.DueDate,
+				"days_overdue":     fmt.Sprintf("%d", data.DaysOverdue),
+			},
+		})
+		switch {
+		case err == nil:
+			return reminder
+		case errors.Is(err, ErrWhatsAppNotOptedIn), errors.Is(err, ErrWhatsAppNoTemplate):
+			return skip(err.Error())
+		default:
+			return fail(err)
+		}
+	}
+
+	subject, body, usedLanguage, err := s.renderReminder(ctx, tenantID, channel, []string{language, defaultLanguage, "en"}, data)
+	if err != nil {
+		return fail(err)
+	}
+	reminder.Language = usedLanguage
+	if channel == models.DunningChannelEmail {
+		err = s.notificationSvc.SendEmail(ctx, tenantID, *recipient, subject, body)
+	} else {
+		err = s.notificationSvc.SendSMS(ctx, tenantID, *recipient, body)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return reminder
+}
+
+// renderReminder renders the tenant's template in the first available
+// language, falling back to the built-in English wording
+func (s *dunningService) renderReminder(ctx context.Context, tenantID uuid.UUID, channel string, languages []string, data models.InvoiceReminderData) (string, string, string, error) {
+	subjectText := defaultDunningTemplates[channel].subject
+	bodyText := defaultDunningTemplates[channel].body
+	language := "en"
+
+	tmpl, err := s.repo.FindTemplate(ctx, tenantID, channel, languages)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to load reminder template: %w", err)
+	}
+	if tmpl != nil {
+		bodyText = tmpl.Body
+		language = tmpl.Language
+		if tmpl.Subject != nil {
+			subjectText = *tmpl.Subject
+		}
+	}
+
+	subject, err := renderDunningText(subjectText, data)
+	if err != nil {
+		return "", "", "", err
+	}
+	body, err := renderDunningText(bodyText, data)
+	if err != nil {
+		return "", "", "", err
+	}
+	return subject, body, language, nil
+}
+
+func renderDunningText(text string, data models.InvoiceReminderData) (string, error) {
+	tmpl, err := template.New("reminder").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// dueReminderStep picks the latest cadence offset reached by today, provided
+// it is within the catch-up window and has not been handled; earlier steps are
+// never sent once a later one is due
+func dueReminderStep(offsets []int, dueDate time.Time, done []int, today time.Time) (int, bool) {
+	for i := len(offsets) - 1; i >= 0; i-- {
+		late := daysBetween(dueDate, today) - offsets[i]
+		if late < 0 {
+			continue
+		}
+		if late > dunningCatchUpDays {
+			return 0, false
+		}
+		for _, handled := range done {
+			if handled == offsets[i] {
+				return 0, false
+			}
+		}
+		return offsets[i], true
+	}
+	return 0, false
+}
+
+// daysBetween counts calendar days from a to b, negative when b is earlier
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	from := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	to := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func isDunningChannel(channel string) bool {
+	switch channel {
+	case models.DunningChannelWhatsApp, models.DunningChannelEmail, models.DunningChannelSMS:
+		return true
+	}
+	return false
+}
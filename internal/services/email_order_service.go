@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrEmailOrdersNotConfigured is returned before a tenant sets up email ordering
+	ErrEmailOrdersNotConfigured = errors.New("email ordering is not configured")
+	// ErrInvalidEmailOrder wraps email ordering validation failures
+	ErrInvalidEmailOrder = errors.New("invalid email order")
+	// ErrEmailOrderNotFound is returned for unknown senders and draft orders
+	ErrEmailOrderNotFound = errors.New("email order not found")
+	// ErrEmailOrderConflict is returned for a sender address already known and
+	// for drafts that have already been reviewed
+	ErrEmailOrderConflict = errors.New("email order conflict")
+)
+
+// DefaultEmailOrderLinePattern reads lines such as "UREA-50 x 10 @ 266.50",
+// "UREA-50, 10, 266.50" or "UREA-50 10"
+const DefaultEmailOrderLinePattern = `^\s*(?P<sku>[A-Za-z0-9][A-Za-z0-9._/-]*)\s*(?:[,;:\t]\s*|\s+x\s*|\s+)(?P<quantity>\d+)(?:\s*(?:[,;\t@]\s*|\s+)(?P<price>\d+(?:\.\d+)?))?\s*$`
+
+// CompileEmailOrderLinePattern compiles a tenant's line pattern, or the
+// default when it is unset, checking it names the sku and quantity groups
+func CompileEmailOrderLinePattern(pattern *string) (*regexp.Regexp, error) {
+	source := DefaultEmailOrderLinePattern
+	if pattern != nil && strings.TrimSpace(*pattern) != "" {
+		source = *pattern
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: line_pattern is not a valid regular expression", ErrInvalidEmailOrder)
+	}
+	if re.SubexpIndex("sku") < 0 || re.SubexpIndex("quantity") < 0 {
+		return nil, fmt.Errorf("%w: line_pattern must have named groups sku and quantity", ErrInvalidEmailOrder)
+	}
+	return re, nil
+}
+
+// EmailOrderService configures a tenant's order mailbox, stores order emails
+// for the ingestion worker and runs the review queue of the draft orders it
+// parses. Approving a draft creates a sales order per line
+type EmailOrderService interface {
+	GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.EmailOrderSettings, error)
+	SaveSettings(ctx context.Context, tenantID uuid.UUID, req *models.EmailOrderSettingsRequest) (*models.EmailOrderSettings, error)
+	RotateToken(ctx context.Context, tenantID uuid.UUID) (*models.EmailOrderSettings, error)
+
+	ListSenders(ctx context.Context, tenantID uuid.UUID) ([]*models.EmailOrderSender, error)
+	AddSender(ctx context.Context, sender *models.EmailOrderSender) error
+	RemoveSender(ctx context.Context, tenantID, id uuid.UUID) error
+
+	// ReceiveEmail stores an email sent to the mailbox with the token. It
+	// returns ErrEmailOrdersNotConfigured for unknown or disabled mailboxes
+	ReceiveEmail(ctx context.Context, token string, email *models.InboundEmail) error
+	ListEmails(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InboundEmail, error)
+
+	ListDrafts(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.EmailDraftOrder, error)
+	GetDraft(ctx context.Context, tenantID, id uuid.UUID) (*models.EmailDraftOrder, error)
+	UpdateDraft(ctx context.Context, tenantID, id uuid.UUID, req *models.EmailDraftOrderUpdate) (*models.EmailDraftOrder, error)
+	ApproveDraft(ctx context.Context, tenantID, id, userID uuid.UUID, note *string) (*models.EmailDraftOrder, error)
+	RejectDraft(ctx context.Context, tenantID, id, userID uuid.UUID, note *string) (*models.EmailDraftOrder, error)
+}
+
+type emailOrderService struct {
+	repo            repositories.EmailOrderRepository
+	productRepo     repositories.ProductRepository
+	warehouseRepo   repositories.WarehouseRepository
+	distributorRepo repositories.DistributorRepository
+	orderService    OrderServiceInterface
+}
+
+// NewEmailOrderService creates a new email order service
+func NewEmailOrderService(repo repositories.EmailOrderRepository, productRepo repositories.ProductRepository,
+	warehouseRepo repositories.WarehouseRepository, distributorRepo repositories.DistributorRepository,
+	orderService OrderServiceInterface) EmailOrderService {
+	return &emailOrderService{
+		repo:            repo,
+		productRepo:     productRepo,
+		warehouseRepo:   warehouseRepo,
+		distributorRepo: distributorRepo,
+		orderService:    orderService,
+	}
+}
+
+func newEmailInboundToken() (string, error) {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate inbound token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+func (s *emailOrderService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.EmailOrderSettings, error) {
+	settings, err := s.repo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email order settings: %w", err)
+	}
+	if settings == nil {
+		return nil, ErrEmailOrdersNotConfigured
+	}
+	return settings, nil
+}
+
+func (s *emailOrderService) SaveSettings(ctx context.Context, tenantID uuid.UUID, req *models.EmailOrderSettingsRequest) (*models.EmailOrderSettings, error) {
+	settings, err := s.repo.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email order settings: %w", err)
+	}
+	if settings == nil {
+		token, err := newEmailInboundToken()
+		if err != nil {
+			return nil, err
+		}
+		settings = &models.EmailOrderSettings{
+			TenantID:       tenantID,
+			InboundToken:   token,
+			Enabled:        true,
+			SKUColumn:      "sku",
+			QuantityColumn: "quantity",
+			PriceColumn:    "price",
+		}
+	}
+
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.DefaultWarehouseID != nil {
+		if _, err := s.warehouseRepo.GetByID(ctx, tenantID, *req.DefaultWarehouseID); err != nil {
+			return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidEmailOrder)
+		}
+		settings.DefaultWarehouseID = req.DefaultWarehouseID
+	}
+	if req.LinePattern != nil {
+		settings.LinePattern = trimmedOrNil(req.LinePattern)
+		if _, err := CompileEmailOrderLinePattern(settings.LinePattern); err != nil {
+			return nil, err
+		}
+	}
+	for _, column := range []struct {
+		value  *string
+		target *string
+		name   string
+	}{
+		{req.SKUColumn, &settings.SKUColumn, "sku_column"},
+		{req.QuantityColumn, &settings.QuantityColumn, "quantity_column"},
+		{req.PriceColumn, &settings.PriceColumn, "price_column"},
+	} {
+		if column.value == nil {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(*column.value))
+		if name == "" || len(name) > 50 {
+			return nil, fmt.Errorf("%w: %s must be 1 to 50 characters", ErrInvalidEmailOrder, column.name)
+		}
+		*column.target = name
+	}
+
+	if err := s.repo.UpsertSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to save email order settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (s *emailOrderService) RotateToken(ctx context.Context, tenantID uuid.UUID) (*models.EmailOrderSettings, error) {
+	settings, err := s.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.InboundToken, err = newEmailInboundToken(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpsertSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to save email order settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (s *emailOrderService) ListSenders(ctx context.Context, tenantID uuid.UUID) ([]*models.EmailOrderSender, error) {
+	return s.repo.ListSenders(ctx, tenantID)
+}
+
+func (s *emailOrderService) AddSender(ctx context.Context, sender *models.EmailOrderSender) error {
+	address := strings.ToLower(strings.TrimSpace(sender.Address))
+	if strings.HasPrefix(address, "@") {
+		if len(address) < 4 || !strings.Contains(address[1:], ".") || strings.Contains(address[1:], "@") {
+			return fmt.Errorf("%w: address must be an email address or @domain", ErrInvalidEmailOrder)
+		}
+	} else if parsed, err := mail.ParseAddress(address); err != nil || parsed.Address != address {
+		return fmt.Errorf("%w: address must be an email address or @domain", ErrInvalidEmailOrder)
+	}
+	sender.Address = address
+
+	if _, err := s.distributorRepo.GetByID(ctx, sender.TenantID, sender.DistributorID); err != nil {
+		return fmt.Errorf("%w: distributor not found", ErrInvalidEmailOrder)
+	}
+	if sender.WarehouseID != nil {
+		if _, err := s.warehouseRepo.GetByID(ctx, sender.TenantID, *sender.WarehouseID); err != nil {
+			return fmt.Errorf("%w: warehouse not found", ErrInvalidEmailOrder)
+		}
+	}
+
+	sender.ID = uuid.New()
+	created, err := s.repo.CreateSender(ctx, sender)
+	if err != nil {
+		return fmt.Errorf("failed to add sender: %w", err)
+	}
+	if !created {
+		return fmt.Errorf("%w: %s is already a known sender", ErrEmailOrderConflict, address)
+	}
+	return nil
+}
+
+func (s *emailOrderService) RemoveSender(ctx context.Context, tenantID, id uuid.UUID) error {
+	deleted, err := s.repo.DeleteSender(ctx, tenantID, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove sender: %w", err)
+	}
+	if !deleted {
+		return ErrEmailOrderNotFound
+	}
+	return nil
+}
+
+func (s *emailOrderService) ReceiveEmail(ctx context.Context, token string, email *models.InboundEmail) error {
+	settings, err := s.repo.GetSettingsByToken(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to load email order settings: %w", err)
+	}
+	if settings == nil || !settings.Enabled {
+		return ErrEmailOrdersNotConfigured
+	}
+
+	email.ID = uuid.New()
+	email.TenantID = settings.TenantID
+	email.Status = models.InboundEmailPending
+	if email.Attachments == nil {
+		email.Attachments = []models.EmailAttachment{}
+	}
+	// A redelivered message is accepted again without being stored twice
+	if _, err := s.repo.CreateEmail(ctx, email); err != nil {
+		return fmt.Errorf("failed to store email: %w", err)
+	}
+	return nil
+}
+
+func (s *emailOrderService) ListEmails(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InboundEmail, error) {
+	return s.repo.ListEmails(ctx, tenantID, status, limit, offset)
+}
+
+func (s *emailOrderService) ListDrafts(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.EmailDraftOrder, error) {
+	return s.repo.ListDrafts(ctx, tenantID, status, limit, offset)
+}
+
+func (s *emailOrderService) GetDraft(ctx context.Context, tenantID, id uuid.UUID) (*models.EmailDraftOrder, error) {
+	draft, err := s.repo.GetDraft(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load draft order: %w", err)
+	}
+	if draft == nil {
+		return nil, ErrEmailOrderNotFound
+	}
+	return draft, nil
+}
+
+func (s *emailOrderService) UpdateDraft(ctx context.Context, tenantID, id uuid.UUID, req *models.EmailDraftOrderUpdate) (*models.EmailDraftOrder, error) {
+	draft, err := s.GetDraft(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.WarehouseID != nil {
+		if _, err := s.warehouseRepo.GetByID(ctx, tenantID, *req.WarehouseID); err != nil {
+			return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidEmailOrder)
+		}
+		draft.WarehouseID = req.WarehouseID
+	}
+	if req.Lines != nil {
+		if len(req.Lines) == 0 {
+			return nil, fmt.Errorf("%w: a draft needs at least one line", ErrInvalidEmailOrder)
+		}
+		lines := make([]models.EmailDraftOrderLine, len(req.Lines))
+		for i, line := range req.Lines {
+			if line.ProductID == nil {
+				return nil, fmt.Errorf("%w: line %d needs a product_id", ErrInvalidEmailOrder, i+1)
+			}
+			if line.Quantity <= 0 {
+				return nil, fmt.Errorf("%w: line %d quantity must be positive", ErrInvalidEmailOrder, i+1)
+			}
+			if line.UnitPrice != nil && *line.UnitPrice < 0 {
+				return nil, fmt.Errorf("%w: line %d unit_price must not be negative", ErrInvalidEmailOrder, i+1)
+			}
+			product, err := s.productRepo.GetByID(ctx, tenantID, *line.ProductID)
+			if err != nil || product == nil {
+				return nil, fmt.Errorf("%w: line %d product not found", ErrInvalidEmailOrder, i+1)
+			}
+			lines[i] = models.EmailDraftOrderLine{
+				SKU:         line.SKU,
+				ProductID:   &product.ID,
+				ProductName: product.Name,
+				Quantity:    line.Quantity,
+				UnitPrice:   line.UnitPrice,
+			}
+		}
+		draft.Lines = lines
+	}
+
+	updated, err := s.repo.UpdateDraft(ctx, draft)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update draft order: %w", err)
+	}
+	if !updated {
+		return nil, fmt.Errorf("%w: draft has already been reviewed", ErrEmailOrderConflict)
+	}
+	return draft, nil
+}
+
+// ApproveDraft creates a sales order for each line that matched a product.
+// If any order fails, or another reviewer got there first, the orders
+// created so far are removed again
+func (s *emailOrderService) ApproveDraft(ctx context.Context, tenantID, id, userID uuid.UUID, note *string) (*models.EmailDraftOrder, error) {
+	draft, err := s.GetDraft(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status != models.EmailDraftPendingReview {
+		return nil, fmt.Errorf("%w: draft has already been reviewed", ErrEmailOrderConflict)
+	}
+	if draft.WarehouseID == nil {
+		return nil, fmt.Errorf("%w: set a warehouse before approving", ErrInvalidEmailOrder)
+	}
+
+	var orderIDs []uuid.UUID
+	rollback := func() {
+		for _, orderID := range orderIDs {
+			if err := s.orderService.DeleteOrder(ctx, tenantID, orderID); err != nil {
+				fmt.Printf("Failed to roll back email order %s: %v\n", orderID, err)
+			}
+		}
+	}
+
+	notes := fmt.Sprintf("Email order from %s: %s", draft.FromAddress, draft.Subject)
+	for i, line := range draft.Lines {
+		if line.ProductID == nil {
+			continue
+		}
+		product, err := s.productRepo.GetByID(ctx, tenantID, *line.ProductID)
+		if err != nil || product == nil {
+			rollback()
+			return nil, fmt.Errorf("%w: line %d product not found", ErrInvalidEmailOrder, i+1)
+		}
+		unitPrice := product.UnitPrice
+		if line.UnitPrice != nil {
+			unitPrice = *line.UnitPrice
+		}
+		distributorID := draft.DistributorID
+		order := &models.Order{
+			TenantID:      tenantID,
+			OrderType:     "sales",
+			DistributorID: &distributorID,
+			ProductID:     product.ID,
+			WarehouseID:   *draft.WarehouseID,
+			Quantity:      line.Quantity,
+			UnitPrice:     unitPrice,
+			Notes:         &notes,
+		}
+		if err := s.orderService.CreateOrder(ctx, tenantID, order); err != nil {
+			rollback()
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidEmailOrder, i+1, err)
+		}
+		orderIDs = append(orderIDs, order.ID)
+	}
+	if len(orderIDs) == 0 {
+		return nil, fmt.Errorf("%w: no line matches a product, correct the lines first", ErrInvalidEmailOrder)
+	}
+
+	draft.Status = models.EmailDraftApproved
+	draft.OrderIDs = orderIDs
+	draft.ReviewNote = trimmedOrNil(note)
+	draft.ReviewedBy = &userID
+	reviewed, err := s.repo.ReviewDraft(ctx, draft)
+	if err != nil || !reviewed {
+		rollback()
+		if err != nil {
+			return nil, fmt.Errorf("failed to approve draft order: %w", err)
+		}
+		return nil, fmt.Errorf("%w: draft has already been reviewed", ErrEmailOrderConflict)
+	}
+	return draft, nil
+}
+
+func (s *emailOrderService) RejectDraft(ctx context.Context, tenantID, id, userID uuid.UUID, note *string) (*models.EmailDraftOrder, error) {
+	draft, err := s.GetDraft(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	draft.Status = models.EmailDraftRejected
+	draft.OrderIDs = []uuid.UUID{}
+	draft.ReviewNote = trimmedOrNil(note)
+	draft.ReviewedBy = &userID
+	reviewed, err := s.repo.ReviewDraft(ctx, draft)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject draft order: %w", err)
+	}
+	if !reviewed {
+		return nil, fmt.Errorf("%w: draft has already been reviewed", ErrEmailOrderConflict)
+	}
+	return draft, nil
+}
@@ -0,0 +1,536 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	expenseAttachmentBucket = "expense-attachments"
+	expenseAttachmentURLTTL = time.Hour
+	// DefaultExpenseOverruns is how many over-budget lines the dashboard lists
+	DefaultExpenseOverruns = 5
+)
+
+var (
+	// ErrExpenseNotFound is returned for expenses outside the tenant
+	ErrExpenseNotFound = errors.New("expense not found")
+	// ErrExpenseCategoryNotFound is returned for categories outside the tenant
+	ErrExpenseCategoryNotFound = errors.New("expense category not found")
+	// ErrExpenseAttachmentNotFound is returned for attachments of another expense
+	ErrExpenseAttachmentNotFound = errors.New("expense attachment not found")
+	// ErrExpenseBudgetNotFound is returned for budgets outside the tenant
+	ErrExpenseBudgetNotFound = errors.New("expense budget not found")
+	// ErrInvalidExpense wraps expense, category and budget validation failures
+	ErrInvalidExpense = errors.New("invalid expense")
+	// ErrExpenseCategoryConflict is returned for a second category of the same name
+	ErrExpenseCategoryConflict = errors.New("expense category already exists")
+)
+
+// ExpenseService records warehouse running costs against monthly budgets and
+// compares the two
+type ExpenseService interface {
+	ListCategories(ctx context.Context, tenantID uuid.UUID) ([]*models.ExpenseCategory, error)
+	CreateCategory(ctx context.Context, tenantID uuid.UUID, req *models.ExpenseCategoryRequest) (*models.ExpenseCategory, error)
+	UpdateCategory(ctx context.Context, tenantID, categoryID uuid.UUID, req *models.ExpenseCategoryRequest) (*models.ExpenseCategory, error)
+
+	ListExpenses(ctx context.Context, tenantID uuid.UUID, filter *models.ExpenseFilter) ([]*models.Expense, error)
+	GetExpense(ctx context.Context, tenantID, expenseID uuid.UUID) (*models.Expense, error)
+	CreateExpense(ctx context.Context, tenantID uuid.UUID, createdBy *uuid.UUID, req *models.ExpenseRequest) (*models.Expense, error)
+	UpdateExpense(ctx context.Context, tenantID, expenseID uuid.UUID, req *models.ExpenseRequest) (*models.Expense, error)
+	DeleteExpense(ctx context.Context, tenantID, expenseID uuid.UUID) error
+
+	AddAttachment(ctx context.Context, tenantID, expenseID uuid.UUID, filename, contentType string, reader io.Reader, size int64) (*models.ExpenseAttachment, error)
+	DeleteAttachment(ctx context.Context, tenantID, expenseID, attachmentID uuid.UUID) error
+
+	// ListBudgets returns the month's budgets; an empty month is the current one
+	ListBudgets(ctx context.Context, tenantID uuid.UUID, month string, warehouseID *uuid.UUID) ([]*models.ExpenseBudget, error)
+	SetBudget(ctx context.Context, tenantID uuid.UUID, req *models.ExpenseBudgetRequest) (*models.ExpenseBudget, error)
+	DeleteBudget(ctx context.Context, tenantID, budgetID uuid.UUID) error
+
+	// BudgetVsActual compares budgets with expenses over the months from
+	// through to (YYYY-MM); both default to the current month
+	BudgetVsActual(ctx context.Context, tenantID uuid.UUID, from, to string, warehouseID *uuid.UUID) (*models.BudgetVsActualReport, error)
+	// Dashboard summarizes the month's budget use with the top overruns
+	Dashboard(ctx context.Context, tenantID uuid.UUID, month string, top int) (*models.ExpenseDashboard, error)
+}
+
+type expenseService struct {
+	repo          repositories.ExpenseRepository
+	warehouseRepo repositories.WarehouseRepository
+	minioService  MinioService
+}
+
+// NewExpenseService creates a new expense service
+func NewExpenseService(repo repositories.ExpenseRepository, warehouseRepo repositories.WarehouseRepository, minioService MinioService) ExpenseService {
+	return &expenseService{
+		repo:          repo,
+		warehouseRepo: warehouseRepo,
+		minioService:  minioService,
+	}
+}
+
+// parseExpenseMonth reads a YYYY-MM month; empty is the current month
+func parseExpenseMonth(month string) (time.Time, error) {
+	if month == "" {
+		return targetMonth(time.Now()), nil
+	}
+	parsed, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: month must be in YYYY-MM format", ErrInvalidExpense)
+	}
+	return parsed, nil
+}
+
+func (s *expenseService) ListCategories(ctx context.Context, tenantID uuid.UUID) ([]*models.ExpenseCategory, error) {
+	return s.repo.ListCategories(ctx, tenantID)
+}
+
+// applyExpenseCategoryRequest sets the fields the request carries
+func applyExpenseCategoryRequest(category *models.ExpenseCategory, req *models.ExpenseCategoryRequest) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 100 {
+			return fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidExpense)
+		}
+		category.Name = name
+	}
+	if req.Description != nil {
+		category.Description = trimmedOrNil(req.Description)
+	}
+	if req.Active != nil {
+		category.Active = *req.Active
+	}
+	return nil
+}
+
+func (s *expenseService) CreateCategory(ctx context.Context, tenantID uuid.UUID, req *models.ExpenseCategoryRequest) (*models.ExpenseCategory, error) {
+	if req.Name == nil {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidExpense)
+	}
+	category := &models.ExpenseCategory{ID: uuid.New(), TenantID: tenantID, Active: true}
+	if err := applyExpenseCategoryRequest(category, req); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.CreateCategory(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create expense category: %w", err)
+	}
+	if !ok {
+		return nil, ErrExpenseCategoryConflict
+	}
+	return category, nil
+}
+
+func (s *expenseService) UpdateCategory(ctx context.Context, tenantID, categoryID uuid.UUID, req *models.ExpenseCategoryRequest) (*models.ExpenseCategory, error) {
+	category, err := s.repo.GetCategory(ctx, tenantID, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expense category: %w", err)
+	}
+	if category == nil {
+		return nil, ErrExpenseCategoryNotFound
+	}
+	if err := applyExpenseCategoryRequest(category, req); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.UpdateCategory(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update expense category: %w", err)
+	}
+	if !ok {
+		return nil, ErrExpenseCategoryConflict
+	}
+	return category, nil
+}
+
+func (s *expenseService) ListExpenses(ctx context.Context, tenantID uuid.UUID, filter *models.ExpenseFilter) ([]*models.Expense, error) {
+	return s.repo.List(ctx, tenantID, filter)
+}
+
+func (s *expenseService) signAttachment(attachment *models.ExpenseAttachment) {
+	url, err := s.minioService.GetPresignedURL(expenseAttachmentBucket, attachment.ObjectKey, expenseAttachmentURLTTL)
+	if err != nil {
+		log.Printf("Failed to sign expense attachment %s: %v", attachment.ID, err)
+		return
+	}
+	attachment.URL = url
+}
+
+func (s *expenseService) GetExpense(ctx context.Context, tenantID, expenseID uuid.UUID) (*models.Expense, error) {
+	expense, err := s.repo.Get(ctx, tenantID, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expense: %w", err)
+	}
+	if expense == nil {
+		return nil, ErrExpenseNotFound
+	}
+
+	attachments, err := s.repo.ListAttachments(ctx, tenantID, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expense attachments: %w", err)
+	}
+	for _, attachment := range attachments {
+		s.signAttachment(attachment)
+	}
+	expense.Attachments = attachments
+	return expense, nil
+}
+
+// applyExpenseRequest validates and sets the fields the request carries
+func (s *expenseService) applyExpenseRequest(ctx context.Context, tenantID uuid.UUID, expense *models.Expense, req *models.ExpenseRequest) error {
+	if req.WarehouseID != nil {
+		warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, *req.WarehouseID)
+		if err != nil || warehouse == nil {
+			return fmt.Errorf("%w: warehouse not found", ErrInvalidExpense)
+		}
+		expense.WarehouseID = *req.WarehouseID
+	}
+	if req.CategoryID != nil {
+		category, err := s.repo.GetCategory(ctx, tenantID, *req.CategoryID)
+		if err != nil || category == nil {
+			return fmt.Errorf("%w: expense category not found", ErrInvalidExpense)
+		}
+		if !category.Active && category.ID != expense.CategoryID {
+			return fmt.Errorf("%w: expense category %s is inactive", ErrInvalidExpense, category.Name)
+		}
+		expense.CategoryID = *req.CategoryID
+	}
+	if req.ExpenseDate != "" {
+		date, err := time.Parse("2006-01-02", req.ExpenseDate)
+		if err != nil {
+			return fmt.Errorf("%w: expense_date must be in YYYY-MM-DD format", ErrInvalidExpense)
+		}
+		if date.After(time.Now()) {
+			return fmt.Errorf("%w: expense_date cannot be in the future", ErrInvalidExpense)
+		}
+		expense.ExpenseDate = date
+		expense.Month = targetMonth(date)
+	}
+	if req.Amount != nil {
+		if *req.Amount <= 0 {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidExpense)
+		}
+		expense.Amount = math.Round(*req.Amount*100) / 100
+	}
+	if req.Description != nil {
+		expense.Description = trimmedOrNil(req.Description)
+	}
+	if req.Vendor != nil {
+		expense.Vendor = trimmedOrNil(req.Vendor)
+	}
+	if req.Reference != nil {
+		expense.Reference = trimmedOrNil(req.Reference)
+	}
+	return nil
+}
+
+func (s *expenseService) CreateExpense(ctx context.Context, tenantID uuid.UUID, createdBy *uuid.UUID, req *models.ExpenseRequest) (*models.Expense, error) {
+	if req.WarehouseID == nil || req.CategoryID == nil || req.Amount == nil || req.ExpenseDate == "" {
+		return nil, fmt.Errorf("%w: warehouse_id, category_id, expense_date and amount are required", ErrInvalidExpense)
+	}
+	expense := &models.Expense{ID: uuid.New(), TenantID: tenantID, CreatedBy: createdBy}
+	if err := s.applyExpenseRequest(ctx, tenantID, expense, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, expense); err != nil {
+		return nil, fmt.Errorf("failed to create expense: %w", err)
+	}
+	return s.GetExpense(ctx, tenantID, expense.ID)
+}
+
+func (s *expenseService) UpdateExpense(ctx context.Context, tenantID, expenseID uuid.UUID, req *models.ExpenseRequest) (*models.Expense, error) {
+	expense, err := s.repo.Get(ctx, tenantID, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expense: %w", err)
+	}
+	if expense == nil {
+		return nil, ErrExpenseNotFound
+	}
+	if err := s.applyExpenseRequest(ctx, tenantID, expense, req); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.Update(ctx, expense)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update expense: %w", err)
+	}
+	if !ok {
+		return nil, ErrExpenseNotFound
+	}
+	return s.GetExpense(ctx, tenantID, expenseID)
+}
+
+// DeleteExpense removes the expense and its attachments; stored files that
+// fail to delete are logged and left behind
+func (s *expenseService) DeleteExpense(ctx context.Context, tenantID, expenseID uuid.UUID) error {
+	attachments, err := s.repo.ListAttachments(ctx, tenantID, expenseID)
+	if err != nil {
+		return fmt.Errorf("failed to load expense attachments: %w", err)
+	}
+
+	ok, err := s.repo.Delete(ctx, tenantID, expenseID)
+	if err != nil {
+		return fmt.Errorf("failed to delete expense: %w", err)
+	}
+	if !ok {
+		return ErrExpenseNotFound
+	}
+	for _, attachment := range attachments {
+		if err := s.minioService.DeleteImage(ctx, expenseAttachmentBucket, attachment.ObjectKey); err != nil {
+			log.Printf("Failed to remove expense attachment %s: %v", attachment.ObjectKey, err)
+		}
+	}
+	return nil
+}
+
+func (s *expenseService) AddAttachment(ctx context.Context, tenantID, expenseID uuid.UUID, filename, contentType string, reader io.Reader, size int64) (*models.ExpenseAttachment, error) {
+	expense, err := s.repo.Get(ctx, tenantID, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expense: %w", err)
+	}
+	if expense == nil {
+		return nil, ErrExpenseNotFound
+	}
+
+	attachment := &models.ExpenseAttachment{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		ExpenseID:   expenseID,
+		FileName:    filepath.Base(filename),
+		ContentType: contentType,
+		SizeBytes:   size,
+	}
+	attachment.ObjectKey = fmt.Sprintf("%s/%s/%s%s", tenantID.String(), expenseID.String(), attachment.ID.String(), strings.ToLower(filepath.Ext(filename)))
+
+	if err := s.minioService.EnsureBucketExists(ctx, expenseAttachmentBucket); err != nil {
+		return nil, fmt.Errorf("failed to prepare attachment storage: %w", err)
+	}
+	if err := s.minioService.UploadObject(ctx, expenseAttachmentBucket, attachment.ObjectKey, reader, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to upload attachment to storage: %w", err)
+	}
+	if err := s.repo.AddAttachment(ctx, attachment); err != nil {
+		if delErr := s.minioService.DeleteImage(ctx, expenseAttachmentBucket, attachment.ObjectKey); delErr != nil {
+			log.Printf("Failed to remove orphaned expense attachment %s: %v", attachment.ObjectKey, delErr)
+		}
+		return nil, fmt.Errorf("failed to save attachment: %w", err)
+	}
+	s.signAttachment(attachment)
+	return attachment, nil
+}
+
+func (s *expenseService) DeleteAttachment(ctx context.Context, tenantID, expenseID, attachmentID uuid.UUID) error {
+	attachment, err := s.repo.GetAttachment(ctx, tenantID, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to load expense attachment: %w", err)
+	}
+	if attachment == nil || attachment.ExpenseID != expenseID {
+		return ErrExpenseAttachmentNotFound
+	}
+
+	ok, err := s.repo.DeleteAttachment(ctx, tenantID, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete expense attachment: %w", err)
+	}
+	if !ok {
+		return ErrExpenseAttachmentNotFound
+	}
+	if err := s.minioService.DeleteImage(ctx, expenseAttachmentBucket, attachment.ObjectKey); err != nil {
+		log.Printf("Failed to remove expense attachment %s: %v", attachment.ObjectKey, err)
+	}
+	return nil
+}
+
+func (s *expenseService) ListBudgets(ctx context.Context, tenantID uuid.UUID, month string, warehouseID *uuid.UUID) ([]*models.ExpenseBudget, error) {
+	period, err := parseExpenseMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListBudgets(ctx, tenantID, period, warehouseID)
+}
+
+func (s *expenseService) SetBudget(ctx context.Context, tenantID uuid.UUID, req *models.ExpenseBudgetRequest) (*models.ExpenseBudget, error) {
+	if req.Month == "" {
+		return nil, fmt.Errorf("%w: month is required", ErrInvalidExpense)
+	}
+	month, err := parseExpenseMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+	if req.Amount == nil || *req.Amount < 0 {
+		return nil, fmt.Errorf("%w: amount must not be negative", ErrInvalidExpense)
+	}
+	warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, req.WarehouseID)
+	if err != nil || warehouse == nil {
+		return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidExpense)
+	}
+	category, err := s.repo.GetCategory(ctx, tenantID, req.CategoryID)
+	if err != nil || category == nil {
+		return nil, fmt.Errorf("%w: expense category not found", ErrInvalidExpense)
+	}
+
+	budget := &models.ExpenseBudget{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		WarehouseID:   req.WarehouseID,
+		WarehouseName: warehouse.Name,
+		CategoryID:    req.CategoryID,
+		CategoryName:  category.Name,
+		Month:         month,
+		Amount:        math.Round(*req.Amount*100) / 100,
+		Notes:         trimmedOrNil(req.Notes),
+	}
+	if err := s.repo.UpsertBudget(ctx, budget); err != nil {
+		return nil, fmt.Errorf("failed to set expense budget: %w", err)
+	}
+	return budget, nil
+}
+
+func (s *expenseService) DeleteBudget(ctx context.Context, tenantID, budgetID uuid.UUID) error {
+	ok, err := s.repo.DeleteBudget(ctx, tenantID, budgetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete expense budget: %w", err)
+	}
+	if !ok {
+		return ErrExpenseBudgetNotFound
+	}
+	return nil
+}
+
+// compareBudget fills in variance, utilization and whether the row is over
+// budget from its Budget and Actual. Spending without a budget is over budget
+func compareBudget(row *models.BudgetVsActualRow) {
+	row.Budget = math.Round(row.Budget*100) / 100
+	row.Actual = math.Round(row.Actual*100) / 100
+	row.Variance = math.Round((row.Budget-row.Actual)*100) / 100
+	row.UtilizationPercent = 0
+	if row.Budget > 0 {
+		row.UtilizationPercent = math.Round(row.Actual/row.Budget*10000) / 100
+	}
+	row.OverBudget = row.Actual > row.Budget
+}
+
+// buildBudgetVsActual compares each warehouse and category, rolls them up per
+// warehouse and totals the report
+func buildBudgetVsActual(from, to time.Time, rows []*models.BudgetVsActualRow) *models.BudgetVsActualReport {
+	report := &models.BudgetVsActualReport{
+		From:       from,
+		To:         to,
+		Rows:       []*models.BudgetVsActualRow{},
+		Warehouses: []*models.BudgetVsActualRow{},
+	}
+	report.Totals.WarehouseName = "Total"
+
+	warehouses := make(map[uuid.UUID]*models.BudgetVsActualRow)
+	for _, row := range rows {
+		compareBudget(row)
+		report.Rows = append(report.Rows, row)
+		if row.OverBudget {
+			report.OverBudgetCount++
+		}
+
+		warehouse, ok := warehouses[row.WarehouseID]
+		if !ok {
+			warehouse = &models.BudgetVsActualRow{WarehouseID: row.WarehouseID, WarehouseName: row.WarehouseName}
+			warehouses[row.WarehouseID] = warehouse
+			report.Warehouses = append(report.Warehouses, warehouse)
+		}
+		warehouse.Budget += row.Budget
+		warehouse.Actual += row.Actual
+		report.Totals.Budget += row.Budget
+		report.Totals.Actual += row.Actual
+	}
+	for _, warehouse := range report.Warehouses {
+		compareBudget(warehouse)
+	}
+	compareBudget(&report.Totals)
+	return report
+}
+
+func (s *expenseService) BudgetVsActual(ctx context.Context, tenantID uuid.UUID, from, to string, warehouseID *uuid.UUID) (*models.BudgetVsActualReport, error) {
+	fromMonth, err := parseExpenseMonth(from)
+	if err != nil {
+		return nil, err
+	}
+	toMonth := fromMonth
+	if to != "" {
+		if toMonth, err = parseExpenseMonth(to); err != nil {
+			return nil, err
+		}
+	}
+	if toMonth.Before(fromMonth) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidExpense)
+	}
+
+	rows, err := s.repo.BudgetVsActual(ctx, tenantID, fromMonth, toMonth, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budget vs actual: %w", err)
+	}
+	return buildBudgetVsActual(fromMonth, toMonth, rows), nil
+}
+
+func (s *expenseService) Dashboard(ctx context.Context, tenantID uuid.UUID, month string, top int) (*models.ExpenseDashboard, error) {
+	if top <= 0 {
+		top = DefaultExpenseOverruns
+	}
+	report, err := s.BudgetVsActual(ctx, tenantID, month, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &models.ExpenseDashboard{
+		Month:              report.From,
+		Budget:             report.Totals.Budget,
+		Actual:             report.Totals.Actual,
+		UtilizationPercent: report.Totals.UtilizationPercent,
+		Warehouses:         report.Warehouses,
+		TopOverruns:        []*models.BudgetVsActualRow{},
+	}
+	for _, row := range report.Rows {
+		if row.OverBudget {
+			dashboard.TopOverruns = append(dashboard.TopOverruns, row)
+		}
+	}
+	sort.SliceStable(dashboard.TopOverruns, func(i, j int) bool {
+		return dashboard.TopOverruns[i].Variance < dashboard.TopOverruns[j].Variance
+	})
+	if len(dashboard.TopOverruns) > top {
+		dashboard.TopOverruns = dashboard.TopOverruns[:top]
+	}
+	return dashboard, nil
+}
+
+// BudgetVsActualCSV renders the report one warehouse and category per row
+// with the totals on the last
+func BudgetVsActualCSV(report *models.BudgetVsActualReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"From", "To", "Warehouse", "Category", "Budget", "Actual", "Variance", "Utilization %", "Over Budget"})
+	rows := append(append([]*models.BudgetVsActualRow{}, report.Rows...), &report.Totals)
+	for _, row := range rows {
+		w.Write([]string{report.From.Format("2006-01"), report.To.Format("2006-01"), row.WarehouseName, row.CategoryName,
+			strconv.FormatFloat(row.Budget, 'f', 2, 64), strconv.FormatFloat(row.Actual, 'f', 2, 64),
+			strconv.FormatFloat(row.Variance, 'f', 2, 64), strconv.FormatFloat(row.UtilizationPercent, 'f', 2, 64),
+			strconv.FormatBool(row.OverBudget)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrExportInvoiceNotFound is returned for invoices that are not export
+	// invoices of the tenant
+	ErrExportInvoiceNotFound = errors.New("export invoice not found")
+	// ErrInvalidExportInvoice wraps export invoice validation failures
+	ErrInvalidExportInvoice = errors.New("invalid export invoice")
+	// ErrExportInvoiceConflict is returned when the order is already invoiced
+	ErrExportInvoiceConflict = errors.New("an invoice already exists for this order")
+)
+
+// exportGSTRate is the IGST rate charged on exports on payment of IGST,
+// matching the rate domestic invoices are generated with
+const exportGSTRate = 18.0
+
+var (
+	currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	portCodePattern     = regexp.MustCompile(`^[A-Z0-9]{6}$`)
+	shippingBillPattern = regexp.MustCompile(`^[0-9]{1,20}$`)
+)
+
+// ExportInvoiceService raises export invoices: zero-rated under a letter of
+// undertaking or on payment of IGST, billed in a foreign currency, and
+// reported in GSTR-1 table 6A with their shipping bills
+type ExportInvoiceService interface {
+	CreateExportInvoice(ctx context.Context, tenantID uuid.UUID, req *models.ExportInvoiceRequest) (*models.ExportInvoice, error)
+	GetExportInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.ExportInvoice, error)
+	// GetExportDetails returns nil for domestic invoices
+	GetExportDetails(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceExportDetails, error)
+	UpdateShipping(ctx context.Context, tenantID, invoiceID uuid.UUID, req *models.ExportShippingRequest) (*models.ExportInvoice, error)
+	// GSTR1Exports is GSTR-1 table 6A for the month containing period
+	GSTR1Exports(ctx context.Context, tenantID uuid.UUID, period time.Time) (*models.GSTR1ExportSection, error)
+}
+
+type exportInvoiceService struct {
+	repo        repositories.ExportInvoiceRepository
+	invoiceRepo repositories.InvoiceRepository
+	orderRepo   repositories.OrderRepository
+}
+
+// NewExportInvoiceService creates a new export invoice service
+func NewExportInvoiceService(repo repositories.ExportInvoiceRepository, invoiceRepo repositories.InvoiceRepository, orderRepo repositories.OrderRepository) ExportInvoiceService {
+	return &exportInvoiceService{
+		repo:        repo,
+		invoiceRepo: invoiceRepo,
+		orderRepo:   orderRepo,
+	}
+}
+
+// CreateExportInvoice invoices a delivered order for export. Amounts on the
+// invoice are in INR; the export details carry the total in the billing
+// currency. Exports are not subject to TCS
+func (s *exportInvoiceService) CreateExportInvoice(ctx context.Context, tenantID uuid.UUID, req *models.ExportInvoiceRequest) (*models.ExportInvoice, error) {
+	details, err := exportDetailsFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetByID(ctx, tenantID, req.OrderID)
+	if err != nil || order == nil {
+		return nil, fmt.Errorf("%w: order not found", ErrInvalidExportInvoice)
+	}
+	if order.Status != "delivered" {
+		return nil, fmt.Errorf("%w: order must be delivered to invoice it, current status: %s", ErrInvalidExportInvoice, order.Status)
+	}
+	if order.Quantity <= 0 || order.UnitPrice <= 0 {
+		return nil, fmt.Errorf("%w: invalid order quantity or unit price", ErrInvalidExportInvoice)
+	}
+	existing, err := s.invoiceRepo.GetInvoicesByOrderID(ctx, tenantID, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing invoices: %w", err)
+	}
+	if len(existing) > 0 {
+		return nil, ErrExportInvoiceConflict
+	}
+
+	taxable, igst, total, foreign := exportAmounts(details, order.Quantity, order.UnitPrice, req.ForeignUnitPrice)
+	details.ForeignAmount = foreign
+	gstRate := 0.0
+	if details.ExportType == models.ExportTypeWithPayment {
+		gstRate = exportGSTRate
+	}
+	zero := 0.0
+
+	issuedDate := time.Now()
+	number, err := s.invoiceRepo.GenerateInvoiceNumber(ctx, tenantID, order.BranchID, issuedDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invoice number: %w", err)
+	}
+	invoice := &models.Invoice{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		OrderID:       order.ID,
+		BranchID:      order.BranchID,
+		InvoiceNumber: number,
+		TaxableAmount: &taxable,
+		GSTRate:       &gstRate,
+		CGST:          &zero,
+		SGST:          &zero,
+		IGST:          &igst,
+		TotalAmount:   total,
+		Status:        "unpaid",
+		IssuedDate:    issuedDate,
+		DueDate:       issuedDate.AddDate(0, 0, 30),
+		CreatedAt:     issuedDate,
+		UpdatedAt:     issuedDate,
+	}
+	details.InvoiceID = invoice.ID
+	details.TenantID = tenantID
+
+	if err := s.repo.CreateExportInvoice(ctx, invoice, details); err != nil {
+		return nil, fmt.Errorf("failed to create export invoice: %w", err)
+	}
+	return &models.ExportInvoice{Invoice: invoice, Export: details}, nil
+}
+
+func (s *exportInvoiceService) GetExportInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.ExportInvoice, error) {
+	details, err := s.repo.GetExportDetails(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, ErrExportInvoiceNotFound
+	}
+	invoice, err := s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+	return &models.ExportInvoice{Invoice: invoice, Export: details}, nil
+}
+
+func (s *exportInvoiceService) GetExportDetails(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceExportDetails, error) {
+	return s.repo.GetExportDetails(ctx, tenantID, invoiceID)
+}
+
+// UpdateShipping records the port and shipping bill of an export invoice;
+// omitted fields are left as they are
+func (s *exportInvoiceService) UpdateShipping(ctx context.Context, tenantID, invoiceID uuid.UUID, req *models.ExportShippingRequest) (*models.ExportInvoice, error) {
+	export, err := s.GetExportInvoice(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	details := export.Export
+	if req.PortCode != nil {
+		details.PortCode = trimmedOrNil(req.PortCode)
+	}
+	if req.ShippingBillNumber != nil {
+		details.ShippingBillNumber = trimmedOrNil(req.ShippingBillNumber)
+	}
+	if req.ShippingBillDate != nil {
+		date, err := parseShippingBillDate(*req.ShippingBillDate)
+		if err != nil {
+			return nil, err
+		}
+		details.ShippingBillDate = date
+	}
+	if err := validateShipping(details, export.IssuedDate); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.UpdateShipping(ctx, details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shipping details: %w", err)
+	}
+	if !ok {
+		return nil, ErrExportInvoiceNotFound
+	}
+	return export, nil
+}
+
+func (s *exportInvoiceService) GSTR1Exports(ctx context.Context, tenantID uuid.UUID, period time.Time) (*models.GSTR1ExportSection, error) {
+	from := time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	rows, err := s.repo.GSTR1Exports(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export invoices: %w", err)
+	}
+
+	section := &models.GSTR1ExportSection{
+		TenantID:     tenantID,
+		ReturnPeriod: from.Format("012006"),
+		Invoices:     rows,
+	}
+	if section.Invoices == nil {
+		section.Invoices = []*models.GSTR1ExportRow{}
+	}
+	for _, row := range rows {
+		section.TotalInvoiceValue += row.InvoiceValue
+		section.TotalTaxableValue += row.TaxableValue
+		section.TotalIGST += row.IGST
+		if row.ShippingBillNumber == nil || row.PortCode == nil || row.ShippingBillDate == nil {
+			section.MissingShippingBills++
+		}
+	}
+	section.TotalInvoiceValue = roundAllocation(section.TotalInvoiceValue)
+	section.TotalTaxableValue = roundAllocation(section.TotalTaxableValue)
+	section.TotalIGST = roundAllocation(section.TotalIGST)
+	return section, nil
+}
+
+// exportDetailsFromRequest validates a request into export details, amounts
+// aside
+func exportDetailsFromRequest(req *models.ExportInvoiceRequest) (*models.InvoiceExportDetails, error) {
+	if req.OrderID == uuid.Nil {
+		return nil, fmt.Errorf("%w: order_id is required", ErrInvalidExportInvoice)
+	}
+	exportType := strings.ToUpper(strings.TrimSpace(req.ExportType))
+	if exportType == "" {
+		exportType = models.ExportTypeWithoutPayment
+	}
+	if exportType != models.ExportTypeWithoutPayment && exportType != models.ExportTypeWithPayment {
+		return nil, fmt.Errorf("%w: export_type must be WOPAY or WPAY", ErrInvalidExportInvoice)
+	}
+	currency := strings.ToUpper(strings.TrimSpace(req.Currency))
+	if !currencyCodePattern.MatchString(currency) || currency == "INR" {
+		return nil, fmt.Errorf("%w: currency must be a foreign ISO 4217 code such as USD", ErrInvalidExportInvoice)
+	}
+	if req.ExchangeRate <= 0 || req.ExchangeRate > 10000 {
+		return nil, fmt.Errorf("%w: exchange_rate must be the INR value of one %s", ErrInvalidExportInvoice, currency)
+	}
+	if req.ForeignUnitPrice != nil && *req.ForeignUnitPrice <= 0 {
+		return nil, fmt.Errorf("%w: foreign_unit_price must be positive", ErrInvalidExportInvoice)
+	}
+
+	details := &models.InvoiceExportDetails{
+		ExportType:         exportType,
+		Currency:           currency,
+		ExchangeRate:       req.ExchangeRate,
+		PortCode:           trimmedOrNil(req.PortCode),
+		ShippingBillNumber: trimmedOrNil(req.ShippingBillNumber),
+		DestinationCountry: trimmedOrNil(req.DestinationCountry),
+	}
+	if exportType == models.ExportTypeWithoutPayment {
+		details.LUTNumber = trimmedOrNil(req.LUTNumber)
+		if details.LUTNumber == nil || len(*details.LUTNumber) > 50 {
+			return nil, fmt.Errorf("%w: lut_number is required for exports without payment of IGST", ErrInvalidExportInvoice)
+		}
+	}
+	if details.DestinationCountry != nil && len(*details.DestinationCountry) > 60 {
+		return nil, fmt.Errorf("%w: destination_country must be at most 60 characters", ErrInvalidExportInvoice)
+	}
+	if req.ShippingBillDate != nil {
+		date, err := parseShippingBillDate(*req.ShippingBillDate)
+		if err != nil {
+			return nil, err
+		}
+		details.ShippingBillDate = date
+	}
+	if err := validateShipping(details, time.Now()); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+func parseShippingBillDate(value string) (*time.Time, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("%w: shipping_bill_date must be in YYYY-MM-DD format", ErrInvalidExportInvoice)
+	}
+	return &date, nil
+}
+
+// validateShipping checks the port code and shipping bill of an export
+// invoice issued on issued, upper-casing the port code
+func validateShipping(details *models.InvoiceExportDetails, issued time.Time) error {
+	if details.PortCode != nil {
+		code := strings.ToUpper(*details.PortCode)
+		if !portCodePattern.MatchString(code) {
+			return fmt.Errorf("%w: port_code must be the 6 character port code, e.g. INNSA1", ErrInvalidExportInvoice)
+		}
+		details.PortCode = &code
+	}
+	if details.ShippingBillNumber != nil && !shippingBillPattern.MatchString(*details.ShippingBillNumber) {
+		return fmt.Errorf("%w: shipping_bill_number must be numeric", ErrInvalidExportInvoice)
+	}
+	if details.ShippingBillDate != nil {
+		issuedDay := time.Date(issued.Year(), issued.Month(), issued.Day(), 0, 0, 0, 0, time.UTC)
+		if details.ShippingBillDate.Before(issuedDay) {
+			return fmt.Errorf("%w: shipping_bill_date cannot be before the invoice date", ErrInvalidExportInvoice)
+		}
+	}
+	return nil
+}
+
+// exportAmounts works out an export invoice's INR taxable value, IGST and
+// total, and the total in the billing currency. With a foreign unit price the
+// goods are billed in that currency and converted; otherwise the order's INR
+// price is
+func exportAmounts(details *models.InvoiceExportDetails, quantity int, unitPrice float64, foreignUnitPrice *float64) (taxable, igst, total, foreign float64) {
+	if foreignUnitPrice != nil {
+		taxable = roundAllocation(roundAllocation(float64(quantity)**foreignUnitPrice) * details.ExchangeRate)
+	} else {
+		taxable = roundAllocation(float64(quantity) * unitPrice)
+	}
+	if details.ExportType == models.ExportTypeWithPayment {
+		igst = roundAllocation(taxable * exportGSTRate / 100)
+	}
+	total = roundAllocation(taxable + igst)
+	return taxable, igst, total, roundAllocation(total / details.ExchangeRate)
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrFeatureFlagNotFound is returned for unknown flag keys
+	ErrFeatureFlagNotFound = errors.New("feature flag not found")
+	// ErrInvalidFeatureFlag wraps feature flag validation failures
+	ErrInvalidFeatureFlag = errors.New("invalid feature flag")
+	// ErrFeatureFlagTenantNotFound is returned when overriding a flag for an
+	// unknown tenant
+	ErrFeatureFlagTenantNotFound = errors.New("tenant not found")
+)
+
+// featureFlagCacheTTL bounds how long an instance may evaluate a flag changed
+// elsewhere without seeing the invalidation
+const featureFlagCacheTTL = time.Minute
+
+// featureFlagsCacheKey holds every flag with its overrides; flags are few and
+// evaluated on hot paths, so they are cached together
+const featureFlagsCacheKey = "feature_flags:all"
+
+var featureFlagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// FeatureFlagService evaluates feature flags for tenants and lets platform
+// admins toggle them without a deployment. A tenant override wins; otherwise
+// an enabled flag is on for the tenants within its rollout percentage
+type FeatureFlagService interface {
+	// IsEnabled reports whether the flag is on for the tenant; unknown flags are off
+	IsEnabled(ctx context.Context, tenantID uuid.UUID, key string) (bool, error)
+	// EnabledFeatures lists the keys of the flags on for the tenant
+	EnabledFeatures(ctx context.Context, tenantID uuid.UUID) ([]string, error)
+
+	ListFlags(ctx context.Context) ([]*models.FeatureFlag, error)
+	GetFlag(ctx context.Context, key string) (*models.FeatureFlag, error)
+	SaveFlag(ctx context.Context, key string, req *models.FeatureFlagRequest) (*models.FeatureFlag, error)
+	DeleteFlag(ctx context.Context, key string) error
+	SetOverride(ctx context.Context, key string, tenantID uuid.UUID, enabled bool) (*models.FeatureFlagOverride, error)
+	DeleteOverride(ctx context.Context, key string, tenantID uuid.UUID) error
+}
+
+type featureFlagService struct {
+	repo     repositories.FeatureFlagRepository
+	cacheSvc caching.CacheService
+}
+
+// NewFeatureFlagService creates a new feature flag service
+func NewFeatureFlagService(repo repositories.FeatureFlagRepository, cacheSvc caching.CacheService) FeatureFlagService {
+	return &featureFlagService{
+		repo:     repo,
+		cacheSvc: cacheSvc,
+	}
+}
+
+// featureRolloutBucket places a tenant in one of 100 buckets per flag, so a
+// rollout percentage selects a stable set of tenants that only grows as the
+// percentage is raised
+func featureRolloutBucket(key string, tenantID uuid.UUID) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{':'})
+	h.Write(tenantID[:])
+	return int(h.Sum32() % 100)
+}
+
+// evaluateFeatureFlag reports whether the flag is on for the tenant
+func evaluateFeatureFlag(flag *models.FeatureFlag, tenantID uuid.UUID) bool {
+	for _, o := range flag.Overrides {
+		if o.TenantID == tenantID {
+			return o.Enabled
+		}
+	}
+	if !flag.Enabled {
+		return false
+	}
+	return featureRolloutBucket(flag.Key, tenantID) < flag.RolloutPercentage
+}
+
+// flags returns every flag, from the cache when possible
+func (s *featureFlagService) flags(ctx context.Context) ([]*models.FeatureFlag, error) {
+	if cached, err := s.cacheSvc.GetString(ctx, featureFlagsCacheKey); err == nil && cached != "" {
+		var flags []*models.FeatureFlag
+		if err := json.Unmarshal([]byte(cached), &flags); err == nil {
+			return flags, nil
+		}
+	}
+
+	flags, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feature flags: %w", err)
+	}
+	if payload, err := json.Marshal(flags); err == nil {
+		if cacheErr := s.cacheSvc.SetString(ctx, featureFlagsCacheKey, string(payload), featureFlagCacheTTL); cacheErr != nil {
+			fmt.Printf("Failed to cache feature flags: %v\n", cacheErr)
+		}
+	}
+	return flags, nil
+}
+
+// invalidate drops the cached flags after a change so every instance reloads them
+func (s *featureFlagService) invalidate(ctx context.Context) {
+	if err := s.cacheSvc.Delete(ctx, featureFlagsCacheKey); err != nil {
+		fmt.Printf("Failed to invalidate cached feature flags: %v\n", err)
+	}
+}
+
+func (s *featureFlagService) IsEnabled(ctx context.Context, tenantID uuid.UUID, key string) (bool, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, flag := range flags {
+		if flag.Key == key {
+			return evaluateFeatureFlag(flag, tenantID), nil
+		}
+	}
+	return false, nil
+}
+
+func (s *featureFlagService) EnabledFeatures(ctx context.Context, tenantID uuid.UUID) ([]string, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enabled := []string{}
+	for _, flag := range flags {
+		if evaluateFeatureFlag(flag, tenantID) {
+			enabled = append(enabled, flag.Key)
+		}
+	}
+	return enabled, nil
+}
+
+func (s *featureFlagService) ListFlags(ctx context.Context) ([]*models.FeatureFlag, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *featureFlagService) GetFlag(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	flag, err := s.repo.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feature flag: %w", err)
+	}
+	if flag == nil {
+		return nil, ErrFeatureFlagNotFound
+	}
+	return flag, nil
+}
+
+func (s *featureFlagService) SaveFlag(ctx context.Context, key string, req *models.FeatureFlagRequest) (*models.FeatureFlag, error) {
+	if !featureFlagKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("%w: key must be lowercase letters, digits and underscores, starting with a letter", ErrInvalidFeatureFlag)
+	}
+
+	existing, err := s.repo.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feature flag: %w", err)
+	}
+
+	flag := &models.FeatureFlag{
+		Key:               key,
+		Description:       trimmedOrNil(req.Description),
+		Enabled:           req.Enabled,
+		RolloutPercentage: 100,
+		Overrides:         []*models.FeatureFlagOverride{},
+	}
+	if existing != nil {
+		flag.RolloutPercentage = existing.RolloutPercentage
+		flag.Overrides = existing.Overrides
+		if req.Description == nil {
+			flag.Description = existing.Description
+		}
+	}
+	if req.RolloutPercentage != nil {
+		if *req.RolloutPercentage < 0 || *req.RolloutPercentage > 100 {
+			return nil, fmt.Errorf("%w: rollout_percentage must be between 0 and 100", ErrInvalidFeatureFlag)
+		}
+		flag.RolloutPercentage = *req.RolloutPercentage
+	}
+
+	if err := s.repo.Upsert(ctx, flag); err != nil {
+		return nil, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+	s.invalidate(ctx)
+	return flag, nil
+}
+
+func (s *featureFlagService) DeleteFlag(ctx context.Context, key string) error {
+	deleted, err := s.repo.Delete(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	if !deleted {
+		return ErrFeatureFlagNotFound
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+func (s *featureFlagService) SetOverride(ctx context.Context, key string, tenantID uuid.UUID, enabled bool) (*models.FeatureFlagOverride, error) {
+	flag, err := s.GetFlag(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	override := &models.FeatureFlagOverride{
+		FlagKey:  flag.Key,
+		TenantID: tenantID,
+		Enabled:  enabled,
+	}
+	saved, err := s.repo.SetOverride(ctx, override)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save feature flag override: %w", err)
+	}
+	if !saved {
+		return nil, ErrFeatureFlagTenantNotFound
+	}
+	s.invalidate(ctx)
+	return override, nil
+}
+
+func (s *featureFlagService) DeleteOverride(ctx context.Context, key string, tenantID uuid.UUID) error {
+	deleted, err := s.repo.DeleteOverride(ctx, key, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag override: %w", err)
+	}
+	if !deleted {
+		return ErrFeatureFlagNotFound
+	}
+	s.invalidate(ctx)
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const googleTokenURL = "https://oauth2.googleapis.com/token"
+
+// googleServiceAccountKey is the subset of a Google service account key file
+// the token exchange needs
+type googleServiceAccountKey struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// GoogleServiceAccount issues OAuth access tokens for one scope from a
+// service account key, caching each token until it is close to expiry
+type GoogleServiceAccount struct {
+	ProjectID string
+
+	clientEmail string
+	tokenURI    string
+	scope       string
+	key         *rsa.PrivateKey
+	httpClient  *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewGoogleServiceAccount parses a service account key file's contents
+func NewGoogleServiceAccount(keyJSON []byte, scope string) (*GoogleServiceAccount, error) {
+	var key googleServiceAccountKey
+	if err := json.Unmarshal(keyJSON, &key); err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+	if key.ProjectID == "" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("service account key must include project_id, client_email and private_key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = googleTokenURL
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(key.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %w", err)
+	}
+	return &GoogleServiceAccount{
+		ProjectID:   key.ProjectID,
+		clientEmail: key.ClientEmail,
+		tokenURI:    key.TokenURI,
+		scope:       scope,
+		key:         privateKey,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Token returns a cached OAuth access token, exchanging a signed service
+// account assertion when it is close to expiry
+func (a *GoogleServiceAccount) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.accessToken != "" && time.Now().Before(a.expiresAt.Add(-time.Minute)) {
+		return a.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   a.clientEmail,
+		"scope": a.scope,
+		"aud":   a.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign service account assertion: %v", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Google token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Google token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode Google token response: %v", err)
+	}
+	a.accessToken = tokenResp.AccessToken
+	a.expiresAt = now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	return a.accessToken, nil
+}
+
+// Invalidate drops the cached token after the API rejected it
+func (a *GoogleServiceAccount) Invalidate() {
+	a.mu.Lock()
+	a.accessToken = ""
+	a.mu.Unlock()
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultImpersonationTTL is used when the caller does not request a duration
+	DefaultImpersonationTTL = 15 * time.Minute
+	// MaxImpersonationTTL caps how long a support session may last
+	MaxImpersonationTTL = 60 * time.Minute
+)
+
+// ImpersonationService lets platform admins act as tenant users without sharing passwords
+type ImpersonationService interface {
+	Start(ctx context.Context, impersonatorID, impersonatorTenantID, tenantID, targetUserID uuid.UUID, reason string, ttl time.Duration) (*models.ImpersonationSession, *models.TokenResponse, error)
+	End(ctx context.Context, sessionID, impersonatorID uuid.UUID) (*models.ImpersonationActivitySummary, error)
+	CloseExpired(ctx context.Context, now time.Time) (int, error)
+	GetByID(ctx context.Context, sessionID uuid.UUID) (*models.ImpersonationSession, error)
+	ListByTenant(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error)
+	ListByImpersonator(ctx context.Context, impersonatorID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error)
+}
+
+type impersonationService struct {
+	impersonationRepo repositories.ImpersonationRepository
+	userRepo          repositories.UserRepository
+	roleRepo          repositories.RoleRepository
+	userRoleRepo      repositories.UserRoleRepository
+	authService       AuthService
+	notificationSvc   NotificationService
+}
+
+// NewImpersonationService creates a new impersonation service
+func NewImpersonationService(impersonationRepo repositories.ImpersonationRepository, userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, userRoleRepo repositories.UserRoleRepository, authService AuthService, notificationSvc NotificationService) ImpersonationService {
+	return &impersonationService{
+		impersonationRepo: impersonationRepo,
+		userRepo:          userRepo,
+		roleRepo:          roleRepo,
+		userRoleRepo:      userRoleRepo,
+		authService:       authService,
+		notificationSvc:   notificationSvc,
+	}
+}
+
+// Start opens an impersonation session and mints a short-lived token acting as the target user
+func (s *impersonationService) Start(ctx context.Context, impersonatorID, impersonatorTenantID, tenantID, targetUserID uuid.UUID, reason string, ttl time.Duration) (*models.ImpersonationSession, *models.TokenResponse, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, nil, errors.New("impersonation reason is required")
+	}
+	if impersonatorID == targetUserID {
+		return nil, nil, errors.New("cannot impersonate yourself")
+	}
+	if ttl <= 0 {
+		ttl = DefaultImpersonationTTL
+	}
+	if ttl > MaxImpersonationTTL {
+		return nil, nil, fmt.Errorf("impersonation cannot exceed %s", MaxImpersonationTTL)
+	}
+
+	target, err := s.userRepo.GetByID(ctx, tenantID, targetUserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("target user not found: %w", err)
+	}
+	if target.Status != "active" {
+		return nil, nil, errors.New("target user is not active")
+	}
+
+	tokens, err := s.authService.GenerateImpersonationToken(ctx, targetUserID, tenantID, impersonatorID, ttl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := &models.ImpersonationSession{
+		ID:                   uuid.New(),
+		TenantID:             tenantID,
+		ImpersonatorID:       impersonatorID,
+		ImpersonatorTenantID: impersonatorTenantID,
+		TargetUserID:         targetUserID,
+		Reason:               reason,
+		TokenID:              tokens.TokenID,
+		ExpiresAt:            tokens.IssuedAt.Add(ttl),
+		CreatedAt:            time.Now(),
+	}
+	if err := s.impersonationRepo.Create(ctx, session); err != nil {
+		return nil, nil, fmt.Errorf("failed to record impersonation session: %w", err)
+	}
+
+	log.Printf("Impersonation started: session=%s impersonator=%s target=%s tenant=%s", session.ID, impersonatorID, targetUserID, tenantID)
+	return session, tokens, nil
+}
+
+// End closes a session and sends the impersonated tenant's admins a summary of the activity
+func (s *impersonationService) End(ctx context.Context, sessionID, impersonatorID uuid.UUID) (*models.ImpersonationActivitySummary, error) {
+	session, err := s.impersonationRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("impersonation session not found: %w", err)
+	}
+	if session.ImpersonatorID != impersonatorID {
+		return nil, errors.New("only the impersonating admin can end this session")
+	}
+
+	if session.EndedAt != nil {
+		// Already closed, by an earlier End or on expiry; the admins have
+		// had their summary
+		return s.summarize(ctx, session, *session.EndedAt)
+	}
+
+	endedAt := time.Now()
+	if endedAt.After(session.ExpiresAt) {
+		endedAt = session.ExpiresAt
+	}
+	return s.close(ctx, session, endedAt)
+}
+
+// expiredSessionBatch caps how many expired sessions one CloseExpired pass closes
+const expiredSessionBatch = 100
+
+// CloseExpired closes the sessions that expired without being ended, so their
+// tenants' admins get the same summary as for an ended session
+func (s *impersonationService) CloseExpired(ctx context.Context, now time.Time) (int, error) {
+	sessions, err := s.impersonationRepo.ListExpired(ctx, now, expiredSessionBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired impersonation sessions: %w", err)
+	}
+
+	closed := 0
+	for _, session := range sessions {
+		if _, err := s.close(ctx, session, session.ExpiresAt); err != nil {
+			log.Printf("Failed to close expired impersonation session %s: %v", session.ID, err)
+			continue
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// close ends an open session and notifies the tenant's admins, unless
+// another call closed it first
+func (s *impersonationService) close(ctx context.Context, session *models.ImpersonationSession, endedAt time.Time) (*models.ImpersonationActivitySummary, error) {
+	ended, err := s.impersonationRepo.End(ctx, session.ID, endedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to end impersonation session: %w", err)
+	}
+	if !ended {
+		if session, err = s.impersonationRepo.GetByID(ctx, session.ID); err != nil {
+			return nil, fmt.Errorf("impersonation session not found: %w", err)
+		}
+		return s.summarize(ctx, session, *session.EndedAt)
+	}
+	session.EndedAt = &endedAt
+
+	summary, err := s.summarize(ctx, session, endedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyTenantAdmins(ctx, session, summary)
+	log.Printf("Impersonation ended: session=%s impersonator=%s target=%s tenant=%s", session.ID, session.ImpersonatorID, session.TargetUserID, session.TenantID)
+	return summary, nil
+}
+
+// summarize counts the audited actions taken during a session
+func (s *impersonationService) summarize(ctx context.Context, session *models.ImpersonationSession, endedAt time.Time) (*models.ImpersonationActivitySummary, error) {
+	breakdown, err := s.impersonationRepo.SummarizeActivity(ctx, session.TenantID, session.ImpersonatorID, session.CreatedAt, endedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize impersonation activity: %w", err)
+	}
+
+	summary := &models.ImpersonationActivitySummary{
+		SessionID:       session.ID,
+		ActionBreakdown: breakdown,
+		PeriodStart:     session.CreatedAt,
+		PeriodEnd:       endedAt,
+	}
+	for _, count := range breakdown {
+		summary.TotalActions += count
+	}
+	return summary, nil
+}
+
+func (s *impersonationService) GetByID(ctx context.Context, sessionID uuid.UUID) (*models.ImpersonationSession, error) {
+	return s.impersonationRepo.GetByID(ctx, sessionID)
+}
+
+func (s *impersonationService) ListByTenant(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error) {
+	return s.impersonationRepo.ListByTenant(ctx, tenantID, limit, offset)
+}
+
+func (s *impersonationService) ListByImpersonator(ctx context.Context, impersonatorID uuid.UUID, limit, offset int) ([]*models.ImpersonationSession, error) {
+	return s.impersonationRepo.ListByImpersonator(ctx, impersonatorID, limit, offset)
+}
+
+// notifyTenantAdmins emails every admin of the impersonated tenant; failures are logged, not returned
+func (s *impersonationService) notifyTenantAdmins(ctx context.Context, session *models.ImpersonationSession, summary *models.ImpersonationActivitySummary) {
+	adminRole, err := s.roleRepo.GetByName(ctx, session.TenantID, "admin")
+	if err != nil || adminRole == nil {
+		log.Printf("No admin role for tenant %s, skipping impersonation summary: %v", session.TenantID, err)
+		return
+	}
+
+	admins, err := s.userRoleRepo.ListByRole(ctx, session.TenantID, adminRole.ID)
+	if err != nil {
+		log.Printf("Failed to list admins for tenant %s: %v", session.TenantID, err)
+		return
+	}
+
+	subject := "Support impersonation session summary"
+	body := formatImpersonationSummary(session, summary)
+	for _, admin := range admins {
+		user, err := s.userRepo.GetByID(ctx, session.TenantID, admin.UserID)
+		if err != nil {
+			continue
+		}
+		if err := s.notificationSvc.SendEmail(ctx, session.TenantID, user.Email, subject, body); err != nil {
+			log.Printf("Failed to send impersonation summary to %s: %v", user.Email, err)
+		}
+	}
+}
+
+func formatImpersonationSummary(session *models.ImpersonationSession, summary *models.ImpersonationActivitySummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "A platform administrator accessed your account as user %s.\n", session.TargetUserID)
+	fmt.Fprintf(&b, "Reason: %s\n", session.Reason)
+	fmt.Fprintf(&b, "Period: %s to %s\n", summary.PeriodStart.Format(time.RFC3339), summary.PeriodEnd.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Total actions: %d\n", summary.TotalActions)
+
+	actions := make([]string, 0, len(summary.ActionBreakdown))
+	for action := range summary.ActionBreakdown {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		fmt.Fprintf(&b, "  %s: %d\n", action, summary.ActionBreakdown[action])
+	}
+	return b.String()
+}
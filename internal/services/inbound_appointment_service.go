@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Bounds on an inbound appointment
+const (
+	minAppointmentSlot     = 15 * time.Minute
+	maxAppointmentSlot     = 8 * time.Hour
+	maxAppointmentOrders   = 50
+	maxAppointmentDockName = 50
+)
+
+var (
+	// ErrAppointmentNotFound is returned for appointments outside the tenant
+	ErrAppointmentNotFound = errors.New("inbound appointment not found")
+	// ErrInvalidAppointment wraps appointment validation failures
+	ErrInvalidAppointment = errors.New("invalid inbound appointment")
+	// ErrAppointmentConflict wraps bookings clashing with a scheduled appointment
+	ErrAppointmentConflict = errors.New("inbound appointment conflicts with another booking")
+)
+
+// BookAppointmentRequest books a supplier delivery of purchase orders into a
+// slot at a warehouse dock
+type BookAppointmentRequest struct {
+	Dock          string      `json:"dock"` // defaults to Dock 1
+	OrderIDs      []uuid.UUID `json:"order_ids"`
+	StartsAt      time.Time   `json:"starts_at"`
+	EndsAt        time.Time   `json:"ends_at"`
+	VehicleNumber *string     `json:"vehicle_number"`
+	Notes         *string     `json:"notes"`
+}
+
+// RescheduleAppointmentRequest moves an appointment to a new slot, and
+// optionally to another dock
+type RescheduleAppointmentRequest struct {
+	Dock          *string   `json:"dock"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	VehicleNumber *string   `json:"vehicle_number"`
+	Notes         *string   `json:"notes"`
+}
+
+// InboundAppointmentService books supplier deliveries into warehouse dock
+// slots and lays out each warehouse's daily receiving schedule
+type InboundAppointmentService interface {
+	Book(ctx context.Context, tenantID, warehouseID uuid.UUID, userID *uuid.UUID, req *BookAppointmentRequest) (*models.InboundAppointment, error)
+	Reschedule(ctx context.Context, tenantID, id uuid.UUID, req *RescheduleAppointmentRequest) (*models.InboundAppointment, error)
+	Cancel(ctx context.Context, tenantID, id uuid.UUID, reason *string) (*models.InboundAppointment, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundAppointment, error)
+	// DailySchedule lists the warehouse's appointments on a calendar date of
+	// the tenant, today when date is empty
+	DailySchedule(ctx context.Context, tenantID, warehouseID uuid.UUID, date string, includeCancelled bool) (*models.InboundSchedule, error)
+}
+
+type inboundAppointmentService struct {
+	appointmentRepo repositories.InboundAppointmentRepository
+	warehouseRepo   repositories.WarehouseRepository
+	orderRepo       repositories.OrderRepository
+	calendarSvc     TenantCalendarService
+}
+
+// NewInboundAppointmentService creates a new inbound appointment service instance
+func NewInboundAppointmentService(appointmentRepo repositories.InboundAppointmentRepository, warehouseRepo repositories.WarehouseRepository,
+	orderRepo repositories.OrderRepository, calendarSvc TenantCalendarService) InboundAppointmentService {
+	return &inboundAppointmentService{
+		appointmentRepo: appointmentRepo,
+		warehouseRepo:   warehouseRepo,
+		orderRepo:       orderRepo,
+		calendarSvc:     calendarSvc,
+	}
+}
+
+// normalizeDock trims the dock label, defaulting an empty one
+func normalizeDock(dock string) (string, error) {
+	dock = strings.TrimSpace(dock)
+	if dock == "" {
+		return models.DefaultDock, nil
+	}
+	if len(dock) > maxAppointmentDockName {
+		return "", fmt.Errorf("%w: dock must be at most %d characters", ErrInvalidAppointment, maxAppointmentDockName)
+	}
+	return dock, nil
+}
+
+// validateSlot checks the slot is in the future and of a bookable length
+func validateSlot(startsAt, endsAt, now time.Time) error {
+	if startsAt.IsZero() || endsAt.IsZero() {
+		return fmt.Errorf("%w: starts_at and ends_at are required", ErrInvalidAppointment)
+	}
+	if !endsAt.After(startsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidAppointment)
+	}
+	if length := endsAt.Sub(startsAt); length < minAppointmentSlot || length > maxAppointmentSlot {
+		return fmt.Errorf("%w: a slot must last between %v and %v", ErrInvalidAppointment, minAppointmentSlot, maxAppointmentSlot)
+	}
+	if startsAt.Before(now) {
+		return fmt.Errorf("%w: slot must not start in the past", ErrInvalidAppointment)
+	}
+	return nil
+}
+
+// conflictError describes the scheduled appointment an appointment clashes with
+func conflictError(appointment, conflict *models.InboundAppointment) error {
+	booked := make(map[uuid.UUID]bool, len(conflict.OrderIDs))
+	for _, id := range conflict.OrderIDs {
+		booked[id] = true
+	}
+	for _, id := range appointment.OrderIDs {
+		if booked[id] {
+			return fmt.Errorf("%w: order %s is already booked on appointment %s", ErrAppointmentConflict, id, conflict.ID)
+		}
+	}
+	return fmt.Errorf("%w: %s is booked from %s to %s by appointment %s", ErrAppointmentConflict, conflict.Dock,
+		conflict.StartsAt.Format(time.RFC3339), conflict.EndsAt.Format(time.RFC3339), conflict.ID)
+}
+
+// Book checks every order is an open purchase order for the warehouse from
+// one supplier, then stores the appointment unless it overlaps another at the
+// same dock or an order is already booked
+func (s *inboundAppointmentService) Book(ctx context.Context, tenantID, warehouseID uuid.UUID, userID *uuid.UUID, req *BookAppointmentRequest) (*models.InboundAppointment, error) {
+	dock, err := normalizeDock(req.Dock)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSlot(req.StartsAt, req.EndsAt, time.Now()); err != nil {
+		return nil, err
+	}
+	if len(req.OrderIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one order is required", ErrInvalidAppointment)
+	}
+	if len(req.OrderIDs) > maxAppointmentOrders {
+		return nil, fmt.Errorf("%w: at most %d orders can be delivered together", ErrInvalidAppointment, maxAppointmentOrders)
+	}
+
+	warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, warehouseID)
+	if err != nil || warehouse == nil {
+		return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidAppointment)
+	}
+	if warehouse.ArchivedAt != nil {
+		return nil, fmt.Errorf("%w: warehouse is archived", ErrInvalidAppointment)
+	}
+
+	appointment := &models.InboundAppointment{
+		ID:            uuid.New(),
+		TenantID:      tenantID,
+		WarehouseID:   warehouseID,
+		Dock:          dock,
+		OrderIDs:      make([]uuid.UUID, 0, len(req.OrderIDs)),
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		Status:        models.AppointmentScheduled,
+		VehicleNumber: req.VehicleNumber,
+		Notes:         req.Notes,
+		CreatedBy:     userID,
+	}
+	seen := make(map[uuid.UUID]bool, len(req.OrderIDs))
+	for i, orderID := range req.OrderIDs {
+		if seen[orderID] {
+			return nil, fmt.Errorf("%w: order %s is listed more than once", ErrInvalidAppointment, orderID)
+		}
+		seen[orderID] = true
+
+		order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
+		if err != nil || order == nil {
+			return nil, fmt.Errorf("%w: order %s not found", ErrInvalidAppointment, orderID)
+		}
+		if order.OrderType != "purchase" {
+			return nil, fmt.Errorf("%w: order %s is not a purchase order", ErrInvalidAppointment, orderID)
+		}
+		if order.WarehouseID != warehouseID {
+			return nil, fmt.Errorf("%w: order %s is not for this warehouse", ErrInvalidAppointment, orderID)
+		}
+		if order.Status == models.OrderStatusDelivered || order.Status == models.OrderStatusCancelled {
+			return nil, fmt.Errorf("%w: order %s is already %s", ErrInvalidAppointment, orderID, order.Status)
+		}
+		if i == 0 {
+			appointment.SupplierID = order.SupplierID
+		} else if (order.SupplierID == nil) != (appointment.SupplierID == nil) ||
+			(order.SupplierID != nil && *order.SupplierID != *appointment.SupplierID) {
+			return nil, fmt.Errorf("%w: orders must be from a single supplier", ErrInvalidAppointment)
+		}
+		appointment.OrderIDs = append(appointment.OrderIDs, orderID)
+	}
+
+	conflict, err := s.appointmentRepo.Create(ctx, appointment)
+	if err != nil {
+		return nil, common.SecureErrorMessage("book inbound appointment", err)
+	}
+	if conflict != nil {
+		return nil, conflictError(appointment, conflict)
+	}
+	return appointment, nil
+}
+
+// Reschedule moves a scheduled appointment, keeping its orders
+func (s *inboundAppointmentService) Reschedule(ctx context.Context, tenantID, id uuid.UUID, req *RescheduleAppointmentRequest) (*models.InboundAppointment, error) {
+	appointment, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status != models.AppointmentScheduled {
+		return nil, fmt.Errorf("%w: a %s appointment cannot be rescheduled", ErrInvalidAppointment, appointment.Status)
+	}
+	if req.Dock != nil {
+		if appointment.Dock, err = normalizeDock(*req.Dock); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateSlot(req.StartsAt, req.EndsAt, time.Now()); err != nil {
+		return nil, err
+	}
+	appointment.StartsAt = req.StartsAt
+	appointment.EndsAt = req.EndsAt
+	if req.VehicleNumber != nil {
+		appointment.VehicleNumber = req.VehicleNumber
+	}
+	if req.Notes != nil {
+		appointment.Notes = req.Notes
+	}
+
+	conflict, err := s.appointmentRepo.Reschedule(ctx, appointment)
+	if err != nil {
+		return nil, common.SecureErrorMessage("reschedule inbound appointment", err)
+	}
+	if conflict != nil {
+		return nil, conflictError(appointment, conflict)
+	}
+	return appointment, nil
+}
+
+// Cancel frees the appointment's slot and orders for another booking
+func (s *inboundAppointmentService) Cancel(ctx context.Context, tenantID, id uuid.UUID, reason *string) (*models.InboundAppointment, error) {
+	appointment, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	appointment.CancelReason = reason
+	cancelled, err := s.appointmentRepo.Cancel(ctx, appointment)
+	if err != nil {
+		return nil, common.SecureErrorMessage("cancel inbound appointment", err)
+	}
+	if !cancelled {
+		return nil, fmt.Errorf("%w: appointment is already %s", ErrInvalidAppointment, appointment.Status)
+	}
+	return appointment, nil
+}
+
+func (s *inboundAppointmentService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundAppointment, error) {
+	appointment, err := s.appointmentRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get inbound appointment", err)
+	}
+	if appointment == nil {
+		return nil, ErrAppointmentNotFound
+	}
+	return appointment, nil
+}
+
+func (s *inboundAppointmentService) DailySchedule(ctx context.Context, tenantID, warehouseID uuid.UUID, date string, includeCancelled bool) (*models.InboundSchedule, error) {
+	if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, warehouseID); err != nil || warehouse == nil {
+		return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidAppointment)
+	}
+	calendar, err := s.calendarSvc.GetCalendar(ctx, tenantID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get tenant calendar", err)
+	}
+
+	day := calendar.Today(time.Now())
+	if date != "" {
+		if day, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidAppointment)
+		}
+	}
+	from, to := calendar.DayStart(day), calendar.DayStart(day.AddDate(0, 0, 1))
+
+	appointments, err := s.appointmentRepo.ListForWarehouse(ctx, tenantID, warehouseID, from, to, includeCancelled)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list inbound appointments", err)
+	}
+	if appointments == nil {
+		appointments = []*models.InboundAppointment{}
+	}
+	return &models.InboundSchedule{
+		WarehouseID:  warehouseID,
+		Date:         day.Format("2006-01-02"),
+		Timezone:     calendar.Location().String(),
+		Appointments: appointments,
+	}, nil
+}
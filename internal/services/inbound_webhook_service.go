@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInboundWebhookNotFound is returned for unknown webhooks and slugs
+	ErrInboundWebhookNotFound = errors.New("inbound webhook not found")
+	// ErrInvalidInboundWebhook wraps inbound webhook validation failures
+	ErrInvalidInboundWebhook = errors.New("invalid inbound webhook")
+	// ErrInboundWebhookConflict is returned when the slug or name is taken
+	ErrInboundWebhookConflict = errors.New("inbound webhook slug or name already in use")
+)
+
+// maxStoredWebhookPayload caps the payload kept in the delivery log
+const maxStoredWebhookPayload = 64 << 10
+
+var inboundWebhookSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,63}$`)
+
+// InboundWebhookService manages the endpoints payment gateways and
+// marketplaces push events to, verifies each delivery and maps its payload
+// to an order or an invoice payment
+type InboundWebhookService interface {
+	ListWebhooks(ctx context.Context, tenantID uuid.UUID) ([]*models.InboundWebhook, error)
+	GetWebhook(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundWebhook, error)
+	// CreateWebhook returns the webhook and its secret, which is only shown once
+	CreateWebhook(ctx context.Context, tenantID uuid.UUID, req *models.InboundWebhookRequest) (*models.InboundWebhook, string, error)
+	UpdateWebhook(ctx context.Context, tenantID, id uuid.UUID, req *models.InboundWebhookRequest) (*models.InboundWebhook, error)
+	RotateSecret(ctx context.Context, tenantID, id uuid.UUID) (string, error)
+	DeleteWebhook(ctx context.Context, tenantID, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, tenantID, webhookID uuid.UUID, status string, limit, offset int) ([]*models.InboundWebhookDelivery, error)
+
+	// Receive verifies and processes a delivery to /v1/hooks/:slug and
+	// returns how it was logged. Only unknown or inactive slugs return an error
+	Receive(ctx context.Context, slug string, header http.Header, body []byte, sourceIP string) (*models.InboundWebhookDelivery, error)
+}
+
+type inboundWebhookService struct {
+	repo            repositories.InboundWebhookRepository
+	productRepo     repositories.ProductRepository
+	warehouseRepo   repositories.WarehouseRepository
+	distributorRepo repositories.DistributorRepository
+	orderService    OrderServiceInterface
+	invoiceService  InvoiceServiceInterface
+}
+
+// NewInboundWebhookService creates a new inbound webhook service
+func NewInboundWebhookService(repo repositories.InboundWebhookRepository, productRepo repositories.ProductRepository,
+	warehouseRepo repositories.WarehouseRepository, distributorRepo repositories.DistributorRepository,
+	orderService OrderServiceInterface, invoiceService InvoiceServiceInterface) InboundWebhookService {
+	return &inboundWebhookService{
+		repo:            repo,
+		productRepo:     productRepo,
+		warehouseRepo:   warehouseRepo,
+		distributorRepo: distributorRepo,
+		orderService:    orderService,
+		invoiceService:  invoiceService,
+	}
+}
+
+func newInboundWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// verifyInboundWebhook checks the signature header against the webhook's secret
+func verifyInboundWebhook(webhook *models.InboundWebhook, header http.Header, body []byte) bool {
+	provided := strings.TrimSpace(header.Get(webhook.SignatureHeader))
+	if provided == "" {
+		return false
+	}
+	switch webhook.Verification {
+	case models.InboundWebhookVerificationSharedSecret:
+		return subtle.ConstantTimeCompare([]byte(provided), []byte(webhook.Secret)) == 1
+	case models.InboundWebhookVerificationHMACSHA256:
+		provided = strings.ToLower(strings.TrimPrefix(provided, "sha256="))
+		return hmac.Equal([]byte(provided), []byte(signMarketplacePayload(webhook.Secret, body)))
+	}
+	return false
+}
+
+// applyInboundWebhookRequest validates the request and copies it onto the webhook
+func (s *inboundWebhookService) applyInboundWebhookRequest(ctx context.Context, webhook *models.InboundWebhook, req *models.InboundWebhookRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidInboundWebhook)
+	}
+	if req.WarehouseID != nil {
+		if _, err := s.warehouseRepo.GetByID(ctx, webhook.TenantID, *req.WarehouseID); err != nil {
+			return fmt.Errorf("%w: warehouse not found", ErrInvalidInboundWebhook)
+		}
+	}
+	if req.DistributorID != nil {
+		if _, err := s.distributorRepo.GetByID(ctx, webhook.TenantID, *req.DistributorID); err != nil {
+			return fmt.Errorf("%w: distributor not found", ErrInvalidInboundWebhook)
+		}
+	}
+
+	webhook.Name = name
+	if header := strings.TrimSpace(req.SignatureHeader); header != "" {
+		webhook.SignatureHeader = http.CanonicalHeaderKey(header)
+	}
+	webhook.FieldMapping = req.FieldMapping
+	webhook.WarehouseID = req.WarehouseID
+	webhook.DistributorID = req.DistributorID
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func (s *inboundWebhookService) ListWebhooks(ctx context.Context, tenantID uuid.UUID) ([]*models.InboundWebhook, error) {
+	return s.repo.List(ctx, tenantID)
+}
+
+func (s *inboundWebhookService) GetWebhook(ctx context.Context, tenantID, id uuid.UUID) (*models.InboundWebhook, error) {
+	webhook, err := s.repo.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load inbound webhook: %w", err)
+	}
+	if webhook == nil {
+		return nil, ErrInboundWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (s *inboundWebhookService) CreateWebhook(ctx context.Context, tenantID uuid.UUID, req *models.InboundWebhookRequest) (*models.InboundWebhook, string, error) {
+	webhook := &models.InboundWebhook{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		Slug:         strings.ToLower(strings.TrimSpace(req.Slug)),
+		Action:       req.Action,
+		Verification: req.Verification,
+		IsActive:     true,
+	}
+
+	switch webhook.Action {
+	case models.InboundWebhookActionCreateOrder, models.InboundWebhookActionMarkInvoicePaid:
+	default:
+		return nil, "", fmt.Errorf("%w: action must be create_order or mark_invoice_paid", ErrInvalidInboundWebhook)
+	}
+	switch webhook.Verification {
+	case models.InboundWebhookVerificationSharedSecret:
+		webhook.SignatureHeader = "X-Webhook-Secret"
+	case models.InboundWebhookVerificationHMACSHA256:
+		webhook.SignatureHeader = "X-Webhook-Signature"
+	default:
+		return nil, "", fmt.Errorf("%w: verification must be shared_secret or hmac_sha256", ErrInvalidInboundWebhook)
+	}
+	if webhook.Slug == "" {
+		// Unguessable by default, so the URL alone reveals nothing
+		webhook.Slug = strings.ReplaceAll(uuid.New().String(), "-", "")
+	} else if !inboundWebhookSlugPattern.MatchString(webhook.Slug) {
+		return nil, "", fmt.Errorf("%w: slug must be 3 to 64 lowercase letters, digits and hyphens", ErrInvalidInboundWebhook)
+	}
+	if err := s.applyInboundWebhookRequest(ctx, webhook, req); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newInboundWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	webhook.Secret = secret
+
+	created, err := s.repo.Create(ctx, webhook)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create inbound webhook: %w", err)
+	}
+	if !created {
+		return nil, "", ErrInboundWebhookConflict
+	}
+	return webhook, secret, nil
+}
+
+func (s *inboundWebhookService) UpdateWebhook(ctx context.Context, tenantID, id uuid.UUID, req *models.InboundWebhookRequest) (*models.InboundWebhook, error) {
+	webhook, err := s.GetWebhook(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInboundWebhookRequest(ctx, webhook, req); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Update(ctx, webhook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update inbound webhook: %w", err)
+	}
+	if !updated {
+		return nil, ErrInboundWebhookConflict
+	}
+	return webhook, nil
+}
+
+func (s *inboundWebhookService) RotateSecret(ctx context.Context, tenantID, id uuid.UUID) (string, error) {
+	secret, err := newInboundWebhookSecret()
+	if err != nil {
+		return "", err
+	}
+	rotated, err := s.repo.SetSecret(ctx, tenantID, id, secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	if !rotated {
+		return "", ErrInboundWebhookNotFound
+	}
+	return secret, nil
+}
+
+func (s *inboundWebhookService) DeleteWebhook(ctx context.Context, tenantID, id uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, tenantID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete inbound webhook: %w", err)
+	}
+	if !deleted {
+		return ErrInboundWebhookNotFound
+	}
+	return nil
+}
+
+func (s *inboundWebhookService) ListDeliveries(ctx context.Context, tenantID, webhookID uuid.UUID, status string, limit, offset int) ([]*models.InboundWebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, tenantID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(ctx, tenantID, webhookID, status, limit, offset)
+}
+
+// Receive logs every delivery to a known webhook. Deliveries carrying an
+// event ID already processed are logged as duplicates and point at what the
+// original created, so senders can retry safely
+func (s *inboundWebhookService) Receive(ctx context.Context, slug string, header http.Header, body []byte, sourceIP string) (*models.InboundWebhookDelivery, error) {
+	webhook, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load inbound webhook: %w", err)
+	}
+	if webhook == nil || !webhook.IsActive {
+		return nil, ErrInboundWebhookNotFound
+	}
+
+	payload := string(body)
+	if len(payload) > maxStoredWebhookPayload {
+		payload = payload[:maxStoredWebhookPayload]
+	}
+	delivery := &models.InboundWebhookDelivery{
+		ID:        uuid.New(),
+		TenantID:  webhook.TenantID,
+		WebhookID: webhook.ID,
+		Payload:   payload,
+	}
+	if sourceIP != "" {
+		delivery.SourceIP = &sourceIP
+	}
+
+	if !verifyInboundWebhook(webhook, header, body) {
+		return s.logDelivery(ctx, delivery, models.InboundDeliveryRejected, "signature verification failed")
+	}
+
+	var event map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		return s.logDelivery(ctx, delivery, models.InboundDeliveryFailed, "payload is not a JSON object")
+	}
+
+	mapping := webhook.FieldMapping.WithDefaults()
+	if eventID, ok := lookupJSONPath(event, mapping.EventID); ok {
+		if id := strings.TrimSpace(jsonString(eventID)); id != "" {
+			delivery.EventID = &id
+		}
+	}
+
+	reserved, err := s.repo.ReserveDelivery(ctx, delivery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	if !reserved {
+		original, err := s.repo.GetProcessedDelivery(ctx, webhook.ID, *delivery.EventID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load original webhook delivery: %w", err)
+		}
+		if original != nil {
+			delivery.OrderID = original.OrderID
+			delivery.InvoiceID = original.InvoiceID
+		}
+		return s.logDelivery(ctx, delivery, models.InboundDeliveryDuplicate, "")
+	}
+
+	switch webhook.Action {
+	case models.InboundWebhookActionCreateOrder:
+		err = s.createOrder(ctx, webhook, mapping, event, delivery)
+	case models.InboundWebhookActionMarkInvoicePaid:
+		err = s.markInvoicePaid(ctx, webhook, mapping, event, delivery)
+	default:
+		err = fmt.Errorf("unknown action %q", webhook.Action)
+	}
+	if err != nil {
+		// Failing the reserved delivery frees its event ID for a retry
+		message := err.Error()
+		delivery.Status = models.InboundDeliveryFailed
+		delivery.Error = &message
+	}
+	if updateErr := s.repo.UpdateDelivery(ctx, delivery); updateErr != nil {
+		return nil, fmt.Errorf("failed to record webhook delivery: %w", updateErr)
+	}
+	return delivery, nil
+}
+
+// logDelivery records a delivery that was not acted on
+func (s *inboundWebhookService) logDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery, status, message string) (*models.InboundWebhookDelivery, error) {
+	delivery.Status = status
+	if message != "" {
+		delivery.Error = &message
+	}
+	if err := s.repo.RecordDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// eventUUID reads an optional UUID from the event, falling back to def
+func eventUUID(event map[string]interface{}, path, field string, def *uuid.UUID) (*uuid.UUID, error) {
+	raw, ok := lookupJSONPath(event, path)
+	if !ok {
+		return def, nil
+	}
+	id, err := uuid.Parse(strings.TrimSpace(jsonString(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s at %q", field, path)
+	}
+	return &id, nil
+}
+
+func (s *inboundWebhookService) createOrder(ctx context.Context, webhook *models.InboundWebhook, mapping models.InboundWebhookFieldMapping, event map[string]interface{}, delivery *models.InboundWebhookDelivery) error {
+	rawSKU, ok := lookupJSONPath(event, mapping.SKU)
+	if !ok {
+		return fmt.Errorf("product SKU not found at %q", mapping.SKU)
+	}
+	sku := strings.TrimSpace(jsonString(rawSKU))
+	var product *models.Product
+	if id, err := uuid.Parse(sku); err == nil {
+		product, _ = s.productRepo.GetByID(ctx, webhook.TenantID, id)
+	}
+	if product == nil {
+		if product, _ = s.productRepo.GetByBarcode(ctx, webhook.TenantID, sku); product == nil {
+			return fmt.Errorf("no product matches SKU %q", sku)
+		}
+	}
+
+	rawQuantity, ok := lookupJSONPath(event, mapping.Quantity)
+	if !ok {
+		return fmt.Errorf("quantity not found at %q", mapping.Quantity)
+	}
+	quantity, err := jsonFloat(rawQuantity)
+	if err != nil || quantity <= 0 || quantity != float64(int(quantity)) {
+		return fmt.Errorf("quantity must be a positive whole number")
+	}
+
+	unitPrice := product.UnitPrice
+	if rawPrice, ok := lookupJSONPath(event, mapping.UnitPrice); ok {
+		if unitPrice, err = jsonFloat(rawPrice); err != nil || unitPrice < 0 {
+			return fmt.Errorf("invalid unit price")
+		}
+	}
+
+	warehouseID, err := eventUUID(event, mapping.WarehouseID, "warehouse ID", webhook.WarehouseID)
+	if err != nil {
+		return err
+	}
+	if warehouseID == nil {
+		return fmt.Errorf("warehouse ID not found at %q and the webhook has no default warehouse", mapping.WarehouseID)
+	}
+	distributorID, err := eventUUID(event, mapping.DistributorID, "distributor ID", webhook.DistributorID)
+	if err != nil {
+		return err
+	}
+
+	notes := fmt.Sprintf("Received by webhook %s", webhook.Name)
+	if delivery.EventID != nil {
+		notes += " event " + *delivery.EventID
+	}
+	if note, ok := lookupJSONPath(event, mapping.Note); ok {
+		if text := strings.TrimSpace(jsonString(note)); text != "" {
+			notes += ": " + text
+		}
+	}
+
+	order := &models.Order{
+		TenantID:      webhook.TenantID,
+		OrderType:     "sales",
+		DistributorID: distributorID,
+		ProductID:     product.ID,
+		WarehouseID:   *warehouseID,
+		Quantity:      int(quantity),
+		UnitPrice:     unitPrice,
+		Notes:         &notes,
+	}
+	if err := s.orderService.CreateOrder(ctx, webhook.TenantID, order); err != nil {
+		return err
+	}
+	delivery.OrderID = &order.ID
+	return nil
+}
+
+func (s *inboundWebhookService) markInvoicePaid(ctx context.Context, webhook *models.InboundWebhook, mapping models.InboundWebhookFieldMapping, event map[string]interface{}, delivery *models.InboundWebhookDelivery) error {
+	invoiceID, err := eventUUID(event, mapping.InvoiceID, "invoice ID", nil)
+	if err != nil {
+		return err
+	}
+	if invoiceID == nil {
+		rawNumber, ok := lookupJSONPath(event, mapping.InvoiceNumber)
+		if !ok {
+			return fmt.Errorf("neither invoice ID at %q nor invoice number at %q found", mapping.InvoiceID, mapping.InvoiceNumber)
+		}
+		number := strings.TrimSpace(jsonString(rawNumber))
+		if invoiceID, err = s.repo.FindInvoiceByNumber(ctx, webhook.TenantID, number); err != nil {
+			return fmt.Errorf("failed to look up invoice: %w", err)
+		}
+		if invoiceID == nil {
+			return fmt.Errorf("no invoice numbered %q", number)
+		}
+	}
+
+	invoice, err := s.invoiceService.GetInvoiceByID(ctx, webhook.TenantID, *invoiceID)
+	if err != nil || invoice == nil {
+		return fmt.Errorf("invoice %s not found", invoiceID)
+	}
+	delivery.InvoiceID = &invoice.ID
+	// A payment confirmed twice under different event IDs is not an error
+	if invoice.Status == "paid" {
+		return nil
+	}
+	if mapping.Amount != "" {
+		rawAmount, ok := lookupJSONPath(event, mapping.Amount)
+		if !ok {
+			return fmt.Errorf("amount not found at %q", mapping.Amount)
+		}
+		amount, err := jsonFloat(rawAmount)
+		if err != nil {
+			return fmt.Errorf("invalid amount")
+		}
+		// A short payment is left for the customer payments screen to allocate
+		if math.Round(amount*100) < math.Round(invoice.TotalAmount*100) {
+			return fmt.Errorf("payment of %.2f is less than the invoice total %.2f", amount, invoice.TotalAmount)
+		}
+	}
+	return s.invoiceService.UpdateInvoiceStatus(ctx, webhook.TenantID, invoice.ID, "paid")
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// maxReconciliationCounts bounds how many counts one snapshot may carry
+const maxReconciliationCounts = 10000
+
+var (
+	// ErrReconciliationNotFound is returned for reconciliations outside the tenant
+	ErrReconciliationNotFound = errors.New("inventory reconciliation not found")
+	// ErrInvalidReconciliation wraps snapshot and approval validation failures
+	ErrInvalidReconciliation = errors.New("invalid inventory reconciliation")
+	// ErrReconciliationResolved is returned when applying or discarding a
+	// reconciliation that is no longer pending
+	ErrReconciliationResolved = errors.New("inventory reconciliation is already resolved")
+)
+
+// InventoryReconciliationService diffs stock snapshots from an external WMS
+// against internal inventory and applies the approved adjustments as
+// inventory transactions
+type InventoryReconciliationService interface {
+	Reconcile(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.InventoryReconciliationRequest) (*models.InventoryReconciliation, error)
+	GetReconciliation(ctx context.Context, tenantID, id uuid.UUID) (*models.InventoryReconciliation, error)
+	ListReconciliations(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InventoryReconciliation, error)
+	Apply(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, lineIDs []uuid.UUID) (*models.InventoryReconciliation, error)
+	Discard(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID) (*models.InventoryReconciliation, error)
+}
+
+type inventoryReconciliationService struct {
+	repo             repositories.InventoryReconciliationRepository
+	productRepo      repositories.ProductRepository
+	warehouseRepo    repositories.WarehouseRepository
+	inventoryService InventoryService
+}
+
+// NewInventoryReconciliationService creates a new inventory reconciliation service instance
+func NewInventoryReconciliationService(repo repositories.InventoryReconciliationRepository, productRepo repositories.ProductRepository,
+	warehouseRepo repositories.WarehouseRepository, inventoryService InventoryService) InventoryReconciliationService {
+	return &inventoryReconciliationService{
+		repo:             repo,
+		productRepo:      productRepo,
+		warehouseRepo:    warehouseRepo,
+		inventoryService: inventoryService,
+	}
+}
+
+// Reconcile diffs the snapshot against current inventory and stores the
+// report for review; no stock moves until it is applied
+func (s *inventoryReconciliationService) Reconcile(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.InventoryReconciliationRequest) (*models.InventoryReconciliation, error) {
+	source := strings.TrimSpace(req.Source)
+	if source == "" || len(source) > 100 {
+		return nil, fmt.Errorf("%w: source is required and must be at most 100 characters", ErrInvalidReconciliation)
+	}
+	if len(req.Counts) == 0 {
+		return nil, fmt.Errorf("%w: at least one count is required", ErrInvalidReconciliation)
+	}
+	if len(req.Counts) > maxReconciliationCounts {
+		return nil, fmt.Errorf("%w: at most %d counts can be reconciled at once", ErrInvalidReconciliation, maxReconciliationCounts)
+	}
+
+	type stockKey struct{ product, warehouse uuid.UUID }
+	seen := make(map[stockKey]bool, len(req.Counts))
+	var productIDs, warehouseIDs []uuid.UUID
+	seenProduct, seenWarehouse := make(map[uuid.UUID]bool), make(map[uuid.UUID]bool)
+	for _, count := range req.Counts {
+		if count.Quantity < 0 {
+			return nil, fmt.Errorf("%w: quantity must not be negative", ErrInvalidReconciliation)
+		}
+		key := stockKey{count.ProductID, count.WarehouseID}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: product %s in warehouse %s is counted more than once", ErrInvalidReconciliation, count.ProductID, count.WarehouseID)
+		}
+		seen[key] = true
+		if !seenProduct[count.ProductID] {
+			seenProduct[count.ProductID] = true
+			productIDs = append(productIDs, count.ProductID)
+		}
+		if !seenWarehouse[count.WarehouseID] {
+			seenWarehouse[count.WarehouseID] = true
+			warehouseIDs = append(warehouseIDs, count.WarehouseID)
+		}
+	}
+
+	products, err := s.productRepo.GetByIDs(ctx, tenantID, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load products: %w", err)
+	}
+	if len(products) != len(productIDs) {
+		return nil, fmt.Errorf("%w: the snapshot counts products that do not exist", ErrInvalidReconciliation)
+	}
+	warehouses, err := s.warehouseRepo.GetByIDs(ctx, tenantID, warehouseIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load warehouses: %w", err)
+	}
+	if len(warehouses) != len(warehouseIDs) {
+		return nil, fmt.Errorf("%w: the snapshot counts warehouses that do not exist", ErrInvalidReconciliation)
+	}
+
+	stock, err := s.repo.StockIn(ctx, tenantID, warehouseIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load inventory: %w", err)
+	}
+
+	rec := &models.InventoryReconciliation{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		Source:       source,
+		Status:       models.ReconciliationPending,
+		SnapshotAt:   time.Now(),
+		FullSnapshot: req.FullSnapshot,
+		Notes:        req.Notes,
+		CreatedBy:    userID,
+	}
+	if req.SnapshotAt != nil {
+		rec.SnapshotAt = *req.SnapshotAt
+	}
+	rec.Lines, rec.MatchedLines = diffStockCounts(req.Counts, stock, req.FullSnapshot)
+	rec.CountedLines = len(rec.Lines) + rec.MatchedLines
+	for _, line := range rec.Lines {
+		line.ID = uuid.New()
+		line.ReconciliationID = rec.ID
+	}
+
+	if err := s.repo.Create(ctx, rec); err != nil {
+		return nil, fmt.Errorf("failed to save inventory reconciliation: %w", err)
+	}
+	return rec, nil
+}
+
+// diffStockCounts compares external counts with internal stock and returns
+// the lines that differ, largest adjustment first, and how many matched.
+// Products with no internal stock record count as zero; with a full snapshot
+// so do internal records the snapshot does not list
+func diffStockCounts(counts []models.WMSStockCount, stock []*models.Inventory, fullSnapshot bool) ([]*models.InventoryReconciliationLine, int) {
+	type stockKey struct{ product, warehouse uuid.UUID }
+	internal := make(map[stockKey]int, len(stock))
+	for _, inv := range stock {
+		internal[stockKey{inv.ProductID, inv.WarehouseID}] = inv.Quantity
+	}
+
+	lines := []*models.InventoryReconciliationLine{}
+	matched := 0
+	counted := make(map[stockKey]bool, len(counts))
+	add := func(productID, warehouseID uuid.UUID, external, internal int) {
+		if external == internal {
+			matched++
+			return
+		}
+		lines = append(lines, &models.InventoryReconciliationLine{
+			ProductID:        productID,
+			WarehouseID:      warehouseID,
+			ExternalQuantity: external,
+			InternalQuantity: internal,
+			Adjustment:       external - internal,
+		})
+	}
+	for _, count := range counts {
+		key := stockKey{count.ProductID, count.WarehouseID}
+		counted[key] = true
+		add(count.ProductID, count.WarehouseID, count.Quantity, internal[key])
+	}
+	if fullSnapshot {
+		for _, inv := range stock {
+			if !counted[stockKey{inv.ProductID, inv.WarehouseID}] {
+				add(inv.ProductID, inv.WarehouseID, 0, inv.Quantity)
+			}
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return absInt(lines[i].Adjustment) > absInt(lines[j].Adjustment)
+	})
+	return lines, matched
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (s *inventoryReconciliationService) GetReconciliation(ctx context.Context, tenantID, id uuid.UUID) (*models.InventoryReconciliation, error) {
+	rec, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrReconciliationNotFound
+	}
+	return rec, nil
+}
+
+func (s *inventoryReconciliationService) ListReconciliations(ctx context.Context, tenantID uuid.UUID, status string, limit, offset int) ([]*models.InventoryReconciliation, error) {
+	switch status {
+	case "", models.ReconciliationPending, models.ReconciliationApplied, models.ReconciliationDiscarded:
+	default:
+		return nil, fmt.Errorf("%w: status must be pending, applied or discarded", ErrInvalidReconciliation)
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.List(ctx, tenantID, status, limit, offset)
+}
+
+// Apply approves the given lines, or every line when none are given, and
+// adds their adjustments to stock. Adjustments are changes rather than the
+// counted quantities, so stock that moved since the snapshot keeps those
+// movements
+func (s *inventoryReconciliationService) Apply(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, lineIDs []uuid.UUID) (*models.InventoryReconciliation, error) {
+	rec, err := s.GetReconciliation(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != models.ReconciliationPending {
+		return nil, ErrReconciliationResolved
+	}
+
+	byID := make(map[uuid.UUID]*models.InventoryReconciliationLine, len(rec.Lines))
+	for _, line := range rec.Lines {
+		byID[line.ID] = line
+	}
+	approved := rec.Lines
+	if len(lineIDs) > 0 {
+		approved = []*models.InventoryReconciliationLine{}
+		for _, lineID := range lineIDs {
+			line, ok := byID[lineID]
+			if !ok {
+				return nil, fmt.Errorf("%w: line %s is not part of this reconciliation", ErrInvalidReconciliation, lineID)
+			}
+			// A line listed twice is still applied once
+			if !line.Approved {
+				line.Approved = true
+				approved = append(approved, line)
+			}
+		}
+	}
+	approvedIDs := make([]uuid.UUID, 0, len(approved))
+	for _, line := range approved {
+		approvedIDs = append(approvedIDs, line.ID)
+	}
+
+	ok, err := s.repo.Resolve(ctx, tenantID, id, models.ReconciliationApplied, userID, approvedIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply inventory reconciliation: %w", err)
+	}
+	if !ok {
+		return nil, ErrReconciliationResolved
+	}
+
+	for _, line := range approved {
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, line.WarehouseID, line.ProductID, line.Adjustment, models.InventoryReasonReconciliation); err != nil {
+			return nil, fmt.Errorf("failed to adjust stock of product %s in warehouse %s: %w", line.ProductID, line.WarehouseID, err)
+		}
+		if err := s.repo.MarkLineApplied(ctx, line.ID); err != nil {
+			return nil, fmt.Errorf("failed to record applied adjustment: %w", err)
+		}
+		line.Applied = true
+	}
+	return s.GetReconciliation(ctx, tenantID, id)
+}
+
+// Discard closes a pending reconciliation without moving any stock
+func (s *inventoryReconciliationService) Discard(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID) (*models.InventoryReconciliation, error) {
+	if _, err := s.GetReconciliation(ctx, tenantID, id); err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.Resolve(ctx, tenantID, id, models.ReconciliationDiscarded, userID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discard inventory reconciliation: %w", err)
+	}
+	if !ok {
+		return nil, ErrReconciliationResolved
+	}
+	return s.GetReconciliation(ctx, tenantID, id)
+}
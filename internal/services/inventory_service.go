@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"agromart2/internal/caching"
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
@@ -15,11 +17,15 @@ import (
 type InventoryService interface {
 	Create(ctx context.Context, tenantID uuid.UUID, inventory *models.Inventory) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Inventory, error)
+	// GetByIDs returns the inventory records found among ids, in request order
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Inventory, error)
 	Update(ctx context.Context, tenantID uuid.UUID, inventory *models.Inventory) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
-	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Inventory, error)
+	List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Inventory, error)
 	Transfer(ctx context.Context, tenantID, productID, fromWarehouseID, toWarehouseID uuid.UUID, quantity int) error
 	AdjustStock(ctx context.Context, tenantID, warehouseID, productID uuid.UUID, quantityChange int) error
+	AdjustStockWithReason(ctx context.Context, tenantID, warehouseID, productID uuid.UUID, quantityChange int, reason string) error
+	GetProductStock(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.Inventory, error)
 	LowStockAlerts(ctx context.Context, tenantID uuid.UUID, threshold int) ([]*models.Inventory, error)
 	GetByWarehouseAndProduct(ctx context.Context, tenantID, warehouseID, productID uuid.UUID) (*models.Inventory, error)
 	AdvancedSearch(ctx context.Context, tenantID uuid.UUID, filter *models.InventorySearchFilter) ([]*models.Inventory, error)
@@ -27,32 +33,71 @@ type InventoryService interface {
 	// Bulk operations
 	BulkAdjustStock(ctx context.Context, tenantID uuid.UUID, bulkAdjust *models.InventoryBulkAdjust) (*models.BulkOperationResult, error)
 	BulkTransferStock(ctx context.Context, tenantID uuid.UUID, bulkTransfer *models.InventoryBulkTransfer) (*models.BulkOperationResult, error)
+
+	// StockChanged purges the cached responses that show stock levels. Callers
+	// that write inventory through the repository directly must call it
+	StockChanged(ctx context.Context, tenantID uuid.UUID)
 }
 
 type inventoryService struct {
-	inventoryRepo repositories.InventoryRepository
-	productRepo   repositories.ProductRepository
-	cacheService  caching.CacheService
+	inventoryRepo   repositories.InventoryRepository
+	productRepo     repositories.ProductRepository
+	transactionRepo repositories.InventoryTransactionRepository
+	cacheService    caching.CacheService
+	storageSvc      StorageConditionService
+	minimumRepo     repositories.StockMinimumRepository
 }
 
-func NewInventoryService(inventoryRepo repositories.InventoryRepository, productRepo repositories.ProductRepository, cacheService caching.CacheService) InventoryService {
+func NewInventoryService(inventoryRepo repositories.InventoryRepository, productRepo repositories.ProductRepository, transactionRepo repositories.InventoryTransactionRepository, cacheService caching.CacheService, storageSvc StorageConditionService, minimumRepo repositories.StockMinimumRepository) InventoryService {
 	return &inventoryService{
-		inventoryRepo: inventoryRepo,
-		productRepo:   productRepo,
-		cacheService:  cacheService,
+		inventoryRepo:   inventoryRepo,
+		productRepo:     productRepo,
+		transactionRepo: transactionRepo,
+		cacheService:    cacheService,
+		storageSvc:      storageSvc,
+		minimumRepo:     minimumRepo,
 	}
 }
 
 func (s *inventoryService) Create(ctx context.Context, tenantID uuid.UUID, inventory *models.Inventory) error {
 	inventory.TenantID = tenantID
 	inventory.ID = uuid.New()
-	return s.inventoryRepo.Create(ctx, inventory)
+	if err := s.inventoryRepo.Create(ctx, inventory); err != nil {
+		return err
+	}
+	s.StockChanged(ctx, tenantID)
+	return nil
 }
 
 func (s *inventoryService) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Inventory, error) {
 	return s.inventoryRepo.GetByID(ctx, tenantID, id)
 }
 
+// GetByIDs loads the records in one query and warms the warehouse/product
+// cache that stock lookups read from
+func (s *inventoryService) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Inventory, error) {
+	inventories, err := s.inventoryRepo.GetByIDs(ctx, tenantID, ids)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get inventory records", err)
+	}
+
+	found := make(map[uuid.UUID]*models.Inventory, len(inventories))
+	for _, inventory := range inventories {
+		found[inventory.ID] = inventory
+		if cacheErr := s.cacheService.SetInventory(ctx, tenantID, inventory, 5*time.Minute); cacheErr != nil {
+			fmt.Printf("Failed to cache inventory %s: %v\n", inventory.ID.String(), cacheErr)
+		}
+	}
+
+	ordered := make([]*models.Inventory, 0, len(found))
+	for _, id := range ids {
+		if inventory, ok := found[id]; ok {
+			ordered = append(ordered, inventory)
+		}
+	}
+	return ordered, nil
+}
+
 func (s *inventoryService) Update(ctx context.Context, tenantID uuid.UUID, inventory *models.Inventory) error {
 	inventory.TenantID = tenantID
 
@@ -65,16 +110,45 @@ func (s *inventoryService) Update(ctx context.Context, tenantID uuid.UUID, inven
 	if cacheErr := s.cacheService.DeleteInventory(ctx, tenantID, inventory.WarehouseID, inventory.ProductID); cacheErr != nil {
 		fmt.Printf("Failed to invalidate cache for inventory %s-%s: %v\n", inventory.WarehouseID.String(), inventory.ProductID.String(), cacheErr)
 	}
+	s.StockChanged(ctx, tenantID)
 
 	return nil
 }
 
 func (s *inventoryService) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
-	return s.inventoryRepo.Delete(ctx, tenantID, id)
+	if err := s.inventoryRepo.Delete(ctx, tenantID, id); err != nil {
+		return err
+	}
+	s.StockChanged(ctx, tenantID)
+	return nil
+}
+
+func (s *inventoryService) StockChanged(ctx context.Context, tenantID uuid.UUID) {
+	caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventStockChanged)
+}
+
+func (s *inventoryService) List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Inventory, error) {
+	inventories, err := s.inventoryRepo.ListPage(ctx, tenantID, page)
+	if err != nil {
+		return nil, err
+	}
+	s.annotateMinimums(ctx, tenantID, inventories, s.minimumRepo.ListByTenant)
+	return inventories, nil
 }
 
-func (s *inventoryService) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Inventory, error) {
-	return s.inventoryRepo.List(ctx, tenantID, limit, offset)
+// annotateMinimums flags the records below their product's stock minimum. A
+// failure to load the minimums only loses the flags, not the listing
+func (s *inventoryService) annotateMinimums(ctx context.Context, tenantID uuid.UUID, inventories []*models.Inventory,
+	load func(ctx context.Context, tenantID uuid.UUID) ([]*models.StockMinimum, error)) {
+	if len(inventories) == 0 {
+		return
+	}
+	minimums, err := load(ctx, tenantID)
+	if err != nil {
+		fmt.Printf("Failed to load stock minimums for tenant %s: %v\n", tenantID.String(), err)
+		return
+	}
+	models.NewStockMinimums(minimums).Annotate(inventories)
 }
 
 func (s *inventoryService) Transfer(ctx context.Context, tenantID, productID, fromWarehouseID, toWarehouseID uuid.UUID, quantity int) error {
@@ -85,6 +159,10 @@ func (s *inventoryService) Transfer(ctx context.Context, tenantID, productID, fr
 	if fromInventory.Quantity < quantity {
 		return err // Insufficient stock
 	}
+	// The destination must offer the storage conditions the product needs
+	if err := s.storageSvc.CheckPlacement(ctx, tenantID, productID, toWarehouseID); err != nil {
+		return err
+	}
 	toInventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, tenantID, toWarehouseID, productID)
 	if err != nil {
 		// Create new inventory if not exists
@@ -107,6 +185,9 @@ func (s *inventoryService) Transfer(ctx context.Context, tenantID, productID, fr
 		return err
 	}
 
+	s.recordTransaction(ctx, fromInventory, -quantity, models.InventoryReasonTransferOut)
+	s.recordTransaction(ctx, toInventory, quantity, models.InventoryReasonTransferIn)
+
 	// Invalidate caches for both inventories
 	if cacheErr := s.cacheService.DeleteInventory(ctx, tenantID, fromWarehouseID, productID); cacheErr != nil {
 		fmt.Printf("Failed to invalidate cache for source inventory %s-%s: %v\n", fromWarehouseID.String(), productID.String(), cacheErr)
@@ -114,11 +195,17 @@ func (s *inventoryService) Transfer(ctx context.Context, tenantID, productID, fr
 	if cacheErr := s.cacheService.DeleteInventory(ctx, tenantID, toWarehouseID, productID); cacheErr != nil {
 		fmt.Printf("Failed to invalidate cache for destination inventory %s-%s: %v\n", toWarehouseID.String(), productID.String(), cacheErr)
 	}
+	s.StockChanged(ctx, tenantID)
 
 	return nil
 }
 
 func (s *inventoryService) AdjustStock(ctx context.Context, tenantID, warehouseID, productID uuid.UUID, quantityChange int) error {
+	return s.AdjustStockWithReason(ctx, tenantID, warehouseID, productID, quantityChange, models.InventoryReasonAdjustment)
+}
+
+// AdjustStockWithReason changes stock in one warehouse and records the movement in the ledger
+func (s *inventoryService) AdjustStockWithReason(ctx context.Context, tenantID, warehouseID, productID uuid.UUID, quantityChange int, reason string) error {
 	inventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, tenantID, warehouseID, productID)
 	if err != nil {
 		// Assume warehouse and product exist
@@ -134,6 +221,7 @@ func (s *inventoryService) AdjustStock(ctx context.Context, tenantID, warehouseI
 			return err
 		}
 	}
+	previous := inventory.Quantity
 	inventory.Quantity += quantityChange
 	if inventory.Quantity < 0 {
 		inventory.Quantity = 0 // Prevent negative
@@ -144,14 +232,54 @@ func (s *inventoryService) AdjustStock(ctx context.Context, tenantID, warehouseI
 		return err
 	}
 
+	s.recordTransaction(ctx, inventory, inventory.Quantity-previous, reason)
+
 	// Invalidate cache for this inventory
 	if cacheErr := s.cacheService.DeleteInventory(ctx, tenantID, warehouseID, productID); cacheErr != nil {
 		fmt.Printf("Failed to invalidate cache for adjusted inventory %s-%s: %v\n", warehouseID.String(), productID.String(), cacheErr)
 	}
+	s.StockChanged(ctx, tenantID)
 
 	return nil
 }
 
+// recordTransaction writes a ledger entry; ledger failures are logged rather than
+// failing a stock change that has already been applied
+func (s *inventoryService) recordTransaction(ctx context.Context, inventory *models.Inventory, applied int, reason string) {
+	if s.transactionRepo == nil || applied == 0 {
+		return
+	}
+
+	txn := &models.InventoryTransaction{
+		ID:             uuid.New(),
+		TenantID:       inventory.TenantID,
+		WarehouseID:    inventory.WarehouseID,
+		ProductID:      inventory.ProductID,
+		QuantityChange: applied,
+		QuantityAfter:  inventory.Quantity,
+		Reason:         reason,
+	}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		txn.CreatedBy = &userID
+	}
+
+	if err := s.transactionRepo.Create(ctx, txn); err != nil {
+		fmt.Printf("Failed to record inventory transaction for %s-%s: %v\n", inventory.WarehouseID.String(), inventory.ProductID.String(), err)
+	}
+}
+
+// GetProductStock returns a product's stock in every warehouse, largest first
+func (s *inventoryService) GetProductStock(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.Inventory, error) {
+	inventories, err := s.inventoryRepo.ListByProduct(ctx, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	s.annotateMinimums(ctx, tenantID, inventories, func(ctx context.Context, tenantID uuid.UUID) ([]*models.StockMinimum, error) {
+		return s.minimumRepo.ListByProduct(ctx, tenantID, productID)
+	})
+	return inventories, nil
+}
+
 func (s *inventoryService) LowStockAlerts(ctx context.Context, tenantID uuid.UUID, threshold int) ([]*models.Inventory, error) {
 	all, err := s.inventoryRepo.List(ctx, tenantID, 1000, 0) // Simplified
 	if err != nil {
@@ -190,7 +318,12 @@ func (s *inventoryService) GetByWarehouseAndProduct(ctx context.Context, tenantI
 }
 
 func (s *inventoryService) AdvancedSearch(ctx context.Context, tenantID uuid.UUID, filter *models.InventorySearchFilter) ([]*models.Inventory, error) {
-	return s.inventoryRepo.AdvancedSearch(ctx, tenantID, filter)
+	inventories, err := s.inventoryRepo.AdvancedSearch(ctx, tenantID, filter)
+	if err != nil {
+		return nil, err
+	}
+	s.annotateMinimums(ctx, tenantID, inventories, s.minimumRepo.ListByTenant)
+	return inventories, nil
 }
 
 // BulkAdjustStock performs bulk stock adjustments
@@ -309,6 +442,9 @@ func (s *inventoryService) BulkAdjustStock(ctx context.Context, tenantID uuid.UU
 	}
 	result.CompletionTime = &time.Time{}
 	*result.CompletionTime = time.Now()
+	if result.ProcessedItems > 0 {
+		s.StockChanged(ctx, tenantID)
+	}
 
 	return result, nil
 }
@@ -373,6 +509,23 @@ func (s *inventoryService) BulkTransferStock(ctx context.Context, tenantID uuid.
 			continue
 		}
 
+		if err := s.storageSvc.CheckPlacement(ctx, tenantID, transfer.ProductID, transfer.ToWarehouseID); err != nil {
+			result.FailedItems++
+			errorMsg := err.Error()
+			result.Errors = append(result.Errors, models.BulkOperationError{
+				ItemIndex: i,
+				ItemID:    fmt.Sprintf("%s-%s", transfer.ToWarehouseID.String(), transfer.ProductID.String()),
+				Error:     errorMsg,
+			})
+			result.Items = append(result.Items, models.BulkOperationItem{
+				ItemIndex: i,
+				ItemID:    fmt.Sprintf("%s-%s", transfer.ToWarehouseID.String(), transfer.ProductID.String()),
+				Status:    "failed",
+				Error:     &errorMsg,
+			})
+			continue
+		}
+
 		// Get or create destination inventory
 		toInventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, tenantID, transfer.ToWarehouseID, transfer.ProductID)
 		if err != nil {
@@ -450,6 +603,9 @@ func (s *inventoryService) BulkTransferStock(ctx context.Context, tenantID uuid.
 	}
 	result.CompletionTime = &time.Time{}
 	*result.CompletionTime = time.Now()
+	if result.ProcessedItems > 0 {
+		s.StockChanged(ctx, tenantID)
+	}
 
 	return result, nil
 }
\ No newline at end of file
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAmendmentInvoiceNotFound is returned for invoices the tenant does not have
+	ErrAmendmentInvoiceNotFound = errors.New("invoice not found")
+	// ErrInvalidInvoiceAmendment wraps amendment validation failures
+	ErrInvalidInvoiceAmendment = errors.New("invalid invoice amendment")
+	// ErrInvoiceAmendmentConflict is returned when the invoice changed while
+	// it was being amended
+	ErrInvoiceAmendmentConflict = errors.New("the invoice changed while it was being amended, try again")
+)
+
+// InvoiceAmendmentService corrects issued invoices by cancelling and
+// reissuing them. The original is settled by a credit note for its whole
+// amount, and the original, the credit note and the new invoice are linked
+// so the chain can be followed either way
+type InvoiceAmendmentService interface {
+	AmendInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID, amendedBy *uuid.UUID, req *models.InvoiceAmendmentRequest) (*models.InvoiceAmendment, error)
+	// ListAmendments lists the amendments in the chain the invoice is part of
+	ListAmendments(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceAmendment, error)
+}
+
+type invoiceAmendmentService struct {
+	repo            repositories.InvoiceAmendmentRepository
+	invoiceRepo     repositories.InvoiceRepository
+	withholdingRepo repositories.WithholdingTaxRepository
+	historyRepo     repositories.StatusHistoryRepository
+	calendars       TenantCalendarService
+}
+
+// NewInvoiceAmendmentService creates a new invoice amendment service
+func NewInvoiceAmendmentService(repo repositories.InvoiceAmendmentRepository, invoiceRepo repositories.InvoiceRepository, withholdingRepo repositories.WithholdingTaxRepository,
+	historyRepo repositories.StatusHistoryRepository, calendars TenantCalendarService) InvoiceAmendmentService {
+	return &invoiceAmendmentService{
+		repo:            repo,
+		invoiceRepo:     invoiceRepo,
+		withholdingRepo: withholdingRepo,
+		historyRepo:     historyRepo,
+		calendars:       calendars,
+	}
+}
+
+// AmendInvoice cancels an unpaid, overdue or paid sales invoice, issues a
+// credit note for it and a new invoice in its place. Within the original's
+// GSTR-1 period, the month it was issued in, the original is reported as
+// cancelled; once that period has passed it stays reported there and the
+// credit note reverses it in the current period
+func (s *invoiceAmendmentService) AmendInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID, amendedBy *uuid.UUID, req *models.InvoiceAmendmentRequest) (*models.InvoiceAmendment, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidInvoiceAmendment)
+	}
+
+	original, err := s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+	source, err := s.repo.Source(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+	if original == nil || source == nil {
+		return nil, ErrAmendmentInvoiceNotFound
+	}
+	switch {
+	case original.Status == "cancelled":
+		return nil, fmt.Errorf("%w: cancelled invoices cannot be amended", ErrInvalidInvoiceAmendment)
+	case source.DistributorID == nil:
+		return nil, fmt.Errorf("%w: only sales invoices to a customer can be amended", ErrInvalidInvoiceAmendment)
+	case source.Consolidated:
+		return nil, fmt.Errorf("%w: consolidated invoices cannot be amended", ErrInvalidInvoiceAmendment)
+	case source.Export:
+		return nil, fmt.Errorf("%w: export invoices cannot be amended", ErrInvalidInvoiceAmendment)
+	case original.TotalAmount <= 0:
+		return nil, fmt.Errorf("%w: the invoice has no amount to credit", ErrInvalidInvoiceAmendment)
+	}
+
+	now := time.Now()
+	today := TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(now)
+	invoice, err := reissuedInvoice(original, req)
+	if err != nil {
+		return nil, err
+	}
+	invoice.ID = uuid.New()
+	invoice.IssuedDate = today
+	invoice.DueDate = today.Add(original.DueDate.Sub(original.IssuedDate))
+	invoice.CreatedAt = now
+	invoice.UpdatedAt = now
+	if invoice.InvoiceNumber, err = s.invoiceRepo.GenerateInvoiceNumber(ctx, tenantID, invoice.BranchID, today); err != nil {
+		return nil, fmt.Errorf("failed to generate invoice number: %w", err)
+	}
+
+	amendment := &models.InvoiceAmendment{
+		ID:                    uuid.New(),
+		TenantID:              tenantID,
+		OriginalInvoiceID:     original.ID,
+		OriginalInvoiceNumber: original.InvoiceNumber,
+		NewInvoiceID:          invoice.ID,
+		NewInvoiceNumber:      invoice.InvoiceNumber,
+		Reason:                reason,
+		GSTTreatment:          amendmentGSTTreatment(original.IssuedDate, today),
+		OriginalPeriod:        time.Date(original.IssuedDate.Year(), original.IssuedDate.Month(), 1, 0, 0, 0, 0, time.UTC),
+		AmendedBy:             amendedBy,
+		NewInvoice:            invoice,
+	}
+
+	if s.withholdingRepo != nil {
+		section, err := s.withholdingRepo.ActiveTCSSection(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate TCS: %w", err)
+		}
+		if section != nil {
+			if amendment.TCS, err = customerTCS(ctx, s.withholdingRepo, section, invoice, *source.DistributorID); err != nil {
+				return nil, fmt.Errorf("failed to calculate TCS: %w", err)
+			}
+		}
+	}
+
+	noteReason := fmt.Sprintf("Amendment of %s: %s", original.InvoiceNumber, reason)
+	amendment.CreditNoteID = uuid.New()
+	amendment.CreditNoteNumber = fmt.Sprintf("CN-%s-%s", today.Format("200601"), strings.ToUpper(amendment.CreditNoteID.String()[:8]))
+	amendment.CreditNote = &models.CreditNote{
+		ID:               amendment.CreditNoteID,
+		TenantID:         tenantID,
+		DistributorID:    *source.DistributorID,
+		InvoiceID:        &original.ID,
+		CreditNoteNumber: amendment.CreditNoteNumber,
+		Amount:           roundAllocation(original.TotalAmount),
+		Reason:           &noteReason,
+		IssuedDate:       today,
+		CreatedBy:        amendedBy,
+	}
+
+	created, err := s.repo.Create(ctx, amendment, original.Status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to amend invoice: %w", err)
+	}
+	if !created {
+		return nil, ErrInvoiceAmendmentConflict
+	}
+	recordStatusChange(ctx, s.historyRepo, tenantID, models.StatusEntityInvoice, original.ID, original.Status, "cancelled")
+	recordStatusChange(ctx, s.historyRepo, tenantID, models.StatusEntityInvoice, invoice.ID, "", invoice.Status)
+	return amendment, nil
+}
+
+func (s *invoiceAmendmentService) ListAmendments(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.InvoiceAmendment, error) {
+	invoice, err := s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice: %w", err)
+	}
+	if invoice == nil {
+		return nil, ErrAmendmentInvoiceNotFound
+	}
+	amendments, err := s.repo.Chain(ctx, tenantID, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load amendments: %w", err)
+	}
+	return amendments, nil
+}
+
+// reissuedInvoice is the unpaid invoice replacing original, with the
+// request's changes and its GST worked out again
+func reissuedInvoice(original *models.Invoice, req *models.InvoiceAmendmentRequest) (*models.Invoice, error) {
+	taxable := original.TaxableAmount
+	if req.TaxableAmount != nil {
+		taxable = req.TaxableAmount
+	}
+	if taxable == nil || *taxable <= 0 {
+		return nil, fmt.Errorf("%w: taxable_amount must be positive", ErrInvalidInvoiceAmendment)
+	}
+	rate := original.GSTRate
+	if req.GSTRate != nil {
+		rate = req.GSTRate
+	}
+	if rate == nil || *rate < 0 || *rate > 100 {
+		return nil, fmt.Errorf("%w: gst_rate must be between 0 and 100", ErrInvalidInvoiceAmendment)
+	}
+	interstate := original.IGST != nil && *original.IGST > 0
+	if req.Interstate != nil {
+		interstate = *req.Interstate
+	}
+
+	invoice := &models.Invoice{
+		TenantID: original.TenantID,
+		OrderID:  original.OrderID,
+		BranchID: original.BranchID,
+		GSTIN:    original.GSTIN,
+		HSNSAC:   original.HSNSAC,
+		Status:   "unpaid",
+	}
+	if req.GSTIN != nil {
+		invoice.GSTIN = req.GSTIN
+	}
+	if req.HSNSAC != nil {
+		invoice.HSNSAC = req.HSNSAC
+	}
+
+	taxableAmount, gstRate := roundAllocation(*taxable), *rate
+	var cgst, sgst, igst float64
+	if interstate {
+		igst = roundAllocation(taxableAmount * gstRate / 100)
+	} else {
+		cgst = roundAllocation(taxableAmount * gstRate / 200)
+		sgst = cgst
+	}
+	invoice.TaxableAmount = &taxableAmount
+	invoice.GSTRate = &gstRate
+	invoice.CGST = &cgst
+	invoice.SGST = &sgst
+	invoice.IGST = &igst
+	invoice.TotalAmount = roundAllocation(taxableAmount + cgst + sgst + igst)
+	return invoice, nil
+}
+
+// amendmentGSTTreatment is how an amendment on day of an invoice issued on
+// issued is reported: within the month of issue the original has not been
+// filed yet and is cancelled, later it is reversed by the credit note
+func amendmentGSTTreatment(issued, day time.Time) string {
+	if issued.Year() == day.Year() && issued.Month() == day.Month() {
+		return models.AmendmentReissuedInPeriod
+	}
+	return models.AmendmentCreditNote
+}
@@ -2,12 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"agromart2/internal/analytics"
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
@@ -19,7 +21,7 @@ import (
 type InvoiceServiceInterface interface {
 	CreateInvoice(ctx context.Context, invoice *models.Invoice) error
 	GetInvoiceByID(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.Invoice, error)
-	ListInvoices(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Invoice, error)
+	ListInvoices(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Invoice, error)
 	UpdateInvoice(ctx context.Context, invoice *models.Invoice) error
 	DeleteInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) error
 	UpdateInvoiceStatus(ctx context.Context, tenantID, invoiceID uuid.UUID, status string) error
@@ -31,6 +33,20 @@ type InvoiceServiceInterface interface {
 	AutoGenerateInvoiceOnDelivery(ctx context.Context, tenantID, orderID uuid.UUID) error
 	MarkOverdueInvoices(ctx context.Context, tenantID uuid.UUID) error
 	CalculateInvoiceAnalytics(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) (*InvoiceAnalytics, error)
+	GSTReport(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, period time.Time) ([]*GSTINReport, error)
+}
+
+// GSTINReport is a month's GST documents issued under one GSTIN, with
+// their totals; GSTIN is nil for invoices issued before branches
+type GSTINReport struct {
+	BranchID      *uuid.UUID                  `json:"branch_id,omitempty"`
+	GSTIN         *string                     `json:"gstin"`
+	TaxableAmount float64                     `json:"taxable_amount"`
+	CGST          float64                     `json:"cgst"`
+	SGST          float64                     `json:"sgst"`
+	IGST          float64                     `json:"igst"`
+	TotalAmount   float64                     `json:"total_amount"`
+	Documents     []repositories.GSTReportRow `json:"documents"`
 }
 
 // InvoiceAnalytics holds invoice analytics data
@@ -50,16 +66,35 @@ type invoiceService struct {
 	orderRepo   repositories.OrderRepository
 	analyticsSvc *analytics.AnalyticsService
 	db          *pgxpool.Pool
+	notificationSvc NotificationService
+	withholdingRepo repositories.WithholdingTaxRepository
+	historyRepo     repositories.StatusHistoryRepository
+	calendars       TenantCalendarService
+	periods         PeriodCloseService
 }
 
 // NewInvoiceService creates a new invoice service
-func NewInvoiceService(invoiceRepo repositories.InvoiceRepository, orderRepo repositories.OrderRepository, analyticsSvc *analytics.AnalyticsService, db *pgxpool.Pool) InvoiceServiceInterface {
+func NewInvoiceService(invoiceRepo repositories.InvoiceRepository, orderRepo repositories.OrderRepository, analyticsSvc *analytics.AnalyticsService, db *pgxpool.Pool, notificationSvc NotificationService, withholdingRepo repositories.WithholdingTaxRepository, historyRepo repositories.StatusHistoryRepository, calendars TenantCalendarService, periods PeriodCloseService) InvoiceServiceInterface {
 	return &invoiceService{
 		invoiceRepo: invoiceRepo,
 		orderRepo:   orderRepo,
 		analyticsSvc: analyticsSvc,
 		db:          db,
+		notificationSvc: notificationSvc,
+		withholdingRepo: withholdingRepo,
+		historyRepo:     historyRepo,
+		calendars:       calendars,
+		periods:         periods,
+	}
+}
+
+// checkInvoicePeriod refuses invoices dated in a closed accounting period
+func (s *invoiceService) checkInvoicePeriod(ctx context.Context, invoice *models.Invoice) error {
+	err := checkPeriodOpen(ctx, s.periods, invoice.TenantID, invoice.BranchID, invoice.IssuedDate)
+	if err != nil && !errors.Is(err, ErrPeriodClosed) {
+		return common.SecureErrorMessage("check accounting period", err)
 	}
+	return err
 }
 
 // validateInvoiceFinancialData validates financial data in invoices
@@ -143,9 +178,27 @@ func (s *invoiceService) CreateInvoice(ctx context.Context, invoice *models.Invo
 	invoice.CreatedAt = time.Now()
 	invoice.UpdatedAt = time.Now()
 
+	// Date the invoice on the tenant's calendar day rather than the server's
+	if invoice.IssuedDate.IsZero() {
+		invoice.IssuedDate = TenantCalendarOrDefault(ctx, s.calendars, invoice.TenantID).Today(invoice.CreatedAt)
+	}
+
+	// The invoice is issued by the branch the order was placed through
+	order, err := s.orderRepo.GetByID(ctx, invoice.TenantID, invoice.OrderID)
+	if err != nil {
+		return common.SecureErrorMessage("load order", err)
+	}
+	invoice.BranchID = nil
+	if order != nil {
+		invoice.BranchID = order.BranchID
+	}
+	if err := s.checkInvoicePeriod(ctx, invoice); err != nil {
+		return err
+	}
+
 	// Generate invoice number if not provided
 	if invoice.InvoiceNumber == "" {
-		invoiceNumber, err := s.invoiceRepo.GenerateInvoiceNumber(ctx, invoice.TenantID, invoice.IssuedDate)
+		invoiceNumber, err := s.invoiceRepo.GenerateInvoiceNumber(ctx, invoice.TenantID, invoice.BranchID, invoice.IssuedDate)
 		if err != nil {
 			return common.SecureErrorMessage("generate invoice number", err)
 		}
@@ -157,9 +210,20 @@ func (s *invoiceService) CreateInvoice(ctx context.Context, invoice *models.Invo
 		invoice.DueDate = invoice.IssuedDate.AddDate(0, 0, 30) // 30 days from issued date
 	}
 
-	if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+	// Add TCS once the customer's sales this financial year cross the threshold
+	tcs, err := s.applyTCS(ctx, invoice)
+	if err != nil {
+		return common.SecureErrorMessage("calculate TCS", err)
+	}
+	if tcs != nil {
+		err = s.withholdingRepo.CreateInvoiceWithTCS(ctx, invoice, tcs)
+	} else {
+		err = s.invoiceRepo.Create(ctx, invoice)
+	}
+	if err != nil {
 		return common.SecureErrorMessage("create invoice", err)
 	}
+	recordStatusChange(ctx, s.historyRepo, invoice.TenantID, models.StatusEntityInvoice, invoice.ID, "", invoice.Status)
 
 	// Update analytics asynchronously
 	s.updateAnalytics(ctx, invoice.TenantID)
@@ -167,24 +231,145 @@ func (s *invoiceService) CreateInvoice(ctx context.Context, invoice *models.Invo
 	return nil
 }
 
+// applyTCS adds TCS under the tenant's active TCS section to a sales invoice
+// and returns it, or nil when none is due: no section is active, the invoice
+// is not to a customer or their sales are still under the threshold
+func (s *invoiceService) applyTCS(ctx context.Context, invoice *models.Invoice) (*models.InvoiceTCS, error) {
+	if s.withholdingRepo == nil {
+		return nil, nil
+	}
+	section, err := s.withholdingRepo.ActiveTCSSection(ctx, invoice.TenantID)
+	if err != nil || section == nil {
+		return nil, err
+	}
+	order, err := s.orderRepo.GetByID(ctx, invoice.TenantID, invoice.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.DistributorID == nil {
+		return nil, nil
+	}
+	return customerTCS(ctx, s.withholdingRepo, section, invoice, *order.DistributorID)
+}
+
+// customerTCS adds the TCS due under section on an invoice to a customer and
+// returns it, or nil when their sales are still under the threshold
+func customerTCS(ctx context.Context, withholdingRepo repositories.WithholdingTaxRepository, section *models.TaxSection, invoice *models.Invoice, distributorID uuid.UUID) (*models.InvoiceTCS, error) {
+	priorSales, err := withholdingRepo.CustomerSales(ctx, invoice.TenantID, distributorID, financialYearStart(invoice.IssuedDate), invoice.IssuedDate)
+	if err != nil {
+		return nil, err
+	}
+	base, amount := tcsOnSale(section, priorSales, invoice.TotalAmount)
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	invoice.TotalAmount = roundAllocation(invoice.TotalAmount + amount)
+	return &models.InvoiceTCS{
+		InvoiceID:     invoice.ID,
+		TenantID:      invoice.TenantID,
+		DistributorID: distributorID,
+		SectionID:     &section.ID,
+		Section:       section.Section,
+		RatePercent:   section.RatePercent,
+		BaseAmount:    base,
+		Amount:        amount,
+	}, nil
+}
+
 // GetInvoiceByID retrieves an invoice by ID
 func (s *invoiceService) GetInvoiceByID(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.Invoice, error) {
-	return s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	invoice, err := s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	if err != nil || invoice == nil || s.historyRepo == nil {
+		return invoice, err
+	}
+	if invoice.StatusHistory, err = s.historyRepo.ListByEntity(ctx, tenantID, models.StatusEntityInvoice, invoice.ID); err != nil {
+		return nil, err
+	}
+	return invoice, nil
 }
 
-// ListInvoices retrieves invoices with pagination
-func (s *invoiceService) ListInvoices(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Invoice, error) {
-	return s.invoiceRepo.List(ctx, tenantID, limit, offset)
+// ListInvoices retrieves invoices with pagination, optionally of one branch
+func (s *invoiceService) ListInvoices(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Invoice, error) {
+	return s.invoiceRepo.ListPage(ctx, tenantID, branchID, page)
+}
+
+// GSTReport lists the month's GST documents split by the GSTIN that issued
+// them, so each registration files its own return
+func (s *invoiceService) GSTReport(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, period time.Time) ([]*GSTINReport, error) {
+	from := time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	rows, err := s.invoiceRepo.GetGSTReportData(ctx, tenantID, branchID, from, to)
+	if err != nil {
+		return nil, common.SecureErrorMessage("load GST report", err)
+	}
+
+	reports := []*GSTINReport{}
+	byGSTIN := make(map[string]*GSTINReport)
+	for _, row := range rows {
+		key := common.SafeString(row.SellerGSTIN)
+		report, ok := byGSTIN[key]
+		if !ok {
+			report = &GSTINReport{BranchID: row.BranchID, GSTIN: row.SellerGSTIN}
+			byGSTIN[key] = report
+			reports = append(reports, report)
+		}
+		if row.TaxableAmount != nil {
+			report.TaxableAmount += *row.TaxableAmount
+		}
+		if row.CGST != nil {
+			report.CGST += *row.CGST
+		}
+		if row.SGST != nil {
+			report.SGST += *row.SGST
+		}
+		if row.IGST != nil {
+			report.IGST += *row.IGST
+		}
+		report.TotalAmount += row.TotalAmount
+		report.Documents = append(report.Documents, row)
+	}
+	for _, report := range reports {
+		report.TaxableAmount = roundAllocation(report.TaxableAmount)
+		report.CGST = roundAllocation(report.CGST)
+		report.SGST = roundAllocation(report.SGST)
+		report.IGST = roundAllocation(report.IGST)
+		report.TotalAmount = roundAllocation(report.TotalAmount)
+	}
+	return reports, nil
 }
 
 // UpdateInvoice updates an invoice
 func (s *invoiceService) UpdateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	// Neither the stored invoice nor its new date may be in a closed period
+	current, err := s.invoiceRepo.GetByID(ctx, invoice.TenantID, invoice.ID)
+	if err != nil {
+		return common.SecureErrorMessage("get invoice for update", err)
+	}
+	if current != nil {
+		if err := s.checkInvoicePeriod(ctx, current); err != nil {
+			return err
+		}
+	}
+	if err := s.checkInvoicePeriod(ctx, invoice); err != nil {
+		return err
+	}
+
 	invoice.UpdatedAt = time.Now()
 	return s.invoiceRepo.Update(ctx, invoice)
 }
 
 // DeleteInvoice deletes an invoice
 func (s *invoiceService) DeleteInvoice(ctx context.Context, tenantID, invoiceID uuid.UUID) error {
+	invoice, err := s.invoiceRepo.GetByID(ctx, tenantID, invoiceID)
+	if err != nil {
+		return common.SecureErrorMessage("get invoice for delete", err)
+	}
+	if invoice != nil {
+		if err := s.checkInvoicePeriod(ctx, invoice); err != nil {
+			return err
+		}
+	}
 	return s.invoiceRepo.Delete(ctx, tenantID, invoiceID)
 }
 
@@ -240,8 +425,16 @@ func (s *invoiceService) UpdateInvoiceStatus(ctx context.Context, tenantID, invo
 	if !s.isValidStatusTransition(invoice.Status, status) {
 		return fmt.Errorf("invalid status transition from %s to %s", invoice.Status, status)
 	}
+	// Cancelling reverses the sale, so it is locked with the period; payment
+	// and overdue changes follow events of the current period
+	if status == "cancelled" {
+		if err := s.checkInvoicePeriod(ctx, invoice); err != nil {
+			return err
+		}
+	}
 
 	// If changing to paid, set paid_date
+	previousStatus := invoice.Status
 	if status == "paid" {
 		now := time.Now()
 		invoice.Status = status
@@ -249,14 +442,22 @@ func (s *invoiceService) UpdateInvoiceStatus(ctx context.Context, tenantID, invo
 		invoice.UpdatedAt = now
 
 		if err := s.invoiceRepo.Update(ctx, invoice); err != nil {
+			if errors.Is(err, repositories.ErrPreconditionFailed) {
+				return err
+			}
 			return common.SecureErrorMessage("update invoice with paid date", err)
 		}
+		s.pushPaymentConfirmation(ctx, tenantID, invoice)
 	} else {
 		// For other statuses, just update status
 		if err := s.invoiceRepo.UpdateInvoiceStatus(ctx, tenantID, invoiceID, status); err != nil {
+			if errors.Is(err, repositories.ErrPreconditionFailed) {
+				return err
+			}
 			return common.SecureErrorMessage("update invoice status", err)
 		}
 	}
+	recordStatusChange(ctx, s.historyRepo, tenantID, models.StatusEntityInvoice, invoiceID, previousStatus, status)
 
 	// Update analytics asynchronously
 	s.updateAnalytics(ctx, tenantID)
@@ -377,9 +578,10 @@ func (s *invoiceService) AutoGenerateInvoiceOnDelivery(ctx context.Context, tena
 	// Calculate total with overflow protection
 	totalAmount := taxableAmount + cgst + sgst + igst
 
-	// Generate invoice number
-	issuedDate := time.Now()
-	invoiceNumber, err := s.invoiceRepo.GenerateInvoiceNumber(ctx, tenantID, issuedDate)
+	// Generate invoice number, dated on the tenant's calendar day
+	now := time.Now()
+	issuedDate := TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(now)
+	invoiceNumber, err := s.invoiceRepo.GenerateInvoiceNumber(ctx, tenantID, order.BranchID, issuedDate)
 	if err != nil {
 		return common.SecureErrorMessage("generate invoice number", err)
 	}
@@ -392,6 +594,7 @@ func (s *invoiceService) AutoGenerateInvoiceOnDelivery(ctx context.Context, tena
 		ID:             uuid.New(),
 		TenantID:       tenantID,
 		OrderID:        orderID,
+		BranchID:       order.BranchID,
 		InvoiceNumber:  invoiceNumber,
 		HSNSAC:         nil, // TODO: Get from product HSN/SAC code
 		TaxableAmount:  &taxableAmount,
@@ -403,8 +606,8 @@ func (s *invoiceService) AutoGenerateInvoiceOnDelivery(ctx context.Context, tena
 		Status:         "unpaid",
 		IssuedDate:     issuedDate,
 		DueDate:        dueDate,
-		CreatedAt:      issuedDate,
-		UpdatedAt:      issuedDate,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
 	return s.CreateInvoice(ctx, invoice)
@@ -421,8 +624,10 @@ func (s *invoiceService) MarkOverdueInvoices(ctx context.Context, tenantID uuid.
 		return common.SecureErrorMessage("retrieve invoices for overdue marking", err)
 	}
 
+	// An invoice is overdue once the tenant's calendar day is past its due date
+	today := TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(time.Now())
 	for _, invoice := range invoices {
-		if invoice.Status == "unpaid" && time.Now().After(invoice.DueDate) {
+		if invoice.Status == "unpaid" && today.After(invoice.DueDate) {
 			if err := s.UpdateInvoiceStatus(ctx, tenantID, invoice.ID, "overdue"); err != nil {
 				log.Printf("Failed to mark invoice %s as overdue: %v", invoice.ID, common.SecureErrorMessage("update overdue status", err))
 			}
@@ -500,4 +705,19 @@ func (s *invoiceService) updateAnalytics(ctx context.Context, tenantID uuid.UUID
 			log.Printf("Failed to update invoice analytics: %v", common.SecureErrorMessage("analytics update", err))
 		}
 	}()
-}
\ No newline at end of file
+}
+
+// pushPaymentConfirmation notifies payment subscribers that an invoice was paid
+func (s *invoiceService) pushPaymentConfirmation(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) {
+	if s.notificationSvc == nil {
+		return
+	}
+	msg := &models.PushMessage{
+		Title: "Payment received",
+		Body:  fmt.Sprintf("Invoice %s for ₹%.2f has been paid", invoice.InvoiceNumber, invoice.TotalAmount),
+		Data:  map[string]string{"event_type": "invoice_paid", "invoice_id": invoice.ID.String()},
+	}
+	if err := s.notificationSvc.SendPush(ctx, tenantID, models.PushTopicPayments, msg); err != nil {
+		fmt.Printf("Failed to push payment confirmation for invoice %s: %v\n", invoice.ID, err)
+	}
+}
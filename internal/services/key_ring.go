@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// reloadCooldown limits how often an unknown kid triggers a reload from the database
+const reloadCooldown = 30 * time.Second
+
+// DefaultKeyRefreshInterval is how stale a key ring may get before it reloads,
+// picking up keys rotated or revoked by other instances
+const DefaultKeyRefreshInterval = time.Minute
+
+// ErrSigningKeyNotFound is returned when revoking a key that does not exist
+// or is already revoked
+var ErrSigningKeyNotFound = errors.New("signing key not found")
+
+type ringKey struct {
+	kid        string
+	status     string
+	createdAt  time.Time
+	expiresAt  *time.Time
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// verifiable reports whether the key may still verify tokens: active, or
+// retiring within its verify window
+func (k *ringKey) verifiable(now time.Time) bool {
+	switch k.status {
+	case models.SigningKeyStatusActive:
+		return true
+	case models.SigningKeyStatusRetiring:
+		return k.expiresAt == nil || now.Before(*k.expiresAt)
+	}
+	return false
+}
+
+// KeyRing holds the EdDSA key pairs used to sign and verify access tokens.
+// Keys are persisted so every instance shares them and restarts keep sessions valid.
+type KeyRing struct {
+	repo            repositories.SigningKeyRepository
+	legacySecret    []byte        // Verifies HS256 tokens issued before the key ring existed
+	verifyWindow    time.Duration // How long a rotated key stays valid for verification
+	refreshInterval time.Duration // How often keys are reloaded from the database
+
+	mu         sync.RWMutex
+	active     *ringKey
+	keys       map[string]*ringKey
+	lastReload time.Time
+	refreshing atomic.Bool
+}
+
+// NewKeyRing loads persisted keys, generating the first key pair if none exist.
+// legacySecret may be empty; when set, HS256 tokens without a kid header are still accepted.
+// The keys are reloaded once they are older than refreshInterval.
+func NewKeyRing(ctx context.Context, repo repositories.SigningKeyRepository, legacySecret string, verifyWindow, refreshInterval time.Duration) (*KeyRing, error) {
+	kr := &KeyRing{
+		repo:            repo,
+		verifyWindow:    verifyWindow,
+		refreshInterval: refreshInterval,
+		keys:            make(map[string]*ringKey),
+	}
+	if legacySecret != "" {
+		kr.legacySecret = []byte(legacySecret)
+	}
+
+	if err := kr.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if kr.activeKey() == nil {
+		if _, err := kr.Rotate(ctx); err != nil {
+			return nil, fmt.Errorf("failed to create initial signing key: %w", err)
+		}
+	}
+	return kr, nil
+}
+
+// Reload refreshes the ring from the database so keys rotated by other instances are picked up
+func (kr *KeyRing) Reload(ctx context.Context) error {
+	stored, err := kr.repo.ListVerifiable(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	keys := make(map[string]*ringKey, len(stored))
+	var active *ringKey
+	for _, sk := range stored {
+		key, err := decodeSigningKey(sk)
+		if err != nil {
+			log.Printf("Skipping unreadable signing key %s: %v", sk.KID, err)
+			continue
+		}
+		keys[key.kid] = key
+		// Keys are ordered newest first, so the first active key signs
+		if active == nil && key.status == models.SigningKeyStatusActive {
+			active = key
+		}
+	}
+
+	kr.mu.Lock()
+	kr.keys = keys
+	kr.active = active
+	kr.lastReload = time.Now()
+	kr.mu.Unlock()
+	return nil
+}
+
+// Rotate generates a new active key and moves the previous one to retiring
+func (kr *KeyRing) Rotate(ctx context.Context) (*models.SigningKey, error) {
+	sk, err := generateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := kr.repo.Create(ctx, sk); err != nil {
+		return nil, fmt.Errorf("failed to persist signing key: %w", err)
+	}
+	if err := kr.repo.RetireActive(ctx, sk.KID, time.Now().Add(kr.verifyWindow)); err != nil {
+		return nil, fmt.Errorf("failed to retire previous signing key: %w", err)
+	}
+	if err := kr.Reload(ctx); err != nil {
+		return nil, err
+	}
+	log.Printf("JWT signing key rotated, new kid=%s", sk.KID)
+	return sk, nil
+}
+
+// Revoke stops a key verifying tokens at once here, and on other instances at
+// their next refresh. Revoking the signing key rotates first so tokens can
+// still be issued
+func (kr *KeyRing) Revoke(ctx context.Context, kid string) error {
+	if err := kr.Reload(ctx); err != nil {
+		return err
+	}
+	if active := kr.activeKey(); active != nil && active.kid == kid {
+		if _, err := kr.Rotate(ctx); err != nil {
+			return err
+		}
+	}
+	revoked, err := kr.repo.Revoke(ctx, kid)
+	if err != nil {
+		return fmt.Errorf("failed to revoke signing key: %w", err)
+	}
+	if !revoked {
+		return ErrSigningKeyNotFound
+	}
+	log.Printf("JWT signing key revoked, kid=%s", kid)
+	return kr.Reload(ctx)
+}
+
+// RotateIfOlderThan rotates when the active key is older than maxAge
+func (kr *KeyRing) RotateIfOlderThan(ctx context.Context, maxAge time.Duration) (bool, error) {
+	active := kr.activeKey()
+	if active != nil && time.Since(active.createdAt) < maxAge {
+		return false, nil
+	}
+	if _, err := kr.Rotate(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Sign signs claims with the active key and sets the kid header
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	active := kr.activeKey()
+	if active == nil {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.privateKey)
+}
+
+// Keyfunc resolves the verification key for a token from its kid header
+func (kr *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if kid == "" {
+		// Tokens minted with the old shared secret carry no kid
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && kr.legacySecret != nil {
+			return kr.legacySecret, nil
+		}
+		return nil, errors.New("token is missing kid header")
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kr.refreshIfStale()
+	if key := kr.lookup(kid); key != nil {
+		return key.publicKey, nil
+	}
+
+	// Another instance may have rotated; reload at most once per cooldown
+	kr.mu.RLock()
+	stale := time.Since(kr.lastReload) > reloadCooldown
+	kr.mu.RUnlock()
+	if stale {
+		if err := kr.Reload(context.Background()); err != nil {
+			return nil, err
+		}
+		if key := kr.lookup(kid); key != nil {
+			return key.publicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %s", kid)
+}
+
+// ValidMethods lists the algorithms accepted by Keyfunc
+func (kr *KeyRing) ValidMethods() []string {
+	methods := []string{jwt.SigningMethodEdDSA.Alg()}
+	if kr.legacySecret != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	return methods
+}
+
+// JWKS returns the public keys that other services may use to validate tokens
+func (kr *KeyRing) JWKS() *models.JSONWebKeySet {
+	kr.refreshIfStale()
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+	set := &models.JSONWebKeySet{Keys: make([]models.JSONWebKey, 0, len(kr.keys))}
+	for _, key := range kr.keys {
+		if !key.verifiable(now) {
+			continue
+		}
+		set.Keys = append(set.Keys, models.JSONWebKey{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.publicKey),
+			KeyID:     key.kid,
+			Use:       "sig",
+			Algorithm: jwt.SigningMethodEdDSA.Alg(),
+		})
+	}
+	return set
+}
+
+func (kr *KeyRing) activeKey() *ringKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.active
+}
+
+// lookup returns the key with kid while it may still verify tokens
+func (kr *KeyRing) lookup(kid string) *ringKey {
+	kr.mu.RLock()
+	key := kr.keys[kid]
+	kr.mu.RUnlock()
+	if key == nil || !key.verifiable(time.Now()) {
+		return nil
+	}
+	return key
+}
+
+// refreshIfStale reloads the keys once they are older than the refresh
+// interval. One caller reloads while the rest keep using the loaded keys,
+// which are also kept when the reload fails
+func (kr *KeyRing) refreshIfStale() {
+	kr.mu.RLock()
+	stale := time.Since(kr.lastReload) > kr.refreshInterval
+	kr.mu.RUnlock()
+	if !stale || !kr.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	defer kr.refreshing.Store(false)
+	if err := kr.Reload(context.Background()); err != nil {
+		log.Printf("Failed to refresh signing keys, keeping the loaded ones: %v", err)
+	}
+}
+
+// generateSigningKey creates a new Ed25519 key pair encoded for storage
+func generateSigningKey() (*models.SigningKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// kid is derived from the public key so it is stable and unique
+	sum := sha256.Sum256(publicKey)
+
+	return &models.SigningKey{
+		KID:        hex.EncodeToString(sum[:12]),
+		Algorithm:  jwt.SigningMethodEdDSA.Alg(),
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		Status:     models.SigningKeyStatusActive,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+func decodeSigningKey(sk *models.SigningKey) (*ringKey, error) {
+	block, _ := pem.Decode([]byte(sk.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	return &ringKey{
+		kid:        sk.KID,
+		status:     sk.Status,
+		createdAt:  sk.CreatedAt,
+		expiresAt:  sk.ExpiresAt,
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}, nil
+}
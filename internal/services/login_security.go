@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+)
+
+// Brute-force protection thresholds
+const (
+	maxAccountFailures   = 5
+	maxIPFailures        = 20
+	loginFailureWindow   = 15 * time.Minute
+	baseLockoutDuration  = 1 * time.Minute
+	maxLockoutDuration   = 24 * time.Hour
+	lockoutHistoryWindow = 24 * time.Hour
+	knownDeviceTTL       = 90 * 24 * time.Hour
+)
+
+// LoginLockedError is returned while an account or IP address is locked out
+type LoginLockedError struct {
+	Scope      string // "account" or "ip"
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts for %s, retry after %s", e.Scope, e.RetryAfter.Round(time.Second))
+}
+
+// CheckLoginAllowed returns a *LoginLockedError if the account or IP is currently locked
+func (s *authService) CheckLoginAllowed(ctx context.Context, email, ip string) error {
+	if err := s.checkLock(ctx, "account", normalizeLoginEmail(email)); err != nil {
+		return err
+	}
+	if ip != "" {
+		return s.checkLock(ctx, "ip", ip)
+	}
+	return nil
+}
+
+// RecordFailedLogin increments failure counters and applies exponential lockouts when thresholds are hit
+func (s *authService) RecordFailedLogin(ctx context.Context, email, ip string) error {
+	if err := s.recordFailure(ctx, "account", normalizeLoginEmail(email), maxAccountFailures); err != nil {
+		return err
+	}
+	if ip != "" {
+		return s.recordFailure(ctx, "ip", ip, maxIPFailures)
+	}
+	return nil
+}
+
+// RecordSuccessfulLogin clears the account's failure counter and notifies the user about logins from new devices
+func (s *authService) RecordSuccessfulLogin(ctx context.Context, user *models.User, ip, userAgent string) error {
+	if err := s.cacheSvc.Delete(ctx, loginFailuresKey("account", normalizeLoginEmail(user.Email))); err != nil {
+		log.Printf("Failed to reset login failures for %s: %v", user.Email, err)
+	}
+
+	fingerprint := DeviceFingerprint(userAgent)
+	deviceKey := fmt.Sprintf("known_device:%s:%s", user.ID.String(), fingerprint)
+	known, err := s.cacheSvc.GetString(ctx, deviceKey)
+	if err != nil {
+		return err
+	}
+	if err := s.cacheSvc.SetString(ctx, deviceKey, strconv.FormatInt(time.Now().Unix(), 10), knownDeviceTTL); err != nil {
+		return err
+	}
+	if known != "" || s.notificationSvc == nil {
+		return nil
+	}
+
+	subject := "New sign-in to your Agromart account"
+	body := fmt.Sprintf("We noticed a sign-in from a new device.\nTime: %s\nIP address: %s\nDevice: %s\n\nIf this wasn't you, reset your password and contact your administrator.",
+		time.Now().Format(time.RFC1123), ip, userAgent)
+	if err := s.notificationSvc.SendEmail(ctx, user.TenantID, user.Email, subject, body); err != nil {
+		log.Printf("Failed to send new device notification to %s: %v", user.Email, err)
+	}
+	return nil
+}
+
+// UnlockAccount clears lockout state and failure history for an account. IP
+// lockouts are left in place: they cover every account tried from the address,
+// not just this one, and lift when they expire
+func (s *authService) UnlockAccount(ctx context.Context, email string) error {
+	subject := normalizeLoginEmail(email)
+	for _, key := range []string{
+		loginLockKey("account", subject),
+		loginFailuresKey("account", subject),
+		loginLockoutsKey("account", subject),
+	} {
+		if err := s.cacheSvc.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to unlock account: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *authService) checkLock(ctx context.Context, scope, subject string) error {
+	value, err := s.cacheSvc.GetString(ctx, loginLockKey(scope, subject))
+	if err != nil || value == "" {
+		// Fail open on cache errors so Redis outages don't block every login
+		return nil
+	}
+	until, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	remaining := time.Until(time.Unix(until, 0))
+	if remaining <= 0 {
+		return nil
+	}
+	return &LoginLockedError{Scope: scope, RetryAfter: remaining}
+}
+
+func (s *authService) recordFailure(ctx context.Context, scope, subject string, threshold int) error {
+	failures, err := s.cacheSvc.IncrementCounter(ctx, loginFailuresKey(scope, subject), loginFailureWindow)
+	if err != nil {
+		return err
+	}
+	if failures < int64(threshold) {
+		return nil
+	}
+
+	lockouts, err := s.cacheSvc.IncrementCounter(ctx, loginLockoutsKey(scope, subject), lockoutHistoryWindow)
+	if err != nil {
+		return err
+	}
+	duration := LockoutDuration(int(lockouts))
+	until := time.Now().Add(duration).Unix()
+	if err := s.cacheSvc.SetString(ctx, loginLockKey(scope, subject), strconv.FormatInt(until, 10), duration); err != nil {
+		return err
+	}
+
+	// Start a fresh window after locking
+	if err := s.cacheSvc.Delete(ctx, loginFailuresKey(scope, subject)); err != nil {
+		return err
+	}
+	log.Printf("Login lockout applied: scope=%s subject=%s lockouts=%d duration=%s", scope, subject, lockouts, duration)
+	return nil
+}
+
+// LockoutDuration doubles the lockout for each consecutive lockout, capped at maxLockoutDuration
+func LockoutDuration(lockouts int) time.Duration {
+	if lockouts < 1 {
+		lockouts = 1
+	}
+	duration := baseLockoutDuration
+	for i := 1; i < lockouts; i++ {
+		duration *= 2
+		if duration >= maxLockoutDuration {
+			return maxLockoutDuration
+		}
+	}
+	return duration
+}
+
+// DeviceFingerprint derives a stable identifier for a device from its user agent
+func DeviceFingerprint(userAgent string) string {
+	normalized := strings.ToLower(strings.TrimSpace(userAgent))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginFailuresKey(scope, subject string) string {
+	return fmt.Sprintf("login_failures:%s:%s", scope, subject)
+}
+
+func loginLockKey(scope, subject string) string {
+	return fmt.Sprintf("login_lock:%s:%s", scope, subject)
+}
+
+func loginLockoutsKey(scope, subject string) string {
+	return fmt.Sprintf("login_lockouts:%s:%s", scope, subject)
+}
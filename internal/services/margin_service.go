@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// MarginViolationError is returned when a sales order is priced below the
+// product's cost plus the tenant's minimum margin and the order may not proceed
+type MarginViolationError struct {
+	ProductID        uuid.UUID
+	UnitPrice        float64
+	MinimumPrice     float64
+	MinMarginPercent float64
+	MarginPercent    float64
+	OverrideAllowed  bool
+}
+
+func (e *MarginViolationError) Error() string {
+	return fmt.Sprintf("unit price %.2f is below the minimum price %.2f (%.2f%% margin required, %.2f%% given)",
+		e.UnitPrice, e.MinimumPrice, e.MinMarginPercent, e.MarginPercent)
+}
+
+type MarginService interface {
+	GetPolicy(ctx context.Context, tenantID uuid.UUID) (*models.MarginPolicy, error)
+	SetPolicy(ctx context.Context, tenantID uuid.UUID, minMarginPercent float64, enforcement string) (*models.MarginPolicy, error)
+	DeletePolicy(ctx context.Context, tenantID uuid.UUID) error
+	CheckOrderPrice(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*models.MarginViolation, error)
+	RecordViolation(ctx context.Context, violation *models.MarginViolation) error
+	ListViolations(ctx context.Context, tenantID uuid.UUID, filter *models.MarginViolationFilter) ([]*models.MarginViolation, error)
+}
+
+type marginService struct {
+	marginRepo  repositories.MarginRepository
+	productRepo repositories.ProductRepository
+}
+
+func NewMarginService(marginRepo repositories.MarginRepository, productRepo repositories.ProductRepository) MarginService {
+	return &marginService{
+		marginRepo:  marginRepo,
+		productRepo: productRepo,
+	}
+}
+
+type marginOverrideKey struct{}
+
+// WithMarginOverride marks the context as allowed to price below the minimum
+// margin; callers must have checked the orders:override_margin permission
+func WithMarginOverride(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, marginOverrideKey{}, reason)
+}
+
+func (s *marginService) GetPolicy(ctx context.Context, tenantID uuid.UUID) (*models.MarginPolicy, error) {
+	return s.marginRepo.GetPolicy(ctx, tenantID)
+}
+
+func (s *marginService) SetPolicy(ctx context.Context, tenantID uuid.UUID, minMarginPercent float64, enforcement string) (*models.MarginPolicy, error) {
+	if minMarginPercent < 0 || minMarginPercent > 999 {
+		return nil, fmt.Errorf("min_margin_percent must be between 0 and 999")
+	}
+	if enforcement == "" {
+		enforcement = models.MarginEnforcementRequireOverride
+	}
+	if enforcement != models.MarginEnforcementBlock && enforcement != models.MarginEnforcementRequireOverride {
+		return nil, fmt.Errorf("enforcement must be %s or %s", models.MarginEnforcementBlock, models.MarginEnforcementRequireOverride)
+	}
+
+	policy := &models.MarginPolicy{
+		TenantID:         tenantID,
+		MinMarginPercent: minMarginPercent,
+		Enforcement:      enforcement,
+	}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		policy.UpdatedBy = &userID
+	}
+
+	if err := s.marginRepo.UpsertPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *marginService) DeletePolicy(ctx context.Context, tenantID uuid.UUID) error {
+	return s.marginRepo.DeletePolicy(ctx, tenantID)
+}
+
+// CheckOrderPrice validates a sales order's unit price against cost plus the
+// tenant's minimum margin. Blocked attempts are recorded here and returned as a
+// *MarginViolationError; an overridden violation is returned for the caller to
+// record once the order has been saved.
+func (s *marginService) CheckOrderPrice(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*models.MarginViolation, error) {
+	if order.OrderType != "sales" {
+		return nil, nil
+	}
+
+	policy, err := s.marginRepo.GetPolicy(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load margin policy: %w", err)
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	product, err := s.productRepo.GetByID(ctx, tenantID, order.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+	// Products without a cost cannot be checked
+	if product.CostPrice == nil || *product.CostPrice <= 0 {
+		return nil, nil
+	}
+
+	cost := *product.CostPrice
+	minimumPrice := math.Round(cost*(1+policy.MinMarginPercent/100)*100) / 100
+	if order.UnitPrice >= minimumPrice {
+		return nil, nil
+	}
+
+	violation := &models.MarginViolation{
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		ProductID:        order.ProductID,
+		UnitPrice:        order.UnitPrice,
+		CostPrice:        cost,
+		MinMarginPercent: policy.MinMarginPercent,
+		MarginPercent:    math.Round((order.UnitPrice-cost)/cost*10000) / 100,
+	}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		violation.UserID = &userID
+	}
+
+	reason, overridden := ctx.Value(marginOverrideKey{}).(string)
+	if overridden && policy.Enforcement == models.MarginEnforcementRequireOverride {
+		orderID := order.ID
+		violation.OrderID = &orderID
+		violation.Outcome = models.MarginOutcomeOverridden
+		if strings.TrimSpace(reason) != "" {
+			violation.OverrideReason = &reason
+		}
+		return violation, nil
+	}
+
+	violation.Outcome = models.MarginOutcomeBlocked
+	if err := s.marginRepo.CreateViolation(ctx, violation); err != nil {
+		fmt.Printf("Failed to record margin violation for product %s: %v\n", order.ProductID.String(), err)
+	}
+
+	return nil, &MarginViolationError{
+		ProductID:        order.ProductID,
+		UnitPrice:        order.UnitPrice,
+		MinimumPrice:     minimumPrice,
+		MinMarginPercent: policy.MinMarginPercent,
+		MarginPercent:    violation.MarginPercent,
+		OverrideAllowed:  policy.Enforcement == models.MarginEnforcementRequireOverride,
+	}
+}
+
+func (s *marginService) RecordViolation(ctx context.Context, violation *models.MarginViolation) error {
+	return s.marginRepo.CreateViolation(ctx, violation)
+}
+
+func (s *marginService) ListViolations(ctx context.Context, tenantID uuid.UUID, filter *models.MarginViolationFilter) ([]*models.MarginViolation, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.marginRepo.ListViolations(ctx, tenantID, filter)
+}
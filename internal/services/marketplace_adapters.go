@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MarketplaceAdapter converts between a marketplace's wire format and ours
+type MarketplaceAdapter interface {
+	ParseOrder(body []byte, channel *models.MarketplaceChannel) (*models.ExternalOrder, error)
+	StatusPayload(link *models.MarketplaceOrderLink, status string) map[string]interface{}
+}
+
+// marketplaceAdapterFor returns the adapter for a channel protocol
+func marketplaceAdapterFor(protocol string) (MarketplaceAdapter, error) {
+	switch protocol {
+	case models.MarketplaceProtocolONDC:
+		return ondcAdapter{}, nil
+	case models.MarketplaceProtocolGeneric:
+		return genericAdapter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported marketplace protocol: %s", protocol)
+	}
+}
+
+// ondcAdapter handles ONDC confirm requests and on_status callbacks
+type ondcAdapter struct{}
+
+type ondcConfirmRequest struct {
+	Context map[string]interface{} `json:"context"`
+	Message struct {
+		Order struct {
+			ID    string `json:"id"`
+			Items []struct {
+				ID       string `json:"id"`
+				Quantity struct {
+					Count int `json:"count"`
+				} `json:"quantity"`
+				Price *struct {
+					Value json.Number `json:"value"`
+				} `json:"price"`
+			} `json:"items"`
+		} `json:"order"`
+	} `json:"message"`
+}
+
+// ondcStates maps our order status to the ONDC order state and fulfillment state
+var ondcStates = map[string][2]string{
+	"pending":    {"Created", "Pending"},
+	"approved":   {"Accepted", "Pending"},
+	"processing": {"In-progress", "Packed"},
+	// Still packed as far as the buyer is concerned
+	"quality_check": {"In-progress", "Packed"},
+	"shipped":       {"In-progress", "Order-picked-up"},
+	"delivered":     {"Completed", "Order-delivered"},
+	"cancelled":     {"Cancelled", "Cancelled"},
+}
+
+func (ondcAdapter) ParseOrder(body []byte, channel *models.MarketplaceChannel) (*models.ExternalOrder, error) {
+	var req ondcConfirmRequest
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, fmt.Errorf("invalid ONDC payload: %w", err)
+	}
+
+	if action, _ := req.Context["action"].(string); action != "" && action != "confirm" {
+		return nil, fmt.Errorf("unsupported ONDC action: %s", action)
+	}
+
+	order := &models.ExternalOrder{
+		ExternalOrderID: strings.TrimSpace(req.Message.Order.ID),
+		Context:         req.Context,
+	}
+	for _, item := range req.Message.Order.Items {
+		parsed := models.ExternalOrderItem{
+			ExternalItemID: item.ID,
+			SKU:            item.ID,
+			Quantity:       item.Quantity.Count,
+		}
+		if item.Price != nil && item.Price.Value != "" {
+			price, err := item.Price.Value.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid price for item %s", item.ID)
+			}
+			parsed.UnitPrice = &price
+		}
+		order.Items = append(order.Items, parsed)
+	}
+	return order, nil
+}
+
+func (ondcAdapter) StatusPayload(link *models.MarketplaceOrderLink, status string) map[string]interface{} {
+	states, ok := ondcStates[status]
+	if !ok {
+		states = [2]string{"In-progress", "Pending"}
+	}
+
+	// Echo the identifiers from the original confirm so the buyer app can correlate
+	callbackContext := map[string]interface{}{}
+	for _, key := range []string{"domain", "country", "city", "core_version", "bap_id", "bap_uri", "bpp_id", "bpp_uri", "transaction_id"} {
+		if value, ok := link.ExternalContext[key]; ok {
+			callbackContext[key] = value
+		}
+	}
+	callbackContext["action"] = "on_status"
+	callbackContext["message_id"] = uuid.New().String()
+	callbackContext["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+
+	return map[string]interface{}{
+		"context": callbackContext,
+		"message": map[string]interface{}{
+			"order": map[string]interface{}{
+				"id":    link.ExternalOrderID,
+				"state": states[0],
+				"items": []map[string]interface{}{{"id": link.ExternalItemID}},
+				"fulfillments": []map[string]interface{}{{
+					"state": map[string]interface{}{
+						"descriptor": map[string]interface{}{"code": states[1]},
+					},
+				}},
+				"updated_at": time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	}
+}
+
+// genericAdapter reads arbitrary JSON using the channel's field mapping
+type genericAdapter struct{}
+
+func (genericAdapter) ParseOrder(body []byte, channel *models.MarketplaceChannel) (*models.ExternalOrder, error) {
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid order payload: %w", err)
+	}
+
+	mapping := channel.FieldMapping.WithDefaults()
+	order := &models.ExternalOrder{}
+
+	orderID, ok := lookupJSONPath(payload, mapping.OrderID)
+	if !ok {
+		return nil, fmt.Errorf("order ID not found at %q", mapping.OrderID)
+	}
+	order.ExternalOrderID = strings.TrimSpace(jsonString(orderID))
+
+	if note, ok := lookupJSONPath(payload, mapping.Note); ok {
+		if s := jsonString(note); s != "" {
+			order.Note = &s
+		}
+	}
+
+	rawItems, _ := lookupJSONPath(payload, mapping.Items)
+	items, ok := rawItems.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items not found at %q", mapping.Items)
+	}
+
+	for i, raw := range items {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("item %d is not an object", i)
+		}
+
+		parsed := models.ExternalOrderItem{}
+		if sku, ok := lookupJSONPath(item, mapping.SKU); ok {
+			parsed.SKU = jsonString(sku)
+		}
+		if id, ok := lookupJSONPath(item, mapping.ItemID); ok {
+			parsed.ExternalItemID = jsonString(id)
+		}
+		if parsed.ExternalItemID == "" {
+			parsed.ExternalItemID = parsed.SKU
+		}
+
+		quantity, ok := lookupJSONPath(item, mapping.Quantity)
+		if !ok {
+			return nil, fmt.Errorf("quantity not found for item %d", i)
+		}
+		qty, err := jsonFloat(quantity)
+		if err != nil || qty != float64(int(qty)) {
+			return nil, fmt.Errorf("invalid quantity for item %d", i)
+		}
+		parsed.Quantity = int(qty)
+
+		if price, ok := lookupJSONPath(item, mapping.UnitPrice); ok {
+			value, err := jsonFloat(price)
+			if err != nil {
+				return nil, fmt.Errorf("invalid unit price for item %d", i)
+			}
+			parsed.UnitPrice = &value
+		}
+
+		order.Items = append(order.Items, parsed)
+	}
+	return order, nil
+}
+
+func (genericAdapter) StatusPayload(link *models.MarketplaceOrderLink, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"event":             "order.status_changed",
+		"external_order_id": link.ExternalOrderID,
+		"external_item_id":  link.ExternalItemID,
+		"order_id":          link.OrderID,
+		"status":            status,
+		"updated_at":        time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// lookupJSONPath walks a dot-separated path through nested objects
+func lookupJSONPath(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[key]
+		if !ok || current == nil {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jsonFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("not a number")
+	}
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// marketplaceStatusBatch bounds status pushes per tenant per sync run
+const marketplaceStatusBatch = 200
+
+// MarketplaceService ingests external marketplace orders and syncs their status back
+type MarketplaceService interface {
+	CreateChannel(ctx context.Context, channel *models.MarketplaceChannel) (string, error)
+	GetChannel(ctx context.Context, tenantID, id uuid.UUID) (*models.MarketplaceChannel, error)
+	ListChannels(ctx context.Context, tenantID uuid.UUID) ([]*models.MarketplaceChannel, error)
+	UpdateChannel(ctx context.Context, channel *models.MarketplaceChannel) error
+	DeleteChannel(ctx context.Context, tenantID, id uuid.UUID) error
+	ListOrderLinks(ctx context.Context, tenantID uuid.UUID, channelID *uuid.UUID, limit, offset int) ([]*models.MarketplaceOrderLink, error)
+	AuthenticateChannel(ctx context.Context, channelID uuid.UUID, body []byte, signature string) (*models.MarketplaceChannel, error)
+	IngestOrder(ctx context.Context, channel *models.MarketplaceChannel, body []byte) (*models.MarketplaceIngestResult, error)
+	SyncStatuses(ctx context.Context, tenantID uuid.UUID) (int, error)
+}
+
+type marketplaceService struct {
+	marketplaceRepo repositories.MarketplaceRepository
+	productRepo     repositories.ProductRepository
+	warehouseRepo   repositories.WarehouseRepository
+	distributorRepo repositories.DistributorRepository
+	orderService    OrderServiceInterface
+	httpClient      *http.Client
+}
+
+// NewMarketplaceService creates a new marketplace service
+func NewMarketplaceService(marketplaceRepo repositories.MarketplaceRepository, productRepo repositories.ProductRepository,
+	warehouseRepo repositories.WarehouseRepository, distributorRepo repositories.DistributorRepository,
+	orderService OrderServiceInterface) MarketplaceService {
+	return &marketplaceService{
+		marketplaceRepo: marketplaceRepo,
+		productRepo:     productRepo,
+		warehouseRepo:   warehouseRepo,
+		distributorRepo: distributorRepo,
+		orderService:    orderService,
+		httpClient:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// signMarketplacePayload returns the hex HMAC-SHA256 of body under secret
+func signMarketplacePayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *marketplaceService) validateChannel(ctx context.Context, channel *models.MarketplaceChannel) error {
+	channel.Name = strings.TrimSpace(channel.Name)
+	if channel.Name == "" {
+		return fmt.Errorf("channel name is required")
+	}
+	if _, err := marketplaceAdapterFor(channel.Protocol); err != nil {
+		return err
+	}
+	if channel.StatusWebhookURL != nil {
+		parsed, err := url.Parse(*channel.StatusWebhookURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("status_webhook_url must be an http(s) URL")
+		}
+	}
+	if _, err := s.warehouseRepo.GetByID(ctx, channel.TenantID, channel.WarehouseID); err != nil {
+		return fmt.Errorf("warehouse not found")
+	}
+	if _, err := s.distributorRepo.GetByID(ctx, channel.TenantID, channel.DistributorID); err != nil {
+		return fmt.Errorf("distributor not found")
+	}
+	return nil
+}
+
+// CreateChannel stores a channel and returns its signing secret, which is
+// shown only once and used for both inbound and outbound signatures
+func (s *marketplaceService) CreateChannel(ctx context.Context, channel *models.MarketplaceChannel) (string, error) {
+	if err := s.validateChannel(ctx, channel); err != nil {
+		return "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate signing secret: %w", err)
+	}
+
+	channel.ID = uuid.New()
+	channel.SigningSecret = hex.EncodeToString(secret)
+	channel.IsActive = true
+	if err := s.marketplaceRepo.CreateChannel(ctx, channel); err != nil {
+		return "", err
+	}
+	return channel.SigningSecret, nil
+}
+
+func (s *marketplaceService) GetChannel(ctx context.Context, tenantID, id uuid.UUID) (*models.MarketplaceChannel, error) {
+	return s.marketplaceRepo.GetChannel(ctx, tenantID, id)
+}
+
+func (s *marketplaceService) ListChannels(ctx context.Context, tenantID uuid.UUID) ([]*models.MarketplaceChannel, error) {
+	return s.marketplaceRepo.ListChannels(ctx, tenantID)
+}
+
+func (s *marketplaceService) UpdateChannel(ctx context.Context, channel *models.MarketplaceChannel) error {
+	if err := s.validateChannel(ctx, channel); err != nil {
+		return err
+	}
+	return s.marketplaceRepo.UpdateChannel(ctx, channel)
+}
+
+func (s *marketplaceService) DeleteChannel(ctx context.Context, tenantID, id uuid.UUID) error {
+	return s.marketplaceRepo.DeleteChannel(ctx, tenantID, id)
+}
+
+func (s *marketplaceService) ListOrderLinks(ctx context.Context, tenantID uuid.UUID, channelID *uuid.UUID, limit, offset int) ([]*models.MarketplaceOrderLink, error) {
+	return s.marketplaceRepo.ListLinks(ctx, tenantID, channelID, limit, offset)
+}
+
+// AuthenticateChannel verifies an inbound request against the channel's signing secret
+func (s *marketplaceService) AuthenticateChannel(ctx context.Context, channelID uuid.UUID, body []byte, signature string) (*models.MarketplaceChannel, error) {
+	channel, err := s.marketplaceRepo.GetChannelByID(ctx, channelID)
+	if err != nil || !channel.IsActive {
+		return nil, fmt.Errorf("invalid marketplace channel")
+	}
+	expected := signMarketplacePayload(channel.SigningSecret, body)
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return nil, fmt.Errorf("invalid marketplace signature")
+	}
+	return channel, nil
+}
+
+// resolveProduct matches an external SKU to a product ID or barcode
+func (s *marketplaceService) resolveProduct(ctx context.Context, tenantID uuid.UUID, sku string) (*models.Product, error) {
+	if id, err := uuid.Parse(sku); err == nil {
+		if product, err := s.productRepo.GetByID(ctx, tenantID, id); err == nil && product != nil {
+			return product, nil
+		}
+	}
+	product, err := s.productRepo.GetByBarcode(ctx, tenantID, sku)
+	if err != nil || product == nil {
+		return nil, fmt.Errorf("no product matches SKU %q", sku)
+	}
+	return product, nil
+}
+
+// IngestOrder creates one sales order per external line. Lines are reserved
+// by external ID first, so a replayed request returns the existing orders
+// instead of creating duplicates; a failure rolls back the whole order.
+func (s *marketplaceService) IngestOrder(ctx context.Context, channel *models.MarketplaceChannel, body []byte) (*models.MarketplaceIngestResult, error) {
+	adapter, err := marketplaceAdapterFor(channel.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	external, err := adapter.ParseOrder(body, channel)
+	if err != nil {
+		return nil, err
+	}
+	if external.ExternalOrderID == "" {
+		return nil, fmt.Errorf("external order ID is required")
+	}
+	if len(external.Items) == 0 {
+		return nil, fmt.Errorf("order has no items")
+	}
+
+	seen := make(map[string]bool, len(external.Items))
+	orders := make([]*models.Order, len(external.Items))
+	for i, item := range external.Items {
+		if item.ExternalItemID == "" {
+			return nil, fmt.Errorf("item %d has no ID", i)
+		}
+		if seen[item.ExternalItemID] {
+			return nil, fmt.Errorf("duplicate item ID %s", item.ExternalItemID)
+		}
+		seen[item.ExternalItemID] = true
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity for item %s must be positive", item.ExternalItemID)
+		}
+
+		product, err := s.resolveProduct(ctx, channel.TenantID, item.SKU)
+		if err != nil {
+			return nil, err
+		}
+
+		unitPrice := product.UnitPrice
+		if item.UnitPrice != nil {
+			unitPrice = *item.UnitPrice
+		}
+		notes := fmt.Sprintf("%s order %s", channel.Name, external.ExternalOrderID)
+		if external.Note != nil {
+			notes += ": " + *external.Note
+		}
+		distributorID := channel.DistributorID
+		orders[i] = &models.Order{
+			TenantID:      channel.TenantID,
+			OrderType:     "sales",
+			DistributorID: &distributorID,
+			ProductID:     product.ID,
+			WarehouseID:   channel.WarehouseID,
+			Quantity:      item.Quantity,
+			UnitPrice:     unitPrice,
+			Notes:         &notes,
+		}
+	}
+
+	result := &models.MarketplaceIngestResult{ExternalOrderID: external.ExternalOrderID}
+	var reserved []*models.MarketplaceOrderLink
+	var created []uuid.UUID
+
+	rollback := func() {
+		for _, orderID := range created {
+			if err := s.orderService.DeleteOrder(ctx, channel.TenantID, orderID); err != nil {
+				fmt.Printf("Failed to roll back marketplace order %s: %v\n", orderID, err)
+			}
+		}
+		for _, link := range reserved {
+			if err := s.marketplaceRepo.ReleaseLink(ctx, link.ID); err != nil {
+				fmt.Printf("Failed to release marketplace order link %s: %v\n", link.ID, err)
+			}
+		}
+	}
+
+	for i, item := range external.Items {
+		link := &models.MarketplaceOrderLink{
+			ID:              uuid.New(),
+			TenantID:        channel.TenantID,
+			ChannelID:       channel.ID,
+			ExternalOrderID: external.ExternalOrderID,
+			ExternalItemID:  item.ExternalItemID,
+			ExternalContext: external.Context,
+		}
+		ok, err := s.marketplaceRepo.ReserveLink(ctx, link)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		if !ok {
+			// Already ingested: undo anything reserved by this request and
+			// report what the original delivery created
+			rollback()
+			links, err := s.marketplaceRepo.ListLinksByExternalOrder(ctx, channel.ID, external.ExternalOrderID)
+			if err != nil {
+				return nil, err
+			}
+			result.Duplicate = true
+			result.Links = links
+			return result, nil
+		}
+		reserved = append(reserved, link)
+
+		if err := s.orderService.CreateOrder(ctx, channel.TenantID, orders[i]); err != nil {
+			rollback()
+			return nil, fmt.Errorf("item %s: %w", item.ExternalItemID, err)
+		}
+		created = append(created, orders[i].ID)
+
+		if err := s.marketplaceRepo.SetLinkOrder(ctx, link.ID, orders[i].ID); err != nil {
+			rollback()
+			return nil, err
+		}
+		orderID := orders[i].ID
+		status := orders[i].Status
+		link.OrderID = &orderID
+		link.OrderStatus = &status
+	}
+
+	result.Links = reserved
+	return result, nil
+}
+
+// SyncStatuses pushes order status changes to each channel's webhook and
+// returns the number delivered; failed pushes are retried on the next run
+func (s *marketplaceService) SyncStatuses(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	links, err := s.marketplaceRepo.ListPendingStatusPushes(ctx, tenantID, marketplaceStatusBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	channels := make(map[uuid.UUID]*models.MarketplaceChannel)
+	pushed := 0
+	for _, link := range links {
+		if link.OrderStatus == nil {
+			continue
+		}
+
+		channel, ok := channels[link.ChannelID]
+		if !ok {
+			channel, err = s.marketplaceRepo.GetChannel(ctx, tenantID, link.ChannelID)
+			if err != nil {
+				fmt.Printf("Failed to load marketplace channel %s: %v\n", link.ChannelID, err)
+				continue
+			}
+			channels[link.ChannelID] = channel
+		}
+
+		if err := s.pushStatus(ctx, channel, link, *link.OrderStatus); err != nil {
+			fmt.Printf("Failed to push status for marketplace order %s/%s: %v\n", link.ExternalOrderID, link.ExternalItemID, err)
+			continue
+		}
+		if err := s.marketplaceRepo.MarkStatusPushed(ctx, link.ID, *link.OrderStatus); err != nil {
+			fmt.Printf("Failed to record status push for link %s: %v\n", link.ID, err)
+			continue
+		}
+		pushed++
+	}
+	return pushed, nil
+}
+
+func (s *marketplaceService) pushStatus(ctx context.Context, channel *models.MarketplaceChannel, link *models.MarketplaceOrderLink, status string) error {
+	if channel.StatusWebhookURL == nil {
+		return fmt.Errorf("channel has no status webhook")
+	}
+	adapter, err := marketplaceAdapterFor(channel.Protocol)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(adapter.StatusPayload(link, status))
+	if err != nil {
+		return fmt.Errorf("failed to marshal status payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *channel.StatusWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create status request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Marketplace-Signature", signMarketplacePayload(channel.SigningSecret, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("status webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"agromart2/internal/common"
+)
+
+// Rules shared by the supplier, distributor and warehouse services
+var (
+	// ErrDuplicateName is returned when another active record already uses the name
+	ErrDuplicateName = errors.New("name already in use")
+	// ErrInvalidGSTIN wraps GSTIN format and check character failures
+	ErrInvalidGSTIN = errors.New("invalid GSTIN")
+	// ErrInvalidBankAccount is returned for a malformed account number or IFSC,
+	// or when only one of them is given
+	ErrInvalidBankAccount = errors.New("invalid bank account")
+)
+
+var (
+	bankAccountNumberPattern = regexp.MustCompile(`^[0-9]{9,18}$`)
+	ifscPattern              = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+)
+
+// normalizeGSTIN uppercases and validates an optional GSTIN, clearing it when blank
+func normalizeGSTIN(gstin **string) error {
+	if *gstin == nil {
+		return nil
+	}
+	value := strings.ToUpper(strings.TrimSpace(**gstin))
+	if value == "" {
+		*gstin = nil
+		return nil
+	}
+	if err := common.ValidateGSTIN(value, "gstin"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidGSTIN, err)
+	}
+	if err := common.ValidateGSTINChecksum(value, "gstin"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidGSTIN, err)
+	}
+	*gstin = &value
+	return nil
+}
+
+// normalizeBankAccount strips spaces from an optional account number and
+// uppercases the IFSC, clearing blank ones; the two go together
+func normalizeBankAccount(number, ifsc **string) error {
+	for _, field := range []**string{number, ifsc} {
+		if *field == nil {
+			continue
+		}
+		value := strings.ToUpper(strings.Join(strings.Fields(**field), ""))
+		if value == "" {
+			*field = nil
+			continue
+		}
+		*field = &value
+	}
+	if (*number == nil) != (*ifsc == nil) {
+		return fmt.Errorf("%w: bank_account_number and bank_ifsc must be given together", ErrInvalidBankAccount)
+	}
+	if *number == nil {
+		return nil
+	}
+	if !bankAccountNumberPattern.MatchString(**number) {
+		return fmt.Errorf("%w: bank_account_number must be 9 to 18 digits", ErrInvalidBankAccount)
+	}
+	if !ifscPattern.MatchString(**ifsc) {
+		return fmt.Errorf("%w: bank_ifsc has invalid IFSC format", ErrInvalidBankAccount)
+	}
+	return nil
+}
@@ -11,9 +11,13 @@ import (
 
 type MinioService interface {
 	UploadImage(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64) error
+	UploadObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, contentType string) error
+	GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error)
 	GetPresignedURL(bucketName, objectName string, expiry time.Duration) (string, error)
 	DeleteImage(ctx context.Context, bucketName, objectName string) error
 	EnsureBucketExists(ctx context.Context, bucketName string) error
+	// ListObjects returns the keys of every object under prefix
+	ListObjects(ctx context.Context, bucketName, prefix string) ([]string, error)
 }
 
 type minioClient struct {
@@ -38,6 +42,22 @@ func (m *minioClient) UploadImage(ctx context.Context, bucketName, objectName st
 	return err
 }
 
+func (m *minioClient) UploadObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, contentType string) error {
+	_, err := m.client.PutObject(ctx, bucketName, objectName, reader, objectSize, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+func (m *minioClient) GetObject(ctx context.Context, bucketName, objectName string) ([]byte, error) {
+	object, err := m.client.GetObject(ctx, bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return io.ReadAll(object)
+}
+
 func (m *minioClient) GetPresignedURL(bucketName, objectName string, expiry time.Duration) (string, error) {
 	url, err := m.client.PresignedGetObject(context.Background(), bucketName, objectName, expiry, nil)
 	if err != nil {
@@ -59,4 +79,14 @@ func (m *minioClient) EnsureBucketExists(ctx context.Context, bucketName string)
 		return m.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
 	}
 	return nil
-}
\ No newline at end of file
+}
+func (m *minioClient) ListObjects(ctx context.Context, bucketName, prefix string) ([]string, error) {
+	var keys []string
+	for object := range m.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		keys = append(keys, object.Key)
+	}
+	return keys, nil
+}
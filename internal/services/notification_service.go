@@ -23,6 +23,8 @@ type NotificationService interface {
 	SendEmail(ctx context.Context, tenantID uuid.UUID, recipient, subject, body string) error
 	SendSMS(ctx context.Context, tenantID uuid.UUID, recipient, message string) error
 	SendWebhook(ctx context.Context, tenantID uuid.UUID, webhook *models.WebhookSubscription, payload map[string]interface{}) error
+	SendPush(ctx context.Context, tenantID uuid.UUID, topic string, msg *models.PushMessage) error
+	SendWhatsApp(ctx context.Context, tenantID uuid.UUID, msg *models.WhatsAppSend) error
 
 	// Template management
 	CreateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.NotificationTemplate) error
@@ -60,10 +62,12 @@ type notificationService struct {
 	redisClient *redis.Client
 	templates   map[string]*template.Template // Cached templates
 	httpClient  *http.Client
+	pushSvc     PushService
+	whatsAppSvc WhatsAppService
 }
 
 // NewNotificationService creates a new notification service
-func NewNotificationService(redisAddr, redisPassword string, redisDB int) NotificationService {
+func NewNotificationService(redisAddr, redisPassword string, redisDB int, pushSvc PushService, whatsAppSvc WhatsAppService) NotificationService {
 	// Create Redis client for this service
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     redisAddr,
@@ -79,6 +83,8 @@ func NewNotificationService(redisAddr, redisPassword string, redisDB int) Notifi
 		redisClient: redisClient,
 		templates:   make(map[string]*template.Template),
 		httpClient:  httpClient,
+		pushSvc:     pushSvc,
+		whatsAppSvc: whatsAppSvc,
 	}
 }
 
@@ -105,6 +111,36 @@ func (s *notificationService) SendNotification(ctx context.Context, tenantID uui
 		}
 
 		return s.SendWebhook(ctx, tenantID, subscription, payload)
+	case models.NotificationTypePush:
+		// For push, recipient is a user ID or a topic name
+		msg := &models.PushMessage{
+			Body: notification.Body,
+			Data: map[string]string{"event_type": notification.EventType, "event_id": notification.EventID},
+		}
+		if notification.Subject != nil {
+			msg.Title = *notification.Subject
+		}
+		if userID, err := uuid.Parse(notification.Recipient); err == nil {
+			if s.pushSvc == nil {
+				return fmt.Errorf("push notifications are not configured")
+			}
+			_, err := s.pushSvc.SendToUser(ctx, tenantID, userID, msg)
+			return err
+		}
+		return s.SendPush(ctx, tenantID, notification.Recipient, msg)
+	case models.NotificationTypeWhatsApp:
+		// For WhatsApp, recipient is a phone number; the event's template takes
+		// the subject and body as parameters
+		params := map[string]string{"body": notification.Body}
+		if notification.Subject != nil {
+			params["subject"] = *notification.Subject
+		}
+		return s.SendWhatsApp(ctx, tenantID, &models.WhatsAppSend{
+			Phone:     notification.Recipient,
+			EventType: notification.EventType,
+			EventID:   notification.EventID,
+			Params:    params,
+		})
 	default:
 		return fmt.Errorf("unsupported notification type: %s", notification.Type)
 	}
@@ -176,6 +212,36 @@ func (s *notificationService) SendWebhook(ctx context.Context, tenantID uuid.UUI
 	return nil
 }
 
+// SendPush sends a push notification to every tenant device subscribed to topic
+func (s *notificationService) SendPush(ctx context.Context, tenantID uuid.UUID, topic string, msg *models.PushMessage) error {
+	if s.pushSvc == nil {
+		return fmt.Errorf("push notifications are not configured")
+	}
+
+	sent, err := s.pushSvc.SendToTopic(ctx, tenantID, topic, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send push notification: %v", err)
+	}
+
+	log.Printf("[PUSH] Tenant=%s, Topic=%s, Devices=%d, Title=%s", tenantID.String(), topic, sent, msg.Title)
+	return nil
+}
+
+// SendWhatsApp sends an event's WhatsApp template to an opted-in contact
+func (s *notificationService) SendWhatsApp(ctx context.Context, tenantID uuid.UUID, msg *models.WhatsAppSend) error {
+	if s.whatsAppSvc == nil {
+		return fmt.Errorf("WhatsApp notifications are not configured")
+	}
+
+	message, err := s.whatsAppSvc.Send(ctx, tenantID, msg)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[WHATSAPP] Tenant=%s, Event=%s, Message=%s, Status=%s", tenantID.String(), msg.EventType, message.ID, message.Status)
+	return nil
+}
+
 // Template management methods
 func (s *notificationService) CreateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.NotificationTemplate) error {
 	template.ID = uuid.NewString()
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OCR providers selectable through OCRConfig.Provider
+const (
+	OCRProviderTesseract    = "tesseract"
+	OCRProviderGoogleVision = "google_vision"
+	ocrProviderNone         = "none"
+)
+
+const (
+	googleVisionURL = "https://vision.googleapis.com/v1"
+	// ocrMaxPDFPages bounds the pages read from a PDF; supplier invoices are short
+	ocrMaxPDFPages = 5
+)
+
+// ErrOCRNotConfigured is returned by the driver used when no OCR provider is set
+var ErrOCRNotConfigured = errors.New("OCR is not configured")
+
+// OCRConfig selects and authenticates the OCR provider
+type OCRConfig struct {
+	// Provider is "tesseract" for the local binary or "google_vision"; empty
+	// stores documents without reading them
+	Provider string
+	// TesseractPath is the tesseract binary; pdftoppm from poppler must be on
+	// the PATH to read PDFs
+	TesseractPath string
+	// Languages is the tesseract language list, e.g. "eng+hin"
+	Languages string
+	// APIKey authenticates Google Cloud Vision
+	APIKey string
+}
+
+// OCRDriver reads the text of a scanned document
+type OCRDriver interface {
+	// Name identifies the provider on the records it reads
+	Name() string
+	// ExtractText returns the document text in reading order, one printed
+	// line per line. contentType is an image type or application/pdf
+	ExtractText(ctx context.Context, content []byte, contentType string) (string, error)
+}
+
+// NewOCRDriver creates the driver for the configured provider
+func NewOCRDriver(cfg OCRConfig) (OCRDriver, error) {
+	switch cfg.Provider {
+	case "":
+		return noopOCRDriver{}, nil
+	case OCRProviderTesseract:
+		path := cfg.TesseractPath
+		if path == "" {
+			path = "tesseract"
+		}
+		resolved, err := exec.LookPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("tesseract binary not found: %w", err)
+		}
+		languages := cfg.Languages
+		if languages == "" {
+			languages = "eng"
+		}
+		return &tesseractOCRDriver{path: resolved, languages: languages}, nil
+	case OCRProviderGoogleVision:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("Google Vision OCR requires an API key")
+		}
+		return &googleVisionOCRDriver{
+			apiKey:     cfg.APIKey,
+			baseURL:    googleVisionURL,
+			httpClient: &http.Client{Timeout: 60 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown OCR provider %q", cfg.Provider)
+	}
+}
+
+// tesseractOCRDriver runs the tesseract command line tool; PDF pages are
+// rendered to images with pdftoppm first
+type tesseractOCRDriver struct {
+	path      string
+	languages string
+}
+
+func (d *tesseractOCRDriver) Name() string {
+	return OCRProviderTesseract
+}
+
+func (d *tesseractOCRDriver) ExtractText(ctx context.Context, content []byte, contentType string) (string, error) {
+	dir, err := os.MkdirTemp("", "ocr-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create OCR work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	images := []string{filepath.Join(dir, "page")}
+	if contentType == "application/pdf" {
+		source := filepath.Join(dir, "document.pdf")
+		if err := os.WriteFile(source, content, 0o600); err != nil {
+			return "", fmt.Errorf("failed to write PDF for OCR: %w", err)
+		}
+		prefix := filepath.Join(dir, "page")
+		cmd := exec.CommandContext(ctx, "pdftoppm", "-r", "300", "-png", "-l", fmt.Sprint(ocrMaxPDFPages), source, prefix)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("failed to render PDF pages: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		if images, err = filepath.Glob(prefix + "-*.png"); err != nil || len(images) == 0 {
+			return "", fmt.Errorf("PDF has no pages to read")
+		}
+		sort.Strings(images)
+	} else if err := os.WriteFile(images[0], content, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write image for OCR: %w", err)
+	}
+
+	var text strings.Builder
+	for _, image := range images {
+		// psm 6 reads the page as one block, which keeps table rows on one line
+		cmd := exec.CommandContext(ctx, d.path, image, "stdout", "-l", d.languages, "--psm", "6")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		text.Write(out)
+		text.WriteString("\n")
+	}
+	return text.String(), nil
+}
+
+// googleVisionOCRDriver uses Cloud Vision document text detection; PDFs go
+// through the synchronous files:annotate call, which reads up to five pages
+type googleVisionOCRDriver struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (d *googleVisionOCRDriver) Name() string {
+	return OCRProviderGoogleVision
+}
+
+type visionAnnotation struct {
+	FullTextAnnotation struct {
+		Text string `json:"text"`
+	} `json:"fullTextAnnotation"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (d *googleVisionOCRDriver) ExtractText(ctx context.Context, content []byte, contentType string) (string, error) {
+	features := []map[string]string{{"type": "DOCUMENT_TEXT_DETECTION"}}
+	encoded := base64.StdEncoding.EncodeToString(content)
+
+	endpoint := "/images:annotate"
+	request := map[string]interface{}{
+		"image":    map[string]string{"content": encoded},
+		"features": features,
+	}
+	if contentType == "application/pdf" {
+		pages := make([]int, ocrMaxPDFPages)
+		for i := range pages {
+			pages[i] = i + 1
+		}
+		endpoint = "/files:annotate"
+		request = map[string]interface{}{
+			"inputConfig": map[string]string{"content": encoded, "mimeType": contentType},
+			"features":    features,
+			"pages":       pages,
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"requests": []interface{}{request}})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal Vision request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+endpoint+"?key="+d.apiKey, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Vision request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Vision request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Vision returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var parsed struct {
+		Responses []struct {
+			visionAnnotation
+			// files:annotate nests one response per page
+			Responses []visionAnnotation `json:"responses"`
+		} `json:"responses"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("invalid Vision response: %v", err)
+	}
+	if len(parsed.Responses) == 0 {
+		return "", fmt.Errorf("Vision response was empty")
+	}
+
+	first := parsed.Responses[0]
+	annotations := append([]visionAnnotation{first.visionAnnotation}, first.Responses...)
+	var text strings.Builder
+	for _, annotation := range annotations {
+		if annotation.Error != nil && annotation.Error.Message != "" {
+			return "", fmt.Errorf("Vision could not read the document: %s", annotation.Error.Message)
+		}
+		if annotation.FullTextAnnotation.Text != "" {
+			text.WriteString(annotation.FullTextAnnotation.Text)
+			text.WriteString("\n")
+		}
+	}
+	return text.String(), nil
+}
+
+// noopOCRDriver is used when no provider is configured; documents are kept
+// and the invoice is keyed in by hand
+type noopOCRDriver struct{}
+
+func (noopOCRDriver) Name() string {
+	return ocrProviderNone
+}
+
+func (noopOCRDriver) ExtractText(ctx context.Context, content []byte, contentType string) (string, error) {
+	return "", ErrOCRNotConfigured
+}
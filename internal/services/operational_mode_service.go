@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidOperationalMode wraps operational mode validation failures
+	ErrInvalidOperationalMode = errors.New("invalid operational mode")
+	// ErrOperationalModeTenantNotFound is returned when switching the mode of
+	// an unknown tenant
+	ErrOperationalModeTenantNotFound = errors.New("tenant not found")
+)
+
+// operationalModeCacheTTL bounds how long an instance keeps enforcing a mode
+// lifted elsewhere without seeing the invalidation
+const operationalModeCacheTTL = 15 * time.Second
+
+// operationalModesCacheKey holds every mode that is set; they are checked on
+// every request and there are rarely more than a handful
+const operationalModesCacheKey = "operational_modes:all"
+
+// operationalModeRank orders the modes by how much they block
+func operationalModeRank(mode string) int {
+	switch mode {
+	case models.OperationalModeMaintenance:
+		return 2
+	case models.OperationalModeReadOnly:
+		return 1
+	}
+	return 0
+}
+
+// OperationalModeService switches the whole service or a single tenant into
+// read-only or maintenance mode, so migrations and incident response can
+// stop writes or traffic without taking the service down
+type OperationalModeService interface {
+	// Effective returns the mode in force for the tenant, the stricter of the
+	// global and tenant modes, or nil in normal operation. A nil tenantID
+	// only considers the global mode
+	Effective(ctx context.Context, tenantID *uuid.UUID) (*models.OperationalMode, error)
+	ListModes(ctx context.Context) ([]*models.OperationalMode, error)
+	// SetMode switches the scope, global when tenantID is nil; the normal mode
+	// lifts whatever mode was set and returns nil
+	SetMode(ctx context.Context, tenantID *uuid.UUID, setBy uuid.UUID, req *models.OperationalModeRequest) (*models.OperationalMode, error)
+}
+
+type operationalModeService struct {
+	repo     repositories.OperationalModeRepository
+	cacheSvc caching.CacheService
+}
+
+// NewOperationalModeService creates a new operational mode service
+func NewOperationalModeService(repo repositories.OperationalModeRepository, cacheSvc caching.CacheService) OperationalModeService {
+	return &operationalModeService{
+		repo:     repo,
+		cacheSvc: cacheSvc,
+	}
+}
+
+// modes returns every mode that is set, from the cache when possible
+func (s *operationalModeService) modes(ctx context.Context) ([]*models.OperationalMode, error) {
+	if cached, err := s.cacheSvc.GetString(ctx, operationalModesCacheKey); err == nil && cached != "" {
+		var modes []*models.OperationalMode
+		if err := json.Unmarshal([]byte(cached), &modes); err == nil {
+			return modes, nil
+		}
+	}
+
+	modes, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load operational modes: %w", err)
+	}
+	if modes == nil {
+		modes = []*models.OperationalMode{}
+	}
+	if payload, err := json.Marshal(modes); err == nil {
+		if cacheErr := s.cacheSvc.SetString(ctx, operationalModesCacheKey, string(payload), operationalModeCacheTTL); cacheErr != nil {
+			fmt.Printf("Failed to cache operational modes: %v\n", cacheErr)
+		}
+	}
+	return modes, nil
+}
+
+func (s *operationalModeService) Effective(ctx context.Context, tenantID *uuid.UUID) (*models.OperationalMode, error) {
+	modes, err := s.modes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var effective *models.OperationalMode
+	for _, m := range modes {
+		applies := m.TenantID == nil || (tenantID != nil && *m.TenantID == *tenantID)
+		if applies && (effective == nil || operationalModeRank(m.Mode) > operationalModeRank(effective.Mode)) {
+			effective = m
+		}
+	}
+	return effective, nil
+}
+
+func (s *operationalModeService) ListModes(ctx context.Context) ([]*models.OperationalMode, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *operationalModeService) SetMode(ctx context.Context, tenantID *uuid.UUID, setBy uuid.UUID, req *models.OperationalModeRequest) (*models.OperationalMode, error) {
+	var mode *models.OperationalMode
+	switch req.Mode {
+	case models.OperationalModeNormal:
+		if _, err := s.repo.Clear(ctx, tenantID); err != nil {
+			return nil, fmt.Errorf("failed to clear operational mode: %w", err)
+		}
+	case models.OperationalModeReadOnly, models.OperationalModeMaintenance:
+		mode = &models.OperationalMode{
+			ID:       uuid.New(),
+			TenantID: tenantID,
+			Mode:     req.Mode,
+			Message:  trimmedOrNil(req.Message),
+			SetBy:    &setBy,
+		}
+		saved, err := s.repo.Set(ctx, mode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set operational mode: %w", err)
+		}
+		if !saved {
+			return nil, ErrOperationalModeTenantNotFound
+		}
+	default:
+		return nil, fmt.Errorf("%w: mode must be normal, read_only or maintenance", ErrInvalidOperationalMode)
+	}
+
+	if err := s.cacheSvc.Delete(ctx, operationalModesCacheKey); err != nil {
+		fmt.Printf("Failed to invalidate cached operational modes: %v\n", err)
+	}
+	return mode, nil
+}
@@ -2,12 +2,15 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 )
@@ -16,10 +19,11 @@ import (
 type OrderServiceInterface interface {
 	CreateOrder(ctx context.Context, tenantID uuid.UUID, order *models.Order) error
 	GetOrderByID(ctx context.Context, tenantID, orderID uuid.UUID) (*models.Order, error)
-	ListOrders(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Order, error)
+	// ListOrders lists orders, only those of the branch when branchID is set
+	ListOrders(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Order, error)
 	UpdateOrder(ctx context.Context, tenantID uuid.UUID, order *models.Order) error
 	DeleteOrder(ctx context.Context, tenantID, orderID uuid.UUID) error
-	GetOrderAnalytics(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) (map[string]interface{}, error)
+	GetOrderAnalytics(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, startDate, endDate time.Time) (map[string]interface{}, error)
 	SearchOrders(ctx context.Context, tenantID uuid.UUID, filter *models.OrderSearchFilter) ([]*models.Order, error)
 	ApproveOrder(ctx context.Context, tenantID, orderID uuid.UUID) error
 	ProcessOrder(ctx context.Context, tenantID, orderID uuid.UUID) error
@@ -27,7 +31,16 @@ type OrderServiceInterface interface {
 	ShipOrder(ctx context.Context, tenantID, orderID uuid.UUID, expectedDelivery *time.Time) error
 	DeliverOrder(ctx context.Context, tenantID, orderID uuid.UUID) error
 	CancelOrder(ctx context.Context, tenantID, orderID uuid.UUID) error
-	GetOrderHistory(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.Order, error)
+	// QualityCheckOrder holds a processed order for its quality check; it
+	// fails with ErrOrderEventNotInWorkflow when the tenant has no such step
+	QualityCheckOrder(ctx context.Context, tenantID, orderID uuid.UUID) error
+	// OrderEvents lists the workflow events the order can take next
+	OrderEvents(ctx context.Context, tenantID uuid.UUID, order *models.Order) ([]string, error)
+	// BulkCancelOrders cancels orders in bulk, or with DryRun reports which
+	// orders the status rules would keep from being cancelled
+	BulkCancelOrders(ctx context.Context, tenantID uuid.UUID, bulkCancel *models.OrderBulkCancel) *models.BulkOperationResult
+	// GetOrderHistory lists the order's status transitions, oldest first
+	GetOrderHistory(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.StatusChange, error)
 }
 
 // OrderFilters defines filters for order queries
@@ -41,15 +54,104 @@ type orderService struct {
 	orderRepo       repositories.OrderRepository
 	inventoryRepo    repositories.InventoryRepository
 	inventoryService InventoryService
+	marginService    MarginService
+	notificationSvc  NotificationService
+	consignmentSvc   ConsignmentService
+	bundleSvc        BundleService
+	complianceSvc    ComplianceService
+	bulkOps          BulkOperationService
+	stockOutRepo     repositories.StockOutRepository
+	historyRepo      repositories.StatusHistoryRepository
+	workflowSvc      OrderWorkflowService
+	beforeHooks      map[string][]orderHook
+	afterHooks       map[string][]orderHook
 }
 
 // NewOrderService creates a new order service instance
-func NewOrderService(orderRepo repositories.OrderRepository, inventoryRepo repositories.InventoryRepository, inventoryService InventoryService) OrderServiceInterface {
-	return &orderService{
+func NewOrderService(orderRepo repositories.OrderRepository, inventoryRepo repositories.InventoryRepository, inventoryService InventoryService, marginService MarginService, notificationSvc NotificationService, consignmentSvc ConsignmentService, bundleSvc BundleService, complianceSvc ComplianceService, bulkOps BulkOperationService, stockOutRepo repositories.StockOutRepository, historyRepo repositories.StatusHistoryRepository, workflowSvc OrderWorkflowService) OrderServiceInterface {
+	s := &orderService{
 		orderRepo:       orderRepo,
 		inventoryRepo:    inventoryRepo,
 		inventoryService: inventoryService,
+		marginService:    marginService,
+		notificationSvc:  notificationSvc,
+		consignmentSvc:   consignmentSvc,
+		bundleSvc:        bundleSvc,
+		complianceSvc:    complianceSvc,
+		bulkOps:          bulkOps,
+		stockOutRepo:     stockOutRepo,
+		historyRepo:      historyRepo,
+		workflowSvc:      workflowSvc,
+	}
+	s.registerHooks()
+	return s
+}
+
+// checkMargin enforces the tenant's margin policy; a *MarginViolationError is
+// returned as-is so handlers can report the minimum price
+func (s *orderService) checkMargin(ctx context.Context, tenantID uuid.UUID, order *models.Order) (*models.MarginViolation, error) {
+	violation, err := s.marginService.CheckOrderPrice(ctx, tenantID, order)
+	if err != nil {
+		if _, ok := err.(*MarginViolationError); ok {
+			return nil, err
+		}
+		return nil, common.SecureErrorMessage("check order margin", err)
 	}
+	return violation, nil
+}
+
+// recordMarginOverride logs an overridden margin violation once the order is saved
+func (s *orderService) recordMarginOverride(ctx context.Context, violation *models.MarginViolation) {
+	if violation == nil {
+		return
+	}
+	if err := s.marginService.RecordViolation(ctx, violation); err != nil {
+		fmt.Printf("Failed to record margin override for order %s: %v\n", violation.OrderID, err)
+	}
+}
+
+// recordStockOut logs sales demand the warehouse could not cover from stock
+// for fill rate analytics; available is nil when bundle components ran short.
+// A logging failure does not change the outcome for the caller
+func (s *orderService) recordStockOut(ctx context.Context, tenantID uuid.UUID, order *models.Order, outcome, source string, requested int, available *int) {
+	if s.stockOutRepo == nil || order.OrderType != "sales" || requested <= 0 {
+		return
+	}
+	short := requested
+	if available != nil && *available > 0 {
+		short = requested - *available
+	}
+	if short <= 0 {
+		return
+	}
+	event := &models.StockOutEvent{
+		ID:                uuid.New(),
+		TenantID:          tenantID,
+		ProductID:         order.ProductID,
+		WarehouseID:       order.WarehouseID,
+		DistributorID:     order.DistributorID,
+		Outcome:           outcome,
+		Source:            source,
+		RequestedQuantity: requested,
+		AvailableQuantity: available,
+		ShortQuantity:     short,
+		UnitPrice:         order.UnitPrice,
+	}
+	if outcome == models.StockOutBackordered {
+		event.OrderID = &order.ID
+	}
+	if err := s.stockOutRepo.Record(ctx, event); err != nil {
+		fmt.Printf("Failed to record stock-out for product %s: %v\n", order.ProductID, err)
+	}
+}
+
+// availableQuantity is the on-hand quantity of an inventory row, zero when there is none
+func availableQuantity(inventory *models.Inventory) *int {
+	available := 0
+	if inventory != nil {
+		available = inventory.Quantity
+	}
+	return &available
 }
 
 // CreateOrder creates a new order with enhanced security and validation
@@ -98,28 +200,55 @@ func (s *orderService) CreateOrder(ctx context.Context, tenantID uuid.UUID, orde
 			return common.SecureErrorMessage("check inventory availability", err)
 		}
 		if inventory == nil || inventory.Quantity < order.Quantity {
+			s.recordStockOut(ctx, tenantID, order, models.StockOutRejected, models.StockOutSourceOrderCreate, order.Quantity, availableQuantity(inventory))
 			return common.SecureErrorMessage("inventory validation",
 				fmt.Errorf("insufficient inventory available for sales order"))
 		}
 	}
 	// For purchase orders, no inventory check is needed as they add inventory to stock
 
+	// Restricted products need a license in force; *ComplianceBlockedError is returned as-is
+	if err := s.complianceSvc.CheckOrder(ctx, tenantID, order); err != nil {
+		return err
+	}
+	buyerLicense, err := s.complianceSvc.ResolveBuyerLicense(ctx, tenantID, order)
+	if err != nil {
+		return err
+	}
+
+	marginViolation, err := s.checkMargin(ctx, tenantID, order)
+	if err != nil {
+		return err
+	}
+
 	// Save the order
 	if err := s.orderRepo.Create(ctx, order); err != nil {
 		return common.SecureErrorMessage("save order", err)
 	}
+	recordStatusChange(ctx, s.historyRepo, tenantID, models.StatusEntityOrder, order.ID, "", order.Status)
+	s.recordMarginOverride(ctx, marginViolation)
+	if err := s.complianceSvc.RecordBuyerLicense(ctx, buyerLicense); err != nil {
+		fmt.Printf("Failed to capture buyer license for order %s: %v\n", order.ID, err)
+	}
 
 	return nil
 }
 
 // GetOrderByID retrieves an order by ID
 func (s *orderService) GetOrderByID(ctx context.Context, tenantID, orderID uuid.UUID) (*models.Order, error) {
-	return s.orderRepo.GetByID(ctx, tenantID, orderID)
+	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
+	if err != nil || order == nil || s.historyRepo == nil {
+		return order, err
+	}
+	if order.StatusHistory, err = s.historyRepo.ListByEntity(ctx, tenantID, models.StatusEntityOrder, order.ID); err != nil {
+		return nil, err
+	}
+	return order, nil
 }
 
 // ListOrders lists orders with pagination
-func (s *orderService) ListOrders(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Order, error) {
-	return s.orderRepo.List(ctx, tenantID, limit, offset)
+func (s *orderService) ListOrders(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Order, error) {
+	return s.orderRepo.ListPage(ctx, tenantID, branchID, page)
 }
 
 // UpdateOrder updates an order with enhanced security and validation
@@ -157,17 +286,30 @@ func (s *orderService) UpdateOrder(ctx context.Context, tenantID uuid.UUID, orde
 				return common.SecureErrorMessage("check updated inventory", err)
 			}
 			if inventory == nil || inventory.Quantity < additionalQuantity {
+				s.recordStockOut(ctx, tenantID, existingOrder, models.StockOutRejected, models.StockOutSourceOrderUpdate, additionalQuantity, availableQuantity(inventory))
 				return common.SecureErrorMessage("inventory validation",
 					fmt.Errorf("insufficient additional inventory"))
 			}
 		}
 	}
 
+	var marginViolation *models.MarginViolation
+	if order.UnitPrice != existingOrder.UnitPrice {
+		marginViolation, err = s.checkMargin(ctx, tenantID, order)
+		if err != nil {
+			return err
+		}
+	}
+
 	order.UpdatedAt = time.Now()
 
 	if err := s.orderRepo.Update(ctx, order); err != nil {
+		if errors.Is(err, repositories.ErrPreconditionFailed) {
+			return err
+		}
 		return common.SecureErrorMessage("update order", err)
 	}
+	s.recordMarginOverride(ctx, marginViolation)
 
 	return nil
 }
@@ -178,14 +320,14 @@ func (s *orderService) DeleteOrder(ctx context.Context, tenantID, orderID uuid.U
 }
 
 // GetOrderAnalytics provides secure order analytics with date range validation
-func (s *orderService) GetOrderAnalytics(ctx context.Context, tenantID uuid.UUID, startDate, endDate time.Time) (map[string]interface{}, error) {
+func (s *orderService) GetOrderAnalytics(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, startDate, endDate time.Time) (map[string]interface{}, error) {
 	// Validate date range to prevent abuse
 	if err := common.ValidateDateRange(startDate, endDate); err != nil {
 		return nil, common.SecureErrorMessage("validate analytics date range", err)
 	}
 
 	// Get orders in validated date range
-	orders, err := s.orderRepo.GetOrdersByTenantAndDateRange(ctx, tenantID, startDate, endDate)
+	orders, err := s.orderRepo.GetOrdersByTenantAndDateRange(ctx, tenantID, branchID, startDate, endDate)
 	if err != nil {
 		return nil, common.SecureErrorMessage("retrieve order analytics data", err)
 	}
@@ -262,206 +404,384 @@ func (s *orderService) SearchOrders(ctx context.Context, tenantID uuid.UUID, fil
 	return orders, nil
 }
 
-// ApproveOrder changes order status to approved
-func (s *orderService) ApproveOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
-	// Get the order
-	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
-	if err != nil {
-		return fmt.Errorf("failed to get order: %w", err)
-	}
-	if order == nil {
-		return fmt.Errorf("order not found")
-	}
-
-	// Can only approve pending orders
-	if order.Status != "pending" {
-		return fmt.Errorf("can only approve orders with status 'pending', current status: %s", order.Status)
-	}
-
-	order.Status = "approved"
-	order.UpdatedAt = time.Now()
-
-	return s.orderRepo.Update(ctx, order)
+// orderTransition is one status change on its way through the event hooks
+type orderTransition struct {
+	tenantID     uuid.UUID
+	order        *models.Order
+	event        string
+	from         string
+	saleOverride *models.RestrictedSaleOverride
 }
 
-// ProcessOrder changes order status to processing and reserves inventory with security checks
-func (s *orderService) ProcessOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
-	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
-	if err != nil {
-		return common.SecureErrorMessage("retrieve order for processing", err)
+// orderHook is a side effect of an order event. Before hooks run ahead of the
+// status update and stop the transition by failing; after hooks run once the
+// new status is saved and only log their failures
+type orderHook func(ctx context.Context, t *orderTransition) error
+
+// registerHooks wires the side effects of each order event
+func (s *orderService) registerHooks() {
+	s.beforeHooks = map[string][]orderHook{
+		models.OrderEventApprove: {s.confirmRestrictedSale},
+		models.OrderEventProcess: {s.confirmSkippedApproval, s.reserveStock},
+		models.OrderEventReceive: {s.receiveStock},
+		models.OrderEventCancel:  {s.releaseStock},
 	}
-	if order == nil {
-		return common.SecureErrorMessage("order lookup", fmt.Errorf("order not found"))
+	s.afterHooks = map[string][]orderHook{
+		models.OrderEventApprove: {s.recordRestrictedSaleOverride, s.pushApproval, s.confirmToDistributor},
+		models.OrderEventProcess: {s.recordRestrictedSaleOverride, s.confirmToDistributor, s.recordConsignmentSale},
 	}
+}
 
-	if order.Status != "approved" {
-		return common.SecureErrorMessage("validate order status for processing",
-			fmt.Errorf("invalid status transition attempted"))
+// stateMachine is the tenant's order lifecycle, the default one when the
+// service has no workflow configuration
+func (s *orderService) stateMachine(ctx context.Context, tenantID uuid.UUID) (*OrderStateMachine, error) {
+	if s.workflowSvc == nil {
+		return NewOrderStateMachine(nil), nil
 	}
-
-	// Additional validation: ensure data integrity
-	if order.Quantity <= 0 || order.UnitPrice <= 0 {
-		return common.SecureErrorMessage("validate order data", fmt.Errorf("invalid order data"))
+	machine, err := s.workflowSvc.StateMachine(ctx, tenantID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("load order workflow", err)
 	}
+	return machine, nil
+}
 
-	// Reserve inventory with additional validation
-	inventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, tenantID, order.WarehouseID, order.ProductID)
+// transition moves an order through an event of the tenant's workflow: the
+// state machine checks the move, the event's before hooks run, the new status
+// is saved with its history entry and then the after hooks run
+func (s *orderService) transition(ctx context.Context, tenantID uuid.UUID, order *models.Order, event string) error {
+	machine, err := s.stateMachine(ctx, tenantID)
 	if err != nil {
-		return common.SecureErrorMessage("retrieve inventory for processing", err)
+		return err
 	}
-	if inventory == nil || inventory.Quantity < order.Quantity {
-		return common.SecureErrorMessage("inventory validation", fmt.Errorf("insufficient inventory"))
-	}
-
-	// Calculate new quantity with overflow protection
-	newQuantity := inventory.Quantity - order.Quantity
-	if newQuantity < 0 {
-		return common.SecureErrorMessage("inventory calculation", fmt.Errorf("negative inventory calculation"))
+	to, err := machine.Next(order, event)
+	if err != nil {
+		return err
 	}
 
-	inventory.Quantity = newQuantity
-	inventory.LastUpdated = time.Now()
-
-	if err := s.inventoryRepo.Update(ctx, inventory); err != nil {
-		return common.SecureErrorMessage("update inventory for order processing", err)
+	t := &orderTransition{tenantID: tenantID, order: order, event: event, from: order.Status}
+	for _, hook := range s.beforeHooks[event] {
+		if err := hook(ctx, t); err != nil {
+			return err
+		}
 	}
 
-	order.Status = "processing"
+	order.Status = to
 	order.UpdatedAt = time.Now()
-
 	if err := s.orderRepo.Update(ctx, order); err != nil {
 		return common.SecureErrorMessage("update order status", err)
 	}
+	recordStatusChange(ctx, s.historyRepo, tenantID, models.StatusEntityOrder, order.ID, t.from, to)
 
+	for _, hook := range s.afterHooks[event] {
+		if err := hook(ctx, t); err != nil {
+			fmt.Printf("Failed to run %s side effect for order %s: %v\n", event, order.ID, err)
+		}
+	}
 	return nil
 }
 
-// ReceiveOrder handles order receipt for purchase orders
-func (s *orderService) ReceiveOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
+// loadOrder gets an order to move through the workflow
+func (s *orderService) loadOrder(ctx context.Context, tenantID, orderID uuid.UUID) (*models.Order, error) {
 	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
 	if err != nil {
-		return fmt.Errorf("failed to get order: %w", err)
+		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 	if order == nil {
-		return fmt.Errorf("order not found")
+		return nil, fmt.Errorf("order not found")
 	}
+	return order, nil
+}
 
-	if order.OrderType != "purchase" {
-		return fmt.Errorf("receive operation only valid for purchase orders")
-	}
-	if order.Status != "processing" {
-		return fmt.Errorf("can only receive orders with status 'processing', current status: %s", order.Status)
+// ApproveOrder changes order status to approved
+func (s *orderService) ApproveOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	order, err := s.loadOrder(ctx, tenantID, orderID)
+	if err != nil {
+		return err
 	}
+	return s.transition(ctx, tenantID, order, models.OrderEventApprove)
+}
 
-		// Add quantity to inventory using AdjustStock (handles existing or new)
-	err = s.inventoryService.AdjustStock(ctx, tenantID, order.WarehouseID, order.ProductID, order.Quantity)
+// ProcessOrder changes order status to processing and reserves inventory
+func (s *orderService) ProcessOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	order, err := s.loadOrder(ctx, tenantID, orderID)
 	if err != nil {
-		return fmt.Errorf("failed to update inventory: %w", err)
+		return err
+	}
+	return s.transition(ctx, tenantID, order, models.OrderEventProcess)
 }
 
-
-	order.Status = "delivered"
-	order.UpdatedAt = time.Now()
-
-	return s.orderRepo.Update(ctx, order)
+// QualityCheckOrder holds a processed order for its quality check, in
+// workflows with that step
+func (s *orderService) QualityCheckOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	order, err := s.loadOrder(ctx, tenantID, orderID)
+	if err != nil {
+		return err
+	}
+	return s.transition(ctx, tenantID, order, models.OrderEventQualityCheck)
 }
 
-// ShipOrder changes status to shipped
-func (s *orderService) ShipOrder(ctx context.Context, tenantID, orderID uuid.UUID, expectedDelivery *time.Time) error {
-	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
+// ReceiveOrder handles order receipt for purchase orders
+func (s *orderService) ReceiveOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	order, err := s.loadOrder(ctx, tenantID, orderID)
 	if err != nil {
-		return fmt.Errorf("failed to get order: %w", err)
+		return err
 	}
-	if order == nil {
-		return fmt.Errorf("order not found")
+	if order.OrderType != "purchase" {
+		return fmt.Errorf("receive operation only valid for purchase orders")
 	}
+	return s.transition(ctx, tenantID, order, models.OrderEventReceive)
+}
 
-	if order.Status != "processing" {
-		return fmt.Errorf("can only ship orders with status 'processing', current status: %s", order.Status)
+// ShipOrder changes status to shipped
+func (s *orderService) ShipOrder(ctx context.Context, tenantID, orderID uuid.UUID, expectedDelivery *time.Time) error {
+	order, err := s.loadOrder(ctx, tenantID, orderID)
+	if err != nil {
+		return err
 	}
-
-	order.Status = "shipped"
 	if expectedDelivery != nil {
 		order.ExpectedDelivery = expectedDelivery
 	}
-	order.UpdatedAt = time.Now()
-
-	return s.orderRepo.Update(ctx, order)
+	return s.transition(ctx, tenantID, order, models.OrderEventShip)
 }
 
 // DeliverOrder changes status to delivered
 func (s *orderService) DeliverOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
-	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
+	order, err := s.loadOrder(ctx, tenantID, orderID)
 	if err != nil {
-		return fmt.Errorf("failed to get order: %w", err)
+		return err
 	}
-	if order == nil {
-		return fmt.Errorf("order not found")
+	return s.transition(ctx, tenantID, order, models.OrderEventDeliver)
+}
+
+// CancelOrder cancels an order and restores inventory if needed
+func (s *orderService) CancelOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
+	order, err := s.loadOrder(ctx, tenantID, orderID)
+	if err != nil {
+		return err
 	}
+	return s.transition(ctx, tenantID, order, models.OrderEventCancel)
+}
 
-	if order.Status != "shipped" {
-		return fmt.Errorf("can only deliver orders with status 'shipped', current status: %s", order.Status)
+// OrderEvents lists the workflow events the order can take next
+func (s *orderService) OrderEvents(ctx context.Context, tenantID uuid.UUID, order *models.Order) ([]string, error) {
+	machine, err := s.stateMachine(ctx, tenantID)
+	if err != nil {
+		return nil, err
 	}
+	return machine.Events(order), nil
+}
 
-	order.Status = "delivered"
-	order.UpdatedAt = time.Now()
+// confirmRestrictedSale checks the buyer license of a sale of a flagged
+// product; *RestrictedSaleBlockedError is returned as-is
+func (s *orderService) confirmRestrictedSale(ctx context.Context, t *orderTransition) error {
+	override, err := s.complianceSvc.CheckSaleConfirmation(ctx, t.tenantID, t.order)
+	if err != nil {
+		return err
+	}
+	t.saleOverride = override
+	return nil
+}
 
-	return s.orderRepo.Update(ctx, order)
+// confirmSkippedApproval runs the approval checks for orders processed
+// straight from pending
+func (s *orderService) confirmSkippedApproval(ctx context.Context, t *orderTransition) error {
+	if t.from != models.OrderStatusPending {
+		return nil
+	}
+	return s.confirmRestrictedSale(ctx, t)
 }
 
-// CancelOrder cancels an order and restores inventory if needed with secure validation
-func (s *orderService) CancelOrder(ctx context.Context, tenantID, orderID uuid.UUID) error {
-	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
-	if err != nil {
-		return common.SecureErrorMessage("retrieve order for cancellation", err)
+func (s *orderService) recordRestrictedSaleOverride(ctx context.Context, t *orderTransition) error {
+	if t.saleOverride == nil {
+		return nil
 	}
-	if order == nil {
-		return common.SecureErrorMessage("order lookup", fmt.Errorf("order not found"))
+	return s.complianceSvc.RecordOverride(ctx, t.saleOverride)
+}
+
+// pushApproval tells subscribed devices the order was approved
+func (s *orderService) pushApproval(ctx context.Context, t *orderTransition) error {
+	if s.notificationSvc == nil {
+		return nil
 	}
+	msg := &models.PushMessage{
+		Title: "Order approved",
+		Body:  fmt.Sprintf("%s order for %d units has been approved", t.order.OrderType, t.order.Quantity),
+		Data:  map[string]string{"event_type": "order_approved", "order_id": t.order.ID.String()},
+	}
+	return s.notificationSvc.SendPush(ctx, t.tenantID, models.PushTopicOrderApprovals, msg)
+}
 
-	// Can only cancel if not yet delivered or cancelled
-	if order.Status == "delivered" || order.Status == "cancelled" {
-		return common.SecureErrorMessage("validate cancellation eligibility",
-			fmt.Errorf("order cannot be cancelled in current status"))
+// confirmToDistributor confirms a sales order to the distributor once it is
+// approved, or processed without approval
+func (s *orderService) confirmToDistributor(ctx context.Context, t *orderTransition) error {
+	if s.notificationSvc == nil || t.order.OrderType != "sales" || t.order.DistributorID == nil {
+		return nil
+	}
+	if t.event == models.OrderEventProcess && t.from != models.OrderStatusPending {
+		return nil
 	}
+	s.sendOrderConfirmation(ctx, t.tenantID, t.order)
+	return nil
+}
 
-	// Restore inventory if order was processing with validation
-	if order.Status == "processing" || order.Status == "approved" {
-		inventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, tenantID, order.WarehouseID, order.ProductID)
-		if err == nil && inventory != nil {
-			// Prevent inventory overflow
-			newQuantity := inventory.Quantity + order.Quantity
-			if newQuantity < inventory.Quantity { // Overflow check
-				return common.SecureErrorMessage("inventory restoration", fmt.Errorf("inventory would overflow"))
-			}
-			inventory.Quantity = newQuantity
-			inventory.LastUpdated = time.Now()
-			if updateErr := s.inventoryRepo.Update(ctx, inventory); updateErr != nil {
-				return common.SecureErrorMessage("restore inventory for cancellation", updateErr)
+// reserveStock takes the order's quantity out of stock: bundle sales draw
+// assembled kits first and explode the rest into components
+func (s *orderService) reserveStock(ctx context.Context, t *orderTransition) error {
+	order := t.order
+	if order.Quantity <= 0 || order.UnitPrice <= 0 {
+		return common.SecureErrorMessage("validate order data", fmt.Errorf("invalid order data"))
+	}
+
+	if s.bundleSvc != nil {
+		bundleSale, err := s.bundleSvc.ConsumeForSale(ctx, t.tenantID, order)
+		if err != nil {
+			if errors.Is(err, ErrInsufficientStock) {
+				s.recordStockOut(ctx, t.tenantID, order, models.StockOutBackordered, models.StockOutSourceOrderProcess, order.Quantity, nil)
 			}
+			return common.SecureErrorMessage("consume bundle stock for processing", err)
+		}
+		if bundleSale {
+			return nil
 		}
 	}
 
-	order.Status = "cancelled"
-	order.UpdatedAt = time.Now()
+	inventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, t.tenantID, order.WarehouseID, order.ProductID)
+	if err != nil {
+		return common.SecureErrorMessage("retrieve inventory for processing", err)
+	}
+	if inventory == nil || inventory.Quantity < order.Quantity {
+		s.recordStockOut(ctx, t.tenantID, order, models.StockOutBackordered, models.StockOutSourceOrderProcess, order.Quantity, availableQuantity(inventory))
+		return common.SecureErrorMessage("inventory validation", fmt.Errorf("insufficient inventory"))
+	}
 
-	if err := s.orderRepo.Update(ctx, order); err != nil {
-		return common.SecureErrorMessage("update order status for cancellation", err)
+	// Calculate new quantity with overflow protection
+	newQuantity := inventory.Quantity - order.Quantity
+	if newQuantity < 0 {
+		return common.SecureErrorMessage("inventory calculation", fmt.Errorf("negative inventory calculation"))
 	}
+	inventory.Quantity = newQuantity
+	inventory.LastUpdated = time.Now()
+	if err := s.inventoryRepo.Update(ctx, inventory); err != nil {
+		return common.SecureErrorMessage("update inventory for order processing", err)
+	}
+	s.inventoryService.StockChanged(ctx, t.tenantID)
+	return nil
+}
+
+// recordConsignmentSale books the supplier's liability for consigned stock
+// that has left the warehouse; a failure is logged for follow-up rather than
+// undoing the sale
+func (s *orderService) recordConsignmentSale(ctx context.Context, t *orderTransition) error {
+	if s.consignmentSvc == nil {
+		return nil
+	}
+	_, err := s.consignmentSvc.RecordSale(ctx, t.tenantID, t.order)
+	return err
+}
 
+// receiveStock adds a received purchase order to inventory
+func (s *orderService) receiveStock(ctx context.Context, t *orderTransition) error {
+	if err := s.inventoryService.AdjustStock(ctx, t.tenantID, t.order.WarehouseID, t.order.ProductID, t.order.Quantity); err != nil {
+		return fmt.Errorf("failed to update inventory: %w", err)
+	}
 	return nil
 }
 
-// GetOrderHistory returns order state changes (simplified implementation)
-func (s *orderService) GetOrderHistory(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.Order, error) {
-	// For now, just return the current order state
+// releaseStock puts back what the order had taken from stock: consignment
+// units return to the supplier's balance and a processed bundle sale is
+// restored exactly as its stock was drawn
+func (s *orderService) releaseStock(ctx context.Context, t *orderTransition) error {
+	order := t.order
+	stockTaken := t.from == models.OrderStatusProcessing || t.from == models.OrderStatusQualityCheck
+
+	if stockTaken && order.OrderType == "sales" && s.consignmentSvc != nil {
+		if _, err := s.consignmentSvc.ReverseSale(ctx, t.tenantID, order.ID); err != nil {
+			fmt.Printf("Failed to reverse consignment liability for order %s: %v\n", order.ID, err)
+		}
+	}
+
+	bundleRestored := false
+	if stockTaken && order.OrderType == "sales" && s.bundleSvc != nil {
+		var err error
+		if bundleRestored, err = s.bundleSvc.RestoreSale(ctx, t.tenantID, order.ID); err != nil {
+			return common.SecureErrorMessage("restore bundle stock for cancellation", err)
+		}
+	}
+	if bundleRestored || !(stockTaken || t.from == models.OrderStatusApproved) {
+		return nil
+	}
+
+	inventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, t.tenantID, order.WarehouseID, order.ProductID)
+	if err != nil || inventory == nil {
+		return nil
+	}
+	// Prevent inventory overflow
+	newQuantity := inventory.Quantity + order.Quantity
+	if newQuantity < inventory.Quantity {
+		return common.SecureErrorMessage("inventory restoration", fmt.Errorf("inventory would overflow"))
+	}
+	inventory.Quantity = newQuantity
+	inventory.LastUpdated = time.Now()
+	if err := s.inventoryRepo.Update(ctx, inventory); err != nil {
+		return common.SecureErrorMessage("restore inventory for cancellation", err)
+	}
+	s.inventoryService.StockChanged(ctx, t.tenantID)
+	return nil
+}
+
+// BulkCancelOrders cancels orders in bulk; orders the workflow no longer lets
+// be cancelled are reported as blocked
+func (s *orderService) BulkCancelOrders(ctx context.Context, tenantID uuid.UUID, bulkCancel *models.OrderBulkCancel) *models.BulkOperationResult {
+	machine, machineErr := s.stateMachine(ctx, tenantID)
+	return s.bulkOps.Run(ctx, tenantID, "bulk_cancel_orders", bulkCancel.OrderIDs, bulkCancel.DryRun, func(ctx context.Context, id uuid.UUID, dryRun bool) error {
+		if machineErr != nil {
+			return machineErr
+		}
+		order, err := s.orderRepo.GetByID(ctx, tenantID, id)
+		if err != nil || order == nil {
+			return fmt.Errorf("order not found")
+		}
+		if _, err := machine.Next(order, models.OrderEventCancel); err != nil {
+			return fmt.Errorf("%w: order is already %s", ErrBulkItemBlocked, order.Status)
+		}
+		if dryRun {
+			return nil
+		}
+		return s.CancelOrder(ctx, tenantID, id)
+	})
+}
+
+// sendOrderConfirmation sends the distributor a WhatsApp order confirmation.
+// Distributors who have not opted in, or tenants without a template, are skipped
+func (s *orderService) sendOrderConfirmation(ctx context.Context, tenantID uuid.UUID, order *models.Order) {
+	expected := "to be confirmed"
+	if order.ExpectedDelivery != nil {
+		expected = order.ExpectedDelivery.Format("02 Jan 2006")
+	}
+	err := s.notificationSvc.SendWhatsApp(ctx, tenantID, &models.WhatsAppSend{
+		DistributorID: order.DistributorID,
+		EventType:     models.WhatsAppEventOrderConfirmation,
+		EventID:       order.ID.String(),
+		Params: map[string]string{
+			"order_number":      strings.ToUpper(order.ID.String()[:8]),
+			"quantity":          strconv.Itoa(order.Quantity),
+			"amount":            fmt.Sprintf("%.2f", float64(order.Quantity)*order.UnitPrice),
+			"expected_delivery": expected,
+		},
+	})
+	if err != nil && !errors.Is(err, ErrWhatsAppNotOptedIn) && !errors.Is(err, ErrWhatsAppNoTemplate) {
+		fmt.Printf("Failed to send WhatsApp order confirmation for %s: %v\n", order.ID, err)
+	}
+}
+
+// GetOrderHistory lists the order's status transitions, oldest first
+func (s *orderService) GetOrderHistory(ctx context.Context, tenantID, orderID uuid.UUID) ([]*models.StatusChange, error) {
 	order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
-	if order == nil {
-		return []*models.Order{}, nil
+	if order == nil || s.historyRepo == nil {
+		return []*models.StatusChange{}, nil
 	}
-
-	return []*models.Order{order}, nil
-}
\ No newline at end of file
+	return s.historyRepo.ListByEntity(ctx, tenantID, models.StatusEntityOrder, order.ID)
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// slaBreachLookback is how long after its deadline an order is still
+	// recorded and alerted as a breach, so a new SLA does not alert on every
+	// old order at once
+	slaBreachLookback = 7 * 24 * time.Hour
+	slaBreachBatch    = 200
+	slaMaxTargetDays  = 90
+	// slaAlertListLimit caps the orders listed in one breach email
+	slaAlertListLimit = 20
+)
+
+// ErrInvalidSLA is returned for SLA definitions that cannot be saved and
+// report queries that cannot be run
+var ErrInvalidSLA = errors.New("invalid SLA")
+
+// OrderSLAService manages order fulfillment SLAs, alerts on breaches and
+// reports compliance
+type OrderSLAService interface {
+	ListDefinitions(ctx context.Context, tenantID uuid.UUID) ([]*models.OrderSLA, error)
+	CreateDefinition(ctx context.Context, tenantID uuid.UUID, sla *models.OrderSLA) error
+	UpdateDefinition(ctx context.Context, tenantID uuid.UUID, sla *models.OrderSLA) error
+	DeleteDefinition(ctx context.Context, tenantID, id uuid.UUID) error
+
+	// RunBreachCheck records orders that have newly missed one of the tenant's
+	// active SLAs, alerts on them, closes breaches whose orders have caught up
+	// and returns how many new breaches were found
+	RunBreachCheck(ctx context.Context, tenantID uuid.UUID, now time.Time) (int, error)
+	ListBreaches(ctx context.Context, tenantID uuid.UUID, slaID *uuid.UUID, openOnly bool, limit, offset int) ([]*models.OrderSLABreach, error)
+
+	// ComplianceReport is compliance per SLA and warehouse or territory for
+	// orders that reached the SLA's start milestone in [from, to)
+	ComplianceReport(ctx context.Context, tenantID uuid.UUID, from, to time.Time, groupBy string, slaID *uuid.UUID) (*models.SLAComplianceReport, error)
+}
+
+type orderSLAService struct {
+	repo            repositories.OrderSLARepository
+	notificationSvc NotificationService
+}
+
+// NewOrderSLAService creates a new order SLA service
+func NewOrderSLAService(repo repositories.OrderSLARepository, notificationSvc NotificationService) OrderSLAService {
+	return &orderSLAService{
+		repo:            repo,
+		notificationSvc: notificationSvc,
+	}
+}
+
+func (s *orderSLAService) ListDefinitions(ctx context.Context, tenantID uuid.UUID) ([]*models.OrderSLA, error) {
+	return s.repo.ListDefinitions(ctx, tenantID, false)
+}
+
+func (s *orderSLAService) CreateDefinition(ctx context.Context, tenantID uuid.UUID, sla *models.OrderSLA) error {
+	if err := validateOrderSLA(sla); err != nil {
+		return err
+	}
+	sla.ID = uuid.New()
+	sla.TenantID = tenantID
+	return s.repo.CreateDefinition(ctx, sla)
+}
+
+func (s *orderSLAService) UpdateDefinition(ctx context.Context, tenantID uuid.UUID, sla *models.OrderSLA) error {
+	if err := validateOrderSLA(sla); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetDefinition(ctx, tenantID, sla.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("SLA not found")
+	}
+	sla.TenantID = tenantID
+	sla.CreatedAt = existing.CreatedAt
+	return s.repo.UpdateDefinition(ctx, sla)
+}
+
+func (s *orderSLAService) DeleteDefinition(ctx context.Context, tenantID, id uuid.UUID) error {
+	deleted, err := s.repo.DeleteDefinition(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("SLA not found")
+	}
+	return nil
+}
+
+// slaEventIndex places a milestone in the order lifecycle, -1 if unknown
+func slaEventIndex(event string) int {
+	for i, known := range models.SLAEvents {
+		if known == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func validateOrderSLA(sla *models.OrderSLA) error {
+	sla.Name = strings.TrimSpace(sla.Name)
+	if sla.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSLA)
+	}
+	if sla.OrderType != nil && *sla.OrderType != "purchase" && *sla.OrderType != "sales" {
+		return fmt.Errorf("%w: order_type must be purchase or sales", ErrInvalidSLA)
+	}
+	start, end := slaEventIndex(sla.StartEvent), slaEventIndex(sla.EndEvent)
+	if start < 0 || start == len(models.SLAEvents)-1 {
+		return fmt.Errorf("%w: start_event must be created, approved, processing or shipped", ErrInvalidSLA)
+	}
+	if end <= 0 {
+		return fmt.Errorf("%w: end_event must be approved, processing, shipped or delivered", ErrInvalidSLA)
+	}
+	if end <= start {
+		return fmt.Errorf("%w: end_event must come after start_event", ErrInvalidSLA)
+	}
+	if sla.TargetMinutes <= 0 || sla.TargetMinutes > slaMaxTargetDays*24*60 {
+		return fmt.Errorf("%w: target_minutes must be between 1 and %d", ErrInvalidSLA, slaMaxTargetDays*24*60)
+	}
+
+	emails := []string{}
+	for _, email := range sla.NotifyEmails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("%w: invalid notify email %q", ErrInvalidSLA, email)
+		}
+		emails = append(emails, email)
+	}
+	sla.NotifyEmails = emails
+	return nil
+}
+
+func (s *orderSLAService) RunBreachCheck(ctx context.Context, tenantID uuid.UUID, now time.Time) (int, error) {
+	slas, err := s.repo.ListDefinitions(ctx, tenantID, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list SLAs: %w", err)
+	}
+
+	found := 0
+	for _, sla := range slas {
+		if _, err := s.repo.ResolveBreaches(ctx, sla); err != nil {
+			log.Printf("Failed to resolve breaches of SLA %s: %v", sla.ID, err)
+		}
+
+		candidates, err := s.repo.FindBreaches(ctx, sla, now, now.Add(-slaBreachLookback), slaBreachBatch)
+		if err != nil {
+			log.Printf("Failed to check SLA %s: %v", sla.ID, err)
+			continue
+		}
+		var breaches []*models.OrderSLABreach
+		for _, breach := range candidates {
+			breach.ID = uuid.New()
+			recorded, err := s.repo.RecordBreach(ctx, breach)
+			if err != nil {
+				log.Printf("Failed to record SLA breach for order %s: %v", breach.OrderID, err)
+				continue
+			}
+			if recorded {
+				breaches = append(breaches, breach)
+			}
+		}
+		if len(breaches) > 0 {
+			s.alert(ctx, sla, breaches, now)
+			found += len(breaches)
+		}
+	}
+	return found, nil
+}
+
+// alert emails the SLA's recipients the orders that have just breached it
+// and pushes a summary; delivery failures are logged and not retried
+func (s *orderSLAService) alert(ctx context.Context, sla *models.OrderSLA, breaches []*models.OrderSLABreach, now time.Time) {
+	if s.notificationSvc == nil {
+		return
+	}
+
+	msg := &models.PushMessage{
+		Title: "SLA breached",
+		Body:  fmt.Sprintf("%d orders missed the %q SLA", len(breaches), sla.Name),
+		Data:  map[string]string{"event_type": "sla_breach", "sla_id": sla.ID.String()},
+	}
+	if len(breaches) == 1 {
+		msg.Body = fmt.Sprintf("%s order for %s missed the %q SLA", breaches[0].OrderType, breaches[0].ProductName, sla.Name)
+		msg.Data["order_id"] = breaches[0].OrderID.String()
+	}
+	if err := s.notificationSvc.SendPush(ctx, sla.TenantID, models.PushTopicSLABreaches, msg); err != nil {
+		log.Printf("Failed to push SLA breaches for SLA %s: %v", sla.ID, err)
+	}
+
+	if len(sla.NotifyEmails) == 0 {
+		return
+	}
+	subject := fmt.Sprintf("SLA breached: %s (%d orders)", sla.Name, len(breaches))
+	body := slaBreachEmailBody(sla, breaches, now)
+	for _, recipient := range sla.NotifyEmails {
+		if err := s.notificationSvc.SendEmail(ctx, sla.TenantID, recipient, subject, body); err != nil {
+			log.Printf("Failed to email SLA breaches to %s: %v", recipient, err)
+		}
+	}
+}
+
+func slaBreachEmailBody(sla *models.OrderSLA, breaches []*models.OrderSLABreach, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The following orders have not reached %s within %s of %s:\n\n", sla.EndEvent, formatSLATarget(sla.TargetMinutes), sla.StartEvent)
+	for i, breach := range breaches {
+		if i == slaAlertListLimit {
+			fmt.Fprintf(&b, "...and %d more\n", len(breaches)-slaAlertListLimit)
+			break
+		}
+		fmt.Fprintf(&b, "- %s order %s for %s at %s (status %s), due %s, %s late\n",
+			breach.OrderType, strings.ToUpper(breach.OrderID.String()[:8]), breach.ProductName, breach.WarehouseName,
+			breach.OrderStatus, breach.DueAt.Format("02 Jan 2006 15:04"), formatSLATarget(int(now.Sub(breach.DueAt).Minutes())))
+	}
+	return b.String()
+}
+
+// formatSLATarget renders minutes as days, hours and minutes, e.g. "2d 4h"
+func formatSLATarget(minutes int) string {
+	if minutes < 1 {
+		return "under a minute"
+	}
+	days, hours, mins := minutes/(24*60), minutes/60%24, minutes%60
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if mins > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", mins))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *orderSLAService) ListBreaches(ctx context.Context, tenantID uuid.UUID, slaID *uuid.UUID, openOnly bool, limit, offset int) ([]*models.OrderSLABreach, error) {
+	return s.repo.ListBreaches(ctx, tenantID, slaID, openOnly, limit, offset)
+}
+
+func (s *orderSLAService) ComplianceReport(ctx context.Context, tenantID uuid.UUID, from, to time.Time, groupBy string, slaID *uuid.UUID) (*models.SLAComplianceReport, error) {
+	if groupBy == "" {
+		groupBy = models.SLAGroupByWarehouse
+	}
+	if groupBy != models.SLAGroupByWarehouse && groupBy != models.SLAGroupByTerritory {
+		return nil, fmt.Errorf("%w: group_by must be warehouse or territory", ErrInvalidSLA)
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidSLA)
+	}
+
+	slas, err := s.repo.ListDefinitions(ctx, tenantID, false)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.SLAComplianceReport{From: from, To: to, GroupBy: groupBy, Rows: []*models.SLAComplianceRow{}}
+	now := time.Now()
+	for _, sla := range slas {
+		if slaID != nil && sla.ID != *slaID {
+			continue
+		}
+		rows, err := s.repo.Compliance(ctx, sla, from, to, now, groupBy)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if measured := row.Met + row.Breached; measured > 0 {
+				percent := math.Round(float64(row.Met)/float64(measured)*10000) / 100
+				row.CompliancePercent = &percent
+			}
+			if row.AvgMinutes != nil {
+				avg := math.Round(*row.AvgMinutes*10) / 10
+				row.AvgMinutes = &avg
+			}
+		}
+		report.Rows = append(report.Rows, rows...)
+	}
+	return report, nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrOrderEventNotInWorkflow is returned for an event that is not part of the
+// tenant's order workflow at all, e.g. a quality check without that step
+var ErrOrderEventNotInWorkflow = errors.New("order step is not part of the order workflow")
+
+// OrderTransitionError is returned for an event the order's workflow does not
+// allow from the order's current status
+type OrderTransitionError struct {
+	Event   string
+	Status  string
+	Allowed []string
+}
+
+func (e *OrderTransitionError) Error() string {
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("cannot %s this order, current status: %s", e.Event, e.Status)
+	}
+	return fmt.Sprintf("can only %s orders with status '%s', current status: %s", e.Event, strings.Join(e.Allowed, "' or '"), e.Status)
+}
+
+// OrderStateMachine is the declarative order lifecycle of one workflow. It
+// only decides which transitions are allowed; the order service runs the
+// side effects of each event
+type OrderStateMachine struct {
+	transitions []models.OrderTransition
+}
+
+// NewOrderStateMachine builds the lifecycle for a workflow; nil is the
+// default pending -> approved -> processing -> shipped -> delivered
+func NewOrderStateMachine(workflow *models.OrderWorkflow) *OrderStateMachine {
+	if workflow == nil {
+		workflow = &models.OrderWorkflow{}
+	}
+	processFrom := []string{models.OrderStatusApproved}
+	if workflow.SkipApproval {
+		processFrom = append(processFrom, models.OrderStatusPending)
+	}
+	// With a quality check, goods leave or enter stock only once checked.
+	// Orders already checked can always move on, so none are stranded when
+	// a tenant drops the step
+	releaseFrom := []string{models.OrderStatusProcessing, models.OrderStatusQualityCheck}
+	if workflow.QualityCheck {
+		releaseFrom = []string{models.OrderStatusQualityCheck}
+	}
+
+	transitions := []models.OrderTransition{
+		{Event: models.OrderEventApprove, From: []string{models.OrderStatusPending}, To: models.OrderStatusApproved},
+		{Event: models.OrderEventProcess, From: processFrom, To: models.OrderStatusProcessing},
+	}
+	if workflow.QualityCheck {
+		transitions = append(transitions, models.OrderTransition{
+			Event: models.OrderEventQualityCheck, From: []string{models.OrderStatusProcessing}, To: models.OrderStatusQualityCheck,
+		})
+	}
+	transitions = append(transitions,
+		models.OrderTransition{Event: models.OrderEventShip, From: releaseFrom, To: models.OrderStatusShipped},
+		models.OrderTransition{Event: models.OrderEventReceive, From: releaseFrom, To: models.OrderStatusDelivered, OrderType: "purchase"},
+		models.OrderTransition{Event: models.OrderEventDeliver, From: []string{models.OrderStatusShipped}, To: models.OrderStatusDelivered},
+		models.OrderTransition{Event: models.OrderEventCancel, To: models.OrderStatusCancelled, From: []string{
+			models.OrderStatusPending, models.OrderStatusApproved, models.OrderStatusProcessing, models.OrderStatusQualityCheck, models.OrderStatusShipped,
+		}},
+	)
+
+	return &OrderStateMachine{transitions: transitions}
+}
+
+// Transitions lists every transition of the workflow
+func (m *OrderStateMachine) Transitions() []models.OrderTransition {
+	return m.transitions
+}
+
+// Next is the status an event moves the order to, or an error when the
+// workflow does not allow it for the order
+func (m *OrderStateMachine) Next(order *models.Order, event string) (string, error) {
+	known := false
+	var allowed []string
+	for _, t := range m.transitions {
+		if t.Event != event {
+			continue
+		}
+		known = true
+		if t.OrderType != "" && t.OrderType != order.OrderType {
+			continue
+		}
+		for _, from := range t.From {
+			if from == order.Status {
+				return t.To, nil
+			}
+		}
+		allowed = append(allowed, t.From...)
+	}
+	if !known {
+		return "", fmt.Errorf("%w: %s", ErrOrderEventNotInWorkflow, event)
+	}
+	return "", &OrderTransitionError{Event: event, Status: order.Status, Allowed: allowed}
+}
+
+// Events lists the events the workflow allows for the order as it stands
+func (m *OrderStateMachine) Events(order *models.Order) []string {
+	events := []string{}
+	for _, t := range m.transitions {
+		if _, err := m.Next(order, t.Event); err == nil && !containsString(events, t.Event) {
+			events = append(events, t.Event)
+		}
+	}
+	return events
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderWorkflowService manages each tenant's order workflow and builds the
+// state machine orders are moved through
+type OrderWorkflowService interface {
+	GetWorkflow(ctx context.Context, tenantID uuid.UUID) (*models.OrderWorkflow, error)
+	SetWorkflow(ctx context.Context, workflow *models.OrderWorkflow) error
+	DeleteWorkflow(ctx context.Context, tenantID uuid.UUID) error
+	StateMachine(ctx context.Context, tenantID uuid.UUID) (*OrderStateMachine, error)
+}
+
+type orderWorkflowService struct {
+	repo repositories.OrderWorkflowRepository
+}
+
+func NewOrderWorkflowService(repo repositories.OrderWorkflowRepository) OrderWorkflowService {
+	return &orderWorkflowService{repo: repo}
+}
+
+// GetWorkflow returns the tenant's workflow, the default one when the tenant
+// has not configured any
+func (s *orderWorkflowService) GetWorkflow(ctx context.Context, tenantID uuid.UUID) (*models.OrderWorkflow, error) {
+	workflow, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if workflow == nil {
+		workflow = &models.OrderWorkflow{TenantID: tenantID}
+	}
+	return workflow, nil
+}
+
+func (s *orderWorkflowService) SetWorkflow(ctx context.Context, workflow *models.OrderWorkflow) error {
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		workflow.UpdatedBy = &userID
+	}
+	return s.repo.Upsert(ctx, workflow)
+}
+
+// DeleteWorkflow puts the tenant back on the default workflow
+func (s *orderWorkflowService) DeleteWorkflow(ctx context.Context, tenantID uuid.UUID) error {
+	return s.repo.Delete(ctx, tenantID)
+}
+
+func (s *orderWorkflowService) StateMachine(ctx context.Context, tenantID uuid.UUID) (*OrderStateMachine, error) {
+	workflow, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return NewOrderStateMachine(workflow), nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"agromart2/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Payload log sinks
+const (
+	PayloadLogSinkLog   = "log"
+	PayloadLogSinkRedis = "redis"
+)
+
+// DefaultPayloadLogStream is the Redis stream payload logs are added to
+const DefaultPayloadLogStream = "agromart:payload_log"
+
+// PayloadLogConfig selects where captured payloads go
+type PayloadLogConfig struct {
+	// Sink is "log" or "redis"; payload logging is off when it is empty
+	Sink string
+	// Stream and MaxLen name the Redis stream and cap its length; the
+	// oldest entries are trimmed as new ones arrive
+	Stream string
+	MaxLen int64
+	// SampleRate is the fraction of requests logged; MaxBody caps each
+	// captured body in bytes
+	SampleRate float64
+	MaxBody    int
+}
+
+// PayloadLogSink stores captured request and response payloads
+type PayloadLogSink interface {
+	Write(ctx context.Context, entry *models.PayloadLogEntry) error
+}
+
+// NewPayloadLogSink creates the sink selected by cfg, or nil when payload
+// logging is off
+func NewPayloadLogSink(cfg PayloadLogConfig, redisAddr, redisPassword string, redisDB int) (PayloadLogSink, error) {
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case PayloadLogSinkLog:
+		return logPayloadSink{}, nil
+	case PayloadLogSinkRedis:
+		stream := cfg.Stream
+		if stream == "" {
+			stream = DefaultPayloadLogStream
+		}
+		maxLen := cfg.MaxLen
+		if maxLen <= 0 {
+			maxLen = 10000
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: redisPassword,
+			DB:       redisDB,
+		})
+		return &redisStreamPayloadSink{client: client, stream: stream, maxLen: maxLen}, nil
+	}
+	return nil, fmt.Errorf("unsupported payload log sink: %s", cfg.Sink)
+}
+
+// logPayloadSink writes each entry to the application log as one JSON line
+type logPayloadSink struct{}
+
+func (logPayloadSink) Write(ctx context.Context, entry *models.PayloadLogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	log.Printf("Payload log: %s", data)
+	return nil
+}
+
+// redisStreamPayloadSink adds entries to a capped Redis stream, where they
+// can be read with XRANGE or XREAD while debugging an integration
+type redisStreamPayloadSink struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func (s *redisStreamPayloadSink) Write(ctx context.Context, entry *models.PayloadLogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"tenant_id": entry.TenantID.String(),
+			"route":     entry.Method + " " + entry.Route,
+			"entry":     data,
+		},
+	}).Err()
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// allocationTolerance absorbs paise rounding when comparing amounts
+const allocationTolerance = 0.005
+
+var (
+	// ErrPaymentNotFound is returned for customer payments outside the tenant
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrPaymentCustomerNotFound is returned for payments of an unknown customer
+	ErrPaymentCustomerNotFound = errors.New("customer not found")
+	// ErrInvalidAllocation wraps payment and allocation validation failures
+	ErrInvalidAllocation = errors.New("invalid payment allocation")
+	// ErrAllocationConflict is returned when invoices or the payment changed
+	// while an allocation was being applied
+	ErrAllocationConflict = errors.New("invoices changed while allocating the payment; please retry")
+)
+
+var paymentMethods = map[string]bool{
+	models.PaymentMethodCash:         true,
+	models.PaymentMethodUPI:          true,
+	models.PaymentMethodCard:         true,
+	models.PaymentMethodBankTransfer: true,
+	models.PaymentMethodCheque:       true,
+}
+
+// PaymentAllocationService records payments received from customers and
+// applies each across their open invoices, oldest first or as directed, keeping
+// invoice statuses in step with what is still outstanding on them
+type PaymentAllocationService interface {
+	RecordPayment(ctx context.Context, tenantID, customerID uuid.UUID, userID *uuid.UUID, req *models.CustomerPaymentRequest) (*models.CustomerPayment, error)
+	GetPayment(ctx context.Context, tenantID, paymentID uuid.UUID) (*models.CustomerPayment, error)
+	ListPayments(ctx context.Context, tenantID, customerID uuid.UUID, limit, offset int) ([]*models.CustomerPayment, error)
+	OpenInvoices(ctx context.Context, tenantID, customerID uuid.UUID) ([]*models.OpenInvoice, error)
+
+	// Allocate applies what is still unallocated on a payment
+	Allocate(ctx context.Context, tenantID, paymentID uuid.UUID, userID *uuid.UUID, req *models.AllocationRequest) (*models.CustomerPayment, error)
+	// Reallocate reverses a payment's allocations and applies it afresh
+	Reallocate(ctx context.Context, tenantID, paymentID uuid.UUID, userID *uuid.UUID, req *models.AllocationRequest) (*models.CustomerPayment, error)
+	// AllocationHistory lists every allocation of a payment, reversed ones included
+	AllocationHistory(ctx context.Context, tenantID, paymentID uuid.UUID) ([]*models.PaymentAllocation, error)
+	InvoiceAllocations(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.PaymentAllocation, error)
+}
+
+type paymentAllocationService struct {
+	repo            repositories.PaymentAllocationRepository
+	distributorRepo repositories.DistributorRepository
+	withholdingRepo repositories.WithholdingTaxRepository
+	periods         PeriodCloseService
+}
+
+// NewPaymentAllocationService creates a new payment allocation service
+func NewPaymentAllocationService(repo repositories.PaymentAllocationRepository, distributorRepo repositories.DistributorRepository, withholdingRepo repositories.WithholdingTaxRepository, periods PeriodCloseService) PaymentAllocationService {
+	return &paymentAllocationService{
+		repo:            repo,
+		distributorRepo: distributorRepo,
+		withholdingRepo: withholdingRepo,
+		periods:         periods,
+	}
+}
+
+func (s *paymentAllocationService) RecordPayment(ctx context.Context, tenantID, customerID uuid.UUID, userID *uuid.UUID, req *models.CustomerPaymentRequest) (*models.CustomerPayment, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidAllocation)
+	}
+	if err := s.requireCustomer(ctx, tenantID, customerID); err != nil {
+		return nil, err
+	}
+
+	paymentDate := time.Now()
+	if req.PaymentDate != nil && *req.PaymentDate != "" {
+		parsed, err := time.Parse("2006-01-02", *req.PaymentDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: payment_date must be in YYYY-MM-DD format", ErrInvalidAllocation)
+		}
+		if parsed.After(time.Now()) {
+			return nil, fmt.Errorf("%w: payment_date cannot be in the future", ErrInvalidAllocation)
+		}
+		paymentDate = parsed
+	}
+	if err := checkPeriodOpen(ctx, s.periods, tenantID, nil, paymentDate); err != nil {
+		return nil, err
+	}
+	var method *string
+	if req.Method != nil && *req.Method != "" {
+		m := strings.ToLower(strings.TrimSpace(*req.Method))
+		if !paymentMethods[m] {
+			return nil, fmt.Errorf("%w: method must be cash, upi, card, bank_transfer or cheque", ErrInvalidAllocation)
+		}
+		method = &m
+	}
+
+	tdsSection, err := s.tdsSection(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := roundAllocation(req.Amount)
+	tdsAmount := roundAllocation(req.TDSAmount)
+	lines, err := s.planAllocation(ctx, tenantID, customerID, amount+tdsAmount, &models.AllocationRequest{Mode: req.Mode, Allocations: req.Allocations}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New()
+	payment := &models.CustomerPayment{
+		ID:            id,
+		TenantID:      tenantID,
+		DistributorID: customerID,
+		PaymentNumber: fmt.Sprintf("RCPT-%s-%s", paymentDate.Format("200601"), strings.ToUpper(id.String()[:8])),
+		Amount:        amount,
+		PaymentDate:   paymentDate,
+		Method:        method,
+		Reference:     trimmedOrNil(req.Reference),
+		Notes:         trimmedOrNil(req.Notes),
+		TDSAmount:     tdsAmount,
+		CreatedBy:     userID,
+	}
+	if tdsSection != nil {
+		payment.TDSSectionID = &tdsSection.ID
+		payment.TDSSection = &tdsSection.Section
+		payment.TDSCertificateNumber = trimmedOrNil(req.TDSCertificateNumber)
+		if payment.TDSCertificateNumber != nil {
+			payment.TDSCertificateDate = &paymentDate
+		}
+	}
+	ok, err := s.repo.CreatePayment(ctx, payment, lines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record payment: %w", err)
+	}
+	if !ok {
+		return nil, ErrAllocationConflict
+	}
+	return s.GetPayment(ctx, tenantID, payment.ID)
+}
+
+func (s *paymentAllocationService) GetPayment(ctx context.Context, tenantID, paymentID uuid.UUID) (*models.CustomerPayment, error) {
+	payment, err := s.repo.GetPayment(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+	allocations, err := s.repo.ListAllocations(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	payment.Allocations = []*models.PaymentAllocation{}
+	for _, allocation := range allocations {
+		if allocation.ReversedAt == nil {
+			payment.Allocations = append(payment.Allocations, allocation)
+		}
+	}
+	return payment, nil
+}
+
+func (s *paymentAllocationService) ListPayments(ctx context.Context, tenantID, customerID uuid.UUID, limit, offset int) ([]*models.CustomerPayment, error) {
+	if err := s.requireCustomer(ctx, tenantID, customerID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPayments(ctx, tenantID, customerID, limit, offset)
+}
+
+func (s *paymentAllocationService) OpenInvoices(ctx context.Context, tenantID, customerID uuid.UUID) ([]*models.OpenInvoice, error) {
+	if err := s.requireCustomer(ctx, tenantID, customerID); err != nil {
+		return nil, err
+	}
+	return s.repo.OpenInvoices(ctx, tenantID, customerID, nil)
+}
+
+func (s *paymentAllocationService) Allocate(ctx context.Context, tenantID, paymentID uuid.UUID, userID *uuid.UUID, req *models.AllocationRequest) (*models.CustomerPayment, error) {
+	payment, err := s.GetPayment(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Unallocated <= allocationTolerance {
+		return nil, fmt.Errorf("%w: payment %s is fully allocated; reallocate it instead", ErrInvalidAllocation, payment.PaymentNumber)
+	}
+	return s.apply(ctx, payment, payment.Unallocated, userID, req, false)
+}
+
+func (s *paymentAllocationService) Reallocate(ctx context.Context, tenantID, paymentID uuid.UUID, userID *uuid.UUID, req *models.AllocationRequest) (*models.CustomerPayment, error) {
+	payment, err := s.GetPayment(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	// Moving a payment's allocations rewrites how the period it was received
+	// in was settled
+	if err := checkPeriodOpen(ctx, s.periods, tenantID, nil, payment.PaymentDate); err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, payment, payment.Settled(), userID, req, true)
+}
+
+// apply plans lines for up to available of the payment and records them. When
+// reallocating, the invoices are planned as if the payment's current
+// allocations were already reversed
+func (s *paymentAllocationService) apply(ctx context.Context, payment *models.CustomerPayment, available float64, userID *uuid.UUID, req *models.AllocationRequest, reallocate bool) (*models.CustomerPayment, error) {
+	var reallocated *models.CustomerPayment
+	if reallocate {
+		reallocated = payment
+	}
+
+	lines, err := s.planAllocation(ctx, payment.TenantID, payment.DistributorID, available, req, reallocated)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.Allocate(ctx, payment.TenantID, payment.ID, lines, userID, reallocate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate payment: %w", err)
+	}
+	if !ok {
+		return nil, ErrAllocationConflict
+	}
+	return s.GetPayment(ctx, payment.TenantID, payment.ID)
+}
+
+func (s *paymentAllocationService) AllocationHistory(ctx context.Context, tenantID, paymentID uuid.UUID) ([]*models.PaymentAllocation, error) {
+	payment, err := s.repo.GetPayment(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+	return s.repo.ListAllocations(ctx, tenantID, paymentID)
+}
+
+func (s *paymentAllocationService) InvoiceAllocations(ctx context.Context, tenantID, invoiceID uuid.UUID) ([]*models.PaymentAllocation, error) {
+	return s.repo.InvoiceAllocations(ctx, tenantID, invoiceID)
+}
+
+// planAllocation turns a request into allocation lines against the customer's
+// open invoices. When reallocating a payment, its current allocations are
+// treated as already reversed
+func (s *paymentAllocationService) planAllocation(ctx context.Context, tenantID, customerID uuid.UUID, available float64, req *models.AllocationRequest, reallocated *models.CustomerPayment) ([]models.AllocationLine, error) {
+	mode := strings.ToLower(strings.TrimSpace(req.Mode))
+	if mode == "" {
+		mode = models.AllocationModeAuto
+		if len(req.Allocations) > 0 {
+			mode = models.AllocationModeManual
+		}
+	}
+	if mode == models.AllocationModeNone {
+		return nil, nil
+	}
+	if mode != models.AllocationModeAuto && mode != models.AllocationModeManual {
+		return nil, fmt.Errorf("%w: mode must be auto, manual or none", ErrInvalidAllocation)
+	}
+
+	var paymentID *uuid.UUID
+	if reallocated != nil {
+		paymentID = &reallocated.ID
+	}
+	open, err := s.repo.OpenInvoices(ctx, tenantID, customerID, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open invoices: %w", err)
+	}
+	if reallocated != nil {
+		open = withReleasedAllocations(open, reallocated.Allocations)
+	}
+
+	if mode == models.AllocationModeAuto {
+		return planOldestFirst(open, available), nil
+	}
+	return checkManualAllocation(open, available, req.Allocations)
+}
+
+// tdsSection checks the TDS a customer deducted from a payment and returns the
+// active TDS section it was deducted under, or nil when there was none
+func (s *paymentAllocationService) tdsSection(ctx context.Context, tenantID uuid.UUID, req *models.CustomerPaymentRequest) (*models.TaxSection, error) {
+	if req.TDSAmount < 0 {
+		return nil, fmt.Errorf("%w: tds_amount cannot be negative", ErrInvalidAllocation)
+	}
+	if req.TDSAmount == 0 {
+		if req.TDSSectionID != nil || trimmedOrNil(req.TDSCertificateNumber) != nil {
+			return nil, fmt.Errorf("%w: tds_amount is required with a TDS section or certificate", ErrInvalidAllocation)
+		}
+		return nil, nil
+	}
+	if req.TDSSectionID == nil {
+		return nil, fmt.Errorf("%w: tds_section_id is required when TDS was deducted", ErrInvalidAllocation)
+	}
+	if req.TDSAmount >= req.Amount {
+		return nil, fmt.Errorf("%w: tds_amount must be less than the amount received", ErrInvalidAllocation)
+	}
+
+	section, err := s.withholdingRepo.GetSection(ctx, tenantID, *req.TDSSectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TDS section: %w", err)
+	}
+	if section == nil || section.Kind != models.WithholdingKindTDS || !section.Active {
+		return nil, fmt.Errorf("%w: tds_section_id is not an active TDS section", ErrInvalidAllocation)
+	}
+	return section, nil
+}
+
+func (s *paymentAllocationService) requireCustomer(ctx context.Context, tenantID, customerID uuid.UUID) error {
+	distributor, err := s.distributorRepo.GetByID(ctx, tenantID, customerID)
+	if err != nil || distributor == nil {
+		return ErrPaymentCustomerNotFound
+	}
+	return nil
+}
+
+// withReleasedAllocations adds back what reversing a payment's allocations
+// frees up on each invoice and drops invoices left with nothing outstanding
+func withReleasedAllocations(open []*models.OpenInvoice, allocations []*models.PaymentAllocation) []*models.OpenInvoice {
+	released := make(map[uuid.UUID]float64, len(allocations))
+	for _, allocation := range allocations {
+		released[allocation.InvoiceID] += allocation.Amount
+	}
+
+	reopened := make([]*models.OpenInvoice, 0, len(open))
+	for _, invoice := range open {
+		if amount, ok := released[invoice.InvoiceID]; ok {
+			invoice.Allocated = roundAllocation(invoice.Allocated - amount)
+			invoice.Outstanding = roundAllocation(invoice.Outstanding + amount)
+		}
+		if invoice.Outstanding > allocationTolerance {
+			reopened = append(reopened, invoice)
+		}
+	}
+	return reopened
+}
+
+// planOldestFirst settles the open invoices in order, oldest due first, until
+// the amount runs out
+func planOldestFirst(open []*models.OpenInvoice, available float64) []models.AllocationLine {
+	var lines []models.AllocationLine
+	remaining := roundAllocation(available)
+	for _, invoice := range open {
+		if remaining <= allocationTolerance {
+			break
+		}
+		amount := roundAllocation(math.Min(remaining, invoice.Outstanding))
+		if amount <= 0 {
+			continue
+		}
+		lines = append(lines, models.AllocationLine{InvoiceID: invoice.InvoiceID, Amount: amount})
+		remaining = roundAllocation(remaining - amount)
+	}
+	return lines
+}
+
+// checkManualAllocation validates requested lines against the open invoices,
+// merging repeated invoices
+func checkManualAllocation(open []*models.OpenInvoice, available float64, requested []models.AllocationLine) ([]models.AllocationLine, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: allocations are required for manual allocation", ErrInvalidAllocation)
+	}
+	outstanding := make(map[uuid.UUID]*models.OpenInvoice, len(open))
+	for _, invoice := range open {
+		outstanding[invoice.InvoiceID] = invoice
+	}
+
+	var lines []models.AllocationLine
+	index := make(map[uuid.UUID]int, len(requested))
+	total := 0.0
+	for _, line := range requested {
+		if line.Amount <= 0 {
+			return nil, fmt.Errorf("%w: allocation amounts must be positive", ErrInvalidAllocation)
+		}
+		if _, ok := outstanding[line.InvoiceID]; !ok {
+			return nil, fmt.Errorf("%w: invoice %s is not an open invoice of this customer", ErrInvalidAllocation, line.InvoiceID)
+		}
+		amount := roundAllocation(line.Amount)
+		if i, ok := index[line.InvoiceID]; ok {
+			lines[i].Amount = roundAllocation(lines[i].Amount + amount)
+		} else {
+			index[line.InvoiceID] = len(lines)
+			lines = append(lines, models.AllocationLine{InvoiceID: line.InvoiceID, Amount: amount})
+		}
+		total += amount
+	}
+
+	for _, line := range lines {
+		invoice := outstanding[line.InvoiceID]
+		if line.Amount > invoice.Outstanding+allocationTolerance {
+			return nil, fmt.Errorf("%w: %.2f exceeds the %.2f outstanding on invoice %s",
+				ErrInvalidAllocation, line.Amount, invoice.Outstanding, invoice.InvoiceNumber)
+		}
+	}
+	if total > available+allocationTolerance {
+		return nil, fmt.Errorf("%w: allocations total %.2f but only %.2f of the payment is available", ErrInvalidAllocation, total, available)
+	}
+	return lines, nil
+}
+
+func trimmedOrNil(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func roundAllocation(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrPeriodClosed is returned for invoices and payments dated in a
+	// closed accounting period
+	ErrPeriodClosed = errors.New("accounting period is closed")
+	// ErrInvalidPeriodClose wraps period close validation failures
+	ErrInvalidPeriodClose = errors.New("invalid period close")
+	// ErrPeriodCloseConflict is returned when closing a closed period or
+	// reopening an open one
+	ErrPeriodCloseConflict = errors.New("period close conflict")
+)
+
+// PeriodCloseService closes accounting months, per tenant or per branch, so
+// the invoices and payments dated in them can no longer change
+type PeriodCloseService interface {
+	Close(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.PeriodCloseRequest) (*models.PeriodClose, error)
+	Reopen(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.PeriodCloseRequest) error
+	List(ctx context.Context, tenantID uuid.UUID) ([]*models.PeriodClose, error)
+	History(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PeriodCloseEvent, error)
+	// CheckOpen returns ErrPeriodClosed when the date falls in a month closed
+	// for the tenant or for the branch
+	CheckOpen(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, date time.Time) error
+}
+
+type periodCloseService struct {
+	repo      repositories.PeriodCloseRepository
+	branchSvc BranchService
+	calendars TenantCalendarService
+}
+
+// NewPeriodCloseService creates a new period close service instance
+func NewPeriodCloseService(repo repositories.PeriodCloseRepository, branchSvc BranchService, calendars TenantCalendarService) PeriodCloseService {
+	return &periodCloseService{
+		repo:      repo,
+		branchSvc: branchSvc,
+		calendars: calendars,
+	}
+}
+
+// checkPeriodOpen checks the date against the closed periods, every period
+// being open when the service isn't wired in
+func checkPeriodOpen(ctx context.Context, periods PeriodCloseService, tenantID uuid.UUID, branchID *uuid.UUID, date time.Time) error {
+	if periods == nil {
+		return nil
+	}
+	return periods.CheckOpen(ctx, tenantID, branchID, date)
+}
+
+// parsePeriod reads the request's month and checks its branch
+func (s *periodCloseService) parsePeriod(ctx context.Context, tenantID uuid.UUID, req *models.PeriodCloseRequest) (time.Time, error) {
+	period, err := time.Parse("2006-01", strings.TrimSpace(req.Period))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: period must be in YYYY-MM format", ErrInvalidPeriodClose)
+	}
+	if req.BranchID != nil {
+		if _, err := s.branchSvc.Get(ctx, tenantID, *req.BranchID); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return period, nil
+}
+
+func (s *periodCloseService) Close(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.PeriodCloseRequest) (*models.PeriodClose, error) {
+	period, err := s.parsePeriod(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	today := TenantCalendarOrDefault(ctx, s.calendars, tenantID).Today(time.Now())
+	if !period.Before(time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+		return nil, fmt.Errorf("%w: only months that have ended can be closed", ErrInvalidPeriodClose)
+	}
+
+	reason := trimmedOrNil(req.Reason)
+	periodClose := &models.PeriodClose{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+		BranchID: req.BranchID,
+		Period:   period,
+		Notes:    reason,
+		ClosedBy: userID,
+	}
+	event := &models.PeriodCloseEvent{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+		BranchID: req.BranchID,
+		Period:   period,
+		Action:   models.PeriodClosed,
+		Reason:   reason,
+		UserID:   userID,
+	}
+	closed, err := s.repo.Close(ctx, periodClose, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close period: %w", err)
+	}
+	if !closed {
+		return nil, fmt.Errorf("%w: %s is already closed", ErrPeriodCloseConflict, period.Format("2006-01"))
+	}
+	return periodClose, nil
+}
+
+func (s *periodCloseService) Reopen(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.PeriodCloseRequest) error {
+	period, err := s.parsePeriod(ctx, tenantID, req)
+	if err != nil {
+		return err
+	}
+	reason := trimmedOrNil(req.Reason)
+	if reason == nil {
+		return fmt.Errorf("%w: a reason is required to reopen a period", ErrInvalidPeriodClose)
+	}
+
+	event := &models.PeriodCloseEvent{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+		BranchID: req.BranchID,
+		Period:   period,
+		Action:   models.PeriodReopened,
+		Reason:   reason,
+		UserID:   userID,
+	}
+	reopened, err := s.repo.Reopen(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to reopen period: %w", err)
+	}
+	if !reopened {
+		return fmt.Errorf("%w: %s is not closed", ErrPeriodCloseConflict, period.Format("2006-01"))
+	}
+	return nil
+}
+
+func (s *periodCloseService) List(ctx context.Context, tenantID uuid.UUID) ([]*models.PeriodClose, error) {
+	closes, err := s.repo.List(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list closed periods: %w", err)
+	}
+	if closes == nil {
+		closes = []*models.PeriodClose{}
+	}
+	return closes, nil
+}
+
+func (s *periodCloseService) History(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PeriodCloseEvent, error) {
+	events, err := s.repo.ListEvents(ctx, tenantID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load period close history: %w", err)
+	}
+	if events == nil {
+		events = []*models.PeriodCloseEvent{}
+	}
+	return events, nil
+}
+
+func (s *periodCloseService) CheckOpen(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, date time.Time) error {
+	periodClose, err := s.repo.Covering(ctx, tenantID, branchID, date)
+	if err != nil {
+		return fmt.Errorf("failed to check closed periods: %w", err)
+	}
+	if periodClose != nil {
+		return fmt.Errorf("%w: %s is closed, date the document in an open period", ErrPeriodClosed, periodClose.Period.Format("2006-01"))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidDimensions wraps product dimension validation failures
+var ErrInvalidDimensions = errors.New("invalid product dimensions")
+
+// maxDimensionCm bounds each side of a unit; larger values are typos
+const maxDimensionCm = 10000
+
+// ProductDimensionsService keeps the size of product units, used to compute
+// warehouse volume and pallet utilization
+type ProductDimensionsService interface {
+	// GetProductDimensions returns nil when the product has no dimensions
+	GetProductDimensions(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductDimensions, error)
+	SetProductDimensions(ctx context.Context, tenantID uuid.UUID, dimensions *models.ProductDimensions) error
+}
+
+type productDimensionsService struct {
+	capacityRepo repositories.WarehouseCapacityRepository
+	productRepo  repositories.ProductRepository
+}
+
+// NewProductDimensionsService creates a new product dimensions service instance
+func NewProductDimensionsService(capacityRepo repositories.WarehouseCapacityRepository, productRepo repositories.ProductRepository) ProductDimensionsService {
+	return &productDimensionsService{
+		capacityRepo: capacityRepo,
+		productRepo:  productRepo,
+	}
+}
+
+func (s *productDimensionsService) GetProductDimensions(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductDimensions, error) {
+	if product, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil || product == nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidDimensions)
+	}
+	dimensions, err := s.capacityRepo.GetProductDimensions(ctx, tenantID, productID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get product dimensions", err)
+	}
+	return dimensions, nil
+}
+
+func (s *productDimensionsService) SetProductDimensions(ctx context.Context, tenantID uuid.UUID, dimensions *models.ProductDimensions) error {
+	if product, err := s.productRepo.GetByID(ctx, tenantID, dimensions.ProductID); err != nil || product == nil {
+		return fmt.Errorf("%w: product not found", ErrInvalidDimensions)
+	}
+	for _, side := range []float64{dimensions.LengthCm, dimensions.WidthCm, dimensions.HeightCm} {
+		if side <= 0 || side > maxDimensionCm || math.IsNaN(side) {
+			return fmt.Errorf("%w: length, width and height must be between 0 and %d cm", ErrInvalidDimensions, maxDimensionCm)
+		}
+	}
+	if dimensions.UnitsPerPallet != nil && *dimensions.UnitsPerPallet <= 0 {
+		return fmt.Errorf("%w: units_per_pallet must be greater than 0", ErrInvalidDimensions)
+	}
+
+	dimensions.TenantID = tenantID
+	if err := s.capacityRepo.UpsertProductDimensions(ctx, dimensions); err != nil {
+		return common.SecureErrorMessage("set product dimensions", err)
+	}
+	return nil
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"agromart2/internal/caching"
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
@@ -19,40 +22,66 @@ import (
 type ProductService interface {
 	Create(ctx context.Context, tenantID uuid.UUID, product *models.Product) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Product, error)
+	// GetByIDs returns the products found among ids, in request order
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Product, error)
 	Update(ctx context.Context, tenantID uuid.UUID, product *models.Product) error
 	Delete(ctx context.Context, tenantID, id uuid.UUID) error
-	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Product, error)
+	List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Product, error)
 	GetByBarcode(ctx context.Context, tenantID uuid.UUID, barcode string) (*models.Product, error)
 	UpdateStock(ctx context.Context, tenantID, productID uuid.UUID, change int) error
 	Search(ctx context.Context, tenantID uuid.UUID, query string, categoryID *uuid.UUID, limit, offset int) ([]*models.Product, error)
 	CategoryAnalytics(ctx context.Context, tenantID uuid.UUID) (map[string]int, error)
 	UploadProductImage(ctx context.Context, tenantID, productID uuid.UUID, filename string, reader io.Reader, size int64, altText *string) error
 	GetProductImages(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.ProductImage, error)
+	// GetImagesByProductIDs returns the images of several products keyed by product ID
+	GetImagesByProductIDs(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID][]*models.ProductImage, error)
+	// GetCategoriesByIDs returns the categories found among ids keyed by category ID
+	GetCategoriesByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*models.Category, error)
 	GetProductImageURL(ctx context.Context, tenantID, imageID uuid.UUID, expiry time.Duration) (string, error)
 	DeleteProductImage(ctx context.Context, tenantID, imageID uuid.UUID) error
 
+	// Price history
+	GetPriceHistory(ctx context.Context, tenantID, productID uuid.UUID, limit, offset int) ([]*models.PriceHistory, error)
+	GetPriceRealization(ctx context.Context, tenantID, productID uuid.UUID, since time.Time) (*models.PriceRealization, error)
+
 	// Bulk operations
 	BulkUpdateProducts(ctx context.Context, tenantID uuid.UUID, bulkUpdate *models.ProductBulkUpdate) (*models.BulkOperationResult, error)
 	BulkCreateProducts(ctx context.Context, tenantID uuid.UUID, bulkCreate *models.ProductBulkCreate) (*models.BulkOperationResult, error)
+	// BulkDeleteProducts deletes products that nothing depends on, or with
+	// DryRun reports which deletes would be blocked
+	BulkDeleteProducts(ctx context.Context, tenantID uuid.UUID, bulkDelete *models.ProductBulkDelete) *models.BulkOperationResult
 }
 
+// ErrNoWarehouseForStock is returned when a stock change cannot be routed to a warehouse
+var ErrNoWarehouseForStock = errors.New("no default warehouse configured for tenant")
+
 type productService struct {
 	productRepo      repositories.ProductRepository
 	inventoryRepo    repositories.InventoryRepository
 	categoryRepo     repositories.CategoryRepository
 	productImageRepo repositories.ProductImageRepository
+	warehouseRepo    repositories.WarehouseRepository
+	inventoryService InventoryService
+	priceHistoryRepo repositories.PriceHistoryRepository
 	minioService     MinioService
 	cacheService     caching.CacheService
+	dependencySvc    DependencyService
+	bulkOps          BulkOperationService
 }
 
-func NewProductService(productRepo repositories.ProductRepository, inventoryRepo repositories.InventoryRepository, categoryRepo repositories.CategoryRepository, productImageRepo repositories.ProductImageRepository, minioService MinioService, cacheService caching.CacheService) ProductService {
+func NewProductService(productRepo repositories.ProductRepository, inventoryRepo repositories.InventoryRepository, categoryRepo repositories.CategoryRepository, productImageRepo repositories.ProductImageRepository, warehouseRepo repositories.WarehouseRepository, inventoryService InventoryService, priceHistoryRepo repositories.PriceHistoryRepository, minioService MinioService, cacheService caching.CacheService, dependencySvc DependencyService, bulkOps BulkOperationService) ProductService {
 	return &productService{
 		productRepo:      productRepo,
 		inventoryRepo:    inventoryRepo,
 		categoryRepo:     categoryRepo,
 		productImageRepo: productImageRepo,
+		warehouseRepo:    warehouseRepo,
+		inventoryService: inventoryService,
+		priceHistoryRepo: priceHistoryRepo,
 		minioService:     minioService,
 		cacheService:     cacheService,
+		dependencySvc:    dependencySvc,
+		bulkOps:          bulkOps,
 	}
 }
 
@@ -83,30 +112,77 @@ func (s *productService) Create(ctx context.Context, tenantID uuid.UUID, product
 		}
 	}
 	product.ID = uuid.New()
-	return s.productRepo.Create(ctx, product)
+
+	// Opening stock is booked into a warehouse; the product row only mirrors the total.
+	// The warehouse is picked before the insert so a tenant without one gets an
+	// error and no half-created product to retry into a duplicate
+	openingStock := product.Quantity
+	var openingWarehouseID uuid.UUID
+	if openingStock > 0 {
+		warehouseID, err := s.selectInboundWarehouse(ctx, tenantID, nil)
+		if err != nil {
+			return err
+		}
+		openingWarehouseID = warehouseID
+	}
+
+	product.Quantity = 0
+	if err := s.productRepo.Create(ctx, product); err != nil {
+		return err
+	}
+	if openingStock > 0 {
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, openingWarehouseID, product.ID, openingStock, models.InventoryReasonProductEdit); err != nil {
+			return fmt.Errorf("product created but opening stock was not recorded: %w", err)
+		}
+		if err := s.syncProductQuantity(ctx, tenantID, product); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *productService) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Product, error) {
-	// Try to get from cache first
-	if cachedProduct, err := s.cacheService.GetProduct(ctx, tenantID, id); cachedProduct != nil {
-		return cachedProduct, nil
-	} else if err != nil {
-		// Log error but continue to database - cache errors shouldn't fail the operation
-		fmt.Printf("Cache error for product %s: %v\n", id.String(), err)
-	}
+	// Serve from cache; concurrent misses on a hot product share one query (TTL: 15 minutes)
+	return s.cacheService.GetOrLoadProduct(ctx, tenantID, id, 15*time.Minute, func(ctx context.Context) (*models.Product, error) {
+		return s.productRepo.GetByID(ctx, tenantID, id)
+	})
+}
 
-	// Cache miss - get from database
-	product, err := s.productRepo.GetByID(ctx, tenantID, id)
+// GetByIDs serves what it can from the cache in one round trip and loads the
+// rest in one query
+func (s *productService) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Product, error) {
+	found, err := s.cacheService.GetProducts(ctx, tenantID, ids)
 	if err != nil {
-		return nil, err
+		fmt.Printf("Cache error for products: %v\n", err)
+		found = make(map[uuid.UUID]*models.Product, len(ids))
+	}
+	var misses []uuid.UUID
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			misses = append(misses, id)
+		}
 	}
 
-	// Cache the product for future requests (TTL: 15 minutes)
-	if cacheErr := s.cacheService.SetProduct(ctx, tenantID, product, 15*time.Minute); cacheErr != nil {
-		fmt.Printf("Failed to cache product %s: %v\n", id.String(), cacheErr)
+	if len(misses) > 0 {
+		products, err := s.productRepo.GetByIDs(ctx, tenantID, misses)
+		if err != nil {
+			return nil, common.SecureErrorMessage("get products", err)
+		}
+		for _, product := range products {
+			found[product.ID] = product
+		}
+		if cacheErr := s.cacheService.SetProducts(ctx, tenantID, products, 15*time.Minute); cacheErr != nil {
+			fmt.Printf("Failed to cache products: %v\n", cacheErr)
+		}
 	}
 
-	return product, nil
+	products := make([]*models.Product, 0, len(found))
+	for _, id := range ids {
+		if product, ok := found[id]; ok {
+			products = append(products, product)
+		}
+	}
+	return products, nil
 }
 
 func (s *productService) Update(ctx context.Context, tenantID uuid.UUID, product *models.Product) error {
@@ -115,25 +191,53 @@ func (s *productService) Update(ctx context.Context, tenantID uuid.UUID, product
 	if err != nil {
 		return err
 	}
-	if product.Quantity != existing.Quantity {
-		change := product.Quantity - existing.Quantity
-		s.UpdateStock(ctx, tenantID, product.ID, change)
+	// The product row is written before any stock moves, so a stale If-Match
+	// fails the request without touching inventory; the quantity mirror is
+	// then brought in line with the stock
+	change := product.Quantity - existing.Quantity
+	if change != 0 {
+		stock, err := s.inventoryService.GetProductStock(ctx, tenantID, product.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkStockChange(ctx, tenantID, stock, change); err != nil {
+			return err
+		}
 	}
-
-	err = s.productRepo.Update(ctx, product)
-	if err != nil {
+	product.Quantity = existing.Quantity
+	if err := s.productRepo.Update(ctx, product); err != nil {
 		return err
 	}
+	if change != 0 {
+		if err := s.UpdateStock(ctx, tenantID, product.ID, change); err != nil {
+			return err
+		}
+		// Keep the mirrored total that UpdateStock just wrote
+		synced, err := s.productRepo.GetByID(ctx, tenantID, product.ID)
+		if err != nil {
+			return err
+		}
+		product.Quantity = synced.Quantity
+	}
+
+	if product.UnitPrice != existing.UnitPrice {
+		s.recordPriceChange(ctx, tenantID, product.ID, existing.UnitPrice, product.UnitPrice, models.PriceChangeSourceManual)
+	}
 
 	// Invalidate cache for this product
 	if cacheErr := s.cacheService.DeleteProduct(ctx, tenantID, product.ID); cacheErr != nil {
 		fmt.Printf("Failed to invalidate cache for product %s: %v\n", product.ID.String(), cacheErr)
 	}
+	caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventProductUpdated)
 
 	return nil
 }
 
 func (s *productService) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	if err := s.dependencySvc.CheckDeletable(ctx, tenantID, models.DependentProduct, id); err != nil {
+		return err
+	}
+
 	err := s.productRepo.Delete(ctx, tenantID, id)
 	if err != nil {
 		return err
@@ -143,12 +247,13 @@ func (s *productService) Delete(ctx context.Context, tenantID, id uuid.UUID) err
 	if cacheErr := s.cacheService.DeleteProduct(ctx, tenantID, id); cacheErr != nil {
 		fmt.Printf("Failed to invalidate cache for product %s: %v\n", id.String(), cacheErr)
 	}
+	caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventProductDeleted)
 
 	return nil
 }
 
-func (s *productService) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Product, error) {
-	return s.productRepo.List(ctx, tenantID, limit, offset)
+func (s *productService) List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Product, error) {
+	return s.productRepo.ListPage(ctx, tenantID, page)
 }
 
 func (s *productService) GetByBarcode(ctx context.Context, tenantID uuid.UUID, barcode string) (*models.Product, error) {
@@ -167,32 +272,205 @@ func (s *productService) GetByBarcode(ctx context.Context, tenantID uuid.UUID, b
 	return product, nil
 }
 
+type priceChangeReasonKey struct{}
+
+// WithPriceChangeReason attaches the reason for a price change to the context so
+// it is stored in the price history alongside the change
+func WithPriceChangeReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, priceChangeReasonKey{}, reason)
+}
+
+// recordPriceChange appends to the price history; failures are logged so the
+// price update itself still succeeds
+func (s *productService) recordPriceChange(ctx context.Context, tenantID, productID uuid.UUID, oldPrice, newPrice float64, source string) {
+	entry := &models.PriceHistory{
+		ID:        uuid.New(),
+		TenantID:  tenantID,
+		ProductID: productID,
+		OldPrice:  oldPrice,
+		NewPrice:  newPrice,
+		Source:    source,
+	}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		entry.ChangedBy = &userID
+	}
+	if reason, ok := ctx.Value(priceChangeReasonKey{}).(string); ok && strings.TrimSpace(reason) != "" {
+		entry.Reason = &reason
+	}
+
+	if err := s.priceHistoryRepo.Create(ctx, entry); err != nil {
+		fmt.Printf("Failed to record price change for product %s: %v\n", productID.String(), err)
+	}
+}
+
+// GetPriceHistory returns a product's price changes, newest first
+func (s *productService) GetPriceHistory(ctx context.Context, tenantID, productID uuid.UUID, limit, offset int) ([]*models.PriceHistory, error) {
+	if _, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+	return s.priceHistoryRepo.ListByProduct(ctx, tenantID, productID, limit, offset)
+}
+
+// GetPriceRealization compares the average selling price since a date with the current list price
+func (s *productService) GetPriceRealization(ctx context.Context, tenantID, productID uuid.UUID, since time.Time) (*models.PriceRealization, error) {
+	product, err := s.productRepo.GetByID(ctx, tenantID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	avgPrice, units, orders, err := s.priceHistoryRepo.SellingPriceSummary(ctx, tenantID, productID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	realization := &models.PriceRealization{
+		ListPrice:       product.UnitPrice,
+		AvgSellingPrice: math.Round(avgPrice*100) / 100,
+		UnitsSold:       units,
+		OrdersCount:     orders,
+		Since:           since,
+	}
+	if product.UnitPrice > 0 && units > 0 {
+		realization.RealizationPercent = math.Round(avgPrice/product.UnitPrice*10000) / 100
+	}
+	return realization, nil
+}
+
+// UpdateStock applies a stock change through inventory and refreshes the
+// deprecated product-level quantity from the per-warehouse totals
 func (s *productService) UpdateStock(ctx context.Context, tenantID, productID uuid.UUID, change int) error {
-	// TODO: Integrate with warehouse management to get appropriate warehouse
-	// For now, use a temporary default warehouse approach
 	product, err := s.productRepo.GetByID(ctx, tenantID, productID)
 	if err != nil {
 		return err
 	}
+	if change == 0 {
+		return nil
+	}
 
-	// Update main product quantity for backward compatibility
-	product.Quantity += change
-	if product.Quantity < 0 {
-		product.Quantity = 0
+	stock, err := s.inventoryService.GetProductStock(ctx, tenantID, productID)
+	if err != nil {
+		return err
 	}
 
-	// TODO: Get actual default warehouse for tenant
-	// defaultWarehouseID := s.getDefaultWarehouseID(ctx, tenantID)
-	// Also move to use inventory service's AdjustStock method
+	if change > 0 {
+		warehouseID, err := s.selectInboundWarehouse(ctx, tenantID, stock)
+		if err != nil {
+			return err
+		}
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, warehouseID, productID, change, models.InventoryReasonProductEdit); err != nil {
+			return err
+		}
+	} else {
+		if err := s.drainStock(ctx, tenantID, productID, stock, -change); err != nil {
+			return err
+		}
+	}
+	return s.syncProductQuantity(ctx, tenantID, product)
+}
+
+// syncProductQuantity mirrors the per-warehouse total onto the product for
+// clients still reading product.quantity
+func (s *productService) syncProductQuantity(ctx context.Context, tenantID uuid.UUID, product *models.Product) error {
+	stock, err := s.inventoryService.GetProductStock(ctx, tenantID, product.ID)
+	if err != nil {
+		return err
+	}
+	product.Quantity = stockOnHand(stock)
+	if err := s.productRepo.Update(ctx, product); err != nil {
+		return err
+	}
+
+	if cacheErr := s.cacheService.DeleteProduct(ctx, tenantID, product.ID); cacheErr != nil {
+		fmt.Printf("Failed to invalidate cache for product %s: %v\n", product.ID.String(), cacheErr)
+	}
+	caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventProductUpdated)
+	return nil
+}
+
+// selectInboundWarehouse picks where added stock goes: the tenant default, else the
+// only warehouse already holding the product, else the tenant's only warehouse
+func (s *productService) selectInboundWarehouse(ctx context.Context, tenantID uuid.UUID, stock []*models.Inventory) (uuid.UUID, error) {
+	if warehouse, err := s.warehouseRepo.GetDefault(ctx, tenantID); err == nil {
+		return warehouse.ID, nil
+	}
+	if len(stock) == 1 {
+		return stock[0].WarehouseID, nil
+	}
+	warehouses, err := s.warehouseRepo.List(ctx, tenantID, 2, 0)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(warehouses) == 1 {
+		return warehouses[0].ID, nil
+	}
+	return uuid.Nil, ErrNoWarehouseForStock
+}
 
-	return s.productRepo.Update(ctx, product)
+// checkStockChange fails as UpdateStock would when change cannot be applied
+// to stock, so an edit can be refused before anything is written
+func (s *productService) checkStockChange(ctx context.Context, tenantID uuid.UUID, stock []*models.Inventory, change int) error {
+	if change > 0 {
+		_, err := s.selectInboundWarehouse(ctx, tenantID, stock)
+		return err
+	}
+	if onHand := stockOnHand(stock); -change > onHand {
+		return fmt.Errorf("%w: cannot remove %d, %d on hand", ErrInsufficientStock, -change, onHand)
+	}
+	return nil
+}
+
+// stockOnHand totals a product's stock across warehouses
+func stockOnHand(stock []*models.Inventory) int {
+	total := 0
+	for _, inv := range stock {
+		total += inv.Quantity
+	}
+	return total
+}
+
+// drainStock removes quantity starting with the default warehouse, then the
+// warehouses holding the most stock, so a single edit never goes negative.
+// Removing more than is on hand fails before any warehouse is touched
+func (s *productService) drainStock(ctx context.Context, tenantID, productID uuid.UUID, stock []*models.Inventory, quantity int) error {
+	available := stockOnHand(stock)
+	if quantity > available {
+		return fmt.Errorf("%w: cannot remove %d, %d on hand", ErrInsufficientStock, quantity, available)
+	}
+
+	ordered := stock
+	if warehouse, err := s.warehouseRepo.GetDefault(ctx, tenantID); err == nil {
+		ordered = make([]*models.Inventory, 0, len(stock))
+		for _, inv := range stock {
+			if inv.WarehouseID == warehouse.ID {
+				ordered = append([]*models.Inventory{inv}, ordered...)
+			} else {
+				ordered = append(ordered, inv)
+			}
+		}
+	}
+
+	remaining := quantity
+	for _, inv := range ordered {
+		if remaining == 0 {
+			break
+		}
+		take := min(inv.Quantity, remaining)
+		if take == 0 {
+			continue
+		}
+		if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, inv.WarehouseID, productID, -take, models.InventoryReasonProductEdit); err != nil {
+			return err
+		}
+		remaining -= take
+	}
+	return nil
 }
 
 // Search products by query string with optional category filter
 func (s *productService) Search(ctx context.Context, tenantID uuid.UUID, query string, categoryID *uuid.UUID, limit, offset int) ([]*models.Product, error) {
 
 	if query == "" {
-		products, err := s.List(ctx, tenantID, limit, offset)
+		products, err := s.productRepo.List(ctx, tenantID, limit, offset)
 		return products, err
 	}
 
@@ -255,7 +533,11 @@ func (s *productService) UploadProductImage(ctx context.Context, tenantID, produ
 		AltText:   altText,
 	}
 
-	return s.productImageRepo.Create(ctx, image)
+	if err := s.productImageRepo.Create(ctx, image); err != nil {
+		return err
+	}
+	caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventProductUpdated)
+	return nil
 }
 
 // GetProductImages retrieves all images for a product
@@ -263,6 +545,36 @@ func (s *productService) GetProductImages(ctx context.Context, tenantID, product
 	return s.productImageRepo.GetByProductID(ctx, tenantID, productID)
 }
 
+func (s *productService) GetImagesByProductIDs(ctx context.Context, tenantID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID][]*models.ProductImage, error) {
+	byProduct := make(map[uuid.UUID][]*models.ProductImage, len(productIDs))
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
+	images, err := s.productImageRepo.GetByProductIDs(ctx, tenantID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		byProduct[image.ProductID] = append(byProduct[image.ProductID], image)
+	}
+	return byProduct, nil
+}
+
+func (s *productService) GetCategoriesByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*models.Category, error) {
+	byID := make(map[uuid.UUID]*models.Category, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+	categories, err := s.categoryRepo.GetByIDs(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	return byID, nil
+}
+
 // GetProductImageURL generates a pre-signed URL for accessing the image
 func (s *productService) GetProductImageURL(ctx context.Context, tenantID, imageID uuid.UUID, expiry time.Duration) (string, error) {
 	// Get image metadata
@@ -298,7 +610,11 @@ func (s *productService) DeleteProductImage(ctx context.Context, tenantID, image
 	}
 
 	// Delete from database
-	return s.productImageRepo.Delete(ctx, tenantID, imageID)
+	if err := s.productImageRepo.Delete(ctx, tenantID, imageID); err != nil {
+		return err
+	}
+	caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventProductUpdated)
+	return nil
 }
 
 // BulkUpdateProducts performs bulk updates on multiple products
@@ -352,6 +668,7 @@ func (s *productService) BulkUpdateProducts(ctx context.Context, tenantID uuid.U
 			updated = true
 		}
 
+		oldPrice := product.UnitPrice
 		if bulkUpdate.UnitPriceChange != nil {
 			if bulkUpdate.UnitPriceMode == "percentage" {
 				if *bulkUpdate.UnitPriceChange > -100 {
@@ -390,6 +707,9 @@ func (s *productService) BulkUpdateProducts(ctx context.Context, tenantID uuid.U
 
 		if updated {
 			err = s.productRepo.Update(ctx, product)
+			if err == nil && product.UnitPrice != oldPrice {
+				s.recordPriceChange(WithPriceChangeReason(ctx, common.SafeString(bulkUpdate.Reason)), tenantID, product.ID, oldPrice, product.UnitPrice, models.PriceChangeSourceBulk)
+			}
 			if err != nil {
 				result.FailedItems++
 				errorMsg := fmt.Sprintf("Failed to update product: %v", err)
@@ -431,6 +751,9 @@ func (s *productService) BulkUpdateProducts(ctx context.Context, tenantID uuid.U
 	}
 	result.CompletionTime = &time.Time{}
 	*result.CompletionTime = time.Now()
+	if result.ProcessedItems > 0 {
+		caching.PublishEvent(ctx, s.cacheService, tenantID, caching.EventProductUpdated)
+	}
 
 	return result, nil
 }
@@ -524,8 +847,17 @@ func (s *productService) BulkCreateProducts(ctx context.Context, tenantID uuid.U
 			}
 		}
 
-		// Create product
+		// Create product; opening stock is booked into a warehouse afterwards
+		openingStock := product.Quantity
+		product.Quantity = 0
 		err := s.productRepo.Create(ctx, product)
+		if err == nil && openingStock > 0 {
+			if stockErr := s.UpdateStock(ctx, tenantID, product.ID, openingStock); stockErr != nil {
+				fmt.Printf("Failed to record opening stock for product %s: %v\n", product.ID.String(), stockErr)
+			} else {
+				product.Quantity = openingStock
+			}
+		}
 		if err != nil {
 			result.FailedItems++
 			errorMsg := fmt.Sprintf("Failed to create product: %v", err)
@@ -561,4 +893,19 @@ func (s *productService) BulkCreateProducts(ctx context.Context, tenantID uuid.U
 	*result.CompletionTime = time.Now()
 
 	return result, nil
-}
\ No newline at end of file
+}
+
+// BulkDeleteProducts deletes products in bulk under the same dependency
+// checks as single deletes
+func (s *productService) BulkDeleteProducts(ctx context.Context, tenantID uuid.UUID, bulkDelete *models.ProductBulkDelete) *models.BulkOperationResult {
+	return s.bulkOps.Run(ctx, tenantID, "bulk_delete_products", bulkDelete.ProductIDs, bulkDelete.DryRun, func(ctx context.Context, id uuid.UUID, dryRun bool) error {
+		product, err := s.productRepo.GetByID(ctx, tenantID, id)
+		if err != nil || product == nil {
+			return fmt.Errorf("product not found")
+		}
+		if dryRun {
+			return s.dependencySvc.CheckDeletable(ctx, tenantID, models.DependentProduct, id)
+		}
+		return s.Delete(ctx, tenantID, id)
+	})
+}
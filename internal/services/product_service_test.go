@@ -207,6 +207,19 @@ func (m *MockMinioService) UploadImage(ctx context.Context, bucket, key string,
 	return args.Error(0)
 }
 
+func (m *MockMinioService) UploadObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, contentType string) error {
+	args := m.Called(ctx, bucket, key, reader, size, contentType)
+	return args.Error(0)
+}
+
+func (m *MockMinioService) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	args := m.Called(ctx, bucket, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
 func (m *MockMinioService) GetPresignedURL(bucket, key string, expiry time.Duration) (string, error) {
 	args := m.Called(bucket, key, expiry)
 	return args.String(0), args.Error(1)
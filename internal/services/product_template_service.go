@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidProductTemplate wraps product template validation failures
+	ErrInvalidProductTemplate = errors.New("invalid product template")
+	// ErrProductTemplateNotFound is returned for templates the tenant does not have
+	ErrProductTemplateNotFound = errors.New("product template not found")
+	// ErrInvalidProductCopy wraps validation failures of products made from a
+	// template or cloned from another product
+	ErrInvalidProductCopy = errors.New("invalid product")
+	// ErrCloneSourceNotFound is returned when the product to clone does not exist
+	ErrCloneSourceNotFound = errors.New("product to clone not found")
+	// ErrDuplicateBarcode is returned when another product already uses the barcode
+	ErrDuplicateBarcode = errors.New("barcode already in use")
+)
+
+// ProductTemplateService keeps product templates and creates products from
+// templates or by cloning existing products
+type ProductTemplateService interface {
+	CreateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.ProductTemplate) error
+	GetTemplate(ctx context.Context, tenantID, templateID uuid.UUID) (*models.ProductTemplate, error)
+	ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.ProductTemplate, error)
+	UpdateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.ProductTemplate) error
+	DeleteTemplate(ctx context.Context, tenantID, templateID uuid.UUID) error
+
+	// CreateFromTemplate creates a product with the template's attributes and
+	// the request's SKU-specific fields
+	CreateFromTemplate(ctx context.Context, tenantID, templateID uuid.UUID, req *models.ProductFromTemplateRequest) (*models.Product, error)
+	// CloneProduct duplicates a product's catalog, pricing and storage
+	// attributes under a new barcode; stock, images and publishing are not copied
+	CloneProduct(ctx context.Context, tenantID, productID uuid.UUID, req *models.CloneProductRequest) (*models.Product, error)
+}
+
+type productTemplateService struct {
+	templateRepo repositories.ProductTemplateRepository
+	categoryRepo repositories.CategoryRepository
+	productRepo  repositories.ProductRepository
+	productSvc   ProductService
+	storageSvc   StorageConditionService
+}
+
+// NewProductTemplateService creates a new product template service instance
+func NewProductTemplateService(templateRepo repositories.ProductTemplateRepository, categoryRepo repositories.CategoryRepository,
+	productRepo repositories.ProductRepository, productSvc ProductService, storageSvc StorageConditionService) ProductTemplateService {
+	return &productTemplateService{
+		templateRepo: templateRepo,
+		categoryRepo: categoryRepo,
+		productRepo:  productRepo,
+		productSvc:   productSvc,
+		storageSvc:   storageSvc,
+	}
+}
+
+func (s *productTemplateService) validateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.ProductTemplate) error {
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProductTemplate)
+	}
+	if template.GSTRate != nil && (*template.GSTRate < 0 || *template.GSTRate > 100) {
+		return fmt.Errorf("%w: gst_rate must be between 0 and 100", ErrInvalidProductTemplate)
+	}
+	if template.UnitPrice != nil && *template.UnitPrice <= 0 {
+		return fmt.Errorf("%w: unit_price must be positive", ErrInvalidProductTemplate)
+	}
+	if template.HazmatClass != nil {
+		class := strings.TrimSpace(*template.HazmatClass)
+		if class == "" {
+			template.HazmatClass = nil
+		} else if !hazmatClassPattern.MatchString(class) {
+			return fmt.Errorf("%w: hazmat_class must be a UN hazard class such as 3 or 6.1", ErrInvalidProductTemplate)
+		} else {
+			template.HazmatClass = &class
+		}
+	}
+	if template.CategoryID != nil {
+		if category, err := s.categoryRepo.GetByID(ctx, tenantID, *template.CategoryID); err != nil || category == nil {
+			return fmt.Errorf("%w: category not found", ErrInvalidProductTemplate)
+		}
+	}
+	return nil
+}
+
+func (s *productTemplateService) CreateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.ProductTemplate) error {
+	if err := s.validateTemplate(ctx, tenantID, template); err != nil {
+		return err
+	}
+	template.ID = uuid.New()
+	template.TenantID = tenantID
+
+	created, err := s.templateRepo.Create(ctx, template)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return fmt.Errorf("%w: product template with this name already exists", ErrDuplicateName)
+	}
+	return nil
+}
+
+func (s *productTemplateService) GetTemplate(ctx context.Context, tenantID, templateID uuid.UUID) (*models.ProductTemplate, error) {
+	template, err := s.templateRepo.GetByID(ctx, tenantID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, ErrProductTemplateNotFound
+	}
+	return template, nil
+}
+
+func (s *productTemplateService) ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.ProductTemplate, error) {
+	return s.templateRepo.List(ctx, tenantID)
+}
+
+func (s *productTemplateService) UpdateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.ProductTemplate) error {
+	if err := s.validateTemplate(ctx, tenantID, template); err != nil {
+		return err
+	}
+	existing, err := s.templateRepo.GetByName(ctx, tenantID, template.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != template.ID {
+		return fmt.Errorf("%w: product template with this name already exists", ErrDuplicateName)
+	}
+
+	template.TenantID = tenantID
+	updated, err := s.templateRepo.Update(ctx, template)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrProductTemplateNotFound
+	}
+	return nil
+}
+
+func (s *productTemplateService) DeleteTemplate(ctx context.Context, tenantID, templateID uuid.UUID) error {
+	deleted, err := s.templateRepo.Delete(ctx, tenantID, templateID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrProductTemplateNotFound
+	}
+	return nil
+}
+
+func (s *productTemplateService) CreateFromTemplate(ctx context.Context, tenantID, templateID uuid.UUID, req *models.ProductFromTemplateRequest) (*models.Product, error) {
+	template, err := s.GetTemplate(ctx, tenantID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &models.Product{
+		Name:          strings.TrimSpace(req.Name),
+		CategoryID:    template.CategoryID,
+		BatchNumber:   req.BatchNumber,
+		Quantity:      req.Quantity,
+		CostPrice:     req.CostPrice,
+		Barcode:       req.Barcode,
+		UnitOfMeasure: template.UnitOfMeasure,
+		Description:   template.Description,
+		GSTRate:       template.GSTRate,
+	}
+	if product.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidProductCopy)
+	}
+	switch {
+	case req.UnitPrice != nil:
+		product.UnitPrice = *req.UnitPrice
+	case template.UnitPrice != nil:
+		product.UnitPrice = *template.UnitPrice
+	}
+	if req.UnitOfMeasure != nil {
+		product.UnitOfMeasure = req.UnitOfMeasure
+	}
+	if req.Description != nil {
+		product.Description = req.Description
+	}
+	if product.ExpiryDate, err = parseExpiryDate(req.ExpiryDate); err != nil {
+		return nil, err
+	}
+
+	var storage *models.ProductStorageRequirement
+	if template.HasStorageRequirement() {
+		storage = &models.ProductStorageRequirement{
+			ColdChain:   template.ColdChain,
+			Shade:       template.Shade,
+			HazmatClass: template.HazmatClass,
+		}
+	}
+	if err := s.create(ctx, tenantID, product, storage); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *productTemplateService) CloneProduct(ctx context.Context, tenantID, productID uuid.UUID, req *models.CloneProductRequest) (*models.Product, error) {
+	source, err := s.productRepo.GetByID(ctx, tenantID, productID)
+	if err != nil || source == nil {
+		return nil, ErrCloneSourceNotFound
+	}
+
+	barcode := strings.TrimSpace(req.Barcode)
+	if barcode == "" {
+		return nil, fmt.Errorf("%w: a new barcode is required", ErrInvalidProductCopy)
+	}
+	product := &models.Product{
+		Name:          source.Name,
+		CategoryID:    source.CategoryID,
+		BatchNumber:   req.BatchNumber,
+		UnitPrice:     source.UnitPrice,
+		CostPrice:     source.CostPrice,
+		Barcode:       &barcode,
+		UnitOfMeasure: source.UnitOfMeasure,
+		Description:   source.Description,
+		Translations:  source.Translations,
+		GSTRate:       source.GSTRate,
+	}
+	if req.Name != nil {
+		product.Name = strings.TrimSpace(*req.Name)
+		if product.Name == "" {
+			return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidProductCopy)
+		}
+	}
+	if product.ExpiryDate, err = parseExpiryDate(req.ExpiryDate); err != nil {
+		return nil, err
+	}
+
+	storage, err := s.storageSvc.GetProductRequirement(ctx, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.create(ctx, tenantID, product, storage); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// create creates the product through the product service, then gives it the
+// storage requirement when there is one
+func (s *productTemplateService) create(ctx context.Context, tenantID uuid.UUID, product *models.Product, storage *models.ProductStorageRequirement) error {
+	if product.UnitPrice <= 0 {
+		return fmt.Errorf("%w: unit_price must be positive", ErrInvalidProductCopy)
+	}
+	if product.Quantity < 0 {
+		return fmt.Errorf("%w: quantity cannot be negative", ErrInvalidProductCopy)
+	}
+	if product.Barcode != nil && strings.TrimSpace(*product.Barcode) != "" {
+		if existing, err := s.productRepo.GetByBarcode(ctx, tenantID, *product.Barcode); err == nil && existing != nil {
+			return fmt.Errorf("%w: %s", ErrDuplicateBarcode, *product.Barcode)
+		}
+	}
+
+	if err := s.productSvc.Create(ctx, tenantID, product); err != nil {
+		return err
+	}
+	if storage == nil {
+		return nil
+	}
+
+	requirement := &models.ProductStorageRequirement{
+		ProductID:   product.ID,
+		TenantID:    tenantID,
+		ColdChain:   storage.ColdChain,
+		Shade:       storage.Shade,
+		HazmatClass: storage.HazmatClass,
+	}
+	if err := s.storageSvc.SetProductRequirement(ctx, tenantID, requirement); err != nil {
+		return fmt.Errorf("product created but storage requirement was not recorded: %w", err)
+	}
+	return nil
+}
+
+func parseExpiryDate(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	expiry, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: expiry_date must be YYYY-MM-DD", ErrInvalidProductCopy)
+	}
+	return &expiry, nil
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	purchaseCaptureBucket = "purchase-invoices"
+	purchaseCaptureURLTTL = time.Hour
+	// purchaseCaptureOCRTimeout bounds OCR of one uploaded document
+	purchaseCaptureOCRTimeout = 2 * time.Minute
+)
+
+var (
+	// ErrPurchaseCaptureNotFound is returned for captures of another tenant
+	ErrPurchaseCaptureNotFound = errors.New("purchase invoice capture not found")
+	// ErrInvalidPurchaseCapture wraps capture validation failures
+	ErrInvalidPurchaseCapture = errors.New("invalid purchase invoice capture")
+	// ErrPurchaseCaptureConflict is returned for captures already confirmed or discarded
+	ErrPurchaseCaptureConflict = errors.New("purchase invoice capture has already been reviewed")
+)
+
+// PurchaseCaptureService captures supplier invoices: the uploaded document is
+// stored, read by OCR and parsed into a draft purchase that a person checks
+// and confirms into purchase orders. The document stays linked to them.
+// Bills that look like an earlier bill of the supplier are flagged and can
+// only be confirmed with an override reason
+type PurchaseCaptureService interface {
+	Upload(ctx context.Context, tenantID, userID uuid.UUID, filename, contentType string, content []byte) (*models.PurchaseInvoiceCapture, error)
+	List(ctx context.Context, tenantID uuid.UUID, status string, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseInvoiceCapture, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseInvoiceCapture, error)
+	UpdateDraft(ctx context.Context, tenantID, id uuid.UUID, draft *models.PurchaseCaptureDraft) (*models.PurchaseInvoiceCapture, error)
+	// Confirm returns a *DuplicateBillError while the capture has open
+	// duplicate flags and overrideReason is nil
+	Confirm(ctx context.Context, tenantID, id, userID uuid.UUID, overrideReason *string) (*models.PurchaseInvoiceCapture, error)
+	Discard(ctx context.Context, tenantID, id, userID uuid.UUID) (*models.PurchaseInvoiceCapture, error)
+
+	// CheckDuplicates re-runs duplicate detection on a capture under review
+	CheckDuplicates(ctx context.Context, tenantID, id uuid.UUID) ([]*models.SupplierBillDuplicate, error)
+	DuplicateReport(ctx context.Context, tenantID uuid.UUID, status string, from, to time.Time, limit, offset int) (*models.SupplierBillDuplicateReport, error)
+}
+
+type purchaseCaptureService struct {
+	repo          repositories.PurchaseCaptureRepository
+	supplierRepo  repositories.SupplierRepository
+	warehouseRepo repositories.WarehouseRepository
+	productRepo   repositories.ProductRepository
+	orderService  OrderServiceInterface
+	minioService  MinioService
+	ocr           OCRDriver
+}
+
+// NewPurchaseCaptureService creates a new purchase invoice capture service
+func NewPurchaseCaptureService(repo repositories.PurchaseCaptureRepository, supplierRepo repositories.SupplierRepository,
+	warehouseRepo repositories.WarehouseRepository, productRepo repositories.ProductRepository,
+	orderService OrderServiceInterface, minioService MinioService, ocr OCRDriver) PurchaseCaptureService {
+	return &purchaseCaptureService{
+		repo:          repo,
+		supplierRepo:  supplierRepo,
+		warehouseRepo: warehouseRepo,
+		productRepo:   productRepo,
+		orderService:  orderService,
+		minioService:  minioService,
+		ocr:           ocr,
+	}
+}
+
+var (
+	gstinPattern         = regexp.MustCompile(`\b\d{2}[A-Z]{5}\d{4}[A-Z][1-9A-Z]Z[0-9A-Z]\b`)
+	invoiceNumberPattern = regexp.MustCompile(`(?i)\b(?:invoice|inv|bill)\s*(?:no|number|#)\.?\s*[:#.\-]?\s*([A-Z0-9][A-Z0-9/\-]{0,30})`)
+	invoiceDatePattern   = regexp.MustCompile(`(?i)\b(?:invoice\s+|bill\s+)?date(?:d)?\s*[:.\-]?\s*(\d{1,2}[./-]\d{1,2}[./-]\d{2,4}|\d{1,2}[ -][A-Za-z]{3,9}[ -,]+\d{2,4})`)
+	invoiceTotalPattern  = regexp.MustCompile(`(?i)\b(grand\s+total|invoice\s+total|total\s+amount|net\s+amount|amount\s+payable|total)\b[^\d\n]*([\d,]+(?:\.\d{1,2})?)\s*$`)
+	invoiceTaxPattern    = regexp.MustCompile(`(?i)\b(?:cgst|sgst|igst|utgst)\b[^\n]*?([\d,]+\.\d{2})\s*$`)
+	invoiceLinePattern   = regexp.MustCompile(`^\s*(?:\d{1,3}[.)]?\s+)?(.*?[A-Za-z].*?)\s+(?:(\d{4,8})\s+)?(\d+(?:\.\d+)?)\s*(?:[A-Za-z]{2,5}\.?\s+)?([\d,]+\.\d{1,2})\s+([\d,]+\.\d{1,2})\s*$`)
+)
+
+var invoiceDateLayouts = []string{
+	"02/01/2006", "2/1/2006", "02/01/06", "2/1/06",
+	"02-01-2006", "2-1-2006", "02-01-06",
+	"02.01.2006", "2.1.2006", "02.01.06",
+	"02-Jan-2006", "2-Jan-2006", "02-Jan-06", "02 Jan 2006", "2 Jan 2006",
+	"02 January 2006", "2 January 2006", "02-January-2006",
+}
+
+func parseInvoiceAmount(raw string) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", ""), 64)
+	return value, err == nil
+}
+
+func parseInvoiceDate(raw string) (time.Time, bool) {
+	raw = strings.Join(strings.Fields(strings.ReplaceAll(raw, ",", " ")), " ")
+	for _, layout := range invoiceDateLayouts {
+		if date, err := time.Parse(layout, raw); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParsePurchaseInvoiceText reads the invoice header and line items out of
+// OCR text. It is a best-effort guess meant for a person to correct: lines
+// are kept only when quantity times rate agrees with the printed amount
+func ParsePurchaseInvoiceText(text string) models.PurchaseCaptureDraft {
+	draft := models.PurchaseCaptureDraft{Lines: []models.PurchaseCaptureLine{}}
+
+	if gstin := gstinPattern.FindString(strings.ToUpper(text)); gstin != "" {
+		draft.SupplierGSTIN = &gstin
+	}
+	if match := invoiceNumberPattern.FindStringSubmatch(text); match != nil {
+		number := strings.ToUpper(match[1])
+		draft.InvoiceNumber = &number
+	}
+	if match := invoiceDatePattern.FindStringSubmatch(text); match != nil {
+		if date, ok := parseInvoiceDate(match[1]); ok {
+			draft.InvoiceDate = &date
+		}
+	}
+
+	var tax float64
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		// The last total printed is the grand total; sub-totals come first
+		if match := invoiceTotalPattern.FindStringSubmatch(line); match != nil {
+			if amount, ok := parseInvoiceAmount(match[2]); ok && amount > 0 {
+				draft.TotalAmount = &amount
+			}
+			continue
+		}
+		if match := invoiceTaxPattern.FindStringSubmatch(line); match != nil {
+			if amount, ok := parseInvoiceAmount(match[1]); ok {
+				tax += amount
+			}
+			continue
+		}
+
+		match := invoiceLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		quantity, err := strconv.ParseFloat(match[3], 64)
+		if err != nil || quantity <= 0 || quantity != math.Trunc(quantity) {
+			continue
+		}
+		rate, rateOK := parseInvoiceAmount(match[4])
+		amount, amountOK := parseInvoiceAmount(match[5])
+		if !rateOK || !amountOK || math.Abs(quantity*rate-amount) > math.Max(1, amount*0.02) {
+			continue
+		}
+		item := models.PurchaseCaptureLine{
+			Description: strings.TrimSpace(match[1]),
+			Quantity:    int(quantity),
+			UnitPrice:   rate,
+			Amount:      amount,
+		}
+		if match[2] != "" {
+			hsn := match[2]
+			item.HSNCode = &hsn
+		}
+		draft.Lines = append(draft.Lines, item)
+	}
+	if tax > 0 {
+		draft.TaxAmount = &tax
+	}
+	return draft
+}
+
+func (s *purchaseCaptureService) signDocument(capture *models.PurchaseInvoiceCapture) {
+	url, err := s.minioService.GetPresignedURL(purchaseCaptureBucket, capture.ObjectKey, purchaseCaptureURLTTL)
+	if err != nil {
+		log.Printf("Failed to sign purchase invoice document %s: %v", capture.ObjectKey, err)
+		return
+	}
+	capture.DocumentURL = url
+}
+
+// suggestMatches fills in the supplier from the GSTIN and a product for each
+// line whose description finds one; the reviewer confirms or changes them
+func (s *purchaseCaptureService) suggestMatches(ctx context.Context, tenantID uuid.UUID, draft *models.PurchaseCaptureDraft) {
+	if draft.SupplierGSTIN != nil {
+		if supplierID, err := s.repo.FindSupplierByGSTIN(ctx, tenantID, *draft.SupplierGSTIN); err != nil {
+			log.Printf("Failed to match supplier GSTIN %s: %v", *draft.SupplierGSTIN, err)
+		} else {
+			draft.SupplierID = supplierID
+		}
+	}
+	for i := range draft.Lines {
+		products, err := s.productRepo.Search(ctx, tenantID, draft.Lines[i].Description, nil, 1, 0)
+		if err == nil && len(products) > 0 {
+			draft.Lines[i].ProductID = &products[0].ID
+		}
+	}
+}
+
+func (s *purchaseCaptureService) Upload(ctx context.Context, tenantID, userID uuid.UUID, filename, contentType string, content []byte) (*models.PurchaseInvoiceCapture, error) {
+	capture := &models.PurchaseInvoiceCapture{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Status:      models.PurchaseCapturePendingReview,
+		FileName:    filepath.Base(filename),
+		ContentType: contentType,
+		SizeBytes:   int64(len(content)),
+		OCRProvider: s.ocr.Name(),
+		Extracted:   models.PurchaseCaptureDraft{Lines: []models.PurchaseCaptureLine{}},
+		OrderIDs:    []uuid.UUID{},
+		UploadedBy:  &userID,
+	}
+	capture.ObjectKey = fmt.Sprintf("%s/%s%s", tenantID.String(), capture.ID.String(), strings.ToLower(filepath.Ext(filename)))
+
+	// A failed read still keeps the document so the invoice can be keyed in
+	ocrCtx, cancel := context.WithTimeout(ctx, purchaseCaptureOCRTimeout)
+	text, err := s.ocr.ExtractText(ocrCtx, content, contentType)
+	cancel()
+	if err != nil {
+		message := err.Error()
+		capture.OCRError = &message
+	} else {
+		capture.OCRText = text
+		capture.Extracted = ParsePurchaseInvoiceText(text)
+		s.suggestMatches(ctx, tenantID, &capture.Extracted)
+	}
+
+	if err := s.minioService.EnsureBucketExists(ctx, purchaseCaptureBucket); err != nil {
+		return nil, fmt.Errorf("failed to prepare document storage: %w", err)
+	}
+	if err := s.minioService.UploadObject(ctx, purchaseCaptureBucket, capture.ObjectKey, bytes.NewReader(content), capture.SizeBytes, contentType); err != nil {
+		return nil, fmt.Errorf("failed to upload document to storage: %w", err)
+	}
+	if err := s.repo.Create(ctx, capture); err != nil {
+		if delErr := s.minioService.DeleteImage(ctx, purchaseCaptureBucket, capture.ObjectKey); delErr != nil {
+			log.Printf("Failed to remove orphaned purchase invoice %s: %v", capture.ObjectKey, delErr)
+		}
+		return nil, fmt.Errorf("failed to save purchase invoice capture: %w", err)
+	}
+	if capture.PossibleDuplicates, err = s.detectDuplicates(ctx, capture); err != nil {
+		log.Printf("Failed to check purchase invoice %s for duplicates: %v", capture.ID, err)
+	}
+	s.signDocument(capture)
+	return capture, nil
+}
+
+func (s *purchaseCaptureService) List(ctx context.Context, tenantID uuid.UUID, status string, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseInvoiceCapture, error) {
+	captures, err := s.repo.List(ctx, tenantID, status, orderID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, capture := range captures {
+		s.signDocument(capture)
+	}
+	return captures, nil
+}
+
+func (s *purchaseCaptureService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseInvoiceCapture, error) {
+	capture, err := s.repo.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load purchase invoice capture: %w", err)
+	}
+	if capture == nil {
+		return nil, ErrPurchaseCaptureNotFound
+	}
+	if capture.Status == models.PurchaseCapturePendingReview {
+		capture.PossibleDuplicates, err = s.repo.ListDuplicates(ctx, tenantID, &id, models.BillDuplicateFlagged, time.Time{},
+			time.Now().Add(time.Hour), 100, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load duplicate flags: %w", err)
+		}
+	}
+	s.signDocument(capture)
+	return capture, nil
+}
+
+func (s *purchaseCaptureService) UpdateDraft(ctx context.Context, tenantID, id uuid.UUID, draft *models.PurchaseCaptureDraft) (*models.PurchaseInvoiceCapture, error) {
+	capture, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if capture.Status != models.PurchaseCapturePendingReview {
+		return nil, ErrPurchaseCaptureConflict
+	}
+
+	if draft.SupplierID != nil {
+		if _, err := s.supplierRepo.GetByID(ctx, tenantID, *draft.SupplierID); err != nil {
+			return nil, fmt.Errorf("%w: supplier not found", ErrInvalidPurchaseCapture)
+		}
+	}
+	if draft.WarehouseID != nil {
+		if _, err := s.warehouseRepo.GetByID(ctx, tenantID, *draft.WarehouseID); err != nil {
+			return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidPurchaseCapture)
+		}
+	}
+	draft.SupplierGSTIN = trimmedOrNil(draft.SupplierGSTIN)
+	draft.InvoiceNumber = trimmedOrNil(draft.InvoiceNumber)
+	if draft.Lines == nil {
+		draft.Lines = []models.PurchaseCaptureLine{}
+	}
+	for i := range draft.Lines {
+		line := &draft.Lines[i]
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: line %d quantity must be positive", ErrInvalidPurchaseCapture, i+1)
+		}
+		if line.UnitPrice < 0 {
+			return nil, fmt.Errorf("%w: line %d unit price cannot be negative", ErrInvalidPurchaseCapture, i+1)
+		}
+		line.Amount = math.Round(float64(line.Quantity)*line.UnitPrice*100) / 100
+	}
+
+	capture.Extracted = *draft
+	updated, err := s.repo.UpdateDraft(ctx, capture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update purchase invoice capture: %w", err)
+	}
+	if !updated {
+		return nil, ErrPurchaseCaptureConflict
+	}
+	if capture.PossibleDuplicates, err = s.detectDuplicates(ctx, capture); err != nil {
+		return nil, err
+	}
+	return capture, nil
+}
+
+// Confirm raises one purchase order per line. It needs the supplier, the
+// receiving warehouse and a product on every line; orders already created
+// are removed again if a later line fails
+func (s *purchaseCaptureService) Confirm(ctx context.Context, tenantID, id, userID uuid.UUID, overrideReason *string) (*models.PurchaseInvoiceCapture, error) {
+	capture, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if capture.Status != models.PurchaseCapturePendingReview {
+		return nil, ErrPurchaseCaptureConflict
+	}
+	draft := capture.Extracted
+	if draft.SupplierID == nil {
+		return nil, fmt.Errorf("%w: set the supplier before confirming", ErrInvalidPurchaseCapture)
+	}
+	if draft.WarehouseID == nil {
+		return nil, fmt.Errorf("%w: set the receiving warehouse before confirming", ErrInvalidPurchaseCapture)
+	}
+	if len(draft.Lines) == 0 {
+		return nil, fmt.Errorf("%w: the invoice has no lines", ErrInvalidPurchaseCapture)
+	}
+	for i, line := range draft.Lines {
+		if line.ProductID == nil {
+			return nil, fmt.Errorf("%w: line %d has no product", ErrInvalidPurchaseCapture, i+1)
+		}
+	}
+
+	// Detection runs again so bills captured since the last check are compared
+	duplicates, err := s.detectDuplicates(ctx, capture)
+	if err != nil {
+		return nil, err
+	}
+	overrideReason = trimmedOrNil(overrideReason)
+	if len(duplicates) > 0 && overrideReason == nil {
+		return nil, &DuplicateBillError{Duplicates: duplicates}
+	}
+
+	var orderIDs []uuid.UUID
+	rollback := func() {
+		for _, orderID := range orderIDs {
+			if err := s.orderService.DeleteOrder(ctx, tenantID, orderID); err != nil {
+				log.Printf("Failed to roll back purchase order %s: %v", orderID, err)
+			}
+		}
+	}
+
+	notes := "Supplier invoice"
+	if draft.InvoiceNumber != nil {
+		notes += " " + *draft.InvoiceNumber
+	}
+	if draft.InvoiceDate != nil {
+		notes += " dated " + draft.InvoiceDate.Format("2006-01-02")
+	}
+	for i, line := range draft.Lines {
+		supplierID := *draft.SupplierID
+		order := &models.Order{
+			TenantID:    tenantID,
+			OrderType:   "purchase",
+			SupplierID:  &supplierID,
+			ProductID:   *line.ProductID,
+			WarehouseID: *draft.WarehouseID,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			Notes:       &notes,
+		}
+		if err := s.orderService.CreateOrder(ctx, tenantID, order); err != nil {
+			rollback()
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidPurchaseCapture, i+1, err)
+		}
+		orderIDs = append(orderIDs, order.ID)
+	}
+
+	capture.Status = models.PurchaseCaptureConfirmed
+	capture.OrderIDs = orderIDs
+	capture.ConfirmedBy = &userID
+	finished, err := s.repo.Finish(ctx, capture)
+	if err != nil || !finished {
+		rollback()
+		if err != nil {
+			return nil, fmt.Errorf("failed to confirm purchase invoice capture: %w", err)
+		}
+		return nil, ErrPurchaseCaptureConflict
+	}
+	if len(duplicates) > 0 {
+		if err := s.repo.ResolveDuplicateFlags(ctx, tenantID, id, models.BillDuplicateOverridden, overrideReason, userID); err != nil {
+			log.Printf("Failed to record duplicate override on purchase invoice %s: %v", id, err)
+		}
+	}
+	capture.PossibleDuplicates = nil
+	return capture, nil
+}
+
+// Discard closes a capture without creating orders; the document is kept
+func (s *purchaseCaptureService) Discard(ctx context.Context, tenantID, id, userID uuid.UUID) (*models.PurchaseInvoiceCapture, error) {
+	capture, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	capture.Status = models.PurchaseCaptureDiscarded
+	capture.ConfirmedBy = &userID
+	finished, err := s.repo.Finish(ctx, capture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discard purchase invoice capture: %w", err)
+	}
+	if !finished {
+		return nil, ErrPurchaseCaptureConflict
+	}
+	// Open flags close as discarded: the duplicate was caught before payment
+	if err := s.repo.ResolveDuplicateFlags(ctx, tenantID, id, models.BillDuplicateDiscarded, nil, userID); err != nil {
+		log.Printf("Failed to close duplicate flags on purchase invoice %s: %v", id, err)
+	}
+	capture.PossibleDuplicates = nil
+	return capture, nil
+}
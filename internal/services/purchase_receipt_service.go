@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"agromart2/internal/analytics"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// maxReceiptOrders bounds how many purchase orders one receipt may cover
+const maxReceiptOrders = 100
+
+var (
+	// ErrReceiptNotFound is returned for receipts outside the tenant
+	ErrReceiptNotFound = errors.New("purchase receipt not found")
+	// ErrInvalidReceipt wraps receipt validation failures
+	ErrInvalidReceipt = errors.New("invalid purchase receipt")
+)
+
+// ReceiptChargeInput is a charge recorded when receiving purchase orders
+type ReceiptChargeInput struct {
+	ChargeType  string  `json:"charge_type"`
+	Amount      float64 `json:"amount"`
+	Description *string `json:"description"`
+}
+
+// ReceivePurchaseRequest receives one or more processing purchase orders
+// together and allocates the charges across them
+type ReceivePurchaseRequest struct {
+	OrderIDs         []uuid.UUID          `json:"order_ids"`
+	Charges          []ReceiptChargeInput `json:"charges"`
+	AllocationMethod string               `json:"allocation_method"` // value (default) or quantity
+	// Consignment receives supplier-owned stock: it is sellable but product
+	// cost is unchanged and a liability is raised only when it sells
+	Consignment bool    `json:"consignment"`
+	Notes       *string `json:"notes"`
+}
+
+// PurchaseReceiptService receives purchase orders and keeps product cost at
+// landed cost: supplier price plus an allocated share of freight, customs
+// and handling charges
+type PurchaseReceiptService interface {
+	Receive(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *ReceivePurchaseRequest) (*models.PurchaseReceipt, error)
+	GetReceipt(ctx context.Context, tenantID, receiptID uuid.UUID) (*models.PurchaseReceipt, error)
+	ListReceipts(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PurchaseReceipt, error)
+}
+
+type purchaseReceiptService struct {
+	receiptRepo      repositories.PurchaseReceiptRepository
+	orderRepo        repositories.OrderRepository
+	productRepo      repositories.ProductRepository
+	inventoryRepo    repositories.InventoryRepository
+	inventoryService InventoryService
+	consignmentRepo  repositories.ConsignmentRepository
+	storageSvc       StorageConditionService
+	historyRepo      repositories.StatusHistoryRepository
+	workflowSvc      OrderWorkflowService
+	utilization      *analytics.WarehouseUtilizationService
+}
+
+// NewPurchaseReceiptService creates a new purchase receipt service instance
+func NewPurchaseReceiptService(receiptRepo repositories.PurchaseReceiptRepository, orderRepo repositories.OrderRepository, productRepo repositories.ProductRepository,
+	inventoryRepo repositories.InventoryRepository, inventoryService InventoryService, consignmentRepo repositories.ConsignmentRepository,
+	storageSvc StorageConditionService, historyRepo repositories.StatusHistoryRepository, workflowSvc OrderWorkflowService,
+	utilization *analytics.WarehouseUtilizationService) PurchaseReceiptService {
+	return &purchaseReceiptService{
+		receiptRepo:      receiptRepo,
+		orderRepo:        orderRepo,
+		productRepo:      productRepo,
+		inventoryRepo:    inventoryRepo,
+		inventoryService: inventoryService,
+		consignmentRepo:  consignmentRepo,
+		storageSvc:       storageSvc,
+		historyRepo:      historyRepo,
+		workflowSvc:      workflowSvc,
+		utilization:      utilization,
+	}
+}
+
+func validateReceiveRequest(req *ReceivePurchaseRequest) error {
+	if len(req.OrderIDs) == 0 {
+		return fmt.Errorf("%w: at least one order is required", ErrInvalidReceipt)
+	}
+	if len(req.OrderIDs) > maxReceiptOrders {
+		return fmt.Errorf("%w: at most %d orders can be received together", ErrInvalidReceipt, maxReceiptOrders)
+	}
+	seen := make(map[uuid.UUID]bool, len(req.OrderIDs))
+	for _, id := range req.OrderIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: order %s is listed more than once", ErrInvalidReceipt, id)
+		}
+		seen[id] = true
+	}
+
+	switch req.AllocationMethod {
+	case "":
+		req.AllocationMethod = models.LandedCostByValue
+	case models.LandedCostByValue, models.LandedCostByQuantity:
+	default:
+		return fmt.Errorf("%w: allocation_method must be 'value' or 'quantity'", ErrInvalidReceipt)
+	}
+
+	for _, charge := range req.Charges {
+		switch charge.ChargeType {
+		case models.ReceiptChargeFreight, models.ReceiptChargeCustoms, models.ReceiptChargeHandling, models.ReceiptChargeOther:
+		default:
+			return fmt.Errorf("%w: charge_type must be freight, customs, handling or other", ErrInvalidReceipt)
+		}
+		if charge.Amount < 0 || math.IsNaN(charge.Amount) || math.IsInf(charge.Amount, 0) {
+			return fmt.Errorf("%w: charge amount must not be negative", ErrInvalidReceipt)
+		}
+	}
+	return nil
+}
+
+// Receive adds the ordered quantities to stock, marks the orders delivered
+// and moves each product's cost to the weighted average of the stock on hand
+// and the received units at landed cost. Consignment receipts leave product
+// cost alone and book the units to the supplier's consignment stock instead.
+func (s *purchaseReceiptService) Receive(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *ReceivePurchaseRequest) (*models.PurchaseReceipt, error) {
+	if err := validateReceiveRequest(req); err != nil {
+		return nil, err
+	}
+
+	receipt := &models.PurchaseReceipt{
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		AllocationMethod: req.AllocationMethod,
+		Consignment:      req.Consignment,
+		Notes:            req.Notes,
+		ReceivedBy:       userID,
+	}
+
+	machine, err := s.workflowSvc.StateMachine(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*models.Order, 0, len(req.OrderIDs))
+	for i, orderID := range req.OrderIDs {
+		order, err := s.orderRepo.GetByID(ctx, tenantID, orderID)
+		if err != nil || order == nil {
+			return nil, fmt.Errorf("%w: order %s not found", ErrInvalidReceipt, orderID)
+		}
+		if order.OrderType != "purchase" {
+			return nil, fmt.Errorf("%w: order %s is not a purchase order", ErrInvalidReceipt, orderID)
+		}
+		if _, err := machine.Next(order, models.OrderEventReceive); err != nil {
+			return nil, fmt.Errorf("%w: order %s: %v", ErrInvalidReceipt, orderID, err)
+		}
+		if order.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: order %s has no quantity to receive", ErrInvalidReceipt, orderID)
+		}
+		// Checked for every order before any stock moves
+		if err := s.storageSvc.CheckPlacement(ctx, tenantID, order.ProductID, order.WarehouseID); err != nil {
+			return nil, err
+		}
+
+		// The receipt names a supplier only when every order is from the same one
+		if i == 0 {
+			receipt.SupplierID = order.SupplierID
+		} else if receipt.SupplierID != nil && (order.SupplierID == nil || *order.SupplierID != *receipt.SupplierID) {
+			receipt.SupplierID = nil
+		}
+
+		if req.Consignment && receipt.SupplierID == nil {
+			return nil, fmt.Errorf("%w: consignment receipts must cover orders from a single supplier", ErrInvalidReceipt)
+		}
+
+		orders = append(orders, order)
+		receipt.Lines = append(receipt.Lines, &models.PurchaseReceiptLine{
+			ID:          uuid.New(),
+			ReceiptID:   receipt.ID,
+			OrderID:     order.ID,
+			ProductID:   order.ProductID,
+			WarehouseID: order.WarehouseID,
+			Quantity:    order.Quantity,
+			UnitPrice:   order.UnitPrice,
+		})
+		receipt.GoodsValue += float64(order.Quantity) * order.UnitPrice
+	}
+	receipt.GoodsValue = math.Round(receipt.GoodsValue*100) / 100
+
+	receipt.Charges = []*models.PurchaseReceiptCharge{}
+	for _, input := range req.Charges {
+		charge := &models.PurchaseReceiptCharge{
+			ID:          uuid.New(),
+			ReceiptID:   receipt.ID,
+			ChargeType:  input.ChargeType,
+			Amount:      math.Round(input.Amount*100) / 100,
+			Description: input.Description,
+		}
+		receipt.Charges = append(receipt.Charges, charge)
+		receipt.TotalCharges += charge.Amount
+	}
+	receipt.TotalCharges = math.Round(receipt.TotalCharges*100) / 100
+
+	allocateLandedCost(receipt.Lines, receipt.TotalCharges, receipt.AllocationMethod)
+
+	// Checked against stock before any of it moves; a full warehouse is
+	// reported, not refused, as the goods have already arrived
+	inbound := make([]*models.InboundStock, len(receipt.Lines))
+	for i, line := range receipt.Lines {
+		inbound[i] = &models.InboundStock{WarehouseID: line.WarehouseID, ProductID: line.ProductID, Quantity: line.Quantity}
+	}
+	warnings, err := s.utilization.CheckInbound(ctx, tenantID, inbound)
+	if err != nil {
+		fmt.Printf("Failed to check warehouse capacity for receipt %s: %v\n", receipt.ID, err)
+	}
+	receipt.CapacityWarnings = warnings
+
+	for i, line := range receipt.Lines {
+		receive := s.receiveLine
+		if receipt.Consignment {
+			receive = s.receiveConsignmentLine
+		}
+		if err := receive(ctx, tenantID, orders[i], line); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.receiptRepo.Create(ctx, receipt); err != nil {
+		return nil, fmt.Errorf("failed to save purchase receipt: %w", err)
+	}
+	return receipt, nil
+}
+
+// receiveLine books one order into stock and updates the product's landed cost
+func (s *purchaseReceiptService) receiveLine(ctx context.Context, tenantID uuid.UUID, order *models.Order, line *models.PurchaseReceiptLine) error {
+	product, err := s.productRepo.GetByID(ctx, tenantID, line.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get product %s: %w", line.ProductID, err)
+	}
+	stock, err := s.inventoryRepo.ListByProduct(ctx, tenantID, line.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get stock for product %s: %w", line.ProductID, err)
+	}
+	onHand := 0
+	for _, inv := range stock {
+		onHand += inv.Quantity
+	}
+
+	if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, line.WarehouseID, line.ProductID, line.Quantity, models.InventoryReasonPurchaseReceipt); err != nil {
+		return fmt.Errorf("failed to update inventory: %w", err)
+	}
+
+	line.PreviousCostPrice = product.CostPrice
+	newCost := movingAverageCost(product.CostPrice, onHand, line.LandedUnitCost, line.Quantity)
+	line.NewCostPrice = &newCost
+	if err := s.receiptRepo.SetProductCost(ctx, tenantID, line.ProductID, newCost); err != nil {
+		return fmt.Errorf("failed to update cost for product %s: %w", line.ProductID, err)
+	}
+
+	return s.markReceived(ctx, order)
+}
+
+// receiveConsignmentLine books supplier-owned units into sellable stock and
+// the supplier's consignment balance at the supplier price
+func (s *purchaseReceiptService) receiveConsignmentLine(ctx context.Context, tenantID uuid.UUID, order *models.Order, line *models.PurchaseReceiptLine) error {
+	if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, line.WarehouseID, line.ProductID, line.Quantity, models.InventoryReasonConsignmentReceipt); err != nil {
+		return fmt.Errorf("failed to update inventory: %w", err)
+	}
+	if err := s.consignmentRepo.AddStock(ctx, tenantID, *order.SupplierID, line.WarehouseID, line.ProductID, line.Quantity, line.UnitPrice); err != nil {
+		return fmt.Errorf("failed to record consignment stock for product %s: %w", line.ProductID, err)
+	}
+	return s.markReceived(ctx, order)
+}
+
+func (s *purchaseReceiptService) markReceived(ctx context.Context, order *models.Order) error {
+	previousStatus := order.Status
+	order.Status = models.OrderStatusDelivered
+	if err := s.orderRepo.Update(ctx, order); err != nil {
+		return fmt.Errorf("failed to update order %s: %w", order.ID, err)
+	}
+	recordStatusChange(ctx, s.historyRepo, order.TenantID, models.StatusEntityOrder, order.ID, previousStatus, order.Status)
+	return nil
+}
+
+func (s *purchaseReceiptService) GetReceipt(ctx context.Context, tenantID, receiptID uuid.UUID) (*models.PurchaseReceipt, error) {
+	receipt, err := s.receiptRepo.GetByID(ctx, tenantID, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, ErrReceiptNotFound
+	}
+	return receipt, nil
+}
+
+func (s *purchaseReceiptService) ListReceipts(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.PurchaseReceipt, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.receiptRepo.List(ctx, tenantID, limit, offset)
+}
+
+// allocateLandedCost spreads the receipt charges over the lines by goods value
+// or by quantity and sets each line's landed unit cost. Shares are rounded to
+// the paisa and the rounding remainder goes to the line with the largest basis,
+// so the allocations always add up to the charges. Free goods (no value) are
+// allocated by quantity.
+func allocateLandedCost(lines []*models.PurchaseReceiptLine, totalCharges float64, method string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	bases := make([]float64, len(lines))
+	total := 0.0
+	for i, line := range lines {
+		bases[i] = float64(line.Quantity)
+		if method == models.LandedCostByValue {
+			bases[i] *= line.UnitPrice
+		}
+		total += bases[i]
+	}
+	if total <= 0 {
+		total = 0
+		for i, line := range lines {
+			bases[i] = float64(line.Quantity)
+			total += bases[i]
+		}
+	}
+
+	chargeCents := int64(math.Round(totalCharges * 100))
+	cents := make([]int64, len(lines))
+	var allocated int64
+	largest := 0
+	for i := range lines {
+		if total > 0 {
+			cents[i] = int64(math.Floor(float64(chargeCents) * bases[i] / total))
+		}
+		allocated += cents[i]
+		if bases[i] > bases[largest] {
+			largest = i
+		}
+	}
+	cents[largest] += chargeCents - allocated
+
+	for i, line := range lines {
+		line.AllocatedCharges = float64(cents[i]) / 100
+		line.LandedUnitCost = line.UnitPrice
+		if line.Quantity > 0 {
+			line.LandedUnitCost += line.AllocatedCharges / float64(line.Quantity)
+		}
+		line.LandedUnitCost = math.Round(line.LandedUnitCost*10000) / 10000
+	}
+}
+
+// movingAverageCost blends received units at landed cost into the cost of the
+// stock already on hand; without a previous cost or stock the landed cost is used
+func movingAverageCost(previous *float64, onHand int, landedUnitCost float64, received int) float64 {
+	if previous == nil || *previous <= 0 || onHand <= 0 {
+		return math.Round(landedUnitCost*100) / 100
+	}
+	blended := (float64(onHand)**previous + float64(received)*landedUnitCost) / float64(onHand+received)
+	return math.Round(blended*100) / 100
+}
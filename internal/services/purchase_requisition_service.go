@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const maxRequisitionsPerConversion = 100
+
+var (
+	// ErrRequisitionNotFound is returned for requisitions outside the tenant
+	ErrRequisitionNotFound = errors.New("purchase requisition not found")
+	// ErrInvalidRequisition wraps requisition validation and workflow failures
+	ErrInvalidRequisition = errors.New("invalid purchase requisition")
+)
+
+// RaiseRequisitionRequest is a warehouse's request to buy stock
+type RaiseRequisitionRequest struct {
+	ProductID   uuid.UUID  `json:"product_id"`
+	WarehouseID uuid.UUID  `json:"warehouse_id"`
+	Quantity    int        `json:"quantity"`
+	NeedBy      string     `json:"need_by"` // YYYY-MM-DD
+	SupplierID  *uuid.UUID `json:"supplier_id"`
+	Notes       *string    `json:"notes"`
+}
+
+// ConvertRequisitionsRequest turns approved requisitions into purchase orders.
+// Unit prices are per product and default to the product's cost price.
+type ConvertRequisitionsRequest struct {
+	RequisitionIDs []uuid.UUID           `json:"requisition_ids"`
+	UnitPrices     map[uuid.UUID]float64 `json:"unit_prices"`
+}
+
+// PurchaseRequisitionService runs the requisition workflow: raise, approve or
+// reject, then convert into purchase orders grouped by supplier
+type PurchaseRequisitionService interface {
+	Raise(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *RaiseRequisitionRequest) (*models.PurchaseRequisition, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.RequisitionTrace, error)
+	List(ctx context.Context, tenantID uuid.UUID, filter *models.PurchaseRequisitionFilter) ([]*models.PurchaseRequisition, error)
+	Approve(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, supplierID *uuid.UUID) (*models.PurchaseRequisition, error)
+	Reject(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, reason string) (*models.PurchaseRequisition, error)
+	Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseRequisition, error)
+	Convert(ctx context.Context, tenantID uuid.UUID, req *ConvertRequisitionsRequest) ([]*models.SupplierPurchaseOrders, error)
+}
+
+type purchaseRequisitionService struct {
+	requisitionRepo repositories.PurchaseRequisitionRepository
+	productRepo     repositories.ProductRepository
+	warehouseRepo   repositories.WarehouseRepository
+	supplierRepo    repositories.SupplierRepository
+	orderRepo       repositories.OrderRepository
+	receiptRepo     repositories.PurchaseReceiptRepository
+	orderService    OrderServiceInterface
+}
+
+// NewPurchaseRequisitionService creates a new purchase requisition service instance
+func NewPurchaseRequisitionService(requisitionRepo repositories.PurchaseRequisitionRepository, productRepo repositories.ProductRepository, warehouseRepo repositories.WarehouseRepository,
+	supplierRepo repositories.SupplierRepository, orderRepo repositories.OrderRepository, receiptRepo repositories.PurchaseReceiptRepository, orderService OrderServiceInterface) PurchaseRequisitionService {
+	return &purchaseRequisitionService{
+		requisitionRepo: requisitionRepo,
+		productRepo:     productRepo,
+		warehouseRepo:   warehouseRepo,
+		supplierRepo:    supplierRepo,
+		orderRepo:       orderRepo,
+		receiptRepo:     receiptRepo,
+		orderService:    orderService,
+	}
+}
+
+func (s *purchaseRequisitionService) Raise(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *RaiseRequisitionRequest) (*models.PurchaseRequisition, error) {
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidRequisition)
+	}
+	needBy, err := time.Parse("2006-01-02", req.NeedBy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: need_by must be a date in YYYY-MM-DD format", ErrInvalidRequisition)
+	}
+	if needBy.Before(time.Now().UTC().Truncate(24 * time.Hour)) {
+		return nil, fmt.Errorf("%w: need_by must not be in the past", ErrInvalidRequisition)
+	}
+	if err := common.SanitizeHTMLField(req.Notes, "requisition notes"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequisition, err)
+	}
+	if product, err := s.productRepo.GetByID(ctx, tenantID, req.ProductID); err != nil || product == nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidRequisition)
+	}
+	if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, req.WarehouseID); err != nil || warehouse == nil {
+		return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidRequisition)
+	}
+	if err := s.checkSupplier(ctx, tenantID, req.SupplierID); err != nil {
+		return nil, err
+	}
+
+	requisition := &models.PurchaseRequisition{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		ProductID:   req.ProductID,
+		WarehouseID: req.WarehouseID,
+		Quantity:    req.Quantity,
+		NeedBy:      needBy,
+		SupplierID:  req.SupplierID,
+		Notes:       req.Notes,
+		Status:      models.RequisitionStatusPending,
+		RequestedBy: userID,
+	}
+	if err := s.requisitionRepo.Create(ctx, requisition); err != nil {
+		return nil, fmt.Errorf("failed to save purchase requisition: %w", err)
+	}
+	return requisition, nil
+}
+
+func (s *purchaseRequisitionService) checkSupplier(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID) error {
+	if supplierID == nil {
+		return nil
+	}
+	if supplier, err := s.supplierRepo.GetByID(ctx, tenantID, *supplierID); err != nil || supplier == nil {
+		return fmt.Errorf("%w: supplier not found", ErrInvalidRequisition)
+	}
+	return nil
+}
+
+func (s *purchaseRequisitionService) getRequisition(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseRequisition, error) {
+	requisition, err := s.requisitionRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if requisition == nil {
+		return nil, ErrRequisitionNotFound
+	}
+	return requisition, nil
+}
+
+// Get returns a requisition with the purchase order it became and the receipt
+// that received that order
+func (s *purchaseRequisitionService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.RequisitionTrace, error) {
+	requisition, err := s.getRequisition(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &models.RequisitionTrace{Requisition: requisition}
+	if requisition.OrderID == nil {
+		return trace, nil
+	}
+	if trace.PurchaseOrder, err = s.orderRepo.GetByID(ctx, tenantID, *requisition.OrderID); err != nil {
+		return nil, err
+	}
+	receiptID, err := s.requisitionRepo.ReceiptIDForOrder(ctx, tenantID, *requisition.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if receiptID != nil {
+		if trace.Receipt, err = s.receiptRepo.GetByID(ctx, tenantID, *receiptID); err != nil {
+			return nil, err
+		}
+	}
+	return trace, nil
+}
+
+func (s *purchaseRequisitionService) List(ctx context.Context, tenantID uuid.UUID, filter *models.PurchaseRequisitionFilter) ([]*models.PurchaseRequisition, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.requisitionRepo.List(ctx, tenantID, filter)
+}
+
+// Approve accepts a pending requisition; the approver may set or change the supplier
+func (s *purchaseRequisitionService) Approve(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, supplierID *uuid.UUID) (*models.PurchaseRequisition, error) {
+	requisition, err := s.getRequisition(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if requisition.Status != models.RequisitionStatusPending {
+		return nil, fmt.Errorf("%w: only pending requisitions can be approved, this one is %s", ErrInvalidRequisition, requisition.Status)
+	}
+	if err := s.checkSupplier(ctx, tenantID, supplierID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	requisition.Status = models.RequisitionStatusApproved
+	requisition.ApprovedBy = userID
+	requisition.ApprovedAt = &now
+	if supplierID != nil {
+		requisition.SupplierID = supplierID
+	}
+	return s.transition(ctx, requisition, models.RequisitionStatusPending)
+}
+
+func (s *purchaseRequisitionService) Reject(ctx context.Context, tenantID, id uuid.UUID, userID *uuid.UUID, reason string) (*models.PurchaseRequisition, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a rejection reason is required", ErrInvalidRequisition)
+	}
+	if err := common.SanitizeHTMLField(&reason, "rejection reason"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequisition, err)
+	}
+	requisition, err := s.getRequisition(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if requisition.Status != models.RequisitionStatusPending {
+		return nil, fmt.Errorf("%w: only pending requisitions can be rejected, this one is %s", ErrInvalidRequisition, requisition.Status)
+	}
+
+	now := time.Now()
+	requisition.Status = models.RequisitionStatusRejected
+	requisition.ApprovedBy = userID
+	requisition.ApprovedAt = &now
+	requisition.RejectionReason = &reason
+	return s.transition(ctx, requisition, models.RequisitionStatusPending)
+}
+
+// Cancel withdraws a requisition that has not yet become a purchase order
+func (s *purchaseRequisitionService) Cancel(ctx context.Context, tenantID, id uuid.UUID) (*models.PurchaseRequisition, error) {
+	requisition, err := s.getRequisition(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	from := requisition.Status
+	if from != models.RequisitionStatusPending && from != models.RequisitionStatusApproved {
+		return nil, fmt.Errorf("%w: a %s requisition cannot be cancelled", ErrInvalidRequisition, from)
+	}
+
+	requisition.Status = models.RequisitionStatusCancelled
+	return s.transition(ctx, requisition, from)
+}
+
+func (s *purchaseRequisitionService) transition(ctx context.Context, requisition *models.PurchaseRequisition, from string) (*models.PurchaseRequisition, error) {
+	updated, err := s.requisitionRepo.UpdateStatus(ctx, requisition, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update purchase requisition: %w", err)
+	}
+	if !updated {
+		return nil, fmt.Errorf("%w: requisition was changed by someone else, reload and retry", ErrInvalidRequisition)
+	}
+	return requisition, nil
+}
+
+// requisitionGroup is the requisitions that become one purchase order
+type requisitionGroup struct {
+	supplierID   uuid.UUID
+	productID    uuid.UUID
+	warehouseID  uuid.UUID
+	quantity     int
+	needBy       time.Time
+	requisitions []uuid.UUID
+}
+
+// Convert raises purchase orders for approved requisitions. Requisitions for
+// the same supplier, product and warehouse share one order whose expected
+// delivery is the earliest need-by date; results are grouped by supplier.
+func (s *purchaseRequisitionService) Convert(ctx context.Context, tenantID uuid.UUID, req *ConvertRequisitionsRequest) ([]*models.SupplierPurchaseOrders, error) {
+	if len(req.RequisitionIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one requisition is required", ErrInvalidRequisition)
+	}
+	if len(req.RequisitionIDs) > maxRequisitionsPerConversion {
+		return nil, fmt.Errorf("%w: at most %d requisitions can be converted together", ErrInvalidRequisition, maxRequisitionsPerConversion)
+	}
+
+	type groupKey struct{ supplier, product, warehouse uuid.UUID }
+	var groups []*requisitionGroup
+	byKey := map[groupKey]*requisitionGroup{}
+	prices := map[uuid.UUID]float64{}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range req.RequisitionIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: requisition %s is listed more than once", ErrInvalidRequisition, id)
+		}
+		seen[id] = true
+
+		requisition, err := s.requisitionRepo.GetByID(ctx, tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		if requisition == nil {
+			return nil, fmt.Errorf("%w: requisition %s not found", ErrInvalidRequisition, id)
+		}
+		if requisition.Status != models.RequisitionStatusApproved {
+			return nil, fmt.Errorf("%w: requisition %s is %s, only approved requisitions can be converted", ErrInvalidRequisition, id, requisition.Status)
+		}
+		if requisition.SupplierID == nil {
+			return nil, fmt.Errorf("%w: requisition %s has no supplier, set one when approving", ErrInvalidRequisition, id)
+		}
+		if _, ok := prices[requisition.ProductID]; !ok {
+			price, err := s.unitPrice(ctx, tenantID, requisition.ProductID, req.UnitPrices)
+			if err != nil {
+				return nil, err
+			}
+			prices[requisition.ProductID] = price
+		}
+
+		key := groupKey{*requisition.SupplierID, requisition.ProductID, requisition.WarehouseID}
+		group, ok := byKey[key]
+		if !ok {
+			group = &requisitionGroup{supplierID: key.supplier, productID: key.product, warehouseID: key.warehouse, needBy: requisition.NeedBy}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.quantity += requisition.Quantity
+		group.requisitions = append(group.requisitions, requisition.ID)
+		if requisition.NeedBy.Before(group.needBy) {
+			group.needBy = requisition.NeedBy
+		}
+	}
+
+	var result []*models.SupplierPurchaseOrders
+	bySupplier := map[uuid.UUID]*models.SupplierPurchaseOrders{}
+	for _, group := range groups {
+		order, err := s.createOrder(ctx, tenantID, group, prices[group.productID])
+		if err != nil {
+			return nil, err
+		}
+
+		supplierOrders, ok := bySupplier[group.supplierID]
+		if !ok {
+			supplierOrders = &models.SupplierPurchaseOrders{SupplierID: group.supplierID}
+			bySupplier[group.supplierID] = supplierOrders
+			result = append(result, supplierOrders)
+		}
+		supplierOrders.Orders = append(supplierOrders.Orders, order)
+		supplierOrders.RequisitionIDs = append(supplierOrders.RequisitionIDs, group.requisitions...)
+		supplierOrders.TotalValue = math.Round((supplierOrders.TotalValue+float64(order.Quantity)*order.UnitPrice)*100) / 100
+	}
+	return result, nil
+}
+
+// unitPrice uses the caller's price for a product, falling back to its cost price
+func (s *purchaseRequisitionService) unitPrice(ctx context.Context, tenantID, productID uuid.UUID, overrides map[uuid.UUID]float64) (float64, error) {
+	if price, ok := overrides[productID]; ok {
+		if price <= 0 {
+			return 0, fmt.Errorf("%w: unit price for product %s must be positive", ErrInvalidRequisition, productID)
+		}
+		return price, nil
+	}
+	product, err := s.productRepo.GetByID(ctx, tenantID, productID)
+	if err != nil || product == nil {
+		return 0, fmt.Errorf("%w: product %s not found", ErrInvalidRequisition, productID)
+	}
+	if product.CostPrice == nil || *product.CostPrice <= 0 {
+		return 0, fmt.Errorf("%w: %s has no cost price, pass a unit price for it", ErrInvalidRequisition, product.Name)
+	}
+	return *product.CostPrice, nil
+}
+
+func (s *purchaseRequisitionService) createOrder(ctx context.Context, tenantID uuid.UUID, group *requisitionGroup, unitPrice float64) (*models.Order, error) {
+	refs := make([]string, len(group.requisitions))
+	for i, id := range group.requisitions {
+		refs[i] = id.String()
+	}
+	notes := "Raised from purchase requisitions: " + strings.Join(refs, ", ")
+	needBy := group.needBy
+	supplierID := group.supplierID
+
+	order := &models.Order{
+		TenantID:         tenantID,
+		OrderType:        "purchase",
+		SupplierID:       &supplierID,
+		ProductID:        group.productID,
+		WarehouseID:      group.warehouseID,
+		Quantity:         group.quantity,
+		UnitPrice:        unitPrice,
+		ExpectedDelivery: &needBy,
+		Notes:            &notes,
+	}
+	if err := s.orderService.CreateOrder(ctx, tenantID, order); err != nil {
+		return nil, fmt.Errorf("failed to create purchase order: %w", err)
+	}
+
+	// The order exists now; a requisition cancelled in the meantime is logged
+	// rather than failing the conversion
+	converted, err := s.requisitionRepo.MarkConverted(ctx, tenantID, group.requisitions, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link requisitions to order %s: %w", order.ID, err)
+	}
+	if converted != int64(len(group.requisitions)) {
+		fmt.Printf("Only %d of %d requisitions linked to purchase order %s\n", converted, len(group.requisitions), order.ID)
+	}
+	return order, nil
+}
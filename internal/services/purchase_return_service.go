@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// supplierStatementMaxDays bounds the period of a supplier statement
+const supplierStatementMaxDays = 366 * 5
+
+var (
+	// ErrPurchaseReturnNotFound is returned for returns outside the tenant
+	ErrPurchaseReturnNotFound = errors.New("purchase return not found")
+	// ErrInvalidPurchaseReturn wraps purchase return validation failures
+	ErrInvalidPurchaseReturn = errors.New("invalid purchase return")
+)
+
+// PurchaseReturnService sends received goods back to suppliers. Each return
+// takes the goods out of stock and issues a debit note for their value and
+// GST, which reduces what is owed to the supplier and the input tax credit
+// claimed
+type PurchaseReturnService interface {
+	CreateReturn(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.PurchaseReturnRequest) (*models.PurchaseReturn, error)
+	GetReturn(ctx context.Context, tenantID, returnID uuid.UUID) (*models.PurchaseReturn, error)
+	ListReturns(ctx context.Context, tenantID uuid.UUID, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseReturn, error)
+	ListDebitNotes(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) ([]*models.DebitNote, error)
+	SupplierStatement(ctx context.Context, tenantID, supplierID uuid.UUID, from, to time.Time) (*models.SupplierStatement, error)
+	ITCReversals(ctx context.Context, tenantID uuid.UUID, period time.Time) (*models.ITCReversalReport, error)
+}
+
+type purchaseReturnService struct {
+	returnRepo       repositories.PurchaseReturnRepository
+	orderRepo        repositories.OrderRepository
+	supplierRepo     repositories.SupplierRepository
+	inventoryRepo    repositories.InventoryRepository
+	inventoryService InventoryService
+	periods          PeriodCloseService
+}
+
+// NewPurchaseReturnService creates a new purchase return service instance
+func NewPurchaseReturnService(returnRepo repositories.PurchaseReturnRepository, orderRepo repositories.OrderRepository, supplierRepo repositories.SupplierRepository,
+	inventoryRepo repositories.InventoryRepository, inventoryService InventoryService, periods PeriodCloseService) PurchaseReturnService {
+	return &purchaseReturnService{
+		returnRepo:       returnRepo,
+		orderRepo:        orderRepo,
+		supplierRepo:     supplierRepo,
+		inventoryRepo:    inventoryRepo,
+		inventoryService: inventoryService,
+		periods:          periods,
+	}
+}
+
+// CreateReturn returns part of a received purchase order to its supplier at
+// the price it was bought at. Goods received on consignment still belong to
+// the supplier and go back through the consignment statement instead
+func (s *purchaseReturnService) CreateReturn(ctx context.Context, tenantID uuid.UUID, userID *uuid.UUID, req *models.PurchaseReturnRequest) (*models.PurchaseReturn, error) {
+	if req.OrderID == uuid.Nil {
+		return nil, fmt.Errorf("%w: order_id is required", ErrInvalidPurchaseReturn)
+	}
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidPurchaseReturn)
+	}
+	if req.GSTRate < 0 || req.GSTRate > 100 || math.IsNaN(req.GSTRate) {
+		return nil, fmt.Errorf("%w: gst_rate must be between 0 and 100", ErrInvalidPurchaseReturn)
+	}
+
+	order, err := s.orderRepo.GetByID(ctx, tenantID, req.OrderID)
+	if err != nil || order == nil {
+		return nil, fmt.Errorf("%w: order %s not found", ErrInvalidPurchaseReturn, req.OrderID)
+	}
+	if order.OrderType != "purchase" {
+		return nil, fmt.Errorf("%w: order %s is not a purchase order", ErrInvalidPurchaseReturn, order.ID)
+	}
+	if order.Status != models.OrderStatusDelivered {
+		return nil, fmt.Errorf("%w: only received purchase orders can be returned, current status: %s", ErrInvalidPurchaseReturn, order.Status)
+	}
+	consignment, err := s.returnRepo.ReceivedOnConsignment(ctx, tenantID, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load purchase receipt: %w", err)
+	}
+	if consignment {
+		return nil, fmt.Errorf("%w: order %s was received on consignment", ErrInvalidPurchaseReturn, order.ID)
+	}
+
+	returned, err := s.returnRepo.ReturnedQuantity(ctx, tenantID, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load earlier returns: %w", err)
+	}
+	if returned+req.Quantity > order.Quantity {
+		return nil, fmt.Errorf("%w: only %d of %d units received can still be returned", ErrInvalidPurchaseReturn, order.Quantity-returned, order.Quantity)
+	}
+	stock, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, tenantID, order.WarehouseID, order.ProductID)
+	if err != nil || stock == nil || stock.Quantity < req.Quantity {
+		return nil, fmt.Errorf("%w: not enough stock in the receiving warehouse to return %d units", ErrInvalidPurchaseReturn, req.Quantity)
+	}
+
+	var reason *string
+	if req.Reason != nil && strings.TrimSpace(*req.Reason) != "" {
+		trimmed := strings.TrimSpace(*req.Reason)
+		reason = &trimmed
+	}
+
+	id := uuid.New()
+	now := time.Now()
+	ret := &models.PurchaseReturn{
+		ID:           id,
+		TenantID:     tenantID,
+		ReturnNumber: fmt.Sprintf("PR-%s-%s", now.Format("200601"), strings.ToUpper(id.String()[:8])),
+		OrderID:      order.ID,
+		SupplierID:   order.SupplierID,
+		ProductID:    order.ProductID,
+		WarehouseID:  order.WarehouseID,
+		Quantity:     req.Quantity,
+		UnitPrice:    order.UnitPrice,
+		Reason:       reason,
+		ReturnedBy:   userID,
+	}
+
+	var supplierGSTIN *string
+	if order.SupplierID != nil {
+		if supplier, err := s.supplierRepo.GetByID(ctx, tenantID, *order.SupplierID); err == nil && supplier != nil {
+			supplierGSTIN = supplier.GSTIN
+		}
+	}
+	noteID := uuid.New()
+	ret.DebitNote = debitNoteFor(ret, req.GSTRate, req.InterState)
+	ret.DebitNote.ID = noteID
+	ret.DebitNote.DebitNoteNumber = fmt.Sprintf("DN-%s-%s", now.Format("200601"), strings.ToUpper(noteID.String()[:8]))
+	ret.DebitNote.SupplierGSTIN = supplierGSTIN
+	ret.DebitNote.IssuedDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	ret.DebitNote.CreatedBy = userID
+	if err := checkPeriodOpen(ctx, s.periods, tenantID, order.BranchID, ret.DebitNote.IssuedDate); err != nil {
+		return nil, err
+	}
+
+	// Stored before stock moves, so a concurrent return of the same units
+	// fails here rather than after taking them out of stock
+	ok, err := s.returnRepo.Create(ctx, ret, order.Quantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save purchase return: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: the order's returns would exceed the %d units received", ErrInvalidPurchaseReturn, order.Quantity)
+	}
+	if err := s.inventoryService.AdjustStockWithReason(ctx, tenantID, order.WarehouseID, order.ProductID, -req.Quantity, models.InventoryReasonPurchaseReturn); err != nil {
+		return nil, fmt.Errorf("failed to update inventory: %w", err)
+	}
+	return ret, nil
+}
+
+// debitNoteFor works out the debit note for a return: the goods value plus
+// GST at the rate, split into CGST and SGST within the state
+func debitNoteFor(ret *models.PurchaseReturn, gstRate float64, interState bool) *models.DebitNote {
+	taxable := roundAllocation(float64(ret.Quantity) * ret.UnitPrice)
+	gst := roundAllocation(taxable * gstRate / 100)
+	note := &models.DebitNote{
+		TenantID:         ret.TenantID,
+		SupplierID:       ret.SupplierID,
+		PurchaseReturnID: ret.ID,
+		TaxableAmount:    taxable,
+		GSTRate:          gstRate,
+		TotalAmount:      roundAllocation(taxable + gst),
+	}
+	if interState {
+		note.IGST = gst
+	} else {
+		// Any odd paisa goes to SGST so the halves add up to the GST
+		note.CGST = math.Floor(gst*100/2) / 100
+		note.SGST = roundAllocation(gst - note.CGST)
+	}
+	return note
+}
+
+func (s *purchaseReturnService) GetReturn(ctx context.Context, tenantID, returnID uuid.UUID) (*models.PurchaseReturn, error) {
+	ret, err := s.returnRepo.GetByID(ctx, tenantID, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, ErrPurchaseReturnNotFound
+	}
+	return ret, nil
+}
+
+func (s *purchaseReturnService) ListReturns(ctx context.Context, tenantID uuid.UUID, orderID *uuid.UUID, limit, offset int) ([]*models.PurchaseReturn, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.returnRepo.List(ctx, tenantID, orderID, limit, offset)
+}
+
+func (s *purchaseReturnService) ListDebitNotes(ctx context.Context, tenantID uuid.UUID, supplierID *uuid.UUID, from, to time.Time) ([]*models.DebitNote, error) {
+	return s.returnRepo.ListDebitNotes(ctx, tenantID, supplierID, from, to)
+}
+
+// SupplierStatement builds the supplier's statement for from to to, both inclusive
+func (s *purchaseReturnService) SupplierStatement(ctx context.Context, tenantID, supplierID uuid.UUID, from, to time.Time) (*models.SupplierStatement, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidPurchaseReturn)
+	}
+	if to.Sub(from) > supplierStatementMaxDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: statement period must be at most %d days", ErrInvalidPurchaseReturn, supplierStatementMaxDays)
+	}
+	supplier, err := s.supplierRepo.GetByID(ctx, tenantID, supplierID)
+	if err != nil || supplier == nil {
+		return nil, ErrSupplierNotFound
+	}
+
+	opening, err := s.returnRepo.SupplierBalanceBefore(ctx, tenantID, supplierID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute opening balance: %w", err)
+	}
+	entries, err := s.returnRepo.ListSupplierEntries(ctx, tenantID, supplierID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load statement entries: %w", err)
+	}
+
+	statement := &models.SupplierStatement{
+		TenantID:       tenantID,
+		SupplierID:     supplier.ID,
+		SupplierName:   supplier.Name,
+		SupplierGSTIN:  supplier.GSTIN,
+		From:           from,
+		To:             to,
+		OpeningBalance: roundAllocation(opening),
+		Entries:        entries,
+		GeneratedAt:    time.Now(),
+	}
+	if statement.Entries == nil {
+		statement.Entries = []*models.StatementEntry{}
+	}
+
+	balance := opening
+	for _, entry := range statement.Entries {
+		entry.Description = describeSupplierEntry(entry)
+		statement.TotalDebits += entry.Debit
+		statement.TotalCredits += entry.Credit
+		balance += entry.Credit - entry.Debit
+		entry.Balance = roundAllocation(balance)
+	}
+	statement.TotalDebits = roundAllocation(statement.TotalDebits)
+	statement.TotalCredits = roundAllocation(statement.TotalCredits)
+	statement.ClosingBalance = roundAllocation(balance)
+	return statement, nil
+}
+
+func describeSupplierEntry(entry *models.StatementEntry) string {
+	switch entry.Type {
+	case models.StatementEntryPurchase:
+		return "Purchase order " + entry.Reference + " received"
+	case models.StatementEntryDebitNote:
+		if entry.Description != "" {
+			return "Debit note " + entry.Reference + ": " + entry.Description
+		}
+		return "Debit note " + entry.Reference
+	}
+	return entry.Reference
+}
+
+// ITCReversals totals the GST on debit notes issued in the period's month,
+// the input tax credit to reverse in GSTR-3B
+func (s *purchaseReturnService) ITCReversals(ctx context.Context, tenantID uuid.UUID, period time.Time) (*models.ITCReversalReport, error) {
+	from := time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	notes, err := s.returnRepo.ListDebitNotes(ctx, tenantID, nil, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load debit notes: %w", err)
+	}
+
+	report := &models.ITCReversalReport{
+		TenantID:     tenantID,
+		ReturnPeriod: from.Format("012006"),
+		DebitNotes:   notes,
+	}
+	if report.DebitNotes == nil {
+		report.DebitNotes = []*models.DebitNote{}
+	}
+	for _, note := range notes {
+		report.TotalTaxableValue += note.TaxableAmount
+		report.TotalCGST += note.CGST
+		report.TotalSGST += note.SGST
+		report.TotalIGST += note.IGST
+	}
+	report.TotalTaxableValue = roundAllocation(report.TotalTaxableValue)
+	report.TotalCGST = roundAllocation(report.TotalCGST)
+	report.TotalSGST = roundAllocation(report.TotalSGST)
+	report.TotalIGST = roundAllocation(report.TotalIGST)
+	return report, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+)
+
+// ErrPushTokenInvalid means the provider no longer accepts a device token and
+// it should be dropped
+var ErrPushTokenInvalid = errors.New("push token is no longer valid")
+
+// PushDriver delivers a message to a single device token
+type PushDriver interface {
+	Send(ctx context.Context, token string, msg *models.PushMessage) error
+}
+
+const (
+	fcmScope   = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmSendURL = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+)
+
+// fcmDriver sends through the FCM HTTP v1 API using a service account
+type fcmDriver struct {
+	account    *GoogleServiceAccount
+	httpClient *http.Client
+}
+
+// NewFCMDriver loads a service account key file for the FCM HTTP v1 API
+func NewFCMDriver(credentialsFile string) (PushDriver, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+	account, err := NewGoogleServiceAccount(data, fcmScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load FCM credentials: %w", err)
+	}
+	return &fcmDriver{
+		account:    account,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (d *fcmDriver) Send(ctx context.Context, token string, msg *models.PushMessage) error {
+	accessToken, err := d.account.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": msg.Title,
+				"body":  msg.Body,
+			},
+			"data":    msg.Data,
+			"android": map[string]string{"priority": "high"},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal FCM message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(fcmSendURL, d.account.ProjectID), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create FCM request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("FCM request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if fcmTokenRejected(resp.StatusCode, respBody) {
+		return ErrPushTokenInvalid
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		d.account.Invalidate()
+	}
+	return fmt.Errorf("FCM returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// fcmTokenRejected reports whether FCM refused the registration token itself:
+// UNREGISTERED for uninstalled apps and INVALID_ARGUMENT for malformed tokens
+func fcmTokenRejected(status int, body []byte) bool {
+	if status != http.StatusNotFound && status != http.StatusBadRequest {
+		return false
+	}
+	var parsed struct {
+		Error struct {
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return false
+	}
+	for _, detail := range parsed.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" || detail.ErrorCode == "INVALID_ARGUMENT" {
+			return true
+		}
+	}
+	return parsed.Error.Status == "NOT_FOUND"
+}
+
+// logPushDriver logs pushes when FCM is not configured, like the email and SMS placeholders
+type logPushDriver struct{}
+
+// NewLogPushDriver creates a driver that only logs messages
+func NewLogPushDriver() PushDriver {
+	return logPushDriver{}
+}
+
+func (logPushDriver) Send(ctx context.Context, token string, msg *models.PushMessage) error {
+	prefix := token
+	if len(prefix) > 12 {
+		prefix = prefix[:12]
+	}
+	log.Printf("[PUSH] Token=%s..., Title=%s, Body=%s", prefix, msg.Title, msg.Body)
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrDeviceNotFound is returned when unregistering a device the user does not own
+var ErrDeviceNotFound = errors.New("device not found")
+
+// PushService manages device registrations and fans messages out to them
+type PushService interface {
+	RegisterDevice(ctx context.Context, tenantID, userID uuid.UUID, device *models.DeviceToken) error
+	ListDevices(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.DeviceToken, error)
+	UnregisterDevice(ctx context.Context, tenantID, userID, deviceID uuid.UUID) error
+	UnregisterToken(ctx context.Context, tenantID, userID uuid.UUID, token string) error
+	SendToUser(ctx context.Context, tenantID, userID uuid.UUID, msg *models.PushMessage) (int, error)
+	SendToTopic(ctx context.Context, tenantID uuid.UUID, topic string, msg *models.PushMessage) (int, error)
+	PruneStaleTokens(ctx context.Context, maxAge time.Duration) (int64, error)
+}
+
+type pushService struct {
+	deviceRepo repositories.DeviceTokenRepository
+	driver     PushDriver
+}
+
+// NewPushService creates a new push notification service
+func NewPushService(deviceRepo repositories.DeviceTokenRepository, driver PushDriver) PushService {
+	return &pushService{
+		deviceRepo: deviceRepo,
+		driver:     driver,
+	}
+}
+
+func (s *pushService) RegisterDevice(ctx context.Context, tenantID, userID uuid.UUID, device *models.DeviceToken) error {
+	device.Token = strings.TrimSpace(device.Token)
+	if device.Token == "" {
+		return fmt.Errorf("token is required")
+	}
+	if len(device.Token) > 4096 {
+		return fmt.Errorf("token is too long")
+	}
+	switch device.Platform {
+	case models.DevicePlatformAndroid, models.DevicePlatformIOS, models.DevicePlatformWeb:
+	default:
+		return fmt.Errorf("platform must be android, ios or web")
+	}
+	if len(device.Topics) == 0 {
+		device.Topics = models.PushTopics
+	}
+	for _, topic := range device.Topics {
+		if !isPushTopic(topic) {
+			return fmt.Errorf("unknown topic %q", topic)
+		}
+	}
+
+	device.ID = uuid.New()
+	device.TenantID = tenantID
+	device.UserID = userID
+	return s.deviceRepo.Upsert(ctx, device)
+}
+
+func isPushTopic(topic string) bool {
+	for _, known := range models.PushTopics {
+		if topic == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *pushService) ListDevices(ctx context.Context, tenantID, userID uuid.UUID) ([]*models.DeviceToken, error) {
+	return s.deviceRepo.ListByUser(ctx, tenantID, userID)
+}
+
+func (s *pushService) UnregisterDevice(ctx context.Context, tenantID, userID, deviceID uuid.UUID) error {
+	deleted, err := s.deviceRepo.Delete(ctx, tenantID, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *pushService) UnregisterToken(ctx context.Context, tenantID, userID uuid.UUID, token string) error {
+	deleted, err := s.deviceRepo.DeleteUserToken(ctx, tenantID, userID, strings.TrimSpace(token))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (s *pushService) SendToUser(ctx context.Context, tenantID, userID uuid.UUID, msg *models.PushMessage) (int, error) {
+	devices, err := s.deviceRepo.ListByUser(ctx, tenantID, userID)
+	if err != nil {
+		return 0, err
+	}
+	return s.deliver(ctx, devices, msg), nil
+}
+
+func (s *pushService) SendToTopic(ctx context.Context, tenantID uuid.UUID, topic string, msg *models.PushMessage) (int, error) {
+	devices, err := s.deviceRepo.ListByTopic(ctx, tenantID, topic)
+	if err != nil {
+		return 0, err
+	}
+	return s.deliver(ctx, devices, msg), nil
+}
+
+// deliver sends to each device and drops tokens the provider rejects; other
+// failures are logged so one bad device does not stop the rest
+func (s *pushService) deliver(ctx context.Context, devices []*models.DeviceToken, msg *models.PushMessage) int {
+	sent := 0
+	for _, device := range devices {
+		err := s.driver.Send(ctx, device.Token, msg)
+		if err == nil {
+			sent++
+			continue
+		}
+		if errors.Is(err, ErrPushTokenInvalid) {
+			if err := s.deviceRepo.DeleteByToken(ctx, device.Token); err != nil {
+				log.Printf("Failed to prune invalid device token %s: %v", device.ID, err)
+			}
+			continue
+		}
+		log.Printf("Failed to push to device %s: %v", device.ID, err)
+	}
+	return sent
+}
+
+// PruneStaleTokens removes registrations the app has not refreshed within maxAge
+func (s *pushService) PruneStaleTokens(ctx context.Context, maxAge time.Duration) (int64, error) {
+	return s.deviceRepo.DeleteStale(ctx, time.Now().Add(-maxAge))
+}
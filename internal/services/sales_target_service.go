@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSalesTargetNotFound is returned for targets outside the tenant
+	ErrSalesTargetNotFound = errors.New("sales target not found")
+	// ErrInvalidSalesTarget wraps sales target validation failures
+	ErrInvalidSalesTarget = errors.New("invalid sales target")
+	// ErrSalesTargetConflict is returned for a second target of the same
+	// subject, month and basis
+	ErrSalesTargetConflict = errors.New("sales target already exists")
+	// ErrTerritoryCustomerNotFound is returned when assigning an unknown
+	// customer to a sales rep or region
+	ErrTerritoryCustomerNotFound = errors.New("customer not found")
+)
+
+// SalesTargetService sets monthly sales targets per sales rep, region and
+// product category and tracks their achievement. Sales count towards the rep
+// and region their customer is assigned to
+type SalesTargetService interface {
+	ListTargets(ctx context.Context, tenantID uuid.UUID, month, scope string) ([]*models.SalesTarget, error)
+	CreateTarget(ctx context.Context, tenantID uuid.UUID, createdBy *uuid.UUID, req *models.SalesTargetRequest) (*models.SalesTarget, error)
+	UpdateTarget(ctx context.Context, tenantID, targetID uuid.UUID, req *models.SalesTargetRequest) (*models.SalesTarget, error)
+	DeleteTarget(ctx context.Context, tenantID, targetID uuid.UUID) error
+
+	SetCustomerTerritory(ctx context.Context, tenantID, customerID uuid.UUID, req *models.CustomerTerritoryRequest) error
+
+	// Leaderboard ranks the month's targets of a scope by achievement; an
+	// empty month is the current one
+	Leaderboard(ctx context.Context, tenantID uuid.UUID, month, scope string) (*models.SalesTargetBoard, error)
+	// CalculateAchievement recomputes the achievement of the month's targets
+	CalculateAchievement(ctx context.Context, tenantID uuid.UUID, month time.Time) (int64, error)
+}
+
+type salesTargetService struct {
+	repo         repositories.SalesTargetRepository
+	userRepo     repositories.UserRepository
+	categoryRepo repositories.CategoryRepository
+}
+
+// NewSalesTargetService creates a new sales target service
+func NewSalesTargetService(repo repositories.SalesTargetRepository, userRepo repositories.UserRepository, categoryRepo repositories.CategoryRepository) SalesTargetService {
+	return &salesTargetService{
+		repo:         repo,
+		userRepo:     userRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// targetMonth is the first day of the month of t
+func targetMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// parseTargetMonth reads a YYYY-MM month; empty is the current month
+func parseTargetMonth(month string) (time.Time, error) {
+	if month == "" {
+		return targetMonth(time.Now()), nil
+	}
+	parsed, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: month must be in YYYY-MM format", ErrInvalidSalesTarget)
+	}
+	return parsed, nil
+}
+
+func validTargetScope(scope string) bool {
+	return scope == models.SalesTargetScopeUser || scope == models.SalesTargetScopeRegion || scope == models.SalesTargetScopeCategory
+}
+
+// achievementPercent is achieved as a percentage of target, to two decimals
+func achievementPercent(achieved, target float64) float64 {
+	if target <= 0 {
+		return 0
+	}
+	return math.Round(achieved/target*10000) / 100
+}
+
+func (s *salesTargetService) ListTargets(ctx context.Context, tenantID uuid.UUID, month, scope string) ([]*models.SalesTarget, error) {
+	period, err := parseTargetMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	scope = strings.ToLower(strings.TrimSpace(scope))
+	if scope != "" && !validTargetScope(scope) {
+		return nil, fmt.Errorf("%w: scope must be user, region or category", ErrInvalidSalesTarget)
+	}
+
+	targets, err := s.repo.List(ctx, tenantID, period, scope)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		target.AchievementPercent = achievementPercent(target.AchievedAmount, target.TargetAmount)
+	}
+	return targets, nil
+}
+
+func (s *salesTargetService) CreateTarget(ctx context.Context, tenantID uuid.UUID, createdBy *uuid.UUID, req *models.SalesTargetRequest) (*models.SalesTarget, error) {
+	if req.Month == "" {
+		return nil, fmt.Errorf("%w: month is required", ErrInvalidSalesTarget)
+	}
+	month, err := parseTargetMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+	if req.TargetAmount == nil || *req.TargetAmount <= 0 {
+		return nil, fmt.Errorf("%w: target_amount must be positive", ErrInvalidSalesTarget)
+	}
+	basis := strings.ToLower(strings.TrimSpace(req.Basis))
+	if basis == "" {
+		basis = models.SalesTargetBasisInvoices
+	}
+	if basis != models.SalesTargetBasisOrders && basis != models.SalesTargetBasisInvoices {
+		return nil, fmt.Errorf("%w: basis must be orders or invoices", ErrInvalidSalesTarget)
+	}
+
+	target := &models.SalesTarget{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		Month:        month,
+		Scope:        strings.ToLower(strings.TrimSpace(req.Scope)),
+		Basis:        basis,
+		TargetAmount: math.Round(*req.TargetAmount*100) / 100,
+		Notes:        trimmedOrNil(req.Notes),
+		CreatedBy:    createdBy,
+	}
+	switch target.Scope {
+	case models.SalesTargetScopeUser:
+		if req.UserID == nil {
+			return nil, fmt.Errorf("%w: user_id is required for a user target", ErrInvalidSalesTarget)
+		}
+		user, err := s.userRepo.GetByID(ctx, tenantID, *req.UserID)
+		if err != nil || user == nil {
+			return nil, fmt.Errorf("%w: user not found", ErrInvalidSalesTarget)
+		}
+		target.UserID = req.UserID
+	case models.SalesTargetScopeRegion:
+		target.Region = trimmedOrNil(req.Region)
+		if target.Region == nil || len(*target.Region) > 100 {
+			return nil, fmt.Errorf("%w: region is required for a region target and must be at most 100 characters", ErrInvalidSalesTarget)
+		}
+	case models.SalesTargetScopeCategory:
+		if req.CategoryID == nil {
+			return nil, fmt.Errorf("%w: category_id is required for a category target", ErrInvalidSalesTarget)
+		}
+		category, err := s.categoryRepo.GetByID(ctx, tenantID, *req.CategoryID)
+		if err != nil || category == nil {
+			return nil, fmt.Errorf("%w: category not found", ErrInvalidSalesTarget)
+		}
+		target.CategoryID = req.CategoryID
+	default:
+		return nil, fmt.Errorf("%w: scope must be user, region or category", ErrInvalidSalesTarget)
+	}
+
+	ok, err := s.repo.Create(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sales target: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w for this %s in %s on %s", ErrSalesTargetConflict, target.Scope, month.Format("2006-01"), basis)
+	}
+
+	// Achievement so far in the month rather than zero; the job catches up
+	// if this fails
+	if _, err := s.repo.CalculateAchievement(ctx, tenantID, month); err != nil {
+		log.Printf("Failed to calculate sales target achievement for tenant %s: %v", tenantID, err)
+	}
+	created, err := s.repo.Get(ctx, tenantID, target.ID)
+	if err != nil || created == nil {
+		return nil, fmt.Errorf("failed to load sales target: %w", err)
+	}
+	created.AchievementPercent = achievementPercent(created.AchievedAmount, created.TargetAmount)
+	return created, nil
+}
+
+// UpdateTarget changes a target's amount or notes; to change what it is set
+// for, delete it and create another
+func (s *salesTargetService) UpdateTarget(ctx context.Context, tenantID, targetID uuid.UUID, req *models.SalesTargetRequest) (*models.SalesTarget, error) {
+	target, err := s.repo.Get(ctx, tenantID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sales target: %w", err)
+	}
+	if target == nil {
+		return nil, ErrSalesTargetNotFound
+	}
+
+	if req.TargetAmount != nil {
+		if *req.TargetAmount <= 0 {
+			return nil, fmt.Errorf("%w: target_amount must be positive", ErrInvalidSalesTarget)
+		}
+		target.TargetAmount = math.Round(*req.TargetAmount*100) / 100
+	}
+	if req.Notes != nil {
+		target.Notes = trimmedOrNil(req.Notes)
+	}
+
+	ok, err := s.repo.Update(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update sales target: %w", err)
+	}
+	if !ok {
+		return nil, ErrSalesTargetNotFound
+	}
+	target.AchievementPercent = achievementPercent(target.AchievedAmount, target.TargetAmount)
+	return target, nil
+}
+
+func (s *salesTargetService) DeleteTarget(ctx context.Context, tenantID, targetID uuid.UUID) error {
+	ok, err := s.repo.Delete(ctx, tenantID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sales target: %w", err)
+	}
+	if !ok {
+		return ErrSalesTargetNotFound
+	}
+	return nil
+}
+
+func (s *salesTargetService) SetCustomerTerritory(ctx context.Context, tenantID, customerID uuid.UUID, req *models.CustomerTerritoryRequest) error {
+	if req.SalesRepID != nil {
+		user, err := s.userRepo.GetByID(ctx, tenantID, *req.SalesRepID)
+		if err != nil || user == nil {
+			return fmt.Errorf("%w: sales rep not found", ErrInvalidSalesTarget)
+		}
+	}
+	region := trimmedOrNil(req.Region)
+	if region != nil && len(*region) > 100 {
+		return fmt.Errorf("%w: region must be at most 100 characters", ErrInvalidSalesTarget)
+	}
+
+	ok, err := s.repo.SetCustomerTerritory(ctx, tenantID, customerID, req.SalesRepID, region)
+	if err != nil {
+		return fmt.Errorf("failed to assign customer: %w", err)
+	}
+	if !ok {
+		return ErrTerritoryCustomerNotFound
+	}
+	return nil
+}
+
+func (s *salesTargetService) Leaderboard(ctx context.Context, tenantID uuid.UUID, month, scope string) (*models.SalesTargetBoard, error) {
+	scope = strings.ToLower(strings.TrimSpace(scope))
+	if scope == "" {
+		scope = models.SalesTargetScopeUser
+	}
+	if !validTargetScope(scope) {
+		return nil, fmt.Errorf("%w: scope must be user, region or category", ErrInvalidSalesTarget)
+	}
+	targets, err := s.ListTargets(ctx, tenantID, month, scope)
+	if err != nil {
+		return nil, err
+	}
+	period, _ := parseTargetMonth(month)
+	return rankSalesTargets(period, scope, targets), nil
+}
+
+// rankSalesTargets orders targets by achievement percentage, then by amount
+// achieved; equal percentages share a rank
+func rankSalesTargets(month time.Time, scope string, targets []*models.SalesTarget) *models.SalesTargetBoard {
+	board := &models.SalesTargetBoard{Month: month, Scope: scope, Standings: []*models.SalesTargetStanding{}}
+
+	sorted := append([]*models.SalesTarget{}, targets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].AchievementPercent != sorted[j].AchievementPercent {
+			return sorted[i].AchievementPercent > sorted[j].AchievementPercent
+		}
+		return sorted[i].AchievedAmount > sorted[j].AchievedAmount
+	})
+
+	for i, target := range sorted {
+		rank := i + 1
+		if i > 0 && target.AchievementPercent == sorted[i-1].AchievementPercent {
+			rank = board.Standings[i-1].Rank
+		}
+		board.Standings = append(board.Standings, &models.SalesTargetStanding{Rank: rank, SalesTarget: target})
+
+		board.TargetTotal += target.TargetAmount
+		board.AchievedTotal += target.AchievedAmount
+		if target.AchievedAmount >= target.TargetAmount {
+			board.TargetsMet++
+		}
+		// The board is as fresh as its least recently calculated target
+		if target.CalculatedAt != nil && (board.CalculatedAt == nil || target.CalculatedAt.Before(*board.CalculatedAt)) {
+			board.CalculatedAt = target.CalculatedAt
+		}
+	}
+	board.TargetTotal = math.Round(board.TargetTotal*100) / 100
+	board.AchievedTotal = math.Round(board.AchievedTotal*100) / 100
+	board.AchievementPercent = achievementPercent(board.AchievedTotal, board.TargetTotal)
+	return board
+}
+
+func (s *salesTargetService) CalculateAchievement(ctx context.Context, tenantID uuid.UUID, month time.Time) (int64, error) {
+	return s.repo.CalculateAchievement(ctx, tenantID, targetMonth(month))
+}
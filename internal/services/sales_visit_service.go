@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	salesVisitPhotoBucket = "sales-visit-photos"
+	salesVisitPhotoURLTTL = time.Hour
+	// maxAccuracyAllowanceM caps how much reported GPS error can stretch a geofence
+	maxAccuracyAllowanceM = 100.0
+	earthRadiusM          = 6371000.0
+)
+
+var (
+	// ErrVisitNotFound is returned for visits outside the tenant or not owned by the caller
+	ErrVisitNotFound = errors.New("visit not found")
+	// ErrVisitAlreadyOpen is returned when checking in before checking out of the previous visit
+	ErrVisitAlreadyOpen = errors.New("already checked in to another visit")
+)
+
+// GeofenceViolationError is returned when a check-in is too far from the distributor
+type GeofenceViolationError struct {
+	DistanceM float64
+	RadiusM   int
+}
+
+func (e *GeofenceViolationError) Error() string {
+	return fmt.Sprintf("check-in is %.0fm from the distributor, outside the %dm geofence", e.DistanceM, e.RadiusM)
+}
+
+// CheckInRequest is a rep arriving at a distributor
+type CheckInRequest struct {
+	DistributorID uuid.UUID `json:"distributor_id"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	AccuracyM     *float64  `json:"accuracy_m"`
+	Notes         *string   `json:"notes"`
+}
+
+// CheckOutRequest is a rep leaving; notes replace the check-in notes when set
+type CheckOutRequest struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Notes     *string `json:"notes"`
+}
+
+// SalesVisitService records field rep visits and builds route reports
+type SalesVisitService interface {
+	CheckIn(ctx context.Context, tenantID, userID uuid.UUID, req *CheckInRequest) (*models.SalesVisit, error)
+	CheckOut(ctx context.Context, tenantID, userID, visitID uuid.UUID, req *CheckOutRequest) (*models.SalesVisit, error)
+	AddPhoto(ctx context.Context, tenantID, userID, visitID uuid.UUID, filename string, reader io.Reader, size int64, caption *string) (*models.SalesVisitPhoto, error)
+	GetVisit(ctx context.Context, tenantID, visitID uuid.UUID) (*models.SalesVisit, error)
+	ListVisits(ctx context.Context, tenantID uuid.UUID, filter *models.SalesVisitFilter) ([]*models.SalesVisit, error)
+	RouteReport(ctx context.Context, tenantID, userID uuid.UUID, date time.Time, loc *time.Location) (*models.RouteReport, error)
+}
+
+type salesVisitService struct {
+	visitRepo       repositories.SalesVisitRepository
+	distributorRepo repositories.DistributorRepository
+	minioService    MinioService
+}
+
+// NewSalesVisitService creates a new sales visit service
+func NewSalesVisitService(visitRepo repositories.SalesVisitRepository, distributorRepo repositories.DistributorRepository, minioService MinioService) SalesVisitService {
+	return &salesVisitService{
+		visitRepo:       visitRepo,
+		distributorRepo: distributorRepo,
+		minioService:    minioService,
+	}
+}
+
+func validateVisitCoordinates(latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("invalid latitude or longitude")
+	}
+	if latitude == 0 && longitude == 0 {
+		return fmt.Errorf("location is required")
+	}
+	return nil
+}
+
+// haversineMeters is the great-circle distance between two points
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// distanceFrom returns the distance from the distributor, or nil when it has no location
+func distanceFrom(distributor *models.Distributor, latitude, longitude float64) *float64 {
+	if distributor.Latitude == nil || distributor.Longitude == nil {
+		return nil
+	}
+	distance := math.Round(haversineMeters(*distributor.Latitude, *distributor.Longitude, latitude, longitude))
+	return &distance
+}
+
+func (s *salesVisitService) CheckIn(ctx context.Context, tenantID, userID uuid.UUID, req *CheckInRequest) (*models.SalesVisit, error) {
+	if req.DistributorID == uuid.Nil {
+		return nil, fmt.Errorf("distributor_id is required")
+	}
+	if err := validateVisitCoordinates(req.Latitude, req.Longitude); err != nil {
+		return nil, err
+	}
+	if req.AccuracyM != nil && *req.AccuracyM < 0 {
+		return nil, fmt.Errorf("accuracy_m cannot be negative")
+	}
+
+	open, err := s.visitRepo.GetOpenForUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, ErrVisitAlreadyOpen
+	}
+
+	distributor, err := s.distributorRepo.GetByID(ctx, tenantID, req.DistributorID)
+	if err != nil || distributor == nil {
+		return nil, fmt.Errorf("distributor not found")
+	}
+
+	// A poor GPS fix may place the rep outside the fence while standing at the
+	// door, so the reported accuracy (capped) is given the benefit of the doubt
+	distance := distanceFrom(distributor, req.Latitude, req.Longitude)
+	if distance != nil {
+		allowance := 0.0
+		if req.AccuracyM != nil {
+			allowance = math.Min(*req.AccuracyM, maxAccuracyAllowanceM)
+		}
+		if *distance-allowance > float64(distributor.GeofenceRadiusM) {
+			return nil, &GeofenceViolationError{DistanceM: *distance, RadiusM: distributor.GeofenceRadiusM}
+		}
+	}
+
+	visit := &models.SalesVisit{
+		ID:               uuid.New(),
+		TenantID:         tenantID,
+		UserID:           userID,
+		DistributorID:    distributor.ID,
+		CheckInAt:        time.Now(),
+		CheckInLatitude:  req.Latitude,
+		CheckInLongitude: req.Longitude,
+		CheckInAccuracyM: req.AccuracyM,
+		CheckInDistanceM: distance,
+		Notes:            req.Notes,
+	}
+	if err := s.visitRepo.Create(ctx, visit); err != nil {
+		// The open-visit unique index catches a concurrent second check-in
+		if strings.Contains(err.Error(), "idx_sales_visits_open") {
+			return nil, ErrVisitAlreadyOpen
+		}
+		return nil, err
+	}
+	return visit, nil
+}
+
+// ownVisit loads a visit the caller checked in to
+func (s *salesVisitService) ownVisit(ctx context.Context, tenantID, userID, visitID uuid.UUID) (*models.SalesVisit, error) {
+	visit, err := s.visitRepo.GetByID(ctx, tenantID, visitID)
+	if err != nil {
+		return nil, err
+	}
+	if visit == nil || visit.UserID != userID {
+		return nil, ErrVisitNotFound
+	}
+	return visit, nil
+}
+
+// CheckOut closes a visit. Leaving outside the geofence is recorded rather
+// than refused, since reps often check out from the road.
+func (s *salesVisitService) CheckOut(ctx context.Context, tenantID, userID, visitID uuid.UUID, req *CheckOutRequest) (*models.SalesVisit, error) {
+	if err := validateVisitCoordinates(req.Latitude, req.Longitude); err != nil {
+		return nil, err
+	}
+	visit, err := s.ownVisit(ctx, tenantID, userID, visitID)
+	if err != nil {
+		return nil, err
+	}
+	if visit.CheckOutAt != nil {
+		return nil, fmt.Errorf("visit is already checked out")
+	}
+
+	distributor, err := s.distributorRepo.GetByID(ctx, tenantID, visit.DistributorID)
+	if err == nil && distributor != nil {
+		visit.CheckOutDistanceM = distanceFrom(distributor, req.Latitude, req.Longitude)
+	}
+
+	now := time.Now()
+	latitude, longitude := req.Latitude, req.Longitude
+	visit.CheckOutAt = &now
+	visit.CheckOutLatitude = &latitude
+	visit.CheckOutLongitude = &longitude
+	if req.Notes != nil {
+		visit.Notes = req.Notes
+	}
+	if err := s.visitRepo.CheckOut(ctx, visit); err != nil {
+		return nil, err
+	}
+	return visit, nil
+}
+
+func (s *salesVisitService) AddPhoto(ctx context.Context, tenantID, userID, visitID uuid.UUID, filename string, reader io.Reader, size int64, caption *string) (*models.SalesVisitPhoto, error) {
+	if _, err := s.ownVisit(ctx, tenantID, userID, visitID); err != nil {
+		return nil, err
+	}
+
+	photo := &models.SalesVisitPhoto{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+		VisitID:  visitID,
+		Caption:  caption,
+	}
+	photo.ObjectKey = fmt.Sprintf("%s/%s/%s%s", tenantID.String(), visitID.String(), photo.ID.String(), strings.ToLower(filepath.Ext(filename)))
+
+	if err := s.minioService.EnsureBucketExists(ctx, salesVisitPhotoBucket); err != nil {
+		return nil, fmt.Errorf("failed to ensure bucket exists: %w", err)
+	}
+	if err := s.minioService.UploadImage(ctx, salesVisitPhotoBucket, photo.ObjectKey, reader, size); err != nil {
+		return nil, fmt.Errorf("failed to upload photo to storage: %w", err)
+	}
+	if err := s.visitRepo.AddPhoto(ctx, photo); err != nil {
+		if delErr := s.minioService.DeleteImage(ctx, salesVisitPhotoBucket, photo.ObjectKey); delErr != nil {
+			fmt.Printf("Failed to remove orphaned visit photo %s: %v\n", photo.ObjectKey, delErr)
+		}
+		return nil, err
+	}
+	s.signPhoto(photo)
+	return photo, nil
+}
+
+func (s *salesVisitService) signPhoto(photo *models.SalesVisitPhoto) {
+	url, err := s.minioService.GetPresignedURL(salesVisitPhotoBucket, photo.ObjectKey, salesVisitPhotoURLTTL)
+	if err != nil {
+		fmt.Printf("Failed to sign visit photo %s: %v\n", photo.ID, err)
+		return
+	}
+	photo.URL = url
+}
+
+func (s *salesVisitService) GetVisit(ctx context.Context, tenantID, visitID uuid.UUID) (*models.SalesVisit, error) {
+	visit, err := s.visitRepo.GetByID(ctx, tenantID, visitID)
+	if err != nil {
+		return nil, err
+	}
+	if visit == nil {
+		return nil, ErrVisitNotFound
+	}
+	photos, err := s.visitRepo.ListPhotos(ctx, tenantID, visitID)
+	if err != nil {
+		return nil, err
+	}
+	for _, photo := range photos {
+		s.signPhoto(photo)
+	}
+	visit.Photos = photos
+	return visit, nil
+}
+
+func (s *salesVisitService) ListVisits(ctx context.Context, tenantID uuid.UUID, filter *models.SalesVisitFilter) ([]*models.SalesVisit, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	return s.visitRepo.List(ctx, tenantID, filter)
+}
+
+// RouteReport lists a rep's visits on one calendar day in loc, with time on
+// site and straight-line travel between consecutive check-ins
+func (s *salesVisitService) RouteReport(ctx context.Context, tenantID, userID uuid.UUID, date time.Time, loc *time.Location) (*models.RouteReport, error) {
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	visits, err := s.visitRepo.List(ctx, tenantID, &models.SalesVisitFilter{
+		UserID: &userID,
+		From:   &dayStart,
+		To:     &dayEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.RouteReport{
+		UserID:   userID,
+		Date:     dayStart.Format("2006-01-02"),
+		Timezone: loc.String(),
+		Stops:    []*models.RouteStop{},
+	}
+	names := make(map[uuid.UUID]string)
+	radii := make(map[uuid.UUID]int)
+	var previous *models.SalesVisit
+	for _, visit := range visits {
+		if _, ok := names[visit.DistributorID]; !ok {
+			distributor, err := s.distributorRepo.GetByID(ctx, tenantID, visit.DistributorID)
+			if err == nil && distributor != nil {
+				names[visit.DistributorID] = distributor.Name
+				radii[visit.DistributorID] = distributor.GeofenceRadiusM
+			} else {
+				names[visit.DistributorID] = ""
+			}
+		}
+
+		stop := &models.RouteStop{Visit: visit, DistributorName: names[visit.DistributorID]}
+		if visit.CheckOutAt != nil {
+			minutes := math.Round(visit.CheckOutAt.Sub(visit.CheckInAt).Minutes()*10) / 10
+			stop.MinutesOnSite = &minutes
+			report.TotalMinutesOnSite += minutes
+			if visit.CheckOutDistanceM != nil && *visit.CheckOutDistanceM > float64(radii[visit.DistributorID]) {
+				stop.OutsideGeofenceAtCheckOut = true
+			}
+		} else {
+			report.OpenVisits++
+		}
+		if previous != nil {
+			km := math.Round(haversineMeters(previous.CheckInLatitude, previous.CheckInLongitude, visit.CheckInLatitude, visit.CheckInLongitude)/10) / 100
+			stop.KmFromPreviousStop = &km
+			report.TotalKm += km
+		}
+		previous = visit
+		report.Stops = append(report.Stops, stop)
+	}
+	report.TotalVisits = len(report.Stops)
+	report.TotalKm = math.Round(report.TotalKm*100) / 100
+	return report, nil
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidSandbox wraps sandbox settings validation failures
+	ErrInvalidSandbox = errors.New("invalid sandbox settings")
+	// ErrSandboxTenantNotFound is returned when making an unknown tenant a sandbox
+	ErrSandboxTenantNotFound = errors.New("tenant not found")
+	// ErrSandboxNotFound is returned for tenants that are not sandboxes
+	ErrSandboxNotFound = errors.New("tenant is not a sandbox")
+)
+
+const (
+	defaultSandboxResetHours = 24
+	maxSandboxResetHours     = 720
+	defaultSandboxSeed       = 42
+)
+
+// sandboxCacheTTL bounds how long an instance keeps treating a tenant that
+// stopped being a sandbox as one without seeing the invalidation
+const sandboxCacheTTL = time.Minute
+
+// sandboxesCacheKey holds the IDs of every sandbox tenant; they are checked on
+// every request and every notification
+const sandboxesCacheKey = "sandboxes:all"
+
+// SandboxService marks tenants as sandboxes for demos and trials. Their data
+// is reset to a demo seed on a schedule, their notifications never leave the
+// service and their rate limits are relaxed
+type SandboxService interface {
+	IsSandbox(ctx context.Context, tenantID uuid.UUID) (bool, error)
+	GetSandbox(ctx context.Context, tenantID uuid.UUID) (*models.TenantSandbox, error)
+	ListSandboxes(ctx context.Context) ([]*models.TenantSandbox, error)
+	// EnableSandbox makes the tenant a sandbox or changes its schedule
+	EnableSandbox(ctx context.Context, tenantID, enabledBy uuid.UUID, req *models.TenantSandboxRequest) (*models.TenantSandbox, error)
+	// DisableSandbox turns the tenant back into a regular one, keeping whatever
+	// data it has at the time
+	DisableSandbox(ctx context.Context, tenantID uuid.UUID) error
+}
+
+type sandboxService struct {
+	repo     repositories.SandboxRepository
+	cacheSvc caching.CacheService
+}
+
+// NewSandboxService creates a new sandbox service
+func NewSandboxService(repo repositories.SandboxRepository, cacheSvc caching.CacheService) SandboxService {
+	return &sandboxService{
+		repo:     repo,
+		cacheSvc: cacheSvc,
+	}
+}
+
+// sandboxIDs returns the IDs of every sandbox tenant, from the cache when possible
+func (s *sandboxService) sandboxIDs(ctx context.Context) ([]uuid.UUID, error) {
+	if cached, err := s.cacheSvc.GetString(ctx, sandboxesCacheKey); err == nil && cached != "" {
+		var ids []uuid.UUID
+		if err := json.Unmarshal([]byte(cached), &ids); err == nil {
+			return ids, nil
+		}
+	}
+
+	sandboxes, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sandboxes: %w", err)
+	}
+	ids := make([]uuid.UUID, 0, len(sandboxes))
+	for _, sandbox := range sandboxes {
+		ids = append(ids, sandbox.TenantID)
+	}
+	if payload, err := json.Marshal(ids); err == nil {
+		if cacheErr := s.cacheSvc.SetString(ctx, sandboxesCacheKey, string(payload), sandboxCacheTTL); cacheErr != nil {
+			fmt.Printf("Failed to cache sandboxes: %v\n", cacheErr)
+		}
+	}
+	return ids, nil
+}
+
+func (s *sandboxService) IsSandbox(ctx context.Context, tenantID uuid.UUID) (bool, error) {
+	ids, err := s.sandboxIDs(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range ids {
+		if id == tenantID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *sandboxService) GetSandbox(ctx context.Context, tenantID uuid.UUID) (*models.TenantSandbox, error) {
+	sandbox, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if sandbox == nil {
+		return nil, ErrSandboxNotFound
+	}
+	return sandbox, nil
+}
+
+func (s *sandboxService) ListSandboxes(ctx context.Context) ([]*models.TenantSandbox, error) {
+	return s.repo.List(ctx)
+}
+
+// EnableSandbox requires confirm_reset for a tenant that is not yet a sandbox,
+// since its first reset is due straight away and deletes its data
+func (s *sandboxService) EnableSandbox(ctx context.Context, tenantID, enabledBy uuid.UUID, req *models.TenantSandboxRequest) (*models.TenantSandbox, error) {
+	hours := req.ResetIntervalHours
+	if hours == 0 {
+		hours = defaultSandboxResetHours
+	}
+	if hours < 1 || hours > maxSandboxResetHours {
+		return nil, fmt.Errorf("%w: reset_interval_hours must be between 1 and %d", ErrInvalidSandbox, maxSandboxResetHours)
+	}
+
+	existing, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sandbox: %w", err)
+	}
+	if existing == nil && !req.ConfirmReset {
+		return nil, fmt.Errorf("%w: confirm_reset is required, as all of the tenant's business data is replaced by demo data", ErrInvalidSandbox)
+	}
+
+	sandbox := &models.TenantSandbox{
+		TenantID:           tenantID,
+		ResetIntervalHours: hours,
+		Seed:               defaultSandboxSeed,
+		EnabledBy:          &enabledBy,
+	}
+	if existing != nil {
+		sandbox.Seed = existing.Seed
+	}
+	if req.Seed != nil {
+		sandbox.Seed = *req.Seed
+	}
+
+	saved, err := s.repo.Upsert(ctx, sandbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save sandbox: %w", err)
+	}
+	if !saved {
+		return nil, ErrSandboxTenantNotFound
+	}
+
+	s.invalidate(ctx)
+	return sandbox, nil
+}
+
+func (s *sandboxService) DisableSandbox(ctx context.Context, tenantID uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to remove sandbox: %w", err)
+	}
+	if !deleted {
+		return ErrSandboxNotFound
+	}
+
+	s.invalidate(ctx)
+	return nil
+}
+
+func (s *sandboxService) invalidate(ctx context.Context) {
+	if err := s.cacheSvc.Delete(ctx, sandboxesCacheKey); err != nil {
+		fmt.Printf("Failed to invalidate cached sandboxes: %v\n", err)
+	}
+}
+
+// sandboxNotificationService stubs out deliveries to the outside world for
+// sandbox tenants: emails, SMS, webhooks, push and WhatsApp messages are
+// logged instead of sent, so demo data never reaches real customers
+type sandboxNotificationService struct {
+	NotificationService
+	sandboxSvc SandboxService
+}
+
+// NewSandboxNotificationService wraps the notification service so sandbox
+// tenants' deliveries are only logged
+func NewSandboxNotificationService(inner NotificationService, sandboxSvc SandboxService) NotificationService {
+	return &sandboxNotificationService{
+		NotificationService: inner,
+		sandboxSvc:          sandboxSvc,
+	}
+}
+
+// stubbed reports whether the tenant's delivery should be skipped. When the
+// check fails the delivery is skipped too, as sending demo data to a real
+// recipient is worse than a lost notification
+func (s *sandboxNotificationService) stubbed(ctx context.Context, tenantID uuid.UUID, kind, recipient string) bool {
+	sandbox, err := s.sandboxSvc.IsSandbox(ctx, tenantID)
+	if err != nil {
+		log.Printf("Failed to check sandbox for tenant %s, skipping %s to %s: %v", tenantID, kind, recipient, err)
+		return true
+	}
+	if sandbox {
+		log.Printf("Sandbox tenant %s: %s to %s not sent", tenantID, kind, recipient)
+	}
+	return sandbox
+}
+
+func (s *sandboxNotificationService) SendNotification(ctx context.Context, tenantID uuid.UUID, notification *models.Notification) error {
+	if s.stubbed(ctx, tenantID, string(notification.Type)+" notification", notification.Recipient) {
+		return nil
+	}
+	return s.NotificationService.SendNotification(ctx, tenantID, notification)
+}
+
+func (s *sandboxNotificationService) SendEmail(ctx context.Context, tenantID uuid.UUID, recipient, subject, body string) error {
+	if s.stubbed(ctx, tenantID, "email", recipient) {
+		return nil
+	}
+	return s.NotificationService.SendEmail(ctx, tenantID, recipient, subject, body)
+}
+
+func (s *sandboxNotificationService) SendSMS(ctx context.Context, tenantID uuid.UUID, recipient, message string) error {
+	if s.stubbed(ctx, tenantID, "SMS", recipient) {
+		return nil
+	}
+	return s.NotificationService.SendSMS(ctx, tenantID, recipient, message)
+}
+
+func (s *sandboxNotificationService) SendWebhook(ctx context.Context, tenantID uuid.UUID, webhook *models.WebhookSubscription, payload map[string]interface{}) error {
+	if s.stubbed(ctx, tenantID, "webhook", webhook.URL) {
+		return nil
+	}
+	return s.NotificationService.SendWebhook(ctx, tenantID, webhook, payload)
+}
+
+func (s *sandboxNotificationService) SendPush(ctx context.Context, tenantID uuid.UUID, topic string, msg *models.PushMessage) error {
+	if s.stubbed(ctx, tenantID, "push", topic) {
+		return nil
+	}
+	return s.NotificationService.SendPush(ctx, tenantID, topic, msg)
+}
+
+func (s *sandboxNotificationService) SendWhatsApp(ctx context.Context, tenantID uuid.UUID, msg *models.WhatsAppSend) error {
+	if s.stubbed(ctx, tenantID, "WhatsApp message", msg.Phone) {
+		return nil
+	}
+	return s.NotificationService.SendWhatsApp(ctx, tenantID, msg)
+}
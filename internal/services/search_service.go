@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// searchSuggestCacheTTL keeps keystroke-by-keystroke lookups off the database
+// while new records still show up within a minute
+const searchSuggestCacheTTL = time.Minute
+
+// Search queries shorter than this match too much to be useful, and
+// longer ones are cut down
+const (
+	minSearchQueryLength = 2
+	maxSearchQueryLength = 64
+)
+
+// orderNumberPattern matches the leading characters of an order ID, as order
+// numbers are shown (ORD-1A2B3C4D)
+var orderNumberPattern = regexp.MustCompile(`^(?:ord-?|#)?([0-9a-f][0-9a-f-]{3,35})$`)
+
+// highlightContext is how many characters of a field are kept on each side
+// of a match in a highlight snippet
+const highlightContext = 40
+
+// searchFields lists each search type's highlighted fields in display order
+var searchFields = map[string][]string{
+	models.SearchTypeProduct:  {"name", "barcode", "description"},
+	models.SearchTypeOrder:    {"order_number", "notes"},
+	models.SearchTypeInvoice:  {"invoice_number"},
+	models.SearchTypeCustomer: {"name", "contact_email", "contact_phone", "gstin"},
+	models.SearchTypeSupplier: {"name", "contact_email", "contact_phone", "gstin"},
+}
+
+type SearchService interface {
+	// Suggest returns up to limit suggestions of the given types, best first
+	Suggest(ctx context.Context, tenantID uuid.UUID, query string, types []string, limit int) (*models.SearchSuggestions, error)
+	// Search returns a page of results of each of the given types, best first
+	Search(ctx context.Context, tenantID uuid.UUID, query string, types []string, limit, offset int) (*models.SearchResults, error)
+}
+
+type searchService struct {
+	searchRepo   repositories.SearchRepository
+	cacheService caching.CacheService
+}
+
+func NewSearchService(searchRepo repositories.SearchRepository, cacheService caching.CacheService) SearchService {
+	return &searchService{
+		searchRepo:   searchRepo,
+		cacheService: cacheService,
+	}
+}
+
+func searchSuggestCacheKey(tenantID uuid.UUID, term string, limit int) string {
+	return fmt.Sprintf("search:suggest:%s:%d:%s", tenantID.String(), limit, term)
+}
+
+// normalizeSearchQuery lowercases the query, collapses whitespace and drops
+// LIKE wildcards
+func normalizeSearchQuery(query string) string {
+	query = strings.Map(func(r rune) rune {
+		switch r {
+		case '%', '_', '\\':
+			return -1
+		}
+		return r
+	}, strings.ToLower(query))
+	query = strings.Join(strings.Fields(query), " ")
+	if runes := []rune(query); len(runes) > maxSearchQueryLength {
+		query = strings.TrimSpace(string(runes[:maxSearchQueryLength]))
+	}
+	return query
+}
+
+// orderNumberPrefix returns the order ID prefix a term names, if it looks
+// like an order number
+func orderNumberPrefix(term string) string {
+	if match := orderNumberPattern.FindStringSubmatch(term); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func (s *searchService) Suggest(ctx context.Context, tenantID uuid.UUID, query string, types []string, limit int) (*models.SearchSuggestions, error) {
+	term := normalizeSearchQuery(query)
+	result := &models.SearchSuggestions{Query: term, Suggestions: []*models.SearchSuggestion{}}
+	if len([]rune(term)) < minSearchQueryLength || len(types) == 0 {
+		return result, nil
+	}
+
+	// Every type is cached together so users allowed different types share
+	// one entry; the wanted types are picked out afterwards
+	var suggestions []*models.SearchSuggestion
+	key := searchSuggestCacheKey(tenantID, term, limit)
+	if cached, err := s.cacheService.GetString(ctx, key); err == nil && cached != "" {
+		if err := json.Unmarshal([]byte(cached), &suggestions); err != nil {
+			suggestions = nil
+		}
+	}
+	if suggestions == nil {
+		found, err := s.searchRepo.Suggest(ctx, tenantID, term, orderNumberPrefix(term), limit)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = found
+		if suggestions == nil {
+			suggestions = []*models.SearchSuggestion{}
+		}
+		if payload, err := json.Marshal(suggestions); err == nil {
+			if cacheErr := s.cacheService.SetString(ctx, key, string(payload), searchSuggestCacheTTL); cacheErr != nil {
+				fmt.Printf("Failed to cache search suggestions: %v\n", cacheErr)
+			}
+		}
+	}
+
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+	for _, suggestion := range suggestions {
+		if len(result.Suggestions) == limit {
+			break
+		}
+		if wanted[suggestion.Type] {
+			result.Suggestions = append(result.Suggestions, suggestion)
+		}
+	}
+	return result, nil
+}
+
+func (s *searchService) Search(ctx context.Context, tenantID uuid.UUID, query string, types []string, limit, offset int) (*models.SearchResults, error) {
+	term := normalizeSearchQuery(query)
+	results := &models.SearchResults{Query: term, Groups: []*models.SearchResultGroup{}}
+	if len([]rune(term)) < minSearchQueryLength {
+		return results, nil
+	}
+
+	orderPrefix := orderNumberPrefix(term)
+	for _, searchType := range types {
+		found, err := s.searchRepo.Search(ctx, tenantID, searchType, term, orderPrefix, limit, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %ss: %w", searchType, err)
+		}
+		group := &models.SearchResultGroup{Type: searchType, Results: []*models.SearchResult{}}
+		for _, result := range found {
+			result.Highlights = []*models.SearchHighlight{}
+			for _, field := range searchFields[searchType] {
+				if snippet, ok := highlight(result.Fields[field], term); ok {
+					result.Highlights = append(result.Highlights, &models.SearchHighlight{Field: field, Snippet: snippet})
+				}
+			}
+			group.Results = append(group.Results, result)
+		}
+		results.Groups = append(results.Groups, group)
+	}
+	return results, nil
+}
+
+// highlight cuts the text around the first case-insensitive match of term
+// out of value, escaping it for HTML and wrapping the match in <mark>
+func highlight(value, term string) (string, bool) {
+	text := []rune(value)
+	needle := []rune(term)
+	folded := make([]rune, len(text))
+	for i, r := range text {
+		folded[i] = unicode.ToLower(r)
+	}
+
+	start := -1
+	for i := 0; i+len(needle) <= len(folded); i++ {
+		if string(folded[i:i+len(needle)]) == term {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+	end := start + len(needle)
+
+	from := max(start-highlightContext, 0)
+	to := min(end+highlightContext, len(text))
+	var snippet strings.Builder
+	if from > 0 {
+		snippet.WriteString("…")
+	}
+	snippet.WriteString(html.EscapeString(string(text[from:start])))
+	snippet.WriteString("<mark>")
+	snippet.WriteString(html.EscapeString(string(text[start:end])))
+	snippet.WriteString("</mark>")
+	snippet.WriteString(html.EscapeString(string(text[end:to])))
+	if to < len(text) {
+		snippet.WriteString("…")
+	}
+	return snippet.String(), true
+}
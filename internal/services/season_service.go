@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+type SeasonService interface {
+	Create(ctx context.Context, season *models.Season) error
+	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Season, error)
+	Update(ctx context.Context, season *models.Season) error
+	Delete(ctx context.Context, tenantID, id uuid.UUID) error
+	List(ctx context.Context, tenantID uuid.UUID) ([]*models.Season, error)
+	CreateDefaults(ctx context.Context, tenantID uuid.UUID) ([]*models.Season, error)
+	GetProductTags(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductSeasonTags, error)
+	SetProductTags(ctx context.Context, tenantID, productID uuid.UUID, seasonIDs []uuid.UUID, crops []string) (*models.ProductSeasonTags, error)
+}
+
+type seasonService struct {
+	seasonRepo  repositories.SeasonRepository
+	productRepo repositories.ProductRepository
+}
+
+func NewSeasonService(seasonRepo repositories.SeasonRepository, productRepo repositories.ProductRepository) SeasonService {
+	return &seasonService{
+		seasonRepo:  seasonRepo,
+		productRepo: productRepo,
+	}
+}
+
+// defaultSeasons are the standard Indian cropping seasons
+var defaultSeasons = []models.Season{
+	{Name: "Kharif", SeasonType: models.SeasonTypeKharif, StartMonth: 6, StartDay: 1, EndMonth: 10, EndDay: 31, LeadDays: 45},
+	{Name: "Rabi", SeasonType: models.SeasonTypeRabi, StartMonth: 10, StartDay: 15, EndMonth: 3, EndDay: 31, LeadDays: 45},
+	{Name: "Zaid", SeasonType: models.SeasonTypeZaid, StartMonth: 3, StartDay: 15, EndMonth: 6, EndDay: 15, LeadDays: 30},
+}
+
+func validateSeason(season *models.Season) error {
+	season.Name = strings.TrimSpace(season.Name)
+	if season.Name == "" {
+		return fmt.Errorf("season name is required")
+	}
+	switch season.SeasonType {
+	case models.SeasonTypeKharif, models.SeasonTypeRabi, models.SeasonTypeZaid, models.SeasonTypeFestival, models.SeasonTypeOther:
+	default:
+		return fmt.Errorf("invalid season type: %s", season.SeasonType)
+	}
+	if !validMonthDay(season.StartMonth, season.StartDay) {
+		return fmt.Errorf("invalid season start date")
+	}
+	if !validMonthDay(season.EndMonth, season.EndDay) {
+		return fmt.Errorf("invalid season end date")
+	}
+	if season.LeadDays < 0 || season.LeadDays > 365 {
+		return fmt.Errorf("lead_days must be between 0 and 365")
+	}
+	return nil
+}
+
+// validMonthDay checks a recurring month/day; 29 February is rejected because it does not recur every year
+func validMonthDay(month, day int) bool {
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	return time.Date(2023, time.Month(month), day, 0, 0, 0, 0, time.UTC).Day() == day
+}
+
+func (s *seasonService) Create(ctx context.Context, season *models.Season) error {
+	if err := validateSeason(season); err != nil {
+		return err
+	}
+	if season.ID == uuid.Nil {
+		season.ID = uuid.New()
+	}
+	season.CreatedAt = time.Now()
+	season.UpdatedAt = season.CreatedAt
+	return s.seasonRepo.Create(ctx, season)
+}
+
+func (s *seasonService) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Season, error) {
+	return s.seasonRepo.GetByID(ctx, tenantID, id)
+}
+
+func (s *seasonService) Update(ctx context.Context, season *models.Season) error {
+	if err := validateSeason(season); err != nil {
+		return err
+	}
+	season.UpdatedAt = time.Now()
+	return s.seasonRepo.Update(ctx, season)
+}
+
+func (s *seasonService) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	return s.seasonRepo.Delete(ctx, tenantID, id)
+}
+
+func (s *seasonService) List(ctx context.Context, tenantID uuid.UUID) ([]*models.Season, error) {
+	return s.seasonRepo.List(ctx, tenantID)
+}
+
+// CreateDefaults adds the kharif, rabi and zaid seasons the tenant does not have yet
+func (s *seasonService) CreateDefaults(ctx context.Context, tenantID uuid.UUID) ([]*models.Season, error) {
+	existing, err := s.seasonRepo.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(existing))
+	for _, season := range existing {
+		names[strings.ToLower(season.Name)] = true
+	}
+
+	var created []*models.Season
+	for _, def := range defaultSeasons {
+		if names[strings.ToLower(def.Name)] {
+			continue
+		}
+		season := def
+		season.TenantID = tenantID
+		if err := s.Create(ctx, &season); err != nil {
+			return nil, err
+		}
+		created = append(created, &season)
+	}
+	return created, nil
+}
+
+func (s *seasonService) GetProductTags(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductSeasonTags, error) {
+	if _, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	seasons, err := s.seasonRepo.ListProductSeasons(ctx, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	crops, err := s.seasonRepo.ListProductCrops(ctx, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := &models.ProductSeasonTags{
+		ProductID: productID,
+		Seasons:   seasons,
+		Crops:     crops,
+	}
+	if tags.Seasons == nil {
+		tags.Seasons = []*models.Season{}
+	}
+	if tags.Crops == nil {
+		tags.Crops = []string{}
+	}
+	return tags, nil
+}
+
+// SetProductTags replaces a product's seasons and crops; crops are stored lower-cased
+func (s *seasonService) SetProductTags(ctx context.Context, tenantID, productID uuid.UUID, seasonIDs []uuid.UUID, crops []string) (*models.ProductSeasonTags, error) {
+	if _, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	for _, seasonID := range seasonIDs {
+		if _, err := s.seasonRepo.GetByID(ctx, tenantID, seasonID); err != nil {
+			return nil, fmt.Errorf("season %s not found", seasonID)
+		}
+	}
+
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, crop := range crops {
+		crop = strings.ToLower(strings.TrimSpace(crop))
+		if crop == "" || seen[crop] {
+			continue
+		}
+		if len(crop) > 100 {
+			return nil, fmt.Errorf("crop name too long: %s", crop)
+		}
+		seen[crop] = true
+		cleaned = append(cleaned, crop)
+	}
+
+	if err := s.seasonRepo.SetProductTags(ctx, tenantID, productID, seasonIDs, cleaned); err != nil {
+		return nil, err
+	}
+	return s.GetProductTags(ctx, tenantID, productID)
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+type statusCommentKey struct{}
+
+// WithStatusComment attaches a comment to the status transitions made with
+// the context; it is stored in the status history with each of them
+func WithStatusComment(ctx context.Context, comment string) context.Context {
+	return context.WithValue(ctx, statusCommentKey{}, comment)
+}
+
+// recordStatusChange writes a transition to the status history, made by the
+// request's user if there is one. fromStatus is empty when the entity was
+// just created. A history failure is logged and does not undo the transition
+func recordStatusChange(ctx context.Context, repo repositories.StatusHistoryRepository, tenantID uuid.UUID, entityType string, entityID uuid.UUID, fromStatus, toStatus string) {
+	if repo == nil || fromStatus == toStatus {
+		return
+	}
+	change := &models.StatusChange{
+		TenantID:   tenantID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		ToStatus:   toStatus,
+	}
+	if fromStatus != "" {
+		change.FromStatus = &fromStatus
+	}
+	if userID, ok := common.RequestContextFrom(ctx).User(); ok {
+		change.ActorID = &userID
+	}
+	if comment, ok := ctx.Value(statusCommentKey{}).(string); ok && strings.TrimSpace(comment) != "" {
+		comment = strings.TrimSpace(comment)
+		change.Comment = &comment
+	}
+	if err := repo.Record(ctx, change); err != nil {
+		fmt.Printf("Failed to record %s %s status change to %s: %v\n", entityType, entityID, toStatus, err)
+	}
+}
@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrStatusIncidentNotFound is returned for an unknown incident
+	ErrStatusIncidentNotFound = errors.New("incident not found")
+	// ErrInvalidStatusIncident wraps incident validation failures
+	ErrInvalidStatusIncident = errors.New("invalid incident")
+	// ErrStatusIncidentResolved is returned when updating a resolved
+	// incident; a recurrence is recorded as a new incident
+	ErrStatusIncidentResolved = errors.New("incident is already resolved")
+)
+
+// statusIncidentsCacheTTL bounds how stale the public status page can be on
+// an instance that missed the invalidation
+const statusIncidentsCacheTTL = 30 * time.Second
+
+// openIncidentsCacheKey holds the open incidents with their updates; the
+// status endpoint is unauthenticated and polled, so it is served from here
+const openIncidentsCacheKey = "status_page:open_incidents"
+
+// statusHistoryDays is how far back the public incident history reaches
+const statusHistoryDays = 90
+
+// statusIncidentTitleMaxLength matches the title column
+const statusIncidentTitleMaxLength = 200
+
+// componentStatusRank orders component statuses by how much they disrupt
+func componentStatusRank(status string) int {
+	switch status {
+	case models.ComponentStatusMaintenance:
+		return 4
+	case models.ComponentStatusMajorOutage:
+		return 3
+	case models.ComponentStatusPartialOutage:
+		return 2
+	case models.ComponentStatusDegraded:
+		return 1
+	}
+	return 0
+}
+
+// severityComponentStatus is the component status an open incident of the
+// severity puts its component in
+func severityComponentStatus(severity string) string {
+	switch severity {
+	case models.IncidentSeverityCritical:
+		return models.ComponentStatusMajorOutage
+	case models.IncidentSeverityMajor:
+		return models.ComponentStatusPartialOutage
+	}
+	return models.ComponentStatusDegraded
+}
+
+func isStatusComponent(key string) bool {
+	for _, component := range models.StatusComponents {
+		if component.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isIncidentSeverity(severity string) bool {
+	switch severity {
+	case models.IncidentSeverityMinor, models.IncidentSeverityMajor, models.IncidentSeverityCritical:
+		return true
+	}
+	return false
+}
+
+func isIncidentStatus(status string) bool {
+	switch status {
+	case models.IncidentStatusInvestigating, models.IncidentStatusIdentified, models.IncidentStatusMonitoring, models.IncidentStatusResolved:
+		return true
+	}
+	return false
+}
+
+// StatusIncidentService records platform incidents and serves the public
+// status page, so tenant admins can tell whether an outage is on the
+// platform side
+type StatusIncidentService interface {
+	Create(ctx context.Context, createdBy uuid.UUID, req *models.StatusIncidentRequest) (*models.StatusIncident, error)
+	// PostUpdate adds an update to the incident's timeline; the resolved
+	// status closes it
+	PostUpdate(ctx context.Context, id, createdBy uuid.UUID, req *models.StatusIncidentUpdateRequest) (*models.StatusIncident, error)
+	// Get returns the incident with its updates
+	Get(ctx context.Context, id uuid.UUID) (*models.StatusIncident, error)
+	List(ctx context.Context, openOnly bool, limit, offset int) ([]*models.StatusIncident, error)
+	// Status returns the public status page; the global operational mode
+	// shows every component under maintenance or read-only
+	Status(ctx context.Context) (*models.PlatformStatus, error)
+	// History lists incidents open or resolved in the last 90 days with
+	// their updates, most recently started first, for the public page
+	History(ctx context.Context, limit, offset int) ([]*models.StatusIncident, error)
+}
+
+type statusIncidentService struct {
+	repo               repositories.StatusIncidentRepository
+	operationalModeSvc OperationalModeService
+	cacheSvc           caching.CacheService
+}
+
+// NewStatusIncidentService creates a new status incident service
+func NewStatusIncidentService(repo repositories.StatusIncidentRepository, operationalModeSvc OperationalModeService, cacheSvc caching.CacheService) StatusIncidentService {
+	return &statusIncidentService{
+		repo:               repo,
+		operationalModeSvc: operationalModeSvc,
+		cacheSvc:           cacheSvc,
+	}
+}
+
+func (s *statusIncidentService) Create(ctx context.Context, createdBy uuid.UUID, req *models.StatusIncidentRequest) (*models.StatusIncident, error) {
+	title := strings.TrimSpace(req.Title)
+	message := strings.TrimSpace(req.Message)
+	status := req.Status
+	if status == "" {
+		status = models.IncidentStatusInvestigating
+	}
+	switch {
+	case title == "":
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidStatusIncident)
+	case len(title) > statusIncidentTitleMaxLength:
+		return nil, fmt.Errorf("%w: title must be at most %d characters", ErrInvalidStatusIncident, statusIncidentTitleMaxLength)
+	case !isStatusComponent(req.Component):
+		return nil, fmt.Errorf("%w: unknown component %q", ErrInvalidStatusIncident, req.Component)
+	case !isIncidentSeverity(req.Severity):
+		return nil, fmt.Errorf("%w: severity must be minor, major or critical", ErrInvalidStatusIncident)
+	case !isIncidentStatus(status):
+		return nil, fmt.Errorf("%w: status must be investigating, identified, monitoring or resolved", ErrInvalidStatusIncident)
+	case message == "":
+		return nil, fmt.Errorf("%w: message is required", ErrInvalidStatusIncident)
+	}
+
+	now := time.Now()
+	startedAt := now
+	if req.StartedAt != nil {
+		if req.StartedAt.After(now) {
+			return nil, fmt.Errorf("%w: started_at cannot be in the future", ErrInvalidStatusIncident)
+		}
+		startedAt = *req.StartedAt
+	}
+
+	incident := &models.StatusIncident{
+		ID:        uuid.New(),
+		Title:     title,
+		Component: req.Component,
+		Severity:  req.Severity,
+		Status:    status,
+		StartedAt: startedAt,
+		CreatedBy: &createdBy,
+	}
+	// Recorded after the fact: the incident is already over
+	if status == models.IncidentStatusResolved {
+		incident.ResolvedAt = &now
+	}
+	update := &models.StatusIncidentUpdate{
+		ID:         uuid.New(),
+		IncidentID: incident.ID,
+		Status:     status,
+		Message:    message,
+		CreatedBy:  &createdBy,
+	}
+	if err := s.repo.Create(ctx, incident, update); err != nil {
+		return nil, fmt.Errorf("failed to create incident: %w", err)
+	}
+	incident.Updates = []*models.StatusIncidentUpdate{update}
+
+	s.invalidate(ctx)
+	return incident, nil
+}
+
+func (s *statusIncidentService) PostUpdate(ctx context.Context, id, createdBy uuid.UUID, req *models.StatusIncidentUpdateRequest) (*models.StatusIncident, error) {
+	message := strings.TrimSpace(req.Message)
+	switch {
+	case !isIncidentStatus(req.Status):
+		return nil, fmt.Errorf("%w: status must be investigating, identified, monitoring or resolved", ErrInvalidStatusIncident)
+	case req.Severity != nil && !isIncidentSeverity(*req.Severity):
+		return nil, fmt.Errorf("%w: severity must be minor, major or critical", ErrInvalidStatusIncident)
+	case message == "":
+		return nil, fmt.Errorf("%w: message is required", ErrInvalidStatusIncident)
+	}
+
+	update := &models.StatusIncidentUpdate{
+		ID:         uuid.New(),
+		IncidentID: id,
+		Status:     req.Status,
+		Message:    message,
+		CreatedBy:  &createdBy,
+	}
+	incident, applied, err := s.repo.AddUpdate(ctx, update, req.Severity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update incident: %w", err)
+	}
+	if incident == nil {
+		return nil, ErrStatusIncidentNotFound
+	}
+	if !applied {
+		return nil, ErrStatusIncidentResolved
+	}
+
+	s.invalidate(ctx)
+	if err := s.attachUpdates(ctx, []*models.StatusIncident{incident}); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+func (s *statusIncidentService) Get(ctx context.Context, id uuid.UUID) (*models.StatusIncident, error) {
+	incident, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	if incident == nil {
+		return nil, ErrStatusIncidentNotFound
+	}
+	if err := s.attachUpdates(ctx, []*models.StatusIncident{incident}); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+func (s *statusIncidentService) List(ctx context.Context, openOnly bool, limit, offset int) ([]*models.StatusIncident, error) {
+	incidents, err := s.repo.List(ctx, openOnly, nil, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+func (s *statusIncidentService) Status(ctx context.Context) (*models.PlatformStatus, error) {
+	incidents, err := s.openIncidents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	worst := make(map[string]string, len(models.StatusComponents))
+	for _, incident := range incidents {
+		status := severityComponentStatus(incident.Severity)
+		if componentStatusRank(status) > componentStatusRank(worst[incident.Component]) {
+			worst[incident.Component] = status
+		}
+	}
+
+	// A global maintenance window takes every component down; read-only mode
+	// is reported in the message without changing component statuses
+	var mode *models.OperationalMode
+	if s.operationalModeSvc != nil {
+		if mode, err = s.operationalModeSvc.Effective(ctx, nil); err != nil {
+			fmt.Printf("Failed to load operational mode for status page: %v\n", err)
+			mode = nil
+		}
+	}
+
+	page := &models.PlatformStatus{
+		Status:      models.ComponentStatusOperational,
+		Components:  make([]models.ComponentStatus, 0, len(models.StatusComponents)),
+		Incidents:   publicIncidents(incidents),
+		GeneratedAt: time.Now().UTC(),
+	}
+	for _, component := range models.StatusComponents {
+		status := models.ComponentStatusOperational
+		if worst[component.Key] != "" {
+			status = worst[component.Key]
+		}
+		if mode != nil && mode.Mode == models.OperationalModeMaintenance {
+			status = models.ComponentStatusMaintenance
+		}
+		page.Components = append(page.Components, models.ComponentStatus{Key: component.Key, Name: component.Name, Status: status})
+		if componentStatusRank(status) > componentStatusRank(page.Status) {
+			page.Status = status
+		}
+	}
+	if mode != nil {
+		page.Message = mode.Message
+		if page.Message == nil && mode.Mode == models.OperationalModeReadOnly {
+			message := "The service is temporarily read-only, changes cannot be saved right now"
+			page.Message = &message
+		}
+	}
+	return page, nil
+}
+
+func (s *statusIncidentService) History(ctx context.Context, limit, offset int) ([]*models.StatusIncident, error) {
+	since := time.Now().AddDate(0, 0, -statusHistoryDays)
+	incidents, err := s.repo.List(ctx, false, &since, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incident history: %w", err)
+	}
+	if err := s.attachUpdates(ctx, incidents); err != nil {
+		return nil, err
+	}
+	return publicIncidents(incidents), nil
+}
+
+// openIncidents returns the open incidents with their updates, from the
+// cache when possible
+func (s *statusIncidentService) openIncidents(ctx context.Context) ([]*models.StatusIncident, error) {
+	if cached, err := s.cacheSvc.GetString(ctx, openIncidentsCacheKey); err == nil && cached != "" {
+		var incidents []*models.StatusIncident
+		if err := json.Unmarshal([]byte(cached), &incidents); err == nil {
+			return incidents, nil
+		}
+	}
+
+	// Open incidents are few; the limit only guards against a runaway backlog
+	incidents, err := s.repo.List(ctx, true, nil, 100, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open incidents: %w", err)
+	}
+	if incidents == nil {
+		incidents = []*models.StatusIncident{}
+	}
+	if err := s.attachUpdates(ctx, incidents); err != nil {
+		return nil, err
+	}
+	if payload, err := json.Marshal(incidents); err == nil {
+		if cacheErr := s.cacheSvc.SetString(ctx, openIncidentsCacheKey, string(payload), statusIncidentsCacheTTL); cacheErr != nil {
+			fmt.Printf("Failed to cache open incidents: %v\n", cacheErr)
+		}
+	}
+	return incidents, nil
+}
+
+func (s *statusIncidentService) attachUpdates(ctx context.Context, incidents []*models.StatusIncident) error {
+	ids := make([]uuid.UUID, 0, len(incidents))
+	for _, incident := range incidents {
+		ids = append(ids, incident.ID)
+	}
+	updates, err := s.repo.ListUpdates(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load incident updates: %w", err)
+	}
+	for _, incident := range incidents {
+		incident.Updates = updates[incident.ID]
+	}
+	return nil
+}
+
+func (s *statusIncidentService) invalidate(ctx context.Context) {
+	if err := s.cacheSvc.Delete(ctx, openIncidentsCacheKey); err != nil {
+		fmt.Printf("Failed to invalidate cached open incidents: %v\n", err)
+	}
+}
+
+// publicIncidents copies the incidents without the platform admins who
+// recorded them, for the unauthenticated status page
+func publicIncidents(incidents []*models.StatusIncident) []*models.StatusIncident {
+	public := make([]*models.StatusIncident, 0, len(incidents))
+	for _, incident := range incidents {
+		copied := *incident
+		copied.CreatedBy = nil
+		copied.Updates = make([]*models.StatusIncidentUpdate, 0, len(incident.Updates))
+		for _, update := range incident.Updates {
+			u := *update
+			u.CreatedBy = nil
+			copied.Updates = append(copied.Updates, &u)
+		}
+		public = append(public, &copied)
+	}
+	return public
+}
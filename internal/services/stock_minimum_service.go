@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidStockMinimum wraps stock minimum validation failures
+var ErrInvalidStockMinimum = errors.New("invalid stock minimum")
+
+// StockMinimumService keeps the per-product and per-warehouse stock minimums
+// that low stock alerts, inventory listings and stocking suggestions use in
+// place of the global threshold
+type StockMinimumService interface {
+	List(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.StockMinimum, error)
+	Set(ctx context.Context, tenantID, productID uuid.UUID, req *models.StockMinimumRequest) (*models.StockMinimum, error)
+	Delete(ctx context.Context, tenantID, productID, id uuid.UUID) (bool, error)
+}
+
+type stockMinimumService struct {
+	minimumRepo   repositories.StockMinimumRepository
+	productRepo   repositories.ProductRepository
+	warehouseRepo repositories.WarehouseRepository
+}
+
+// NewStockMinimumService creates a new stock minimum service instance
+func NewStockMinimumService(minimumRepo repositories.StockMinimumRepository, productRepo repositories.ProductRepository, warehouseRepo repositories.WarehouseRepository) StockMinimumService {
+	return &stockMinimumService{
+		minimumRepo:   minimumRepo,
+		productRepo:   productRepo,
+		warehouseRepo: warehouseRepo,
+	}
+}
+
+func (s *stockMinimumService) List(ctx context.Context, tenantID, productID uuid.UUID) ([]*models.StockMinimum, error) {
+	if product, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil || product == nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidStockMinimum)
+	}
+	minimums, err := s.minimumRepo.ListByProduct(ctx, tenantID, productID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("list stock minimums", err)
+	}
+	if minimums == nil {
+		minimums = []*models.StockMinimum{}
+	}
+	return minimums, nil
+}
+
+func (s *stockMinimumService) Set(ctx context.Context, tenantID, productID uuid.UUID, req *models.StockMinimumRequest) (*models.StockMinimum, error) {
+	if req.MinQuantity < 0 {
+		return nil, fmt.Errorf("%w: min_quantity cannot be negative", ErrInvalidStockMinimum)
+	}
+	if product, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil || product == nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidStockMinimum)
+	}
+	if req.WarehouseID != nil {
+		if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, *req.WarehouseID); err != nil || warehouse == nil {
+			return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidStockMinimum)
+		}
+	}
+
+	minimum := &models.StockMinimum{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		ProductID:   productID,
+		WarehouseID: req.WarehouseID,
+		MinQuantity: req.MinQuantity,
+	}
+	if err := s.minimumRepo.Upsert(ctx, minimum); err != nil {
+		return nil, common.SecureErrorMessage("save stock minimum", err)
+	}
+	return minimum, nil
+}
+
+func (s *stockMinimumService) Delete(ctx context.Context, tenantID, productID, id uuid.UUID) (bool, error) {
+	deleted, err := s.minimumRepo.Delete(ctx, tenantID, productID, id)
+	if err != nil {
+		return false, common.SecureErrorMessage("delete stock minimum", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnsuitableWarehouse is wrapped by *StoragePlacementError
+	ErrUnsuitableWarehouse = errors.New("warehouse cannot store this product")
+	// ErrInvalidStorageCondition wraps storage requirement and capability validation failures
+	ErrInvalidStorageCondition = errors.New("invalid storage condition")
+)
+
+// hazmatClassPattern matches a UN hazard class (1-9) with an optional division
+var hazmatClassPattern = regexp.MustCompile(`^[1-9](\.[1-9])?$`)
+
+// StoragePlacementError is returned when stock would be placed in a warehouse
+// lacking storage conditions the product needs
+type StoragePlacementError struct {
+	ProductID   uuid.UUID
+	WarehouseID uuid.UUID
+	Unmet       []string
+}
+
+func (e *StoragePlacementError) Error() string {
+	return fmt.Sprintf("warehouse %s cannot store product %s: lacks %s", e.WarehouseID, e.ProductID, strings.Join(e.Unmet, ", "))
+}
+
+func (e *StoragePlacementError) Unwrap() error {
+	return ErrUnsuitableWarehouse
+}
+
+// StorageConditionService keeps product storage requirements and warehouse
+// capabilities and checks stock is only placed where it can be stored
+type StorageConditionService interface {
+	GetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductStorageRequirement, error)
+	SetProductRequirement(ctx context.Context, tenantID uuid.UUID, req *models.ProductStorageRequirement) error
+	GetWarehouseCapability(ctx context.Context, tenantID, warehouseID uuid.UUID) (*models.WarehouseStorageCapability, error)
+	SetWarehouseCapability(ctx context.Context, tenantID uuid.UUID, capability *models.WarehouseStorageCapability) error
+	CheckPlacement(ctx context.Context, tenantID, productID, warehouseID uuid.UUID) error
+}
+
+type storageConditionService struct {
+	storageRepo   repositories.StorageConditionRepository
+	productRepo   repositories.ProductRepository
+	warehouseRepo repositories.WarehouseRepository
+}
+
+// NewStorageConditionService creates a new storage condition service instance
+func NewStorageConditionService(storageRepo repositories.StorageConditionRepository, productRepo repositories.ProductRepository, warehouseRepo repositories.WarehouseRepository) StorageConditionService {
+	return &storageConditionService{
+		storageRepo:   storageRepo,
+		productRepo:   productRepo,
+		warehouseRepo: warehouseRepo,
+	}
+}
+
+// GetProductRequirement returns the product's requirement; products without
+// one need nothing special
+func (s *storageConditionService) GetProductRequirement(ctx context.Context, tenantID, productID uuid.UUID) (*models.ProductStorageRequirement, error) {
+	if product, err := s.productRepo.GetByID(ctx, tenantID, productID); err != nil || product == nil {
+		return nil, fmt.Errorf("%w: product not found", ErrInvalidStorageCondition)
+	}
+	req, err := s.storageRepo.GetProductRequirement(ctx, tenantID, productID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get product storage requirement", err)
+	}
+	if req == nil {
+		req = &models.ProductStorageRequirement{ProductID: productID, TenantID: tenantID}
+	}
+	return req, nil
+}
+
+func (s *storageConditionService) SetProductRequirement(ctx context.Context, tenantID uuid.UUID, req *models.ProductStorageRequirement) error {
+	if product, err := s.productRepo.GetByID(ctx, tenantID, req.ProductID); err != nil || product == nil {
+		return fmt.Errorf("%w: product not found", ErrInvalidStorageCondition)
+	}
+	if req.HazmatClass != nil {
+		class := strings.TrimSpace(*req.HazmatClass)
+		if class == "" {
+			req.HazmatClass = nil
+		} else if !hazmatClassPattern.MatchString(class) {
+			return fmt.Errorf("%w: hazmat_class must be a UN hazard class such as 3 or 6.1", ErrInvalidStorageCondition)
+		} else {
+			req.HazmatClass = &class
+		}
+	}
+	req.TenantID = tenantID
+	if err := s.storageRepo.UpsertProductRequirement(ctx, req); err != nil {
+		return common.SecureErrorMessage("save product storage requirement", err)
+	}
+	return nil
+}
+
+// GetWarehouseCapability returns the warehouse's capability; warehouses
+// without one offer plain ambient storage
+func (s *storageConditionService) GetWarehouseCapability(ctx context.Context, tenantID, warehouseID uuid.UUID) (*models.WarehouseStorageCapability, error) {
+	if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, warehouseID); err != nil || warehouse == nil {
+		return nil, fmt.Errorf("%w: warehouse not found", ErrInvalidStorageCondition)
+	}
+	capability, err := s.storageRepo.GetWarehouseCapability(ctx, tenantID, warehouseID)
+	if err != nil {
+		return nil, common.SecureErrorMessage("get warehouse storage capability", err)
+	}
+	if capability == nil {
+		capability = &models.WarehouseStorageCapability{WarehouseID: warehouseID, TenantID: tenantID, HazmatClasses: []string{}}
+	}
+	return capability, nil
+}
+
+func (s *storageConditionService) SetWarehouseCapability(ctx context.Context, tenantID uuid.UUID, capability *models.WarehouseStorageCapability) error {
+	if warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, capability.WarehouseID); err != nil || warehouse == nil {
+		return fmt.Errorf("%w: warehouse not found", ErrInvalidStorageCondition)
+	}
+	classes := make([]string, 0, len(capability.HazmatClasses))
+	seen := map[string]bool{}
+	for _, class := range capability.HazmatClasses {
+		class = strings.TrimSpace(class)
+		if !hazmatClassPattern.MatchString(class) {
+			return fmt.Errorf("%w: hazmat_classes must be UN hazard classes such as 3 or 6.1", ErrInvalidStorageCondition)
+		}
+		if !seen[class] {
+			seen[class] = true
+			classes = append(classes, class)
+		}
+	}
+	capability.HazmatClasses = classes
+	capability.TenantID = tenantID
+	if err := s.storageRepo.UpsertWarehouseCapability(ctx, capability); err != nil {
+		return common.SecureErrorMessage("save warehouse storage capability", err)
+	}
+	return nil
+}
+
+// CheckPlacement returns a *StoragePlacementError when the warehouse lacks a
+// storage condition the product needs
+func (s *storageConditionService) CheckPlacement(ctx context.Context, tenantID, productID, warehouseID uuid.UUID) error {
+	req, err := s.storageRepo.GetProductRequirement(ctx, tenantID, productID)
+	if err != nil {
+		return common.SecureErrorMessage("get product storage requirement", err)
+	}
+	if req == nil {
+		return nil
+	}
+	capability, err := s.storageRepo.GetWarehouseCapability(ctx, tenantID, warehouseID)
+	if err != nil {
+		return common.SecureErrorMessage("get warehouse storage capability", err)
+	}
+	if unmet := req.Unmet(capability); len(unmet) > 0 {
+		return &StoragePlacementError{ProductID: productID, WarehouseID: warehouseID, Unmet: unmet}
+	}
+	return nil
+}
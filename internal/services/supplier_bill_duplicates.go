@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// billDuplicateThreshold is the score at which a bill is flagged; a
+	// matching invoice number alone reaches it, as do the same amount and date
+	billDuplicateThreshold = 0.5
+	// billDuplicateLookback bounds how far back earlier bills are compared
+	billDuplicateLookback = 18 * 30 * 24 * time.Hour
+	// billDuplicateDateWindow is how close two invoice dates count as near
+	billDuplicateDateWindow = 7 * 24 * time.Hour
+)
+
+// ErrPossibleDuplicateBill is wrapped by DuplicateBillError
+var ErrPossibleDuplicateBill = errors.New("possible duplicate supplier bill")
+
+// DuplicateBillError is returned when a supplier invoice is confirmed while
+// it is flagged as a likely duplicate and no override reason was given
+type DuplicateBillError struct {
+	Duplicates []*models.SupplierBillDuplicate
+}
+
+func (e *DuplicateBillError) Error() string {
+	return fmt.Sprintf("%v: matches %d earlier bill(s)", ErrPossibleDuplicateBill, len(e.Duplicates))
+}
+
+func (e *DuplicateBillError) Unwrap() error {
+	return ErrPossibleDuplicateBill
+}
+
+var (
+	billNumberNoise        = regexp.MustCompile(`[^A-Z0-9]`)
+	billNumberLeadingZeros = regexp.MustCompile(`(^|[A-Z])0+([0-9])`)
+)
+
+// normalizeBillNumber drops separators, case and zero padding so that
+// "inv/0192" and "INV-192" compare equal
+func normalizeBillNumber(number string) string {
+	normalized := billNumberNoise.ReplaceAllString(strings.ToUpper(number), "")
+	return billNumberLeadingZeros.ReplaceAllString(normalized, "$1$2")
+}
+
+// editDistance is the Levenshtein distance between two short strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// scoreBillDuplicate compares two bills of the same supplier and returns how
+// alike they are, from 0 to 1, with what matched
+func scoreBillDuplicate(bill, earlier models.PurchaseCaptureDraft) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if bill.InvoiceNumber != nil && earlier.InvoiceNumber != nil {
+		a, b := normalizeBillNumber(*bill.InvoiceNumber), normalizeBillNumber(*earlier.InvoiceNumber)
+		switch {
+		case a != "" && a == b:
+			score += 0.5
+			reasons = append(reasons, "same invoice number")
+		case len(a) >= 5 && len(b) >= 5 && editDistance(a, b) <= 1:
+			score += 0.3
+			reasons = append(reasons, "similar invoice number")
+		case len(a) >= 3 && len(b) >= 3 && (strings.HasSuffix(a, b) || strings.HasSuffix(b, a)):
+			score += 0.3
+			reasons = append(reasons, "similar invoice number")
+		}
+	}
+
+	amount, earlierAmount := bill.BillAmount(), earlier.BillAmount()
+	if amount > 0 && earlierAmount > 0 {
+		switch difference := math.Abs(amount - earlierAmount); {
+		case difference <= 1:
+			score += 0.35
+			reasons = append(reasons, "same amount")
+		case difference <= math.Max(amount, earlierAmount)*0.01:
+			score += 0.2
+			reasons = append(reasons, "amount within 1%")
+		}
+	}
+
+	if bill.InvoiceDate != nil && earlier.InvoiceDate != nil {
+		switch gap := bill.InvoiceDate.Sub(*earlier.InvoiceDate).Abs(); {
+		case gap < 24*time.Hour:
+			score += 0.15
+			reasons = append(reasons, "same invoice date")
+		case gap <= billDuplicateDateWindow:
+			score += 0.1
+			reasons = append(reasons, "invoice dates within a week")
+		}
+	}
+
+	return math.Min(math.Round(score*100)/100, 1), reasons
+}
+
+// detectDuplicates compares the capture with the supplier's earlier bills
+// and records the resulting flags in place of the open ones
+func (s *purchaseCaptureService) detectDuplicates(ctx context.Context, capture *models.PurchaseInvoiceCapture) ([]*models.SupplierBillDuplicate, error) {
+	bill := capture.Extracted
+	var flags []*models.SupplierBillDuplicate
+	if bill.SupplierID != nil || bill.SupplierGSTIN != nil {
+		candidates, err := s.repo.ListDuplicateCandidates(ctx, capture.TenantID, capture.ID, bill.SupplierID, bill.SupplierGSTIN,
+			time.Now().Add(-billDuplicateLookback))
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up earlier bills: %w", err)
+		}
+		for _, candidate := range candidates {
+			score, reasons := scoreBillDuplicate(bill, candidate.Extracted)
+			if score < billDuplicateThreshold {
+				continue
+			}
+			amount := bill.BillAmount()
+			flags = append(flags, &models.SupplierBillDuplicate{
+				ID:                   uuid.New(),
+				TenantID:             capture.TenantID,
+				CaptureID:            capture.ID,
+				MatchedCaptureID:     candidate.ID,
+				Score:                score,
+				Reasons:              reasons,
+				Status:               models.BillDuplicateFlagged,
+				SupplierID:           bill.SupplierID,
+				InvoiceNumber:        bill.InvoiceNumber,
+				TotalAmount:          &amount,
+				MatchedInvoiceNumber: candidate.Extracted.InvoiceNumber,
+				MatchedStatus:        candidate.Status,
+			})
+		}
+	}
+
+	if err := s.repo.ReplaceDuplicateFlags(ctx, capture.TenantID, capture.ID, flags); err != nil {
+		return nil, fmt.Errorf("failed to record duplicate flags: %w", err)
+	}
+	return flags, nil
+}
+
+func (s *purchaseCaptureService) CheckDuplicates(ctx context.Context, tenantID, id uuid.UUID) ([]*models.SupplierBillDuplicate, error) {
+	capture, err := s.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if capture.Status != models.PurchaseCapturePendingReview {
+		return s.repo.ListDuplicates(ctx, tenantID, &id, "", time.Time{}, time.Now().Add(time.Hour), 100, 0)
+	}
+	return s.detectDuplicates(ctx, capture)
+}
+
+func (s *purchaseCaptureService) DuplicateReport(ctx context.Context, tenantID uuid.UUID, status string, from, to time.Time, limit, offset int) (*models.SupplierBillDuplicateReport, error) {
+	switch status {
+	case "", models.BillDuplicateFlagged, models.BillDuplicateOverridden, models.BillDuplicateDiscarded:
+	default:
+		return nil, fmt.Errorf("%w: status must be flagged, overridden or discarded", ErrInvalidPurchaseCapture)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidPurchaseCapture)
+	}
+
+	report, err := s.repo.SummarizeDuplicates(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize duplicate bills: %w", err)
+	}
+	report.Duplicates, err = s.repo.ListDuplicates(ctx, tenantID, nil, status, from, to, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duplicate bills: %w", err)
+	}
+	if report.Duplicates == nil {
+		report.Duplicates = []*models.SupplierBillDuplicate{}
+	}
+	return report, nil
+}
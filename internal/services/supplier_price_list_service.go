@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agromart2/internal/common"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const maxPriceListItems = 1000
+
+var (
+	// ErrPriceListNotFound is returned for price lists outside the tenant
+	ErrPriceListNotFound = errors.New("supplier price list not found")
+	// ErrInvalidPriceList wraps price list validation failures
+	ErrInvalidPriceList = errors.New("invalid supplier price list")
+)
+
+// PriceListItemInput is one product price in a new price list
+type PriceListItemInput struct {
+	ProductID    uuid.UUID `json:"product_id"`
+	MinQuantity  int       `json:"min_quantity"` // quantity break, defaults to 1
+	UnitPrice    float64   `json:"unit_price"`
+	LeadTimeDays int       `json:"lead_time_days"`
+}
+
+// CreatePriceListRequest creates a supplier price list; dates are YYYY-MM-DD
+// and valid_to is inclusive
+type CreatePriceListRequest struct {
+	Name      string               `json:"name"`
+	ValidFrom string               `json:"valid_from"`
+	ValidTo   *string              `json:"valid_to"`
+	Notes     *string              `json:"notes"`
+	Items     []PriceListItemInput `json:"items"`
+}
+
+// SupplierPriceListService manages supplier price lists. A new list for a
+// period does not replace older ones; quotes take each supplier's lowest
+// valid price.
+type SupplierPriceListService interface {
+	Create(ctx context.Context, tenantID, supplierID uuid.UUID, req *CreatePriceListRequest) (*models.SupplierPriceList, error)
+	Get(ctx context.Context, tenantID, id uuid.UUID) (*models.SupplierPriceList, error)
+	ListBySupplier(ctx context.Context, tenantID, supplierID uuid.UUID) ([]*models.SupplierPriceList, error)
+	Delete(ctx context.Context, tenantID, id uuid.UUID) error
+}
+
+type supplierPriceListService struct {
+	priceListRepo repositories.SupplierPriceListRepository
+	supplierRepo  repositories.SupplierRepository
+	productRepo   repositories.ProductRepository
+}
+
+// NewSupplierPriceListService creates a new supplier price list service instance
+func NewSupplierPriceListService(priceListRepo repositories.SupplierPriceListRepository, supplierRepo repositories.SupplierRepository, productRepo repositories.ProductRepository) SupplierPriceListService {
+	return &supplierPriceListService{
+		priceListRepo: priceListRepo,
+		supplierRepo:  supplierRepo,
+		productRepo:   productRepo,
+	}
+}
+
+func (s *supplierPriceListService) Create(ctx context.Context, tenantID, supplierID uuid.UUID, req *CreatePriceListRequest) (*models.SupplierPriceList, error) {
+	if supplier, err := s.supplierRepo.GetByID(ctx, tenantID, supplierID); err != nil || supplier == nil {
+		return nil, ErrSupplierNotFound
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidPriceList)
+	}
+	if err := common.SanitizeHTMLField(req.Notes, "price list notes"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPriceList, err)
+	}
+	validFrom, err := time.Parse("2006-01-02", req.ValidFrom)
+	if err != nil {
+		return nil, fmt.Errorf("%w: valid_from must be a date in YYYY-MM-DD format", ErrInvalidPriceList)
+	}
+	var validTo *time.Time
+	if req.ValidTo != nil && *req.ValidTo != "" {
+		to, err := time.Parse("2006-01-02", *req.ValidTo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: valid_to must be a date in YYYY-MM-DD format", ErrInvalidPriceList)
+		}
+		if to.Before(validFrom) {
+			return nil, fmt.Errorf("%w: valid_to must not be before valid_from", ErrInvalidPriceList)
+		}
+		validTo = &to
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrInvalidPriceList)
+	}
+	if len(req.Items) > maxPriceListItems {
+		return nil, fmt.Errorf("%w: at most %d items per price list", ErrInvalidPriceList, maxPriceListItems)
+	}
+
+	list := &models.SupplierPriceList{
+		ID:         uuid.New(),
+		TenantID:   tenantID,
+		SupplierID: supplierID,
+		Name:       name,
+		ValidFrom:  validFrom,
+		ValidTo:    validTo,
+		Notes:      req.Notes,
+	}
+	type breakKey struct {
+		product     uuid.UUID
+		minQuantity int
+	}
+	seen := map[breakKey]bool{}
+	checked := map[uuid.UUID]bool{}
+	for _, input := range req.Items {
+		if input.MinQuantity == 0 {
+			input.MinQuantity = 1
+		}
+		switch {
+		case input.MinQuantity < 0:
+			return nil, fmt.Errorf("%w: min_quantity must be positive", ErrInvalidPriceList)
+		case input.UnitPrice <= 0:
+			return nil, fmt.Errorf("%w: unit_price must be positive", ErrInvalidPriceList)
+		case input.LeadTimeDays < 0:
+			return nil, fmt.Errorf("%w: lead_time_days must not be negative", ErrInvalidPriceList)
+		}
+		key := breakKey{input.ProductID, input.MinQuantity}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: product %s has two prices from %d units", ErrInvalidPriceList, input.ProductID, input.MinQuantity)
+		}
+		seen[key] = true
+		if !checked[input.ProductID] {
+			if product, err := s.productRepo.GetByID(ctx, tenantID, input.ProductID); err != nil || product == nil {
+				return nil, fmt.Errorf("%w: product %s not found", ErrInvalidPriceList, input.ProductID)
+			}
+			checked[input.ProductID] = true
+		}
+
+		list.Items = append(list.Items, &models.SupplierPriceListItem{
+			ID:           uuid.New(),
+			PriceListID:  list.ID,
+			ProductID:    input.ProductID,
+			MinQuantity:  input.MinQuantity,
+			UnitPrice:    input.UnitPrice,
+			LeadTimeDays: input.LeadTimeDays,
+		})
+	}
+
+	if err := s.priceListRepo.Create(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to save supplier price list: %w", err)
+	}
+	return s.Get(ctx, tenantID, list.ID)
+}
+
+func (s *supplierPriceListService) Get(ctx context.Context, tenantID, id uuid.UUID) (*models.SupplierPriceList, error) {
+	list, err := s.priceListRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, ErrPriceListNotFound
+	}
+	return list, nil
+}
+
+func (s *supplierPriceListService) ListBySupplier(ctx context.Context, tenantID, supplierID uuid.UUID) ([]*models.SupplierPriceList, error) {
+	if supplier, err := s.supplierRepo.GetByID(ctx, tenantID, supplierID); err != nil || supplier == nil {
+		return nil, ErrSupplierNotFound
+	}
+	return s.priceListRepo.ListBySupplier(ctx, tenantID, supplierID)
+}
+
+func (s *supplierPriceListService) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
+	deleted, err := s.priceListRepo.Delete(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPriceListNotFound
+	}
+	return nil
+}
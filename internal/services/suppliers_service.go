@@ -3,7 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
@@ -13,31 +17,43 @@ import (
 type SupplierService interface {
 	Create(ctx context.Context, tenantID uuid.UUID, supplier *models.Supplier) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Supplier, error)
+	// GetByIDs returns the suppliers found among ids, archived ones included
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Supplier, error)
 	Update(ctx context.Context, tenantID uuid.UUID, supplier *models.Supplier) error
-	Delete(ctx context.Context, tenantID, id uuid.UUID) error
-	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Supplier, error)
+	// Delete removes the supplier, or archives it when past orders reference it
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (archived bool, err error)
+	List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Supplier, error)
 	GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Supplier, error)
 }
 
 type supplierService struct {
-	supplierRepo repositories.SupplierRepository
+	supplierRepo  repositories.SupplierRepository
+	dependencySvc DependencyService
 }
 
-func NewSupplierService(supplierRepo repositories.SupplierRepository) SupplierService {
+func NewSupplierService(supplierRepo repositories.SupplierRepository, dependencySvc DependencyService) SupplierService {
 	return &supplierService{
-		supplierRepo: supplierRepo,
+		supplierRepo:  supplierRepo,
+		dependencySvc: dependencySvc,
 	}
 }
 
 func (s *supplierService) Create(ctx context.Context, tenantID uuid.UUID, supplier *models.Supplier) error {
+	supplier.Name = strings.TrimSpace(supplier.Name)
 	if supplier.Name == "" {
 		return errors.New("supplier name is required")
 	}
+	if err := normalizeGSTIN(&supplier.GSTIN); err != nil {
+		return err
+	}
+	if err := normalizeBankAccount(&supplier.BankAccountNumber, &supplier.BankIFSC); err != nil {
+		return err
+	}
 
 	// Check for duplicate name
 	existing, err := s.supplierRepo.GetByName(ctx, tenantID, supplier.Name)
 	if err == nil && existing != nil {
-		return errors.New("supplier with this name already exists")
+		return fmt.Errorf("%w: supplier with this name already exists", ErrDuplicateName)
 	}
 
 	supplier.TenantID = tenantID
@@ -50,23 +66,58 @@ func (s *supplierService) GetByID(ctx context.Context, tenantID, id uuid.UUID) (
 	return s.supplierRepo.GetByID(ctx, tenantID, id)
 }
 
+func (s *supplierService) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Supplier, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.supplierRepo.GetByIDs(ctx, tenantID, ids)
+}
+
 func (s *supplierService) Update(ctx context.Context, tenantID uuid.UUID, supplier *models.Supplier) error {
+	supplier.Name = strings.TrimSpace(supplier.Name)
 	if supplier.Name == "" {
 		return errors.New("supplier name is required")
 	}
+	if err := normalizeGSTIN(&supplier.GSTIN); err != nil {
+		return err
+	}
+	if err := normalizeBankAccount(&supplier.BankAccountNumber, &supplier.BankIFSC); err != nil {
+		return err
+	}
+
+	existing, err := s.supplierRepo.GetByName(ctx, tenantID, supplier.Name)
+	if err == nil && existing != nil && existing.ID != supplier.ID {
+		return fmt.Errorf("%w: supplier with this name already exists", ErrDuplicateName)
+	}
 
 	supplier.TenantID = tenantID
 	return s.supplierRepo.Update(ctx, supplier)
 }
 
-func (s *supplierService) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
-	return s.supplierRepo.Delete(ctx, tenantID, id)
+func (s *supplierService) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	if err := s.dependencySvc.CheckDeletable(ctx, tenantID, models.DependentSupplier, id); err != nil {
+		return false, err
+	}
+	orders, err := s.supplierRepo.OrderCount(ctx, tenantID, id)
+	if err != nil {
+		return false, common.SecureErrorMessage("check supplier usage", err)
+	}
+	if orders > 0 {
+		if err := s.supplierRepo.Archive(ctx, tenantID, id); err != nil {
+			return false, common.SecureErrorMessage("archive supplier", err)
+		}
+		return true, nil
+	}
+	if err := s.supplierRepo.Delete(ctx, tenantID, id); err != nil {
+		return false, common.SecureErrorMessage("delete supplier", err)
+	}
+	return false, nil
 }
 
-func (s *supplierService) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Supplier, error) {
-	return s.supplierRepo.List(ctx, tenantID, limit, offset)
+func (s *supplierService) List(ctx context.Context, tenantID uuid.UUID, page listquery.Page) ([]*models.Supplier, error) {
+	return s.supplierRepo.ListPage(ctx, tenantID, page)
 }
 
 func (s *supplierService) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Supplier, error) {
 	return s.supplierRepo.GetByName(ctx, tenantID, name)
-}
\ No newline at end of file
+}
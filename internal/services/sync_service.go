@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SyncDefaultPageSize and SyncMaxPageSize bound the changes in one pull
+	SyncDefaultPageSize = 500
+	SyncMaxPageSize     = 2000
+	syncMaxUploadBatch  = 100
+	syncTokenPrefix     = "s1:"
+	// syncCommitLag holds back log rows this recent so a transaction that
+	// took its seq earlier but commits later is not skipped by a device
+	syncCommitLag = 5 * time.Second
+)
+
+// ErrInvalidSyncToken is returned for change tokens this server did not issue
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+
+// SyncService serves the offline changes feed and accepts orders taken offline
+type SyncService interface {
+	GetChanges(ctx context.Context, tenantID uuid.UUID, token string, entities []string, limit int) (*models.SyncChangesResponse, error)
+	UploadOrders(ctx context.Context, tenantID uuid.UUID, req *models.SyncUploadRequest) (*models.SyncUploadResponse, error)
+}
+
+type syncService struct {
+	syncRepo      repositories.SyncRepository
+	productRepo   repositories.ProductRepository
+	inventoryRepo repositories.InventoryRepository
+	orderService  OrderServiceInterface
+	stockOutRepo  repositories.StockOutRepository
+}
+
+// NewSyncService creates a new offline sync service
+func NewSyncService(syncRepo repositories.SyncRepository, productRepo repositories.ProductRepository, inventoryRepo repositories.InventoryRepository, orderService OrderServiceInterface, stockOutRepo repositories.StockOutRepository) SyncService {
+	return &syncService{
+		syncRepo:      syncRepo,
+		productRepo:   productRepo,
+		inventoryRepo: inventoryRepo,
+		orderService:  orderService,
+		stockOutRepo:  stockOutRepo,
+	}
+}
+
+// EncodeSyncToken wraps a change log seq in an opaque token
+func EncodeSyncToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncTokenPrefix + strconv.FormatInt(seq, 10)))
+}
+
+// DecodeSyncToken returns the seq behind a token; an empty token starts from the beginning
+func DecodeSyncToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(raw), syncTokenPrefix) {
+		return 0, ErrInvalidSyncToken
+	}
+	seq, err := strconv.ParseInt(strings.TrimPrefix(string(raw), syncTokenPrefix), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidSyncToken
+	}
+	return seq, nil
+}
+
+func (s *syncService) GetChanges(ctx context.Context, tenantID uuid.UUID, token string, entities []string, limit int) (*models.SyncChangesResponse, error) {
+	since, err := DecodeSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		entities = models.SyncEntities
+	}
+	for _, entity := range entities {
+		if !isSyncEntity(entity) {
+			return nil, fmt.Errorf("unknown sync entity %q", entity)
+		}
+	}
+	if limit <= 0 {
+		limit = SyncDefaultPageSize
+	}
+	if limit > SyncMaxPageSize {
+		limit = SyncMaxPageSize
+	}
+
+	upper, hasMore, err := s.syncRepo.PageBound(ctx, tenantID, since, entities, limit, syncCommitLag)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.SyncChangesResponse{
+		Changes:   make(map[string]*models.SyncEntityChanges, len(entities)),
+		NextToken: EncodeSyncToken(upper),
+		HasMore:   hasMore,
+	}
+	upserted := make(map[string][]uuid.UUID, len(entities))
+	for _, entity := range entities {
+		resp.Changes[entity] = &models.SyncEntityChanges{Deleted: []uuid.UUID{}}
+	}
+	if upper > since {
+		changes, err := s.syncRepo.ListChanges(ctx, tenantID, since, upper, entities)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			if change.Operation == models.SyncOpDelete {
+				resp.Changes[change.Entity].Deleted = append(resp.Changes[change.Entity].Deleted, change.RecordID)
+			} else {
+				upserted[change.Entity] = append(upserted[change.Entity], change.RecordID)
+			}
+		}
+	}
+
+	// Records are read at their current state; a record deleted after upper is
+	// simply absent here and its tombstone arrives on the next page
+	for _, entity := range entities {
+		records, err := s.loadRecords(ctx, tenantID, entity, upserted[entity])
+		if err != nil {
+			return nil, err
+		}
+		resp.Changes[entity].Upserted = records
+	}
+	return resp, nil
+}
+
+func (s *syncService) loadRecords(ctx context.Context, tenantID uuid.UUID, entity string, ids []uuid.UUID) (interface{}, error) {
+	switch entity {
+	case models.SyncEntityProducts:
+		if len(ids) == 0 {
+			return []*models.SyncProduct{}, nil
+		}
+		return s.syncRepo.GetProducts(ctx, tenantID, ids)
+	case models.SyncEntityPriceLists:
+		if len(ids) == 0 {
+			return []*models.SyncPrice{}, nil
+		}
+		return s.syncRepo.GetPrices(ctx, tenantID, ids)
+	case models.SyncEntityCustomers:
+		if len(ids) == 0 {
+			return []*models.Distributor{}, nil
+		}
+		return s.syncRepo.GetCustomers(ctx, tenantID, ids)
+	default:
+		if len(ids) == 0 {
+			return []*models.Order{}, nil
+		}
+		return s.syncRepo.GetOrders(ctx, tenantID, ids)
+	}
+}
+
+func isSyncEntity(entity string) bool {
+	for _, known := range models.SyncEntities {
+		if entity == known {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadOrders creates sales orders taken offline. Each order is decided on
+// its own so one bad order does not block the batch:
+//   - an ID already used by this tenant is a replay and returns the server copy
+//   - an ID deleted on the server or owned by another tenant is rejected
+//   - stock on the server is authoritative; short orders are rejected
+//   - a price changed since base_token follows price_conflict: server_wins
+//     (default) reprices to the current price, client_wins keeps the device
+//     price subject to margin guardrails, reject refuses the order
+func (s *syncService) UploadOrders(ctx context.Context, tenantID uuid.UUID, req *models.SyncUploadRequest) (*models.SyncUploadResponse, error) {
+	if len(req.Orders) == 0 {
+		return nil, fmt.Errorf("at least one order is required")
+	}
+	if len(req.Orders) > syncMaxUploadBatch {
+		return nil, fmt.Errorf("at most %d orders can be uploaded per batch", syncMaxUploadBatch)
+	}
+	if req.PriceConflict == "" {
+		req.PriceConflict = models.SyncPriceServerWins
+	}
+	if req.PriceConflict != models.SyncPriceServerWins && req.PriceConflict != models.SyncPriceClientWins && req.PriceConflict != models.SyncPriceReject {
+		return nil, fmt.Errorf("price_conflict must be server_wins, client_wins or reject")
+	}
+	baseSeq, err := DecodeSyncToken(req.BaseToken)
+	if err != nil {
+		return nil, err
+	}
+	for i, upload := range req.Orders {
+		if upload.ID == uuid.Nil {
+			return nil, fmt.Errorf("order %d: id is required", i)
+		}
+		if upload.DistributorID == uuid.Nil || upload.ProductID == uuid.Nil || upload.WarehouseID == uuid.Nil {
+			return nil, fmt.Errorf("order %d: customer_id, product_id and warehouse_id are required", i)
+		}
+		if upload.Quantity <= 0 {
+			return nil, fmt.Errorf("order %d: quantity must be positive", i)
+		}
+	}
+
+	resp := &models.SyncUploadResponse{Results: make([]models.SyncUploadResult, 0, len(req.Orders))}
+	for _, upload := range req.Orders {
+		result := s.uploadOrder(ctx, tenantID, baseSeq, req.PriceConflict, upload)
+		switch result.Status {
+		case models.SyncUploadCreated, models.SyncUploadRepriced:
+			resp.Created++
+		case models.SyncUploadRejected:
+			resp.Rejected++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+func (s *syncService) uploadOrder(ctx context.Context, tenantID uuid.UUID, baseSeq int64, priceConflict string, upload models.SyncOrderUpload) models.SyncUploadResult {
+	result := models.SyncUploadResult{ID: upload.ID}
+	retry := func(err error) models.SyncUploadResult {
+		fmt.Printf("Offline order %s upload failed: %v\n", upload.ID, err)
+		result.Status = models.SyncUploadRetry
+		result.Reason = "temporarily unavailable"
+		return result
+	}
+	reject := func(reason string) models.SyncUploadResult {
+		result.Status = models.SyncUploadRejected
+		result.Reason = reason
+		return result
+	}
+
+	owner, err := s.syncRepo.FindOrderTenant(ctx, upload.ID)
+	if err != nil {
+		return retry(err)
+	}
+	if owner != nil {
+		if *owner != tenantID {
+			return reject("order id already in use")
+		}
+		existing, err := s.orderService.GetOrderByID(ctx, tenantID, upload.ID)
+		if err != nil {
+			return retry(err)
+		}
+		result.Status = models.SyncUploadDuplicate
+		result.Order = existing
+		return result
+	}
+	deleted, err := s.syncRepo.HasTombstone(ctx, tenantID, models.SyncEntityOrders, upload.ID)
+	if err != nil {
+		return retry(err)
+	}
+	if deleted {
+		return reject("order was deleted on the server")
+	}
+
+	product, err := s.productRepo.GetByID(ctx, tenantID, upload.ProductID)
+	if err != nil || product == nil {
+		return reject("product no longer exists")
+	}
+
+	inventory, err := s.inventoryRepo.GetByWarehouseAndProduct(ctx, tenantID, upload.WarehouseID, upload.ProductID)
+	if err != nil || inventory == nil || inventory.Quantity < upload.Quantity {
+		if err == nil {
+			s.recordStockOut(ctx, tenantID, upload, inventory)
+		}
+		return reject("insufficient stock")
+	}
+
+	unitPrice := upload.UnitPrice
+	if unitPrice != product.UnitPrice {
+		changed, err := s.syncRepo.PriceChangedSince(ctx, tenantID, upload.ProductID, baseSeq)
+		if err != nil {
+			return retry(err)
+		}
+		// A price that differs without a server change since base_token was set
+		// on the device on purpose and is left to the margin guardrails
+		if changed {
+			switch priceConflict {
+			case models.SyncPriceReject:
+				return reject("price changed on the server")
+			case models.SyncPriceServerWins:
+				result.ClientPrice = upload.UnitPrice
+				unitPrice = product.UnitPrice
+			}
+		}
+	}
+
+	distributorID := upload.DistributorID
+	order := &models.Order{
+		ID:               upload.ID,
+		TenantID:         tenantID,
+		OrderType:        "sales",
+		DistributorID:    &distributorID,
+		ProductID:        upload.ProductID,
+		WarehouseID:      upload.WarehouseID,
+		Quantity:         upload.Quantity,
+		UnitPrice:        unitPrice,
+		OrderDate:        upload.CreatedOfflineAt,
+		ExpectedDelivery: upload.ExpectedDelivery,
+		Notes:            upload.Notes,
+	}
+	if order.OrderDate.IsZero() || order.OrderDate.After(time.Now()) {
+		order.OrderDate = time.Now()
+	}
+	if err := s.orderService.CreateOrder(ctx, tenantID, order); err != nil {
+		return reject(err.Error())
+	}
+
+	result.Status = models.SyncUploadCreated
+	if result.ClientPrice != 0 {
+		result.Status = models.SyncUploadRepriced
+	}
+	result.Order = order
+	return result
+}
+
+// recordStockOut logs an offline order turned away for lack of stock; a
+// logging failure does not change the upload result
+func (s *syncService) recordStockOut(ctx context.Context, tenantID uuid.UUID, upload models.SyncOrderUpload, inventory *models.Inventory) {
+	if s.stockOutRepo == nil {
+		return
+	}
+	available := 0
+	if inventory != nil {
+		available = inventory.Quantity
+	}
+	distributorID := upload.DistributorID
+	event := &models.StockOutEvent{
+		ID:                uuid.New(),
+		TenantID:          tenantID,
+		ProductID:         upload.ProductID,
+		WarehouseID:       upload.WarehouseID,
+		DistributorID:     &distributorID,
+		Outcome:           models.StockOutRejected,
+		Source:            models.StockOutSourceOfflineSync,
+		RequestedQuantity: upload.Quantity,
+		AvailableQuantity: &available,
+		ShortQuantity:     upload.Quantity - max(available, 0),
+		UnitPrice:         upload.UnitPrice,
+	}
+	if err := s.stockOutRepo.Record(ctx, event); err != nil {
+		fmt.Printf("Failed to record stock-out for offline order %s: %v\n", upload.ID, err)
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidTenantCalendar wraps tenant calendar validation failures
+var ErrInvalidTenantCalendar = errors.New("invalid tenant calendar")
+
+// TenantCalendarService keeps each tenant's timezone and fiscal year start,
+// which date invoices, age overdue balances, bound report periods and decide
+// when the scheduled jobs act for the tenant
+type TenantCalendarService interface {
+	// GetCalendar returns the tenant's calendar, or the default one when the
+	// tenant has not configured it
+	GetCalendar(ctx context.Context, tenantID uuid.UUID) (*models.TenantCalendar, error)
+	UpdateCalendar(ctx context.Context, tenantID uuid.UUID, req *models.TenantCalendarRequest) (*models.TenantCalendar, error)
+}
+
+type tenantCalendarService struct {
+	repo repositories.TenantCalendarRepository
+}
+
+// NewTenantCalendarService creates a new tenant calendar service
+func NewTenantCalendarService(repo repositories.TenantCalendarRepository) TenantCalendarService {
+	return &tenantCalendarService{repo: repo}
+}
+
+func (s *tenantCalendarService) GetCalendar(ctx context.Context, tenantID uuid.UUID) (*models.TenantCalendar, error) {
+	calendar, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if calendar == nil {
+		return models.DefaultTenantCalendar(tenantID), nil
+	}
+	return calendar, nil
+}
+
+func (s *tenantCalendarService) UpdateCalendar(ctx context.Context, tenantID uuid.UUID, req *models.TenantCalendarRequest) (*models.TenantCalendar, error) {
+	calendar, err := s.GetCalendar(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Timezone != nil {
+		timezone := strings.TrimSpace(*req.Timezone)
+		// Local would follow whichever host runs the job, which is what the
+		// setting is there to avoid
+		if timezone == "" || timezone == "Local" {
+			return nil, fmt.Errorf("%w: timezone is required", ErrInvalidTenantCalendar)
+		}
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q, use an IANA name like Asia/Kolkata", ErrInvalidTenantCalendar, timezone)
+		}
+		calendar.Timezone = timezone
+	}
+	if req.FiscalYearStartMonth != nil {
+		if *req.FiscalYearStartMonth < 1 || *req.FiscalYearStartMonth > 12 {
+			return nil, fmt.Errorf("%w: fiscal_year_start_month must be between 1 and 12", ErrInvalidTenantCalendar)
+		}
+		calendar.FiscalYearStartMonth = *req.FiscalYearStartMonth
+	}
+
+	calendar.TenantID = tenantID
+	if err := s.repo.Upsert(ctx, calendar); err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+// TenantCalendarOrDefault returns the tenant's calendar for dating records,
+// falling back to the default one when it cannot be loaded so the work still
+// goes ahead
+func TenantCalendarOrDefault(ctx context.Context, calendars TenantCalendarService, tenantID uuid.UUID) *models.TenantCalendar {
+	if calendars == nil {
+		return models.DefaultTenantCalendar(tenantID)
+	}
+	calendar, err := calendars.GetCalendar(ctx, tenantID)
+	if err != nil {
+		log.Printf("Failed to load calendar for tenant %s, using the default: %v", tenantID, err)
+		return models.DefaultTenantCalendar(tenantID)
+	}
+	return calendar
+}
@@ -54,6 +54,11 @@ func (m *MockTenantRepository) List(ctx context.Context, limit, offset int) ([]*
 	return args.Get(0).([]*models.Tenant), args.Error(1)
 }
 
+func (m *MockTenantRepository) SetResidency(ctx context.Context, id uuid.UUID, region *string) error {
+	args := m.Called(ctx, id, region)
+	return args.Error(0)
+}
+
 type TenantServiceTestSuite struct {
 	suite.Suite
 	mockRepo *MockTenantRepository
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTerritoryNotFound is returned for territories outside the tenant
+	ErrTerritoryNotFound = errors.New("territory not found")
+	// ErrInvalidTerritory wraps territory validation failures
+	ErrInvalidTerritory = errors.New("invalid territory")
+	// ErrTerritoryConflict is returned for a second territory of the same name
+	// under a parent and for deleting a territory that has others below it
+	ErrTerritoryConflict = errors.New("territory conflict")
+	// ErrTerritoryOutOfScope is returned when a user restricted to a
+	// territory asks for one outside it
+	ErrTerritoryOutOfScope = errors.New("territory is outside your territory")
+	// ErrTerritoryEntityNotFound is returned when assigning an unknown
+	// customer, warehouse or user
+	ErrTerritoryEntityNotFound = errors.New("territory assignee not found")
+)
+
+// territoryLevels orders the levels from the top of the hierarchy down
+var territoryLevels = []string{models.TerritoryLevelState, models.TerritoryLevelDistrict, models.TerritoryLevelArea}
+
+// territoryLevelRank is the depth of a level, or -1 for an unknown one
+func territoryLevelRank(level string) int {
+	for i, l := range territoryLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// childTerritoryLevel is the level below level; areas have none below them
+// and report their own
+func childTerritoryLevel(level string) string {
+	rank := territoryLevelRank(level)
+	if rank < 0 || rank+1 >= len(territoryLevels) {
+		return level
+	}
+	return territoryLevels[rank+1]
+}
+
+// TerritoryService manages the state, district and area hierarchy, assigns
+// customers, warehouses and users to it and reports sales by territory. A
+// user assigned to a territory only sees sales within it
+type TerritoryService interface {
+	ListTerritories(ctx context.Context, tenantID uuid.UUID) ([]*models.Territory, error)
+	CreateTerritory(ctx context.Context, tenantID uuid.UUID, req *models.TerritoryRequest) (*models.Territory, error)
+	UpdateTerritory(ctx context.Context, tenantID, territoryID uuid.UUID, req *models.TerritoryRequest) (*models.Territory, error)
+	DeleteTerritory(ctx context.Context, tenantID, territoryID uuid.UUID) error
+	Assign(ctx context.Context, tenantID uuid.UUID, req *models.TerritoryAssignmentRequest) error
+	// UserTerritory returns the territory the user is restricted to, or nil
+	// when they see the whole tenant
+	UserTerritory(ctx context.Context, tenantID, userID uuid.UUID) (*uuid.UUID, error)
+
+	// SalesByTerritory breaks the user's visible sales down by the
+	// territories of a level. An empty level is the one below territoryID,
+	// or states when there is none
+	SalesByTerritory(ctx context.Context, tenantID, userID uuid.UUID, level string, territoryID *uuid.UUID, from, to time.Time) (*models.TerritorySalesReport, error)
+	// DailySales totals the user's visible sales per day, within territoryID
+	// when set
+	DailySales(ctx context.Context, tenantID, userID uuid.UUID, territoryID *uuid.UUID, from, to time.Time) ([]*models.TerritoryDailySales, error)
+}
+
+type territoryService struct {
+	repo repositories.TerritoryRepository
+}
+
+// NewTerritoryService creates a new territory service
+func NewTerritoryService(repo repositories.TerritoryRepository) TerritoryService {
+	return &territoryService{repo: repo}
+}
+
+func (s *territoryService) ListTerritories(ctx context.Context, tenantID uuid.UUID) ([]*models.Territory, error) {
+	return s.repo.List(ctx, tenantID)
+}
+
+func territoryName(req *models.TerritoryRequest) (string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return "", fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidTerritory)
+	}
+	return name, nil
+}
+
+func (s *territoryService) CreateTerritory(ctx context.Context, tenantID uuid.UUID, req *models.TerritoryRequest) (*models.Territory, error) {
+	name, err := territoryName(req)
+	if err != nil {
+		return nil, err
+	}
+
+	territory := &models.Territory{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+		Level:    models.TerritoryLevelState,
+		Name:     name,
+		Code:     trimmedOrNil(req.Code),
+		Path:     name,
+	}
+	if req.ParentID != nil {
+		parent, err := s.repo.Get(ctx, tenantID, *req.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent territory: %w", err)
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("%w: parent territory not found", ErrInvalidTerritory)
+		}
+		if parent.Level == models.TerritoryLevelArea {
+			return nil, fmt.Errorf("%w: areas cannot have territories below them", ErrInvalidTerritory)
+		}
+		territory.ParentID = &parent.ID
+		territory.Level = childTerritoryLevel(parent.Level)
+		territory.Path = parent.Path + " / " + name
+	}
+
+	ok, err := s.repo.Create(ctx, territory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create territory: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s already exists there", ErrTerritoryConflict, name)
+	}
+	return territory, nil
+}
+
+// UpdateTerritory renames a territory or changes its code; it stays where it
+// is in the hierarchy
+func (s *territoryService) UpdateTerritory(ctx context.Context, tenantID, territoryID uuid.UUID, req *models.TerritoryRequest) (*models.Territory, error) {
+	territory, err := s.repo.Get(ctx, tenantID, territoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load territory: %w", err)
+	}
+	if territory == nil {
+		return nil, ErrTerritoryNotFound
+	}
+	name, err := territoryName(req)
+	if err != nil {
+		return nil, err
+	}
+	territory.Name = name
+	territory.Code = trimmedOrNil(req.Code)
+
+	ok, err := s.repo.Update(ctx, territory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update territory: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s already exists there", ErrTerritoryConflict, name)
+	}
+	return s.repo.Get(ctx, tenantID, territoryID)
+}
+
+// DeleteTerritory removes a territory with nothing below it; customers,
+// warehouses and users in it are left without one
+func (s *territoryService) DeleteTerritory(ctx context.Context, tenantID, territoryID uuid.UUID) error {
+	children, err := s.repo.ChildCount(ctx, tenantID, territoryID)
+	if err != nil {
+		return fmt.Errorf("failed to check territory: %w", err)
+	}
+	if children > 0 {
+		return fmt.Errorf("%w: delete the %d territories below it first", ErrTerritoryConflict, children)
+	}
+
+	ok, err := s.repo.Delete(ctx, tenantID, territoryID)
+	if err != nil {
+		return fmt.Errorf("failed to delete territory: %w", err)
+	}
+	if !ok {
+		return ErrTerritoryNotFound
+	}
+	return nil
+}
+
+func (s *territoryService) Assign(ctx context.Context, tenantID uuid.UUID, req *models.TerritoryAssignmentRequest) error {
+	entityType := strings.ToLower(strings.TrimSpace(req.EntityType))
+	if entityType != models.TerritoryEntityCustomer && entityType != models.TerritoryEntityWarehouse && entityType != models.TerritoryEntityUser {
+		return fmt.Errorf("%w: entity_type must be customer, warehouse or user", ErrInvalidTerritory)
+	}
+	if req.TerritoryID != nil {
+		territory, err := s.repo.Get(ctx, tenantID, *req.TerritoryID)
+		if err != nil {
+			return fmt.Errorf("failed to load territory: %w", err)
+		}
+		if territory == nil {
+			return ErrTerritoryNotFound
+		}
+	}
+
+	ok, err := s.repo.Assign(ctx, tenantID, entityType, req.EntityID, req.TerritoryID)
+	if err != nil {
+		return fmt.Errorf("failed to assign territory: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s not found", ErrTerritoryEntityNotFound, entityType)
+	}
+	return nil
+}
+
+func (s *territoryService) UserTerritory(ctx context.Context, tenantID, userID uuid.UUID) (*uuid.UUID, error) {
+	return s.repo.UserTerritory(ctx, tenantID, userID)
+}
+
+// scopedTerritory narrows the requested territory to what the user may see:
+// their own territory when they have one and asked for none, and an error
+// when they asked for one outside it
+func (s *territoryService) scopedTerritory(ctx context.Context, tenantID, userID uuid.UUID, requested *uuid.UUID) (*models.Territory, error) {
+	scope, err := s.repo.UserTerritory(ctx, tenantID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user territory: %w", err)
+	}
+
+	territoryID := requested
+	if territoryID == nil {
+		territoryID = scope
+	}
+	if territoryID == nil {
+		return nil, nil
+	}
+	territory, err := s.repo.Get(ctx, tenantID, *territoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load territory: %w", err)
+	}
+	if territory == nil {
+		return nil, ErrTerritoryNotFound
+	}
+
+	if scope != nil && requested != nil {
+		within, err := s.repo.Contains(ctx, tenantID, *scope, *requested)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check territory scope: %w", err)
+		}
+		if !within {
+			return nil, ErrTerritoryOutOfScope
+		}
+	}
+	return territory, nil
+}
+
+func (s *territoryService) SalesByTerritory(ctx context.Context, tenantID, userID uuid.UUID, level string, territoryID *uuid.UUID, from, to time.Time) (*models.TerritorySalesReport, error) {
+	territory, err := s.scopedTerritory(ctx, tenantID, userID, territoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" {
+		level = models.TerritoryLevelState
+		if territory != nil {
+			level = childTerritoryLevel(territory.Level)
+		}
+	}
+	if territoryLevelRank(level) < 0 {
+		return nil, fmt.Errorf("%w: level must be state, district or area", ErrInvalidTerritory)
+	}
+	if territory != nil && territoryLevelRank(level) < territoryLevelRank(territory.Level) {
+		return nil, fmt.Errorf("%w: level must not be above the territory's %s level", ErrInvalidTerritory, territory.Level)
+	}
+
+	report := &models.TerritorySalesReport{From: from, To: to, Level: level, Territories: []*models.TerritorySales{}}
+	var within *uuid.UUID
+	if territory != nil {
+		within = &territory.ID
+		report.TerritoryID = within
+	}
+
+	sales, err := s.repo.SalesByTerritory(ctx, tenantID, level, within, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load territory sales: %w", err)
+	}
+	report.Totals.Name = "Total"
+	report.Totals.Level = level
+	for _, row := range sales {
+		row.OrderAmount = math.Round(row.OrderAmount*100) / 100
+		row.InvoiceAmount = math.Round(row.InvoiceAmount*100) / 100
+		report.Territories = append(report.Territories, row)
+
+		report.Totals.CustomerCount += row.CustomerCount
+		report.Totals.OrderCount += row.OrderCount
+		report.Totals.OrderAmount += row.OrderAmount
+		report.Totals.InvoiceCount += row.InvoiceCount
+		report.Totals.InvoiceAmount += row.InvoiceAmount
+	}
+	report.Totals.OrderAmount = math.Round(report.Totals.OrderAmount*100) / 100
+	report.Totals.InvoiceAmount = math.Round(report.Totals.InvoiceAmount*100) / 100
+	return report, nil
+}
+
+func (s *territoryService) DailySales(ctx context.Context, tenantID, userID uuid.UUID, territoryID *uuid.UUID, from, to time.Time) ([]*models.TerritoryDailySales, error) {
+	territory, err := s.scopedTerritory(ctx, tenantID, userID, territoryID)
+	if err != nil {
+		return nil, err
+	}
+	var within *uuid.UUID
+	if territory != nil {
+		within = &territory.ID
+	}
+
+	days, err := s.repo.DailySales(ctx, tenantID, within, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load territory daily sales: %w", err)
+	}
+	for _, day := range days {
+		day.OrderAmount = math.Round(day.OrderAmount*100) / 100
+		day.InvoiceAmount = math.Round(day.InvoiceAmount*100) / 100
+	}
+	return days, nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/pkg/qrcode"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidPaymentSettings wraps tenant payment settings validation failures
+	ErrInvalidPaymentSettings = errors.New("invalid payment settings")
+	// ErrUPINotConfigured is returned when the tenant has no UPI VPA
+	ErrUPINotConfigured = errors.New("UPI payments are not configured for this tenant")
+	// ErrInvoiceNotPayable is returned for invoices that are paid or cancelled
+	ErrInvoiceNotPayable = errors.New("invoice is not awaiting payment")
+)
+
+// upiQRScale is the pixels per module of the QR PNG, enough for a phone to
+// read it off a screen or a printed invoice
+const upiQRScale = 8
+
+// upiPayeeNameLimit keeps the payee name within what UPI apps display
+const upiPayeeNameLimit = 50
+
+var upiVPAPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{2,256}@[a-zA-Z][a-zA-Z0-9]{1,63}$`)
+
+// UPIPaymentService keeps the tenant's UPI payee details and builds the UPI
+// payment intents printed on its invoices. The intent's transaction
+// reference is the invoice number, so a payment gateway confirming the
+// payment to an inbound webhook with the mark_invoice_paid action finds the
+// invoice by it
+type UPIPaymentService interface {
+	GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.TenantPaymentSettings, error)
+	UpdateSettings(ctx context.Context, tenantID uuid.UUID, req *models.TenantPaymentSettingsRequest) (*models.TenantPaymentSettings, error)
+	// InvoiceIntent returns the upi://pay URI for the invoice's total
+	InvoiceIntent(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) (string, error)
+	// InvoiceQR returns the invoice's UPI intent as a QR code PNG
+	InvoiceQR(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) ([]byte, error)
+}
+
+type upiPaymentService struct {
+	repo       repositories.TenantPaymentSettingsRepository
+	tenantRepo repositories.TenantRepository
+}
+
+// NewUPIPaymentService creates a new UPI payment service
+func NewUPIPaymentService(repo repositories.TenantPaymentSettingsRepository, tenantRepo repositories.TenantRepository) UPIPaymentService {
+	return &upiPaymentService{repo: repo, tenantRepo: tenantRepo}
+}
+
+func (s *upiPaymentService) GetSettings(ctx context.Context, tenantID uuid.UUID) (*models.TenantPaymentSettings, error) {
+	settings, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return &models.TenantPaymentSettings{TenantID: tenantID}, nil
+	}
+	return settings, nil
+}
+
+func (s *upiPaymentService) UpdateSettings(ctx context.Context, tenantID uuid.UUID, req *models.TenantPaymentSettingsRequest) (*models.TenantPaymentSettings, error) {
+	settings, err := s.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.UPIVPA != nil {
+		vpa := strings.TrimSpace(*req.UPIVPA)
+		if vpa == "" {
+			settings.UPIVPA = nil
+		} else {
+			if !upiVPAPattern.MatchString(vpa) {
+				return nil, fmt.Errorf("%w: upi_vpa must look like name@bank", ErrInvalidPaymentSettings)
+			}
+			vpa = strings.ToLower(vpa)
+			settings.UPIVPA = &vpa
+		}
+	}
+	if req.PayeeName != nil {
+		name := strings.TrimSpace(*req.PayeeName)
+		if name == "" {
+			settings.PayeeName = nil
+		} else {
+			if len([]rune(name)) > upiPayeeNameLimit {
+				return nil, fmt.Errorf("%w: payee_name must be at most %d characters", ErrInvalidPaymentSettings, upiPayeeNameLimit)
+			}
+			settings.PayeeName = &name
+		}
+	}
+
+	settings.TenantID = tenantID
+	if err := s.repo.Upsert(ctx, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *upiPaymentService) InvoiceIntent(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) (string, error) {
+	if invoice.Status != "unpaid" && invoice.Status != "overdue" {
+		return "", ErrInvoiceNotPayable
+	}
+	settings, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	if settings == nil || settings.UPIVPA == nil {
+		return "", ErrUPINotConfigured
+	}
+
+	payee := ""
+	if settings.PayeeName != nil {
+		payee = *settings.PayeeName
+	} else {
+		tenant, err := s.tenantRepo.GetByID(ctx, tenantID)
+		if err != nil {
+			return "", fmt.Errorf("failed to load tenant: %w", err)
+		}
+		payee = tenant.Name
+	}
+	if runes := []rune(payee); len(runes) > upiPayeeNameLimit {
+		payee = string(runes[:upiPayeeNameLimit])
+	}
+
+	reference := invoice.InvoiceNumber
+	if reference == "" {
+		reference = invoice.ID.String()
+	}
+	return upiIntent([][2]string{
+		{"pa", *settings.UPIVPA},
+		{"pn", payee},
+		{"am", fmt.Sprintf("%.2f", invoice.TotalAmount)},
+		{"cu", "INR"},
+		{"tn", "Invoice " + reference},
+		{"tr", reference},
+	}), nil
+}
+
+func (s *upiPaymentService) InvoiceQR(ctx context.Context, tenantID uuid.UUID, invoice *models.Invoice) ([]byte, error) {
+	intent, err := s.InvoiceIntent(ctx, tenantID, invoice)
+	if err != nil {
+		return nil, err
+	}
+	code, err := qrcode.Encode([]byte(intent), qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode UPI QR code: %w", err)
+	}
+	return code.PNG(upiQRScale)
+}
+
+// upiIntent builds a upi://pay URI with the parameters in the given order.
+// Spaces are sent as %20 and the @ of the VPA is kept as is, which is what
+// UPI apps expect
+func upiIntent(params [][2]string) string {
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		value := strings.ReplaceAll(url.QueryEscape(param[1]), "+", "%20")
+		value = strings.ReplaceAll(value, "%40", "@")
+		parts = append(parts, param[0]+"="+value)
+	}
+	return "upi://pay?" + strings.Join(parts, "&")
+}
@@ -3,7 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
+	"agromart2/internal/common"
+	"agromart2/internal/listquery"
 	"agromart2/internal/models"
 	"agromart2/internal/repositories"
 
@@ -13,23 +17,34 @@ import (
 type WarehouseService interface {
 	Create(ctx context.Context, tenantID uuid.UUID, warehouse *models.Warehouse) error
 	GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Warehouse, error)
+	// GetByIDs returns the warehouses found among ids, archived ones included
+	GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Warehouse, error)
 	Update(ctx context.Context, tenantID uuid.UUID, warehouse *models.Warehouse) error
-	Delete(ctx context.Context, tenantID, id uuid.UUID) error
-	List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Warehouse, error)
+	// Delete removes the warehouse, or archives it when past orders reference it
+	Delete(ctx context.Context, tenantID, id uuid.UUID) (archived bool, err error)
+	// List lists active warehouses, only those of the branch when branchID is set
+	List(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Warehouse, error)
 	GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Warehouse, error)
+	GetDefault(ctx context.Context, tenantID uuid.UUID) (*models.Warehouse, error)
+	SetDefault(ctx context.Context, tenantID, id uuid.UUID) error
 }
 
 type warehouseService struct {
 	warehouseRepo repositories.WarehouseRepository
+	dependencySvc DependencyService
+	branchSvc     BranchService
 }
 
-func NewWarehouseService(warehouseRepo repositories.WarehouseRepository) WarehouseService {
+func NewWarehouseService(warehouseRepo repositories.WarehouseRepository, dependencySvc DependencyService, branchSvc BranchService) WarehouseService {
 	return &warehouseService{
 		warehouseRepo: warehouseRepo,
+		dependencySvc: dependencySvc,
+		branchSvc:     branchSvc,
 	}
 }
 
 func (s *warehouseService) Create(ctx context.Context, tenantID uuid.UUID, warehouse *models.Warehouse) error {
+	warehouse.Name = strings.TrimSpace(warehouse.Name)
 	if warehouse.Name == "" {
 		return errors.New("warehouse name is required")
 	}
@@ -37,24 +52,56 @@ func (s *warehouseService) Create(ctx context.Context, tenantID uuid.UUID, wareh
 	if warehouse.Capacity == nil || *warehouse.Capacity <= 0 {
 		return errors.New("warehouse capacity must be greater than 0")
 	}
+	if err := validatePhysicalCapacity(warehouse); err != nil {
+		return err
+	}
+	if warehouse.BranchID != nil {
+		if err := s.branchSvc.CheckActive(ctx, tenantID, *warehouse.BranchID); err != nil {
+			return err
+		}
+	}
 
 	// Check for duplicate name
 	existing, err := s.warehouseRepo.GetByName(ctx, tenantID, warehouse.Name)
 	if err == nil && existing != nil {
-		return errors.New("warehouse with this name already exists")
+		return fmt.Errorf("%w: warehouse with this name already exists", ErrDuplicateName)
 	}
 
 	warehouse.TenantID = tenantID
 	warehouse.ID = uuid.New()
 
+	// The tenant's first warehouse becomes its default
+	if _, err := s.warehouseRepo.GetDefault(ctx, tenantID); err != nil {
+		warehouse.IsDefault = true
+	}
+
 	return s.warehouseRepo.Create(ctx, warehouse)
 }
 
+// validatePhysicalCapacity checks the optional volume and pallet capacities
+func validatePhysicalCapacity(warehouse *models.Warehouse) error {
+	if warehouse.VolumeCapacityM3 != nil && !(*warehouse.VolumeCapacityM3 > 0) {
+		return errors.New("warehouse volume capacity must be greater than 0")
+	}
+	if warehouse.PalletPositions != nil && *warehouse.PalletPositions <= 0 {
+		return errors.New("warehouse pallet positions must be greater than 0")
+	}
+	return nil
+}
+
 func (s *warehouseService) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Warehouse, error) {
 	return s.warehouseRepo.GetByID(ctx, tenantID, id)
 }
 
+func (s *warehouseService) GetByIDs(ctx context.Context, tenantID uuid.UUID, ids []uuid.UUID) ([]*models.Warehouse, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.warehouseRepo.GetByIDs(ctx, tenantID, ids)
+}
+
 func (s *warehouseService) Update(ctx context.Context, tenantID uuid.UUID, warehouse *models.Warehouse) error {
+	warehouse.Name = strings.TrimSpace(warehouse.Name)
 	if warehouse.Name == "" {
 		return errors.New("warehouse name is required")
 	}
@@ -62,19 +109,58 @@ func (s *warehouseService) Update(ctx context.Context, tenantID uuid.UUID, wareh
 	if warehouse.Capacity != nil && *warehouse.Capacity <= 0 {
 		return errors.New("warehouse capacity must be greater than 0")
 	}
+	if err := validatePhysicalCapacity(warehouse); err != nil {
+		return err
+	}
+	if warehouse.BranchID != nil {
+		if err := s.branchSvc.CheckActive(ctx, tenantID, *warehouse.BranchID); err != nil {
+			return err
+		}
+	}
+
+	existing, err := s.warehouseRepo.GetByName(ctx, tenantID, warehouse.Name)
+	if err == nil && existing != nil && existing.ID != warehouse.ID {
+		return fmt.Errorf("%w: warehouse with this name already exists", ErrDuplicateName)
+	}
 
 	warehouse.TenantID = tenantID
 	return s.warehouseRepo.Update(ctx, warehouse)
 }
 
-func (s *warehouseService) Delete(ctx context.Context, tenantID, id uuid.UUID) error {
-	return s.warehouseRepo.Delete(ctx, tenantID, id)
+func (s *warehouseService) Delete(ctx context.Context, tenantID, id uuid.UUID) (bool, error) {
+	if err := s.dependencySvc.CheckDeletable(ctx, tenantID, models.DependentWarehouse, id); err != nil {
+		return false, err
+	}
+	orders, err := s.warehouseRepo.OrderCount(ctx, tenantID, id)
+	if err != nil {
+		return false, common.SecureErrorMessage("check warehouse usage", err)
+	}
+	if orders > 0 {
+		if err := s.warehouseRepo.Archive(ctx, tenantID, id); err != nil {
+			return false, common.SecureErrorMessage("archive warehouse", err)
+		}
+		return true, nil
+	}
+	if err := s.warehouseRepo.Delete(ctx, tenantID, id); err != nil {
+		return false, common.SecureErrorMessage("delete warehouse", err)
+	}
+	return false, nil
 }
 
-func (s *warehouseService) List(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.Warehouse, error) {
-	return s.warehouseRepo.List(ctx, tenantID, limit, offset)
+func (s *warehouseService) List(ctx context.Context, tenantID uuid.UUID, branchID *uuid.UUID, page listquery.Page) ([]*models.Warehouse, error) {
+	return s.warehouseRepo.ListPage(ctx, tenantID, branchID, page)
 }
 
 func (s *warehouseService) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Warehouse, error) {
 	return s.warehouseRepo.GetByName(ctx, tenantID, name)
-}
\ No newline at end of file
+}
+func (s *warehouseService) GetDefault(ctx context.Context, tenantID uuid.UUID) (*models.Warehouse, error) {
+	return s.warehouseRepo.GetDefault(ctx, tenantID)
+}
+
+func (s *warehouseService) SetDefault(ctx context.Context, tenantID, id uuid.UUID) error {
+	if _, err := s.warehouseRepo.GetByID(ctx, tenantID, id); err != nil {
+		return errors.New("warehouse not found")
+	}
+	return s.warehouseRepo.SetDefault(ctx, tenantID, id)
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// WeatherClient fetches daily forecasts for a location
+type WeatherClient interface {
+	DailyForecast(ctx context.Context, latitude, longitude float64, days int) ([]models.DailyForecast, error)
+}
+
+type openMeteoClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewOpenMeteoClient creates a client for the open-meteo forecast API
+func NewOpenMeteoClient(baseURL string) WeatherClient {
+	if baseURL == "" {
+		baseURL = "https://api.open-meteo.com/v1/forecast"
+	}
+	return &openMeteoClient{
+		baseURL: baseURL,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type openMeteoResponse struct {
+	Daily struct {
+		Time             []string  `json:"time"`
+		PrecipitationSum []float64 `json:"precipitation_sum"`
+		Temperature2mMax []float64 `json:"temperature_2m_max"`
+		Temperature2mMin []float64 `json:"temperature_2m_min"`
+		WindSpeed10mMax  []float64 `json:"wind_speed_10m_max"`
+	} `json:"daily"`
+}
+
+func (c *openMeteoClient) DailyForecast(ctx context.Context, latitude, longitude float64, days int) ([]models.DailyForecast, error) {
+	params := url.Values{}
+	params.Set("latitude", fmt.Sprintf("%.4f", latitude))
+	params.Set("longitude", fmt.Sprintf("%.4f", longitude))
+	params.Set("daily", "precipitation_sum,temperature_2m_max,temperature_2m_min,wind_speed_10m_max")
+	params.Set("timezone", "Asia/Kolkata")
+	params.Set("forecast_days", fmt.Sprintf("%d", days))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("weather request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("weather API returned status %d", resp.StatusCode)
+	}
+
+	var body openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode weather response: %w", err)
+	}
+
+	daily := body.Daily
+	forecasts := make([]models.DailyForecast, 0, len(daily.Time))
+	for i, day := range daily.Time {
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forecast date %q", day)
+		}
+		forecasts = append(forecasts, models.DailyForecast{
+			Date:            date,
+			PrecipitationMM: valueAt(daily.PrecipitationSum, i),
+			TempMaxC:        valueAt(daily.Temperature2mMax, i),
+			TempMinC:        valueAt(daily.Temperature2mMin, i),
+			WindMaxKmh:      valueAt(daily.WindSpeed10mMax, i),
+		})
+	}
+	return forecasts, nil
+}
+
+func valueAt(values []float64, i int) float64 {
+	if i < len(values) {
+		return values[i]
+	}
+	return 0
+}
+
+// WeatherAlertService manages weather alert rules and raises alerts from warehouse forecasts
+type WeatherAlertService interface {
+	CreateRule(ctx context.Context, rule *models.WeatherAlertRule) error
+	GetRule(ctx context.Context, tenantID, id uuid.UUID) (*models.WeatherAlertRule, error)
+	UpdateRule(ctx context.Context, rule *models.WeatherAlertRule) error
+	DeleteRule(ctx context.Context, tenantID, id uuid.UUID) error
+	ListRules(ctx context.Context, tenantID uuid.UUID) ([]*models.WeatherAlertRule, error)
+	GetWarehouseForecast(ctx context.Context, tenantID, warehouseID uuid.UUID) ([]models.DailyForecast, error)
+	CheckTenant(ctx context.Context, tenantID uuid.UUID) ([]*models.WeatherAlertEvent, error)
+	ListEvents(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WeatherAlertEvent, error)
+}
+
+type weatherAlertService struct {
+	weatherRepo     repositories.WeatherAlertRepository
+	warehouseRepo   repositories.WarehouseRepository
+	client          WeatherClient
+	notificationSvc NotificationService
+	cacheService    caching.CacheService
+}
+
+func NewWeatherAlertService(weatherRepo repositories.WeatherAlertRepository, warehouseRepo repositories.WarehouseRepository, client WeatherClient, notificationSvc NotificationService, cacheService caching.CacheService) WeatherAlertService {
+	return &weatherAlertService{
+		weatherRepo:     weatherRepo,
+		warehouseRepo:   warehouseRepo,
+		client:          client,
+		notificationSvc: notificationSvc,
+		cacheService:    cacheService,
+	}
+}
+
+const forecastDays = 7
+
+func validateWeatherRule(rule *models.WeatherAlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	switch rule.Condition {
+	case models.WeatherConditionHeavyRain, models.WeatherConditionHeatWave, models.WeatherConditionColdWave, models.WeatherConditionHighWind:
+	default:
+		return fmt.Errorf("invalid condition: %s", rule.Condition)
+	}
+	if rule.LookaheadDays == 0 {
+		rule.LookaheadDays = 3
+	}
+	if rule.LookaheadDays < 1 || rule.LookaheadDays > forecastDays {
+		return fmt.Errorf("lookahead_days must be between 1 and %d", forecastDays)
+	}
+	if strings.TrimSpace(rule.Message) == "" {
+		return fmt.Errorf("message is required")
+	}
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+	if rule.Severity != "info" && rule.Severity != "warning" && rule.Severity != "critical" {
+		return fmt.Errorf("severity must be info, warning or critical")
+	}
+	if rule.Recipients == nil {
+		rule.Recipients = []string{}
+	}
+	for _, recipient := range rule.Recipients {
+		if !strings.Contains(recipient, "@") {
+			return fmt.Errorf("invalid recipient email: %s", recipient)
+		}
+	}
+	return nil
+}
+
+func (s *weatherAlertService) CreateRule(ctx context.Context, rule *models.WeatherAlertRule) error {
+	if err := validateWeatherRule(rule); err != nil {
+		return err
+	}
+	if rule.ID == uuid.Nil {
+		rule.ID = uuid.New()
+	}
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+	return s.weatherRepo.CreateRule(ctx, rule)
+}
+
+func (s *weatherAlertService) GetRule(ctx context.Context, tenantID, id uuid.UUID) (*models.WeatherAlertRule, error) {
+	return s.weatherRepo.GetRule(ctx, tenantID, id)
+}
+
+func (s *weatherAlertService) UpdateRule(ctx context.Context, rule *models.WeatherAlertRule) error {
+	if err := validateWeatherRule(rule); err != nil {
+		return err
+	}
+	rule.UpdatedAt = time.Now()
+	return s.weatherRepo.UpdateRule(ctx, rule)
+}
+
+func (s *weatherAlertService) DeleteRule(ctx context.Context, tenantID, id uuid.UUID) error {
+	return s.weatherRepo.DeleteRule(ctx, tenantID, id)
+}
+
+func (s *weatherAlertService) ListRules(ctx context.Context, tenantID uuid.UUID) ([]*models.WeatherAlertRule, error) {
+	return s.weatherRepo.ListRules(ctx, tenantID, false)
+}
+
+func (s *weatherAlertService) ListEvents(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WeatherAlertEvent, error) {
+	return s.weatherRepo.ListEvents(ctx, tenantID, limit, offset)
+}
+
+func (s *weatherAlertService) GetWarehouseForecast(ctx context.Context, tenantID, warehouseID uuid.UUID) ([]models.DailyForecast, error) {
+	warehouse, err := s.warehouseRepo.GetByID(ctx, tenantID, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("warehouse not found: %w", err)
+	}
+	if warehouse.Latitude == nil || warehouse.Longitude == nil {
+		return nil, fmt.Errorf("warehouse has no location")
+	}
+	return s.forecast(ctx, *warehouse.Latitude, *warehouse.Longitude)
+}
+
+// forecast returns the location's forecast, cached for an hour since forecasts
+// change slowly and several warehouses often share a location
+func (s *weatherAlertService) forecast(ctx context.Context, latitude, longitude float64) ([]models.DailyForecast, error) {
+	cacheKey := fmt.Sprintf("weather:forecast:%.2f:%.2f", latitude, longitude)
+	if cached, err := s.cacheService.GetString(ctx, cacheKey); err == nil && cached != "" {
+		var forecasts []models.DailyForecast
+		if err := json.Unmarshal([]byte(cached), &forecasts); err == nil {
+			return forecasts, nil
+		}
+	}
+
+	forecasts, err := s.client.DailyForecast(ctx, latitude, longitude, forecastDays)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(forecasts); err == nil {
+		if cacheErr := s.cacheService.SetString(ctx, cacheKey, string(data), time.Hour); cacheErr != nil {
+			fmt.Printf("Failed to cache weather forecast: %v\n", cacheErr)
+		}
+	}
+	return forecasts, nil
+}
+
+// CheckTenant evaluates the tenant's enabled rules against the forecast of every
+// warehouse with a location and dispatches an alert for each new match
+func (s *weatherAlertService) CheckTenant(ctx context.Context, tenantID uuid.UUID) ([]*models.WeatherAlertEvent, error) {
+	rules, err := s.weatherRepo.ListRules(ctx, tenantID, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	warehouses, err := s.warehouseRepo.List(ctx, tenantID, 1000, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var raised []*models.WeatherAlertEvent
+	for _, warehouse := range warehouses {
+		if warehouse.Latitude == nil || warehouse.Longitude == nil {
+			continue
+		}
+
+		forecasts, err := s.forecast(ctx, *warehouse.Latitude, *warehouse.Longitude)
+		if err != nil {
+			fmt.Printf("Failed to fetch forecast for warehouse %s: %v\n", warehouse.ID.String(), err)
+			continue
+		}
+
+		for _, rule := range rules {
+			day, value, ok := MatchWeatherRule(rule, forecasts, time.Now())
+			if !ok {
+				continue
+			}
+
+			event, err := s.raise(ctx, rule, warehouse, day, value)
+			if err != nil {
+				fmt.Printf("Failed to raise weather alert for rule %s: %v\n", rule.ID.String(), err)
+				continue
+			}
+			if event != nil {
+				raised = append(raised, event)
+			}
+		}
+	}
+	return raised, nil
+}
+
+// raise records the alert and notifies recipients; it returns nil when the
+// alert was already raised for this rule, warehouse and day
+func (s *weatherAlertService) raise(ctx context.Context, rule *models.WeatherAlertRule, warehouse *models.Warehouse, day models.DailyForecast, value float64) (*models.WeatherAlertEvent, error) {
+	stock, err := s.weatherRepo.StockAtRisk(ctx, rule.TenantID, warehouse.ID, rule.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	units := 0
+	for _, item := range stock {
+		units += item.Quantity
+	}
+
+	message := fmt.Sprintf("%s: %s forecast for %s on %s (%.1f). %s",
+		rule.Name, strings.ReplaceAll(rule.Condition, "_", " "), warehouse.Name, day.Date.Format("02 Jan 2006"), value, rule.Message)
+	if len(stock) > 0 {
+		message += fmt.Sprintf(" %d products (%d units) in stock.", len(stock), units)
+	}
+
+	event := &models.WeatherAlertEvent{
+		ID:            uuid.New(),
+		TenantID:      rule.TenantID,
+		RuleID:        rule.ID,
+		WarehouseID:   warehouse.ID,
+		ForecastDate:  day.Date,
+		ObservedValue: value,
+		Message:       message,
+		CreatedAt:     time.Now(),
+	}
+	created, err := s.weatherRepo.RecordEvent(ctx, event)
+	if err != nil || !created {
+		return nil, err
+	}
+
+	data := models.WeatherAlertData{
+		RuleID:         rule.ID.String(),
+		Condition:      rule.Condition,
+		WarehouseID:    warehouse.ID.String(),
+		WarehouseName:  warehouse.Name,
+		ForecastDate:   day.Date.Format("2006-01-02"),
+		ObservedValue:  value,
+		Threshold:      rule.Threshold,
+		ProductsAtRisk: len(stock),
+		UnitsAtRisk:    units,
+	}
+	alert := &models.Alert{
+		TenantID:  rule.TenantID.String(),
+		AlertType: models.AlertTypeWeather,
+		EventID:   event.ID.String(),
+		Message:   message,
+		Data: models.JSONB{
+			"rule_id":          data.RuleID,
+			"condition":        data.Condition,
+			"warehouse_id":     data.WarehouseID,
+			"warehouse_name":   data.WarehouseName,
+			"forecast_date":    data.ForecastDate,
+			"observed_value":   data.ObservedValue,
+			"threshold":        data.Threshold,
+			"products_at_risk": data.ProductsAtRisk,
+			"units_at_risk":    data.UnitsAtRisk,
+		},
+		Severity: rule.Severity,
+		Status:   "pending",
+	}
+	if err := s.notificationSvc.CreateAlert(ctx, rule.TenantID, alert); err != nil {
+		fmt.Printf("Failed to create weather alert: %v\n", err)
+	}
+
+	subject := fmt.Sprintf("[%s] Weather alert for %s", strings.ToUpper(rule.Severity), warehouse.Name)
+	for _, recipient := range rule.Recipients {
+		if err := s.notificationSvc.SendEmail(ctx, rule.TenantID, recipient, subject, message); err != nil {
+			fmt.Printf("Failed to email weather alert to %s: %v\n", recipient, err)
+		}
+	}
+
+	return event, nil
+}
+
+// MatchWeatherRule returns the first forecast day within the rule's lookahead
+// window that crosses its threshold, along with the forecast value
+func MatchWeatherRule(rule *models.WeatherAlertRule, forecasts []models.DailyForecast, now time.Time) (models.DailyForecast, float64, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	last := today.AddDate(0, 0, rule.LookaheadDays)
+
+	for _, day := range forecasts {
+		date := time.Date(day.Date.Year(), day.Date.Month(), day.Date.Day(), 0, 0, 0, 0, time.UTC)
+		if date.Before(today) || !date.Before(last) {
+			continue
+		}
+
+		switch rule.Condition {
+		case models.WeatherConditionHeavyRain:
+			if day.PrecipitationMM >= rule.Threshold {
+				return day, day.PrecipitationMM, true
+			}
+		case models.WeatherConditionHeatWave:
+			if day.TempMaxC >= rule.Threshold {
+				return day, day.TempMaxC, true
+			}
+		case models.WeatherConditionColdWave:
+			if day.TempMinC <= rule.Threshold {
+				return day, day.TempMinC, true
+			}
+		case models.WeatherConditionHighWind:
+			if day.WindMaxKmh >= rule.Threshold {
+				return day, day.WindMaxKmh, true
+			}
+		}
+	}
+	return models.DailyForecast{}, 0, false
+}
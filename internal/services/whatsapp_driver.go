@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+)
+
+// WhatsApp providers selectable through WhatsAppConfig.Provider
+const (
+	WhatsAppProviderMeta    = "meta"
+	WhatsAppProviderGupshup = "gupshup"
+)
+
+const (
+	metaGraphURL   = "https://graph.facebook.com/v19.0"
+	gupshupBaseURL = "https://api.gupshup.io/wa/api/v1"
+)
+
+// WhatsAppConfig selects and authenticates the WhatsApp Business provider
+type WhatsAppConfig struct {
+	// Provider is "meta" for the Cloud API or "gupshup"; empty only logs messages
+	Provider string
+	// APIToken is the Meta access token or the Gupshup API key
+	APIToken string
+	// PhoneNumberID is the Meta business phone number ID
+	PhoneNumberID string
+	// SourceNumber and AppName identify the Gupshup sender
+	SourceNumber string
+	AppName      string
+	// AppSecret signs Meta webhooks (X-Hub-Signature-256)
+	AppSecret string
+	// VerifyToken answers Meta's subscription check and is the token query
+	// parameter on the Gupshup callback URL
+	VerifyToken string
+}
+
+// WhatsAppDriver sends approved template messages through a provider and
+// understands its delivery receipt callbacks
+type WhatsAppDriver interface {
+	// SendTemplate sends a template with its body parameters in order and
+	// returns the provider's message ID
+	SendTemplate(ctx context.Context, phone string, template *models.WhatsAppTemplate, params []string) (string, error)
+	// VerifyWebhook authenticates a provider callback
+	VerifyWebhook(header http.Header, query url.Values, body []byte) bool
+	// ParseWebhook extracts delivery receipts and inbound texts from a callback
+	ParseWebhook(body []byte) (*models.WhatsAppWebhook, error)
+}
+
+// NewWhatsAppDriver creates the driver for the configured provider
+func NewWhatsAppDriver(cfg WhatsAppConfig) (WhatsAppDriver, error) {
+	httpClient := &http.Client{Timeout: 15 * time.Second}
+	switch cfg.Provider {
+	case "":
+		return NewLogWhatsAppDriver(), nil
+	case WhatsAppProviderMeta:
+		if cfg.APIToken == "" || cfg.PhoneNumberID == "" {
+			return nil, fmt.Errorf("Meta WhatsApp requires an access token and phone number ID")
+		}
+		return &metaWhatsAppDriver{cfg: cfg, baseURL: metaGraphURL, httpClient: httpClient}, nil
+	case WhatsAppProviderGupshup:
+		if cfg.APIToken == "" || cfg.SourceNumber == "" {
+			return nil, fmt.Errorf("Gupshup WhatsApp requires an API key and source number")
+		}
+		return &gupshupWhatsAppDriver{cfg: cfg, baseURL: gupshupBaseURL, httpClient: httpClient}, nil
+	default:
+		return nil, fmt.Errorf("unknown WhatsApp provider %q", cfg.Provider)
+	}
+}
+
+// metaWhatsAppDriver sends through the WhatsApp Cloud API
+type metaWhatsAppDriver struct {
+	cfg        WhatsAppConfig
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (d *metaWhatsAppDriver) SendTemplate(ctx context.Context, phone string, template *models.WhatsAppTemplate, params []string) (string, error) {
+	parameters := make([]map[string]string, 0, len(params))
+	for _, param := range params {
+		parameters = append(parameters, map[string]string{"type": "text", "text": param})
+	}
+	tmpl := map[string]interface{}{
+		"name":     template.Name,
+		"language": map[string]string{"code": template.Language},
+	}
+	if len(parameters) > 0 {
+		tmpl["components"] = []map[string]interface{}{{"type": "body", "parameters": parameters}}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                phone,
+		"type":              "template",
+		"template":          tmpl,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal WhatsApp message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s/messages", d.baseURL, d.cfg.PhoneNumberID), bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create WhatsApp request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+d.cfg.APIToken)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("WhatsApp request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var parsed struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+		Error struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(respBody, &parsed)
+	if resp.StatusCode != http.StatusOK {
+		if parsed.Error.Message != "" {
+			return "", fmt.Errorf("WhatsApp returned status %d: %s (code %d)", resp.StatusCode, parsed.Error.Message, parsed.Error.Code)
+		}
+		return "", fmt.Errorf("WhatsApp returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if len(parsed.Messages) == 0 || parsed.Messages[0].ID == "" {
+		return "", fmt.Errorf("WhatsApp response did not include a message ID")
+	}
+	return parsed.Messages[0].ID, nil
+}
+
+// VerifyWebhook checks the X-Hub-Signature-256 HMAC Meta computes over the
+// raw body with the app secret
+func (d *metaWhatsAppDriver) VerifyWebhook(header http.Header, query url.Values, body []byte) bool {
+	signature := strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+	if d.cfg.AppSecret == "" || signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(d.cfg.AppSecret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+func (d *metaWhatsAppDriver) ParseWebhook(body []byte) (*models.WhatsAppWebhook, error) {
+	var payload struct {
+		Entry []struct {
+			Changes []struct {
+				Value struct {
+					Statuses []struct {
+						ID        string `json:"id"`
+						Status    string `json:"status"`
+						Timestamp string `json:"timestamp"`
+						Errors    []struct {
+							Code  int    `json:"code"`
+							Title string `json:"title"`
+						} `json:"errors"`
+					} `json:"statuses"`
+					Messages []struct {
+						From string `json:"from"`
+						Type string `json:"type"`
+						Text struct {
+							Body string `json:"body"`
+						} `json:"text"`
+						Button struct {
+							Text string `json:"text"`
+						} `json:"button"`
+					} `json:"messages"`
+				} `json:"value"`
+			} `json:"changes"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid WhatsApp webhook payload: %v", err)
+	}
+
+	webhook := &models.WhatsAppWebhook{}
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			for _, status := range change.Value.Statuses {
+				receipt := models.WhatsAppReceipt{
+					ProviderMessageID: status.ID,
+					Status:            status.Status,
+					Timestamp:         unixTimestamp(status.Timestamp),
+				}
+				if len(status.Errors) > 0 {
+					receipt.Error = fmt.Sprintf("%s (code %d)", status.Errors[0].Title, status.Errors[0].Code)
+				}
+				if isWhatsAppStatus(receipt.Status) && receipt.ProviderMessageID != "" {
+					webhook.Receipts = append(webhook.Receipts, receipt)
+				}
+			}
+			for _, message := range change.Value.Messages {
+				text := message.Text.Body
+				if message.Type == "button" {
+					text = message.Button.Text
+				}
+				if text != "" {
+					webhook.Inbound = append(webhook.Inbound, models.WhatsAppInbound{Phone: message.From, Text: text})
+				}
+			}
+		}
+	}
+	return webhook, nil
+}
+
+// gupshupWhatsAppDriver sends through the Gupshup WhatsApp API
+type gupshupWhatsAppDriver struct {
+	cfg        WhatsAppConfig
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (d *gupshupWhatsAppDriver) SendTemplate(ctx context.Context, phone string, template *models.WhatsAppTemplate, params []string) (string, error) {
+	if params == nil {
+		params = []string{}
+	}
+	tmpl, err := json.Marshal(map[string]interface{}{"id": template.Name, "params": params})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal WhatsApp template: %v", err)
+	}
+	form := url.Values{
+		"channel":     {"whatsapp"},
+		"source":      {d.cfg.SourceNumber},
+		"destination": {phone},
+		"template":    {string(tmpl)},
+	}
+	if d.cfg.AppName != "" {
+		form.Set("src.name", d.cfg.AppName)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.baseURL+"/template/msg", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create WhatsApp request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", d.cfg.APIToken)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("WhatsApp request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var parsed struct {
+		Status    string `json:"status"`
+		MessageID string `json:"messageId"`
+		Message   string `json:"message"`
+	}
+	_ = json.Unmarshal(respBody, &parsed)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || parsed.Status == "error" {
+		return "", fmt.Errorf("Gupshup returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if parsed.MessageID == "" {
+		return "", fmt.Errorf("Gupshup response did not include a message ID")
+	}
+	return parsed.MessageID, nil
+}
+
+// VerifyWebhook compares the token query parameter configured on the Gupshup
+// callback URL, as Gupshup does not sign callbacks
+func (d *gupshupWhatsAppDriver) VerifyWebhook(header http.Header, query url.Values, body []byte) bool {
+	token := query.Get("token")
+	if d.cfg.VerifyToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(d.cfg.VerifyToken)) == 1
+}
+
+func (d *gupshupWhatsAppDriver) ParseWebhook(body []byte) (*models.WhatsAppWebhook, error) {
+	var payload struct {
+		Type      string `json:"type"`
+		Timestamp int64  `json:"timestamp"`
+		Payload   struct {
+			ID      string `json:"id"`
+			GsID    string `json:"gsId"`
+			Type    string `json:"type"`
+			Source  string `json:"source"`
+			Payload struct {
+				Text   string `json:"text"`
+				Reason string `json:"reason"`
+			} `json:"payload"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid WhatsApp webhook payload: %v", err)
+	}
+
+	webhook := &models.WhatsAppWebhook{}
+	switch payload.Type {
+	case "message-event":
+		// The send response returned the Gupshup ID; later events carry it as gsId
+		messageID := payload.Payload.GsID
+		if messageID == "" {
+			messageID = payload.Payload.ID
+		}
+		receipt := models.WhatsAppReceipt{
+			ProviderMessageID: messageID,
+			Status:            payload.Payload.Type,
+			Error:             payload.Payload.Payload.Reason,
+			Timestamp:         time.UnixMilli(payload.Timestamp),
+		}
+		if payload.Timestamp == 0 {
+			receipt.Timestamp = time.Now()
+		}
+		if isWhatsAppStatus(receipt.Status) && messageID != "" {
+			webhook.Receipts = append(webhook.Receipts, receipt)
+		}
+	case "message":
+		if payload.Payload.Type == "text" && payload.Payload.Payload.Text != "" {
+			webhook.Inbound = append(webhook.Inbound, models.WhatsAppInbound{Phone: payload.Payload.Source, Text: payload.Payload.Payload.Text})
+		}
+	}
+	return webhook, nil
+}
+
+func isWhatsAppStatus(status string) bool {
+	switch status {
+	case models.WhatsAppStatusSent, models.WhatsAppStatusDelivered, models.WhatsAppStatusRead, models.WhatsAppStatusFailed:
+		return true
+	}
+	return false
+}
+
+func unixTimestamp(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds == 0 {
+		return time.Now()
+	}
+	return time.Unix(seconds, 0)
+}
+
+// logWhatsAppDriver logs messages when no provider is configured, like the
+// email and SMS placeholders
+type logWhatsAppDriver struct{}
+
+// NewLogWhatsAppDriver creates a driver that only logs messages
+func NewLogWhatsAppDriver() WhatsAppDriver {
+	return logWhatsAppDriver{}
+}
+
+func (logWhatsAppDriver) SendTemplate(ctx context.Context, phone string, template *models.WhatsAppTemplate, params []string) (string, error) {
+	log.Printf("[WHATSAPP] To=%s, Template=%s (%s), Params=%v", phone, template.Name, template.Language, params)
+	return "", nil
+}
+
+func (logWhatsAppDriver) VerifyWebhook(header http.Header, query url.Values, body []byte) bool {
+	return false
+}
+
+func (logWhatsAppDriver) ParseWebhook(body []byte) (*models.WhatsAppWebhook, error) {
+	return &models.WhatsAppWebhook{}, nil
+}
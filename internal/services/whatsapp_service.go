@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrWhatsAppNotOptedIn means the contact has not agreed to receive
+	// business-initiated messages, so nothing was sent
+	ErrWhatsAppNotOptedIn = errors.New("contact has not opted in to WhatsApp messages")
+	// ErrWhatsAppNoTemplate means the tenant has no active template for the event
+	ErrWhatsAppNoTemplate = errors.New("no active WhatsApp template for event")
+	// ErrWhatsAppWebhookUnauthorized means a callback failed provider verification
+	ErrWhatsAppWebhookUnauthorized = errors.New("WhatsApp webhook verification failed")
+)
+
+// Reply keywords that withdraw or give consent
+var (
+	whatsAppOptOutKeywords = map[string]bool{"STOP": true, "UNSUBSCRIBE": true, "STOP ALL": true}
+	whatsAppOptInKeywords  = map[string]bool{"START": true, "SUBSCRIBE": true}
+)
+
+// WhatsAppService sends WhatsApp Business template messages to opted-in
+// contacts and tracks their delivery
+type WhatsAppService interface {
+	CreateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.WhatsAppTemplate) error
+	UpdateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.WhatsAppTemplate) error
+	DeleteTemplate(ctx context.Context, tenantID, templateID uuid.UUID) error
+	ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.WhatsAppTemplate, error)
+
+	SetContactOptIn(ctx context.Context, tenantID uuid.UUID, contact *models.WhatsAppContact) error
+	ListContacts(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppContact, error)
+
+	// Send sends the event's template; the message is logged even when the
+	// provider rejects it
+	Send(ctx context.Context, tenantID uuid.UUID, req *models.WhatsAppSend) (*models.WhatsAppMessage, error)
+	ListMessages(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppMessage, error)
+
+	// VerifySubscription answers the provider's webhook subscription check
+	VerifySubscription(mode, token string) bool
+	// HandleWebhook applies delivery receipts and STOP/START replies
+	HandleWebhook(ctx context.Context, header http.Header, query url.Values, body []byte) error
+}
+
+type whatsAppService struct {
+	repo            repositories.WhatsAppRepository
+	distributorRepo repositories.DistributorRepository
+	driver          WhatsAppDriver
+	verifyToken     string
+}
+
+// NewWhatsAppService creates a new WhatsApp notification service
+func NewWhatsAppService(repo repositories.WhatsAppRepository, distributorRepo repositories.DistributorRepository, driver WhatsAppDriver, verifyToken string) WhatsAppService {
+	return &whatsAppService{
+		repo:            repo,
+		distributorRepo: distributorRepo,
+		driver:          driver,
+		verifyToken:     verifyToken,
+	}
+}
+
+func (s *whatsAppService) CreateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.WhatsAppTemplate) error {
+	if err := validateWhatsAppTemplate(template); err != nil {
+		return err
+	}
+	template.ID = uuid.New()
+	template.TenantID = tenantID
+	return s.repo.CreateTemplate(ctx, template)
+}
+
+func (s *whatsAppService) UpdateTemplate(ctx context.Context, tenantID uuid.UUID, template *models.WhatsAppTemplate) error {
+	if err := validateWhatsAppTemplate(template); err != nil {
+		return err
+	}
+	template.TenantID = tenantID
+	updated, err := s.repo.UpdateTemplate(ctx, template)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("template not found")
+	}
+	return nil
+}
+
+func (s *whatsAppService) DeleteTemplate(ctx context.Context, tenantID, templateID uuid.UUID) error {
+	deleted, err := s.repo.DeleteTemplate(ctx, tenantID, templateID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("template not found")
+	}
+	return nil
+}
+
+func (s *whatsAppService) ListTemplates(ctx context.Context, tenantID uuid.UUID) ([]*models.WhatsAppTemplate, error) {
+	return s.repo.ListTemplates(ctx, tenantID)
+}
+
+func validateWhatsAppTemplate(template *models.WhatsAppTemplate) error {
+	template.EventType = strings.TrimSpace(template.EventType)
+	template.Name = strings.TrimSpace(template.Name)
+	if template.EventType == "" || template.Name == "" {
+		return fmt.Errorf("event_type and name are required")
+	}
+	if template.Language == "" {
+		template.Language = "en"
+	}
+	switch template.Category {
+	case "":
+		template.Category = models.WhatsAppCategoryUtility
+	case models.WhatsAppCategoryUtility, models.WhatsAppCategoryMarketing, models.WhatsAppCategoryAuthentication:
+	default:
+		return fmt.Errorf("category must be utility, marketing or authentication")
+	}
+	if template.Parameters == nil {
+		template.Parameters = []string{}
+	}
+	for _, param := range template.Parameters {
+		if strings.TrimSpace(param) == "" {
+			return fmt.Errorf("template parameters must be named")
+		}
+	}
+	return nil
+}
+
+func (s *whatsAppService) SetContactOptIn(ctx context.Context, tenantID uuid.UUID, contact *models.WhatsAppContact) error {
+	phone, err := normalizeWhatsAppPhone(contact.Phone)
+	if err != nil {
+		return err
+	}
+	contact.TenantID = tenantID
+	contact.Phone = phone
+	return s.repo.UpsertContact(ctx, contact)
+}
+
+func (s *whatsAppService) ListContacts(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppContact, error) {
+	return s.repo.ListContacts(ctx, tenantID, limit, offset)
+}
+
+func (s *whatsAppService) Send(ctx context.Context, tenantID uuid.UUID, req *models.WhatsAppSend) (*models.WhatsAppMessage, error) {
+	params := make(map[string]string, len(req.Params)+1)
+	for key, value := range req.Params {
+		params[key] = value
+	}
+
+	rawPhone := req.Phone
+	if rawPhone == "" && req.DistributorID != nil {
+		distributor, err := s.distributorRepo.GetByID(ctx, tenantID, *req.DistributorID)
+		if err != nil || distributor == nil {
+			return nil, fmt.Errorf("distributor not found")
+		}
+		if distributor.ContactPhone == nil || *distributor.ContactPhone == "" {
+			return nil, fmt.Errorf("distributor has no contact phone")
+		}
+		rawPhone = *distributor.ContactPhone
+		if _, ok := params["distributor_name"]; !ok {
+			params["distributor_name"] = distributor.Name
+		}
+	}
+	phone, err := normalizeWhatsAppPhone(rawPhone)
+	if err != nil {
+		return nil, err
+	}
+
+	contact, err := s.repo.GetContact(ctx, tenantID, phone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check WhatsApp opt-in: %w", err)
+	}
+	if contact == nil || !contact.OptedIn {
+		return nil, ErrWhatsAppNotOptedIn
+	}
+
+	template, err := s.repo.GetActiveTemplate(ctx, tenantID, req.EventType, req.Language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load WhatsApp template: %w", err)
+	}
+	if template == nil {
+		return nil, fmt.Errorf("%w %q", ErrWhatsAppNoTemplate, req.EventType)
+	}
+	values := make([]string, 0, len(template.Parameters))
+	for _, name := range template.Parameters {
+		value := params[name]
+		if value == "" {
+			return nil, fmt.Errorf("missing value for template parameter %q", name)
+		}
+		values = append(values, value)
+	}
+
+	message := &models.WhatsAppMessage{
+		ID:         uuid.New(),
+		TenantID:   tenantID,
+		Phone:      phone,
+		TemplateID: &template.ID,
+		EventType:  req.EventType,
+		Status:     models.WhatsAppStatusSent,
+	}
+	if req.EventID != "" {
+		message.EventID = &req.EventID
+	}
+	providerID, sendErr := s.driver.SendTemplate(ctx, phone, template, values)
+	if sendErr != nil {
+		errMsg := sendErr.Error()
+		message.Status = models.WhatsAppStatusFailed
+		message.ErrorMessage = &errMsg
+	} else if providerID != "" {
+		message.ProviderMessageID = &providerID
+	}
+
+	if err := s.repo.CreateMessage(ctx, message); err != nil {
+		log.Printf("Failed to log WhatsApp message %s: %v", message.ID, err)
+	}
+	if sendErr != nil {
+		return message, fmt.Errorf("failed to send WhatsApp message: %w", sendErr)
+	}
+	return message, nil
+}
+
+func (s *whatsAppService) ListMessages(ctx context.Context, tenantID uuid.UUID, limit, offset int) ([]*models.WhatsAppMessage, error) {
+	return s.repo.ListMessages(ctx, tenantID, limit, offset)
+}
+
+func (s *whatsAppService) VerifySubscription(mode, token string) bool {
+	if mode != "subscribe" || s.verifyToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.verifyToken)) == 1
+}
+
+func (s *whatsAppService) HandleWebhook(ctx context.Context, header http.Header, query url.Values, body []byte) error {
+	if !s.driver.VerifyWebhook(header, query, body) {
+		return ErrWhatsAppWebhookUnauthorized
+	}
+	webhook, err := s.driver.ParseWebhook(body)
+	if err != nil {
+		return err
+	}
+
+	for i := range webhook.Receipts {
+		if _, err := s.repo.ApplyReceipt(ctx, &webhook.Receipts[i]); err != nil {
+			log.Printf("Failed to apply WhatsApp receipt for %s: %v", webhook.Receipts[i].ProviderMessageID, err)
+		}
+	}
+
+	for _, inbound := range webhook.Inbound {
+		keyword := strings.ToUpper(strings.TrimSpace(inbound.Text))
+		optOut, optIn := whatsAppOptOutKeywords[keyword], whatsAppOptInKeywords[keyword]
+		if !optOut && !optIn {
+			continue
+		}
+		phone, err := normalizeWhatsAppPhone(inbound.Phone)
+		if err != nil {
+			continue
+		}
+		if _, err := s.repo.SetOptInByPhone(ctx, phone, optIn, "whatsapp_reply"); err != nil {
+			log.Printf("Failed to apply WhatsApp %s reply from %s: %v", keyword, phone, err)
+		}
+	}
+	return nil
+}
+
+// normalizeWhatsAppPhone reduces a phone number to E.164 digits without the
+// +, the form providers report. Ten-digit numbers are taken as Indian mobiles
+func normalizeWhatsAppPhone(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+") || strings.HasPrefix(raw, "00")
+	var b strings.Builder
+	for _, r := range raw {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if strings.HasPrefix(raw, "00") {
+		digits = digits[2:]
+	}
+	if !international {
+		switch {
+		case len(digits) == 10:
+			digits = "91" + digits
+		case len(digits) == 11 && digits[0] == '0':
+			digits = "91" + digits[1:]
+		}
+	}
+	if len(digits) < 11 || len(digits) > 15 || digits[0] == '0' {
+		return "", fmt.Errorf("invalid phone number %q", raw)
+	}
+	return digits, nil
+}
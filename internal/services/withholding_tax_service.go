@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTaxSectionNotFound is returned for TDS/TCS sections outside the tenant
+	ErrTaxSectionNotFound = errors.New("tax section not found")
+	// ErrInvalidWithholding wraps TDS/TCS validation failures
+	ErrInvalidWithholding = errors.New("invalid TDS/TCS details")
+	// ErrTaxSectionConflict is returned for duplicate sections and for a
+	// second active TCS section
+	ErrTaxSectionConflict = errors.New("tax section conflicts with an existing one")
+)
+
+// maxWithholdingRate bounds section rates; no TDS/TCS section goes beyond it
+const maxWithholdingRate = 30.0
+
+// WithholdingTaxService configures the TDS/TCS sections a tenant withholds
+// under, records TDS certificates received from customers and summarises
+// TDS/TCS per quarter for returns. TCS itself is added by the invoice service
+// and TDS recorded with customer payments
+type WithholdingTaxService interface {
+	ListSections(ctx context.Context, tenantID uuid.UUID, kind string) ([]*models.TaxSection, error)
+	CreateSection(ctx context.Context, tenantID uuid.UUID, req *models.TaxSectionRequest) (*models.TaxSection, error)
+	UpdateSection(ctx context.Context, tenantID, sectionID uuid.UUID, req *models.TaxSectionRequest) (*models.TaxSection, error)
+
+	InvoiceTCS(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceTCS, error)
+	RecordTDSCertificate(ctx context.Context, tenantID, paymentID uuid.UUID, req *models.TDSCertificateRequest) (*models.CustomerPayment, error)
+	// QuarterlySummary totals TDS and TCS for a quarter of the financial year
+	// starting in April of financialYear; zero for both means the current quarter
+	QuarterlySummary(ctx context.Context, tenantID uuid.UUID, financialYear, quarter int) (*models.WithholdingSummary, error)
+}
+
+type withholdingTaxService struct {
+	repo        repositories.WithholdingTaxRepository
+	paymentRepo repositories.PaymentAllocationRepository
+}
+
+// NewWithholdingTaxService creates a new TDS/TCS service
+func NewWithholdingTaxService(repo repositories.WithholdingTaxRepository, paymentRepo repositories.PaymentAllocationRepository) WithholdingTaxService {
+	return &withholdingTaxService{
+		repo:        repo,
+		paymentRepo: paymentRepo,
+	}
+}
+
+func (s *withholdingTaxService) ListSections(ctx context.Context, tenantID uuid.UUID, kind string) ([]*models.TaxSection, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind != "" && kind != models.WithholdingKindTDS && kind != models.WithholdingKindTCS {
+		return nil, fmt.Errorf("%w: kind must be tds or tcs", ErrInvalidWithholding)
+	}
+	return s.repo.ListSections(ctx, tenantID, kind)
+}
+
+func (s *withholdingTaxService) CreateSection(ctx context.Context, tenantID uuid.UUID, req *models.TaxSectionRequest) (*models.TaxSection, error) {
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+	if kind != models.WithholdingKindTDS && kind != models.WithholdingKindTCS {
+		return nil, fmt.Errorf("%w: kind must be tds or tcs", ErrInvalidWithholding)
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Section))
+	if code == "" || len(code) > 20 {
+		return nil, fmt.Errorf("%w: section is required and must be at most 20 characters", ErrInvalidWithholding)
+	}
+	if req.RatePercent == nil {
+		return nil, fmt.Errorf("%w: rate_percent is required", ErrInvalidWithholding)
+	}
+
+	section := &models.TaxSection{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Kind:        kind,
+		Section:     code,
+		Description: trimmedOrNil(req.Description),
+		RatePercent: *req.RatePercent,
+		Active:      true,
+	}
+	if req.ThresholdAmount != nil {
+		section.ThresholdAmount = *req.ThresholdAmount
+	}
+	if req.Active != nil {
+		section.Active = *req.Active
+	}
+	if err := validateTaxSection(section); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.CreateSection(ctx, section)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tax section: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s already exists or another TCS section is active", ErrTaxSectionConflict, strings.ToUpper(kind), code)
+	}
+	return section, nil
+}
+
+// UpdateSection changes a section's rate, threshold, description or whether
+// it is active; its kind and code stay as they are
+func (s *withholdingTaxService) UpdateSection(ctx context.Context, tenantID, sectionID uuid.UUID, req *models.TaxSectionRequest) (*models.TaxSection, error) {
+	section, err := s.repo.GetSection(ctx, tenantID, sectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tax section: %w", err)
+	}
+	if section == nil {
+		return nil, ErrTaxSectionNotFound
+	}
+
+	if req.Description != nil {
+		section.Description = trimmedOrNil(req.Description)
+	}
+	if req.RatePercent != nil {
+		section.RatePercent = *req.RatePercent
+	}
+	if req.ThresholdAmount != nil {
+		section.ThresholdAmount = *req.ThresholdAmount
+	}
+	if req.Active != nil {
+		section.Active = *req.Active
+	}
+	if err := validateTaxSection(section); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.UpdateSection(ctx, section)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tax section: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: another TCS section is active; deactivate it first", ErrTaxSectionConflict)
+	}
+	return section, nil
+}
+
+func (s *withholdingTaxService) InvoiceTCS(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.InvoiceTCS, error) {
+	return s.repo.GetInvoiceTCS(ctx, tenantID, invoiceID)
+}
+
+func (s *withholdingTaxService) RecordTDSCertificate(ctx context.Context, tenantID, paymentID uuid.UUID, req *models.TDSCertificateRequest) (*models.CustomerPayment, error) {
+	number := strings.TrimSpace(req.CertificateNumber)
+	if number == "" || len(number) > 50 {
+		return nil, fmt.Errorf("%w: certificate_number is required and must be at most 50 characters", ErrInvalidWithholding)
+	}
+	date := time.Now()
+	if req.CertificateDate != nil && *req.CertificateDate != "" {
+		parsed, err := time.Parse("2006-01-02", *req.CertificateDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: certificate_date must be in YYYY-MM-DD format", ErrInvalidWithholding)
+		}
+		date = parsed
+	}
+
+	payment, err := s.paymentRepo.GetPayment(ctx, tenantID, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payment: %w", err)
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.TDSAmount <= 0 {
+		return nil, fmt.Errorf("%w: no TDS was deducted from payment %s", ErrInvalidWithholding, payment.PaymentNumber)
+	}
+
+	ok, err := s.repo.SetTDSCertificate(ctx, tenantID, paymentID, number, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record TDS certificate: %w", err)
+	}
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	payment.TDSCertificateNumber = &number
+	payment.TDSCertificateDate = &date
+	return payment, nil
+}
+
+func (s *withholdingTaxService) QuarterlySummary(ctx context.Context, tenantID uuid.UUID, financialYear, quarter int) (*models.WithholdingSummary, error) {
+	if financialYear == 0 && quarter == 0 {
+		now := time.Now()
+		financialYear = financialYearStart(now).Year()
+		quarter = (int(now.Month())+8)%12/3 + 1
+	}
+	if quarter < 1 || quarter > 4 {
+		return nil, fmt.Errorf("%w: quarter must be between 1 and 4", ErrInvalidWithholding)
+	}
+	if financialYear < 2000 || financialYear > 2100 {
+		return nil, fmt.Errorf("%w: financial_year is out of range", ErrInvalidWithholding)
+	}
+	from, to := financialQuarter(financialYear, quarter)
+
+	tcs, err := s.repo.TCSSummary(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise TCS: %w", err)
+	}
+	tds, err := s.repo.TDSSummary(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise TDS: %w", err)
+	}
+
+	summary := &models.WithholdingSummary{
+		TenantID:      tenantID,
+		FinancialYear: fmt.Sprintf("%d-%02d", financialYear, (financialYear+1)%100),
+		Quarter:       quarter,
+		From:          from,
+		To:            to,
+		TCS:           tcs,
+		TDS:           tds,
+	}
+	if summary.TCS == nil {
+		summary.TCS = []*models.WithholdingSummaryRow{}
+	}
+	if summary.TDS == nil {
+		summary.TDS = []*models.WithholdingSummaryRow{}
+	}
+	for _, row := range tcs {
+		summary.TotalTCS += row.Amount
+	}
+	for _, row := range tds {
+		summary.TotalTDS += row.Amount
+		summary.CertificatesPending += row.CertificatesPending
+	}
+	summary.TotalTCS = roundAllocation(summary.TotalTCS)
+	summary.TotalTDS = roundAllocation(summary.TotalTDS)
+	return summary, nil
+}
+
+func validateTaxSection(section *models.TaxSection) error {
+	if section.RatePercent <= 0 || section.RatePercent > maxWithholdingRate {
+		return fmt.Errorf("%w: rate_percent must be above 0 and at most %.0f", ErrInvalidWithholding, maxWithholdingRate)
+	}
+	if section.ThresholdAmount < 0 {
+		return fmt.Errorf("%w: threshold_amount cannot be negative", ErrInvalidWithholding)
+	}
+	return nil
+}
+
+// financialYearStart is 1 April of the Indian financial year date falls in.
+// TDS and TCS follow this statutory year whatever fiscal year the tenant's
+// calendar reports on
+func financialYearStart(date time.Time) time.Time {
+	year := date.Year()
+	if date.Month() < time.April {
+		year--
+	}
+	return time.Date(year, time.April, 1, 0, 0, 0, 0, date.Location())
+}
+
+// financialQuarter is the first and last day of a quarter of the financial
+// year starting in April of financialYear
+func financialQuarter(financialYear, quarter int) (time.Time, time.Time) {
+	from := time.Date(financialYear, time.April, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 3*(quarter-1), 0)
+	return from, from.AddDate(0, 3, -1)
+}
+
+// tcsOnSale is the TCS on a sale to a customer who has already bought
+// priorSales this financial year: the rate applies only to what the year's
+// sales exceed the section threshold by
+func tcsOnSale(section *models.TaxSection, priorSales, sale float64) (base, amount float64) {
+	base = sale - math.Max(0, section.ThresholdAmount-priorSales)
+	if base <= 0 {
+		return 0, 0
+	}
+	base = roundAllocation(math.Min(base, sale))
+	return base, roundAllocation(base * section.RatePercent / 100)
+}
@@ -0,0 +1,29 @@
+-- Support impersonation sessions and on-behalf-of auditing
+-- Migration: 20250901090000_add_impersonation_sessions.sql
+
+-- Impersonation sessions minted by platform admins acting as tenant users
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    impersonator_id UUID NOT NULL,
+    impersonator_tenant_id UUID NOT NULL,
+    target_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    token_id VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_tenant ON impersonation_sessions(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_impersonator ON impersonation_sessions(impersonator_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_impersonation_sessions_token ON impersonation_sessions(token_id);
+
+-- Record the real actor alongside changed_by for requests made under impersonation
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS impersonator_id UUID NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_impersonator ON audit_logs(tenant_id, impersonator_id) WHERE impersonator_id IS NOT NULL;
+
+-- Permission required to start impersonation sessions
+INSERT INTO permissions (name, description) VALUES
+  ('platform:impersonate', 'Can impersonate tenant users for support')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,23 @@
+-- Persisted asymmetric JWT signing keys with rotation support
+-- Migration: 20250901100000_add_jwt_signing_keys.sql
+
+-- Signing keys are shared by every API instance; the newest active key signs,
+-- retiring keys remain valid for verification until expires_at
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL DEFAULT 'EdDSA',
+    private_key TEXT NOT NULL, -- PKCS#8 PEM
+    public_key TEXT NOT NULL,  -- PKIX PEM
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rotated_at TIMESTAMPTZ NULL,
+    expires_at TIMESTAMPTZ NULL,
+    CONSTRAINT jwt_signing_keys_status_check CHECK (status IN ('active', 'retiring', 'revoked'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_jwt_signing_keys_status ON jwt_signing_keys(status, created_at DESC);
+
+-- Permission required to rotate signing keys
+INSERT INTO permissions (name, description) VALUES
+  ('platform:manage_keys', 'Can rotate JWT signing keys')
+ON CONFLICT (name) DO NOTHING;
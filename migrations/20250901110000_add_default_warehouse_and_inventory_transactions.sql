@@ -0,0 +1,49 @@
+-- Default warehouse per tenant and an inventory transaction ledger
+-- Migration: 20250901110000_add_default_warehouse_and_inventory_transactions.sql
+
+-- Each tenant has at most one default warehouse used when callers don't pick one
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS is_default BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_tenant_default ON warehouses(tenant_id) WHERE is_default;
+
+-- Backfill: the oldest warehouse of each tenant becomes its default
+UPDATE warehouses w
+SET is_default = TRUE
+FROM (
+    SELECT DISTINCT ON (tenant_id) id
+    FROM warehouses
+    ORDER BY tenant_id, created_at ASC
+) oldest
+WHERE w.id = oldest.id
+  AND NOT EXISTS (SELECT 1 FROM warehouses d WHERE d.tenant_id = w.tenant_id AND d.is_default);
+
+-- Ledger of every stock movement so inventory levels can be explained and audited
+CREATE TABLE IF NOT EXISTS inventory_transactions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity_change INTEGER NOT NULL,
+    quantity_after INTEGER NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_transactions_product ON inventory_transactions(tenant_id, product_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_inventory_transactions_warehouse ON inventory_transactions(tenant_id, warehouse_id, created_at DESC);
+
+-- Move product-level stock that never reached a warehouse into the tenant's default warehouse
+INSERT INTO inventory (id, tenant_id, warehouse_id, product_id, quantity, last_updated)
+SELECT gen_random_uuid(), p.tenant_id, w.id, p.id, p.quantity, NOW()
+FROM products p
+JOIN warehouses w ON w.tenant_id = p.tenant_id AND w.is_default
+WHERE p.quantity > 0
+  AND NOT EXISTS (SELECT 1 FROM inventory i WHERE i.tenant_id = p.tenant_id AND i.product_id = p.id)
+ON CONFLICT (tenant_id, warehouse_id, product_id) DO NOTHING;
+
+COMMENT ON COLUMN products.quantity IS 'Deprecated: mirror of the sum of inventory.quantity across warehouses; write stock through inventory';
+
+-- Changing the default warehouse requires warehouse update rights
+INSERT INTO permissions (name, description) VALUES
+  ('warehouses:update', 'Can update warehouses')
+ON CONFLICT (name) DO NOTHING;
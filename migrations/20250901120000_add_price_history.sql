@@ -0,0 +1,16 @@
+-- Price history for every product list price change
+-- Migration: 20250901120000_add_price_history.sql
+
+CREATE TABLE IF NOT EXISTS price_history (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    old_price DECIMAL(10,2) NOT NULL,
+    new_price DECIMAL(10,2) NOT NULL,
+    changed_by UUID NULL,
+    reason TEXT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'bulk', 'import')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_product ON price_history(tenant_id, product_id, created_at DESC);
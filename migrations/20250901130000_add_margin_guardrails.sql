@@ -0,0 +1,37 @@
+-- Product cost, tenant margin policy and margin violation log for sales order pricing
+-- Migration: 20250901130000_add_margin_guardrails.sql
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS cost_price DECIMAL(10,2) NULL CHECK (cost_price >= 0);
+
+-- One policy per tenant; no row means margins are not enforced
+CREATE TABLE IF NOT EXISTS margin_policies (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    min_margin_percent DECIMAL(5,2) NOT NULL CHECK (min_margin_percent >= 0),
+    enforcement VARCHAR(20) NOT NULL DEFAULT 'require_override' CHECK (enforcement IN ('block', 'require_override')),
+    updated_by UUID NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Sales orders priced below cost plus the minimum margin, whether blocked or overridden
+CREATE TABLE IF NOT EXISTS margin_violations (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    order_id UUID NULL,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    unit_price DECIMAL(10,2) NOT NULL,
+    cost_price DECIMAL(10,2) NOT NULL,
+    min_margin_percent DECIMAL(5,2) NOT NULL,
+    margin_percent DECIMAL(7,2) NOT NULL,
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('blocked', 'overridden')),
+    user_id UUID NULL,
+    override_reason TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_margin_violations_tenant ON margin_violations(tenant_id, created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('orders:override_margin', 'Create sales orders priced below the minimum margin'),
+('pricing:manage', 'Manage tenant margin policy'),
+('pricing:read', 'View margin policy and margin violation reports')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,45 @@
+-- Tenant season calendar (sowing seasons, festivals) and product season/crop tags
+-- Migration: 20250901140000_add_season_calendar.sql
+
+-- Seasons recur every year; a window whose end falls before its start wraps into the next year
+CREATE TABLE IF NOT EXISTS seasons (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    season_type VARCHAR(20) NOT NULL CHECK (season_type IN ('kharif', 'rabi', 'zaid', 'festival', 'other')),
+    region VARCHAR(100) NULL,
+    start_month SMALLINT NOT NULL CHECK (start_month BETWEEN 1 AND 12),
+    start_day SMALLINT NOT NULL CHECK (start_day BETWEEN 1 AND 31),
+    end_month SMALLINT NOT NULL CHECK (end_month BETWEEN 1 AND 12),
+    end_day SMALLINT NOT NULL CHECK (end_day BETWEEN 1 AND 31),
+    lead_days INTEGER NOT NULL DEFAULT 30 CHECK (lead_days >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_seasons_tenant ON seasons(tenant_id);
+
+CREATE TABLE IF NOT EXISTS product_seasons (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    season_id UUID NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, season_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_seasons_season ON product_seasons(tenant_id, season_id);
+
+CREATE TABLE IF NOT EXISTS product_crops (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    crop VARCHAR(100) NOT NULL,
+    PRIMARY KEY (product_id, crop)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_crops_crop ON product_crops(tenant_id, crop);
+
+INSERT INTO permissions (name, description) VALUES
+('seasons:read', 'View the season calendar and product season tags'),
+('seasons:manage', 'Manage the season calendar and product season tags'),
+('analytics:read', 'View seasonal demand analytics and stocking suggestions')
+ON CONFLICT (name) DO NOTHING;
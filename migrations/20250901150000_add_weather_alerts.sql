@@ -0,0 +1,45 @@
+-- Warehouse coordinates, tenant weather alert rules and dispatched weather alerts
+-- Migration: 20250901150000_add_weather_alerts.sql
+
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION NULL;
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION NULL;
+
+-- A rule fires when a forecast day within lookahead_days crosses the threshold:
+-- heavy_rain (mm/day, >=), heat_wave (max °C, >=), cold_wave (min °C, <=), high_wind (km/h, >=)
+CREATE TABLE IF NOT EXISTS weather_alert_rules (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    condition VARCHAR(20) NOT NULL CHECK (condition IN ('heavy_rain', 'heat_wave', 'cold_wave', 'high_wind')),
+    threshold DECIMAL(6,2) NOT NULL,
+    lookahead_days SMALLINT NOT NULL DEFAULT 3 CHECK (lookahead_days BETWEEN 1 AND 7),
+    category_id UUID NULL REFERENCES categories(id) ON DELETE SET NULL,
+    message TEXT NOT NULL,
+    severity VARCHAR(20) NOT NULL DEFAULT 'warning' CHECK (severity IN ('info', 'warning', 'critical')),
+    recipients TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_weather_alert_rules_tenant ON weather_alert_rules(tenant_id) WHERE enabled;
+
+-- One alert per rule, warehouse and forecast day so repeated checks do not re-notify
+CREATE TABLE IF NOT EXISTS weather_alert_events (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    rule_id UUID NOT NULL REFERENCES weather_alert_rules(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    forecast_date DATE NOT NULL,
+    observed_value DECIMAL(6,2) NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (rule_id, warehouse_id, forecast_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_weather_alert_events_tenant ON weather_alert_events(tenant_id, created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('weather:read', 'View warehouse weather forecasts and weather alerts'),
+('weather:manage', 'Manage weather alert rules')
+ON CONFLICT (name) DO NOTHING;
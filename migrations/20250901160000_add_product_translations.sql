@@ -0,0 +1,7 @@
+-- Per-locale product catalog text for dealer-facing apps
+-- Migration: 20250901160000_add_product_translations.sql
+
+-- Keyed by lower-case locale code, e.g. {"hi": {"name": "...", "description": "..."}}
+ALTER TABLE products ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_products_translations ON products USING GIN (translations);
@@ -0,0 +1,27 @@
+-- Storefront catalog: product publish flag and per-tenant catalog API tokens
+-- Migration: 20250901170000_add_catalog_publishing.sql
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_published BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_published ON products(tenant_id, name) WHERE is_published;
+
+-- Only a SHA-256 hash of each token is stored; the prefix identifies it in listings
+CREATE TABLE IF NOT EXISTS catalog_tokens (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NULL,
+    revoked_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_tokens_tenant ON catalog_tokens(tenant_id);
+
+INSERT INTO permissions (name, description) VALUES
+('catalog:publish', 'Publish and unpublish products on the storefront catalog'),
+('catalog:manage_tokens', 'Create and revoke storefront catalog API tokens')
+ON CONFLICT (name) DO NOTHING;
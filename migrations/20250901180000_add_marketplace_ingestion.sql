@@ -0,0 +1,43 @@
+-- External marketplace (ONDC / generic) order ingestion and status sync
+-- Migration: 20250901180000_add_marketplace_ingestion.sql
+
+-- A channel is one marketplace integration; its orders are fulfilled from a
+-- warehouse and billed to the distributor representing the marketplace
+CREATE TABLE IF NOT EXISTS marketplace_channels (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    protocol VARCHAR(20) NOT NULL CHECK (protocol IN ('ondc', 'generic')),
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+    distributor_id UUID NOT NULL REFERENCES distributors(id),
+    signing_secret VARCHAR(128) NOT NULL,
+    status_webhook_url TEXT NULL,
+    field_mapping JSONB NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+-- One row per external order line; the unique key makes ingestion idempotent
+CREATE TABLE IF NOT EXISTS marketplace_order_links (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    channel_id UUID NOT NULL REFERENCES marketplace_channels(id) ON DELETE CASCADE,
+    external_order_id VARCHAR(255) NOT NULL,
+    external_item_id VARCHAR(255) NOT NULL,
+    order_id UUID NULL REFERENCES orders(id) ON DELETE SET NULL,
+    external_context JSONB NOT NULL DEFAULT '{}',
+    last_pushed_status VARCHAR(50) NULL,
+    last_pushed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (channel_id, external_order_id, external_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_marketplace_order_links_tenant ON marketplace_order_links(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_marketplace_order_links_order ON marketplace_order_links(order_id);
+
+INSERT INTO permissions (name, description) VALUES
+('marketplace:read', 'View marketplace channels and ingested orders'),
+('marketplace:manage', 'Manage marketplace channels and sync order status')
+ON CONFLICT (name) DO NOTHING;
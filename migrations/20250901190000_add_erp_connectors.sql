@@ -0,0 +1,46 @@
+-- ERP connector framework: per-tenant connectors (Tally XML, Busy CSV, Marg) and sync runs
+-- Migration: 20250901190000_add_erp_connectors.sql
+
+CREATE TABLE IF NOT EXISTS erp_connectors (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    connector_type VARCHAR(30) NOT NULL CHECK (connector_type IN ('tally_xml', 'busy_csv', 'marg')),
+    document_types TEXT[] NOT NULL DEFAULT ARRAY['invoices'],
+    settings JSONB NOT NULL DEFAULT '{}',
+    -- 0 means manual sync only
+    sync_interval_minutes INTEGER NOT NULL DEFAULT 1440 CHECK (sync_interval_minutes >= 0),
+    -- Last fully exported day; scheduled runs continue from the day after
+    synced_through DATE NULL,
+    next_sync_at TIMESTAMPTZ NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_erp_connectors_due ON erp_connectors(next_sync_at) WHERE is_active AND sync_interval_minutes > 0;
+
+CREATE TABLE IF NOT EXISTS erp_sync_runs (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    connector_id UUID NOT NULL REFERENCES erp_connectors(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL CHECK (trigger IN ('manual', 'scheduled')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    records_exported INTEGER NOT NULL DEFAULT 0,
+    file_name VARCHAR(255) NULL,
+    object_key TEXT NULL,
+    delivered BOOLEAN NOT NULL DEFAULT FALSE,
+    error_message TEXT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_erp_sync_runs_connector ON erp_sync_runs(connector_id, started_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('erp:read', 'View ERP connectors and sync status'),
+('erp:manage', 'Manage ERP connectors and run syncs')
+ON CONFLICT (name) DO NOTHING;
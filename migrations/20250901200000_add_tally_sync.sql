@@ -0,0 +1,52 @@
+-- Incremental Tally exports: per-entity sync cursors, per-tenant schedules and export runs
+-- Migration: 20250901200000_add_tally_sync.sql
+
+-- Cursor is the (updated_at, id) of the last exported record, so ties on
+-- updated_at are neither skipped nor exported twice
+CREATE TABLE IF NOT EXISTS tally_sync_cursors (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    entity VARCHAR(20) NOT NULL CHECK (entity IN ('invoices', 'orders')),
+    last_exported_at TIMESTAMPTZ NULL,
+    last_exported_id UUID NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, entity)
+);
+
+CREATE TABLE IF NOT EXISTS tally_sync_settings (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    interval_minutes INTEGER NOT NULL DEFAULT 1440 CHECK (interval_minutes >= 15),
+    entities TEXT[] NOT NULL DEFAULT ARRAY['invoices', 'orders'],
+    next_run_at TIMESTAMPTZ NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tally_sync_settings_due ON tally_sync_settings(next_run_at) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS tally_export_runs (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    entity VARCHAR(20) NOT NULL CHECK (entity IN ('invoices', 'orders')),
+    trigger VARCHAR(20) NOT NULL CHECK (trigger IN ('manual', 'scheduled')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    records_exported INTEGER NOT NULL DEFAULT 0,
+    has_more BOOLEAN NOT NULL DEFAULT FALSE,
+    cursor_from TIMESTAMPTZ NULL,
+    cursor_to TIMESTAMPTZ NULL,
+    file_name VARCHAR(255) NULL,
+    object_key TEXT NULL,
+    error_message TEXT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tally_export_runs_tenant ON tally_export_runs(tenant_id, entity, started_at DESC);
+
+-- Incremental export scans
+CREATE INDEX IF NOT EXISTS idx_invoices_tenant_updated ON invoices(tenant_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_tenant_updated ON orders(tenant_id, updated_at, id);
+
+INSERT INTO permissions (name, description) VALUES
+('tally:read', 'View Tally sync status and download exports'),
+('tally:manage', 'Run Tally exports and imports and configure scheduled sync')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,66 @@
+-- Offline sync for field sales apps: per-tenant change log feeding change tokens
+-- Migration: 20250901210000_add_offline_sync.sql
+
+-- Every insert/update/delete on a synced table appends a row; a change token
+-- is the last seq a device has applied. Customers are distributors and the
+-- price list is the tenant's current product selling prices.
+CREATE TABLE IF NOT EXISTS sync_changes (
+    seq BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    entity VARCHAR(20) NOT NULL CHECK (entity IN ('products', 'customers', 'price_lists', 'orders')),
+    record_id UUID NOT NULL,
+    operation VARCHAR(10) NOT NULL CHECK (operation IN ('upsert', 'delete')),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_tenant_seq ON sync_changes(tenant_id, seq);
+CREATE INDEX IF NOT EXISTS idx_sync_changes_tombstones ON sync_changes(tenant_id, entity, record_id) WHERE operation = 'delete';
+
+CREATE OR REPLACE FUNCTION record_sync_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO sync_changes (tenant_id, entity, record_id, operation)
+        VALUES (OLD.tenant_id, TG_ARGV[0], OLD.id, 'delete');
+        RETURN OLD;
+    END IF;
+    INSERT INTO sync_changes (tenant_id, entity, record_id, operation)
+    VALUES (NEW.tenant_id, TG_ARGV[0], NEW.id, 'upsert');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_sync_products ON products;
+CREATE TRIGGER trg_sync_products
+    AFTER INSERT OR UPDATE OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('products');
+
+DROP TRIGGER IF EXISTS trg_sync_price_lists ON products;
+CREATE TRIGGER trg_sync_price_lists
+    AFTER INSERT OR UPDATE OF unit_price OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('price_lists');
+
+DROP TRIGGER IF EXISTS trg_sync_customers ON distributors;
+CREATE TRIGGER trg_sync_customers
+    AFTER INSERT OR UPDATE OR DELETE ON distributors
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('customers');
+
+DROP TRIGGER IF EXISTS trg_sync_orders ON orders;
+CREATE TRIGGER trg_sync_orders
+    AFTER INSERT OR UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION record_sync_change('orders');
+
+-- Seed the log with existing rows so an empty token yields a full snapshot
+INSERT INTO sync_changes (tenant_id, entity, record_id, operation)
+SELECT tenant_id, 'products', id, 'upsert' FROM products
+UNION ALL
+SELECT tenant_id, 'price_lists', id, 'upsert' FROM products
+UNION ALL
+SELECT tenant_id, 'customers', id, 'upsert' FROM distributors
+UNION ALL
+SELECT tenant_id, 'orders', id, 'upsert' FROM orders;
+
+INSERT INTO permissions (name, description) VALUES
+('sync:read', 'Pull product, customer, price list and order changes for offline devices'),
+('sync:upload', 'Upload orders created offline')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,20 @@
+-- Push notification device registrations for the field app
+-- Migration: 20250901220000_add_device_tokens.sql
+
+-- A token belongs to one installation; re-registering it under another user
+-- (shared device, new login) moves it rather than duplicating it
+CREATE TABLE IF NOT EXISTS device_tokens (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    platform VARCHAR(10) NOT NULL CHECK (platform IN ('android', 'ios', 'web')),
+    topics TEXT[] NOT NULL DEFAULT ARRAY['order_approvals', 'low_stock', 'payments'],
+    app_version VARCHAR(50) NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_tokens_tenant ON device_tokens(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_device_tokens_user ON device_tokens(tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_device_tokens_last_seen ON device_tokens(last_seen_at);
@@ -0,0 +1,49 @@
+-- Field-force sales visits with geofenced check-ins at distributor locations
+-- Migration: 20250901230000_add_sales_visits.sql
+
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION NULL;
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION NULL;
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS geofence_radius_m INTEGER NOT NULL DEFAULT 200
+    CHECK (geofence_radius_m BETWEEN 25 AND 5000);
+
+-- Distance columns are NULL when the distributor has no location on file,
+-- in which case the check-in was not geofenced
+CREATE TABLE IF NOT EXISTS sales_visits (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    check_in_at TIMESTAMPTZ NOT NULL,
+    check_in_latitude DOUBLE PRECISION NOT NULL,
+    check_in_longitude DOUBLE PRECISION NOT NULL,
+    check_in_accuracy_m DOUBLE PRECISION NULL,
+    check_in_distance_m DOUBLE PRECISION NULL,
+    check_out_at TIMESTAMPTZ NULL,
+    check_out_latitude DOUBLE PRECISION NULL,
+    check_out_longitude DOUBLE PRECISION NULL,
+    check_out_distance_m DOUBLE PRECISION NULL,
+    notes TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_visits_user_day ON sales_visits(tenant_id, user_id, check_in_at);
+CREATE INDEX IF NOT EXISTS idx_sales_visits_distributor ON sales_visits(tenant_id, distributor_id, check_in_at DESC);
+-- A rep can be checked in at one place at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_visits_open ON sales_visits(tenant_id, user_id) WHERE check_out_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS sales_visit_photos (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    visit_id UUID NOT NULL REFERENCES sales_visits(id) ON DELETE CASCADE,
+    object_key TEXT NOT NULL,
+    caption TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_visit_photos_visit ON sales_visit_photos(visit_id);
+
+INSERT INTO permissions (name, description) VALUES
+('visits:create', 'Check in and out of sales visits and attach photos'),
+('visits:read', 'View sales visits and route reports for all users')
+ON CONFLICT (name) DO NOTHING;
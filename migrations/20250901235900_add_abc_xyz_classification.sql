@@ -0,0 +1,12 @@
+-- ABC/XYZ product classification: revenue contribution (ABC) and demand variability (XYZ)
+-- Migration: 20250901235900_add_abc_xyz_classification.sql
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS abc_class CHAR(1) CHECK (abc_class IN ('A', 'B', 'C'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS xyz_class CHAR(1) CHECK (xyz_class IN ('X', 'Y', 'Z'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS classified_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_products_abc_xyz ON products(tenant_id, abc_class, xyz_class);
+
+INSERT INTO permissions (name, description) VALUES
+('analytics:manage', 'Run analytics jobs such as ABC/XYZ product classification')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,49 @@
+-- Purchase receipts with freight, customs and handling charges allocated to received lines as landed cost
+-- Migration: 20250902000000_add_purchase_receipts.sql
+
+CREATE TABLE IF NOT EXISTS purchase_receipts (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    supplier_id UUID NULL REFERENCES suppliers(id) ON DELETE SET NULL,
+    allocation_method VARCHAR(20) NOT NULL CHECK (allocation_method IN ('value', 'quantity')),
+    goods_value DECIMAL(14,2) NOT NULL,
+    total_charges DECIMAL(14,2) NOT NULL CHECK (total_charges >= 0),
+    notes TEXT NULL,
+    received_by UUID NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_receipts_tenant ON purchase_receipts(tenant_id, received_at DESC);
+
+CREATE TABLE IF NOT EXISTS purchase_receipt_charges (
+    id UUID PRIMARY KEY,
+    receipt_id UUID NOT NULL REFERENCES purchase_receipts(id) ON DELETE CASCADE,
+    charge_type VARCHAR(20) NOT NULL CHECK (charge_type IN ('freight', 'customs', 'handling', 'other')),
+    amount DECIMAL(14,2) NOT NULL CHECK (amount >= 0),
+    description TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_receipt_charges_receipt ON purchase_receipt_charges(receipt_id);
+
+-- One line per received purchase order; previous_cost_price is the product cost before the receipt
+CREATE TABLE IF NOT EXISTS purchase_receipt_lines (
+    id UUID PRIMARY KEY,
+    receipt_id UUID NOT NULL REFERENCES purchase_receipts(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price DECIMAL(10,2) NOT NULL,
+    allocated_charges DECIMAL(14,2) NOT NULL DEFAULT 0,
+    landed_unit_cost DECIMAL(12,4) NOT NULL,
+    previous_cost_price DECIMAL(10,2) NULL,
+    new_cost_price DECIMAL(10,2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_receipt_lines_receipt ON purchase_receipt_lines(receipt_id);
+CREATE INDEX IF NOT EXISTS idx_purchase_receipt_lines_product ON purchase_receipt_lines(product_id);
+
+INSERT INTO permissions (name, description) VALUES
+('purchases:receive', 'Receive purchase orders and record landed cost charges'),
+('purchases:read', 'View purchase receipts and landed cost allocations')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,48 @@
+-- Consignment stock: supplier-owned stock that is held and sold but not valued, with liabilities raised on sale
+-- Migration: 20250902010000_add_consignment_stock.sql
+
+ALTER TABLE purchase_receipts ADD COLUMN IF NOT EXISTS is_consignment BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Consignment receipts do not change product cost
+ALTER TABLE purchase_receipt_lines ALTER COLUMN new_cost_price DROP NOT NULL;
+
+-- Consignment units on hand per supplier; these units are also counted in inventory so they can be sold
+CREATE TABLE IF NOT EXISTS consignment_stock (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    supplier_id UUID NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    unit_cost DECIMAL(10,2) NOT NULL CHECK (unit_cost >= 0),
+    first_received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, supplier_id, warehouse_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_stock_position ON consignment_stock(tenant_id, warehouse_id, product_id);
+
+-- Amounts owed to suppliers for consignment units sold
+CREATE TABLE IF NOT EXISTS consignment_liabilities (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    supplier_id UUID NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_cost DECIMAL(10,2) NOT NULL,
+    amount DECIMAL(14,2) NOT NULL,
+    sale_unit_price DECIMAL(10,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'settled', 'reversed')),
+    settlement_reference VARCHAR(100) NULL,
+    settled_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_liabilities_supplier ON consignment_liabilities(tenant_id, supplier_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_consignment_liabilities_order ON consignment_liabilities(order_id);
+
+INSERT INTO permissions (name, description) VALUES
+('consignment:read', 'View consignment stock and supplier settlement reports'),
+('consignment:settle', 'Settle consignment liabilities with suppliers')
+ON CONFLICT (name) DO NOTHING;
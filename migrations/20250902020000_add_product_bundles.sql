@@ -0,0 +1,39 @@
+-- Bundle (kit) products built from component products
+-- Migration: 20250902020000_add_product_bundles.sql
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_bundle BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS product_bundle_components (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    bundle_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    component_id UUID NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (bundle_id, component_id),
+    CHECK (bundle_id <> component_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_bundle_components_component ON product_bundle_components(tenant_id, component_id);
+
+-- How a processed bundle sales order drew stock: assembled bundle units first,
+-- the rest exploded into components. Kept so a cancellation can put it back.
+CREATE TABLE IF NOT EXISTS bundle_sales (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    bundle_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    bundle_units INTEGER NOT NULL CHECK (bundle_units >= 0),
+    exploded_units INTEGER NOT NULL CHECK (exploded_units >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS bundle_sale_components (
+    order_id UUID NOT NULL REFERENCES bundle_sales(order_id) ON DELETE CASCADE,
+    component_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (order_id, component_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+('bundles:read', 'View bundle product components'),
+('bundles:manage', 'Define bundle components and assemble or disassemble bundle stock')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,33 @@
+-- Purchase requisitions raised by warehouse staff, approved, then converted into purchase orders
+-- Migration: 20250902030000_add_purchase_requisitions.sql
+
+CREATE TABLE IF NOT EXISTS purchase_requisitions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    need_by DATE NOT NULL,
+    supplier_id UUID NULL REFERENCES suppliers(id) ON DELETE SET NULL,
+    notes TEXT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'converted', 'cancelled')),
+    requested_by UUID NULL,
+    approved_by UUID NULL,
+    approved_at TIMESTAMPTZ NULL,
+    rejection_reason TEXT NULL,
+    -- The purchase order the requisition was converted into; receipts link to it by order_id
+    order_id UUID NULL REFERENCES orders(id) ON DELETE SET NULL,
+    converted_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_requisitions_tenant_status ON purchase_requisitions(tenant_id, status, need_by);
+CREATE INDEX IF NOT EXISTS idx_purchase_requisitions_order ON purchase_requisitions(order_id) WHERE order_id IS NOT NULL;
+
+INSERT INTO permissions (name, description) VALUES
+('requisitions:create', 'Raise and cancel purchase requisitions'),
+('requisitions:read', 'View purchase requisitions and their purchase orders and receipts'),
+('requisitions:approve', 'Approve or reject purchase requisitions'),
+('requisitions:convert', 'Convert approved requisitions into purchase orders')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,30 @@
+-- Supplier price lists with validity windows and quantity breaks, used to compare supplier quotes
+-- Migration: 20250902040000_add_supplier_price_lists.sql
+
+CREATE TABLE IF NOT EXISTS supplier_price_lists (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    supplier_id UUID NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    valid_from DATE NOT NULL,
+    valid_to DATE NULL,
+    notes TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (valid_to IS NULL OR valid_to >= valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplier_price_lists_supplier ON supplier_price_lists(tenant_id, supplier_id, valid_from DESC);
+
+-- min_quantity is a quantity break: the price applies to orders of at least that many units
+CREATE TABLE IF NOT EXISTS supplier_price_list_items (
+    id UUID PRIMARY KEY,
+    price_list_id UUID NOT NULL REFERENCES supplier_price_lists(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    min_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_quantity > 0),
+    unit_price DECIMAL(10,2) NOT NULL CHECK (unit_price > 0),
+    lead_time_days INTEGER NOT NULL DEFAULT 0 CHECK (lead_time_days >= 0),
+    UNIQUE (price_list_id, product_id, min_quantity)
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplier_price_list_items_product ON supplier_price_list_items(product_id);
@@ -0,0 +1,45 @@
+-- Advance bookings: sales booked months ahead with a delivery window and advance payments,
+-- converted into regular sales orders as the window approaches
+-- Migration: 20250902050000_add_advance_bookings.sql
+
+CREATE TABLE IF NOT EXISTS advance_bookings (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price DECIMAL(10,2) NOT NULL CHECK (unit_price > 0),
+    region VARCHAR(100) NULL,
+    window_start DATE NOT NULL,
+    window_end DATE NOT NULL,
+    advance_paid DECIMAL(14,2) NOT NULL DEFAULT 0 CHECK (advance_paid >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'booked' CHECK (status IN ('booked', 'converted', 'cancelled')),
+    order_id UUID NULL REFERENCES orders(id) ON DELETE SET NULL,
+    converted_at TIMESTAMPTZ NULL,
+    notes TEXT NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (window_end >= window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_advance_bookings_tenant_window ON advance_bookings(tenant_id, status, window_start);
+CREATE INDEX IF NOT EXISTS idx_advance_bookings_distributor ON advance_bookings(tenant_id, distributor_id);
+
+CREATE TABLE IF NOT EXISTS advance_booking_payments (
+    id UUID PRIMARY KEY,
+    booking_id UUID NOT NULL REFERENCES advance_bookings(id) ON DELETE CASCADE,
+    amount DECIMAL(14,2) NOT NULL CHECK (amount > 0),
+    method VARCHAR(30) NOT NULL,
+    reference VARCHAR(255) NULL,
+    recorded_by UUID NULL,
+    paid_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_advance_booking_payments_booking ON advance_booking_payments(booking_id);
+
+INSERT INTO permissions (name, description) VALUES
+('bookings:read', 'View advance bookings and booked versus fulfilled demand'),
+('bookings:manage', 'Create, cancel and convert advance bookings and record advance payments')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,38 @@
+-- Compliance licenses (pesticide, fertilizer, seed) held by the tenant, its suppliers and
+-- warehouses, with expiry alert tracking and per-product license requirements
+-- Migration: 20250902060000_add_compliance_licenses.sql
+
+CREATE TABLE IF NOT EXISTS compliance_licenses (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    -- holder_id is the tenant, supplier or warehouse ID depending on holder_type
+    holder_type VARCHAR(20) NOT NULL CHECK (holder_type IN ('tenant', 'supplier', 'warehouse')),
+    holder_id UUID NOT NULL,
+    license_type VARCHAR(20) NOT NULL CHECK (license_type IN ('pesticide', 'fertilizer', 'seed', 'other')),
+    license_number VARCHAR(100) NOT NULL,
+    issuing_authority VARCHAR(255) NULL,
+    valid_from DATE NULL,
+    expires_on DATE NOT NULL,
+    document_key TEXT NULL,
+    document_name VARCHAR(255) NULL,
+    document_content_type VARCHAR(100) NULL,
+    notes TEXT NULL,
+    -- Smallest alert threshold (days before expiry) already sent; reset on renewal
+    last_alert_days INTEGER NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (valid_from IS NULL OR expires_on >= valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_compliance_licenses_holder ON compliance_licenses(tenant_id, holder_type, holder_id, license_type);
+CREATE INDEX IF NOT EXISTS idx_compliance_licenses_expiry ON compliance_licenses(tenant_id, expires_on);
+
+-- Products that may only be bought and sold under a license of this type
+ALTER TABLE products ADD COLUMN IF NOT EXISTS required_license_type VARCHAR(20) NULL
+    CHECK (required_license_type IN ('pesticide', 'fertilizer', 'seed', 'other'));
+
+INSERT INTO permissions (name, description) VALUES
+('compliance:read', 'View licenses, license documents and compliance status'),
+('compliance:manage', 'Record and renew licenses and set product license requirements')
+ON CONFLICT (name) DO NOTHING;
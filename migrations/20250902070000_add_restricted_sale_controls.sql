@@ -0,0 +1,44 @@
+-- Regulatory flags on products, buyer license capture on restricted sales orders and
+-- an override trail for confirmations made without a valid buyer license
+-- Migration: 20250902070000_add_restricted_sale_controls.sql
+
+-- Buyers (distributors) can now hold licenses too
+ALTER TABLE compliance_licenses DROP CONSTRAINT IF EXISTS compliance_licenses_holder_type_check;
+ALTER TABLE compliance_licenses ADD CONSTRAINT compliance_licenses_holder_type_check
+    CHECK (holder_type IN ('tenant', 'supplier', 'warehouse', 'distributor'));
+
+-- Flagged products may only be sold to buyers holding a valid license
+ALTER TABLE products ADD COLUMN IF NOT EXISTS regulatory_flag VARCHAR(30) NULL
+    CHECK (regulatory_flag IN ('red_triangle', 'yellow_triangle', 'blue_triangle', 'green_triangle', 'restricted'));
+
+-- Buyer license captured on a sales order, with a snapshot of its number and expiry
+CREATE TABLE IF NOT EXISTS order_buyer_licenses (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    license_id UUID NULL REFERENCES compliance_licenses(id) ON DELETE SET NULL,
+    license_number VARCHAR(100) NOT NULL,
+    license_expires_on DATE NOT NULL,
+    captured_by UUID NULL,
+    captured_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS restricted_sale_overrides (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    distributor_id UUID NULL,
+    regulatory_flag VARCHAR(30) NOT NULL,
+    license_id UUID NULL,
+    -- Why the license check failed: missing or expired
+    license_problem VARCHAR(20) NOT NULL CHECK (license_problem IN ('missing', 'expired')),
+    reason TEXT NOT NULL,
+    user_id UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_restricted_sale_overrides_tenant ON restricted_sale_overrides(tenant_id, created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('orders:override_restricted', 'Confirm restricted product sales without a valid buyer license')
+ON CONFLICT (name) DO NOTHING;
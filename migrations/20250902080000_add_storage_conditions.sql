@@ -0,0 +1,30 @@
+-- Storage requirements on products (cold chain, shade, hazmat class) and matching
+-- capabilities on warehouses, checked on receipts and transfers
+-- Migration: 20250902080000_add_storage_conditions.sql
+
+CREATE TABLE IF NOT EXISTS product_storage_requirements (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    cold_chain BOOLEAN NOT NULL DEFAULT FALSE,
+    shade BOOLEAN NOT NULL DEFAULT FALSE,
+    -- UN hazard class or division, e.g. '3' or '6.1'
+    hazmat_class VARCHAR(10) NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS warehouse_storage_capabilities (
+    warehouse_id UUID PRIMARY KEY REFERENCES warehouses(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    cold_chain BOOLEAN NOT NULL DEFAULT FALSE,
+    shade BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Hazard classes the warehouse is approved for; a class such as '6' covers its divisions
+    hazmat_classes TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_storage_requirements_tenant ON product_storage_requirements(tenant_id);
+
+INSERT INTO permissions (name, description) VALUES
+('storage:read', 'View product storage requirements, warehouse capabilities and placement compliance'),
+('storage:manage', 'Set product storage requirements and warehouse storage capabilities')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,19 @@
+-- GSTIN on suppliers and distributors, and soft archiving of suppliers,
+-- distributors and warehouses still referenced by past orders
+-- Migration: 20250902090000_add_master_data_archiving.sql
+
+ALTER TABLE suppliers
+    ADD COLUMN IF NOT EXISTS gstin VARCHAR(15) NULL,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP NULL;
+
+ALTER TABLE distributors
+    ADD COLUMN IF NOT EXISTS gstin VARCHAR(15) NULL,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP NULL;
+
+ALTER TABLE warehouses
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP NULL;
+
+-- Duplicate-name checks compare names case-insensitively among active rows
+CREATE INDEX IF NOT EXISTS idx_suppliers_active_name ON suppliers (tenant_id, LOWER(name)) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_distributors_active_name ON distributors (tenant_id, LOWER(name)) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_warehouses_active_name ON warehouses (tenant_id, LOWER(name)) WHERE archived_at IS NULL;
@@ -0,0 +1,25 @@
+-- Generated product catalog PDFs, rendered in the background and stored in MinIO
+-- Migration: 20250902100000_add_catalog_exports.sql
+
+CREATE TABLE IF NOT EXISTS catalog_exports (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    title VARCHAR(200) NOT NULL,
+    -- Supplier price list the catalog prices come from; NULL prints product unit prices
+    price_list_id UUID NULL REFERENCES supplier_price_lists(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    products_total INTEGER NOT NULL DEFAULT 0,
+    products_done INTEGER NOT NULL DEFAULT 0,
+    file_name VARCHAR(255) NULL,
+    object_key TEXT NULL,
+    error_message TEXT NULL,
+    requested_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_exports_tenant ON catalog_exports(tenant_id, started_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('catalog:export', 'Generate and download product catalog PDFs')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,62 @@
+-- WhatsApp Business channel: approved templates, contact opt-in and a message log
+-- Migration: 20250902110000_add_whatsapp_channel.sql
+
+-- Templates are approved with the provider (Meta or the BSP); this maps a
+-- business event to the approved template and its body parameter order
+CREATE TABLE IF NOT EXISTS whatsapp_templates (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    -- Template name for Meta, template ID for Gupshup
+    name VARCHAR(255) NOT NULL,
+    language VARCHAR(10) NOT NULL DEFAULT 'en',
+    category VARCHAR(20) NOT NULL DEFAULT 'utility' CHECK (category IN ('utility', 'marketing', 'authentication')),
+    -- Event data keys filling {{1}}, {{2}}, ... in order
+    parameters TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, event_type, language)
+);
+
+-- Business-initiated messages may only go to contacts who opted in; phones
+-- are stored as E.164 digits without the leading +
+CREATE TABLE IF NOT EXISTS whatsapp_contacts (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    phone VARCHAR(15) NOT NULL,
+    name VARCHAR(255) NULL,
+    opted_in BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Where consent was captured: web form, sales visit, inbound keyword, ...
+    source VARCHAR(50) NULL,
+    opted_in_at TIMESTAMPTZ NULL,
+    opted_out_at TIMESTAMPTZ NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, phone)
+);
+
+CREATE INDEX IF NOT EXISTS idx_whatsapp_contacts_phone ON whatsapp_contacts(phone);
+
+CREATE TABLE IF NOT EXISTS whatsapp_messages (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    phone VARCHAR(15) NOT NULL,
+    template_id UUID NULL REFERENCES whatsapp_templates(id) ON DELETE SET NULL,
+    event_type VARCHAR(50) NOT NULL,
+    event_id VARCHAR(100) NULL,
+    provider_message_id VARCHAR(255) NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'delivered', 'read', 'failed')),
+    error_message TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ NULL,
+    read_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_tenant ON whatsapp_messages(tenant_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_whatsapp_messages_provider_id ON whatsapp_messages(provider_message_id) WHERE provider_message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_whatsapp_messages_event ON whatsapp_messages(tenant_id, event_type, event_id);
+
+INSERT INTO permissions (name, description) VALUES
+('whatsapp:read', 'View WhatsApp templates, contacts and message history'),
+('whatsapp:manage', 'Manage WhatsApp templates and contact opt-in, and send messages')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,52 @@
+-- Invoice payment reminder cadences, localized reminder templates and reminder history
+-- Migration: 20250902120000_add_dunning.sql
+
+-- Language reminders are sent in; NULL uses the tenant's default
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS preferred_language VARCHAR(10) NULL;
+
+-- One cadence per tenant. Offsets are days relative to the due date, negative
+-- before it, so the default reminds 3 days early, on the day, then 7 and 15 days late
+CREATE TABLE IF NOT EXISTS dunning_schedules (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    offset_days INTEGER[] NOT NULL DEFAULT '{-3,0,7,15}',
+    channels TEXT[] NOT NULL DEFAULT '{whatsapp}',
+    default_language VARCHAR(10) NOT NULL DEFAULT 'en',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Email and SMS reminder wording per language; WhatsApp uses its approved
+-- payment_reminder templates instead
+CREATE TABLE IF NOT EXISTS dunning_templates (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('email', 'sms')),
+    language VARCHAR(10) NOT NULL,
+    subject VARCHAR(255) NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, channel, language)
+);
+
+-- Each cadence step goes out at most once per channel
+CREATE TABLE IF NOT EXISTS invoice_reminders (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    offset_days INTEGER NOT NULL,
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('whatsapp', 'email', 'sms')),
+    recipient VARCHAR(255) NULL,
+    language VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'failed', 'skipped')),
+    error_message TEXT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (invoice_id, offset_days, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_reminders_invoice ON invoice_reminders(tenant_id, invoice_id, sent_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('dunning:read', 'View payment reminder settings and invoice reminder history'),
+('dunning:manage', 'Configure payment reminder cadences and templates')
+ON CONFLICT (name) DO NOTHING;
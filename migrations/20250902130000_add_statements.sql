@@ -0,0 +1,37 @@
+-- Customer statements of account: credit notes and monthly statement dispatches
+-- Migration: 20250902130000_add_statements.sql
+
+-- Credits issued to a distributor, optionally against one of its invoices
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    invoice_id UUID NULL REFERENCES invoices(id) ON DELETE SET NULL,
+    credit_note_number VARCHAR(50) NOT NULL,
+    amount DECIMAL(14,2) NOT NULL CHECK (amount > 0),
+    reason TEXT NULL,
+    issued_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, credit_note_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_notes_distributor ON credit_notes(tenant_id, distributor_id, issued_date);
+
+-- One row per distributor and month a statement was emailed, so the daily job
+-- sends each monthly statement once
+CREATE TABLE IF NOT EXISTS statement_dispatches (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    closing_balance DECIMAL(14,2) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    object_key VARCHAR(500) NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, distributor_id, period_start)
+);
+
+INSERT INTO permissions (name, description) VALUES
+('statements:read', 'View and download customer statements of account and credit notes'),
+('statements:manage', 'Issue credit notes and send customer statements')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,57 @@
+-- Counter sales (POS): cash registers per warehouse/store and the counter sales rung up on them
+-- Migration: 20250902140000_add_counter_sales.sql
+
+-- A register session from opening to closing. Denominations are note/coin
+-- value -> count; expected cash is the opening float plus cash sales
+CREATE TABLE IF NOT EXISTS cash_registers (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    opening_float DECIMAL(14,2) NOT NULL DEFAULT 0 CHECK (opening_float >= 0),
+    opening_denominations JSONB NOT NULL DEFAULT '{}',
+    opened_by UUID NULL,
+    opened_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closing_denominations JSONB NULL,
+    counted_cash DECIMAL(14,2) NULL,
+    expected_cash DECIMAL(14,2) NULL,
+    variance DECIMAL(14,2) NULL,
+    closed_by UUID NULL,
+    closed_at TIMESTAMPTZ NULL,
+    notes TEXT NULL
+);
+
+-- At most one open register per warehouse/store
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cash_registers_one_open
+    ON cash_registers(tenant_id, warehouse_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_cash_registers_opened ON cash_registers(tenant_id, warehouse_id, opened_at DESC);
+
+CREATE TABLE IF NOT EXISTS counter_sales (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    register_id UUID NOT NULL REFERENCES cash_registers(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    invoice_id UUID NULL REFERENCES invoices(id) ON DELETE SET NULL,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    amount DECIMAL(14,2) NOT NULL CHECK (amount >= 0),
+    payment_method VARCHAR(10) NOT NULL CHECK (payment_method IN ('cash', 'upi', 'card')),
+    amount_tendered DECIMAL(14,2) NULL,
+    change_given DECIMAL(14,2) NOT NULL DEFAULT 0,
+    payment_reference VARCHAR(100) NULL,
+    customer_name VARCHAR(255) NULL,
+    customer_phone VARCHAR(20) NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_counter_sales_day ON counter_sales(tenant_id, warehouse_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_counter_sales_register ON counter_sales(register_id);
+
+INSERT INTO permissions (name, description) VALUES
+('pos:sell', 'Ring up counter sales on an open cash register'),
+('pos:manage', 'Open and close cash registers'),
+('pos:read', 'View cash registers and the counter sales day book')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,48 @@
+-- Interest on overdue invoices: tenant and customer terms and the interest accrued
+-- Migration: 20250902150000_add_overdue_interest.sql
+
+-- One set of terms per tenant; interest is off until the tenant enables it and
+-- is never accrued for days before enabled_since
+CREATE TABLE IF NOT EXISTS overdue_interest_settings (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled_since DATE NULL,
+    rate_percent_per_month DECIMAL(5,2) NOT NULL DEFAULT 1.5 CHECK (rate_percent_per_month >= 0),
+    grace_days INTEGER NOT NULL DEFAULT 0 CHECK (grace_days >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Per-customer overrides; NULL falls back to the tenant's terms and a zero
+-- rate exempts the customer
+CREATE TABLE IF NOT EXISTS customer_interest_terms (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    rate_percent_per_month DECIMAL(5,2) NULL CHECK (rate_percent_per_month >= 0),
+    grace_days INTEGER NULL CHECK (grace_days >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, distributor_id)
+);
+
+-- Simple interest accrued on an invoice for the days after period_start up to
+-- and including period_end, at the rate in force when it was accrued
+CREATE TABLE IF NOT EXISTS invoice_interest_entries (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    days INTEGER NOT NULL CHECK (days > 0),
+    principal DECIMAL(14,2) NOT NULL,
+    rate_percent_per_month DECIMAL(5,2) NOT NULL,
+    amount DECIMAL(14,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (invoice_id, period_end)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_interest_distributor ON invoice_interest_entries(tenant_id, distributor_id, period_end);
+
+INSERT INTO permissions (name, description) VALUES
+('interest:read', 'View overdue interest terms and interest accrued on invoices'),
+('interest:manage', 'Configure overdue interest terms and run interest accrual')
+ON CONFLICT (name) DO NOTHING;
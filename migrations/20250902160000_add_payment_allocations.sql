@@ -0,0 +1,44 @@
+-- Customer payments and their allocation across open invoices
+-- Migration: 20250902160000_add_payment_allocations.sql
+
+-- Money received from a distributor. Whatever is not allocated to invoices
+-- stays on account as a credit
+CREATE TABLE IF NOT EXISTS customer_payments (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    payment_number VARCHAR(50) NOT NULL,
+    amount DECIMAL(14,2) NOT NULL CHECK (amount > 0),
+    payment_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    method VARCHAR(20) NULL,
+    reference VARCHAR(100) NULL,
+    notes TEXT NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, payment_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_payments_distributor ON customer_payments(tenant_id, distributor_id, payment_date);
+
+-- Allocations are never deleted: reallocating reverses the active ones so the
+-- history of where a payment was applied is kept
+CREATE TABLE IF NOT EXISTS payment_allocations (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    payment_id UUID NOT NULL REFERENCES customer_payments(id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    amount DECIMAL(14,2) NOT NULL CHECK (amount > 0),
+    allocated_by UUID NULL,
+    allocated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reversed_by UUID NULL,
+    reversed_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_allocations_payment ON payment_allocations(payment_id, allocated_at);
+CREATE INDEX IF NOT EXISTS idx_payment_allocations_invoice_active
+    ON payment_allocations(invoice_id) WHERE reversed_at IS NULL;
+
+INSERT INTO permissions (name, description) VALUES
+('payments:read', 'View customer payments and how they are allocated to invoices'),
+('payments:manage', 'Record customer payments and allocate or reallocate them to invoices')
+ON CONFLICT (name) DO NOTHING;
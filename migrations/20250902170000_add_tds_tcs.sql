@@ -0,0 +1,57 @@
+-- TDS deducted by customers on payments and TCS collected on sales invoices
+-- Migration: 20250902170000_add_tds_tcs.sql
+
+-- Income tax sections a tenant withholds under, e.g. TDS 194Q or TCS 206C(1H).
+-- The threshold is the financial year aggregate per customer beyond which the
+-- rate applies
+CREATE TABLE IF NOT EXISTS withholding_tax_sections (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    kind VARCHAR(3) NOT NULL CHECK (kind IN ('tds', 'tcs')),
+    section VARCHAR(20) NOT NULL,
+    description TEXT NULL,
+    rate_percent DECIMAL(6,3) NOT NULL CHECK (rate_percent > 0 AND rate_percent <= 30),
+    threshold_amount DECIMAL(14,2) NOT NULL DEFAULT 0 CHECK (threshold_amount >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, kind, section)
+);
+
+-- TCS is added to sales invoices automatically, so only one TCS section can
+-- be in force at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_withholding_tax_sections_active_tcs
+    ON withholding_tax_sections(tenant_id) WHERE kind = 'tcs' AND active;
+
+-- TCS added to a sales invoice; the invoice total includes it. The section and
+-- rate are copied so the invoice keeps them if the section changes later
+CREATE TABLE IF NOT EXISTS invoice_tcs (
+    invoice_id UUID PRIMARY KEY REFERENCES invoices(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    section_id UUID NULL REFERENCES withholding_tax_sections(id) ON DELETE SET NULL,
+    section VARCHAR(20) NOT NULL,
+    rate_percent DECIMAL(6,3) NOT NULL,
+    base_amount DECIMAL(14,2) NOT NULL,
+    amount DECIMAL(14,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_tcs_distributor ON invoice_tcs(tenant_id, distributor_id);
+
+-- TDS the customer deducted from a payment. It settles invoices like the
+-- amount received; the certificate (Form 16A) usually arrives after the quarter
+ALTER TABLE customer_payments
+    ADD COLUMN IF NOT EXISTS tds_section_id UUID NULL REFERENCES withholding_tax_sections(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS tds_section VARCHAR(20) NULL,
+    ADD COLUMN IF NOT EXISTS tds_amount DECIMAL(14,2) NOT NULL DEFAULT 0 CHECK (tds_amount >= 0),
+    ADD COLUMN IF NOT EXISTS tds_certificate_number VARCHAR(50) NULL,
+    ADD COLUMN IF NOT EXISTS tds_certificate_date DATE NULL;
+
+CREATE INDEX IF NOT EXISTS idx_customer_payments_tds
+    ON customer_payments(tenant_id, payment_date) WHERE tds_amount > 0;
+
+INSERT INTO permissions (name, description) VALUES
+('withholding_tax:read', 'View TDS/TCS sections and the quarterly TDS/TCS summary'),
+('withholding_tax:manage', 'Configure TDS/TCS sections and record TDS certificates')
+ON CONFLICT (name) DO NOTHING;
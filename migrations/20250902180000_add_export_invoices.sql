@@ -0,0 +1,30 @@
+-- Export invoices: zero-rated supplies under LUT or on payment of IGST, billed
+-- in a foreign currency with shipping bill details for GSTR-1
+-- Migration: 20250902180000_add_export_invoices.sql
+
+-- Export details of an invoice. The invoice itself holds the INR amounts;
+-- export_type is the GSTR-1 table 6A type: WOPAY under LUT without payment of
+-- IGST, WPAY on payment of IGST
+CREATE TABLE IF NOT EXISTS invoice_export_details (
+    invoice_id UUID PRIMARY KEY REFERENCES invoices(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    export_type VARCHAR(5) NOT NULL CHECK (export_type IN ('WOPAY', 'WPAY')),
+    lut_number VARCHAR(50) NULL,
+    currency CHAR(3) NOT NULL,
+    exchange_rate DECIMAL(14,6) NOT NULL CHECK (exchange_rate > 0),
+    foreign_amount DECIMAL(14,2) NOT NULL CHECK (foreign_amount > 0),
+    port_code VARCHAR(6) NULL,
+    shipping_bill_number VARCHAR(20) NULL,
+    shipping_bill_date DATE NULL,
+    destination_country VARCHAR(60) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (export_type = 'WPAY' OR lut_number IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_export_details_tenant ON invoice_export_details(tenant_id);
+
+INSERT INTO permissions (name, description) VALUES
+('exports:read', 'View export invoices and the GSTR-1 export section'),
+('exports:manage', 'Raise export invoices and record their shipping bills')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,77 @@
+-- Materialized views behind the sales, stock and receivables dashboards,
+-- refreshed by a scheduled job instead of recomputed per request
+-- Migration: 20250902190000_add_analytics_materialized_views.sql
+
+-- Sales orders per tenant and day, cancelled orders left out
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_daily_sales AS
+SELECT o.tenant_id,
+    o.order_date::date AS sale_date,
+    COUNT(*)::int AS order_count,
+    SUM(o.quantity)::bigint AS quantity,
+    SUM(o.quantity * o.unit_price)::numeric(16,2) AS sales_amount
+FROM orders o
+WHERE o.order_type = 'sales' AND o.status <> 'cancelled'
+GROUP BY o.tenant_id, o.order_date::date;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_daily_sales_key ON mv_daily_sales(tenant_id, sale_date);
+
+-- Owned stock per tenant and product category at list price; consignment
+-- units belong to suppliers and are left out. Uncategorised products share
+-- the nil category key
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_stock_by_category AS
+SELECT p.tenant_id,
+    COALESCE(p.category_id, '00000000-0000-0000-0000-000000000000'::uuid) AS category_key,
+    p.category_id,
+    COALESCE(c.name, 'Uncategorised') AS category_name,
+    COUNT(DISTINCT p.id)::int AS product_count,
+    COALESCE(SUM(GREATEST(i.quantity - COALESCE(cs.quantity, 0), 0)), 0)::bigint AS quantity,
+    COALESCE(SUM(GREATEST(i.quantity - COALESCE(cs.quantity, 0), 0) * COALESCE(p.unit_price, 0)), 0)::numeric(16,2) AS stock_value
+FROM products p
+LEFT JOIN categories c ON c.id = p.category_id
+LEFT JOIN inventory i ON i.product_id = p.id AND i.tenant_id = p.tenant_id
+LEFT JOIN (
+    SELECT tenant_id, warehouse_id, product_id, SUM(quantity) AS quantity
+    FROM consignment_stock
+    GROUP BY tenant_id, warehouse_id, product_id
+) cs ON cs.tenant_id = i.tenant_id AND cs.warehouse_id = i.warehouse_id AND cs.product_id = i.product_id
+GROUP BY p.tenant_id, p.category_id, c.name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_stock_by_category_key ON mv_stock_by_category(tenant_id, category_key);
+
+-- What each distributor still owes on unpaid and overdue invoices, accrued
+-- interest included, by days past due as of the refresh
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_receivables_aging AS
+SELECT r.tenant_id,
+    r.distributor_id,
+    d.name AS distributor_name,
+    COUNT(*)::int AS invoice_count,
+    SUM(r.outstanding) FILTER (WHERE r.days_past_due <= 0)::numeric(16,2) AS current_amount,
+    SUM(r.outstanding) FILTER (WHERE r.days_past_due BETWEEN 1 AND 30)::numeric(16,2) AS days_1_30,
+    SUM(r.outstanding) FILTER (WHERE r.days_past_due BETWEEN 31 AND 60)::numeric(16,2) AS days_31_60,
+    SUM(r.outstanding) FILTER (WHERE r.days_past_due BETWEEN 61 AND 90)::numeric(16,2) AS days_61_90,
+    SUM(r.outstanding) FILTER (WHERE r.days_past_due > 90)::numeric(16,2) AS days_over_90,
+    SUM(r.outstanding)::numeric(16,2) AS total_outstanding,
+    CURRENT_DATE AS as_of
+FROM (
+    SELECT i.tenant_id, o.distributor_id, CURRENT_DATE - i.due_date::date AS days_past_due,
+        i.total_amount
+            + COALESCE((SELECT SUM(e.amount) FROM invoice_interest_entries e WHERE e.invoice_id = i.id), 0)
+            - COALESCE((SELECT SUM(a.amount) FROM payment_allocations a WHERE a.invoice_id = i.id AND a.reversed_at IS NULL), 0)
+            AS outstanding
+    FROM invoices i
+    JOIN orders o ON o.id = i.order_id AND o.tenant_id = i.tenant_id
+    WHERE i.status IN ('unpaid', 'overdue') AND o.distributor_id IS NOT NULL
+) r
+JOIN distributors d ON d.id = r.distributor_id
+WHERE r.outstanding > 0.005
+GROUP BY r.tenant_id, r.distributor_id, d.name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_receivables_aging_key ON mv_receivables_aging(tenant_id, distributor_id);
+
+-- Postgres does not record when a materialized view was refreshed, so the
+-- refresh job does; readers fall back to live queries once a view is stale
+CREATE TABLE IF NOT EXISTS analytics_view_refreshes (
+    view_name VARCHAR(63) PRIMARY KEY,
+    refreshed_at TIMESTAMPTZ NOT NULL,
+    duration_ms INTEGER NOT NULL DEFAULT 0
+);
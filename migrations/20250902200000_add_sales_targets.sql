@@ -0,0 +1,46 @@
+-- Monthly sales targets per sales rep, region or product category, with
+-- achievement calculated from sales orders or invoices by a scheduled job
+-- Migration: 20250902200000_add_sales_targets.sql
+
+-- Sales to a customer count towards its sales rep's targets and its region's
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS sales_rep_id UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS region VARCHAR(100) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_distributors_sales_rep ON distributors(tenant_id, sales_rep_id) WHERE sales_rep_id IS NOT NULL;
+
+-- basis says what counts as achieved: the value of non-cancelled sales orders
+-- dated in the month, or the taxable value of invoices issued in it.
+-- achieved_amount is as of calculated_at
+CREATE TABLE IF NOT EXISTS sales_targets (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    period_month DATE NOT NULL CHECK (EXTRACT(DAY FROM period_month) = 1),
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('user', 'region', 'category')),
+    user_id UUID NULL REFERENCES users(id) ON DELETE CASCADE,
+    region VARCHAR(100) NULL,
+    category_id UUID NULL REFERENCES categories(id) ON DELETE CASCADE,
+    basis VARCHAR(10) NOT NULL DEFAULT 'invoices' CHECK (basis IN ('orders', 'invoices')),
+    target_amount DECIMAL(14,2) NOT NULL CHECK (target_amount > 0),
+    achieved_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    calculated_at TIMESTAMPTZ NULL,
+    notes TEXT NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (
+        (scope = 'user' AND user_id IS NOT NULL AND region IS NULL AND category_id IS NULL) OR
+        (scope = 'region' AND region IS NOT NULL AND user_id IS NULL AND category_id IS NULL) OR
+        (scope = 'category' AND category_id IS NOT NULL AND user_id IS NULL AND region IS NULL)
+    )
+);
+
+-- One target per subject, month and basis
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_targets_subject ON sales_targets(
+    tenant_id, period_month, scope, basis,
+    COALESCE(user_id, category_id, '00000000-0000-0000-0000-000000000000'::uuid), LOWER(COALESCE(region, ''))
+);
+
+INSERT INTO permissions (name, description) VALUES
+('targets:read', 'View sales targets, achievement and leaderboards'),
+('targets:manage', 'Set sales targets and assign customers to sales reps and regions')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,103 @@
+-- Commissions for sales staff and external agents: rules on invoiced or
+-- collected amounts with slabs, and monthly commission runs for payroll
+-- Migration: 20250902210000_add_commissions.sql
+
+-- Agents are paid commission on the customers they bring in but are not users
+CREATE TABLE IF NOT EXISTS commission_agents (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(20) NULL,
+    email VARCHAR(255) NULL,
+    pan VARCHAR(10) NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_agents_tenant ON commission_agents(tenant_id, name);
+
+-- A customer's sales earn commission for its sales rep and its agent
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS commission_agent_id UUID NULL REFERENCES commission_agents(id) ON DELETE SET NULL;
+
+-- payee_id is a user for payee_type user and an agent for agent; a rule
+-- without one is the default for every payee of the type
+CREATE TABLE IF NOT EXISTS commission_rules (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    payee_type VARCHAR(10) NOT NULL CHECK (payee_type IN ('user', 'agent')),
+    payee_id UUID NULL,
+    basis VARCHAR(10) NOT NULL CHECK (basis IN ('invoiced', 'collected')),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One active rule per payee, and one active default per payee type
+CREATE UNIQUE INDEX IF NOT EXISTS idx_commission_rules_active_payee ON commission_rules(
+    tenant_id, payee_type, COALESCE(payee_id, '00000000-0000-0000-0000-000000000000'::uuid)
+) WHERE active;
+
+-- Slabs are marginal: each rate applies to the part of the month's amount
+-- from from_amount up to the next slab. A flat rate is one slab from zero
+CREATE TABLE IF NOT EXISTS commission_rule_slabs (
+    rule_id UUID NOT NULL REFERENCES commission_rules(id) ON DELETE CASCADE,
+    from_amount DECIMAL(14,2) NOT NULL CHECK (from_amount >= 0),
+    rate_percent DECIMAL(5,2) NOT NULL CHECK (rate_percent >= 0 AND rate_percent <= 100),
+    PRIMARY KEY (rule_id, from_amount)
+);
+
+-- A month's run stays a draft, recalculated on each run, until finalized for payroll
+CREATE TABLE IF NOT EXISTS commission_runs (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    period_month DATE NOT NULL CHECK (EXTRACT(DAY FROM period_month) = 1),
+    status VARCHAR(10) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'finalized')),
+    total_commission DECIMAL(14,2) NOT NULL DEFAULT 0,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finalized_by UUID NULL,
+    finalized_at TIMESTAMPTZ NULL,
+    UNIQUE (tenant_id, period_month)
+);
+
+CREATE TABLE IF NOT EXISTS commission_run_lines (
+    id UUID PRIMARY KEY,
+    run_id UUID NOT NULL REFERENCES commission_runs(id) ON DELETE CASCADE,
+    payee_type VARCHAR(10) NOT NULL,
+    payee_id UUID NOT NULL,
+    payee_name VARCHAR(255) NOT NULL,
+    rule_id UUID NULL REFERENCES commission_rules(id) ON DELETE SET NULL,
+    basis VARCHAR(10) NOT NULL,
+    invoiced_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    released_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    returns_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    withheld_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    collected_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    base_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    commission_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    UNIQUE (run_id, payee_type, payee_id)
+);
+
+-- The documents behind each line. Unpaid invoices past due are withheld and
+-- released into a later run once paid
+CREATE TABLE IF NOT EXISTS commission_run_items (
+    run_id UUID NOT NULL REFERENCES commission_runs(id) ON DELETE CASCADE,
+    payee_type VARCHAR(10) NOT NULL,
+    payee_id UUID NOT NULL,
+    payee_name VARCHAR(255) NOT NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('invoice', 'withheld', 'released', 'return', 'collection')),
+    document_id UUID NOT NULL,
+    document_number VARCHAR(100) NOT NULL,
+    document_date DATE NOT NULL,
+    amount DECIMAL(14,2) NOT NULL,
+    PRIMARY KEY (run_id, payee_type, payee_id, kind, document_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_run_items_document ON commission_run_items(document_id, kind);
+
+INSERT INTO permissions (name, description) VALUES
+('commissions:read', 'View commission rules, runs and payee statements'),
+('commissions:manage', 'Manage commission agents and rules and run and finalize monthly commissions')
+ON CONFLICT (name) DO NOTHING;
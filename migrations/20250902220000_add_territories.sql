@@ -0,0 +1,33 @@
+-- Territory hierarchy (state, district, area) for customers, warehouses and
+-- users, used as a reporting dimension and to scope regional managers
+-- Migration: 20250902220000_add_territories.sql
+
+-- A state has no parent, a district sits under a state and an area under a district
+CREATE TABLE IF NOT EXISTS territories (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    parent_id UUID NULL REFERENCES territories(id) ON DELETE RESTRICT,
+    level VARCHAR(10) NOT NULL CHECK (level IN ('state', 'district', 'area')),
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(20) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((level = 'state') = (parent_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_territories_name ON territories(
+    tenant_id, COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), LOWER(name)
+);
+CREATE INDEX IF NOT EXISTS idx_territories_parent ON territories(parent_id);
+
+ALTER TABLE distributors ADD COLUMN IF NOT EXISTS territory_id UUID NULL REFERENCES territories(id) ON DELETE SET NULL;
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS territory_id UUID NULL REFERENCES territories(id) ON DELETE SET NULL;
+-- A user with a territory only sees analytics within it
+ALTER TABLE users ADD COLUMN IF NOT EXISTS territory_id UUID NULL REFERENCES territories(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_distributors_territory ON distributors(territory_id) WHERE territory_id IS NOT NULL;
+
+INSERT INTO permissions (name, description) VALUES
+('territories:read', 'View territories and territory sales analytics'),
+('territories:manage', 'Manage territories and assign customers, warehouses and users to them')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,67 @@
+-- Warehouse running costs: expense categories, expense entries with
+-- attachments and monthly budgets per warehouse and category
+-- Migration: 20250902230000_add_expenses.sql
+
+CREATE TABLE IF NOT EXISTS expense_categories (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expense_categories_name ON expense_categories(tenant_id, LOWER(name));
+
+-- period_month is the first day of the expense's month, kept so entries and
+-- budgets line up without date arithmetic in every report
+CREATE TABLE IF NOT EXISTS expenses (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES expense_categories(id) ON DELETE RESTRICT,
+    expense_date DATE NOT NULL,
+    period_month DATE NOT NULL CHECK (EXTRACT(DAY FROM period_month) = 1),
+    amount DECIMAL(14,2) NOT NULL CHECK (amount > 0),
+    description TEXT NULL,
+    vendor VARCHAR(255) NULL,
+    reference VARCHAR(100) NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_expenses_month ON expenses(tenant_id, period_month, warehouse_id, category_id);
+
+-- Bills and receipts backing an expense
+CREATE TABLE IF NOT EXISTS expense_attachments (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    object_key TEXT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_attachments_expense ON expense_attachments(expense_id);
+
+CREATE TABLE IF NOT EXISTS expense_budgets (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES expense_categories(id) ON DELETE CASCADE,
+    period_month DATE NOT NULL CHECK (EXTRACT(DAY FROM period_month) = 1),
+    amount DECIMAL(14,2) NOT NULL CHECK (amount >= 0),
+    notes TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, warehouse_id, category_id, period_month)
+);
+
+INSERT INTO permissions (name, description) VALUES
+('expenses:read', 'View warehouse expenses, budgets and the budget vs actual report'),
+('expenses:manage', 'Record warehouse expenses and attachments and set expense budgets')
+ON CONFLICT (name) DO NOTHING;
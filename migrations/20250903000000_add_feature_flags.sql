@@ -0,0 +1,35 @@
+-- Feature flags toggled per tenant or rolled out to a percentage of tenants
+-- without a deployment
+-- Migration: 20250903000000_add_feature_flags.sql
+
+-- A disabled flag is off for every tenant without an override; an enabled one
+-- is on for the tenants that fall within rollout_percentage
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key VARCHAR(64) PRIMARY KEY CHECK (key ~ '^[a-z][a-z0-9_]*$'),
+    description TEXT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    rollout_percentage INTEGER NOT NULL DEFAULT 100 CHECK (rollout_percentage BETWEEN 0 AND 100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A tenant override wins over the flag's rollout
+CREATE TABLE IF NOT EXISTS feature_flag_overrides (
+    flag_key VARCHAR(64) NOT NULL REFERENCES feature_flags(key) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flag_key, tenant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_feature_flag_overrides_tenant ON feature_flag_overrides(tenant_id);
+
+INSERT INTO feature_flags (key, description) VALUES
+('enable_einvoicing', 'GST e-invoicing through the IRP'),
+('enable_distributor_portal', 'Self-service portal for distributors')
+ON CONFLICT (key) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+('platform:manage_feature_flags', 'Can toggle feature flags for tenants')
+ON CONFLICT (name) DO NOTHING;
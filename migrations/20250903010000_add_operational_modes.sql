@@ -0,0 +1,22 @@
+-- Read-only and maintenance switches for the whole service or a single tenant
+-- Migration: 20250903010000_add_operational_modes.sql
+
+-- A row with no tenant applies to every tenant; tenants in normal operation
+-- have no row
+CREATE TABLE IF NOT EXISTS operational_modes (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    mode VARCHAR(20) NOT NULL CHECK (mode IN ('read_only', 'maintenance')),
+    message TEXT NULL,
+    set_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operational_modes_scope ON operational_modes(
+    COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid)
+);
+
+INSERT INTO permissions (name, description) VALUES
+('platform:manage_operational_modes', 'Can put the service or a tenant in read-only or maintenance mode')
+ON CONFLICT (name) DO NOTHING;
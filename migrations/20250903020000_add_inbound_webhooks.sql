@@ -0,0 +1,48 @@
+-- Inbound webhook endpoints that map pushed events to orders and invoice
+-- payments, with a log of every delivery
+-- Migration: 20250903020000_add_inbound_webhooks.sql
+
+-- Each endpoint is reached at /v1/hooks/:slug, so slugs are unique across tenants
+CREATE TABLE IF NOT EXISTS inbound_webhooks (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    action VARCHAR(30) NOT NULL CHECK (action IN ('create_order', 'mark_invoice_paid')),
+    verification VARCHAR(20) NOT NULL CHECK (verification IN ('shared_secret', 'hmac_sha256')),
+    secret VARCHAR(128) NOT NULL,
+    signature_header VARCHAR(100) NOT NULL,
+    field_mapping JSONB NOT NULL DEFAULT '{}',
+    -- Defaults for orders whose payload names no warehouse or customer
+    warehouse_id UUID NULL REFERENCES warehouses(id) ON DELETE SET NULL,
+    distributor_id UUID NULL REFERENCES distributors(id) ON DELETE SET NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+-- Every request to a known endpoint, verified or not. A processed event ID
+-- is only acted on once, so retried deliveries are recorded as duplicates
+CREATE TABLE IF NOT EXISTS inbound_webhook_deliveries (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    webhook_id UUID NOT NULL REFERENCES inbound_webhooks(id) ON DELETE CASCADE,
+    event_id VARCHAR(255) NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('processed', 'duplicate', 'rejected', 'failed')),
+    error TEXT NULL,
+    payload TEXT NOT NULL,
+    order_id UUID NULL REFERENCES orders(id) ON DELETE SET NULL,
+    invoice_id UUID NULL REFERENCES invoices(id) ON DELETE SET NULL,
+    source_ip VARCHAR(64) NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_webhook_deliveries_webhook ON inbound_webhook_deliveries(webhook_id, received_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inbound_webhook_deliveries_event ON inbound_webhook_deliveries(webhook_id, event_id)
+    WHERE status = 'processed' AND event_id IS NOT NULL;
+
+INSERT INTO permissions (name, description) VALUES
+('webhooks:read', 'View inbound webhook endpoints and their delivery log'),
+('webhooks:manage', 'Manage inbound webhook endpoints')
+ON CONFLICT (name) DO NOTHING;
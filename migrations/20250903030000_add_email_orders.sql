@@ -0,0 +1,78 @@
+-- Email-to-order ingestion: order emails forwarded by the mail provider's
+-- inbound parse webhook are parsed into draft orders for review
+-- Migration: 20250903030000_add_email_orders.sql
+
+-- One inbound address per tenant; the token is the secret part of the
+-- webhook URL configured at SES/SendGrid. line_pattern is a regular
+-- expression with named groups sku, quantity and optionally price, matched
+-- against each line of the body; CSV attachments are read by column name
+CREATE TABLE IF NOT EXISTS email_order_settings (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    inbound_token VARCHAR(64) NOT NULL UNIQUE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    default_warehouse_id UUID NULL REFERENCES warehouses(id) ON DELETE SET NULL,
+    line_pattern TEXT NULL,
+    sku_column VARCHAR(50) NOT NULL DEFAULT 'sku',
+    quantity_column VARCHAR(50) NOT NULL DEFAULT 'quantity',
+    price_column VARCHAR(50) NOT NULL DEFAULT 'price',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Known dealer addresses; an address of the form @example.com matches the
+-- whole domain. Mail from anyone else is kept but never becomes an order
+CREATE TABLE IF NOT EXISTS email_order_senders (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    address VARCHAR(255) NOT NULL,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    warehouse_id UUID NULL REFERENCES warehouses(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, address)
+);
+
+-- Received emails waiting for, or already through, the ingestion worker
+CREATE TABLE IF NOT EXISTS inbound_emails (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    message_id VARCHAR(255) NULL,
+    from_address VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    body_text TEXT NOT NULL DEFAULT '',
+    attachments JSONB NOT NULL DEFAULT '[]',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'parsed', 'unknown_sender', 'failed')),
+    error TEXT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMPTZ NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inbound_emails_message ON inbound_emails(tenant_id, message_id) WHERE message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_inbound_emails_pending ON inbound_emails(received_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_inbound_emails_tenant ON inbound_emails(tenant_id, received_at DESC);
+
+-- Parsed orders waiting in the review queue; approving one creates a sales
+-- order per line
+CREATE TABLE IF NOT EXISTS email_draft_orders (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    email_id UUID NOT NULL UNIQUE REFERENCES inbound_emails(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    warehouse_id UUID NULL REFERENCES warehouses(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending_review'
+        CHECK (status IN ('pending_review', 'approved', 'rejected')),
+    lines JSONB NOT NULL DEFAULT '[]',
+    order_ids UUID[] NOT NULL DEFAULT '{}',
+    review_note TEXT NULL,
+    reviewed_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_draft_orders_queue ON email_draft_orders(tenant_id, status, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+('email_orders:read', 'View order emails and the draft order review queue'),
+('email_orders:manage', 'Configure email ordering and approve or reject draft orders')
+ON CONFLICT (name) DO NOTHING;
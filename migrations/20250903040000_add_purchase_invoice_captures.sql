@@ -0,0 +1,35 @@
+-- OCR purchase invoice capture: uploaded supplier invoices are read by OCR
+-- into a draft purchase for review; confirming it raises the purchase orders
+-- Migration: 20250903040000_add_purchase_invoice_captures.sql
+
+-- The source document stays in object storage under object_key and is kept
+-- after confirmation, linked to the purchase orders it produced. extracted
+-- holds the reviewable draft (supplier, invoice number and date, lines)
+CREATE TABLE IF NOT EXISTS purchase_invoice_captures (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending_review'
+        CHECK (status IN ('pending_review', 'confirmed', 'discarded')),
+    object_key VARCHAR(500) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    ocr_provider VARCHAR(30) NOT NULL,
+    ocr_text TEXT NOT NULL DEFAULT '',
+    ocr_error TEXT NULL,
+    extracted JSONB NOT NULL DEFAULT '{}'::jsonb,
+    order_ids UUID[] NOT NULL DEFAULT '{}',
+    uploaded_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    confirmed_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    confirmed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_invoice_captures_queue ON purchase_invoice_captures(tenant_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_purchase_invoice_captures_orders ON purchase_invoice_captures USING GIN (order_ids);
+
+INSERT INTO permissions (name, description) VALUES
+('purchase_captures:read', 'View captured supplier invoices and their source documents'),
+('purchase_captures:manage', 'Upload supplier invoices for OCR and confirm them into purchase orders')
+ON CONFLICT (name) DO NOTHING;
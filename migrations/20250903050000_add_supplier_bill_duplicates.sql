@@ -0,0 +1,29 @@
+-- Duplicate supplier bill detection: captured supplier invoices that look
+-- like an earlier bill (same supplier with a matching invoice number, amount
+-- or date) are flagged and need an override to be posted
+-- Migration: 20250903050000_add_supplier_bill_duplicates.sql
+
+-- One row per suspected pair. Open flags are recomputed whenever the bill
+-- is edited; a flag is closed as overridden when the bill is posted anyway
+-- or discarded when the bill is thrown out as the duplicate it was
+CREATE TABLE IF NOT EXISTS supplier_bill_duplicates (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    capture_id UUID NOT NULL REFERENCES purchase_invoice_captures(id) ON DELETE CASCADE,
+    matched_capture_id UUID NOT NULL REFERENCES purchase_invoice_captures(id) ON DELETE CASCADE,
+    score NUMERIC(4,2) NOT NULL,
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'flagged'
+        CHECK (status IN ('flagged', 'overridden', 'discarded')),
+    override_reason TEXT NULL,
+    resolved_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (capture_id, matched_capture_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplier_bill_duplicates_report ON supplier_bill_duplicates(tenant_id, status, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+('purchase_captures:override_duplicates', 'Post a supplier invoice flagged as a likely duplicate bill')
+ON CONFLICT (name) DO NOTHING;
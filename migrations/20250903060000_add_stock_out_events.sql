@@ -0,0 +1,27 @@
+-- Stock-out events: sales demand turned away (rejected) or left waiting
+-- (back-ordered) because the warehouse did not have the stock, used for
+-- fill rate and lost sales analytics
+-- Migration: 20250903060000_add_stock_out_events.sql
+
+-- A rejected event never became an order (or an order increase); a
+-- back-ordered event is an order that could not be processed and may be
+-- logged more than once while it waits. available_quantity is NULL when
+-- the shortfall came from bundle components
+CREATE TABLE IF NOT EXISTS stock_out_events (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    order_id UUID NULL REFERENCES orders(id) ON DELETE SET NULL,
+    distributor_id UUID NULL REFERENCES distributors(id) ON DELETE SET NULL,
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('rejected', 'backordered')),
+    source VARCHAR(30) NOT NULL,
+    requested_quantity INTEGER NOT NULL CHECK (requested_quantity > 0),
+    available_quantity INTEGER NULL,
+    short_quantity INTEGER NOT NULL CHECK (short_quantity > 0),
+    unit_price DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_out_events_tenant ON stock_out_events(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_stock_out_events_order ON stock_out_events(order_id) WHERE order_id IS NOT NULL;
@@ -0,0 +1,51 @@
+-- Notification digests: users can swap individual low-stock and approval
+-- alerts for one daily or weekly email summarising low stock, overdue
+-- invoices and pending approvals
+-- Migration: 20250903070000_add_notification_digests.sql
+
+-- No row means digests are off. send_hour and weekly_day (0 = Sunday) are
+-- in the user's timezone. While a digest covering a section is on, the
+-- matching push topic is not sent to the user's devices
+CREATE TABLE IF NOT EXISTS notification_digest_preferences (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'off' CHECK (frequency IN ('off', 'daily', 'weekly')),
+    sections TEXT[] NOT NULL DEFAULT '{low_stock,overdue_invoices,pending_approvals}',
+    send_hour SMALLINT NOT NULL DEFAULT 8 CHECK (send_hour BETWEEN 0 AND 23),
+    weekly_day SMALLINT NOT NULL DEFAULT 1 CHECK (weekly_day BETWEEN 0 AND 6),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Kolkata',
+    last_sent_at TIMESTAMPTZ NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_digest_preferences_user ON notification_digest_preferences(user_id) WHERE frequency <> 'off';
+
+-- A tenant's own digest wording; without one the built-in template is used
+CREATE TABLE IF NOT EXISTS notification_digest_templates (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    updated_by UUID NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS notification_digest_deliveries (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('sent', 'empty', 'failed')),
+    item_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_digest_deliveries_tenant ON notification_digest_deliveries(tenant_id, created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('notifications:manage', 'Manage the tenant notification digest template and view digest deliveries')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,81 @@
+-- Order fulfillment SLAs: each tenant defines targets between order status
+-- milestones (e.g. approve within 4h of creation, ship within 48h of
+-- approval), breaches are logged and alerted by a scheduled job and reported
+-- per warehouse and territory
+-- Migration: 20250903080000_add_order_slas.sql
+
+-- When an order first reached each status. Orders from before this migration
+-- have no milestones and are not measured
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS approved_at TIMESTAMPTZ NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS processing_at TIMESTAMPTZ NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipped_at TIMESTAMPTZ NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ NULL;
+
+CREATE OR REPLACE FUNCTION record_order_status_time()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.status IS NOT DISTINCT FROM OLD.status THEN
+        RETURN NEW;
+    END IF;
+    IF NEW.status = 'approved' THEN
+        NEW.approved_at := COALESCE(NEW.approved_at, NOW());
+    ELSIF NEW.status = 'processing' THEN
+        NEW.processing_at := COALESCE(NEW.processing_at, NOW());
+    ELSIF NEW.status = 'shipped' THEN
+        NEW.shipped_at := COALESCE(NEW.shipped_at, NOW());
+    ELSIF NEW.status = 'delivered' THEN
+        NEW.delivered_at := COALESCE(NEW.delivered_at, NOW());
+    ELSIF NEW.status = 'cancelled' THEN
+        NEW.cancelled_at := COALESCE(NEW.cancelled_at, NOW());
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_order_status_time ON orders;
+CREATE TRIGGER trg_order_status_time
+    BEFORE INSERT OR UPDATE OF status ON orders
+    FOR EACH ROW EXECUTE FUNCTION record_order_status_time();
+
+-- start_event and end_event are order milestones; an SLA applies to orders
+-- of order_type (NULL for both) in warehouse_id (NULL for all)
+CREATE TABLE IF NOT EXISTS order_sla_definitions (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    order_type VARCHAR(20) NULL CHECK (order_type IN ('purchase', 'sales')),
+    warehouse_id UUID NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    start_event VARCHAR(20) NOT NULL CHECK (start_event IN ('created', 'approved', 'processing', 'shipped')),
+    end_event VARCHAR(20) NOT NULL CHECK (end_event IN ('approved', 'processing', 'shipped', 'delivered')),
+    target_minutes INTEGER NOT NULL CHECK (target_minutes > 0),
+    notify_emails TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_sla_definitions_name ON order_sla_definitions(tenant_id, LOWER(name));
+
+-- One row per order that missed an SLA, written when the job first sees the
+-- deadline pass; resolved_at is when the order finally reached the end event
+CREATE TABLE IF NOT EXISTS order_sla_breaches (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    sla_id UUID NOT NULL REFERENCES order_sla_definitions(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL,
+    warehouse_id UUID NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ NULL,
+    UNIQUE (sla_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_sla_breaches_tenant ON order_sla_breaches(tenant_id, detected_at DESC);
+CREATE INDEX IF NOT EXISTS idx_order_sla_breaches_open ON order_sla_breaches(tenant_id) WHERE resolved_at IS NULL;
+
+INSERT INTO permissions (name, description) VALUES
+('sla:read', 'View order SLA definitions, breaches and compliance reports'),
+('sla:manage', 'Create, update and delete order SLA definitions')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,20 @@
+-- Status history: every order and invoice status transition with who made it,
+-- when and why, written by the services' transition methods alongside the
+-- status update
+-- Migration: 20250903090000_add_status_history.sql
+
+CREATE TABLE IF NOT EXISTS status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('order', 'invoice')),
+    entity_id UUID NOT NULL,
+    -- NULL when the entity was created in to_status
+    from_status VARCHAR(50) NULL,
+    to_status VARCHAR(50) NOT NULL,
+    -- NULL for changes made by the system, e.g. scheduled jobs
+    actor_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    comment TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_history_entity ON status_history(tenant_id, entity_type, entity_id, created_at);
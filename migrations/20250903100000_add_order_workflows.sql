@@ -0,0 +1,22 @@
+-- Configurable order lifecycle: tenants can let orders be processed without
+-- approval and add a quality check step before shipping or receipt. Without
+-- a row orders follow the default pending -> approved -> processing ->
+-- shipped -> delivered lifecycle
+-- Migration: 20250903100000_add_order_workflows.sql
+
+-- The original status check predates the processing status
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'approved', 'processing', 'quality_check', 'received', 'shipped', 'delivered', 'cancelled'));
+
+CREATE TABLE IF NOT EXISTS order_workflows (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    skip_approval BOOLEAN NOT NULL DEFAULT FALSE,
+    quality_check BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_by UUID NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO permissions (name, description) VALUES
+('orders:workflow', 'Configure the order workflow: skipping approval and the quality check step')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,50 @@
+-- Returns to supplier: goods from a received purchase order sent back to the
+-- supplier, each with a debit note for the goods value and the GST on it.
+-- Debit notes reduce what is owed on the supplier statement and the input
+-- tax credit to reverse in GSTR-3B
+-- Migration: 20250903110000_add_purchase_returns.sql
+
+CREATE TABLE IF NOT EXISTS purchase_returns (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    return_number VARCHAR(50) NOT NULL,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    supplier_id UUID NULL REFERENCES suppliers(id) ON DELETE SET NULL,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price DECIMAL(10,2) NOT NULL CHECK (unit_price >= 0),
+    reason TEXT NULL,
+    returned_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    returned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, return_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_returns_order ON purchase_returns(tenant_id, order_id);
+CREATE INDEX IF NOT EXISTS idx_purchase_returns_returned_at ON purchase_returns(tenant_id, returned_at DESC);
+
+CREATE TABLE IF NOT EXISTS debit_notes (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    supplier_id UUID NULL REFERENCES suppliers(id) ON DELETE SET NULL,
+    purchase_return_id UUID NOT NULL UNIQUE REFERENCES purchase_returns(id) ON DELETE CASCADE,
+    debit_note_number VARCHAR(50) NOT NULL,
+    supplier_gstin VARCHAR(15) NULL,
+    taxable_amount DECIMAL(14,2) NOT NULL CHECK (taxable_amount >= 0),
+    gst_rate DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (gst_rate >= 0 AND gst_rate <= 100),
+    cgst DECIMAL(14,2) NOT NULL DEFAULT 0,
+    sgst DECIMAL(14,2) NOT NULL DEFAULT 0,
+    igst DECIMAL(14,2) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(14,2) NOT NULL CHECK (total_amount >= 0),
+    issued_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, debit_note_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_debit_notes_supplier ON debit_notes(tenant_id, supplier_id, issued_date);
+CREATE INDEX IF NOT EXISTS idx_debit_notes_issued ON debit_notes(tenant_id, issued_date);
+
+INSERT INTO permissions (name, description) VALUES
+('purchases:return', 'Return received goods to suppliers and issue debit notes')
+ON CONFLICT (name) DO NOTHING;
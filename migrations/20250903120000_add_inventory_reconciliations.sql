@@ -0,0 +1,42 @@
+-- Inventory reconciliation against stock snapshots from an external WMS, e.g.
+-- a 3PL's. Each snapshot is diffed against internal inventory into proposed
+-- adjustments; only the lines approved when the reconciliation is applied
+-- move stock
+-- Migration: 20250903120000_add_inventory_reconciliations.sql
+
+CREATE TABLE IF NOT EXISTS inventory_reconciliations (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    source VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'applied', 'discarded')),
+    snapshot_at TIMESTAMPTZ NOT NULL,
+    full_snapshot BOOLEAN NOT NULL DEFAULT FALSE,
+    notes TEXT NULL,
+    counted_lines INTEGER NOT NULL DEFAULT 0,
+    matched_lines INTEGER NOT NULL DEFAULT 0,
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reconciliations_tenant ON inventory_reconciliations(tenant_id, created_at DESC);
+
+-- Only lines that differ are kept
+CREATE TABLE IF NOT EXISTS inventory_reconciliation_lines (
+    id UUID PRIMARY KEY,
+    reconciliation_id UUID NOT NULL REFERENCES inventory_reconciliations(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    external_quantity INTEGER NOT NULL CHECK (external_quantity >= 0),
+    internal_quantity INTEGER NOT NULL,
+    adjustment INTEGER NOT NULL,
+    approved BOOLEAN NOT NULL DEFAULT FALSE,
+    applied BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reconciliation_lines_reconciliation ON inventory_reconciliation_lines(reconciliation_id);
+
+INSERT INTO permissions (name, description) VALUES
+('inventories:reconcile', 'Reconcile inventory against external WMS stock snapshots and apply the adjustments')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,22 @@
+-- Sandbox (demo) tenants: their business data is wiped and reseeded with a
+-- demo catalogue on a schedule, notifications to the outside world are only
+-- logged and rate limits are relaxed, so prospects and developers can
+-- experiment safely. Tenants that are not sandboxes have no row
+-- Migration: 20250903130000_add_sandbox_tenants.sql
+
+CREATE TABLE IF NOT EXISTS tenant_sandboxes (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    reset_interval_hours INTEGER NOT NULL DEFAULT 24 CHECK (reset_interval_hours BETWEEN 1 AND 720),
+    seed BIGINT NOT NULL DEFAULT 42,
+    last_reset_at TIMESTAMPTZ NULL,
+    next_reset_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    enabled_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_sandboxes_next_reset ON tenant_sandboxes(next_reset_at);
+
+INSERT INTO permissions (name, description) VALUES
+('platform:manage_sandboxes', 'Can mark tenants as sandboxes and reset their demo data')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,29 @@
+-- Product templates pre-fill the category, unit of measure, GST rate and storage
+-- attributes of new products, and products gain a GST rate to carry it
+-- Migration: 20250903140000_add_product_templates.sql
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS gst_rate NUMERIC(5,2) NULL CHECK (gst_rate >= 0 AND gst_rate <= 100);
+
+CREATE TABLE IF NOT EXISTS product_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    category_id UUID NULL REFERENCES categories(id) ON DELETE SET NULL,
+    unit_of_measure VARCHAR(50) NULL,
+    gst_rate NUMERIC(5,2) NULL CHECK (gst_rate >= 0 AND gst_rate <= 100),
+    unit_price DECIMAL(10,2) NULL CHECK (unit_price > 0),
+    description TEXT NULL,
+    cold_chain BOOLEAN NOT NULL DEFAULT FALSE,
+    shade BOOLEAN NOT NULL DEFAULT FALSE,
+    -- UN hazard class or division, e.g. '3' or '6.1'
+    hazmat_class VARCHAR(10) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+INSERT INTO permissions (name, description) VALUES
+('product_templates:read', 'View product templates'),
+('product_templates:manage', 'Create, edit and delete product templates')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,17 @@
+-- Tenant calendar: the timezone a tenant's dates are reckoned in and the month
+-- its fiscal year starts. Invoice dates, overdue days, report periods and
+-- the scheduled job windows follow it. Tenants without a row use India's
+-- timezone and the April to March financial year
+-- Migration: 20250903150000_add_tenant_calendar_settings.sql
+
+CREATE TABLE IF NOT EXISTS tenant_calendar_settings (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Kolkata',
+    fiscal_year_start_month SMALLINT NOT NULL DEFAULT 4 CHECK (fiscal_year_start_month BETWEEN 1 AND 12),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO permissions (name, description) VALUES
+('tenant_settings:read', 'View the tenant timezone and fiscal year settings'),
+('tenant_settings:manage', 'Change the tenant timezone and fiscal year settings')
+ON CONFLICT (name) DO NOTHING;
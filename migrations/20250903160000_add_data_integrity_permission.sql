@@ -0,0 +1,7 @@
+-- Data integrity checks: platform admins scan a tenant for broken references,
+-- invoice totals that do not add up, negative stock and stray image objects
+-- Migration: 20250903160000_add_data_integrity_permission.sql
+
+INSERT INTO permissions (name, description) VALUES
+('platform:check_integrity', 'Can scan tenants for data integrity problems and apply the safe fixes')
+ON CONFLICT (name) DO NOTHING;
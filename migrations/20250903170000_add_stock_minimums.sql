@@ -0,0 +1,21 @@
+-- Stock minimums: the least stock of a product a tenant wants to hold, either
+-- in every warehouse or overriding that in one warehouse. Low stock alerts,
+-- the digest, inventory listings and stocking suggestions compare against it
+-- and fall back to the global threshold for products without one
+-- Migration: 20250903170000_add_stock_minimums.sql
+
+CREATE TABLE IF NOT EXISTS stock_minimums (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    -- NULL applies the minimum to every warehouse without its own
+    warehouse_id UUID REFERENCES warehouses(id) ON DELETE CASCADE,
+    min_quantity INTEGER NOT NULL CHECK (min_quantity >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_minimums_product
+    ON stock_minimums (tenant_id, product_id) WHERE warehouse_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_minimums_warehouse
+    ON stock_minimums (tenant_id, product_id, warehouse_id) WHERE warehouse_id IS NOT NULL;
@@ -0,0 +1,41 @@
+-- Consolidated invoices: one invoice covering a customer's delivered sales
+-- orders over a period, as weekly dealers are billed, with a line per order
+-- Migration: 20250903180000_add_consolidated_invoices.sql
+
+-- The invoice's order_id is its first order, so statements and reports that
+-- reach the customer through it keep working
+CREATE TABLE IF NOT EXISTS consolidated_invoices (
+    invoice_id UUID PRIMARY KEY REFERENCES invoices(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    distributor_id UUID NOT NULL REFERENCES distributors(id) ON DELETE CASCADE,
+    period_from DATE NOT NULL,
+    period_to DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (period_to >= period_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consolidated_invoices_customer ON consolidated_invoices(tenant_id, distributor_id);
+
+CREATE TABLE IF NOT EXISTS consolidated_invoice_orders (
+    invoice_id UUID NOT NULL REFERENCES consolidated_invoices(invoice_id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    product_id UUID NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price DECIMAL(12,2) NOT NULL,
+    taxable_amount DECIMAL(12,2) NOT NULL,
+    gst_rate DECIMAL(5,2) NOT NULL,
+    cgst DECIMAL(12,2) NOT NULL DEFAULT 0,
+    sgst DECIMAL(12,2) NOT NULL DEFAULT 0,
+    igst DECIMAL(12,2) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(12,2) NOT NULL,
+    PRIMARY KEY (invoice_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consolidated_invoice_orders_order ON consolidated_invoice_orders(tenant_id, order_id);
+
+INSERT INTO permissions (name, description) VALUES
+('invoices:read', 'View consolidated invoice lines and the invoices of an order'),
+('invoices:consolidate', 'Raise one invoice for a customer''s delivered orders over a period')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,29 @@
+-- Invoice amendments: an issued or paid invoice cancelled and reissued, with a
+-- credit note for the original linking it to its replacement
+-- Migration: 20250903190000_add_invoice_amendments.sql
+
+-- gst_treatment records how the amendment is reported: an original issued in
+-- the month of the amendment is simply cancelled before its GSTR-1 is filed
+-- ('reissued_in_period'); an original in an earlier, filed month stays
+-- reported there and the credit note reverses it in the current month
+-- ('credit_note')
+CREATE TABLE IF NOT EXISTS invoice_amendments (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    original_invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    credit_note_id UUID NOT NULL REFERENCES credit_notes(id) ON DELETE CASCADE,
+    new_invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    gst_treatment VARCHAR(20) NOT NULL CHECK (gst_treatment IN ('reissued_in_period', 'credit_note')),
+    original_period DATE NOT NULL,
+    amended_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, original_invoice_id),
+    UNIQUE (tenant_id, new_invoice_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_amendments_credit_note ON invoice_amendments(credit_note_id);
+
+INSERT INTO permissions (name, description) VALUES
+('invoices:amend', 'Cancel and reissue an invoice with a credit note for the original')
+ON CONFLICT (name) DO NOTHING;
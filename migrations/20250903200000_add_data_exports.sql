@@ -0,0 +1,61 @@
+-- Data warehouse exports: denormalized fact tables pushed incrementally to a
+-- tenant's BigQuery dataset or ClickHouse database for their own BI
+-- Migration: 20250903200000_add_data_exports.sql
+
+-- A destination only runs once a tenant admin has consented to the export;
+-- credentials are the BigQuery service account key or the ClickHouse password
+CREATE TABLE IF NOT EXISTS data_export_destinations (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    provider VARCHAR(20) NOT NULL CHECK (provider IN ('bigquery', 'clickhouse')),
+    settings JSONB NOT NULL DEFAULT '{}',
+    credentials TEXT NOT NULL DEFAULT '',
+    facts TEXT[] NOT NULL,
+    sync_interval_minutes INTEGER NOT NULL DEFAULT 60 CHECK (sync_interval_minutes >= 15),
+    next_sync_at TIMESTAMPTZ NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    consented_by UUID NULL,
+    consented_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_export_destinations_due ON data_export_destinations(next_sync_at) WHERE is_active;
+
+-- Cursor is the (changed_at, id) of the last exported row of each fact table,
+-- changed_at being updated_at, or created_at for append-only inventory
+-- transactions
+CREATE TABLE IF NOT EXISTS data_export_cursors (
+    destination_id UUID NOT NULL REFERENCES data_export_destinations(id) ON DELETE CASCADE,
+    fact VARCHAR(30) NOT NULL CHECK (fact IN ('orders', 'order_items', 'invoices', 'inventory_transactions')),
+    last_exported_at TIMESTAMPTZ NULL,
+    last_exported_id UUID NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (destination_id, fact)
+);
+
+CREATE TABLE IF NOT EXISTS data_export_runs (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    destination_id UUID NOT NULL REFERENCES data_export_destinations(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL CHECK (trigger IN ('manual', 'scheduled')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    records_exported INTEGER NOT NULL DEFAULT 0,
+    has_more BOOLEAN NOT NULL DEFAULT FALSE,
+    error_message TEXT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_export_runs_destination ON data_export_runs(tenant_id, destination_id, started_at DESC);
+
+-- Incremental scans of inventory transactions; orders and invoices already
+-- have (tenant_id, updated_at, id) indexes for the Tally sync
+CREATE INDEX IF NOT EXISTS idx_inventory_transactions_tenant_created ON inventory_transactions(tenant_id, created_at, id);
+
+INSERT INTO permissions (name, description) VALUES
+('data_exports:read', 'View data warehouse export destinations and runs'),
+('data_exports:manage', 'Consent to, configure and run data warehouse exports')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,43 @@
+-- Column-level PII encryption: contact emails, phones and GSTINs of
+-- distributors and suppliers are encrypted by the application with
+-- per-tenant data keys, with blind indexes for exact-match lookups
+-- Migration: 20250903210000_add_pii_encryption.sql
+
+-- Data keys are wrapped with the PII_MASTER_KEY; retired keys are kept until
+-- the rotation job has re-encrypted every value written with them
+CREATE TABLE IF NOT EXISTS tenant_data_keys (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL CHECK (version > 0),
+    wrapped_key BYTEA NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMPTZ NULL,
+    PRIMARY KEY (tenant_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_data_keys_active ON tenant_data_keys(tenant_id) WHERE is_active;
+
+-- Ciphertext no longer fits the original widths. Existing plaintext stays
+-- readable and is encrypted by the rotation job
+ALTER TABLE distributors
+    ALTER COLUMN contact_email TYPE TEXT,
+    ALTER COLUMN contact_phone TYPE TEXT,
+    ALTER COLUMN gstin TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS contact_email_bidx VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS contact_phone_bidx VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS gstin_bidx VARCHAR(64) NULL;
+
+ALTER TABLE suppliers
+    ALTER COLUMN contact_email TYPE TEXT,
+    ALTER COLUMN contact_phone TYPE TEXT,
+    ALTER COLUMN gstin TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS contact_email_bidx VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS contact_phone_bidx VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS gstin_bidx VARCHAR(64) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_distributors_contact_email_bidx ON distributors(tenant_id, contact_email_bidx) WHERE contact_email_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_distributors_contact_phone_bidx ON distributors(tenant_id, contact_phone_bidx) WHERE contact_phone_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_distributors_gstin_bidx ON distributors(tenant_id, gstin_bidx) WHERE gstin_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_suppliers_contact_email_bidx ON suppliers(tenant_id, contact_email_bidx) WHERE contact_email_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_suppliers_contact_phone_bidx ON suppliers(tenant_id, contact_phone_bidx) WHERE contact_phone_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_suppliers_gstin_bidx ON suppliers(tenant_id, gstin_bidx) WHERE gstin_bidx IS NOT NULL;
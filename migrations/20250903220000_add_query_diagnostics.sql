@@ -0,0 +1,26 @@
+-- Query diagnostics: slow and failing queries recorded by the in-app query
+-- audit, with the request that ran them, for the platform admin report
+-- Migration: 20250903220000_add_query_diagnostics.sql
+
+-- query_text is the parameterized statement with literals replaced, so no
+-- customer data is stored. tenant_id is NULL for queries outside a request
+CREATE TABLE IF NOT EXISTS query_diagnostics (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NULL,
+    user_id UUID NULL,
+    request_id VARCHAR(100) NULL,
+    fingerprint VARCHAR(16) NOT NULL,
+    query_text TEXT NOT NULL,
+    duration_ms DOUBLE PRECISION NOT NULL,
+    row_count BIGINT NOT NULL DEFAULT 0,
+    error_message TEXT NULL,
+    sample_rate DOUBLE PRECISION NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_diagnostics_created_at ON query_diagnostics(created_at);
+CREATE INDEX IF NOT EXISTS idx_query_diagnostics_tenant ON query_diagnostics(tenant_id, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+('platform:read_query_diagnostics', 'Can view slow and failing database queries across tenants')
+ON CONFLICT (name) DO NOTHING;
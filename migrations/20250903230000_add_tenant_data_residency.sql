@@ -0,0 +1,10 @@
+-- Data residency: tenants that must keep their files in a specific region
+-- are tagged with it, and their objects are stored on that region's
+-- endpoint. NULL keeps the tenant on the default store
+-- Migration: 20250903230000_add_tenant_data_residency.sql
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS data_residency VARCHAR(32) NULL;
+
+INSERT INTO permissions (name, description) VALUES
+('platform:manage_data_residency', 'Can pin tenants to a data residency region')
+ON CONFLICT (name) DO NOTHING;
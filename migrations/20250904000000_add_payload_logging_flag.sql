@@ -0,0 +1,7 @@
+-- Payload logging: request and response bodies of a tenant's API calls are
+-- captured, redacted, for debugging integrations while the flag is on for it
+-- Migration: 20250904000000_add_payload_logging_flag.sql
+
+INSERT INTO feature_flags (key, description) VALUES
+('payload_logging', 'Log redacted request and response bodies for debugging integrations')
+ON CONFLICT (key) DO NOTHING;
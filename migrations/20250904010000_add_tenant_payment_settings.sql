@@ -0,0 +1,11 @@
+-- Tenant payment settings: the UPI ID (VPA) buyers pay the tenant at and the
+-- payee name shown in their UPI app. Invoices of tenants with a VPA carry a
+-- QR code with a UPI payment intent for the exact invoice amount
+-- Migration: 20250904010000_add_tenant_payment_settings.sql
+
+CREATE TABLE IF NOT EXISTS tenant_payment_settings (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    upi_vpa VARCHAR(255),
+    payee_name VARCHAR(100),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
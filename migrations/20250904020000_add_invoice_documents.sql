@@ -0,0 +1,33 @@
+-- Invoice documents: the PDF archived for each issued invoice. A background
+-- job renders it shortly after the invoice is created and stores it under a
+-- key that includes its SHA-256, so the archived file is never overwritten.
+-- Rows cannot be changed once written; a correction is a new invoice
+-- through the amendment flow, which gets its own document
+-- Migration: 20250904020000_add_invoice_documents.sql
+
+CREATE TABLE IF NOT EXISTS invoice_documents (
+    invoice_id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    invoice_number VARCHAR(50) NOT NULL,
+    template VARCHAR(20) NOT NULL CHECK (template IN ('standard', 'export', 'consolidated')),
+    object_key VARCHAR(255) NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_documents_tenant ON invoice_documents(tenant_id, generated_at);
+-- The archive job walks invoices without a document in creation order
+CREATE INDEX IF NOT EXISTS idx_invoices_created_id ON invoices(created_at, id);
+
+CREATE OR REPLACE FUNCTION prevent_invoice_document_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'invoice documents are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_invoice_documents_immutable ON invoice_documents;
+CREATE TRIGGER trg_invoice_documents_immutable
+    BEFORE UPDATE ON invoice_documents
+    FOR EACH ROW EXECUTE FUNCTION prevent_invoice_document_update();
@@ -0,0 +1,19 @@
+-- Cost visibility: cost prices, landed costs, stock valued at cost and
+-- margins are left out of API responses for users without products:view_cost.
+-- Existing roles keep seeing them; revoke the permission from roles such as
+-- counter staff that should only see selling prices
+-- Migration: 20250904030000_add_cost_visibility_permission.sql
+
+INSERT INTO permissions (name, description) VALUES
+('products:view_cost', 'View product cost prices and margins')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+CROSS JOIN permissions p
+WHERE p.name = 'products:view_cost'
+  AND NOT EXISTS (
+      SELECT 1 FROM role_permissions rp
+      WHERE rp.role_id = r.id AND rp.permission_id = p.id
+  );
@@ -0,0 +1,31 @@
+-- Tenant takeouts: a full export of a tenant's data, one JSON or CSV file per
+-- table plus the tenant's stored documents, zipped in the background and
+-- stored in MinIO. The requester is emailed a download link when it finishes
+-- Migration: 20250904040000_add_tenant_takeouts.sql
+
+CREATE TABLE IF NOT EXISTS tenant_takeouts (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('json', 'csv')),
+    include_documents BOOLEAN NOT NULL DEFAULT TRUE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    entities_total INTEGER NOT NULL DEFAULT 0,
+    entities_done INTEGER NOT NULL DEFAULT 0,
+    rows_exported BIGINT NOT NULL DEFAULT 0,
+    documents_exported INTEGER NOT NULL DEFAULT 0,
+    file_name VARCHAR(255) NULL,
+    object_key TEXT NULL,
+    size_bytes BIGINT NULL,
+    error_message TEXT NULL,
+    requested_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_takeouts_tenant ON tenant_takeouts(tenant_id, started_at DESC);
+-- One takeout runs per tenant at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_takeouts_running ON tenant_takeouts(tenant_id) WHERE status = 'running';
+
+INSERT INTO permissions (name, description) VALUES
+('tenants:takeout', 'Export all of the tenant''s data and documents')
+ON CONFLICT (name) DO NOTHING;
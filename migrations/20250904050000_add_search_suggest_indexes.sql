@@ -0,0 +1,14 @@
+-- Search-as-you-type: trigram indexes serve substring and fuzzy matches on
+-- names, and pattern-ops indexes serve prefix matches on barcodes and order
+-- numbers (the leading characters of the order ID)
+-- Migration: 20250904050000_add_search_suggest_indexes.sql
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_distributors_name_trgm ON distributors USING GIN (LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_suppliers_name_trgm ON suppliers USING GIN (LOWER(name) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_products_barcode_prefix ON products (tenant_id, LOWER(barcode) text_pattern_ops)
+    WHERE barcode IS NOT NULL AND barcode != '';
+CREATE INDEX IF NOT EXISTS idx_orders_number_prefix ON orders (tenant_id, (id::text) text_pattern_ops);
@@ -0,0 +1,7 @@
+-- Global search: trigram indexes serve substring matches on product
+-- descriptions, order notes and invoice numbers
+-- Migration: 20250904060000_add_global_search_indexes.sql
+
+CREATE INDEX IF NOT EXISTS idx_products_description_trgm ON products USING GIN (LOWER(COALESCE(description, '')) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_orders_notes_trgm ON orders USING GIN (LOWER(COALESCE(notes, '')) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_invoices_number_trgm ON invoices USING GIN (LOWER(invoice_number) gin_trgm_ops);
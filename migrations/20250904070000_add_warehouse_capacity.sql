@@ -0,0 +1,21 @@
+-- Warehouse capacity: warehouses gain a storage volume and a count of pallet
+-- positions alongside their unit capacity, and products optionally record
+-- the size of one unit and how many fit on a pallet, so utilization can be
+-- computed from stock on hand
+-- Migration: 20250904070000_add_warehouse_capacity.sql
+
+ALTER TABLE warehouses
+    ADD COLUMN IF NOT EXISTS volume_capacity_m3 NUMERIC(12, 3) NULL CHECK (volume_capacity_m3 > 0),
+    ADD COLUMN IF NOT EXISTS pallet_positions INTEGER NULL CHECK (pallet_positions > 0);
+
+CREATE TABLE IF NOT EXISTS product_dimensions (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    length_cm NUMERIC(10, 2) NOT NULL CHECK (length_cm > 0),
+    width_cm NUMERIC(10, 2) NOT NULL CHECK (width_cm > 0),
+    height_cm NUMERIC(10, 2) NOT NULL CHECK (height_cm > 0),
+    units_per_pallet INTEGER NULL CHECK (units_per_pallet > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_dimensions_tenant ON product_dimensions(tenant_id);
@@ -0,0 +1,35 @@
+-- Inbound delivery appointments: supplier deliveries are booked into a time
+-- slot at a warehouse dock against the purchase orders they bring, so the
+-- receiving team can plan the day
+-- Migration: 20250904080000_add_inbound_appointments.sql
+
+CREATE TABLE IF NOT EXISTS inbound_appointments (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    -- Dock label, compared case-insensitively; bookings at different docks may overlap
+    dock VARCHAR(50) NOT NULL,
+    supplier_id UUID NULL REFERENCES suppliers(id) ON DELETE SET NULL,
+    order_ids UUID[] NOT NULL DEFAULT '{}',
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'cancelled')),
+    vehicle_number VARCHAR(30) NULL,
+    notes TEXT NULL,
+    cancel_reason TEXT NULL,
+    cancelled_at TIMESTAMPTZ NULL,
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_appointments_schedule ON inbound_appointments(tenant_id, warehouse_id, starts_at)
+    WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_inbound_appointments_orders ON inbound_appointments USING GIN (order_ids)
+    WHERE status = 'scheduled';
+
+INSERT INTO permissions (name, description) VALUES
+('appointments:read', 'View the inbound delivery schedule of warehouses'),
+('appointments:manage', 'Book, reschedule and cancel inbound delivery appointments')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,66 @@
+-- Branches: a tenant's legal entities or registered places of business, each
+-- with its own GSTIN. Warehouses belong to a branch, orders follow their
+-- warehouse's branch and invoices their order's, and invoices are numbered in
+-- a separate series per branch so each GSTIN's GST returns stand alone
+-- Migration: 20250904090000_add_branches.sql
+
+CREATE TABLE IF NOT EXISTS branches (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    -- Short code used in the branch's invoice numbers, e.g. 'MH01'
+    code VARCHAR(10) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    legal_name VARCHAR(255) NULL,
+    gstin VARCHAR(15) NOT NULL,
+    -- GST state code, the first two digits of the GSTIN
+    state_code CHAR(2) NOT NULL,
+    address TEXT NULL,
+    -- Set when a branch with warehouses, orders or invoices is deleted
+    archived_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_branches_tenant_code ON branches(tenant_id, UPPER(code));
+CREATE UNIQUE INDEX IF NOT EXISTS uq_branches_tenant_gstin ON branches(tenant_id, gstin);
+
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS branch_id UUID NULL REFERENCES branches(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS branch_id UUID NULL REFERENCES branches(id);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS branch_id UUID NULL REFERENCES branches(id);
+
+CREATE INDEX IF NOT EXISTS idx_warehouses_branch ON warehouses(tenant_id, branch_id);
+CREATE INDEX IF NOT EXISTS idx_orders_branch ON orders(tenant_id, branch_id);
+CREATE INDEX IF NOT EXISTS idx_invoices_branch ON invoices(tenant_id, branch_id, issued_date);
+
+-- An order belongs to the branch of its warehouse at the time it is placed
+-- or moved; moving a warehouse to another branch leaves past orders alone
+CREATE OR REPLACE FUNCTION set_order_branch()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.warehouse_id IS NOT DISTINCT FROM OLD.warehouse_id THEN
+        RETURN NEW;
+    END IF;
+    NEW.branch_id := (SELECT branch_id FROM warehouses WHERE id = NEW.warehouse_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_order_branch ON orders;
+CREATE TRIGGER trg_order_branch
+    BEFORE INSERT OR UPDATE OF warehouse_id ON orders
+    FOR EACH ROW EXECUTE FUNCTION set_order_branch();
+
+-- Invoice numbers of a branch run in their own monthly series
+CREATE TABLE IF NOT EXISTS branch_invoice_sequences (
+    branch_id UUID NOT NULL REFERENCES branches(id) ON DELETE CASCADE,
+    year_month VARCHAR(7) NOT NULL, -- Format: YYYY-MM
+    last_number INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (branch_id, year_month)
+);
+
+INSERT INTO permissions (name, description) VALUES
+('branches:read', 'View the tenant''s branches and their GSTINs'),
+('branches:manage', 'Create, update and archive branches')
+ON CONFLICT (name) DO NOTHING;
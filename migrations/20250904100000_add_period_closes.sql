@@ -0,0 +1,40 @@
+-- Fiscal period closing: once a month is closed for the tenant, or for one
+-- of its branches, invoices and payments dated in it can no longer be
+-- created, changed or cancelled. Orders are unaffected. Reopening needs its
+-- own permission, and every close and reopen is kept in period_close_events
+-- Migration: 20250904100000_add_period_closes.sql
+
+CREATE TABLE IF NOT EXISTS period_closes (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    -- NULL closes the period for the whole tenant, every branch included
+    branch_id UUID NULL REFERENCES branches(id) ON DELETE CASCADE,
+    -- First day of the closed month
+    period DATE NOT NULL,
+    notes TEXT NULL,
+    closed_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    closed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_period_closes_scope
+    ON period_closes(tenant_id, (COALESCE(branch_id, '00000000-0000-0000-0000-000000000000'::uuid)), period);
+
+-- Append-only history of closes and reopens
+CREATE TABLE IF NOT EXISTS period_close_events (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    branch_id UUID NULL REFERENCES branches(id) ON DELETE CASCADE,
+    period DATE NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('closed', 'reopened')),
+    reason TEXT NULL,
+    user_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_period_close_events_tenant ON period_close_events(tenant_id, created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+('periods:read', 'View closed accounting periods and their history'),
+('periods:close', 'Close accounting periods to lock their invoices and payments'),
+('periods:unlock', 'Reopen closed accounting periods')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,48 @@
+-- Approval delegation: an approver away for a few days routes their pending
+-- approvals of orders, purchase requisitions and stock adjustments
+-- (inventory reconciliations) to a delegate. While a delegation is active the
+-- delegate approves with the delegator's authority, the pending approvals
+-- inbox lists the items for them, and every approval they make is recorded
+-- against the delegation and reported to the delegator
+-- Migration: 20250904110000_add_approval_delegations.sql
+
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    delegator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Approval kinds routed: order, requisition, stock_adjustment
+    kinds TEXT[] NOT NULL,
+    -- Inclusive range of days, on the tenant's calendar
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    reason TEXT NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    revoked_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (delegator_id <> delegate_id),
+    CHECK (ends_on >= starts_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegator ON approval_delegations(tenant_id, delegator_id, ends_on) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate ON approval_delegations(tenant_id, delegate_id, ends_on) WHERE revoked_at IS NULL;
+
+-- Approvals made under a delegation, on the delegator's behalf
+CREATE TABLE IF NOT EXISTS delegated_approvals (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    delegation_id UUID NOT NULL REFERENCES approval_delegations(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    acted_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delegated_approvals_delegation ON delegated_approvals(delegation_id, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+('approvals:delegate', 'Route your own pending approvals to a delegate for a period'),
+('approvals:manage_delegations', 'Set up and revoke approval delegations for any user')
+ON CONFLICT (name) DO NOTHING;
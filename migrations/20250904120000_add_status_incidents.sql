@@ -0,0 +1,39 @@
+-- Platform incidents behind the public status page: platform admins record
+-- incidents against a component and post updates as they progress; the
+-- status endpoint derives each component's status from its open incidents
+-- Migration: 20250904120000_add_status_incidents.sql
+
+-- Incidents are platform-wide, not tenant data
+CREATE TABLE IF NOT EXISTS status_incidents (
+    id UUID PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    -- Component key: api, web, invoicing, notifications, integrations, reports
+    component VARCHAR(50) NOT NULL,
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('minor', 'major', 'critical')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('investigating', 'identified', 'monitoring', 'resolved')),
+    started_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ NULL,
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((status = 'resolved') = (resolved_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_incidents_open ON status_incidents(component) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_status_incidents_started ON status_incidents(started_at DESC);
+
+-- Timeline of an incident; the first update is posted with the incident
+CREATE TABLE IF NOT EXISTS status_incident_updates (
+    id UUID PRIMARY KEY,
+    incident_id UUID NOT NULL REFERENCES status_incidents(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_incident_updates_incident ON status_incident_updates(incident_id, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+('platform:manage_incidents', 'Can record platform incidents and post updates to the status page')
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,15 @@
+-- User emails are encrypted like party contacts. Their blind index is shared
+-- by every tenant, since the login email is checked for uniqueness across
+-- tenants
+-- Migration: 20250904130000_encrypt_user_emails.sql
+
+-- Ciphertext no longer fits the original width. Existing plaintext stays
+-- readable and is encrypted by the rotation job
+ALTER TABLE users
+    ALTER COLUMN email TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS email_bidx VARCHAR(64) NULL;
+
+-- users_tenant_email_unique only holds for plaintext rows; sealed rows are
+-- kept unique within the tenant by their index
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email_bidx ON users(tenant_id, email_bidx) WHERE email_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_email_bidx ON users(email_bidx) WHERE email_bidx IS NOT NULL;
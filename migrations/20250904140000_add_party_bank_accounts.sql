@@ -0,0 +1,18 @@
+-- Bank accounts of distributors and suppliers, for refunds, payouts and
+-- matching incoming transfers. Account numbers are encrypted by the
+-- application like contact PII, with a blind index for exact-match lookups;
+-- the IFSC only names the branch and is kept in plaintext
+-- Migration: 20250904140000_add_party_bank_accounts.sql
+
+ALTER TABLE distributors
+    ADD COLUMN IF NOT EXISTS bank_account_number TEXT NULL,
+    ADD COLUMN IF NOT EXISTS bank_account_number_bidx VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS bank_ifsc VARCHAR(11) NULL;
+
+ALTER TABLE suppliers
+    ADD COLUMN IF NOT EXISTS bank_account_number TEXT NULL,
+    ADD COLUMN IF NOT EXISTS bank_account_number_bidx VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS bank_ifsc VARCHAR(11) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_distributors_bank_account_number_bidx ON distributors(tenant_id, bank_account_number_bidx) WHERE bank_account_number_bidx IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_suppliers_bank_account_number_bidx ON suppliers(tenant_id, bank_account_number_bidx) WHERE bank_account_number_bidx IS NOT NULL;
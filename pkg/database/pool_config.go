@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolConfig tunes the connection pool; zero values keep the pgx defaults or
+// whatever the DSN sets
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// StatementCacheMode is the pgx query exec mode: cache_statement,
+	// cache_describe, describe_exec, exec or simple_protocol. Poolers in
+	// transaction mode, like PgBouncer, need exec or simple_protocol
+	StatementCacheMode string
+	// SlowQueryThreshold logs queries that take longer; 0 disables the log
+	SlowQueryThreshold time.Duration
+	// Observer, when set, also receives queries over the threshold and
+	// queries that failed
+	Observer QueryObserver
+}
+
+// QueryEvent is a finished query that was slow or failed. SQL is the
+// parameterized statement; arguments are never included
+type QueryEvent struct {
+	SQL      string
+	Duration time.Duration
+	Rows     int64
+	Err      error
+	Slow     bool
+}
+
+// QueryObserver receives slow and failing queries. It is called on the
+// query's goroutine, so it must not block
+type QueryObserver interface {
+	ObserveQuery(ctx context.Context, event QueryEvent)
+}
+
+type untracedKey struct{}
+
+// WithoutQueryTrace marks ctx so its queries are not observed, e.g. for an
+// observer writing its own records
+func WithoutQueryTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untracedKey{}, true)
+}
+
+var statementCacheModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// Validate reports settings the pool would reject or ignore
+func (cfg PoolConfig) Validate() error {
+	if cfg.MaxConns < 0 || cfg.MinConns < 0 {
+		return fmt.Errorf("pool connection counts cannot be negative")
+	}
+	if cfg.MaxConns > 0 && cfg.MinConns > cfg.MaxConns {
+		return fmt.Errorf("min conns %d exceeds max conns %d", cfg.MinConns, cfg.MaxConns)
+	}
+	if cfg.StatementCacheMode != "" {
+		if _, ok := statementCacheModes[cfg.StatementCacheMode]; !ok {
+			return fmt.Errorf("unknown statement cache mode %q", cfg.StatementCacheMode)
+		}
+	}
+	return nil
+}
+
+// Apply sets the configured values on a parsed pool config
+func (cfg PoolConfig) Apply(config *pgxpool.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.MaxConns > 0 {
+		config.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		config.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.StatementCacheMode != "" {
+		config.ConnConfig.DefaultQueryExecMode = statementCacheModes[cfg.StatementCacheMode]
+	}
+	if cfg.SlowQueryThreshold > 0 || cfg.Observer != nil {
+		config.ConnConfig.Tracer = &slowQueryTracer{threshold: cfg.SlowQueryThreshold, observer: cfg.Observer}
+	}
+	return nil
+}
+
+// Open creates a pool for dsn tuned by cfg. Like pgxpool.New it connects
+// lazily, so a database that is down surfaces on first use
+func Open(ctx context.Context, dsn string, cfg PoolConfig) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Apply(config); err != nil {
+		return nil, err
+	}
+	return pgxpool.NewWithConfig(ctx, config)
+}
+
+// slowQueries counts queries over the slow query threshold since startup
+var slowQueries atomic.Uint64
+
+// SlowQueryCount returns how many queries have exceeded the slow query threshold
+func SlowQueryCount() uint64 {
+	return slowQueries.Load()
+}
+
+type slowQueryStartKey struct{}
+
+type slowQueryStart struct {
+	sql   string
+	start time.Time
+}
+
+// slowQueryTracer logs the SQL of queries slower than the threshold and hands
+// them, with failed queries, to the observer. Arguments are left out since
+// they carry customer data
+type slowQueryTracer struct {
+	threshold time.Duration
+	observer  QueryObserver
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if untraced, _ := ctx.Value(untracedKey{}).(bool); untraced {
+		return ctx
+	}
+	return context.WithValue(ctx, slowQueryStartKey{}, slowQueryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	started, ok := ctx.Value(slowQueryStartKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(started.start)
+	slow := t.threshold > 0 && elapsed >= t.threshold
+	// Cancelled requests are the client's doing, not the query's
+	failed := data.Err != nil && !errors.Is(data.Err, context.Canceled)
+	if !slow && !failed {
+		return
+	}
+
+	if slow {
+		slowQueries.Add(1)
+		status := "ok"
+		if data.Err != nil {
+			status = data.Err.Error()
+		}
+		log.Printf("Slow query (%s, %s): %s", elapsed.Round(time.Millisecond), status, compactSQL(started.sql))
+	}
+	if t.observer != nil {
+		t.observer.ObserveQuery(ctx, QueryEvent{
+			SQL:      started.sql,
+			Duration: elapsed,
+			Rows:     data.CommandTag.RowsAffected(),
+			Err:      data.Err,
+			Slow:     slow,
+		})
+	}
+}
+
+// compactSQL collapses whitespace and truncates the statement for a single log line
+func compactSQL(sql string) string {
+	const maxLen = 500
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxLen {
+		sql = sql[:maxLen] + "..."
+	}
+	return sql
+}
@@ -0,0 +1,516 @@
+// Package qrcode encodes short byte strings, like payment URIs, as QR codes.
+// It covers byte mode and versions 1 to 10, which hold up to 271 bytes at
+// the lowest error correction level
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Level is the error correction level; higher levels survive more damage
+// but hold less data
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+// maxVersion is the largest symbol the encoder produces
+const maxVersion = 10
+
+// quietZone is the light border, in modules, scanners need around a symbol
+const quietZone = 4
+
+// ErrDataTooLong is returned when the data does not fit in a version 10 symbol
+var ErrDataTooLong = errors.New("qrcode: data too long")
+
+// Code is an encoded QR symbol
+type Code struct {
+	// Size is the width and height in modules, without the quiet zone
+	Size    int
+	modules []bool
+}
+
+// Dark reports whether the module at x, y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y*c.Size+x]
+}
+
+// blockLayout is how a version's codewords split into error correction
+// blocks at one level: count blocks of dataLen data codewords each, and
+// optionally count2 blocks one codeword longer
+type blockLayout struct {
+	ecLen   int
+	count   int
+	dataLen int
+	count2  int
+}
+
+// blockLayouts is indexed by version and then level
+var blockLayouts = [maxVersion + 1][4]blockLayout{
+	1:  {{7, 1, 19, 0}, {10, 1, 16, 0}, {13, 1, 13, 0}, {17, 1, 9, 0}},
+	2:  {{10, 1, 34, 0}, {16, 1, 28, 0}, {22, 1, 22, 0}, {28, 1, 16, 0}},
+	3:  {{15, 1, 55, 0}, {26, 1, 44, 0}, {18, 2, 17, 0}, {22, 2, 13, 0}},
+	4:  {{20, 1, 80, 0}, {18, 2, 32, 0}, {26, 2, 24, 0}, {16, 4, 9, 0}},
+	5:  {{26, 1, 108, 0}, {24, 2, 43, 0}, {18, 2, 15, 2}, {22, 2, 11, 2}},
+	6:  {{18, 2, 68, 0}, {16, 4, 27, 0}, {24, 4, 19, 0}, {28, 4, 15, 0}},
+	7:  {{20, 2, 78, 0}, {18, 4, 31, 0}, {18, 2, 14, 4}, {26, 4, 13, 1}},
+	8:  {{24, 2, 97, 0}, {22, 2, 38, 2}, {22, 4, 18, 2}, {26, 4, 14, 2}},
+	9:  {{30, 2, 116, 0}, {22, 3, 36, 2}, {20, 4, 16, 4}, {24, 4, 12, 4}},
+	10: {{18, 2, 68, 2}, {26, 4, 43, 1}, {24, 6, 19, 2}, {28, 6, 15, 2}},
+}
+
+// alignmentCenters are the row and column coordinates of the alignment
+// patterns of each version
+var alignmentCenters = [maxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// formatLevelBits are the level's two bits in the format information
+var formatLevelBits = [4]int{Low: 1, Medium: 0, Quartile: 3, High: 2}
+
+func (l blockLayout) dataCodewords() int {
+	return l.count*l.dataLen + l.count2*(l.dataLen+1)
+}
+
+// Encode encodes data at the given level in the smallest version it fits
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, errors.New("qrcode: invalid error correction level")
+	}
+	for version := 1; version <= maxVersion; version++ {
+		layout := blockLayouts[version][level]
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > 8*layout.dataCodewords() {
+			continue
+		}
+		codewords := addErrorCorrection(encodeData(data, countBits, layout.dataCodewords()), layout)
+		return newSymbol(version, level, codewords), nil
+	}
+	return nil, ErrDataTooLong
+}
+
+// encodeData packs the byte mode segment and pads it to capacity codewords
+func encodeData(data []byte, countBits, capacity int) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits)
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	terminator := 8*capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	codewords := bits.bytes()
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// addErrorCorrection splits the data into blocks, appends each block's
+// error correction codewords and interleaves the result
+func addErrorCorrection(data []byte, layout blockLayout) []byte {
+	divisor := reedSolomonDivisor(layout.ecLen)
+	var blocks, ecBlocks [][]byte
+	for i := 0; i < layout.count+layout.count2; i++ {
+		n := layout.dataLen
+		if i >= layout.count {
+			n++
+		}
+		block := data[:n]
+		data = data[n:]
+		blocks = append(blocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i <= layout.dataLen; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecLen; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// symbol is a symbol being laid out; function modules are the finder,
+// timing, alignment, format and version patterns that masks skip
+type symbol struct {
+	size     int
+	modules  []bool
+	function []bool
+}
+
+func (s *symbol) set(x, y int, dark bool) {
+	s.modules[y*s.size+x] = dark
+	s.function[y*s.size+x] = true
+}
+
+func newSymbol(version int, level Level, codewords []byte) *Code {
+	size := 17 + 4*version
+	s := &symbol{size: size, modules: make([]bool, size*size), function: make([]bool, size*size)}
+
+	s.drawFunctionPatterns(version)
+	s.drawCodewords(codewords)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		s.applyMask(mask)
+		s.drawFormat(level, mask)
+		if penalty := s.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		s.applyMask(mask)
+	}
+	s.applyMask(bestMask)
+	s.drawFormat(level, bestMask)
+
+	return &Code{Size: size, modules: s.modules}
+}
+
+func (s *symbol) drawFunctionPatterns(version int) {
+	for i := 0; i < s.size; i++ {
+		s.set(6, i, i%2 == 0)
+		s.set(i, 6, i%2 == 0)
+	}
+
+	for _, corner := range [][2]int{{3, 3}, {s.size - 4, 3}, {3, s.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || x >= s.size || y < 0 || y >= s.size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				s.set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	centers := alignmentCenters[version]
+	last := len(centers) - 1
+	for i, cy := range centers {
+		for j, cx := range centers {
+			// The three corners hold the finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					s.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas until the mask is chosen
+	s.drawFormat(Low, 0)
+
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 != 0
+			a, b := s.size-11+i%3, i/3
+			s.set(a, b, dark)
+			s.set(b, a, dark)
+		}
+	}
+}
+
+// drawFormat writes both copies of the level and mask information
+func (s *symbol) drawFormat(level Level, mask int) {
+	data := formatLevelBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		s.set(8, i, bit(i))
+	}
+	s.set(8, 7, bit(6))
+	s.set(8, 8, bit(7))
+	s.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		s.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		s.set(s.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		s.set(8, s.size-15+i, bit(i))
+	}
+	s.set(8, s.size-8, true)
+}
+
+// drawCodewords fills the data area in the two module wide zigzag from the
+// bottom right corner; modules left over stay light
+func (s *symbol) drawCodewords(codewords []byte) {
+	i := 0
+	for right := s.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < s.size; vert++ {
+			y := vert
+			if upward {
+				y = s.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if s.function[y*s.size+x] || i >= 8*len(codewords) {
+					continue
+				}
+				s.modules[y*s.size+x] = (codewords[i/8]>>(7-i%8))&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules the mask selects; applying it twice
+// undoes it
+func (s *symbol) applyMask(mask int) {
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !s.function[y*s.size+x] {
+				s.modules[y*s.size+x] = !s.modules[y*s.size+x]
+			}
+		}
+	}
+}
+
+// penalty scores a masked symbol by the rules of the standard: long runs,
+// 2x2 blocks, finder-like patterns and an unbalanced dark ratio
+func (s *symbol) penalty() int {
+	dark := func(x, y int) bool { return s.modules[y*s.size+x] }
+	penalty := 0
+	for _, transpose := range []bool{false, true} {
+		for a := 0; a < s.size; a++ {
+			line := make([]bool, s.size)
+			for b := 0; b < s.size; b++ {
+				if transpose {
+					line[b] = dark(a, b)
+				} else {
+					line[b] = dark(b, a)
+				}
+			}
+			penalty += linePenalty(line)
+		}
+	}
+
+	darkCount := 0
+	for y := 0; y < s.size; y++ {
+		for x := 0; x < s.size; x++ {
+			if dark(x, y) {
+				darkCount++
+			}
+			if x+1 < s.size && y+1 < s.size {
+				c := dark(x, y)
+				if c == dark(x+1, y) && c == dark(x, y+1) && c == dark(x+1, y+1) {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := s.size * s.size
+	penalty += abs(darkCount*100/total-50) / 5 * 10
+	return penalty
+}
+
+var finderLikePatterns = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty scores one row or column for runs and finder-like patterns
+func linePenalty(line []bool) int {
+	penalty := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += 3 + run - 5
+		}
+		run = 1
+	}
+	// The quiet zone counts as light on both ends
+	padded := make([]bool, len(line)+2*quietZone)
+	copy(padded[quietZone:], line)
+	for i := 0; i+11 <= len(padded); i++ {
+		for _, pattern := range finderLikePatterns {
+			matched := true
+			for j, dark := range pattern {
+				if padded[i+j] != dark {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				penalty += 40
+			}
+		}
+	}
+	return penalty
+}
+
+// Image renders the symbol with its quiet zone, scale pixels per module
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	width := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, color.Gray{})
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG renders the symbol as a PNG image, scale pixels per module
+func (c *Code) PNG(scale int) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, c.Image(scale)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 != 0)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, (len(b)+7)/8)
+	for i, bit := range b {
+		if bit {
+			result[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor is the generator polynomial of the given degree,
+// highest coefficient first with the leading 1 left out
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder is the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// HELLO WORLD at 1-M, from the worked example of the standard
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	assert.Equal(t, expected, reedSolomonRemainder(data, reedSolomonDivisor(10)))
+}
+
+func TestEncodeData(t *testing.T) {
+	codewords := encodeData([]byte("ab"), 8, 16)
+	require.Len(t, codewords, 16)
+	// Mode 0100, count 00000010, then the bytes, terminator and padding
+	assert.Equal(t, []byte{0x40, 0x26, 0x16, 0x20, 0xEC, 0x11, 0xEC}, codewords[:7])
+}
+
+func TestEncodePicksSmallestVersion(t *testing.T) {
+	code, err := Encode([]byte("upi://pay"), Medium)
+	require.NoError(t, err)
+	assert.Equal(t, 21, code.Size)
+
+	code, err = Encode([]byte(strings.Repeat("x", 150)), Medium)
+	require.NoError(t, err)
+	assert.Equal(t, 17+4*8, code.Size)
+
+	code, err = Encode([]byte(strings.Repeat("x", 271)), Low)
+	require.NoError(t, err)
+	assert.Equal(t, 57, code.Size)
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	_, err := Encode([]byte(strings.Repeat("x", 272)), Low)
+	assert.ErrorIs(t, err, ErrDataTooLong)
+	_, err = Encode([]byte(strings.Repeat("x", 214)), Medium)
+	assert.ErrorIs(t, err, ErrDataTooLong)
+}
+
+func TestEncodeFunctionPatterns(t *testing.T) {
+	code, err := Encode([]byte("upi://pay?pa=shop@upi&am=1180.00&cu=INR"), Medium)
+	require.NoError(t, err)
+
+	// Finder pattern rings in the three corners
+	for _, corner := range [][2]int{{0, 0}, {code.Size - 7, 0}, {0, code.Size - 7}} {
+		for i := 0; i < 7; i++ {
+			assert.True(t, code.Dark(corner[0]+i, corner[1]))
+			assert.True(t, code.Dark(corner[0], corner[1]+i))
+		}
+		assert.False(t, code.Dark(corner[0]+1, corner[1]+1))
+		assert.True(t, code.Dark(corner[0]+3, corner[1]+3))
+	}
+	// Timing patterns alternate between the finders
+	for i := 8; i < code.Size-8; i++ {
+		assert.Equal(t, i%2 == 0, code.Dark(i, 6))
+		assert.Equal(t, i%2 == 0, code.Dark(6, i))
+	}
+	assert.True(t, code.Dark(8, code.Size-8))
+
+	// Both copies of the format information agree
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= bit(code.Dark(8, i)) << i
+	}
+	first |= bit(code.Dark(8, 7))<<6 | bit(code.Dark(8, 8))<<7 | bit(code.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(code.Dark(14-i, 8)) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(code.Dark(code.Size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(code.Dark(8, code.Size-15+i)) << i
+	}
+	assert.Equal(t, first, second)
+	assert.Equal(t, formatLevelBits[Medium], (first^0x5412)>>13)
+}
+
+func TestPNG(t *testing.T) {
+	code, err := Encode([]byte("upi://pay?pa=shop@upi"), Medium)
+	require.NoError(t, err)
+
+	data, err := code.PNG(4)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	width := (code.Size + 2*quietZone) * 4
+	assert.Equal(t, width, img.Bounds().Dx())
+	assert.Equal(t, width, img.Bounds().Dy())
+
+	r, _, _, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0xFFFF), r, "quiet zone is light")
+	r, _, _, _ = img.At(quietZone*4, quietZone*4).RGBA()
+	assert.Equal(t, uint32(0), r, "finder corner is dark")
+}
+
+func bit(dark bool) int {
+	if dark {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SeededTenant is a tenant with an admin user holding every permission
+type SeededTenant struct {
+	TenantID uuid.UUID
+	UserID   uuid.UUID
+	Email    string
+	Password string
+	Token    string
+}
+
+// SeedTenant creates an isolated tenant with an admin user and issues it an access token.
+// The tenant's rows are removed when the test finishes.
+func (h *Harness) SeedTenant(t testing.TB) *SeededTenant {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Now()
+	suffix := uuid.New().String()[:8]
+
+	seeded := &SeededTenant{
+		TenantID: uuid.New(),
+		UserID:   uuid.New(),
+		Email:    "admin-" + suffix + "@integration.test",
+		Password: "integration-pass",
+	}
+	roleID := uuid.New()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(seeded.Password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{
+			`INSERT INTO tenants (id, name, subdomain, status, created_at, updated_at) VALUES ($1, $2, $3, 'active', $4, $4)`,
+			[]interface{}{seeded.TenantID, "Integration " + suffix, "it-" + suffix, now},
+		},
+		{
+			`INSERT INTO roles (id, tenant_id, name, description, created_at, updated_at) VALUES ($1, $2, 'admin', 'Integration admin', $3, $3)`,
+			[]interface{}{roleID, seeded.TenantID, now},
+		},
+		{
+			`INSERT INTO role_permissions (role_id, permission_id, created_at) SELECT $1, id, $2 FROM permissions`,
+			[]interface{}{roleID, now},
+		},
+		{
+			`INSERT INTO users (id, tenant_id, email, password_hash, first_name, last_name, status, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, 'Integration', 'Admin', 'active', $5, $5)`,
+			[]interface{}{seeded.UserID, seeded.TenantID, seeded.Email, string(hash), now},
+		},
+		{
+			`INSERT INTO user_roles (user_id, role_id, created_at) VALUES ($1, $2, $3)`,
+			[]interface{}{seeded.UserID, roleID, now},
+		},
+	}
+	for _, stmt := range statements {
+		if _, err := h.Pool.Exec(ctx, stmt.query, stmt.args...); err != nil {
+			t.Fatalf("Failed to seed tenant: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		// Cascading foreign keys remove the tenant's users, roles and business data
+		h.Pool.Exec(context.Background(), `DELETE FROM tenants WHERE id = $1`, seeded.TenantID)
+	})
+
+	// Mint the token directly: login only resolves users in the built-in tenants
+	tokens, err := h.App.Auth.GenerateTokens(ctx, seeded.UserID, seeded.TenantID, nil)
+	if err != nil {
+		t.Fatalf("Failed to issue token for seeded tenant: %v", err)
+	}
+	seeded.Token = tokens.AccessToken
+
+	return seeded
+}
@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delegationRepoStub stores created delegations and returns a fixed set as
+// active; the rest of the repository is left unimplemented
+type delegationRepoStub struct {
+	repositories.ApprovalDelegationRepository
+	created []*models.ApprovalDelegation
+	active  []*models.ApprovalDelegation
+}
+
+func (r *delegationRepoStub) Create(ctx context.Context, delegation *models.ApprovalDelegation) (*models.ApprovalDelegation, error) {
+	r.created = append(r.created, delegation)
+	return nil, nil
+}
+
+func (r *delegationRepoStub) Active(ctx context.Context, tenantID, userID uuid.UUID, day time.Time) ([]*models.ApprovalDelegation, error) {
+	return r.active, nil
+}
+
+// delegationUsersStub finds every user in the tenant
+type delegationUsersStub struct {
+	repositories.UserRepository
+}
+
+func (delegationUsersStub) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.User, error) {
+	return &models.User{ID: id, TenantID: tenantID, Email: id.String() + "@example.com"}, nil
+}
+
+func delegationToday(tenantID uuid.UUID) time.Time {
+	return services.TenantCalendarOrDefault(context.Background(), nil, tenantID).Today(time.Now())
+}
+
+func TestApprovalDelegationCoversItsWindowInclusively(t *testing.T) {
+	startsOn := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	endsOn := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	delegation := &models.ApprovalDelegation{
+		Kinds:    []string{models.ApprovalKindOrder},
+		StartsOn: startsOn,
+		EndsOn:   endsOn,
+	}
+
+	assert.False(t, delegation.Covers(models.ApprovalKindOrder, startsOn.AddDate(0, 0, -1)), "day before the start")
+	assert.True(t, delegation.Covers(models.ApprovalKindOrder, startsOn), "first day")
+	assert.True(t, delegation.Covers(models.ApprovalKindOrder, endsOn), "last day")
+	assert.False(t, delegation.Covers(models.ApprovalKindOrder, endsOn.AddDate(0, 0, 1)), "day after the end")
+	assert.False(t, delegation.Covers(models.ApprovalKindRequisition, startsOn), "kind not delegated")
+
+	revokedAt := startsOn
+	delegation.RevokedAt = &revokedAt
+	assert.False(t, delegation.Covers(models.ApprovalKindOrder, endsOn), "revoked")
+}
+
+func TestApprovalDelegationCreateWindowBoundaries(t *testing.T) {
+	tenantID := uuid.New()
+	today := delegationToday(tenantID)
+	date := func(days int) string { return today.AddDate(0, 0, days).Format("2006-01-02") }
+
+	tests := []struct {
+		name     string
+		startsOn string
+		endsOn   string
+		valid    bool
+	}{
+		{name: "starts today", endsOn: date(0), valid: true},
+		{name: "starts yesterday", startsOn: date(-1), endsOn: date(3)},
+		{name: "ends on the start day", startsOn: date(5), endsOn: date(5), valid: true},
+		{name: "ends before the start", startsOn: date(5), endsOn: date(4)},
+		{name: "runs the longest allowed", startsOn: date(1), endsOn: date(90), valid: true},
+		{name: "runs a day too long", startsOn: date(1), endsOn: date(91)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &delegationRepoStub{}
+			svc := services.NewApprovalDelegationService(repo, delegationUsersStub{}, nil, nil, nil)
+			delegatorID := uuid.New()
+
+			delegation, err := svc.Create(context.Background(), tenantID, &delegatorID, &models.ApprovalDelegationRequest{
+				DelegatorID: &delegatorID,
+				DelegateID:  uuid.New(),
+				StartsOn:    tt.startsOn,
+				EndsOn:      tt.endsOn,
+			})
+
+			if !tt.valid {
+				assert.ErrorIs(t, err, services.ErrInvalidApprovalDelegation)
+				assert.Empty(t, repo.created)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.endsOn, delegation.EndsOn.Format("2006-01-02"))
+			assert.Len(t, repo.created, 1)
+		})
+	}
+}
+
+func TestApprovalDelegationActingForOnlyWithinTheWindow(t *testing.T) {
+	tenantID, delegateID := uuid.New(), uuid.New()
+	today := delegationToday(tenantID)
+	window := func(startsIn, endsIn int) *models.ApprovalDelegation {
+		return &models.ApprovalDelegation{
+			ID:          uuid.New(),
+			TenantID:    tenantID,
+			DelegatorID: uuid.New(),
+			DelegateID:  delegateID,
+			Kinds:       []string{models.ApprovalKindOrder},
+			StartsOn:    today.AddDate(0, 0, startsIn),
+			EndsOn:      today.AddDate(0, 0, endsIn),
+		}
+	}
+	ended, notStarted, endsToday := window(-5, -1), window(1, 5), window(-3, 0)
+	repo := &delegationRepoStub{active: []*models.ApprovalDelegation{ended, notStarted, endsToday}}
+	svc := services.NewApprovalDelegationService(repo, delegationUsersStub{}, nil, nil, nil)
+
+	delegation, err := svc.ActingFor(context.Background(), tenantID, delegateID, models.ApprovalKindOrder, "")
+	require.NoError(t, err)
+	require.NotNil(t, delegation)
+	assert.Equal(t, endsToday.ID, delegation.ID)
+
+	repo.active = []*models.ApprovalDelegation{ended, notStarted}
+	delegation, err = svc.ActingFor(context.Background(), tenantID, delegateID, models.ApprovalKindOrder, "")
+	require.NoError(t, err)
+	assert.Nil(t, delegation)
+}
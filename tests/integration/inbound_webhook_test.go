@@ -0,0 +1,448 @@
+package integration
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookRepoStub holds webhooks by slug and keeps the delivery log, with an
+// event ID taken while its delivery is processed; the rest of the repository
+// is left unimplemented
+type webhookRepoStub struct {
+	repositories.InboundWebhookRepository
+	webhooks   map[string]*models.InboundWebhook
+	invoices   map[string]uuid.UUID
+	deliveries []*models.InboundWebhookDelivery
+	processed  map[string]*models.InboundWebhookDelivery
+}
+
+func newWebhookRepoStub(webhooks ...*models.InboundWebhook) *webhookRepoStub {
+	r := &webhookRepoStub{
+		webhooks:  map[string]*models.InboundWebhook{},
+		invoices:  map[string]uuid.UUID{},
+		processed: map[string]*models.InboundWebhookDelivery{},
+	}
+	for _, webhook := range webhooks {
+		r.webhooks[webhook.Slug] = webhook
+	}
+	return r
+}
+
+func (r *webhookRepoStub) GetBySlug(ctx context.Context, slug string) (*models.InboundWebhook, error) {
+	return r.webhooks[slug], nil
+}
+
+func (r *webhookRepoStub) ReserveDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) (bool, error) {
+	if delivery.EventID != nil {
+		if _, taken := r.processed[*delivery.EventID]; taken {
+			return false, nil
+		}
+		r.processed[*delivery.EventID] = delivery
+	}
+	delivery.Status = models.InboundDeliveryProcessed
+	r.deliveries = append(r.deliveries, delivery)
+	return true, nil
+}
+
+func (r *webhookRepoStub) RecordDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) error {
+	r.deliveries = append(r.deliveries, delivery)
+	return nil
+}
+
+func (r *webhookRepoStub) UpdateDelivery(ctx context.Context, delivery *models.InboundWebhookDelivery) error {
+	if delivery.EventID != nil && delivery.Status != models.InboundDeliveryProcessed {
+		delete(r.processed, *delivery.EventID)
+	}
+	return nil
+}
+
+func (r *webhookRepoStub) GetProcessedDelivery(ctx context.Context, webhookID uuid.UUID, eventID string) (*models.InboundWebhookDelivery, error) {
+	return r.processed[eventID], nil
+}
+
+func (r *webhookRepoStub) FindInvoiceByNumber(ctx context.Context, tenantID uuid.UUID, number string) (*uuid.UUID, error) {
+	id, ok := r.invoices[number]
+	if !ok {
+		return nil, nil
+	}
+	return &id, nil
+}
+
+// webhookProductsStub finds products by ID or barcode
+type webhookProductsStub struct {
+	repositories.ProductRepository
+	products []*models.Product
+}
+
+func (r *webhookProductsStub) GetByID(ctx context.Context, tenantID, id uuid.UUID) (*models.Product, error) {
+	for _, product := range r.products {
+		if product.ID == id {
+			return product, nil
+		}
+	}
+	return nil, errors.New("product not found")
+}
+
+func (r *webhookProductsStub) GetByBarcode(ctx context.Context, tenantID uuid.UUID, barcode string) (*models.Product, error) {
+	for _, product := range r.products {
+		if product.Barcode != nil && *product.Barcode == barcode {
+			return product, nil
+		}
+	}
+	return nil, errors.New("product not found")
+}
+
+// webhookOrdersStub records created orders, failing while err is set
+type webhookOrdersStub struct {
+	services.OrderServiceInterface
+	created []*models.Order
+	err     error
+}
+
+func (s *webhookOrdersStub) CreateOrder(ctx context.Context, tenantID uuid.UUID, order *models.Order) error {
+	if s.err != nil {
+		return s.err
+	}
+	order.ID = uuid.New()
+	s.created = append(s.created, order)
+	return nil
+}
+
+// webhookInvoicesStub holds invoices and records the statuses set on them
+type webhookInvoicesStub struct {
+	services.InvoiceServiceInterface
+	invoices map[uuid.UUID]*models.Invoice
+	paid     []uuid.UUID
+}
+
+func (s *webhookInvoicesStub) GetInvoiceByID(ctx context.Context, tenantID, invoiceID uuid.UUID) (*models.Invoice, error) {
+	invoice, ok := s.invoices[invoiceID]
+	if !ok {
+		return nil, errors.New("invoice not found")
+	}
+	return invoice, nil
+}
+
+func (s *webhookInvoicesStub) UpdateInvoiceStatus(ctx context.Context, tenantID, invoiceID uuid.UUID, status string) error {
+	s.invoices[invoiceID].Status = status
+	s.paid = append(s.paid, invoiceID)
+	return nil
+}
+
+type webhookTest struct {
+	repo     *webhookRepoStub
+	products *webhookProductsStub
+	orders   *webhookOrdersStub
+	invoices *webhookInvoicesStub
+	svc      services.InboundWebhookService
+}
+
+func newWebhookTest(webhooks ...*models.InboundWebhook) *webhookTest {
+	tt := &webhookTest{
+		repo:     newWebhookRepoStub(webhooks...),
+		products: &webhookProductsStub{},
+		orders:   &webhookOrdersStub{},
+		invoices: &webhookInvoicesStub{invoices: map[uuid.UUID]*models.Invoice{}},
+	}
+	tt.svc = services.NewInboundWebhookService(tt.repo, tt.products, nil, nil, tt.orders, tt.invoices)
+	return tt
+}
+
+func orderWebhook(verification string) *models.InboundWebhook {
+	warehouseID := uuid.New()
+	webhook := &models.InboundWebhook{
+		ID:              uuid.New(),
+		TenantID:        uuid.New(),
+		Name:            "Storefront",
+		Slug:            "storefront-orders",
+		Action:          models.InboundWebhookActionCreateOrder,
+		Verification:    verification,
+		Secret:          "whsec_0123456789abcdef",
+		SignatureHeader: "X-Webhook-Secret",
+		WarehouseID:     &warehouseID,
+		IsActive:        true,
+	}
+	if verification == models.InboundWebhookVerificationHMACSHA256 {
+		webhook.SignatureHeader = "X-Webhook-Signature"
+	}
+	return webhook
+}
+
+func signWebhook(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookHeader(name, value string) http.Header {
+	header := http.Header{}
+	if value != "" {
+		header.Set(name, value)
+	}
+	return header
+}
+
+func (tt *webhookTest) addProduct(barcode string, unitPrice float64) *models.Product {
+	product := &models.Product{ID: uuid.New(), Name: "Urea 45kg", Barcode: &barcode, UnitPrice: unitPrice}
+	tt.products.products = append(tt.products.products, product)
+	return product
+}
+
+func TestInboundWebhookVerifiesSignatures(t *testing.T) {
+	const body = `{"event_id":"evt_1","sku":"8901234567890","quantity":2}`
+	secret := orderWebhook("").Secret
+	signature := signWebhook(secret, body)
+
+	tests := []struct {
+		name         string
+		verification string
+		signature    string
+		body         string
+		accepted     bool
+	}{
+		{name: "shared secret", verification: models.InboundWebhookVerificationSharedSecret, signature: secret, accepted: true},
+		{name: "shared secret with spaces", verification: models.InboundWebhookVerificationSharedSecret, signature: " " + secret + " ", accepted: true},
+		{name: "wrong shared secret", verification: models.InboundWebhookVerificationSharedSecret, signature: "whsec_guessed"},
+		{name: "missing shared secret", verification: models.InboundWebhookVerificationSharedSecret},
+		{name: "HMAC", verification: models.InboundWebhookVerificationHMACSHA256, signature: signature, accepted: true},
+		{name: "prefixed uppercase HMAC", verification: models.InboundWebhookVerificationHMACSHA256, signature: "sha256=" + strings.ToUpper(signature), accepted: true},
+		{name: "HMAC with another secret", verification: models.InboundWebhookVerificationHMACSHA256, signature: signWebhook("whsec_other", body)},
+		{name: "HMAC of another body", verification: models.InboundWebhookVerificationHMACSHA256, signature: signature, body: `{"event_id":"evt_1","sku":"8901234567890","quantity":200}`},
+		{name: "shared secret sent to an HMAC webhook", verification: models.InboundWebhookVerificationHMACSHA256, signature: secret},
+		{name: "missing HMAC", verification: models.InboundWebhookVerificationHMACSHA256},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			webhook := orderWebhook(tc.verification)
+			tt := newWebhookTest(webhook)
+			tt.addProduct("8901234567890", 266.5)
+			sent := body
+			if tc.body != "" {
+				sent = tc.body
+			}
+
+			delivery, err := tt.svc.Receive(context.Background(), webhook.Slug, webhookHeader(webhook.SignatureHeader, tc.signature), []byte(sent), "203.0.113.7")
+			require.NoError(t, err)
+			require.Len(t, tt.repo.deliveries, 1)
+			assert.Same(t, delivery, tt.repo.deliveries[0])
+			assert.Equal(t, "203.0.113.7", *delivery.SourceIP)
+
+			if !tc.accepted {
+				assert.Equal(t, models.InboundDeliveryRejected, delivery.Status)
+				assert.Equal(t, "signature verification failed", *delivery.Error)
+				assert.Nil(t, delivery.EventID, "rejected deliveries do not take the event ID")
+				assert.Empty(t, tt.orders.created)
+				return
+			}
+			assert.Equal(t, models.InboundDeliveryProcessed, delivery.Status)
+			assert.Nil(t, delivery.Error)
+			assert.Len(t, tt.orders.created, 1)
+		})
+	}
+}
+
+func TestInboundWebhookUnknownAndInactiveSlugs(t *testing.T) {
+	webhook := orderWebhook(models.InboundWebhookVerificationSharedSecret)
+	tt := newWebhookTest(webhook)
+	header := webhookHeader(webhook.SignatureHeader, webhook.Secret)
+
+	_, err := tt.svc.Receive(context.Background(), "no-such-hook", header, []byte(`{}`), "")
+	assert.ErrorIs(t, err, services.ErrInboundWebhookNotFound)
+
+	webhook.IsActive = false
+	_, err = tt.svc.Receive(context.Background(), webhook.Slug, header, []byte(`{}`), "")
+	assert.ErrorIs(t, err, services.ErrInboundWebhookNotFound)
+	assert.Empty(t, tt.repo.deliveries, "nothing is logged against unknown or inactive webhooks")
+}
+
+func TestInboundWebhookDuplicateEventsPointAtTheOriginal(t *testing.T) {
+	ctx := context.Background()
+	webhook := orderWebhook(models.InboundWebhookVerificationSharedSecret)
+	tt := newWebhookTest(webhook)
+	tt.addProduct("8901234567890", 266.5)
+	header := webhookHeader(webhook.SignatureHeader, webhook.Secret)
+	body := []byte(`{"event_id":"evt_42","sku":"8901234567890","quantity":3}`)
+
+	// A failed delivery frees its event ID, so the sender's retry is processed
+	tt.orders.err = errors.New("insufficient stock")
+	failed, err := tt.svc.Receive(ctx, webhook.Slug, header, body, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.InboundDeliveryFailed, failed.Status)
+	assert.Equal(t, "insufficient stock", *failed.Error)
+	assert.Nil(t, failed.OrderID)
+
+	tt.orders.err = nil
+	first, err := tt.svc.Receive(ctx, webhook.Slug, header, body, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.InboundDeliveryProcessed, first.Status)
+	assert.Equal(t, "evt_42", *first.EventID)
+	require.NotNil(t, first.OrderID)
+
+	// Once processed, the same event is logged as a duplicate of it
+	second, err := tt.svc.Receive(ctx, webhook.Slug, header, body, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.InboundDeliveryDuplicate, second.Status)
+	assert.Equal(t, first.OrderID, second.OrderID)
+	assert.Len(t, tt.orders.created, 1)
+
+	// Events without an ID cannot be told apart and are all processed
+	for i := 0; i < 2; i++ {
+		delivery, err := tt.svc.Receive(ctx, webhook.Slug, header, []byte(`{"sku":"8901234567890","quantity":1}`), "")
+		require.NoError(t, err)
+		assert.Equal(t, models.InboundDeliveryProcessed, delivery.Status)
+	}
+	assert.Len(t, tt.orders.created, 3)
+	assert.Len(t, tt.repo.deliveries, 5)
+}
+
+func TestInboundWebhookMapsPayloadsToOrders(t *testing.T) {
+	webhook := orderWebhook(models.InboundWebhookVerificationSharedSecret)
+	webhook.FieldMapping = models.InboundWebhookFieldMapping{
+		EventID:   "id",
+		SKU:       "data.item.sku",
+		Quantity:  "data.item.qty",
+		UnitPrice: "data.item.price",
+		Note:      "data.remarks",
+	}
+	header := webhookHeader(webhook.SignatureHeader, webhook.Secret)
+	distributorID, warehouseID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+		check   func(t *testing.T, tt *webhookTest, order *models.Order)
+	}{
+		{
+			name: "product by barcode at the product's price",
+			body: `{"id":"ord_1","data":{"item":{"sku":"8901234567890","qty":"4"},"remarks":"Deliver before noon"}}`,
+			check: func(t *testing.T, tt *webhookTest, order *models.Order) {
+				assert.Equal(t, tt.products.products[0].ID, order.ProductID)
+				assert.Equal(t, 4, order.Quantity)
+				assert.Equal(t, 266.5, order.UnitPrice)
+				assert.Equal(t, "sales", order.OrderType)
+				assert.Equal(t, webhook.TenantID, order.TenantID)
+				assert.Equal(t, *webhook.WarehouseID, order.WarehouseID, "the webhook's default warehouse")
+				assert.Nil(t, order.DistributorID)
+				assert.Equal(t, "Received by webhook Storefront event ord_1: Deliver before noon", *order.Notes)
+			},
+		},
+		{
+			name: "product by ID at the sent price and warehouse",
+			body: `{"data":{"item":{"sku":"{product}","qty":2,"price":250},"warehouse_id":"` + warehouseID.String() + `"},"distributor_id":"` + distributorID.String() + `"}`,
+			check: func(t *testing.T, tt *webhookTest, order *models.Order) {
+				assert.Equal(t, tt.products.products[0].ID, order.ProductID)
+				assert.Equal(t, 250.0, order.UnitPrice)
+				assert.Equal(t, *webhook.WarehouseID, order.WarehouseID, "warehouse_id is only read at its mapped path")
+				assert.Equal(t, &distributorID, order.DistributorID)
+				assert.Equal(t, "Received by webhook Storefront", *order.Notes)
+			},
+		},
+		{name: "unknown SKU", body: `{"data":{"item":{"sku":"0000000000000","qty":1}}}`, wantErr: `no product matches SKU "0000000000000"`},
+		{name: "missing SKU", body: `{"sku":"8901234567890","quantity":1}`, wantErr: `product SKU not found at "data.item.sku"`},
+		{name: "fractional quantity", body: `{"data":{"item":{"sku":"8901234567890","qty":1.5}}}`, wantErr: "quantity must be a positive whole number"},
+		{name: "zero quantity", body: `{"data":{"item":{"sku":"8901234567890","qty":0}}}`, wantErr: "quantity must be a positive whole number"},
+		{name: "negative price", body: `{"data":{"item":{"sku":"8901234567890","qty":1,"price":-1}}}`, wantErr: "invalid unit price"},
+		{name: "malformed distributor", body: `{"data":{"item":{"sku":"8901234567890","qty":1}},"distributor_id":"ramesh"}`, wantErr: `invalid distributor ID at "distributor_id"`},
+		{name: "not a JSON object", body: `[{"sku":"8901234567890"}]`, wantErr: "payload is not a JSON object"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newWebhookTest(webhook)
+			product := tt.addProduct("8901234567890", 266.5)
+			body := strings.ReplaceAll(tc.body, "{product}", product.ID.String())
+
+			delivery, err := tt.svc.Receive(context.Background(), webhook.Slug, header, []byte(body), "")
+			require.NoError(t, err)
+			assert.Equal(t, body, delivery.Payload)
+
+			if tc.wantErr != "" {
+				assert.Equal(t, models.InboundDeliveryFailed, delivery.Status)
+				assert.Equal(t, tc.wantErr, *delivery.Error)
+				assert.Empty(t, tt.orders.created)
+				return
+			}
+			assert.Equal(t, models.InboundDeliveryProcessed, delivery.Status)
+			require.Len(t, tt.orders.created, 1)
+			assert.Equal(t, &tt.orders.created[0].ID, delivery.OrderID)
+			tc.check(t, tt, tt.orders.created[0])
+		})
+	}
+}
+
+func TestInboundWebhookMapsPayloadsToInvoicePayments(t *testing.T) {
+	webhook := &models.InboundWebhook{
+		ID:              uuid.New(),
+		TenantID:        uuid.New(),
+		Name:            "UPI gateway",
+		Slug:            "upi-payments",
+		Action:          models.InboundWebhookActionMarkInvoicePaid,
+		Verification:    models.InboundWebhookVerificationHMACSHA256,
+		Secret:          "whsec_upi",
+		SignatureHeader: "X-Webhook-Signature",
+		FieldMapping:    models.InboundWebhookFieldMapping{InvoiceNumber: "payment.reference", Amount: "payment.amount"},
+		IsActive:        true,
+	}
+	invoiceID := uuid.New()
+
+	tests := []struct {
+		name     string
+		body     string
+		status   string
+		wantErr  string
+		wantPaid bool
+	}{
+		{name: "paid in full by number", body: `{"payment":{"reference":"INV-2025-0042","amount":"1180.00"}}`, status: "issued", wantPaid: true},
+		{name: "paid in full by ID", body: `{"invoice_id":"{invoice}","payment":{"amount":1180}}`, status: "issued", wantPaid: true},
+		{name: "overpaid", body: `{"payment":{"reference":"INV-2025-0042","amount":1200}}`, status: "issued", wantPaid: true},
+		{name: "already paid", body: `{"payment":{"reference":"INV-2025-0042","amount":1180}}`, status: "paid"},
+		{name: "short payment", body: `{"payment":{"reference":"INV-2025-0042","amount":1179.99}}`, status: "issued", wantErr: "payment of 1179.99 is less than the invoice total 1180.00"},
+		{name: "missing amount", body: `{"payment":{"reference":"INV-2025-0042"}}`, status: "issued", wantErr: `amount not found at "payment.amount"`},
+		{name: "unknown number", body: `{"payment":{"reference":"INV-2025-9999","amount":1180}}`, status: "issued", wantErr: `no invoice numbered "INV-2025-9999"`},
+		{name: "no invoice reference", body: `{"payment":{"amount":1180}}`, status: "issued", wantErr: `neither invoice ID at "invoice_id" nor invoice number at "payment.reference" found`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newWebhookTest(webhook)
+			tt.repo.invoices["INV-2025-0042"] = invoiceID
+			tt.invoices.invoices[invoiceID] = &models.Invoice{ID: invoiceID, TenantID: webhook.TenantID, Status: tc.status, TotalAmount: 1180}
+			body := strings.ReplaceAll(tc.body, "{invoice}", invoiceID.String())
+
+			header := webhookHeader(webhook.SignatureHeader, "sha256="+signWebhook(webhook.Secret, body))
+			delivery, err := tt.svc.Receive(context.Background(), webhook.Slug, header, []byte(body), "")
+			require.NoError(t, err)
+
+			if tc.wantErr != "" {
+				assert.Equal(t, models.InboundDeliveryFailed, delivery.Status)
+				assert.Equal(t, tc.wantErr, *delivery.Error)
+				assert.Empty(t, tt.invoices.paid)
+				return
+			}
+			assert.Equal(t, models.InboundDeliveryProcessed, delivery.Status)
+			assert.Equal(t, &invoiceID, delivery.InvoiceID)
+			assert.Equal(t, "paid", tt.invoices.invoices[invoiceID].Status)
+			if tc.wantPaid {
+				assert.Equal(t, []uuid.UUID{invoiceID}, tt.invoices.paid)
+			} else {
+				assert.Empty(t, tt.invoices.paid, "a paid invoice is left as it is")
+			}
+		})
+	}
+}
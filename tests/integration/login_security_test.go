@@ -0,0 +1,195 @@
+package integration
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"agromart2/internal/caching"
+	"agromart2/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cachedValue struct {
+	value   string
+	expires time.Time
+}
+
+// expiringCache keeps strings and counters with their TTLs against a clock
+// the test moves; the rest of the cache is left unimplemented
+type expiringCache struct {
+	caching.CacheService
+	now     time.Time
+	entries map[string]*cachedValue
+}
+
+func newExpiringCache() *expiringCache {
+	return &expiringCache{now: time.Now(), entries: map[string]*cachedValue{}}
+}
+
+func (c *expiringCache) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func (c *expiringCache) get(key string) *cachedValue {
+	entry, ok := c.entries[key]
+	if !ok || !c.now.Before(entry.expires) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func (c *expiringCache) IncrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	entry := c.get(key)
+	if entry == nil {
+		entry = &cachedValue{value: "0", expires: c.now.Add(ttl)}
+		c.entries[key] = entry
+	}
+	count, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	count++
+	entry.value = strconv.FormatInt(count, 10)
+	return count, nil
+}
+
+func (c *expiringCache) SetString(ctx context.Context, key string, value string, ttl time.Duration) error {
+	c.entries[key] = &cachedValue{value: value, expires: c.now.Add(ttl)}
+	return nil
+}
+
+func (c *expiringCache) GetString(ctx context.Context, key string) (string, error) {
+	if entry := c.get(key); entry != nil {
+		return entry.value, nil
+	}
+	return "", nil
+}
+
+func (c *expiringCache) Delete(ctx context.Context, key string) error {
+	delete(c.entries, key)
+	return nil
+}
+
+func newLoginSecurity() (services.AuthService, *expiringCache) {
+	cache := newExpiringCache()
+	return services.NewAuthService(cache, nil, nil, services.DefaultAccessTokenTTL, services.DefaultRefreshTokenTTL), cache
+}
+
+// failLogins records n failed logins for the email from the address
+func failLogins(t *testing.T, auth services.AuthService, email, ip string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		require.NoError(t, auth.RecordFailedLogin(context.Background(), email, ip))
+	}
+}
+
+// requireLocked asserts the login is locked in the scope for about retryAfter
+func requireLocked(t *testing.T, err error, scope string, retryAfter time.Duration) {
+	t.Helper()
+	var locked *services.LoginLockedError
+	require.ErrorAs(t, err, &locked)
+	assert.Equal(t, scope, locked.Scope)
+	assert.InDelta(t, retryAfter.Seconds(), locked.RetryAfter.Seconds(), 2)
+}
+
+func TestLockoutDurationDoublesUpToTheCap(t *testing.T) {
+	tests := []struct {
+		lockouts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{11, 1024 * time.Minute},
+		{12, 24 * time.Hour},
+		{100, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, services.LockoutDuration(tt.lockouts), "lockouts=%d", tt.lockouts)
+	}
+}
+
+func TestAccountLocksAtTheFailureThreshold(t *testing.T) {
+	ctx := context.Background()
+	auth, cache := newLoginSecurity()
+
+	failLogins(t, auth, "Farmer@Example.com", "", 4)
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""))
+
+	failLogins(t, auth, " farmer@example.com ", "", 1)
+	requireLocked(t, auth.CheckLoginAllowed(ctx, "FARMER@example.com", "10.0.0.1"), "account", time.Minute)
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "other@example.com", "10.0.0.1"))
+
+	// The lock lifts when it expires, and the next one lasts twice as long
+	cache.advance(time.Minute)
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""))
+	failLogins(t, auth, "farmer@example.com", "", 5)
+	requireLocked(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""), "account", 2*time.Minute)
+
+	// Failures older than the window do not count towards a lock
+	cache.advance(2 * time.Minute)
+	failLogins(t, auth, "farmer@example.com", "", 4)
+	cache.advance(15 * time.Minute)
+	failLogins(t, auth, "farmer@example.com", "", 1)
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""))
+}
+
+func TestAddressLocksAcrossAccounts(t *testing.T) {
+	ctx := context.Background()
+	auth, _ := newLoginSecurity()
+
+	for i := 0; i < 20; i++ {
+		failLogins(t, auth, "user"+strconv.Itoa(i)+"@example.com", "10.0.0.1", 1)
+	}
+	requireLocked(t, auth.CheckLoginAllowed(ctx, "new@example.com", "10.0.0.1"), "ip", time.Minute)
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "new@example.com", "10.0.0.2"))
+
+	// Unlocking an account leaves the address locked
+	require.NoError(t, auth.UnlockAccount(ctx, "user0@example.com"))
+	requireLocked(t, auth.CheckLoginAllowed(ctx, "user0@example.com", "10.0.0.1"), "ip", time.Minute)
+}
+
+func TestUnlockAccountClearsLockAndHistory(t *testing.T) {
+	ctx := context.Background()
+	auth, cache := newLoginSecurity()
+
+	failLogins(t, auth, "farmer@example.com", "", 5)
+	cache.advance(time.Minute)
+	failLogins(t, auth, "farmer@example.com", "", 9)
+	requireLocked(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""), "account", 2*time.Minute)
+
+	require.NoError(t, auth.UnlockAccount(ctx, " Farmer@example.com"))
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""))
+
+	// The failures counted before the unlock are gone, and so is the
+	// lockout history that doubles the next lock
+	failLogins(t, auth, "farmer@example.com", "", 4)
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""))
+	failLogins(t, auth, "farmer@example.com", "", 1)
+	requireLocked(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""), "account", time.Minute)
+}
+
+func TestLapsedLockDoesNotBlockLogin(t *testing.T) {
+	ctx := context.Background()
+	auth, cache := newLoginSecurity()
+
+	// A lock whose time has passed but whose key has not expired yet
+	lapsed := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	require.NoError(t, cache.SetString(ctx, "login_lock:account:farmer@example.com", lapsed, time.Minute))
+	require.NoError(t, auth.CheckLoginAllowed(ctx, "farmer@example.com", ""))
+}
+
+func TestDeviceFingerprint(t *testing.T) {
+	chrome := "Mozilla/5.0 (Linux; Android 14) Chrome/126.0"
+
+	assert.Len(t, services.DeviceFingerprint(chrome), 16)
+	assert.Equal(t, services.DeviceFingerprint(chrome), services.DeviceFingerprint("  "+chrome+"\n"))
+	assert.Equal(t, services.DeviceFingerprint(chrome), services.DeviceFingerprint("MOZILLA/5.0 (LINUX; ANDROID 14) CHROME/126.0"))
+	assert.NotEqual(t, services.DeviceFingerprint(chrome), services.DeviceFingerprint("Mozilla/5.0 (Linux; Android 14) Chrome/127.0"))
+}
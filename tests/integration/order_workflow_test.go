@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"testing"
+
+	"agromart2/internal/models"
+	"agromart2/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderStateMachineRejectsIllegalTransitions(t *testing.T) {
+	tests := []struct {
+		name      string
+		workflow  *models.OrderWorkflow
+		orderType string
+		status    string
+		event     string
+		allowed   []string
+	}{
+		{
+			name:    "ship before processing",
+			status:  models.OrderStatusApproved,
+			event:   models.OrderEventShip,
+			allowed: []string{models.OrderStatusProcessing, models.OrderStatusQualityCheck},
+		},
+		{
+			name:    "process without approval",
+			status:  models.OrderStatusPending,
+			event:   models.OrderEventProcess,
+			allowed: []string{models.OrderStatusApproved},
+		},
+		{
+			name:    "approve twice",
+			status:  models.OrderStatusApproved,
+			event:   models.OrderEventApprove,
+			allowed: []string{models.OrderStatusPending},
+		},
+		{
+			name:    "cancel after delivery",
+			status:  models.OrderStatusDelivered,
+			event:   models.OrderEventCancel,
+			allowed: []string{models.OrderStatusPending, models.OrderStatusApproved, models.OrderStatusProcessing, models.OrderStatusQualityCheck, models.OrderStatusShipped},
+		},
+		{
+			name:    "reopen a cancelled order",
+			status:  models.OrderStatusCancelled,
+			event:   models.OrderEventApprove,
+			allowed: []string{models.OrderStatusPending},
+		},
+		{
+			name:     "ship an unchecked order",
+			workflow: &models.OrderWorkflow{QualityCheck: true},
+			status:   models.OrderStatusProcessing,
+			event:    models.OrderEventShip,
+			allowed:  []string{models.OrderStatusQualityCheck},
+		},
+		{
+			name:      "receive a sales order",
+			orderType: "sales",
+			status:    models.OrderStatusProcessing,
+			event:     models.OrderEventReceive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderType := tt.orderType
+			if orderType == "" {
+				orderType = "purchase"
+			}
+			order := &models.Order{OrderType: orderType, Status: tt.status}
+
+			next, err := services.NewOrderStateMachine(tt.workflow).Next(order, tt.event)
+
+			assert.Empty(t, next)
+			var transitionErr *services.OrderTransitionError
+			require.ErrorAs(t, err, &transitionErr)
+			assert.Equal(t, tt.event, transitionErr.Event)
+			assert.Equal(t, tt.status, transitionErr.Status)
+			assert.Equal(t, tt.allowed, transitionErr.Allowed)
+		})
+	}
+}
+
+func TestOrderStateMachineRejectsEventsOutsideTheWorkflow(t *testing.T) {
+	order := &models.Order{OrderType: "sales", Status: models.OrderStatusProcessing}
+
+	_, err := services.NewOrderStateMachine(nil).Next(order, models.OrderEventQualityCheck)
+	assert.ErrorIs(t, err, services.ErrOrderEventNotInWorkflow)
+
+	_, err = services.NewOrderStateMachine(nil).Next(order, "refund")
+	assert.ErrorIs(t, err, services.ErrOrderEventNotInWorkflow)
+
+	next, err := services.NewOrderStateMachine(&models.OrderWorkflow{QualityCheck: true}).Next(order, models.OrderEventQualityCheck)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusQualityCheck, next)
+}
+
+func TestOrderStateMachineEventsExcludeIllegalTransitions(t *testing.T) {
+	machine := services.NewOrderStateMachine(&models.OrderWorkflow{QualityCheck: true})
+
+	assert.Equal(t, []string{models.OrderEventApprove, models.OrderEventCancel},
+		machine.Events(&models.Order{OrderType: "sales", Status: models.OrderStatusPending}))
+	assert.Equal(t, []string{models.OrderEventQualityCheck, models.OrderEventCancel},
+		machine.Events(&models.Order{OrderType: "sales", Status: models.OrderStatusProcessing}))
+	assert.Empty(t, machine.Events(&models.Order{OrderType: "sales", Status: models.OrderStatusDelivered}))
+}
@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"agromart2/internal/models"
+	"agromart2/internal/repositories"
+	"agromart2/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// supplierRepoStub records created suppliers and finds none by name; the
+// rest of the repository is left unimplemented
+type supplierRepoStub struct {
+	repositories.SupplierRepository
+	created []*models.Supplier
+}
+
+func (r *supplierRepoStub) GetByName(ctx context.Context, tenantID uuid.UUID, name string) (*models.Supplier, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *supplierRepoStub) Create(ctx context.Context, supplier *models.Supplier) error {
+	r.created = append(r.created, supplier)
+	return nil
+}
+
+func TestSupplierBankAccountIsValidatedAndNormalized(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name       string
+		number     *string
+		ifsc       *string
+		wantNumber *string
+		wantIFSC   *string
+		valid      bool
+	}{
+		{name: "none", valid: true},
+		{name: "blank", number: str(" "), ifsc: str(""), valid: true},
+		{name: "spaced and lowercase", number: str("5010 0123 4567 89"), ifsc: str("hdfc0001234"), wantNumber: str("50100123456789"), wantIFSC: str("HDFC0001234"), valid: true},
+		{name: "number without IFSC", number: str("50100123456789")},
+		{name: "IFSC without number", ifsc: str("HDFC0001234")},
+		{name: "too short", number: str("12345678"), ifsc: str("HDFC0001234")},
+		{name: "letters in number", number: str("5010012345678A"), ifsc: str("HDFC0001234")},
+		{name: "IFSC without the zero", number: str("50100123456789"), ifsc: str("HDFC1001234")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &supplierRepoStub{}
+			svc := services.NewSupplierService(repo, nil)
+
+			err := svc.Create(context.Background(), uuid.New(), &models.Supplier{
+				Name:              "Krishna Agro Inputs",
+				BankAccountNumber: tt.number,
+				BankIFSC:          tt.ifsc,
+			})
+
+			if !tt.valid {
+				assert.ErrorIs(t, err, services.ErrInvalidBankAccount)
+				assert.Empty(t, repo.created)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, repo.created, 1)
+			assert.Equal(t, tt.wantNumber, repo.created[0].BankAccountNumber)
+			assert.Equal(t, tt.wantIFSC, repo.created[0].BankIFSC)
+		})
+	}
+}
@@ -94,14 +94,20 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+// expectPermissionNames makes the permission repository resolve names for the user in the tenant
+func (suite *RBACIntegrationTestSuite) expectPermissionNames(ctx context.Context, tenantID uuid.UUID, names ...string) *mock.Call {
+	if names == nil {
+		names = []string{}
+	}
+	return suite.mockPermissionRepo.On("ListNamesByUser", ctx, tenantID, suite.userID).Return(names, nil)
+}
+
 // TestEndToEndPermissionWorkflow_UserHasPermission_Granted tests successful permission grant
 func (suite *RBACIntegrationTestSuite) TestEndToEndPermissionWorkflow_UserHasPermission_Granted() {
 	ctx := context.Background()
 
 	// Setup: User has role with required permission
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{suite.testUserRole}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return([]*models.RolePermission{suite.testRolePermission}, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, suite.testPermission.ID).Return(suite.testPermission, nil).Once()
+	suite.expectPermissionNames(ctx, suite.tenantID, suite.testPermission.Name).Once()
 
 	hasPermission, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, suite.testPermission.Name)
 
@@ -113,8 +119,8 @@ func (suite *RBACIntegrationTestSuite) TestEndToEndPermissionWorkflow_UserHasPer
 func (suite *RBACIntegrationTestSuite) TestEndToEndPermissionWorkflow_UserHasPermission_Denied_NoRole() {
 	ctx := context.Background()
 
-	// Setup: User has no roles
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{}, nil).Once()
+	// Setup: User has no roles, so no permission names resolve
+	suite.expectPermissionNames(ctx, suite.tenantID).Once()
 
 	hasPermission, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, "any:permission")
 
@@ -122,18 +128,17 @@ func (suite *RBACIntegrationTestSuite) TestEndToEndPermissionWorkflow_UserHasPer
 	assert.False(suite.T(), hasPermission, "User without roles should not have any permissions")
 }
 
-// TestEndToEndPermissionWorkflow_UserHasPermission_Denied_RoleNoPermission tests permission denial when role has no permissions
+// TestEndToEndPermissionWorkflow_UserHasPermission_Denied_RoleNoPermission tests permission denial when role lacks the permission
 func (suite *RBACIntegrationTestSuite) TestEndToEndPermissionWorkflow_UserHasPermission_Denied_RoleNoPermission() {
 	ctx := context.Background()
 
-	// Setup: User has role but role has no permissions
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{suite.testUserRole}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return([]*models.RolePermission{}, nil).Once()
+	// Setup: User's role grants other permissions only
+	suite.expectPermissionNames(ctx, suite.tenantID, suite.testPermission.Name).Once()
 
 	hasPermission, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, "missing:permission")
 
 	assert.NoError(suite.T(), err)
-	assert.False(suite.T(), hasPermission, "User with role but no permissions should be denied access")
+	assert.False(suite.T(), hasPermission, "User with role but no matching permission should be denied access")
 }
 
 // TestMultiTenantPermissionIsolation tests that permissions are properly isolated between tenants
@@ -143,14 +148,9 @@ func (suite *RBACIntegrationTestSuite) TestMultiTenantPermissionIsolation() {
 	tenantID1 := uuid.New()
 	tenantID2 := uuid.New()
 
-	// Setup tenant 1: User has permission
-	userRole1 := &models.UserRole{ID: uuid.New(), UserID: suite.userID, RoleID: suite.testRole.ID}
-	suite.mockUserRoleRepo.On("ListByUser", ctx, tenantID1, suite.userID).Return([]*models.UserRole{userRole1}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, tenantID1, suite.testRole.ID).Return([]*models.RolePermission{suite.testRolePermission}, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, suite.testPermission.ID).Return(suite.testPermission, nil).Once()
-
-	// Setup tenant 2: Same user but different tenant has no permissions
-	suite.mockUserRoleRepo.On("ListByUser", ctx, tenantID2, suite.userID).Return([]*models.UserRole{}, nil).Once()
+	// Setup tenant 1: User has permission; tenant 2: same user has none
+	suite.expectPermissionNames(ctx, tenantID1, suite.testPermission.Name).Once()
+	suite.expectPermissionNames(ctx, tenantID2).Once()
 
 	// Test: User has permission in tenant 1 but not in tenant 2
 	hasPermission1, err := suite.rbacService.UserHasPermission(ctx, suite.userID, tenantID1, suite.testPermission.Name)
@@ -166,9 +166,7 @@ func (suite *RBACIntegrationTestSuite) TestMultiTenantPermissionIsolation() {
 func (suite *RBACIntegrationTestSuite) TestGetUserPermissions_Success() {
 	ctx := context.Background()
 
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{suite.testUserRole}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return([]*models.RolePermission{suite.testRolePermission}, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, suite.testPermission.ID).Return(suite.testPermission, nil).Once()
+	suite.expectPermissionNames(ctx, suite.tenantID, suite.testPermission.Name).Once()
 
 	permissions, err := suite.rbacService.GetUserPermissions(ctx, suite.userID, suite.tenantID)
 
@@ -181,7 +179,7 @@ func (suite *RBACIntegrationTestSuite) TestGetUserPermissions_Success() {
 func (suite *RBACIntegrationTestSuite) TestGetUserPermissions_NoRoles() {
 	ctx := context.Background()
 
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{}, nil).Once()
+	suite.expectPermissionNames(ctx, suite.tenantID).Once()
 
 	permissions, err := suite.rbacService.GetUserPermissions(ctx, suite.userID, suite.tenantID)
 
@@ -193,44 +191,8 @@ func (suite *RBACIntegrationTestSuite) TestGetUserPermissions_NoRoles() {
 func (suite *RBACIntegrationTestSuite) TestMultipleRolesMultiplePermissions() {
 	ctx := context.Background()
 
-	// Create additional test data
-	role2 := &models.Role{
-		ID:          uuid.New(),
-		TenantID:    suite.tenantID,
-		Name:        "AdditionalRole",
-		Description: stringPtr("Additional test role"),
-	}
-
-	permission2 := &models.Permission{
-		ID:          uuid.New(),
-		Name:        "additional:permission",
-		Description: stringPtr("Additional test permission"),
-	}
-
-	userRole2 := &models.UserRole{
-		ID:        uuid.New(),
-		UserID:    suite.userID,
-		RoleID:    role2.ID,
-		CreatedAt: time.Now(),
-	}
-
-	rolePermission2 := &models.RolePermission{
-		ID:            uuid.New(),
-		RoleID:        role2.ID,
-		PermissionID:  permission2.ID,
-		CreatedAt:     time.Now(),
-	}
-
-	// User has two roles with different permissions
-	userRoles := []*models.UserRole{suite.testUserRole, userRole2}
-	rolePermissions1 := []*models.RolePermission{suite.testRolePermission}
-	rolePermissions2 := []*models.RolePermission{rolePermission2}
-
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return(userRoles, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return(rolePermissions1, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, role2.ID).Return(rolePermissions2, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, suite.testPermission.ID).Return(suite.testPermission, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, permission2.ID).Return(permission2, nil).Once()
+	// User has two roles with different permissions, resolved in one query
+	suite.expectPermissionNames(ctx, suite.tenantID, "additional:permission", suite.testPermission.Name).Times(3)
 
 	permissions, err := suite.rbacService.GetUserPermissions(ctx, suite.userID, suite.tenantID)
 
@@ -242,7 +204,7 @@ func (suite *RBACIntegrationTestSuite) TestMultipleRolesMultiplePermissions() {
 	assert.NoError(suite.T(), err)
 	assert.True(suite.T(), hasPerm1, "User should have first permission")
 
-	hasPerm2, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, permission2.Name)
+	hasPerm2, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, "additional:permission")
 	assert.NoError(suite.T(), err)
 	assert.True(suite.T(), hasPerm2, "User should have second permission")
 }
@@ -251,137 +213,50 @@ func (suite *RBACIntegrationTestSuite) TestMultipleRolesMultiplePermissions() {
 func (suite *RBACIntegrationTestSuite) TestRepositoryErrorHandling() {
 	ctx := context.Background()
 
-	// Test error in user role repository
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return((*[]*models.UserRole)(nil), assert.AnError).Once()
-
-	hasPermission, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, "any:permission")
-	assert.Error(suite.T(), err)
-	assert.False(suite.T(), hasPermission, "Error should result in permission denial")
+	suite.mockPermissionRepo.On("ListNamesByUser", ctx, suite.tenantID, suite.userID).Return([]string(nil), assert.AnError).Twice()
 
-	// Test error in role permission repository
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{suite.testUserRole}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return((*[]*models.RolePermission)(nil), assert.AnError).Once()
-
-	hasPermission, err = suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, suite.testPermission.Name)
+	hasPermission, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, suite.testPermission.Name)
 	assert.Error(suite.T(), err)
 	assert.False(suite.T(), hasPermission, "Repository error should result in permission denial")
 
-	// Test error in permission repository
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{suite.testUserRole}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return([]*models.RolePermission{suite.testRolePermission}, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, suite.testPermission.ID).Return((*models.Permission)(nil), assert.AnError).Once()
-
-	hasPermission, err = suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, suite.testPermission.Name)
+	permissions, err := suite.rbacService.GetUserPermissions(ctx, suite.userID, suite.tenantID)
 	assert.Error(suite.T(), err)
-	assert.False(suite.T(), hasPermission, "Permission repository error should result in denial")
+	assert.Empty(suite.T(), permissions)
 }
 
 // TestPermissionsDeduplication tests that duplicate permissions are correctly handled
 func (suite *RBACIntegrationTestSuite) TestPermissionsDeduplication() {
 	ctx := context.Background()
 
-	// Create two roles that share the same permission
-	role2 := &models.Role{
-		ID:          uuid.New(),
-		TenantID:    suite.tenantID,
-		Name:        "DuplicateRole",
-		Description: stringPtr("Duplicate test role"),
-	}
-
-	userRole2 := &models.UserRole{
-		ID:        uuid.New(),
-		UserID:    suite.userID,
-		RoleID:    role2.ID,
-		CreatedAt: time.Now(),
-	}
-
-	rolePermission2 := &models.RolePermission{
-		ID:            uuid.New(),
-		RoleID:        role2.ID,
-		PermissionID:  suite.testPermission.ID, // Same permission as first role
-		CreatedAt:     time.Now(),
-	}
-
-	userRoles := []*models.UserRole{suite.testUserRole, userRole2}
-	rolePermissions1 := []*models.RolePermission{suite.testRolePermission}
-	rolePermissions2 := []*models.RolePermission{rolePermission2}
-
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return(userRoles, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return(rolePermissions1, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, role2.ID).Return(rolePermissions2, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, suite.testPermission.ID).Return(suite.testPermission, nil).Twice() // Same permission retrieved twice
+	// Two roles share the same permission; the join query selects each name once
+	suite.expectPermissionNames(ctx, suite.tenantID, suite.testPermission.Name).Twice()
 
 	permissions, err := suite.rbacService.GetUserPermissions(ctx, suite.userID, suite.tenantID)
 
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), permissions, 1, "Duplicate permissions should be deduplicated")
 	assert.Equal(suite.T(), suite.testPermission.Name, permissions[0])
+
+	hasPermission, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, suite.testPermission.Name)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), hasPermission, "A permission granted by two roles should still be granted")
 }
 
 // TestRoleHierarchyInheritance tests permission inheritance through multiple roles
 func (suite *RBACIntegrationTestSuite) TestRoleHierarchyInheritance() {
 	ctx := context.Background()
 
-	// Create hierarchy: Manager role inherits from Employee role
-
-	// Manager role
-	managerRole := &models.Role{
-		ID:          uuid.New(),
-		TenantID:    suite.tenantID,
-		Name:        "Manager",
-		Description: stringPtr("Manager role inherits employee permissions"),
-	}
-
-	// Employee permission
-	employeePerm := &models.Permission{
-		ID:          suite.testPermission.ID,
-		Name:        "employee:access",
-		Description: stringPtr("Basic employee access"),
-	}
-
-	// Manager permission
-	managerPerm := &models.Permission{
-		ID:          uuid.New(),
-		Name:        "manager:approve",
-		Description: stringPtr("Manager approval permission"),
-	}
-
-	// User has Manager role (should inherit both permissions)
-	userRole := &models.UserRole{
-		ID:        uuid.New(),
-		UserID:    suite.userID,
-		RoleID:    managerRole.ID,
-		CreatedAt: time.Now(),
-	}
-
-	// Manager role has both employee and manager permissions
-	employeeRolePerm := &models.RolePermission{
-		ID:            suite.testRolePermission.ID,
-		RoleID:        managerRole.ID,
-		PermissionID:  employeePerm.ID,
-		CreatedAt:     time.Now(),
-	}
-
-	managerRolePerm := &models.RolePermission{
-		ID:            uuid.New(),
-		RoleID:        managerRole.ID,
-		PermissionID:  managerPerm.ID,
-		CreatedAt:     time.Now(),
-	}
-
-	rolePermissions := []*models.RolePermission{employeeRolePerm, managerRolePerm}
-
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{userRole}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, managerRole.ID).Return(rolePermissions, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, employeePerm.ID).Return(employeePerm, nil).Once()
-	suite.mockPermissionRepo.On("GetByID", ctx, managerPerm.ID).Return(managerPerm, nil).Once()
+	// User has the Manager role, which carries both employee and manager permissions
+	employeePerm := "employee:access"
+	managerPerm := "manager:approve"
+	suite.expectPermissionNames(ctx, suite.tenantID, employeePerm, managerPerm).Times(3)
 
 	// Test that manager has both employee and manager permissions
-	hasEmployeePerm, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, employeePerm.Name)
+	hasEmployeePerm, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, employeePerm)
 	assert.NoError(suite.T(), err)
 	assert.True(suite.T(), hasEmployeePerm, "Manager should have employee permissions")
 
-	hasManagerPerm, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, managerPerm.Name)
+	hasManagerPerm, err := suite.rbacService.UserHasPermission(ctx, suite.userID, suite.tenantID, managerPerm)
 	assert.NoError(suite.T(), err)
 	assert.True(suite.T(), hasManagerPerm, "Manager should have manager permissions")
 
@@ -396,35 +271,9 @@ func (suite *RBACIntegrationTestSuite) TestAccessControlPattern_ComprehensiveCRU
 	ctx := context.Background()
 
 	// Create permissions for CRUD operations on products
-	productPermissions := []string{"products:create", "products:read", "products:update", "products:delete"}
-	userPermissions := make([]*models.Permission, len(productPermissions))
-
-	rolePermissions := []*models.RolePermission{}
-	for i, permName := range productPermissions {
-		permObj := &models.Permission{
-			ID:          uuid.New(),
-			Name:        permName,
-			Description: stringPtr("Product " + permName + " permission"),
-			CreatedAt:  time.Now(),
-		}
-		userPermissions[i] = permObj
-
-		rolePermission := &models.RolePermission{
-			ID:            uuid.New(),
-			RoleID:        suite.testRole.ID,
-			PermissionID:  permObj.ID,
-			CreatedAt:     time.Now(),
-		}
-		rolePermissions = append(rolePermissions, rolePermission)
-	}
+	productPermissions := []string{"products:create", "products:delete", "products:read", "products:update"}
 
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{suite.testUserRole}, nil).Once()
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return(rolePermissions, nil).Once()
-
-	// Set up all permission expectations
-	for _, perm := range userPermissions {
-		suite.mockPermissionRepo.On("GetByID", ctx, perm.ID).Return(perm, nil).Once()
-	}
+	suite.expectPermissionNames(ctx, suite.tenantID, productPermissions...)
 
 	// Test that user has all CRUD permissions
 	permissions, err := suite.rbacService.GetUserPermissions(ctx, suite.userID, suite.tenantID)
@@ -449,9 +298,7 @@ func (suite *RBACIntegrationTestSuite) TestConcurrentPermissionAccess() {
 	ctx := context.Background()
 
 	// Setup expectations that should work for multiple concurrent calls
-	suite.mockUserRoleRepo.On("ListByUser", ctx, suite.tenantID, suite.userID).Return([]*models.UserRole{suite.testUserRole}, nil)
-	suite.mockRolePermissionRepo.On("ListByRole", ctx, suite.tenantID, suite.testRole.ID).Return([]*models.RolePermission{suite.testRolePermission}, nil)
-	suite.mockPermissionRepo.On("GetByID", ctx, suite.testPermission.ID).Return(suite.testPermission, nil)
+	suite.expectPermissionNames(ctx, suite.tenantID, suite.testPermission.Name)
 
 	// Run multiple concurrent permission checks
 	done := make(chan bool, 10)
@@ -574,4 +421,9 @@ func (m *MockPermissionRepository) Delete(ctx context.Context, id uuid.UUID) err
 func (m *MockPermissionRepository) List(ctx context.Context, limit, offset int) ([]*models.Permission, error) {
 	args := m.Called(ctx, limit, offset)
 	return args.Get(0).([]*models.Permission), args.Error(1)
+}
+
+func (m *MockPermissionRepository) ListNamesByUser(ctx context.Context, tenantID, userID uuid.UUID) ([]string, error) {
+	args := m.Called(ctx, tenantID, userID)
+	return args.Get(0).([]string), args.Error(1)
 }
\ No newline at end of file
@@ -0,0 +1,62 @@
+//go:build integration
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	harness "agromart2/testhelpers/integration"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with: go test -tags integration ./tests/integration/...
+func TestTenantIsolationEndToEnd(t *testing.T) {
+	h := harness.Setup(t)
+
+	tenantA := h.SeedTenant(t)
+	tenantB := h.SeedTenant(t)
+
+	capacity := 500
+	resp := h.Do(t, http.MethodPost, "/v1/warehouses", map[string]interface{}{
+		"name":     "Integration Warehouse",
+		"capacity": capacity,
+	}, tenantA.Token)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	var listA struct {
+		Warehouses []map[string]interface{} `json:"warehouses"`
+	}
+	harness.DecodeJSON(t, h.Do(t, http.MethodGet, "/v1/warehouses", nil, tenantA.Token), &listA)
+	assert.Len(t, listA.Warehouses, 1)
+
+	var listB struct {
+		Warehouses []map[string]interface{} `json:"warehouses"`
+	}
+	harness.DecodeJSON(t, h.Do(t, http.MethodGet, "/v1/warehouses", nil, tenantB.Token), &listB)
+	assert.Empty(t, listB.Warehouses)
+}
+
+func TestInvoiceRequiresExistingOrder(t *testing.T) {
+	h := harness.Setup(t)
+	tenant := h.SeedTenant(t)
+
+	resp := h.Do(t, http.MethodPost, "/v1/invoices", map[string]interface{}{
+		"order_id": "00000000-0000-0000-0000-000000000001",
+	}, tenant.Token)
+	defer resp.Body.Close()
+
+	assert.NotEqual(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestProtectedRoutesRejectAnonymousRequests(t *testing.T) {
+	h := harness.Setup(t)
+
+	resp := h.Do(t, http.MethodGet, "/v1/orders", nil, "")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}